@@ -100,11 +100,13 @@ func (n *DropDatabaseStmt) Accept(v Visitor) (Node, bool) {
 }
 
 // IndexColName is used for parsing index column name from SQL.
+// It's an expression rather than a column name if Expr is set, e.g. (lower(name)).
 type IndexColName struct {
 	node
 
 	Column *ColumnName
 	Length int
+	Expr   ExprNode
 }
 
 // Accept implements Node Accept interface.
@@ -114,11 +116,20 @@ func (n *IndexColName) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*IndexColName)
-	node, ok := n.Column.Accept(v)
-	if !ok {
-		return n, false
+	if n.Column != nil {
+		node, ok := n.Column.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Column = node.(*ColumnName)
+	}
+	if n.Expr != nil {
+		node, ok := n.Expr.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Expr = node.(ExprNode)
 	}
-	n.Column = node.(*ColumnName)
 	return v.Leave(n)
 }
 
@@ -243,6 +254,7 @@ const (
 	ColumnOptionOnUpdate // For Timestamp and Datetime only.
 	ColumnOptionFulltext
 	ColumnOptionComment
+	ColumnOptionAutoRandom
 )
 
 // ColumnOption is used for parsing column constraint info from SQL.
@@ -252,6 +264,32 @@ type ColumnOption struct {
 	Tp ColumnOptionType
 	// The value For Default or On Update.
 	Expr ExprNode
+	// AutoRandomBitLength is the number of the shard bits of the AUTO_RANDOM column, it's
+	// types.UnspecifiedLength if the length isn't specified.
+	AutoRandomBitLength int
+	// PrimaryKeyTp is the CLUSTERED/NONCLUSTERED option of the PRIMARY KEY column option.
+	PrimaryKeyTp PrimaryKeyType
+}
+
+// PrimaryKeyType is the type for the CLUSTERED/NONCLUSTERED option of the primary key.
+type PrimaryKeyType int
+
+// PrimaryKeyType types.
+const (
+	PrimaryKeyTypeDefault PrimaryKeyType = iota
+	PrimaryKeyTypeClustered
+	PrimaryKeyTypeNonClustered
+)
+
+// String implements fmt.Stringer interface.
+func (t PrimaryKeyType) String() string {
+	switch t {
+	case PrimaryKeyTypeClustered:
+		return "CLUSTERED"
+	case PrimaryKeyTypeNonClustered:
+		return "NONCLUSTERED"
+	}
+	return ""
 }
 
 // Accept implements Node Accept interface.
@@ -276,6 +314,8 @@ func (n *ColumnOption) Accept(v Visitor) (Node, bool) {
 //  | index_type
 //  | WITH PARSER parser_name
 //  | COMMENT 'string'
+//  | {VISIBLE | INVISIBLE}
+//  | {CLUSTERED | NONCLUSTERED}
 // See http://dev.mysql.com/doc/refman/5.7/en/create-table.html
 type IndexOption struct {
 	node
@@ -283,8 +323,21 @@ type IndexOption struct {
 	KeyBlockSize uint64
 	Tp           model.IndexType
 	Comment      string
+	Visibility   IndexVisibility
+	// PrimaryKeyTp is only valid for the primary key.
+	PrimaryKeyTp PrimaryKeyType
 }
 
+// IndexVisibility is the type for the visibility of an index.
+type IndexVisibility int
+
+// IndexVisibility types.
+const (
+	IndexVisibilityDefault IndexVisibility = iota
+	IndexVisibilityVisible
+	IndexVisibilityInvisible
+)
+
 // Accept implements Node Accept interface.
 func (n *IndexOption) Accept(v Visitor) (Node, bool) {
 	newNode, skipChildren := v.Enter(n)
@@ -396,11 +449,14 @@ type CreateTableStmt struct {
 	ddlNode
 
 	IfNotExists bool
+	IsTemporary bool
 	Table       *TableName
 	ReferTable  *TableName
 	Cols        []*ColumnDef
 	Constraints []*Constraint
 	Options     []*TableOption
+	// Select is the query of CREATE TABLE ... SELECT, whose result is inserted into the created table.
+	Select ResultSetNode
 }
 
 // Accept implements Node Accept interface.
@@ -436,6 +492,13 @@ func (n *CreateTableStmt) Accept(v Visitor) (Node, bool) {
 		}
 		n.Constraints[i] = node.(*Constraint)
 	}
+	if n.Select != nil {
+		node, ok = n.Select.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Select = node.(ResultSetNode)
+	}
 	return v.Leave(n)
 }
 
@@ -444,8 +507,9 @@ func (n *CreateTableStmt) Accept(v Visitor) (Node, bool) {
 type DropTableStmt struct {
 	ddlNode
 
-	IfExists bool
-	Tables   []*TableName
+	IfExists    bool
+	IsTemporary bool
+	Tables      []*TableName
 }
 
 // Accept implements Node Accept interface.
@@ -465,13 +529,12 @@ func (n *DropTableStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
-// RenameTableStmt is a statement to rename a table.
+// RenameTableStmt is a statement to rename tables, the tables are renamed in order.
 // See http://dev.mysql.com/doc/refman/5.7/en/rename-table.html
 type RenameTableStmt struct {
 	ddlNode
 
-	OldTable *TableName
-	NewTable *TableName
+	TableToTables []*TableToTable
 }
 
 // Accept implements Node Accept interface.
@@ -481,6 +544,81 @@ func (n *RenameTableStmt) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*RenameTableStmt)
+	for i, t := range n.TableToTables {
+		node, ok := t.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.TableToTables[i] = node.(*TableToTable)
+	}
+	return v.Leave(n)
+}
+
+// RecoverTableStmt is a statement to recover the table dropped or truncated by the DDL job, or the latest dropped
+// table of the name if JobID is 0.
+type RecoverTableStmt struct {
+	ddlNode
+
+	JobID int64
+	Table *TableName
+}
+
+// Accept implements Node Accept interface.
+func (n *RecoverTableStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*RecoverTableStmt)
+	if n.Table != nil {
+		node, ok := n.Table.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Table = node.(*TableName)
+	}
+	return v.Leave(n)
+}
+
+// FlashbackTableStmt is a statement to recover the latest dropped or truncated table of the name, the table is
+// renamed to NewName if it's not empty.
+type FlashbackTableStmt struct {
+	ddlNode
+
+	Table   *TableName
+	NewName string
+}
+
+// Accept implements Node Accept interface.
+func (n *FlashbackTableStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*FlashbackTableStmt)
+	node, ok := n.Table.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Table = node.(*TableName)
+	return v.Leave(n)
+}
+
+// TableToTable represents renaming the old table to the new table in the rename table statement.
+type TableToTable struct {
+	node
+
+	OldTable *TableName
+	NewTable *TableName
+}
+
+// Accept implements Node Accept interface.
+func (n *TableToTable) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*TableToTable)
 	node, ok := n.OldTable.Accept(v)
 	if !ok {
 		return n, false
@@ -574,6 +712,12 @@ const (
 	TableOptionDelayKeyWrite
 	TableOptionRowFormat
 	TableOptionStatsPersistent
+	TableOptionAutoIDCache
+	TableOptionTTL
+	TableOptionTTLEnable
+	TableOptionTTLJobInterval
+	TableOptionShardRowID
+	TableOptionPreSplitRegion
 )
 
 // RowFormat types
@@ -591,6 +735,8 @@ type TableOption struct {
 	Tp        TableOptionType
 	StrValue  string
 	UintValue uint64
+	// ColumnName is the time column of the TTL option, the rows expire UintValue of the time unit StrValue after it.
+	ColumnName *ColumnName
 }
 
 // ColumnPositionType is the type for ColumnPosition.
@@ -646,6 +792,8 @@ const (
 	AlterTableRenameTable
 	AlterTableAlterColumn
 	AlterTableLock
+	AlterTableRemoveTTL
+	AlterTableIndexVisibility
 
 // TODO: Add more actions
 )
@@ -662,6 +810,8 @@ type AlterTableSpec struct {
 	NewColumn     *ColumnDef
 	OldColumnName *ColumnName
 	Position      *ColumnPosition
+	// Visibility is the new visibility of the index Name.
+	Visibility IndexVisibility
 }
 
 // Accept implements Node Accept interface.
@@ -111,6 +111,14 @@ type TableName struct {
 	TableInfo *model.TableInfo
 
 	IndexHints []*IndexHint
+	// AsOf is set when the table is read at a historical timestamp.
+	AsOf *AsOfClause
+}
+
+// AsOfClause represents the AS OF TIMESTAMP clause of a table, the table is read from the snapshot at the timestamp.
+// The expression is evaluated before the statement is planned, so it can't refer to any column.
+type AsOfClause struct {
+	TsExpr ExprNode
 }
 
 // IndexHintType is the type for index hint use, ignore or force.
@@ -148,6 +156,13 @@ func (n *TableName) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*TableName)
+	if n.AsOf != nil {
+		node, ok := n.AsOf.TsExpr.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.AsOf.TsExpr = node.(ExprNode)
+	}
 	return v.Leave(n)
 }
 
@@ -243,8 +258,17 @@ const (
 	SelectLockNone SelectLockType = iota
 	SelectLockForUpdate
 	SelectLockInShareMode
+	// SelectLockForUpdateNoWait returns an error at once if a row is locked by another transaction.
+	SelectLockForUpdateNoWait
+	// SelectLockForUpdateSkipLocked skips the rows locked by the other transactions.
+	SelectLockForUpdateSkipLocked
 )
 
+// IsForUpdate checks whether the lock type is one of the FOR UPDATE lock types.
+func (lt SelectLockType) IsForUpdate() bool {
+	return lt == SelectLockForUpdate || lt == SelectLockForUpdateNoWait || lt == SelectLockForUpdateSkipLocked
+}
+
 // WildCardField is a special type of select field content.
 type WildCardField struct {
 	node
@@ -437,14 +461,93 @@ func (n *OrderByClause) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// CommonTableExpression is a named temporary result set defined in the WITH clause.
+type CommonTableExpression struct {
+	node
+
+	Name     model.CIStr
+	ColNames []model.CIStr
+	Query    *SubqueryExpr
+
+	// The fields below are set by the name resolver.
+	// TableInfo is the table info of the result set, which is referenced by the name of the CTE.
+	TableInfo *model.TableInfo
+	// WorkTableInfo is the table info of the rows generated by the last iteration of a recursive CTE,
+	// the recursive part reads them by the name of the CTE.
+	WorkTableInfo *model.TableInfo
+	// SeedCount is the number of the selects in the non-recursive part, the selects after them are the recursive part.
+	SeedCount int
+}
+
+// Accept implements Node Accept interface.
+func (n *CommonTableExpression) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CommonTableExpression)
+	node, ok := n.Query.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Query = node.(*SubqueryExpr)
+	return v.Leave(n)
+}
+
+// Selects returns the selects of the CTE query.
+func (n *CommonTableExpression) Selects() []*SelectStmt {
+	switch x := n.Query.Query.(type) {
+	case *SelectStmt:
+		return []*SelectStmt{x}
+	case *UnionStmt:
+		return x.SelectList.Selects
+	}
+	return nil
+}
+
+// IsRecursive checks whether the CTE has a recursive part.
+func (n *CommonTableExpression) IsRecursive() bool {
+	return n.SeedCount > 0 && n.SeedCount < len(n.Selects())
+}
+
+// WithClause is the WITH clause of a select or union statement.
+// See https://dev.mysql.com/doc/refman/8.0/en/with.html
+type WithClause struct {
+	node
+
+	IsRecursive bool
+	CTEs        []*CommonTableExpression
+}
+
+// Accept implements Node Accept interface.
+func (n *WithClause) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*WithClause)
+	for i, cte := range n.CTEs {
+		node, ok := cte.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.CTEs[i] = node.(*CommonTableExpression)
+	}
+	return v.Leave(n)
+}
+
 // SelectStmt represents the select query node.
 // See https://dev.mysql.com/doc/refman/5.7/en/select.html
 type SelectStmt struct {
 	dmlNode
 	resultSetNode
 
+	// With is the WITH clause of the statement.
+	With *WithClause
 	// Distinct represents if the select has distinct option.
 	Distinct bool
+	// Priority is the HIGH_PRIORITY or LOW_PRIORITY option of the select.
+	Priority int
 	// From is the from clause of the query.
 	From *TableRefsClause
 	// Where is the where clause in select statement.
@@ -473,6 +576,13 @@ func (n *SelectStmt) Accept(v Visitor) (Node, bool) {
 	}
 
 	n = newNode.(*SelectStmt)
+	if n.With != nil {
+		node, ok := n.With.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.With = node.(*WithClause)
+	}
 	if n.TableHints != nil && len(n.TableHints) != 0 {
 		newHints := make([]*TableOptimizerHint, len(n.TableHints))
 		for i, hint := range n.TableHints {
@@ -574,6 +684,7 @@ type UnionStmt struct {
 	dmlNode
 	resultSetNode
 
+	With       *WithClause
 	Distinct   bool
 	SelectList *UnionSelectList
 	OrderBy    *OrderByClause
@@ -587,6 +698,13 @@ func (n *UnionStmt) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*UnionStmt)
+	if n.With != nil {
+		node, ok := n.With.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.With = node.(*WithClause)
+	}
 	if n.SelectList != nil {
 		node, ok := n.SelectList.Accept(v)
 		if !ok {
@@ -659,6 +777,8 @@ type LoadDataStmt struct {
 	Table      *TableName
 	FieldsInfo *FieldsClause
 	LinesInfo  *LinesClause
+	// IgnoreLines is the number of lines to skip at the start of the file.
+	IgnoreLines uint64
 }
 
 // Accept implements Node Accept interface.
@@ -18,6 +18,11 @@ func HasAggFlag(expr ExprNode) bool {
 	return expr.GetFlag()&FlagHasAggregateFunc > 0
 }
 
+// HasWindowFlag checks if the expr contains FlagHasWindowFunc.
+func HasWindowFlag(expr ExprNode) bool {
+	return expr.GetFlag()&FlagHasWindowFunc > 0
+}
+
 // SetFlag sets flag for expression.
 func SetFlag(n Node) {
 	var setter flagSetter
@@ -35,6 +40,8 @@ func (f *flagSetter) Leave(in Node) (Node, bool) {
 	switch x := in.(type) {
 	case *AggregateFuncExpr:
 		f.aggregateFunc(x)
+	case *WindowFuncExpr:
+		f.windowFunc(x)
 	case *BetweenExpr:
 		x.SetFlag(x.Expr.GetFlag() | x.Left.GetFlag() | x.Right.GetFlag())
 	case *BinaryOperationExpr:
@@ -154,3 +161,19 @@ func (f *flagSetter) aggregateFunc(x *AggregateFuncExpr) {
 	}
 	x.SetFlag(flag)
 }
+
+func (f *flagSetter) windowFunc(x *WindowFuncExpr) {
+	flag := FlagHasWindowFunc
+	for _, val := range x.Args {
+		flag |= val.GetFlag()
+	}
+	for _, val := range x.Spec.PartitionBy {
+		flag |= val.GetFlag()
+	}
+	if x.Spec.OrderBy != nil {
+		for _, item := range x.Spec.OrderBy.Items {
+			flag |= item.Expr.GetFlag()
+		}
+	}
+	x.SetFlag(flag)
+}
@@ -22,6 +22,7 @@ var (
 	_ FuncNode = &AggregateFuncExpr{}
 	_ FuncNode = &FuncCallExpr{}
 	_ FuncNode = &FuncCastExpr{}
+	_ FuncNode = &WindowFuncExpr{}
 )
 
 // List scalar function names.
@@ -390,6 +391,12 @@ const (
 	AggFuncMin = "min"
 	// AggFuncGroupConcat is the name of group_concat function.
 	AggFuncGroupConcat = "group_concat"
+	// AggFuncJSONArrayAgg is the name of json_arrayagg function.
+	AggFuncJSONArrayAgg = "json_arrayagg"
+	// AggFuncJSONObjectAgg is the name of json_objectagg function.
+	AggFuncJSONObjectAgg = "json_objectagg"
+	// AggFuncApproxCountDistinct is the name of approx_count_distinct function.
+	AggFuncApproxCountDistinct = "approx_count_distinct"
 )
 
 // AggregateFuncExpr represents aggregate function expression.
@@ -403,6 +410,10 @@ type AggregateFuncExpr struct {
 	// For example, column c1 values are "1", "2", "2",  "sum(c1)" is "5",
 	// but "sum(distinct c1)" is "3".
 	Distinct bool
+	// Order is the order of the values in the result, it's only used by group_concat.
+	Order *OrderByClause
+	// Separator is the string between the values in the result, it's only used by group_concat.
+	Separator string
 }
 
 // Accept implements Node Accept interface.
@@ -419,5 +430,112 @@ func (n *AggregateFuncExpr) Accept(v Visitor) (Node, bool) {
 		}
 		n.Args[i] = node.(ExprNode)
 	}
+	if n.Order != nil {
+		// The items are visited as expressions, they are not resolved like the order by clause of a select.
+		for _, item := range n.Order.Items {
+			node, ok := item.Expr.Accept(v)
+			if !ok {
+				return n, false
+			}
+			item.Expr = node.(ExprNode)
+		}
+	}
+	return v.Leave(n)
+}
+
+// List window function names which are not aggregate functions, the aggregate functions can be window functions too.
+const (
+	WindowFuncRowNumber = "row_number"
+	WindowFuncRank      = "rank"
+	WindowFuncDenseRank = "dense_rank"
+	WindowFuncLag       = "lag"
+	WindowFuncLead      = "lead"
+)
+
+// FrameType is the unit of the window frame bounds.
+type FrameType int
+
+const (
+	// Rows counts the bounds in rows.
+	Rows FrameType = iota
+	// Ranges makes the CURRENT ROW bound include the peers of the current row, which have the same order by values.
+	Ranges
+)
+
+// BoundType is the type of a window frame bound.
+type BoundType int
+
+// List the window frame bound types, in the order of their positions in the partition.
+const (
+	UnboundedPreceding BoundType = iota
+	Preceding
+	CurrentRow
+	Following
+	UnboundedFollowing
+)
+
+// FrameBound represents a bound of the window frame, Num is the offset of Preceding and Following.
+type FrameBound struct {
+	Type BoundType
+	Num  int64
+}
+
+// FrameClause represents the ROWS or RANGE clause of a window.
+type FrameClause struct {
+	Type  FrameType
+	Start FrameBound
+	End   FrameBound
+}
+
+// WindowSpec represents the OVER clause of a window function.
+type WindowSpec struct {
+	PartitionBy []ExprNode
+	OrderBy     *OrderByClause
+	// Frame is nil if the frame clause is omitted.
+	Frame *FrameClause
+}
+
+// WindowFuncExpr represents a window function expression.
+type WindowFuncExpr struct {
+	funcNode
+	// F is the function name.
+	F string
+	// Args is the function args.
+	Args []ExprNode
+	// Spec is the window the function is evaluated on.
+	Spec WindowSpec
+}
+
+// Accept implements Node Accept interface.
+func (n *WindowFuncExpr) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*WindowFuncExpr)
+	for i, val := range n.Args {
+		node, ok := val.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Args[i] = node.(ExprNode)
+	}
+	for i, val := range n.Spec.PartitionBy {
+		node, ok := val.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Spec.PartitionBy[i] = node.(ExprNode)
+	}
+	if n.Spec.OrderBy != nil {
+		// The items are visited as expressions like the order by items of group_concat.
+		for _, item := range n.Spec.OrderBy.Items {
+			node, ok := item.Expr.Accept(v)
+			if !ok {
+				return n, false
+			}
+			item.Expr = node.(ExprNode)
+		}
+	}
 	return v.Leave(n)
 }
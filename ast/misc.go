@@ -23,24 +23,32 @@ import (
 
 var (
 	_ StmtNode = &AdminStmt{}
+	_ StmtNode = &AlterResourceGroupStmt{}
 	_ StmtNode = &AlterUserStmt{}
 	_ StmtNode = &BeginStmt{}
 	_ StmtNode = &BinlogStmt{}
 	_ StmtNode = &CommitStmt{}
+	_ StmtNode = &CreateResourceGroupStmt{}
 	_ StmtNode = &CreateUserStmt{}
 	_ StmtNode = &DeallocateStmt{}
 	_ StmtNode = &DoStmt{}
+	_ StmtNode = &DropResourceGroupStmt{}
 	_ StmtNode = &ExecuteStmt{}
 	_ StmtNode = &ExplainStmt{}
+	_ StmtNode = &ExplainForStmt{}
 	_ StmtNode = &GrantStmt{}
 	_ StmtNode = &PrepareStmt{}
 	_ StmtNode = &RollbackStmt{}
+	_ StmtNode = &SavepointStmt{}
+	_ StmtNode = &ReleaseSavepointStmt{}
 	_ StmtNode = &SetPwdStmt{}
 	_ StmtNode = &SetStmt{}
 	_ StmtNode = &UseStmt{}
 	_ StmtNode = &AnalyzeTableStmt{}
 	_ StmtNode = &FlushStmt{}
 	_ StmtNode = &KillStmt{}
+	_ StmtNode = &LoadStatsStmt{}
+	_ StmtNode = &SplitRegionStmt{}
 
 	_ Node = &PrivElem{}
 	_ Node = &VariableAssignment{}
@@ -75,6 +83,8 @@ type ExplainStmt struct {
 	stmtNode
 
 	Stmt StmtNode
+	// Analyze is true for EXPLAIN ANALYZE, which executes the statement and shows the runtime statistics of the plans.
+	Analyze bool
 }
 
 // Accept implements Node Accept interface.
@@ -92,6 +102,25 @@ func (n *ExplainStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// ExplainForStmt is a statement to provide information about how is the SQL statement executing
+// in the connection #ConnectionID.
+// See https://dev.mysql.com/doc/refman/5.7/en/explain-for-connection.html
+type ExplainForStmt struct {
+	stmtNode
+
+	ConnectionID uint64
+}
+
+// Accept implements Node Accept interface.
+func (n *ExplainForStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ExplainForStmt)
+	return v.Leave(n)
+}
+
 // PrepareStmt is a statement to prepares a SQL statement which contains placeholders,
 // and it is executed with ExecuteStmt and released with DeallocateStmt.
 // See https://dev.mysql.com/doc/refman/5.7/en/prepare.html
@@ -164,10 +193,19 @@ func (n *ExecuteStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// The transaction modes of BEGIN statement.
+const (
+	Pessimistic = "PESSIMISTIC"
+	Optimistic  = "OPTIMISTIC"
+)
+
 // BeginStmt is a statement to start a new transaction.
 // See https://dev.mysql.com/doc/refman/5.7/en/commit.html
 type BeginStmt struct {
 	stmtNode
+
+	// Mode is the mode of the transaction, it overrides tidb_txn_mode if it isn't empty.
+	Mode string
 }
 
 // Accept implements Node Accept interface.
@@ -214,10 +252,13 @@ func (n *CommitStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
-// RollbackStmt is a statement to roll back the current transaction.
+// RollbackStmt is a statement to roll back the current transaction,
+// or to roll it back to a savepoint if SavepointName is not empty.
 // See https://dev.mysql.com/doc/refman/5.7/en/commit.html
 type RollbackStmt struct {
 	stmtNode
+
+	SavepointName string
 }
 
 // Accept implements Node Accept interface.
@@ -230,6 +271,42 @@ func (n *RollbackStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// SavepointStmt is a statement to set a named savepoint of the current transaction.
+// See https://dev.mysql.com/doc/refman/5.7/en/savepoint.html
+type SavepointStmt struct {
+	stmtNode
+
+	Name string
+}
+
+// Accept implements Node Accept interface.
+func (n *SavepointStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SavepointStmt)
+	return v.Leave(n)
+}
+
+// ReleaseSavepointStmt is a statement to remove a named savepoint of the current transaction.
+// See https://dev.mysql.com/doc/refman/5.7/en/savepoint.html
+type ReleaseSavepointStmt struct {
+	stmtNode
+
+	Name string
+}
+
+// Accept implements Node Accept interface.
+func (n *ReleaseSavepointStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ReleaseSavepointStmt)
+	return v.Leave(n)
+}
+
 // UseStmt is a statement to use the DBName database as the current database.
 // See https://dev.mysql.com/doc/refman/5.7/en/use.html
 type UseStmt struct {
@@ -439,6 +516,8 @@ type AlterUserStmt struct {
 	IfExists    bool
 	CurrentAuth *AuthOption
 	Specs       []*UserSpec
+	// ResourceGroup is the resource group assigned to the users, it's empty if the statement doesn't assign one.
+	ResourceGroup string
 }
 
 // Accept implements Node Accept interface.
@@ -470,6 +549,83 @@ func (n *DropUserStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// DefaultResourceGroup is the name of the resource group of the users who aren't assigned one, it has no limits.
+const DefaultResourceGroup = "default"
+
+// ResourceGroupOptionType is the type of a resource group option.
+type ResourceGroupOptionType int
+
+// ResourceGroupOption types.
+const (
+	// ResourceGroupMaxConcurrency is the max number of the statements of the group executing at the same time.
+	ResourceGroupMaxConcurrency ResourceGroupOptionType = iota + 1
+	// ResourceGroupRUPerSec is the request units the statements of the group can consume per second.
+	ResourceGroupRUPerSec
+	// ResourceGroupQueueSize is the max number of the statements of the group waiting to be executed.
+	ResourceGroupQueueSize
+)
+
+// ResourceGroupOption is a limit of a resource group, 0 means unlimited.
+type ResourceGroupOption struct {
+	Tp        ResourceGroupOptionType
+	UintValue uint64
+}
+
+// CreateResourceGroupStmt creates a resource group, which limits the statements of the users assigned to it.
+type CreateResourceGroupStmt struct {
+	stmtNode
+
+	IfNotExists bool
+	Name        string
+	Options     []*ResourceGroupOption
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateResourceGroupStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateResourceGroupStmt)
+	return v.Leave(n)
+}
+
+// AlterResourceGroupStmt modifies the limits of a resource group.
+type AlterResourceGroupStmt struct {
+	stmtNode
+
+	Name    string
+	Options []*ResourceGroupOption
+}
+
+// Accept implements Node Accept interface.
+func (n *AlterResourceGroupStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*AlterResourceGroupStmt)
+	return v.Leave(n)
+}
+
+// DropResourceGroupStmt drops a resource group, its users are moved to the default resource group.
+type DropResourceGroupStmt struct {
+	stmtNode
+
+	IfExists bool
+	Name     string
+}
+
+// Accept implements Node Accept interface.
+func (n *DropResourceGroupStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DropResourceGroupStmt)
+	return v.Leave(n)
+}
+
 // DoStmt is the struct for DO statement.
 type DoStmt struct {
 	stmtNode
@@ -501,6 +657,10 @@ type AdminStmtType int
 const (
 	AdminShowDDL = iota + 1
 	AdminCheckTable
+	AdminChecksumTable
+	AdminShowIndexAdvice
+	AdminShowDDLJobs
+	AdminCancelDDLJobs
 )
 
 // AdminStmt is the struct for Admin statement.
@@ -509,6 +669,7 @@ type AdminStmt struct {
 
 	Tp     AdminStmtType
 	Tables []*TableName
+	JobIDs []int64
 }
 
 // Accept implements Node Accpet interface.
@@ -690,9 +851,81 @@ func (n *AnalyzeTableStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// SplitRegionStmt is the statement to split the regions of a table or an index, built from the
+// 'split table' statement.
+type SplitRegionStmt struct {
+	stmtNode
+
+	Table *TableName
+	// IndexName is empty when the record data of the table is split.
+	IndexName model.CIStr
+
+	SplitOpt *SplitOption
+}
+
+// SplitOption is the option of the 'split table' statement. The regions are split evenly into Num regions between
+// Lower and Upper, or split at every value list of ValueLists.
+type SplitOption struct {
+	Lower      []ExprNode
+	Upper      []ExprNode
+	Num        int64
+	ValueLists [][]ExprNode
+}
+
+// Accept implements Node Accept interface.
+func (n *SplitRegionStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SplitRegionStmt)
+	node, ok := n.Table.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Table = node.(*TableName)
+	acceptExprs := func(exprs []ExprNode) bool {
+		for i, val := range exprs {
+			node, ok := val.Accept(v)
+			if !ok {
+				return false
+			}
+			exprs[i] = node.(ExprNode)
+		}
+		return true
+	}
+	if !acceptExprs(n.SplitOpt.Lower) || !acceptExprs(n.SplitOpt.Upper) {
+		return n, false
+	}
+	for _, values := range n.SplitOpt.ValueLists {
+		if !acceptExprs(values) {
+			return n, false
+		}
+	}
+	return v.Leave(n)
+}
+
+// LoadStatsStmt is the statement node for loading statistic.
+type LoadStatsStmt struct {
+	stmtNode
+
+	Path string
+}
+
+// Accept implements Node Accept interface.
+func (n *LoadStatsStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*LoadStatsStmt)
+	return v.Leave(n)
+}
+
 // SelectStmtOpts wrap around select hints and switches
 type SelectStmtOpts struct {
 	Distinct      bool
+	Priority      int
 	SQLCache      bool
 	CalcFoundRows bool
 	TableHints    []*TableOptimizerHint
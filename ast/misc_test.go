@@ -57,6 +57,8 @@ func (ts *testMiscSuite) TestMiscVisitorCover(c *C) {
 		(&GrantStmt{}),
 		(&PrepareStmt{SQLVar: &VariableExpr{Value: &ValueExpr{}}}),
 		(&RollbackStmt{}),
+		(&SavepointStmt{}),
+		(&ReleaseSavepointStmt{}),
 		(&SetPwdStmt{}),
 		(&SetStmt{Variables: []*VariableAssignment{
 			{
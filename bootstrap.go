@@ -50,6 +50,9 @@ const (
 		Execute_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Index_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Create_user_priv	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Process_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Resource_group		CHAR(64) NOT NULL DEFAULT '',
+		Super_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		PRIMARY KEY (Host, User));`
 	// CreateDBPrivTable is the SQL statement creates DB scope privilege table in system db.
 	CreateDBPrivTable = `CREATE TABLE if not exists mysql.db (
@@ -150,6 +153,25 @@ const (
 		value blob NOT NULL,
 		unique index tbl(table_id, is_index, hist_id, bucket_id)
 	);`
+
+	// CreateTTLTableStatusTable stores the status of the TTL jobs of the tables.
+	CreateTTLTableStatusTable = `CREATE TABLE if not exists mysql.tidb_ttl_table_status (
+		table_id bigint(64) NOT NULL,
+		last_job_start_time datetime DEFAULT NULL,
+		last_job_finish_time datetime DEFAULT NULL,
+		last_job_deleted_rows bigint(64) NOT NULL DEFAULT 0,
+		total_deleted_rows bigint(64) NOT NULL DEFAULT 0,
+		unique index tbl(table_id)
+	);`
+
+	// CreateResourceGroupTable stores the limits of the resource groups, 0 means unlimited.
+	CreateResourceGroupTable = `CREATE TABLE if not exists mysql.resource_group (
+		Name		CHAR(64) NOT NULL,
+		Max_concurrency	BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		RU_per_sec	BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		Queue_size	BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		PRIMARY KEY (Name)
+	);`
 )
 
 // Bootstrap initiates system DB for a store.
@@ -180,6 +202,11 @@ const (
 	version3 = 3
 	version4 = 4
 	version5 = 5
+	version6 = 6
+	// version7 was used by a dropped upgrade and is skipped, it must not be reused.
+	version8  = 8
+	version9  = 9
+	version10 = 10
 )
 
 func checkBootstrapped(s Session) (bool, error) {
@@ -257,6 +284,22 @@ func upgrade(s Session) {
 		upgradeToVer5(s)
 	}
 
+	if ver < version6 {
+		upgradeToVer6(s)
+	}
+
+	if ver < version8 {
+		upgradeToVer8(s)
+	}
+
+	if ver < version9 {
+		upgradeToVer9(s)
+	}
+
+	if ver < version10 {
+		upgradeToVer10(s)
+	}
+
 	updateBootstrapVer(s)
 	_, err = s.Execute("COMMIT")
 
@@ -315,6 +358,41 @@ func upgradeToVer5(s Session) {
 	mustExecute(s, CreateStatsBucketsTable)
 }
 
+func upgradeToVer6(s Session) {
+	mustExecute(s, CreateTTLTableStatusTable)
+}
+
+// Update to version 8.
+func upgradeToVer8(s Session) {
+	// Version 8 adds the PROCESS privilege, it's granted to the users who can create users.
+	mustAddColumn(s, "ALTER TABLE mysql.user ADD COLUMN `Process_priv` ENUM('N','Y') NOT NULL DEFAULT 'N'")
+	mustExecute(s, "UPDATE mysql.user SET Process_priv='Y' WHERE Create_user_priv='Y'")
+}
+
+// Update to version 9.
+func upgradeToVer9(s Session) {
+	// Version 9 adds the resource groups.
+	mustExecute(s, CreateResourceGroupTable)
+	mustAddColumn(s, "ALTER TABLE mysql.user ADD COLUMN `Resource_group` CHAR(64) NOT NULL DEFAULT ''")
+}
+
+// Update to version 10.
+func upgradeToVer10(s Session) {
+	// Version 10 adds the SUPER privilege, it's granted to the users who can create users.
+	mustAddColumn(s, "ALTER TABLE mysql.user ADD COLUMN `Super_priv` ENUM('N','Y') NOT NULL DEFAULT 'N'")
+	mustExecute(s, "UPDATE mysql.user SET Super_priv='Y' WHERE Create_user_priv='Y'")
+}
+
+// mustAddColumn executes the ALTER TABLE ADD COLUMN statement, the column may be added by another TiDB server
+// upgrading at the same time.
+func mustAddColumn(s Session, sql string) {
+	_, err := s.Execute(sql)
+	if err != nil && !infoschema.ErrColumnExists.Equal(err) {
+		debug.PrintStack()
+		log.Fatal(err)
+	}
+}
+
 // Update boostrap version variable in mysql.TiDB table.
 func updateBootstrapVer(s Session) {
 	// Update bootstrap version.
@@ -359,6 +437,10 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateStatsColsTable)
 	// Create stats_buckets table.
 	mustExecute(s, CreateStatsBucketsTable)
+	// Create tidb_ttl_table_status table.
+	mustExecute(s, CreateTTLTableStatusTable)
+	// Create resource_group table.
+	mustExecute(s, CreateResourceGroupTable)
 }
 
 // Execute DML statements in bootstrap stage.
@@ -368,7 +450,7 @@ func doDMLWorks(s Session) {
 
 	// Insert a default user with empty password.
 	mustExecute(s, `INSERT INTO mysql.user VALUES
-		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
+		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "", "Y")`)
 
 	// Init global system variables table.
 	values := make([]string, 0, len(variable.SysVars))
@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util"
+	goctx "golang.org/x/net/context"
 )
 
 // Context is an interface for transaction and executive args environment.
@@ -78,7 +79,12 @@ func (ctx CtxForCancel) Deadline() (deadline time.Time, ok bool) {
 
 // Err implements the standard Go context.Context interface.
 func (ctx CtxForCancel) Err() error {
-	return nil
+	select {
+	case <-ctx.Done():
+		return goctx.Canceled
+	default:
+		return nil
+	}
 }
 
 type basicCtxType int
@@ -75,6 +75,11 @@ func (d *ddl) handleBgJobQueue() error {
 // runBgJob runs a background job.
 func (d *ddl) runBgJob(t *meta.Meta, job *model.Job) {
 	log.Infof("[ddl] run background job %s", job)
+	if job.IsCancelling() {
+		// The dropped table is recovered, its data is kept.
+		job.State = model.JobCancelled
+		return
+	}
 	job.State = model.JobRunning
 
 	var err error
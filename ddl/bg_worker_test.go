@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/testleak"
 )
 
@@ -123,3 +124,32 @@ func (s *testDDLSuite) TestInvalidBgJobType(c *C) {
 
 	verifyBgJobState(c, d, job, model.JobCancelled, testLease*2)
 }
+
+func (s *testDDLSuite) TestCancelDropTableBgJob(c *C) {
+	defer testleak.AfterTest(c)()
+	store := testCreateStore(c, "test_cancel_drop_table_bg_job")
+	defer store.Close()
+
+	d := newDDL(store, nil, nil, testLease)
+	defer d.Stop()
+
+	// The background job of the recovered table is cancelled, the others aren't.
+	jobs := []*model.Job{
+		{ID: 1, SchemaID: 1, TableID: 1, Type: model.ActionDropTable, Args: []interface{}{tablecodec.EncodeTablePrefix(1)}},
+		{ID: 2, SchemaID: 1, TableID: 2, Type: model.ActionTruncateTable, Args: []interface{}{tablecodec.EncodeTablePrefix(2)}},
+	}
+	err := kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		for _, job := range jobs {
+			if err := d.prepareBgJob(t, job); err != nil {
+				return err
+			}
+		}
+		return cancelDropTableBgJobs(t, 1)
+	})
+	c.Check(err, IsNil)
+	d.startBgJob(model.ActionDropTable)
+
+	verifyBgJobState(c, d, jobs[0], model.JobCancelled, testLease*2)
+	verifyBgJobState(c, d, jobs[1], model.JobDone, 0)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
@@ -64,6 +65,13 @@ func (d *ddl) createColumnInfo(tblInfo *model.TableInfo, colInfo *model.ColumnIn
 	// Check column name duplicate.
 	cols := tblInfo.Columns
 	position := len(cols)
+	// The hidden columns of the expression indices are always behind the other columns.
+	for _, col := range cols {
+		if col.IsGenerated() {
+			position = col.Offset
+			break
+		}
+	}
 
 	// Get column position.
 	if pos.Tp == ast.ColumnPositionFirst {
@@ -94,6 +102,11 @@ func (d *ddl) createColumnInfo(tblInfo *model.TableInfo, colInfo *model.ColumnIn
 }
 
 func (d *ddl) onAddColumn(t *meta.Meta, job *model.Job) error {
+	// Handle rollback job, the added column is dropped.
+	if job.State == model.JobRollback {
+		return errors.Trace(d.onDropColumn(t, job))
+	}
+
 	schemaID := job.SchemaID
 	tblInfo, err := getTableInfo(t, job, schemaID)
 	if err != nil {
@@ -207,6 +220,15 @@ func (d *ddl) onDropColumn(t *meta.Meta, job *model.Job) error {
 		job.State = model.JobCancelled
 		return errCantDropColWithIndex.Gen("can't drop column %s with index covered now", colName)
 	}
+	inExprIndex, err := isColumnInExpressionIndex(tblInfo, colName.L)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	if inExprIndex {
+		job.State = model.JobCancelled
+		return errCannotDropColumnFunctionalIndex.GenByArgs(colName)
+	}
 
 	originalState := colInfo.State
 	switch colInfo.State {
@@ -253,7 +275,11 @@ func (d *ddl) onDropColumn(t *meta.Meta, job *model.Job) error {
 		}
 
 		// Finish this job.
-		job.State = model.JobDone
+		if job.State == model.JobRollback {
+			job.State = model.JobRollbackDone
+		} else {
+			job.State = model.JobDone
+		}
 		job.BinlogInfo.AddTableInfo(ver, tblInfo)
 	default:
 		err = ErrInvalidTableState.Gen("invalid table state %v", tblInfo.State)
@@ -261,6 +287,42 @@ func (d *ddl) onDropColumn(t *meta.Meta, job *model.Job) error {
 	return errors.Trace(err)
 }
 
+// rollbackAddColumn handles the add column job which is requested to be cancelled. The column which isn't public is
+// dropped like dropping a column, which begins with the delete only state.
+func (d *ddl) rollbackAddColumn(t *meta.Meta, job *model.Job) error {
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	col := &model.ColumnInfo{}
+	err = job.DecodeArgs(col)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	columnInfo := findCol(tblInfo.Columns, col.Name.L)
+	if columnInfo == nil {
+		job.State = model.JobCancelled
+		return errCancelledDDLJob
+	}
+	if columnInfo.State == model.StatePublic {
+		job.State = model.JobRunning
+		return nil
+	}
+
+	job.State = model.JobRollback
+	job.Args = []interface{}{columnInfo.Name}
+	originalState := columnInfo.State
+	job.SchemaState = model.StateDeleteOnly
+	columnInfo.State = model.StateDeleteOnly
+	_, err = updateTableInfo(t, job, tblInfo, originalState)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errCancelledDDLJob
+}
+
 // TODO: Use it when updating the column type or remove it.
 // How to backfill column data in reorganization state?
 //  1. Generate a snapshot with special version.
@@ -422,13 +484,520 @@ func (d *ddl) onSetDefaultValue(t *meta.Meta, job *model.Job) error {
 func (d *ddl) onModifyColumn(t *meta.Meta, job *model.Job) error {
 	newCol := &model.ColumnInfo{}
 	oldColName := &model.CIStr{}
-	err := job.DecodeArgs(newCol, oldColName)
+	var needReorg, strict bool
+	err := job.DecodeArgs(newCol, oldColName, &needReorg, &strict)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+
+	if !needReorg {
+		return errors.Trace(d.updateColumn(t, job, newCol, oldColName))
+	}
+	return errors.Trace(d.changeColumnType(t, job, newCol, oldColName, strict))
+}
+
+// How to change the column type with data reorganization?
+//  1. Add a non-public column of the new type, which is named by changingColumnName, and the non-public indices on it
+// for the indices that cover the old column. Their values are converted from the old column by the writes.
+//  2. In the reorganization state, convert the values of the old column in the existing rows and backfill the indices.
+//  3. Replace the old column and its indices with the changing ones. The old column becomes a non-public column whose
+// values are converted back from the new column, then it's dropped with its indices like dropping a column.
+// If a value can't be converted or breaks a unique index in the reorganization, the changing column and its indices
+// are dropped in the same way and the job is rolled back.
+func (d *ddl) changeColumnType(t *meta.Meta, job *model.Job, newCol *model.ColumnInfo, oldColName *model.CIStr,
+	strict bool) error {
+	schemaID := job.SchemaID
+	tblInfo, err := getTableInfo(t, job, schemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	changingName := changingColumnName(*oldColName)
+	changingCol := findCol(tblInfo.Columns, changingName.L)
+	if changingCol == nil {
+		oldCol := findCol(tblInfo.Columns, oldColName.L)
+		if oldCol == nil || oldCol.State != model.StatePublic {
+			job.State = model.JobCancelled
+			return infoschema.ErrColumnNotExists.GenByArgs(oldColName, tblInfo.Name)
+		}
+		changingCol = createChangingColumn(tblInfo, oldCol, newCol)
+	}
+
+	originalState := changingCol.State
+	switch changingCol.State {
+	case model.StateNone:
+		// none -> delete only
+		job.SchemaState = model.StateDeleteOnly
+		setChangingColumnState(tblInfo, changingCol, model.StateDeleteOnly)
+		_, err = updateTableInfo(t, job, tblInfo, originalState)
+	case model.StateDeleteOnly:
+		if changingCol.ChangeStateInfo.Origin || job.State == model.JobRollback {
+			// delete only -> reorganization
+			job.SchemaState = model.StateDeleteReorganization
+			setChangingColumnState(tblInfo, changingCol, model.StateDeleteReorganization)
+		} else {
+			// delete only -> write only
+			job.SchemaState = model.StateWriteOnly
+			setChangingColumnState(tblInfo, changingCol, model.StateWriteOnly)
+		}
+		_, err = updateTableInfo(t, job, tblInfo, originalState)
+	case model.StateWriteOnly:
+		if changingCol.ChangeStateInfo.Origin {
+			// write only -> delete only
+			job.SchemaState = model.StateDeleteOnly
+			setChangingColumnState(tblInfo, changingCol, model.StateDeleteOnly)
+		} else {
+			// write only -> reorganization
+			job.SchemaState = model.StateWriteReorganization
+			setChangingColumnState(tblInfo, changingCol, model.StateWriteReorganization)
+			// Initialize SnapshotVer to 0 for later reorganization check.
+			job.SnapshotVer = 0
+		}
+		_, err = updateTableInfo(t, job, tblInfo, originalState)
+	case model.StateWriteReorganization:
+		// reorganization -> public
+		reorgInfo, err := d.getReorgInfo(t, job)
+		if err != nil || reorgInfo.first {
+			// If we run reorg firstly, we should update the job snapshot version
+			// and then run the reorg next time.
+			return errors.Trace(err)
+		}
+
+		var tbl table.Table
+		tbl, err = d.getTable(schemaID, tblInfo)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		oldCol := tblInfo.Columns[changingCol.ChangeStateInfo.DependencyColumnOffset]
+		err = d.runReorgJob(job, func() error {
+			return d.convertTableColumn(tbl, oldCol, changingCol, strict, reorgInfo, job)
+		})
+		if err != nil {
+			if terror.ErrorEqual(err, errWaitReorgTimeout) {
+				// if timeout, we should return, check for the owner and re-wait job done.
+				return nil
+			}
+			if isInvalidValueErr(err) || terror.ErrorEqual(err, errCancelledDDLJob) {
+				log.Warnf("[ddl] run DDL job %v err %v, convert job to rollback job", job, err)
+				err = d.convertChangeColumnType2RollbackJob(t, job, tblInfo, changingCol, err)
+			}
+			return errors.Trace(err)
+		}
+
+		// The old column becomes the changing column which is dropped later.
+		replaceChangingColumn(tblInfo, oldCol, changingCol, newCol.Name)
+		job.SchemaState = model.StatePublic
+		_, err = updateTableInfo(t, job, tblInfo, originalState)
+		return errors.Trace(err)
+	case model.StateDeleteReorganization:
+		// reorganization -> absent
+		indices := findChangingIndices(tblInfo, changingCol)
+		err = d.runReorgJob(job, func() error {
+			for _, indexInfo := range indices {
+				if err1 := d.dropTableIndex(indexInfo, job); err1 != nil {
+					return errors.Trace(err1)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// If the timeout happens, we should return.
+			// Then check for the owner and re-wait job to finish.
+			return errors.Trace(filterError(err, errWaitReorgTimeout))
+		}
+
+		// All reorganization jobs are done, drop the changing column and its indices.
+		removeChangingColumn(tblInfo, changingCol)
+		job.SchemaState = model.StateNone
+		ver, err := updateTableInfo(t, job, tblInfo, originalState)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		// Finish this job.
+		if job.State == model.JobRollback {
+			job.State = model.JobRollbackDone
+		} else {
+			job.State = model.JobDone
+		}
+		job.BinlogInfo.AddTableInfo(ver, tblInfo)
+	default:
+		err = ErrInvalidColumnState.Gen("invalid column state %v", changingCol.State)
+	}
+
+	return errors.Trace(err)
+}
+
+// rollbackModifyColumn handles the modify column job which is requested to be cancelled. The changing column is
+// dropped before it replaces the old column, but the changing column in the reorganization state stops the
+// reorganization and then is dropped.
+func (d *ddl) rollbackModifyColumn(t *meta.Meta, job *model.Job) error {
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	newCol := &model.ColumnInfo{}
+	oldColName := &model.CIStr{}
+	err = job.DecodeArgs(newCol, oldColName)
 	if err != nil {
 		job.State = model.JobCancelled
 		return errors.Trace(err)
 	}
+	changingCol := findCol(tblInfo.Columns, changingColumnName(*oldColName).L)
+	if changingCol == nil {
+		job.State = model.JobCancelled
+		return errCancelledDDLJob
+	}
+	if changingCol.ChangeStateInfo.Origin {
+		// The changing column has replaced the old column.
+		job.State = model.JobRunning
+		return nil
+	}
+
+	switch changingCol.State {
+	case model.StateDeleteOnly, model.StateWriteOnly:
+		return d.convertChangeColumnType2RollbackJob(t, job, tblInfo, changingCol, errCancelledDDLJob)
+	case model.StateWriteReorganization:
+		// The reorganization returns errCancelledDDLJob, then the job is converted to a rollback job.
+		d.notifyReorgCancel()
+	default:
+		job.State = model.JobRunning
+	}
+	return nil
+}
+
+// changingColumnName returns the name of the non-public column used for changing the type of the column.
+func changingColumnName(colName model.CIStr) model.CIStr {
+	return model.NewCIStr(changingColumnPrefix + colName.O)
+}
+
+// changingIndexName returns the name of the non-public index used for changing the type of a column in the index.
+func changingIndexName(idxName model.CIStr) model.CIStr {
+	return model.NewCIStr(changingIndexPrefix + idxName.O)
+}
+
+const (
+	changingColumnPrefix = "_Col$_"
+	changingIndexPrefix  = "_Idx$_"
+)
+
+// createChangingColumn adds the changing column to the end of the columns, and adds a changing index for every index
+// that covers the old column.
+func createChangingColumn(tblInfo *model.TableInfo, oldCol *model.ColumnInfo, newCol *model.ColumnInfo) *model.ColumnInfo {
+	changingCol := newCol.Clone()
+	changingCol.ID = allocateColumnID(tblInfo)
+	changingCol.Name = changingColumnName(oldCol.Name)
+	changingCol.Offset = len(tblInfo.Columns)
+	changingCol.State = model.StateNone
+	changingCol.ChangeStateInfo = &model.ChangeStateInfo{DependencyColumnOffset: oldCol.Offset}
+	tblInfo.Columns = append(tblInfo.Columns, changingCol)
+
+	for _, idx := range tblInfo.Indices {
+		if !isColumnInIndex(oldCol.Name.L, idx) {
+			continue
+		}
+		changingIdx := idx.Clone()
+		changingIdx.ID = allocateIndexID(tblInfo)
+		changingIdx.Name = changingIndexName(idx.Name)
+		changingIdx.Primary = false
+		changingIdx.State = model.StateNone
+		for _, ic := range changingIdx.Columns {
+			if ic.Name.L == oldCol.Name.L {
+				ic.Name = changingCol.Name
+				ic.Offset = changingCol.Offset
+			}
+		}
+		tblInfo.Indices = append(tblInfo.Indices, changingIdx)
+	}
+	return changingCol
+}
+
+// setChangingColumnState sets the state of the changing column and the indices on it.
+func setChangingColumnState(tblInfo *model.TableInfo, changingCol *model.ColumnInfo, state model.SchemaState) {
+	changingCol.State = state
+	for _, idx := range findChangingIndices(tblInfo, changingCol) {
+		idx.State = state
+	}
+}
+
+func findChangingIndices(tblInfo *model.TableInfo, changingCol *model.ColumnInfo) []*model.IndexInfo {
+	var indices []*model.IndexInfo
+	for _, idx := range tblInfo.Indices {
+		if isColumnInIndex(changingCol.Name.L, idx) {
+			indices = append(indices, idx)
+		}
+	}
+	return indices
+}
+
+// replaceChangingColumn makes the changing column public in place of the old column with the new name, and the old
+// column becomes the changing column in the write only state, whose values are converted from the new column. So are
+// their indices.
+func replaceChangingColumn(tblInfo *model.TableInfo, oldCol *model.ColumnInfo, changingCol *model.ColumnInfo,
+	newName model.CIStr) {
+	newIndices := findChangingIndices(tblInfo, changingCol)
+	oldIndices := make([]*model.IndexInfo, 0, len(newIndices))
+	for _, idx := range tblInfo.Indices {
+		if isColumnInIndex(oldCol.Name.L, idx) {
+			oldIndices = append(oldIndices, idx)
+		}
+	}
+
+	colName, offset, changingOffset := oldCol.Name, oldCol.Offset, changingCol.Offset
+	changingCol.Name = newName
+	changingCol.Offset = offset
+	changingCol.State = model.StatePublic
+	changingCol.ChangeStateInfo = nil
+	changingCol.Flag |= oldCol.Flag & (mysql.PriKeyFlag | mysql.UniqueKeyFlag | mysql.MultipleKeyFlag)
+	oldCol.Name = changingColumnName(colName)
+	oldCol.Offset = changingOffset
+	oldCol.State = model.StateWriteOnly
+	oldCol.ChangeStateInfo = &model.ChangeStateInfo{DependencyColumnOffset: offset, Origin: true}
+	tblInfo.Columns[offset] = changingCol
+	tblInfo.Columns[changingOffset] = oldCol
+
+	for i, newIdx := range newIndices {
+		oldIdx := oldIndices[i]
+		idxName := oldIdx.Name
+		newIdx.Name, newIdx.Primary, newIdx.State = idxName, oldIdx.Primary, model.StatePublic
+		oldIdx.Name, oldIdx.Primary, oldIdx.State = changingIndexName(idxName), false, model.StateWriteOnly
+		for _, ic := range newIdx.Columns {
+			if ic.Offset == changingOffset {
+				ic.Name, ic.Offset = newName, offset
+			}
+		}
+		for _, ic := range oldIdx.Columns {
+			if ic.Offset == offset {
+				ic.Name, ic.Offset = oldCol.Name, changingOffset
+			}
+		}
+	}
+}
+
+// removeChangingColumn removes the changing column, which is the last column, and the indices on it.
+func removeChangingColumn(tblInfo *model.TableInfo, changingCol *model.ColumnInfo) {
+	tblInfo.Columns = tblInfo.Columns[:changingCol.Offset]
+	newIndices := make([]*model.IndexInfo, 0, len(tblInfo.Indices))
+	for _, idx := range tblInfo.Indices {
+		if !isColumnInIndex(changingCol.Name.L, idx) {
+			newIndices = append(newIndices, idx)
+		}
+	}
+	tblInfo.Indices = newIndices
+}
+
+func (d *ddl) convertChangeColumnType2RollbackJob(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo,
+	changingCol *model.ColumnInfo, err error) error {
+	job.State = model.JobRollback
+	// The changing column and its indices are dropped like dropping a column. The write reorganization state is like
+	// the write only state of dropping a column, so the next state is delete only state.
+	originalState := changingCol.State
+	job.SchemaState = model.StateDeleteOnly
+	setChangingColumnState(tblInfo, changingCol, model.StateDeleteOnly)
+	_, err1 := updateTableInfo(t, job, tblInfo, originalState)
+	if err1 != nil {
+		return errors.Trace(err1)
+	}
+	return errors.Trace(err)
+}
+
+// isInvalidValueErr checks whether the reorganization fails because a value can't be converted to the new type or
+// breaks a unique index. Retrying can't fix it, so the job is rolled back.
+func isInvalidValueErr(err error) bool {
+	if terror.ErrorEqual(err, kv.ErrKeyExists) || terror.ErrorEqual(err, table.ErrTruncateWrongValue) {
+		return true
+	}
+	tErr, ok := errors.Cause(err).(*terror.Error)
+	return ok && tErr.Class() == terror.ClassTypes
+}
+
+// changingColumnMeta records the information that is needed to convert the values of a column.
+type changingColumnMeta struct {
+	oldCol    *model.ColumnInfo
+	newCol    *model.ColumnInfo
+	indices   []table.Index
+	colMap    map[int64]*types.FieldType
+	sc        *variable.StatementContext
+	originVal types.Datum
+}
+
+// How to convert the column data in reorganization state?
+//  1. Traverse the snapshot with special version to get the handles of the rows, up to the last handle in it.
+//  2. For one row, if the row has been already deleted, skip to next row.
+//  3. If the value of the new column doesn't exist, convert the value of the old column and write it into the row.
+//  4. Backfill the indices on the new column with the value, skip the index entries which have existed.
+// The rows written after the snapshot have been converted by the writes in the write only state.
+func (d *ddl) convertTableColumn(t table.Table, oldCol, newCol *model.ColumnInfo, strict bool, reorgInfo *reorgInfo,
+	job *model.Job) error {
+	var err error
+	reorgInfo.EndHandle, err = d.getReorgEndHandle(t, reorgInfo.SnapshotVer)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[ddl] modify column %s from handle %d to %d", oldCol.Name, reorgInfo.Handle, reorgInfo.EndHandle)
+
+	colMeta := &changingColumnMeta{
+		oldCol: oldCol,
+		newCol: newCol,
+		colMap: make(map[int64]*types.FieldType),
+		// The truncated values are accepted without warnings if the SQL mode of the statement isn't strict.
+		sc: &variable.StatementContext{IgnoreTruncate: !strict},
+	}
+	if oldCol.OriginDefaultValue != nil {
+		colMeta.originVal, err = table.GetColOriginDefaultValue(d.newContext(), oldCol)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, col := range t.Meta().Columns {
+		colMeta.colMap[col.ID] = &col.FieldType
+	}
+	for _, idx := range t.Indices() {
+		if isColumnInIndex(newCol.Name.L, idx.Meta()) {
+			colMeta.indices = append(colMeta.indices, idx)
+		}
+	}
+
+	count := job.GetRowCount()
+	seekHandle := reorgInfo.Handle
+	handles := make([]int64, 0, defaultBatchCnt)
+	for {
+		startTime := time.Now()
+		handles = handles[:0]
+		err = d.iterateSnapshotRows(t, reorgInfo.SnapshotVer, seekHandle,
+			func(h int64, rowKey kv.Key, rawRecord []byte) (bool, error) {
+				if h > reorgInfo.EndHandle {
+					return false, nil
+				}
+				handles = append(handles, h)
+				return len(handles) < defaultBatchCnt, nil
+			})
+		if err != nil {
+			return errors.Trace(err)
+		} else if len(handles) == 0 {
+			return nil
+		}
+
+		count += int64(len(handles))
+		err = d.backfillChangingColumn(t, colMeta, handles, reorgInfo)
+		sub := time.Since(startTime).Seconds()
+		if err != nil {
+			log.Warnf("[ddl] modified column for %v rows failed, take time %v", count, sub)
+			return errors.Trace(err)
+		}
+
+		d.setReorgRowCount(count)
+		batchHandleDataHistogram.WithLabelValues(batchModifyCol).Observe(sub)
+		log.Infof("[ddl] modified column for %v rows, take time %v", count, sub)
+
+		lastHandle := handles[len(handles)-1]
+		if lastHandle >= reorgInfo.EndHandle {
+			return nil
+		}
+		seekHandle = lastHandle + 1
+	}
+}
+
+func (d *ddl) backfillChangingColumn(t table.Table, colMeta *changingColumnMeta, handles []int64, reorgInfo *reorgInfo) error {
+	var endIdx int
+	for len(handles) > 0 {
+		if len(handles) >= defaultSmallBatchCnt {
+			endIdx = defaultSmallBatchCnt
+		} else {
+			endIdx = len(handles)
+		}
+
+		err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+			if err := d.isReorgRunnable(txn, ddlJobFlag); err != nil {
+				return errors.Trace(err)
+			}
+
+			if err := d.backfillChangingColumnInTxn(t, colMeta, handles[:endIdx], txn); err != nil {
+				return errors.Trace(err)
+			}
+			return errors.Trace(reorgInfo.UpdateHandle(txn, handles[endIdx-1]))
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		handles = handles[endIdx:]
+	}
 
-	return errors.Trace(d.updateColumn(t, job, newCol, oldColName))
+	return nil
+}
+
+// backfillChangingColumnInTxn deals with a part of converting column data in a Transaction.
+// This part of the column data rows is defaultSmallBatchCnt.
+func (d *ddl) backfillChangingColumnInTxn(t table.Table, colMeta *changingColumnMeta, handles []int64,
+	txn kv.Transaction) error {
+	cols := t.Meta().Columns
+	for _, handle := range handles {
+		log.Debug("[ddl] convert column...", handle)
+		rowKey := t.RecordKey(handle)
+		rowVal, err := txn.Get(rowKey)
+		if err != nil {
+			if terror.ErrorEqual(err, kv.ErrNotExist) {
+				// If row doesn't exist, skip it.
+				continue
+			}
+			return errors.Trace(err)
+		}
+
+		rowColumns, err := tablecodec.DecodeRow(rowVal, colMeta.colMap)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if rowColumns == nil {
+			// All the non-handle columns of the row are NULL.
+			rowColumns = make(map[int64]types.Datum, 1)
+		}
+		if _, ok := rowColumns[colMeta.newCol.ID]; !ok {
+			// The value isn't converted by update or insert statement, convert it.
+			oldVal, ok := rowColumns[colMeta.oldCol.ID]
+			if !ok {
+				oldVal = colMeta.originVal
+			}
+			newVal, err := oldVal.ConvertTo(colMeta.sc, &colMeta.newCol.FieldType)
+			if err = colMeta.sc.HandleTruncate(err); err != nil {
+				return errors.Trace(err)
+			}
+			rowColumns[colMeta.newCol.ID] = newVal
+
+			colIDs := make([]int64, 0, len(rowColumns))
+			row := make([]types.Datum, 0, len(rowColumns))
+			for colID, val := range rowColumns {
+				colIDs = append(colIDs, colID)
+				row = append(row, val)
+			}
+			newRowVal, err := tablecodec.EncodeRow(row, colIDs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if err = txn.Set(rowKey, newRowVal); err != nil {
+				return errors.Trace(err)
+			}
+		}
+
+		for _, idx := range colMeta.indices {
+			idxVals := make([]types.Datum, 0, len(idx.Meta().Columns))
+			for _, ic := range idx.Meta().Columns {
+				idxVals = append(idxVals, rowColumns[cols[ic.Offset].ID])
+			}
+			h, err := idx.Create(txn, idxVals, handle)
+			if err != nil {
+				if terror.ErrorEqual(err, kv.ErrKeyExists) && h == handle {
+					// Index already exists, skip it.
+					continue
+				}
+				return errors.Trace(err)
+			}
+		}
+	}
+
+	return nil
 }
 
 func (d *ddl) updateColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnInfo, oldColName *model.CIStr) error {
@@ -441,6 +1010,8 @@ func (d *ddl) updateColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnInf
 		job.State = model.JobCancelled
 		return infoschema.ErrColumnNotExists.GenByArgs(newCol.Name, tblInfo.Name)
 	}
+	// The offset may be changed after the job is built, e.g. by the earlier sub-jobs of a multi-schema change.
+	newCol.Offset = oldCol.Offset
 	*oldCol = *newCol
 
 	originalState := job.SchemaState
@@ -458,10 +1029,17 @@ func (d *ddl) updateColumn(t *meta.Meta, job *model.Job, newCol *model.ColumnInf
 
 func isColumnWithIndex(colName string, indices []*model.IndexInfo) bool {
 	for _, indexInfo := range indices {
-		for _, col := range indexInfo.Columns {
-			if col.Name.L == colName {
-				return true
-			}
+		if isColumnInIndex(colName, indexInfo) {
+			return true
+		}
+	}
+	return false
+}
+
+func isColumnInIndex(colName string, indexInfo *model.IndexInfo) bool {
+	for _, col := range indexInfo.Columns {
+		if col.Name.L == colName {
+			return true
 		}
 	}
 	return false
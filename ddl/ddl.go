@@ -47,6 +47,7 @@ var (
 	errRunMultiSchemaChanges = terror.ClassDDL.New(codeRunMultiSchemaChanges, "can't run multi schema change")
 	errWaitReorgTimeout      = terror.ClassDDL.New(codeWaitReorgTimeout, "wait for reorganization timeout")
 	errInvalidStoreVer       = terror.ClassDDL.New(codeInvalidStoreVer, "invalid storage current version")
+	errCancelledDDLJob       = terror.ClassDDL.New(codeCancelledDDLJob, "cancelled DDL job")
 
 	// We don't support dropping column with index covered now.
 	errCantDropColWithIndex    = terror.ClassDDL.New(codeCantDropColWithIndex, "can't drop column with index")
@@ -54,6 +55,17 @@ var (
 	errUnsupportedModifyColumn = terror.ClassDDL.New(codeUnsupportedModifyColumn, "unsupported modify column")
 	errUnsupportedPKHandle     = terror.ClassDDL.New(codeUnsupportedDropPKHandle,
 		"unsupported drop integer primary key")
+	errOperateSameColumn          = terror.ClassDDL.New(codeOperateSameColumn, "operate same column")
+	errOperateSameIndex           = terror.ClassDDL.New(codeOperateSameIndex, "operate same index")
+	errInvalidAutoRandom          = terror.ClassDDL.New(codeInvalidAutoRandom, "Invalid auto random: %s")
+	errInvalidTTL                 = terror.ClassDDL.New(codeInvalidTTL, "Invalid TTL option: %s")
+	errUnsupportedExpressionIndex = terror.ClassDDL.New(codeUnsupportedExpressionIndex,
+		"unsupported expression index: %s")
+	errTableRecovered = terror.ClassDDL.New(codeTableRecovered, "the table has been recovered as '%s'")
+	// errUnsupportedClusteredIndex returns for the CLUSTERED/NONCLUSTERED option that can't be applied.
+	errUnsupportedClusteredIndex = terror.ClassDDL.New(codeUnsupportedClusteredIndex, "unsupported clustered index: %s")
+	// errUnsupportedShardRowIDBits returns for the SHARD_ROW_ID_BITS option that can't be applied.
+	errUnsupportedShardRowIDBits = terror.ClassDDL.New(codeUnsupportedShardRowIDBits, "unsupported shard_row_id_bits: %s")
 
 	errBlobKeyWithoutLength = terror.ClassDDL.New(codeBlobKeyWithoutLength, "index for BLOB/TEXT column must specificate a key length")
 	errIncorrectPrefixKey   = terror.ClassDDL.New(codeIncorrectPrefixKey, "Incorrect prefix key; the used key part isn't a string, the used length is longer than the key part, or the storage engine doesn't support unique prefix keys")
@@ -71,6 +83,33 @@ var (
 	errInvalidDefault        = terror.ClassDDL.New(codeInvalidDefault, "Invalid default value for '%s'")
 	errInvalidUseOfNull      = terror.ClassDDL.New(codeInvalidUseOfNull, "Invalid use of NULL value")
 
+	errUnknownCharacterSet = terror.ClassDDL.New(codeUnknownCharacterSet,
+		mysql.MySQLErrName[mysql.ErrUnknownCharacterSet])
+	errUnknownCollation         = terror.ClassDDL.New(codeUnknownCollation, mysql.MySQLErrName[mysql.ErrUnknownCollation])
+	errCollationCharsetMismatch = terror.ClassDDL.New(codeCollationCharsetMismatch,
+		mysql.MySQLErrName[mysql.ErrCollationCharsetMismatch])
+
+	errWrongValue = terror.ClassDDL.New(codeWrongValue, "Incorrect %s value: '%d'")
+	// errKeyDoesNotExist returns for altering the index which doesn't exist.
+	errKeyDoesNotExist = terror.ClassDDL.New(codeKeyDoesNotExist, "Key '%s' doesn't exist in table '%s'")
+	// errPKIndexCantBeInvisible returns for making the primary key invisible.
+	errPKIndexCantBeInvisible = terror.ClassDDL.New(codePKIndexCantBeInvisible, "A primary key index cannot be invisible")
+
+	errFunctionalIndexRefAutoIncrement = terror.ClassDDL.New(codeFunctionalIndexRefAutoIncrement,
+		mysql.MySQLErrName[mysql.ErrFunctionalIndexRefAutoIncrement])
+	errCannotDropColumnFunctionalIndex = terror.ClassDDL.New(codeCannotDropColumnFunctionalIndex,
+		mysql.MySQLErrName[mysql.ErrCannotDropColumnFunctionalIndex])
+	errFunctionalIndexPrimaryKey = terror.ClassDDL.New(codeFunctionalIndexPrimaryKey,
+		mysql.MySQLErrName[mysql.ErrFunctionalIndexPrimaryKey])
+	errFunctionalIndexOnLob = terror.ClassDDL.New(codeFunctionalIndexOnLob,
+		mysql.MySQLErrName[mysql.ErrFunctionalIndexOnLob])
+	errFunctionalIndexFunctionIsNotAllowed = terror.ClassDDL.New(codeFunctionalIndexFunctionIsNotAllowed,
+		mysql.MySQLErrName[mysql.ErrFunctionalIndexFunctionIsNotAllowed])
+	errFunctionalIndexOnField = terror.ClassDDL.New(codeFunctionalIndexOnField,
+		mysql.MySQLErrName[mysql.ErrFunctionalIndexOnField])
+	errDependentByFunctionalIndex = terror.ClassDDL.New(codeDependentByFunctionalIndex,
+		mysql.MySQLErrName[mysql.ErrDependentByFunctionalIndex])
+
 	// ErrInvalidDBState returns for invalid database state.
 	ErrInvalidDBState = terror.ClassDDL.New(codeInvalidDBState, "invalid database state")
 	// ErrInvalidTableState returns for invalid Table state.
@@ -101,7 +140,15 @@ type DDL interface {
 	CreateTable(ctx context.Context, ident ast.Ident, cols []*ast.ColumnDef,
 		constrs []*ast.Constraint, options []*ast.TableOption) error
 	CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ident) error
+	// CreateTemporaryTable builds the meta of a session temporary table, it's not written to the storage.
+	CreateTemporaryTable(ctx context.Context, ident ast.Ident, cols []*ast.ColumnDef,
+		constrs []*ast.Constraint, options []*ast.TableOption) (*model.TableInfo, error)
+	// CreateTemporaryTableWithLike builds the meta of a session temporary table like the refer table.
+	CreateTemporaryTableWithLike(ctx context.Context, ident ast.Ident, referTblInfo *model.TableInfo) (*model.TableInfo, error)
 	DropTable(ctx context.Context, tableIdent ast.Ident) (err error)
+	// RecoverTable recovers the dropped or truncated table in the schema, its data is restored from the snapshot of
+	// snapshotTS, which is taken before the data is deleted.
+	RecoverTable(ctx context.Context, schemaID int64, tblInfo *model.TableInfo, autoID int64, snapshotTS uint64) error
 	CreateIndex(ctx context.Context, tableIdent ast.Ident, unique bool, indexName model.CIStr,
 		columnNames []*ast.IndexColName) error
 	DropIndex(ctx context.Context, tableIdent ast.Ident, indexName model.CIStr) error
@@ -109,15 +156,22 @@ type DDL interface {
 	AlterTable(ctx context.Context, tableIdent ast.Ident, spec []*ast.AlterTableSpec) error
 	TruncateTable(ctx context.Context, tableIdent ast.Ident) error
 	RenameTable(ctx context.Context, oldTableIdent, newTableIdent ast.Ident) error
+	// RenameTables renames the tables in order atomically.
+	RenameTables(ctx context.Context, oldTableIdents, newTableIdents []ast.Ident) error
 	// SetLease will reset the lease time for online DDL change,
 	// it's a very dangerous function and you must guarantee that all servers have the same lease time.
 	SetLease(lease time.Duration)
 	// GetLease returns current schema lease time.
 	GetLease() time.Duration
+	// SchemaSyncer gets the schema syncer.
+	SchemaSyncer() SchemaSyncer
 	// Stats returns the DDL statistics.
 	Stats() (map[string]interface{}, error)
 	// GetScope gets the status variables scope.
 	GetScope(status string) variable.ScopeFlag
+	// ResignOwner makes the server give up the DDL job owner, so another server can become the owner. The server
+	// doesn't try to become the owner again during the owner timeout.
+	ResignOwner() error
 	// Stop stops DDL worker.
 	Stop() error
 	// Start starts DDL worker.
@@ -136,6 +190,8 @@ type ddl struct {
 	uuid         string
 	ddlJobCh     chan struct{}
 	ddlJobDoneCh chan struct{}
+	// reorgJobCh notifies the worker of the reorganization job queue.
+	reorgJobCh chan struct{}
 	// Drop database/table job that runs in the background.
 	bgJobCh chan struct{}
 	// reorgDoneCh is for reorganization, if the reorganization job is done,
@@ -145,6 +201,13 @@ type ddl struct {
 	reorgDoneCh chan error
 	// reorgRowCount is for reorganization, it uses to simulate a job's row count.
 	reorgRowCount int64
+	// reorgCancelled is set to 1 if the running reorganization job is requested to be cancelled.
+	reorgCancelled int32
+	// schemaSyncer syncs the schema versions between the owner and the other servers.
+	schemaSyncer *schemaSyncer
+	// resignedUntil is the time in nanoseconds before which the server doesn't try to become the DDL job owner,
+	// it's set by ResignOwner.
+	resignedUntil int64
 
 	quitCh chan struct{}
 	wait   sync.WaitGroup
@@ -168,8 +231,10 @@ func newDDL(store kv.Storage, infoHandle *infoschema.Handle, hook Callback, leas
 		uuid:         uuid.NewV4().String(),
 		ddlJobCh:     make(chan struct{}, 1),
 		ddlJobDoneCh: make(chan struct{}, 1),
+		reorgJobCh:   make(chan struct{}, 1),
 		bgJobCh:      make(chan struct{}, 1),
 	}
+	d.schemaSyncer = newSchemaSyncer(store, d.uuid)
 
 	d.start()
 
@@ -185,6 +250,12 @@ func (d *ddl) Stop() error {
 
 	d.close()
 
+	if d.lease > 0 {
+		if err := d.schemaSyncer.RemoveSelfVersion(); err != nil {
+			log.Warnf("[ddl] remove self schema version err %v", errors.ErrorStack(err))
+		}
+	}
+
 	err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
 		t := meta.NewMeta(txn)
 		owner, err1 := t.GetDDLJobOwner()
@@ -235,13 +306,27 @@ func (d *ddl) Start() error {
 
 func (d *ddl) start() {
 	d.quitCh = make(chan struct{})
-	d.wait.Add(2)
+	d.wait.Add(3)
 	go d.onBackgroundWorker()
-	go d.onDDLWorker()
+	// The jobs that reorganize the data are handled by a separate worker,
+	// so they don't block the other jobs on unrelated tables.
+	go d.onDDLWorker(meta.DefaultJobListKey, d.ddlJobCh)
+	go d.onDDLWorker(meta.ReorgJobListKey, d.reorgJobCh)
 	// For every start, we will send a fake job to let worker
 	// check owner firstly and try to find whether a job exists and run.
 	asyncNotify(d.ddlJobCh)
+	asyncNotify(d.reorgJobCh)
 	asyncNotify(d.bgJobCh)
+
+	if d.lease > 0 && SchemaSyncInterval > 0 {
+		d.wait.Add(1)
+		go d.onSchemaSyncer()
+	}
+}
+
+func (d *ddl) onSchemaSyncer() {
+	defer d.wait.Done()
+	d.schemaSyncer.watchGlobalVersion(d.quitCh)
 }
 
 func (d *ddl) close() {
@@ -293,6 +378,10 @@ func (d *ddl) GetLease() time.Duration {
 	return lease
 }
 
+func (d *ddl) SchemaSyncer() SchemaSyncer {
+	return d.schemaSyncer
+}
+
 func (d *ddl) GetInformationSchema() infoschema.InfoSchema {
 	return d.infoHandle.Get()
 }
@@ -321,7 +410,11 @@ func (d *ddl) doDDLJob(ctx context.Context, job *model.Job) error {
 	}
 
 	// Notice worker that we push a new job and wait the job done.
-	asyncNotify(d.ddlJobCh)
+	if isReorgJob(job) {
+		asyncNotify(d.reorgJobCh)
+	} else {
+		asyncNotify(d.ddlJobCh)
+	}
 	log.Infof("[ddl] start DDL job %s, Query:\n%s", job, job.Query)
 
 	var historyJob *model.Job
@@ -400,6 +493,7 @@ const (
 	codeInvalidStoreVer                      = 8
 	codeUnknownTypeLength                    = 9
 	codeUnknownFractionLength                = 10
+	codeCancelledDDLJob                      = 11
 
 	codeInvalidDBState         = 100
 	codeInvalidTableState      = 101
@@ -407,10 +501,18 @@ const (
 	codeInvalidIndexState      = 103
 	codeInvalidForeignKeyState = 104
 
-	codeCantDropColWithIndex    = 201
-	codeUnsupportedAddColumn    = 202
-	codeUnsupportedModifyColumn = 203
-	codeUnsupportedDropPKHandle = 204
+	codeCantDropColWithIndex       = 201
+	codeUnsupportedAddColumn       = 202
+	codeUnsupportedModifyColumn    = 203
+	codeUnsupportedDropPKHandle    = 204
+	codeOperateSameColumn          = 205
+	codeOperateSameIndex           = 206
+	codeInvalidAutoRandom          = 207
+	codeInvalidTTL                 = 208
+	codeUnsupportedExpressionIndex = 209
+	codeTableRecovered             = 210
+	codeUnsupportedClusteredIndex  = 211
+	codeUnsupportedShardRowIDBits  = 212
 
 	codeFileNotFound          = 1017
 	codeErrorOnRename         = 1025
@@ -428,7 +530,23 @@ const (
 	codeWrongTableName        = 1103
 	codeInvalidUseOfNull      = 1138
 	codeBlobKeyWithoutLength  = 1170
+	codeKeyDoesNotExist       = 1176
 	codeInvalidOnUpdate       = 1294
+
+	codeUnknownCharacterSet      = 1115
+	codeCollationCharsetMismatch = 1253
+	codeUnknownCollation         = 1273
+
+	codeWrongValue             = 1525
+	codePKIndexCantBeInvisible = 3522
+
+	codeFunctionalIndexRefAutoIncrement     = 3754
+	codeCannotDropColumnFunctionalIndex     = 3755
+	codeFunctionalIndexPrimaryKey           = 3756
+	codeFunctionalIndexOnLob                = 3757
+	codeFunctionalIndexFunctionIsNotAllowed = 3758
+	codeFunctionalIndexOnField              = 3762
+	codeDependentByFunctionalIndex          = 3837
 )
 
 func init() {
@@ -450,6 +568,22 @@ func init() {
 		codeBadField:              mysql.ErrBadField,
 		codeInvalidDefault:        mysql.ErrInvalidDefault,
 		codeInvalidUseOfNull:      mysql.ErrInvalidUseOfNull,
+		codeKeyDoesNotExist:       mysql.ErrKeyDoesNotExits,
+
+		codeUnknownCharacterSet:      mysql.ErrUnknownCharacterSet,
+		codeCollationCharsetMismatch: mysql.ErrCollationCharsetMismatch,
+		codeUnknownCollation:         mysql.ErrUnknownCollation,
+
+		codeWrongValue:             mysql.ErrWrongValue,
+		codePKIndexCantBeInvisible: mysql.ErrPKIndexCantBeInvisible,
+
+		codeFunctionalIndexRefAutoIncrement:     mysql.ErrFunctionalIndexRefAutoIncrement,
+		codeCannotDropColumnFunctionalIndex:     mysql.ErrCannotDropColumnFunctionalIndex,
+		codeFunctionalIndexPrimaryKey:           mysql.ErrFunctionalIndexPrimaryKey,
+		codeFunctionalIndexOnLob:                mysql.ErrFunctionalIndexOnLob,
+		codeFunctionalIndexFunctionIsNotAllowed: mysql.ErrFunctionalIndexFunctionIsNotAllowed,
+		codeFunctionalIndexOnField:              mysql.ErrFunctionalIndexOnField,
+		codeDependentByFunctionalIndex:          mysql.ErrDependentByFunctionalIndex,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassDDL] = ddlMySQLErrCodes
 }
@@ -19,6 +19,7 @@ package ddl
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -121,13 +122,20 @@ func getDefaultCollateForCharset(str string) string {
 	case charset.CharsetUTF8:
 		return charset.CollationUTF8
 	}
-	return ""
+	co, err := charset.GetDefaultCollation(str)
+	if err != nil {
+		return ""
+	}
+	return co
 }
 
 func setColumnFlagWithConstraint(colMap map[string]*table.Column, v *ast.Constraint) {
 	switch v.Tp {
 	case ast.ConstraintPrimaryKey:
 		for _, key := range v.Keys {
+			if key.Column == nil {
+				continue
+			}
 			c, ok := colMap[key.Column.Name.L]
 			if !ok {
 				continue
@@ -138,6 +146,9 @@ func setColumnFlagWithConstraint(colMap map[string]*table.Column, v *ast.Constra
 		}
 	case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
 		for i, key := range v.Keys {
+			if key.Column == nil {
+				continue
+			}
 			c, ok := colMap[key.Column.Name.L]
 			if !ok {
 				continue
@@ -156,6 +167,9 @@ func setColumnFlagWithConstraint(colMap map[string]*table.Column, v *ast.Constra
 		}
 	case ast.ConstraintKey, ast.ConstraintIndex:
 		for i, key := range v.Keys {
+			if key.Column == nil {
+				continue
+			}
 			c, ok := colMap[key.Column.Name.L]
 			if !ok {
 				continue
@@ -263,6 +277,9 @@ func columnDefToCol(ctx context.Context, offset int, colDef *ast.ColumnDef) (*ta
 				col.Flag |= mysql.AutoIncrementFlag
 			case ast.ColumnOptionPrimaryKey:
 				constraint := &ast.Constraint{Tp: ast.ConstraintPrimaryKey, Keys: keys}
+				if v.PrimaryKeyTp != ast.PrimaryKeyTypeDefault {
+					constraint.Option = &ast.IndexOption{PrimaryKeyTp: v.PrimaryKeyTp}
+				}
 				constraints = append(constraints, constraint)
 				col.Flag |= mysql.PriKeyFlag
 			case ast.ColumnOptionUniq:
@@ -284,11 +301,12 @@ func columnDefToCol(ctx context.Context, offset int, colDef *ast.ColumnDef) (*ta
 				constraints = append(constraints, constraint)
 				col.Flag |= mysql.UniqueKeyFlag
 			case ast.ColumnOptionDefaultValue:
-				value, err := getDefaultValue(ctx, v, colDef.Tp.Tp, colDef.Tp.Decimal)
+				value, isExpr, err := getDefaultValue(ctx, v, colDef.Tp.Tp, colDef.Tp.Decimal)
 				if err != nil {
 					return nil, nil, ErrColumnBadNull.Gen("invalid default value - %s", err)
 				}
 				col.DefaultValue = value
+				col.DefaultIsExpr = isExpr
 				hasDefaultValue = true
 				removeOnUpdateNowFlag(col)
 			case ast.ColumnOptionOnUpdate:
@@ -310,6 +328,9 @@ func columnDefToCol(ctx context.Context, offset int, colDef *ast.ColumnDef) (*ta
 		}
 	}
 
+	if col.DefaultIsExpr && mysql.HasAutoIncrementFlag(col.Flag) {
+		return nil, nil, errInvalidDefault.GenByArgs(col.Name)
+	}
 	setTimestampDefaultValue(col, hasDefaultValue, setOnUpdateNow)
 
 	// Set `NoDefaultValueFlag` if this field doesn't have a default value and
@@ -326,34 +347,64 @@ func columnDefToCol(ctx context.Context, offset int, colDef *ast.ColumnDef) (*ta
 	return col, constraints, nil
 }
 
-func getDefaultValue(ctx context.Context, c *ast.ColumnOption, tp byte, fsp int) (interface{}, error) {
+// getDefaultValue returns the default value of the column option, it returns true and the expression string if the
+// default value is an expression.
+func getDefaultValue(ctx context.Context, c *ast.ColumnOption, tp byte, fsp int) (interface{}, bool, error) {
+	if x, ok := c.Expr.(*ast.ParenthesesExpr); ok {
+		checker := &defaultExprChecker{}
+		x.Expr.Accept(checker)
+		if checker.disallowed {
+			return nil, false, errors.Errorf("the expression %s isn't allowed", x.Expr.Text())
+		}
+		return x.Expr.Text(), true, nil
+	}
 	if tp == mysql.TypeTimestamp || tp == mysql.TypeDatetime {
 		vd, err := expression.GetTimeValue(ctx, c.Expr, tp, fsp)
 		value := vd.GetValue()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return nil, false, errors.Trace(err)
 		}
 
 		// Value is nil means `default null`.
 		if value == nil {
-			return nil, nil
+			return nil, false, nil
 		}
 
 		// If value is types.Time, convert it to string.
 		if vv, ok := value.(types.Time); ok {
-			return vv.String(), nil
+			return vv.String(), false, nil
 		}
 
-		return value, nil
+		return value, false, nil
 	}
 	v, err := expression.EvalAstExpr(c.Expr, ctx)
 	if err != nil {
-		return nil, errors.Trace(err)
+		return nil, false, errors.Trace(err)
 	}
 	if v.IsNull() {
-		return nil, nil
+		return nil, false, nil
+	}
+	value, err := v.ToString()
+	return value, false, errors.Trace(err)
+}
+
+// defaultExprChecker checks whether the expression can be a default value, which is evaluated without any row, so
+// it rejects the columns, the subqueries, the aggregate functions and the variables.
+type defaultExprChecker struct {
+	disallowed bool
+}
+
+func (c *defaultExprChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch in.(type) {
+	case *ast.ColumnNameExpr, *ast.SubqueryExpr, *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr,
+		*ast.AggregateFuncExpr, *ast.VariableExpr, *ast.ParamMarkerExpr, *ast.DefaultExpr, *ast.ValuesExpr:
+		c.disallowed = true
 	}
-	return v.ToString()
+	return in, c.disallowed
+}
+
+func (c *defaultExprChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
 }
 
 func removeOnUpdateNowFlag(c *table.Column) {
@@ -453,7 +504,10 @@ func checkDuplicateConstraint(namesMap map[string]bool, name string, foreign boo
 
 func setEmptyConstraintName(namesMap map[string]bool, constr *ast.Constraint, foreign bool) {
 	if constr.Name == "" && len(constr.Keys) > 0 {
-		colName := constr.Keys[0].Column.Name.L
+		colName := expressionIndexName
+		if constr.Keys[0].Column != nil {
+			colName = constr.Keys[0].Column.Name.L
+		}
 		constrName := colName
 		i := 2
 		for namesMap[constrName] {
@@ -501,7 +555,8 @@ func checkConstraintNames(constraints []*ast.Constraint) error {
 	return nil
 }
 
-func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constraints []*ast.Constraint) (tbInfo *model.TableInfo, err error) {
+func (d *ddl) buildTableInfo(ctx context.Context, tableName model.CIStr, cols []*table.Column,
+	constraints []*ast.Constraint) (tbInfo *model.TableInfo, err error) {
 	tbInfo = &model.TableInfo{
 		Name: tableName,
 	}
@@ -525,6 +580,9 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 			fk.RefTable = constr.Refer.Table.Name
 			fk.State = model.StatePublic
 			for _, key := range constr.Keys {
+				if key.Column == nil {
+					return nil, infoschema.ErrCannotAddForeign
+				}
 				fk.Cols = append(fk.Cols, key.Column.Name)
 			}
 			for _, key := range constr.Refer.IndexColNames {
@@ -542,7 +600,22 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 			tbInfo.ForeignKeys = append(tbInfo.ForeignKeys, &fk)
 			continue
 		}
+		pkTp := ast.PrimaryKeyTypeDefault
+		if constr.Option != nil {
+			pkTp = constr.Option.PrimaryKeyTp
+		}
+		if constr.Tp != ast.ConstraintPrimaryKey && pkTp != ast.PrimaryKeyTypeDefault {
+			return nil, errUnsupportedClusteredIndex.GenByArgs("only the primary key can be CLUSTERED or NONCLUSTERED")
+		}
 		if constr.Tp == ast.ConstraintPrimaryKey {
+			if constr.Option != nil && constr.Option.Visibility == ast.IndexVisibilityInvisible {
+				return nil, errPKIndexCantBeInvisible
+			}
+			for _, key := range constr.Keys {
+				if key.Expr != nil {
+					return nil, errors.Trace(errFunctionalIndexPrimaryKey)
+				}
+			}
 			if len(constr.Keys) == 1 {
 				key := constr.Keys[0]
 				col := table.FindCol(cols, key.Column.Name.O)
@@ -552,14 +625,29 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 				switch col.Tp {
 				case mysql.TypeLong, mysql.TypeLonglong,
 					mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24:
-					tbInfo.PKIsHandle = true
-					// Avoid creating index for PK handle column.
-					continue
+					// The integer primary key is clustered by default, the rows are keyed by it directly.
+					// A NONCLUSTERED one is a unique index on the hidden row ID like the other primary keys.
+					if pkTp != ast.PrimaryKeyTypeNonClustered {
+						tbInfo.PKIsHandle = true
+						// Avoid creating index for PK handle column.
+						continue
+					}
 				}
 			}
+			// The row handle is always an int64 in the storage and executor layers, keying the rows by a
+			// non-integer or composite primary key is not supported, they are keyed by the hidden row ID.
+			if pkTp == ast.PrimaryKeyTypeClustered {
+				return nil, errUnsupportedClusteredIndex.GenByArgs("CLUSTERED is not supported on a non-integer or composite primary key")
+			}
+		}
+		// The hidden columns of the expression index are appended to the columns.
+		keys, hiddenCols, err := buildHiddenColumns(ctx, tbInfo, model.NewCIStr(constr.Name), constr.Keys)
+		if err != nil {
+			return nil, errors.Trace(err)
 		}
+		addHiddenColumns(tbInfo, hiddenCols)
 		// build index info.
-		idxInfo, err := buildIndexInfo(tbInfo, model.NewCIStr(constr.Name), constr.Keys, model.StatePublic)
+		idxInfo, err := buildIndexInfo(tbInfo, model.NewCIStr(constr.Name), keys, model.StatePublic)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -576,6 +664,7 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 		if constr.Option != nil {
 			idxInfo.Comment = constr.Option.Comment
 			idxInfo.Tp = constr.Option.Tp
+			idxInfo.Invisible = constr.Option.Visibility == ast.IndexVisibilityInvisible
 		} else {
 			// Use btree as default index type.
 			idxInfo.Tp = model.IndexTypeBtree
@@ -604,7 +693,8 @@ func (d *ddl) CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ide
 		return infoschema.ErrTableExists.GenByArgs(ident)
 	}
 
-	tblInfo := *referTbl.Meta()
+	// The columns, the indices and the options are copied, the foreign keys and the auto increment ID aren't.
+	tblInfo := referTbl.Meta().Clone()
 	tblInfo.Name = ident.Name
 	tblInfo.AutoIncID = 0
 	tblInfo.ForeignKeys = nil
@@ -621,6 +711,9 @@ func (d *ddl) CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ide
 	}
 
 	err = d.doDDLJob(ctx, job)
+	if err == nil {
+		d.preSplitTableRegions(tblInfo)
+	}
 	err = d.callHookOnChanged(err)
 	return errors.Trace(err)
 }
@@ -644,6 +737,9 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 	if err = checkTooLongColumn(colDefs); err != nil {
 		return errors.Trace(err)
 	}
+	if err = setColumnsCharsetByOptions(colDefs, options); err != nil {
+		return errors.Trace(err)
+	}
 
 	cols, newConstraints, err := buildColumnsAndConstraints(ctx, colDefs, constraints)
 	if err != nil {
@@ -655,10 +751,13 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 		return errors.Trace(err)
 	}
 
-	tbInfo, err := d.buildTableInfo(ident.Name, cols, newConstraints)
+	tbInfo, err := d.buildTableInfo(ctx, ident.Name, cols, newConstraints)
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if err = setAutoRandomBits(tbInfo, colDefs); err != nil {
+		return errors.Trace(err)
+	}
 
 	job := &model.Job{
 		SchemaID:   schema.ID,
@@ -668,7 +767,9 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 		Args:       []interface{}{tbInfo},
 	}
 
-	handleTableOptions(options, tbInfo)
+	if err = handleTableOptions(options, tbInfo); err != nil {
+		return errors.Trace(err)
+	}
 	err = d.doDDLJob(ctx, job)
 	if err == nil {
 		if tbInfo.AutoIncID > 1 {
@@ -676,11 +777,130 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 			// If the first id is expected to greater than 1, we need to do rebase.
 			d.handleAutoIncID(tbInfo, schema.ID)
 		}
+		d.preSplitTableRegions(tbInfo)
 	}
 	err = d.callHookOnChanged(err)
 	return errors.Trace(err)
 }
 
+func (d *ddl) CreateTemporaryTable(ctx context.Context, ident ast.Ident, colDefs []*ast.ColumnDef,
+	constraints []*ast.Constraint, options []*ast.TableOption) (*model.TableInfo, error) {
+	is := d.GetInformationSchema()
+	if !is.SchemaExists(ident.Schema) {
+		return nil, infoschema.ErrDatabaseNotExists.GenByArgs(ident.Schema)
+	}
+	if err := checkTooLongTable(ident.Name); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkDuplicateColumn(colDefs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkTooLongColumn(colDefs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := setColumnsCharsetByOptions(colDefs, options); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	cols, newConstraints, err := buildColumnsAndConstraints(ctx, colDefs, constraints)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = checkConstraintNames(newConstraints); err != nil {
+		return nil, errors.Trace(err)
+	}
+	tbInfo, err := d.buildTableInfo(ctx, ident.Name, cols, newConstraints)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = setAutoRandomBits(tbInfo, colDefs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = handleTableOptions(options, tbInfo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tbInfo.TTLInfo != nil {
+		return nil, errInvalidTTL.GenByArgs("TTL isn't supported on the temporary table")
+	}
+	if err = checkNoExpressionIndex(tbInfo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The foreign keys of a temporary table are not checked, the same as MySQL.
+	tbInfo.ForeignKeys = nil
+	tbInfo.State = model.StatePublic
+	tbInfo.Temporary = true
+	return tbInfo, nil
+}
+
+func (d *ddl) CreateTemporaryTableWithLike(ctx context.Context, ident ast.Ident, referTblInfo *model.TableInfo) (*model.TableInfo, error) {
+	is := d.GetInformationSchema()
+	if !is.SchemaExists(ident.Schema) {
+		return nil, infoschema.ErrDatabaseNotExists.GenByArgs(ident.Schema)
+	}
+	if err := checkNoExpressionIndex(referTblInfo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	tbInfo := referTblInfo.Clone()
+	tbInfo.Name = ident.Name
+	tbInfo.AutoIncID = 0
+	tbInfo.OldSchemaID = 0
+	tbInfo.ForeignKeys = nil
+	// The rows of a temporary table never expire.
+	tbInfo.TTLInfo = nil
+	tbInfo.State = model.StatePublic
+	tbInfo.Temporary = true
+	var err error
+	tbInfo.ID, err = d.genGlobalID()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return tbInfo, nil
+}
+
+// checkNoExpressionIndex returns an error if the table has an expression index, the rows of a temporary table are
+// written without evaluating the hidden columns.
+func checkNoExpressionIndex(tbInfo *model.TableInfo) error {
+	for _, col := range tbInfo.Columns {
+		if col.IsGenerated() {
+			return errUnsupportedExpressionIndex.GenByArgs("the temporary table can't have an expression index")
+		}
+	}
+	return nil
+}
+
+// setAutoRandomBits sets the shard bits of the AUTO_RANDOM column, which must be the bigint primary key handle without
+// the default value.
+func setAutoRandomBits(tbInfo *model.TableInfo, colDefs []*ast.ColumnDef) error {
+	for _, colDef := range colDefs {
+		for _, op := range colDef.Options {
+			if op.Tp != ast.ColumnOptionAutoRandom {
+				continue
+			}
+			col := findCol(tbInfo.Columns, colDef.Name.Name.L)
+			if col.Tp != mysql.TypeLonglong {
+				return errInvalidAutoRandom.GenByArgs("AUTO_RANDOM is only supported on the bigint column")
+			}
+			if !tbInfo.PKIsHandle || !mysql.HasPriKeyFlag(col.Flag) {
+				return errInvalidAutoRandom.GenByArgs("AUTO_RANDOM is only supported on the single column primary key")
+			}
+			if mysql.HasAutoIncrementFlag(col.Flag) || col.DefaultValue != nil {
+				return errInvalidAutoRandom.GenByArgs(
+					"AUTO_RANDOM can't be specified with AUTO_INCREMENT or the default value")
+			}
+			bits := op.AutoRandomBitLength
+			if bits == types.UnspecifiedLength {
+				bits = autoid.DefaultAutoRandomBits
+			}
+			if bits <= 0 || bits > autoid.MaxAutoRandomBits {
+				return errInvalidAutoRandom.GenByArgs(fmt.Sprintf("the shard bits must be in [1, %d]",
+					autoid.MaxAutoRandomBits))
+			}
+			tbInfo.AutoRandomBits = uint64(bits)
+		}
+	}
+	return nil
+}
+
 // If create table with auto_increment option, we should rebase tableAutoIncID value.
 func (d *ddl) handleAutoIncID(tbInfo *model.TableInfo, schemaID int64) error {
 	if tbInfo.OldSchemaID != 0 {
@@ -702,19 +922,235 @@ func (d *ddl) handleAutoIncID(tbInfo *model.TableInfo, schemaID int64) error {
 }
 
 // Add create table options into TableInfo.
-func handleTableOptions(options []*ast.TableOption, tbInfo *model.TableInfo) {
+func handleTableOptions(options []*ast.TableOption, tbInfo *model.TableInfo) error {
 	for _, op := range options {
 		switch op.Tp {
 		case ast.TableOptionAutoIncrement:
 			tbInfo.AutoIncID = int64(op.UintValue)
+		case ast.TableOptionAutoIDCache:
+			if op.UintValue > math.MaxInt64 {
+				return errWrongValue.GenByArgs("AUTO_ID_CACHE", op.UintValue)
+			}
+			tbInfo.AutoIDCache = int64(op.UintValue)
+		case ast.TableOptionShardRowID:
+			if op.UintValue > autoid.MaxShardRowIDBits {
+				return errUnsupportedShardRowIDBits.GenByArgs(fmt.Sprintf("the shard bits must be in [0, %d]",
+					autoid.MaxShardRowIDBits))
+			}
+			if op.UintValue > 0 && tbInfo.PKIsHandle {
+				return errUnsupportedShardRowIDBits.GenByArgs("the table whose primary key is the row ID has no implicit row ID")
+			}
+			tbInfo.ShardRowIDBits = op.UintValue
+		case ast.TableOptionPreSplitRegion:
+			tbInfo.PreSplitRegions = op.UintValue
+		}
+	}
+	// The regions are split by the shards, so there are at most 2^shardBits regions.
+	if shardBits := shardBitsOfTable(tbInfo); tbInfo.PreSplitRegions > shardBits {
+		tbInfo.PreSplitRegions = shardBits
+	}
+	if err := setTableOptions(tbInfo, options); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(handleTTLOptions(options, tbInfo))
+}
+
+var rowFormatNames = map[uint64]string{
+	ast.RowFormatDefault:    "DEFAULT",
+	ast.RowFormatDynamic:    "DYNAMIC",
+	ast.RowFormatFixed:      "FIXED",
+	ast.RowFormatCompressed: "COMPRESSED",
+	ast.RowFormatRedundant:  "REDUNDANT",
+	ast.RowFormatCompact:    "COMPACT",
+}
+
+// setTableOptions sets the comment, the default charset and collation, and the storage options of the table by the
+// options. The collation is reset if the charset is changed without it, and the charset is set by the collation if
+// only the collation is given.
+func setTableOptions(tbInfo *model.TableInfo, options []*ast.TableOption) error {
+	storage := &model.TableStorageOptions{}
+	if tbInfo.StorageOptions != nil {
+		storage = tbInfo.StorageOptions.Clone()
+	}
+	var charsetSpecified bool
+	for _, op := range options {
+		switch op.Tp {
 		case ast.TableOptionComment:
 			tbInfo.Comment = op.StrValue
 		case ast.TableOptionCharset:
-			tbInfo.Charset = op.StrValue
-		case ast.TableOptionCollate:
-			tbInfo.Charset = op.StrValue
+			cs := strings.ToLower(op.StrValue)
+			if !charset.ValidCharsetAndCollation(cs, "") {
+				return errUnknownCharacterSet.GenByArgs(op.StrValue)
+			}
+			if cs != tbInfo.Charset {
+				tbInfo.Collate = ""
+			}
+			tbInfo.Charset = cs
+			charsetSpecified = true
+		case ast.TableOptionRowFormat:
+			storage.RowFormat = rowFormatNames[op.UintValue]
+		case ast.TableOptionCompression:
+			storage.Compression = op.StrValue
+		case ast.TableOptionKeyBlockSize:
+			storage.KeyBlockSize = op.UintValue
+		case ast.TableOptionAvgRowLength:
+			storage.AvgRowLength = op.UintValue
+		case ast.TableOptionMaxRows:
+			storage.MaxRows = op.UintValue
+		case ast.TableOptionMinRows:
+			storage.MinRows = op.UintValue
+		case ast.TableOptionCheckSum:
+			storage.Checksum = op.UintValue
+		case ast.TableOptionDelayKeyWrite:
+			storage.DelayKeyWrite = op.UintValue
+		}
+	}
+	for _, op := range options {
+		if op.Tp != ast.TableOptionCollate {
+			continue
+		}
+		co, err := charset.GetCollationByName(op.StrValue)
+		if err != nil {
+			return errUnknownCollation.GenByArgs(op.StrValue)
+		}
+		if charsetSpecified && co.CharsetName != tbInfo.Charset {
+			return errCollationCharsetMismatch.GenByArgs(co.Name, tbInfo.Charset)
 		}
+		tbInfo.Charset, tbInfo.Collate = co.CharsetName, co.Name
 	}
+
+	tbInfo.StorageOptions = nil
+	if !storage.IsEmpty() {
+		tbInfo.StorageOptions = storage
+	}
+	return nil
+}
+
+// GetTableCharsetAndCollate returns the default charset and collation of the string columns in the table.
+func GetTableCharsetAndCollate(tbInfo *model.TableInfo) (string, string) {
+	if tbInfo.Charset == "" {
+		return getDefaultCharsetAndCollate()
+	}
+	if tbInfo.Collate == "" {
+		return tbInfo.Charset, getDefaultCollateForCharset(tbInfo.Charset)
+	}
+	return tbInfo.Charset, tbInfo.Collate
+}
+
+// setColumnsCharsetByOptions sets the charset and collation of the string columns to the ones of the table options,
+// if they aren't specified.
+func setColumnsCharsetByOptions(colDefs []*ast.ColumnDef, options []*ast.TableOption) error {
+	tbInfo := &model.TableInfo{}
+	if err := setTableOptions(tbInfo, options); err != nil {
+		return errors.Trace(err)
+	}
+	for _, colDef := range colDefs {
+		setColumnCharsetByTable(colDef.Tp, tbInfo)
+	}
+	return nil
+}
+
+// setColumnCharsetByTable sets the charset and collation of the string column to the ones of the table, if they
+// aren't specified.
+func setColumnCharsetByTable(tp *types.FieldType, tbInfo *model.TableInfo) {
+	if tp.Charset != "" || tbInfo.Charset == "" {
+		return
+	}
+	switch tp.Tp {
+	case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeBlob, mysql.TypeTinyBlob,
+		mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeEnum, mysql.TypeSet:
+		tp.Charset, tp.Collate = GetTableCharsetAndCollate(tbInfo)
+	}
+}
+
+// handleTTLOptions sets the TTL info of the table by the TTL options. The TTL option must be specified before, or
+// together with, TTL_ENABLE and TTL_JOB_INTERVAL.
+func handleTTLOptions(options []*ast.TableOption, tbInfo *model.TableInfo) error {
+	ttlInfo := tbInfo.TTLInfo
+	if ttlInfo != nil {
+		ttlInfo = ttlInfo.Clone()
+	}
+	var changed bool
+	for _, op := range options {
+		if op.Tp != ast.TableOptionTTL {
+			continue
+		}
+		if ttlInfo == nil {
+			ttlInfo = &model.TTLInfo{Enable: true, JobInterval: model.DefaultTTLJobInterval}
+		}
+		ttlInfo.ColumnName = op.ColumnName.Name
+		ttlInfo.IntervalValue = op.UintValue
+		ttlInfo.IntervalTimeUnit = op.StrValue
+		changed = true
+	}
+	for _, op := range options {
+		switch op.Tp {
+		case ast.TableOptionTTLEnable:
+			if ttlInfo == nil {
+				return errInvalidTTL.GenByArgs("TTL_ENABLE is specified without TTL")
+			}
+			switch strings.ToUpper(op.StrValue) {
+			case "ON":
+				ttlInfo.Enable = true
+			case "OFF":
+				ttlInfo.Enable = false
+			default:
+				return errInvalidTTL.GenByArgs("TTL_ENABLE must be 'ON' or 'OFF'")
+			}
+		case ast.TableOptionTTLJobInterval:
+			if ttlInfo == nil {
+				return errInvalidTTL.GenByArgs("TTL_JOB_INTERVAL is specified without TTL")
+			}
+			interval, err := time.ParseDuration(op.StrValue)
+			if err != nil || interval <= 0 {
+				return errInvalidTTL.GenByArgs(fmt.Sprintf("TTL_JOB_INTERVAL '%s' isn't a positive duration",
+					op.StrValue))
+			}
+			ttlInfo.JobInterval = op.StrValue
+		default:
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	col := findCol(tbInfo.Columns, ttlInfo.ColumnName.L)
+	if col == nil {
+		return errInvalidTTL.GenByArgs(fmt.Sprintf("the TTL column %s doesn't exist", ttlInfo.ColumnName))
+	}
+	if !isTTLColumnType(col.Tp) {
+		return errInvalidTTL.GenByArgs(fmt.Sprintf("the TTL column %s isn't a date, datetime or timestamp column",
+			col.Name))
+	}
+	ttlInfo.ColumnName = col.Name
+	switch ttlInfo.IntervalTimeUnit {
+	case "MICROSECOND", "SECOND", "MINUTE", "HOUR", "DAY", "WEEK", "MONTH", "QUARTER", "YEAR":
+	default:
+		return errInvalidTTL.GenByArgs(fmt.Sprintf("the time unit %s isn't supported", ttlInfo.IntervalTimeUnit))
+	}
+	tbInfo.TTLInfo = ttlInfo
+	return nil
+}
+
+func isTTLColumnType(tp byte) bool {
+	return tp == mysql.TypeDate || tp == mysql.TypeDatetime || tp == mysql.TypeTimestamp
+}
+
+// checkTTLColumnChange checks the TTL column is still a time column and isn't renamed after it's modified.
+func checkTTLColumnChange(tblInfo *model.TableInfo, col *table.Column, newCol *table.Column) error {
+	if tblInfo.TTLInfo == nil || tblInfo.TTLInfo.ColumnName.L != col.Name.L {
+		return nil
+	}
+	if newCol.Name.L != col.Name.L {
+		return errInvalidTTL.GenByArgs(fmt.Sprintf("can't rename the TTL column %s", col.Name))
+	}
+	if !isTTLColumnType(newCol.Tp) {
+		return errInvalidTTL.GenByArgs(fmt.Sprintf("the TTL column %s must be a date, datetime or timestamp column",
+			col.Name))
+	}
+	return nil
 }
 
 func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.AlterTableSpec) (err error) {
@@ -724,14 +1160,20 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 		if spec.Tp == ast.AlterTableLock {
 			continue
 		}
+		if spec.Tp == ast.AlterTableAddConstraint && spec.Constraint.Option != nil &&
+			spec.Constraint.Option.PrimaryKeyTp != ast.PrimaryKeyTypeDefault {
+			// The rows are keyed by the primary key only when the table is created.
+			return errUnsupportedClusteredIndex.GenByArgs("can't add a CLUSTERED or NONCLUSTERED key")
+		}
 		validSpecs = append(validSpecs, spec)
 	}
 
-	if len(validSpecs) != 1 {
-		// TODO: Hanlde len(validSpecs) == 0.
-		// Now we only allow one schema changing at the same time.
+	if len(validSpecs) == 0 {
 		return errRunMultiSchemaChanges
 	}
+	if len(validSpecs) > 1 {
+		return errors.Trace(d.multiSchemaChange(ctx, ident, validSpecs))
+	}
 
 	for _, spec := range validSpecs {
 		switch spec.Tp {
@@ -764,6 +1206,22 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 		case ast.AlterTableRenameTable:
 			newIdent := ast.Ident{Schema: spec.NewTable.Schema, Name: spec.NewTable.Name}
 			err = d.RenameTable(ctx, ident, newIdent)
+		case ast.AlterTableOption:
+			for _, option := range spec.Options {
+				if err = d.ModifyTableOption(ctx, ident, option); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = d.AlterTableTTL(ctx, ident, spec.Options)
+			}
+			if err == nil {
+				err = d.AlterTableOptions(ctx, ident, spec.Options)
+			}
+		case ast.AlterTableRemoveTTL:
+			err = d.RemoveTTL(ctx, ident)
+		case ast.AlterTableIndexVisibility:
+			err = d.AlterIndexVisibility(ctx, ident, model.NewCIStr(spec.Name), spec.Visibility)
 		default:
 			// Nothing to do now.
 		}
@@ -776,107 +1234,603 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 	return nil
 }
 
-func checkColumnConstraint(constraints []*ast.ColumnOption) error {
-	for _, constraint := range constraints {
-		switch constraint.Tp {
-		case ast.ColumnOptionAutoIncrement, ast.ColumnOptionPrimaryKey, ast.ColumnOptionUniq, ast.ColumnOptionUniqKey:
-			return errUnsupportedAddColumn.Gen("unsupported add column constraint - %v", constraint.Tp)
-		}
-	}
-
-	return nil
-}
-
-// AddColumn will add a new column to the table.
-func (d *ddl) AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableSpec) error {
-	// Check whether the added column constraints are supported.
-	err := checkColumnConstraint(spec.NewColumn.Options)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
+// multiSchemaChange makes the changes of the specifications in one job, the sub-jobs of the job run in order. If a
+// sub-job fails, the done sub-jobs are reverted. The changes that can't be reverted, which are dropping columns and
+// indices, changing the table options and changing the column type, run after the others, and only one column type
+// change is allowed.
+func (d *ddl) multiSchemaChange(ctx context.Context, ident ast.Ident, specs []*ast.AlterTableSpec) error {
 	is := d.infoHandle.Get()
-	schema, ok := is.SchemaByName(ti.Schema)
+	schema, ok := is.SchemaByName(ident.Schema)
 	if !ok {
 		return errors.Trace(infoschema.ErrDatabaseNotExists)
 	}
-	t, err := is.TableByName(ti.Schema, ti.Name)
+	t, err := is.TableByName(ident.Schema, ident.Name)
 	if err != nil {
 		return errors.Trace(infoschema.ErrTableNotExists)
 	}
 
-	// Check whether added column has existed.
-	colName := spec.NewColumn.Name.Name.O
-	col := table.FindCol(t.Cols(), colName)
-	if col != nil {
-		return infoschema.ErrColumnExists.GenByArgs(colName)
-	}
-
-	if len(colName) > mysql.MaxColumnNameLength {
-		return ErrTooLongIdent.Gen("too long column %s", colName)
-	}
-
-	// Ingore table constraints now, maybe return error later.
-	// We use length(t.Cols()) as the default offset firstly, we will change the
-	// column's offset later.
-	col, _, err = buildColumnAndConstraint(ctx, len(t.Cols()), spec.NewColumn)
-	if err != nil {
-		return errors.Trace(err)
-	}
-	col.OriginDefaultValue = col.DefaultValue
-	if col.OriginDefaultValue == nil && mysql.HasNotNullFlag(col.Flag) {
-		zeroVal := table.GetZeroValue(col.ToInfo())
-		col.OriginDefaultValue, err = zeroVal.ToString()
-		if err != nil {
-			return errors.Trace(err)
-		}
-	}
-
-	if col.OriginDefaultValue == expression.CurrentTimestamp &&
-		(col.Tp == mysql.TypeTimestamp || col.Tp == mysql.TypeDatetime) {
-		col.OriginDefaultValue = time.Now().Format(types.TimeFormat)
-	}
-
-	job := &model.Job{
-		SchemaID:   schema.ID,
-		TableID:    t.Meta().ID,
-		Type:       model.ActionAddColumn,
-		BinlogInfo: &model.HistoryInfo{},
-		Args:       []interface{}{col, spec.Position, 0},
-	}
-
-	err = d.doDDLJob(ctx, job)
-	err = d.callHookOnChanged(err)
-	return errors.Trace(err)
-}
-
-// DropColumn will drop a column from the table, now we don't support drop the column with index covered.
+	checker := newMultiSchemaChecker()
+	var others, typeChanges, drops []*model.Job
+	var otherReverts []*model.Job
+	for _, spec := range specs {
+		var job *model.Job
+		switch spec.Tp {
+		case ast.AlterTableOption:
+			for _, option := range spec.Options {
+				job, err = d.buildTableOptionJob(ident, option)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				if job != nil {
+					drops = append(drops, job)
+				}
+			}
+			job, err = d.buildTTLJob(ident, spec.Options)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if job != nil {
+				drops = append(drops, job)
+			}
+			job, err = d.buildTableOptionsJob(ident, spec.Options)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if job != nil {
+				drops = append(drops, job)
+			}
+			continue
+		case ast.AlterTableRemoveTTL:
+			job, err = d.buildRemoveTTLJob(ident)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			drops = append(drops, job)
+			continue
+		case ast.AlterTableAddColumn:
+			if spec.Position != nil && spec.Position.Tp == ast.ColumnPositionAfter {
+				checker.addUsedColumn(spec.Position.RelativeColumn.Name)
+			}
+			err = checker.addColumn(spec.NewColumn.Name.Name)
+			if err == nil {
+				job, err = d.buildAddColumnJob(ctx, ident, spec)
+			}
+		case ast.AlterTableDropColumn:
+			err = checker.dropColumn(spec.OldColumnName.Name)
+			if err == nil {
+				job, err = d.buildDropColumnJob(ident, spec.OldColumnName.Name)
+			}
+		case ast.AlterTableAddConstraint:
+			var unique bool
+			switch spec.Constraint.Tp {
+			case ast.ConstraintKey, ast.ConstraintIndex:
+			case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
+				unique = true
+			default:
+				return errRunMultiSchemaChanges.Gen("can't run multi schema change with the constraint %v",
+					spec.Constraint.Tp)
+			}
+			for _, key := range spec.Constraint.Keys {
+				if key.Expr != nil {
+					for _, name := range exprColumnNames(key.Expr) {
+						checker.addUsedColumn(name)
+					}
+					continue
+				}
+				checker.addUsedColumn(key.Column.Name)
+			}
+			job, err = d.buildCreateIndexJob(ctx, ident, unique, model.NewCIStr(spec.Constraint.Name),
+				spec.Constraint.Keys)
+			if err == nil {
+				// The anonymous index is named when the job is built.
+				err = checker.addIndex(job.Args[1].(model.CIStr))
+			}
+		case ast.AlterTableDropIndex:
+			indexName := model.NewCIStr(spec.Name)
+			err = checker.addIndex(indexName)
+			if err == nil {
+				job, err = d.buildDropIndexJob(ident, indexName)
+			}
+		case ast.AlterTableIndexVisibility:
+			indexName := model.NewCIStr(spec.Name)
+			err = checker.addIndex(indexName)
+			if err == nil {
+				job, err = d.buildAlterIndexVisibilityJob(ident, indexName, spec.Visibility)
+			}
+		case ast.AlterTableModifyColumn:
+			err = checker.modifyColumn(spec.NewColumn.Name.Name, spec.NewColumn.Name.Name)
+			if err == nil {
+				job, err = d.buildModifyColumnJob(ctx, ident, spec)
+			}
+		case ast.AlterTableChangeColumn:
+			err = checker.modifyColumn(spec.OldColumnName.Name, spec.NewColumn.Name.Name)
+			if err == nil {
+				job, err = d.buildChangeColumnJob(ctx, ident, spec)
+			}
+		case ast.AlterTableAlterColumn:
+			err = checker.modifyColumn(spec.NewColumn.Name.Name, spec.NewColumn.Name.Name)
+			if err == nil {
+				job, err = d.buildAlterColumnJob(ctx, ident, spec)
+			}
+		default:
+			return errRunMultiSchemaChanges.Gen("can't run multi schema change with the specification %v", spec.Tp)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		switch job.Type {
+		case model.ActionDropColumn, model.ActionDropIndex:
+			drops = append(drops, job)
+		case model.ActionModifyColumn:
+			if isReorgJob(job) {
+				typeChanges = append(typeChanges, job)
+				break
+			}
+			others = append(others, job)
+			otherReverts = append(otherReverts, buildRevertJob(t, job, ctx.GetSessionVars().StrictSQLMode))
+		default:
+			others = append(others, job)
+			otherReverts = append(otherReverts, buildRevertJob(t, job, ctx.GetSessionVars().StrictSQLMode))
+		}
+	}
+	if err = checker.check(len(t.Cols())); err != nil {
+		return errors.Trace(err)
+	}
+	if len(typeChanges) > 1 {
+		return errRunMultiSchemaChanges.Gen("can't change the types of multiple columns at the same time")
+	}
+
+	subJobs := append(append(others, typeChanges...), drops...)
+	revertJobs := make([]*model.Job, len(subJobs))
+	copy(revertJobs, otherReverts)
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    t.Meta().ID,
+		Type:       model.ActionMultiSchemaChange,
+		BinlogInfo: &model.HistoryInfo{},
+		MultiSchemaInfo: &model.MultiSchemaInfo{
+			SubJobs:    subJobs,
+			RevertJobs: revertJobs,
+			Revertible: true,
+		},
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// buildRevertJob builds the job that reverts the changes of the sub-job, which is added to the multi-schema change
+// job. It returns nil if the sub-job can't be reverted.
+func buildRevertJob(t table.Table, job *model.Job, strict bool) *model.Job {
+	revertJob := &model.Job{
+		SchemaID: job.SchemaID,
+		TableID:  job.TableID,
+	}
+	switch job.Type {
+	case model.ActionAddColumn:
+		col := job.Args[0].(*table.Column)
+		revertJob.Type = model.ActionDropColumn
+		revertJob.Args = []interface{}{col.Name}
+	case model.ActionAddIndex:
+		revertJob.Type = model.ActionDropIndex
+		revertJob.Args = []interface{}{job.Args[1]}
+	case model.ActionModifyColumn:
+		newCol := *job.Args[0].(**table.Column)
+		oldColName := job.Args[1].(model.CIStr)
+		oldCol := table.FindCol(t.Cols(), oldColName.L)
+		revertJob.Type = model.ActionModifyColumn
+		revertJob.Args = []interface{}{oldCol.ToInfo().Clone(), newCol.Name, false, strict}
+	case model.ActionSetDefaultValue:
+		col := job.Args[0].(*table.Column)
+		oldCol := table.FindCol(t.Cols(), col.Name.L)
+		revertJob.Type = model.ActionSetDefaultValue
+		revertJob.Args = []interface{}{oldCol.ToInfo().Clone()}
+	case model.ActionAlterIndexVisibility:
+		indexName := job.Args[0].(model.CIStr)
+		indexInfo := findIndexByName(indexName.L, t.Meta().Indices)
+		revertJob.Type = model.ActionAlterIndexVisibility
+		revertJob.Args = []interface{}{indexName, indexInfo.Invisible}
+	default:
+		return nil
+	}
+	return revertJob
+}
+
+// multiSchemaChecker checks whether the specifications of an ALTER TABLE statement conflict with each other.
+type multiSchemaChecker struct {
+	// operatedColumns are the columns which are added, dropped or modified.
+	operatedColumns map[string]struct{}
+	// changedColumns are the existing columns which are dropped or modified.
+	changedColumns map[string]struct{}
+	// usedColumns are the columns which are used by the added indices and the positions of the added columns.
+	usedColumns   []model.CIStr
+	operatedIndex map[string]struct{}
+	addedCount    int
+	droppedCount  int
+}
+
+func newMultiSchemaChecker() *multiSchemaChecker {
+	return &multiSchemaChecker{
+		operatedColumns: make(map[string]struct{}),
+		changedColumns:  make(map[string]struct{}),
+		operatedIndex:   make(map[string]struct{}),
+	}
+}
+
+func (c *multiSchemaChecker) operateColumn(name model.CIStr) error {
+	if _, ok := c.operatedColumns[name.L]; ok {
+		return errOperateSameColumn.Gen("can't operate the column %s more than once", name)
+	}
+	c.operatedColumns[name.L] = struct{}{}
+	return nil
+}
+
+func (c *multiSchemaChecker) addColumn(name model.CIStr) error {
+	c.addedCount++
+	return errors.Trace(c.operateColumn(name))
+}
+
+func (c *multiSchemaChecker) dropColumn(name model.CIStr) error {
+	c.droppedCount++
+	c.changedColumns[name.L] = struct{}{}
+	return errors.Trace(c.operateColumn(name))
+}
+
+func (c *multiSchemaChecker) modifyColumn(oldName, newName model.CIStr) error {
+	c.changedColumns[oldName.L] = struct{}{}
+	if err := c.operateColumn(oldName); err != nil {
+		return errors.Trace(err)
+	}
+	if oldName.L == newName.L {
+		return nil
+	}
+	return errors.Trace(c.operateColumn(newName))
+}
+
+func (c *multiSchemaChecker) addUsedColumn(name model.CIStr) {
+	c.usedColumns = append(c.usedColumns, name)
+}
+
+func (c *multiSchemaChecker) addIndex(name model.CIStr) error {
+	if _, ok := c.operatedIndex[name.L]; ok {
+		return errOperateSameIndex.Gen("can't operate the index %s more than once", name)
+	}
+	c.operatedIndex[name.L] = struct{}{}
+	return nil
+}
+
+// check checks the columns used by the other specifications aren't dropped or modified, and there are columns left.
+func (c *multiSchemaChecker) check(columnCount int) error {
+	for _, name := range c.usedColumns {
+		if _, ok := c.changedColumns[name.L]; ok {
+			return errOperateSameColumn.Gen("can't use the column %s which is dropped or modified", name)
+		}
+	}
+	if columnCount+c.addedCount <= c.droppedCount {
+		return ErrCantRemoveAllFields.Gen("can't drop all columns in table")
+	}
+	return nil
+}
+
+// ModifyTableOption changes the table option. ALTER TABLE ... AUTO_INCREMENT = n rebases the auto ID of the table
+// without allocating the IDs, the value less than the current auto ID is ignored. The other table options which aren't
+// supported are ignored.
+func (d *ddl) ModifyTableOption(ctx context.Context, ident ast.Ident, option *ast.TableOption) error {
+	job, err := d.buildTableOptionJob(ident, option)
+	if err != nil || job == nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// buildTableOptionJob builds the job to change the table option, it returns nil if the option is ignored.
+func (d *ddl) buildTableOptionJob(ident ast.Ident, option *ast.TableOption) (*model.Job, error) {
+	var tp model.ActionType
+	var name string
+	switch option.Tp {
+	case ast.TableOptionAutoIncrement:
+		tp, name = model.ActionRebaseAutoID, "AUTO_INCREMENT"
+	case ast.TableOptionAutoIDCache:
+		tp, name = model.ActionModifyTableAutoIDCache, "AUTO_ID_CACHE"
+	default:
+		return nil, nil
+	}
+	if option.UintValue > math.MaxInt64 {
+		return nil, errWrongValue.GenByArgs(name, option.UintValue)
+	}
+
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    t.Meta().ID,
+		Type:       tp,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{int64(option.UintValue)},
+	}
+	return job, nil
+}
+
+// AlterTableTTL changes the TTL info of the table by the TTL options, the other options are ignored.
+func (d *ddl) AlterTableTTL(ctx context.Context, ident ast.Ident, options []*ast.TableOption) error {
+	job, err := d.buildTTLJob(ident, options)
+	if err != nil || job == nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// buildTTLJob builds the job to change the TTL info of the table, it returns nil if there are no TTL options.
+func (d *ddl) buildTTLJob(ident ast.Ident, options []*ast.TableOption) (*model.Job, error) {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	tblInfo := t.Meta().Clone()
+	if err = handleTTLOptions(options, tblInfo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tblInfo.TTLInfo == t.Meta().TTLInfo || (tblInfo.TTLInfo != nil && t.Meta().TTLInfo != nil &&
+		*tblInfo.TTLInfo == *t.Meta().TTLInfo) {
+		return nil, nil
+	}
+
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    tblInfo.ID,
+		Type:       model.ActionAlterTTLInfo,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{tblInfo.TTLInfo},
+	}
+	return job, nil
+}
+
+// RemoveTTL removes the TTL info of the table, so the rows never expire.
+func (d *ddl) RemoveTTL(ctx context.Context, ident ast.Ident) error {
+	job, err := d.buildRemoveTTLJob(ident)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) buildRemoveTTLJob(ident ast.Ident) (*model.Job, error) {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    t.Meta().ID,
+		Type:       model.ActionRemoveTTL,
+		BinlogInfo: &model.HistoryInfo{},
+	}
+	return job, nil
+}
+
+// AlterTableOptions changes the comment, the default charset and collation, and the storage options of the table.
+// The charset and collation of the existing columns aren't changed.
+func (d *ddl) AlterTableOptions(ctx context.Context, ident ast.Ident, options []*ast.TableOption) error {
+	job, err := d.buildTableOptionsJob(ident, options)
+	if err != nil || job == nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// buildTableOptionsJob builds the job to change the table options, it returns nil if nothing is changed.
+func (d *ddl) buildTableOptionsJob(ident ast.Ident, options []*ast.TableOption) (*model.Job, error) {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	origin := t.Meta()
+	tblInfo := origin.Clone()
+	if err = setTableOptions(tblInfo, options); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tblInfo.Comment == origin.Comment && tblInfo.Charset == origin.Charset && tblInfo.Collate == origin.Collate &&
+		(tblInfo.StorageOptions == origin.StorageOptions || (tblInfo.StorageOptions != nil &&
+			origin.StorageOptions != nil && *tblInfo.StorageOptions == *origin.StorageOptions)) {
+		return nil, nil
+	}
+
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    tblInfo.ID,
+		Type:       model.ActionModifyTableOptions,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{tblInfo.Comment, tblInfo.Charset, tblInfo.Collate, tblInfo.StorageOptions},
+	}
+	return job, nil
+}
+
+func checkColumnConstraint(constraints []*ast.ColumnOption) error {
+	for _, constraint := range constraints {
+		switch constraint.Tp {
+		case ast.ColumnOptionAutoIncrement, ast.ColumnOptionPrimaryKey, ast.ColumnOptionUniq, ast.ColumnOptionUniqKey,
+			ast.ColumnOptionAutoRandom:
+			return errUnsupportedAddColumn.Gen("unsupported add column constraint - %v", constraint.Tp)
+		}
+	}
+
+	return nil
+}
+
+// AddColumn will add a new column to the table.
+func (d *ddl) AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableSpec) error {
+	job, err := d.buildAddColumnJob(ctx, ti, spec)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) buildAddColumnJob(ctx context.Context, ti ast.Ident, spec *ast.AlterTableSpec) (*model.Job, error) {
+	// Check whether the added column constraints are supported.
+	err := checkColumnConstraint(spec.NewColumn.Options)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ti.Schema)
+	if !ok {
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ti.Schema, ti.Name)
+	if err != nil {
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	// Check whether added column has existed.
+	colName := spec.NewColumn.Name.Name.O
+	col := table.FindCol(t.Cols(), colName)
+	if col != nil {
+		return nil, infoschema.ErrColumnExists.GenByArgs(colName)
+	}
+
+	if len(colName) > mysql.MaxColumnNameLength {
+		return nil, ErrTooLongIdent.Gen("too long column %s", colName)
+	}
+
+	// Ingore table constraints now, maybe return error later.
+	// We use length(t.Cols()) as the default offset firstly, we will change the
+	// column's offset later.
+	setColumnCharsetByTable(spec.NewColumn.Tp, t.Meta())
+	col, _, err = buildColumnAndConstraint(ctx, len(t.Cols()), spec.NewColumn)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	col.OriginDefaultValue = col.DefaultValue
+	if col.DefaultIsExpr {
+		// The expression default value is evaluated once for the existing rows.
+		value, err1 := table.GetColDefaultValue(ctx, col.ToInfo())
+		if err1 != nil {
+			return nil, errors.Trace(err1)
+		}
+		col.OriginDefaultValue = nil
+		if !value.IsNull() {
+			col.OriginDefaultValue, err = value.ToString()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	if col.OriginDefaultValue == nil && mysql.HasNotNullFlag(col.Flag) {
+		zeroVal := table.GetZeroValue(col.ToInfo())
+		col.OriginDefaultValue, err = zeroVal.ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	if col.OriginDefaultValue == expression.CurrentTimestamp &&
+		(col.Tp == mysql.TypeTimestamp || col.Tp == mysql.TypeDatetime) {
+		col.OriginDefaultValue = time.Now().Format(types.TimeFormat)
+	}
+
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    t.Meta().ID,
+		Type:       model.ActionAddColumn,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{col, spec.Position, 0},
+	}
+	return job, nil
+}
+
+// DropColumn will drop a column from the table, now we don't support drop the column with index covered.
 func (d *ddl) DropColumn(ctx context.Context, ti ast.Ident, colName model.CIStr) error {
+	job, err := d.buildDropColumnJob(ti, colName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) buildDropColumnJob(ti ast.Ident, colName model.CIStr) (*model.Job, error) {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ti.Schema)
 	if !ok {
-		return errors.Trace(infoschema.ErrDatabaseNotExists)
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
 	}
 	t, err := is.TableByName(ti.Schema, ti.Name)
 	if err != nil {
-		return errors.Trace(infoschema.ErrTableNotExists)
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
 	}
 
 	// Check whether dropped column has existed.
 	col := table.FindCol(t.Cols(), colName.L)
 	if col == nil {
-		return ErrCantDropFieldOrKey.Gen("column %s doesn't exist", colName)
+		return nil, ErrCantDropFieldOrKey.Gen("column %s doesn't exist", colName)
 	}
 
 	tblInfo := t.Meta()
 	// We don't support dropping column with index covered now.
 	// We must drop the index first, then drop the column.
 	if isColumnWithIndex(colName.L, tblInfo.Indices) {
-		return errCantDropColWithIndex.Gen("can't drop column %s with index covered now", colName)
+		return nil, errCantDropColWithIndex.Gen("can't drop column %s with index covered now", colName)
+	}
+	inExprIndex, err := isColumnInExpressionIndex(tblInfo, colName.L)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if inExprIndex {
+		return nil, errCannotDropColumnFunctionalIndex.GenByArgs(colName)
 	}
 	// We don't support dropping column with PK handle covered now.
 	if col.IsPKHandleColumn(tblInfo) {
-		return errUnsupportedPKHandle
+		return nil, errUnsupportedPKHandle
+	}
+	if tblInfo.TTLInfo != nil && tblInfo.TTLInfo.ColumnName.L == colName.L {
+		return nil, errInvalidTTL.GenByArgs(fmt.Sprintf("can't drop the TTL column %s", colName))
 	}
 
 	job := &model.Job{
@@ -886,10 +1840,7 @@ func (d *ddl) DropColumn(ctx context.Context, ti ast.Ident, colName model.CIStr)
 		BinlogInfo: &model.HistoryInfo{},
 		Args:       []interface{}{colName},
 	}
-
-	err = d.doDDLJob(ctx, job)
-	err = d.callHookOnChanged(err)
-	return errors.Trace(err)
+	return job, nil
 }
 
 // modifiable checks if the 'origin' type can be modified to 'to' type with out the need to
@@ -936,11 +1887,15 @@ func modifiable(origin *types.FieldType, to *types.FieldType) bool {
 }
 
 func setDefaultValue(ctx context.Context, col *table.Column, option *ast.ColumnOption) error {
-	value, err := getDefaultValue(ctx, option, col.Tp, col.Decimal)
+	value, isExpr, err := getDefaultValue(ctx, option, col.Tp, col.Decimal)
 	if err != nil {
 		return ErrColumnBadNull.Gen("invalid default value - %s", err)
 	}
+	if isExpr && mysql.HasAutoIncrementFlag(col.Flag) {
+		return errInvalidDefault.GenByArgs(col.Name)
+	}
 	col.DefaultValue = value
+	col.DefaultIsExpr = isExpr
 	return errors.Trace(checkDefaultValue(ctx, col, true))
 }
 
@@ -962,11 +1917,12 @@ func setDefaultAndComment(ctx context.Context, col *table.Column, options []*ast
 	for _, opt := range options {
 		switch opt.Tp {
 		case ast.ColumnOptionDefaultValue:
-			value, err := getDefaultValue(ctx, opt, col.Tp, col.Decimal)
+			value, isExpr, err := getDefaultValue(ctx, opt, col.Tp, col.Decimal)
 			if err != nil {
 				return ErrColumnBadNull.Gen("invalid default value - %s", err)
 			}
 			col.DefaultValue = value
+			col.DefaultIsExpr = isExpr
 			hasDefaultValue = true
 		case ast.ColumnOptionComment:
 			err := setColumnComment(ctx, col, opt)
@@ -1028,9 +1984,14 @@ func (d *ddl) getModifiableColumnJob(ctx context.Context, ident ast.Ident, origi
 		OriginDefaultValue: col.OriginDefaultValue,
 		FieldType:          *spec.NewColumn.Tp,
 	}
+	setColumnCharsetByTable(&newCol.FieldType, t.Meta())
 	setCharsetCollationFlenDecimal(&newCol.FieldType)
-	if !modifiable(&col.FieldType, &newCol.FieldType) {
-		return nil, errors.Trace(errUnsupportedModifyColumn)
+	// The existing values are converted to the new type in the reorganization if the type can't be modified directly.
+	needReorg := !modifiable(&col.FieldType, &newCol.FieldType)
+	if needReorg {
+		if err = checkColumnTypeChange(t.Meta(), col); err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	if err := setDefaultAndComment(ctx, newCol, spec.NewColumn.Options); err != nil {
 		return nil, errors.Trace(err)
@@ -1041,34 +2002,89 @@ func (d *ddl) getModifiableColumnJob(ctx context.Context, ident ast.Ident, origi
 	}
 
 	newCol.Name = spec.NewColumn.Name.Name
+	if err = checkTTLColumnChange(t.Meta(), col, newCol); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = checkExpressionIndexColumnChange(t.Meta(), col, newCol, needReorg); err != nil {
+		return nil, errors.Trace(err)
+	}
 	job := &model.Job{
 		SchemaID:   schema.ID,
 		TableID:    t.Meta().ID,
 		Type:       model.ActionModifyColumn,
 		BinlogInfo: &model.HistoryInfo{},
-		Args:       []interface{}{&newCol, originalColName},
+		Args:       []interface{}{&newCol, originalColName, needReorg, ctx.GetSessionVars().StrictSQLMode},
 	}
 	return job, nil
 }
 
+// checkColumnTypeChange checks whether the type of the column can be changed by converting the existing values.
+func checkColumnTypeChange(tblInfo *model.TableInfo, col *table.Column) error {
+	if col.IsPKHandleColumn(tblInfo) || mysql.HasAutoIncrementFlag(col.Flag) {
+		return errors.Trace(errUnsupportedModifyColumn)
+	}
+	for _, idx := range tblInfo.Indices {
+		for _, ic := range idx.Columns {
+			// The prefix length of the index may not fit the new type.
+			if ic.Name.L == col.Name.L && ic.Length != types.UnspecifiedLength {
+				return errors.Trace(errUnsupportedModifyColumn)
+			}
+		}
+	}
+	return nil
+}
+
+// checkExpressionIndexColumnChange checks whether the column which the expression indices depend on can be changed.
+// The column can't be renamed, and its values can't be converted since the indices aren't rebuilt.
+func checkExpressionIndexColumnChange(tblInfo *model.TableInfo, col, newCol *table.Column, needReorg bool) error {
+	inExprIndex, err := isColumnInExpressionIndex(tblInfo, col.Name.L)
+	if err != nil || !inExprIndex {
+		return errors.Trace(err)
+	}
+	if col.Name.L != newCol.Name.L {
+		return errDependentByFunctionalIndex.GenByArgs(col.Name)
+	}
+	if needReorg {
+		return errors.Trace(errUnsupportedModifyColumn)
+	}
+	return nil
+}
+
 // ChangeColumn renames an existing column and modifies the column's definition,
-// currently we only support limited kind of changes
-// that do not need to change or check data on the table.
+// the existing data is converted in the reorganization if the new type can't hold it directly.
 func (d *ddl) ChangeColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
+	job, err := d.buildChangeColumnJob(ctx, ident, spec)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) buildChangeColumnJob(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) (*model.Job, error) {
 	if len(spec.NewColumn.Name.Schema.O) != 0 && ident.Schema.L != spec.NewColumn.Name.Schema.L {
-		return errWrongDBName.GenByArgs(spec.NewColumn.Name.Schema.O)
+		return nil, errWrongDBName.GenByArgs(spec.NewColumn.Name.Schema.O)
 	}
 	if len(spec.OldColumnName.Schema.O) != 0 && ident.Schema.L != spec.OldColumnName.Schema.L {
-		return errWrongDBName.GenByArgs(spec.OldColumnName.Schema.O)
+		return nil, errWrongDBName.GenByArgs(spec.OldColumnName.Schema.O)
 	}
 	if len(spec.NewColumn.Name.Table.O) != 0 && ident.Name.L != spec.NewColumn.Name.Table.L {
-		return errWrongTableName.GenByArgs(spec.NewColumn.Name.Table.O)
+		return nil, errWrongTableName.GenByArgs(spec.NewColumn.Name.Table.O)
 	}
 	if len(spec.OldColumnName.Table.O) != 0 && ident.Name.L != spec.OldColumnName.Table.L {
-		return errWrongTableName.GenByArgs(spec.OldColumnName.Table.O)
+		return nil, errWrongTableName.GenByArgs(spec.OldColumnName.Table.O)
 	}
 
 	job, err := d.getModifiableColumnJob(ctx, ident, spec.OldColumnName.Name, spec)
+	return job, errors.Trace(err)
+}
+
+// ModifyColumn does modification on an existing column,
+// the existing data is converted in the reorganization if the new type can't hold it directly.
+func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
+	job, err := d.buildModifyColumnJob(ctx, ident, spec)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1078,18 +2094,21 @@ func (d *ddl) ChangeColumn(ctx context.Context, ident ast.Ident, spec *ast.Alter
 	return errors.Trace(err)
 }
 
-// ModifyColumn does modification on an existing column, currently we only support limited kind of changes
-// that do not need to change or check data on the table.
-func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
+func (d *ddl) buildModifyColumnJob(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) (*model.Job, error) {
 	if len(spec.NewColumn.Name.Schema.O) != 0 && ident.Schema.L != spec.NewColumn.Name.Schema.L {
-		return errWrongDBName.GenByArgs(spec.NewColumn.Name.Schema.O)
+		return nil, errWrongDBName.GenByArgs(spec.NewColumn.Name.Schema.O)
 	}
 	if len(spec.NewColumn.Name.Table.O) != 0 && ident.Name.L != spec.NewColumn.Name.Table.L {
-		return errWrongTableName.GenByArgs(spec.NewColumn.Name.Table.O)
+		return nil, errWrongTableName.GenByArgs(spec.NewColumn.Name.Table.O)
 	}
 
 	originalColName := spec.NewColumn.Name.Name
 	job, err := d.getModifiableColumnJob(ctx, ident, originalColName, spec)
+	return job, errors.Trace(err)
+}
+
+func (d *ddl) AlterColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
+	job, err := d.buildAlterColumnJob(ctx, ident, spec)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1099,30 +2118,33 @@ func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.Alter
 	return errors.Trace(err)
 }
 
-func (d *ddl) AlterColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
+func (d *ddl) buildAlterColumnJob(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) (*model.Job, error) {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ident.Schema)
 	if !ok {
-		return infoschema.ErrTableNotExists.GenByArgs(ident.Schema, ident.Name)
+		return nil, infoschema.ErrTableNotExists.GenByArgs(ident.Schema, ident.Name)
 	}
 	t, err := is.TableByName(ident.Schema, ident.Name)
 	if err != nil {
-		return infoschema.ErrTableNotExists.GenByArgs(ident.Schema, ident.Name)
+		return nil, infoschema.ErrTableNotExists.GenByArgs(ident.Schema, ident.Name)
 	}
 
 	colName := spec.NewColumn.Name.Name
 	// Check whether alter column has existed.
 	col := table.FindCol(t.Cols(), colName.L)
 	if col == nil {
-		return errBadField.GenByArgs(colName, ident.Name)
+		return nil, errBadField.GenByArgs(colName, ident.Name)
 	}
+	// The column is shared by the information schema, change its copy.
+	col = (*table.Column)(col.ToInfo().Clone())
 
 	if len(spec.NewColumn.Options) == 0 {
 		col.DefaultValue = nil
+		col.DefaultIsExpr = false
 	} else {
 		err := setDefaultValue(ctx, col, spec.NewColumn.Options[0])
 		if err != nil {
-			return errors.Trace(err)
+			return nil, errors.Trace(err)
 		}
 	}
 
@@ -1133,10 +2155,7 @@ func (d *ddl) AlterColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterT
 		BinlogInfo: &model.HistoryInfo{},
 		Args:       []interface{}{col},
 	}
-
-	err = d.doDDLJob(ctx, job)
-	err = d.callHookOnChanged(err)
-	return errors.Trace(err)
+	return job, nil
 }
 
 // DropTable will proceed even if some table in the list does not exists.
@@ -1164,6 +2183,35 @@ func (d *ddl) DropTable(ctx context.Context, ti ast.Ident) (err error) {
 	return errors.Trace(err)
 }
 
+func (d *ddl) RecoverTable(ctx context.Context, schemaID int64, tblInfo *model.TableInfo, autoID int64,
+	snapshotTS uint64) error {
+	is := d.GetInformationSchema()
+	schema, ok := is.SchemaByID(schemaID)
+	if !ok {
+		return errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	if tbl, ok := is.TableByID(tblInfo.ID); ok {
+		return errTableRecovered.GenByArgs(tbl.Meta().Name)
+	}
+	if is.TableExists(schema.Name, tblInfo.Name) {
+		return infoschema.ErrTableExists.GenByArgs(ast.Ident{Schema: schema.Name, Name: tblInfo.Name})
+	}
+	if err := checkTooLongTable(tblInfo.Name); err != nil {
+		return errors.Trace(err)
+	}
+
+	job := &model.Job{
+		SchemaID:   schemaID,
+		TableID:    tblInfo.ID,
+		Type:       model.ActionRecoverTable,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{tblInfo, autoID, snapshotTS},
+	}
+	err := d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
 func (d *ddl) TruncateTable(ctx context.Context, ti ast.Ident) error {
 	is := d.GetInformationSchema()
 	schema, ok := is.SchemaByName(ti.Schema)
@@ -1192,28 +2240,17 @@ func (d *ddl) TruncateTable(ctx context.Context, ti ast.Ident) error {
 
 func (d *ddl) RenameTable(ctx context.Context, oldIdent, newIdent ast.Ident) error {
 	is := d.GetInformationSchema()
-	oldSchema, ok := is.SchemaByName(oldIdent.Schema)
-	if !ok {
-		return errFileNotFound.GenByArgs(oldIdent.Schema, oldIdent.Name)
-	}
-	oldTbl, err := is.TableByName(oldIdent.Schema, oldIdent.Name)
+	oldSchemaID, newSchemaID, tableID, err := checkRenameTable(is, oldIdent, newIdent, make(map[string]int64))
 	if err != nil {
-		return errFileNotFound.GenByArgs(oldIdent.Schema, oldIdent.Name)
-	}
-	newSchema, ok := is.SchemaByName(newIdent.Schema)
-	if !ok {
-		return errErrorOnRename.GenByArgs(oldIdent.Schema, oldIdent.Name, newIdent.Schema, newIdent.Name)
-	}
-	if is.TableExists(newIdent.Schema, newIdent.Name) {
-		return infoschema.ErrTableExists.GenByArgs(newIdent)
+		return errors.Trace(err)
 	}
 
 	job := &model.Job{
-		SchemaID:   newSchema.ID,
-		TableID:    oldTbl.Meta().ID,
+		SchemaID:   newSchemaID,
+		TableID:    tableID,
 		Type:       model.ActionRenameTable,
 		BinlogInfo: &model.HistoryInfo{},
-		Args:       []interface{}{oldSchema.ID, newIdent.Name},
+		Args:       []interface{}{oldSchemaID, newIdent.Name},
 	}
 
 	err = d.doDDLJob(ctx, job)
@@ -1221,6 +2258,73 @@ func (d *ddl) RenameTable(ctx context.Context, oldIdent, newIdent ast.Ident) err
 	return errors.Trace(err)
 }
 
+func (d *ddl) RenameTables(ctx context.Context, oldIdents, newIdents []ast.Ident) error {
+	is := d.GetInformationSchema()
+	oldSchemaIDs := make([]int64, 0, len(oldIdents))
+	newSchemaIDs := make([]int64, 0, len(oldIdents))
+	tableNames := make([]model.CIStr, 0, len(oldIdents))
+	tableIDs := make([]int64, 0, len(oldIdents))
+	tables := make(map[string]int64)
+	for i, oldIdent := range oldIdents {
+		oldSchemaID, newSchemaID, tableID, err := checkRenameTable(is, oldIdent, newIdents[i], tables)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		oldSchemaIDs = append(oldSchemaIDs, oldSchemaID)
+		newSchemaIDs = append(newSchemaIDs, newSchemaID)
+		tableNames = append(tableNames, newIdents[i].Name)
+		tableIDs = append(tableIDs, tableID)
+	}
+
+	job := &model.Job{
+		SchemaID:   newSchemaIDs[0],
+		TableID:    tableIDs[0],
+		Type:       model.ActionRenameTables,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{oldSchemaIDs, newSchemaIDs, tableNames, tableIDs},
+	}
+
+	err := d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// checkRenameTable checks whether the old table can be renamed to the new table. The tables map records the table
+// IDs of the table names changed by the preceding renames in the same statement, zero for the names renamed away.
+// The tables map is updated by the rename if it's valid.
+func checkRenameTable(is infoschema.InfoSchema, oldIdent, newIdent ast.Ident, tables map[string]int64) (
+	oldSchemaID, newSchemaID, tableID int64, err error) {
+	oldSchema, ok := is.SchemaByName(oldIdent.Schema)
+	if !ok {
+		return 0, 0, 0, errFileNotFound.GenByArgs(oldIdent.Schema, oldIdent.Name)
+	}
+	oldKey := oldIdent.Schema.L + "." + oldIdent.Name.L
+	if id, ok := tables[oldKey]; ok {
+		if id == 0 {
+			return 0, 0, 0, errFileNotFound.GenByArgs(oldIdent.Schema, oldIdent.Name)
+		}
+		tableID = id
+	} else {
+		oldTbl, err := is.TableByName(oldIdent.Schema, oldIdent.Name)
+		if err != nil {
+			return 0, 0, 0, errFileNotFound.GenByArgs(oldIdent.Schema, oldIdent.Name)
+		}
+		tableID = oldTbl.Meta().ID
+	}
+	newSchema, ok := is.SchemaByName(newIdent.Schema)
+	if !ok {
+		return 0, 0, 0, errErrorOnRename.GenByArgs(oldIdent.Schema, oldIdent.Name, newIdent.Schema, newIdent.Name)
+	}
+	newKey := newIdent.Schema.L + "." + newIdent.Name.L
+	if id, ok := tables[newKey]; (ok && id != 0) || (!ok && is.TableExists(newIdent.Schema, newIdent.Name)) {
+		return 0, 0, 0, infoschema.ErrTableExists.GenByArgs(newIdent)
+	}
+
+	tables[oldKey] = 0
+	tables[newKey] = tableID
+	return oldSchema.ID, newSchema.ID, tableID, nil
+}
+
 func getAnonymousIndex(t table.Table, colName model.CIStr) model.CIStr {
 	id := 2
 	l := len(t.Indices())
@@ -1236,23 +2340,45 @@ func getAnonymousIndex(t table.Table, colName model.CIStr) model.CIStr {
 }
 
 func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexName model.CIStr, idxColNames []*ast.IndexColName) error {
+	job, err := d.buildCreateIndexJob(ctx, ti, unique, indexName, idxColNames)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) buildCreateIndexJob(ctx context.Context, ti ast.Ident, unique bool, indexName model.CIStr,
+	idxColNames []*ast.IndexColName) (*model.Job, error) {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ti.Schema)
 	if !ok {
-		return infoschema.ErrDatabaseNotExists.GenByArgs(ti.Schema)
+		return nil, infoschema.ErrDatabaseNotExists.GenByArgs(ti.Schema)
 	}
 	t, err := is.TableByName(ti.Schema, ti.Name)
 	if err != nil {
-		return errors.Trace(infoschema.ErrTableNotExists)
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
 	}
 
 	// Deal with anonymous index.
 	if len(indexName.L) == 0 {
-		indexName = getAnonymousIndex(t, idxColNames[0].Column.Name)
+		colName := model.NewCIStr(expressionIndexName)
+		if idxColNames[0].Column != nil {
+			colName = idxColNames[0].Column.Name
+		}
+		indexName = getAnonymousIndex(t, colName)
 	}
 
 	if indexInfo := findIndexByName(indexName.L, t.Meta().Indices); indexInfo != nil {
-		return errDupKeyName.Gen("index already exist %s", indexName)
+		return nil, errDupKeyName.Gen("index already exist %s", indexName)
+	}
+
+	// The expressions are replaced by the hidden columns, which are added with the index.
+	idxColNames, hiddenCols, err := buildHiddenColumns(ctx, t.Meta(), indexName, idxColNames)
+	if err != nil {
+		return nil, errors.Trace(err)
 	}
 
 	job := &model.Job{
@@ -1260,12 +2386,9 @@ func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexN
 		TableID:    t.Meta().ID,
 		Type:       model.ActionAddIndex,
 		BinlogInfo: &model.HistoryInfo{},
-		Args:       []interface{}{unique, indexName, idxColNames},
+		Args:       []interface{}{unique, indexName, idxColNames, hiddenCols},
 	}
-
-	err = d.doDDLJob(ctx, job)
-	err = d.callHookOnChanged(err)
-	return errors.Trace(err)
+	return job, nil
 }
 
 func buildFKInfo(fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef) (*model.FKInfo, error) {
@@ -1347,18 +2470,29 @@ func (d *ddl) DropForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIS
 }
 
 func (d *ddl) DropIndex(ctx context.Context, ti ast.Ident, indexName model.CIStr) error {
+	job, err := d.buildDropIndexJob(ti, indexName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) buildDropIndexJob(ti ast.Ident, indexName model.CIStr) (*model.Job, error) {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ti.Schema)
 	if !ok {
-		return errors.Trace(infoschema.ErrDatabaseNotExists)
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
 	}
 	t, err := is.TableByName(ti.Schema, ti.Name)
 	if err != nil {
-		return errors.Trace(infoschema.ErrTableNotExists)
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
 	}
 
 	if indexInfo := findIndexByName(indexName.L, t.Meta().Indices); indexInfo == nil {
-		return ErrCantDropFieldOrKey.Gen("index %s doesn't exist", indexName)
+		return nil, ErrCantDropFieldOrKey.Gen("index %s doesn't exist", indexName)
 	}
 
 	job := &model.Job{
@@ -1368,12 +2502,54 @@ func (d *ddl) DropIndex(ctx context.Context, ti ast.Ident, indexName model.CIStr
 		BinlogInfo: &model.HistoryInfo{},
 		Args:       []interface{}{indexName},
 	}
+	return job, nil
+}
+
+// AlterIndexVisibility makes the index visible or invisible to the planner. An invisible index is still maintained,
+// so it can be made visible again without rebuilding it.
+func (d *ddl) AlterIndexVisibility(ctx context.Context, ident ast.Ident, indexName model.CIStr,
+	visibility ast.IndexVisibility) error {
+	job, err := d.buildAlterIndexVisibilityJob(ident, indexName, visibility)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
 	err = d.doDDLJob(ctx, job)
 	err = d.callHookOnChanged(err)
 	return errors.Trace(err)
 }
 
+func (d *ddl) buildAlterIndexVisibilityJob(ti ast.Ident, indexName model.CIStr,
+	visibility ast.IndexVisibility) (*model.Job, error) {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ti.Schema)
+	if !ok {
+		return nil, errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ti.Schema, ti.Name)
+	if err != nil {
+		return nil, errors.Trace(infoschema.ErrTableNotExists)
+	}
+
+	indexInfo := findIndexByName(indexName.L, t.Meta().Indices)
+	if indexInfo == nil {
+		return nil, errKeyDoesNotExist.GenByArgs(indexName, ti.Name)
+	}
+	invisible := visibility == ast.IndexVisibilityInvisible
+	if invisible && indexInfo.Primary {
+		return nil, errors.Trace(errPKIndexCantBeInvisible)
+	}
+
+	job := &model.Job{
+		SchemaID:   schema.ID,
+		TableID:    t.Meta().ID,
+		Type:       model.ActionAlterIndexVisibility,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{indexInfo.Name, invisible},
+	}
+	return job, nil
+}
+
 // findCol finds column in cols by name.
 func findCol(cols []*model.ColumnInfo, name string) *model.ColumnInfo {
 	name = strings.ToLower(name)
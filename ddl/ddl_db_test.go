@@ -163,6 +163,7 @@ func (s *testDBSuite) TestIndex(c *C) {
 	s.testDropIndex(c)
 	s.testAddUniqueIndexRollback(c)
 	s.testAddIndexWithDupCols(c)
+	s.testAddIndexWithNegativeHandle(c)
 }
 
 func (s *testDBSuite) testGetTable(c *C, name string) table.Table {
@@ -409,6 +410,16 @@ LOOP:
 	c.Assert(handles, HasLen, 0)
 }
 
+func (s *testDBSuite) testAddIndexWithNegativeHandle(c *C) {
+	s.tk.MustExec("drop table if exists t_neg")
+	s.tk.MustExec("create table t_neg (a int primary key, b int)")
+	s.tk.MustExec("insert t_neg values (-3, 1), (-1, 2), (0, 3), (2, 4)")
+	s.tk.MustExec("alter table t_neg add index idx_b (b)")
+	s.tk.MustQuery("select a from t_neg use index (idx_b) where b < 3 order by a").Check(testkit.Rows("-3", "-1"))
+	s.tk.MustExec("admin check table t_neg")
+	s.tk.MustExec("drop table t_neg")
+}
+
 func (s *testDBSuite) testDropIndex(c *C) {
 	done := make(chan error, 1)
 	s.mustExec(c, "delete from t1")
@@ -789,6 +800,73 @@ func (s *testDBSuite) TestChangeColumn(c *C) {
 	s.testErrorCode(c, sql, tmysql.ErrUnknown)
 }
 
+func (s *testDBSuite) TestModifyColumnType(c *C) {
+	defer testleak.AfterTest(c)()
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use " + s.schemaName)
+
+	// The values of the string columns are returned as bytes.
+	bytesRows := func(vals ...string) [][]interface{} {
+		rows := make([]string, 0, len(vals))
+		for _, v := range vals {
+			rows = append(rows, fmt.Sprintf("%v", []byte(v)))
+		}
+		return testkit.Rows(rows...)
+	}
+	s.mustExec(c, "create table t_mct (a int, b int, c decimal(10,2), unique index idx_b(b))")
+	s.mustExec(c, "insert into t_mct values (1, 10, 1.5), (2, 200, 2.5)")
+	s.mustExec(c, "alter table t_mct modify b varchar(10)")
+	s.tk.MustQuery("select b from t_mct order by a").Check(bytesRows("10", "200"))
+	s.tk.MustQuery("select a from t_mct where b = '200'").Check(testkit.Rows("2"))
+	s.mustExec(c, "insert into t_mct values (3, 3000, 3.5)")
+	s.mustExec(c, "update t_mct set b = 'x' where a = 1")
+	s.tk.MustQuery("select a from t_mct where b = 'x'").Check(testkit.Rows("1"))
+	s.tk.MustExec("admin check table t_mct")
+
+	// The values that don't fit the new type fail the job in strict mode, and the column is unchanged.
+	_, err := s.tk.Exec("alter table t_mct modify b varchar(2)")
+	c.Assert(err, NotNil)
+	s.tk.MustQuery("select b from t_mct order by a").Check(bytesRows("x", "200", "3000"))
+	s.tk.MustExec("admin check table t_mct")
+	ctx := s.tk.Se.(context.Context)
+	dom := sessionctx.GetDomain(ctx)
+	// The schema isn't reloaded by the failed DDL.
+	c.Assert(dom.Reload(), IsNil)
+	tbl, err := dom.InfoSchema().TableByName(model.NewCIStr("test_db"), model.NewCIStr("t_mct"))
+	c.Assert(err, IsNil)
+	c.Assert(tbl.Meta().Columns, HasLen, 3)
+	c.Assert(tbl.Meta().Columns[1].Flen, Equals, 10)
+	c.Assert(tbl.Meta().Indices, HasLen, 1)
+
+	s.mustExec(c, "alter table t_mct modify c decimal(5,2)")
+	s.tk.MustQuery("select c from t_mct order by a").Check(testkit.Rows("1.50", "2.50", "3.50"))
+	// The values are truncated if the SQL mode isn't strict.
+	s.mustExec(c, "set sql_mode=''")
+	s.mustExec(c, "alter table t_mct modify b varchar(2)")
+	s.tk.MustQuery("select b from t_mct order by a").Check(bytesRows("x", "20", "30"))
+	s.tk.MustExec("admin check table t_mct")
+	s.mustExec(c, "set sql_mode='STRICT_TRANS_TABLES'")
+
+	// The converted values break the unique index.
+	s.mustExec(c, "update t_mct set b = '02' where a = 1")
+	s.mustExec(c, "insert into t_mct values (4, '2', 4.5)")
+	_, err = s.tk.Exec("alter table t_mct modify b int")
+	c.Assert(err, NotNil)
+	s.mustExec(c, "delete from t_mct where a = 4")
+	s.mustExec(c, "alter table t_mct modify b int")
+	s.tk.MustQuery("select a, b from t_mct order by b").Check(testkit.Rows("1 2", "2 20", "3 30"))
+	s.tk.MustExec("admin check table t_mct")
+	s.mustExec(c, "drop table t_mct")
+
+	// The rows whose non-handle columns are all NULL.
+	s.mustExec(c, "create table t_mct (id int primary key, a int)")
+	s.mustExec(c, "insert into t_mct values (1, NULL), (2, NULL), (3, 3)")
+	s.mustExec(c, "alter table t_mct modify a varchar(5)")
+	s.tk.MustQuery("select id from t_mct where a is null order by id").Check(testkit.Rows("1", "2"))
+	s.tk.MustQuery("select a from t_mct where id = 3").Check(bytesRows("3"))
+	s.mustExec(c, "drop table t_mct")
+}
+
 func (s *testDBSuite) TestAlterColumn(c *C) {
 	defer testleak.AfterTest(c)()
 	s.tk = testkit.NewTestKit(c, s.store)
@@ -1069,6 +1147,329 @@ func (s *testDBSuite) testRenameTable(c *C, storeStr, sql string) {
 	s.testErrorCode(c, failSQL, tmysql.ErrTableExists)
 }
 
+func (s *testDBSuite) TestRenameMultiTables(c *C) {
+	defer testleak.AfterTest(c)
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use test_db")
+	s.tk.MustExec("create table t_rename1 (a int primary key auto_increment, b int)")
+	s.tk.MustExec("create table t_rename2 (a int)")
+	s.tk.MustExec("insert t_rename1 (b) values (1), (2)")
+	s.tk.MustExec("insert t_rename2 values (10)")
+
+	// Swap the tables.
+	s.tk.MustExec("rename table t_rename1 to t_rename_tmp, t_rename2 to t_rename1, t_rename_tmp to t_rename2")
+	s.tk.MustQuery("select * from t_rename1").Check(testkit.Rows("10"))
+	s.tk.MustQuery("select * from t_rename2").Check(testkit.Rows("1 1", "2 2"))
+	ctx := s.tk.Se.(context.Context)
+	is := sessionctx.GetDomain(ctx).InfoSchema()
+	c.Assert(is.TableExists(model.NewCIStr("test_db"), model.NewCIStr("t_rename_tmp")), IsFalse)
+
+	// Move the tables to another database, the auto increment ID goes along with the table.
+	s.tk.MustExec("create database test_rename_db")
+	s.tk.MustExec("rename table t_rename1 to test_rename_db.t_rename1, t_rename2 to test_rename_db.t_rename3")
+	s.tk.MustExec("rename table test_rename_db.t_rename3 to t_rename3")
+	s.tk.MustExec("drop database test_rename_db")
+	s.tk.MustExec("insert t_rename3 (b) values (3)")
+	s.tk.MustQuery("select count(distinct a) from t_rename3").Check(testkit.Rows("3"))
+
+	// The tables are renamed atomically.
+	s.tk.MustExec("create table t_rename4 (a int)")
+	failSQL := "rename table t_rename3 to t_rename5, t_rename4 to t_rename5"
+	s.testErrorCode(c, failSQL, tmysql.ErrTableExists)
+	failSQL = "rename table t_rename3 to t_rename5, t_rename3 to t_rename6"
+	s.testErrorCode(c, failSQL, tmysql.ErrFileNotFound)
+	s.tk.MustQuery("select b from t_rename3").Check(testkit.Rows("1", "2", "3"))
+	s.tk.MustQuery("select * from t_rename4").Check(testkit.Rows())
+	s.tk.MustExec("drop table t_rename3, t_rename4")
+}
+
+func (s *testDBSuite) TestMultiSchemaChange(c *C) {
+	defer testleak.AfterTest(c)
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use test_db")
+	s.tk.MustExec("create table t_multi (a int, b int, c varchar(10), d int, index idx_d (d))")
+	s.tk.MustExec("insert t_multi values (1, 1, 'a', 1), (2, 1, 'b', 2)")
+
+	s.tk.MustExec("alter table t_multi add column e int default 5 after a, add index idx_e (e), " +
+		"modify d bigint, alter b set default 3, drop index idx_d")
+	s.tk.MustQuery("select a, e, b, d from t_multi").Check(testkit.Rows("1 5 1 1", "2 5 1 2"))
+	s.tk.MustExec("insert t_multi (a, c, d) values (3, 'c', 12345678901)")
+	s.tk.MustQuery("select a, b from t_multi where e = 5 and d = 12345678901").Check(testkit.Rows("3 3"))
+	t := s.testGetTable(c, "t_multi")
+	c.Assert(t.Meta().Indices, HasLen, 1)
+	c.Assert(t.Meta().Indices[0].Name.L, Equals, "idx_e")
+	colNames := make([]string, 0, len(t.Cols()))
+	for _, col := range t.Cols() {
+		colNames = append(colNames, col.Name.L)
+	}
+	c.Assert(colNames, DeepEquals, []string{"a", "e", "b", "c", "d"})
+
+	// The specifications conflict with each other.
+	sqls := []string{
+		"alter table t_multi add column f int, drop column f",
+		"alter table t_multi modify b bigint, alter b set default 1",
+		"alter table t_multi drop column d, add index idx_d (d)",
+		"alter table t_multi change b b1 int, add column f int after b",
+	}
+	for _, sql := range sqls {
+		_, err := s.tk.Exec(sql)
+		c.Assert(err, NotNil, Commentf("sql: %s", sql))
+		c.Assert(err.Error(), Matches, ".*can't (operate|use) the column.*", Commentf("sql: %s", sql))
+	}
+	_, err := s.tk.Exec("alter table t_multi add index idx_f (a), add index idx_f (b)")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*can't operate the index idx_f more than once.*")
+	_, err = s.tk.Exec("alter table t_multi modify a varchar(10), modify b varchar(10)")
+	c.Assert(err, NotNil)
+	s.tk.MustExec("create table t_multi1 (a int, b int)")
+	s.testErrorCode(c, "alter table t_multi1 drop column a, drop column b", tmysql.ErrCantRemoveAllFields)
+	s.tk.MustExec("drop table t_multi1")
+
+	// The unique index fails, the added column is reverted.
+	s.tk.MustExec("alter table t_multi add column f int default 1, add unique index idx_a (a)")
+	s.testErrorCode(c, "alter table t_multi add column g int, add unique index idx_b1 (b)", tmysql.ErrDupEntry)
+	t = s.testGetTable(c, "t_multi")
+	c.Assert(table.FindCol(t.Cols(), "g"), IsNil)
+	c.Assert(t.Meta().Indices, HasLen, 2)
+	s.tk.MustQuery("select a, e, b, d, f from t_multi where a = 1").Check(testkit.Rows("1 5 1 1 1"))
+
+	// The column type is changed after the others, the drops are the last.
+	s.tk.MustExec("delete from t_multi where a = 3")
+	s.tk.MustExec("alter table t_multi drop column f, modify d int(3), add column g int default 7 first")
+	s.tk.MustQuery("select g, a, e, b, d from t_multi").Check(testkit.Rows("7 1 5 1 1", "7 2 5 1 2"))
+	t = s.testGetTable(c, "t_multi")
+	c.Assert(t.Cols()[0].Name.L, Equals, "g")
+	c.Assert(table.FindCol(t.Cols(), "f"), IsNil)
+	c.Assert(table.FindCol(t.Cols(), "d").Flen, Equals, 3)
+	s.tk.MustExec("drop table t_multi")
+}
+
+func (s *testDBSuite) TestAutoRandom(c *C) {
+	defer testleak.AfterTest(c)
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use test_db")
+	s.tk.MustExec("create table t_rand (a bigint auto_random primary key)")
+	t := s.testGetTable(c, "t_rand")
+	c.Assert(t.Meta().AutoRandomBits, Equals, uint64(5))
+	s.tk.MustExec("drop table t_rand")
+
+	sqls := []string{
+		"create table t_rand (a int auto_random primary key)",
+		"create table t_rand (a bigint auto_random, b int, primary key (a, b))",
+		"create table t_rand (a bigint auto_random, b int primary key)",
+		"create table t_rand (a bigint auto_random auto_increment primary key)",
+		"create table t_rand (a bigint auto_random default 1 primary key)",
+		"create table t_rand (a bigint auto_random(0) primary key)",
+		"create table t_rand (a bigint auto_random(16) primary key)",
+	}
+	for _, sql := range sqls {
+		_, err := s.tk.Exec(sql)
+		c.Assert(err, NotNil, Commentf("sql: %s", sql))
+		c.Assert(err.Error(), Matches, ".*Invalid auto random.*", Commentf("sql: %s", sql))
+	}
+
+	s.tk.MustExec("create table t_rand (a bigint auto_random(3) primary key, b int)")
+	sqls = []string{
+		"alter table t_rand add column c bigint auto_random",
+		"alter table t_rand modify b bigint auto_random",
+	}
+	for _, sql := range sqls {
+		_, err := s.tk.Exec(sql)
+		c.Assert(err, NotNil, Commentf("sql: %s", sql))
+	}
+	s.tk.MustExec("drop table t_rand")
+}
+
+func (s *testDBSuite) TestTTL(c *C) {
+	defer testleak.AfterTest(c)
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use test_db")
+	s.tk.MustExec("create table t_ttl (a int, b int, created_at datetime) ttl = Created_At + interval 3 month")
+	ttlInfo := s.testGetTable(c, "t_ttl").Meta().TTLInfo
+	c.Assert(ttlInfo, NotNil)
+	c.Assert(ttlInfo.ColumnName.O, Equals, "created_at")
+	c.Assert(ttlInfo.IntervalValue, Equals, uint64(3))
+	c.Assert(ttlInfo.IntervalTimeUnit, Equals, "MONTH")
+	c.Assert(ttlInfo.Enable, IsTrue)
+	c.Assert(ttlInfo.JobInterval, Equals, model.DefaultTTLJobInterval)
+	result := s.tk.MustQuery("show create table t_ttl")
+	c.Assert(result.Rows()[0][1], Matches,
+		"(?s).* TTL=`created_at` \\+ INTERVAL 3 MONTH TTL_ENABLE='ON' TTL_JOB_INTERVAL='1h'$")
+
+	sqls := []string{
+		"create table t_ttl1 (a int, created_at datetime) ttl = b + interval 1 day",
+		"create table t_ttl1 (a int, created_at datetime) ttl = a + interval 1 day",
+		"create table t_ttl1 (a int, created_at datetime) ttl = created_at + interval 1 day_hour",
+		"create table t_ttl1 (a int, created_at datetime) ttl_enable = 'on'",
+		"create table t_ttl1 (a int, created_at datetime) ttl = created_at + interval 1 day ttl_enable = 'yes'",
+		"create table t_ttl1 (a int, created_at datetime) ttl = created_at + interval 1 day ttl_job_interval = '1d'",
+		"create table t_ttl1 (a int, created_at datetime) ttl = created_at + interval 1 day ttl_job_interval = '0s'",
+		"create temporary table t_ttl1 (a int, created_at datetime) ttl = created_at + interval 1 day",
+		"alter table t_ttl drop column created_at",
+		"alter table t_ttl change created_at created_at1 datetime",
+		"alter table t_ttl modify created_at int",
+		"alter table t_ttl ttl = a + interval 1 day",
+	}
+	for _, sql := range sqls {
+		_, err := s.tk.Exec(sql)
+		c.Assert(err, NotNil, Commentf("sql: %s", sql))
+		c.Assert(err.Error(), Matches, ".*Invalid TTL option.*", Commentf("sql: %s", sql))
+	}
+
+	s.tk.MustExec("alter table t_ttl modify created_at timestamp")
+	s.tk.MustExec("alter table t_ttl ttl_enable = 'off' ttl_job_interval = '30m'")
+	ttlInfo = s.testGetTable(c, "t_ttl").Meta().TTLInfo
+	c.Assert(ttlInfo.Enable, IsFalse)
+	c.Assert(ttlInfo.JobInterval, Equals, "30m")
+	c.Assert(ttlInfo.IntervalTimeUnit, Equals, "MONTH")
+	s.tk.MustExec("alter table t_ttl add column d date")
+	s.tk.MustExec("alter table t_ttl ttl = d + interval 7 day")
+	ttlInfo = s.testGetTable(c, "t_ttl").Meta().TTLInfo
+	c.Assert(ttlInfo.ColumnName.L, Equals, "d")
+	c.Assert(ttlInfo.IntervalValue, Equals, uint64(7))
+	c.Assert(ttlInfo.Enable, IsFalse)
+	s.tk.MustExec("alter table t_ttl drop column created_at")
+
+	s.tk.MustExec("alter table t_ttl remove ttl")
+	c.Assert(s.testGetTable(c, "t_ttl").Meta().TTLInfo, IsNil)
+	s.tk.MustExec("alter table t_ttl drop column d")
+	s.tk.MustExec("drop table t_ttl")
+}
+
+func (s *testDBSuite) TestInvisibleIndex(c *C) {
+	defer testleak.AfterTest(c)
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use test_db")
+	s.tk.MustExec("create table t_invisible (a int, b int, c int, primary key (a), unique key idx_b (b) invisible, key idx_c (c))")
+	invisible := func() map[string]bool {
+		m := make(map[string]bool)
+		for _, idx := range s.testGetTable(c, "t_invisible").Meta().Indices {
+			m[idx.Name.L] = idx.Invisible
+		}
+		return m
+	}
+	c.Assert(invisible(), DeepEquals, map[string]bool{"idx_b": true, "idx_c": false})
+	result := s.tk.MustQuery("show create table t_invisible")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*UNIQUE KEY `idx_b` \\(`b`\\) /\\*!80000 INVISIBLE \\*/.*")
+
+	// The invisible unique index is still maintained.
+	s.tk.MustExec("insert t_invisible values (1, 1, 1)")
+	_, err := s.tk.Exec("insert t_invisible values (2, 1, 2)")
+	c.Assert(err, NotNil)
+	s.tk.MustQuery("select count(*) from t_invisible use index (idx_b) where b = 1").Check(testkit.Rows("1"))
+
+	// The invisible index is ignored by the planner, even if it's in the index hints.
+	usesIndex := func() bool {
+		for _, row := range s.tk.MustQuery("explain select b from t_invisible use index (idx_b) where b = 1").Rows() {
+			if strings.HasPrefix(row[0].(string), "IndexScan") {
+				return true
+			}
+		}
+		return false
+	}
+	c.Assert(usesIndex(), IsFalse)
+
+	s.tk.MustExec("alter table t_invisible alter index idx_b visible")
+	c.Assert(usesIndex(), IsTrue)
+	s.tk.MustExec("alter table t_invisible alter index idx_c invisible")
+	c.Assert(invisible(), DeepEquals, map[string]bool{"idx_b": false, "idx_c": true})
+	s.tk.MustExec("alter table t_invisible alter index idx_b invisible, alter index idx_c visible")
+	c.Assert(invisible(), DeepEquals, map[string]bool{"idx_b": true, "idx_c": false})
+
+	s.testErrorCode(c, "alter table t_invisible alter index idx_d invisible", tmysql.ErrKeyDoesNotExits)
+	_, err = s.tk.Exec("alter table t_invisible alter index idx_b invisible, alter index idx_b visible")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*can't operate the index idx_b more than once.*")
+	s.testErrorCode(c, "create table t_invisible1 (a int, primary key (a) invisible)", tmysql.ErrPKIndexCantBeInvisible)
+	s.tk.MustExec("create table t_invisible1 (a int, b int, primary key (a, b))")
+	s.testErrorCode(c, "alter table t_invisible1 alter index `primary` invisible", tmysql.ErrPKIndexCantBeInvisible)
+	s.tk.MustExec("drop table t_invisible, t_invisible1")
+}
+
+func (s *testDBSuite) TestExpressionIndex(c *C) {
+	defer testleak.AfterTest(c)
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use test_db")
+	s.tk.MustExec("create table t_expr (id int primary key, name varchar(20), a int)")
+	s.tk.MustExec("insert t_expr values (1, 'Foo', 1), (2, 'BAR', 2), (3, null, 3)")
+
+	// The existing rows are backfilled.
+	s.tk.MustExec("create index idx_name on t_expr ((lower(name)))")
+	s.tk.MustExec("alter table t_expr add unique index idx_a ((a + 1), id)")
+	s.tk.MustExec("admin check table t_expr")
+	c.Assert(s.tk.MustQuery("select * from t_expr").Rows()[0], HasLen, 3)
+	s.tk.MustQuery("show columns from t_expr").Check(testkit.Rows(
+		"id int(11) NO PRI <nil> ", "name varchar(20) YES  <nil> ", "a int(11) YES  <nil> "))
+	result := s.tk.MustQuery("show create table t_expr")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*KEY `idx_name` \\(\\(lower\\(name\\)\\)\\).*")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*UNIQUE KEY `idx_a` \\(\\(a \\+ 1\\),`id`\\).*")
+	s.tk.MustQuery("show index from t_expr where key_name = 'idx_name'").Check(testkit.Rows(
+		"t_expr 1 idx_name 1 <nil> utf8_bin 0 <nil> <nil> YES    lower(name)"))
+
+	// The planner matches the expressions in the conditions.
+	usesIndex := func(sql string) bool {
+		for _, row := range s.tk.MustQuery("explain " + sql).Rows() {
+			if strings.HasPrefix(row[0].(string), "IndexScan") {
+				return strings.Contains(row[1].(string), "lower(test_db.t_expr.name)") ||
+					strings.Contains(row[1].(string), "plus(test_db.t_expr.a, 1)")
+			}
+		}
+		return false
+	}
+	sql := "select id from t_expr use index (idx_name) where lower(name) = 'bar'"
+	c.Assert(usesIndex(sql), IsTrue)
+	s.tk.MustQuery(sql).Check(testkit.Rows("2"))
+	sql = "select id from t_expr use index (idx_a) where a + 1 = 2"
+	c.Assert(usesIndex(sql), IsTrue)
+	s.tk.MustQuery(sql).Check(testkit.Rows("1"))
+
+	// The index is maintained by the writes.
+	s.tk.MustExec("insert t_expr values (4, 'Bar', 4)")
+	s.tk.MustExec("update t_expr set name = 'foo' where id = 2")
+	s.tk.MustExec("delete from t_expr where id = 3")
+	s.tk.MustExec("admin check table t_expr")
+	s.tk.MustQuery("select id from t_expr use index (idx_name) where lower(name) = 'bar'").Check(testkit.Rows("4"))
+	s.tk.MustQuery("select id from t_expr use index (idx_name) where lower(name) = 'foo'").Check(testkit.Rows("1", "2"))
+	s.tk.MustExec("begin")
+	s.tk.MustExec("update t_expr set name = 'baz' where id = 1")
+	s.tk.MustQuery("select id from t_expr use index (idx_name) where lower(name) = 'foo'").Check(testkit.Rows("2"))
+	s.tk.MustExec("commit")
+	s.tk.MustExec("create unique index idx_u on t_expr ((lower(name)))")
+	_, err := s.tk.Exec("insert t_expr values (5, 'BAZ', 5)")
+	c.Assert(err, NotNil)
+
+	// The hidden columns are put behind the added columns.
+	s.tk.MustExec("alter table t_expr add column b int default 5")
+	s.tk.MustQuery("select id, a, b from t_expr where id = 4").Check(testkit.Rows("4 4 5"))
+	c.Assert(s.tk.MustQuery("select * from t_expr").Rows()[0], HasLen, 4)
+	s.tk.MustExec("admin check table t_expr")
+
+	s.testErrorCode(c, "create index idx_err on t_expr ((name))", tmysql.ErrFunctionalIndexOnField)
+	s.testErrorCode(c, "create index idx_err on t_expr ((a + rand()))", tmysql.ErrFunctionalIndexFunctionIsNotAllowed)
+	s.testErrorCode(c, "create index idx_err on t_expr ((now()))", tmysql.ErrFunctionalIndexFunctionIsNotAllowed)
+	s.testErrorCode(c, "alter table t_expr drop column name", tmysql.ErrCannotDropColumnFunctionalIndex)
+	s.testErrorCode(c, "alter table t_expr change name name1 varchar(20)", tmysql.ErrDependentByFunctionalIndex)
+	s.testErrorCode(c, "create table t_expr1 (a int auto_increment, key ((a + 1)), key (a))",
+		tmysql.ErrFunctionalIndexRefAutoIncrement)
+	s.testErrorCode(c, "create table t_expr1 (a int, primary key ((a + 1)))", tmysql.ErrFunctionalIndexPrimaryKey)
+	s.testErrorCode(c, "create table t_expr1 (a text, key ((greatest(a, 'a'))))", tmysql.ErrFunctionalIndexOnLob)
+	_, err = s.tk.Exec("create temporary table t_expr1 (a int, key ((a + 1)))")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*expression index.*")
+
+	// The hidden columns are removed with the index.
+	s.tk.MustExec("alter table t_expr drop index idx_name, drop index idx_u")
+	s.tk.MustExec("alter table t_expr drop column name")
+	c.Assert(s.testGetTable(c, "t_expr").Meta().Columns, HasLen, 4)
+	s.tk.MustQuery("select * from t_expr where id = 4").Check(testkit.Rows("4 4 5"))
+	s.tk.MustExec("admin check table t_expr")
+
+	s.tk.MustExec("create table t_expr1 (a int, b int, key idx ((a * b)))")
+	s.tk.MustExec("insert t_expr1 values (2, 3), (3, 3)")
+	s.tk.MustQuery("select a from t_expr1 use index (idx) where a * b = 6").Check(testkit.Rows("2"))
+	s.tk.MustExec("drop table t_expr, t_expr1")
+}
+
 func (s *testDBSuite) TestAddNotNullColumn(c *C) {
 	defer testleak.AfterTest(c)
 	s.tk = testkit.NewTestKit(c, s.store)
@@ -14,6 +14,7 @@
 package ddl
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -31,7 +32,8 @@ var RunWorker = true
 
 // onDDLWorker is for async online schema changing, it will try to become the owner firstly,
 // then wait or pull the job queue to handle a schema change job.
-func (d *ddl) onDDLWorker() {
+// There is a worker for each job queue, jobCh notifies the worker that a job is put in its queue.
+func (d *ddl) onDDLWorker(jobListKey meta.JobListKeyType, jobCh chan struct{}) {
 	defer d.wait.Done()
 	if !RunWorker {
 		return
@@ -48,12 +50,12 @@ func (d *ddl) onDDLWorker() {
 		select {
 		case <-ticker.C:
 			log.Debugf("[ddl] wait %s to check DDL status again", checkTime)
-		case <-d.ddlJobCh:
+		case <-jobCh:
 		case <-d.quitCh:
 			return
 		}
 
-		err := d.handleDDLJobQueue()
+		err := d.handleDDLJobQueue(jobListKey)
 		if err != nil {
 			log.Errorf("[ddl] handle ddl job err %v", errors.ErrorStack(err))
 		}
@@ -101,6 +103,9 @@ func (d *ddl) getCheckOwnerTimeout(flag JobType) int64 {
 }
 
 func (d *ddl) checkOwner(t *meta.Meta, flag JobType) (*model.Owner, error) {
+	if flag == ddlJobFlag && time.Now().UnixNano() < atomic.LoadInt64(&d.resignedUntil) {
+		return nil, errors.Trace(errNotOwner)
+	}
 	owner, err := d.getJobOwner(t, flag)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -138,6 +143,30 @@ func (d *ddl) checkOwner(t *meta.Meta, flag JobType) (*model.Owner, error) {
 	return owner, nil
 }
 
+// ResignOwner implements DDL ResignOwner interface.
+func (d *ddl) ResignOwner() error {
+	resignedUntil := time.Now().UnixNano() + d.getCheckOwnerTimeout(ddlJobFlag)
+	atomic.StoreInt64(&d.resignedUntil, resignedUntil)
+	err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		owner, err1 := t.GetDDLJobOwner()
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		if owner == nil || owner.OwnerID != d.uuid {
+			return errors.Trace(errNotOwner)
+		}
+		// Clean the owner, so the other servers can take it over without waiting for the owner timeout.
+		return t.SetDDLJobOwner(&model.Owner{})
+	})
+	if err != nil {
+		atomic.StoreInt64(&d.resignedUntil, 0)
+		return errors.Trace(err)
+	}
+	log.Infof("[ddl] %s resigns the DDL job owner", d.uuid)
+	return nil
+}
+
 func (d *ddl) getJobOwner(t *meta.Meta, flag JobType) (*model.Owner, error) {
 	var owner *model.Owner
 	var err error
@@ -166,30 +195,131 @@ func (d *ddl) addDDLJob(ctx context.Context, job *model.Job) error {
 			return errors.Trace(err)
 		}
 
-		err = t.EnQueueDDLJob(job)
+		jobListKey, otherJobListKey := meta.DefaultJobListKey, meta.ReorgJobListKey
+		if isReorgJob(job) {
+			jobListKey, otherJobListKey = otherJobListKey, jobListKey
+		}
+		job.DependencyID, err = getDependencyJobID(t, job, otherJobListKey)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		err = t.EnQueueDDLJob(job, jobListKey)
 		return errors.Trace(err)
 	})
 }
 
+// isReorgJob returns whether the job reorganizes the data, such jobs are put in the reorganization job queue.
+func isReorgJob(job *model.Job) bool {
+	switch job.Type {
+	case model.ActionAddIndex, model.ActionDropIndex, model.ActionRecoverTable:
+		return true
+	case model.ActionModifyColumn:
+		// The third argument is whether the column type change needs reorganization.
+		if len(job.Args) > 2 {
+			needReorg, _ := job.Args[2].(bool)
+			return needReorg
+		}
+	case model.ActionMultiSchemaChange:
+		for _, sub := range job.MultiSchemaInfo.SubJobs {
+			if isReorgJob(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getDependencyJobID gets the ID of the last job in the other queue that the job depends on.
+// The jobs on the same table must run in the order they're submitted, even if they're in different queues.
+// So are the schema jobs and the jobs in the schema.
+func getDependencyJobID(t *meta.Meta, job *model.Job, jobListKey meta.JobListKeyType) (int64, error) {
+	jobs, err := t.GetAllDDLJobs(jobListKey)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	tableIDs, err := changedTableIDs(job)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var dependencyID int64
+	for _, other := range jobs {
+		if job.TableID != 0 && other.TableID != 0 {
+			otherTableIDs, err := changedTableIDs(other)
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			if hasSameTableID(tableIDs, otherTableIDs) {
+				dependencyID = other.ID
+			}
+		} else if job.SchemaID == other.SchemaID {
+			dependencyID = other.ID
+		}
+	}
+	return dependencyID, nil
+}
+
+// changedTableIDs returns the IDs of the tables changed by the job. The rename tables job changes the tables in its
+// fourth argument, which is decoded if the job is read from the queue.
+func changedTableIDs(job *model.Job) ([]int64, error) {
+	if job.Type != model.ActionRenameTables {
+		return []int64{job.TableID}, nil
+	}
+	if len(job.Args) > 3 {
+		if tableIDs, ok := job.Args[3].([]int64); ok {
+			return tableIDs, nil
+		}
+	}
+	var oldSchemaIDs, newSchemaIDs, tableIDs []int64
+	var tableNames []model.CIStr
+	err := job.DecodeArgs(&oldSchemaIDs, &newSchemaIDs, &tableNames, &tableIDs)
+	return tableIDs, errors.Trace(err)
+}
+
+func hasSameTableID(tableIDs, otherTableIDs []int64) bool {
+	for _, id := range tableIDs {
+		for _, otherID := range otherTableIDs {
+			if id == otherID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDependencyJobDone checks whether the job that the job depends on is done.
+func isDependencyJobDone(t *meta.Meta, job *model.Job) (bool, error) {
+	if job.DependencyID == 0 {
+		return true, nil
+	}
+
+	historyJob, err := t.GetHistoryDDLJob(job.DependencyID)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return historyJob != nil, nil
+}
+
 // getFirstDDLJob gets the first DDL job form DDL queue.
-func (d *ddl) getFirstDDLJob(t *meta.Meta) (*model.Job, error) {
-	job, err := t.GetDDLJob(0)
+func (d *ddl) getFirstDDLJob(t *meta.Meta, jobListKey meta.JobListKeyType) (*model.Job, error) {
+	job, err := t.GetDDLJob(0, jobListKey)
 	return job, errors.Trace(err)
 }
 
 // updateDDLJob updates the DDL job information.
 // Every time we enter another state except final state, we must call this function.
-func (d *ddl) updateDDLJob(t *meta.Meta, job *model.Job) error {
-	err := t.UpdateDDLJob(0, job)
+func (d *ddl) updateDDLJob(t *meta.Meta, job *model.Job, jobListKey meta.JobListKeyType) error {
+	err := t.UpdateDDLJob(0, job, jobListKey)
 	return errors.Trace(err)
 }
 
 // finishDDLJob deletes the finished DDL job in the ddl queue and puts it to history queue.
 // If the DDL job need to handle in background, it will prepare a background job.
-func (d *ddl) finishDDLJob(t *meta.Meta, job *model.Job) error {
+func (d *ddl) finishDDLJob(t *meta.Meta, job *model.Job, jobListKey meta.JobListKeyType) error {
 	log.Infof("[ddl] finish DDL job %v", job)
 	// Job is finished, notice and run the next job.
-	_, err := t.DeQueueDDLJob()
+	_, err := t.DeQueueDDLJob(jobListKey)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -237,7 +367,7 @@ func (j JobType) String() string {
 	return "unknown"
 }
 
-func (d *ddl) handleDDLJobQueue() error {
+func (d *ddl) handleDDLJobQueue(jobListKey meta.JobListKeyType) error {
 	for {
 		if d.isClosed() {
 			return nil
@@ -256,11 +386,22 @@ func (d *ddl) handleDDLJobQueue() error {
 			}
 
 			// We become the owner. Get the first job and run it.
-			job, err = d.getFirstDDLJob(t)
+			job, err = d.getFirstDDLJob(t, jobListKey)
 			if job == nil || err != nil {
 				return errors.Trace(err)
 			}
 
+			done, err := isDependencyJobDone(t, job)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !done {
+				// Wait for the job in the other queue, we will be notified when it's finished.
+				log.Infof("[ddl] the job %d depends on the job %d, wait again", job.ID, job.DependencyID)
+				job = nil
+				return nil
+			}
+
 			if job.IsRunning() {
 				// If we enter a new state, crash when waiting 2 * lease time, and restart quickly,
 				// we may run the job immediately again, but we don't wait enough 2 * lease time to
@@ -284,9 +425,9 @@ func (d *ddl) handleDDLJobQueue() error {
 			d.runDDLJob(t, job)
 			if job.IsFinished() {
 				binloginfo.SetDDLBinlog(txn, job.ID, job.Query)
-				err = d.finishDDLJob(t, job)
+				err = d.finishDDLJob(t, job, jobListKey)
 			} else {
-				err = d.updateDDLJob(t, job)
+				err = d.updateDDLJob(t, job, jobListKey)
 			}
 			if err != nil {
 				return errors.Trace(err)
@@ -315,7 +456,7 @@ func (d *ddl) handleDDLJobQueue() error {
 		if job.State == model.JobRunning || job.State == model.JobDone {
 			switch job.Type {
 			case model.ActionCreateSchema, model.ActionDropSchema, model.ActionCreateTable,
-				model.ActionTruncateTable, model.ActionDropTable:
+				model.ActionTruncateTable, model.ActionDropTable, model.ActionRecoverTable:
 				// Do not need to wait for those DDL, because those DDL do not need to modify data,
 				// So there is no data inconsistent issue.
 			default:
@@ -325,6 +466,9 @@ func (d *ddl) handleDDLJobQueue() error {
 		if job.IsFinished() {
 			d.startBgJob(job.Type)
 			asyncNotify(d.ddlJobDoneCh)
+			// The jobs in the other queue may depend on it.
+			asyncNotify(d.ddlJobCh)
+			asyncNotify(d.reorgJobCh)
 		}
 	}
 }
@@ -344,10 +488,37 @@ func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) {
 		return
 	}
 
-	if job.State != model.JobRollback {
+	if job.IsCancelling() {
+		err := d.cancelJob(t, job)
+		if err != nil {
+			log.Infof("[ddl] the job %d is cancelled because %v", job.ID, errors.ErrorStack(err))
+			job.Error = toTError(err)
+			job.ErrorCount++
+			return
+		}
+	}
+	if job.State != model.JobRollback && job.State != model.JobCancelling {
 		job.State = model.JobRunning
 	}
 
+	err := d.runDDLJobStep(t, job)
+
+	// Save errors in job, so that others can know errors happened.
+	if err != nil {
+		// If job is not cancelled, we should log this error.
+		if job.State != model.JobCancelled {
+			log.Errorf("[ddl] run ddl job err %v", errors.ErrorStack(err))
+		} else {
+			log.Infof("[ddl] the job is normal to cancel because %v", errors.ErrorStack(err))
+		}
+
+		job.Error = toTError(err)
+		job.ErrorCount++
+	}
+}
+
+// runDDLJobStep runs one step of the job according to its type.
+func (d *ddl) runDDLJobStep(t *meta.Meta, job *model.Job) error {
 	var err error
 	switch job.Type {
 	case model.ActionCreateSchema:
@@ -376,26 +547,62 @@ func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) {
 		err = d.onTruncateTable(t, job)
 	case model.ActionRenameTable:
 		err = d.onRenameTable(t, job)
+	case model.ActionRenameTables:
+		err = d.onRenameTables(t, job)
 	case model.ActionSetDefaultValue:
 		err = d.onSetDefaultValue(t, job)
+	case model.ActionMultiSchemaChange:
+		err = d.onMultiSchemaChange(t, job)
+	case model.ActionRebaseAutoID:
+		err = d.onRebaseAutoID(t, job)
+	case model.ActionModifyTableAutoIDCache:
+		err = d.onModifyTableAutoIDCache(t, job)
+	case model.ActionAlterTTLInfo:
+		err = d.onAlterTTLInfo(t, job)
+	case model.ActionRemoveTTL:
+		err = d.onRemoveTTL(t, job)
+	case model.ActionAlterIndexVisibility:
+		err = d.onAlterIndexVisibility(t, job)
+	case model.ActionRecoverTable:
+		err = d.onRecoverTable(t, job)
+	case model.ActionModifyTableOptions:
+		err = d.onModifyTableOptions(t, job)
 	default:
 		// Invalid job, cancel it.
 		job.State = model.JobCancelled
 		err = errInvalidDDLJob.Gen("invalid ddl job %v", job)
 	}
+	return errors.Trace(err)
+}
 
-	// Save errors in job, so that others can know errors happened.
-	if err != nil {
-		// If job is not cancelled, we should log this error.
-		if job.State != model.JobCancelled {
-			log.Errorf("[ddl] run ddl job err %v", errors.ErrorStack(err))
-		} else {
-			log.Infof("[ddl] the job is normal to cancel because %v", errors.ErrorStack(err))
-		}
+// cancelJob handles the job which is requested to be cancelled by the user. If the job hasn't changed anything, it's
+// cancelled. If the job has partially applied states that can be undone, it's converted to a rollback job, and a job
+// in the reorganization state stops the reorganization first. Otherwise the job goes on running.
+// It returns errCancelledDDLJob if the job is cancelled or converted to a rollback job.
+func (d *ddl) cancelJob(t *meta.Meta, job *model.Job) error {
+	if job.Type == model.ActionMultiSchemaChange {
+		return errors.Trace(d.cancelMultiSchemaChange(t, job))
+	}
+	if job.SchemaState == model.StateNone {
+		job.State = model.JobCancelled
+		return errCancelledDDLJob
+	}
 
-		job.Error = toTError(err)
-		job.ErrorCount++
+	var err error
+	switch job.Type {
+	case model.ActionAddIndex:
+		err = d.rollbackAddIndex(t, job)
+	case model.ActionAddColumn:
+		err = d.rollbackAddColumn(t, job)
+	case model.ActionModifyColumn:
+		err = d.rollbackModifyColumn(t, job)
+	default:
+		job.State = model.JobRunning
 	}
+	if job.State == model.JobRunning {
+		log.Warnf("[ddl] the job %s can't be cancelled in the state %s, go on running", job, job.SchemaState)
+	}
+	return errors.Trace(err)
 }
 
 func toTError(err error) *terror.Error {
@@ -411,11 +618,27 @@ func toTError(err error) *terror.Error {
 
 // For every lease, we will re-update the whole schema, so we will wait 2 * lease time
 // to guarantee that all servers have already updated schema.
+// If the schema syncer is enabled, we stop waiting as soon as all the servers have loaded the latest schema.
 func (d *ddl) waitSchemaChanged(waitTime time.Duration) {
 	if waitTime == 0 {
 		return
 	}
 
+	if SchemaSyncInterval > 0 {
+		startTime := time.Now()
+		latestVer, err := d.schemaSyncer.getGlobalVersion()
+		if err == nil {
+			synced := d.schemaSyncer.ownerCheckAllVersions(latestVer, d.lease, waitTime, d.quitCh)
+			result := schemaSyncTimeout
+			if synced {
+				result = schemaSyncSucc
+			}
+			ownerWaitSchemaSyncHistogram.WithLabelValues(result).Observe(time.Since(startTime).Seconds())
+			return
+		}
+		log.Warnf("[ddl] get global schema version err %v, wait %s", errors.ErrorStack(err), waitTime)
+	}
+
 	select {
 	case <-time.After(waitTime):
 	case <-d.quitCh:
@@ -446,6 +669,22 @@ func updateSchemaVersion(t *meta.Meta, job *model.Job) (int64, error) {
 			return 0, errors.Trace(err)
 		}
 		diff.TableID = job.TableID
+	} else if job.Type == model.ActionRenameTables {
+		var oldSchemaIDs, newSchemaIDs, tableIDs []int64
+		var tableNames []model.CIStr
+		err = job.DecodeArgs(&oldSchemaIDs, &newSchemaIDs, &tableNames, &tableIDs)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		diff.TableID = job.TableID
+		diff.AffectedOpts = make([]*model.AffectedOption, 0, len(tableIDs))
+		for i, tableID := range tableIDs {
+			diff.AffectedOpts = append(diff.AffectedOpts, &model.AffectedOption{
+				SchemaID:    newSchemaIDs[i],
+				TableID:     tableID,
+				OldSchemaID: oldSchemaIDs[i],
+			})
+		}
 	} else {
 		diff.TableID = job.TableID
 	}
@@ -84,6 +84,17 @@ func (s *testDDLSuite) TestCheckOwner(c *C) {
 	d2.SetLease(1 * time.Second)
 	d2.SetLease(2 * time.Second)
 	c.Assert(d2.GetLease(), Equals, 2*time.Second)
+
+	// The owner resigns, another server becomes the owner.
+	err = d2.Start()
+	c.Assert(err, IsNil)
+	err = d2.ResignOwner()
+	c.Assert(terror.ErrorEqual(err, errNotOwner), IsTrue)
+	err = d1.ResignOwner()
+	c.Assert(err, IsNil)
+	testCheckOwner(c, d1, false, ddlJobFlag)
+	testCheckOwner(c, d2, true, ddlJobFlag)
+	testCheckOwner(c, d1, false, ddlJobFlag)
 }
 
 func (s *testDDLSuite) TestSchemaError(c *C) {
@@ -230,6 +241,51 @@ func (s *testDDLSuite) TestColumnError(c *C) {
 	doDDLJobErr(c, dbInfo.ID, tblInfo.ID, model.ActionDropColumn, []interface{}{model.NewCIStr("c5")}, ctx, d)
 }
 
+func (s *testDDLSuite) TestJobDependency(c *C) {
+	defer testleak.AfterTest(c)()
+	store := testCreateStore(c, "test_job_dependency")
+	defer store.Close()
+
+	jobs := []*model.Job{
+		{ID: 1, SchemaID: 1, TableID: 1, Type: model.ActionAddColumn},
+		{ID: 2, SchemaID: 1, TableID: 2, Type: model.ActionCreateTable},
+		{ID: 3, SchemaID: 1, TableID: 1, Type: model.ActionAddColumn},
+	}
+	err := kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		for _, job := range jobs {
+			c.Assert(t.EnQueueDDLJob(job, meta.DefaultJobListKey), IsNil)
+		}
+
+		job := &model.Job{SchemaID: 1, TableID: 1, Type: model.ActionAddIndex}
+		c.Assert(isReorgJob(job), IsTrue)
+		id, err := getDependencyJobID(t, job, meta.DefaultJobListKey)
+		c.Assert(err, IsNil)
+		c.Assert(id, Equals, int64(3))
+		job.TableID = 3
+		id, err = getDependencyJobID(t, job, meta.DefaultJobListKey)
+		c.Assert(err, IsNil)
+		c.Assert(id, Equals, int64(0))
+		// The schema job depends on all the jobs in the schema.
+		job = &model.Job{SchemaID: 1, Type: model.ActionDropSchema}
+		c.Assert(isReorgJob(job), IsFalse)
+		id, err = getDependencyJobID(t, job, meta.DefaultJobListKey)
+		c.Assert(err, IsNil)
+		c.Assert(id, Equals, int64(3))
+
+		job.DependencyID = 1
+		done, err := isDependencyJobDone(t, job)
+		c.Assert(err, IsNil)
+		c.Assert(done, IsFalse)
+		c.Assert(t.AddHistoryDDLJob(jobs[0]), IsNil)
+		done, err = isDependencyJobDone(t, job)
+		c.Assert(err, IsNil)
+		c.Assert(done, IsTrue)
+		return nil
+	})
+	c.Assert(err, IsNil)
+}
+
 func testCheckOwner(c *C, d *ddl, isOwner bool, flag JobType) {
 	err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
 		t := meta.NewMeta(txn)
@@ -284,3 +340,121 @@ func doDDLJobErr(c *C, schemaID, tableID int64, tp model.ActionType, args []inte
 
 	return job
 }
+
+func (s *testDDLSuite) TestCancelJob(c *C) {
+	defer testleak.AfterTest(c)()
+	store := testCreateStore(c, "test_cancel_job")
+	defer store.Close()
+	d := newDDL(store, nil, nil, testLease)
+	defer d.Stop()
+
+	dbInfo := testSchemaInfo(c, d, "test_cancel_job")
+	testCreateSchema(c, testNewContext(d), d, dbInfo)
+	// create table t (c1 int, c2 int);
+	tblInfo := testTableInfo(c, d, "t", 2)
+	ctx := testNewContext(d)
+	err := ctx.NewTxn()
+	c.Assert(err, IsNil)
+	testCreateTable(c, ctx, d, dbInfo, tblInfo)
+	tbl := testGetTable(c, d, dbInfo.ID, tblInfo.ID)
+	for i := 1; i <= 3; i++ {
+		_, err = tbl.AddRecord(ctx, types.MakeDatums(i, i))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(ctx.Txn().Commit(), IsNil)
+
+	// The job is requested to be cancelled when it's going to run in the cancelState.
+	var cancelState model.SchemaState
+	tc := &testDDLCallback{}
+	tc.onJobRunBefore = func(job *model.Job) {
+		if !job.IsFinished() && job.State != model.JobRollback && job.SchemaState == cancelState {
+			job.State = model.JobCancelling
+		}
+	}
+	d.setHook(tc)
+
+	states := []model.SchemaState{model.StateNone, model.StateDeleteOnly, model.StateWriteOnly,
+		model.StateWriteReorganization}
+	for _, state := range states {
+		cancelState = state
+		job := &model.Job{
+			SchemaID:   dbInfo.ID,
+			TableID:    tblInfo.ID,
+			Type:       model.ActionAddIndex,
+			BinlogInfo: &model.HistoryInfo{},
+			Args: []interface{}{false, model.NewCIStr("idx"),
+				[]*ast.IndexColName{{
+					Column: &ast.ColumnName{Name: model.NewCIStr("c2")},
+					Length: types.UnspecifiedLength}}},
+		}
+		err = d.doDDLJob(ctx, job)
+		c.Assert(terror.ErrorEqual(err, errCancelledDDLJob), IsTrue, Commentf("state %s, err %v", state, err))
+		testCheckJobCancelledOrRolledBack(c, d, job, state)
+		t := testGetTable(c, d, dbInfo.ID, tblInfo.ID)
+		c.Assert(t.Meta().Indices, HasLen, 0)
+		// The index data added by the reorganization is dropped.
+		c.Assert(ctx.NewTxn(), IsNil)
+		it, err := ctx.Txn().Seek(t.IndexPrefix())
+		c.Assert(err, IsNil)
+		c.Assert(it.Valid() && it.Key().HasPrefix(t.IndexPrefix()), IsFalse)
+		it.Close()
+
+		cancelState = state
+		col := &model.ColumnInfo{
+			Name:   model.NewCIStr("c3"),
+			Offset: len(tblInfo.Columns),
+		}
+		col.FieldType = *types.NewFieldType(mysql.TypeLong)
+		job = &model.Job{
+			SchemaID:   dbInfo.ID,
+			TableID:    tblInfo.ID,
+			Type:       model.ActionAddColumn,
+			BinlogInfo: &model.HistoryInfo{},
+			Args:       []interface{}{col, &ast.ColumnPosition{Tp: ast.ColumnPositionNone}, 0},
+		}
+		err = d.doDDLJob(ctx, job)
+		c.Assert(terror.ErrorEqual(err, errCancelledDDLJob), IsTrue, Commentf("state %s, err %v", state, err))
+		testCheckJobCancelledOrRolledBack(c, d, job, state)
+		t = testGetTable(c, d, dbInfo.ID, tblInfo.ID)
+		c.Assert(t.Meta().Columns, HasLen, 2)
+
+		// The column type is changed from int to varchar with data reorganization.
+		cancelState = state
+		newCol := t.Meta().Columns[1].Clone()
+		newCol.FieldType = *types.NewFieldType(mysql.TypeVarchar)
+		newCol.Flen = 10
+		job = &model.Job{
+			SchemaID:   dbInfo.ID,
+			TableID:    tblInfo.ID,
+			Type:       model.ActionModifyColumn,
+			BinlogInfo: &model.HistoryInfo{},
+			Args:       []interface{}{newCol, newCol.Name, true, true},
+		}
+		err = d.doDDLJob(ctx, job)
+		c.Assert(terror.ErrorEqual(err, errCancelledDDLJob), IsTrue, Commentf("state %s, err %v", state, err))
+		testCheckJobCancelledOrRolledBack(c, d, job, state)
+		t = testGetTable(c, d, dbInfo.ID, tblInfo.ID)
+		c.Assert(t.Meta().Columns, HasLen, 2)
+		c.Assert(t.Meta().Columns[1].Tp, Equals, mysql.TypeLong)
+	}
+
+	// The drop column job can't be cancelled after it has changed the column.
+	cancelState = model.StateWriteOnly
+	job := testDropColumn(c, ctx, d, dbInfo, tblInfo, "c2", false)
+	testCheckJobDone(c, d, job, false)
+}
+
+func testCheckJobCancelledOrRolledBack(c *C, d *ddl, job *model.Job, state model.SchemaState) {
+	if state == model.StateNone {
+		testCheckJobCancelled(c, d, job)
+		return
+	}
+	kv.RunInNewTxn(d.store, false, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		historyJob, err := t.GetHistoryDDLJob(job.ID)
+		c.Assert(err, IsNil)
+		c.Assert(historyJob.State, Equals, model.JobRollbackDone)
+		c.Assert(historyJob.SchemaState, Equals, model.StateNone)
+		return nil
+	})
+}
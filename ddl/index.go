@@ -14,6 +14,7 @@
 package ddl
 
 import (
+	"fmt"
 	"math"
 	"sort"
 	"sync"
@@ -22,10 +23,13 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/tablecodec"
@@ -131,6 +135,207 @@ func buildIndexInfo(tblInfo *model.TableInfo, indexName model.CIStr, idxColNames
 	return idxInfo, nil
 }
 
+const (
+	// expressionIndexColumnPrefix is the name prefix of the hidden columns of the expression indices.
+	expressionIndexColumnPrefix = "_V$_"
+	// expressionIndexName is the name of the anonymous index whose first part is an expression.
+	expressionIndexName = "functional_index"
+)
+
+// buildHiddenColumns builds a hidden column for every expression part of the index, whose values are evaluated from
+// the expression. The expression parts are replaced by the names of the hidden columns in the returned index column
+// names.
+func buildHiddenColumns(ctx context.Context, tblInfo *model.TableInfo, indexName model.CIStr,
+	idxColNames []*ast.IndexColName) ([]*ast.IndexColName, []*model.ColumnInfo, error) {
+	var hiddenCols []*model.ColumnInfo
+	newColNames := make([]*ast.IndexColName, 0, len(idxColNames))
+	for i, ic := range idxColNames {
+		if ic.Expr == nil {
+			newColNames = append(newColNames, ic)
+			continue
+		}
+		col, err := buildHiddenColumn(ctx, tblInfo, indexName, i, ic.Expr)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		hiddenCols = append(hiddenCols, col)
+		newColNames = append(newColNames, &ast.IndexColName{
+			Column: &ast.ColumnName{Name: col.Name},
+			Length: types.UnspecifiedLength,
+		})
+	}
+	return newColNames, hiddenCols, nil
+}
+
+func buildHiddenColumn(ctx context.Context, tblInfo *model.TableInfo, indexName model.CIStr, offset int,
+	exprNode ast.ExprNode) (*model.ColumnInfo, error) {
+	if _, ok := exprNode.(*ast.ColumnNameExpr); ok {
+		return nil, errors.Trace(errFunctionalIndexOnField)
+	}
+	name := model.NewCIStr(fmt.Sprintf("%s%s_%d", expressionIndexColumnPrefix, indexName.O, offset))
+	checker := &generatedExprChecker{}
+	exprNode.Accept(checker)
+	if checker.disallowed {
+		return nil, errFunctionalIndexFunctionIsNotAllowed.GenByArgs(name)
+	}
+	exprString := exprNode.Text()
+	expr, err := expression.RewriteTableExpr(exprNode, ctx, tblInfo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !expression.IsDeterministic(expr) {
+		return nil, errFunctionalIndexFunctionIsNotAllowed.GenByArgs(name)
+	}
+	for _, col := range expression.ExtractColumns(expr) {
+		if mysql.HasAutoIncrementFlag(tblInfo.Columns[col.Index].Flag) {
+			return nil, errFunctionalIndexRefAutoIncrement.GenByArgs(name)
+		}
+	}
+	tp := *expr.GetType()
+	if types.IsTypeBlob(tp.Tp) {
+		return nil, errors.Trace(errFunctionalIndexOnLob)
+	}
+	tp.Flag &= mysql.UnsignedFlag | mysql.BinaryFlag
+	if tp.Flen == types.UnspecifiedLength {
+		tp.Flen = mysql.GetDefaultFieldLength(tp.Tp)
+	}
+	// The length of the string expression isn't inferred, it's estimated by the lengths of the columns in the
+	// expression to check the length of the index key.
+	if tp.Flen == types.UnspecifiedLength && (types.IsTypeChar(tp.Tp) || types.IsTypeVarchar(tp.Tp)) {
+		tp.Flen = 0
+		for _, col := range expression.ExtractColumns(expr) {
+			if col.RetType.Flen > 0 {
+				tp.Flen += col.RetType.Flen
+			}
+		}
+	}
+	return &model.ColumnInfo{
+		Name:                name,
+		FieldType:           tp,
+		GeneratedExprString: exprString,
+	}, nil
+}
+
+// addHiddenColumns appends the hidden columns of an expression index to the columns of the table. They're in the
+// delete only state all the time, so their values are never written to the rows.
+func addHiddenColumns(tblInfo *model.TableInfo, hiddenCols []*model.ColumnInfo) {
+	for _, col := range hiddenCols {
+		col.ID = allocateColumnID(tblInfo)
+		col.Offset = len(tblInfo.Columns)
+		col.State = model.StateDeleteOnly
+		tblInfo.Columns = append(tblInfo.Columns, col)
+	}
+}
+
+// removeHiddenColumns removes the hidden columns of the expression index, the offsets of the columns after them are
+// decreased.
+func removeHiddenColumns(tblInfo *model.TableInfo, indexInfo *model.IndexInfo) {
+	removed := make(map[int]bool)
+	for _, ic := range indexInfo.Columns {
+		if tblInfo.Columns[ic.Offset].IsGenerated() {
+			removed[ic.Offset] = true
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	newOffset := func(offset int) int {
+		n := offset
+		for removedOffset := range removed {
+			if removedOffset < offset {
+				n--
+			}
+		}
+		return n
+	}
+
+	newColumns := make([]*model.ColumnInfo, 0, len(tblInfo.Columns)-len(removed))
+	for _, col := range tblInfo.Columns {
+		if !removed[col.Offset] {
+			newColumns = append(newColumns, col)
+		}
+	}
+	for _, col := range newColumns {
+		col.Offset = newOffset(col.Offset)
+	}
+	for _, idx := range tblInfo.Indices {
+		for _, ic := range idx.Columns {
+			ic.Offset = newOffset(ic.Offset)
+		}
+	}
+	tblInfo.Columns = newColumns
+}
+
+// nonDeterministicTimeFuncs are the functions which return the current time, they can't be used by the expression
+// indices.
+var nonDeterministicTimeFuncs = map[string]bool{
+	"now": true, "current_timestamp": true, "localtime": true, "localtimestamp": true, "sysdate": true,
+	"curdate": true, "current_date": true, "curtime": true, "current_time": true, "utc_date": true,
+	"utc_time": true, "utc_timestamp": true, "unix_timestamp": true,
+}
+
+// generatedExprChecker checks whether the expression can be evaluated on a single row of the table alone, which
+// rejects the subqueries, the aggregate functions, the variables and the current time.
+type generatedExprChecker struct {
+	disallowed bool
+}
+
+func (c *generatedExprChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch x := in.(type) {
+	case *ast.SubqueryExpr, *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr, *ast.AggregateFuncExpr,
+		*ast.VariableExpr, *ast.ParamMarkerExpr, *ast.DefaultExpr, *ast.ValuesExpr, *ast.RowExpr:
+		c.disallowed = true
+	case *ast.FuncCallExpr:
+		c.disallowed = nonDeterministicTimeFuncs[x.FnName.L]
+	}
+	return in, c.disallowed
+}
+
+func (c *generatedExprChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, !c.disallowed
+}
+
+// columnNameCollector collects the names of the columns in the expression.
+type columnNameCollector struct {
+	names []model.CIStr
+}
+
+func (c *columnNameCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if cn, ok := in.(*ast.ColumnNameExpr); ok {
+		c.names = append(c.names, cn.Name.Name)
+	}
+	return in, false
+}
+
+func (c *columnNameCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+func exprColumnNames(expr ast.ExprNode) []model.CIStr {
+	collector := &columnNameCollector{}
+	expr.Accept(collector)
+	return collector.names
+}
+
+// isColumnInExpressionIndex checks whether the column is used by the expressions of the expression indices.
+func isColumnInExpressionIndex(tblInfo *model.TableInfo, colName string) (bool, error) {
+	for _, col := range tblInfo.Columns {
+		if !col.IsGenerated() {
+			continue
+		}
+		expr, err := table.ParseGeneratedExpr(col.GeneratedExprString)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		for _, name := range exprColumnNames(expr) {
+			if name.L == colName {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 func addIndexColumnFlag(tblInfo *model.TableInfo, indexInfo *model.IndexInfo) {
 	col := indexInfo.Columns[0]
 
@@ -185,8 +390,9 @@ func (d *ddl) onCreateIndex(t *meta.Meta, job *model.Job) error {
 		unique      bool
 		indexName   model.CIStr
 		idxColNames []*ast.IndexColName
+		hiddenCols  []*model.ColumnInfo
 	)
-	err = job.DecodeArgs(&unique, &indexName, &idxColNames)
+	err = job.DecodeArgs(&unique, &indexName, &idxColNames, &hiddenCols)
 	if err != nil {
 		job.State = model.JobCancelled
 		return errors.Trace(err)
@@ -199,6 +405,12 @@ func (d *ddl) onCreateIndex(t *meta.Meta, job *model.Job) error {
 	}
 
 	if indexInfo == nil {
+		// The columns in the expressions may have been changed after the job is built.
+		if err = d.checkGeneratedExprs(tblInfo, hiddenCols); err != nil {
+			job.State = model.JobCancelled
+			return errors.Trace(err)
+		}
+		addHiddenColumns(tblInfo, hiddenCols)
 		indexInfo, err = buildIndexInfo(tblInfo, indexName, idxColNames, model.StateNone)
 		if err != nil {
 			job.State = model.JobCancelled
@@ -254,7 +466,11 @@ func (d *ddl) onCreateIndex(t *meta.Meta, job *model.Job) error {
 			}
 			if terror.ErrorEqual(err, kv.ErrKeyExists) {
 				log.Warnf("[ddl] run DDL job %v err %v, convert job to rollback job", job, err)
-				err = d.convert2RollbackJob(t, job, tblInfo, indexInfo)
+				err = d.convert2RollbackJob(t, job, tblInfo, indexInfo,
+					kv.ErrKeyExists.Gen("Duplicate for key %s", indexInfo.Name.O))
+			} else if terror.ErrorEqual(err, errCancelledDDLJob) {
+				log.Infof("[ddl] run DDL job %v is cancelled, convert job to rollback job", job)
+				err = d.convert2RollbackJob(t, job, tblInfo, indexInfo, errCancelledDDLJob)
 			}
 			return errors.Trace(err)
 		}
@@ -279,21 +495,57 @@ func (d *ddl) onCreateIndex(t *meta.Meta, job *model.Job) error {
 	return errors.Trace(err)
 }
 
-func (d *ddl) convert2RollbackJob(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo, indexInfo *model.IndexInfo) error {
+func (d *ddl) convert2RollbackJob(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo, indexInfo *model.IndexInfo,
+	err error) error {
 	job.State = model.JobRollback
 	job.Args = []interface{}{indexInfo.Name}
 	// If add index job rollbacks in write reorganization state, its need to delete all keys which has been added.
 	// Its work is the same as drop index job do.
 	// The write reorganization state in add index job that likes write only state in drop index job.
 	// So the next state is delete only state.
-	indexInfo.State = model.StateDeleteOnly
 	originalState := indexInfo.State
+	indexInfo.State = model.StateDeleteOnly
 	job.SchemaState = model.StateDeleteOnly
-	_, err := updateTableInfo(t, job, tblInfo, originalState)
+	_, err1 := updateTableInfo(t, job, tblInfo, originalState)
+	if err1 != nil {
+		return errors.Trace(err1)
+	}
+	return errors.Trace(err)
+}
+
+// rollbackAddIndex handles the add index job which is requested to be cancelled. The index which isn't public is
+// dropped, but the index in the reorganization state stops the reorganization and then is dropped.
+func (d *ddl) rollbackAddIndex(t *meta.Meta, job *model.Job) error {
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return kv.ErrKeyExists.Gen("Duplicate for key %s", indexInfo.Name.O)
+
+	var (
+		unique    bool
+		indexName model.CIStr
+	)
+	err = job.DecodeArgs(&unique, &indexName)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	indexInfo := findIndexByName(indexName.L, tblInfo.Indices)
+	if indexInfo == nil {
+		job.State = model.JobCancelled
+		return errCancelledDDLJob
+	}
+
+	switch indexInfo.State {
+	case model.StateDeleteOnly, model.StateWriteOnly:
+		return d.convert2RollbackJob(t, job, tblInfo, indexInfo, errCancelledDDLJob)
+	case model.StateWriteReorganization:
+		// The reorganization returns errCancelledDDLJob, then the job is converted to a rollback job.
+		d.notifyReorgCancel()
+	default:
+		job.State = model.JobRunning
+	}
+	return nil
 }
 
 func (d *ddl) onDropIndex(t *meta.Meta, job *model.Job) error {
@@ -353,6 +605,7 @@ func (d *ddl) onDropIndex(t *meta.Meta, job *model.Job) error {
 		tblInfo.Indices = newIndices
 		// Set column index flag.
 		dropIndexColumnFlag(tblInfo, indexInfo)
+		removeHiddenColumns(tblInfo, indexInfo)
 
 		job.SchemaState = model.StateNone
 		ver, err := updateTableInfo(t, job, tblInfo, originalState)
@@ -373,14 +626,90 @@ func (d *ddl) onDropIndex(t *meta.Meta, job *model.Job) error {
 	return errors.Trace(err)
 }
 
+// onAlterIndexVisibility makes the index visible or invisible, the index data isn't changed.
+func (d *ddl) onAlterIndexVisibility(t *meta.Meta, job *model.Job) error {
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var indexName model.CIStr
+	var invisible bool
+	if err = job.DecodeArgs(&indexName, &invisible); err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+
+	indexInfo := findIndexByName(indexName.L, tblInfo.Indices)
+	if indexInfo == nil {
+		job.State = model.JobCancelled
+		return errKeyDoesNotExist.GenByArgs(indexName, tblInfo.Name)
+	}
+	if invisible && indexInfo.Primary {
+		job.State = model.JobCancelled
+		return errors.Trace(errPKIndexCantBeInvisible)
+	}
+
+	indexInfo.Invisible = invisible
+	return errors.Trace(finishTableOptionJob(t, job, tblInfo))
+}
+
+// checkGeneratedExprs checks whether the expressions of the hidden columns can be built on the table.
+func (d *ddl) checkGeneratedExprs(tblInfo *model.TableInfo, hiddenCols []*model.ColumnInfo) error {
+	ctx := d.newContext()
+	for _, col := range hiddenCols {
+		if _, err := table.BuildGeneratedExpr(ctx, tblInfo, col); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// buildGeneratedExprs builds the expressions of the hidden columns of the expression index by their offsets.
+func buildGeneratedExprs(ctx context.Context, tblInfo *model.TableInfo, indexInfo *model.IndexInfo) (
+	map[int]expression.Expression, error) {
+	var exprs map[int]expression.Expression
+	for _, ic := range indexInfo.Columns {
+		col := tblInfo.Columns[ic.Offset]
+		if !col.IsGenerated() {
+			continue
+		}
+		expr, err := table.BuildGeneratedExpr(ctx, tblInfo, col)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if exprs == nil {
+			exprs = make(map[int]expression.Expression)
+		}
+		exprs[ic.Offset] = expr
+	}
+	return exprs, nil
+}
+
+// evalGeneratedValue evaluates the value of the hidden column on the row for backfilling the index. The value which
+// fails to be evaluated is set to NULL, and the value which fails to be converted is truncated.
+func evalGeneratedValue(expr expression.Expression, col *model.ColumnInfo, row []types.Datum) types.Datum {
+	val, err := expr.Eval(row)
+	if err != nil {
+		log.Warnf("[ddl] evaluate the expression of column %s err %v", col.Name, err)
+		return types.Datum{}
+	}
+	sc := &variable.StatementContext{IgnoreTruncate: true}
+	casted, _ := val.ConvertTo(sc, &col.FieldType)
+	return casted
+}
+
 func (d *ddl) fetchRowColVals(txn kv.Transaction, t table.Table, taskOpInfo *indexTaskOpInfo, handleInfo *handleInfo) (
 	[]*indexRecord, *taskResult) {
-	handleCnt := defaultTaskHandleCnt
+	handleCnt := getReorgBatchSize()
 	rawRecords := make([][]byte, 0, handleCnt)
 	idxRecords := make([]*indexRecord, 0, handleCnt)
 	ret := &taskResult{doneHandle: handleInfo.startHandle}
 	err := d.iterateSnapshotRows(t, txn.StartTS(), handleInfo.startHandle,
 		func(h int64, rowKey kv.Key, rawRecord []byte) (bool, error) {
+			if h > taskOpInfo.endHandle {
+				return false, nil
+			}
 			rawRecords = append(rawRecords, rawRecord)
 			indexRecord := &indexRecord{handle: h, key: rowKey}
 			idxRecords = append(idxRecords, indexRecord)
@@ -412,17 +741,36 @@ func (d *ddl) fetchRowColVals(txn kv.Transaction, t table.Table, taskOpInfo *ind
 		return nil, ret
 	}
 
-	cols := t.Cols()
+	tblInfo := t.Meta()
 	idxInfo := taskOpInfo.tblIndex.Meta()
+	ctx := d.newContext()
+	genExprs, err := buildGeneratedExprs(ctx, tblInfo, idxInfo)
+	if err != nil {
+		ret.err = errors.Trace(err)
+		return nil, ret
+	}
 	for i, idxRecord := range idxRecords {
 		rowMap, err := tablecodec.DecodeRow(rawRecords[i], taskOpInfo.colMap)
 		if err != nil {
 			ret.err = errors.Trace(err)
 			return nil, ret
 		}
+		var row []types.Datum
+		if len(genExprs) > 0 {
+			// The expressions are evaluated on the row of all the public columns.
+			row, err = fillRowByColumns(ctx, tblInfo, idxRecord.handle, rowMap)
+			if err != nil {
+				ret.err = errors.Trace(err)
+				return nil, ret
+			}
+		}
 		idxVal := make([]types.Datum, 0, len(idxInfo.Columns))
 		for _, v := range idxInfo.Columns {
-			col := cols[v.Offset]
+			col := tblInfo.Columns[v.Offset]
+			if expr, ok := genExprs[v.Offset]; ok {
+				idxVal = append(idxVal, evalGeneratedValue(expr, col, row))
+				continue
+			}
 			idxVal = append(idxVal, rowMap[col.ID])
 		}
 		idxRecord.vals = idxVal
@@ -430,11 +778,35 @@ func (d *ddl) fetchRowColVals(txn kv.Transaction, t table.Table, taskOpInfo *ind
 	return idxRecords, ret
 }
 
+// fillRowByColumns returns the row of the public columns indexed by the column offsets. The values of the columns
+// which are not in the row data are the original default values.
+func fillRowByColumns(ctx context.Context, tblInfo *model.TableInfo, handle int64, rowMap map[int64]types.Datum) (
+	[]types.Datum, error) {
+	row := make([]types.Datum, len(tblInfo.Columns))
+	for _, col := range tblInfo.Columns {
+		if col.State != model.StatePublic {
+			continue
+		}
+		if tblInfo.PKIsHandle && mysql.HasPriKeyFlag(col.Flag) {
+			row[col.Offset] = types.NewIntDatum(handle)
+			continue
+		}
+		val, ok := rowMap[col.ID]
+		if !ok {
+			var err error
+			val, err = table.GetColOriginDefaultValue(ctx, col)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		row[col.Offset] = val
+	}
+	return row, nil
+}
+
 const (
 	defaultBatchCnt      = 1024
 	defaultSmallBatchCnt = 128
-	defaultTaskHandleCnt = 128
-	defaultTaskCnt       = 16
 )
 
 // taskResult is the result of the task.
@@ -463,11 +835,12 @@ type indexTaskOpInfo struct {
 	colMap    map[int64]*types.FieldType // It's the index columns map.
 	taskRetCh chan *taskResult           // Get the results of all tasks.
 	nextCh    chan int64                 // It notifies to start the next task.
+	endHandle int64                      // The tasks don't backfill the rows beyond it.
 }
 
 // How to add index in reorganization state?
-// Concurrently process the defaultTaskHandleCnt tasks. Each task deals with a handle range of the index record.
-// The handle range size is defaultTaskHandleCnt.
+// Concurrently process the tidb_ddl_reorg_worker_cnt tasks. Each task deals with a handle range of the index record.
+// The handle range size is tidb_ddl_reorg_batch_size.
 // Because each handle range depends on the previous one, it's necessary to obtain the handle range serially.
 // Real concurrent processing needs to perform after the handle range has been acquired.
 // The operation flow of the each task of data is as follows:
@@ -482,25 +855,46 @@ type indexTaskOpInfo struct {
 // task results, get the total number of rows in the concurrent task and update the processed handle value. If
 // an error message is displayed, exit the traversal.
 // Finally, update the concurrent processing of the total number of rows, and store the completed handle value.
+// The tasks only backfill the rows up to the last handle in the snapshot of the reorganization, the rows written
+// after the snapshot have been indexed by the writes in the write only state, so the concurrent writes can't keep the
+// reorganization from finishing.
 func (d *ddl) addTableIndex(t table.Table, indexInfo *model.IndexInfo, reorgInfo *reorgInfo, job *model.Job) error {
-	cols := t.Cols()
+	var err error
+	reorgInfo.EndHandle, err = d.getReorgEndHandle(t, reorgInfo.SnapshotVer)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[ddl] add index %s from handle %d to %d", indexInfo.Name, reorgInfo.Handle, reorgInfo.EndHandle)
+	tblInfo := t.Meta()
 	colMap := make(map[int64]*types.FieldType)
 	for _, v := range indexInfo.Columns {
-		col := cols[v.Offset]
-		colMap[col.ID] = &col.FieldType
+		col := tblInfo.Columns[v.Offset]
+		if !col.IsGenerated() {
+			colMap[col.ID] = &col.FieldType
+			continue
+		}
+		// The values of the hidden column are evaluated from the public columns.
+		for _, c := range tblInfo.Columns {
+			if c.State == model.StatePublic {
+				colMap[c.ID] = &c.FieldType
+			}
+		}
 	}
-	taskCnt := defaultTaskCnt
 	taskOpInfo := &indexTaskOpInfo{
 		tblIndex:  tables.NewIndex(t.Meta(), indexInfo),
 		colMap:    colMap,
 		nextCh:    make(chan int64, 1),
-		taskRetCh: make(chan *taskResult, taskCnt),
+		endHandle: reorgInfo.EndHandle,
 	}
 
 	addedCount := job.GetRowCount()
 	taskStartHandle := reorgInfo.Handle
 	for {
 		startTime := time.Now()
+		// The worker count may be changed while the job is running.
+		taskCnt := getReorgWorkerCnt()
+		taskOpInfo.taskRetCh = make(chan *taskResult, taskCnt)
+		reachEnd := false
 		wg := sync.WaitGroup{}
 		for i := 0; i < taskCnt; i++ {
 			wg.Add(1)
@@ -510,6 +904,10 @@ func (d *ddl) addTableIndex(t table.Table, indexInfo *model.IndexInfo, reorgInfo
 			if doneHandle == taskStartHandle {
 				break
 			}
+			if doneHandle >= reorgInfo.EndHandle {
+				reachEnd = true
+				break
+			}
 			taskStartHandle = doneHandle + 1
 		}
 		wg.Wait()
@@ -542,7 +940,7 @@ func (d *ddl) addTableIndex(t table.Table, indexInfo *model.IndexInfo, reorgInfo
 		log.Infof("[ddl] total added index for %d rows, this task added index for %d rows, take time %v",
 			addedCount, taskAddedCount, sub)
 
-		if retCnt < taskCnt {
+		if retCnt < taskCnt || reachEnd {
 			return nil
 		}
 	}
@@ -613,7 +1011,7 @@ func (d *ddl) doBackfillIndexTask(t table.Table, taskOpInfo *indexTaskOpInfo, st
 }
 
 // doBackfillIndexTaskInTxn deals with a part of backfilling index data in a Transaction.
-// This part of the index data rows is defaultTaskHandleCnt.
+// This part of the index data rows is tidb_ddl_reorg_batch_size.
 func (d *ddl) doBackfillIndexTaskInTxn(t table.Table, txn kv.Transaction, taskOpInfo *indexTaskOpInfo,
 	handleInfo *handleInfo) *taskResult {
 	idxRecords, taskRet := d.fetchRowColVals(txn, t, taskOpInfo, handleInfo)
@@ -675,6 +1073,8 @@ func (d *ddl) iterateSnapshotRows(t table.Table, version uint64, seekHandle int6
 	if err != nil {
 		return errors.Trace(err)
 	}
+	defer snap.Release()
+	snap.SetPriority(getReorgPriority())
 
 	firstKey := t.RecordKey(seekHandle)
 	it, err := snap.Seek(firstKey)
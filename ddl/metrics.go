@@ -39,6 +39,7 @@ var (
 	// handle batch data type.
 	batchAddCol              = "batch_add_col"
 	batchAddIdx              = "batch_add_idx"
+	batchModifyCol           = "batch_modify_col"
 	batchDelData             = "batch_del_data"
 	batchHandleDataHistogram = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -48,10 +49,32 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of batch handle data",
 			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 20),
 		}, []string{"handle_data_type"})
+
+	// schema sync result.
+	schemaSyncSucc               = "synced"
+	schemaSyncTimeout            = "timeout"
+	ownerWaitSchemaSyncHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "tidb",
+			Subsystem: "ddl",
+			Name:      "owner_wait_schema_sync_duration_seconds",
+			Help:      "Bucketed histogram of time (s) that the owner waits for all servers to load the latest schema",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 20),
+		}, []string{"result"})
+
+	schemaVersionLagGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "tidb",
+			Subsystem: "ddl",
+			Name:      "schema_version_lag",
+			Help:      "Gauge of the number of versions that the loaded schema lags behind the global schema.",
+		})
 )
 
 func init() {
 	prometheus.MustRegister(jobsGauge)
 	prometheus.MustRegister(handleJobHistogram)
 	prometheus.MustRegister(batchHandleDataHistogram)
+	prometheus.MustRegister(ownerWaitSchemaSyncHistogram)
+	prometheus.MustRegister(schemaVersionLagGauge)
 }
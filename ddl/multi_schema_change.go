@@ -0,0 +1,154 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+)
+
+// onMultiSchemaChange runs one step of the first unfinished sub-job. If a sub-job fails, the done sub-jobs are
+// reverted in the reverse order by their revert jobs.
+func (d *ddl) onMultiSchemaChange(t *meta.Meta, job *model.Job) error {
+	if job.State == model.JobRollback {
+		d.revertMultiSchemaChange(t, job)
+		return nil
+	}
+
+	info := job.MultiSchemaInfo
+	for i, sub := range info.SubJobs {
+		if sub.IsDone() {
+			continue
+		}
+
+		fillSubJob(job, sub)
+		if sub.State != model.JobRollback && sub.State != model.JobCancelling {
+			sub.State = model.JobRunning
+		}
+		err := d.runDDLJobStep(t, sub)
+		sub.BinlogInfo = nil
+		job.SchemaState = sub.SchemaState
+		updateMultiSchemaRowCount(job)
+		// The dropping and the column type change which has replaced the old column can't be reverted.
+		if info.RevertJobs[i] == nil && (sub.Type != model.ActionModifyColumn || sub.SchemaState == model.StatePublic) {
+			info.Revertible = false
+		}
+
+		if sub.State == model.JobCancelled || sub.State == model.JobRollbackDone {
+			failMultiSchemaChange(job, i)
+		} else if sub.IsDone() && i == len(info.SubJobs)-1 {
+			job.State = model.JobDone
+		}
+		return errors.Trace(err)
+	}
+
+	job.State = model.JobDone
+	return nil
+}
+
+// revertMultiSchemaChange runs one step of the revert job of the last done sub-job which isn't reverted. The job is
+// rolled back when all the done sub-jobs are reverted.
+func (d *ddl) revertMultiSchemaChange(t *meta.Meta, job *model.Job) {
+	info := job.MultiSchemaInfo
+	for i := len(info.SubJobs) - 1; i >= 0; i-- {
+		revertJob := info.RevertJobs[i]
+		if !info.SubJobs[i].IsDone() || revertJob == nil || revertJob.IsFinished() {
+			continue
+		}
+
+		fillSubJob(job, revertJob)
+		revertJob.State = model.JobRunning
+		// Keep the error of the failed sub-job in the job, the revert job is retried if it isn't finished.
+		err := d.runDDLJobStep(t, revertJob)
+		if err != nil {
+			log.Warnf("[ddl] revert the sub-job of job %d err %v", job.ID, errors.ErrorStack(err))
+		}
+		revertJob.BinlogInfo = nil
+		job.SchemaState = revertJob.SchemaState
+		return
+	}
+
+	job.State = model.JobRollbackDone
+}
+
+// cancelMultiSchemaChange cancels the running sub-job. The job goes on running if it can't be reverted.
+func (d *ddl) cancelMultiSchemaChange(t *meta.Meta, job *model.Job) error {
+	info := job.MultiSchemaInfo
+	if !info.Revertible {
+		job.State = model.JobRunning
+		log.Warnf("[ddl] the job %s has made the changes that can't be reverted, go on running", job)
+		return nil
+	}
+
+	for i, sub := range info.SubJobs {
+		if sub.IsDone() {
+			continue
+		}
+		if sub.State == model.JobRollback {
+			// The sub-job is being rolled back.
+			job.State = model.JobRunning
+			return nil
+		}
+
+		fillSubJob(job, sub)
+		sub.State = model.JobCancelling
+		err := d.cancelJob(t, sub)
+		sub.BinlogInfo = nil
+		job.SchemaState = sub.SchemaState
+		if sub.State == model.JobCancelled {
+			failMultiSchemaChange(job, i)
+		} else {
+			job.State = model.JobRunning
+		}
+		return errors.Trace(err)
+	}
+
+	job.State = model.JobRunning
+	return nil
+}
+
+// failMultiSchemaChange handles the failed sub-job at the offset. The job is rolled back if there are done sub-jobs,
+// otherwise it's finished in the state of the sub-job.
+func failMultiSchemaChange(job *model.Job, offset int) {
+	sub := job.MultiSchemaInfo.SubJobs[offset]
+	if offset == 0 {
+		job.State = sub.State
+		return
+	}
+	if !job.MultiSchemaInfo.Revertible {
+		log.Warnf("[ddl] the job %s can't revert the done sub-jobs", job)
+		job.State = model.JobRollbackDone
+		return
+	}
+	job.State = model.JobRollback
+}
+
+// fillSubJob fills the sub-job with the information of the multi-schema change job before it runs.
+func fillSubJob(job *model.Job, sub *model.Job) {
+	sub.ID = job.ID
+	sub.SchemaID = job.SchemaID
+	sub.TableID = job.TableID
+	sub.Query = job.Query
+	sub.BinlogInfo = job.BinlogInfo
+}
+
+func updateMultiSchemaRowCount(job *model.Job) {
+	var rowCount int64
+	for _, sub := range job.MultiSchemaInfo.SubJobs {
+		rowCount += sub.GetRowCount()
+	}
+	job.SetRowCount(rowCount)
+}
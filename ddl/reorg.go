@@ -14,6 +14,9 @@
 package ddl
 
 import (
+	"math"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -24,6 +27,9 @@ import (
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 )
@@ -38,6 +44,63 @@ func (d *ddl) newContext() context.Context {
 
 const waitReorgTimeout = 10 * time.Second
 
+const (
+	maxReorgWorkerCnt = 128
+	maxReorgBatchSize = 10240
+)
+
+// The resources used by the reorganization, they're set by the global variables and
+// can be changed while the job is running.
+var (
+	reorgWorkerCnt int32 = variable.DefDDLReorgWorkerCount
+	reorgBatchSize int32 = variable.DefDDLReorgBatchSize
+	reorgPriority  int32 = kv.PriorityLow
+)
+
+var reorgPriorities = map[string]int32{
+	"PRIORITY_LOW":    kv.PriorityLow,
+	"PRIORITY_NORMAL": kv.PriorityNormal,
+	"PRIORITY_HIGH":   kv.PriorityHigh,
+}
+
+// SetReorgVariable sets the global variable that limits the resources used by the reorganization,
+// the other variables are ignored. It returns an error if the value is invalid.
+func SetReorgVariable(name, val string) error {
+	switch name {
+	case variable.TiDBDDLReorgWorkerCount:
+		cnt, err := strconv.Atoi(val)
+		if err != nil || cnt < 1 || cnt > maxReorgWorkerCnt {
+			return variable.ErrWrongValueForVar.GenByArgs(name, val)
+		}
+		atomic.StoreInt32(&reorgWorkerCnt, int32(cnt))
+	case variable.TiDBDDLReorgBatchSize:
+		size, err := strconv.Atoi(val)
+		if err != nil || size < 1 || size > maxReorgBatchSize {
+			return variable.ErrWrongValueForVar.GenByArgs(name, val)
+		}
+		atomic.StoreInt32(&reorgBatchSize, int32(size))
+	case variable.TiDBDDLReorgPriority:
+		priority, ok := reorgPriorities[strings.ToUpper(val)]
+		if !ok {
+			return variable.ErrWrongValueForVar.GenByArgs(name, val)
+		}
+		atomic.StoreInt32(&reorgPriority, priority)
+	}
+	return nil
+}
+
+func getReorgWorkerCnt() int {
+	return int(atomic.LoadInt32(&reorgWorkerCnt))
+}
+
+func getReorgBatchSize() int {
+	return int(atomic.LoadInt32(&reorgBatchSize))
+}
+
+func getReorgPriority() int {
+	return int(atomic.LoadInt32(&reorgPriority))
+}
+
 func (d *ddl) setReorgRowCount(count int64) {
 	atomic.StoreInt64(&d.reorgRowCount, count)
 }
@@ -46,6 +109,15 @@ func (d *ddl) getReorgRowCount() int64 {
 	return atomic.LoadInt64(&d.reorgRowCount)
 }
 
+// notifyReorgCancel notifies the running reorganization job to stop, then it returns errCancelledDDLJob.
+func (d *ddl) notifyReorgCancel() {
+	atomic.StoreInt32(&d.reorgCancelled, 1)
+}
+
+func (d *ddl) isReorgCancelled() bool {
+	return atomic.LoadInt32(&d.reorgCancelled) == 1
+}
+
 func (d *ddl) runReorgJob(job *model.Job, f func() error) error {
 	if d.reorgDoneCh == nil {
 		// start a reorganization job
@@ -75,10 +147,12 @@ func (d *ddl) runReorgJob(job *model.Job, f func() error) error {
 		// Update a job's RowCount.
 		job.SetRowCount(d.getReorgRowCount())
 		d.setReorgRowCount(0)
+		atomic.StoreInt32(&d.reorgCancelled, 0)
 		return errors.Trace(err)
 	case <-d.quitCh:
 		log.Info("[ddl] run reorg job ddl quit")
 		d.setReorgRowCount(0)
+		atomic.StoreInt32(&d.reorgCancelled, 0)
 		// We return errWaitReorgTimeout here too, so that outer loop will break.
 		return errWaitReorgTimeout
 	case <-time.After(waitTimeout):
@@ -90,11 +164,26 @@ func (d *ddl) runReorgJob(job *model.Job, f func() error) error {
 	}
 }
 
+// currentVersion returns the current version of the storage.
+func (d *ddl) currentVersion() (uint64, error) {
+	ver, err := d.store.CurrentVersion()
+	if err != nil {
+		return 0, errors.Trace(err)
+	} else if ver.Ver <= 0 {
+		return 0, errInvalidStoreVer.Gen("invalid storage current version %d", ver.Ver)
+	}
+	return ver.Ver, nil
+}
+
 func (d *ddl) isReorgRunnable(txn kv.Transaction, flag JobType) error {
 	if d.isClosed() {
 		// worker is closed, can't run reorganization.
 		return errInvalidWorker.Gen("worker is closed")
 	}
+	if flag == ddlJobFlag && d.isReorgCancelled() {
+		// The reorganization job is cancelled, it's rolled back later.
+		return errCancelledDDLJob
+	}
 
 	t := meta.NewMeta(txn)
 	owner, err := d.getJobOwner(t, flag)
@@ -107,6 +196,16 @@ func (d *ddl) isReorgRunnable(txn kv.Transaction, flag JobType) error {
 		log.Infof("[ddl] %s job, self id %s owner %s, txnTS:%d", flag, d.uuid, owner, txn.StartTS())
 		return errors.Trace(errNotOwner)
 	}
+	if flag == bgJobFlag {
+		// The background job deleting the data of a dropped table is cancelled if the table is recovered.
+		job, err := t.GetBgJob(0)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if job != nil && job.IsCancelling() {
+			return errCancelledDDLJob
+		}
+	}
 
 	return nil
 }
@@ -193,8 +292,11 @@ func (d *ddl) delKeysWithStartKey(prefix, startKey kv.Key, jobType JobType, job
 type reorgInfo struct {
 	*model.Job
 	Handle int64
-	d      *ddl
-	first  bool
+	// EndHandle is the last handle of the table in the snapshot of the reorganization. The rows written after the
+	// snapshot are handled by the writes themselves, so the reorganization doesn't need to scan beyond it.
+	EndHandle int64
+	d         *ddl
+	first     bool
 }
 
 func (d *ddl) getReorgInfo(t *meta.Meta, job *model.Job) (*reorgInfo, error) {
@@ -208,15 +310,13 @@ func (d *ddl) getReorgInfo(t *meta.Meta, job *model.Job) (*reorgInfo, error) {
 
 	if info.first {
 		// get the current version for reorganization if we don't have
-		var ver kv.Version
-		ver, err = d.store.CurrentVersion()
+		job.SnapshotVer, err = d.currentVersion()
 		if err != nil {
 			return nil, errors.Trace(err)
-		} else if ver.Ver <= 0 {
-			return nil, errInvalidStoreVer.Gen("invalid storage current version %d", ver.Ver)
 		}
-
-		job.SnapshotVer = ver.Ver
+		// Start from the smallest handle, the handles may be negative if the primary key is the handle.
+		info.Handle = math.MinInt64
+		err = t.UpdateDDLReorgHandle(job, info.Handle)
 	} else {
 		info.Handle, err = t.GetDDLReorgHandle(job)
 		if err != nil {
@@ -236,3 +336,28 @@ func (r *reorgInfo) UpdateHandle(txn kv.Transaction, handle int64) error {
 	t := meta.NewMeta(txn)
 	return errors.Trace(t.UpdateDDLReorgHandle(r.Job, handle))
 }
+
+// getReorgEndHandle gets the last handle of the table in the snapshot of the version. It returns math.MaxInt64 if the
+// storage doesn't support reverse seeking, and the reorganization scans to the end of the table.
+func (d *ddl) getReorgEndHandle(t table.Table, version uint64) (int64, error) {
+	snap, err := d.store.GetSnapshot(kv.Version{Ver: version})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer snap.Release()
+	snap.SetPriority(getReorgPriority())
+	it, err := snap.SeekReverse(t.RecordPrefix().PrefixNext())
+	if terror.ErrorEqual(err, kv.ErrNotImplemented) {
+		return math.MaxInt64, nil
+	}
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer it.Close()
+	if !it.Valid() || !it.Key().HasPrefix(t.RecordPrefix()) {
+		// The table is empty.
+		return math.MinInt64, nil
+	}
+	handle, err := tablecodec.DecodeRowKey(it.Key())
+	return handle, errors.Trace(err)
+}
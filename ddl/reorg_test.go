@@ -14,6 +14,7 @@
 package ddl
 
 import (
+	"math"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -171,3 +172,45 @@ func (s *testDDLSuite) TestReorgOwner(c *C) {
 	})
 	c.Assert(err, IsNil)
 }
+
+func (s *testDDLSuite) TestReorgEndHandle(c *C) {
+	defer testleak.AfterTest(c)()
+	store := testCreateStore(c, "test_reorg_end_handle")
+	defer store.Close()
+
+	d := newDDL(store, nil, nil, testLease)
+	defer d.Stop()
+
+	ctx := testNewContext(d)
+	dbInfo := testSchemaInfo(c, d, "test")
+	testCreateSchema(c, ctx, d, dbInfo)
+	tblInfo := testTableInfo(c, d, "t", 3)
+	testCreateTable(c, ctx, d, dbInfo, tblInfo)
+	t := testGetTable(c, d, dbInfo.ID, tblInfo.ID)
+
+	ver, err := store.CurrentVersion()
+	c.Assert(err, IsNil)
+	handle, err := d.getReorgEndHandle(t, ver.Ver)
+	c.Assert(err, IsNil)
+	c.Assert(handle, Equals, int64(math.MinInt64))
+
+	c.Assert(ctx.NewTxn(), IsNil)
+	var lastHandle int64
+	for i := 0; i < 10; i++ {
+		lastHandle, err = t.AddRecord(ctx, types.MakeDatums(i, i, i))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(ctx.Txn().Commit(), IsNil)
+	ver, err = store.CurrentVersion()
+	c.Assert(err, IsNil)
+
+	// The rows added after the snapshot are not counted.
+	c.Assert(ctx.NewTxn(), IsNil)
+	_, err = t.AddRecord(ctx, types.MakeDatums(10, 10, 10))
+	c.Assert(err, IsNil)
+	c.Assert(ctx.Txn().Commit(), IsNil)
+
+	handle, err = d.getReorgEndHandle(t, ver.Ver)
+	c.Assert(err, IsNil)
+	c.Assert(handle, Equals, lastHandle)
+}
@@ -0,0 +1,206 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+)
+
+// SchemaSyncInterval is the interval to check the global schema version and the schema versions
+// loaded by the servers. If it's 0, the servers only learn of a new schema version when they
+// reload the schema every lease/2, and the DDL owner always waits 2 * lease for a schema change.
+var SchemaSyncInterval = 100 * time.Millisecond
+
+// SchemaSyncer syncs the schema versions between the DDL owner and the other servers through the storage.
+// Every server records the schema version it has loaded, so the owner doesn't need to wait 2 * lease
+// when all the servers have caught up with the latest schema version.
+type SchemaSyncer interface {
+	// UpdateSelfVersion records the schema version loaded by this server.
+	UpdateSelfVersion(version int64) error
+	// RemoveSelfVersion removes the schema version record of this server.
+	RemoveSelfVersion() error
+	// GlobalVersionCh returns the channel which is notified when the global schema version changes.
+	GlobalVersionCh() <-chan struct{}
+}
+
+type schemaSyncer struct {
+	store  kv.Storage
+	selfID string
+	// selfVersion is the schema version loaded by this server.
+	selfVersion   int64
+	globalVersion int64
+	globalVerCh   chan struct{}
+}
+
+func newSchemaSyncer(store kv.Storage, selfID string) *schemaSyncer {
+	return &schemaSyncer{
+		store:       store,
+		selfID:      selfID,
+		globalVerCh: make(chan struct{}, 1),
+	}
+}
+
+// UpdateSelfVersion implements SchemaSyncer.UpdateSelfVersion interface.
+func (s *schemaSyncer) UpdateSelfVersion(version int64) error {
+	atomic.StoreInt64(&s.selfVersion, version)
+	if SchemaSyncInterval == 0 {
+		return nil
+	}
+
+	err := kv.RunInNewTxn(s.store, true, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		return t.SetSchemaSyncInfo(&model.SchemaSyncInfo{
+			ServerID:     s.selfID,
+			Version:      version,
+			LastUpdateTS: time.Now().UnixNano(),
+		})
+	})
+	return errors.Trace(err)
+}
+
+// RemoveSelfVersion implements SchemaSyncer.RemoveSelfVersion interface.
+func (s *schemaSyncer) RemoveSelfVersion() error {
+	if SchemaSyncInterval == 0 {
+		return nil
+	}
+
+	err := kv.RunInNewTxn(s.store, true, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		return t.DelSchemaSyncInfo(s.selfID)
+	})
+	return errors.Trace(err)
+}
+
+// GlobalVersionCh implements SchemaSyncer.GlobalVersionCh interface.
+func (s *schemaSyncer) GlobalVersionCh() <-chan struct{} {
+	return s.globalVerCh
+}
+
+func (s *schemaSyncer) getGlobalVersion() (int64, error) {
+	ver, err := s.store.CurrentVersion()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	snapshot, err := s.store.GetSnapshot(ver)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer snapshot.Release()
+	latestVer, err := meta.NewSnapshotMeta(snapshot).GetSchemaVersion()
+	return latestVer, errors.Trace(err)
+}
+
+// checkGlobalVersion notifies GlobalVersionCh if the global schema version changes.
+func (s *schemaSyncer) checkGlobalVersion() {
+	latestVer, err := s.getGlobalVersion()
+	if err != nil {
+		log.Warnf("[ddl] get global schema version err %v", errors.ErrorStack(err))
+		return
+	}
+
+	schemaVersionLagGauge.Set(float64(latestVer - atomic.LoadInt64(&s.selfVersion)))
+	if latestVer != s.globalVersion {
+		s.globalVersion = latestVer
+		asyncNotify(s.globalVerCh)
+	}
+}
+
+// watchGlobalVersion checks the global schema version every SchemaSyncInterval,
+// so the server can reload the schema as soon as the DDL owner changes it.
+func (s *schemaSyncer) watchGlobalVersion(quitCh chan struct{}) {
+	ticker := time.NewTicker(SchemaSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkGlobalVersion()
+		case <-quitCh:
+			return
+		}
+	}
+}
+
+// ownerCheckAllVersions waits until all the live servers have loaded the schema version latestVer.
+// A server is considered as down if it hasn't updated its schema version in 2 * lease, its record
+// is removed if it hasn't been updated in maxOwnerTimeout. If there isn't any live server, it waits
+// for waitTime.
+// It returns true if all the servers have caught up within waitTime.
+func (s *schemaSyncer) ownerCheckAllVersions(latestVer int64, lease time.Duration, waitTime time.Duration, quitCh chan struct{}) bool {
+	ticker := time.NewTicker(SchemaSyncInterval)
+	defer ticker.Stop()
+	timeout := time.After(waitTime)
+
+	for {
+		synced, err := s.isAllVersionsSynced(latestVer, lease)
+		if err != nil {
+			log.Warnf("[ddl] check all schema versions err %v", errors.ErrorStack(err))
+		} else if synced {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			return false
+		case <-quitCh:
+			return false
+		}
+	}
+}
+
+func (s *schemaSyncer) isAllVersionsSynced(latestVer int64, lease time.Duration) (bool, error) {
+	var synced bool
+	err := kv.RunInNewTxn(s.store, true, func(txn kv.Transaction) error {
+		synced = false
+		liveCnt := 0
+		t := meta.NewMeta(txn)
+		infos, err := t.GetAllSchemaSyncInfos()
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		now := time.Now().UnixNano()
+		for _, info := range infos {
+			elapsed := now - info.LastUpdateTS
+			if elapsed > maxOwnerTimeout {
+				log.Infof("[ddl] remove the expired schema version of server %s", info.ServerID)
+				if err = t.DelSchemaSyncInfo(info.ServerID); err != nil {
+					return errors.Trace(err)
+				}
+				continue
+			}
+			if elapsed > int64(2*lease) {
+				continue
+			}
+			if info.Version < latestVer {
+				log.Debugf("[ddl] server %s schema version %d is behind %d", info.ServerID, info.Version, latestVer)
+				return nil
+			}
+			liveCnt++
+		}
+		// If there isn't any live server, the servers don't record their versions,
+		// so we can't know whether they are synced.
+		synced = liveCnt > 0
+		return nil
+	})
+	return synced, errors.Trace(err)
+}
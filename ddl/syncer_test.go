@@ -0,0 +1,89 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+var _ = Suite(&testSyncerSuite{})
+
+type testSyncerSuite struct{}
+
+func (s *testSyncerSuite) TestSyncer(c *C) {
+	defer testleak.AfterTest(c)()
+	store := testCreateStore(c, "test_syncer")
+	defer store.Close()
+
+	d := newDDL(store, nil, nil, testLease)
+	defer d.Stop()
+	syncer := d.schemaSyncer
+
+	// The owner waits for the whole time if there isn't any server.
+	c.Assert(syncer.ownerCheckAllVersions(1, time.Hour, 10*testLease, d.quitCh), IsFalse)
+
+	// The owner waits for the servers which are behind the latest version.
+	err := syncer.UpdateSelfVersion(1)
+	c.Assert(err, IsNil)
+	other := newSchemaSyncer(store, "other")
+	err = other.UpdateSelfVersion(0)
+	c.Assert(err, IsNil)
+	c.Assert(syncer.ownerCheckAllVersions(1, time.Hour, 10*testLease, d.quitCh), IsFalse)
+	err = other.UpdateSelfVersion(1)
+	c.Assert(err, IsNil)
+	c.Assert(syncer.ownerCheckAllVersions(1, time.Hour, time.Hour, d.quitCh), IsTrue)
+
+	// The servers which haven't updated their versions in 2 * lease are considered as down.
+	err = other.UpdateSelfVersion(0)
+	c.Assert(err, IsNil)
+	err = syncer.UpdateSelfVersion(1)
+	c.Assert(err, IsNil)
+	time.Sleep(10 * time.Millisecond)
+	err = syncer.UpdateSelfVersion(1)
+	c.Assert(err, IsNil)
+	c.Assert(syncer.ownerCheckAllVersions(1, 2*time.Millisecond, time.Hour, d.quitCh), IsTrue)
+
+	// The version is removed when the server stops.
+	err = other.RemoveSelfVersion()
+	c.Assert(err, IsNil)
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		infos, err1 := meta.NewMeta(txn).GetAllSchemaSyncInfos()
+		c.Assert(err1, IsNil)
+		c.Assert(infos, HasLen, 1)
+		c.Assert(infos[0].ServerID, Equals, d.uuid)
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	// The server is notified when the global schema version changes.
+	select {
+	case <-syncer.GlobalVersionCh():
+	default:
+	}
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		_, err1 := meta.NewMeta(txn).GenSchemaVersion()
+		return err1
+	})
+	c.Assert(err, IsNil)
+	select {
+	case <-syncer.GlobalVersionCh():
+	case <-time.After(time.Second):
+		c.Fatal("the global schema version change isn't notified")
+	}
+}
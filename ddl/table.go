@@ -15,6 +15,7 @@ package ddl
 
 import (
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
@@ -102,6 +103,10 @@ func (d *ddl) onDropTable(t *meta.Meta, job *model.Job) error {
 		if err = t.DropTable(job.SchemaID, job.TableID); err != nil {
 			break
 		}
+		// The data isn't deleted until the background job runs, it's complete in the current snapshot.
+		if job.SnapshotVer, err = d.currentVersion(); err != nil {
+			return errors.Trace(err)
+		}
 		// Finish this job.
 		job.State = model.JobDone
 		job.BinlogInfo.AddTableInfo(ver, tblInfo)
@@ -141,7 +146,7 @@ func (d *ddl) getTable(schemaID int64, tblInfo *model.TableInfo) (table.Table, e
 	if tblInfo.OldSchemaID != 0 {
 		schemaID = tblInfo.OldSchemaID
 	}
-	alloc := autoid.NewAllocator(d.store, schemaID)
+	alloc := autoid.NewAllocatorWithStep(d.store, schemaID, tblInfo.AutoIDCache)
 	tbl, err := table.TableFromMeta(alloc, tblInfo)
 	return tbl, errors.Trace(err)
 }
@@ -209,6 +214,10 @@ func (d *ddl) onTruncateTable(t *meta.Meta, job *model.Job) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	// The old table can be recovered from the current snapshot.
+	if job.SnapshotVer, err = d.currentVersion(); err != nil {
+		return errors.Trace(err)
+	}
 	job.State = model.JobDone
 	job.BinlogInfo.AddTableInfo(ver, tblInfo)
 	startKey := tablecodec.EncodeTablePrefix(tableID)
@@ -216,49 +225,326 @@ func (d *ddl) onTruncateTable(t *meta.Meta, job *model.Job) error {
 	return nil
 }
 
-func (d *ddl) onRenameTable(t *meta.Meta, job *model.Job) error {
-	var oldSchemaID int64
-	var tableName model.CIStr
-	if err := job.DecodeArgs(&oldSchemaID, &tableName); err != nil {
+// onRecoverTable recovers the dropped or truncated table with its old table ID. The background job which deletes its
+// data is cancelled first, then the keys in the snapshot before it's dropped are written back, at last the table
+// becomes public.
+func (d *ddl) onRecoverTable(t *meta.Meta, job *model.Job) error {
+	schemaID := job.SchemaID
+	tblInfo := &model.TableInfo{}
+	var (
+		autoID     int64
+		snapshotTS uint64
+	)
+	if err := job.DecodeArgs(tblInfo, &autoID, &snapshotTS); err != nil {
 		// Invalid arguments, cancel this job.
 		job.State = model.JobCancelled
 		return errors.Trace(err)
 	}
 
-	tblInfo, err := getTableInfo(t, job, oldSchemaID)
+	switch job.SchemaState {
+	case model.StateNone:
+		// none -> write reorganization
+		if err := checkRecoverTable(t, job, schemaID, tblInfo); err != nil {
+			return errors.Trace(err)
+		}
+		if err := cancelDropTableBgJobs(t, tblInfo.ID); err != nil {
+			return errors.Trace(err)
+		}
+		// The table isn't written to the meta until its data is restored, so it's invisible in the meantime.
+		job.SchemaState = model.StateWriteReorganization
+		return nil
+	case model.StateWriteReorganization:
+		// write reorganization -> public
+		err := d.runReorgJob(job, func() error {
+			return d.restoreTableData(tblInfo.ID, snapshotTS)
+		})
+		if err != nil {
+			if terror.ErrorEqual(err, errWaitReorgTimeout) {
+				// if timeout, we should return, check for the owner and re-wait job done.
+				return nil
+			}
+			return errors.Trace(d.cancelRecoverTable(t, job, err))
+		}
+		// Another table of the same name may be created during the restoring.
+		if err = checkRecoverTable(t, job, schemaID, tblInfo); err != nil {
+			return errors.Trace(d.cancelRecoverTable(t, job, err))
+		}
+
+		tblInfo.State = model.StatePublic
+		if err = t.CreateTable(schemaID, tblInfo); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err = t.GenAutoTableID(schemaID, tblInfo.ID, autoID); err != nil {
+			return errors.Trace(err)
+		}
+		ver, err := updateSchemaVersion(t, job)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// Finish this job.
+		job.SchemaState = model.StatePublic
+		job.State = model.JobDone
+		job.BinlogInfo.AddTableInfo(ver, tblInfo)
+		return nil
+	default:
+		return ErrInvalidTableState.Gen("invalid recover table state %v", job.SchemaState)
+	}
+}
+
+// checkRecoverTable checks that neither the table ID nor the table name is taken in the schema.
+func checkRecoverTable(t *meta.Meta, job *model.Job, schemaID int64, tblInfo *model.TableInfo) error {
+	oldTblInfo, err := t.GetTable(schemaID, tblInfo.ID)
+	if err != nil {
+		if terror.ErrorEqual(err, meta.ErrDBNotExists) {
+			job.State = model.JobCancelled
+			return errors.Trace(infoschema.ErrDatabaseNotExists)
+		}
+		return errors.Trace(err)
+	}
+	if oldTblInfo != nil {
+		job.State = model.JobCancelled
+		return errTableRecovered.GenByArgs(oldTblInfo.Name)
+	}
+	return errors.Trace(checkTableNotExists(t, job, schemaID, tblInfo.Name.L))
+}
+
+// cancelDropTableBgJobs requests to cancel the background jobs which delete the data of the table, the data deleted
+// in the snapshot of a cancelled job isn't deleted any more.
+func cancelDropTableBgJobs(t *meta.Meta, tableID int64) error {
+	n, err := t.BgJobQueueLen()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	newSchemaID := job.SchemaID
-	if newSchemaID != oldSchemaID {
-		err = checkTableNotExists(t, job, newSchemaID, tblInfo.Name.L)
+	for i := int64(0); i < n; i++ {
+		job, err := t.GetBgJob(i)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if tblInfo.OldSchemaID == 0 {
-			tblInfo.OldSchemaID = oldSchemaID
+		if job == nil || job.TableID != tableID || job.IsFinished() {
+			continue
+		}
+		if job.Type != model.ActionDropTable && job.Type != model.ActionTruncateTable {
+			continue
+		}
+		job.State = model.JobCancelling
+		if err = t.UpdateBgJob(i, job); err != nil {
+			return errors.Trace(err)
 		}
 	}
+	return nil
+}
 
-	err = t.DropTable(oldSchemaID, tblInfo.ID)
+// cancelRecoverTable cancels the recover table job which fails to restore the data, the partially restored data is
+// deleted by a new background job.
+func (d *ddl) cancelRecoverTable(t *meta.Meta, job *model.Job, cause error) error {
+	log.Warnf("[ddl] run DDL job %v err %v, cancel the job", job, cause)
+	job.State = model.JobCancelled
+	bgJob := &model.Job{
+		ID:       job.ID,
+		SchemaID: job.SchemaID,
+		TableID:  job.TableID,
+		Type:     model.ActionDropTable,
+		Args:     []interface{}{tablecodec.EncodeTablePrefix(job.TableID)},
+	}
+	if err := d.prepareBgJob(t, bgJob); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cause)
+}
+
+// restoreTableData writes the keys of the table in the snapshot back in batches, so the keys deleted after the
+// snapshot are restored.
+func (d *ddl) restoreTableData(tableID int64, snapshotTS uint64) error {
+	snapshot, err := d.store.GetSnapshot(kv.NewVersion(snapshotTS))
 	if err != nil {
+		return errors.Trace(err)
+	}
+	defer snapshot.Release()
+	prefix := tablecodec.EncodeTablePrefix(tableID)
+	iter, err := snapshot.Seek(prefix)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer iter.Close()
+
+	var total int64
+	keys := make([]kv.Key, 0, defaultBatchCnt)
+	values := make([][]byte, 0, defaultBatchCnt)
+	for {
+		keys, values = keys[:0], values[:0]
+		for len(keys) < defaultBatchCnt && iter.Valid() && iter.Key().HasPrefix(prefix) {
+			keys = append(keys, iter.Key().Clone())
+			values = append(values, append([]byte(nil), iter.Value()...))
+			if err = iter.Next(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		err = kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+			if err1 := d.isReorgRunnable(txn, ddlJobFlag); err1 != nil {
+				return errors.Trace(err1)
+			}
+			for i, key := range keys {
+				if err1 := txn.Set(key, values[i]); err1 != nil {
+					return errors.Trace(err1)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		total += int64(len(keys))
+		d.setReorgRowCount(total)
+	}
+}
+
+func (d *ddl) onRenameTable(t *meta.Meta, job *model.Job) error {
+	var oldSchemaID int64
+	var tableName model.CIStr
+	if err := job.DecodeArgs(&oldSchemaID, &tableName); err != nil {
+		// Invalid arguments, cancel this job.
 		job.State = model.JobCancelled
 		return errors.Trace(err)
 	}
-	tblInfo.Name = tableName
-	err = t.CreateTable(newSchemaID, tblInfo)
+
+	tblInfos, err := renameTables(t, job, []int64{oldSchemaID}, []int64{job.SchemaID}, []model.CIStr{tableName},
+		[]int64{job.TableID})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	ver, err := updateSchemaVersion(t, job)
 	if err != nil {
+		return errors.Trace(err)
+	}
+	job.State = model.JobDone
+	job.SchemaState = model.StatePublic
+	job.BinlogInfo.AddTableInfo(ver, tblInfos[0])
+	return nil
+}
+
+func (d *ddl) onRenameTables(t *meta.Meta, job *model.Job) error {
+	var oldSchemaIDs, newSchemaIDs, tableIDs []int64
+	var tableNames []model.CIStr
+	if err := job.DecodeArgs(&oldSchemaIDs, &newSchemaIDs, &tableNames, &tableIDs); err != nil {
+		// Invalid arguments, cancel this job.
 		job.State = model.JobCancelled
 		return errors.Trace(err)
 	}
 
+	tblInfos, err := renameTables(t, job, oldSchemaIDs, newSchemaIDs, tableNames, tableIDs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	ver, err := updateSchemaVersion(t, job)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	job.State = model.JobDone
 	job.SchemaState = model.StatePublic
-	job.BinlogInfo.AddTableInfo(ver, tblInfo)
+	job.BinlogInfo.SetTableInfos(ver, tblInfos)
+	return nil
+}
+
+// renameTables renames the tables in order. All the renames are checked before changing anything, so the tables are
+// renamed atomically. The auto increment ID of the table is moved along with the table.
+func renameTables(t *meta.Meta, job *model.Job, oldSchemaIDs, newSchemaIDs []int64, tableNames []model.CIStr,
+	tableIDs []int64) ([]*model.TableInfo, error) {
+	err := checkRenameTables(t, job, oldSchemaIDs, newSchemaIDs, tableNames, tableIDs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	tblInfos := make([]*model.TableInfo, 0, len(tableIDs))
+	for i, tableID := range tableIDs {
+		tblInfo, err := t.GetTable(oldSchemaIDs[i], tableID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		autoIDSchemaID := oldSchemaIDs[i]
+		if tblInfo.OldSchemaID != 0 {
+			autoIDSchemaID = tblInfo.OldSchemaID
+		}
+		baseID, err := t.GetAutoTableID(autoIDSchemaID, tableID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		err = t.DropTable(oldSchemaIDs[i], tableID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tblInfo.Name = tableNames[i]
+		tblInfo.OldSchemaID = 0
+		err = t.CreateTable(newSchemaIDs[i], tblInfo)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		_, err = t.GenAutoTableID(newSchemaIDs[i], tableID, baseID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tblInfos = append(tblInfos, tblInfo)
+	}
+	return tblInfos, nil
+}
+
+// checkRenameTables checks the renames in order with the table names changed by the preceding renames.
+func checkRenameTables(t *meta.Meta, job *model.Job, oldSchemaIDs, newSchemaIDs []int64, tableNames []model.CIStr,
+	tableIDs []int64) error {
+	// tables records the table IDs of the table names in every database.
+	tables := make(map[int64]map[string]int64)
+	getTables := func(schemaID int64) (map[string]int64, error) {
+		if names, ok := tables[schemaID]; ok {
+			return names, nil
+		}
+		tblInfos, err := t.ListTables(schemaID)
+		if err != nil {
+			if terror.ErrorEqual(err, meta.ErrDBNotExists) {
+				job.State = model.JobCancelled
+				return nil, infoschema.ErrDatabaseNotExists.GenByArgs("")
+			}
+			return nil, errors.Trace(err)
+		}
+		names := make(map[string]int64, len(tblInfos))
+		for _, tblInfo := range tblInfos {
+			names[tblInfo.Name.L] = tblInfo.ID
+		}
+		tables[schemaID] = names
+		return names, nil
+	}
+
+	for i, tableID := range tableIDs {
+		oldTables, err := getTables(oldSchemaIDs[i])
+		if err != nil {
+			return errors.Trace(err)
+		}
+		newTables, err := getTables(newSchemaIDs[i])
+		if err != nil {
+			return errors.Trace(err)
+		}
+		oldName := ""
+		for name, id := range oldTables {
+			if id == tableID {
+				oldName = name
+				break
+			}
+		}
+		if oldName == "" {
+			job.State = model.JobCancelled
+			return errors.Trace(infoschema.ErrTableNotExists)
+		}
+		if _, ok := newTables[tableNames[i].L]; ok {
+			// The table already exists and can't be renamed to, we should cancel this job now.
+			job.State = model.JobCancelled
+			return infoschema.ErrTableExists.GenByArgs(tableNames[i])
+		}
+		delete(oldTables, oldName)
+		newTables[tableNames[i].L] = tableID
+	}
 	return nil
 }
 
@@ -285,6 +571,118 @@ func checkTableNotExists(t *meta.Meta, job *model.Job, schemaID int64, tableName
 	return nil
 }
 
+// onRebaseAutoID rebases the auto ID of the table, so the next auto ID is the AUTO_INCREMENT value. The IDs aren't
+// allocated by the rebase, and the auto ID isn't changed if it's already larger than the value.
+func (d *ddl) onRebaseAutoID(t *meta.Meta, job *model.Job) error {
+	schemaID := job.SchemaID
+	var autoIncID int64
+	err := job.DecodeArgs(&autoIncID)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := getTableInfo(t, job, schemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	autoIDSchemaID := schemaID
+	if tblInfo.OldSchemaID != 0 {
+		autoIDSchemaID = tblInfo.OldSchemaID
+	}
+	base, err := t.GetAutoTableID(autoIDSchemaID, tblInfo.ID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if newBase := autoIncID - 1; newBase > base {
+		if _, err = t.GenAutoTableID(autoIDSchemaID, tblInfo.ID, newBase-base); err != nil {
+			return errors.Trace(err)
+		}
+		tblInfo.AutoIncID = autoIncID
+	}
+
+	return errors.Trace(finishTableOptionJob(t, job, tblInfo))
+}
+
+// onModifyTableAutoIDCache changes the number of the auto IDs which are allocated at a time.
+func (d *ddl) onModifyTableAutoIDCache(t *meta.Meta, job *model.Job) error {
+	var cache int64
+	err := job.DecodeArgs(&cache)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	tblInfo.AutoIDCache = cache
+	return errors.Trace(finishTableOptionJob(t, job, tblInfo))
+}
+
+// onAlterTTLInfo changes the TTL info of the table.
+func (d *ddl) onAlterTTLInfo(t *meta.Meta, job *model.Job) error {
+	ttlInfo := &model.TTLInfo{}
+	err := job.DecodeArgs(ttlInfo)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	tblInfo.TTLInfo = ttlInfo
+	return errors.Trace(finishTableOptionJob(t, job, tblInfo))
+}
+
+// onModifyTableOptions changes the comment, the default charset and collation, and the storage options of the table.
+func (d *ddl) onModifyTableOptions(t *meta.Meta, job *model.Job) error {
+	var comment, cs, co string
+	var storage *model.TableStorageOptions
+	err := job.DecodeArgs(&comment, &cs, &co, &storage)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	tblInfo.Comment, tblInfo.Charset, tblInfo.Collate = comment, cs, co
+	tblInfo.StorageOptions = storage
+	return errors.Trace(finishTableOptionJob(t, job, tblInfo))
+}
+
+// onRemoveTTL removes the TTL info of the table.
+func (d *ddl) onRemoveTTL(t *meta.Meta, job *model.Job) error {
+	tblInfo, err := getTableInfo(t, job, job.SchemaID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	tblInfo.TTLInfo = nil
+	return errors.Trace(finishTableOptionJob(t, job, tblInfo))
+}
+
+// finishTableOptionJob updates the table info, the schema version is always updated, so the servers use the new
+// table options, like the new auto ID allocators of the table.
+func finishTableOptionJob(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo) error {
+	originalState := job.SchemaState
+	job.SchemaState = model.StatePublic
+	ver, err := updateTableInfo(t, job, tblInfo, originalState)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	job.State = model.JobDone
+	job.BinlogInfo.AddTableInfo(ver, tblInfo)
+	return nil
+}
+
 func updateTableInfo(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo, originalState model.SchemaState) (
 	ver int64, err error) {
 	if originalState != job.SchemaState {
@@ -296,3 +694,36 @@ func updateTableInfo(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo, ori
 
 	return ver, t.UpdateTable(job.SchemaID, tblInfo)
 }
+
+// shardBitsOfTable returns the number of the shard bits of the row IDs of the table, they are the bits of the
+// AUTO_RANDOM primary key or the implicit row IDs.
+func shardBitsOfTable(tblInfo *model.TableInfo) uint64 {
+	if tblInfo.AutoRandomBits > 0 {
+		return tblInfo.AutoRandomBits
+	}
+	return tblInfo.ShardRowIDBits
+}
+
+// preSplitTableRegions splits the rows of the new table into 2^PreSplitRegions regions by the highest shard bits of
+// the row IDs, so the writes to the table are spread from the start instead of hitting a single region. The split is
+// best-effort, the table is created even if the store can't split the regions.
+func (d *ddl) preSplitTableRegions(tblInfo *model.TableInfo) {
+	if tblInfo.PreSplitRegions == 0 || !d.store.SupportCapability(kv.CapSplitRegion) {
+		return
+	}
+	store := d.store.(kv.SplittableStore)
+	recordPrefix := tablecodec.GenTableRecordPrefix(tblInfo.ID)
+	splitKeys := []kv.Key{recordPrefix}
+	incrementalBits := 64 - 1 - tblInfo.PreSplitRegions
+	for i := int64(1); i < 1<<tblInfo.PreSplitRegions; i++ {
+		splitKeys = append(splitKeys, tablecodec.EncodeRowKeyWithHandle(tblInfo.ID, i<<incrementalBits))
+	}
+	splitKeys = append(splitKeys, recordPrefix.PrefixNext())
+	for _, key := range splitKeys {
+		if err := store.SplitRegion(key); err != nil {
+			log.Warnf("[ddl] pre-split the regions of table %s failed: %v", tblInfo.Name, err)
+			return
+		}
+	}
+	log.Infof("[ddl] pre-split the regions of table %s into %d regions", tblInfo.Name, 1<<tblInfo.PreSplitRegions)
+}
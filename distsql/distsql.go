@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 	goctx "golang.org/x/net/context"
@@ -63,6 +64,13 @@ type selectResult struct {
 
 	results chan resultWithErr
 	closed  chan struct{}
+
+	// If limit is greater than 0, no more result is fetched after limit rows.
+	limit   int64
+	fetched int64
+
+	// readDetails records the coprocessor responses of the statement, it may be nil.
+	readDetails *execdetails.ReadDetails
 }
 
 type resultWithErr struct {
@@ -92,6 +100,9 @@ func (r *selectResult) fetch(ctx goctx.Context) {
 		}
 		pr := &partialResult{}
 		pr.unmarshal(resultSubset)
+		if r.readDetails != nil {
+			r.readDetails.Record(pr.rowCount())
+		}
 
 		select {
 		case r.results <- resultWithErr{result: pr}:
@@ -99,8 +110,19 @@ func (r *selectResult) fetch(ctx goctx.Context) {
 			// if selectResult called Close() already, make fetch goroutine exit
 			return
 		case <-ctx.Done():
+			// The request is canceled, the reader gets the error rather than a result cut short.
+			select {
+			case r.results <- resultWithErr{err: errors.Trace(ctx.Err())}:
+			case <-r.closed:
+			}
 			return
 		}
+		if r.limit > 0 {
+			r.fetched += pr.rowCount()
+			if r.fetched >= r.limit {
+				return
+			}
+		}
 	}
 }
 
@@ -154,6 +176,15 @@ func (pr *partialResult) Next() (handle int64, data []byte, err error) {
 	return
 }
 
+// rowCount returns the number of rows in the sub result.
+func (pr *partialResult) rowCount() int64 {
+	var count int64
+	for _, chunk := range pr.resp.Chunks {
+		count += int64(len(chunk.RowsMeta))
+	}
+	return count
+}
+
 func (pr *partialResult) getChunk() *tipb.Chunk {
 	for {
 		if pr.chunkIdx >= len(pr.resp.Chunks) {
@@ -178,7 +209,11 @@ func (pr *partialResult) Close() error {
 // concurrency: The max concurrency for underlying coprocessor request.
 // keepOrder: If the result should returned in key order. For example if we need keep data in order by
 //            scan index, we should set keepOrder to true.
-func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (SelectResult, error) {
+// streaming: If the rows of a table scan are returned in several small responses of each region.
+// replicaRead: Which replica of the regions serves the request.
+// priority: The kv priority of the request, the low priority requests are throttled by the store.
+func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool,
+	streaming bool, replicaRead kv.ReplicaReadType, priority int) (SelectResult, error) {
 	var err error
 	defer func() {
 		// Add metrics
@@ -190,7 +225,7 @@ func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRan
 	}()
 
 	// Convert tipb.*Request to kv.Request.
-	kvReq, err1 := composeRequest(req, keyRanges, concurrency, keepOrder)
+	kvReq, err1 := composeRequest(req, keyRanges, concurrency, keepOrder, streaming, replicaRead, priority)
 	if err1 != nil {
 		err = errors.Trace(err1)
 		return nil, err
@@ -205,7 +240,9 @@ func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRan
 		resp:    resp,
 		results: make(chan resultWithErr, 5),
 		closed:  make(chan struct{}),
+		limit:   kvReq.Limit,
 	}
+	result.readDetails, _ = ctx.Value(execdetails.ReadDetailsKey).(*execdetails.ReadDetails)
 	// If Aggregates is not nil, we should set result fields latter.
 	if len(req.Aggregates) == 0 && len(req.GroupBy) == 0 {
 		if req.TableInfo != nil {
@@ -220,11 +257,14 @@ func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRan
 }
 
 // Convert tipb.Request to kv.Request.
-func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (*kv.Request, error) {
+func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool, streaming bool,
+	replicaRead kv.ReplicaReadType, priority int) (*kv.Request, error) {
 	kvReq := &kv.Request{
 		Concurrency: concurrency,
 		KeepOrder:   keepOrder,
 		KeyRanges:   keyRanges,
+		ReplicaRead: replicaRead,
+		Priority:    priority,
 	}
 	if req.IndexInfo != nil {
 		kvReq.Tp = kv.ReqTypeIndex
@@ -234,6 +274,13 @@ func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrenc
 	if req.OrderBy != nil {
 		kvReq.Desc = req.OrderBy[0].Desc
 	}
+	if keepOrder && req.Limit != nil && len(req.Aggregates) == 0 && len(req.GroupBy) == 0 && !hasTopN(req) {
+		// The rows are returned in key order, so the first Limit rows are the result.
+		kvReq.Limit = *req.Limit
+	}
+	// The streaming responses are split by the handles of the rows, so the request must return the scanned rows.
+	kvReq.Streaming = streaming && req.TableInfo != nil && req.Limit == nil && len(req.Aggregates) == 0 &&
+		len(req.GroupBy) == 0 && !hasTopN(req)
 	var err error
 	kvReq.Data, err = req.Marshal()
 	if err != nil {
@@ -242,6 +289,16 @@ func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrenc
 	return kvReq, nil
 }
 
+// hasTopN checks if the request sorts the rows by expressions, the results of different regions need to be merged.
+func hasTopN(req *tipb.SelectRequest) bool {
+	for _, item := range req.OrderBy {
+		if item.Expr != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // XAPI error codes.
 const (
 	codeInvalidResp = 1
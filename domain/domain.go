@@ -27,9 +27,14 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/privilege/privileges"
+	"github.com/pingcap/tidb/resourcegroup"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/statistics"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/ttl"
+	"github.com/pingcap/tidb/util/generallog"
+	"github.com/pingcap/tidb/util/stmtsummary"
 )
 
 // Domain represents a storage space. Different domains can use the same database name.
@@ -39,6 +44,8 @@ type Domain struct {
 	infoHandle      *infoschema.Handle
 	privHandle      *privileges.Handle
 	statsHandle     *statistics.Handle
+	ttlJobManager   *ttl.JobManager
+	resourceGroups  *resourcegroup.Manager
 	ddl             ddl.DDL
 	m               sync.Mutex
 	SchemaValidator SchemaValidator
@@ -56,6 +63,7 @@ func (do *Domain) loadInfoSchema(handle *infoschema.Handle, usedSchemaVersion in
 	if err != nil {
 		return 0, errors.Trace(err)
 	}
+	defer snapshot.Release()
 	m := meta.NewSnapshotMeta(snapshot)
 	latestSchemaVersion, err := m.GetSchemaVersion()
 	if err != nil {
@@ -270,6 +278,13 @@ func (do *Domain) Reload() error {
 	do.SchemaValidator.Update(ver.Ver, latestSchemaVersion)
 
 	lease := do.DDL().GetLease()
+	if lease > 0 {
+		// Let the DDL owner know that this server has loaded the latest schema.
+		err = do.ddl.SchemaSyncer().UpdateSelfVersion(latestSchemaVersion)
+		if err != nil {
+			log.Warnf("[ddl] update self schema version err %v", errors.ErrorStack(err))
+		}
+	}
 	sub := time.Since(startTime)
 	if sub > lease && lease > 0 {
 		log.Warnf("[ddl] loading schema takes a long time %v", sub)
@@ -283,6 +298,7 @@ func (do *Domain) loadSchemaInLoop(lease time.Duration) {
 	// Use lease/2 here as recommend by paper.
 	ticker := time.NewTicker(lease / 2)
 	defer ticker.Stop()
+	syncer := do.ddl.SchemaSyncer()
 
 	for {
 		select {
@@ -291,6 +307,12 @@ func (do *Domain) loadSchemaInLoop(lease time.Duration) {
 			if err != nil {
 				log.Errorf("[ddl] reload schema in loop err %v", errors.ErrorStack(err))
 			}
+		case <-syncer.GlobalVersionCh():
+			// The global schema version is changed, reload it without waiting for the next tick.
+			err := do.Reload()
+			if err != nil {
+				log.Errorf("[ddl] reload schema on global version change err %v", errors.ErrorStack(err))
+			}
 		case <-do.exit:
 			return
 		}
@@ -356,6 +378,13 @@ func NewDomain(store kv.Storage, lease time.Duration) (d *Domain, err error) {
 		return nil, errors.Trace(err)
 	}
 	d.ddl = ddl.NewDDL(d.store, d.infoHandle, &ddlCallback{do: d}, lease)
+	if lease > 0 {
+		// Register this server before loading the schema, so the DDL owner waits for it
+		// if the schema changes during the loading.
+		if err = d.ddl.SchemaSyncer().UpdateSelfVersion(0); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	if err = d.Reload(); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -402,11 +431,113 @@ func (do *Domain) LoadPrivilegeLoop(ctx context.Context) error {
 	return nil
 }
 
+// LoadServerVarsLoop loads the global variables which take effect on the whole server, like the ones that limit the
+// resources used by the DDL reorganization and the general log switch, and reloads them in a loop, so their changes
+// on the other servers take effect here.
+func (do *Domain) LoadServerVarsLoop(ctx context.Context) error {
+	loaded := make(map[string]string, len(serverVars))
+	err := loadServerVars(ctx, loaded)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	lease := do.DDL().GetLease()
+	if lease <= 0 {
+		return nil
+	}
+
+	go func(do *Domain) {
+		ticker := time.NewTicker(lease)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := loadServerVars(ctx, loaded)
+				if err != nil {
+					log.Error(errors.ErrorStack(err))
+				}
+			case <-do.exit:
+				return
+			}
+		}
+	}(do)
+	return nil
+}
+
+// serverVars are the global variables which take effect on the whole server, and the functions to apply them.
+var serverVars = []struct {
+	name  string
+	apply func(name, val string) error
+}{
+	{variable.TiDBDDLReorgWorkerCount, ddl.SetReorgVariable},
+	{variable.TiDBDDLReorgBatchSize, ddl.SetReorgVariable},
+	{variable.TiDBDDLReorgPriority, ddl.SetReorgVariable},
+	{variable.GeneralLogFile, generallog.SetVariable},
+	{variable.GeneralLog, generallog.SetVariable},
+	{variable.TiDBEnableStmtSummary, stmtsummary.SetVariable},
+}
+
+// loadServerVars applies the global variables whose values are changed since they are loaded last time.
+func loadServerVars(ctx context.Context, loaded map[string]string) error {
+	for _, v := range serverVars {
+		val, err := varsutil.GetGlobalSystemVar(ctx.GetSessionVars(), v.name)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if last, ok := loaded[v.name]; ok && last == val {
+			continue
+		}
+		// The value is not applied again even if it fails, so the warning is not logged repeatedly.
+		loaded[v.name] = val
+		err = v.apply(v.name, val)
+		if err != nil {
+			// Keep using the current value.
+			log.Warnf("[domain] load global variable %s = %s err %v", v.name, val, err)
+		}
+	}
+	return nil
+}
+
 // PrivilegeHandle returns the MySQLPrivilege.
 func (do *Domain) PrivilegeHandle() *privileges.Handle {
 	return do.privHandle
 }
 
+// ResourceGroupManager returns the manager of the resource groups.
+func (do *Domain) ResourceGroupManager() *resourcegroup.Manager {
+	return do.resourceGroups
+}
+
+// LoadResourceGroupLoop creates a goroutine loads the resource groups in a loop, it
+// should be called only once in BootstrapSession.
+func (do *Domain) LoadResourceGroupLoop(ctx context.Context) error {
+	do.resourceGroups = resourcegroup.NewManager(ctx)
+	err := do.resourceGroups.Update()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	lease := do.DDL().GetLease()
+	if lease <= 0 {
+		return nil
+	}
+
+	go func(do *Domain) {
+		ticker := time.NewTicker(lease)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := do.resourceGroups.Update()
+				if err != nil {
+					log.Error(errors.ErrorStack(err))
+				}
+			case <-do.exit:
+				return
+			}
+		}
+	}(do)
+	return nil
+}
+
 // StatsHandle returns the statistic handle.
 func (do *Domain) StatsHandle() *statistics.Handle {
 	return do.statsHandle
@@ -443,6 +574,37 @@ func (do *Domain) LoadTableStatsLoop(ctx context.Context) error {
 	return nil
 }
 
+// TTLJobManager returns the TTL job manager.
+func (do *Domain) TTLJobManager() *ttl.JobManager {
+	return do.ttlJobManager
+}
+
+// StartTTLJobLoop creates a goroutine runs the TTL jobs in a loop, it
+// should be called only once in BootstrapSession.
+func (do *Domain) StartTTLJobLoop(ctx context.Context) {
+	do.ttlJobManager = ttl.NewJobManager(ctx, do.exit)
+	lease := do.DDL().GetLease()
+	if lease <= 0 {
+		return
+	}
+	go func(do *Domain) {
+		ticker := time.NewTicker(ttl.JobCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := do.ttlJobManager.RunOnce(do.InfoSchema(), time.Now())
+				if err != nil {
+					log.Error(errors.ErrorStack(err))
+				}
+			case <-do.exit:
+				return
+			}
+		}
+	}(do)
+}
+
 // Domain error codes.
 const (
 	codeInfoSchemaExpired terror.ErrCode = 1
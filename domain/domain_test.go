@@ -19,6 +19,8 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/store/localstore/goleveldb"
@@ -94,3 +96,45 @@ func (*testSuite) TestT(c *C) {
 	err = store.Close()
 	c.Assert(err, IsNil)
 }
+
+func (*testSuite) TestSchemaSync(c *C) {
+	defer testleak.AfterTest(c)()
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory://test_schema_sync")
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	dom, err := NewDomain(store, 80*time.Millisecond)
+	c.Assert(err, IsNil)
+	defer dom.Close()
+	// The other server reloads the schema every 5s, but it learns of the new schema version without waiting.
+	dom1, err := NewDomain(store, 10*time.Second)
+	c.Assert(err, IsNil)
+	defer dom1.Close()
+
+	ctx := mock.NewContext()
+	ctx.Store = store
+	err = dom.DDL().CreateSchema(ctx, model.NewCIStr("aaa"), &ast.CharsetOpt{Chs: "utf8", Col: "utf8_bin"})
+	c.Assert(err, IsNil)
+	latestVer := dom.InfoSchema().SchemaMetaVersion()
+	for i := 0; i < 100; i++ {
+		if dom1.InfoSchema().SchemaMetaVersion() == latestVer {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, ok := dom1.InfoSchema().SchemaByName(model.NewCIStr("aaa"))
+	c.Assert(ok, IsTrue)
+
+	// Both servers record the schema versions they have loaded.
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		infos, err1 := meta.NewMeta(txn).GetAllSchemaSyncInfos()
+		c.Assert(err1, IsNil)
+		c.Assert(infos, HasLen, 2)
+		for _, info := range infos {
+			c.Assert(info.Version, Equals, latestVer)
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+}
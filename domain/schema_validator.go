@@ -54,8 +54,10 @@ func (s *schemaValidator) Update(leaseGrantTS uint64, schemaVer int64) {
 	s.items[schemaVer] = leaseExpire
 
 	// Delete expired items, leaseGrantTime is server current time, actually.
+	// The older versions are deleted too, because the DDL owner may not wait for
+	// their leases to expire once all the servers have loaded the newer version.
 	for k, expire := range s.items {
-		if leaseGrantTime.After(expire) {
+		if leaseGrantTime.After(expire) || k < schemaVer {
 			delete(s.items, k)
 		}
 	}
@@ -63,6 +63,15 @@ func (*testSuite) TestSchemaValidator(c *C) {
 	c.Assert(item.schemaVer, Less, validator.Latest())
 
 	exit <- struct{}{}
+
+	// The older schema version is invalid once a newer one is loaded, even if its lease isn't expired.
+	validator = newSchemaValidator(time.Hour)
+	leaseTS := uint64(time.Now().UnixNano())
+	validator.Update(leaseTS, 1)
+	c.Assert(validator.Check(leaseTS, 1), IsTrue)
+	validator.Update(leaseTS, 2)
+	c.Assert(validator.Check(leaseTS, 1), IsFalse)
+	c.Assert(validator.Check(leaseTS, 2), IsTrue)
 }
 
 func reload(validator SchemaValidator, leaseGrantCh chan leaseGrantItem) {
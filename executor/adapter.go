@@ -16,6 +16,8 @@ package executor
 import (
 	"fmt"
 	"math"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -23,12 +25,19 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/resourcegroup"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/slowlog"
+	"github.com/pingcap/tidb/util/stmtsummary"
 )
 
 type processinfoSetter interface {
-	SetProcessInfo(string)
+	SetProcessInfo(sql string, p interface{})
 }
 
 // recordSet wraps an executor, implements ast.RecordSet interface
@@ -59,18 +68,41 @@ func (a *recordSet) Fields() ([]*ast.ResultField, error) {
 }
 
 func (a *recordSet) Next() (*ast.Row, error) {
+	// The record sets built by AnalyzeExec have no statement.
+	if a.stmt != nil && isKilled(a.stmt.ctx) {
+		return nil, ErrQueryInterrupted
+	}
 	row, err := a.executor.Next()
-	if err != nil || row == nil {
+	if err != nil && a.stmt != nil && isKilled(a.stmt.ctx) {
+		err = ErrQueryInterrupted
+	}
+	if err != nil {
+		a.err = err
 		return nil, errors.Trace(err)
 	}
+	if row == nil {
+		return nil, nil
+	}
+	if a.stmt != nil {
+		a.stmt.resultRows++
+	}
 	return &ast.Row{Data: row.Data}, nil
 }
 
+// isKilled checks whether the session is killed, it's checked for every row of the statement. The error of a killed
+// statement is replaced by ErrQueryInterrupted, because the cancellation surfaces as the errors of the reads.
+func isKilled(ctx context.Context) bool {
+	return atomic.LoadUint32(&ctx.GetSessionVars().Killed) == 1
+}
+
 func (a *recordSet) Close() error {
 	err := a.executor.Close()
-	a.stmt.logSlowQuery()
+	if a.stmt != nil {
+		a.stmt.logSlowQuery(a.err == nil)
+		a.stmt.releaseResourceGroup()
+	}
 	if a.processinfo != nil {
-		a.processinfo.SetProcessInfo("")
+		a.processinfo.SetProcessInfo("", nil)
 	}
 	return errors.Trace(err)
 }
@@ -83,6 +115,14 @@ type statement struct {
 	text      string
 	plan      plan.Plan
 	startTime time.Time
+	// label is the statement label of the metrics.
+	label string
+	// txnStartTS and resultRows are logged with the slow query.
+	txnStartTS uint64
+	resultRows int64
+	// ticket is the admission of the resource group of the user, the statement is admitted at admitTime.
+	ticket    *resourcegroup.Ticket
+	admitTime time.Time
 }
 
 func (a *statement) OriginText() string {
@@ -93,14 +133,25 @@ func (a *statement) OriginText() string {
 // This function builds an Executor from a plan. If the Executor doesn't return result,
 // like the INSERT, UPDATE statements, it executes in this function, if the Executor returns
 // result, execution is done after this function returns, in the returned ast.RecordSet Next method.
-func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
+func (a *statement) Exec(ctx context.Context) (_ ast.RecordSet, err error) {
 	a.startTime = time.Now()
 	a.ctx = ctx
+	if err = a.admitResourceGroup(ctx); err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer func() {
+		if err != nil {
+			a.releaseResourceGroup()
+		}
+	}()
 	if _, ok := a.plan.(*plan.Execute); !ok {
 		// Do not sync transaction for Execute statement, because the real optimization work is done in
 		// "ExecuteExec.Build".
 		var err error
-		if IsPointGetWithPKOrUniqueKeyByAutoCommit(ctx, a.plan) {
+		if readTS := ctx.GetSessionVars().StmtCtx.ReadTS; readTS != 0 {
+			// The statement reads a snapshot, it doesn't need a new timestamp.
+			err = ctx.InitTxnWithStartTS(readTS)
+		} else if IsPointGetWithPKOrUniqueKeyByAutoCommit(ctx, a.plan) {
 			log.Debugf("[%d][InitTxnWithStartTS] %s", ctx.GetSessionVars().ConnectionID, a.text)
 			err = ctx.InitTxnWithStartTS(math.MaxUint64)
 		} else {
@@ -126,48 +177,46 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 		}
 		a.text = executorExec.Stmt.Text()
 		a.plan = executorExec.Plan
+		a.label = executorExec.stmtLabel
 		e = executorExec.StmtExec
 	}
 
+	// The backoffs and the kv requests of the reads are recorded in the statement context, so they are logged with
+	// the slow query.
+	// The point reads of the statement are sent with its priority.
+	if txn := ctx.Txn(); txn != nil {
+		txn.SetOption(kv.BackoffDetails, ctx.GetSessionVars().StmtCtx.BackoffDetails)
+		txn.SetOption(kv.ReadDetails, ctx.GetSessionVars().StmtCtx.ReadDetails)
+		txn.SetOption(kv.Priority, ctx.GetSessionVars().StmtCtx.Priority)
+		a.txnStartTS = txn.StartTS()
+	}
+
+	// The statement of EXPLAIN ANALYZE is executed here, because it may write data, which must be done before the
+	// transaction is committed.
+	if explain, ok := unwrapRuntimeStats(e).(*ExplainExec); ok && explain.analyzeExec != nil {
+		if err := checkSnapshotWrite(ctx, explain.analyzeExec); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := explain.executeAnalyzeExec(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	var pi processinfoSetter
 	if raw, ok := ctx.(processinfoSetter); ok {
 		pi = raw
-		// Update processinfo, ShowProcess() will use it.
-		pi.SetProcessInfo(a.OriginText())
+		// Update processinfo, ShowProcess() will use it, and EXPLAIN FOR CONNECTION explains the plan.
+		pi.SetProcessInfo(a.OriginText(), a.plan)
 	}
 
 	// Fields or Schema are only used for statements that return result set.
 	if e.Schema().Len() == 0 {
-		// Check if "tidb_snapshot" is set for the write executors.
-		// In history read mode, we can not do write operations.
-		switch e.(type) {
-		case *DeleteExec, *InsertExec, *UpdateExec, *ReplaceExec, *LoadData, *DDLExec:
-			snapshotTS := ctx.GetSessionVars().SnapshotTS
-			if snapshotTS != 0 {
-				return nil, errors.New("can not execute write statement when 'tidb_snapshot' is set")
-			}
+		if err := checkSnapshotWrite(ctx, e); err != nil {
+			return nil, errors.Trace(err)
 		}
 
-		defer func() {
-			if pi != nil {
-				pi.SetProcessInfo("")
-			}
-			e.Close()
-			a.logSlowQuery()
-		}()
-		for {
-			row, err := e.Next()
-			if err != nil {
-				return nil, errors.Trace(err)
-			}
-			// Even though there isn't any result set, the row is still used to indicate if there is
-			// more work to do.
-			// For example, the UPDATE statement updates a single row on a Next call, we keep calling Next until
-			// There is no more rows to update.
-			if row == nil {
-				return nil, nil
-			}
-		}
+		err = a.handleNoResult(ctx, e, pi)
+		return nil, errors.Trace(err)
 	}
 
 	return &recordSet{
@@ -177,23 +226,185 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 	}, nil
 }
 
-const (
-	queryLogMaxLen = 2048
-	slowThreshold  = 300 * time.Millisecond
-)
+// handleNoResult executes the statement which doesn't return a result set.
+func (a *statement) handleNoResult(ctx context.Context, e Executor, pi processinfoSetter) (err error) {
+	defer func() {
+		if pi != nil {
+			pi.SetProcessInfo("", nil)
+		}
+		e.Close()
+		a.logSlowQuery(err == nil)
+		a.releaseResourceGroup()
+	}()
+	for {
+		if isKilled(ctx) {
+			return ErrQueryInterrupted
+		}
+		row, err := e.Next()
+		if err != nil && isKilled(ctx) {
+			err = ErrQueryInterrupted
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// Even though there isn't any result set, the row is still used to indicate if there is
+		// more work to do.
+		// For example, the UPDATE statement updates a single row on a Next call, we keep calling Next until
+		// There is no more rows to update.
+		if row == nil {
+			return nil
+		}
+	}
+}
+
+// admitResourceGroup waits until the statement is admitted by the resource group of the user. The internal
+// statements and the simple statements, like SET and the statements managing the users and the groups, are not
+// limited, so a busy group can always be administrated.
+func (a *statement) admitResourceGroup(ctx context.Context) error {
+	sessVars := ctx.GetSessionVars()
+	if sessVars.InRestrictedSQL {
+		return nil
+	}
+	switch a.plan.(type) {
+	case *plan.Simple, *plan.Set:
+		return nil
+	}
+	strs := strings.Split(sessVars.User, "@")
+	if len(strs) != 2 {
+		return nil
+	}
+	dom := sessionctx.GetDomain(ctx)
+	if dom == nil || dom.ResourceGroupManager() == nil {
+		return nil
+	}
+	ticket, err := dom.ResourceGroupManager().Admit(strs[0], strs[1], ctx.Done())
+	if err != nil {
+		if terror.ErrorEqual(err, resourcegroup.ErrAdmissionCanceled) && isKilled(ctx) {
+			return ErrQueryInterrupted
+		}
+		return errors.Trace(err)
+	}
+	a.ticket, a.admitTime = ticket, time.Now()
+	return nil
+}
 
-func (a *statement) logSlowQuery() {
-	costTime := time.Since(a.startTime)
-	sql := a.text
+// releaseResourceGroup releases the admission of the statement, the resources it consumed are charged to the group.
+func (a *statement) releaseResourceGroup() {
+	if a.ticket == nil {
+		return
+	}
+	sc := a.ctx.GetSessionVars().StmtCtx
+	usage := resourcegroup.Usage{
+		AffectedRows:  sc.AffectedRows(),
+		ExecutionTime: time.Since(a.admitTime),
+	}
+	if sc.ReadDetails != nil {
+		usage.ReadRequests = sc.ReadDetails.Requests()
+		usage.ScannedKeys = sc.ReadDetails.ScannedKeys()
+	}
+	a.ticket.Release(usage)
+	a.ticket = nil
+}
+
+// checkSnapshotWrite checks if "tidb_snapshot" is set for the write executors.
+// In history read mode, we can not do write operations.
+func checkSnapshotWrite(ctx context.Context, e Executor) error {
+	switch unwrapRuntimeStats(e).(type) {
+	case *DeleteExec, *InsertExec, *UpdateExec, *ReplaceExec, *LoadData, *DDLExec:
+		if ctx.GetSessionVars().SnapshotTS != 0 {
+			return errors.New("can not execute write statement when 'tidb_snapshot' is set")
+		}
+	}
+	return nil
+}
+
+const queryLogMaxLen = 2048
+
+// logSlowQuery observes the duration of the statement and logs it if it takes longer than tidb_slow_log_threshold,
+// the slow query is also recorded in memory, so it can be queried by information_schema.SLOW_QUERY.
+// The statement is added to the statement summary too.
+func (a *statement) logSlowQuery(succ bool) {
+	sessVars := a.ctx.GetSessionVars()
+	sc := sessVars.StmtCtx
+	executeTime := time.Since(a.startTime)
+	queryTime := sc.ParseTime + sc.CompileTime + executeTime
+	observeStmtDuration(a.label, queryTime, succ)
+	a.summarizeStmt(queryTime, succ)
+	sql := parser.RedactPassword(a.text)
+	if sessVars.SlowLogNormalize {
+		sql = parser.Normalize(sql)
+	}
+	if len(sql) > queryLogMaxLen {
+		sql = sql[:queryLogMaxLen] + fmt.Sprintf("(len:%d)", len(sql))
+	}
+	connID := sessVars.ConnectionID
+	if queryTime < sessVars.SlowLogThreshold {
+		log.Debugf("[%d][TIME_QUERY] %v %s", connID, queryTime, sql)
+		return
+	}
+	var details []string
+	if sc.RuntimeStatsColl != nil {
+		// Log the runtime statistics of the executors, to find out the slow parts of the plan.
+		details = append(details, fmt.Sprintf("[EXEC_DETAILS] %s", sc.RuntimeStatsColl))
+	}
+	if sc.BackoffDetails != nil && sc.BackoffDetails.Times() > 0 {
+		// Log the retries of the kv requests, to find out if the statement is slowed down by the region errors
+		// or the locks.
+		details = append(details, fmt.Sprintf("[BACKOFF_DETAILS] %s", sc.BackoffDetails))
+	}
+	entry := &slowlog.Entry{
+		Time:        time.Now(),
+		TxnStartTS:  a.txnStartTS,
+		ConnID:      connID,
+		DB:          sessVars.CurrentDB,
+		Query:       sql,
+		QueryTime:   queryTime,
+		ParseTime:   sc.ParseTime,
+		CompileTime: sc.CompileTime,
+		ExecuteTime: executeTime,
+		ResultRows:  a.resultRows,
+		Succ:        succ,
+		Details:     strings.Join(details, " "),
+	}
+	if strs := strings.Split(sessVars.User, "@"); len(strs) == 2 {
+		entry.User, entry.Host = strs[0], strs[1]
+	}
+	if sc.ReadDetails != nil {
+		entry.KVRequests = sc.ReadDetails.Requests()
+		entry.ScannedKeys = sc.ReadDetails.ScannedKeys()
+	}
+	slowlog.Record(entry)
+	log.Warnf("[%d][SLOW_QUERY] %s", connID, entry)
+}
+
+// summarizeStmt adds the statement to the summary of its digest if tidb_enable_stmt_summary is on, the internal
+// statements are not summarized.
+func (a *statement) summarizeStmt(queryTime time.Duration, succ bool) {
+	sessVars := a.ctx.GetSessionVars()
+	if !stmtsummary.Enabled() || sessVars.InRestrictedSQL {
+		return
+	}
+	sc := sessVars.StmtCtx
+	// The sample is readable by all the users, the passwords aren't kept in it.
+	sql := parser.RedactPassword(a.text)
 	if len(sql) > queryLogMaxLen {
 		sql = sql[:queryLogMaxLen] + fmt.Sprintf("(len:%d)", len(sql))
 	}
-	connID := a.ctx.GetSessionVars().ConnectionID
-	if costTime < slowThreshold {
-		log.Debugf("[%d][TIME_QUERY] %v %s", connID, costTime, sql)
-	} else {
-		log.Warnf("[%d][TIME_QUERY] %v %s", connID, costTime, sql)
+	info := &stmtsummary.StmtExecInfo{
+		SchemaName:   sessVars.CurrentDB,
+		SQL:          sql,
+		Plan:         plan.ToString(a.plan),
+		StartTime:    a.startTime,
+		Latency:      queryTime,
+		Succ:         succ,
+		Warnings:     uint64(sc.WarningCount()),
+		AffectedRows: sc.AffectedRows(),
+		SentRows:     uint64(a.resultRows),
+	}
+	if sc.ReadDetails != nil {
+		info.ExaminedRows = uint64(sc.ReadDetails.ScannedKeys())
 	}
+	stmtsummary.Add(info)
 }
 
 // IsPointGetWithPKOrUniqueKeyByAutoCommit returns true when meets following conditions:
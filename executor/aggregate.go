@@ -14,17 +14,31 @@
 package executor
 
 import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
 // HashAggExec deals with all the aggregate functions.
 // It is built from the Aggregate Plan. When Next() is called, it reads all the data from Src
 // and updates all the items in AggFuncs.
+// If concurrency is greater than 1, the rows of Src are aggregated by multiple partial workers, then the partial
+// results of every group are sent to the final worker the group belongs to, and merged there. If the partial results
+// of some aggregate functions can't be merged, such as the distinct ones, the partial workers dispatch the rows to
+// the final workers by their groups instead, and the final workers aggregate the rows completely.
+// The number of groups kept in memory is limited by the session variable tidb_max_hash_rows_in_memory. The rows of
+// the other groups are spilled to partitions on disk, and aggregated partition by partition after the groups in
+// memory are returned. A partial worker sends its partial results to the final workers when it reaches the limit.
 type HashAggExec struct {
 	Src               Executor
 	schema            *expression.Schema
@@ -37,16 +51,113 @@ type HashAggExec struct {
 	groups            [][]byte
 	currentGroupIndex int
 	GroupByItems      []expression.Expression
+
+	// concurrency is the number of partial workers and final workers.
+	concurrency int
+	// shuffle indicates the partial workers only dispatch the rows to the final workers.
+	shuffle  bool
+	prepared bool
+	finished atomic.Value
+	// partialWg waits for the fetcher and the partial workers, finalWg waits for the final workers.
+	partialWg sync.WaitGroup
+	finalWg   sync.WaitGroup
+	closeCh   chan struct{}
+	inputCh   chan *execResult
+	// partialOutputChs sends the partial results to the final workers, a partial result row is made up of the group key
+	// and the partial results of all the aggregate functions.
+	partialOutputChs []chan *execResult
+	resultCh         chan *execResult
+	rows             []*Row
+	cursor           int
+
+	maxGroupsInMemory int
+	// spill holds the rows of the groups not kept in memory when concurrency is 1, spillIdx is the index of the next
+	// partition to aggregate.
+	spill    *spillPartitions
+	spillIdx int
+
+	// memTracker tracks the memory usage of the groups, the trackers of the workers are attached to it.
+	memTracker *memory.Tracker
+}
+
+// aggFuncGroupSize is the approximate memory usage of the result of an aggregate function for a group.
+const aggFuncGroupSize = 64
+
+// groupMemUsage returns the approximate memory usage of a group kept in memory.
+func groupMemUsage(groupKey []byte, numAggFuncs int) int64 {
+	return int64(len(groupKey)) + int64(numAggFuncs)*aggFuncGroupSize
+}
+
+// hashAggWorker holds the aggregate functions of a partial worker or a final worker, and the groups it has met.
+type hashAggWorker struct {
+	aggFuncs     []expression.AggregationFunction
+	groupByItems []expression.Expression
+	groupMap     map[string]bool
+	groups       [][]byte
+	// dataOffset is the offset of the data to aggregate in the rows, the rows dispatched to a final worker in shuffle
+	// mode start with the group key.
+	dataOffset int
+	// spill holds the rows of the groups not kept in memory by a final worker.
+	spill *spillPartitions
+	// memTracker tracks the memory usage of the groups of the worker.
+	memTracker *memory.Tracker
+}
+
+func (w *hashAggWorker) update(row *Row, groupKey []byte, ctx context.Context) error {
+	if !w.groupMap[string(groupKey)] {
+		w.groupMap[string(groupKey)] = true
+		w.groups = append(w.groups, groupKey)
+		err := w.memTracker.Consume(groupMemUsage(groupKey, len(w.aggFuncs)))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, af := range w.aggFuncs {
+		err := af.Update(row.Data[w.dataOffset:], groupKey, ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// reset clears the groups and the aggregate results, so the worker can aggregate another set of groups.
+func (w *hashAggWorker) reset() {
+	for _, af := range w.aggFuncs {
+		af.Clear()
+	}
+	w.groupMap = make(map[string]bool)
+	w.groups = nil
+	w.memTracker.Consume(-w.memTracker.BytesConsumed())
 }
 
 // Close implements the Executor Close interface.
 func (e *HashAggExec) Close() error {
+	if e.prepared {
+		e.finished.Store(true)
+		for range e.resultCh {
+		}
+		<-e.closeCh
+		e.prepared = false
+		e.rows = nil
+		e.cursor = 0
+	}
 	e.executed = false
 	e.groups = nil
 	e.currentGroupIndex = 0
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
+	if e.spill != nil {
+		err := e.spill.close()
+		e.spill = nil
+		e.spillIdx = 0
+		if err != nil {
+			e.Src.Close()
+			return errors.Trace(err)
+		}
+	}
 	return e.Src.Close()
 }
 
@@ -57,9 +168,13 @@ func (e *HashAggExec) Schema() *expression.Schema {
 
 // Next implements the Executor Next interface.
 func (e *HashAggExec) Next() (*Row, error) {
+	if e.concurrency > 1 {
+		return e.parallelNext()
+	}
 	// In this stage we consider all data from src as a single group.
 	if !e.executed {
 		e.groupMap = make(map[string]bool)
+		e.maxGroupsInMemory = e.ctx.GetSessionVars().MaxHashRowsInMemory
 		for {
 			hasMore, err := e.innerNext()
 			if err != nil {
@@ -78,8 +193,14 @@ func (e *HashAggExec) Next() (*Row, error) {
 			e.groups = append(e.groups, []byte{})
 		}
 	}
-	if e.currentGroupIndex >= len(e.groups) {
-		return nil, nil
+	for e.currentGroupIndex >= len(e.groups) {
+		if e.spill == nil || e.spillIdx >= len(e.spill.files) {
+			return nil, nil
+		}
+		err := e.aggregateSpilledPartition()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	retRow := &Row{Data: make([]types.Datum, 0, len(e.AggFuncs))}
 	groupKey := e.groups[e.currentGroupIndex]
@@ -90,9 +211,9 @@ func (e *HashAggExec) Next() (*Row, error) {
 	return retRow, nil
 }
 
-func (e *HashAggExec) getGroupKey(row *Row) ([]byte, error) {
+func (e *HashAggExec) getGroupKey(groupByItems []expression.Expression, row *Row) ([]byte, error) {
 	if e.aggType == plan.FinalAgg {
-		val, err := e.GroupByItems[0].Eval(row.Data)
+		val, err := groupByItems[0].Eval(row.Data)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -101,8 +222,8 @@ func (e *HashAggExec) getGroupKey(row *Row) ([]byte, error) {
 	if !e.hasGby {
 		return []byte{}, nil
 	}
-	vals := make([]types.Datum, 0, len(e.GroupByItems))
-	for _, item := range e.GroupByItems {
+	vals := make([]types.Datum, 0, len(groupByItems))
+	for _, item := range groupByItems {
 		v, err := item.Eval(row.Data)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -135,20 +256,409 @@ func (e *HashAggExec) innerNext() (ret bool, err error) {
 		}
 	}
 	e.executed = true
-	groupKey, err := e.getGroupKey(srcRow)
+	groupKey, err := e.getGroupKey(e.GroupByItems, srcRow)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
 	if _, ok := e.groupMap[string(groupKey)]; !ok {
+		if len(e.groups) >= e.maxGroupsInMemory || (len(e.groups) > 0 && e.memTracker.ShouldSpill()) {
+			if e.spill == nil {
+				e.spill, err = newSpillPartitions(hashSpillPartitions)
+				if err != nil {
+					return false, errors.Trace(err)
+				}
+			}
+			return true, errors.Trace(e.spill.add(groupKey, srcRow))
+		}
 		e.groupMap[string(groupKey)] = true
 		e.groups = append(e.groups, groupKey)
+		err = e.memTracker.Consume(groupMemUsage(groupKey, len(e.AggFuncs)))
+		if err != nil {
+			return false, errors.Trace(err)
+		}
 	}
 	for _, af := range e.AggFuncs {
-		af.Update(srcRow.Data, groupKey, e.ctx)
+		err = af.Update(srcRow.Data, groupKey, e.ctx)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
 	}
 	return true, nil
 }
 
+// aggregateSpilledPartition clears the groups which have been returned, and aggregates the rows in the next spilled
+// partition.
+func (e *HashAggExec) aggregateSpilledPartition() error {
+	file := e.spill.files[e.spillIdx]
+	e.spillIdx++
+	for _, af := range e.AggFuncs {
+		af.Clear()
+	}
+	e.groupMap = make(map[string]bool)
+	e.groups = nil
+	e.currentGroupIndex = 0
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	for {
+		row, err := file.next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			return nil
+		}
+		groupKey, err := e.getGroupKey(e.GroupByItems, row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !e.groupMap[string(groupKey)] {
+			e.groupMap[string(groupKey)] = true
+			e.groups = append(e.groups, groupKey)
+			err = e.memTracker.Consume(groupMemUsage(groupKey, len(e.AggFuncs)))
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		for _, af := range e.AggFuncs {
+			err = af.Update(row.Data, groupKey, e.ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+}
+
+// parallelNext returns the rows produced by the final workers. The order of the groups is not kept.
+func (e *HashAggExec) parallelNext() (*Row, error) {
+	if !e.prepared {
+		e.prepare()
+	}
+	for e.cursor >= len(e.rows) {
+		result, ok := <-e.resultCh
+		if !ok {
+			return nil, nil
+		}
+		if result.err != nil {
+			e.finished.Store(true)
+			return nil, errors.Trace(result.err)
+		}
+		e.rows = result.rows
+		e.cursor = 0
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// prepare starts a worker to fetch rows from Src, then starts the partial workers and the final workers.
+func (e *HashAggExec) prepare() {
+	e.finished.Store(false)
+	e.closeCh = make(chan struct{})
+	e.inputCh = make(chan *execResult, e.concurrency)
+	e.resultCh = make(chan *execResult, e.concurrency)
+	e.partialOutputChs = make([]chan *execResult, e.concurrency)
+	for i := range e.partialOutputChs {
+		e.partialOutputChs[i] = make(chan *execResult, e.concurrency)
+	}
+	e.partialWg = sync.WaitGroup{}
+	e.finalWg = sync.WaitGroup{}
+	e.maxGroupsInMemory = e.ctx.GetSessionVars().MaxHashRowsInMemory
+
+	e.partialWg.Add(1)
+	go e.fetchInput()
+	for i := 0; i < e.concurrency; i++ {
+		e.partialWg.Add(1)
+		go e.runPartialWorker(e.newPartialWorker())
+	}
+	for i := 0; i < e.concurrency; i++ {
+		e.finalWg.Add(1)
+		go e.runFinalWorker(e.newFinalWorker(), e.partialOutputChs[i])
+	}
+	go e.waitWorkersAndCloseResultChan()
+	e.prepared = true
+}
+
+func (e *HashAggExec) waitWorkersAndCloseResultChan() {
+	e.partialWg.Wait()
+	for _, ch := range e.partialOutputChs {
+		close(ch)
+	}
+	e.finalWg.Wait()
+	close(e.resultCh)
+	close(e.closeCh)
+}
+
+// fetchInput reads the rows from Src in batches, and sends them to the partial workers.
+func (e *HashAggExec) fetchInput() {
+	defer func() {
+		close(e.inputCh)
+		e.partialWg.Done()
+	}()
+	result := &execResult{rows: make([]*Row, 0, batchSize)}
+	for {
+		if e.finished.Load().(bool) {
+			return
+		}
+		row, err := e.Src.Next()
+		if err != nil {
+			e.resultCh <- &execResult{err: errors.Trace(err)}
+			return
+		}
+		if row == nil {
+			break
+		}
+		result.rows = append(result.rows, row)
+		if len(result.rows) >= batchSize {
+			e.inputCh <- result
+			result = &execResult{rows: make([]*Row, 0, batchSize)}
+		}
+	}
+	if len(result.rows) > 0 {
+		e.inputCh <- result
+	}
+}
+
+// newPartialWorker clones the aggregate functions and the group by items, so they can be evaluated in another
+// goroutine.
+func (e *HashAggExec) newPartialWorker() *hashAggWorker {
+	w := &hashAggWorker{
+		aggFuncs:     make([]expression.AggregationFunction, 0, len(e.AggFuncs)),
+		groupByItems: make([]expression.Expression, 0, len(e.GroupByItems)),
+		groupMap:     make(map[string]bool),
+		memTracker:   memory.NewTracker("HashAggPartialWorker", -1),
+	}
+	w.memTracker.AttachTo(e.memTracker)
+	for _, af := range e.AggFuncs {
+		newFunc := af.Clone()
+		newFunc.SetMemTracker(w.memTracker)
+		w.aggFuncs = append(w.aggFuncs, newFunc)
+	}
+	for _, item := range e.GroupByItems {
+		w.groupByItems = append(w.groupByItems, item.Clone())
+	}
+	return w
+}
+
+// newFinalWorker builds the aggregate functions in FinalMode whose arguments are the columns of the partial results.
+// In shuffle mode, it clones the aggregate functions to aggregate the dispatched rows.
+func (e *HashAggExec) newFinalWorker() *hashAggWorker {
+	w := &hashAggWorker{
+		aggFuncs:   make([]expression.AggregationFunction, 0, len(e.AggFuncs)),
+		groupMap:   make(map[string]bool),
+		memTracker: memory.NewTracker("HashAggFinalWorker", -1),
+	}
+	w.memTracker.AttachTo(e.memTracker)
+	if e.shuffle {
+		w.dataOffset = 1
+		for _, af := range e.AggFuncs {
+			newFunc := af.Clone()
+			newFunc.SetMemTracker(w.memTracker)
+			w.aggFuncs = append(w.aggFuncs, newFunc)
+		}
+		return w
+	}
+	// The first column of a partial result row is the group key.
+	cursor := 1
+	for _, af := range e.AggFuncs {
+		var args []expression.Expression
+		if af.GetName() == ast.AggFuncAvg {
+			args = append(args, &expression.Column{Index: cursor, RetType: types.NewFieldType(mysql.TypeLonglong)})
+			cursor++
+		}
+		args = append(args, &expression.Column{Index: cursor, RetType: af.GetType()})
+		cursor++
+		// Clone the function to keep its attributes, such as the separator of group_concat.
+		finalFunc := af.Clone()
+		finalFunc.SetArgs(args)
+		finalFunc.SetMode(expression.FinalMode)
+		finalFunc.SetMemTracker(w.memTracker)
+		w.aggFuncs = append(w.aggFuncs, finalFunc)
+	}
+	return w
+}
+
+// runPartialWorker aggregates the input rows, then sends the partial result of each group to the final workers.
+func (e *HashAggExec) runPartialWorker(w *hashAggWorker) {
+	defer func() {
+		w.memTracker.Detach()
+		e.partialWg.Done()
+	}()
+	for input := range e.inputCh {
+		if e.finished.Load().(bool) {
+			continue
+		}
+		if e.shuffle {
+			if err := e.dispatchRows(w, input.rows); err != nil {
+				e.finished.Store(true)
+				e.resultCh <- &execResult{err: errors.Trace(err)}
+			}
+			continue
+		}
+		for _, row := range input.rows {
+			groupKey, err := e.getGroupKey(w.groupByItems, row)
+			if err == nil {
+				err = w.update(row, groupKey, e.ctx)
+			}
+			if err != nil {
+				e.finished.Store(true)
+				e.resultCh <- &execResult{err: errors.Trace(err)}
+				break
+			}
+			if len(w.groups) >= e.maxGroupsInMemory || w.memTracker.ShouldSpill() {
+				e.sendPartialResults(w)
+				w.reset()
+			}
+		}
+	}
+	if e.finished.Load().(bool) {
+		return
+	}
+	e.sendPartialResults(w)
+}
+
+// sendPartialResults sends the partial result of each group to the final worker the group belongs to.
+func (e *HashAggExec) sendPartialResults(w *hashAggWorker) {
+	outputs := make([]*execResult, e.concurrency)
+	for _, groupKey := range w.groups {
+		partialRow := &Row{Data: []types.Datum{types.NewBytesDatum(groupKey)}}
+		for _, af := range w.aggFuncs {
+			partialRow.Data = append(partialRow.Data, af.GetPartialResult(groupKey)...)
+		}
+		e.addPartialOutput(outputs, groupKey, partialRow)
+	}
+	e.flushPartialOutputs(outputs)
+}
+
+// dispatchRows sends the rows to the final workers their groups belong to in shuffle mode, the group key is put
+// before the data of a row.
+func (e *HashAggExec) dispatchRows(w *hashAggWorker, rows []*Row) error {
+	outputs := make([]*execResult, e.concurrency)
+	for _, row := range rows {
+		groupKey, err := e.getGroupKey(w.groupByItems, row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		keyedRow := &Row{Data: make([]types.Datum, 0, len(row.Data)+1)}
+		keyedRow.Data = append(keyedRow.Data, types.NewBytesDatum(groupKey))
+		keyedRow.Data = append(keyedRow.Data, row.Data...)
+		e.addPartialOutput(outputs, groupKey, keyedRow)
+	}
+	e.flushPartialOutputs(outputs)
+	return nil
+}
+
+// addPartialOutput adds the row to the output of the final worker the group belongs to, the output is sent when
+// it's full.
+func (e *HashAggExec) addPartialOutput(outputs []*execResult, groupKey []byte, row *Row) {
+	h := fnv.New32a()
+	h.Write(groupKey)
+	idx := int(h.Sum32() % uint32(e.concurrency))
+	if outputs[idx] == nil {
+		outputs[idx] = &execResult{rows: make([]*Row, 0, batchSize)}
+	}
+	outputs[idx].rows = append(outputs[idx].rows, row)
+	if len(outputs[idx].rows) >= batchSize {
+		e.partialOutputChs[idx] <- outputs[idx]
+		outputs[idx] = nil
+	}
+}
+
+func (e *HashAggExec) flushPartialOutputs(outputs []*execResult) {
+	for idx, output := range outputs {
+		if output != nil {
+			e.partialOutputChs[idx] <- output
+		}
+	}
+}
+
+// runFinalWorker merges the partial results of the groups dispatched to it, or aggregates the rows of them in shuffle
+// mode, then sends the final results.
+func (e *HashAggExec) runFinalWorker(w *hashAggWorker, inputCh chan *execResult) {
+	defer func() {
+		if w.spill != nil {
+			w.spill.close()
+		}
+		w.memTracker.Detach()
+		e.finalWg.Done()
+	}()
+	for input := range inputCh {
+		if e.finished.Load().(bool) {
+			continue
+		}
+		for _, row := range input.rows {
+			err := e.mergeOrSpill(w, row)
+			if err != nil {
+				e.finished.Store(true)
+				e.resultCh <- &execResult{err: errors.Trace(err)}
+				break
+			}
+		}
+	}
+	if e.finished.Load().(bool) {
+		return
+	}
+	e.sendFinalResults(w)
+	if w.spill == nil {
+		return
+	}
+	for _, file := range w.spill.files {
+		w.reset()
+		for {
+			row, err := file.next()
+			if err == nil && row != nil {
+				err = w.update(row, row.Data[0].GetBytes(), e.ctx)
+			}
+			if err != nil {
+				e.finished.Store(true)
+				e.resultCh <- &execResult{err: errors.Trace(err)}
+				return
+			}
+			if row == nil {
+				break
+			}
+		}
+		if e.finished.Load().(bool) {
+			return
+		}
+		e.sendFinalResults(w)
+	}
+}
+
+// mergeOrSpill merges the partial result row or aggregates the dispatched row into its group, or spills it to disk if
+// the group is not in memory and the final worker can't keep more groups.
+func (e *HashAggExec) mergeOrSpill(w *hashAggWorker, row *Row) error {
+	groupKey := row.Data[0].GetBytes()
+	if w.groupMap[string(groupKey)] || (len(w.groups) < e.maxGroupsInMemory && !w.memTracker.ShouldSpill()) {
+		return errors.Trace(w.update(row, groupKey, e.ctx))
+	}
+	if w.spill == nil {
+		var err error
+		w.spill, err = newSpillPartitions(hashSpillPartitions)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(w.spill.add(groupKey, row))
+}
+
+// sendFinalResults sends the final results of the groups in memory.
+func (e *HashAggExec) sendFinalResults(w *hashAggWorker) {
+	result := &execResult{rows: make([]*Row, 0, batchSize)}
+	for _, groupKey := range w.groups {
+		retRow := &Row{Data: make([]types.Datum, 0, len(w.aggFuncs))}
+		for _, af := range w.aggFuncs {
+			retRow.Data = append(retRow.Data, af.GetGroupResult(groupKey))
+		}
+		result.rows = append(result.rows, retRow)
+		if len(result.rows) >= batchSize {
+			e.resultCh <- result
+			result = &execResult{rows: make([]*Row, 0, batchSize)}
+		}
+	}
+	if len(result.rows) > 0 {
+		e.resultCh <- result
+	}
+}
+
 // StreamAggExec deals with all the aggregate functions.
 // It assumes all the input data is sorted by group by key.
 // When Next() is called, it will return a result for the same group.
@@ -163,15 +673,20 @@ type StreamAggExec struct {
 	curGroupEncodedKey []byte
 	curGroupKey        []types.Datum
 	tmpGroupKey        []types.Datum
+
+	// memTracker tracks the memory usage of the distinct values of the current group.
+	memTracker *memory.Tracker
 }
 
 // Close implements the Executor Close interface.
 func (e *StreamAggExec) Close() error {
 	e.executed = false
 	e.hasData = false
+	e.curGroupKey = nil
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
+	e.releaseMemory()
 	return e.Src.Close()
 }
 
@@ -206,6 +721,7 @@ func (e *StreamAggExec) Next() (*Row, error) {
 			for _, af := range e.AggFuncs {
 				retRow.Data = append(retRow.Data, af.GetStreamResult())
 			}
+			e.releaseMemory()
 		}
 		if e.executed {
 			break
@@ -226,6 +742,13 @@ func (e *StreamAggExec) Next() (*Row, error) {
 	return retRow, nil
 }
 
+// releaseMemory releases the memory consumed by the group whose results have been returned.
+func (e *StreamAggExec) releaseMemory() {
+	if e.memTracker != nil {
+		e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	}
+}
+
 // meetNewGroup returns a value that represents if the new group is different from last group.
 func (e *StreamAggExec) meetNewGroup(row *Row) (bool, error) {
 	if len(e.GroupByItems) == 0 {
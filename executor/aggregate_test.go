@@ -17,10 +17,12 @@ import (
 	"fmt"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/expression"
-	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
@@ -57,13 +59,13 @@ func (m *MockExec) Close() error {
 }
 
 func (s *testSuite) TestAggregation(c *C) {
-	plan.JoinConcurrency = 1
 	defer func() {
-		plan.JoinConcurrency = 5
 		s.cleanEnv(c)
 		testleak.AfterTest(c)()
 	}()
 	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("set @@tidb_hash_join_concurrency=1")
+	tk.MustExec("set @@tidb_hash_agg_concurrency=1")
 	tk.MustExec("use test")
 	tk.MustExec("drop table if exists t")
 	tk.MustExec("create table t (c int, d int)")
@@ -228,6 +230,13 @@ func (s *testSuite) TestAggregation(c *C) {
 	result.Check(testkit.Rows("4", "2", "5"))
 	result = tk.MustQuery("select min(b) from (select * from t1) t group by a")
 	result.Check(testkit.Rows("1", "2", "3"))
+	result = tk.MustQuery("select group_concat(b) from (select * from t1) t group by a")
+	result.Check(testkit.Rows("1,4", "2", "3,5"))
+	tk.MustExec("drop table if exists t2")
+	tk.MustExec("create table t2(x int)")
+	tk.MustExec("insert into t2 values (1), (3)")
+	result = tk.MustQuery("select x, (select sum(b) from t1 where t1.a = t2.x group by t1.a) from t2")
+	result.Check(testkit.Rows("1 5", "3 8"))
 	tk.MustExec("drop table if exists t1")
 	tk.MustExec("create table t1(a int, b int, index(a,b))")
 	tk.MustExec("insert into t1 (a, b) values (1, 1),(2, 2),(3, 3),(1, 4), (1,1),(3, 5), (2,2), (3,5), (3,3)")
@@ -270,7 +279,7 @@ func (s *testSuite) TestAggregation(c *C) {
 
 	result = tk.MustQuery("select count(*) from information_schema.columns")
 	// When adding new memory table in information_schema, please update this variable.
-	columnCountOfAllInformationSchemaTables := "576"
+	columnCountOfAllInformationSchemaTables := "637"
 	result.Check(testkit.Rows(columnCountOfAllInformationSchemaTables))
 
 	tk.MustExec("drop table if exists t1")
@@ -294,6 +303,149 @@ func (s *testSuite) TestAggregation(c *C) {
 	result.Check(testkit.Rows("2 1"))
 }
 
+func (s *testSuite) TestParallelHashAgg(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c varchar(10))")
+	for i := 0; i < 300; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d, 'x%d')", i%50, i, i%50))
+	}
+	tk.MustExec("insert t values (NULL, NULL, NULL)")
+	queries := []string{
+		"select a, count(*), count(b), sum(b), avg(b), max(b), min(b) from t group by a",
+		"select c, count(*), max(a) from t where b < 100 group by c",
+		"select a, count(distinct b % 3) from t group by a",
+		"select sum(b) s from t group by a having s > 1030",
+		"select count(*), sum(b), a from t group by a % 7, c",
+		"select count(*), sum(a) from t",
+	}
+	tk.MustExec("set @@tidb_hash_agg_concurrency=1")
+	var expected [][][]interface{}
+	for _, query := range queries {
+		expected = append(expected, tk.MustQuery(query).Sort().Rows())
+	}
+	tk.MustExec("set @@tidb_hash_agg_concurrency=4")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+	result := tk.MustQuery(queries[0]).Sort().Rows()
+	c.Assert(result, HasLen, 51)
+	c.Assert(fmt.Sprintf("%v", result[0]), Equals, "[0 6 6 750 125.0000 250 0]")
+	c.Assert(fmt.Sprintf("%v", result[50]), Equals, "[<nil> 1 0 <nil> <nil> <nil> <nil>]")
+	// The order of the values in group_concat is not defined when the groups are merged in parallel.
+	tk.MustQuery("select a, length(group_concat(b)) from t where a < 2 group by a").Sort().Check(testkit.Rows("0 20", "1 20"))
+	tk.MustQuery(queries[3]).Sort().Check(testkit.Rows("1032", "1038", "1044"))
+	tk.MustQuery(queries[5]).Check(testkit.Rows("301 7350"))
+	// The executor is closed before all the groups are returned.
+	c.Assert(tk.MustQuery("select a from t group by a limit 2").Rows(), HasLen, 2)
+	// The user variables are changed row by row, the groups aren't aggregated in parallel.
+	tk.MustExec("set @s = 0")
+	tk.MustQuery("select a, max(@s := @s + 1) from t where a < 2 group by a").Sort().Check(testkit.Rows("0 11", "1 12"))
+	tk.MustQuery("select @s").Check(testkit.Rows("12"))
+}
+
+func (s *testSuite) TestHashAggSpill(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c varchar(10), d datetime)")
+	for i := 0; i < 200; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d, 'x%d', '2017-09-%02d')", i%40, i, i%40, i%28+1))
+	}
+	tk.MustExec("insert t values (NULL, NULL, NULL, NULL)")
+	queries := []string{
+		"select a, count(*), count(b), sum(b), avg(b), max(b), min(b) from t group by a",
+		"select c, count(*), max(d), group_concat(b) from t where b < 100 group by c",
+		"select sum(b) s from t group by a having s > 540",
+		"select x, count(*), min(d) from (select a + 1 as x, d from t) t group by x",
+		"select d, count(*) from t group by d",
+	}
+	tk.MustExec("set @@tidb_hash_agg_concurrency=1")
+	var expected [][][]interface{}
+	for _, query := range queries {
+		expected = append(expected, tk.MustQuery(query).Sort().Rows())
+	}
+	tk.MustExec("set @@tidb_max_hash_rows_in_memory=3")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+	tk.MustExec("set @@tidb_hash_agg_concurrency=4")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+}
+
+func (s *testSuite) TestDistinctAggregation(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c varchar(10))")
+	for i := 0; i < 200; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d, 'x%d')", i%20, i%7, i%11))
+	}
+	tk.MustExec("insert t values (NULL, NULL, NULL), (1, NULL, 'x1')")
+	queries := []string{
+		"select a, count(distinct b), sum(distinct b), count(distinct c), count(distinct b, c) from t group by a",
+		"select a % 3, avg(distinct b), count(*), group_concat(distinct b order by b) from t group by a % 3",
+		"select c, count(distinct a), max(b), count(b) from t group by c",
+		"select a, approx_count_distinct(b), approx_count_distinct(b, c), count(distinct c) from t group by a",
+		"select count(distinct b), count(distinct c), approx_count_distinct(a), approx_count_distinct(c, b) from t",
+	}
+	tk.MustExec("set @@tidb_hash_agg_concurrency=1")
+	var expected [][][]interface{}
+	for _, query := range queries {
+		expected = append(expected, tk.MustQuery(query).Sort().Rows())
+	}
+	result := tk.MustQuery(queries[0]).Sort().Rows()
+	c.Assert(result, HasLen, 21)
+	c.Assert(fmt.Sprintf("%v", result[1]), Equals, "[1 7 21 10 10]")
+	c.Assert(fmt.Sprintf("%v", result[20]), Equals, "[<nil> 0 <nil> 0 0]")
+	// There are 77 distinct values of (c, b), the estimation is approximate.
+	tk.MustQuery(queries[4]).Check(testkit.Rows("7 11 20 78"))
+	tk.MustQuery("select approx_count_distinct(a) from t where a > 100").Check(testkit.Rows("0"))
+	tk.MustQuery("select a, approx_count_distinct(b), approx_count_distinct(null) from t where a < 2 group by a").Sort().Check(testkit.Rows("0 7 0", "1 7 0"))
+
+	// The rows are dispatched to the final workers by their groups, and the groups are spilled to disk.
+	tk.MustExec("set @@tidb_hash_agg_concurrency=4")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+	tk.MustExec("set @@tidb_max_hash_rows_in_memory=3")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+	tk.MustExec("set @@tidb_hash_agg_concurrency=1")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+
+	// The memory usage of the distinct values makes the groups spill.
+	tk.MustExec("set @@tidb_max_hash_rows_in_memory=10000")
+	tk.MustExec("set @@tidb_mem_quota_query = 1000")
+	tk.MustExec("set @@tidb_mem_oom_action = 'spill'")
+	for i, query := range queries[:3] {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+	tk.MustExec("set @@tidb_mem_oom_action = 'cancel'")
+	rs, err := tk.Exec("select count(distinct a, b, c) from t")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(terror.ErrorEqual(err, memory.ErrMemExceed), IsTrue, Commentf("%v", err))
+}
+
 func (s *testSuite) TestStreamAgg(c *C) {
 	col := &expression.Column{
 		Index: 1,
@@ -310,6 +462,8 @@ func (s *testSuite) TestStreamAgg(c *C) {
 		result  string
 		input   [][]interface{}
 		result1 []string
+		// result2 is the result of executing the executor again with a single row (2, 4).
+		result2 string
 	}{
 		{
 			sumAgg,
@@ -320,6 +474,7 @@ func (s *testSuite) TestStreamAgg(c *C) {
 			[]string{
 				"1", "5",
 			},
+			"4",
 		},
 		{
 			cntAgg,
@@ -330,6 +485,7 @@ func (s *testSuite) TestStreamAgg(c *C) {
 			[]string{
 				"1", "2",
 			},
+			"1",
 		},
 		{
 			avgAgg,
@@ -340,6 +496,7 @@ func (s *testSuite) TestStreamAgg(c *C) {
 			[]string{
 				"1.0000", "2.5000",
 			},
+			"4.0000",
 		},
 		{
 			maxAgg,
@@ -350,6 +507,7 @@ func (s *testSuite) TestStreamAgg(c *C) {
 			[]string{
 				"1", "3",
 			},
+			"4",
 		},
 	}
 	ctx := mock.NewContext()
@@ -380,6 +538,16 @@ func (s *testSuite) TestStreamAgg(c *C) {
 			c.Check(row, NotNil)
 			c.Assert(fmt.Sprintf("%v", row.Data[0].GetValue()), Equals, res)
 		}
+		// The executor can be executed again after it's closed, the group key of the last execution is not kept.
+		e.Close()
+		mock.Rows = []*executor.Row{{Data: types.MakeDatums(2, 4)}}
+		row, err = e.Next()
+		c.Check(err, IsNil)
+		c.Check(row, NotNil)
+		c.Assert(fmt.Sprintf("%v", row.Data[0].GetValue()), Equals, ca.result2)
+		row, err = e.Next()
+		c.Check(err, IsNil)
+		c.Check(row, IsNil)
 	}
 }
 
@@ -435,5 +603,47 @@ func (s *testSuite) TestAggPushDown(c *C) {
 	tk.MustExec("insert into t values(1, 1, 1), (2, 1, 1)")
 	tk.MustExec("insert into tt values(1, 2, 1)")
 	tk.MustQuery("select max(a.b), max(b.b) from t a join tt b on a.a = b.a group by a.c").Check(testkit.Rows("1 2"))
-	tk.MustQuery("select a, count(b) from (select * from t union all select * from tt) k group by a").Check(testkit.Rows("1 2", "2 1"))
+	tk.MustQuery("select a, count(b) from (select * from t union all select * from tt) k group by a").Sort().Check(testkit.Rows("1 2", "2 1"))
+}
+
+func (s *testSuite) TestGroupConcatAndJSONAgg(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t(a int, b int, c varchar(20))")
+	tk.MustExec(`insert into t values (1, 3, 'x'), (1, 1, 'y'), (1, 2, 'x'), (2, 5, null), (2, 4, 'z"')`)
+
+	// The order by items and the separator of group_concat.
+	tk.MustQuery("select a, group_concat(b order by b) from t group by a order by a").Check(testkit.Rows("1 1,2,3", "2 4,5"))
+	tk.MustQuery("select a, group_concat(b, c order by b desc separator '; ') from t group by a order by a").Check(testkit.Rows("1 3x; 2x; 1y", `2 4z"`))
+	tk.MustQuery("select group_concat(distinct c order by c desc separator '') from t where a = 1").Check(testkit.Rows("yx"))
+	tk.MustQuery("select group_concat(b order by c, b) from t").Check(testkit.Rows("5,2,3,1,4"))
+	tk.MustQuery("select group_concat(b order by b), group_concat(b order by b desc), group_concat(b separator '-') from t where a = 2").Check(testkit.Rows("4,5 5,4 5-4"))
+	tk.MustQuery("select group_concat(b) from t where a > 2").Check(testkit.Rows("<nil>"))
+	tk.MustQuery("select a, group_concat(c separator '-') from t group by a order by a").Check(testkit.Rows("1 x-y-x", `2 z"`))
+
+	// The result is cut by group_concat_max_len.
+	tk.MustExec("set @@group_concat_max_len = 4")
+	tk.MustQuery("select group_concat(b order by b) from t").Check(testkit.Rows("1,2,"))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1260 Row 5 was cut by GROUP_CONCAT()"))
+	tk.MustQuery("select a, group_concat(c) from t group by a order by a").Check(testkit.Rows("1 x,y,", `2 z"`))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1260 Row 3 was cut by GROUP_CONCAT()"))
+	tk.MustExec("set @@group_concat_max_len = 1024")
+
+	// The json aggregation functions.
+	tk.MustQuery("select a, json_arrayagg(c) from t group by a order by a").Check(testkit.Rows(`1 ["x", "y", "x"]`, `2 [null, "z\""]`))
+	tk.MustQuery("select json_arrayagg(b) from t where a = 2").Check(testkit.Rows("[5, 4]"))
+	tk.MustQuery("select json_objectagg(c, b) from t where a = 1").Check(testkit.Rows(`{"x": 2, "y": 1}`))
+	tk.MustQuery("select json_objectagg(b, c) from t where a = 2").Check(testkit.Rows(`{"4": "z\"", "5": null}`))
+	tk.MustQuery("select json_objectagg(concat('k', b * 10), a) from t where b < 3").Check(testkit.Rows(`{"k10": 1, "k20": 1}`))
+	tk.MustQuery("select json_arrayagg(b), json_objectagg(c, b) from t where a > 2").Check(testkit.Rows("<nil> <nil>"))
+	rs, err := tk.Exec("select json_objectagg(c, b) from t")
+	c.Assert(err, IsNil)
+	_, err = rs.Next()
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "[expression:3158]JSON documents may not contain NULL member names.")
 }
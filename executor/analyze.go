@@ -62,7 +62,7 @@ func (e *AnalyzeExec) Close() error {
 // Next implements the Executor Next interface.
 func (e *AnalyzeExec) Next() (*Row, error) {
 	for _, src := range e.Srcs {
-		ae := src.(*AnalyzeExec)
+		ae := unwrapRuntimeStats(src).(*AnalyzeExec)
 		var count int64 = -1
 		var sampleRows []*ast.Row
 		if ae.colOffsets != nil {
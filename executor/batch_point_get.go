@@ -0,0 +1,243 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var _ Executor = &BatchPointGetExec{}
+
+// BatchPointGetExec reads the rows of a set of handles, or a set of unique index values, with a single BatchGet
+// to the KV layer instead of a coprocessor request. It is built from a table scan or an index scan whose ranges are
+// all points, like "where pk in (1, 2, 3)".
+type BatchPointGetExec struct {
+	ctx     context.Context
+	schema  *expression.Schema
+	table   table.Table
+	asName  *model.CIStr
+	columns []*model.ColumnInfo
+	startTS uint64
+
+	// handles are the handles of the rows to read when index is nil.
+	handles []int64
+	// index is the unique index to read the handles from, idxVals are the values of the index columns.
+	index   *model.IndexInfo
+	idxVals [][]types.Datum
+
+	limitCount *int64
+	// txn is set when the table has been modified in the transaction, the keys are read from it one by one instead
+	// of the snapshot, to see the uncommitted changes.
+	txn kv.Transaction
+
+	fetched bool
+	rows    []*Row
+	cursor  int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *BatchPointGetExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Close implements the Executor Close interface.
+func (e *BatchPointGetExec) Close() error {
+	e.fetched = false
+	e.rows = nil
+	e.cursor = 0
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *BatchPointGetExec) Next() (*Row, error) {
+	if !e.fetched {
+		err := e.fetchRows()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.fetched = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+func (e *BatchPointGetExec) fetchRows() error {
+	handles := e.handles
+	if e.index != nil {
+		var err error
+		handles, err = e.fetchHandles()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	tblInfo := e.table.Meta()
+	keys := make([]kv.Key, 0, len(handles))
+	for _, h := range handles {
+		keys = append(keys, tablecodec.EncodeRowKeyWithHandle(tblInfo.ID, h))
+	}
+	values, err := e.batchGet(keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i, h := range handles {
+		if e.limitCount != nil && len(e.rows) >= int(*e.limitCount) {
+			break
+		}
+		value, ok := values[string(keys[i])]
+		if !ok {
+			continue
+		}
+		data, err := e.decodeRowData(h, value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.rows = append(e.rows, resultRowToRow(e.table, h, data, e.asName))
+	}
+	return nil
+}
+
+// decodeRowData decodes the row value of handle h into the datums of the columns. Like the coprocessor, a column
+// missing in the value is filled with its origin default value, or NULL if it has none.
+func (e *BatchPointGetExec) decodeRowData(h int64, value []byte) ([]types.Datum, error) {
+	colTps := make(map[int64]*types.FieldType, len(e.columns))
+	for _, col := range e.columns {
+		colTps[col.ID] = &col.FieldType
+	}
+	row, err := tablecodec.DecodeRow(value, colTps)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data := make([]types.Datum, len(e.columns))
+	pkIsHandle := e.table.Meta().PKIsHandle
+	for i, col := range e.columns {
+		if pkIsHandle && mysql.HasPriKeyFlag(col.Flag) {
+			if mysql.HasUnsignedFlag(col.Flag) {
+				data[i].SetUint64(uint64(h))
+			} else {
+				data[i].SetInt64(h)
+			}
+			continue
+		}
+		if d, ok := row[col.ID]; ok {
+			// The TIMESTAMP values are encoded in the system time zone.
+			if d.Kind() == types.KindMysqlTime {
+				t := d.GetMysqlTime()
+				if err = t.ConvertTimeZone(nil, e.ctx.GetSessionVars().StmtCtx.TimeZone); err != nil {
+					return nil, errors.Trace(err)
+				}
+				d.SetMysqlTime(t)
+			}
+			data[i] = d
+			continue
+		}
+		if col.OriginDefaultValue != nil {
+			data[i], err = table.GetColOriginDefaultValue(e.ctx, col)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	return data, nil
+}
+
+// fetchHandles reads the handles of the unique index values, the values which are not found are skipped.
+func (e *BatchPointGetExec) fetchHandles() ([]int64, error) {
+	tblInfo := e.table.Meta()
+	keys := make([]kv.Key, 0, len(e.idxVals))
+	tz := e.ctx.GetSessionVars().StmtCtx.TimeZone
+	for _, vals := range e.idxVals {
+		vals, err := tablecodec.ConvertTimeZone(vals, tz, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		encoded, err := codec.EncodeKey(nil, vals...)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		keys = append(keys, tablecodec.EncodeIndexSeekKey(tblInfo.ID, e.index.ID, encoded))
+	}
+	values, err := e.batchGet(keys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	handles := make([]int64, 0, len(values))
+	for _, key := range keys {
+		value, ok := values[string(key)]
+		if !ok {
+			continue
+		}
+		h, err := tables.DecodeHandle(value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		handles = append(handles, h)
+	}
+	return handles, nil
+}
+
+// batchGet gets the values of the keys, the keys not found are absent in the returned map.
+func (e *BatchPointGetExec) batchGet(keys []kv.Key) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	// The transaction of an auto-commit statement may be committed before the rows are read, the snapshot has the
+	// same data then.
+	if e.txn != nil && e.txn.Valid() {
+		values := make(map[string][]byte, len(keys))
+		for _, key := range keys {
+			value, err := e.txn.Get(key)
+			if kv.IsErrNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			values[string(key)] = value
+		}
+		return values, nil
+	}
+	store := sessionctx.GetDomain(e.ctx).Store()
+	snapshot, err := store.GetSnapshot(kv.Version{Ver: e.startTS})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer snapshot.Release()
+	snapshot.SetReplicaRead(varsutil.GetReplicaRead(e.ctx.GetSessionVars(), store))
+	values, err := snapshot.BatchGet(keys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The snapshot is not the one of the transaction, so its reads are not recorded by the store, the BatchGet is
+	// recorded as a request.
+	if details := e.ctx.GetSessionVars().StmtCtx.ReadDetails; details != nil {
+		details.Record(int64(len(values)))
+	}
+	return values, nil
+}
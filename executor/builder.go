@@ -24,8 +24,14 @@ import (
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -36,6 +42,10 @@ type executorBuilder struct {
 	is  infoschema.InfoSchema
 	// If there is any error during Executor building process, err is set.
 	err error
+	// startTS is cached by getStartTS, so the executors built during execution read the same snapshot.
+	startTS uint64
+	// cteTables are the temporary tables keeping the rows of the common table expressions, by the table IDs.
+	cteTables map[int64]table.Table
 }
 
 func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executorBuilder {
@@ -46,11 +56,70 @@ func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executor
 }
 
 func (b *executorBuilder) build(p plan.Plan) Executor {
+	e := b.buildExecutor(p)
+	if e == nil {
+		return nil
+	}
+	return b.wrapRuntimeStats(p, e)
+}
+
+// wrapRuntimeStats wraps the executor to record its runtime statistics by the ID of the plan.
+func (b *executorBuilder) wrapRuntimeStats(p plan.Plan, e Executor) Executor {
+	coll := b.ctx.GetSessionVars().StmtCtx.RuntimeStatsColl
+	if coll == nil {
+		return e
+	}
+	// ExecuteExec only builds the executor of the prepared statement, which is wrapped when it's built.
+	if _, ok := e.(*ExecuteExec); ok {
+		return e
+	}
+	stats := coll.Get(p.ID())
+	stats.SetConcurrency(b.concurrencyOf(e))
+	return &runtimeStatsExec{Executor: e, stats: stats}
+}
+
+// concurrencyOf returns the number of workers used by the executor, 0 means the executor runs serially.
+func (b *executorBuilder) concurrencyOf(e Executor) int {
+	switch x := e.(type) {
+	case *HashJoinExec:
+		return x.concurrency
+	case *HashAggExec:
+		if x.concurrency > 1 {
+			return x.concurrency
+		}
+	case *ProjectionExec:
+		if x.concurrency > 1 {
+			return x.concurrency
+		}
+	case *UnionExec:
+		return len(x.Srcs)
+	case *ApplyJoinExec:
+		if x.parallel {
+			return 2
+		}
+	case *XSelectTableExec:
+		return b.ctx.GetSessionVars().DistSQLScanConcurrency
+	case *XSelectIndexExec:
+		if x.indexPlan.DoubleRead {
+			return b.ctx.GetSessionVars().IndexLookupConcurrency
+		}
+		return b.ctx.GetSessionVars().DistSQLScanConcurrency
+	}
+	return 0
+}
+
+func (b *executorBuilder) buildExecutor(p plan.Plan) Executor {
 	switch v := p.(type) {
 	case nil:
 		return nil
 	case *plan.CheckTable:
 		return b.buildCheckTable(v)
+	case *plan.ChecksumTable:
+		return b.buildChecksumTable(v)
+	case *plan.SplitRegion:
+		return b.buildSplitRegion(v)
+	case *plan.ShowIndexAdvice:
+		return b.buildShowIndexAdvice(v)
 	case *plan.DDL:
 		return b.buildDDL(v)
 	case *plan.Deallocate:
@@ -65,6 +134,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildInsert(v)
 	case *plan.LoadData:
 		return b.buildLoadData(v)
+	case *plan.LoadStats:
+		return b.buildLoadStats(v)
 	case *plan.Limit:
 		return b.buildLimit(v)
 	case *plan.Prepare:
@@ -73,6 +144,10 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildSelectLock(v)
 	case *plan.ShowDDL:
 		return b.buildShowDDL(v)
+	case *plan.ShowDDLJobs:
+		return b.buildShowDDLJobs(v)
+	case *plan.CancelDDLJobs:
+		return b.buildCancelDDLJobs(v)
 	case *plan.Show:
 		return b.buildShow(v)
 	case *plan.Simple:
@@ -83,6 +158,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildSort(v)
 	case *plan.Union:
 		return b.buildUnion(v)
+	case *plan.Window:
+		return b.buildWindow(v)
 	case *plan.Update:
 		return b.buildUpdate(v)
 	case *plan.PhysicalUnionScan:
@@ -91,6 +168,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildHashJoin(v)
 	case *plan.PhysicalMergeJoin:
 		return b.buildMergeJoin(v)
+	case *plan.PhysicalIndexJoin:
+		return b.buildIndexJoin(v)
 	case *plan.PhysicalHashSemiJoin:
 		return b.buildSemiJoin(v)
 	case *plan.Selection:
@@ -117,6 +196,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildDummyScan(v)
 	case *plan.Cache:
 		return b.buildCache(v)
+	case *plan.PhysicalCTE:
+		return b.buildCTE(v)
 	case *plan.Analyze:
 		return b.buildAnalyze(v)
 	default:
@@ -148,6 +229,42 @@ func (b *executorBuilder) buildShowDDL(v *plan.ShowDDL) Executor {
 	return e
 }
 
+func (b *executorBuilder) buildShowDDLJobs(v *plan.ShowDDLJobs) Executor {
+	// Like ShowDDL, the jobs are read here because the transaction may have been committed when Next is called.
+	e := &ShowDDLJobsExec{
+		ctx:    b.ctx,
+		is:     b.is,
+		schema: v.Schema(),
+	}
+	jobs, err := inspectkv.GetDDLJobs(e.ctx.Txn())
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	historyJobs, err := inspectkv.GetHistoryDDLJobs(e.ctx.Txn(), maxHistoryDDLJobs)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	e.jobs = append(jobs, historyJobs...)
+	return e
+}
+
+func (b *executorBuilder) buildCancelDDLJobs(v *plan.CancelDDLJobs) Executor {
+	// The jobs are requested to be cancelled in the transaction here, it's committed before Next is called.
+	e := &CancelDDLJobsExec{
+		schema: v.Schema(),
+		jobIDs: v.JobIDs,
+	}
+	errs, err := inspectkv.CancelJobs(b.ctx.Txn(), e.jobIDs)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	e.errs = errs
+	return e
+}
+
 func (b *executorBuilder) buildCheckTable(v *plan.CheckTable) Executor {
 	return &CheckTableExec{
 		tables: v.Tables,
@@ -156,6 +273,37 @@ func (b *executorBuilder) buildCheckTable(v *plan.CheckTable) Executor {
 	}
 }
 
+func (b *executorBuilder) buildChecksumTable(v *plan.ChecksumTable) Executor {
+	return &ChecksumTableExec{
+		tables:  v.Tables,
+		schema:  v.Schema(),
+		ctx:     b.ctx,
+		is:      b.is,
+		startTS: b.getStartTS(),
+	}
+}
+
+func (b *executorBuilder) buildSplitRegion(v *plan.SplitRegion) Executor {
+	return &SplitRegionExec{
+		schema:     v.Schema(),
+		ctx:        b.ctx,
+		tableInfo:  v.TableInfo,
+		indexInfo:  v.IndexInfo,
+		lower:      v.Lower,
+		upper:      v.Upper,
+		num:        v.Num,
+		valueLists: v.ValueLists,
+	}
+}
+
+func (b *executorBuilder) buildShowIndexAdvice(v *plan.ShowIndexAdvice) Executor {
+	return &ShowIndexAdviceExec{
+		schema: v.Schema(),
+		ctx:    b.ctx,
+		is:     b.is,
+	}
+}
+
 func (b *executorBuilder) buildDeallocate(v *plan.Deallocate) Executor {
 	return &DeallocateExec{
 		ctx:  b.ctx,
@@ -281,13 +429,14 @@ func (b *executorBuilder) buildLoadData(v *plan.LoadData) Executor {
 	return &LoadData{
 		IsLocal: v.IsLocal,
 		loadDataInfo: &LoadDataInfo{
-			row:        make([]types.Datum, len(tbl.Cols())),
-			insertVal:  &InsertValues{ctx: b.ctx, Table: tbl},
-			Path:       v.Path,
-			Table:      tbl,
-			FieldsInfo: v.FieldsInfo,
-			LinesInfo:  v.LinesInfo,
-			Ctx:        b.ctx,
+			row:         make([]types.Datum, len(tbl.Cols())),
+			insertVal:   &InsertValues{ctx: b.ctx, Table: tbl},
+			Path:        v.Path,
+			Table:       tbl,
+			FieldsInfo:  v.FieldsInfo,
+			LinesInfo:   v.LinesInfo,
+			IgnoreLines: v.IgnoreLines,
+			Ctx:         b.ctx,
 		},
 	}
 }
@@ -322,14 +471,23 @@ func (b *executorBuilder) buildRevoke(revoke *ast.RevokeStmt) Executor {
 }
 
 func (b *executorBuilder) buildDDL(v *plan.DDL) Executor {
-	return &DDLExec{Statement: v.Statement, ctx: b.ctx, is: b.is}
+	e := &DDLExec{Statement: v.Statement, ctx: b.ctx, is: b.is}
+	if len(v.Children()) > 0 {
+		e.SelectExec = b.build(v.Children()[0])
+	}
+	return e
 }
 
 func (b *executorBuilder) buildExplain(v *plan.Explain) Executor {
-	return &ExplainExec{
+	e := &ExplainExec{
 		StmtPlan: v.StmtPlan,
 		schema:   v.Schema(),
+		ctx:      b.ctx,
 	}
+	if v.Analyze {
+		e.analyzeExec = b.build(v.StmtPlan)
+	}
+	return e
 }
 
 func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) Executor {
@@ -338,7 +496,7 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) Executor
 		return nil
 	}
 	us := &UnionScanExec{ctx: b.ctx, Src: src, schema: v.Schema()}
-	switch x := src.(type) {
+	switch x := unwrapRuntimeStats(src).(type) {
 	case *XSelectTableExec:
 		us.desc = x.desc
 		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
@@ -357,6 +515,10 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) Executor
 		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
 		us.condition = v.Condition
 		us.buildAndSortAddedRows(x.table, x.asName)
+	case *BatchPointGetExec:
+		// The rows are read from the transaction, so the uncommitted changes are seen without the union scan.
+		x.txn = b.ctx.Txn()
+		return x
 	default:
 		// The mem table will not be written by sql directly, so we can omit the union scan to avoid err reporting.
 		return src
@@ -391,6 +553,46 @@ func (b *executorBuilder) buildMergeJoin(v *plan.PhysicalMergeJoin) Executor {
 	return exec
 }
 
+func (b *executorBuilder) buildIndexJoin(v *plan.PhysicalIndexJoin) Executor {
+	outerExec := b.build(v.Children()[v.OuterIndex])
+	if b.err != nil {
+		return nil
+	}
+	innerPlan := v.Children()[1-v.OuterIndex].(plan.PhysicalPlan)
+	targetTypes := make([]*types.FieldType, 0, len(v.OuterJoinKeys))
+	for i, outerKey := range v.OuterJoinKeys {
+		targetTypes = append(targetTypes, types.NewFieldType(types.MergeFieldType(outerKey.GetType().Tp, v.InnerJoinKeys[i].GetType().Tp)))
+	}
+	outerConds, innerConds := v.LeftConditions, v.RightConditions
+	if v.OuterIndex == 1 {
+		outerConds, innerConds = innerConds, outerConds
+	}
+	defaultValues := make([]types.Datum, innerPlan.Schema().Len())
+	copy(defaultValues, v.DefaultValues)
+	// The inner executors are built during execution, when the transaction may have been committed.
+	innerBuilder := newExecutorBuilder(b.ctx, b.is)
+	innerBuilder.startTS = b.getStartTS()
+	innerBuilder.cteTables = b.cteTables
+	return &IndexLookUpJoin{
+		ctx:             b.ctx,
+		schema:          v.Schema(),
+		outerExec:       outerExec,
+		innerPlan:       innerPlan,
+		innerBuilder:    innerBuilder,
+		outerKeys:       v.OuterJoinKeys,
+		innerKeys:       v.InnerJoinKeys,
+		targetTypes:     targetTypes,
+		outerFilter:     expression.ComposeCNFCondition(b.ctx, outerConds...),
+		innerFilter:     expression.ComposeCNFCondition(b.ctx, innerConds...),
+		otherFilter:     expression.ComposeCNFCondition(b.ctx, v.OtherConditions...),
+		outer:           v.JoinType != plan.InnerJoin,
+		outerIsLeft:     v.OuterIndex == 0,
+		defaultInnerRow: &Row{Data: defaultValues},
+		batchSize:       b.ctx.GetSessionVars().IndexJoinBatchSize,
+		memTracker:      b.newMemTracker("IndexLookUpJoin"),
+	}
+}
+
 func (b *executorBuilder) buildHashJoin(v *plan.PhysicalHashJoin) Executor {
 	var leftHashKey, rightHashKey []*expression.Column
 	var targetTypes []*types.FieldType
@@ -409,6 +611,7 @@ func (b *executorBuilder) buildHashJoin(v *plan.PhysicalHashJoin) Executor {
 		targetTypes:   targetTypes,
 		concurrency:   v.Concurrency,
 		defaultValues: v.DefaultValues,
+		memTracker:    b.newMemTracker("HashJoin"),
 	}
 	if v.SmallTable == 1 {
 		e.smallFilter = expression.ComposeCNFCondition(b.ctx, v.RightConditions...)
@@ -472,22 +675,37 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) *HashSemiJ
 		auxMode:      v.WithAux,
 		anti:         v.Anti,
 		targetTypes:  targetTypes,
+		memTracker:   b.newMemTracker("HashSemiJoin"),
 	}
 	return e
 }
 
 func (b *executorBuilder) buildAggregation(v *plan.PhysicalAggregation) Executor {
+	if keyOffsets, outputOffsets, ok := unionDistinctOffsets(v); ok {
+		return &UnionDistinctExec{
+			Src:           b.build(v.Children()[0]),
+			schema:        v.Schema(),
+			keyOffsets:    keyOffsets,
+			outputOffsets: outputOffsets,
+			memTracker:    b.newMemTracker("UnionDistinct"),
+		}
+	}
 	src := b.build(v.Children()[0])
 	if v.AggType == plan.StreamedAgg {
-		return &StreamAggExec{
+		e := &StreamAggExec{
 			Src:          src,
 			schema:       v.Schema(),
 			Ctx:          b.ctx,
 			AggFuncs:     v.AggFuncs,
 			GroupByItems: v.GroupByItems,
+			memTracker:   b.newMemTracker("StreamAgg"),
+		}
+		for _, af := range e.AggFuncs {
+			af.SetMemTracker(e.memTracker)
 		}
+		return e
 	}
-	return &HashAggExec{
+	e := &HashAggExec{
 		Src:          src,
 		schema:       v.Schema(),
 		ctx:          b.ctx,
@@ -495,7 +713,78 @@ func (b *executorBuilder) buildAggregation(v *plan.PhysicalAggregation) Executor
 		GroupByItems: v.GroupByItems,
 		aggType:      v.AggType,
 		hasGby:       v.HasGby,
+		concurrency:  1,
+		memTracker:   b.newMemTracker("HashAgg"),
+	}
+	for _, af := range e.AggFuncs {
+		af.SetMemTracker(e.memTracker)
+	}
+	// It's meaningless to aggregate a single group in parallel. The functions which read or change the session state
+	// can't be evaluated in the workers concurrently.
+	if v.HasGby && !hasOrderSensitiveFunc(aggEvalExprs(v)) {
+		e.concurrency = b.ctx.GetSessionVars().HashAggConcurrency
+		e.shuffle = hasUnmergeableAggFunc(v.AggFuncs)
+	}
+	return e
+}
+
+// aggEvalExprs returns the group by items and the arguments of the aggregate functions, which are evaluated on the
+// input rows.
+func aggEvalExprs(v *plan.PhysicalAggregation) []expression.Expression {
+	exprs := append([]expression.Expression(nil), v.GroupByItems...)
+	for _, af := range v.AggFuncs {
+		exprs = append(exprs, af.GetArgs()...)
+	}
+	return exprs
+}
+
+// unionDistinctOffsets checks whether the aggregation is the one deduplicating the rows of a UNION DISTINCT, which
+// groups the rows of the union by columns and returns the first row of the columns. It returns the offsets of the
+// group by columns and the returned columns in the union schema.
+func unionDistinctOffsets(v *plan.PhysicalAggregation) ([]int, []int, bool) {
+	if _, ok := v.Children()[0].(*plan.Union); !ok || !v.HasGby {
+		return nil, nil, false
+	}
+	keyOffsets := make([]int, 0, len(v.GroupByItems))
+	for _, item := range v.GroupByItems {
+		col, ok := item.(*expression.Column)
+		if !ok {
+			return nil, nil, false
+		}
+		keyOffsets = append(keyOffsets, col.Index)
 	}
+	outputOffsets := make([]int, 0, len(v.AggFuncs))
+	for _, af := range v.AggFuncs {
+		if af.GetName() != ast.AggFuncFirstRow || len(af.GetArgs()) != 1 {
+			return nil, nil, false
+		}
+		col, ok := af.GetArgs()[0].(*expression.Column)
+		if !ok {
+			return nil, nil, false
+		}
+		outputOffsets = append(outputOffsets, col.Index)
+	}
+	return keyOffsets, outputOffsets, true
+}
+
+// hasUnmergeableAggFunc checks if the partial results of an aggregate function can't be merged. They are the
+// distinct aggregate functions, the ordered group_concat functions and the json aggregate functions, whose partial
+// results don't keep the values or their order.
+func hasUnmergeableAggFunc(aggFuncs []expression.AggregationFunction) bool {
+	for _, af := range aggFuncs {
+		if af.IsDistinct() {
+			return true
+		}
+		switch af.GetName() {
+		case ast.AggFuncGroupConcat:
+			if _, ordered := expression.GroupConcatInfo(af); ordered {
+				return true
+			}
+		case ast.AggFuncJSONArrayAgg, ast.AggFuncJSONObjectAgg:
+			return true
+		}
+	}
+	return false
 }
 
 func (b *executorBuilder) buildSelection(v *plan.Selection) Executor {
@@ -506,16 +795,63 @@ func (b *executorBuilder) buildSelection(v *plan.Selection) Executor {
 		scanController: v.ScanController,
 		Conditions:     v.Conditions,
 	}
+	exec.useChunk = supportChunk(exec.Src) && !hasOrderSensitiveFunc(v.Conditions)
 	return exec
 }
 
 func (b *executorBuilder) buildProjection(v *plan.Projection) Executor {
-	return &ProjectionExec{
-		Src:    b.build(v.Children()[0]),
-		ctx:    b.ctx,
-		exprs:  v.Exprs,
-		schema: v.Schema(),
+	e := &ProjectionExec{
+		Src:         b.build(v.Children()[0]),
+		ctx:         b.ctx,
+		exprs:       v.Exprs,
+		schema:      v.Schema(),
+		concurrency: 1,
 	}
+	if hasExpensiveFunc(v.Exprs) && !hasOrderSensitiveFunc(v.Exprs) {
+		e.concurrency = b.ctx.GetSessionVars().ProjectionConcurrency
+	}
+	e.useChunk = e.concurrency == 1 && supportChunk(e.Src) && !hasOrderSensitiveFunc(v.Exprs)
+	return e
+}
+
+// expensiveFuncs are the functions which are costly enough to be evaluated in the projection workers.
+var expensiveFuncs = map[string]struct{}{
+	ast.Regexp: {},
+	ast.Like:   {},
+}
+
+// orderSensitiveFuncs are the functions whose results depend on the order the rows are evaluated in.
+var orderSensitiveFuncs = map[string]struct{}{
+	ast.SetVar:       {},
+	ast.GetVar:       {},
+	ast.Rand:         {},
+	ast.Sleep:        {},
+	ast.LastInsertId: {},
+	ast.RowCount:     {},
+}
+
+func hasExpensiveFunc(exprs []expression.Expression) bool {
+	return hasFuncIn(exprs, expensiveFuncs)
+}
+
+func hasOrderSensitiveFunc(exprs []expression.Expression) bool {
+	return hasFuncIn(exprs, orderSensitiveFuncs)
+}
+
+func hasFuncIn(exprs []expression.Expression, funcs map[string]struct{}) bool {
+	for _, expr := range exprs {
+		sf, ok := expr.(*expression.ScalarFunction)
+		if !ok {
+			continue
+		}
+		if _, ok := funcs[sf.FuncName.L]; ok {
+			return true
+		}
+		if hasFuncIn(sf.GetArgs(), funcs) {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *executorBuilder) buildTableDual(v *plan.TableDual) Executor {
@@ -523,15 +859,34 @@ func (b *executorBuilder) buildTableDual(v *plan.TableDual) Executor {
 }
 
 func (b *executorBuilder) getStartTS() uint64 {
-	startTS := b.ctx.GetSessionVars().SnapshotTS
+	if b.startTS != 0 {
+		return b.startTS
+	}
+	startTS := b.ctx.GetSessionVars().StmtCtx.ReadTS
+	if startTS == 0 {
+		startTS = b.ctx.GetSessionVars().SnapshotTS
+	}
 	if startTS == 0 {
 		startTS = b.ctx.Txn().StartTS()
 	}
+	b.startTS = startTS
 	return startTS
 }
 
+// newMemTracker creates a memory tracker for an executor, and attaches it to the tracker of the statement.
+func (b *executorBuilder) newMemTracker(label string) *memory.Tracker {
+	tracker := memory.NewTracker(label, -1)
+	if sc := b.ctx.GetSessionVars().StmtCtx; sc.MemTracker != nil {
+		tracker.AttachTo(sc.MemTracker)
+	}
+	return tracker
+}
+
 func (b *executorBuilder) buildMemTable(v *plan.PhysicalMemTable) Executor {
-	table, _ := b.is.TableByID(v.Table.ID)
+	table, ok := b.cteTables[v.Table.ID]
+	if !ok {
+		table, _ = b.is.TableByID(v.Table.ID)
+	}
 	ts := &TableScanExec{
 		t:            table,
 		asName:       v.TableAsName,
@@ -540,7 +895,7 @@ func (b *executorBuilder) buildMemTable(v *plan.PhysicalMemTable) Executor {
 		schema:       v.Schema(),
 		seekHandle:   math.MinInt64,
 		ranges:       v.Ranges,
-		isInfoSchema: strings.EqualFold(v.DBName.L, infoschema.Name),
+		isInfoSchema: strings.EqualFold(v.DBName.L, infoschema.Name) || perfschema.IsVirtualTable(table),
 	}
 	return ts
 }
@@ -551,6 +906,18 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
 		return nil
 	}
 	table, _ := b.is.TableByID(v.Table.ID)
+	if handles, ok := pointGetHandles(v); ok {
+		return &BatchPointGetExec{
+			ctx:        b.ctx,
+			schema:     v.Schema(),
+			table:      table,
+			asName:     v.TableAsName,
+			columns:    v.Columns,
+			startTS:    startTS,
+			handles:    handles,
+			limitCount: v.LimitCount,
+		}
+	}
 	client := b.ctx.GetClient()
 	supportDesc := client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeDesc)
 	e := &XSelectTableExec{
@@ -575,12 +942,99 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
 	return e
 }
 
+// pointGetHandles returns the handles to read if the table scan only reads some points, so a BatchPointGetExec can
+// be used instead of a coprocessor request.
+func pointGetHandles(v *plan.PhysicalTableScan) ([]int64, bool) {
+	if len(v.Ranges) == 0 || v.Aggregated || v.TableConditionPBExpr != nil || len(v.SortItemsPB) > 0 {
+		return nil, false
+	}
+	handles := make([]int64, 0, len(v.Ranges))
+	for i := range v.Ranges {
+		if !v.Ranges[i].IsPoint() {
+			return nil, false
+		}
+		handles = append(handles, v.Ranges[i].LowVal)
+	}
+	if v.Desc {
+		for i, j := 0, len(handles)-1; i < j; i, j = i+1, j-1 {
+			handles[i], handles[j] = handles[j], handles[i]
+		}
+	}
+	return handles, true
+}
+
+// pointGetIndexValues returns the values of the unique index to read if the index scan only reads some points of
+// a unique index, so a BatchPointGetExec can be used instead of coprocessor requests. The values are converted to
+// the types of the index columns, the points which can't match any row are skipped.
+func pointGetIndexValues(sc *variable.StatementContext, v *plan.PhysicalIndexScan) ([][]types.Datum, bool) {
+	if len(v.Ranges) == 0 || !v.Index.Unique || v.Aggregated || v.IndexConditionPBExpr != nil ||
+		v.TableConditionPBExpr != nil || len(v.SortItemsPB) > 0 {
+		return nil, false
+	}
+	for _, idxCol := range v.Index.Columns {
+		// The index of a column prefix doesn't identify a row.
+		if idxCol.Length != types.UnspecifiedLength {
+			return nil, false
+		}
+	}
+	idxVals := make([][]types.Datum, 0, len(v.Ranges))
+	for _, ran := range v.Ranges {
+		if len(ran.LowVal) != len(v.Index.Columns) || !ran.IsPoint(sc) {
+			return nil, false
+		}
+	}
+	for _, ran := range v.Ranges {
+		vals := make([]types.Datum, 0, len(ran.LowVal))
+		for i, val := range ran.LowVal {
+			if val.IsNull() {
+				// The unique index allows multiple NULL values, they can't be read by a point get.
+				break
+			}
+			converted, err := val.ConvertTo(sc, &v.Table.Columns[v.Index.Columns[i].Offset].FieldType)
+			if err != nil {
+				return nil, false
+			}
+			cmp, err := converted.CompareDatum(sc, val)
+			if err != nil {
+				return nil, false
+			}
+			if cmp != 0 {
+				// The converted value has changed, there will be no match.
+				break
+			}
+			vals = append(vals, converted)
+		}
+		if len(vals) == len(ran.LowVal) {
+			idxVals = append(idxVals, vals)
+		}
+	}
+	if v.Desc {
+		for i, j := 0, len(idxVals)-1; i < j; i, j = i+1, j-1 {
+			idxVals[i], idxVals[j] = idxVals[j], idxVals[i]
+		}
+	}
+	return idxVals, true
+}
+
 func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 	startTS := b.getStartTS()
 	if b.err != nil {
 		return nil
 	}
 	table, _ := b.is.TableByID(v.Table.ID)
+	if idxVals, ok := pointGetIndexValues(b.ctx.GetSessionVars().StmtCtx, v); ok {
+		return &BatchPointGetExec{
+			ctx:        b.ctx,
+			schema:     v.Schema(),
+			table:      table,
+			asName:     v.TableAsName,
+			columns:    v.Columns,
+			startTS:    startTS,
+			index:      v.Index,
+			idxVals:    idxVals,
+			limitCount: v.LimitCount,
+		}
+	}
 	client := b.ctx.GetClient()
 	supportDesc := client.SupportRequestType(kv.ReqTypeIndex, kv.ReqSubTypeDesc)
 	e := &XSelectIndexExec{
@@ -613,24 +1067,82 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 	return e
 }
 
+func (b *executorBuilder) buildWindow(v *plan.Window) Executor {
+	src := b.build(v.Children()[0])
+	e := &WindowExec{
+		Src:         src,
+		Ctx:         b.ctx,
+		PartitionBy: v.PartitionBy,
+		Frame:       v.Frame,
+		WindowFuncs: make([]*WindowFuncDesc, 0, len(v.WindowFuncs)),
+		schema:      v.Schema(),
+		memTracker:  b.newMemTracker("Window"),
+	}
+	for _, item := range v.OrderBy {
+		e.OrderBy = append(e.OrderBy, item.Expr)
+	}
+	for _, f := range v.WindowFuncs {
+		switch f.Name {
+		case ast.WindowFuncRowNumber, ast.WindowFuncRank, ast.WindowFuncDenseRank:
+			e.WindowFuncs = append(e.WindowFuncs, &WindowFuncDesc{Name: f.Name})
+		case ast.WindowFuncLag, ast.WindowFuncLead:
+			desc := &WindowFuncDesc{Name: f.Name, Arg: f.Args[0], Offset: 1}
+			if len(f.Args) > 1 {
+				// The planner makes sure the offset is a non-negative integer constant.
+				offset, err := f.Args[1].Eval(nil)
+				if err != nil {
+					b.err = errors.Trace(err)
+					return nil
+				}
+				desc.Offset = offset.GetInt64()
+			}
+			if len(f.Args) > 2 {
+				desc.Default = f.Args[2]
+			}
+			e.WindowFuncs = append(e.WindowFuncs, desc)
+		default:
+			e.WindowFuncs = append(e.WindowFuncs, &WindowFuncDesc{Agg: expression.NewAggFunction(f.Name, f.Args, false)})
+		}
+	}
+	return e
+}
+
 func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
 	src := b.build(v.Children()[0])
-	if v.ExecLimit != nil {
+	if v.ExecLimit != nil && !topNExceedsMemory(v.ExecLimit, b.ctx.GetSessionVars().MaxSortRowsInMemory) {
 		return &TopnExec{
 			SortExec: SortExec{
-				Src:     src,
-				ByItems: v.ByItems,
-				ctx:     b.ctx,
-				schema:  v.Schema()},
+				Src:        src,
+				ByItems:    v.ByItems,
+				ctx:        b.ctx,
+				schema:     v.Schema(),
+				memTracker: b.newMemTracker("TopN")},
 			limit: v.ExecLimit,
 		}
 	}
-	return &SortExec{
-		Src:     src,
-		ByItems: v.ByItems,
-		ctx:     b.ctx,
-		schema:  v.Schema(),
+	e := &SortExec{
+		Src:        src,
+		ByItems:    v.ByItems,
+		ctx:        b.ctx,
+		schema:     v.Schema(),
+		memTracker: b.newMemTracker("Sort"),
 	}
+	if v.ExecLimit != nil {
+		return &LimitExec{
+			Src:    e,
+			Offset: v.ExecLimit.Offset,
+			Count:  v.ExecLimit.Count,
+			schema: v.Schema(),
+		}
+	}
+	return e
+}
+
+// topNExceedsMemory checks whether the rows kept by a TopN exceed the rows allowed to be sorted in memory. The heap
+// of TopN can't spill to disk, so a sort which can spill followed by a limit is used instead.
+func topNExceedsMemory(limit *plan.Limit, maxRowsInMemory int) bool {
+	max := uint64(maxRowsInMemory)
+	return limit.Count > max || limit.Offset > max-limit.Count
 }
 
 func (b *executorBuilder) buildNestedLoopJoin(v *plan.PhysicalHashJoin) *NestedLoopJoinExec {
@@ -666,10 +1178,86 @@ func (b *executorBuilder) buildApply(v *plan.PhysicalApply) Executor {
 		join:        join,
 		outerSchema: v.OuterSchema,
 		schema:      v.Schema(),
+		parallel:    b.ctx.GetSessionVars().EnableParallelApply,
+	}
+	if len(v.OuterSchema) > 0 {
+		apply.cache = &applyCache{memTracker: b.newMemTracker("ApplyCache")}
+		b.wrapApplyCache(join, v.OuterSchema, apply.cache)
+	}
+	if apply.parallel && join != nil {
+		apply.workers = b.buildApplyWorkers(v, apply)
 	}
 	return apply
 }
 
+// wrapApplyCache makes the inner executor of join read the rows from cache.
+func (b *executorBuilder) wrapApplyCache(join joinExec, corCols []*expression.CorrelatedColumn, cache *applyCache) {
+	cacheExec := &applyCacheExec{
+		corCols: corCols,
+		cache:   cache,
+	}
+	switch x := join.(type) {
+	case *NestedLoopJoinExec:
+		cacheExec.Src, x.SmallExec = x.SmallExec, cacheExec
+	case *HashSemiJoinExec:
+		cacheExec.Src, x.smallExec = x.smallExec, cacheExec
+	}
+}
+
+// buildApplyWorkers builds the workers of apply in parallel mode. The first worker uses the join of apply, every
+// other one has its own copy of the inner plan. Only one worker is built if the inner plan can't be copied.
+func (b *executorBuilder) buildApplyWorkers(v *plan.PhysicalApply, apply *ApplyJoinExec) []*applyWorker {
+	workers := []*applyWorker{{join: apply.join, corCols: v.OuterSchema}}
+	if len(v.OuterSchema) == 0 {
+		return workers
+	}
+	for i := 1; i < b.ctx.GetSessionVars().ApplyConcurrency; i++ {
+		innerPlan, corCols, ok := v.CloneInnerPlan()
+		if !ok {
+			break
+		}
+		inner := b.build(innerPlan)
+		var join joinExec
+		switch x := apply.join.(type) {
+		case *NestedLoopJoinExec:
+			join = &NestedLoopJoinExec{
+				SmallExec:   inner,
+				Ctx:         x.Ctx,
+				SmallFilter: cloneExpr(x.SmallFilter),
+				OtherFilter: cloneExpr(x.OtherFilter),
+				schema:      x.schema,
+				outer:       x.outer,
+			}
+		case *HashSemiJoinExec:
+			join = &HashSemiJoinExec{
+				smallExec:    inner,
+				ctx:          x.ctx,
+				smallHashKey: x.smallHashKey,
+				bigHashKey:   x.bigHashKey,
+				smallFilter:  cloneExpr(x.smallFilter),
+				otherFilter:  cloneExpr(x.otherFilter),
+				schema:       x.schema,
+				auxMode:      x.auxMode,
+				anti:         x.anti,
+				targetTypes:  x.targetTypes,
+				memTracker:   b.newMemTracker("HashSemiJoin"),
+			}
+		}
+		if apply.cache != nil {
+			b.wrapApplyCache(join, corCols, apply.cache)
+		}
+		workers = append(workers, &applyWorker{join: join, corCols: corCols, inner: inner})
+	}
+	return workers
+}
+
+func cloneExpr(expr expression.Expression) expression.Expression {
+	if expr == nil {
+		return nil
+	}
+	return expr.Clone()
+}
+
 func (b *executorBuilder) buildExists(v *plan.Exists) Executor {
 	return &ExistsExec{
 		schema: v.Schema(),
@@ -726,6 +1314,14 @@ func (b *executorBuilder) buildCache(v *plan.Cache) Executor {
 	}
 }
 
+func (b *executorBuilder) buildLoadStats(v *plan.LoadStats) Executor {
+	return &LoadStatsExec{
+		ctx:  b.ctx,
+		is:   b.is,
+		path: v.Path,
+	}
+}
+
 func (b *executorBuilder) buildAnalyze(v *plan.Analyze) Executor {
 	var tblInfo *model.TableInfo
 	if v.Table != nil {
@@ -746,3 +1342,49 @@ func (b *executorBuilder) buildAnalyze(v *plan.Analyze) Executor {
 	}
 	return e
 }
+
+func (b *executorBuilder) buildCTE(v *plan.PhysicalCTE) Executor {
+	if b.cteTables == nil {
+		b.cteTables = make(map[int64]table.Table)
+	}
+	// The executors of the CTEs are built during execution, when the transaction may have been committed.
+	b.getStartTS()
+	if b.err != nil {
+		return nil
+	}
+	e := &CTEExec{
+		ctx:        b.ctx,
+		schema:     v.Schema(),
+		builder:    b,
+		memTracker: b.newMemTracker("CTE"),
+	}
+	maxSize := b.ctx.GetSessionVars().TmpTableMaxSize
+	newTable := func(tblInfo *model.TableInfo) *tables.TemporaryTable {
+		t, err := tables.TemporaryTableFromMeta(autoid.NewLocalAllocator(), tblInfo, maxSize)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		return t
+	}
+	for _, def := range v.CTEs {
+		cte := &cteStorage{def: def}
+		e.ctes = append(e.ctes, cte)
+		cte.result = newTable(def.TableInfo)
+		if b.err == nil && def.Recursive != nil {
+			cte.work = newTable(def.WorkTableInfo)
+			cte.next = newTable(def.WorkTableInfo)
+		}
+		if b.err != nil {
+			e.releaseTables()
+			return nil
+		}
+		b.cteTables[def.TableInfo.ID] = cte.result
+	}
+	e.children = []Executor{b.build(v.Children()[0])}
+	if b.err != nil {
+		e.releaseTables()
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/types"
+)
+
+const (
+	// initChunkSize is the number of the rows of the first chunk read by an executor, the following chunks double it
+	// up to maxChunkSize, so a statement with a small limit doesn't read many rows ahead.
+	initChunkSize = 32
+	maxChunkSize  = 1024
+)
+
+// chunkExecutor is implemented by the executors which can return the rows in chunks, the rows are read and the
+// expressions are evaluated over a chunk of rows at once instead of a row at a time. The table reader, the selection
+// and the projection implement it so far, see supportChunk.
+type chunkExecutor interface {
+	Executor
+	// NextChunk fills chk with at most its capacity rows, there are no more rows if chk is empty.
+	NextChunk(chk *rowChunk) error
+}
+
+// supportChunk checks whether the executor returns the rows in chunks. The selection and the projection return the
+// rows in chunks if their sources do and they have no order sensitive function, which can't be evaluated ahead.
+func supportChunk(e Executor) bool {
+	switch x := unwrapRuntimeStats(e).(type) {
+	case *XSelectTableExec:
+		return true
+	case *SelectionExec:
+		return x.useChunk
+	case *ProjectionExec:
+		return x.useChunk
+	}
+	return false
+}
+
+// rowChunk is a chunk of rows with the row keys of each row.
+type rowChunk struct {
+	*chunk.Chunk
+	rowKeys [][]*RowKeyEntry
+	// capacity is the max number of the rows filled by NextChunk.
+	capacity int
+}
+
+func newRowChunk(numCols int) *rowChunk {
+	return &rowChunk{
+		Chunk:    chunk.NewChunk(numCols, initChunkSize),
+		capacity: initChunkSize,
+	}
+}
+
+func (c *rowChunk) reset() {
+	c.Reset()
+	c.rowKeys = c.rowKeys[:0]
+}
+
+func (c *rowChunk) isFull() bool {
+	return c.NumRows() >= c.capacity
+}
+
+// grow doubles the capacity up to maxChunkSize.
+func (c *rowChunk) grow() {
+	if c.capacity < maxChunkSize {
+		c.capacity *= 2
+	}
+}
+
+func (c *rowChunk) appendRow(data []types.Datum, rowKeys []*RowKeyEntry) {
+	c.AppendRow(data)
+	c.rowKeys = append(c.rowKeys, rowKeys)
+}
+
+// filter keeps the rows whose selected is true with their row keys.
+func (c *rowChunk) filter(selected []bool) {
+	n := 0
+	for i, keys := range c.rowKeys {
+		if selected[i] {
+			c.rowKeys[n] = keys
+			n++
+		}
+	}
+	c.rowKeys = c.rowKeys[:n]
+	c.Filter(selected)
+}
+
+// row returns the i-th row, its data is a copy, so it's kept after the chunk is reset.
+func (c *rowChunk) row(i int) *Row {
+	return &Row{Data: c.GetRow(i, nil), RowKeys: c.rowKeys[i]}
+}
+
+// chunkRowReader returns the rows of the chunks filled by a chunk executor one by one, it implements the Next of
+// the executor by its NextChunk.
+type chunkRowReader struct {
+	chk    *rowChunk
+	cursor int
+	done   bool
+}
+
+func (r *chunkRowReader) next(e chunkExecutor) (*Row, error) {
+	if r.chk == nil {
+		r.chk = newRowChunk(e.Schema().Len())
+	}
+	if r.cursor >= r.chk.NumRows() {
+		if r.done {
+			return nil, nil
+		}
+		if r.cursor > 0 {
+			r.chk.grow()
+		}
+		if err := e.NextChunk(r.chk); err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.cursor = 0
+		if r.chk.NumRows() == 0 {
+			r.done = true
+			return nil, nil
+		}
+	}
+	row := r.chk.row(r.cursor)
+	r.cursor++
+	return row, nil
+}
+
+func (r *chunkRowReader) reset() {
+	r.chk = nil
+	r.cursor = 0
+	r.done = false
+}
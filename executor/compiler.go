@@ -19,7 +19,9 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
 )
 
 // Compiler compiles an ast.StmtNode to a stmt.Statement.
@@ -30,6 +32,10 @@ type Compiler struct {
 // After preprocessed and validated, it will be optimized to a plan,
 // then wrappped to an adapter *statement as stmt.Statement.
 func (c *Compiler) Compile(ctx context.Context, node ast.StmtNode) (ast.Statement, error) {
+	if err := setStmtReadTS(ctx, node); err != nil {
+		return nil, errors.Trace(err)
+	}
+	ctx.GetSessionVars().StmtCtx.Priority = stmtPriority(node)
 	is := GetInfoSchema(ctx)
 	if err := plan.Preprocess(node, is, ctx); err != nil {
 		return nil, errors.Trace(err)
@@ -42,25 +48,80 @@ func (c *Compiler) Compile(ctx context.Context, node ast.StmtNode) (ast.Statemen
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	stmtCount(node, p)
+	stmtLabel := stmtCount(node, p)
+	recordWorkload(ctx, node, is)
 	sa := &statement{
-		is:   is,
-		plan: p,
-		text: node.Text(),
+		is:    is,
+		plan:  p,
+		text:  node.Text(),
+		label: stmtLabel,
 	}
 	return sa, nil
 }
 
+// setStmtReadTS sets the timestamp of the AS OF TIMESTAMP clauses of the statement to the statement context, the
+// statement reads the snapshot and the schema at the timestamp.
+func setStmtReadTS(ctx context.Context, node ast.StmtNode) error {
+	readTS, err := plan.GetAsOfTS(ctx, node)
+	if err != nil || readTS == 0 {
+		return errors.Trace(err)
+	}
+	if err = validateSnapshot(ctx, readTS); err != nil {
+		return errors.Trace(err)
+	}
+	is, err := sessionctx.GetDomain(ctx).GetSnapshotInfoSchema(readTS)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	sc.ReadTS = readTS
+	sc.ReadInfoSchema = is
+	return nil
+}
+
+// stmtPriority returns the kv priority of the reads of the statement by its priority option. DELAYED is treated as
+// no priority, the delayed insert is executed as a normal insert, like what MySQL does for InnoDB.
+func stmtPriority(node ast.StmtNode) int {
+	priority := ast.NoPriority
+	switch x := node.(type) {
+	case *ast.SelectStmt:
+		priority = x.Priority
+	case *ast.InsertStmt:
+		priority = x.Priority
+	case *ast.UpdateStmt:
+		if x.LowPriority {
+			priority = ast.LowPriority
+		}
+	case *ast.DeleteStmt:
+		if x.LowPriority {
+			priority = ast.LowPriority
+		}
+	}
+	switch priority {
+	case ast.LowPriority:
+		return kv.PriorityLow
+	case ast.HighPriority:
+		return kv.PriorityHigh
+	}
+	return kv.PriorityNormal
+}
+
 // GetInfoSchema gets TxnCtx InfoSchema if snapshot schema is not set,
 // Otherwise, snapshot schema is returned.
+// The temporary tables of the session are visible in the returned schema.
 func GetInfoSchema(ctx context.Context) infoschema.InfoSchema {
 	sessVar := ctx.GetSessionVars()
 	var is infoschema.InfoSchema
-	if snap := sessVar.SnapshotInfoschema; snap != nil {
+	if snap := sessVar.StmtCtx.ReadInfoSchema; snap != nil {
+		is = snap.(infoschema.InfoSchema)
+	} else if snap := sessVar.SnapshotInfoschema; snap != nil {
 		is = snap.(infoschema.InfoSchema)
 		log.Infof("[%d] use snapshot schema %d", sessVar.ConnectionID, is.SchemaMetaVersion())
 	} else {
 		is = sessVar.TxnCtx.InfoSchema.(infoschema.InfoSchema)
 	}
+	if tmpTables, ok := sessVar.TemporaryTables.(*infoschema.TemporaryTables); ok {
+		is = infoschema.AttachTemporaryTables(is, tmpTables)
+	}
 	return is
 }
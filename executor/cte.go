@@ -0,0 +1,189 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/memory"
+)
+
+// cteStorage keeps the rows of a CTE. The rows generated by the last iteration of a recursive CTE are kept in the
+// work table as well, which is read by the next iteration, and the rows generated by the next iteration are written
+// to the next table, then the two tables are swapped.
+type cteStorage struct {
+	def    *plan.CTEDefinition
+	result *tables.TemporaryTable
+	work   *tables.TemporaryTable
+	next   *tables.TemporaryTable
+}
+
+func (s *cteStorage) close() error {
+	var err error
+	for _, t := range []*tables.TemporaryTable{s.result, s.work, s.next} {
+		if t == nil {
+			continue
+		}
+		if err1 := t.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return errors.Trace(err)
+}
+
+// CTEExec materializes the common table expressions of a statement into temporary tables, then returns the rows of
+// the statement, which reads the CTEs from the temporary tables.
+type CTEExec struct {
+	ctx      context.Context
+	schema   *expression.Schema
+	children []Executor
+	ctes     []*cteStorage
+	// builder builds the executors of the CTEs, the executors of the recursive part are built for each iteration
+	// to read the current work table.
+	builder      *executorBuilder
+	materialized bool
+	// memTracker tracks the memory usage of the rows kept for deduplication.
+	memTracker *memory.Tracker
+}
+
+// Schema implements the Executor Schema interface.
+func (e *CTEExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *CTEExec) Next() (*Row, error) {
+	if !e.materialized {
+		for _, cte := range e.ctes {
+			if err := e.materialize(cte); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		e.materialized = true
+	}
+	row, err := e.children[0].Next()
+	return row, errors.Trace(err)
+}
+
+// materialize generates the rows of the seed part, then executes the recursive part repeatedly until no new rows
+// are generated. It fails if the number of iterations which generate rows exceeds cte_max_recursion_depth.
+func (e *CTEExec) materialize(cte *cteStorage) error {
+	var seen map[string]struct{}
+	if cte.def.Distinct {
+		seen = make(map[string]struct{})
+	}
+	count, err := e.execPart(cte, cte.def.Seed, seen)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if cte.def.Recursive == nil {
+		return nil
+	}
+	maxDepth := e.ctx.GetSessionVars().CTEMaxRecursionDepth
+	for iteration := int64(1); count > 0; iteration++ {
+		// The rows written to the next table become the input of this iteration.
+		if err = cte.work.Truncate(); err != nil {
+			return errors.Trace(err)
+		}
+		cte.work, cte.next = cte.next, cte.work
+		e.builder.cteTables[cte.def.WorkTableInfo.ID] = cte.work
+		count, err = e.execPart(cte, cte.def.Recursive, seen)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if count > 0 && iteration > maxDepth {
+			return ErrCTEMaxRecursionDepth.GenByArgs(iteration)
+		}
+	}
+	return nil
+}
+
+// execPart executes a part of the CTE and writes the new rows to the result table, and the next table if the CTE is
+// recursive. It returns the number of the new rows.
+func (e *CTEExec) execPart(cte *cteStorage, p plan.PhysicalPlan, seen map[string]struct{}) (int64, error) {
+	exec := e.builder.build(p)
+	if e.builder.err != nil {
+		return 0, errors.Trace(e.builder.err)
+	}
+	count, err := e.writeRows(cte, exec, seen)
+	if err1 := exec.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return count, errors.Trace(err)
+}
+
+func (e *CTEExec) writeRows(cte *cteStorage, exec Executor, seen map[string]struct{}) (int64, error) {
+	var count int64
+	for {
+		row, err := exec.Next()
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if row == nil {
+			return count, nil
+		}
+		if seen != nil {
+			key, err := codec.EncodeValue(nil, row.Data...)
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			if _, ok := seen[string(key)]; ok {
+				continue
+			}
+			seen[string(key)] = struct{}{}
+			if err = e.memTracker.Consume(int64(len(key))); err != nil {
+				return 0, errors.Trace(err)
+			}
+		}
+		if _, err = cte.result.AddRecord(e.ctx, row.Data); err != nil {
+			return 0, errors.Trace(err)
+		}
+		if cte.next != nil {
+			if _, err = cte.next.AddRecord(e.ctx, row.Data); err != nil {
+				return 0, errors.Trace(err)
+			}
+		}
+		count++
+	}
+}
+
+// Close implements the Executor Close interface.
+func (e *CTEExec) Close() error {
+	err := e.children[0].Close()
+	if err1 := e.releaseTables(); err1 != nil && err == nil {
+		err = err1
+	}
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return errors.Trace(err)
+}
+
+// releaseTables closes the temporary tables of the CTEs to release their memory and disk.
+func (e *CTEExec) releaseTables() error {
+	var err error
+	for _, cte := range e.ctes {
+		if err1 := cte.close(); err1 != nil && err == nil {
+			err = err1
+		}
+		delete(e.builder.cteTables, cte.def.TableInfo.ID)
+		if cte.def.WorkTableInfo != nil {
+			delete(e.builder.cteTables, cte.def.WorkTableInfo.ID)
+		}
+	}
+	e.ctes = nil
+	return errors.Trace(err)
+}
@@ -14,21 +14,33 @@
 package executor
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/inspectkv"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -36,9 +48,11 @@ import (
 // It grabs a DDL instance from Domain, calling the DDL methods to do the work.
 type DDLExec struct {
 	Statement ast.StmtNode
-	ctx       context.Context
-	is        infoschema.InfoSchema
-	done      bool
+	// SelectExec is the executor of the query of CREATE TABLE ... SELECT.
+	SelectExec Executor
+	ctx        context.Context
+	is         infoschema.InfoSchema
+	done       bool
 }
 
 // Schema implements the Executor Schema interface.
@@ -58,28 +72,33 @@ func (e *DDLExec) Next() (*Row, error) {
 	var err error
 	switch x := e.Statement.(type) {
 	case *ast.TruncateTableStmt:
-		err = e.executeTruncateTable(x)
-		needWait = true
+		needWait, err = e.executeTruncateTable(x)
 	case *ast.CreateDatabaseStmt:
 		err = e.executeCreateDatabase(x)
 		needWait = true
 	case *ast.CreateTableStmt:
 		err = e.executeCreateTable(x)
-		needWait = true
+		// The temporary tables are only visible in the session, there is no need to wait for the other servers.
+		needWait = !x.IsTemporary
 	case *ast.CreateIndexStmt:
 		err = e.executeCreateIndex(x)
 	case *ast.DropDatabaseStmt:
 		err = e.executeDropDatabase(x)
 		needWait = true
 	case *ast.DropTableStmt:
-		err = e.executeDropTable(x)
-		needWait = true
+		needWait, err = e.executeDropTable(x)
 	case *ast.DropIndexStmt:
 		err = e.executeDropIndex(x)
 	case *ast.AlterTableStmt:
 		err = e.executeAlterTable(x)
 	case *ast.RenameTableStmt:
 		err = e.executeRenameTable(x)
+	case *ast.RecoverTableStmt:
+		err = e.executeRecoverTable(x)
+		needWait = true
+	case *ast.FlashbackTableStmt:
+		err = e.executeFlashbackTable(x)
+		needWait = true
 	}
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -109,19 +128,186 @@ func (e *DDLExec) Close() error {
 	return nil
 }
 
-func (e *DDLExec) executeTruncateTable(s *ast.TruncateTableStmt) error {
+// getTemporaryTable returns the temporary table of the session by its schema and name.
+func getTemporaryTable(ctx context.Context, schema, name model.CIStr) (*tables.TemporaryTable, bool) {
+	tmpTables, ok := ctx.GetSessionVars().TemporaryTables.(*infoschema.TemporaryTables)
+	if !ok {
+		return nil, false
+	}
+	return tmpTables.TableByName(schema, name)
+}
+
+// checkNotTemporaryTable returns an error if the table is a temporary table, the statement can't be applied on it.
+func (e *DDLExec) checkNotTemporaryTable(stmt string, tn *ast.TableName) error {
+	if _, ok := getTemporaryTable(e.ctx, tn.Schema, tn.Name); ok {
+		return ErrTemporaryTableUnsupported.GenByArgs(stmt, tn.Name)
+	}
+	return nil
+}
+
+// executeTruncateTable truncates the table, it returns whether the other servers need to be waited for.
+func (e *DDLExec) executeTruncateTable(s *ast.TruncateTableStmt) (bool, error) {
+	if tbl, ok := getTemporaryTable(e.ctx, s.Table.Schema, s.Table.Name); ok {
+		return false, errors.Trace(tbl.Truncate())
+	}
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
 	err := sessionctx.GetDomain(e.ctx).DDL().TruncateTable(e.ctx, ident)
-	return errors.Trace(err)
+	return true, errors.Trace(err)
 }
 
 func (e *DDLExec) executeRenameTable(s *ast.RenameTableStmt) error {
-	oldIdent := ast.Ident{Schema: s.OldTable.Schema, Name: s.OldTable.Name}
-	newIdent := ast.Ident{Schema: s.NewTable.Schema, Name: s.NewTable.Name}
-	err := sessionctx.GetDomain(e.ctx).DDL().RenameTable(e.ctx, oldIdent, newIdent)
+	oldIdents := make([]ast.Ident, 0, len(s.TableToTables))
+	newIdents := make([]ast.Ident, 0, len(s.TableToTables))
+	for _, t := range s.TableToTables {
+		if err := e.checkNotTemporaryTable("RENAME TABLE", t.OldTable); err != nil {
+			return errors.Trace(err)
+		}
+		oldIdents = append(oldIdents, ast.Ident{Schema: t.OldTable.Schema, Name: t.OldTable.Name})
+		newIdents = append(newIdents, ast.Ident{Schema: t.NewTable.Schema, Name: t.NewTable.Name})
+	}
+	var err error
+	if len(s.TableToTables) == 1 {
+		err = sessionctx.GetDomain(e.ctx).DDL().RenameTable(e.ctx, oldIdents[0], newIdents[0])
+	} else {
+		err = sessionctx.GetDomain(e.ctx).DDL().RenameTables(e.ctx, oldIdents, newIdents)
+	}
+	return errors.Trace(err)
+}
+
+func (e *DDLExec) executeRecoverTable(s *ast.RecoverTableStmt) error {
+	var (
+		job *model.Job
+		err error
+	)
+	if s.Table == nil {
+		job, err = e.getRecoverTableJobByID(s.JobID)
+	} else {
+		job, err = e.getRecoverTableJobByName(s.Table)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(e.recoverTable(job, ""))
+}
+
+func (e *DDLExec) executeFlashbackTable(s *ast.FlashbackTableStmt) error {
+	job, err := e.getRecoverTableJobByName(s.Table)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(e.recoverTable(job, s.NewName))
+}
+
+// getRecoverTableJobByID returns the drop or truncate table job of the ID.
+func (e *DDLExec) getRecoverTableJobByID(id int64) (*model.Job, error) {
+	var job *model.Job
+	err := kv.RunInNewTxn(sessionctx.GetDomain(e.ctx).Store(), false, func(txn kv.Transaction) error {
+		var err1 error
+		job, err1 = meta.NewMeta(txn).GetHistoryDDLJob(id)
+		return errors.Trace(err1)
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if job == nil {
+		return nil, inspectkv.ErrDDLJobNotFound.GenByArgs(id)
+	}
+	if !isRecoverableJob(job) {
+		return nil, ErrCannotRecoverTable.GenByArgs(fmt.Sprintf("job %d isn't a finished drop or truncate table job", id))
+	}
+	return job, nil
+}
+
+// getRecoverTableJobByName returns the latest drop or truncate table job of the table name.
+func (e *DDLExec) getRecoverTableJobByName(tn *ast.TableName) (*model.Job, error) {
+	schema, ok := e.is.SchemaByName(tn.Schema)
+	if !ok {
+		return nil, infoschema.ErrDatabaseNotExists.GenByArgs(tn.Schema)
+	}
+	var jobs []*model.Job
+	err := kv.RunInNewTxn(sessionctx.GetDomain(e.ctx).Store(), false, func(txn kv.Transaction) error {
+		var err1 error
+		jobs, err1 = inspectkv.GetHistoryDDLJobs(txn, math.MaxInt32)
+		return errors.Trace(err1)
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, job := range jobs {
+		if job.SchemaID == schema.ID && isRecoverableJob(job) && job.BinlogInfo.TableInfo.Name.L == tn.Name.L {
+			return job, nil
+		}
+	}
+	return nil, ErrCannotRecoverTable.GenByArgs(fmt.Sprintf("no dropped or truncated table '%s' is found", tn.Name))
+}
+
+// isRecoverableJob returns whether the table dropped or truncated by the job can be recovered.
+func isRecoverableJob(job *model.Job) bool {
+	if job.Type != model.ActionDropTable && job.Type != model.ActionTruncateTable {
+		return false
+	}
+	return job.IsDone() && job.BinlogInfo != nil && job.BinlogInfo.TableInfo != nil
+}
+
+// recoverTable recovers the table dropped or truncated by the job. The data of the table is restored from the
+// snapshot before the job is finished, which must be newer than the GC safe point.
+func (e *DDLExec) recoverTable(job *model.Job, newName string) error {
+	snapshotTS := job.SnapshotVer
+	if snapshotTS == 0 {
+		return ErrCannotRecoverTable.GenByArgs(fmt.Sprintf("the snapshot of job %d isn't recorded", job.ID))
+	}
+	if err := validateSnapshot(e.ctx, snapshotTS); err != nil {
+		return errors.Trace(err)
+	}
+	snapshot, err := sessionctx.GetDomain(e.ctx).Store().GetSnapshot(kv.Version{Ver: snapshotTS})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer snapshot.Release()
+	autoID, err := meta.NewSnapshotMeta(snapshot).GetAutoTableID(job.SchemaID, job.TableID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	tblInfo := job.BinlogInfo.TableInfo.Clone()
+	// The truncated table keeps its old table ID, which its data is encoded with.
+	tblInfo.ID = job.TableID
+	if newName != "" {
+		tblInfo.Name = model.NewCIStr(newName)
+	}
+	err = sessionctx.GetDomain(e.ctx).DDL().RecoverTable(e.ctx, job.SchemaID, tblInfo, autoID, snapshotTS)
 	return errors.Trace(err)
 }
 
+// The GC safe point is saved in mysql.tidb by the GC worker of TiKV.
+const (
+	gcSafePointKey = "tikv_gc_safe_point"
+	gcTimeFormat   = "20060102-15:04:05 -0700 MST"
+)
+
+// validateSnapshot checks that the snapshot is newer than the GC safe point, its data may have been cleaned up
+// otherwise.
+func validateSnapshot(ctx context.Context, snapshotTS uint64) error {
+	sql := fmt.Sprintf(`SELECT variable_value FROM %s.%s WHERE variable_name = "%s"`, mysql.SystemDB, mysql.TiDBTable,
+		gcSafePointKey)
+	rows, _, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(rows) == 0 {
+		// GC hasn't run yet.
+		return nil
+	}
+	safePoint, err := time.Parse(gcTimeFormat, rows[0].Data[0].GetString())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if snapshotTS < oracle.ComposeTS(oracle.GetPhysical(safePoint), 0) {
+		return ErrSnapshotTooOld.GenByArgs(rows[0].Data[0].GetString())
+	}
+	return nil
+}
+
 func (e *DDLExec) executeCreateDatabase(s *ast.CreateDatabaseStmt) error {
 	var opt *ast.CharsetOpt
 	if len(s.Options) != 0 {
@@ -145,24 +331,232 @@ func (e *DDLExec) executeCreateDatabase(s *ast.CreateDatabaseStmt) error {
 }
 
 func (e *DDLExec) executeCreateTable(s *ast.CreateTableStmt) error {
+	if s.IsTemporary {
+		return errors.Trace(e.executeCreateTemporaryTable(s))
+	}
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
+	d := sessionctx.GetDomain(e.ctx).DDL()
 	var err error
-	if s.ReferTable == nil {
-		err = sessionctx.GetDomain(e.ctx).DDL().CreateTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
-	} else {
+	switch {
+	case s.ReferTable != nil:
 		referIdent := ast.Ident{Schema: s.ReferTable.Schema, Name: s.ReferTable.Name}
-		err = sessionctx.GetDomain(e.ctx).DDL().CreateTableWithLike(e.ctx, ident, referIdent)
+		err = d.CreateTableWithLike(e.ctx, ident, referIdent)
+	case s.Select != nil:
+		colDefs := buildSelectColumnDefs(s.Cols, e.SelectExec.Schema())
+		err = d.CreateTable(e.ctx, ident, colDefs, s.Constraints, s.Options)
+	default:
+		err = d.CreateTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
 	}
 	if terror.ErrorEqual(err, infoschema.ErrTableExists) {
+		// The existing table isn't changed, the result of the query isn't inserted either.
 		if s.IfNotExists {
 			return nil
 		}
 		return err
 	}
-	return errors.Trace(err)
+	if err != nil || s.Select == nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(e.insertSelectResult(ident))
+}
+
+// SelectInsertBatchSize is the number of rows of CREATE TABLE ... SELECT committed in a transaction.
+var SelectInsertBatchSize = 10000
+
+// insertSelectResult inserts the result of the query of CREATE TABLE ... SELECT into the created table. The rows are
+// committed in a new transaction which sees the table, and the table is dropped if they fail to be inserted.
+func (e *DDLExec) insertSelectResult(ident ast.Ident) error {
+	if err := e.ctx.NewTxn(); err != nil {
+		return errors.Trace(err)
+	}
+	is := sessionctx.GetDomain(e.ctx).InfoSchema()
+	txnCtx := e.ctx.GetSessionVars().TxnCtx
+	txnCtx.InfoSchema = is
+	txnCtx.SchemaVersion = is.SchemaMetaVersion()
+	tbl, err := is.TableByName(ident.Schema, ident.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The table is dropped if any batch fails, so the committed batches aren't left behind.
+	err = e.insertSelectRows(tbl, SelectInsertBatchSize)
+	if err == nil {
+		err = e.ctx.NewTxn()
+	}
+	if err != nil {
+		// The transaction is gone if it fails to be committed.
+		if txn := e.ctx.Txn(); txn != nil && txn.Valid() {
+			if err1 := txn.Rollback(); err1 != nil {
+				log.Warnf("[%d] rollback the rows of CREATE TABLE ... SELECT err %v", e.ctx.GetSessionVars().ConnectionID, err1)
+			}
+		}
+		if err1 := sessionctx.GetDomain(e.ctx).DDL().DropTable(e.ctx, ident); err1 != nil {
+			log.Errorf("[%d] drop the table %s of the failed CREATE TABLE ... SELECT err %v",
+				e.ctx.GetSessionVars().ConnectionID, ident, errors.ErrorStack(err1))
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// insertSelectRows inserts the result of the query into the table by the columns of the same names. If batchSize is
+// positive, the rows are committed every batchSize rows, so the result of the query isn't buffered in one transaction.
+func (e *DDLExec) insertSelectRows(tbl table.Table, batchSize int) error {
+	schema := e.SelectExec.Schema()
+	columns := make([]*ast.ColumnName, 0, schema.Len())
+	for _, col := range schema.Columns {
+		columns = append(columns, &ast.ColumnName{Name: col.ColName})
+	}
+	insert := &InsertValues{
+		ctx:        e.ctx,
+		SelectExec: e.SelectExec,
+		Table:      tbl,
+		Columns:    columns,
+	}
+	cols, err := insert.getColumns(tbl.Cols())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for rows := 0; ; rows++ {
+		srcRow, err := e.SelectExec.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if srcRow == nil {
+			return nil
+		}
+		if batchSize > 0 && rows > 0 && rows%batchSize == 0 {
+			if err = e.ctx.NewTxn(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		insert.currRow = int64(rows)
+		row, err := insert.fillRowData(cols, srcRow.Data, false)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		txn := e.ctx.Txn()
+		txn.SetOption(kv.PresumeKeyNotExists, nil)
+		_, err = tbl.AddRecord(e.ctx, row)
+		txn.DelOption(kv.PresumeKeyNotExists)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// buildSelectColumnDefs returns the column definitions of the table created by CREATE TABLE ... SELECT. The result
+// columns of the query are appended to the defined columns, and the defined column of the same name keeps its
+// definition.
+func buildSelectColumnDefs(colDefs []*ast.ColumnDef, schema *expression.Schema) []*ast.ColumnDef {
+	defined := make(map[string]bool, len(colDefs))
+	for _, colDef := range colDefs {
+		defined[colDef.Name.Name.L] = true
+	}
+	newColDefs := append([]*ast.ColumnDef(nil), colDefs...)
+	for _, col := range schema.Columns {
+		if defined[col.ColName.L] {
+			continue
+		}
+		newColDefs = append(newColDefs, &ast.ColumnDef{
+			Name: &ast.ColumnName{Name: col.ColName},
+			Tp:   selectColumnType(col.RetType),
+		})
+	}
+	return newColDefs
+}
+
+// selectColumnType returns the type of the column created for the result column of the query. The inferred type of
+// the expression is converted to the type which can be stored.
+func selectColumnType(retType *types.FieldType) *types.FieldType {
+	tp := *retType
+	tp.Flag &= mysql.UnsignedFlag | mysql.BinaryFlag
+	switch tp.Tp {
+	case mysql.TypeNull:
+		// The same as MySQL, the column of NULL is BINARY(0).
+		tp.Tp, tp.Flen = mysql.TypeString, 0
+		tp.Charset, tp.Collate = charset.CharsetBin, charset.CollationBin
+		tp.Flag |= mysql.BinaryFlag
+	case mysql.TypeVarString:
+		tp.Tp = mysql.TypeVarchar
+	case mysql.TypeNewDecimal:
+		// The precision isn't always inferred, the max precision and scale are used then.
+		if tp.Decimal == types.UnspecifiedLength {
+			tp.Decimal = types.MaxFraction
+		}
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Flen = mysql.MaxDecimalWidth
+		}
+	}
+	// The length of the string isn't always inferred, the string of any length can be stored in the text column.
+	if tp.Flen == types.UnspecifiedLength && types.IsTypeChar(tp.Tp) {
+		tp.Tp = mysql.TypeBlob
+	}
+	return &tp
+}
+
+// executeCreateTemporaryTable creates a temporary table in the session, it hides the table of the same name.
+func (e *DDLExec) executeCreateTemporaryTable(s *ast.CreateTableStmt) error {
+	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
+	if _, ok := getTemporaryTable(e.ctx, s.Table.Schema, s.Table.Name); ok {
+		if s.IfNotExists {
+			return nil
+		}
+		return infoschema.ErrTableExists.GenByArgs(ident)
+	}
+	var (
+		tblInfo *model.TableInfo
+		err     error
+	)
+	d := sessionctx.GetDomain(e.ctx).DDL()
+	switch {
+	case s.Select != nil:
+		colDefs := buildSelectColumnDefs(s.Cols, e.SelectExec.Schema())
+		tblInfo, err = d.CreateTemporaryTable(e.ctx, ident, colDefs, s.Constraints, s.Options)
+	case s.ReferTable == nil:
+		tblInfo, err = d.CreateTemporaryTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
+	default:
+		referTbl, err1 := e.is.TableByName(s.ReferTable.Schema, s.ReferTable.Name)
+		if err1 != nil {
+			return infoschema.ErrTableNotExists.GenByArgs(s.ReferTable.Schema, s.ReferTable.Name)
+		}
+		tblInfo, err = d.CreateTemporaryTableWithLike(e.ctx, ident, referTbl.Meta())
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	vars := e.ctx.GetSessionVars()
+	tbl, err := tables.TemporaryTableFromMeta(autoid.NewLocalAllocator(), tblInfo, vars.TmpTableMaxSize)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if tblInfo.AutoIncID > 1 {
+		// The same as the normal tables, the first auto increment ID is AutoIncID.
+		if err = tbl.RebaseAutoID(tblInfo.AutoIncID-1, false); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if s.Select != nil {
+		// The rows of the temporary table aren't transactional, the table isn't added if they fail to be inserted.
+		if err = e.insertSelectRows(tbl, 0); err != nil {
+			tbl.Close()
+			return errors.Trace(err)
+		}
+	}
+	// The following changes are undone if the statement or the transaction fails.
+	tbl.EnableTxn()
+	tmpTables, ok := vars.TemporaryTables.(*infoschema.TemporaryTables)
+	if !ok {
+		tmpTables = infoschema.NewTemporaryTables()
+		vars.TemporaryTables = tmpTables
+	}
+	tmpTables.Add(s.Table.Schema, tbl)
+	return nil
 }
 
 func (e *DDLExec) executeCreateIndex(s *ast.CreateIndexStmt) error {
+	if err := e.checkNotTemporaryTable("CREATE INDEX", s.Table); err != nil {
+		return errors.Trace(err)
+	}
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
 	err := sessionctx.GetDomain(e.ctx).DDL().CreateIndex(e.ctx, ident, s.Unique, model.NewCIStr(s.IndexName), s.IndexColNames)
 	return errors.Trace(err)
@@ -193,10 +587,27 @@ func (e *DDLExec) executeDropDatabase(s *ast.DropDatabaseStmt) error {
 	return errors.Trace(err)
 }
 
-func (e *DDLExec) executeDropTable(s *ast.DropTableStmt) error {
-	var notExistTables []string
+// executeDropTable drops the tables, a temporary table is dropped first if it hides the table of the same name.
+// It returns whether the other servers need to be waited for.
+func (e *DDLExec) executeDropTable(s *ast.DropTableStmt) (bool, error) {
+	var (
+		notExistTables []string
+		needWait       bool
+	)
 	for _, tn := range s.Tables {
 		fullti := ast.Ident{Schema: tn.Schema, Name: tn.Name}
+		if tmpTables, ok := e.ctx.GetSessionVars().TemporaryTables.(*infoschema.TemporaryTables); ok {
+			if tbl := tmpTables.Remove(tn.Schema, tn.Name); tbl != nil {
+				if err := tbl.Close(); err != nil {
+					return needWait, errors.Trace(err)
+				}
+				continue
+			}
+		}
+		if s.IsTemporary {
+			notExistTables = append(notExistTables, fullti.String())
+			continue
+		}
 		schema, ok := e.is.SchemaByName(tn.Schema)
 		if !ok {
 			// TODO: we should return special error for table not exist, checking "not exist" is not enough,
@@ -209,32 +620,36 @@ func (e *DDLExec) executeDropTable(s *ast.DropTableStmt) error {
 			notExistTables = append(notExistTables, fullti.String())
 			continue
 		} else if err != nil {
-			return errors.Trace(err)
+			return needWait, errors.Trace(err)
 		}
 		// Check Privilege
 		privChecker := privilege.GetPrivilegeChecker(e.ctx)
 		hasPriv, err := privChecker.Check(e.ctx, schema, tb.Meta(), mysql.DropPriv)
 		if err != nil {
-			return errors.Trace(err)
+			return needWait, errors.Trace(err)
 		}
 		if !hasPriv {
-			return errors.Errorf("You do not have the privilege to drop table %s.%s.", tn.Schema, tn.Name)
+			return needWait, errors.Errorf("You do not have the privilege to drop table %s.%s.", tn.Schema, tn.Name)
 		}
 
 		err = sessionctx.GetDomain(e.ctx).DDL().DropTable(e.ctx, fullti)
 		if infoschema.ErrDatabaseNotExists.Equal(err) || infoschema.ErrTableNotExists.Equal(err) {
 			notExistTables = append(notExistTables, fullti.String())
 		} else if err != nil {
-			return errors.Trace(err)
+			return needWait, errors.Trace(err)
 		}
+		needWait = true
 	}
 	if len(notExistTables) > 0 && !s.IfExists {
-		return infoschema.ErrTableDropExists.GenByArgs(strings.Join(notExistTables, ","))
+		return needWait, infoschema.ErrTableDropExists.GenByArgs(strings.Join(notExistTables, ","))
 	}
-	return nil
+	return needWait, nil
 }
 
 func (e *DDLExec) executeDropIndex(s *ast.DropIndexStmt) error {
+	if err := e.checkNotTemporaryTable("DROP INDEX", s.Table); err != nil {
+		return errors.Trace(err)
+	}
 	ti := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
 	err := sessionctx.GetDomain(e.ctx).DDL().DropIndex(e.ctx, ti, model.NewCIStr(s.IndexName))
 	if (infoschema.ErrDatabaseNotExists.Equal(err) || infoschema.ErrTableNotExists.Equal(err)) && s.IfExists {
@@ -244,6 +659,9 @@ func (e *DDLExec) executeDropIndex(s *ast.DropIndexStmt) error {
 }
 
 func (e *DDLExec) executeAlterTable(s *ast.AlterTableStmt) error {
+	if err := e.checkNotTemporaryTable("ALTER TABLE", s.Table); err != nil {
+		return errors.Trace(err)
+	}
 	ti := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
 	err := sessionctx.GetDomain(e.ctx).DDL().AlterTable(e.ctx, ti, s.Specs)
 	return errors.Trace(err)
@@ -18,8 +18,14 @@ import (
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/inspectkv"
+	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -115,6 +121,79 @@ func (s *testSuite) TestCreateTable(c *C) {
 	r.Check(testkit.Rows(rowStr1))
 }
 
+func (s *testSuite) TestCreateTableLikeAndSelect(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table src (id int primary key auto_increment, a int default 5, b decimal(10, 2), c varchar(20), key idx_a (a)) comment 'src'")
+	tk.MustExec("insert src (a, b, c) values (1, 1.5, 'x'), (2, 2.5, 'y'), (null, null, null)")
+
+	// LIKE copies the columns, the indices and the options but not the rows.
+	tk.MustExec("create table src_like like src")
+	tk.MustQuery("select count(*) from src_like").Check(testkit.Rows("0"))
+	tk.MustExec("insert src_like (b) values (1)")
+	tk.MustQuery("select id, a from src_like").Check(testkit.Rows("1 5"))
+	result := tk.MustQuery("show create table src_like")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*KEY `idx_a` \\(`a`\\).*COMMENT='src'")
+
+	// The types of the created columns are inferred from the query.
+	tk.MustExec("create table ctas as select id, a + 1 as a1, b * 2 as b2, c, concat(c, 'z') as c2, null as n, " +
+		"id * 1.5 as d, b + 1.125 as e, concat(c, a) as c3 from src")
+	result = tk.MustQuery("show create table ctas")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*`id` int\\(11\\).*`a1` bigint\\(12\\).*`b2` decimal\\(11,2\\).*"+
+		"`c` varchar\\(20\\).*`c2` varchar\\(21\\).*`n` binary\\(0\\).*`d` decimal\\(14,1\\).*"+
+		"`e` decimal\\(12,3\\).*`c3` varchar\\(31\\).*")
+	tk.MustQuery("select d, e from ctas where c3 = 'y2'").Check(testkit.Rows("3.0 3.625"))
+	tk.MustQuery("select id, a1, n from ctas").Check(testkit.Rows("1 2 <nil>", "2 3 <nil>", "3 <nil> <nil>"))
+	tk.MustQuery("select count(*) from ctas where b2 = 5 and c2 = 'yz'").Check(testkit.Rows("1"))
+	// The result of CASE is as long as its longest result.
+	tk.MustExec("create table ctas_case select case when a = 1 then 'x' else 'abc' end as k from src")
+	result = tk.MustQuery("show create table ctas_case")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*`k` varchar\\(3\\).*")
+	tk.MustQuery("select count(*) from ctas_case where k = 'abc'").Check(testkit.Rows("2"))
+
+	// The defined columns are kept and the result columns of the same names are inserted into them.
+	tk.MustExec("create table ctas_def (id bigint primary key, d int default 7) select id, a from src where a is not null")
+	tk.MustQuery("select * from ctas_def").Check(testkit.Rows("1 7 1", "2 7 2"))
+	tk.MustExec("create table if not exists ctas_def select 1 as id")
+	tk.MustQuery("select count(*) from ctas_def").Check(testkit.Rows("2"))
+	_, err := tk.Exec("create table ctas_def select 1 as id")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("create table ctas_union as select a from src where a = 1 union all select a from src where a = 2")
+	tk.MustQuery("select a from ctas_union order by a").Check(testkit.Rows("1", "2"))
+
+	_, err = tk.Exec("create table ctas_dup select a, b as a from src")
+	c.Assert(err, NotNil)
+	// The table is dropped if the rows fail to be inserted.
+	_, err = tk.Exec("create table ctas_fail (unique key (a)) select 1 as a from src")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from ctas_fail")
+	c.Assert(err, NotNil)
+
+	// The rows are committed in batches, and the committed batches are dropped with the table if a later batch fails.
+	defer func(batchSize int) { executor.SelectInsertBatchSize = batchSize }(executor.SelectInsertBatchSize)
+	executor.SelectInsertBatchSize = 2
+	tk.MustExec("create table ctas_batch select id, a from src")
+	tk.MustQuery("select * from ctas_batch").Check(testkit.Rows("1 1", "2 2", "3 <nil>"))
+	_, err = tk.Exec("create table ctas_batch_fail (unique key (a)) select if(id = 3, 1, id) as a from src")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from ctas_batch_fail")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("create temporary table tmp_ctas select id, a from src where id < 3")
+	tk.MustQuery("select * from tmp_ctas").Check(testkit.Rows("1 1", "2 2"))
+	_, err = tk.Exec("create temporary table tmp_ctas_fail (unique key (a)) select 1 as a from src")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from tmp_ctas_fail")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop temporary table tmp_ctas")
+	tk.MustExec("drop table src, src_like, ctas, ctas_case, ctas_def, ctas_union")
+}
+
 func (s *testSuite) TestCreateDropDatabase(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -144,6 +223,52 @@ func (s *testSuite) TestCreateDropIndex(c *C) {
 	tk.MustExec("drop table drop_test")
 }
 
+func (s *testSuite) TestDDLReorgVariables(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	defer func() {
+		tk.MustExec("set @@global.tidb_ddl_reorg_worker_cnt = 16")
+		tk.MustExec("set @@global.tidb_ddl_reorg_batch_size = 128")
+		tk.MustExec("set @@global.tidb_ddl_reorg_priority = 'PRIORITY_LOW'")
+	}()
+
+	tk.MustQuery("select @@global.tidb_ddl_reorg_worker_cnt, @@global.tidb_ddl_reorg_batch_size, @@global.tidb_ddl_reorg_priority").
+		Check(testkit.Rows("16 128 PRIORITY_LOW"))
+	tk.MustExec("set @@global.tidb_ddl_reorg_worker_cnt = 2")
+	tk.MustExec("set @@global.tidb_ddl_reorg_batch_size = 3")
+	tk.MustExec("set @@global.tidb_ddl_reorg_priority = 'priority_high'")
+	tk.MustQuery("select @@global.tidb_ddl_reorg_worker_cnt, @@global.tidb_ddl_reorg_batch_size, @@global.tidb_ddl_reorg_priority").
+		Check(testkit.Rows("2 3 priority_high"))
+
+	// The invalid values are rejected.
+	for _, sql := range []string{
+		"set @@global.tidb_ddl_reorg_worker_cnt = 0",
+		"set @@global.tidb_ddl_reorg_worker_cnt = 129",
+		"set @@global.tidb_ddl_reorg_batch_size = 'a'",
+		"set @@global.tidb_ddl_reorg_batch_size = 10241",
+		"set @@global.tidb_ddl_reorg_priority = 'PRIORITY_MEDIUM'",
+	} {
+		_, err := tk.Exec(sql)
+		c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue, Commentf("sql: %s, err: %v", sql, err))
+	}
+	_, err := tk.Exec("set tidb_ddl_reorg_worker_cnt = 1")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select @@global.tidb_ddl_reorg_worker_cnt, @@global.tidb_ddl_reorg_batch_size, @@global.tidb_ddl_reorg_priority").
+		Check(testkit.Rows("2 3 priority_high"))
+
+	// The index is backfilled with the small batches.
+	tk.MustExec("create table reorg_vars (a int primary key, b int)")
+	for i := 0; i < 20; i++ {
+		tk.MustExec(fmt.Sprintf("insert into reorg_vars values (%d, %d)", i, i))
+	}
+	tk.MustExec("set @@global.tidb_ddl_reorg_priority = 'PRIORITY_LOW'")
+	tk.MustExec("alter table reorg_vars add index idx_b (b)")
+	tk.MustExec("admin check table reorg_vars")
+	tk.MustQuery("select count(*) from reorg_vars use index (idx_b) where b >= 0").Check(testkit.Rows("20"))
+	tk.MustExec("drop table reorg_vars")
+}
+
 func (s *testSuite) TestAlterTableAddColumn(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -188,6 +313,59 @@ func (s *testSuite) TestAddNotNullColumnNoDefault(c *C) {
 	tk.MustQuery("select * from nn").Check(testkit.Rows("1 0", "2 0", "3 0"))
 }
 
+func (s *testSuite) TestExpressionDefaultValue(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists ed")
+	tk.MustExec("create table ed (id int, u varchar(36) default (uuid()), d datetime default (date_add('2017-01-01', interval 1 day)), n int default (1 + 2))")
+	tk.MustExec("insert ed (id) values (1), (2)")
+	tk.MustQuery("select id, length(u), d, n from ed").Check(testkit.Rows(
+		"1 36 2017-01-02 00:00:00 3", "2 36 2017-01-02 00:00:00 3"))
+	// The expression is evaluated for every row.
+	tk.MustQuery("select count(distinct u) from ed").Check(testkit.Rows("2"))
+	tk.MustExec("insert ed values (3, 'a', default, default)")
+	tk.MustQuery("select d, n from ed where id = 3").Check(testkit.Rows("2017-01-02 00:00:00 3"))
+	tk.MustExec("delete from ed where id = 3")
+	tk.MustQuery("show columns from ed where field = 'n'").Check(testkit.Rows("n int(11) YES  1 + 2 DEFAULT_GENERATED"))
+
+	// The output of SHOW CREATE TABLE can be executed to create the same table.
+	expected := "CREATE TABLE `ed` (\n  `id` int(11) DEFAULT NULL,\n  `u` varchar(36) DEFAULT (uuid()),\n" +
+		"  `d` datetime DEFAULT (date_add('2017-01-01', interval 1 day)),\n  `n` int(11) DEFAULT (1 + 2)\n) ENGINE=InnoDB"
+	createSQL := tk.MustQuery("show create table ed").Rows()[0][1]
+	c.Assert(createSQL, Equals, expected)
+	tk.MustExec("drop table ed")
+	tk.MustExec(expected)
+	c.Assert(tk.MustQuery("show create table ed").Rows()[0][1], Equals, expected)
+
+	// The existing rows get the value evaluated when the column is added.
+	tk.MustExec("insert ed (id) values (1), (2)")
+	tk.MustExec("alter table ed add column s varchar(10) default (concat('a', 'b'))")
+	rowStr := fmt.Sprintf("%v", []byte("ab"))
+	tk.MustQuery("select id, s from ed").Check(testkit.Rows("1 "+rowStr, "2 "+rowStr))
+	tk.MustExec("alter table ed alter column s set default (repeat('c', 2))")
+	tk.MustExec("insert ed (id) values (3)")
+	tk.MustExec("alter table ed modify column n bigint default (4 * 5)")
+	tk.MustExec("insert ed (id) values (4)")
+	tk.MustQuery("select id, s = 'cc', n from ed where id > 2").Check(testkit.Rows("3 1 3", "4 1 20"))
+	tk.MustExec("alter table ed alter column n drop default")
+	tk.MustExec("insert ed (id) values (5)")
+	tk.MustQuery("select n from ed where id = 5").Check(testkit.Rows("<nil>"))
+
+	// The expression can't refer to the columns, the subqueries or the variables.
+	_, err := tk.Exec("create table ed2 (a int, b int default (a + 1))")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table ed2 (a int default ((select 1)))")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table ed2 (a int default (@a))")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table ed2 (a int auto_increment key default (1))")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table ed alter column s set default (id)")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table ed")
+}
+
 func (s *testSuite) TestAlterTableModifyColumn(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -196,10 +374,9 @@ func (s *testSuite) TestAlterTableModifyColumn(c *C) {
 	_, err := tk.Exec("alter table mc modify column c1 short")
 	c.Assert(err, NotNil)
 	tk.MustExec("alter table mc modify column c1 bigint")
+	tk.MustExec("insert into mc values (1, 'abcdefghij')")
 
-	_, err = tk.Exec("alter table mc modify column c2 blob")
-	c.Assert(err, NotNil)
-
+	// The existing value is too long for the new type.
 	_, err = tk.Exec("alter table mc modify column c2 varchar(8)")
 	c.Assert(err, NotNil)
 	tk.MustExec("alter table mc modify column c2 varchar(11)")
@@ -209,6 +386,9 @@ func (s *testSuite) TestAlterTableModifyColumn(c *C) {
 	createSQL := result.Rows()[0][1]
 	expected := "CREATE TABLE `mc` (\n  `c1` bigint(21) DEFAULT NULL,\n  `c2` text DEFAULT NULL\n) ENGINE=InnoDB"
 	c.Assert(createSQL, Equals, expected)
+
+	tk.MustExec("alter table mc modify column c1 varchar(10)")
+	tk.MustQuery("select count(*) from mc where c1 = '1'").Check(testkit.Rows("1"))
 }
 
 func (s *testSuite) TestDefaultDBAfterDropCurDB(c *C) {
@@ -245,9 +425,300 @@ func (s *testSuite) TestRenameTable(c *C) {
 	tk.MustExec("insert rename2.t values ()")
 	tk.MustExec("rename table rename2.t to rename3.t")
 	tk.MustExec("insert rename3.t values ()")
-	tk.MustQuery("select * from rename3.t").Check(testkit.Rows("1", "2", "3"))
+	// The auto ID moves to the new database with the table, the IDs cached in the old database are skipped.
+	step := autoid.GetStep()
+	tk.MustQuery("select * from rename3.t").Check(testkit.Rows("1", fmt.Sprint(step+1), fmt.Sprint(step*2+1)))
 
+	tk.MustExec("create table rename1.t1 (a int)")
+	tk.MustExec("rename table rename3.t to rename1.t2, rename1.t1 to rename3.t, rename1.t2 to rename2.t")
 	tk.MustExec("drop database rename1")
+	tk.MustExec("insert rename2.t values ()")
+	tk.MustQuery("select count(*) from rename2.t").Check(testkit.Rows("4"))
+	tk.MustQuery("select * from rename3.t").Check(testkit.Rows())
+
 	tk.MustExec("drop database rename2")
 	tk.MustExec("drop database rename3")
 }
+
+func (s *testSuite) TestRecoverTable(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t_recover (id int primary key auto_increment, a int, key idx_a (a))")
+	tk.MustExec("insert t_recover (a) values (1), (2), (3)")
+
+	// The rows, the indices and the auto ID are recovered.
+	tk.MustExec("drop table t_recover")
+	tk.MustExec("recover table t_recover")
+	tk.MustQuery("select * from t_recover").Check(testkit.Rows("1 1", "2 2", "3 3"))
+	tk.MustQuery("select id from t_recover use index (idx_a) where a > 1").Check(testkit.Rows("2", "3"))
+	tk.MustExec("insert t_recover (a) values (4)")
+	tk.MustQuery("select count(*) from t_recover where id > 3").Check(testkit.Rows("1"))
+	tk.MustExec("delete from t_recover where a = 4")
+	_, err := tk.Exec("recover table t_recover")
+	c.Assert(err, NotNil)
+
+	// The truncated table is recovered with a new name.
+	tk.MustExec("truncate table t_recover")
+	_, err = tk.Exec("flashback table t_recover")
+	c.Assert(err, NotNil)
+	tk.MustExec("flashback table t_recover to t_flashback")
+	tk.MustQuery("select count(*) from t_recover").Check(testkit.Rows("0"))
+	tk.MustQuery("select * from t_flashback").Check(testkit.Rows("1 1", "2 2", "3 3"))
+
+	// The table is recovered by the ID of the DDL job.
+	tk.MustExec("drop table t_flashback")
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	jobs, err := inspectkv.GetHistoryDDLJobs(txn, 1)
+	c.Assert(err, IsNil)
+	c.Assert(txn.Rollback(), IsNil)
+	tk.MustExec("create table t_flashback (a int)")
+	_, err = tk.Exec(fmt.Sprintf("recover table by job %d", jobs[0].ID))
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table t_flashback")
+	tk.MustExec(fmt.Sprintf("recover table by job %d", jobs[0].ID))
+	tk.MustQuery("select count(*) from t_flashback").Check(testkit.Rows("3"))
+	_, err = tk.Exec(fmt.Sprintf("recover table by job %d", jobs[0].ID))
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("recover table by job 100000000")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("recover table t_not_dropped")
+	c.Assert(err, NotNil)
+
+	// The table dropped before the GC safe point can't be recovered.
+	tk.MustExec("drop table t_flashback")
+	safePoint := time.Now().Add(time.Hour).Format("20060102-15:04:05 -0700 MST")
+	tk.MustExec(fmt.Sprintf("insert mysql.tidb values ('tikv_gc_safe_point', '%s', '')", safePoint))
+	_, err = tk.Exec("recover table t_flashback")
+	c.Assert(executor.ErrSnapshotTooOld.Equal(err), IsTrue)
+	tk.MustExec("delete from mysql.tidb where variable_name = 'tikv_gc_safe_point'")
+	tk.MustExec("recover table t_flashback")
+	tk.MustQuery("select count(*) from t_flashback").Check(testkit.Rows("3"))
+	tk.MustExec("drop table t_recover, t_flashback")
+}
+
+func (s *testSuite) TestAutoIDCache(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+
+	tk.MustExec("create table t_cache (a int primary key auto_increment, b int) auto_id_cache 1")
+	result := tk.MustQuery("show create table t_cache")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).* AUTO_ID_CACHE=1")
+	tk.MustExec("insert t_cache (b) values (1), (2)")
+	// The auto ID is rebased without allocating IDs, the value less than the auto ID is ignored.
+	tk.MustExec("alter table t_cache auto_increment = 100")
+	tk.MustExec("insert t_cache (b) values (3)")
+	tk.MustExec("alter table t_cache auto_increment = 50")
+	tk.MustExec("insert t_cache (b) values (4)")
+	tk.MustQuery("select a from t_cache").Check(testkit.Rows("1", "2", "100", "101"))
+	tk.MustExec("alter table t_cache auto_id_cache = 10")
+	tk.MustExec("insert t_cache (b) values (5)")
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.MustExec("insert t_cache (b) values (6)")
+	c.Assert(tk2.Se.Close(), IsNil)
+	tk.MustQuery("select a from t_cache where b > 4").Check(testkit.Rows("102", "103"))
+
+	// The allocated auto IDs of the table with the default step are skipped after the rebase.
+	tk.MustExec("create table t_rebase (a int primary key auto_increment)")
+	tk.MustExec("insert t_rebase values ()")
+	tk.MustExec("alter table t_rebase add column b int, auto_increment = 10")
+	tk.MustExec("insert t_rebase values (), ()")
+	step := autoid.GetStep()
+	tk.MustExec(fmt.Sprintf("alter table t_rebase auto_increment = %d", step*3))
+	tk.MustExec("insert t_rebase values ()")
+	tk.MustQuery("select a from t_rebase").Check(testkit.Rows("1", fmt.Sprint(step+1), fmt.Sprint(step+2),
+		fmt.Sprint(step*3)))
+
+	_, err := tk.Exec("alter table t_rebase auto_id_cache = 18446744073709551615")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table t_cache, t_rebase")
+}
+
+func (s *testSuite) TestShardRowIDBits(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+
+	// The pre-split regions are limited by the shard bits.
+	tk.MustExec("create table t_shard (a int) shard_row_id_bits = 4 pre_split_regions = 8")
+	result := tk.MustQuery("show create table t_shard")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).* SHARD_ROW_ID_BITS=4 PRE_SPLIT_REGIONS=4")
+	for i := 0; i < 10; i++ {
+		tk.MustExec(fmt.Sprintf("insert t_shard values (%d)", i))
+	}
+	tk.MustQuery("select count(*) from t_shard").Check(testkit.Rows("10"))
+
+	// The row IDs allocated in order are spread by the shards of the transactions.
+	tbl, err := sessionctx.GetDomain(tk.Se).InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("t_shard"))
+	c.Assert(err, IsNil)
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	defer txn.Rollback()
+	prefix := tablecodec.GenTableRecordPrefix(tbl.Meta().ID)
+	it, err := txn.Seek(prefix)
+	c.Assert(err, IsNil)
+	defer it.Close()
+	shards := make(map[int64]struct{})
+	var rowIDs []int64
+	for it.Valid() && it.Key().HasPrefix(prefix) {
+		handle, err := tablecodec.DecodeRowKey(it.Key())
+		c.Assert(err, IsNil)
+		shards[handle>>(64-1-4)] = struct{}{}
+		rowIDs = append(rowIDs, autoid.DecodeAutoRandomID(handle, 4))
+		c.Assert(it.Next(), IsNil)
+	}
+	c.Assert(rowIDs, HasLen, 10)
+	c.Assert(len(shards) > 1, IsTrue)
+
+	tk.MustExec("create table t_random (a bigint primary key auto_random(3)) pre_split_regions = 2")
+	result = tk.MustQuery("show create table t_random")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).* PRE_SPLIT_REGIONS=2")
+	_, err = tk.Exec("create table t_shard_pk (a int primary key) shard_row_id_bits = 4")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table t_shard_big (a int) shard_row_id_bits = 16")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestTemporaryTable(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table tmp_t (a int)")
+	tk.MustExec("insert tmp_t values (100)")
+
+	// The temporary table hides the table of the same name in the session.
+	tk.MustExec("create temporary table tmp_t (id int primary key auto_increment, b int, unique key (b)) auto_increment = 10")
+	tk.MustExec("insert tmp_t (b) values (1), (2)")
+	tk.MustQuery("select * from tmp_t").Check(testkit.Rows("10 1", "11 2"))
+	tk.MustQuery("show tables like 'tmp_t'").Check(testkit.Rows("tmp_t"))
+	result := tk.MustQuery("show create table tmp_t")
+	c.Assert(result.Rows()[0][1], Matches, "(?s)CREATE TEMPORARY TABLE `tmp_t` .*")
+	_, err := tk.Exec("create temporary table tmp_t (a int)")
+	c.Assert(err, NotNil)
+	tk.MustExec("create temporary table if not exists tmp_t (a int)")
+
+	// The other sessions can't see the temporary table.
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.MustQuery("select * from tmp_t").Check(testkit.Rows("100"))
+	tk2.MustExec("create temporary table tmp_t (c int)")
+	tk2.MustExec("insert tmp_t values (1)")
+	tk2.MustQuery("select * from tmp_t").Check(testkit.Rows("1"))
+	c.Assert(tk2.Se.Close(), IsNil)
+	tk.MustQuery("select * from tmp_t").Check(testkit.Rows("10 1", "11 2"))
+
+	_, err = tk.Exec("insert tmp_t values (12, 1)")
+	c.Assert(err, NotNil)
+	tk.MustExec("insert ignore tmp_t values (12, 1)")
+	tk.MustExec("insert tmp_t values (12, 1) on duplicate key update b = 3")
+	tk.MustExec("replace tmp_t values (11, 4)")
+	tk.MustExec("insert tmp_t (b) values (5)")
+	tk.MustExec("update tmp_t set b = 6 where id = 13")
+	_, err = tk.Exec("update tmp_t set b = 3 where id = 13")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from tmp_t").Check(testkit.Rows("10 3", "11 4", "13 6"))
+	tk.MustExec("delete from tmp_t where id = 10")
+	tk.MustQuery("select b from tmp_t where id > 10 order by b desc").Check(testkit.Rows("6", "4"))
+	tk.MustQuery("select count(*), max(t.id) from tmp_t t, tmp_t n where t.b = n.b").Check(testkit.Rows("2 13"))
+
+	_, err = tk.Exec("alter table tmp_t add column c int")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create index idx on tmp_t (b)")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("create temporary table tmp_like like tmp_t")
+	tk.MustExec("insert tmp_like (b) values (1)")
+	tk.MustQuery("select * from tmp_like").Check(testkit.Rows("1 1"))
+
+	// The rows are spilled to disk beyond the max size.
+	tk.MustExec("set @@tidb_tmp_table_max_size = 1024")
+	tk.MustExec("create temporary table tmp_big (a int, b varchar(100))")
+	for i := 0; i < 10; i++ {
+		tk.MustExec(fmt.Sprintf("insert tmp_big values (%d, repeat('a', 100)), (%d, repeat('b', 100))", i, -i))
+	}
+	tk.MustQuery("select count(*), sum(a), max(length(b)) from tmp_big").Check(testkit.Rows("20 0 100"))
+
+	tk.MustExec("truncate table tmp_t")
+	tk.MustQuery("select * from tmp_t").Check(nil)
+	_, err = tk.Exec("drop temporary table tmp_t, tmp_t")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from tmp_t").Check(testkit.Rows("100"))
+	tk.MustExec("drop temporary table if exists tmp_t")
+	tk.MustQuery("select * from tmp_t").Check(testkit.Rows("100"))
+	tk.MustExec("drop table tmp_like, tmp_big")
+	_, err = tk.Exec("select * from tmp_like")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestTemporaryTableTxn(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table tmp_txn (a int primary key, b int)")
+	tk.MustExec("insert tmp_txn values (1, 1)")
+	tk.MustExec("create temporary table tmp (a int primary key, b int)")
+	tk.MustExec("insert tmp values (1, 1)")
+
+	// The changes are undone by the rollback.
+	tk.MustExec("begin")
+	tk.MustExec("insert tmp values (2, 2)")
+	tk.MustExec("update tmp set b = 10 where a = 1")
+	tk.MustExec("delete from tmp where a = 2")
+	tk.MustExec("insert tmp values (3, 3)")
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 10", "3 3"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 1"))
+
+	// The changes of the failed statement are undone, the others are kept by the commit.
+	tk.MustExec("begin")
+	tk.MustExec("insert tmp values (2, 2)")
+	_, err := tk.Exec("insert tmp values (4, 4), (1, 1)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 1", "2 2"))
+	tk.MustExec("commit")
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 1", "2 2"))
+	_, err = tk.Exec("insert tmp values (5, 5), (1, 1)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 1", "2 2"))
+
+	// The changes made after the savepoint are undone.
+	tk.MustExec("begin")
+	tk.MustExec("insert tmp values (3, 3)")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("update tmp set b = 30 where a = 3")
+	tk.MustExec("delete from tmp where a = 1")
+	tk.MustExec("rollback to savepoint s1")
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 1", "2 2", "3 3"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 1", "2 2"))
+
+	// The changes aren't applied twice when the transaction is retried.
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk.MustExec("begin")
+	tk.MustExec("update tmp set b = b + 1 where a = 1")
+	tk.MustExec("insert tmp values (3, 3)")
+	tk.MustExec("update tmp_txn set b = 2 where a = 1")
+	tk2.MustExec("update tmp_txn set b = 3 where a = 1")
+	tk.MustExec("commit")
+	tk.MustQuery("select * from tmp").Check(testkit.Rows("1 2", "2 2", "3 3"))
+	tk.MustQuery("select * from tmp_txn").Check(testkit.Rows("1 2"))
+}
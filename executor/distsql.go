@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -29,10 +30,13 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 	goctx "golang.org/x/net/context"
@@ -42,6 +46,28 @@ const (
 	minLogDuration = 50 * time.Millisecond
 )
 
+// withExecDetails attaches the BackoffDetails and the ReadDetails of the statement to goCtx, so the backoffs and the
+// responses of the coprocessor requests are recorded in the statement context.
+func withExecDetails(ctx context.Context, goCtx goctx.Context) goctx.Context {
+	sc := ctx.GetSessionVars().StmtCtx
+	if sc.BackoffDetails != nil {
+		goCtx = goctx.WithValue(goCtx, execdetails.BackoffDetailsKey, sc.BackoffDetails)
+	}
+	if sc.ReadDetails != nil {
+		goCtx = goctx.WithValue(goCtx, execdetails.ReadDetailsKey, sc.ReadDetails)
+	}
+	return goCtx
+}
+
+// replicaRead returns the type of the replicas which serve the coprocessor requests of the session.
+func replicaRead(ctx context.Context) kv.ReplicaReadType {
+	dom := sessionctx.GetDomain(ctx)
+	if dom == nil {
+		return kv.ReplicaReadLeader
+	}
+	return varsutil.GetReplicaRead(ctx.GetSessionVars(), dom.Store())
+}
+
 func resultRowToRow(t table.Table, h int64, data []types.Datum, tableAsName *model.CIStr) *Row {
 	entry := &RowKeyEntry{
 		Handle:      h,
@@ -160,15 +186,24 @@ func indexRangesToKVRanges(sc *variable.StatementContext, tid, idxID int64, rang
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		// The index values are encoded in the system time zone, the range is not modified as it may be used again.
+		lowVal, err := tablecodec.ConvertTimeZone(ran.LowVal, sc.TimeZone, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		highVal, err := tablecodec.ConvertTimeZone(ran.HighVal, sc.TimeZone, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 
-		low, err := codec.EncodeKey(nil, ran.LowVal...)
+		low, err := codec.EncodeKey(nil, lowVal...)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		if ran.LowExclude {
 			low = []byte(kv.Key(low).PrefixNext())
 		}
-		high, err := codec.EncodeKey(nil, ran.HighVal...)
+		high, err := codec.EncodeKey(nil, highVal...)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -318,13 +353,14 @@ func closeAll(objs ...Closeable) error {
 // To speed up the execution, index request or table request is done concurrently. The concurrency is controlled
 // by kv.Client, we only need to pass the concurrency parameter.
 //
-// We also make a higher level of concurrency by doing index request in a background goroutine. The index goroutine
-// starts multiple worker goroutines and fetches handles from each index partial request, builds lookup table tasks
-// and sends the task to 'workerCh'.
+// We also make a higher level of concurrency by pipelining the index request and the table requests. An index worker
+// goroutine fetches handles from each index partial result, builds lookup table tasks and sends them to 'workCh',
+// while a fixed number of table worker goroutines receive the tasks from 'workCh' and execute them concurrently.
 //
-// Each worker goroutine receives tasks through the 'workerCh', then executes the task.
-// After finishing the task, the workers send the task to a taskChan. At the outer most Executor.Next method,
-// we receive the finished task through taskChan, and return each row in that task until no more tasks to receive.
+// In ordered mode, the index worker also sends the tasks to 'taskChan' in the index order, so the outer most
+// Executor.Next method receives the tasks in order and waits for each of them to be finished. In unordered mode,
+// the table workers send the tasks to 'taskChan' once they are finished, so the rows of the faster tasks are returned
+// first. 'taskChan' is closed after all the workers exit.
 type XSelectIndexExec struct {
 	tableInfo      *model.TableInfo
 	table          table.Table
@@ -346,6 +382,10 @@ type XSelectIndexExec struct {
 	tasksErr    error // not nil if tasks closed due to error.
 	taskCurr    *lookupTableTask
 	handleCount uint64 // returned handle count in double read.
+	// finished is closed when the executor is closed, to stop the index worker and the table workers.
+	finished chan struct{}
+	// workerWg waits for the index worker and the table workers to exit.
+	workerWg sync.WaitGroup
 
 	where        *tipb.Expr
 	startTS      uint64
@@ -380,10 +420,12 @@ func (e *XSelectIndexExec) Close() error {
 
 	e.taskCurr = nil
 	if e.taskChan != nil {
-		// Consume the task channel in case channel is full.
+		close(e.finished)
+		// Consume the task channel until it is closed, which means all the workers have exited.
 		for range e.taskChan {
 		}
 		e.taskChan = nil
+		e.tasksErr = nil
 	}
 	e.returnedRows = 0
 	e.partialCount = 0
@@ -450,7 +492,7 @@ func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
 		}
 		values := make([]types.Datum, schema.Len())
 		codec.SetRawValues(rowData, values)
-		err = decodeRawValues(values, schema)
+		err = decodeRawValues(values, schema, e.ctx.GetSessionVars().StmtCtx.TimeZone)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -462,7 +504,9 @@ func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
 	}
 }
 
-func decodeRawValues(values []types.Datum, schema *expression.Schema) error {
+// decodeRawValues decodes the raw values of the rows returned by the coprocessor, the TIMESTAMP values are converted
+// from the system time zone, in which they are encoded, to the time zone loc.
+func decodeRawValues(values []types.Datum, schema *expression.Schema, loc *time.Location) error {
 	var err error
 	for i := 0; i < schema.Len(); i++ {
 		if values[i].Kind() == types.KindRaw {
@@ -470,6 +514,14 @@ func decodeRawValues(values []types.Datum, schema *expression.Schema) error {
 			if err != nil {
 				return errors.Trace(err)
 			}
+			if values[i].Kind() != types.KindMysqlTime {
+				continue
+			}
+			t := values[i].GetMysqlTime()
+			if err = t.ConvertTimeZone(nil, loc); err != nil {
+				return errors.Trace(err)
+			}
+			values[i].SetMysqlTime(t)
 		}
 	}
 	return nil
@@ -504,12 +556,7 @@ func (e *XSelectIndexExec) nextForDoubleRead() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 		idxResult.Fetch(context.CtxForCancel{e.ctx})
-
-		// Use a background goroutine to fetch index and put the result in e.taskChan.
-		// e.taskChan serves as a pipeline, so fetching index and getting table data can
-		// run concurrently.
-		e.taskChan = make(chan *lookupTableTask, LookupTableTaskChannelSize)
-		go e.fetchHandles(idxResult, e.taskChan)
+		e.startWorkers(idxResult)
 	}
 
 	for {
@@ -543,25 +590,34 @@ func (e *XSelectIndexExec) slowQueryInfo(duration time.Duration) string {
 		e.partialCount, e.scanConcurrency, e.returnedRows, e.handleCount)
 }
 
-func (e *XSelectIndexExec) addWorker(workCh chan *lookupTableTask, concurrency *int, concurrencyLimit int) {
-	if *concurrency < concurrencyLimit {
-		go e.pickAndExecTask(workCh)
-		*concurrency++
+// startWorkers starts the index worker and the table workers of the double read. e.taskChan serves as a pipeline,
+// so fetching index and getting table data can run concurrently.
+func (e *XSelectIndexExec) startWorkers(idxResult distsql.SelectResult) {
+	concurrency := e.ctx.GetSessionVars().IndexLookupConcurrency
+	e.finished = make(chan struct{})
+	e.taskChan = make(chan *lookupTableTask, LookupTableTaskChannelSize)
+	workCh := make(chan *lookupTableTask, concurrency)
+	e.workerWg.Add(concurrency + 1)
+	go e.fetchHandles(idxResult, workCh, e.taskChan)
+	for i := 0; i < concurrency; i++ {
+		go e.pickAndExecTask(workCh, e.taskChan)
 	}
+	go func(taskChan chan *lookupTableTask) {
+		e.workerWg.Wait()
+		close(taskChan)
+	}(e.taskChan)
 }
 
-func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<- *lookupTableTask) {
-	workCh := make(chan *lookupTableTask, 1)
+// fetchHandles is the index worker function. It fetches the handles from the index result, builds the lookup table
+// tasks and sends them to the table workers through workCh. In ordered mode, the tasks are also sent to ch in the
+// index order.
+func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, workCh chan<- *lookupTableTask, ch chan<- *lookupTableTask) {
 	defer func() {
-		close(ch)
 		close(workCh)
 		idxResult.Close()
+		e.workerWg.Done()
 	}()
 
-	lookupConcurrencyLimit := e.ctx.GetSessionVars().IndexLookupConcurrency
-	var concurrency int
-	e.addWorker(workCh, &concurrency, lookupConcurrencyLimit)
-
 	for {
 		handles, finish, err := extractHandlesFromIndexResult(idxResult)
 		if err != nil || finish {
@@ -571,19 +627,23 @@ func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<
 		e.handleCount += uint64(len(handles))
 		tasks := e.buildTableTasks(handles)
 		for _, task := range tasks {
-			if concurrency < len(tasks) {
-				e.addWorker(workCh, &concurrency, lookupConcurrencyLimit)
-			}
-
 			select {
 			case <-e.ctx.Done():
 				return
+			case <-e.finished:
+				return
 			case workCh <- task:
-			default:
-				e.addWorker(workCh, &concurrency, lookupConcurrencyLimit)
-				workCh <- task
 			}
-			ch <- task
+			if e.indexPlan.OutOfOrder {
+				continue
+			}
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-e.finished:
+				return
+			case ch <- task:
+			}
 		}
 	}
 }
@@ -591,7 +651,7 @@ func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<
 func (e *XSelectIndexExec) doIndexRequest() (distsql.SelectResult, error) {
 	selIdxReq := new(tipb.SelectRequest)
 	selIdxReq.StartTs = e.startTS
-	selIdxReq.TimeZoneOffset = timeZoneOffset()
+	selIdxReq.TimeZoneOffset = timeZoneOffset(e.ctx.GetSessionVars().StmtCtx)
 	selIdxReq.Flags = statementContextToFlags(e.ctx.GetSessionVars().StmtCtx)
 	selIdxReq.IndexInfo = distsql.IndexToProto(e.table.Meta(), e.indexPlan.Index)
 	if e.indexPlan.Desc {
@@ -617,16 +677,22 @@ func (e *XSelectIndexExec) doIndexRequest() (distsql.SelectResult, error) {
 		// which may not have been pushed down, so we set concurrency to 1 to avoid fetching unnecessary data.
 		e.scanConcurrency = e.ctx.GetSessionVars().IndexSerialScanConcurrency
 	}
+	if !e.indexPlan.OutOfOrder && selIdxReq.Limit != nil && len(e.indexPlan.SortItemsPB) == 0 {
+		// The ordered index scan with a limit stops after reading enough rows from the first few regions, so the
+		// regions are scanned serially to avoid sending the requests of the following regions.
+		e.scanConcurrency = e.ctx.GetSessionVars().IndexSerialScanConcurrency
+	}
 	fieldTypes := make([]*types.FieldType, len(e.indexPlan.Index.Columns))
 	for i, v := range e.indexPlan.Index.Columns {
-		fieldTypes[i] = &(e.table.Cols()[v.Offset].FieldType)
+		fieldTypes[i] = &(e.table.Meta().Columns[v.Offset].FieldType)
 	}
 	sc := e.ctx.GetSessionVars().StmtCtx
 	keyRanges, err := indexRangesToKVRanges(sc, e.table.Meta().ID, e.indexPlan.Index.ID, e.indexPlan.Ranges, fieldTypes)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return distsql.Select(e.ctx.GetClient(), context.CtxForCancel{e.ctx}, selIdxReq, keyRanges, e.scanConcurrency, !e.indexPlan.OutOfOrder)
+	return distsql.Select(e.ctx.GetClient(), withExecDetails(e.ctx, context.CtxForCancel{e.ctx}), selIdxReq, keyRanges, e.scanConcurrency, !e.indexPlan.OutOfOrder, false,
+		replicaRead(e.ctx), sc.Priority)
 }
 
 func (e *XSelectIndexExec) buildTableTasks(handles []int64) []*lookupTableTask {
@@ -666,12 +732,26 @@ func (e *XSelectIndexExec) buildTableTasks(handles []int64) []*lookupTableTask {
 	return tasks
 }
 
-// pickAndExecTask is a worker function, the common usage is
-// go e.pickAndExecTask(ch)
-func (e *XSelectIndexExec) pickAndExecTask(ch <-chan *lookupTableTask) {
-	for task := range ch {
+// pickAndExecTask is the table worker function, it executes the tasks received from workCh until workCh is closed.
+// In unordered mode, the finished tasks are sent to ch.
+func (e *XSelectIndexExec) pickAndExecTask(workCh <-chan *lookupTableTask, ch chan<- *lookupTableTask) {
+	defer e.workerWg.Done()
+	for task := range workCh {
+		select {
+		case <-e.finished:
+			return
+		default:
+		}
 		err := e.executeTask(task)
 		task.doneCh <- err
+		if !e.indexPlan.OutOfOrder {
+			continue
+		}
+		select {
+		case <-e.finished:
+			return
+		case ch <- task:
+		}
 	}
 }
 
@@ -731,12 +811,17 @@ func (e *XSelectIndexExec) extractRowsFromPartialResult(t table.Table, partialRe
 		if rowData == nil {
 			break
 		}
-		values := make([]types.Datum, len(e.indexPlan.Columns))
+		values := make([]types.Datum, e.Schema().Len())
 		codec.SetRawValues(rowData, values)
-		err = decodeRawValues(values, e.Schema())
+		err = decodeRawValues(values, e.Schema(), e.ctx.GetSessionVars().StmtCtx.TimeZone)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		if e.aggregate {
+			// The rows are the partial aggregate results.
+			rows = append(rows, &Row{Data: values})
+			continue
+		}
 		row := resultRowToRow(t, h, values, e.indexPlan.TableAsName)
 		rows = append(rows, row)
 	}
@@ -751,7 +836,7 @@ func (e *XSelectIndexExec) doTableRequest(handles []int64) (distsql.SelectResult
 		selTableReq.OrderBy = e.indexPlan.SortItemsPB
 	}
 	selTableReq.StartTs = e.startTS
-	selTableReq.TimeZoneOffset = timeZoneOffset()
+	selTableReq.TimeZoneOffset = timeZoneOffset(e.ctx.GetSessionVars().StmtCtx)
 	selTableReq.Flags = statementContextToFlags(e.ctx.GetSessionVars().StmtCtx)
 	selTableReq.TableInfo = &tipb.TableInfo{
 		TableId: e.table.Meta().ID,
@@ -767,7 +852,8 @@ func (e *XSelectIndexExec) doTableRequest(handles []int64) (distsql.SelectResult
 	selTableReq.GroupBy = e.byItems
 	keyRanges := tableHandlesToKVRanges(e.table.Meta().ID, handles)
 
-	resp, err := distsql.Select(e.ctx.GetClient(), goctx.Background(), selTableReq, keyRanges, e.scanConcurrency, false)
+	resp, err := distsql.Select(e.ctx.GetClient(), withExecDetails(e.ctx, context.CtxForCancel{e.ctx}), selTableReq, keyRanges, e.scanConcurrency, false, false,
+		replicaRead(e.ctx), e.ctx.GetSessionVars().StmtCtx.Priority)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -821,11 +907,26 @@ func (e *XSelectTableExec) Schema() *expression.Schema {
 	return e.schema
 }
 
+// priority returns the kv priority of the table scan. The full table scan without limit has low priority if the
+// statement has no priority option, the big scans of the batch jobs don't starve the interactive queries then.
+func (e *XSelectTableExec) priority() int {
+	priority := e.ctx.GetSessionVars().StmtCtx.Priority
+	if priority == kv.PriorityNormal && e.limitCount == nil && isFullTableRange(e.ranges) {
+		return kv.PriorityLow
+	}
+	return priority
+}
+
+// isFullTableRange checks whether the ranges cover all the handles of the table.
+func isFullTableRange(ranges []plan.TableRange) bool {
+	return len(ranges) == 1 && ranges[0].LowVal == math.MinInt64 && ranges[0].HighVal == math.MaxInt64
+}
+
 // doRequest sends a *tipb.SelectRequest via kv.Client and gets the distsql.SelectResult.
 func (e *XSelectTableExec) doRequest() error {
 	selReq := new(tipb.SelectRequest)
 	selReq.StartTs = e.startTS
-	selReq.TimeZoneOffset = timeZoneOffset()
+	selReq.TimeZoneOffset = timeZoneOffset(e.ctx.GetSessionVars().StmtCtx)
 	selReq.Flags = statementContextToFlags(e.ctx.GetSessionVars().StmtCtx)
 	selReq.Where = e.where
 	selReq.TableInfo = &tipb.TableInfo{
@@ -846,8 +947,15 @@ func (e *XSelectTableExec) doRequest() error {
 	selReq.Aggregates = e.aggFuncs
 	selReq.GroupBy = e.byItems
 
+	concurrency := e.ctx.GetSessionVars().DistSQLScanConcurrency
+	if e.keepOrder && e.limitCount != nil && len(e.orderByList) == 0 {
+		// The ordered table scan with a limit stops after reading enough rows from the first few regions.
+		concurrency = e.ctx.GetSessionVars().IndexSerialScanConcurrency
+	}
 	kvRanges := tableRangesToKVRanges(e.table.Meta().ID, e.ranges)
-	e.result, err = distsql.Select(e.ctx.GetClient(), goctx.Background(), selReq, kvRanges, e.ctx.GetSessionVars().DistSQLScanConcurrency, e.keepOrder)
+	vars := e.ctx.GetSessionVars()
+	e.result, err = distsql.Select(e.ctx.GetClient(), withExecDetails(e.ctx, context.CtxForCancel{e.ctx}), selReq, kvRanges, concurrency, e.keepOrder,
+		vars.EnableStreaming, replicaRead(e.ctx), e.priority())
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -870,14 +978,63 @@ func (e *XSelectTableExec) Close() error {
 
 // Next implements the Executor interface.
 func (e *XSelectTableExec) Next() (*Row, error) {
+	h, rowData, err := e.nextRowData()
+	if err != nil || rowData == nil {
+		return nil, errors.Trace(err)
+	}
+	values := make([]types.Datum, e.schema.Len())
+	if err = e.decodeRow(rowData, values); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if e.aggregate {
+		// compose aggregate row
+		return &Row{Data: values}, nil
+	}
+	return resultRowToRow(e.table, h, values, e.asName), nil
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *XSelectTableExec) NextChunk(chk *rowChunk) error {
+	chk.reset()
+	values := make([]types.Datum, e.schema.Len())
+	for !chk.isFull() {
+		h, rowData, err := e.nextRowData()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if rowData == nil {
+			return nil
+		}
+		if err = e.decodeRow(rowData, values); err != nil {
+			return errors.Trace(err)
+		}
+		var rowKeys []*RowKeyEntry
+		if !e.aggregate {
+			rowKeys = []*RowKeyEntry{{Handle: h, Tbl: e.table, TableAsName: e.asName}}
+		}
+		chk.appendRow(values, rowKeys)
+	}
+	return nil
+}
+
+func (e *XSelectTableExec) decodeRow(rowData []byte, values []types.Datum) error {
+	for i := range values {
+		values[i] = types.Datum{}
+	}
+	codec.SetRawValues(rowData, values)
+	return errors.Trace(decodeRawValues(values, e.schema, e.ctx.GetSessionVars().StmtCtx.TimeZone))
+}
+
+// nextRowData returns the handle and the raw data of the next row, the data is nil if there are no more rows.
+func (e *XSelectTableExec) nextRowData() (int64, []byte, error) {
 	if e.limitCount != nil && e.returnedRows >= uint64(*e.limitCount) {
-		return nil, nil
+		return 0, nil, nil
 	}
 	if e.result == nil {
 		e.execStart = time.Now()
 		err := e.doRequest()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 	}
 	for {
@@ -886,7 +1043,7 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			var err error
 			e.partialResult, err = e.result.Next()
 			if err != nil {
-				return nil, errors.Trace(err)
+				return 0, nil, errors.Trace(err)
 			}
 			if e.partialResult == nil {
 				// Finished.
@@ -895,14 +1052,14 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 					connID := e.ctx.GetSessionVars().ConnectionID
 					log.Infof("[%d] [TIME_TABLE_SCAN] %s", connID, e.slowQueryInfo(duration))
 				}
-				return nil, nil
+				return 0, nil, nil
 			}
 			e.partialCount++
 		}
 		// Get a row from partial result.
 		h, rowData, err := e.partialResult.Next()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 		if rowData == nil {
 			// Finish the current partial result and get the next one.
@@ -911,17 +1068,7 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			continue
 		}
 		e.returnedRows++
-		values := make([]types.Datum, e.schema.Len())
-		codec.SetRawValues(rowData, values)
-		err = decodeRawValues(values, e.schema)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		if e.aggregate {
-			// compose aggregate row
-			return &Row{Data: values}, nil
-		}
-		return resultRowToRow(e.table, h, values, e.asName), nil
+		return h, rowData, nil
 	}
 }
 
@@ -931,9 +1078,13 @@ func (e *XSelectTableExec) slowQueryInfo(duration time.Duration) string {
 		e.startTS, e.returnedRows)
 }
 
-// timeZoneOffset returns the local time zone offset in seconds.
-func timeZoneOffset() int64 {
-	_, offset := time.Now().Zone()
+// timeZoneOffset returns the offset in seconds of the session time zone, the system time zone is used if it's not set.
+func timeZoneOffset(sc *variable.StatementContext) int64 {
+	loc := sc.TimeZone
+	if loc == nil {
+		loc = time.Local
+	}
+	_, offset := time.Now().In(loc).Zone()
 	return int64(offset)
 }
 
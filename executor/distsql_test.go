@@ -21,12 +21,18 @@ import (
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/store/tikv/mock-tikv"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/testkit"
+	"github.com/pingcap/tidb/util/types"
 )
 
 // This test checks that when a index double read returns before reading all the rows, the goroutine doesn't
@@ -63,6 +69,52 @@ func (s *testSuite) TestIndexDoubleReadClose(c *C) {
 	executor.LookupTableTaskChannelSize = originSize
 }
 
+func (s *testSuite) TestIndexDoubleReadPipeline(c *C) {
+	defer s.cleanEnv(c)
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists dist")
+	tk.MustExec("create table dist (id int primary key, c_idx int, c_col int, index (c_idx))")
+	var values []string
+	for i := 0; i < 100; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d, %d)", i, 99-i, i))
+	}
+	tk.MustExec("insert dist values " + strings.Join(values, ","))
+
+	var ordered, orderedDesc []string
+	for i := 10; i < 100; i++ {
+		ordered = append(ordered, fmt.Sprintf("%d", 109-i))
+		orderedDesc = append(orderedDesc, fmt.Sprintf("%d", i))
+	}
+	tk.MustExec("set @@tidb_index_lookup_size = '3'")
+	for _, concurrency := range []int{1, 3, 10} {
+		tk.MustExec(fmt.Sprintf("set @@tidb_index_lookup_concurrency = %d", concurrency))
+		// Ordered mode returns the rows in the index order.
+		tk.MustQuery("select c_col from dist where c_idx < 90 order by c_idx desc").Check(testkit.Rows(orderedDesc...))
+		tk.MustQuery("select c_col from dist where c_idx < 90 order by c_idx").Check(testkit.Rows(ordered...))
+		tk.MustQuery("select c_col from dist where c_idx < 90 order by c_idx limit 2").Check(testkit.Rows("99", "98"))
+		// Unordered mode returns all the rows in any order.
+		tk.MustQuery("select count(c_col), sum(c_col) from dist where c_idx < 90").Check(testkit.Rows("90 4905"))
+		tk.MustQuery("select count(*) from dist where c_idx < 90 and c_col > 50").Check(testkit.Rows("49"))
+	}
+
+	// Closing the executor before reading all the rows stops the workers.
+	for _, sql := range []string{
+		"select * from dist where c_idx < 90 order by c_idx",
+		"select * from dist where c_idx < 90",
+	} {
+		rss, err := tk.Se.Execute(sql)
+		c.Assert(err, IsNil)
+		rs := rss[0]
+		_, err = rs.Next()
+		c.Assert(err, IsNil)
+		rs.Close()
+		time.Sleep(time.Millisecond * 50)
+		c.Check(checkGoroutineExists("pickAndExecTask"), IsFalse)
+		c.Check(checkGoroutineExists("fetchHandles"), IsFalse)
+	}
+}
+
 func checkGoroutineExists(keyword string) bool {
 	buf := new(bytes.Buffer)
 	profile := pprof.Lookup("goroutine")
@@ -71,6 +123,104 @@ func checkGoroutineExists(keyword string) bool {
 	return strings.Contains(str, keyword)
 }
 
+func (s *testSuite) TestPreSplitRegions(c *C) {
+	if _, ok := s.store.GetClient().(*tikv.CopClient); !ok {
+		// Make sure the store is tikv store.
+		return
+	}
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t_split (a int) shard_row_id_bits = 4 pre_split_regions = 2")
+	tbl, err := sessionctx.GetDomain(tk.Se).InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("t_split"))
+	c.Assert(err, IsNil)
+	tblID := tbl.Meta().ID
+
+	// The rows are split into 4 regions by the highest 2 bits of the row IDs.
+	cli := tikv.GetMockTiKVClient(s.store)
+	regionIDs := make(map[uint64]struct{})
+	for i := int64(0); i < 4; i++ {
+		key := tablecodec.EncodeRowKeyWithHandle(tblID, i<<61)
+		region, _ := cli.Cluster.GetRegionByKey(mocktikv.NewMvccKey(key))
+		c.Assert(region, NotNil)
+		regionIDs[region.GetId()] = struct{}{}
+	}
+	c.Assert(regionIDs, HasLen, 4)
+	region, _ := cli.Cluster.GetRegionByKey(mocktikv.NewMvccKey(tablecodec.GenTableRecordPrefix(tblID)))
+	c.Assert([]byte(region.GetStartKey()), DeepEquals, []byte(mocktikv.NewMvccKey(tablecodec.GenTableRecordPrefix(tblID))))
+
+	for i := 0; i < 20; i++ {
+		tk.MustExec(fmt.Sprintf("insert t_split values (%d)", i))
+	}
+	tk.MustQuery("select count(*), sum(a) from t_split").Check(testkit.Rows("20 190"))
+	tk.MustExec("drop table t_split")
+}
+
+func (s *testSuite) TestSplitRegion(c *C) {
+	if _, ok := s.store.GetClient().(*tikv.CopClient); !ok {
+		// Make sure the store is tikv store.
+		return
+	}
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t_split_region (a int, b varchar(10), index idx(a, b))")
+	tbl, err := sessionctx.GetDomain(tk.Se).InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("t_split_region"))
+	c.Assert(err, IsNil)
+	tblInfo := tbl.Meta()
+	idxID := tblInfo.Indices[0].ID
+	cli := tikv.GetMockTiKVClient(s.store)
+	checkRegionStart := func(key []byte) {
+		region, _ := cli.Cluster.GetRegionByKey(mocktikv.NewMvccKey(key))
+		c.Assert(region, NotNil)
+		c.Assert([]byte(region.GetStartKey()), DeepEquals, []byte(mocktikv.NewMvccKey(key)))
+	}
+	indexKey := func(values ...interface{}) []byte {
+		encoded, err1 := codec.EncodeKey(nil, types.MakeDatums(values...)...)
+		c.Assert(err1, IsNil)
+		return tablecodec.EncodeIndexSeekKey(tblInfo.ID, idxID, encoded)
+	}
+
+	tk.MustQuery("split table t_split_region by (1000), (2000)").Check(testkit.Rows("2 0"))
+	checkRegionStart(tablecodec.EncodeRowKeyWithHandle(tblInfo.ID, 1000))
+	checkRegionStart(tablecodec.EncodeRowKeyWithHandle(tblInfo.ID, 2000))
+	tk.MustQuery("split table t_split_region between (0) and (100) regions 4").Check(testkit.Rows("4 0"))
+	for _, h := range []int64{0, 25, 50, 75} {
+		checkRegionStart(tablecodec.EncodeRowKeyWithHandle(tblInfo.ID, h))
+	}
+
+	// The values are converted to the types of the index columns.
+	tk.MustQuery("split table t_split_region index idx by ('10', 'a'), (20)").Check(testkit.Rows("2 0"))
+	checkRegionStart(indexKey(10, "a"))
+	checkRegionStart(indexKey(20))
+	tk.MustQuery("split table t_split_region index idx between (100) and (200) regions 2").Check(testkit.Rows("2 0"))
+	checkRegionStart(indexKey(100))
+	lowerRegion, _ := cli.Cluster.GetRegionByKey(mocktikv.NewMvccKey(indexKey(100)))
+	upperRegion, _ := cli.Cluster.GetRegionByKey(mocktikv.NewMvccKey(indexKey(199)))
+	c.Assert(lowerRegion.GetId(), Not(Equals), upperRegion.GetId())
+
+	_, err = tk.Exec("split table t_split_region index idx2 by (1)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrSplitRegion), IsTrue, Commentf("%v", err))
+	_, err = tk.Exec("split table t_split_region by (1, 2)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrSplitRegion), IsTrue, Commentf("%v", err))
+	_, err = tk.Exec("split table t_split_region between (0) and (100) regions 1001")
+	c.Assert(terror.ErrorEqual(err, plan.ErrSplitRegion), IsTrue, Commentf("%v", err))
+	for _, sql := range []string{
+		"split table t_split_region between (100) and (0) regions 4",
+		"split table t_split_region between (0) and (3) regions 4",
+	} {
+		rs, err := tk.Exec(sql)
+		c.Assert(err, IsNil)
+		_, err = tidb.GetRows(rs)
+		c.Assert(terror.ErrorEqual(err, executor.ErrCannotSplitRegion), IsTrue, Commentf("%v", err))
+	}
+
+	for i := 0; i < 20; i++ {
+		tk.MustExec(fmt.Sprintf("insert t_split_region values (%d, 'a')", i*10))
+	}
+	tk.MustQuery("select count(*), sum(a) from t_split_region").Check(testkit.Rows("20 1900"))
+	tk.MustQuery("select count(*) from t_split_region use index(idx) where a >= 10").Check(testkit.Rows("19"))
+	tk.MustExec("drop table t_split_region")
+}
+
 func (s *testSuite) TestCopClientSend(c *C) {
 	c.Skip("not stable")
 	if _, ok := s.store.GetClient().(*tikv.CopClient); !ok {
@@ -14,8 +14,10 @@
 package executor
 
 import (
+	"hash/crc64"
 	"sync"
-	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
@@ -27,14 +29,20 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/execdetails"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
 var (
 	_ Executor = &CheckTableExec{}
+	_ Executor = &ChecksumTableExec{}
+	_ Executor = &ShowIndexAdviceExec{}
 	_ Executor = &DummyScanExec{}
 	_ Executor = &ExistsExec{}
 	_ Executor = &HashAggExec{}
@@ -45,12 +53,15 @@ var (
 	_ Executor = &SelectionExec{}
 	_ Executor = &SelectLockExec{}
 	_ Executor = &ShowDDLExec{}
+	_ Executor = &ShowDDLJobsExec{}
+	_ Executor = &CancelDDLJobsExec{}
 	_ Executor = &SortExec{}
 	_ Executor = &StreamAggExec{}
 	_ Executor = &TableDualExec{}
 	_ Executor = &TableScanExec{}
 	_ Executor = &TopnExec{}
 	_ Executor = &UnionExec{}
+	_ Executor = &UnionDistinctExec{}
 )
 
 // Error instances.
@@ -65,6 +76,17 @@ var (
 	ErrPasswordNoMatch = terror.ClassExecutor.New(CodePasswordNoMatch, "Can't find any matching row in the user table")
 	ErrResultIsEmpty   = terror.ClassExecutor.New(codeResultIsEmpty, "result is empty")
 	ErrBuildExecutor   = terror.ClassExecutor.New(codeErrBuildExec, "Failed to build executor")
+
+	ErrTemporaryTableUnsupported = terror.ClassExecutor.New(codeTemporaryTableUnsupported, "%s is not supported on temporary table '%s'")
+	ErrCTEMaxRecursionDepth      = terror.ClassExecutor.New(codeCTEMaxRecursionDepth, mysql.MySQLErrName[mysql.ErrCTEMaxRecursionDepth])
+	ErrCannotRecoverTable        = terror.ClassExecutor.New(codeCannotRecoverTable, "Can't recover the table: %s")
+	ErrSnapshotTooOld            = terror.ClassExecutor.New(codeSnapshotTooOld, "Snapshot is older than GC safe point %s")
+	ErrCannotSplitRegion         = terror.ClassExecutor.New(codeCannotSplitRegion, "Can't split the regions: %s")
+	ErrSavepointNotExists        = terror.ClassExecutor.New(codeSavepointNotExists, "SAVEPOINT %s does not exist")
+	ErrQueryInterrupted          = terror.ClassExecutor.New(codeQueryInterrupted, "Query execution was interrupted")
+	ErrNoSuchThread              = terror.ClassExecutor.New(codeNoSuchThread, "Unknown thread id: %d")
+	ErrKillDenied                = terror.ClassExecutor.New(codeKillDenied, "You are not owner of thread %d")
+	ErrPessimisticTxnDisabled    = terror.ClassExecutor.New(codePessimisticTxnDisabled, "Pessimistic transaction is experimental, it's disabled unless tidb-server starts with -experimental-pessimistic-txn")
 )
 
 // Error codes.
@@ -78,9 +100,20 @@ const (
 	codePrepareDDL      terror.ErrCode = 7
 	codeResultIsEmpty   terror.ErrCode = 8
 	codeErrBuildExec    terror.ErrCode = 9
+
+	codeTemporaryTableUnsupported terror.ErrCode = 10
+	codeCTEMaxRecursionDepth      terror.ErrCode = 11
+	codeCannotRecoverTable        terror.ErrCode = 12
+	codeSnapshotTooOld            terror.ErrCode = 13
+	codeCannotSplitRegion         terror.ErrCode = 14
+	codePessimisticTxnDisabled    terror.ErrCode = 15
 	// MySQL error code
-	CodePasswordNoMatch terror.ErrCode = 1133
-	CodeCannotUser      terror.ErrCode = 1396
+	CodePasswordNoMatch    terror.ErrCode = 1133
+	CodeCannotUser         terror.ErrCode = 1396
+	codeSavepointNotExists terror.ErrCode = 1305
+	codeQueryInterrupted   terror.ErrCode = 1317
+	codeNoSuchThread       terror.ErrCode = 1094
+	codeKillDenied         terror.ErrCode = 1095
 )
 
 // Row represents a result set row, it may be returned from a table, a join, or a projection.
@@ -101,6 +134,32 @@ type RowKeyEntry struct {
 	TableAsName *model.CIStr
 }
 
+var (
+	datumSize   = int64(unsafe.Sizeof(types.Datum{}))
+	decimalSize = int64(unsafe.Sizeof(types.MyDecimal{}))
+	rowKeySize  = int64(unsafe.Sizeof(&RowKeyEntry{}))
+)
+
+// memUsage returns the approximate memory usage of the row in bytes, it is consumed by the memory tracker of the
+// executor which keeps the row in memory.
+func (r *Row) memUsage() int64 {
+	return datumsMemUsage(r.Data) + rowKeySize*int64(len(r.RowKeys))
+}
+
+// datumsMemUsage returns the approximate memory usage of the datums in bytes.
+func datumsMemUsage(data []types.Datum) int64 {
+	usage := datumSize * int64(len(data))
+	for i := range data {
+		switch data[i].Kind() {
+		case types.KindString, types.KindBytes:
+			usage += int64(len(data[i].GetBytes()))
+		case types.KindMysqlDecimal:
+			usage += decimalSize
+		}
+	}
+	return usage
+}
+
 // Executor executes a query.
 type Executor interface {
 	Next() (*Row, error)
@@ -108,6 +167,42 @@ type Executor interface {
 	Schema() *expression.Schema
 }
 
+// runtimeStatsExec wraps an executor to record its runtime statistics.
+type runtimeStatsExec struct {
+	Executor
+	stats *execdetails.RuntimeStats
+}
+
+// Next implements the Executor Next interface.
+func (e *runtimeStatsExec) Next() (*Row, error) {
+	start := time.Now()
+	row, err := e.Executor.Next()
+	rowNum := 0
+	if row != nil {
+		rowNum = 1
+	}
+	e.stats.Record(time.Since(start), rowNum)
+	// The error is returned as is, the wrapper should be transparent to the callers.
+	return row, err
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface, a chunk is recorded as a loop. It must only be called
+// if the wrapped executor supports chunks.
+func (e *runtimeStatsExec) NextChunk(chk *rowChunk) error {
+	start := time.Now()
+	err := e.Executor.(chunkExecutor).NextChunk(chk)
+	e.stats.Record(time.Since(start), chk.NumRows())
+	return err
+}
+
+// unwrapRuntimeStats returns the executor wrapped by runtimeStatsExec.
+func unwrapRuntimeStats(e Executor) Executor {
+	if x, ok := e.(*runtimeStatsExec); ok {
+		return x.Executor
+	}
+	return e
+}
+
 // ShowDDLExec represents a show DDL executor.
 type ShowDDLExec struct {
 	schema  *expression.Schema
@@ -162,6 +257,95 @@ func (e *ShowDDLExec) Close() error {
 	return nil
 }
 
+// maxHistoryDDLJobs is the max number of the finished DDL jobs shown by the 'admin show ddl jobs' statement.
+const maxHistoryDDLJobs = 10
+
+// ShowDDLJobsExec represents a show DDL jobs executor.
+// It shows the DDL jobs in the job queues, and then the latest finished DDL jobs.
+type ShowDDLJobsExec struct {
+	schema *expression.Schema
+	ctx    context.Context
+	is     infoschema.InfoSchema
+	jobs   []*model.Job
+	cursor int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ShowDDLJobsExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowDDLJobsExec) Next() (*Row, error) {
+	if e.cursor >= len(e.jobs) {
+		return nil, nil
+	}
+	job := e.jobs[e.cursor]
+	e.cursor++
+
+	// The schema or the table may not exist in the current information schema, e.g. it's being created or has
+	// been dropped, then its name is left empty.
+	var dbName, tableName string
+	if db, ok := e.is.SchemaByID(job.SchemaID); ok {
+		dbName = db.Name.O
+	}
+	if tbl, ok := e.is.TableByID(job.TableID); ok {
+		tableName = tbl.Meta().Name.O
+	}
+	row := &Row{}
+	row.Data = types.MakeDatums(
+		job.ID,
+		dbName,
+		tableName,
+		job.Type.String(),
+		job.SchemaState.String(),
+		job.SchemaID,
+		job.TableID,
+		job.GetRowCount(),
+		job.State.String(),
+	)
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ShowDDLJobsExec) Close() error {
+	return nil
+}
+
+// CancelDDLJobsExec represents a cancel DDL jobs executor.
+// It shows whether every job is requested to be cancelled successfully.
+type CancelDDLJobsExec struct {
+	schema *expression.Schema
+	jobIDs []int64
+	errs   []error
+	cursor int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *CancelDDLJobsExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *CancelDDLJobsExec) Next() (*Row, error) {
+	if e.cursor >= len(e.jobIDs) {
+		return nil, nil
+	}
+	result := "successful"
+	if err := e.errs[e.cursor]; err != nil {
+		result = err.Error()
+	}
+	row := &Row{}
+	row.Data = types.MakeDatums(e.jobIDs[e.cursor], result)
+	e.cursor++
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *CancelDDLJobsExec) Close() error {
+	return nil
+}
+
 // CheckTableExec represents a check table executor.
 // It is built from the "admin check table" statement, and it checks if the
 // index matches the records in the table.
@@ -183,18 +367,24 @@ func (e *CheckTableExec) Next() (*Row, error) {
 		return nil, nil
 	}
 
-	dbName := model.NewCIStr(e.ctx.GetSessionVars().CurrentDB)
-
 	for _, t := range e.tables {
-		tb, err := e.is.TableByName(dbName, t.Name)
+		tb, err := e.is.TableByName(t.Schema, t.Name)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		for _, idx := range tb.Indices() {
+			// The index which is being added or dropped is not consistent with the records yet.
+			if idx.Meta().State != model.StatePublic {
+				continue
+			}
+			// The values of the expression index aren't stored in the records.
+			if tb.Meta().IsExpressionIndex(idx.Meta()) {
+				continue
+			}
 			txn := e.ctx.Txn()
 			err = inspectkv.CompareIndexData(txn, tb, idx)
 			if err != nil {
-				return nil, errors.Errorf("%v err:%v", t.Name, err)
+				return nil, errors.Errorf("%v index %v err:%v", t.Name, idx.Meta().Name, err)
 			}
 		}
 	}
@@ -208,12 +398,89 @@ func (e *CheckTableExec) Close() error {
 	return nil
 }
 
+// ChecksumTableExec represents a checksum table executor.
+// It is built from the "admin checksum table" statement, it returns a row with the checksum, the number of kv pairs
+// and the number of bytes for each table, which can be used to compare the data of two clusters after a migration.
+type ChecksumTableExec struct {
+	tables  []*ast.TableName
+	schema  *expression.Schema
+	ctx     context.Context
+	is      infoschema.InfoSchema
+	startTS uint64
+	cursor  int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ChecksumTableExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ChecksumTableExec) Next() (*Row, error) {
+	if e.cursor >= len(e.tables) {
+		return nil, nil
+	}
+	t := e.tables[e.cursor]
+	tb, err := e.is.TableByName(t.Schema, t.Name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The transaction of an auto-commit statement may be committed before the rows are read, so the data is read
+	// from a snapshot.
+	snapshot, err := sessionctx.GetDomain(e.ctx).Store().GetSnapshot(kv.Version{Ver: e.startTS})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer snapshot.Release()
+	checksum, totalKvs, totalBytes, err := checksumTable(snapshot, tb.Meta().ID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	e.cursor++
+	return &Row{Data: types.MakeDatums(t.Schema.O, t.Name.O, checksum, totalKvs, totalBytes)}, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ChecksumTableExec) Close() error {
+	return nil
+}
+
+// checksumTable computes the xor of the crc64 checksums of all the kv pairs of the table, including the records and
+// the indices. The table ID prefix is stripped from the keys, so the same data in two clusters has the same checksum
+// even if the table IDs are different.
+func checksumTable(retriever kv.Retriever, tableID int64) (checksum, totalKvs, totalBytes uint64, err error) {
+	prefix := tablecodec.EncodeTablePrefix(tableID)
+	it, err := retriever.Seek(prefix)
+	if err != nil {
+		return 0, 0, 0, errors.Trace(err)
+	}
+	defer it.Close()
+
+	table := crc64.MakeTable(crc64.ECMA)
+	for it.Valid() && it.Key().HasPrefix(prefix) {
+		key, value := it.Key(), it.Value()
+		h := crc64.New(table)
+		h.Write(key[len(prefix):])
+		h.Write(value)
+		checksum ^= h.Sum64()
+		totalKvs++
+		totalBytes += uint64(len(key) + len(value))
+		if err = it.Next(); err != nil {
+			return 0, 0, 0, errors.Trace(err)
+		}
+	}
+	return checksum, totalKvs, totalBytes, nil
+}
+
 // SelectLockExec represents a select lock executor.
 // It is built from the "SELECT .. FOR UPDATE" or the "SELECT .. LOCK IN SHARE MODE" statement.
 // For "SELECT .. FOR UPDATE" statement, it locks every row key from source Executor.
 // After the execution, the keys are buffered in transaction, and will be sent to KV
 // when doing commit. If there is any key already locked by another transaction,
 // the transaction will rollback and retry.
+// In a pessimistic transaction, the keys are locked when they are read. With NOWAIT, it returns an error at once
+// if a row is locked by another transaction, and with SKIP LOCKED the row is skipped. The keys of an optimistic
+// transaction are only locked when it commits, so NOWAIT and SKIP LOCKED make no difference.
 type SelectLockExec struct {
 	Src    Executor
 	Lock   ast.SelectLockType
@@ -228,25 +495,42 @@ func (e *SelectLockExec) Schema() *expression.Schema {
 
 // Next implements the Executor Next interface.
 func (e *SelectLockExec) Next() (*Row, error) {
-	row, err := e.Src.Next()
-	if err != nil {
-		return nil, errors.Trace(err)
+	for {
+		row, err := e.Src.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			return nil, nil
+		}
+		if len(row.RowKeys) == 0 || !e.Lock.IsForUpdate() {
+			return row, nil
+		}
+		e.ctx.GetSessionVars().TxnCtx.ForUpdate = true
+		err = e.lockRow(row)
+		if e.Lock == ast.SelectLockForUpdateSkipLocked && terror.ErrorEqual(err, kv.ErrLockNowait) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return row, nil
 	}
-	if row == nil {
-		return nil, nil
+}
+
+func (e *SelectLockExec) lockRow(row *Row) error {
+	txn := e.ctx.Txn()
+	if e.Lock != ast.SelectLockForUpdate {
+		txn.SetOption(kv.LockWaitPolicy, kv.LockNoWait)
+		defer txn.DelOption(kv.LockWaitPolicy)
 	}
-	if len(row.RowKeys) != 0 && e.Lock == ast.SelectLockForUpdate {
-		e.ctx.GetSessionVars().TxnCtx.ForUpdate = true
-		txn := e.ctx.Txn()
-		for _, k := range row.RowKeys {
-			lockKey := tablecodec.EncodeRowKeyWithHandle(k.Tbl.Meta().ID, k.Handle)
-			err = txn.LockKeys(lockKey)
-			if err != nil {
-				return nil, errors.Trace(err)
-			}
+	for _, k := range row.RowKeys {
+		lockKey := tablecodec.EncodeRowKeyWithHandle(k.Tbl.Meta().ID, k.Handle)
+		if err := txn.LockKeys(lockKey); err != nil {
+			return errors.Trace(err)
 		}
 	}
-	return row, nil
+	return nil
 }
 
 // Close implements the Executor Close interface.
@@ -377,6 +661,12 @@ func init() {
 	tableMySQLErrCodes := map[terror.ErrCode]uint16{
 		CodeCannotUser:      mysql.ErrCannotUser,
 		CodePasswordNoMatch: mysql.ErrPasswordNoMatch,
+
+		codeCTEMaxRecursionDepth: mysql.ErrCTEMaxRecursionDepth,
+		codeSavepointNotExists:   mysql.ErrSpDoesNotExist,
+		codeQueryInterrupted:     mysql.ErrQueryInterrupted,
+		codeNoSuchThread:         mysql.ErrNoSuchThread,
+		codeKillDenied:           mysql.ErrKillDenied,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassExecutor] = tableMySQLErrCodes
 }
@@ -388,6 +678,29 @@ type ProjectionExec struct {
 	executed bool
 	ctx      context.Context
 	exprs    []expression.Expression
+
+	// concurrency is the number of workers evaluating the expressions over the batches of input rows, the expressions
+	// are evaluated in the executor goroutine if it's 1.
+	concurrency int
+	prepared    bool
+	finished    chan struct{}
+	wg          sync.WaitGroup
+	taskCh      chan *projectionTask
+	// outputCh receives the tasks in the order of the input, so the rows are returned in order.
+	outputCh chan *projectionTask
+	rows     []*Row
+	cursor   int
+
+	// useChunk is set if the rows are read from Src in chunks and the expressions are evaluated over a chunk at once.
+	useChunk bool
+	reader   chunkRowReader
+	srcChk   *rowChunk
+}
+
+// projectionTask is a batch of input rows, the result is sent to respCh by a worker.
+type projectionTask struct {
+	rows   []*Row
+	respCh chan *execResult
 }
 
 // Schema implements the Executor Schema interface.
@@ -397,7 +710,12 @@ func (e *ProjectionExec) Schema() *expression.Schema {
 
 // Next implements the Executor Next interface.
 func (e *ProjectionExec) Next() (retRow *Row, err error) {
-	var rowKeys []*RowKeyEntry
+	if e.concurrency > 1 && e.Src != nil {
+		return e.parallelNext()
+	}
+	if e.useChunk {
+		return e.reader.next(e)
+	}
 	var srcRow *Row
 	if e.Src != nil {
 		srcRow, err = e.Src.Next()
@@ -407,7 +725,6 @@ func (e *ProjectionExec) Next() (retRow *Row, err error) {
 		if srcRow == nil {
 			return nil, nil
 		}
-		rowKeys = srcRow.RowKeys
 	} else {
 		// If Src is nil, only one row should be returned.
 		if e.executed {
@@ -415,12 +732,43 @@ func (e *ProjectionExec) Next() (retRow *Row, err error) {
 		}
 	}
 	e.executed = true
+	return evalProjection(e.exprs, srcRow)
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *ProjectionExec) NextChunk(chk *rowChunk) error {
+	if e.srcChk == nil {
+		e.srcChk = newRowChunk(e.Src.Schema().Len())
+	}
+	e.srcChk.capacity = chk.capacity
+	err := e.Src.(chunkExecutor).NextChunk(e.srcChk)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	chk.Resize(e.srcChk.NumRows())
+	for i, expr := range e.exprs {
+		err = expression.VectorizedEval(expr, e.srcChk.Chunk, chk.Column(i))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	chk.rowKeys = append(chk.rowKeys[:0], e.srcChk.rowKeys...)
+	return nil
+}
+
+func evalProjection(exprs []expression.Expression, srcRow *Row) (*Row, error) {
+	var rowKeys []*RowKeyEntry
+	var data []types.Datum
+	if srcRow != nil {
+		rowKeys = srcRow.RowKeys
+		data = srcRow.Data
+	}
 	row := &Row{
 		RowKeys: rowKeys,
-		Data:    make([]types.Datum, 0, len(e.exprs)),
+		Data:    make([]types.Datum, 0, len(exprs)),
 	}
-	for _, expr := range e.exprs {
-		val, err := expr.Eval(srcRow.Data)
+	for _, expr := range exprs {
+		val, err := expr.Eval(data)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -429,8 +777,118 @@ func (e *ProjectionExec) Next() (retRow *Row, err error) {
 	return row, nil
 }
 
+func (e *ProjectionExec) parallelNext() (*Row, error) {
+	if !e.prepared {
+		e.prepare()
+	}
+	for e.cursor >= len(e.rows) {
+		task, ok := <-e.outputCh
+		if !ok {
+			return nil, nil
+		}
+		result := <-task.respCh
+		if result.err != nil {
+			return nil, errors.Trace(result.err)
+		}
+		e.rows = result.rows
+		e.cursor = 0
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// prepare starts a worker to fetch rows from Src, and the workers to evaluate the expressions.
+func (e *ProjectionExec) prepare() {
+	e.finished = make(chan struct{})
+	e.taskCh = make(chan *projectionTask, e.concurrency)
+	e.outputCh = make(chan *projectionTask, e.concurrency)
+	e.wg.Add(1)
+	go e.fetchInput()
+	for i := 0; i < e.concurrency; i++ {
+		// The expressions are cloned, so they can be evaluated in another goroutine.
+		exprs := make([]expression.Expression, 0, len(e.exprs))
+		for _, expr := range e.exprs {
+			exprs = append(exprs, expr.Clone())
+		}
+		e.wg.Add(1)
+		go e.runWorker(exprs)
+	}
+	e.prepared = true
+}
+
+// fetchInput reads the rows from Src in batches, and sends a task for each batch to both the workers and outputCh.
+func (e *ProjectionExec) fetchInput() {
+	defer func() {
+		close(e.taskCh)
+		close(e.outputCh)
+		e.wg.Done()
+	}()
+	for {
+		task := &projectionTask{
+			rows:   make([]*Row, 0, batchSize),
+			respCh: make(chan *execResult, 1),
+		}
+		var err error
+		for len(task.rows) < batchSize {
+			var row *Row
+			row, err = e.Src.Next()
+			if err != nil || row == nil {
+				break
+			}
+			task.rows = append(task.rows, row)
+		}
+		if err != nil {
+			task.respCh <- &execResult{err: errors.Trace(err)}
+		} else if len(task.rows) == 0 {
+			return
+		}
+		select {
+		case e.outputCh <- task:
+		case <-e.finished:
+			return
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case e.taskCh <- task:
+		case <-e.finished:
+			return
+		}
+		if len(task.rows) < batchSize {
+			return
+		}
+	}
+}
+
+func (e *ProjectionExec) runWorker(exprs []expression.Expression) {
+	defer e.wg.Done()
+	for task := range e.taskCh {
+		result := &execResult{rows: make([]*Row, 0, len(task.rows))}
+		for _, srcRow := range task.rows {
+			row, err := evalProjection(exprs, srcRow)
+			if err != nil {
+				result = &execResult{err: errors.Trace(err)}
+				break
+			}
+			result.rows = append(result.rows, row)
+		}
+		task.respCh <- result
+	}
+}
+
 // Close implements the Executor Close interface.
 func (e *ProjectionExec) Close() error {
+	if e.prepared {
+		close(e.finished)
+		e.wg.Wait()
+		e.prepared = false
+		e.rows = nil
+		e.cursor = 0
+	}
+	e.reader.reset()
+	e.srcChk = nil
 	if e.Src != nil {
 		return e.Src.Close()
 	}
@@ -479,6 +937,11 @@ type SelectionExec struct {
 	scanController bool
 	controllerInit bool
 	Conditions     []expression.Expression
+
+	// useChunk is set if the rows are read from Src in chunks and the conditions are evaluated over a chunk at once.
+	useChunk bool
+	reader   chunkRowReader
+	selected []bool
 }
 
 // Schema implements the Executor Schema interface.
@@ -500,7 +963,7 @@ func (e *SelectionExec) initController() error {
 		newConds = append(newConds, newCond)
 	}
 
-	switch x := e.Src.(type) {
+	switch x := unwrapRuntimeStats(e.Src).(type) {
 	case *XSelectTableExec:
 		accessCondition, restCondtion := plan.DetachTableScanConditions(newConds, x.tableInfo)
 		x.where, _, _ = plan.ExpressionsToPB(sc, restCondtion, client)
@@ -534,6 +997,9 @@ func (e *SelectionExec) Next() (*Row, error) {
 		}
 		e.controllerInit = true
 	}
+	if e.useChunk {
+		return e.reader.next(e)
+	}
 	for {
 		srcRow, err := e.Src.Next()
 		if err != nil {
@@ -559,11 +1025,33 @@ func (e *SelectionExec) Next() (*Row, error) {
 	}
 }
 
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *SelectionExec) NextChunk(chk *rowChunk) error {
+	for {
+		err := e.Src.(chunkExecutor).NextChunk(chk)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if chk.NumRows() == 0 {
+			return nil
+		}
+		e.selected, err = expression.VectorizedFilter(e.ctx, e.Conditions, chk.Chunk, e.selected)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		chk.filter(e.selected)
+		if chk.NumRows() > 0 {
+			return nil
+		}
+	}
+}
+
 // Close implements the Executor Close interface.
 func (e *SelectionExec) Close() error {
 	if e.scanController {
 		e.controllerInit = false
 	}
+	e.reader.reset()
 	return e.Src.Close()
 }
 
@@ -779,19 +1267,18 @@ func (e *MaxOneRowExec) Next() (*Row, error) {
 }
 
 // UnionExec represents union executor.
-// UnionExec has multiple source Executors, it executes them sequentially, and do conversion to the same type
-// as source Executors may has different field type, we need to do conversion.
+// UnionExec has multiple source Executors, it executes them concurrently, and do conversion to the same type
+// if there is any. The rows of the sources are multiplexed into resultCh by batches, so their order is not kept.
 type UnionExec struct {
 	schema   *expression.Schema
 	Srcs     []Executor
 	ctx      context.Context
 	inited   bool
-	finished atomic.Value
+	finished chan struct{}
 	resultCh chan *execResult
 	rows     []*Row
 	cursor   int
 	wg       sync.WaitGroup
-	closedCh chan struct{}
 }
 
 type execResult struct {
@@ -804,10 +1291,14 @@ func (e *UnionExec) Schema() *expression.Schema {
 	return e.schema
 }
 
-func (e *UnionExec) waitAllFinished() {
-	e.wg.Wait()
-	close(e.resultCh)
-	close(e.closedCh)
+// sendResult sends the result to the collector, it returns false if the union is closed.
+func (e *UnionExec) sendResult(result *execResult) bool {
+	select {
+	case e.resultCh <- result:
+		return true
+	case <-e.finished:
+		return false
+	}
 }
 
 func (e *UnionExec) fetchData(idx int) {
@@ -818,19 +1309,20 @@ func (e *UnionExec) fetchData(idx int) {
 			err:  nil,
 		}
 		for i := 0; i < batchSize; i++ {
-			if e.finished.Load().(bool) {
+			select {
+			case <-e.finished:
 				return
+			default:
 			}
 			row, err := e.Srcs[idx].Next()
 			if err != nil {
-				e.finished.Store(true)
 				result.err = err
-				e.resultCh <- result
+				e.sendResult(result)
 				return
 			}
 			if row == nil {
 				if len(result.rows) > 0 {
-					e.resultCh <- result
+					e.sendResult(result)
 				}
 				return
 			}
@@ -839,30 +1331,33 @@ func (e *UnionExec) fetchData(idx int) {
 				col := e.schema.Columns[j]
 				val, err := row.Data[j].ConvertTo(e.ctx.GetSessionVars().StmtCtx, col.RetType)
 				if err != nil {
-					e.finished.Store(true)
 					result.err = err
-					e.resultCh <- result
+					e.sendResult(result)
 					return
 				}
 				row.Data[j] = val
 			}
 			result.rows = append(result.rows, row)
 		}
-		e.resultCh <- result
+		if !e.sendResult(result) {
+			return
+		}
 	}
 }
 
 // Next implements the Executor Next interface.
 func (e *UnionExec) Next() (*Row, error) {
 	if !e.inited {
-		e.finished.Store(false)
+		e.finished = make(chan struct{})
 		e.resultCh = make(chan *execResult, len(e.Srcs))
-		e.closedCh = make(chan struct{})
 		for i := range e.Srcs {
 			e.wg.Add(1)
 			go e.fetchData(i)
 		}
-		go e.waitAllFinished()
+		go func() {
+			e.wg.Wait()
+			close(e.resultCh)
+		}()
 		e.inited = true
 	}
 	if e.cursor >= len(e.rows) {
@@ -873,9 +1368,6 @@ func (e *UnionExec) Next() (*Row, error) {
 		if result.err != nil {
 			return nil, errors.Trace(result.err)
 		}
-		if len(result.rows) == 0 {
-			return nil, nil
-		}
 		e.rows = result.rows
 		e.cursor = 0
 	}
@@ -886,9 +1378,11 @@ func (e *UnionExec) Next() (*Row, error) {
 
 // Close implements the Executor Close interface.
 func (e *UnionExec) Close() error {
-	e.finished.Store(true)
 	if e.inited {
-		<-e.closedCh
+		close(e.finished)
+		// Wait for the workers to exit before closing the sources they read.
+		for range e.resultCh {
+		}
 	}
 	e.cursor = 0
 	e.inited = false
@@ -902,6 +1396,68 @@ func (e *UnionExec) Close() error {
 	return nil
 }
 
+// UnionDistinctExec deduplicates the rows of a UNION DISTINCT by hashing them as they come from the union, so the
+// distinct rows are returned without waiting for all the branches to finish, which an aggregation has to.
+type UnionDistinctExec struct {
+	Src    Executor
+	schema *expression.Schema
+	// keyOffsets are the offsets of the columns of Src the rows are deduplicated by, outputOffsets are the offsets of
+	// the columns returned.
+	keyOffsets    []int
+	outputOffsets []int
+	seen          map[string]struct{}
+	keyBuf        []byte
+	keyData       []types.Datum
+
+	// memTracker tracks the memory usage of the keys of the returned rows.
+	memTracker *memory.Tracker
+}
+
+// Schema implements the Executor Schema interface.
+func (e *UnionDistinctExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *UnionDistinctExec) Next() (*Row, error) {
+	if e.seen == nil {
+		e.seen = make(map[string]struct{})
+	}
+	for {
+		row, err := e.Src.Next()
+		if err != nil || row == nil {
+			return nil, errors.Trace(err)
+		}
+		e.keyData = e.keyData[:0]
+		for _, offset := range e.keyOffsets {
+			e.keyData = append(e.keyData, row.Data[offset])
+		}
+		e.keyBuf, err = codec.EncodeValue(e.keyBuf[:0], e.keyData...)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, ok := e.seen[string(e.keyBuf)]; ok {
+			continue
+		}
+		e.seen[string(e.keyBuf)] = struct{}{}
+		if err = e.memTracker.Consume(int64(len(e.keyBuf))); err != nil {
+			return nil, errors.Trace(err)
+		}
+		data := make([]types.Datum, 0, len(e.outputOffsets))
+		for _, offset := range e.outputOffsets {
+			data = append(data, row.Data[offset])
+		}
+		return &Row{Data: data, RowKeys: row.RowKeys}, nil
+	}
+}
+
+// Close implements the Executor Close interface.
+func (e *UnionDistinctExec) Close() error {
+	e.seen = nil
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return errors.Trace(e.Src.Close())
+}
+
 // DummyScanExec returns zero results, when some where condition never match, there won't be any
 // rows to return, so DummyScan is used to avoid real scan on KV.
 type DummyScanExec struct {
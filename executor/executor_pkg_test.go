@@ -14,8 +14,12 @@
 package executor
 
 import (
+	"math"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/tablecodec"
 )
 
@@ -61,3 +65,29 @@ func (s *testExecSuite) TestMergeHandles(c *C) {
 		c.Assert(kr.EndKey, DeepEquals, ekr.EndKey)
 	}
 }
+
+func (s *testExecSuite) TestStmtPriority(c *C) {
+	tests := []struct {
+		sql      string
+		priority int
+	}{
+		{"select * from t", kv.PriorityNormal},
+		{"select high_priority * from t", kv.PriorityHigh},
+		{"select low_priority * from t", kv.PriorityLow},
+		{"insert high_priority into t values (1)", kv.PriorityHigh},
+		{"insert delayed into t values (1)", kv.PriorityNormal},
+		{"replace low_priority into t values (1)", kv.PriorityLow},
+		{"update low_priority t set a = 1", kv.PriorityLow},
+		{"delete low_priority from t", kv.PriorityLow},
+		{"delete from t", kv.PriorityNormal},
+	}
+	for _, t := range tests {
+		stmt, err := parser.New().ParseOneStmt(t.sql, "", "")
+		c.Assert(err, IsNil)
+		c.Assert(stmtPriority(stmt), Equals, t.priority, Commentf("sql: %s", t.sql))
+	}
+
+	c.Assert(isFullTableRange([]plan.TableRange{{LowVal: math.MinInt64, HighVal: math.MaxInt64}}), IsTrue)
+	c.Assert(isFullTableRange([]plan.TableRange{{LowVal: 1, HighVal: math.MaxInt64}}), IsFalse)
+	c.Assert(isFullTableRange([]plan.TableRange{{LowVal: math.MinInt64, HighVal: 0}, {LowVal: 2, HighVal: math.MaxInt64}}), IsFalse)
+}
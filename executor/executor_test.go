@@ -26,14 +26,18 @@ import (
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/tikv"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -121,6 +125,32 @@ func (s *testSuite) TestAdmin(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(row, IsNil)
 
+	// The finished DDL jobs are shown in the descending order of job IDs.
+	r, err = tk.Exec("admin show ddl jobs")
+	c.Assert(err, IsNil)
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row.Data, HasLen, 9)
+	historyJobs, err := inspectkv.GetHistoryDDLJobs(txn, 1)
+	c.Assert(err, IsNil)
+	c.Assert(historyJobs, HasLen, 1)
+	c.Assert(row.Data[0].GetInt64(), Equals, historyJobs[0].ID)
+	c.Assert(row.Data[1].GetString(), Equals, "test")
+	c.Assert(row.Data[2].GetString(), Equals, "admin_test")
+	c.Assert(row.Data[3].GetString(), Equals, model.ActionCreateTable.String())
+	c.Assert(row.Data[8].GetString(), Equals, model.JobDone.String())
+
+	// The finished DDL job isn't in the job queues.
+	r, err = tk.Exec(fmt.Sprintf("admin cancel ddl jobs %d", historyJobs[0].ID))
+	c.Assert(err, IsNil)
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row.Data[0].GetInt64(), Equals, historyJobs[0].ID)
+	c.Assert(row.Data[1].GetString(), Equals, inspectkv.ErrDDLJobNotFound.GenByArgs(historyJobs[0].ID).Error())
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, IsNil)
+
 	// check table test
 	tk.MustExec("create table admin_test1 (c1 int, c2 int default 1, index (c1))")
 	tk.MustExec("insert admin_test1 (c1) values (21),(22)")
@@ -146,6 +176,129 @@ func (s *testSuite) TestAdmin(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *testSuite) TestAdminCheckTableNegativeHandle(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists admin_test")
+	tk.MustExec("create table admin_test (c1 int primary key, c2 int, index (c2))")
+	tk.MustExec("insert admin_test values (-10, -10), (-1, -1), (1, 1)")
+	tk.MustExec("use mysql")
+	tk.MustExec("admin check table test.admin_test")
+
+	// the record of a negative handle without the index entry
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	is := sessionctx.GetDomain(tk.Se.(context.Context)).InfoSchema()
+	tb, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("admin_test"))
+	c.Assert(err, IsNil)
+	err = tb.Indices()[0].Delete(txn, types.MakeDatums(int64(-10)), -10)
+	c.Assert(err, IsNil)
+	c.Assert(txn.Commit(), IsNil)
+	_, err = tk.Exec("admin check table test.admin_test")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*admin_test index c2 err:.*")
+}
+
+func (s *testSuite) TestAdminChecksumTable(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2, t3")
+	tk.MustExec("create table t1 (a int primary key, b varchar(10), index (b))")
+	tk.MustExec("create table t2 (a int primary key, b varchar(10), index (b))")
+	tk.MustExec("create table t3 (a int primary key, b varchar(10), index (b))")
+	for _, t := range []string{"t1", "t2"} {
+		tk.MustExec(fmt.Sprintf("insert %s values (1, 'a'), (2, 'b'), (3, 'c')", t))
+	}
+	rows := tk.MustQuery("admin checksum table t1, test.t2, t3").Rows()
+	c.Assert(rows, HasLen, 3)
+	c.Assert(rows[0][0], Equals, "test")
+	c.Assert(rows[0][1], Equals, "t1")
+	c.Assert(rows[1][1], Equals, "t2")
+	// The tables have different IDs but the same data.
+	c.Assert(rows[0][2:], DeepEquals, rows[1][2:])
+	c.Assert(rows[0][3], Equals, uint64(6))
+	c.Assert(rows[2][2:], DeepEquals, []interface{}{uint64(0), uint64(0), uint64(0)})
+
+	tk.MustExec("update t2 set b = 'd' where a = 3")
+	result := tk.MustQuery("admin checksum table t2")
+	c.Assert(result.Rows()[0][2], Not(Equals), rows[1][2])
+	c.Assert(result.Rows()[0][3], Equals, uint64(6))
+
+	// The checksum is read from the snapshot of the transaction, the uncommitted changes are not included.
+	tk.MustExec("begin")
+	tk.MustExec("update t2 set b = 'c' where a = 3")
+	c.Assert(tk.MustQuery("admin checksum table t2").Rows(), DeepEquals, result.Rows())
+	tk.MustExec("commit")
+	c.Assert(tk.MustQuery("admin checksum table t2").Rows(), Not(DeepEquals), result.Rows())
+
+	_, err := tk.Exec("admin checksum table t_not_exists")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestIndexAdvisor(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int primary key, b int, c int, d int, index (d))")
+	tk.MustExec("create table t2 (a int, b int)")
+
+	// The statements are not recorded until the index advisor is enabled.
+	tk.MustQuery("select * from t1 where b = 1")
+	tk.MustQuery("admin show index advice").Check(testkit.Rows())
+
+	tk.MustExec("set @@tidb_enable_index_advisor = 1")
+	tk.MustQuery("select * from t1 where b = 1 and c > 10")
+	tk.MustQuery("select * from t1 where b = 2 and c > 20")
+	tk.MustQuery("select * from t1 where b = 3 and c > 30")
+	tk.MustQuery("select * from t2 where b in (1, 2)")
+	// The primary key, the existing index and the predicates without constants don't need a new index.
+	tk.MustQuery("select * from t1 where a = 1")
+	tk.MustQuery("select * from t1 where d = 1")
+	tk.MustQuery("select * from t1 where b = c")
+	tk.MustExec("delete from t2 where a > 1")
+
+	rows := tk.MustQuery("admin show index advice").Rows()
+	c.Assert(rows, HasLen, 3)
+	byColumns := make(map[string][]interface{})
+	for _, row := range rows {
+		c.Assert(row[0], Equals, "test")
+		c.Assert(row[5].(float64), Greater, float64(0))
+		byColumns[row[1].(string)+"("+row[2].(string)+")"] = row
+	}
+	c.Assert(byColumns["t1(b,c)"][3:5], DeepEquals, []interface{}{int64(1), int64(3)})
+	c.Assert(byColumns["t2(b)"][3:5], DeepEquals, []interface{}{int64(1), int64(1)})
+	c.Assert(byColumns["t2(a)"][3:5], DeepEquals, []interface{}{int64(1), int64(1)})
+	// The statements executed three times are the most important.
+	c.Assert(rows[0][1:3], DeepEquals, []interface{}{"t1", "b,c"})
+
+	// The recommended index is no longer recommended after it's created.
+	tk.MustExec("set @@tidb_enable_index_advisor = 0")
+	tk.MustExec("create index idx_b_c on t1 (b, c)")
+	tk.MustExec("use mysql")
+	rows = tk.MustQuery("admin show index advice").Rows()
+	c.Assert(rows, HasLen, 2)
+	for _, row := range rows {
+		c.Assert(row[1], Equals, "t2")
+	}
+	c.Assert(tk.Se.GetSessionVars().CurrentDB, Equals, "mysql")
+
+	// The statements of a dropped table are skipped.
+	tk.MustExec("drop table test.t2")
+	tk.MustQuery("admin show index advice").Check(testkit.Rows())
+}
+
 func (s *testSuite) fillData(tk *testkit.TestKit, table string) {
 	tk.MustExec("use test")
 	tk.MustExec(fmt.Sprintf("create table %s(id int not null default 1, name varchar(255), PRIMARY KEY(id));", table))
@@ -273,6 +426,21 @@ func (s *testSuite) TestDAG(c *C) {
 
 	r = tk.MustQuery("select * from select_dag where id > 1;")
 	r.Check(testkit.Rows(rowStr2, rowStr3))
+
+	// The aggregation, TopN, limit and desc scan are pushed down as the executors of the DAG request.
+	tk.MustExec("create table select_dag2(a int primary key, b int, c int, index idx_b(b))")
+	tk.MustExec("insert select_dag2 values (1, 1, 1), (2, 2, 1), (3, 2, 2), (4, 3, 2), (5, null, 3)")
+	tk.MustQuery("select count(*), sum(b), max(b), min(b), avg(b) from select_dag2").Check(
+		testkit.Rows("5 8 3 1 2.0000"))
+	tk.MustQuery("select c, count(b), sum(a) from select_dag2 where a > 1 group by c order by c").Check(
+		testkit.Rows("1 1 2", "2 2 7", "3 0 5"))
+	tk.MustQuery("select count(*) from select_dag2 where b > 5").Check(testkit.Rows("0"))
+	tk.MustQuery("select a from select_dag2 order by c desc, a limit 3").Check(testkit.Rows("5", "3", "4"))
+	tk.MustQuery("select a from select_dag2 where b > 1 limit 2").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select a from select_dag2 order by a desc limit 2").Check(testkit.Rows("5", "4"))
+	tk.MustQuery("select b from select_dag2 use index(idx_b) where b > 1 order by b desc limit 2").Check(
+		testkit.Rows("3", "2"))
+	tk.MustQuery("select count(b), max(b) from select_dag2 use index(idx_b) where b > 1").Check(testkit.Rows("3 3"))
 }
 
 func (s *testSuite) TestSelectOrderBy(c *C) {
@@ -367,6 +535,330 @@ func (s *testSuite) TestSelectOrderBy(c *C) {
 	r.Check(testkit.Rows("2"))
 }
 
+func (s *testSuite) TestSortSpill(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(10), c datetime(3), d decimal(6,2), e time(2), f enum('x','y','z'), g float, h bit(4), i bigint unsigned)")
+	for i := 0; i < 40; i++ {
+		if i%9 == 0 {
+			tk.MustExec(fmt.Sprintf("insert t values (%d, null, null, null, null, null, null, null, null)", i))
+			continue
+		}
+		tk.MustExec(fmt.Sprintf("insert t values (%d, 'str%d', '2017-09-%02d 10:11:12.%03d', %d.%02d, '10:%02d:00.%02d', %d, %d.5, %d, %d)",
+			i, i%7, i%5+1, i, i%11, i, i%13, i, i%3+1, i%6, i%16, uint64(1<<63)+uint64(i%4)))
+	}
+	queries := []string{
+		"select * from t order by b desc, a",
+		"select * from t order by c, a desc",
+		"select * from t order by d + a, a",
+		"select * from t order by e, f, g, a",
+		"select * from t order by i, h, a",
+	}
+	var expected [][][]interface{}
+	for _, q := range queries {
+		expected = append(expected, tk.MustQuery(q).Rows())
+	}
+	tk.MustExec("set @@tidb_max_sort_rows_in_memory = 4")
+	for i, q := range queries {
+		tk.MustQuery(q).Check(expected[i])
+	}
+	tk.MustQuery("select a, c from t where a > 30 order by c desc, a").Check(testkit.Rows(
+		"39 2017-09-05 10:11:12.039",
+		"34 2017-09-05 10:11:12.034",
+		"38 2017-09-04 10:11:12.038",
+		"33 2017-09-04 10:11:12.033",
+		"37 2017-09-03 10:11:12.037",
+		"32 2017-09-03 10:11:12.032",
+		"31 2017-09-02 10:11:12.031",
+		"35 2017-09-01 10:11:12.035",
+		"36 <nil>",
+	))
+
+	// The row keys of the spilled rows are kept for the delete executor.
+	tk.MustExec("delete from t where a >= 10 order by b, a desc")
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("10"))
+}
+
+func (s *testSuite) TestTopN(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int)")
+	for i := 0; i < 20; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d)", i, i%6))
+	}
+	queries := []string{
+		"select * from t order by b desc, a limit 5",
+		"select * from t order by b, a desc limit 3, 4",
+		"select a from t order by a + b, a limit 2, 30",
+		"select a from t order by b limit 0",
+	}
+	tk.MustQuery(queries[0]).Check(testkit.Rows("5 5", "11 5", "17 5", "4 4", "10 4"))
+	tk.MustQuery(queries[1]).Check(testkit.Rows("0 0", "19 1", "13 1", "7 1"))
+	tk.MustQuery(queries[3]).Check(testkit.Rows())
+	var expected [][][]interface{}
+	for _, q := range queries {
+		expected = append(expected, tk.MustQuery(q).Rows())
+	}
+	// The limits exceed the rows sorted in memory, so the rows are sorted by a sort which can spill, then limited.
+	tk.MustExec("set @@tidb_max_sort_rows_in_memory = 3")
+	for i, q := range queries {
+		tk.MustQuery(q).Check(expected[i])
+	}
+	tk.MustQuery("select a from t order by a desc limit 18446744073709551615").Check(tk.MustQuery("select a from t order by a desc").Rows())
+}
+
+func (s *testSuite) TestParallelProjection(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b varchar(20))")
+	values := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		values = append(values, fmt.Sprintf("(%d, 'x%d')", i, i*7))
+	}
+	tk.MustExec("insert t values " + strings.Join(values, ","))
+	queries := []string{
+		"select a, b regexp '^x1.*5$', b like '%3' from t",
+		"select a + 1, concat(b, 'y') regexp '9y$' from t where a > 500",
+		"select a, b from t where b regexp '^x[12]' order by a desc",
+	}
+	tk.MustExec("set @@tidb_projection_concurrency=1")
+	var expected [][][]interface{}
+	for _, query := range queries {
+		expected = append(expected, tk.MustQuery(query).Rows())
+	}
+	tk.MustExec("set @@tidb_projection_concurrency=4")
+	for i, query := range queries {
+		// The order of the rows is kept.
+		tk.MustQuery(query).Check(expected[i])
+	}
+	c.Assert(expected[0], HasLen, 1000)
+	c.Assert(fmt.Sprintf("%v", expected[0][15]), Equals, "[15 1 0]")
+	c.Assert(fmt.Sprintf("%v", expected[0][999]), Equals, "[999 0 1]")
+	projectionInfo := func(sql string) string {
+		for _, row := range tk.MustQuery("explain analyze " + sql).Rows() {
+			if strings.HasPrefix(row[0].(string), "Projection") {
+				return row[3].(string)
+			}
+		}
+		return ""
+	}
+	c.Assert(projectionInfo(queries[0]), Matches, "time:.*, loops:1001, rows:1000, concurrency:4")
+	// The projection without expensive functions is evaluated serially.
+	c.Assert(projectionInfo("select a + 1 from t"), Matches, "time:.*, loops:1001, rows:1000")
+
+	// The executor is closed before all the rows are returned.
+	tk.MustQuery("select b regexp 'x' from t limit 2").Check(testkit.Rows("1", "1"))
+	// The error of a worker is returned.
+	rs, err := tk.Exec("select b regexp '(' from t")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+	c.Assert(rs.Close(), IsNil)
+}
+
+func (s *testSuite) TestChunkExecution(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int)")
+	values := make([]string, 0, 3000)
+	for i := 0; i < 3000; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i%10))
+	}
+	tk.MustExec("insert t values " + strings.Join(values, ","))
+
+	// The rows are read in chunks by the table reader, the selection and the projection, abs isn't pushed down, so
+	// the conditions with it are evaluated by the selection.
+	tk.MustQuery("select count(*), sum(c) from (select a, b + 1 c, 'x' from t where abs(b - 3) = 0 and a > 5) k").Check(testkit.Rows("299 1196"))
+	tk.MustQuery("select a, b * 2 from t where b > 8 and abs(a) < 50").Check(testkit.Rows("9 18", "19 18", "29 18", "39 18", "49 18"))
+	tk.MustQuery("select a from t where a > 2995").Check(testkit.Rows("2996", "2997", "2998", "2999"))
+	tk.MustQuery("select a from t where abs(b) = 7 limit 2").Check(testkit.Rows("7", "17"))
+	tk.MustQuery("select a from t where abs(b) = 100").Check(testkit.Rows())
+	// The order sensitive functions are evaluated row by row, so the rows after the limit aren't evaluated.
+	tk.MustExec("set @n = 0")
+	tk.MustQuery("select @n := @n + 1 from t where abs(b) = 1 limit 3").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select @n").Check(testkit.Rows("3"))
+	// The row keys are kept in the chunks.
+	tk.MustExec("update t set b = 100 where abs(b) = 5 and a < 2000")
+	tk.CheckExecResult(200, 0)
+	tk.MustExec("delete from t where b = 100")
+	tk.CheckExecResult(200, 0)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("2800"))
+}
+
+func (s *testSuite) TestWindowFunction(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c int)")
+	tk.MustExec("insert t values (1, 1, 10), (2, 1, 20), (3, 1, 20), (4, 2, 5), (5, 2, null), (6, 3, 7)")
+
+	result := tk.MustQuery("select a, row_number() over (partition by b order by a), rank() over (partition by b order by c), " +
+		"dense_rank() over (partition by b order by c) from t order by a")
+	result.Check(testkit.Rows("1 1 1 1", "2 2 2 2", "3 3 2 2", "4 1 2 2", "5 2 1 1", "6 1 1 1"))
+	result = tk.MustQuery("select a, lag(c) over (order by a), lead(c, 2, -1) over (order by a) from t order by a")
+	result.Check(testkit.Rows("1 <nil> 20", "2 10 5", "3 20 <nil>", "4 20 7", "5 5 -1", "6 <nil> -1"))
+	result = tk.MustQuery("select a, sum(c) over (partition by b), count(c) over (partition by b order by a), " +
+		"sum(a) over (order by a rows between 1 preceding and 1 following) from t order by a")
+	result.Check(testkit.Rows("1 50 1 3", "2 50 2 6", "3 50 3 9", "4 5 1 12", "5 5 1 15", "6 7 1 11"))
+	// The peers of the current row are in the default frame with order by.
+	result = tk.MustQuery("select a, sum(a) over (order by c) from t order by a")
+	result.Check(testkit.Rows("1 16", "2 21", "3 21", "4 9", "5 5", "6 15"))
+	result = tk.MustQuery("select b, sum(a), rank() over (order by sum(a) desc) from t group by b order by b")
+	result.Check(testkit.Rows("1 6 2", "2 9 1", "3 6 2"))
+	result = tk.MustQuery("select a, row_number() over (order by a desc) as rn from t order by rn limit 2")
+	result.Check(testkit.Rows("6 1", "5 2"))
+	result = tk.MustQuery("select * from (select a, row_number() over (partition by b order by a) rn from t) x where rn = 1 order by a")
+	result.Check(testkit.Rows("1 1", "4 1", "6 1"))
+	result = tk.MustQuery("select a, max(c) over (rows between current row and unbounded following) from (select * from t order by a) x order by a")
+	result.Check(testkit.Rows("1 20", "2 20", "3 20", "4 7", "5 7", "6 7"))
+
+	_, err := tk.Exec("select a from t where row_number() over () > 1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidWindowFuncUse), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("select a from t order by row_number() over ()")
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidWindowFuncUse), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("select sum(row_number() over ()) from t")
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidWindowFuncUse), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("select lag(a, -1) over () from t")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWindowFuncArguments), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("select lead(a, b) over () from t")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWindowFuncArguments), IsTrue, Commentf("err %v", err))
+}
+
+func (s *testSuite) TestApply(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("create table s (a int, b int)")
+	tk.MustExec("insert t values (1, 1), (2, 1), (3, 2), (4, 2), (5, 3), (6, null)")
+	tk.MustExec("insert s values (1, 1), (2, 1), (3, 2), (4, 4)")
+	queries := []string{
+		"select a, (select count(1) k from s where s.b = t.b having k != 0) from t order by a",
+		"select a from t where exists (select a from s where s.b = t.b and s.a > t.a) order by a",
+		"select a, (select max(a) from s where s.a < t.a and s.b = t.b) from t order by a",
+	}
+	expected := [][]string{
+		{"1 2", "2 2", "3 1", "4 1", "5 <nil>", "6 <nil>"},
+		{"1"},
+		{"1 <nil>", "2 1", "3 <nil>", "4 3", "5 <nil>", "6 <nil>"},
+	}
+	for i, q := range queries {
+		tk.MustQuery(q).Check(testkit.Rows(expected[i]...))
+	}
+	// The outer rows are fetched in parallel.
+	tk.MustExec("set @@tidb_enable_parallel_apply = 1")
+	for i, q := range queries {
+		tk.MustQuery(q).Check(testkit.Rows(expected[i]...))
+	}
+	// Close the executor before reading all the rows.
+	rss, err := tk.Se.Execute(queries[0])
+	c.Assert(err, IsNil)
+	_, err = rss[0].Next()
+	c.Assert(err, IsNil)
+	c.Assert(rss[0].Close(), IsNil)
+
+	// The inner plan is executed by the workers, the order of the outer rows is kept.
+	for i := 7; i < 300; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d)", i, i%13))
+		tk.MustExec(fmt.Sprintf("insert s values (%d, %d)", i, i%11))
+	}
+	queries = append(queries, "select a, (select count(*) from s where s.a < t.a and s.b = t.b) from t")
+	tk.MustExec("set @@tidb_enable_parallel_apply = 0")
+	var allRows [][][]interface{}
+	for _, q := range queries {
+		allRows = append(allRows, tk.MustQuery(q).Rows())
+	}
+	tk.MustExec("set @@tidb_enable_parallel_apply = 1")
+	for _, concurrency := range []int{1, 4} {
+		tk.MustExec(fmt.Sprintf("set @@tidb_apply_concurrency = %d", concurrency))
+		for i, q := range queries {
+			tk.MustQuery(q).Check(allRows[i])
+		}
+	}
+	// The inner rows are not cached when the memory quota is exceeded, the statement is not canceled.
+	tk.MustExec("set @@tidb_mem_quota_query = 1000")
+	tk.MustQuery(queries[3]).Check(allRows[3])
+	tk.MustExec("set @@tidb_enable_parallel_apply = 0")
+	tk.MustQuery(queries[3]).Check(allRows[3])
+}
+
+func (s *testSuite) TestMemQuota(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, t1")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("create table t1 (a int, b int)")
+	for i := 0; i < 50; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, %d)", i, i%7))
+		tk.MustExec(fmt.Sprintf("insert t1 values (%d, %d)", i%10, i))
+	}
+	queries := []string{
+		"select * from t order by b, a",
+		"select * from t order by b desc, a limit 3, 10",
+		"select b, count(*), sum(a) from t group by b order by b",
+		"select t.a, t1.b from t join t1 on t.a = t1.a order by t.a, t1.b",
+		"select a from t where a in (select b from t1) order by a",
+	}
+	var expected [][][]interface{}
+	for _, q := range queries {
+		expected = append(expected, tk.MustQuery(q).Rows())
+	}
+
+	tk.MustExec("set @@tidb_mem_quota_query = 100")
+	tk.MustQuery("select @@tidb_mem_oom_action").Check(testkit.Rows("cancel"))
+	for _, q := range queries {
+		rs, err := tk.Exec(q)
+		c.Assert(err, IsNil)
+		_, err = tidb.GetRows(rs)
+		c.Assert(terror.ErrorEqual(err, memory.ErrMemExceed), IsTrue, Commentf("%s", q))
+	}
+	// A statement which keeps no rows in memory is not canceled.
+	tk.MustQuery("select count(*) from t where a > 10").Check(testkit.Rows("39"))
+
+	tk.MustExec("set @@tidb_mem_oom_action = 'spill'")
+	for i, q := range queries {
+		tk.MustQuery(q).Check(expected[i])
+	}
+	tk.MustExec("set @@tidb_mem_oom_action = 'log'")
+	for i, q := range queries {
+		tk.MustQuery(q).Check(expected[i])
+	}
+	_, err := tk.Exec("set @@tidb_mem_oom_action = 'abort'")
+	c.Assert(err, NotNil)
+}
+
 func (s *testSuite) TestSelectErrorRow(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -490,7 +982,7 @@ func (s *testSuite) TestUnion(c *C) {
 
 	testSQL = `select id from union_test union select id from union_test;`
 	tk.MustExec("begin")
-	r := tk.MustQuery(testSQL)
+	r := tk.MustQuery(testSQL).Sort()
 	r.Check(testkit.Rows("1", "2"))
 
 	testSQL = `select * from (select id from union_test union select id from union_test) t order by id;`
@@ -579,6 +1071,25 @@ func (s *testSuite) TestUnion(c *C) {
 	tk.MustExec("CREATE TABLE t (a int, b int)")
 	tk.MustExec("INSERT INTO t VALUES ('1', '1')")
 	r = tk.MustQuery("select b from (SELECT * FROM t UNION ALL SELECT a, b FROM t order by a) t")
+
+	// The branches are closed while they still have rows to send.
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int)")
+	values := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i%10))
+	}
+	tk.MustExec("insert into t values " + strings.Join(values, ","))
+	rs, err := tk.Exec("select a from t union all select a from t union all select a from t")
+	c.Assert(err, IsNil)
+	row, err := rs.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, NotNil)
+	c.Assert(rs.Close(), IsNil)
+	r = tk.MustQuery("select count(*) from (select b from t union select a from t where a < 20 union select b + 1 from t) x")
+	r.Check(testkit.Rows("20"))
+	r = tk.MustQuery("select a, b from t where a < 3 union select b, b from t where b < 2 order by a, b").Sort()
+	r.Check(testkit.Rows("0 0", "1 1", "2 2"))
 }
 
 func (s *testSuite) TestIn(c *C) {
@@ -1190,6 +1701,48 @@ func (s *testSuite) TestPointGet(c *C) {
 
 }
 
+func (s *testSuite) TestBatchPointGet(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int, unique key idx_b(b), unique key idx_bc(b, c))")
+	tk.MustExec("insert t values (1, 10, 100), (2, 20, 200), (3, 30, 300), (4, 40, 400)")
+
+	tk.MustQuery("select * from t where a in (3, 1, 5)").Check(testkit.Rows("1 10 100", "3 30 300"))
+	tk.MustQuery("select * from t where a in (3, 1, 5) order by a desc").Check(testkit.Rows("3 30 300", "1 10 100"))
+	tk.MustQuery("select a from t where a in (1, 2, 3) limit 2").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select * from t where a = 5").Check(testkit.Rows())
+	tk.MustQuery("select * from t where b in (40, 20, 50)").Check(testkit.Rows("2 20 200", "4 40 400"))
+	tk.MustQuery("select a from t where b in (40, 20) order by b desc").Check(testkit.Rows("4", "2"))
+	tk.MustQuery("select a from t where b = 20.5").Check(testkit.Rows())
+	tk.MustQuery("select a from t where (b, c) in ((10, 100), (30, 900))").Check(testkit.Rows("1"))
+
+	// The uncommitted changes of the transaction are seen.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (5, 50, 500)")
+	tk.MustExec("update t set b = 11 where a = 1")
+	tk.MustExec("delete from t where a = 2")
+	tk.MustQuery("select * from t where a in (1, 2, 5)").Check(testkit.Rows("1 11 100", "5 50 500"))
+	tk.MustQuery("select a from t where b in (10, 11, 20, 50)").Check(testkit.Rows("1", "5"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select * from t where a in (1, 2, 5)").Check(testkit.Rows("1 10 100", "2 20 200"))
+
+	// The rows are read from the snapshot of the history read.
+	time.Sleep(time.Millisecond)
+	snapshotTime := time.Now()
+	time.Sleep(time.Millisecond)
+	tk.MustExec("delete from t where a = 1")
+	tk.MustExec("set @@tidb_snapshot = '" + snapshotTime.Format("2006-01-02 15:04:05.999999") + "'")
+	tk.MustQuery("select a from t where a in (1, 2)").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select a from t where b in (10, 20)").Check(testkit.Rows("1", "2"))
+	tk.MustExec("set @@tidb_snapshot = ''")
+	tk.MustQuery("select a from t where a in (1, 2)").Check(testkit.Rows("2"))
+}
+
 func (s *testSuite) TestRow(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -1309,6 +1862,112 @@ func (s *testSuite) TestHistoryRead(c *C) {
 	tk.MustQuery("select * from history_read order by a").Check(testkit.Rows("2", "4"))
 	tk.MustExec("set @@tidb_snapshot = ''")
 	tk.MustQuery("select * from history_read order by a").Check(testkit.Rows("2 <nil>", "4 <nil>", "8 8", "9 9"))
+
+	// The snapshot in the future or older than the GC safe point can't be read, the previous snapshot is kept.
+	safePoint := snapshotTime.Add(-time.Millisecond).Format("20060102-15:04:05 -0700 MST")
+	tk.MustExec(fmt.Sprintf("insert mysql.tidb values ('tikv_gc_safe_point', '%s', '')", safePoint))
+	snapshot := snapshotTime.Format("2006-01-02 15:04:05.999999")
+	tooOld := snapshotTime.Add(-time.Second).Format("2006-01-02 15:04:05")
+	tk.MustExec("set @@tidb_snapshot = '" + snapshot + "'")
+	_, err = tk.Exec("set @@tidb_snapshot = '" + time.Now().Add(time.Hour).Format("2006-01-02 15:04:05") + "'")
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue, Commentf("err: %v", err))
+	_, err = tk.Exec("set @@tidb_snapshot = '" + tooOld + "'")
+	c.Assert(executor.ErrSnapshotTooOld.Equal(err), IsTrue, Commentf("err: %v", err))
+	tk.MustQuery("select @@tidb_snapshot").Check(testkit.Rows(snapshot))
+	tk.MustQuery("select * from history_read order by a").Check(testkit.Rows("2", "4"))
+	tk.MustExec("set @@tidb_snapshot = ''")
+	_, err = tk.Exec("select * from history_read as of timestamp '" + tooOld + "'")
+	c.Assert(executor.ErrSnapshotTooOld.Equal(err), IsTrue, Commentf("err: %v", err))
+	tk.MustExec("delete from mysql.tidb where variable_name = 'tikv_gc_safe_point'")
+}
+
+func (s *testSuite) TestAsOfRead(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists as_of, as_of2")
+	tk.MustExec("create table as_of (a int primary key, b int)")
+	tk.MustExec("create table as_of2 (a int)")
+	tk.MustExec("insert as_of values (1, 1), (2, 2)")
+	tk.MustExec("insert as_of2 values (1)")
+	time.Sleep(time.Millisecond)
+	snapshot := time.Now()
+	snapshotTime := snapshot.Format("2006-01-02 15:04:05.999999")
+	time.Sleep(time.Millisecond)
+	tk.MustExec("insert as_of values (3, 3)")
+	tk.MustExec("delete from as_of2")
+	tk.MustExec("alter table as_of add column c int")
+
+	asOf := " as of timestamp '" + snapshotTime + "'"
+	tk.MustQuery("select * from as_of" + asOf).Check(testkit.Rows("1 1", "2 2"))
+	tk.MustQuery("select * from as_of" + asOf + " where a in (1, 3)").Check(testkit.Rows("1 1"))
+	tk.MustQuery("select count(*) from as_of t1" + asOf + " join as_of2 t2" + asOf + " on t1.a = t2.a").Check(
+		testkit.Rows("1"))
+	tk.MustQuery("select * from as_of" + asOf + " where a in (select a from as_of2" + asOf + ")").Check(
+		testkit.Rows("1 1"))
+	tk.MustQuery("select * from as_of").Check(testkit.Rows("1 1 <nil>", "2 2 <nil>", "3 3 <nil>"))
+	// The table didn't exist at the timestamp.
+	_, err := tk.Exec("select * from as_of as of timestamp date_sub(now(), interval 1 hour)")
+	c.Assert(terror.ErrorEqual(err, infoschema.ErrTableNotExists), IsTrue, Commentf("err: %v", err))
+	tk.MustExec("prepare stmt from 'select * from as_of as of timestamp ?'")
+	tk.MustExec("set @ts = '" + snapshotTime + "'")
+	tk.MustQuery("execute stmt using @ts").Check(testkit.Rows("1 1", "2 2"))
+	// The timestamp is in the session time zone, use a zone other than the local one.
+	tz, offset := "+07:00", 7*3600
+	if _, localOffset := snapshot.Zone(); localOffset == offset {
+		tz, offset = "-07:00", -7*3600
+	}
+	tk.MustExec("set @@time_zone = '" + tz + "'")
+	tzAsOf := " as of timestamp '" + snapshot.In(time.FixedZone("", offset)).Format("2006-01-02 15:04:05.999999") + "'"
+	tk.MustQuery("select * from as_of" + tzAsOf).Check(testkit.Rows("1 1", "2 2"))
+	tk.MustExec("set @@time_zone = 'SYSTEM'")
+
+	// The invalid statements.
+	invalidSQLs := []string{
+		"select * from as_of" + asOf + ", as_of2",
+		"select * from as_of" + asOf + ", as_of2 as of timestamp date_sub(now(), interval 1 hour)",
+		"select * from as_of" + asOf + " for update",
+		"insert as_of2 select a from as_of" + asOf,
+		"select * from as_of as of timestamp date_add(now(), interval 1 hour)",
+		"select * from as_of as of timestamp a",
+		"select * from as_of as of timestamp null",
+	}
+	for _, sql := range invalidSQLs {
+		_, err := tk.Exec(sql)
+		c.Assert(terror.ErrorEqual(err, plan.ErrAsOf), IsTrue, Commentf("sql: %s, err: %v", sql, err))
+	}
+	tk.MustExec("begin")
+	_, err = tk.Exec("select * from as_of" + asOf)
+	c.Assert(terror.ErrorEqual(err, plan.ErrAsOf), IsTrue)
+	tk.MustExec("rollback")
+}
+
+func (s *testSuite) TestPriority(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t(a int primary key, b int, index idx_b(b))")
+	tk.MustExec("insert high_priority into t values (1, 1), (2, 2)")
+	tk.MustExec("insert delayed into t values (3, 3)")
+	tk.MustExec("update low_priority t set b = b + 1 where a = 3")
+	tk.MustExec("delete low_priority from t where a = 2")
+	tk.MustQuery("select high_priority * from t").Check(testkit.Rows("1 1", "3 4"))
+	c.Assert(tk.Se.GetSessionVars().StmtCtx.Priority, Equals, kv.PriorityHigh)
+	tk.MustQuery("select low_priority b from t where b > 1").Check(testkit.Rows("4"))
+	c.Assert(tk.Se.GetSessionVars().StmtCtx.Priority, Equals, kv.PriorityLow)
+	tk.MustQuery("select distinct low_priority count(*) from t").Check(testkit.Rows("2"))
+
+	tk.MustExec("prepare stmt from 'select high_priority a from t where a > ?'")
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("3"))
+	c.Assert(tk.Se.GetSessionVars().StmtCtx.Priority, Equals, kv.PriorityHigh)
 }
 
 func (s *testSuite) TestScanControlSelection(c *C) {
@@ -1323,3 +1982,388 @@ func (s *testSuite) TestScanControlSelection(c *C) {
 	tk.MustExec("insert into t values (1, 1, 1), (2, 1, 1), (3, 1, 2), (4, 2, 3)")
 	tk.MustQuery("select (select count(1) k from t s where s.b = t1.c) from t t1").Check(testkit.Rows("3", "3", "1", "0"))
 }
+
+func (s *testSuite) TestCommonTableExpression(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists emp")
+	tk.MustExec("create table emp (id int primary key, manager int)")
+	tk.MustExec("insert emp values (1, null), (2, 1), (3, 1), (4, 2), (5, 4), (6, 3)")
+
+	tk.MustQuery("with t as (select id from emp where manager = 1) select * from t").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("with t (a) as (select id from emp where manager = 1), t2 as (select a + 10 as b from t) " +
+		"select t.a, t2.b from t, t2 order by t.a, t2.b").Check(testkit.Rows("2 12", "2 13", "3 12", "3 13"))
+	tk.MustQuery("with t as (select manager from emp) select * from emp where id in (select * from t) order by id").
+		Check(testkit.Rows("1 <nil>", "2 1", "3 1", "4 2"))
+
+	tk.MustQuery("with recursive t (n) as (select 1 union all select n + 1 from t where n < 10) select sum(n), count(*) from t").
+		Check(testkit.Rows("55 10"))
+	tk.MustQuery("with recursive chain (id, depth) as (select id, 0 from emp where manager is null " +
+		"union all select emp.id, chain.depth + 1 from emp join chain on emp.manager = chain.id) " +
+		"select * from chain order by id").Check(testkit.Rows("1 0", "2 1", "3 1", "4 2", "5 3", "6 2"))
+	// UNION DISTINCT stops the recursion on a cycle.
+	tk.MustQuery("with recursive t (n) as (select 1 union select n % 3 + 1 from t) select * from t order by n").
+		Check(testkit.Rows("1", "2", "3"))
+
+	tk.MustExec("set @@cte_max_recursion_depth = 5")
+	tk.MustQuery("with recursive t (n) as (select 1 union all select n + 1 from t where n < 6) select count(*) from t").
+		Check(testkit.Rows("6"))
+	rs, err := tk.Exec("with recursive t (n) as (select 1 union all select n + 1 from t where n < 7) select count(*) from t")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*Recursive query aborted after 6 iterations.*")
+	c.Assert(rs.Close(), IsNil)
+	tk.MustExec("set @@cte_max_recursion_depth = default")
+	result := tk.MustQuery("explain with recursive t (n) as (select 1 union all select n + 1 from t where n < 10) select * from t")
+	c.Assert(len(result.Rows()), Greater, 0)
+
+	_, err = tk.Exec("with recursive t (n) as (select n + 1 from t) select * from t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("with recursive t (n) as (select 1 union all select count(*) from t) select * from t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("with t (a, b) as (select 1) select * from t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("with t as (select 1), t as (select 2) select * from t")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestPessimisticTxnDisabled(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	// The pessimistic mode is rejected unless it's enabled by the experimental flag.
+	_, err := tk.Exec("begin pessimistic")
+	c.Assert(terror.ErrorEqual(err, executor.ErrPessimisticTxnDisabled), IsTrue, Commentf("err: %v", err))
+	for _, sql := range []string{"set @@tidb_txn_mode = 'pessimistic'", "set @@global.tidb_txn_mode = 'pessimistic'"} {
+		_, err = tk.Exec(sql)
+		c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue, Commentf("sql: %s, err: %v", sql, err))
+	}
+	tk.MustExec("set @@tidb_txn_mode = 'optimistic'")
+	tk.MustExec("begin optimistic")
+	tk.MustExec("rollback")
+}
+
+func (s *testSuite) TestPessimisticTxn(c *C) {
+	if !*mockTikv {
+		c.Skip("the pessimistic transactions are supported by tikv store only")
+	}
+	variable.EnablePessimisticTxn = true
+	defer func() {
+		variable.EnablePessimisticTxn = false
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (k int primary key, v int)")
+	tk.MustExec("insert into t values (1, 0), (2, 0)")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustExec("set @@tidb_txn_mode = 'pessimistic'")
+
+	// The conflicting update waits for the lock, then it updates the new data instead of failing on commit.
+	tk.MustExec("begin pessimistic")
+	tk.MustExec("update t set v = v + 1 where k = 1")
+	tk1.MustExec("begin")
+	tk1.MustExec("update t set v = v + 1 where k = 2")
+	done := make(chan struct{})
+	go func() {
+		tk1.MustExec("update t set v = v + 10 where k = 1")
+		close(done)
+	}()
+	select {
+	case <-done:
+		c.Fatal("the update doesn't wait for the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+	tk.MustExec("commit")
+	<-done
+	tk1.MustExec("commit")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 11", "2 1"))
+
+	// The autocommit statements are pessimistic too.
+	tk.MustExec("begin pessimistic")
+	tk.MustExec("update t set v = 0 where k = 1")
+	done = make(chan struct{})
+	go func() {
+		tk1.MustExec("update t set v = v + 1 where k = 1")
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	tk.MustExec("commit")
+	<-done
+	tk.MustQuery("select v from t where k = 1").Check(testkit.Rows("1"))
+
+	// The wait is limited by innodb_lock_wait_timeout.
+	tk1.MustExec("set @@innodb_lock_wait_timeout = 0")
+	tk.MustExec("begin pessimistic")
+	tk.MustExec("delete from t where k = 2")
+	tk1.MustExec("begin")
+	_, err := tk1.Exec("update t set v = 5 where k = 2")
+	c.Assert(terror.ErrorEqual(err, kv.ErrLockWaitTimeout), IsTrue, Commentf("err %v", err))
+	tk1.MustExec("rollback")
+	tk.MustExec("rollback")
+
+	// The deadlock is detected, the transaction which finds it is rolled back.
+	tk1.MustExec("set @@innodb_lock_wait_timeout = 50")
+	tk.MustExec("begin pessimistic")
+	tk.MustExec("update t set v = 100 where k = 1")
+	tk1.MustExec("begin")
+	tk1.MustExec("update t set v = 200 where k = 2")
+	done = make(chan struct{})
+	go func() {
+		tk.MustExec("update t set v = 100 where k = 2")
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_, err = tk1.Exec("update t set v = 200 where k = 1")
+	c.Assert(terror.ErrorEqual(err, kv.ErrDeadlock), IsTrue, Commentf("err %v", err))
+	<-done
+	tk.MustExec("commit")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 100", "2 100"))
+
+	// BEGIN OPTIMISTIC overrides tidb_txn_mode.
+	tk.MustExec("begin pessimistic")
+	tk.MustExec("update t set v = 1 where k = 1")
+	tk1.MustExec("begin optimistic")
+	tk1.MustExec("update t set v = 2 where k = 1")
+	tk.MustExec("commit")
+	tk1.MustExec("commit")
+	tk.MustQuery("select v from t where k = 1").Check(testkit.Rows("2"))
+
+	_, err = tk1.Exec("set @@tidb_txn_mode = 'unknown'")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table t")
+}
+
+func (s *testSuite) TestLockingReadNoWait(c *C) {
+	if !*mockTikv {
+		c.Skip("the pessimistic transactions are supported by tikv store only")
+	}
+	variable.EnablePessimisticTxn = true
+	defer func() {
+		variable.EnablePessimisticTxn = false
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists q")
+	tk.MustExec("create table q (id int primary key, v int)")
+	tk.MustExec("insert into q values (1, 1), (2, 2), (3, 3), (4, 4)")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+
+	tk.MustExec("begin pessimistic")
+	tk.MustQuery("select * from q where id = 1 for update").Check(testkit.Rows("1 1"))
+	tk1.MustExec("begin pessimistic")
+	rs, err := tk1.Exec("select * from q where id = 1 for update nowait")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(terror.ErrorEqual(err, kv.ErrLockNowait), IsTrue, Commentf("err %v", err))
+	tk1.MustQuery("select * from q where id = 2 for update nowait").Check(testkit.Rows("2 2"))
+	// The rows locked by the transaction itself are not skipped.
+	tk1.MustQuery("select id from q for update skip locked").Check(testkit.Rows("2", "3", "4"))
+	tk1.MustExec("rollback")
+
+	// The queue consumers take the first row which isn't taken by the others.
+	tk1.MustExec("begin pessimistic")
+	tk1.MustQuery("select id from q limit 1 for update skip locked").Check(testkit.Rows("2"))
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.MustExec("begin pessimistic")
+	tk2.MustQuery("select id from q limit 1 for update skip locked").Check(testkit.Rows("3"))
+	tk2.MustExec("commit")
+	tk1.MustExec("commit")
+
+	// The optimistic transactions lock the keys when they commit.
+	tk1.MustExec("begin optimistic")
+	tk1.MustQuery("select id from q where id = 1 for update nowait").Check(testkit.Rows("1"))
+	tk1.MustQuery("select id from q where id < 3 for update skip locked").Check(testkit.Rows("1", "2"))
+	tk1.MustExec("rollback")
+	tk.MustExec("commit")
+	tk.MustExec("drop table q")
+}
+
+func (s *testSuite) TestHotRegions(c *C) {
+	if !*mockTikv {
+		c.Skip("the hot regions are recorded by tikv store only")
+	}
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists hot")
+	tk.MustExec("create table hot (id int primary key, v int, index idx_v(v))")
+	// Make the keys hotter than the others written by the tests.
+	for i := 0; i < 50; i++ {
+		tk.MustExec("insert into hot values (1, 1)")
+		tk.MustExec("delete from hot where id = 1")
+	}
+	tk.MustQuery("select * from hot").Check(testkit.Rows())
+
+	tk.MustQuery("select db_name, index_name, write_count from information_schema.tidb_hot_keys where table_name = 'hot' order by index_name").
+		Check(testkit.Rows("test  100", "test idx_v 100"))
+	// The region is named by the first key accessed, all the tables are in the same region of the mock store.
+	tk.MustQuery("select read_count > 0, write_count >= 200 from information_schema.tidb_hot_regions where region_id in " +
+		"(select region_id from information_schema.tidb_hot_keys where table_name = 'hot')").Check(testkit.Rows("1 1"))
+	tk.MustExec("drop table hot")
+}
+
+func (s *testSuite) TestSlowQuery(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists slow")
+	tk.MustExec("create table slow (id int primary key, v int)")
+	tk.MustExec("insert into slow values (1, 1), (2, 2), (3, 3)")
+
+	// The statements faster than the threshold are not logged.
+	tk.MustQuery("select * from slow where v > 1").Check(testkit.Rows("2 2", "3 3"))
+	tk.MustQuery("select count(*) from information_schema.slow_query where query like '%from slow%'").Check(testkit.Rows("0"))
+
+	tk.MustExec("set @@tidb_slow_log_threshold = 0")
+	tk.MustQuery("select * from slow where v > 1").Check(testkit.Rows("2 2", "3 3"))
+	tk.MustExec("update slow set v = 4 where id = 3")
+	_, err := tk.Exec("insert into slow values (4, 4), (4, 4)")
+	c.Assert(err, NotNil)
+	tk.MustExec("create user 'slow_user'@'%' identified by 'slow_pwd'")
+	tk.MustExec("set @@tidb_slow_log_normalize = 1")
+	tk.MustQuery("select v from slow where id = 2").Check(testkit.Rows("2"))
+	tk.MustExec("set @@tidb_slow_log_threshold = 300, @@tidb_slow_log_normalize = 0")
+	tk.MustExec("drop user 'slow_user'@'%'")
+	// The passwords aren't logged.
+	tk.MustQuery("select query from information_schema.slow_query where query like 'create user%'").Check(
+		testkit.Rows("create user 'slow_user'@'%' identified by ?"))
+
+	tk.MustQuery("select db, result_rows, succ, txn_start_ts > 0, query_time >= execute_time from information_schema.slow_query " +
+		"where query like '%from slow%' or query like '%into slow%' or query like 'update slow%'").Check(testkit.Rows(
+		"test 2 1 1 1",
+		"test 0 1 1 1",
+		"test 0 0 1 1",
+		"test 1 1 1 1",
+	))
+	tk.MustQuery("select query from information_schema.slow_query where query like '%where id = %'").Check(
+		testkit.Rows("update slow set v = 4 where id = 3", "select v from slow where id = ?"))
+	if *mockTikv {
+		// The rows are filtered by the coprocessor, the point get reads a key.
+		tk.MustQuery("select kv_requests > 0, scanned_keys from information_schema.slow_query where query like '%from slow where%'").Check(
+			testkit.Rows("1 2", "1 1"))
+	}
+	tk.MustExec("drop table slow")
+}
+
+func (s *testSuite) TestStmtSummary(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists summary")
+	tk.MustExec("create table summary (id int primary key, v int)")
+
+	tk.MustExec("set global tidb_enable_stmt_summary = 1")
+	defer tk.MustExec("set global tidb_enable_stmt_summary = 0")
+	tk.MustExec("insert into summary values (1, 1), (2, 2)")
+	tk.MustExec("insert into summary values (3, 3)")
+	tk.MustQuery("select * from summary where v > 1").Check(testkit.Rows("2 2", "3 3"))
+	tk.MustQuery("select * from summary where v > 2").Check(testkit.Rows("3 3"))
+	_, err := tk.Exec("insert into summary values (4, 4), (4, 4)")
+	c.Assert(err, NotNil)
+	tk.MustExec("create user 'summary_user'@'%' identified by 'summary_pwd'")
+	defer tk.MustExec("drop user 'summary_user'@'%'")
+	tk.MustQuery("select query_sample_text from performance_schema.events_statements_summary_by_digest " +
+		"where digest_text like 'create user %'").Check(testkit.Rows("create user 'summary_user'@'%' identified by ?"))
+
+	tk.MustQuery("select schema_name, digest_text, count_star, sum_errors, sum_rows_affected, sum_rows_sent, query_sample_text " +
+		"from performance_schema.events_statements_summary_by_digest where digest_text like '% summary %' order by digest_text").Check(testkit.Rows(
+		"test insert into summary values ( ... ) 1 0 1 0 insert into summary values (3, 3)",
+		"test insert into summary values ( ... ) , ( ... ) 2 1 3 0 insert into summary values (4, 4), (4, 4)",
+		"test select * from summary where v > ? 2 0 0 3 select * from summary where v > 2",
+	))
+	tk.MustQuery("select count(*) from performance_schema.events_statements_summary_by_digest " +
+		"where digest_text like '%from summary %' and quantile_95 <= max_timer_wait and min_timer_wait <= avg_timer_wait " +
+		"and avg_timer_wait <= max_timer_wait and last_seen >= first_seen and query_sample_plan != ''").Check(testkit.Rows("1"))
+	if *mockTikv {
+		tk.MustQuery("select sum_rows_examined from performance_schema.events_statements_summary_by_digest " +
+			"where digest_text like '%from summary %'").Check(testkit.Rows("3"))
+	}
+
+	// The summaries are cleared when it's turned off, and the table can't be written.
+	_, err = tk.Exec("insert into performance_schema.events_statements_summary_by_digest (count_star) values (1)")
+	c.Assert(err, NotNil)
+	tk.MustExec("set global tidb_enable_stmt_summary = 0")
+	tk.MustQuery("select * from summary where v > 1")
+	tk.MustQuery("select count(*) from performance_schema.events_statements_summary_by_digest").Check(testkit.Rows("0"))
+	tk.MustExec("drop table summary")
+}
+
+func (s *testSuite) TestTimeZone(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b timestamp null, c datetime, unique key idx_b(b))")
+	tk.MustExec("set time_zone = '+00:00'")
+	tk.MustExec("insert t values (1, '2017-01-01 00:00:00', '2017-01-01 00:00:00')")
+
+	// The TIMESTAMP values are shown in the session time zone, while the DATETIME values are not converted.
+	tk.MustExec("set time_zone = '+08:00'")
+	tk.MustQuery("select @@time_zone").Check(testkit.Rows("+08:00"))
+	tk.MustQuery("select b, c from t").Check(testkit.Rows("2017-01-01 08:00:00 2017-01-01 00:00:00"))
+	tk.MustQuery("select a from t where b = '2017-01-01 08:00:00'").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b in ('2017-01-01 08:00:00', '2017-01-01 00:00:00')").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b > '2017-01-01 07:00:00' and b < '2017-01-01 09:00:00'").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b < '2017-01-01 08:00:00'").Check(testkit.Rows())
+	tk.MustQuery("select count(*), max(b) from t").Check(testkit.Rows("1 2017-01-01 08:00:00"))
+	tk.MustExec("insert t values (2, '2017-01-01 10:00:00', '2017-01-01 10:00:00')")
+	_, err := tk.Exec("insert t values (3, '2017-01-01 10:00:00', null)")
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue, Commentf("err %v", err))
+	tk.MustExec("update t set c = '2017-01-02 00:00:00' where b = '2017-01-01 10:00:00'")
+	tk.MustQuery("select a, b, c from t order by a").Check(testkit.Rows(
+		"1 2017-01-01 08:00:00 2017-01-01 00:00:00", "2 2017-01-01 10:00:00 2017-01-02 00:00:00"))
+
+	tk.MustExec("set time_zone = '-02:00'")
+	tk.MustQuery("select a, b from t order by b").Check(testkit.Rows("1 2016-12-31 22:00:00", "2 2017-01-01 00:00:00"))
+	tk.MustExec("delete from t where b = '2016-12-31 22:00:00'")
+	// The index entry of the deleted row is removed.
+	tk.MustExec("insert t values (3, '2016-12-31 22:00:00', null)")
+	tk.MustExec("set time_zone = 'SYSTEM'")
+	tk.MustQuery("select b from t where a = 2").Check(testkit.Rows(
+		time.Date(2017, 1, 1, 2, 0, 0, 0, time.UTC).Local().Format(types.TimeFormat)))
+
+	// NOW() and the CURRENT_TIMESTAMP default values are in the session time zone.
+	tk.MustExec("set time_zone = '+08:00'")
+	tk.MustQuery("select timestampdiff(hour, utc_timestamp(), now())").Check(testkit.Rows("8"))
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t1 (a timestamp default current_timestamp)")
+	tk.MustExec("set @@timestamp = 1483228800")
+	tk.MustExec("insert t1 values ()")
+	tk.MustQuery("select a from t1").Check(testkit.Rows("2017-01-01 08:00:00"))
+	tk.MustExec("set time_zone = '+00:00'")
+	tk.MustQuery("select a from t1").Check(testkit.Rows("2017-01-01 00:00:00"))
+
+	_, err = tk.Exec("set time_zone = 'Asia/Xxx'")
+	c.Assert(terror.ErrorEqual(err, variable.ErrUnknownTimeZone), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("set global time_zone = '+14:00'")
+	c.Assert(terror.ErrorEqual(err, variable.ErrUnknownTimeZone), IsTrue, Commentf("err %v", err))
+
+	// The new sessions use the global time zone.
+	tk.MustExec("set global time_zone = 'Asia/Shanghai'")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustQuery("select @@time_zone").Check(testkit.Rows("Asia/Shanghai"))
+	tk1.MustQuery("select a from t1").Check(testkit.Rows("2017-01-01 08:00:00"))
+	tk.MustExec("set global time_zone = 'SYSTEM'")
+	tk.MustExec("drop table t, t1")
+}
@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/util/types"
@@ -29,6 +30,10 @@ type ExplainExec struct {
 	schema   *expression.Schema
 	rows     []*Row
 	cursor   int
+
+	ctx context.Context
+	// analyzeExec is the executor of StmtPlan for EXPLAIN ANALYZE, it's executed before the plans are explained.
+	analyzeExec Executor
 }
 
 // Schema implements the Executor Schema interface.
@@ -54,16 +59,47 @@ func (e *ExplainExec) prepareExplainInfo(p plan.Plan, parent plan.Plan) error {
 	row := &Row{
 		Data: types.MakeDatums(p.ID(), string(explain), parentStr),
 	}
+	if e.schema.Len() > 3 {
+		execInfo := "N/A"
+		if coll := e.ctx.GetSessionVars().StmtCtx.RuntimeStatsColl; coll != nil && coll.Exists(p.ID()) {
+			execInfo = coll.Get(p.ID()).String()
+		}
+		row.Data = append(row.Data, types.NewStringDatum(execInfo))
+	}
 	e.rows = append(e.rows, row)
 	return nil
 }
 
+// executeAnalyzeExec runs the statement of EXPLAIN ANALYZE to collect the runtime statistics, the rows are discarded.
+func (e *ExplainExec) executeAnalyzeExec() error {
+	for {
+		row, err := e.analyzeExec.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+	}
+	err := e.analyzeExec.Close()
+	e.analyzeExec = nil
+	return errors.Trace(err)
+}
+
 // Next implements Execution Next interface.
 func (e *ExplainExec) Next() (*Row, error) {
 	if e.cursor == 0 {
-		err := e.prepareExplainInfo(e.StmtPlan, nil)
-		if err != nil {
-			return nil, errors.Trace(err)
+		if e.analyzeExec != nil {
+			if err := e.executeAnalyzeExec(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		// StmtPlan is nil for EXPLAIN FOR CONNECTION if the connection isn't executing a statement.
+		if e.StmtPlan != nil {
+			err := e.prepareExplainInfo(e.StmtPlan, nil)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
 		}
 	}
 	if e.cursor >= len(e.rows) {
@@ -77,5 +113,10 @@ func (e *ExplainExec) Next() (*Row, error) {
 // Close implements the Executor Close interface.
 func (e *ExplainExec) Close() error {
 	e.rows = nil
+	if e.analyzeExec != nil {
+		err := e.analyzeExec.Close()
+		e.analyzeExec = nil
+		return errors.Trace(err)
+	}
 	return nil
 }
@@ -14,7 +14,13 @@
 package executor_test
 
 import (
+	"fmt"
+	"strings"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -628,3 +634,92 @@ func (s *testSuite) TestExplain(c *C) {
 		result.Check(testkit.Rows(resultList...))
 	}
 }
+
+func (s *testSuite) TestExplainAnalyze(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (c1 int primary key, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+	tk.MustExec("insert into t1 values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("insert into t2 values (1, 1), (2, 2), (2, 2)")
+
+	execInfos := func(sql string) map[string]string {
+		infos := make(map[string]string)
+		for _, row := range tk.MustQuery(sql).Rows() {
+			infos[strings.Split(row[0].(string), "_")[0]] = row[3].(string)
+		}
+		return infos
+	}
+	infos := execInfos("explain analyze select * from t2 order by c2")
+	c.Assert(infos, HasLen, 2)
+	c.Assert(infos["TableScan"], Matches, "time:.*, loops:4, rows:3, concurrency:10")
+	c.Assert(infos["Sort"], Matches, "time:.*, loops:4, rows:3")
+
+	infos = execInfos("explain analyze select t1.c1, count(*) from t1 join t2 on t1.c1 = t2.c1 group by t1.c1")
+	c.Assert(infos["HashAgg"], Matches, "time:.*, loops:3, rows:2, concurrency:4")
+	c.Assert(infos["HashLeftJoin"], Matches, "time:.*, loops:3, rows:2, concurrency:5")
+
+	// EXPLAIN ANALYZE executes the statement.
+	infos = execInfos("explain analyze delete from t2 where c1 = 2")
+	c.Assert(infos["Delete"], Matches, "time:.*, loops:1, rows:0")
+	tk.MustQuery("select * from t2").Check(testkit.Rows("1 1"))
+}
+
+func (s *testSuite) TestExplainForConnection(c *C) {
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk2 := testkit.NewTestKit(c, s.store)
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk1.MustExec("use test")
+	tk2.MustExec("use test")
+	tk1.MustExec("drop table if exists t")
+	tk1.MustExec("create table t (c1 int primary key, c2 int)")
+	tk1.MustExec("insert into t values (1, 1), (2, 2)")
+	id1 := tk1.Se.GetSessionVars().ConnectionID
+	id2 := tk2.Se.GetSessionVars().ConnectionID
+	sm := &mockSessionManager{sessions: map[uint64]tidb.Session{id1: tk1.Se, id2: tk2.Se}}
+	tk1.Se.SetSessionManager(sm)
+	tk2.Se.SetSessionManager(sm)
+
+	// The plan of the executing statement is explained.
+	sql := "select * from t where c2 > 1 order by c2"
+	rs, err := tk2.Exec(sql)
+	c.Assert(err, IsNil)
+	explainFor := fmt.Sprintf("explain for connection %d", id2)
+	result := tk1.MustQuery(explainFor)
+	c.Assert(result.Rows(), HasLen, 2)
+	result.Check(tk1.MustQuery("explain " + sql).Rows())
+	c.Assert(rs.Close(), IsNil)
+
+	// The result is empty if the connection isn't executing a statement.
+	tk1.MustQuery(explainFor).Check(testkit.Rows())
+
+	_, err = tk1.Exec("explain for connection 12345678")
+	c.Assert(plan.ErrNoSuchThread.Equal(err), IsTrue, Commentf("err %v", err))
+
+	// The PROCESS privilege is required to explain the statements of the other users.
+	save := privileges.Enable
+	privileges.Enable = true
+	defer func() {
+		privileges.Enable = save
+	}()
+	tk1.MustExec("create user 'testexplainfor'@'localhost'")
+	se, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	c.Assert(se.Auth("testexplainfor@localhost", nil, nil), IsTrue)
+	se.SetSessionManager(sm)
+	_, err = se.Execute(explainFor)
+	c.Assert(err, NotNil)
+	tk1.MustExec("grant process on *.* to 'testexplainfor'@'localhost'")
+	tk1.MustExec("flush privileges")
+	_, err = se.Execute(explainFor)
+	c.Assert(err, IsNil)
+}
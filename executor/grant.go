@@ -86,7 +86,7 @@ func (e *GrantExec) Next() (*Row, error) {
 
 			user := fmt.Sprintf(`("%s", "%s", "%s")`, host, userName, pwd)
 			sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password) VALUES %s;`, mysql.SystemDB, mysql.UserTable, user)
-			_, err := e.ctx.(sqlexec.SQLExecutor).Execute(sql)
+			err := execInternalSQL(e.ctx, sql)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -0,0 +1,249 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// workloadCapacity is the maximum number of statement digests in the workload, the statements of new digests are
+// not recorded when it's full.
+const workloadCapacity = 1024
+
+// workload is the statements recorded for the index advisor, grouped by their databases and digests.
+type workload struct {
+	mu    sync.Mutex
+	stmts map[string]*workloadStmt
+}
+
+// workloadStmt is a statement digest in the workload, the sql is the text of the first statement of the digest.
+type workloadStmt struct {
+	digest     string
+	sql        string
+	db         string
+	count      int64
+	candidates []*plan.IndexCandidate
+}
+
+var globalWorkload = &workload{stmts: make(map[string]*workloadStmt)}
+
+// record records a statement of the digest, the index candidates are only built for a new digest.
+// The same statement in different databases may access different tables, so it's recorded separately.
+func (w *workload) record(ctx context.Context, node ast.StmtNode, is infoschema.InfoSchema) {
+	db := ctx.GetSessionVars().CurrentDB
+	digest := parser.Digest(parser.Normalize(node.Text()))
+	key := db + "." + digest
+	w.mu.Lock()
+	stmt, ok := w.stmts[key]
+	if ok {
+		stmt.count++
+	}
+	full := len(w.stmts) >= workloadCapacity
+	w.mu.Unlock()
+	if ok || full {
+		return
+	}
+
+	candidates, err := plan.IndexCandidates(ctx, node, is)
+	if err != nil {
+		log.Warnf("[%d] build index candidates of %s error: %v", ctx.GetSessionVars().ConnectionID, node.Text(), err)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stmt, ok = w.stmts[key]; ok {
+		stmt.count++
+		return
+	}
+	w.stmts[key] = &workloadStmt{
+		digest:     digest,
+		sql:        node.Text(),
+		db:         db,
+		count:      1,
+		candidates: candidates,
+	}
+}
+
+// snapshot returns copies of the statements which have index candidates.
+func (w *workload) snapshot() []workloadStmt {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stmts := make([]workloadStmt, 0, len(w.stmts))
+	for _, stmt := range w.stmts {
+		if len(stmt.candidates) > 0 {
+			stmts = append(stmts, *stmt)
+		}
+	}
+	return stmts
+}
+
+// recordWorkload records the statement in the workload of the index advisor if it's enabled.
+func recordWorkload(ctx context.Context, node ast.StmtNode, is infoschema.InfoSchema) {
+	if !ctx.GetSessionVars().EnableIndexAdvisor {
+		return
+	}
+	switch node.(type) {
+	case *ast.SelectStmt, *ast.UpdateStmt, *ast.DeleteStmt:
+		globalWorkload.record(ctx, node, is)
+	}
+}
+
+// ShowIndexAdviceExec represents a show index advice executor.
+// It is built from the "admin show index advice" statement. The cost of each recorded statement is estimated with
+// and without each of its index candidates, a candidate which reduces the cost is recommended, the total cost
+// reduction is weighted by the execution count of the statements.
+type ShowIndexAdviceExec struct {
+	schema *expression.Schema
+	ctx    context.Context
+	is     infoschema.InfoSchema
+	rows   []*Row
+	done   bool
+	cursor int
+}
+
+// indexAdvice is a recommended index and the statements it speeds up.
+type indexAdvice struct {
+	candidate     *plan.IndexCandidate
+	statements    int64
+	execCount     int64
+	costReduction float64
+}
+
+// byCostReduction sorts the index advices by the cost reduction in descending order.
+type byCostReduction []*indexAdvice
+
+func (a byCostReduction) Len() int      { return len(a) }
+func (a byCostReduction) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byCostReduction) Less(i, j int) bool {
+	if a[i].costReduction != a[j].costReduction {
+		return a[i].costReduction > a[j].costReduction
+	}
+	return a[i].candidate.String() < a[j].candidate.String()
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ShowIndexAdviceExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowIndexAdviceExec) Next() (*Row, error) {
+	if !e.done {
+		e.evaluate()
+		e.done = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+func (e *ShowIndexAdviceExec) evaluate() {
+	advices := make(map[string]*indexAdvice)
+	for _, stmt := range globalWorkload.snapshot() {
+		if err := e.evaluateStmt(stmt, advices); err != nil {
+			// The tables of the statement may be dropped or changed since it's recorded.
+			log.Warnf("[%d] evaluate index candidates of %s error: %v", e.ctx.GetSessionVars().ConnectionID, stmt.sql, err)
+		}
+	}
+	sorted := make(byCostReduction, 0, len(advices))
+	for _, advice := range advices {
+		sorted = append(sorted, advice)
+	}
+	sort.Sort(sorted)
+	for _, advice := range sorted {
+		c := advice.candidate
+		cols := make([]string, 0, len(c.Columns))
+		for _, col := range c.Columns {
+			cols = append(cols, col.O)
+		}
+		e.rows = append(e.rows, &Row{Data: types.MakeDatums(c.DBName.O, c.Table.O, strings.Join(cols, ","),
+			advice.statements, advice.execCount, advice.costReduction)})
+	}
+}
+
+// evaluateStmt estimates the cost of the statement with each of its index candidates, in the database where the
+// statement was executed.
+func (e *ShowIndexAdviceExec) evaluateStmt(stmt workloadStmt, advices map[string]*indexAdvice) error {
+	vars := e.ctx.GetSessionVars()
+	currentDB := vars.CurrentDB
+	vars.CurrentDB = stmt.db
+	defer func() {
+		vars.CurrentDB = currentDB
+	}()
+
+	charset, collation := vars.GetCharsetInfo()
+	var (
+		nodes []ast.StmtNode
+		err   error
+	)
+	if sqlParser, ok := e.ctx.(sqlexec.SQLParser); ok {
+		nodes, err = sqlParser.ParseSQL(stmt.sql, charset, collation)
+	} else {
+		nodes, err = parser.New().Parse(stmt.sql, charset, collation)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(nodes) != 1 {
+		return nil
+	}
+	node := nodes[0]
+	if err = plan.PrepareStmt(e.is, e.ctx, node); err != nil {
+		return errors.Trace(err)
+	}
+	baseCost, err := plan.EstimateCost(e.ctx, node, e.is, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, candidate := range stmt.candidates {
+		cost, err := plan.EstimateCost(e.ctx, node, e.is, []*plan.IndexCandidate{candidate})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if cost >= baseCost {
+			continue
+		}
+		key := candidate.String()
+		advice, ok := advices[key]
+		if !ok {
+			advice = &indexAdvice{candidate: candidate}
+			advices[key] = advice
+		}
+		advice.statements++
+		advice.execCount += stmt.count
+		advice.costReduction += (baseCost - cost) * float64(stmt.count)
+	}
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ShowIndexAdviceExec) Close() error {
+	return nil
+}
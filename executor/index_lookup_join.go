@@ -0,0 +1,308 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var _ Executor = &IndexLookUpJoin{}
+
+// IndexLookUpJoin implements the index nested loop join algorithm.
+// It reads a batch of rows from the outer executor, then builds an executor to read the inner rows whose look up
+// keys equal to the join keys of the outer rows, through the handle or an index of the inner table.
+// The order of the outer rows is kept in the result.
+type IndexLookUpJoin struct {
+	ctx    context.Context
+	schema *expression.Schema
+
+	outerExec Executor
+	// innerPlan is the scan of the inner table, an executor is built from it with the ranges of every batch.
+	innerPlan    plan.PhysicalPlan
+	innerBuilder *executorBuilder
+	outerKeys    []*expression.Column
+	innerKeys    []*expression.Column
+	// targetTypes are the types that both the outer keys and the inner keys are converted to before comparing.
+	targetTypes []*types.FieldType
+	outerFilter expression.Expression
+	innerFilter expression.Expression
+	otherFilter expression.Expression
+	// outer means the unmatched outer rows should be returned with the default inner row.
+	outer           bool
+	outerIsLeft     bool
+	defaultInnerRow *Row
+	batchSize       int
+
+	outerRows  []*Row
+	resultRows []*Row
+	cursor     int
+	exhausted  bool
+
+	// memTracker tracks the memory usage of the outer rows, the inner rows and the result rows of the current batch.
+	memTracker *memory.Tracker
+}
+
+// Schema implements the Executor Schema interface.
+func (e *IndexLookUpJoin) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Close implements the Executor Close interface.
+func (e *IndexLookUpJoin) Close() error {
+	e.outerRows = nil
+	e.resultRows = nil
+	e.cursor = 0
+	e.exhausted = false
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return errors.Trace(e.outerExec.Close())
+}
+
+// Next implements the Executor Next interface.
+func (e *IndexLookUpJoin) Next() (*Row, error) {
+	for e.cursor >= len(e.resultRows) {
+		if e.exhausted {
+			return nil, nil
+		}
+		e.resultRows = e.resultRows[:0]
+		e.cursor = 0
+		err := e.fetchAndJoin()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	row := e.resultRows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// fetchAndJoin reads a batch of outer rows and joins them with the inner rows which have the same join keys.
+func (e *IndexLookUpJoin) fetchAndJoin() error {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	e.outerRows = e.outerRows[:0]
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	// A nil hash key means the outer row can't match any inner row.
+	var outerHashKeys [][]byte
+	var lookUpKeys []types.Datum
+	vals := make([]types.Datum, len(e.outerKeys))
+	for len(e.outerRows) < e.batchSize {
+		row, err := e.outerExec.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			e.exhausted = true
+			break
+		}
+		matched := true
+		if e.outerFilter != nil {
+			matched, err = expression.EvalBool(e.outerFilter, row.Data, e.ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		var hashKey []byte
+		if matched {
+			hasNull, key, err := getHashKey(sc, e.outerKeys, row, e.targetTypes, vals, nil)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !hasNull {
+				hashKey = key
+				lookUpKeys = append(lookUpKeys, vals[0])
+			}
+		}
+		if hashKey == nil && !e.outer {
+			continue
+		}
+		e.outerRows = append(e.outerRows, row)
+		outerHashKeys = append(outerHashKeys, hashKey)
+		err = e.memTracker.Consume(row.memUsage() + int64(len(hashKey)))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	innerRows, err := e.fetchInnerRows(lookUpKeys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i, outerRow := range e.outerRows {
+		matched := false
+		if outerHashKeys[i] != nil {
+			for _, innerRow := range innerRows[string(outerHashKeys[i])] {
+				joinedRow := e.makeJoinRow(outerRow, innerRow)
+				if e.otherFilter != nil {
+					ok, err := expression.EvalBool(e.otherFilter, joinedRow.Data, e.ctx)
+					if err != nil {
+						return errors.Trace(err)
+					}
+					if !ok {
+						continue
+					}
+				}
+				matched = true
+				e.resultRows = append(e.resultRows, joinedRow)
+				err = e.memTracker.Consume(joinedRow.memUsage())
+				if err != nil {
+					return errors.Trace(err)
+				}
+			}
+		}
+		if !matched && e.outer {
+			joinedRow := e.makeJoinRow(outerRow, e.defaultInnerRow)
+			e.resultRows = append(e.resultRows, joinedRow)
+			err = e.memTracker.Consume(joinedRow.memUsage())
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *IndexLookUpJoin) makeJoinRow(outerRow, innerRow *Row) *Row {
+	if e.outerIsLeft {
+		return makeJoinRow(outerRow, innerRow)
+	}
+	return makeJoinRow(innerRow, outerRow)
+}
+
+// fetchInnerRows reads the inner rows of the look up keys, and groups them by their join keys.
+func (e *IndexLookUpJoin) fetchInnerRows(lookUpKeys []types.Datum) (map[string][]*Row, error) {
+	if len(lookUpKeys) == 0 {
+		return nil, nil
+	}
+	innerExec, err := e.buildInnerExec(lookUpKeys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	innerRows, err := e.groupInnerRows(innerExec)
+	closeErr := innerExec.Close()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return innerRows, errors.Trace(closeErr)
+}
+
+func (e *IndexLookUpJoin) groupInnerRows(innerExec Executor) (map[string][]*Row, error) {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	innerRows := make(map[string][]*Row)
+	vals := make([]types.Datum, len(e.innerKeys))
+	for {
+		row, err := innerExec.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			return innerRows, nil
+		}
+		if e.innerFilter != nil {
+			matched, err := expression.EvalBool(e.innerFilter, row.Data, e.ctx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		hasNull, key, err := getHashKey(sc, e.innerKeys, row, e.targetTypes, vals, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if hasNull {
+			continue
+		}
+		innerRows[string(key)] = append(innerRows[string(key)], row)
+		err = e.memTracker.Consume(row.memUsage() + int64(len(key)))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+}
+
+// buildInnerExec builds the executor to read the inner rows whose look up keys are in lookUpKeys.
+func (e *IndexLookUpJoin) buildInnerExec(lookUpKeys []types.Datum) (Executor, error) {
+	var innerExec Executor
+	switch v := e.innerPlan.(type) {
+	case *plan.PhysicalTableScan:
+		handles := make([]int64, 0, len(lookUpKeys))
+		for _, key := range lookUpKeys {
+			if key.Kind() == types.KindUint64 {
+				handles = append(handles, int64(key.GetUint64()))
+			} else {
+				handles = append(handles, key.GetInt64())
+			}
+		}
+		sort.Sort(int64Slice(handles))
+		ranges := make([]plan.TableRange, 0, len(handles))
+		for i, h := range handles {
+			if i > 0 && h == handles[i-1] {
+				continue
+			}
+			ranges = append(ranges, plan.TableRange{LowVal: h, HighVal: h})
+		}
+		ts := *v
+		ts.Ranges = ranges
+		innerExec = e.innerBuilder.build(&ts)
+	case *plan.PhysicalIndexScan:
+		keys := make(encodedDatums, 0, len(lookUpKeys))
+		for _, key := range lookUpKeys {
+			b, err := codec.EncodeKey(nil, key)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			keys = append(keys, encodedDatum{encoded: b, datum: key})
+		}
+		sort.Sort(keys)
+		ranges := make([]*plan.IndexRange, 0, len(keys))
+		for i, key := range keys {
+			if i > 0 && bytes.Equal(key.encoded, keys[i-1].encoded) {
+				continue
+			}
+			ranges = append(ranges, &plan.IndexRange{
+				LowVal:  []types.Datum{key.datum},
+				HighVal: []types.Datum{key.datum},
+			})
+		}
+		is := *v
+		is.Ranges = ranges
+		innerExec = e.innerBuilder.build(&is)
+	default:
+		return nil, errors.Errorf("unsupported inner plan %T for index join", e.innerPlan)
+	}
+	if e.innerBuilder.err != nil {
+		return nil, errors.Trace(e.innerBuilder.err)
+	}
+	return innerExec, nil
+}
+
+// encodedDatum is a datum with its key encoding, which is used to sort the datums in the order of index keys.
+type encodedDatum struct {
+	encoded []byte
+	datum   types.Datum
+}
+
+type encodedDatums []encodedDatum
+
+func (s encodedDatums) Len() int           { return len(s) }
+func (s encodedDatums) Less(i, j int) bool { return bytes.Compare(s[i].encoded, s[j].encoded) < 0 }
+func (s encodedDatums) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
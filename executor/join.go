@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -66,6 +67,15 @@ type HashJoinExec struct {
 
 	// Channels for output.
 	resultCh chan *execResult
+
+	// smallSpill and bigSpill are the partitions of the small table rows and the big table rows on disk, they are used
+	// when the small table has more rows than the session variable tidb_max_hash_rows_in_memory. The partitions are
+	// joined one by one after all the big table rows are partitioned.
+	smallSpill *spillPartitions
+	bigSpill   *spillPartitions
+
+	// memTracker tracks the memory usage of the hash table.
+	memTracker *memory.Tracker
 }
 
 // hashJoinCtx holds the variables needed to do a hash join in one of many concurrent goroutines.
@@ -88,9 +98,31 @@ func (e *HashJoinExec) Close() error {
 	e.prepared = false
 	e.cursor = 0
 	e.rows = nil
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	err := e.closeSpill()
+	if err != nil {
+		e.smallExec.Close()
+		return errors.Trace(err)
+	}
 	return e.smallExec.Close()
 }
 
+func (e *HashJoinExec) closeSpill() error {
+	var err error
+	if e.smallSpill != nil {
+		err = e.smallSpill.close()
+		e.smallSpill = nil
+	}
+	if e.bigSpill != nil {
+		bigErr := e.bigSpill.close()
+		if err == nil {
+			err = bigErr
+		}
+		e.bigSpill = nil
+	}
+	return errors.Trace(err)
+}
+
 // makeJoinRow simply creates a new row that appends row b to row a.
 func makeJoinRow(a *Row, b *Row) *Row {
 	ret := &Row{
@@ -211,6 +243,8 @@ func (e *HashJoinExec) prepare() error {
 	e.hashTable = make(map[string][]*Row)
 	e.cursor = 0
 	sc := e.ctx.GetSessionVars().StmtCtx
+	maxRowsInMemory := e.ctx.GetSessionVars().MaxHashRowsInMemory
+	numRows := 0
 	for {
 		row, err := e.smallExec.Next()
 		if err != nil {
@@ -238,11 +272,36 @@ func (e *HashJoinExec) prepare() error {
 		if hasNull {
 			continue
 		}
+		if e.smallSpill != nil {
+			err = e.smallSpill.add(hashcode, row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
 		if rows, ok := e.hashTable[string(hashcode)]; !ok {
 			e.hashTable[string(hashcode)] = []*Row{row}
 		} else {
 			e.hashTable[string(hashcode)] = append(rows, row)
 		}
+		err = e.memTracker.Consume(row.memUsage() + int64(len(hashcode)))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		numRows++
+		if numRows > maxRowsInMemory || e.memTracker.ShouldSpill() {
+			err = e.spillHashTable()
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	if e.smallSpill != nil {
+		var err error
+		e.bigSpill, err = newSpillPartitions(hashSpillPartitions)
+		if err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	e.resultCh = make(chan *execResult, e.concurrency)
@@ -257,8 +316,32 @@ func (e *HashJoinExec) prepare() error {
 	return nil
 }
 
+// spillHashTable moves the rows in the hash table to the partitions on disk.
+func (e *HashJoinExec) spillHashTable() error {
+	var err error
+	e.smallSpill, err = newSpillPartitions(hashSpillPartitions)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for hashcode, rows := range e.hashTable {
+		for _, row := range rows {
+			err = e.smallSpill.add([]byte(hashcode), row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	// The hash table is kept empty, so the big table rows that can't be spilled find no matching rows in it.
+	e.hashTable = make(map[string][]*Row)
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return nil
+}
+
 func (e *HashJoinExec) waitJoinWorkersAndCloseResultChan() {
 	e.wg.Wait()
+	if e.bigSpill != nil {
+		e.joinSpilledPartitions()
+	}
 	close(e.resultCh)
 	e.hashTable = nil
 	close(e.closeCh)
@@ -289,7 +372,12 @@ func (e *HashJoinExec) runJoinWorker(idx int) {
 			break
 		}
 		for _, bigRow := range bigTableResult.rows {
-			succ := e.joinOneBigRow(e.hashJoinContexts[idx], bigRow, result)
+			var succ bool
+			if e.bigSpill != nil {
+				succ = e.spillOneBigRow(e.hashJoinContexts[idx], bigRow, result)
+			} else {
+				succ = e.joinOneBigRow(e.hashJoinContexts[idx], bigRow, result)
+			}
 			if !succ {
 				break
 			}
@@ -338,6 +426,103 @@ func (e *HashJoinExec) joinOneBigRow(ctx *hashJoinCtx, bigRow *Row, result *exec
 	return true
 }
 
+// spillOneBigRow writes a row in the big table to the partition its join key belongs to.
+// If the row can't match any row in the small table and it is outer join, a null filled result row is created.
+func (e *HashJoinExec) spillOneBigRow(ctx *hashJoinCtx, bigRow *Row, result *execResult) bool {
+	var err error
+	bigMatched := true
+	if e.bigFilter != nil {
+		bigMatched, err = expression.EvalBool(ctx.bigFilter, bigRow.Data, e.ctx)
+		if err != nil {
+			result.err = errors.Trace(err)
+			return false
+		}
+	}
+	if bigMatched {
+		sc := e.ctx.GetSessionVars().StmtCtx
+		hasNull, hashcode, err := getHashKey(sc, e.bigHashKey, bigRow, e.targetTypes, ctx.datumBuffer, ctx.hashKeyBuffer[0:0:cap(ctx.hashKeyBuffer)])
+		if err != nil {
+			result.err = errors.Trace(err)
+			return false
+		}
+		if !hasNull {
+			err = e.bigSpill.add(hashcode, bigRow)
+			if err != nil {
+				result.err = errors.Trace(err)
+				return false
+			}
+			return true
+		}
+	}
+	if e.outer {
+		result.rows = append(result.rows, e.fillRowWithDefaultValues(bigRow))
+	}
+	return true
+}
+
+// joinSpilledPartitions builds the hash table from every partition of the small table rows, and joins the
+// rows in the same partition of the big table with it.
+func (e *HashJoinExec) joinSpilledPartitions() {
+	ctx := e.hashJoinContexts[0]
+	result := &execResult{rows: make([]*Row, 0, batchSize)}
+	for i, smallFile := range e.smallSpill.files {
+		err := e.buildHashTableFromSpill(smallFile)
+		if err != nil {
+			e.resultCh <- &execResult{err: errors.Trace(err)}
+			return
+		}
+		bigFile := e.bigSpill.files[i]
+		for {
+			if e.finished.Load().(bool) {
+				return
+			}
+			bigRow, err := bigFile.next()
+			if err != nil {
+				e.resultCh <- &execResult{err: errors.Trace(err)}
+				return
+			}
+			if bigRow == nil {
+				break
+			}
+			if !e.joinOneBigRow(ctx, bigRow, result) {
+				e.resultCh <- result
+				return
+			}
+			if len(result.rows) >= batchSize {
+				e.resultCh <- result
+				result = &execResult{rows: make([]*Row, 0, batchSize)}
+			}
+		}
+	}
+	if len(result.rows) > 0 {
+		e.resultCh <- result
+	}
+}
+
+func (e *HashJoinExec) buildHashTableFromSpill(file *rowSpillFile) error {
+	sc := e.ctx.GetSessionVars().StmtCtx
+	e.hashTable = make(map[string][]*Row)
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	for {
+		row, err := file.next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			return nil
+		}
+		_, hashcode, err := getHashKey(sc, e.smallHashKey, row, e.targetTypes, e.hashJoinContexts[0].datumBuffer, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.hashTable[string(hashcode)] = append(e.hashTable[string(hashcode)], row)
+		err = e.memTracker.Consume(row.memUsage() + int64(len(hashcode)))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
 // constructMatchedRows creates matching result rows from a row in the big table.
 func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matchedRows []*Row, err error) {
 	sc := e.ctx.GetSessionVars().StmtCtx
@@ -612,6 +797,9 @@ type HashSemiJoinExec struct {
 	smallTableHasNull bool
 	// If anti is true, semi join only output the unmatched row.
 	anti bool
+
+	// memTracker tracks the memory usage of the hash table.
+	memTracker *memory.Tracker
 }
 
 // Close implements the Executor Close interface.
@@ -620,6 +808,7 @@ func (e *HashSemiJoinExec) Close() error {
 	e.hashTable = make(map[string][]*Row)
 	e.smallTableHasNull = false
 	e.resultRows = nil
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
 	err := e.smallExec.Close()
 	if err != nil {
 		return errors.Trace(err)
@@ -640,6 +829,7 @@ func (e *HashSemiJoinExec) prepare() error {
 		return errors.Trace(err)
 	}
 	e.hashTable = make(map[string][]*Row)
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
 	sc := e.ctx.GetSessionVars().StmtCtx
 	e.resultRows = make([]*Row, 1)
 	for {
@@ -675,6 +865,10 @@ func (e *HashSemiJoinExec) prepare() error {
 		} else {
 			e.hashTable[string(hashcode)] = append(rows, row)
 		}
+		err = e.memTracker.Consume(row.memUsage() + int64(len(hashcode)))
+		if err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	e.prepared = true
@@ -794,12 +988,54 @@ func (e *HashSemiJoinExec) Next() (*Row, error) {
 }
 
 // ApplyJoinExec is the new logic of apply.
+// For every outer row, it sets the correlated columns and executes the inner plan again. The rows of the inner plan
+// are cached by the values of the correlated columns, so the outer rows with the same values don't execute the inner
+// plan again. In parallel mode, the outer rows are fetched and filtered in a background goroutine, and the inner plan
+// is executed for them by concurrent workers, each worker has its own inner executor and correlated columns. The
+// results are returned in the order of the outer rows.
 type ApplyJoinExec struct {
 	join        joinExec
 	outerSchema []*expression.CorrelatedColumn
 	cursor      int
 	resultRows  []*Row
 	schema      *expression.Schema
+
+	// cache is the cache of the inner rows shared by the inner executors, it's nil if they can't be cached.
+	cache *applyCache
+
+	parallel bool
+	// workers execute the inner plan in parallel mode. The first one uses the inner executor of join, the others use
+	// the copies of the inner plan.
+	workers []*applyWorker
+	// outerCh receives the outer rows fetched in parallel mode, in the order of the outer rows.
+	outerCh chan *applyOuterRow
+	// taskCh sends the fetched outer rows to the workers.
+	taskCh  chan *applyOuterRow
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// applyOuterRow is an outer row fetched in parallel mode.
+type applyOuterRow struct {
+	row   *Row
+	match bool
+	err   error
+	// resultCh receives the joined rows of row from the worker.
+	resultCh chan *applyInnerResult
+}
+
+// applyInnerResult is the joined rows of an outer row.
+type applyInnerResult struct {
+	rows []*Row
+	err  error
+}
+
+// applyWorker joins the outer rows with the rows of its inner executor.
+type applyWorker struct {
+	join    joinExec
+	corCols []*expression.CorrelatedColumn
+	// inner is the inner executor of join, it's nil if join is the join of ApplyJoinExec.
+	inner Executor
 }
 
 // Schema implements the Executor interface.
@@ -809,19 +1045,46 @@ func (e *ApplyJoinExec) Schema() *expression.Schema {
 
 // Close implements the Executor interface.
 func (e *ApplyJoinExec) Close() error {
+	if e.outerCh != nil {
+		close(e.closeCh)
+		e.wg.Wait()
+		e.outerCh = nil
+	}
 	e.cursor = 0
 	e.resultRows = nil
+	if e.cache != nil {
+		e.cache.reset()
+	}
+	for _, w := range e.workers {
+		if w.inner != nil {
+			if err := w.inner.Close(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
 	return e.join.Close()
 }
 
 // Next implements the Executor interface.
 func (e *ApplyJoinExec) Next() (*Row, error) {
+	if e.parallel && e.outerCh == nil {
+		e.startWorkers()
+	}
 	for {
 		if e.cursor < len(e.resultRows) {
 			row := e.resultRows[e.cursor]
 			e.cursor++
 			return row, nil
 		}
+		var err error
+		if e.parallel {
+			e.resultRows, err = e.fetchParallelResult()
+			if e.resultRows == nil || err != nil {
+				return nil, errors.Trace(err)
+			}
+			e.cursor = 0
+			continue
+		}
 		bigRow, match, err := e.join.fetchBigRow()
 		if bigRow == nil || err != nil {
 			return nil, errors.Trace(err)
@@ -840,3 +1103,219 @@ func (e *ApplyJoinExec) Next() (*Row, error) {
 		e.cursor = 0
 	}
 }
+
+func (e *ApplyJoinExec) startWorkers() {
+	e.outerCh = make(chan *applyOuterRow, batchSize)
+	e.taskCh = make(chan *applyOuterRow, len(e.workers))
+	e.closeCh = make(chan struct{})
+	e.wg.Add(len(e.workers) + 1)
+	go e.fetchOuterRows()
+	for _, w := range e.workers {
+		go e.runWorker(w)
+	}
+}
+
+// fetchParallelResult returns the joined rows of the next outer row in parallel mode, the result is empty but not
+// nil if the outer row doesn't join any row. It returns nil if there is no more outer row.
+func (e *ApplyJoinExec) fetchParallelResult() ([]*Row, error) {
+	outerRow, ok := <-e.outerCh
+	if !ok {
+		return nil, nil
+	}
+	if outerRow.err != nil {
+		return nil, errors.Trace(outerRow.err)
+	}
+	result := <-outerRow.resultCh
+	if result.err != nil {
+		return nil, errors.Trace(result.err)
+	}
+	return result.rows, nil
+}
+
+// fetchOuterRows fetches the outer rows until there is no more row or an error happens.
+// It only calls fetchBigRow of join, which doesn't touch the inner side used by the first worker.
+func (e *ApplyJoinExec) fetchOuterRows() {
+	defer func() {
+		close(e.outerCh)
+		close(e.taskCh)
+		e.wg.Done()
+	}()
+	for {
+		row, match, err := e.join.fetchBigRow()
+		if row == nil && err == nil {
+			return
+		}
+		outerRow := &applyOuterRow{row: row, match: match, err: err}
+		if err == nil {
+			outerRow.resultCh = make(chan *applyInnerResult, 1)
+			select {
+			case <-e.closeCh:
+				return
+			case e.taskCh <- outerRow:
+			}
+		}
+		select {
+		case <-e.closeCh:
+			return
+		case e.outerCh <- outerRow:
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (e *ApplyJoinExec) runWorker(w *applyWorker) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-e.closeCh:
+			return
+		case task, ok := <-e.taskCh:
+			if !ok {
+				return
+			}
+			rows, err := w.joinOuterRow(task.row, task.match)
+			task.resultCh <- &applyInnerResult{rows: rows, err: err}
+		}
+	}
+}
+
+// joinOuterRow sets the correlated columns of w by the outer row and joins it with the inner rows.
+func (w *applyWorker) joinOuterRow(outerRow *Row, match bool) ([]*Row, error) {
+	for _, col := range w.corCols {
+		*col.Data = outerRow.Data[col.Index]
+	}
+	err := w.join.prepare()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rows, err := w.join.doJoin(outerRow, match)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The slice returned by doJoin is reused by the next call.
+	return append(make([]*Row, 0, len(rows)), rows...), nil
+}
+
+// applyCacheCapacity is the maximum memory usage in bytes of the inner rows cached by an apply executor, the inner
+// rows of the new correlated values are not cached after it's exceeded.
+var applyCacheCapacity int64 = 64 << 20
+
+// applyCache caches the rows of the inner plan of an apply by the values of the correlated columns, it's shared by
+// the inner executors of the workers.
+type applyCache struct {
+	sync.RWMutex
+	rows map[string][]*Row
+
+	// memTracker tracks the memory usage of the cached rows.
+	memTracker *memory.Tracker
+}
+
+func (c *applyCache) get(key string) ([]*Row, bool) {
+	c.RLock()
+	rows, ok := c.rows[key]
+	c.RUnlock()
+	return rows, ok
+}
+
+// put caches the rows of key. The rows are not cached if the capacity or the memory quota of the statement is
+// exceeded, the statement goes on without them.
+func (c *applyCache) put(key string, rows []*Row) {
+	c.Lock()
+	defer c.Unlock()
+	if c.memTracker.BytesConsumed() >= applyCacheCapacity {
+		return
+	}
+	memUsage := int64(len(key))
+	for _, r := range rows {
+		memUsage += r.memUsage()
+	}
+	if err := c.memTracker.Consume(memUsage); err != nil {
+		c.memTracker.Consume(-memUsage)
+		return
+	}
+	if c.rows == nil {
+		c.rows = make(map[string][]*Row)
+	}
+	c.rows[key] = rows
+}
+
+// reset clears the cache.
+func (c *applyCache) reset() {
+	c.Lock()
+	c.rows = nil
+	c.memTracker.Consume(-c.memTracker.BytesConsumed())
+	c.Unlock()
+}
+
+// applyCacheExec reads the rows of the inner plan of an apply from the cache, or from Src and caches them.
+type applyCacheExec struct {
+	Src     Executor
+	corCols []*expression.CorrelatedColumn
+	cache   *applyCache
+
+	// rows are the rows to return, they are read from the cache, or from Src and cached when Src is finished.
+	rows    []*Row
+	key     string
+	cached  bool
+	started bool
+	cursor  int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *applyCacheExec) Schema() *expression.Schema {
+	return e.Src.Schema()
+}
+
+// Close implements the Executor Close interface. It is called before the inner plan is executed for every outer
+// row, the cached rows are kept.
+func (e *applyCacheExec) Close() error {
+	e.rows = nil
+	e.started = false
+	e.cursor = 0
+	return e.Src.Close()
+}
+
+// Next implements the Executor Next interface.
+func (e *applyCacheExec) Next() (*Row, error) {
+	if !e.started {
+		key, err := e.correlatedKey()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.key = key
+		e.rows, e.cached = e.cache.get(key)
+		e.started = true
+	}
+	if e.cached {
+		if e.cursor >= len(e.rows) {
+			return nil, nil
+		}
+		row := e.rows[e.cursor]
+		e.cursor++
+		return row, nil
+	}
+	row, err := e.Src.Next()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row != nil {
+		e.rows = append(e.rows, row)
+		return row, nil
+	}
+	e.cached = true
+	e.cursor = len(e.rows)
+	e.cache.put(e.key, e.rows)
+	return nil, nil
+}
+
+// correlatedKey encodes the current values of the correlated columns.
+func (e *applyCacheExec) correlatedKey() (string, error) {
+	vals := make([]types.Datum, 0, len(e.corCols))
+	for _, col := range e.corCols {
+		vals = append(vals, *col.Data)
+	}
+	key, err := codec.EncodeValue(nil, vals...)
+	return string(key), errors.Trace(err)
+}
@@ -15,6 +15,7 @@ package executor_test
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -210,6 +211,42 @@ func (s *testSuite) TestJoin(c *C) {
 	result.Check(testkit.Rows("1 2 1 0", "2 3 2 0"))
 }
 
+func (s *testSuite) TestHashJoinSpill(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int, b int, c varchar(10))")
+	tk.MustExec("create table t2 (a int, b int, c varchar(10))")
+	for i := 0; i < 60; i++ {
+		tk.MustExec(fmt.Sprintf("insert t1 values (%d, %d, 'x%d')", i%20, i, i))
+		tk.MustExec(fmt.Sprintf("insert t2 values (%d, %d, 'y%d')", i%30, i%7, i))
+	}
+	tk.MustExec("insert t1 values (NULL, NULL, NULL)")
+	tk.MustExec("insert t2 values (NULL, NULL, NULL)")
+	queries := []string{
+		"select * from t1 join t2 on t1.a = t2.a",
+		"select * from t1 left join t2 on t1.a = t2.a and t2.b > 2 and t1.b > t2.b",
+		"select * from t1 right join t2 on t1.a = t2.a where t2.b < 5",
+		"select t1.c, t2.c from t1 join t2 on t1.a = t2.b and t1.c < t2.c",
+	}
+	var expected [][][]interface{}
+	for _, query := range queries {
+		expected = append(expected, tk.MustQuery(query).Sort().Rows())
+	}
+	tk.MustExec("set @@tidb_max_hash_rows_in_memory=3")
+	for i, query := range queries {
+		tk.MustQuery(query).Sort().Check(expected[i])
+	}
+	// The row keys of the spilled rows are kept for the update executor.
+	tk.MustExec("update t1 join t2 on t1.a = t2.a set t1.c = 'z', t2.c = 'z' where t1.a < 10")
+	tk.MustQuery("select count(*) from t1 where c = 'z'").Check(testkit.Rows("30"))
+	tk.MustQuery("select count(*) from t2 where c = 'z'").Check(testkit.Rows("20"))
+}
+
 func (s *testSuite) TestMultiJoin(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -323,13 +360,12 @@ func (s *testSuite) TestSubquerySameTable(c *C) {
 }
 
 func (s *testSuite) TestSubquery(c *C) {
-	plan.JoinConcurrency = 1
 	defer func() {
 		s.cleanEnv(c)
 		testleak.AfterTest(c)()
-		plan.JoinConcurrency = 5
 	}()
 	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("set @@tidb_hash_join_concurrency=1")
 	tk.MustExec("use test")
 	tk.MustExec("drop table if exists t")
 	tk.MustExec("create table t (c int, d int)")
@@ -508,14 +544,12 @@ func (s *testSuite) TestInSubquery(c *C) {
 }
 
 func (s *testSuite) TestJoinLeak(c *C) {
-	savedConcurrency := plan.JoinConcurrency
-	plan.JoinConcurrency = 1
 	defer func() {
-		plan.JoinConcurrency = savedConcurrency
 		s.cleanEnv(c)
 		testleak.AfterTest(c)()
 	}()
 	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("set @@tidb_hash_join_concurrency=1")
 	tk.MustExec("use test")
 	tk.MustExec("drop table if exists t")
 	tk.MustExec("create table t (d int)")
@@ -531,3 +565,46 @@ func (s *testSuite) TestJoinLeak(c *C) {
 	time.Sleep(100 * time.Millisecond)
 	result.Close()
 }
+
+func checkIndexJoinAndRun(tk *testkit.TestKit, c *C, sql string) *testkit.Result {
+	result := tk.MustQuery("explain " + sql)
+	if !strings.Contains(fmt.Sprintf("%v", result.Rows()), "IndexJoin") {
+		c.Errorf("Expected IndexJoin in plan of %s.", sql)
+	}
+	return tk.MustQuery(sql)
+}
+
+func (s *testSuite) TestIndexLookupJoin(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("set @@tidb_index_join_batch_size=2")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("create table t(a int primary key, b int)")
+	tk.MustExec("create table s(a int, b int, key idx_b(b))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3), (4, 4)")
+	tk.MustExec("insert into s values (4, 1), (3, 2), (2, 3), (1, 5), (5, null), (4, 6)")
+
+	// Look up t by the handle, the order of s is kept.
+	result := checkIndexJoinAndRun(tk, c, "select /*+ TIDB_INLJ(s) */ s.a, t.b from s join t on s.a = t.a")
+	result.Check(testkit.Rows("4 4", "3 3", "2 2", "1 1", "4 4"))
+	result = checkIndexJoinAndRun(tk, c, "select /*+ TIDB_INLJ(s) */ s.a, t.a from s left join t on s.a = t.a and t.b > 2")
+	result.Check(testkit.Rows("4 4", "3 3", "2 <nil>", "1 <nil>", "5 <nil>", "4 4"))
+	result = checkIndexJoinAndRun(tk, c, "select /*+ TIDB_INLJ(s) */ s.a, t.a from s join t on s.a = t.a where s.b > 1 and t.b < 4")
+	result.Check(testkit.Rows("3 3", "2 2", "1 1"))
+
+	// Look up s by the index idx_b, the outer table is on the right side.
+	result = checkIndexJoinAndRun(tk, c, "select /*+ TIDB_INLJ(t) */ s.a, t.a from s right join t on s.b = t.a")
+	result.Check(testkit.Rows("4 1", "3 2", "2 3", "<nil> 4"))
+	result = checkIndexJoinAndRun(tk, c, "select /*+ TIDB_INLJ(t) */ s.a, t.a from s join t on s.b = t.b and s.a > t.a")
+	result.Check(testkit.Rows("4 1", "3 2"))
+	result = checkIndexJoinAndRun(tk, c, "select /*+ TIDB_INLJ(t) */ t.a, s.a from t join s on t.b = s.b and t.a = s.a")
+	result.Check(testkit.Rows())
+
+	// The hint is ignored if the inner table can't be looked up by the join key.
+	result = tk.MustQuery("select /*+ TIDB_INLJ(t) */ t.a, s.a from t join s on t.a = s.a order by t.a, s.a")
+	result.Check(testkit.Rows("1 1", "2 2", "3 3", "4 4", "4 4"))
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/statistics"
+)
+
+var _ Executor = &LoadStatsExec{}
+
+// LoadStatsExec represents a load statistic executor.
+// It reads the statistics dumped in json format and saves them to storage.
+type LoadStatsExec struct {
+	ctx  context.Context
+	is   infoschema.InfoSchema
+	path string
+	done bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *LoadStatsExec) Schema() *expression.Schema {
+	return expression.NewSchema()
+}
+
+// Close implements the Executor Close interface.
+func (e *LoadStatsExec) Close() error {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *LoadStatsExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	content, err := ioutil.ReadFile(e.path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	jsonTbl := &statistics.JSONTable{}
+	err = json.Unmarshal(content, jsonTbl)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tbl, err := e.is.TableByName(model.NewCIStr(jsonTbl.DatabaseName), model.NewCIStr(jsonTbl.TableName))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	statsTbl, err := statistics.TableStatsFromJSON(tbl.Meta(), jsonTbl)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return nil, errors.Trace(statsTbl.SaveToStorage(e.ctx))
+}
@@ -119,6 +119,13 @@ func (b *joinBuilder) DefaultVals(defaultValues []types.Datum) *joinBuilder {
 	return b
 }
 
+// makeDefaultRow creates the row used to pad the inner side of an outer join when there is no matching row.
+func (b *joinBuilder) makeDefaultRow(inner Executor) *Row {
+	data := make([]types.Datum, inner.Schema().Len())
+	copy(data, b.defaultValues)
+	return &Row{Data: data}
+}
+
 func (b *joinBuilder) BuildMergeJoin(assumeSortedDesc bool) (*MergeJoinExec, error) {
 	var leftJoinKeys, rightJoinKeys []*expression.Column
 	for _, eqCond := range b.eqConditions {
@@ -166,14 +173,14 @@ func (b *joinBuilder) BuildMergeJoin(assumeSortedDesc bool) (*MergeJoinExec, err
 		exec.leftRowBlock.filter = nil
 		exec.leftFilter = b.leftFilter
 		exec.preserveLeft = true
-		exec.defaultRightRow = &Row{Data: b.defaultValues}
+		exec.defaultRightRow = b.makeDefaultRow(b.rightChild)
 	case plan.RightOuterJoin:
 		exec.leftRowBlock = rightRowBlock
 		exec.rightRowBlock = leftRowBlock
 		exec.leftRowBlock.filter = nil
 		exec.leftFilter = b.leftFilter
 		exec.preserveLeft = true
-		exec.defaultRightRow = &Row{Data: b.defaultValues}
+		exec.defaultRightRow = b.makeDefaultRow(b.leftChild)
 		exec.flipSide = true
 		exec.leftJoinKeys = rightJoinKeys
 		exec.rightJoinKeys = leftJoinKeys
@@ -162,7 +162,7 @@ const plan3 = `[[TableScan_12 {
         "index filter conditions": null,
         "table filter conditions": null
     }
-} MergeJoin_9] [TableScan_15 {
+} MergeJoin_17] [TableScan_15 {
     "db": "test",
     "table": "t2",
     "desc": false,
@@ -173,7 +173,7 @@ const plan3 = `[[TableScan_12 {
         "index filter conditions": null,
         "table filter conditions": null
     }
-} MergeJoin_9] [MergeJoin_9 {
+} MergeJoin_17] [MergeJoin_17 {
     "eqCond": [
         "eq(test.t1.c1, test.t2.c1)"
     ],
@@ -183,7 +183,7 @@ const plan3 = `[[TableScan_12 {
     "leftPlan": "TableScan_12",
     "rightPlan": "TableScan_15",
     "desc": "false"
-} Sort_16] [Sort_16 {
+} Sort_20] [Sort_20 {
     "exprs": [
         {
             "Expr": "test.t1.c1",
@@ -191,7 +191,7 @@ const plan3 = `[[TableScan_12 {
         }
     ],
     "limit": null,
-    "child": "MergeJoin_9"
+    "child": "MergeJoin_17"
 } MergeJoin_8] [TableScan_23 {
     "db": "test",
     "table": "t3",
@@ -210,7 +210,7 @@ const plan3 = `[[TableScan_12 {
     "leftCond": null,
     "rightCond": null,
     "otherCond": [],
-    "leftPlan": "Sort_16",
+    "leftPlan": "Sort_20",
     "rightPlan": "TableScan_23",
     "desc": "false"
 } ]]`
@@ -16,6 +16,7 @@ package executor
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
@@ -40,17 +41,41 @@ var (
 			Name:      "expensive_query_total",
 			Help:      "Counter of expensive query.",
 		}, []string{"type"})
+	stmtDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "tidb",
+			Subsystem: "executor",
+			Name:      "statement_duration_seconds",
+			Help:      "Bucketed histogram of the duration of the statements, including the parse and compile time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 22),
+		}, []string{"type", "result"})
 )
 
 func init() {
 	prometheus.MustRegister(stmtNodeCounter)
 	prometheus.MustRegister(expensiveQueryCounter)
+	prometheus.MustRegister(stmtDurationHistogram)
 }
 
-func stmtCount(node ast.StmtNode, p plan.Plan) {
-	if stmtLabel := StatementLabel(node, p); stmtLabel != IGNORE {
+// stmtCount counts the statement by its label and returns the label.
+func stmtCount(node ast.StmtNode, p plan.Plan) string {
+	stmtLabel := StatementLabel(node, p)
+	if stmtLabel != IGNORE {
 		stmtNodeCounter.WithLabelValues(stmtLabel).Inc()
 	}
+	return stmtLabel
+}
+
+// observeStmtDuration observes the duration of the statement by its label.
+func observeStmtDuration(stmtLabel string, d time.Duration, succ bool) {
+	if stmtLabel == "" || stmtLabel == IGNORE {
+		return
+	}
+	result := "ok"
+	if !succ {
+		result = "error"
+	}
+	stmtDurationHistogram.WithLabelValues(stmtLabel, result).Observe(d.Seconds())
 }
 
 const (
@@ -90,12 +115,20 @@ const (
 	Insert = "Insert"
 	// LoadDataStmt represents load data statements.
 	LoadDataStmt = "LoadData"
+	// LoadStats represents load stats statements.
+	LoadStats = "LoadStats"
+	// ReleaseSavepoint represents release savepoint statements.
+	ReleaseSavepoint = "ReleaseSavepoint"
 	// RollBack represents roll back statements.
 	RollBack = "RollBack"
+	// Savepoint represents savepoint statements.
+	Savepoint = "Savepoint"
 	// Set represents set statements.
 	Set = "Set"
 	// Show represents show statements.
 	Show = "Show"
+	// SplitRegion represents split region statements.
+	SplitRegion = "SplitRegion"
 	// TruncateTable represents truncate table statements.
 	TruncateTable = "TruncateTable"
 	// Update represents update statements.
@@ -133,7 +166,7 @@ func StatementLabel(node ast.StmtNode, p plan.Plan) string {
 		return DropIndex
 	case *ast.DropTableStmt:
 		return DropTable
-	case *ast.ExplainStmt:
+	case *ast.ExplainStmt, *ast.ExplainForStmt:
 		return Explain
 	case *ast.InsertStmt:
 		if x.IsReplace {
@@ -142,14 +175,22 @@ func StatementLabel(node ast.StmtNode, p plan.Plan) string {
 		return Insert
 	case *ast.LoadDataStmt:
 		return LoadDataStmt
+	case *ast.LoadStatsStmt:
+		return LoadStats
 	case *ast.RollbackStmt:
 		return RollBack
+	case *ast.SavepointStmt:
+		return Savepoint
+	case *ast.ReleaseSavepointStmt:
+		return ReleaseSavepoint
 	case *ast.SelectStmt:
 		return getSelectStmtLabel(x, p)
 	case *ast.SetStmt, *ast.SetPwdStmt:
 		return Set
 	case *ast.ShowStmt:
 		return Show
+	case *ast.SplitRegionStmt:
+		return SplitRegion
 	case *ast.TruncateTableStmt:
 		return TruncateTable
 	case *ast.UpdateStmt:
@@ -237,7 +278,7 @@ func (pa *stmtAttributes) fromPlan(p plan.Plan) {
 		pa.hasApply = true
 	case *plan.PhysicalAggregation:
 		pa.hasAggregate = true
-	case *plan.PhysicalHashJoin:
+	case *plan.PhysicalHashJoin, *plan.PhysicalMergeJoin, *plan.PhysicalIndexJoin:
 		pa.hasJoin = true
 	case *plan.PhysicalTableScan:
 		pa.hasTableScan = true
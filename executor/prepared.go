@@ -186,6 +186,8 @@ type ExecuteExec struct {
 	StmtExec  Executor
 	Stmt      ast.StmtNode
 	Plan      plan.Plan
+	// stmtLabel is the label of the prepared statement, it's used by the metrics.
+	stmtLabel string
 }
 
 // Schema implements the Executor Schema interface.
@@ -231,6 +233,13 @@ func (e *ExecuteExec) Build() error {
 		prepared.Params[i].SetDatum(val)
 	}
 
+	if err := setStmtReadTS(e.Ctx, prepared.Stmt); err != nil {
+		return errors.Trace(err)
+	}
+	e.Ctx.GetSessionVars().StmtCtx.Priority = stmtPriority(prepared.Stmt)
+	if e.Ctx.GetSessionVars().StmtCtx.ReadTS != 0 {
+		e.IS = GetInfoSchema(e.Ctx)
+	}
 	if prepared.SchemaVersion != e.IS.SchemaMetaVersion() {
 		// If the schema version has changed we need to prepare it again,
 		// if this time it failed, the real reason for the error is schema changed.
@@ -244,7 +253,9 @@ func (e *ExecuteExec) Build() error {
 	if err != nil {
 		return errors.Trace(err)
 	}
-	if IsPointGetWithPKOrUniqueKeyByAutoCommit(e.Ctx, p) {
+	if readTS := e.Ctx.GetSessionVars().StmtCtx.ReadTS; readTS != 0 {
+		err = e.Ctx.InitTxnWithStartTS(readTS)
+	} else if IsPointGetWithPKOrUniqueKeyByAutoCommit(e.Ctx, p) {
 		err = e.Ctx.InitTxnWithStartTS(math.MaxUint64)
 	} else {
 		err = e.Ctx.ActivePendingTxn()
@@ -260,7 +271,7 @@ func (e *ExecuteExec) Build() error {
 	e.StmtExec = stmtExec
 	e.Stmt = prepared.Stmt
 	e.Plan = p
-	stmtCount(e.Stmt, e.Plan)
+	e.stmtLabel = stmtCount(e.Stmt, e.Plan)
 	return nil
 }
 
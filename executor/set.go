@@ -16,16 +16,21 @@ package executor
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/generallog"
+	"github.com/pingcap/tidb/util/stmtsummary"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -92,42 +97,79 @@ func (e *SetExecutor) executeSet() error {
 			return variable.UnknownSystemVar.GenByArgs(name)
 		}
 		if sysVar.Scope == variable.ScopeNone {
-			return errors.Errorf("Variable '%s' is a read only variable", name)
+			return variable.ErrReadOnly.GenByArgs(name)
 		}
 		if v.IsGlobal {
 			// Set global scope system variable.
 			if sysVar.Scope&variable.ScopeGlobal == 0 {
-				return errors.Errorf("Variable '%s' is a SESSION variable and can't be used with SET GLOBAL", name)
+				return variable.ErrLocalVariable.GenByArgs(name)
 			}
 			value, err := e.getVarValue(v, sysVar)
 			if err != nil {
 				return errors.Trace(err)
 			}
 			if value.IsNull() {
+				if name != variable.CharacterSetResults {
+					return variable.ErrCantSetToNull
+				}
 				value.SetString("")
 			}
-			svalue, err := value.ToString()
+			svalue, err := e.validateSysVarValue(v, name, value)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// Validate the value and let the running DDL job, the general log and the statement summary on this
+			// server use it at once, the other servers load it in a lease.
+			err = ddl.SetReorgVariable(name, svalue)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			err = generallog.SetVariable(name, svalue)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			err = stmtsummary.SetVariable(name, svalue)
 			if err != nil {
 				return errors.Trace(err)
 			}
+			// The value is saved in mysql.global_variables, the new sessions of all the servers load it.
 			err = sessionVars.GlobalVarsAccessor.SetGlobalSysVar(name, svalue)
 			if err != nil {
 				return errors.Trace(err)
 			}
+			log.Infof("[%d] set global system variable %s = %s", sessionVars.ConnectionID, name, svalue)
 		} else {
 			// Set session scope system variable.
 			if sysVar.Scope&variable.ScopeSession == 0 {
-				return errors.Errorf("Variable '%s' is a GLOBAL variable and should be set with SET GLOBAL", name)
+				return variable.ErrGlobalVariable.GenByArgs(name)
+			}
+			// The DEFAULT of a session only variable is the compiled-in value.
+			var defaultVar *variable.SysVar
+			if sysVar.Scope&variable.ScopeGlobal == 0 {
+				defaultVar = sysVar
 			}
-			value, err := e.getVarValue(v, nil)
+			value, err := e.getVarValue(v, defaultVar)
 			if err != nil {
 				return errors.Trace(err)
 			}
+			if !value.IsNull() {
+				svalue, err1 := e.validateSysVarValue(v, name, value)
+				if err1 != nil {
+					return errors.Trace(err1)
+				}
+				value = types.NewStringDatum(svalue)
+			}
+			oldSnapshotTS, oldSnapshot := sessionVars.SnapshotTS, sessionVars.Systems[variable.TiDBSnapshot]
 			err = varsutil.SetSessionSystemVar(sessionVars, name, value)
 			if err != nil {
 				return errors.Trace(err)
 			}
-			e.loadSnapshotInfoSchemaIfNeeded(name)
+			if err = e.loadSnapshotInfoSchemaIfNeeded(name); err != nil {
+				// The snapshot can't be read, the previous one is kept.
+				sessionVars.SnapshotTS = oldSnapshotTS
+				sessionVars.Systems[variable.TiDBSnapshot] = oldSnapshot
+				return errors.Trace(err)
+			}
 			valStr, _ := value.ToString()
 			log.Infof("[%d] set system variable %s = %s", sessionVars.ConnectionID, name, valStr)
 		}
@@ -181,6 +223,20 @@ func (e *SetExecutor) getVarValue(v *expression.VarAssignment, sysVar *variable.
 	return value, errors.Trace(err)
 }
 
+// validateSysVarValue checks the value assigned to the system variable, and returns the normalized value. The DEFAULT
+// value is the compiled-in value or the global value, which is valid already.
+func (e *SetExecutor) validateSysVarValue(v *expression.VarAssignment, name string, value types.Datum) (string, error) {
+	svalue, err := value.ToString()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if v.IsDefault {
+		return svalue, nil
+	}
+	svalue, err = varsutil.ValidateSetSystemVar(name, svalue)
+	return svalue, errors.Trace(err)
+}
+
 func (e *SetExecutor) loadSnapshotInfoSchemaIfNeeded(name string) error {
 	if name != variable.TiDBSnapshot {
 		return nil
@@ -188,14 +244,29 @@ func (e *SetExecutor) loadSnapshotInfoSchemaIfNeeded(name string) error {
 	vars := e.ctx.GetSessionVars()
 	if vars.SnapshotTS == 0 {
 		vars.SnapshotInfoschema = nil
+		vars.HoldSnapshot(nil)
 		return nil
 	}
-	log.Infof("[%d] loadSnapshotInfoSchema, SnapshotTS:%d", vars.ConnectionID, vars.SnapshotTS)
+	if vars.SnapshotTS > varsutil.GoTimeToTS(time.Now()) {
+		return variable.ErrWrongValueForVar.GenByArgs(name, vars.Systems[name])
+	}
+	// The snapshot is held before it's validated, so the GC can't pass it after the validation.
 	dom := sessionctx.GetDomain(e.ctx)
+	snapshot, err := dom.Store().GetSnapshot(kv.Version{Ver: vars.SnapshotTS})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = validateSnapshot(e.ctx, vars.SnapshotTS); err != nil {
+		snapshot.Release()
+		return errors.Trace(err)
+	}
+	log.Infof("[%d] loadSnapshotInfoSchema, SnapshotTS:%d", vars.ConnectionID, vars.SnapshotTS)
 	snapInfo, err := dom.GetSnapshotInfoSchema(vars.SnapshotTS)
 	if err != nil {
+		snapshot.Release()
 		return errors.Trace(err)
 	}
 	vars.SnapshotInfoschema = snapInfo
+	vars.HoldSnapshot(snapshot)
 	return nil
 }
@@ -15,9 +15,12 @@ package executor_test
 
 import (
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -37,21 +40,20 @@ func (s *testSuite) TestSetVar(c *C) {
 	testSQL = "SET @@global.autocommit = 1;"
 	tk.MustExec(testSQL)
 
-	// TODO: this test case should returns error.
-	// testSQL = "SET @@global.autocommit = null;"
-	// _, err := tk.Exec(testSQL)
-	// c.Assert(err, NotNil)
+	testSQL = "SET @@global.autocommit = null;"
+	_, err := tk.Exec(testSQL)
+	c.Assert(terror.ErrorEqual(err, variable.ErrCantSetToNull), IsTrue, Commentf("err %v", err))
 
 	testSQL = "SET @@autocommit = 1;"
 	tk.MustExec(testSQL)
 
 	testSQL = "SET @@autocommit = null;"
-	_, err := tk.Exec(testSQL)
+	_, err = tk.Exec(testSQL)
 	c.Assert(err, NotNil)
 
 	errTestSql := "SET @@date_format = 1;"
 	_, err = tk.Exec(errTestSql)
-	c.Assert(err, NotNil)
+	c.Assert(terror.ErrorEqual(err, variable.ErrReadOnly), IsTrue, Commentf("err %v", err))
 
 	errTestSql = "SET @@rewriter_enabled = 1;"
 	_, err = tk.Exec(errTestSql)
@@ -67,7 +69,7 @@ func (s *testSuite) TestSetVar(c *C) {
 
 	errTestSql = "SET @@global.timestamp = 1;"
 	_, err = tk.Exec(errTestSql)
-	c.Assert(err, NotNil)
+	c.Assert(terror.ErrorEqual(err, variable.ErrLocalVariable), IsTrue, Commentf("err %v", err))
 
 	// For issue 998
 	testSQL = "SET @issue998a=1, @issue998b=5;"
@@ -123,6 +125,75 @@ func (s *testSuite) TestSetVar(c *C) {
 	c.Assert(vars.SkipConstraintCheck, IsTrue)
 	tk.MustExec("set @@tidb_skip_constraint_check = '0'")
 	c.Assert(vars.SkipConstraintCheck, IsFalse)
+
+	// The values are checked by the types of the variables.
+	for _, t := range []struct {
+		sql string
+		err *terror.Error
+	}{
+		{"set @@autocommit = 2", variable.ErrWrongValueForVar},
+		{"set @@global.tidb_skip_utf8_check = 'yes'", variable.ErrWrongValueForVar},
+		{"set @@tidb_index_lookup_size = 'a'", variable.ErrWrongTypeForVar},
+		{"set @@global.tidb_index_lookup_size = 0", variable.ErrWrongValueForVar},
+		{"set @@sql_mode = 'strict_trans_tables,xxx'", variable.ErrWrongValueForVar},
+		{"set @@global.tx_isolation = 'xxx'", variable.ErrWrongValueForVar},
+		{"set @@character_set_client = 'xxx'", variable.ErrWrongValueForVar},
+		{"set @@tidb_ttl_job_enable = 1", variable.ErrGlobalVariable},
+	} {
+		_, err = tk.Exec(t.sql)
+		c.Assert(terror.ErrorEqual(err, t.err), IsTrue, Commentf("sql: %s, err: %v", t.sql, err))
+	}
+
+	// The normalized values are saved.
+	tk.MustExec("set @@global.autocommit = 'off', @@global.tidb_skip_utf8_check = 'on', @@global.tx_isolation = 'read-committed'")
+	tk.MustQuery("select @@global.autocommit, @@global.tidb_skip_utf8_check, @@global.tx_isolation").
+		Check(testkit.Rows("OFF 1 READ-COMMITTED"))
+	tk.MustExec("set @@global.tidb_index_lookup_size = '16', @@tidb_skip_constraint_check = true")
+	tk.MustQuery("select @@global.tidb_index_lookup_size, @@tidb_skip_constraint_check").Check(testkit.Rows("16 1"))
+	c.Assert(vars.SkipConstraintCheck, IsTrue)
+
+	// The global values are loaded by the new sessions.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustQuery("select @@autocommit, @@tidb_skip_utf8_check, @@tidb_index_lookup_size").Check(testkit.Rows("OFF 1 16"))
+	c.Assert(tk1.Se.(context.Context).GetSessionVars().IndexLookupSize, Equals, 16)
+	// The DEFAULT of a session only variable is the compiled-in value.
+	tk1.MustExec("set @@tidb_skip_constraint_check = 1")
+	tk1.MustExec("set @@tidb_skip_constraint_check = DEFAULT")
+	tk1.MustQuery("select @@tidb_skip_constraint_check").Check(testkit.Rows("0"))
+
+	tk.MustExec("set @@global.autocommit = 1, @@global.tidb_skip_utf8_check = 0, @@global.tx_isolation = DEFAULT")
+	tk.MustExec("set @@global.tidb_index_lookup_size = DEFAULT")
+}
+
+func (s *testSuite) TestSetGlobalPrivilege(c *C) {
+	defer testleak.AfterTest(c)()
+	save := privileges.Enable
+	privileges.Enable = true
+	defer func() {
+		privileges.Enable = save
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("create user 'testsetglobal'@'localhost'")
+	se, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	c.Assert(se.Auth("testsetglobal@localhost", nil, nil), IsTrue)
+
+	// The SUPER privilege is required to set the global variables, not the session variables.
+	_, err = se.Execute("set @@global.max_connections = 1")
+	c.Assert(err, NotNil)
+	_, err = se.Execute("set global sql_mode = ''")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select @@global.max_connections").Check(testkit.Rows("151"))
+	_, err = se.Execute("set @@session.sql_mode = ''")
+	c.Assert(err, IsNil)
+
+	tk.MustExec("grant super on *.* to 'testsetglobal'@'localhost'")
+	tk.MustExec("flush privileges")
+	_, err = se.Execute("set @@global.max_connections = 10")
+	c.Assert(err, IsNil)
+	tk.MustQuery("select @@global.max_connections").Check(testkit.Rows("10"))
+	tk.MustExec("set @@global.max_connections = DEFAULT")
 }
 
 func (s *testSuite) TestSetCharset(c *C) {
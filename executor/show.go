@@ -23,6 +23,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
@@ -32,6 +33,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -156,11 +158,25 @@ func (e *ShowExec) fetchShowProcessList() error {
 		return nil
 	}
 
+	// The users without the PROCESS privilege only see their own connections.
+	checker := privilege.GetPrivilegeChecker(e.ctx)
+	hasProcessPriv := checker == nil || checker.RequestVerification("", "", "", mysql.ProcessPriv)
+	loginUser := e.ctx.GetSessionVars().User
 	pl := sm.ShowProcessList()
+	sort.Sort(processInfoSlice(pl))
 	for _, pi := range pl {
-		var t uint64
-		if len(pi.Info) != 0 {
-			t = uint64(time.Since(pi.Time) / time.Second)
+		if !hasProcessPriv && pi.User+"@"+pi.Host != loginUser {
+			continue
+		}
+		info := pi.Info
+		if !e.Full && len(info) > showProcessListInfoLen {
+			info = info[:showProcessListInfoLen]
+		}
+		var state string
+		if pi.Info != "" {
+			state = "executing"
+		} else if pi.State&mysql.ServerStatusInTrans > 0 {
+			state = "in transaction"
 		}
 		row := &Row{
 			Data: []types.Datum{
@@ -169,9 +185,9 @@ func (e *ShowExec) fetchShowProcessList() error {
 				types.NewStringDatum(pi.Host),
 				types.NewStringDatum(pi.DB),
 				types.NewStringDatum(pi.Command),
-				types.NewUintDatum(t),
-				types.NewStringDatum(fmt.Sprintf("%d", pi.State)),
-				types.NewStringDatum(pi.Info),
+				types.NewUintDatum(uint64(time.Since(pi.Time) / time.Second)),
+				types.NewStringDatum(state),
+				types.NewStringDatum(info),
 			},
 		}
 		e.rows = append(e.rows, row)
@@ -179,6 +195,17 @@ func (e *ShowExec) fetchShowProcessList() error {
 	return nil
 }
 
+// showProcessListInfoLen is the length of the statements shown by SHOW PROCESSLIST, SHOW FULL PROCESSLIST shows
+// the whole statements.
+const showProcessListInfoLen = 100
+
+// processInfoSlice sorts the connections by their IDs.
+type processInfoSlice []util.ProcessInfo
+
+func (s processInfoSlice) Len() int           { return len(s) }
+func (s processInfoSlice) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s processInfoSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 func (e *ShowExec) fetchShowTables() error {
 	if !e.is.SchemaExists(e.DBName) {
 		return errors.Errorf("Can not find DB: %s", e.DBName)
@@ -295,6 +322,7 @@ func (e *ShowExec) fetchShowIndex() error {
 			"BTREE",          // Index_type
 			"",               // Comment
 			"",               // Index_comment
+			nil,              // Expression
 		)
 		e.rows = append(e.rows, &Row{Data: data})
 	}
@@ -308,12 +336,17 @@ func (e *ShowExec) fetchShowIndex() error {
 			if col.Length != types.UnspecifiedLength {
 				subPart = col.Length
 			}
+			// The part of the expression index shows the expression rather than the hidden column.
+			var colName, expr interface{} = col.Name.O, nil
+			if colInfo := tb.Meta().Columns[col.Offset]; colInfo.IsGenerated() {
+				colName, expr = nil, colInfo.GeneratedExprString
+			}
 			data := types.MakeDatums(
 				tb.Meta().Name.O,  // Table
 				nonUniq,           // Non_unique
 				idx.Meta().Name.O, // Key_name
 				i+1,               // Seq_in_index
-				col.Name.O,        // Column_name
+				colName,           // Column_name
 				"utf8_bin",        // Colation
 				0,                 // Cardinality
 				subPart,           // Sub_part
@@ -322,6 +355,7 @@ func (e *ShowExec) fetchShowIndex() error {
 				idx.Meta().Tp.String(), // Index_type
 				"",                 // Comment
 				idx.Meta().Comment, // Index_comment
+				expr,               // Expression
 			)
 			e.rows = append(e.rows, &Row{Data: data})
 		}
@@ -397,17 +431,31 @@ func (e *ShowExec) fetchShowCreateTable() error {
 
 	// TODO: let the result more like MySQL.
 	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("CREATE TABLE `%s` (\n", tb.Meta().Name.O))
+	if tb.Meta().Temporary {
+		buf.WriteString(fmt.Sprintf("CREATE TEMPORARY TABLE `%s` (\n", tb.Meta().Name.O))
+	} else {
+		buf.WriteString(fmt.Sprintf("CREATE TABLE `%s` (\n", tb.Meta().Name.O))
+	}
 	var pkCol *table.Column
+	tblCharset, tblCollate := ddl.GetTableCharsetAndCollate(tb.Meta())
 	for i, col := range tb.Cols() {
 		buf.WriteString(fmt.Sprintf("  `%s` %s", col.Name.O, col.GetTypeDesc()))
+		if col.Charset != "" && col.Charset != charset.CharsetBin &&
+			(col.Charset != tblCharset || (col.Collate != "" && col.Collate != tblCollate)) {
+			buf.WriteString(fmt.Sprintf(" CHARACTER SET %s", col.Charset))
+			if col.Collate != "" {
+				buf.WriteString(fmt.Sprintf(" COLLATE %s", col.Collate))
+			}
+		}
 		if mysql.HasAutoIncrementFlag(col.Flag) {
 			buf.WriteString(" NOT NULL AUTO_INCREMENT")
 		} else {
 			if mysql.HasNotNullFlag(col.Flag) {
 				buf.WriteString(" NOT NULL")
 			}
-			if !mysql.HasNoDefaultValueFlag(col.Flag) {
+			if col.DefaultIsExpr {
+				buf.WriteString(fmt.Sprintf(" DEFAULT (%s)", col.DefaultValue))
+			} else if !mysql.HasNoDefaultValueFlag(col.Flag) {
 				switch col.DefaultValue {
 				case nil:
 					if !mysql.HasNotNullFlag(col.Flag) {
@@ -419,15 +467,18 @@ func (e *ShowExec) fetchShowCreateTable() error {
 				case "CURRENT_TIMESTAMP":
 					buf.WriteString(" DEFAULT CURRENT_TIMESTAMP")
 				default:
-					buf.WriteString(fmt.Sprintf(" DEFAULT '%v'", col.DefaultValue))
+					buf.WriteString(fmt.Sprintf(" DEFAULT '%s'", escapeSingleQuote(fmt.Sprintf("%v", col.DefaultValue))))
 				}
 			}
 			if mysql.HasOnUpdateNowFlag(col.Flag) {
 				buf.WriteString(" ON UPDATE CURRENT_TIMESTAMP")
 			}
 		}
+		if tb.Meta().AutoRandomBits > 0 && col.IsPKHandleColumn(tb.Meta()) {
+			buf.WriteString(fmt.Sprintf(" AUTO_RANDOM(%d)", tb.Meta().AutoRandomBits))
+		}
 		if len(col.Comment) > 0 {
-			buf.WriteString(fmt.Sprintf(" COMMENT '%s'", col.Comment))
+			buf.WriteString(fmt.Sprintf(" COMMENT '%s'", escapeSingleQuote(col.Comment)))
 		}
 		if i != len(tb.Cols())-1 {
 			buf.WriteString(",\n")
@@ -440,7 +491,7 @@ func (e *ShowExec) fetchShowCreateTable() error {
 	if pkCol != nil {
 		// If PKIsHanle, pk info is not in tb.Indices(). We should handle it here.
 		buf.WriteString(",\n")
-		buf.WriteString(fmt.Sprintf(" PRIMARY KEY (`%s`)", pkCol.Name.O))
+		buf.WriteString(fmt.Sprintf(" PRIMARY KEY (`%s`) /*T![clustered_index] CLUSTERED */", pkCol.Name.O))
 	}
 
 	if len(tb.Indices()) > 0 || len(tb.Meta().ForeignKeys) > 0 {
@@ -459,9 +510,19 @@ func (e *ShowExec) fetchShowCreateTable() error {
 
 		cols := make([]string, 0, len(idxInfo.Columns))
 		for _, c := range idxInfo.Columns {
-			cols = append(cols, c.Name.O)
+			if colInfo := tb.Meta().Columns[c.Offset]; colInfo.IsGenerated() {
+				cols = append(cols, fmt.Sprintf("(%s)", colInfo.GeneratedExprString))
+				continue
+			}
+			cols = append(cols, fmt.Sprintf("`%s`", c.Name.O))
+		}
+		buf.WriteString(fmt.Sprintf("(%s)", strings.Join(cols, ",")))
+		if idxInfo.Primary {
+			buf.WriteString(" /*T![clustered_index] NONCLUSTERED */")
+		}
+		if idxInfo.Invisible {
+			buf.WriteString(" /*!80000 INVISIBLE */")
 		}
-		buf.WriteString(fmt.Sprintf("(`%s`)", strings.Join(cols, "`,`")))
 		if i != len(tb.Indices())-1 {
 			buf.WriteString(",\n")
 		}
@@ -507,13 +568,41 @@ func (e *ShowExec) fetchShowCreateTable() error {
 	if s := tb.Meta().Charset; len(s) > 0 {
 		buf.WriteString(fmt.Sprintf(" DEFAULT CHARSET=%s", s))
 	}
+	if s := tb.Meta().Collate; len(s) > 0 {
+		buf.WriteString(fmt.Sprintf(" COLLATE=%s", s))
+	}
 
 	if tb.Meta().AutoIncID > 0 {
 		buf.WriteString(fmt.Sprintf(" AUTO_INCREMENT=%d", tb.Meta().AutoIncID))
 	}
 
+	if tb.Meta().AutoIDCache > 0 {
+		buf.WriteString(fmt.Sprintf(" AUTO_ID_CACHE=%d", tb.Meta().AutoIDCache))
+	}
+
+	if tb.Meta().ShardRowIDBits > 0 {
+		buf.WriteString(fmt.Sprintf(" SHARD_ROW_ID_BITS=%d", tb.Meta().ShardRowIDBits))
+	}
+
+	if tb.Meta().PreSplitRegions > 0 {
+		buf.WriteString(fmt.Sprintf(" PRE_SPLIT_REGIONS=%d", tb.Meta().PreSplitRegions))
+	}
+
+	if storage := tb.Meta().StorageOptions; storage != nil {
+		writeTableStorageOptions(&buf, storage)
+	}
+
 	if len(tb.Meta().Comment) > 0 {
-		buf.WriteString(fmt.Sprintf(" COMMENT='%s'", tb.Meta().Comment))
+		buf.WriteString(fmt.Sprintf(" COMMENT='%s'", escapeSingleQuote(tb.Meta().Comment)))
+	}
+
+	if ttlInfo := tb.Meta().TTLInfo; ttlInfo != nil {
+		enable := "ON"
+		if !ttlInfo.Enable {
+			enable = "OFF"
+		}
+		buf.WriteString(fmt.Sprintf(" TTL=`%s` + INTERVAL %d %s TTL_ENABLE='%s' TTL_JOB_INTERVAL='%s'",
+			ttlInfo.ColumnName.O, ttlInfo.IntervalValue, ttlInfo.IntervalTimeUnit, enable, ttlInfo.JobInterval))
 	}
 
 	data := types.MakeDatums(tb.Meta().Name.O, buf.String())
@@ -521,6 +610,39 @@ func (e *ShowExec) fetchShowCreateTable() error {
 	return nil
 }
 
+// writeTableStorageOptions writes the storage options of the table in the order of MySQL.
+func writeTableStorageOptions(buf *bytes.Buffer, storage *model.TableStorageOptions) {
+	if storage.MaxRows > 0 {
+		fmt.Fprintf(buf, " MAX_ROWS=%d", storage.MaxRows)
+	}
+	if storage.MinRows > 0 {
+		fmt.Fprintf(buf, " MIN_ROWS=%d", storage.MinRows)
+	}
+	if storage.AvgRowLength > 0 {
+		fmt.Fprintf(buf, " AVG_ROW_LENGTH=%d", storage.AvgRowLength)
+	}
+	if storage.Checksum > 0 {
+		fmt.Fprintf(buf, " CHECKSUM=%d", storage.Checksum)
+	}
+	if storage.DelayKeyWrite > 0 {
+		fmt.Fprintf(buf, " DELAY_KEY_WRITE=%d", storage.DelayKeyWrite)
+	}
+	if storage.RowFormat != "" {
+		fmt.Fprintf(buf, " ROW_FORMAT=%s", storage.RowFormat)
+	}
+	if storage.KeyBlockSize > 0 {
+		fmt.Fprintf(buf, " KEY_BLOCK_SIZE=%d", storage.KeyBlockSize)
+	}
+	if storage.Compression != "" {
+		fmt.Fprintf(buf, " COMPRESSION='%s'", escapeSingleQuote(storage.Compression))
+	}
+}
+
+// escapeSingleQuote escapes the single quotes in the string literal of SHOW CREATE statements.
+func escapeSingleQuote(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
+
 // Compose show create database result.
 func (e *ShowExec) fetchShowCreateDatabase() error {
 	db, ok := e.is.SchemaByName(e.DBName)
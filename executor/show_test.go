@@ -47,7 +47,7 @@ func (s *testSuite) TestShow(c *C) {
 	row := result.Rows()[0]
 	// For issue https://github.com/pingcap/tidb/issues/1061
 	expectedRow := []interface{}{
-		"SHOW_test", "CREATE TABLE `SHOW_test` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n  `c1` int(11) DEFAULT NULL COMMENT 'c1_comment',\n  `c2` int(11) DEFAULT NULL,\n  `c3` int(11) DEFAULT '1',\n PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8 AUTO_INCREMENT=28934 COMMENT='table_comment'"}
+		"SHOW_test", "CREATE TABLE `SHOW_test` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n  `c1` int(11) DEFAULT NULL COMMENT 'c1_comment',\n  `c2` int(11) DEFAULT NULL,\n  `c3` int(11) DEFAULT '1',\n PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */\n) ENGINE=InnoDB DEFAULT CHARSET=utf8 AUTO_INCREMENT=28934 COMMENT='table_comment'"}
 	for i, r := range row {
 		c.Check(r, Equals, expectedRow[i])
 	}
@@ -66,7 +66,7 @@ func (s *testSuite) TestShow(c *C) {
 	c.Check(result.Rows(), HasLen, 1)
 	row = result.Rows()[0]
 	expectedRow = []interface{}{
-		"ptest", "CREATE TABLE `ptest` (\n  `a` int(11) NOT NULL,\n  `b` double NOT NULL DEFAULT '2.0',\n  `c` varchar(10) NOT NULL,\n  `d` time DEFAULT NULL,\n  `e` timestamp NULL DEFAULT NULL,\n PRIMARY KEY (`a`),\n  UNIQUE KEY `d` (`d`)\n) ENGINE=InnoDB"}
+		"ptest", "CREATE TABLE `ptest` (\n  `a` int(11) NOT NULL,\n  `b` double NOT NULL DEFAULT '2.0',\n  `c` varchar(10) NOT NULL,\n  `d` time DEFAULT NULL,\n  `e` timestamp NULL DEFAULT NULL,\n PRIMARY KEY (`a`) /*T![clustered_index] CLUSTERED */,\n  UNIQUE KEY `d` (`d`)\n) ENGINE=InnoDB"}
 	for i, r := range row {
 		c.Check(r, Equals, expectedRow[i])
 	}
@@ -82,7 +82,7 @@ func (s *testSuite) TestShow(c *C) {
 	c.Check(result.Rows(), HasLen, 2)
 	expectedRow = []interface{}{
 		"show_index", int64(0), "PRIMARY", int64(1), "id", "utf8_bin",
-		int64(0), nil, nil, "", "BTREE", "", ""}
+		int64(0), nil, nil, "", "BTREE", "", "", nil}
 	row = result.Rows()[0]
 	c.Check(row, HasLen, len(expectedRow))
 	for i, r := range row {
@@ -90,7 +90,7 @@ func (s *testSuite) TestShow(c *C) {
 	}
 	expectedRow = []interface{}{
 		"show_index", int64(1), "cIdx", int64(1), "c", "utf8_bin",
-		int64(0), nil, nil, "YES", "HASH", "", "index_comment_for_cIdx"}
+		int64(0), nil, nil, "YES", "HASH", "", "index_comment_for_cIdx", nil}
 	row = result.Rows()[1]
 	c.Check(row, HasLen, len(expectedRow))
 	for i, r := range row {
@@ -205,7 +205,7 @@ func (s *testSuite) TestForeignKeyInShowCreateTable(c *C) {
 	sqlLines := []string{
 		"CREATE TABLE `show_test` (",
 		"  `id` int(11) NOT NULL AUTO_INCREMENT,",
-		" PRIMARY KEY (`id`),",
+		" PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */,",
 		"  CONSTRAINT `Fk` FOREIGN KEY (`id`) REFERENCES `t1` (`id`) ON DELETE CASCADE ON UPDATE CASCADE",
 		") ENGINE=InnoDB",
 	}
@@ -245,7 +245,7 @@ func (s *testSuite) TestForeignKeyInShowCreateTable(c *C) {
 	c.Check(result.Rows(), HasLen, 1)
 	row = result.Rows()[0]
 	expectedRow = []interface{}{
-		"show_test", "CREATE TABLE `show_test` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n PRIMARY KEY (`id`)\n) ENGINE=InnoDB"}
+		"show_test", "CREATE TABLE `show_test` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */\n) ENGINE=InnoDB"}
 	for i, r := range row {
 		c.Check(r, Equals, expectedRow[i])
 	}
@@ -257,12 +257,98 @@ func (s *testSuite) TestForeignKeyInShowCreateTable(c *C) {
 	c.Check(result.Rows(), HasLen, 1)
 	row = result.Rows()[0]
 	expectedRow = []interface{}{
-		"show_test", "CREATE TABLE `show_test` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n PRIMARY KEY (`id`),\n  CONSTRAINT `Fk` FOREIGN KEY (`id`) REFERENCES `t1` (`id`) ON DELETE CASCADE ON UPDATE CASCADE\n) ENGINE=InnoDB"}
+		"show_test", "CREATE TABLE `show_test` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */,\n  CONSTRAINT `Fk` FOREIGN KEY (`id`) REFERENCES `t1` (`id`) ON DELETE CASCADE ON UPDATE CASCADE\n) ENGINE=InnoDB"}
 	for i, r := range row {
 		c.Check(r, Equals, expectedRow[i])
 	}
 }
 
+func (s *testSuite) TestClusteredPrimaryKey(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2, t3")
+
+	// The integer primary key is clustered by default.
+	tk.MustExec("create table t1 (a int primary key clustered, b int)")
+	tk.MustQuery("show create table t1").Check(testkit.Rows("t1 CREATE TABLE `t1` (\n" +
+		"  `a` int(11) NOT NULL,\n  `b` int(11) DEFAULT NULL,\n PRIMARY KEY (`a`) /*T![clustered_index] CLUSTERED */\n) ENGINE=InnoDB"))
+	tk.MustExec("create table t2 (a int, b int, primary key (a) nonclustered)")
+	tk.MustQuery("show create table t2").Check(testkit.Rows("t2 CREATE TABLE `t2` (\n" +
+		"  `a` int(11) NOT NULL,\n  `b` int(11) DEFAULT NULL,\n  PRIMARY KEY (`a`) /*T![clustered_index] NONCLUSTERED */\n) ENGINE=InnoDB"))
+	tk.MustExec("insert into t2 values (1, 1), (2, 2)")
+	_, err := tk.Exec("insert into t2 values (1, 3)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select * from t2 where a = 2").Check(testkit.Rows("2 2"))
+
+	// The output of SHOW CREATE TABLE keeps the option.
+	tk.MustExec("drop table t2")
+	tk.MustExec("create table t2 (\n  `a` int(11) NOT NULL,\n  `b` int(11) DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`a`) /*T![clustered_index] NONCLUSTERED */\n) ENGINE=InnoDB")
+	tk.MustQuery("show index from t2").Check(testkit.Rows("t2 0 PRIMARY 1 a utf8_bin 0 <nil> <nil> YES    <nil>"))
+
+	// The non-integer primary key is non-clustered.
+	tk.MustExec("create table t3 (a varchar(10) key, b int)")
+	tk.MustQuery("show create table t3").Check(testkit.Rows("t3 CREATE TABLE `t3` (\n" +
+		"  `a` varchar(10) NOT NULL,\n  `b` int(11) DEFAULT NULL,\n  PRIMARY KEY (`a`) /*T![clustered_index] NONCLUSTERED */\n) ENGINE=InnoDB"))
+	// The rows can't be keyed by a non-integer or composite primary key.
+	_, err = tk.Exec("create table t4 (a varchar(10) primary key clustered)")
+	c.Assert(err, ErrorMatches, ".*CLUSTERED is not supported on a non-integer or composite primary key")
+	_, err = tk.Exec("create table t4 (a int, b int, primary key (a, b) clustered)")
+	c.Assert(err, ErrorMatches, ".*CLUSTERED is not supported on a non-integer or composite primary key")
+	_, err = tk.Exec("create table t4 (a int, b int, unique key (a) nonclustered)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table t3 add index idx_b (b) clustered")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table t1, t2, t3")
+}
+
+func (s *testSuite) TestShowCreateTableOptions(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1")
+
+	tk.MustExec("create table t1 (a varchar(10) charset latin1 comment 'it''s a', b varchar(10) default 'x''y', c int) " +
+		"default charset=utf8mb4 collate=utf8mb4_general_ci max_rows=100 row_format=compressed key_block_size=8 " +
+		"compression='zlib' comment='it''s t1'")
+	expected := "t1 CREATE TABLE `t1` (\n" +
+		"  `a` varchar(10) CHARACTER SET latin1 COLLATE latin1_swedish_ci DEFAULT NULL COMMENT 'it''s a',\n" +
+		"  `b` varchar(10) DEFAULT 'x''y',\n  `c` int(11) DEFAULT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci MAX_ROWS=100 ROW_FORMAT=COMPRESSED " +
+		"KEY_BLOCK_SIZE=8 COMPRESSION='zlib' COMMENT='it''s t1'"
+	tk.MustQuery("show create table t1").Check(testkit.Rows(expected))
+
+	// The table restored from the output of SHOW CREATE TABLE is the same.
+	createSQL := tk.MustQuery("show create table t1").Rows()[0][1].(string)
+	tk.MustExec("drop table t1")
+	tk.MustExec(createSQL)
+	tk.MustQuery("show create table t1").Check(testkit.Rows(expected))
+
+	// The options can be changed by ALTER TABLE, the existing columns are kept.
+	tk.MustExec("alter table t1 comment = 'new comment', row_format = dynamic, key_block_size = 0, compression = 'lz4'")
+	tk.MustExec("alter table t1 default charset = latin1")
+	tk.MustExec("alter table t1 add column d varchar(10)")
+	tk.MustQuery("show create table t1").Check(testkit.Rows("t1 CREATE TABLE `t1` (\n" +
+		"  `a` varchar(10) DEFAULT NULL COMMENT 'it''s a',\n" +
+		"  `b` varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT 'x''y',\n" +
+		"  `c` int(11) DEFAULT NULL,\n  `d` varchar(10) DEFAULT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1 MAX_ROWS=100 ROW_FORMAT=DYNAMIC COMPRESSION='lz4' COMMENT='new comment'"))
+	tk.MustExec("alter table t1 collate = utf8_general_ci")
+	tk.MustQuery("show create table t1").Check(testkit.Rows("t1 CREATE TABLE `t1` (\n" +
+		"  `a` varchar(10) CHARACTER SET latin1 COLLATE latin1_swedish_ci DEFAULT NULL COMMENT 'it''s a',\n" +
+		"  `b` varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT 'x''y',\n" +
+		"  `c` int(11) DEFAULT NULL,\n  `d` varchar(10) CHARACTER SET latin1 COLLATE latin1_swedish_ci DEFAULT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_general_ci MAX_ROWS=100 ROW_FORMAT=DYNAMIC " +
+		"COMPRESSION='lz4' COMMENT='new comment'"))
+
+	_, err := tk.Exec("alter table t1 default charset = unknown")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table t1 collate = unknown_ci")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table t1 default charset = latin1 collate = utf8_general_ci")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop table t1")
+}
+
 func (s *testSuite) TestShowWarnings(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
@@ -23,8 +23,11 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/resourcegroup"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/terror"
@@ -35,7 +38,7 @@ import (
 // SimpleExec represents simple statement executor.
 // For statements do simple execution.
 // includes `UseStmt`, 'SetStmt`, `DoStmt`,
-// `BeginStmt`, `CommitStmt`, `RollbackStmt`.
+// `BeginStmt`, `CommitStmt`, `RollbackStmt`, `SavepointStmt`, `ReleaseSavepointStmt`.
 // TODO: list all simple statements.
 type SimpleExec struct {
 	Statement ast.StmtNode
@@ -66,6 +69,10 @@ func (e *SimpleExec) Next() (*Row, error) {
 		e.executeCommit(x)
 	case *ast.RollbackStmt:
 		err = e.executeRollback(x)
+	case *ast.SavepointStmt:
+		err = e.executeSavepoint(x)
+	case *ast.ReleaseSavepointStmt:
+		err = e.executeReleaseSavepoint(x)
 	case *ast.CreateUserStmt:
 		err = e.executeCreateUser(x)
 	case *ast.AlterUserStmt:
@@ -76,6 +83,12 @@ func (e *SimpleExec) Next() (*Row, error) {
 		err = e.executeSetPwd(x)
 	case *ast.KillStmt:
 		err = e.executeKillStmt(x)
+	case *ast.CreateResourceGroupStmt:
+		err = e.executeCreateResourceGroup(x)
+	case *ast.AlterResourceGroupStmt:
+		err = e.executeAlterResourceGroup(x)
+	case *ast.DropResourceGroupStmt:
+		err = e.executeDropResourceGroup(x)
 	case *ast.BinlogStmt:
 		// We just ignore it.
 		return nil, nil
@@ -109,6 +122,9 @@ func (e *SimpleExec) executeUse(s *ast.UseStmt) error {
 }
 
 func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
+	if s.Mode == ast.Pessimistic && !variable.EnablePessimisticTxn {
+		return ErrPessimisticTxnDisabled.GenByArgs()
+	}
 	// If BEGIN is the first statement in TxnCtx, we can reuse the existing transaction, without the
 	// need to call NewTxn, which commits the existing transaction and begins a new one.
 	txnCtx := e.ctx.GetSessionVars().TxnCtx
@@ -117,11 +133,22 @@ func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
+		// The savepoints belong to the committed transaction.
+		txnCtx.Savepoints = nil
 	}
 	// With START TRANSACTION, autocommit remains disabled until you end
 	// the transaction with COMMIT or ROLLBACK. The autocommit mode then
 	// reverts to its previous state.
-	e.ctx.GetSessionVars().SetStatusFlag(mysql.ServerStatusInTrans, true)
+	vars := e.ctx.GetSessionVars()
+	vars.SetStatusFlag(mysql.ServerStatusInTrans, true)
+	// The global tidb_txn_mode may be loaded after the transaction context is prepared.
+	txnCtx = vars.TxnCtx
+	if s.Mode != "" {
+		txnCtx.IsPessimistic = s.Mode == ast.Pessimistic
+	} else {
+		txnCtx.IsPessimistic = vars.TxnMode == variable.TxnModePessimistic
+	}
+	e.ctx.Txn().SetOption(kv.Pessimistic, txnCtx.IsPessimistic)
 	return nil
 }
 
@@ -130,15 +157,83 @@ func (e *SimpleExec) executeCommit(s *ast.CommitStmt) {
 }
 
 func (e *SimpleExec) executeRollback(s *ast.RollbackStmt) error {
+	if s.SavepointName != "" {
+		return e.executeRollbackToSavepoint(s)
+	}
 	sessVars := e.ctx.GetSessionVars()
 	log.Infof("[%d] execute rollback statement", sessVars.ConnectionID)
 	sessVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
+	if tmpTables, ok := sessVars.TemporaryTables.(*infoschema.TemporaryTables); ok {
+		if err := tmpTables.RollbackTo(nil); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	if e.ctx.Txn().Valid() {
 		return e.ctx.Txn().Rollback()
 	}
 	return nil
 }
 
+// executeSavepoint sets a savepoint by beginning a staging of the transaction buffer, the changes made after it can
+// be discarded by rolling back to the savepoint. Like MySQL, the old savepoint with the same name is deleted, its
+// staging is left in the buffer and merged when the outer savepoint is released or the transaction ends.
+func (e *SimpleExec) executeSavepoint(s *ast.SavepointStmt) error {
+	txn := e.ctx.Txn()
+	if txn == nil || !txn.Valid() {
+		return nil
+	}
+	txnCtx := e.ctx.GetSessionVars().TxnCtx
+	name := strings.ToLower(s.Name)
+	if idx := txnCtx.FindSavepoint(name); idx >= 0 {
+		txnCtx.Savepoints = append(txnCtx.Savepoints[:idx], txnCtx.Savepoints[idx+1:]...)
+	}
+	sp := variable.SavepointRecord{
+		Name:          name,
+		StagingHandle: int(txn.Staging()),
+		DirtyDB:       getDirtyDB(e.ctx).clone(),
+	}
+	if tmpTables, ok := e.ctx.GetSessionVars().TemporaryTables.(*infoschema.TemporaryTables); ok {
+		sp.TemporaryTables = tmpTables.Checkpoint()
+	}
+	txnCtx.Savepoints = append(txnCtx.Savepoints, sp)
+	return nil
+}
+
+// executeRollbackToSavepoint discards the changes made after the savepoint and deletes the savepoints set after it,
+// the savepoint itself is kept. Like InnoDB, the locks acquired after the savepoint are not released.
+func (e *SimpleExec) executeRollbackToSavepoint(s *ast.RollbackStmt) error {
+	txnCtx := e.ctx.GetSessionVars().TxnCtx
+	idx := txnCtx.FindSavepoint(strings.ToLower(s.SavepointName))
+	if idx < 0 {
+		return ErrSavepointNotExists.GenByArgs(s.SavepointName)
+	}
+	txn := e.ctx.Txn()
+	sp := &txnCtx.Savepoints[idx]
+	txn.Cleanup(kv.StagingHandle(sp.StagingHandle))
+	sp.StagingHandle = int(txn.Staging())
+	// The savepoint keeps its own copy, so the transaction can be rolled back to it again.
+	txnCtx.DirtyDB = sp.DirtyDB.(*dirtyDB).clone()
+	if tmpTables, ok := e.ctx.GetSessionVars().TemporaryTables.(*infoschema.TemporaryTables); ok {
+		if err := tmpTables.RollbackTo(sp.TemporaryTables); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	txnCtx.Savepoints = txnCtx.Savepoints[:idx+1]
+	return nil
+}
+
+// executeReleaseSavepoint deletes the savepoint and the savepoints set after it, their changes are kept.
+func (e *SimpleExec) executeReleaseSavepoint(s *ast.ReleaseSavepointStmt) error {
+	txnCtx := e.ctx.GetSessionVars().TxnCtx
+	idx := txnCtx.FindSavepoint(strings.ToLower(s.Name))
+	if idx < 0 {
+		return ErrSavepointNotExists.GenByArgs(s.Name)
+	}
+	e.ctx.Txn().Release(kv.StagingHandle(txnCtx.Savepoints[idx].StagingHandle))
+	txnCtx.Savepoints = txnCtx.Savepoints[:idx]
+	return nil
+}
+
 func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
 	users := make([]string, 0, len(s.Specs))
 	for _, spec := range s.Specs {
@@ -168,7 +263,7 @@ func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
 		return nil
 	}
 	sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password) VALUES %s;`, mysql.SystemDB, mysql.UserTable, strings.Join(users, ", "))
-	_, err := e.ctx.(sqlexec.SQLExecutor).Execute(sql)
+	err := execInternalSQL(e.ctx, sql)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -191,6 +286,15 @@ func (e *SimpleExec) executeAlterUser(s *ast.AlterUserStmt) error {
 		}
 		s.Specs = []*ast.UserSpec{spec}
 	}
+	if s.ResourceGroup != "" && s.ResourceGroup != ast.DefaultResourceGroup {
+		exists, err := resourceGroupExists(e.ctx, s.ResourceGroup)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !exists {
+			return resourcegroup.ErrGroupNotExists.GenByArgs(s.ResourceGroup)
+		}
+	}
 
 	failedUsers := make([]string, 0, len(s.Specs))
 	for _, spec := range s.Specs {
@@ -206,16 +310,29 @@ func (e *SimpleExec) executeAlterUser(s *ast.AlterUserStmt) error {
 			}
 			continue
 		}
-		pwd := ""
-		if spec.AuthOpt != nil {
-			if spec.AuthOpt.ByAuthString {
-				pwd = util.EncodePassword(spec.AuthOpt.AuthString)
-			} else {
-				pwd = util.EncodePassword(spec.AuthOpt.HashString)
+		var sets []string
+		// The password is kept if the statement only assigns the resource group.
+		if spec.AuthOpt != nil || s.ResourceGroup == "" {
+			pwd := ""
+			if spec.AuthOpt != nil {
+				if spec.AuthOpt.ByAuthString {
+					pwd = util.EncodePassword(spec.AuthOpt.AuthString)
+				} else {
+					pwd = util.EncodePassword(spec.AuthOpt.HashString)
+				}
+			}
+			sets = append(sets, fmt.Sprintf(`Password = "%s"`, pwd))
+		}
+		if s.ResourceGroup != "" {
+			// The users of the default group are stored with an empty group.
+			group := s.ResourceGroup
+			if group == ast.DefaultResourceGroup {
+				group = ""
 			}
+			sets = append(sets, fmt.Sprintf(`Resource_group = "%s"`, group))
 		}
-		sql := fmt.Sprintf(`UPDATE %s.%s SET Password = "%s" WHERE Host = "%s" and User = "%s";`,
-			mysql.SystemDB, mysql.UserTable, pwd, host, userName)
+		sql := fmt.Sprintf(`UPDATE %s.%s SET %s WHERE Host = "%s" and User = "%s";`,
+			mysql.SystemDB, mysql.UserTable, strings.Join(sets, ", "), host, userName)
 		_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
 		if err != nil {
 			failedUsers = append(failedUsers, spec.User)
@@ -230,6 +347,9 @@ func (e *SimpleExec) executeAlterUser(s *ast.AlterUserStmt) error {
 		errMsg := "Operation ALTER USER failed for " + strings.Join(failedUsers, ",")
 		return terror.ClassExecutor.New(CodeCannotUser, errMsg)
 	}
+	if s.ResourceGroup != "" {
+		return errors.Trace(flushResourceGroups(e.ctx))
+	}
 	return nil
 }
 
@@ -281,6 +401,114 @@ func userExists(ctx context.Context, name string, host string) (bool, error) {
 	return len(rows) > 0, nil
 }
 
+func resourceGroupExists(ctx context.Context, name string) (bool, error) {
+	sql := fmt.Sprintf(`SELECT * FROM %s.%s WHERE Name="%s";`, mysql.SystemDB, mysql.ResourceGroupTable, name)
+	rows, _, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return len(rows) > 0, nil
+}
+
+// flushResourceGroups reloads the resource groups, so the changes take effect on this server immediately, the other
+// servers reload them in a lease.
+func flushResourceGroups(ctx context.Context) error {
+	if m := sessionctx.GetDomain(ctx).ResourceGroupManager(); m != nil {
+		return errors.Trace(m.Update())
+	}
+	return nil
+}
+
+// resourceGroupColumns are the columns of mysql.resource_group which store the options.
+var resourceGroupColumns = map[ast.ResourceGroupOptionType]string{
+	ast.ResourceGroupMaxConcurrency: "Max_concurrency",
+	ast.ResourceGroupRUPerSec:       "RU_per_sec",
+	ast.ResourceGroupQueueSize:      "Queue_size",
+}
+
+func (e *SimpleExec) executeCreateResourceGroup(s *ast.CreateResourceGroupStmt) error {
+	if s.Name == ast.DefaultResourceGroup {
+		return resourcegroup.ErrGroupDisallowed.GenByArgs("CREATE", ast.DefaultResourceGroup)
+	}
+	exists, err := resourceGroupExists(e.ctx, s.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if exists {
+		if s.IfNotExists {
+			return nil
+		}
+		return resourcegroup.ErrGroupExists.GenByArgs(s.Name)
+	}
+	cols := []string{"Name"}
+	vals := []string{fmt.Sprintf(`"%s"`, s.Name)}
+	for _, opt := range s.Options {
+		cols = append(cols, resourceGroupColumns[opt.Tp])
+		vals = append(vals, fmt.Sprintf("%d", opt.UintValue))
+	}
+	sql := fmt.Sprintf(`INSERT INTO %s.%s (%s) VALUES (%s);`, mysql.SystemDB, mysql.ResourceGroupTable,
+		strings.Join(cols, ", "), strings.Join(vals, ", "))
+	_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(flushResourceGroups(e.ctx))
+}
+
+// executeAlterResourceGroup changes the options in the statement, the other options are kept.
+func (e *SimpleExec) executeAlterResourceGroup(s *ast.AlterResourceGroupStmt) error {
+	if s.Name == ast.DefaultResourceGroup {
+		return resourcegroup.ErrGroupDisallowed.GenByArgs("ALTER", ast.DefaultResourceGroup)
+	}
+	exists, err := resourceGroupExists(e.ctx, s.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !exists {
+		return resourcegroup.ErrGroupNotExists.GenByArgs(s.Name)
+	}
+	sets := make([]string, 0, len(s.Options))
+	for _, opt := range s.Options {
+		sets = append(sets, fmt.Sprintf("%s = %d", resourceGroupColumns[opt.Tp], opt.UintValue))
+	}
+	sql := fmt.Sprintf(`UPDATE %s.%s SET %s WHERE Name = "%s";`, mysql.SystemDB, mysql.ResourceGroupTable,
+		strings.Join(sets, ", "), s.Name)
+	_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(flushResourceGroups(e.ctx))
+}
+
+// executeDropResourceGroup drops the resource group, its users are moved to the default group.
+func (e *SimpleExec) executeDropResourceGroup(s *ast.DropResourceGroupStmt) error {
+	if s.Name == ast.DefaultResourceGroup {
+		return resourcegroup.ErrGroupDisallowed.GenByArgs("DROP", ast.DefaultResourceGroup)
+	}
+	exists, err := resourceGroupExists(e.ctx, s.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !exists {
+		if s.IfExists {
+			return nil
+		}
+		return resourcegroup.ErrGroupNotExists.GenByArgs(s.Name)
+	}
+	sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE Name = "%s";`, mysql.SystemDB, mysql.ResourceGroupTable, s.Name)
+	_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sql = fmt.Sprintf(`UPDATE %s.%s SET Resource_group = "" WHERE Resource_group = "%s";`,
+		mysql.SystemDB, mysql.UserTable, s.Name)
+	_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(flushResourceGroups(e.ctx))
+}
+
 func (e *SimpleExec) executeSetPwd(s *ast.SetPwdStmt) error {
 	if len(s.User) == 0 {
 		vars := e.ctx.GetSessionVars()
@@ -304,14 +532,42 @@ func (e *SimpleExec) executeSetPwd(s *ast.SetPwdStmt) error {
 	return errors.Trace(err)
 }
 
+// execInternalSQL executes the sql in the transaction of the session. Like the sql executed by ExecRestrictedSQL,
+// it isn't logged, so the password hashes in it aren't exposed.
+func execInternalSQL(ctx context.Context, sql string) error {
+	sessVars := ctx.GetSessionVars()
+	save := sessVars.InRestrictedSQL
+	sessVars.InRestrictedSQL = true
+	defer func() {
+		sessVars.InRestrictedSQL = save
+	}()
+	_, err := ctx.(sqlexec.SQLExecutor).Execute(sql)
+	return errors.Trace(err)
+}
+
 func (e *SimpleExec) executeKillStmt(s *ast.KillStmt) error {
-	if s.TiDBExtension {
-		sm := e.ctx.GetSessionManager()
-		if sm == nil {
-			return nil
+	if !s.TiDBExtension {
+		// The connection IDs are only unique in a server, a KILL sent through a proxy may reach another server and
+		// kill a wrong connection.
+		e.ctx.GetSessionVars().StmtCtx.AppendWarning(errors.New("KILL is ignored, use KILL TIDB when connected to the server directly"))
+		return nil
+	}
+	sm := e.ctx.GetSessionManager()
+	if sm == nil {
+		return nil
+	}
+	pi, ok := sm.GetProcessInfo(s.ConnectionID)
+	if !ok {
+		return ErrNoSuchThread.GenByArgs(s.ConnectionID)
+	}
+	// Like MySQL, the SUPER privilege is required to kill the connections of the other users.
+	if pi.User+"@"+pi.Host != e.ctx.GetSessionVars().User {
+		checker := privilege.GetPrivilegeChecker(e.ctx)
+		if checker != nil && !checker.RequestVerification("", "", "", mysql.SuperPriv) {
+			return ErrKillDenied.GenByArgs(s.ConnectionID)
 		}
-		sm.Kill(s.ConnectionID, s.Query)
 	}
+	sm.Kill(s.ConnectionID, s.Query)
 	return nil
 }
 
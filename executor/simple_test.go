@@ -15,6 +15,8 @@ package executor_test
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
@@ -22,6 +24,7 @@ import (
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/privilege/privileges"
+	"github.com/pingcap/tidb/resourcegroup"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testkit"
@@ -85,6 +88,88 @@ func (s *testSuite) TestTransaction(c *C) {
 	tk.MustQuery("select * from txn").Check(testkit.Rows("1", "2"))
 }
 
+func (s *testSuite) TestSavepoint(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table sp (a int primary key, b int)")
+	ctx := tk.Se.(context.Context)
+
+	tk.MustExec("begin")
+	tk.MustExec("insert sp values (1, 1)")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("insert sp values (2, 2)")
+	tk.MustExec("savepoint s2")
+	tk.MustExec("insert sp values (3, 3)")
+	tk.MustQuery("select a from sp").Check(testkit.Rows("1", "2", "3"))
+	tk.MustExec("rollback to s2")
+	c.Assert(inTxn(ctx), IsTrue)
+	tk.MustQuery("select a from sp").Check(testkit.Rows("1", "2"))
+	tk.MustExec("rollback to savepoint S1")
+	tk.MustQuery("select a from sp").Check(testkit.Rows("1"))
+	// The savepoints set after the savepoint rolled back to are deleted.
+	_, err := tk.Exec("rollback to s2")
+	c.Assert(terror.ErrorEqual(err, executor.ErrSavepointNotExists), IsTrue, Commentf("%v", err))
+	// The savepoint is kept after it's rolled back to.
+	tk.MustExec("insert sp values (4, 4)")
+	tk.MustExec("rollback to s1")
+	tk.MustQuery("select a from sp").Check(testkit.Rows("1"))
+	tk.MustExec("insert sp values (5, 5)")
+	tk.MustExec("release savepoint s1")
+	_, err = tk.Exec("rollback to s1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrSavepointNotExists), IsTrue, Commentf("%v", err))
+	tk.MustExec("commit")
+	tk.MustQuery("select a from sp").Check(testkit.Rows("1", "5"))
+
+	// The updated and deleted rows are restored.
+	tk.MustExec("begin")
+	tk.MustExec("update sp set b = 10 where a = 1")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("delete from sp where a = 1")
+	tk.MustExec("update sp set b = 50 where a = 5")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("insert sp values (6, 6)")
+	tk.MustExec("rollback to s1")
+	tk.MustQuery("select * from sp").Check(testkit.Rows("5 50"))
+	tk.MustExec("rollback to s1")
+	tk.MustQuery("select * from sp").Check(testkit.Rows("5 50"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select * from sp").Check(testkit.Rows("1 1", "5 5"))
+
+	tk.MustExec("begin")
+	tk.MustExec("update sp set b = 10 where a = 1")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("delete from sp where a = 1")
+	tk.MustExec("rollback to s1")
+	tk.MustExec("commit")
+	tk.MustQuery("select * from sp").Check(testkit.Rows("1 10", "5 5"))
+
+	// The lock keys after the savepoint are discarded, so the commit doesn't conflict on them.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk.MustExec("begin")
+	tk.MustExec("insert sp values (7, 7)")
+	tk.MustExec("savepoint s1")
+	tk.MustQuery("select * from sp where a = 5 for update").Check(testkit.Rows("5 5"))
+	tk.MustExec("rollback to s1")
+	tk1.MustExec("update sp set b = 55 where a = 5")
+	tk.MustExec("commit")
+	tk.MustQuery("select * from sp").Check(testkit.Rows("1 10", "5 55", "7 7"))
+
+	// The savepoints end with the transaction.
+	tk.MustExec("begin")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("commit")
+	_, err = tk.Exec("release savepoint s1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrSavepointNotExists), IsTrue, Commentf("%v", err))
+	tk.MustExec("begin")
+	tk.MustExec("savepoint s1")
+	tk.MustExec("begin")
+	_, err = tk.Exec("rollback to s1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrSavepointNotExists), IsTrue, Commentf("%v", err))
+	tk.MustExec("rollback")
+}
+
 func inTxn(ctx context.Context) bool {
 	return (ctx.GetSessionVars().Status & mysql.ServerStatusInTrans) > 0
 }
@@ -245,3 +330,205 @@ func (s *testSuite) TestFlushPrivileges(c *C) {
 
 	privileges.Enable = save
 }
+
+// mockSessionManager manages the sessions of a test.
+type mockSessionManager struct {
+	sessions map[uint64]tidb.Session
+}
+
+func (sm *mockSessionManager) ShowProcessList() []util.ProcessInfo {
+	var pl []util.ProcessInfo
+	for _, se := range sm.sessions {
+		pl = append(pl, se.ShowProcess())
+	}
+	return pl
+}
+
+func (sm *mockSessionManager) GetProcessInfo(id uint64) (util.ProcessInfo, bool) {
+	se, ok := sm.sessions[id]
+	if !ok {
+		return util.ProcessInfo{}, false
+	}
+	return se.ShowProcess(), true
+}
+
+func (sm *mockSessionManager) Kill(id uint64, query bool) {
+	if se, ok := sm.sessions[id]; ok {
+		se.Cancel()
+	}
+}
+
+func (s *testSuite) TestProcessListAndKill(c *C) {
+	defer testleak.AfterTest(c)()
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk2.MustExec("use test")
+	id1 := tk1.Se.GetSessionVars().ConnectionID
+	id2 := tk2.Se.GetSessionVars().ConnectionID
+	sm := &mockSessionManager{sessions: map[uint64]tidb.Session{id1: tk1.Se, id2: tk2.Se}}
+	tk1.Se.SetSessionManager(sm)
+	tk2.Se.SetSessionManager(sm)
+
+	result := tk1.MustQuery("show processlist")
+	c.Assert(result.Rows(), HasLen, 2)
+	// Id, User, Host, db, Command, Time, State, Info.
+	c.Assert(fmt.Sprint(result.Rows()[0][4:]), Equals, "[Query 0 executing show processlist]")
+	c.Assert(fmt.Sprint(result.Rows()[1][3:]), Equals, "[test Sleep 0  ]")
+	// The statements are truncated unless it's SHOW FULL PROCESSLIST.
+	comment := " /* " + strings.Repeat("x", 100) + " */"
+	result = tk1.MustQuery("show processlist" + comment)
+	c.Assert(result.Rows()[0][7], Equals, ("show processlist" + comment)[:100])
+	result = tk1.MustQuery("show full processlist" + comment)
+	c.Assert(result.Rows()[0][7], Equals, "show full processlist"+comment)
+
+	// KILL QUERY interrupts the executing statement.
+	done := make(chan error, 1)
+	go func() {
+		rs, err := tk2.Exec("select sleep(10)")
+		if err == nil {
+			_, err = tidb.GetRows(rs)
+		}
+		done <- err
+	}()
+	for i := 0; i < 100; i++ {
+		if pi, _ := sm.GetProcessInfo(id2); pi.Info != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tk1.MustExec(fmt.Sprintf("kill tidb query %d", id2))
+	select {
+	case err := <-done:
+		c.Assert(terror.ErrorEqual(err, executor.ErrQueryInterrupted), IsTrue, Commentf("err: %v", err))
+	case <-time.After(5 * time.Second):
+		c.Fatal("the killed statement doesn't return")
+	}
+	// The next statement of the session isn't affected.
+	tk2.MustQuery("select 1").Check(testkit.Rows("1"))
+
+	_, err := tk1.Exec("kill tidb query 123456789")
+	c.Assert(terror.ErrorEqual(err, executor.ErrNoSuchThread), IsTrue)
+	// The connection IDs are only unique in a server, KILL without TIDB is ignored.
+	tk1.MustExec(fmt.Sprintf("kill %d", id2))
+	c.Assert(tk1.Se.GetSessionVars().StmtCtx.GetWarnings(), HasLen, 1)
+}
+
+func (s *testSuite) TestProcessListAndKillPrivilege(c *C) {
+	defer testleak.AfterTest(c)()
+	save := privileges.Enable
+	privileges.Enable = true
+	defer func() {
+		privileges.Enable = save
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("create user 'testkill'@'localhost'")
+	se, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	c.Assert(se.Auth("testkill@localhost", nil, nil), IsTrue)
+	id := tk.Se.GetSessionVars().ConnectionID
+	seID := se.GetSessionVars().ConnectionID
+	sm := &mockSessionManager{sessions: map[uint64]tidb.Session{id: tk.Se, seID: se}}
+	tk.Se.SetSessionManager(sm)
+	se.SetSessionManager(sm)
+
+	// The users without the PROCESS privilege only see their own connections.
+	rs, err := se.Execute("show processlist")
+	c.Assert(err, IsNil)
+	rows, err := tidb.GetRows(rs[0])
+	c.Assert(err, IsNil)
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0].GetUint64(), Equals, seID)
+	c.Assert(tk.MustQuery("show processlist").Rows(), HasLen, 2)
+
+	// The SUPER privilege is required to kill the connections of the other users.
+	_, err = se.Execute(fmt.Sprintf("kill tidb query %d", id))
+	c.Assert(terror.ErrorEqual(err, executor.ErrKillDenied), IsTrue, Commentf("err: %v", err))
+	_, err = se.Execute(fmt.Sprintf("kill tidb query %d", seID))
+	c.Assert(err, IsNil)
+
+	tk.MustExec("grant process, super on *.* to 'testkill'@'localhost'")
+	tk.MustExec("flush privileges")
+	rs, err = se.Execute("show processlist")
+	c.Assert(err, IsNil)
+	rows, err = tidb.GetRows(rs[0])
+	c.Assert(err, IsNil)
+	c.Assert(rows, HasLen, 2)
+	_, err = se.Execute(fmt.Sprintf("kill tidb query %d", id))
+	c.Assert(err, IsNil)
+}
+
+func (s *testSuite) TestResourceGroup(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("create resource group rg1 max_concurrency = 1, queue_size = 1")
+	tk.MustExec("create resource group if not exists rg1 max_concurrency = 2")
+	tk.MustQuery("select Name, Max_concurrency, RU_per_sec, Queue_size from mysql.resource_group").
+		Check(testkit.Rows(fmt.Sprintf("%v 1 0 1", []byte("rg1"))))
+	_, err := tk.Exec("create resource group rg1")
+	c.Assert(terror.ErrorEqual(err, resourcegroup.ErrGroupExists), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("create resource group default")
+	c.Assert(terror.ErrorEqual(err, resourcegroup.ErrGroupDisallowed), IsTrue, Commentf("err %v", err))
+	_, err = tk.Exec("alter resource group rg2 queue_size = 1")
+	c.Assert(terror.ErrorEqual(err, resourcegroup.ErrGroupNotExists), IsTrue, Commentf("err %v", err))
+	// The options not in ALTER RESOURCE GROUP are kept.
+	tk.MustExec("alter resource group rg1 ru_per_sec = 1000")
+	tk.MustQuery("select Name, Max_concurrency, RU_per_sec, Queue_size from mysql.resource_group").
+		Check(testkit.Rows(fmt.Sprintf("%v 1 1000 1", []byte("rg1"))))
+
+	tk.MustExec("create user 'rguser'@'localhost' identified by 'pwd'")
+	_, err = tk.Exec("alter user 'rguser'@'localhost' resource group rg2")
+	c.Assert(terror.ErrorEqual(err, resourcegroup.ErrGroupNotExists), IsTrue, Commentf("err %v", err))
+	// The password is kept when the user is assigned a resource group.
+	tk.MustExec("alter user 'rguser'@'localhost' resource group rg1")
+	tk.MustQuery(`select Resource_group, Password from mysql.user where User = "rguser"`).
+		Check(testkit.Rows(fmt.Sprintf("%v %v", []byte("rg1"), []byte(util.EncodePassword("pwd")))))
+
+	// The statements of the user wait in the queue of the group when the max concurrency is reached.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk2.MustExec("use test")
+	tk1.Se.GetSessionVars().User = "rguser@localhost"
+	tk2.Se.GetSessionVars().User = "rguser@localhost"
+	rs, err := tk1.Exec("select 1")
+	c.Assert(err, IsNil)
+	done := make(chan error, 1)
+	go func() {
+		rs, err := tk2.Exec("select 1")
+		if err == nil {
+			_, err = tidb.GetRows(rs)
+		}
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		c.Fatalf("the statement isn't limited by the group, err %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// The waiting statement is interrupted by KILL QUERY, it's sent until it reaches the waiting statement.
+	for err = nil; err == nil; {
+		tk2.Se.Cancel()
+		select {
+		case err = <-done:
+			c.Assert(terror.ErrorEqual(err, executor.ErrQueryInterrupted), IsTrue, Commentf("err %v", err))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	c.Assert(rs.Close(), IsNil)
+	tk2.MustQuery("select 1").Check(testkit.Rows("1"))
+
+	// The users of a dropped group are moved to the default group.
+	tk.MustExec("drop resource group rg1")
+	tk.MustExec("drop resource group if exists rg1")
+	_, err = tk.Exec("drop resource group default")
+	c.Assert(terror.ErrorEqual(err, resourcegroup.ErrGroupDisallowed), IsTrue, Commentf("err %v", err))
+	tk.MustQuery(`select Resource_group from mysql.user where User = "rguser"`).Check(testkit.Rows("[]"))
+	tk.MustExec("create resource group rg1 max_concurrency = 1")
+	tk.MustExec("alter user 'rguser'@'localhost' resource group rg1")
+	tk.MustExec("alter user 'rguser'@'localhost' resource group default")
+	tk.MustQuery(`select Resource_group from mysql.user where User = "rguser"`).Check(testkit.Rows("[]"))
+	tk.MustExec("drop resource group rg1")
+	tk.MustExec("drop user 'rguser'@'localhost'")
+}
@@ -15,15 +15,23 @@ package executor
 
 import (
 	"container/heap"
+	"io/ioutil"
+	"os"
 	"sort"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/filesort"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
+// sortSpillWorkers is the number of workers that sort and write the runs to temporary files concurrently
+// when the sort executor spills.
+var sortSpillWorkers = 2
+
 // SortExec represents sorting executor.
 type SortExec struct {
 	Src     Executor
@@ -34,12 +42,32 @@ type SortExec struct {
 	fetched bool
 	err     error
 	schema  *expression.Schema
+
+	// sorter is used instead of Rows when there are more rows than the session variable
+	// tidb_max_sort_rows_in_memory, it sorts the rows in runs written to temporary files and merges them on output.
+	sorter *filesort.FileSorter
+	// spilledRowKeys are the row keys of the spilled rows, the row's index in it is passed to sorter as the handle.
+	spilledRowKeys [][]*RowKeyEntry
+
+	// memTracker tracks the memory usage of the rows kept in Rows.
+	memTracker *memory.Tracker
 }
 
 // Close implements the Executor Close interface.
 func (e *SortExec) Close() error {
 	e.fetched = false
 	e.Rows = nil
+	e.Idx = 0
+	e.spilledRowKeys = nil
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	if e.sorter != nil {
+		err := e.sorter.Close()
+		e.sorter = nil
+		if err != nil {
+			e.Src.Close()
+			return errors.Trace(err)
+		}
+	}
 	return e.Src.Close()
 }
 
@@ -88,32 +116,21 @@ func (e *SortExec) Less(i, j int) bool {
 // Next implements the Executor Next interface.
 func (e *SortExec) Next() (*Row, error) {
 	if !e.fetched {
-		for {
-			srcRow, err := e.Src.Next()
-			if err != nil {
-				return nil, errors.Trace(err)
-			}
-			if srcRow == nil {
-				break
-			}
-			orderRow := &orderByRow{
-				row: srcRow,
-				key: make([]types.Datum, len(e.ByItems)),
-			}
-			for i, byItem := range e.ByItems {
-				orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-			}
-			e.Rows = append(e.Rows, orderRow)
+		err := e.fetchAll()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if e.sorter == nil {
+			sort.Sort(e)
 		}
-		sort.Sort(e)
 		e.fetched = true
 	}
 	if e.err != nil {
 		return nil, errors.Trace(e.err)
 	}
+	if e.sorter != nil {
+		return e.nextSpilled()
+	}
 	if e.Idx >= len(e.Rows) {
 		return nil, nil
 	}
@@ -122,6 +139,117 @@ func (e *SortExec) Next() (*Row, error) {
 	return row, nil
 }
 
+func (e *SortExec) fetchAll() error {
+	maxRowsInMemory := e.ctx.GetSessionVars().MaxSortRowsInMemory
+	for {
+		srcRow, err := e.Src.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if srcRow == nil {
+			return nil
+		}
+		orderRow := &orderByRow{
+			row: srcRow,
+			key: make([]types.Datum, len(e.ByItems)),
+		}
+		for i, byItem := range e.ByItems {
+			orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if e.sorter != nil {
+			err = e.spillRow(orderRow)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		e.Rows = append(e.Rows, orderRow)
+		err = e.memTracker.Consume(orderRow.memUsage())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(e.Rows) > maxRowsInMemory || e.memTracker.ShouldSpill() {
+			err = e.spill(maxRowsInMemory)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+}
+
+// spill creates a file sorter in a temporary directory, and moves the rows in memory to it.
+func (e *SortExec) spill(maxRowsInMemory int) error {
+	tmpDir, err := ioutil.TempDir("", "tidb_sort")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	byDesc := make([]bool, len(e.ByItems))
+	for i, by := range e.ByItems {
+		byDesc[i] = by.Desc
+	}
+	// The rows are spilled early when the memory quota is exceeded, the file sorter keeps fewer rows in that case.
+	bufSize := len(e.Rows)
+	if bufSize > maxRowsInMemory {
+		bufSize = maxRowsInMemory
+	}
+	if bufSize < batchSize {
+		bufSize = batchSize
+	}
+	e.sorter, err = new(filesort.Builder).
+		SetSC(e.ctx.GetSessionVars().StmtCtx).
+		SetSchema(len(e.ByItems), 1).
+		SetBuf(bufSize).
+		SetWorkers(sortSpillWorkers).
+		SetDesc(byDesc).
+		SetDir(tmpDir).
+		Build()
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return errors.Trace(err)
+	}
+	for _, orderRow := range e.Rows {
+		err = e.spillRow(orderRow)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.Rows = nil
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return nil
+}
+
+func (r *orderByRow) memUsage() int64 {
+	return r.row.memUsage() + datumsMemUsage(r.key)
+}
+
+func (e *SortExec) spillRow(orderRow *orderByRow) error {
+	val, err := encodeRowData(nil, orderRow.row.Data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	handle := int64(len(e.spilledRowKeys))
+	e.spilledRowKeys = append(e.spilledRowKeys, orderRow.row.RowKeys)
+	return errors.Trace(e.sorter.Input(orderRow.key, []types.Datum{types.NewBytesDatum(val)}, handle))
+}
+
+func (e *SortExec) nextSpilled() (*Row, error) {
+	_, val, handle, err := e.sorter.Output()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if val == nil {
+		return nil, nil
+	}
+	data, err := decodeRowData(val[0].GetBytes())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Row{Data: data, RowKeys: e.spilledRowKeys[handle]}, nil
+}
+
 // TopnExec implements a Top-N algorithm and it is built from a SELECT statement with ORDER BY and LIMIT.
 // Instead of sorting all the rows fetched from the table, it keeps the Top-N elements only in a heap to reduce memory usage.
 type TopnExec struct {
@@ -180,7 +308,12 @@ func (e *TopnExec) Next() (*Row, error) {
 	if !e.fetched {
 		e.Idx = int(e.limit.Offset)
 		e.totalCount = int(e.limit.Offset + e.limit.Count)
-		e.Rows = make([]*orderByRow, 0, e.totalCount+1)
+		// The heap grows as the rows are pushed, a large limit doesn't allocate all the space at the beginning.
+		initCap := e.totalCount
+		if initCap > batchSize {
+			initCap = batchSize
+		}
+		e.Rows = make([]*orderByRow, 0, initCap+1)
 		e.heapSize = 0
 		for {
 			srcRow, err := e.Src.Next()
@@ -206,13 +339,24 @@ func (e *TopnExec) Next() (*Row, error) {
 				// to reduce the number of comparisons.
 				e.Rows = append(e.Rows, orderRow)
 				if e.Less(0, e.heapSize) {
+					err = e.memTracker.Consume(orderRow.memUsage() - e.Rows[0].memUsage())
+					if err != nil {
+						return nil, errors.Trace(err)
+					}
 					e.Swap(0, e.heapSize)
 					heap.Fix(e, 0)
 				}
 				e.Rows = e.Rows[:e.heapSize]
 			} else {
+				err = e.memTracker.Consume(orderRow.memUsage())
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
 				heap.Push(e, orderRow)
 			}
+			if e.err != nil {
+				return nil, errors.Trace(e.err)
+			}
 		}
 		if e.limit.Offset == 0 {
 			sort.Sort(&e.SortExec)
@@ -221,6 +365,9 @@ func (e *TopnExec) Next() (*Row, error) {
 				heap.Pop(e)
 			}
 		}
+		if e.err != nil {
+			return nil, errors.Trace(e.err)
+		}
 		e.fetched = true
 	}
 	if e.Idx >= len(e.Rows) {
@@ -0,0 +1,290 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// hashSpillPartitions is the number of partitions the rows are spread into when a hash join or a hash aggregation
+// spills to disk.
+var hashSpillPartitions = 16
+
+// spillPartitions spreads the rows into a number of temporary files by the hash of their keys, so the rows with the
+// same key are in the same partition, and the partitions can be processed one by one in memory.
+type spillPartitions struct {
+	dir   string
+	files []*rowSpillFile
+	// mu protects the files from being written by multiple workers at the same time.
+	mu sync.Mutex
+}
+
+func newSpillPartitions(n int) (*spillPartitions, error) {
+	dir, err := ioutil.TempDir("", "tidb_spill")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	p := &spillPartitions{dir: dir, files: make([]*rowSpillFile, 0, n)}
+	for i := 0; i < n; i++ {
+		file, err := ioutil.TempFile(dir, "")
+		if err != nil {
+			p.close()
+			return nil, errors.Trace(err)
+		}
+		p.files = append(p.files, &rowSpillFile{file: file, writer: bufio.NewWriter(file)})
+	}
+	return p, nil
+}
+
+// add writes the row to the partition its key belongs to.
+func (p *spillPartitions) add(key []byte, row *Row) error {
+	h := fnv.New32a()
+	h.Write(key)
+	idx := int(h.Sum32() % uint32(len(p.files)))
+	p.mu.Lock()
+	err := p.files[idx].add(row)
+	p.mu.Unlock()
+	return errors.Trace(err)
+}
+
+// close closes the files and removes the temporary directory.
+func (p *spillPartitions) close() error {
+	var firstErr error
+	for _, file := range p.files {
+		err := file.file.Close()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.files = nil
+	err := os.RemoveAll(p.dir)
+	if firstErr == nil {
+		firstErr = err
+	}
+	return errors.Trace(firstErr)
+}
+
+// rowSpillFile writes the rows to a temporary file, then reads them back in the same order.
+// The row keys can't be encoded, they are kept in memory.
+type rowSpillFile struct {
+	file    *os.File
+	writer  *bufio.Writer
+	reader  *bufio.Reader
+	rowKeys [][]*RowKeyEntry
+	cursor  int
+	buf     []byte
+}
+
+func (f *rowSpillFile) add(row *Row) error {
+	var err error
+	f.buf, err = encodeRowData(f.buf[:0], row.Data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var head [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(head[:], uint64(len(f.buf)))
+	if _, err = f.writer.Write(head[:n]); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = f.writer.Write(f.buf); err != nil {
+		return errors.Trace(err)
+	}
+	f.rowKeys = append(f.rowKeys, row.RowKeys)
+	return nil
+}
+
+// next returns the next row written to the file, it returns nil when all the rows are read.
+// The file can't be written any more after next is called.
+func (f *rowSpillFile) next() (*Row, error) {
+	if f.reader == nil {
+		if err := f.writer.Flush(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Trace(err)
+		}
+		f.reader = bufio.NewReader(f.file)
+	}
+	if f.cursor >= len(f.rowKeys) {
+		return nil, nil
+	}
+	size, err := binary.ReadUvarint(f.reader)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if uint64(cap(f.buf)) < size {
+		f.buf = make([]byte, size)
+	}
+	f.buf = f.buf[:size]
+	if _, err = io.ReadFull(f.reader, f.buf); err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := decodeRowData(f.buf)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	row := &Row{Data: data, RowKeys: f.rowKeys[f.cursor]}
+	f.rowKeys[f.cursor] = nil
+	f.cursor++
+	return row, nil
+}
+
+// encodeRowData encodes the row data to be written to disk. Unlike the codec package, it keeps the kind and the
+// other attributes of every datum, so the decoded row is the same as the original one.
+func encodeRowData(b []byte, data []types.Datum) ([]byte, error) {
+	for _, d := range data {
+		b = append(b, d.Kind())
+		switch d.Kind() {
+		case types.KindNull:
+		case types.KindInt64:
+			b = codec.EncodeVarint(b, d.GetInt64())
+		case types.KindUint64:
+			b = codec.EncodeUvarint(b, d.GetUint64())
+		case types.KindFloat32, types.KindFloat64:
+			b = codec.EncodeFloat(b, d.GetFloat64())
+		case types.KindString, types.KindBytes:
+			b = codec.EncodeCompactBytes(b, d.GetBytes())
+		case types.KindMysqlDecimal:
+			b = codec.EncodeDecimal(b, d)
+		case types.KindMysqlDuration:
+			dur := d.GetMysqlDuration()
+			b = codec.EncodeVarint(b, int64(dur.Duration))
+			b = append(b, byte(dur.Fsp))
+		case types.KindMysqlTime:
+			t := d.GetMysqlTime()
+			v, err := t.ToPackedUint()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			b = codec.EncodeUvarint(b, v)
+			b = append(b, t.Type, byte(t.Fsp))
+		case types.KindMysqlEnum:
+			enum := d.GetMysqlEnum()
+			b = codec.EncodeUvarint(b, enum.Value)
+			b = codec.EncodeCompactBytes(b, []byte(enum.Name))
+		case types.KindMysqlSet:
+			set := d.GetMysqlSet()
+			b = codec.EncodeUvarint(b, set.Value)
+			b = codec.EncodeCompactBytes(b, []byte(set.Name))
+		case types.KindMysqlBit:
+			bit := d.GetMysqlBit()
+			b = codec.EncodeUvarint(b, bit.Value)
+			b = codec.EncodeVarint(b, int64(bit.Width))
+		case types.KindMysqlHex:
+			b = codec.EncodeVarint(b, d.GetMysqlHex().Value)
+		default:
+			return nil, errors.Errorf("unsupported datum kind %d to write to disk", d.Kind())
+		}
+	}
+	return b, nil
+}
+
+// decodeRowData decodes the row data encoded by encodeRowData.
+func decodeRowData(b []byte) ([]types.Datum, error) {
+	var data []types.Datum
+	for len(b) > 0 {
+		kind := b[0]
+		b = b[1:]
+		var (
+			d     types.Datum
+			err   error
+			intV  int64
+			uintV uint64
+			bytes []byte
+		)
+		switch kind {
+		case types.KindNull:
+		case types.KindInt64:
+			b, intV, err = codec.DecodeVarint(b)
+			d.SetInt64(intV)
+		case types.KindUint64:
+			b, uintV, err = codec.DecodeUvarint(b)
+			d.SetUint64(uintV)
+		case types.KindFloat32, types.KindFloat64:
+			var f float64
+			b, f, err = codec.DecodeFloat(b)
+			if kind == types.KindFloat32 {
+				d.SetFloat32(float32(f))
+			} else {
+				d.SetFloat64(f)
+			}
+		case types.KindString, types.KindBytes:
+			b, bytes, err = codec.DecodeCompactBytes(b)
+			if kind == types.KindString {
+				d.SetString(string(bytes))
+			} else {
+				d.SetBytes(append([]byte(nil), bytes...))
+			}
+		case types.KindMysqlDecimal:
+			b, d, err = codec.DecodeDecimal(b)
+		case types.KindMysqlDuration:
+			b, intV, err = codec.DecodeVarint(b)
+			if err == nil && len(b) < 1 {
+				err = errors.New("insufficient bytes to decode the duration fsp")
+			}
+			if err == nil {
+				d.SetMysqlDuration(types.Duration{Duration: time.Duration(intV), Fsp: int(b[0])})
+				b = b[1:]
+			}
+		case types.KindMysqlTime:
+			b, uintV, err = codec.DecodeUvarint(b)
+			if err == nil && len(b) < 2 {
+				err = errors.New("insufficient bytes to decode the time type and fsp")
+			}
+			if err == nil {
+				t := types.Time{Type: b[0], Fsp: int(b[1])}
+				b = b[2:]
+				err = t.FromPackedUint(uintV)
+				d.SetMysqlTime(t)
+			}
+		case types.KindMysqlEnum, types.KindMysqlSet:
+			b, uintV, err = codec.DecodeUvarint(b)
+			if err == nil {
+				b, bytes, err = codec.DecodeCompactBytes(b)
+			}
+			if kind == types.KindMysqlEnum {
+				d.SetMysqlEnum(types.Enum{Name: string(bytes), Value: uintV})
+			} else {
+				d.SetMysqlSet(types.Set{Name: string(bytes), Value: uintV})
+			}
+		case types.KindMysqlBit:
+			b, uintV, err = codec.DecodeUvarint(b)
+			if err == nil {
+				b, intV, err = codec.DecodeVarint(b)
+			}
+			d.SetMysqlBit(types.Bit{Value: uintV, Width: int(intV)})
+		case types.KindMysqlHex:
+			b, intV, err = codec.DecodeVarint(b)
+			d.SetMysqlHex(types.Hex{Value: intV})
+		default:
+			return nil, errors.Errorf("invalid datum kind %d in the row read from disk", kind)
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var _ Executor = &SplitRegionExec{}
+
+// SplitRegionExec represents a split region executor.
+// It is built from the "split table" statement, it splits the regions of the record data or an index of the table,
+// and returns a row with the number of the split keys and the ratio of the regions scattered. PD can't scatter the
+// regions in this version, so the ratio is always 0, the new regions are balanced by PD in the background.
+type SplitRegionExec struct {
+	schema     *expression.Schema
+	ctx        context.Context
+	tableInfo  *model.TableInfo
+	indexInfo  *model.IndexInfo
+	lower      []types.Datum
+	upper      []types.Datum
+	num        int
+	valueLists [][]types.Datum
+	done       bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *SplitRegionExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *SplitRegionExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	store := sessionctx.GetDomain(e.ctx).Store()
+	if !store.SupportCapability(kv.CapSplitRegion) {
+		return nil, ErrCannotSplitRegion.GenByArgs("the store doesn't support splitting regions")
+	}
+	splitter := store.(kv.SplittableStore)
+	var splitKeys []kv.Key
+	var err error
+	if e.indexInfo == nil {
+		splitKeys, err = e.recordSplitKeys()
+	} else {
+		splitKeys, err = e.indexSplitKeys()
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, key := range splitKeys {
+		if err = splitter.SplitRegion(key); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	log.Infof("[executor] split the regions of table %s at %d keys", e.tableInfo.Name, len(splitKeys))
+	return &Row{Data: types.MakeDatums(len(splitKeys), float64(0))}, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *SplitRegionExec) Close() error {
+	return nil
+}
+
+// recordSplitKeys returns the row keys of the handles to split the record data at.
+func (e *SplitRegionExec) recordSplitKeys() ([]kv.Key, error) {
+	var handles []int64
+	if len(e.valueLists) > 0 {
+		for _, values := range e.valueLists {
+			handles = append(handles, values[0].GetInt64())
+		}
+	} else {
+		lower, upper := e.lower[0].GetInt64(), e.upper[0].GetInt64()
+		if lower >= upper {
+			return nil, ErrCannotSplitRegion.GenByArgs("the lower bound should be less than the upper bound")
+		}
+		step := (uint64(upper) - uint64(lower)) / uint64(e.num)
+		if step == 0 {
+			return nil, ErrCannotSplitRegion.GenByArgs(fmt.Sprintf("the range is too small to be split into %d regions", e.num))
+		}
+		for i := 0; i < e.num; i++ {
+			handles = append(handles, int64(uint64(lower)+uint64(i)*step))
+		}
+	}
+	keys := make([]kv.Key, 0, len(handles))
+	for _, h := range handles {
+		keys = append(keys, tablecodec.EncodeRowKeyWithHandle(e.tableInfo.ID, h))
+	}
+	return keys, nil
+}
+
+// indexSplitKeys returns the index keys to split the index at. The index values are encoded in the memcomparable
+// format, so the keys between the bounds are interpolated on the first 8 bytes after their common prefix.
+func (e *SplitRegionExec) indexSplitKeys() ([]kv.Key, error) {
+	if len(e.valueLists) > 0 {
+		keys := make([]kv.Key, 0, len(e.valueLists))
+		for _, values := range e.valueLists {
+			key, err := e.encodeIndexKey(values)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+	lowerKey, err := e.encodeIndexKey(e.lower)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	upperKey, err := e.encodeIndexKey(e.upper)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if bytes.Compare(lowerKey, upperKey) >= 0 {
+		return nil, ErrCannotSplitRegion.GenByArgs("the lower bound should be less than the upper bound")
+	}
+	prefixLen := 0
+	for prefixLen < len(lowerKey) && lowerKey[prefixLen] == upperKey[prefixLen] {
+		prefixLen++
+	}
+	lower, upper := uint64At(lowerKey, prefixLen), uint64At(upperKey, prefixLen)
+	step := (upper - lower) / uint64(e.num)
+	if step == 0 {
+		return nil, ErrCannotSplitRegion.GenByArgs(fmt.Sprintf("the range is too small to be split into %d regions", e.num))
+	}
+	keys := []kv.Key{lowerKey}
+	for i := 1; i < e.num; i++ {
+		key := make([]byte, prefixLen+8)
+		copy(key, lowerKey[:prefixLen])
+		binary.BigEndian.PutUint64(key[prefixLen:], lower+uint64(i)*step)
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (e *SplitRegionExec) encodeIndexKey(values []types.Datum) (kv.Key, error) {
+	encoded, err := codec.EncodeKey(nil, values...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return tablecodec.EncodeIndexSeekKey(e.tableInfo.ID, e.indexInfo.ID, encoded), nil
+}
+
+// uint64At returns the 8 bytes of the key from the offset as a big-endian uint64, the missing bytes are zeros.
+func uint64At(key []byte, offset int) uint64 {
+	var buf [8]byte
+	if offset < len(key) {
+		copy(buf[:], key[offset:])
+	}
+	return binary.BigEndian.Uint64(buf[:])
+}
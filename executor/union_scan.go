@@ -53,6 +53,26 @@ func (udb *dirtyDB) truncateTable(tid int64) {
 	dt.truncated = true
 }
 
+// clone returns a copy of the dirtyDB, the rows are shared because they are not modified after they are added.
+func (udb *dirtyDB) clone() *dirtyDB {
+	tables := make(map[int64]*dirtyTable, len(udb.tables))
+	for tid, dt := range udb.tables {
+		ndt := &dirtyTable{
+			addedRows:   make(map[int64][]types.Datum, len(dt.addedRows)),
+			deletedRows: make(map[int64]struct{}, len(dt.deletedRows)),
+			truncated:   dt.truncated,
+		}
+		for h, row := range dt.addedRows {
+			ndt.addedRows[h] = row
+		}
+		for h := range dt.deletedRows {
+			ndt.deletedRows[h] = struct{}{}
+		}
+		tables[tid] = ndt
+	}
+	return &dirtyDB{tables: tables}
+}
+
 func (udb *dirtyDB) getDirtyTable(tid int64) *dirtyTable {
 	dt, ok := udb.tables[tid]
 	if !ok {
@@ -240,10 +260,10 @@ func (us *UnionScanExec) buildAndSortAddedRows(t table.Table, asName *model.CISt
 		} else {
 			newData = make([]types.Datum, 0, us.Src.Schema().Len())
 			var columns []*model.ColumnInfo
-			if t, ok := us.Src.(*XSelectTableExec); ok {
+			if t, ok := unwrapRuntimeStats(us.Src).(*XSelectTableExec); ok {
 				columns = t.Columns
 			} else {
-				columns = us.Src.(*XSelectIndexExec).indexPlan.Columns
+				columns = unwrapRuntimeStats(us.Src).(*XSelectIndexExec).indexPlan.Columns
 			}
 			for _, col := range columns {
 				newData = append(newData, data[col.Offset])
@@ -0,0 +1,534 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func validateFrame(f *ast.FrameClause) error {
+	if f.Start.Type == ast.UnboundedFollowing || f.End.Type == ast.UnboundedPreceding || f.Start.Type > f.End.Type {
+		return errors.New("invalid window frame bounds")
+	}
+	for _, b := range []ast.FrameBound{f.Start, f.End} {
+		if b.Type != ast.Preceding && b.Type != ast.Following {
+			continue
+		}
+		if f.Type == ast.Ranges {
+			return errors.New("RANGE window frame with an offset is not supported")
+		}
+		if b.Num < 0 {
+			return errors.Errorf("invalid window frame offset %d", b.Num)
+		}
+	}
+	return nil
+}
+
+// WindowFuncDesc describes a window function.
+type WindowFuncDesc struct {
+	// Name is the name of a non-aggregate window function, it's empty when Agg is set.
+	Name string
+	// Agg is the aggregate function evaluated on the frame of each row.
+	Agg expression.AggregationFunction
+	// Arg is the argument of LAG and LEAD, it's evaluated on the row Offset rows before or after the current row.
+	Arg    expression.Expression
+	Offset int64
+	// Default is evaluated on the current row when the row of LAG or LEAD is out of the partition, nil means NULL.
+	Default expression.Expression
+}
+
+// windowRow is a row of the current partition along with its order by values.
+type windowRow struct {
+	row      *Row
+	partKey  []types.Datum
+	orderKey []types.Datum
+}
+
+// WindowExec evaluates the window functions of a window on its input, which must be sorted by the partition by
+// items and then the order by items. It outputs the input rows with the results of the functions appended.
+// Only the rows needed by the frame and the LAG and LEAD offsets are buffered, so ROW_NUMBER, RANK, LAG, LEAD and
+// the cumulative aggregates don't need to keep the whole partition in memory. The aggregates on a frame starting from
+// UNBOUNDED PRECEDING are updated incrementally, the aggregates on a sliding frame are evaluated on the rows of the
+// frame for each row.
+type WindowExec struct {
+	Src         Executor
+	Ctx         context.Context
+	PartitionBy []expression.Expression
+	OrderBy     []expression.Expression
+	// Frame is the frame of the aggregate functions. When it's nil, the frame is the rows from the start of the
+	// partition to the last peer of the current row with order by items, otherwise it is the whole partition.
+	Frame       *ast.FrameClause
+	WindowFuncs []*WindowFuncDesc
+	schema      *expression.Schema
+
+	prepared bool
+	frame    ast.FrameClause
+	// rows are the buffered rows of the current partition, base is the index of rows[0] in the partition.
+	rows []*windowRow
+	base int64
+	// cur is the index of the row to output next, entered is the last row whose peer group has been tracked.
+	cur     int64
+	entered int64
+	// partitionDone is set after all the rows of the current partition are read, pending is the first row of the
+	// next partition read from Src.
+	partitionDone bool
+	pending       *windowRow
+	srcDone       bool
+	curPartKey    []types.Datum
+	// peerStart and peerEnd are the first and the last known peers of the current row, peerKey is their order by values.
+	peerStart int64
+	peerEnd   int64
+	peerKey   []types.Datum
+	denseRank int64
+	// accFed is the number of rows updated into the aggregates when the frame starts from UNBOUNDED PRECEDING.
+	accFed int64
+
+	// memTracker tracks the memory usage of the buffered rows.
+	memTracker *memory.Tracker
+}
+
+// Schema implements the Executor Schema interface.
+func (e *WindowExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Close implements the Executor Close interface.
+func (e *WindowExec) Close() error {
+	e.prepared = false
+	e.rows = nil
+	e.pending = nil
+	e.srcDone = false
+	e.curPartKey = nil
+	e.peerKey = nil
+	for _, f := range e.WindowFuncs {
+		if f.Agg != nil {
+			f.Agg.Clear()
+		}
+	}
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return e.Src.Close()
+}
+
+// Next implements the Executor Next interface.
+func (e *WindowExec) Next() (*Row, error) {
+	if !e.prepared {
+		if err := e.prepare(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for {
+		if e.cur >= e.bufferedEnd() && e.partitionDone {
+			ok, err := e.nextPartition()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if !ok {
+				return nil, nil
+			}
+		}
+		if e.cur < e.bufferedEnd() {
+			if e.entered < e.cur {
+				if err := e.enterRow(); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+			ready, err := e.ready()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if ready {
+				row, err := e.evalRow()
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				e.cur++
+				e.shrink()
+				return row, nil
+			}
+		}
+		if err := e.fetchRow(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+}
+
+func (e *WindowExec) prepare() error {
+	if e.Frame != nil {
+		e.frame = *e.Frame
+	} else if len(e.OrderBy) > 0 {
+		e.frame = ast.FrameClause{Type: ast.Ranges, Start: ast.FrameBound{Type: ast.UnboundedPreceding}, End: ast.FrameBound{Type: ast.CurrentRow}}
+	} else {
+		e.frame = ast.FrameClause{Type: ast.Rows, Start: ast.FrameBound{Type: ast.UnboundedPreceding}, End: ast.FrameBound{Type: ast.UnboundedFollowing}}
+	}
+	if err := validateFrame(&e.frame); err != nil {
+		return errors.Trace(err)
+	}
+	for _, f := range e.WindowFuncs {
+		if (f.Name == ast.WindowFuncLag || f.Name == ast.WindowFuncLead) && f.Offset < 0 {
+			return errors.Errorf("invalid offset %d of %s", f.Offset, f.Name)
+		}
+	}
+	e.partitionDone = true
+	e.prepared = true
+	return nil
+}
+
+func (e *WindowExec) bufferedEnd() int64 {
+	return e.base + int64(len(e.rows))
+}
+
+func (e *WindowExec) rowAt(idx int64) *windowRow {
+	return e.rows[idx-e.base]
+}
+
+func (e *WindowExec) hasAgg() bool {
+	for _, f := range e.WindowFuncs {
+		if f.Agg != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// nextPartition starts the next partition from the pending row, it returns false if there are no more rows.
+func (e *WindowExec) nextPartition() (bool, error) {
+	if e.pending == nil {
+		if e.srcDone {
+			return false, nil
+		}
+		row, err := e.Src.Next()
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if row == nil {
+			e.srcDone = true
+			return false, nil
+		}
+		e.pending, err = e.newWindowRow(row)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+	}
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	e.rows = e.rows[:0]
+	err := e.appendRow(e.pending)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	e.curPartKey = e.pending.partKey
+	e.pending = nil
+	e.partitionDone = false
+	e.base, e.cur, e.entered, e.accFed = 0, 0, -1, 0
+	e.peerStart, e.peerEnd, e.peerKey, e.denseRank = 0, 0, nil, 0
+	for _, f := range e.WindowFuncs {
+		if f.Agg != nil {
+			f.Agg.Clear()
+		}
+	}
+	return true, nil
+}
+
+// fetchRow reads a row from Src into the current partition, it marks the partition done when the row belongs to
+// the next partition or there are no more rows.
+func (e *WindowExec) fetchRow() error {
+	row, err := e.Src.Next()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if row == nil {
+		e.srcDone = true
+		e.partitionDone = true
+		return nil
+	}
+	wr, err := e.newWindowRow(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c, err := compareDatumSlices(e.Ctx.GetSessionVars().StmtCtx, wr.partKey, e.curPartKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if c != 0 {
+		e.pending = wr
+		e.partitionDone = true
+		return nil
+	}
+	return errors.Trace(e.appendRow(wr))
+}
+
+func (e *WindowExec) appendRow(wr *windowRow) error {
+	e.rows = append(e.rows, wr)
+	return errors.Trace(e.memTracker.Consume(wr.row.memUsage()))
+}
+
+func (e *WindowExec) newWindowRow(row *Row) (*windowRow, error) {
+	wr := &windowRow{row: row}
+	var err error
+	wr.partKey, err = evalDatums(e.PartitionBy, row.Data)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	wr.orderKey, err = evalDatums(e.OrderBy, row.Data)
+	return wr, errors.Trace(err)
+}
+
+// enterRow tracks the peer group and the dense rank of the current row.
+func (e *WindowExec) enterRow() error {
+	e.entered = e.cur
+	key := e.rowAt(e.cur).orderKey
+	if e.cur > 0 {
+		c, err := compareDatumSlices(e.Ctx.GetSessionVars().StmtCtx, key, e.peerKey)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if c == 0 {
+			if e.peerEnd < e.cur {
+				e.peerEnd = e.cur
+			}
+			return nil
+		}
+	}
+	e.peerStart, e.peerEnd, e.peerKey = e.cur, e.cur, key
+	e.denseRank++
+	return nil
+}
+
+// findPeerEnd advances peerEnd to the last buffered peer of the current row. It returns false if the peers may go
+// on after the buffered rows.
+func (e *WindowExec) findPeerEnd() (bool, error) {
+	sc := e.Ctx.GetSessionVars().StmtCtx
+	for e.peerEnd+1 < e.bufferedEnd() {
+		c, err := compareDatumSlices(sc, e.rowAt(e.peerEnd+1).orderKey, e.peerKey)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if c != 0 {
+			return true, nil
+		}
+		e.peerEnd++
+	}
+	return e.partitionDone, nil
+}
+
+// ready returns whether all the rows needed to evaluate the functions of the current row are buffered.
+func (e *WindowExec) ready() (bool, error) {
+	hasAgg := e.hasAgg()
+	if hasAgg && e.frame.Type == ast.Ranges && e.frame.End.Type == ast.CurrentRow {
+		found, err := e.findPeerEnd()
+		if err != nil || !found {
+			return false, errors.Trace(err)
+		}
+	}
+	if e.partitionDone {
+		return true, nil
+	}
+	need := e.cur
+	for _, f := range e.WindowFuncs {
+		if f.Name == ast.WindowFuncLead && e.cur+f.Offset > need {
+			need = e.cur + f.Offset
+		}
+	}
+	if hasAgg {
+		switch e.frame.End.Type {
+		case ast.Following:
+			if e.cur+e.frame.End.Num > need {
+				need = e.cur + e.frame.End.Num
+			}
+		case ast.UnboundedFollowing:
+			return false, nil
+		}
+	}
+	return need < e.bufferedEnd(), nil
+}
+
+// shrink drops the buffered rows which are not needed by the rows after the current row.
+func (e *WindowExec) shrink() {
+	low := e.cur
+	for _, f := range e.WindowFuncs {
+		if f.Name == ast.WindowFuncLag && e.cur-f.Offset < low {
+			low = e.cur - f.Offset
+		}
+	}
+	if e.hasAgg() {
+		switch e.frame.Start.Type {
+		case ast.UnboundedPreceding:
+			if e.accFed < low {
+				low = e.accFed
+			}
+		case ast.Preceding:
+			if e.cur-e.frame.Start.Num < low {
+				low = e.cur - e.frame.Start.Num
+			}
+		case ast.CurrentRow:
+			if e.frame.Type == ast.Ranges && e.peerStart < low {
+				low = e.peerStart
+			}
+		}
+	}
+	n := low - e.base
+	if n <= 0 {
+		return
+	}
+	if n > int64(len(e.rows)) {
+		n = int64(len(e.rows))
+	}
+	var released int64
+	for _, wr := range e.rows[:n] {
+		released += wr.row.memUsage()
+	}
+	e.memTracker.Consume(-released)
+	e.rows = e.rows[n:]
+	e.base += n
+}
+
+// frameBounds returns the first and the last index of the frame of the current row, the frame is empty if the last
+// one is less than the first one.
+func (e *WindowExec) frameBounds() (int64, int64) {
+	first := e.boundIndex(e.frame.Start, e.peerStart)
+	last := e.boundIndex(e.frame.End, e.peerEnd)
+	if first < 0 {
+		first = 0
+	}
+	if last >= e.bufferedEnd() {
+		last = e.bufferedEnd() - 1
+	}
+	return first, last
+}
+
+func (e *WindowExec) boundIndex(b ast.FrameBound, peer int64) int64 {
+	switch b.Type {
+	case ast.UnboundedPreceding:
+		return 0
+	case ast.Preceding:
+		return e.cur - b.Num
+	case ast.CurrentRow:
+		if e.frame.Type == ast.Ranges {
+			return peer
+		}
+		return e.cur
+	case ast.Following:
+		return e.cur + b.Num
+	}
+	return math.MaxInt64
+}
+
+// evalRow evaluates the window functions of the current row.
+func (e *WindowExec) evalRow() (*Row, error) {
+	cur := e.rowAt(e.cur).row
+	data := make([]types.Datum, 0, len(cur.Data)+len(e.WindowFuncs))
+	data = append(data, cur.Data...)
+	for _, f := range e.WindowFuncs {
+		var (
+			d   types.Datum
+			err error
+		)
+		switch {
+		case f.Agg != nil:
+			d, err = e.evalAgg(f.Agg)
+		case f.Name == ast.WindowFuncRowNumber:
+			d.SetInt64(e.cur + 1)
+		case f.Name == ast.WindowFuncRank:
+			d.SetInt64(e.peerStart + 1)
+		case f.Name == ast.WindowFuncDenseRank:
+			d.SetInt64(e.denseRank)
+		case f.Name == ast.WindowFuncLag:
+			d, err = e.evalOffsetRow(f, e.cur-f.Offset, cur)
+		case f.Name == ast.WindowFuncLead:
+			d, err = e.evalOffsetRow(f, e.cur+f.Offset, cur)
+		default:
+			err = errors.Errorf("unknown window function %s", f.Name)
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data = append(data, d)
+	}
+	return &Row{Data: data, RowKeys: cur.RowKeys}, nil
+}
+
+// evalOffsetRow evaluates the argument of LAG or LEAD on the row at idx, or the default value on the current row if
+// idx is out of the partition.
+func (e *WindowExec) evalOffsetRow(f *WindowFuncDesc, idx int64, cur *Row) (types.Datum, error) {
+	if idx >= 0 && idx < e.bufferedEnd() {
+		d, err := f.Arg.Eval(e.rowAt(idx).row.Data)
+		return d, errors.Trace(err)
+	}
+	if f.Default == nil {
+		return types.Datum{}, nil
+	}
+	d, err := f.Default.Eval(cur.Data)
+	return d, errors.Trace(err)
+}
+
+// evalAgg evaluates the aggregate function on the frame of the current row.
+func (e *WindowExec) evalAgg(agg expression.AggregationFunction) (types.Datum, error) {
+	first, last := e.frameBounds()
+	if e.frame.Start.Type != ast.UnboundedPreceding {
+		agg.Clear()
+		for i := first; i <= last; i++ {
+			if err := agg.Update(e.rowAt(i).row.Data, nil, e.Ctx); err != nil {
+				return types.Datum{}, errors.Trace(err)
+			}
+		}
+		return agg.GetGroupResult(nil), nil
+	}
+	// The end of the frame never moves backwards, so the rows after the ones updated last time are updated.
+	if e.accFed <= last {
+		for _, f := range e.WindowFuncs {
+			if f.Agg == nil {
+				continue
+			}
+			for i := e.accFed; i <= last; i++ {
+				if err := f.Agg.Update(e.rowAt(i).row.Data, nil, e.Ctx); err != nil {
+					return types.Datum{}, errors.Trace(err)
+				}
+			}
+		}
+		e.accFed = last + 1
+	}
+	return agg.GetGroupResult(nil), nil
+}
+
+func evalDatums(exprs []expression.Expression, data []types.Datum) ([]types.Datum, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	values := make([]types.Datum, 0, len(exprs))
+	for _, expr := range exprs {
+		v, err := expr.Eval(data)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func compareDatumSlices(sc *variable.StatementContext, a, b []types.Datum) (int, error) {
+	for i := range a {
+		c, err := a[i].CompareDatum(sc, b[i])
+		if err != nil || c != 0 {
+			return c, errors.Trace(err)
+		}
+	}
+	return 0, nil
+}
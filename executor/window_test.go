@@ -0,0 +1,183 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// mockWindowSrc returns the rows of (p, o, v) sorted by p and o.
+type mockWindowSrc struct {
+	rows   [][]int64
+	cursor int
+	// maxRead records how many rows have been read, to check how many rows are buffered by the window.
+	maxRead int
+}
+
+func (m *mockWindowSrc) Next() (*Row, error) {
+	if m.cursor >= len(m.rows) {
+		return nil, nil
+	}
+	r := m.rows[m.cursor]
+	m.cursor++
+	m.maxRead = m.cursor
+	return &Row{Data: types.MakeDatums(r[0], r[1], r[2])}, nil
+}
+
+func (m *mockWindowSrc) Close() error {
+	m.cursor = 0
+	return nil
+}
+
+func (m *mockWindowSrc) Schema() *expression.Schema {
+	return nil
+}
+
+func windowCol(idx int) *expression.Column {
+	return &expression.Column{Index: idx, RetType: types.NewFieldType(mysql.TypeLonglong)}
+}
+
+func runWindow(c *C, e *WindowExec) []string {
+	var result []string
+	for {
+		row, err := e.Next()
+		c.Assert(err, IsNil)
+		if row == nil {
+			break
+		}
+		strs := make([]string, 0, len(row.Data))
+		for _, d := range row.Data {
+			if d.IsNull() {
+				strs = append(strs, "<nil>")
+				continue
+			}
+			s, err := d.ToString()
+			c.Assert(err, IsNil)
+			strs = append(strs, s)
+		}
+		result = append(result, strings.Join(strs, " "))
+	}
+	c.Assert(e.Close(), IsNil)
+	c.Assert(e.memTracker.BytesConsumed(), Equals, int64(0))
+	return result
+}
+
+func newWindowExec(rows [][]int64, frame *ast.FrameClause, funcs ...*WindowFuncDesc) *WindowExec {
+	return &WindowExec{
+		Src:         &mockWindowSrc{rows: rows},
+		Ctx:         mock.NewContext(),
+		PartitionBy: []expression.Expression{windowCol(0)},
+		OrderBy:     []expression.Expression{windowCol(1)},
+		Frame:       frame,
+		WindowFuncs: funcs,
+		memTracker:  memory.NewTracker("Window", 0),
+	}
+}
+
+func (s *testExecSuite) TestWindowRankAndOffset(c *C) {
+	defer testleak.AfterTest(c)()
+	rows := [][]int64{{1, 1, 10}, {1, 2, 20}, {1, 2, 30}, {1, 3, 40}, {2, 1, 50}, {2, 2, 60}}
+	e := newWindowExec(rows, nil,
+		&WindowFuncDesc{Name: ast.WindowFuncRowNumber},
+		&WindowFuncDesc{Name: ast.WindowFuncRank},
+		&WindowFuncDesc{Name: ast.WindowFuncDenseRank},
+		&WindowFuncDesc{Name: ast.WindowFuncLag, Arg: windowCol(2), Offset: 1},
+		&WindowFuncDesc{Name: ast.WindowFuncLead, Arg: windowCol(2), Offset: 2, Default: windowCol(1)},
+	)
+	c.Assert(runWindow(c, e), DeepEquals, []string{
+		"1 1 10 1 1 1 <nil> 30",
+		"1 2 20 2 2 2 10 40",
+		"1 2 30 3 2 2 20 2",
+		"1 3 40 4 4 3 30 3",
+		"2 1 50 1 1 1 <nil> 1",
+		"2 2 60 2 2 2 50 2",
+	})
+
+	// ROW_NUMBER, LAG and LEAD only buffer the rows of their offsets.
+	var rowsInPartition [][]int64
+	for i := int64(0); i < 1000; i++ {
+		rowsInPartition = append(rowsInPartition, []int64{1, i, i})
+	}
+	e = newWindowExec(rowsInPartition, nil,
+		&WindowFuncDesc{Name: ast.WindowFuncRowNumber},
+		&WindowFuncDesc{Name: ast.WindowFuncLag, Arg: windowCol(2), Offset: 3},
+		&WindowFuncDesc{Name: ast.WindowFuncLead, Arg: windowCol(2), Offset: 2},
+	)
+	src := e.Src.(*mockWindowSrc)
+	for i := 0; i < 500; i++ {
+		row, err := e.Next()
+		c.Assert(err, IsNil)
+		c.Assert(row.Data[3].GetInt64(), Equals, int64(i+1))
+	}
+	c.Assert(src.maxRead, Equals, 502)
+	c.Assert(len(e.rows) <= 6, IsTrue)
+	c.Assert(e.Close(), IsNil)
+}
+
+func (s *testExecSuite) TestWindowFrame(c *C) {
+	defer testleak.AfterTest(c)()
+	rows := [][]int64{{1, 1, 1}, {1, 2, 2}, {1, 2, 3}, {1, 3, 4}, {1, 4, 5}, {2, 1, 6}}
+	newSum := func() *WindowFuncDesc {
+		return &WindowFuncDesc{Agg: expression.NewAggFunction(ast.AggFuncSum, []expression.Expression{windowCol(2)}, false)}
+	}
+	newCount := func() *WindowFuncDesc {
+		return &WindowFuncDesc{Agg: expression.NewAggFunction(ast.AggFuncCount, []expression.Expression{windowCol(2)}, false)}
+	}
+	bound := func(tp ast.BoundType, num int64) ast.FrameBound {
+		return ast.FrameBound{Type: tp, Num: num}
+	}
+	tests := []struct {
+		frame  *ast.FrameClause
+		result []string
+	}{
+		// The default frame includes the peers of the current row.
+		{nil, []string{"1 1", "6 3", "6 3", "10 4", "15 5", "6 1"}},
+		{&ast.FrameClause{Type: ast.Rows, Start: bound(ast.UnboundedPreceding, 0), End: bound(ast.CurrentRow, 0)},
+			[]string{"1 1", "3 2", "6 3", "10 4", "15 5", "6 1"}},
+		{&ast.FrameClause{Type: ast.Rows, Start: bound(ast.Preceding, 1), End: bound(ast.Following, 1)},
+			[]string{"3 2", "6 3", "9 3", "12 3", "9 2", "6 1"}},
+		{&ast.FrameClause{Type: ast.Rows, Start: bound(ast.Following, 1), End: bound(ast.Following, 2)},
+			[]string{"5 2", "7 2", "9 2", "5 1", "<nil> 0", "<nil> 0"}},
+		{&ast.FrameClause{Type: ast.Rows, Start: bound(ast.UnboundedPreceding, 0), End: bound(ast.Preceding, 1)},
+			[]string{"<nil> 0", "1 1", "3 2", "6 3", "10 4", "<nil> 0"}},
+		{&ast.FrameClause{Type: ast.Ranges, Start: bound(ast.CurrentRow, 0), End: bound(ast.UnboundedFollowing, 0)},
+			[]string{"15 5", "14 4", "14 4", "9 2", "5 1", "6 1"}},
+		{&ast.FrameClause{Type: ast.Rows, Start: bound(ast.UnboundedPreceding, 0), End: bound(ast.UnboundedFollowing, 0)},
+			[]string{"15 5", "15 5", "15 5", "15 5", "15 5", "6 1"}},
+	}
+	for i, t := range tests {
+		e := newWindowExec(rows, t.frame, newSum(), newCount())
+		result := runWindow(c, e)
+		c.Assert(len(result), Equals, len(t.result), Commentf("for %d", i))
+		for j, r := range result {
+			c.Assert(strings.Join(strings.Fields(r)[3:], " "), Equals, t.result[j], Commentf("for %d", i))
+		}
+	}
+
+	e := newWindowExec(rows, &ast.FrameClause{Type: ast.Ranges, Start: bound(ast.Preceding, 1), End: bound(ast.CurrentRow, 0)}, newSum())
+	_, err := e.Next()
+	c.Assert(err, NotNil)
+	e = newWindowExec(rows, &ast.FrameClause{Type: ast.Rows, Start: bound(ast.CurrentRow, 0), End: bound(ast.Preceding, 1)}, newSum())
+	_, err = e.Next()
+	c.Assert(err, NotNil)
+}
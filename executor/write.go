@@ -23,8 +23,12 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta/autoid"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -63,6 +67,12 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 				return errors.Trace(err)
 			}
 			t.RebaseAutoID(val, true)
+		} else if isAutoRandomColumn(t.Meta(), col) {
+			val, err := newData[i].ToInt64(sc)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			t.RebaseAutoID(autoid.DecodeAutoRandomID(val, t.Meta().AutoRandomBits), true)
 		}
 		casted, err := table.CastValue(ctx, newData[i], col.ToInfo())
 		if err != nil {
@@ -134,6 +144,86 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 	return nil
 }
 
+// dmlBatcher splits the changes of a single table DELETE or UPDATE statement into batches of DMLBatchSize rows,
+// each batch is committed in its own transaction. The rows are read in handle order from the table, so every batch
+// covers a handle range, which is logged as the progress of the statement.
+type dmlBatcher struct {
+	ctx     context.Context
+	size    int
+	dryRun  bool
+	batches int
+	total   int
+	// The number of rows and the handle range of the current batch.
+	rows  int
+	first int64
+	last  int64
+}
+
+// newDMLBatcher returns nil if batched DML is disabled, or the statement is executed in a transaction.
+func newDMLBatcher(ctx context.Context) *dmlBatcher {
+	vars := ctx.GetSessionVars()
+	if vars.DMLBatchSize <= 0 || vars.InTxn() || !vars.IsAutocommit() {
+		return nil
+	}
+	// The committed batches can't be rolled back, so the statement must not be retried.
+	vars.TxnCtx.BatchDML = true
+	return &dmlBatcher{ctx: ctx, size: vars.DMLBatchSize, dryRun: vars.BatchDMLDryRun}
+}
+
+// add adds a changed row to the current batch, and commits the batch if it is full.
+func (b *dmlBatcher) add(h int64) error {
+	if b.rows == 0 {
+		b.first = h
+	}
+	b.last = h
+	b.rows++
+	b.total++
+	if b.rows < b.size {
+		return nil
+	}
+	return errors.Trace(b.commit())
+}
+
+func (b *dmlBatcher) commit() error {
+	if b.rows == 0 {
+		return nil
+	}
+	b.batches++
+	vars := b.ctx.GetSessionVars()
+	if b.dryRun {
+		vars.StmtCtx.AppendWarning(errors.Errorf("batch %d: %d rows, handle range [%d, %d]", b.batches, b.rows, b.first, b.last))
+		b.rows = 0
+		return nil
+	}
+	if err := b.ctx.NewTxn(); err != nil {
+		return errors.Annotatef(err, "batch %d failed, the %d rows of the previous batches are committed",
+			b.batches, b.total-b.rows)
+	}
+	// The binlog of the committed batch is written already.
+	vars.TxnCtx.Binlog = nil
+	log.Infof("[%d] batched DML committed batch %d: %d rows, handle range [%d, %d], %d rows in total",
+		vars.ConnectionID, b.batches, b.rows, b.first, b.last, b.total)
+	b.rows = 0
+	return nil
+}
+
+// finish reports the last batch, which is committed with the statement, and a summary of the batches.
+func (b *dmlBatcher) finish() {
+	vars := b.ctx.GetSessionVars()
+	if b.rows > 0 {
+		b.batches++
+		if b.dryRun {
+			vars.StmtCtx.AppendWarning(errors.Errorf("batch %d: %d rows, handle range [%d, %d]", b.batches, b.rows, b.first, b.last))
+		}
+	}
+	b.rows = 0
+	if b.dryRun {
+		vars.StmtCtx.AppendWarning(errors.Errorf("dry run: %d rows in %d batches, no row is changed", b.total, b.batches))
+		return
+	}
+	vars.StmtCtx.AppendWarning(errors.Errorf("%d rows are changed in %d batches", b.total, b.batches))
+}
+
 // DeleteExec represents a delete executor.
 // See https://dev.mysql.com/doc/refman/5.7/en/delete.html
 type DeleteExec struct {
@@ -236,6 +326,7 @@ func isMatchTableName(entry *RowKeyEntry, tblMap map[int64][]string) bool {
 }
 
 func (e *DeleteExec) deleteSingleTable() error {
+	batcher := newDMLBatcher(e.ctx)
 	for {
 		row, err := e.SelectExec.Next()
 		if err != nil {
@@ -245,11 +336,21 @@ func (e *DeleteExec) deleteSingleTable() error {
 			break
 		}
 		rowKey := row.RowKeys[0]
-		err = e.removeRow(e.ctx, rowKey.Tbl, rowKey.Handle, row.Data)
-		if err != nil {
-			return errors.Trace(err)
+		if batcher == nil || !batcher.dryRun {
+			err = e.removeRow(e.ctx, rowKey.Tbl, rowKey.Handle, row.Data)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if batcher != nil {
+			if err = batcher.add(rowKey.Handle); err != nil {
+				return errors.Trace(err)
+			}
 		}
 	}
+	if batcher != nil {
+		batcher.finish()
+	}
 	return nil
 }
 
@@ -287,7 +388,14 @@ type LoadDataInfo struct {
 	Table      table.Table
 	FieldsInfo *ast.FieldsClause
 	LinesInfo  *ast.LinesClause
-	Ctx        context.Context
+	// IgnoreLines is the number of lines skipped at the start of the file.
+	IgnoreLines uint64
+	Ctx         context.Context
+
+	// ignoredLines is the number of lines skipped so far, committedLines is the number of lines, including the
+	// skipped ones, whose rows are committed.
+	ignoredLines   uint64
+	committedLines uint64
 }
 
 // SetBatchCount sets the number of rows to insert in a batch.
@@ -295,6 +403,24 @@ func (e *LoadDataInfo) SetBatchCount(limit int64) {
 	e.insertVal.batchRows = limit
 }
 
+// CommitBatch commits the rows inserted so far and begins a new transaction for the next batch, so a big file isn't
+// loaded in a single transaction.
+func (e *LoadDataInfo) CommitBatch() error {
+	// Make sure that there are no retries when committing, the history of the transaction doesn't have the rows.
+	if err := e.Ctx.Txn().Commit(); err != nil {
+		return errors.Trace(err)
+	}
+	e.committedLines = e.ignoredLines + uint64(e.insertVal.currRow)
+	log.Infof("[%d] Load Data: %d lines are committed", e.Ctx.GetSessionVars().ConnectionID, e.committedLines)
+	return errors.Trace(e.Ctx.NewTxn())
+}
+
+// CommittedLines returns the number of lines whose rows are committed. If the loading fails, it can be resumed from
+// the next line with "IGNORE n LINES".
+func (e *LoadDataInfo) CommittedLines() uint64 {
+	return e.committedLines
+}
+
 // getValidData returns prevData and curData that starts from starting symbol.
 // If the data doesn't have starting symbol, prevData is nil and curData is curData[len(curData)-startingLen+1:].
 // If curData size less than startingLen, curData is returned directly.
@@ -307,7 +433,7 @@ func (e *LoadDataInfo) getValidData(prevData, curData []byte) ([]byte, []byte) {
 	prevLen := len(prevData)
 	if prevLen > 0 {
 		// starting symbol in the prevData
-		idx := strings.Index(string(prevData), e.LinesInfo.Starting)
+		idx := bytes.Index(prevData, []byte(e.LinesInfo.Starting))
 		if idx != -1 {
 			return prevData[idx:], curData
 		}
@@ -318,14 +444,14 @@ func (e *LoadDataInfo) getValidData(prevData, curData []byte) ([]byte, []byte) {
 			restStart = curData[:startingLen-1]
 		}
 		prevData = append(prevData, restStart...)
-		idx = strings.Index(string(prevData), e.LinesInfo.Starting)
+		idx = bytes.Index(prevData, []byte(e.LinesInfo.Starting))
 		if idx != -1 {
 			return prevData[idx:prevLen], curData
 		}
 	}
 
 	// starting symbol in the curData
-	idx := strings.Index(string(curData), e.LinesInfo.Starting)
+	idx := bytes.Index(curData, []byte(e.LinesInfo.Starting))
 	if idx != -1 {
 		return nil, curData[idx:]
 	}
@@ -354,7 +480,7 @@ func (e *LoadDataInfo) getLine(prevData, curData []byte) ([]byte, []byte, bool)
 	}
 	endIdx := -1
 	if len(curData) >= curStartIdx {
-		endIdx = strings.Index(string(curData[curStartIdx:]), e.LinesInfo.Terminated)
+		endIdx = bytes.Index(curData[curStartIdx:], []byte(e.LinesInfo.Terminated))
 	}
 	if endIdx == -1 {
 		// no terminated symbol
@@ -364,7 +490,7 @@ func (e *LoadDataInfo) getLine(prevData, curData []byte) ([]byte, []byte, bool)
 
 		// terminated symbol in the middle of prevData and curData
 		curData = append(prevData, curData...)
-		endIdx = strings.Index(string(curData[startingLen:]), e.LinesInfo.Terminated)
+		endIdx = bytes.Index(curData[startingLen:], []byte(e.LinesInfo.Terminated))
 		if endIdx != -1 {
 			nextDataIdx := startingLen + endIdx + terminatedLen
 			return curData[startingLen : startingLen+endIdx], curData[nextDataIdx:], true
@@ -381,7 +507,7 @@ func (e *LoadDataInfo) getLine(prevData, curData []byte) ([]byte, []byte, bool)
 
 	// terminated symbol in the curData
 	prevData = append(prevData, curData[:nextDataIdx]...)
-	endIdx = strings.Index(string(prevData[startingLen:]), e.LinesInfo.Terminated)
+	endIdx = bytes.Index(prevData[startingLen:], []byte(e.LinesInfo.Terminated))
 	if endIdx >= prevLen {
 		return prevData[startingLen : startingLen+endIdx], curData[nextDataIdx:], true
 	}
@@ -428,6 +554,10 @@ func (e *LoadDataInfo) InsertData(prevData, curData []byte) ([]byte, bool, error
 			line = curData[len(e.LinesInfo.Starting):]
 			curData = nil
 		}
+		if e.ignoredLines < e.IgnoreLines {
+			e.ignoredLines++
+			continue
+		}
 
 		rawCols := bytes.Split(line, []byte(e.FieldsInfo.Terminated))
 		cols = escapeCols(rawCols)
@@ -626,7 +756,19 @@ func (e *InsertExec) Next() (*Row, error) {
 		return nil, errors.Trace(err)
 	}
 
-	for _, row := range rows {
+	// The duplicate rows of INSERT IGNORE and INSERT ON DUPLICATE KEY UPDATE are found by reading the keys one by one,
+	// the keys are prefetched in batches to save the round trips to the store.
+	prefetch := (e.Ignore || len(e.OnDuplicate) > 0) && !e.ctx.GetSessionVars().SkipConstraintCheck
+	for i, row := range rows {
+		if prefetch && i%prefetchBatchSize == 0 {
+			end := i + prefetchBatchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			if err = e.prefetchUniqueKeys(rows[i:end], len(e.OnDuplicate) > 0); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 		if len(e.OnDuplicate) == 0 && !e.Ignore {
 			txn.SetOption(kv.PresumeKeyNotExists, nil)
 		}
@@ -661,6 +803,83 @@ func (e *InsertExec) Next() (*Row, error) {
 	return nil, nil
 }
 
+// prefetchBatchSize is the number of rows whose unique keys are prefetched by one BatchGet.
+const prefetchBatchSize = 1024
+
+// prefetchUniqueKeys reads the record keys and the unique index keys of the rows from the store with a single
+// BatchGet, so checking whether the rows are duplicated doesn't need to read the keys one by one.
+// If dupRows is true, the duplicate rows found by the unique index keys are prefetched by another BatchGet.
+func (e *InsertValues) prefetchUniqueKeys(rows [][]types.Datum, dupRows bool) error {
+	tblInfo := e.Table.Meta()
+	var handleCol *table.Column
+	if tblInfo.PKIsHandle {
+		for _, col := range e.Table.Cols() {
+			if col.IsPKHandleColumn(tblInfo) {
+				handleCol = col
+				break
+			}
+		}
+	}
+	keys := make([]kv.Key, 0, len(rows))
+	var idxKeys []kv.Key
+	for _, row := range rows {
+		if handleCol != nil {
+			keys = append(keys, e.Table.RecordKey(row[handleCol.Offset].GetInt64()))
+		}
+		for _, idx := range e.Table.Indices() {
+			idxInfo := idx.Meta()
+			// The values of the expression index are evaluated when the row is added.
+			if (!idxInfo.Unique && !idxInfo.Primary) || e.Table.Meta().IsExpressionIndex(idxInfo) {
+				continue
+			}
+			if idxInfo.State == model.StateDeleteOnly || idxInfo.State == model.StateDeleteReorganization {
+				continue
+			}
+			vals, err := idx.FetchValues(row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// The index values are encoded in the system time zone.
+			vals, err = tablecodec.ConvertTimeZone(vals, e.ctx.GetSessionVars().StmtCtx.TimeZone, nil)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			key, distinct, err := idx.GenIndexKey(vals, 0)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if distinct {
+				keys = append(keys, key)
+				idxKeys = append(idxKeys, key)
+			}
+		}
+	}
+	txn := e.ctx.Txn()
+	if err := txn.Prefetch(keys); err != nil {
+		return errors.Trace(err)
+	}
+	if !dupRows {
+		return nil
+	}
+
+	rowKeys := make([]kv.Key, 0, len(idxKeys))
+	for _, key := range idxKeys {
+		val, err := txn.Get(key)
+		if kv.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		h, err := tables.DecodeHandle(val)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		rowKeys = append(rowKeys, e.Table.RecordKey(h))
+	}
+	return errors.Trace(txn.Prefetch(rowKeys))
+}
+
 // Close implements the Executor Close interface.
 func (e *InsertExec) Close() error {
 	e.ctx.GetSessionVars().CurrInsertValues = nil
@@ -847,10 +1066,13 @@ func filterErr(err error, ignoreErr bool) error {
 func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struct{}, ignoreErr bool) error {
 	var defaultValueCols []*table.Column
 	sc := e.ctx.GetSessionVars().StmtCtx
+	tblInfo := e.Table.Meta()
 	for i, c := range e.Table.Cols() {
+		// The AUTO_RANDOM column is filled like the auto-increment column.
+		autoRandom := isAutoRandomColumn(tblInfo, c)
+		isAutoID := mysql.HasAutoIncrementFlag(c.Flag) || autoRandom
 		// It's used for retry.
-		if mysql.HasAutoIncrementFlag(c.Flag) && row[i].IsNull() &&
-			e.ctx.GetSessionVars().RetryInfo.Retrying {
+		if isAutoID && row[i].IsNull() && e.ctx.GetSessionVars().RetryInfo.Retrying {
 			id, err := e.ctx.GetSessionVars().RetryInfo.GetCurrAutoIncrementID()
 			if err != nil {
 				return errors.Trace(err)
@@ -859,7 +1081,7 @@ func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struc
 		}
 		if !row[i].IsNull() {
 			// Column value isn't nil and column isn't auto-increment, continue.
-			if !mysql.HasAutoIncrementFlag(c.Flag) {
+			if !isAutoID {
 				continue
 			}
 			val, err := row[i].ToInt64(sc)
@@ -868,21 +1090,31 @@ func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struc
 			}
 			row[i].SetInt64(val)
 			if val != 0 {
+				if autoRandom {
+					val = autoid.DecodeAutoRandomID(val, tblInfo.AutoRandomBits)
+				}
 				e.Table.RebaseAutoID(val, true)
 				continue
 			}
 		}
 
 		// If the nil value is evaluated in insert list, we will use nil except auto increment column.
-		if _, ok := marked[i]; ok && !mysql.HasAutoIncrementFlag(c.Flag) && !mysql.HasTimestampFlag(c.Flag) {
+		if _, ok := marked[i]; ok && !isAutoID && !mysql.HasTimestampFlag(c.Flag) {
 			continue
 		}
 
-		if mysql.HasAutoIncrementFlag(c.Flag) {
+		if isAutoID {
 			recordID, err := e.Table.AllocAutoID()
 			if err != nil {
 				return errors.Trace(err)
 			}
+			if autoRandom {
+				recordID, err = autoid.EncodeAutoRandomID(recordID, autoid.TxnShard(e.ctx.Txn().StartTS()),
+					tblInfo.AutoRandomBits)
+				if err != nil {
+					return errors.Trace(err)
+				}
+			}
 			row[i].SetInt64(recordID)
 			// It's compatible with mysql. So it sets last insert id to the first row.
 			if e.currRow == 0 {
@@ -909,6 +1141,11 @@ func (e *InsertValues) initDefaultValues(row []types.Datum, marked map[int]struc
 	return nil
 }
 
+// isAutoRandomColumn returns whether the column is the AUTO_RANDOM primary key of the table.
+func isAutoRandomColumn(tblInfo *model.TableInfo, col *table.Column) bool {
+	return tblInfo.AutoRandomBits > 0 && col.IsPKHandleColumn(tblInfo)
+}
+
 // onDuplicateUpdate updates the duplicate row.
 // TODO: Report rows affected and last insert id.
 func (e *InsertExec) onDuplicateUpdate(row []types.Datum, h int64, cols map[int]*expression.Assignment) error {
@@ -1029,10 +1266,22 @@ func (e *ReplaceExec) Next() (*Row, error) {
 	idx := 0
 	rowsLen := len(rows)
 	sc := e.ctx.GetSessionVars().StmtCtx
+	// The duplicate rows are prefetched in batches with their unique keys, prefetchEnd is the end of the batch.
+	prefetch := !e.ctx.GetSessionVars().SkipConstraintCheck
+	prefetchEnd := 0
 	for {
 		if idx >= rowsLen {
 			break
 		}
+		if prefetch && idx >= prefetchEnd {
+			prefetchEnd = idx + prefetchBatchSize
+			if prefetchEnd > rowsLen {
+				prefetchEnd = rowsLen
+			}
+			if err = e.prefetchUniqueKeys(rows[idx:prefetchEnd], true); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 		row := rows[idx]
 		h, err1 := e.Table.AddRecord(e.ctx, row)
 		if err1 == nil {
@@ -1086,6 +1335,8 @@ type UpdateExec struct {
 	newRowsData [][]types.Datum // The new values to be set.
 	fetched     bool
 	cursor      int
+
+	batcher *dmlBatcher
 }
 
 // Schema implements the Executor Schema interface.
@@ -1101,6 +1352,10 @@ func (e *UpdateExec) Next() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 		e.fetched = true
+		// Only single table update can be batched.
+		if len(e.rows) > 0 && len(e.rows[0].RowKeys) == 1 {
+			e.batcher = newDMLBatcher(e.ctx)
+		}
 	}
 
 	assignFlag, err := getUpdateColumns(e.OrderedList)
@@ -1108,6 +1363,10 @@ func (e *UpdateExec) Next() (*Row, error) {
 		return nil, errors.Trace(err)
 	}
 	if e.cursor >= len(e.rows) {
+		if e.batcher != nil {
+			e.batcher.finish()
+			e.batcher = nil
+		}
 		return nil, nil
 	}
 	if e.updatedRowKeys == nil {
@@ -1131,12 +1390,19 @@ func (e *UpdateExec) Next() (*Row, error) {
 			// Each matched row is updated once, even if it matches the conditions multiple times.
 			continue
 		}
-		// Update row
-		err1 := updateRecord(e.ctx, handle, oldData, newTableData, flags, tbl, false)
-		if err1 != nil {
-			return nil, errors.Trace(err1)
+		if e.batcher == nil || !e.batcher.dryRun {
+			// Update row
+			err1 := updateRecord(e.ctx, handle, oldData, newTableData, flags, tbl, false)
+			if err1 != nil {
+				return nil, errors.Trace(err1)
+			}
 		}
 		e.updatedRowKeys[tbl][handle] = struct{}{}
+		if e.batcher != nil {
+			if err1 := e.batcher.add(handle); err1 != nil {
+				return nil, errors.Trace(err1)
+			}
+		}
 	}
 	e.cursor++
 	return &Row{}, nil
@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/testutil"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -224,6 +225,43 @@ func (s *testSuite) TestInsertAutoInc(c *C) {
 	r.Check(testkit.Rows(rowStr3, rowStr1, rowStr2, rowStr4, rowStr5, rowStr6))
 }
 
+func (s *testSuite) TestInsertAutoRandom(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t_rand (a bigint primary key auto_random(3), b int)")
+	result := tk.MustQuery("show create table t_rand")
+	c.Assert(result.Rows()[0][1], Matches, "(?s).*`a` bigint\\(21\\) NOT NULL AUTO_RANDOM\\(3\\),.*")
+
+	// The rows inserted by a transaction have the same shard, the auto IDs are in the low bits.
+	tk.MustExec("insert t_rand (b) values (1), (2)")
+	tk.MustExec("insert t_rand (a, b) values (null, 3)")
+	lastID := tk.Se.LastInsertID()
+	rows := tk.MustQuery("select a from t_rand order by b").Rows()
+	c.Assert(rows, HasLen, 3)
+	var ids []int64
+	for _, row := range rows {
+		var id int64
+		_, err := fmt.Sscan(fmt.Sprint(row[0]), &id)
+		c.Assert(err, IsNil)
+		ids = append(ids, id)
+	}
+	c.Assert(ids[0]>>60, Equals, ids[1]>>60)
+	c.Assert(uint64(ids[2]), Equals, lastID)
+	for i, id := range ids {
+		c.Assert(id&(1<<60-1), Equals, int64(i+1))
+	}
+
+	// The explicit value rebases the auto ID.
+	tk.MustExec("insert t_rand values (100, 4)")
+	tk.MustExec("insert t_rand (b) values (5)")
+	tk.MustQuery("select a & 1152921504606846975 from t_rand where b = 5").Check(testkit.Rows("101"))
+	tk.MustExec("drop table t_rand")
+}
+
 func (s *testSuite) TestInsertIgnore(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -253,6 +291,26 @@ func (s *testSuite) TestInsertIgnore(c *C) {
 	_, err := tk.Exec("insert ignore into t values (1, 3)")
 	c.Assert(err, NotNil)
 	cfg.SetGetError(nil)
+
+	// The unique keys of the rows are prefetched, the rows duplicated with the stored rows or the earlier rows of
+	// the statement are both ignored.
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, c1 int, c2 int, unique key u (c1))")
+	tk.MustExec("insert into t values (1, 10, 0), (2, 20, 0)")
+	tk.MustExec("insert ignore into t values (1, 30, 1), (3, 20, 1), (4, 40, 1), (5, 40, 1), (6, null, 1), (7, null, 1)")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 10 0", "2 20 0", "4 40 1", "6 <nil> 1", "7 <nil> 1"))
+	tk.MustExec("insert into t values (2, 50, 2), (8, 40, 2), (9, 90, 2) on duplicate key update c2 = c2 + 10")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 10 0", "2 20 10", "4 40 11", "6 <nil> 1", "7 <nil> 1", "9 90 2"))
+	tk.MustExec("insert into t select id + 10, c1, c2 from t on duplicate key update c2 = 100")
+	tk.MustQuery("select count(*), sum(c2) from t").Check(testkit.Rows("8 404"))
+
+	// The index entries are written without buffering when the constraints are not checked.
+	tk.MustExec("set @@tidb_skip_constraint_check = 1")
+	tk.MustExec("insert into t values (30, 300, 3), (31, 310, 3)")
+	tk.MustExec("set @@tidb_skip_constraint_check = 0")
+	tk.MustQuery("select id from t use index (u) where c1 >= 300").Check(testkit.Rows("30", "31"))
+	_, err = tk.Exec("insert into t values (32, 300, 3)")
+	c.Assert(err, NotNil)
 }
 
 func (s *testSuite) TestReplace(c *C) {
@@ -378,6 +436,15 @@ func (s *testSuite) TestReplace(c *C) {
 	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(3))
 	r = tk.MustQuery("select * from tIssue1012;")
 	r.Check(testkit.Rows("1 1"))
+
+	// The duplicate rows are prefetched, the rows duplicated with the stored rows or the earlier rows of the
+	// statement are both replaced.
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, c1 int, c2 int, unique key u (c1))")
+	tk.MustExec("insert into t values (1, 10, 0), (2, 20, 0), (3, 30, 0)")
+	tk.MustExec("replace into t values (1, 40, 1), (4, 20, 1), (5, 40, 1), (3, 30, 0)")
+	c.Assert(int64(tk.Se.AffectedRows()), Equals, int64(7))
+	tk.MustQuery("select * from t").Check(testkit.Rows("3 30 0", "4 20 1", "5 40 1"))
 }
 
 func (s *testSuite) TestUpdate(c *C) {
@@ -555,6 +622,49 @@ func (s *testSuite) TestDelete(c *C) {
 	tk.CheckExecResult(1, 0)
 }
 
+func (s *testSuite) TestBatchDML(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, v int, index idx(v))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3), (4, 4), (5, 5), (6, 6), (7, 7), (8, 8), (9, 9), (10, 10)")
+	tk.MustExec("set @@session.tidb_dml_batch_size = 3")
+
+	// Dry run only reports the batches.
+	tk.MustExec("set @@session.tidb_batch_dml_dry_run = 1")
+	tk.MustExec("delete from t where id > 2")
+	tk.CheckExecResult(0, 0)
+	tk.MustQuery("show warnings").Check(testutil.RowsWithSep("|",
+		"Warning|1105|batch 1: 3 rows, handle range [3, 5]",
+		"Warning|1105|batch 2: 3 rows, handle range [6, 8]",
+		"Warning|1105|batch 3: 2 rows, handle range [9, 10]",
+		"Warning|1105|dry run: 8 rows in 3 batches, no row is changed"))
+	tk.MustExec("update t set v = v + 10")
+	tk.CheckExecResult(0, 0)
+	tk.MustQuery("select count(*), sum(v) from t").Check(testkit.Rows("10 55"))
+
+	tk.MustExec("set @@session.tidb_batch_dml_dry_run = 0")
+	tk.MustExec("update t set v = v + 10 where id <= 7")
+	tk.CheckExecResult(7, 0)
+	tk.MustQuery("show warnings").Check(testutil.RowsWithSep("|", "Warning|1105|7 rows are changed in 3 batches"))
+	tk.MustQuery("select v from t use index(idx) where v > 10").Check(testkit.Rows("11", "12", "13", "14", "15", "16", "17"))
+	tk.MustExec("delete from t where id > 2")
+	tk.CheckExecResult(8, 0)
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 11", "2 12"))
+	tk.MustQuery("select count(*) from t use index(idx)").Check(testkit.Rows("2"))
+
+	// The statements in a transaction are not batched.
+	tk.MustExec("insert into t values (3, 3), (4, 4), (5, 5), (6, 6)")
+	tk.MustExec("begin")
+	tk.MustExec("delete from t where id > 2")
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("6"))
+}
+
 func (s *testSuite) fillDataMultiTable(tk *testkit.TestKit) {
 	tk.MustExec("use test")
 	tk.MustExec("drop table if exists t1, t2, t3")
@@ -837,6 +947,46 @@ func (s *testSuite) TestLoadDataEscape(c *C) {
 	checkCases(cases, ld, c, tk, ctx, selectSQL, deleteSQL)
 }
 
+func (s *testSuite) TestLoadDataIgnoreLinesAndBatch(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test; drop table if exists load_data_test;")
+	tk.MustExec("create table load_data_test (id int primary key, c1 int)")
+	tk.MustExec("load data local infile '/tmp/nonexistence.csv' into table load_data_test ignore 2 lines")
+	ctx := tk.Se.(context.Context)
+	ld := makeLoadDataInfo(2, ctx, c)
+	ld.IgnoreLines = 2
+	ld.SetBatchCount(2)
+
+	c.Assert(ctx.NewTxn(), IsNil)
+	data, reachLimit, err := ld.InsertData(nil, []byte("1\t1\n2\t2\n3\t3\n4\t4\n5\t5\n6\t6\n7\t7"))
+	c.Assert(err, IsNil)
+	c.Assert(reachLimit, IsTrue)
+	c.Assert(ld.CommitBatch(), IsNil)
+	c.Assert(ld.CommittedLines(), Equals, uint64(4))
+	// The committed rows are visible to other sessions.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustQuery("select * from load_data_test").Check(testkit.Rows("3 3", "4 4"))
+
+	data, reachLimit, err = ld.InsertData(nil, data)
+	c.Assert(err, IsNil)
+	c.Assert(reachLimit, IsTrue)
+	c.Assert(ld.CommitBatch(), IsNil)
+	c.Assert(ld.CommittedLines(), Equals, uint64(6))
+	data, reachLimit, err = ld.InsertData(data, nil)
+	c.Assert(err, IsNil)
+	c.Assert(reachLimit, IsFalse)
+	c.Assert(data, HasLen, 0)
+	// The rows of the last batch are not committed yet.
+	tk1.MustQuery("select count(*) from load_data_test").Check(testkit.Rows("4"))
+	c.Assert(ctx.Txn().Commit(), IsNil)
+	tk.MustQuery("select * from load_data_test").Check(testkit.Rows("3 3", "4 4", "5 5", "6 6", "7 7"))
+}
+
 func makeLoadDataInfo(column int, ctx context.Context, c *C) (ld *executor.LoadDataInfo) {
 	domain := sessionctx.GetDomain(ctx)
 	is := domain.InfoSchema()
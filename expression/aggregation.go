@@ -17,14 +17,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/hyperloglog"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -50,6 +58,9 @@ type AggregationFunction interface {
 	// GetStreamResult gets a result using streaming agg.
 	GetStreamResult() types.Datum
 
+	// GetPartialResult gets the intermediate result of a group, which can be merged by the same function in FinalMode.
+	GetPartialResult(groupKey []byte) []types.Datum
+
 	// GetArgs stands for getting all arguments.
 	GetArgs() []Expression
 
@@ -77,6 +88,9 @@ type AggregationFunction interface {
 	// GetType gets field type of aggregate function.
 	GetType() *types.FieldType
 
+	// SetMemTracker sets the tracker which tracks the memory usage of the distinct values and the sketches.
+	SetMemTracker(tracker *memory.Tracker)
+
 	// CalculateDefaultValue gets the default value when the aggregate function's input is null.
 	// The input stands for the schema of Aggregation's child. If the function can't produce a default value, the second
 	// return value will be false.
@@ -88,10 +102,18 @@ type aggEvaluateContext struct {
 	DistinctChecker *distinctChecker
 	Count           int64
 	Value           types.Datum
-	Buffer          *bytes.Buffer // Buffer is used for group_concat.
+	Buffer          *bytes.Buffer // Buffer is used for group_concat and json_arrayagg.
 	GotFirstRow     bool          // It will check if the agg has met the first row key.
+
+	concatRows  []*concatRow        // concatRows is used for group_concat with order by.
+	truncated   bool                // truncated indicates the result of group_concat is cut by group_concat_max_len.
+	jsonMembers map[string]string   // jsonMembers is used for json_objectagg.
+	sketch      *hyperloglog.Sketch // sketch is used for approx_count_distinct.
 }
 
+// defaultGroupConcatMaxLen is the group_concat_max_len used when the variable can't be read.
+const defaultGroupConcatMaxLen = 1024
+
 // NewAggFunction creates a new AggregationFunction.
 func NewAggFunction(funcType string, funcArgs []Expression, distinct bool) AggregationFunction {
 	switch tp := strings.ToLower(funcType); tp {
@@ -102,7 +124,13 @@ func NewAggFunction(funcType string, funcArgs []Expression, distinct bool) Aggre
 	case ast.AggFuncAvg:
 		return &avgFunction{aggFunction: newAggFunc(tp, funcArgs, distinct)}
 	case ast.AggFuncGroupConcat:
-		return &concatFunction{aggFunction: newAggFunc(tp, funcArgs, distinct)}
+		return &concatFunction{aggFunction: newAggFunc(tp, funcArgs, distinct), separator: ","}
+	case ast.AggFuncJSONArrayAgg:
+		return &jsonArrayAggFunction{aggFunction: newAggFunc(tp, funcArgs, distinct)}
+	case ast.AggFuncJSONObjectAgg:
+		return &jsonObjectAggFunction{aggFunction: newAggFunc(tp, funcArgs, distinct)}
+	case ast.AggFuncApproxCountDistinct:
+		return &approxCountDistinctFunction{aggFunction: newAggFunc(tp, funcArgs, distinct)}
 	case ast.AggFuncMax:
 		return &maxMinFunction{aggFunction: newAggFunc(tp, funcArgs, distinct), isMax: true}
 	case ast.AggFuncMin:
@@ -132,6 +160,7 @@ type aggFunction struct {
 	Distinct     bool
 	resultMapper aggCtxMapper
 	streamCtx    *aggEvaluateContext
+	memTracker   *memory.Tracker
 }
 
 // Equal implements AggregationFunction interface.
@@ -222,12 +251,17 @@ func (af *aggFunction) SetArgs(args []Expression) {
 	af.Args = args
 }
 
+// SetMemTracker implements AggregationFunction interface.
+func (af *aggFunction) SetMemTracker(tracker *memory.Tracker) {
+	af.memTracker = tracker
+}
+
 func (af *aggFunction) getContext(groupKey []byte) *aggEvaluateContext {
 	ctx, ok := af.resultMapper[string(groupKey)]
 	if !ok {
 		ctx = &aggEvaluateContext{}
 		if af.Distinct {
-			ctx.DistinctChecker = createDistinctChecker()
+			ctx.DistinctChecker = createDistinctChecker(af.memTracker)
 		}
 		af.resultMapper[string(groupKey)] = ctx
 	}
@@ -238,7 +272,7 @@ func (af *aggFunction) getStreamedContext() *aggEvaluateContext {
 	if af.streamCtx == nil {
 		af.streamCtx = &aggEvaluateContext{}
 		if af.Distinct {
-			af.streamCtx.DistinctChecker = createDistinctChecker()
+			af.streamCtx.DistinctChecker = createDistinctChecker(af.memTracker)
 		}
 	}
 	return af.streamCtx
@@ -310,6 +344,7 @@ type sumFunction struct {
 // Clone implements AggregationFunction interface.
 func (sf *sumFunction) Clone() AggregationFunction {
 	nf := *sf
+	nf.Args = make([]Expression, len(sf.Args))
 	for i, arg := range sf.Args {
 		nf.Args[i] = arg.Clone()
 	}
@@ -342,6 +377,11 @@ func (sf *sumFunction) GetStreamResult() (d types.Datum) {
 	return
 }
 
+// GetPartialResult implements AggregationFunction interface.
+func (sf *sumFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{sf.GetGroupResult(groupKey)}
+}
+
 // CalculateDefaultValue implements AggregationFunction interface.
 func (sf *sumFunction) CalculateDefaultValue(schema *Schema, ctx context.Context) (d types.Datum, valid bool) {
 	arg := sf.Args[0]
@@ -376,6 +416,7 @@ type countFunction struct {
 // Clone implements AggregationFunction interface.
 func (cf *countFunction) Clone() AggregationFunction {
 	nf := *cf
+	nf.Args = make([]Expression, len(cf.Args))
 	for i, arg := range cf.Args {
 		nf.Args[i] = arg.Clone()
 	}
@@ -496,6 +537,11 @@ func (cf *countFunction) GetStreamResult() (d types.Datum) {
 	return
 }
 
+// GetPartialResult implements AggregationFunction interface.
+func (cf *countFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{cf.GetGroupResult(groupKey)}
+}
+
 type avgFunction struct {
 	aggFunction
 }
@@ -503,6 +549,7 @@ type avgFunction struct {
 // Clone implements AggregationFunction interface.
 func (af *avgFunction) Clone() AggregationFunction {
 	nf := *af
+	nf.Args = make([]Expression, len(af.Args))
 	for i, arg := range af.Args {
 		nf.Args[i] = arg.Clone()
 	}
@@ -595,13 +642,74 @@ func (af *avgFunction) GetStreamResult() (d types.Datum) {
 	return
 }
 
+// GetPartialResult implements AggregationFunction interface.
+func (af *avgFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	ctx := af.getContext(groupKey)
+	return []types.Datum{types.NewIntDatum(ctx.Count), ctx.Value}
+}
+
 type concatFunction struct {
 	aggFunction
+	separator string
+	// descs are the orders of the order by items, the order by items are the last len(descs) arguments.
+	descs []bool
+
+	// sc and maxLen are the statement context and the group_concat_max_len of the statement using the function.
+	sc     *variable.StatementContext
+	maxLen uint64
+}
+
+// concatRow is a value of group_concat with order by, the values are sorted by the keys when getting the result.
+type concatRow struct {
+	value string
+	keys  []types.Datum
+}
+
+// concatRowSorter sorts the values of group_concat with order by.
+type concatRowSorter struct {
+	rows  []*concatRow
+	descs []bool
+	sc    *variable.StatementContext
+	err   error
+}
+
+func (s *concatRowSorter) Len() int      { return len(s.rows) }
+func (s *concatRowSorter) Swap(i, j int) { s.rows[i], s.rows[j] = s.rows[j], s.rows[i] }
+func (s *concatRowSorter) Less(i, j int) bool {
+	for k, desc := range s.descs {
+		cmp, err := s.rows[i].keys[k].CompareDatum(s.sc, s.rows[j].keys[k])
+		if err != nil {
+			s.err = errors.Trace(err)
+			return false
+		}
+		if cmp != 0 {
+			return (cmp < 0) != desc
+		}
+	}
+	return false
+}
+
+// NewGroupConcatFunction creates a group_concat function, the values are ordered by the byItems and joined by the
+// separator.
+func NewGroupConcatFunction(args []Expression, distinct bool, byItems []Expression, descs []bool, separator string) AggregationFunction {
+	cf := &concatFunction{
+		aggFunction: newAggFunc(ast.AggFuncGroupConcat, append(args, byItems...), distinct),
+		separator:   separator,
+		descs:       descs,
+	}
+	return cf
+}
+
+// GroupConcatInfo returns the separator of the group_concat function, and whether its values are ordered.
+func GroupConcatInfo(af AggregationFunction) (separator string, ordered bool) {
+	cf := af.(*concatFunction)
+	return cf.separator, len(cf.descs) > 0
 }
 
 // Clone implements AggregationFunction interface.
 func (cf *concatFunction) Clone() AggregationFunction {
 	nf := *cf
+	nf.Args = make([]Expression, len(cf.Args))
 	for i, arg := range cf.Args {
 		nf.Args[i] = arg.Clone()
 	}
@@ -609,60 +717,103 @@ func (cf *concatFunction) Clone() AggregationFunction {
 	return &nf
 }
 
+// Equal implements AggregationFunction interface.
+func (cf *concatFunction) Equal(b AggregationFunction, ctx context.Context) bool {
+	if !cf.aggFunction.Equal(b, ctx) {
+		return false
+	}
+	other := b.(*concatFunction)
+	if cf.separator != other.separator || len(cf.descs) != len(other.descs) {
+		return false
+	}
+	for i, desc := range cf.descs {
+		if desc != other.descs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String implements fmt.Stringer interface.
+func (cf *concatFunction) String() string {
+	args := cf.valueArgs()
+	strs := make([]string, 0, len(args))
+	for _, arg := range args {
+		strs = append(strs, arg.String())
+	}
+	result := cf.name + "(" + strings.Join(strs, ", ")
+	if len(cf.descs) > 0 {
+		items := make([]string, 0, len(cf.descs))
+		for i, item := range cf.Args[len(args):] {
+			if cf.descs[i] {
+				items = append(items, item.String()+" desc")
+			} else {
+				items = append(items, item.String())
+			}
+		}
+		result += " order by " + strings.Join(items, ", ")
+	}
+	if cf.separator != "," {
+		result += fmt.Sprintf(" separator %q", cf.separator)
+	}
+	return result + ")"
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (cf *concatFunction) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(fmt.Sprintf("%q", cf))
+	return buffer.Bytes(), nil
+}
+
 // GetType implements AggregationFunction interface.
 func (cf *concatFunction) GetType() *types.FieldType {
 	return types.NewFieldType(mysql.TypeVarString)
 }
 
-// Update implements AggregationFunction interface.
-func (cf *concatFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
-	ctx := cf.getContext(groupKey)
-	vals := make([]interface{}, 0, len(cf.Args))
-	for _, a := range cf.Args {
-		value, err := a.Eval(row)
-		if err != nil {
-			return errors.Trace(err)
-		}
-		if value.GetValue() == nil {
-			return nil
-		}
-		vals = append(vals, value.GetValue())
-	}
-	if cf.Distinct {
-		d, err := ctx.DistinctChecker.Check(vals)
-		if err != nil {
-			return errors.Trace(err)
-		}
-		if !d {
-			return nil
-		}
+// valueArgs returns the arguments which are concatenated, the others are the order by items.
+func (cf *concatFunction) valueArgs() []Expression {
+	return cf.Args[:len(cf.Args)-len(cf.descs)]
+}
+
+// prepare reads the group_concat_max_len when a new statement starts using the function.
+func (cf *concatFunction) prepare(ectx context.Context) {
+	vars := ectx.GetSessionVars()
+	if cf.sc == vars.StmtCtx {
+		return
 	}
-	if ctx.Buffer == nil {
-		ctx.Buffer = &bytes.Buffer{}
-	} else {
-		// now use comma separator
-		ctx.Buffer.WriteString(",")
+	cf.sc = vars.StmtCtx
+	cf.maxLen = defaultGroupConcatMaxLen
+	val, err := varsutil.GetSessionSystemVar(vars, variable.GroupConcatMaxLen)
+	if err != nil {
+		log.Warnf("get %s error: %v", variable.GroupConcatMaxLen, err)
+		return
 	}
-	for _, val := range vals {
-		ctx.Buffer.WriteString(fmt.Sprintf("%v", val))
+	if maxLen, err := strconv.ParseUint(val, 10, 64); err == nil {
+		cf.maxLen = maxLen
 	}
-	// TODO: if total length is greater than global var group_concat_max_len, truncate it.
-	return nil
 }
 
-// StreamUpdate implements AggregationFunction interface.
-func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
-	ctx := cf.getStreamedContext()
-	vals := make([]interface{}, 0, len(cf.Args))
-	for _, a := range cf.Args {
+func (cf *concatFunction) update(ctx *aggEvaluateContext, row []types.Datum, ectx context.Context) error {
+	cf.prepare(ectx)
+	args := cf.valueArgs()
+	vals := make([]interface{}, 0, len(args))
+	buf := &bytes.Buffer{}
+	for _, a := range args {
 		value, err := a.Eval(row)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if value.GetValue() == nil {
+		if value.IsNull() {
 			return nil
 		}
-		vals = append(vals, value)
+		if cf.Distinct {
+			vals = append(vals, value.GetValue())
+		}
+		s, err := value.ToString()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		buf.WriteString(s)
 	}
 	if cf.Distinct {
 		d, err := ctx.DistinctChecker.Check(vals)
@@ -673,22 +824,68 @@ func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context)
 			return nil
 		}
 	}
+	ctx.Count++
+	if len(cf.descs) > 0 {
+		keys := make([]types.Datum, 0, len(cf.descs))
+		for _, item := range cf.Args[len(args):] {
+			key, err := item.Eval(row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			keys = append(keys, key)
+		}
+		ctx.concatRows = append(ctx.concatRows, &concatRow{value: buf.String(), keys: keys})
+		return nil
+	}
+	if ctx.truncated {
+		return nil
+	}
 	if ctx.Buffer == nil {
 		ctx.Buffer = &bytes.Buffer{}
 	} else {
-		// now use comma separator
-		ctx.Buffer.WriteString(",")
+		ctx.Buffer.WriteString(cf.separator)
 	}
-	for _, val := range vals {
-		ctx.Buffer.WriteString(fmt.Sprintf("%v", val))
-	}
-	// TODO: if total length is greater than global var group_concat_max_len, truncate it.
+	ctx.Buffer.Write(buf.Bytes())
+	cf.truncate(ctx)
 	return nil
 }
 
-// GetGroupResult implements AggregationFunction interface.
-func (cf *concatFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
-	ctx := cf.getContext(groupKey)
+// truncate cuts the result to group_concat_max_len bytes, a warning is appended if the result is cut.
+func (cf *concatFunction) truncate(ctx *aggEvaluateContext) {
+	if uint64(ctx.Buffer.Len()) <= cf.maxLen {
+		return
+	}
+	n := int(cf.maxLen)
+	// Don't leave a partial utf8 character at the end.
+	for n > 0 && !utf8.RuneStart(ctx.Buffer.Bytes()[n]) {
+		n--
+	}
+	ctx.Buffer.Truncate(n)
+	ctx.truncated = true
+	cf.sc.AppendWarning(errCutValueGroupConcat.GenByArgs(ctx.Count))
+}
+
+// result returns the result of the context, the values of group_concat with order by are sorted and joined.
+func (cf *concatFunction) result(ctx *aggEvaluateContext) (d types.Datum) {
+	if len(ctx.concatRows) > 0 {
+		sorter := &concatRowSorter{rows: ctx.concatRows, descs: cf.descs, sc: cf.sc}
+		sort.Stable(sorter)
+		if sorter.err != nil {
+			log.Warnf("sort the values of %s error: %v", cf, sorter.err)
+		}
+		ctx.Buffer = &bytes.Buffer{}
+		for i, row := range ctx.concatRows {
+			if i > 0 {
+				ctx.Buffer.WriteString(cf.separator)
+			}
+			ctx.Buffer.WriteString(row.value)
+			if uint64(ctx.Buffer.Len()) > cf.maxLen {
+				cf.truncate(ctx)
+				break
+			}
+		}
+		ctx.concatRows = nil
+	}
 	if ctx.Buffer != nil {
 		d.SetString(ctx.Buffer.String())
 	} else {
@@ -697,20 +894,36 @@ func (cf *concatFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
 	return d
 }
 
+// Update implements AggregationFunction interface.
+func (cf *concatFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return cf.update(cf.getContext(groupKey), row, ectx)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return cf.update(cf.getStreamedContext(), row, ectx)
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (cf *concatFunction) GetGroupResult(groupKey []byte) types.Datum {
+	return cf.result(cf.getContext(groupKey))
+}
+
 // GetStreamResult implements AggregationFunction interface.
 func (cf *concatFunction) GetStreamResult() (d types.Datum) {
 	if cf.streamCtx == nil {
 		return
 	}
-	if cf.streamCtx.Buffer != nil {
-		d.SetString(cf.streamCtx.Buffer.String())
-	} else {
-		d.SetNull()
-	}
+	d = cf.result(cf.streamCtx)
 	cf.streamCtx = nil
 	return
 }
 
+// GetPartialResult implements AggregationFunction interface.
+func (cf *concatFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{cf.GetGroupResult(groupKey)}
+}
+
 type maxMinFunction struct {
 	aggFunction
 	isMax bool
@@ -719,6 +932,7 @@ type maxMinFunction struct {
 // Clone implements AggregationFunction interface.
 func (mmf *maxMinFunction) Clone() AggregationFunction {
 	nf := *mmf
+	nf.Args = make([]Expression, len(mmf.Args))
 	for i, arg := range mmf.Args {
 		nf.Args[i] = arg.Clone()
 	}
@@ -760,6 +974,11 @@ func (mmf *maxMinFunction) GetStreamResult() (d types.Datum) {
 	return
 }
 
+// GetPartialResult implements AggregationFunction interface.
+func (mmf *maxMinFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{mmf.GetGroupResult(groupKey)}
+}
+
 // Update implements AggregationFunction interface.
 func (mmf *maxMinFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
 	ctx := mmf.getContext(groupKey)
@@ -823,6 +1042,7 @@ type firstRowFunction struct {
 // Clone implements AggregationFunction interface.
 func (ff *firstRowFunction) Clone() AggregationFunction {
 	nf := *ff
+	nf.Args = make([]Expression, len(ff.Args))
 	for i, arg := range ff.Args {
 		nf.Args[i] = arg.Clone()
 	}
@@ -886,6 +1106,11 @@ func (ff *firstRowFunction) GetStreamResult() (d types.Datum) {
 	return
 }
 
+// GetPartialResult implements AggregationFunction interface.
+func (ff *firstRowFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{ff.GetGroupResult(groupKey)}
+}
+
 // CalculateDefaultValue implements AggregationFunction interface.
 func (ff *firstRowFunction) CalculateDefaultValue(schema *Schema, ctx context.Context) (d types.Datum, valid bool) {
 	arg := ff.Args[0]
@@ -899,3 +1124,344 @@ func (ff *firstRowFunction) CalculateDefaultValue(schema *Schema, ctx context.Co
 	}
 	return d, false
 }
+
+type jsonArrayAggFunction struct {
+	aggFunction
+}
+
+// Clone implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) Clone() AggregationFunction {
+	nf := *jf
+	nf.Args = make([]Expression, len(jf.Args))
+	for i, arg := range jf.Args {
+		nf.Args[i] = arg.Clone()
+	}
+	nf.resultMapper = make(aggCtxMapper)
+	return &nf
+}
+
+// GetType implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) GetType() *types.FieldType {
+	return types.NewFieldType(mysql.TypeVarString)
+}
+
+func (jf *jsonArrayAggFunction) update(ctx *aggEvaluateContext, row []types.Datum) error {
+	value, err := jf.Args[0].Eval(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s, err := jsonEncode(value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ctx.Buffer == nil {
+		ctx.Buffer = bytes.NewBufferString("[")
+	} else {
+		ctx.Buffer.WriteString(", ")
+	}
+	ctx.Buffer.WriteString(s)
+	return nil
+}
+
+func (jf *jsonArrayAggFunction) result(ctx *aggEvaluateContext) (d types.Datum) {
+	if ctx.Buffer == nil {
+		return
+	}
+	d.SetString(ctx.Buffer.String() + "]")
+	return
+}
+
+// Update implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return jf.update(jf.getContext(groupKey), row)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return jf.update(jf.getStreamedContext(), row)
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) GetGroupResult(groupKey []byte) types.Datum {
+	return jf.result(jf.getContext(groupKey))
+}
+
+// GetStreamResult implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) GetStreamResult() (d types.Datum) {
+	if jf.streamCtx == nil {
+		return
+	}
+	d = jf.result(jf.streamCtx)
+	jf.streamCtx = nil
+	return
+}
+
+// GetPartialResult implements AggregationFunction interface.
+func (jf *jsonArrayAggFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{jf.GetGroupResult(groupKey)}
+}
+
+type jsonObjectAggFunction struct {
+	aggFunction
+}
+
+// Clone implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) Clone() AggregationFunction {
+	nf := *jf
+	nf.Args = make([]Expression, len(jf.Args))
+	for i, arg := range jf.Args {
+		nf.Args[i] = arg.Clone()
+	}
+	nf.resultMapper = make(aggCtxMapper)
+	return &nf
+}
+
+// GetType implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) GetType() *types.FieldType {
+	return types.NewFieldType(mysql.TypeVarString)
+}
+
+func (jf *jsonObjectAggFunction) update(ctx *aggEvaluateContext, row []types.Datum) error {
+	key, err := jf.Args[0].Eval(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if key.IsNull() {
+		return errJSONDocumentNULLKey
+	}
+	k, err := key.ToString()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	value, err := jf.Args[1].Eval(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	v, err := jsonEncode(value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ctx.jsonMembers == nil {
+		ctx.jsonMembers = make(map[string]string)
+	}
+	// The value of a duplicate key overwrites the former one.
+	ctx.jsonMembers[k] = v
+	return nil
+}
+
+func (jf *jsonObjectAggFunction) result(ctx *aggEvaluateContext) (d types.Datum) {
+	if ctx.jsonMembers == nil {
+		return
+	}
+	keys := make(jsonKeys, 0, len(ctx.jsonMembers))
+	for k := range ctx.jsonMembers {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	buf := bytes.NewBufferString("{")
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(jsonQuote(k))
+		buf.WriteString(": ")
+		buf.WriteString(ctx.jsonMembers[k])
+	}
+	buf.WriteString("}")
+	d.SetString(buf.String())
+	return
+}
+
+// Update implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return jf.update(jf.getContext(groupKey), row)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return jf.update(jf.getStreamedContext(), row)
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) GetGroupResult(groupKey []byte) types.Datum {
+	return jf.result(jf.getContext(groupKey))
+}
+
+// GetStreamResult implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) GetStreamResult() (d types.Datum) {
+	if jf.streamCtx == nil {
+		return
+	}
+	d = jf.result(jf.streamCtx)
+	jf.streamCtx = nil
+	return
+}
+
+// GetPartialResult implements AggregationFunction interface.
+func (jf *jsonObjectAggFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	return []types.Datum{jf.GetGroupResult(groupKey)}
+}
+
+// jsonKeys sorts the keys of a json object like MySQL, the shorter keys come first and the keys of the same length
+// are sorted in byte order.
+type jsonKeys []string
+
+func (k jsonKeys) Len() int      { return len(k) }
+func (k jsonKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k jsonKeys) Less(i, j int) bool {
+	if len(k[i]) != len(k[j]) {
+		return len(k[i]) < len(k[j])
+	}
+	return k[i] < k[j]
+}
+
+// jsonEncode encodes the datum to a json value, the numbers are kept as numbers and the others are strings.
+func jsonEncode(d types.Datum) (string, error) {
+	switch d.Kind() {
+	case types.KindNull:
+		return "null", nil
+	case types.KindInt64, types.KindUint64, types.KindFloat32, types.KindFloat64, types.KindMysqlDecimal:
+		s, err := d.ToString()
+		return s, errors.Trace(err)
+	}
+	s, err := d.ToString()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return jsonQuote(s), nil
+}
+
+// jsonQuote quotes the string as a json string.
+func jsonQuote(s string) string {
+	buf := bytes.NewBufferString(`"`)
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteString(`"`)
+	return buf.String()
+}
+
+// approxCountDistinctFunction estimates the number of distinct values by a HyperLogLog sketch, whose memory usage
+// is fixed no matter how many distinct values there are. Its partial result is the encoded sketch.
+type approxCountDistinctFunction struct {
+	aggFunction
+}
+
+// Clone implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) Clone() AggregationFunction {
+	nf := *af
+	nf.Args = make([]Expression, len(af.Args))
+	for i, arg := range af.Args {
+		nf.Args[i] = arg.Clone()
+	}
+	nf.resultMapper = make(aggCtxMapper)
+	return &nf
+}
+
+// GetType implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetType() *types.FieldType {
+	ft := types.NewFieldType(mysql.TypeLonglong)
+	ft.Flen = 21
+	ft.Charset = charset.CharsetBin
+	ft.Collate = charset.CollationBin
+	return ft
+}
+
+func (af *approxCountDistinctFunction) update(ctx *aggEvaluateContext, row []types.Datum) error {
+	vals := make([]types.Datum, 0, len(af.Args))
+	for _, a := range af.Args {
+		value, err := a.Eval(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if value.IsNull() {
+			return nil
+		}
+		vals = append(vals, value)
+	}
+	if ctx.sketch == nil {
+		ctx.sketch = hyperloglog.New()
+	}
+	memUsage := ctx.sketch.MemUsage()
+	if af.mode == FinalMode {
+		sketch, err := hyperloglog.Decode(vals[0].GetBytes())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		ctx.sketch.Merge(sketch)
+	} else {
+		key, err := codec.EncodeValue(nil, vals...)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		ctx.sketch.Insert(key)
+	}
+	if delta := ctx.sketch.MemUsage() - memUsage; delta > 0 && af.memTracker != nil {
+		return errors.Trace(af.memTracker.Consume(delta))
+	}
+	return nil
+}
+
+// Update implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
+	return af.update(af.getContext(groupKey), row)
+}
+
+// StreamUpdate implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) StreamUpdate(row []types.Datum, ectx context.Context) error {
+	return af.update(af.getStreamedContext(), row)
+}
+
+// GetGroupResult implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
+	ctx := af.getContext(groupKey)
+	if ctx.sketch == nil {
+		d.SetInt64(0)
+		return
+	}
+	d.SetInt64(ctx.sketch.Count())
+	return
+}
+
+// GetStreamResult implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetStreamResult() (d types.Datum) {
+	d.SetInt64(0)
+	if af.streamCtx == nil {
+		return
+	}
+	if af.streamCtx.sketch != nil {
+		d.SetInt64(af.streamCtx.sketch.Count())
+	}
+	af.streamCtx = nil
+	return
+}
+
+// GetPartialResult implements AggregationFunction interface.
+func (af *approxCountDistinctFunction) GetPartialResult(groupKey []byte) []types.Datum {
+	ctx := af.getContext(groupKey)
+	if ctx.sketch == nil {
+		return []types.Datum{types.NewBytesDatum(nil)}
+	}
+	return []types.Datum{types.NewBytesDatum(ctx.sketch.Encode())}
+}
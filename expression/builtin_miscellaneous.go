@@ -114,11 +114,19 @@ func (b *builtinSleepSig) eval(row []types.Datum) (d types.Datum, err error) {
 		return
 	}
 
-	// TODO: consider it's interrupted using KILL QUERY from other session, or
-	// interrupted by time out.
-	duration := time.Duration(args[0].GetFloat64() * float64(time.Second.Nanoseconds()))
-	time.Sleep(duration)
-	d.SetInt64(0)
+	// The argument may be an integer or a decimal, GetFloat64 only works for a float.
+	seconds, err := args[0].ToFloat64(sc)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	duration := time.Duration(seconds * float64(time.Second.Nanoseconds()))
+	select {
+	case <-time.After(duration):
+		d.SetInt64(0)
+	case <-b.ctx.Done():
+		// Like MySQL, SLEEP returns 1 if it's interrupted by KILL QUERY.
+		d.SetInt64(1)
+	}
 	return
 }
 
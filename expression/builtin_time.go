@@ -602,7 +602,7 @@ func builtinNow(args []types.Datum, ctx context.Context) (d types.Datum, err err
 		}
 	}
 
-	t, err := convertTimeToMysqlTime(time.Now(), fsp)
+	t, err := convertTimeToMysqlTime(time.Now().In(getTimeZone(ctx)), fsp)
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -1014,7 +1014,7 @@ func (b *builtinFromUnixTimeSig) eval(row []types.Datum) (d types.Datum, err err
 		fsp = types.MaxFsp
 	}
 
-	t, err := convertTimeToMysqlTime(time.Unix(integralPart, fractionalPart), fsp)
+	t, err := convertTimeToMysqlTime(time.Unix(integralPart, fractionalPart).In(getTimeZone(b.ctx)), fsp)
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -1164,7 +1164,7 @@ type builtinCurrentDateSig struct {
 
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_curdate
 func (b *builtinCurrentDateSig) eval(_ []types.Datum) (d types.Datum, err error) {
-	year, month, day := time.Now().Date()
+	year, month, day := time.Now().In(getTimeZone(b.ctx)).Date()
 	t := types.Time{
 		Time: types.FromDate(year, int(month), day, 0, 0, 0, 0),
 		Type: mysql.TypeDate, Fsp: 0}
@@ -1198,7 +1198,7 @@ func (b *builtinCurrentTimeSig) eval(row []types.Datum) (d types.Datum, err erro
 			return d, errors.Trace(err)
 		}
 	}
-	d.SetString(time.Now().Format("15:04:05.000000"))
+	d.SetString(time.Now().In(getTimeZone(b.ctx)).Format("15:04:05.000000"))
 	return convertToDuration(b.ctx.GetSessionVars().StmtCtx, d, fsp)
 }
 
@@ -1464,8 +1464,7 @@ func (b *builtinDateArithSig) eval(row []types.Datum) (d types.Datum, err error)
 	if b.op == ast.DateArithSub {
 		year, month, day, duration = -year, -month, -day, -duration
 	}
-	// TODO: Consider time_zone variable.
-	t, err := result.Time.GoTime(time.Local)
+	t, err := result.Time.GoTime(getTimeZone(b.ctx))
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -0,0 +1,116 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// VectorizedEval evaluates the expression over the rows of the chunk into result, which has a datum for each row.
+// The columns and the constants are evaluated over the whole chunk at once, the other expressions are evaluated row
+// by row.
+func VectorizedEval(expr Expression, chk *chunk.Chunk, result []types.Datum) error {
+	switch x := expr.(type) {
+	case *Column:
+		copy(result, chk.Column(x.Index))
+		return nil
+	case *Constant:
+		for i := range result {
+			result[i] = x.Value
+		}
+		return nil
+	}
+	var (
+		row []types.Datum
+		err error
+	)
+	for i := range result {
+		row = chk.GetRow(i, row)
+		result[i], err = expr.Eval(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// VectorizedFilter evaluates the conditions over the rows of the chunk, selected is set to whether each row matches all
+// of them, it's reallocated if it's too short. A condition is only evaluated for the rows which match the conditions
+// before it, like EvalBool is called row by row.
+func VectorizedFilter(ctx context.Context, conds []Expression, chk *chunk.Chunk, selected []bool) ([]bool, error) {
+	n := chk.NumRows()
+	if cap(selected) < n {
+		selected = make([]bool, n)
+	}
+	selected = selected[:n]
+	for i := range selected {
+		selected[i] = true
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	var row []types.Datum
+	for _, cond := range conds {
+		var vals []types.Datum
+		switch x := cond.(type) {
+		case *Column:
+			vals = chk.Column(x.Index)
+		case *Constant:
+			match, err := datumToBool(sc, x.Value)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if !match {
+				for i := range selected {
+					selected[i] = false
+				}
+			}
+			continue
+		}
+		for i := range selected {
+			if !selected[i] {
+				continue
+			}
+			var (
+				match bool
+				err   error
+			)
+			if vals != nil {
+				match, err = datumToBool(sc, vals[i])
+			} else {
+				row = chk.GetRow(i, row)
+				match, err = EvalBool(cond, row, ctx)
+			}
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			selected[i] = match
+		}
+	}
+	return selected, nil
+}
+
+// datumToBool converts the datum to a boolean value like EvalBool, NULL is false.
+func datumToBool(sc *variable.StatementContext, d types.Datum) (bool, error) {
+	if d.IsNull() {
+		return false, nil
+	}
+	i, err := d.ToBool(sc)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return i != 0, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (*testExpressionSuite) TestVectorizedEval(c *C) {
+	defer testleak.AfterTest(c)()
+	chk := chunk.NewChunk(2, 4)
+	chk.AppendRow(types.MakeDatums(1, 0))
+	chk.AppendRow(types.MakeDatums(2, nil))
+	chk.AppendRow(types.MakeDatums(3, 1))
+	chk.AppendRow(types.MakeDatums(4, 1))
+	col := newColumn("a")
+	col.Index = 1
+	cases := []struct {
+		expr   Expression
+		result []types.Datum
+	}{
+		{col, types.MakeDatums(0, nil, 1, 1)},
+		{newLonglong(5), types.MakeDatums(5, 5, 5, 5)},
+		{newFunction(ast.Plus, &Column{Index: 0}, newLonglong(1)), types.MakeDatums(2, 3, 4, 5)},
+	}
+	for _, t := range cases {
+		result := make([]types.Datum, chk.NumRows())
+		err := VectorizedEval(t.expr, chk, result)
+		c.Assert(err, IsNil)
+		c.Assert(result, DeepEquals, t.result)
+	}
+
+	ctx := mock.NewContext()
+	filters := []struct {
+		conds    []Expression
+		selected []bool
+	}{
+		{[]Expression{col}, []bool{false, false, true, true}},
+		{[]Expression{newLonglong(1)}, []bool{true, true, true, true}},
+		{[]Expression{newLonglong(0), col}, []bool{false, false, false, false}},
+		{[]Expression{col, newFunction(ast.GT, &Column{Index: 0}, newLonglong(3))}, []bool{false, false, false, true}},
+	}
+	var selected []bool
+	for _, t := range filters {
+		var err error
+		selected, err = VectorizedFilter(ctx, t.conds, chk, selected)
+		c.Assert(err, IsNil)
+		c.Assert(selected, DeepEquals, t.selected)
+	}
+}
@@ -33,6 +33,8 @@ var (
 	errInvalidOperation        = terror.ClassExpression.New(codeInvalidOperation, "invalid operation")
 	errIncorrectParameterCount = terror.ClassExpression.New(codeIncorrectParameterCount, "Incorrect parameter count in the call to native function '%s'")
 	errFunctionNotExists       = terror.ClassExpression.New(codeFunctionNotExists, "FUNCTION %s does not exist")
+	errCutValueGroupConcat     = terror.ClassExpression.New(codeCutValueGroupConcat, "Row %d was cut by GROUP_CONCAT()")
+	errJSONDocumentNULLKey     = terror.ClassExpression.New(codeJSONDocumentNULLKey, "JSON documents may not contain NULL member names.")
 )
 
 // Error codes.
@@ -40,11 +42,17 @@ const (
 	codeInvalidOperation        terror.ErrCode = 1
 	codeIncorrectParameterCount                = 1582
 	codeFunctionNotExists                      = 1305
+	codeCutValueGroupConcat                    = 1260
+	codeJSONDocumentNULLKey                    = 3158
 )
 
 // EvalAstExpr evaluates ast expression directly.
 var EvalAstExpr func(expr ast.ExprNode, ctx context.Context) (types.Datum, error)
 
+// RewriteTableExpr rewrites the ast expression on the public columns of the table, the columns in the result are
+// indexed by their offsets in the table.
+var RewriteTableExpr func(expr ast.ExprNode, ctx context.Context, tblInfo *model.TableInfo) (Expression, error)
+
 // Expression represents all scalar expression in SQL.
 type Expression interface {
 	fmt.Stringer
@@ -80,15 +88,7 @@ func EvalBool(expr Expression, row []types.Datum, ctx context.Context) (bool, er
 	if err != nil {
 		return false, errors.Trace(err)
 	}
-	if data.IsNull() {
-		return false, nil
-	}
-
-	i, err := data.ToBool(ctx.GetSessionVars().StmtCtx)
-	if err != nil {
-		return false, errors.Trace(err)
-	}
-	return i != 0, nil
+	return datumToBool(ctx.GetSessionVars().StmtCtx, data)
 }
 
 // One stands for a number 1.
@@ -353,6 +353,8 @@ func init() {
 	expressionMySQLErrCodes := map[terror.ErrCode]uint16{
 		codeIncorrectParameterCount: mysql.ErrWrongParamcountToNativeFct,
 		codeFunctionNotExists:       mysql.ErrSpDoesNotExist,
+		codeCutValueGroupConcat:     mysql.ErrCutValueGroupConcat,
+		codeJSONDocumentNULLKey:     mysql.ErrJSONDocumentNULLKey,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassExpression] = expressionMySQLErrCodes
 }
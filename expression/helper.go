@@ -141,6 +141,8 @@ func getSystemTimestamp(ctx context.Context) (time.Time, error) {
 		return value, nil
 	}
 
+	tz := getTimeZone(ctx)
+	value = value.In(tz)
 	// check whether use timestamp variable
 	sessionVars := ctx.GetSessionVars()
 	val, err := varsutil.GetSessionSystemVar(sessionVars, "timestamp")
@@ -155,7 +157,7 @@ func getSystemTimestamp(ctx context.Context) (time.Time, error) {
 		if timestamp <= 0 {
 			return value, nil
 		}
-		return time.Unix(timestamp, 0), nil
+		return time.Unix(timestamp, 0).In(tz), nil
 	}
 	return value, nil
 }
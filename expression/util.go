@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -36,6 +37,21 @@ func ExtractColumns(expr Expression) (cols []*Column) {
 	return
 }
 
+// IsDeterministic checks whether the expression returns the same result for the same inputs.
+func IsDeterministic(expr Expression) bool {
+	if f, ok := expr.(*ScalarFunction); ok {
+		if !f.Function.isDeterministic() {
+			return false
+		}
+		for _, arg := range f.GetArgs() {
+			if !IsDeterministic(arg) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // ColumnSubstitute substitutes the columns in filter to expressions in select fields.
 // e.g. select * from (select b as a from t) k where a < 10 => select * from (select b as a from t where b < 10) k.
 func ColumnSubstitute(expr Expression, schema *Schema, newExprs []Expression) Expression {
@@ -149,16 +165,22 @@ Loop:
 	return s[:validLen]
 }
 
-// createDistinctChecker creates a new distinct checker.
-func createDistinctChecker() *distinctChecker {
+// createDistinctChecker creates a new distinct checker, the memory usage of the keys is consumed by the tracker if
+// it's not nil.
+func createDistinctChecker(tracker *memory.Tracker) *distinctChecker {
 	return &distinctChecker{
 		existingKeys: make(map[string]bool),
+		memTracker:   tracker,
 	}
 }
 
+// distinctKeyOverhead is the approximate memory usage of a key in the map besides its bytes.
+const distinctKeyOverhead = 32
+
 // Checker stores existing keys and checks if given data is distinct.
 type distinctChecker struct {
 	existingKeys map[string]bool
+	memTracker   *memory.Tracker
 }
 
 // Check checks if values is distinct.
@@ -173,9 +195,42 @@ func (d *distinctChecker) Check(values []interface{}) (bool, error) {
 		return false, nil
 	}
 	d.existingKeys[key] = true
+	if d.memTracker != nil {
+		if err = d.memTracker.Consume(int64(len(key)) + distinctKeyOverhead); err != nil {
+			return false, errors.Trace(err)
+		}
+	}
 	return true, nil
 }
 
+// SubstituteCorColData returns a copy of expr, the correlated columns whose Data is a key of dataMap are replaced by
+// the ones that hold the mapped Data. It's used to evaluate a correlated expression for different outer rows at the
+// same time.
+func SubstituteCorColData(expr Expression, dataMap map[*types.Datum]*types.Datum) Expression {
+	switch x := expr.(type) {
+	case *ScalarFunction:
+		newArgs := make([]Expression, 0, len(x.GetArgs()))
+		for _, arg := range x.GetArgs() {
+			newArgs = append(newArgs, SubstituteCorColData(arg, dataMap))
+		}
+		switch v := x.Function.(type) {
+		case *builtinCastSig:
+			return NewCastFunc(v.tp, newArgs[0], x.GetCtx())
+		case *builtinValuesSig:
+			return NewValuesFunc(v.offset, x.GetType(), x.GetCtx())
+		}
+		newSf, _ := NewFunction(x.GetCtx(), x.FuncName.L, x.RetType, newArgs...)
+		return newSf
+	case *CorrelatedColumn:
+		if data, ok := dataMap[x.Data]; ok {
+			return &CorrelatedColumn{Column: x.Column, Data: data}
+		}
+		return x
+	default:
+		return x.Clone()
+	}
+}
+
 // SubstituteCorCol2Constant will substitute correlated column to constant value which it contains.
 // If the args of one scalar function are all constant, we will substitute it to constant.
 func SubstituteCorCol2Constant(expr Expression) (Expression, error) {
@@ -17,6 +17,7 @@ import (
 	"github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -29,7 +30,8 @@ type testUtilSuite struct {
 
 func (s *testUtilSuite) TestDistinct(c *check.C) {
 	defer testleak.AfterTest(c)()
-	dc := createDistinctChecker()
+	tracker := memory.NewTracker("distinct", -1)
+	dc := createDistinctChecker(tracker)
 	cases := []struct {
 		vals   []interface{}
 		expect bool
@@ -42,9 +44,11 @@ func (s *testUtilSuite) TestDistinct(c *check.C) {
 		{[]interface{}{1, nil}, false},
 	}
 	for _, t := range cases {
+		consumed := tracker.BytesConsumed()
 		d, err := dc.Check(t.vals)
 		c.Assert(err, check.IsNil)
 		c.Assert(d, check.Equals, t.expect)
+		c.Assert(tracker.BytesConsumed() > consumed, check.Equals, t.expect)
 	}
 }
 
@@ -38,6 +38,8 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 	} else if diff.Type == model.ActionDropSchema {
 		b.applyDropSchema(diff.SchemaID)
 		return nil
+	} else if diff.Type == model.ActionRenameTables {
+		return b.applyRenameTables(m, diff)
 	}
 
 	roDBInfo, ok := b.is.SchemaByID(diff.SchemaID)
@@ -46,7 +48,7 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 	}
 	var oldTableID, newTableID int64
 	switch diff.Type {
-	case model.ActionCreateTable:
+	case model.ActionCreateTable, model.ActionRecoverTable:
 		newTableID = diff.TableID
 	case model.ActionDropTable:
 		oldTableID = diff.TableID
@@ -61,9 +63,12 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 	b.copySortedTables(oldTableID, newTableID)
 
 	// We try to reuse the old allocator, so the cached auto ID can be reused.
+	// The allocator of a table moved to another database can't be reused, its auto ID is moved to the new database.
+	// Neither can the allocator of a table whose auto ID is rebased or whose auto ID cache is changed.
 	var alloc autoid.Allocator
 	if tableIDIsValid(oldTableID) {
-		if oldTableID == newTableID {
+		if oldTableID == newTableID && (diff.Type != model.ActionRenameTable || diff.OldSchemaID == diff.SchemaID) &&
+			diff.Type != model.ActionRebaseAutoID && diff.Type != model.ActionModifyTableAutoIDCache {
 			alloc, _ = b.is.AllocByID(oldTableID)
 		}
 		if diff.Type == model.ActionRenameTable {
@@ -71,6 +76,9 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 			if !ok {
 				return ErrDatabaseNotExists
 			}
+			if oldRoDBInfo.ID != roDBInfo.ID {
+				b.copySchemaTables(oldRoDBInfo.Name.L)
+			}
 			b.applyDropTable(oldRoDBInfo, oldTableID)
 		} else {
 			b.applyDropTable(roDBInfo, oldTableID)
@@ -86,6 +94,50 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 	return nil
 }
 
+// applyRenameTables applies the diff of renaming multiple tables. All the renamed tables are dropped first, because a
+// table may take the name of another renamed table, then every table is created once in its final database.
+func (b *Builder) applyRenameTables(m *meta.Meta, diff *model.SchemaDiff) error {
+	copiedSchemas := make(map[int64]*model.DBInfo)
+	getSchema := func(schemaID int64) (*model.DBInfo, error) {
+		if roDBInfo, ok := copiedSchemas[schemaID]; ok {
+			return roDBInfo, nil
+		}
+		roDBInfo, ok := b.is.SchemaByID(schemaID)
+		if !ok {
+			return nil, ErrDatabaseNotExists
+		}
+		b.copySchemaTables(roDBInfo.Name.L)
+		copiedSchemas[schemaID] = roDBInfo
+		return roDBInfo, nil
+	}
+
+	var tableIDs []int64
+	newSchemaIDs := make(map[int64]int64, len(diff.AffectedOpts))
+	for _, opt := range diff.AffectedOpts {
+		if _, ok := newSchemaIDs[opt.TableID]; !ok {
+			oldRoDBInfo, err := getSchema(opt.OldSchemaID)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			b.copySortedTables(opt.TableID, opt.TableID)
+			b.applyDropTable(oldRoDBInfo, opt.TableID)
+			tableIDs = append(tableIDs, opt.TableID)
+		}
+		newSchemaIDs[opt.TableID] = opt.SchemaID
+	}
+	for _, tableID := range tableIDs {
+		roDBInfo, err := getSchema(newSchemaIDs[tableID])
+		if err != nil {
+			return errors.Trace(err)
+		}
+		err = b.applyCreateTable(m, roDBInfo, tableID, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 // CopySortedTables copies sortedTables for old table and new table for later modification.
 func (b *Builder) copySortedTables(oldTableID, newTableID int64) {
 	buckets := b.is.sortedTablesBuckets
@@ -144,7 +196,7 @@ func (b *Builder) applyCreateTable(m *meta.Meta, roDBInfo *model.DBInfo, tableID
 		if tblInfo.OldSchemaID != 0 {
 			schemaID = tblInfo.OldSchemaID
 		}
-		alloc = autoid.NewAllocator(b.handle.store, schemaID)
+		alloc = autoid.NewAllocatorWithStep(b.handle.store, schemaID, tblInfo.AutoIDCache)
 	}
 	tbl, err := tables.TableFromMeta(alloc, tblInfo)
 	if err != nil {
@@ -249,7 +301,7 @@ func (b *Builder) createSchemaTablesForDB(di *model.DBInfo) error {
 		if t.OldSchemaID != 0 {
 			schemaID = t.OldSchemaID
 		}
-		alloc := autoid.NewAllocator(b.handle.store, schemaID)
+		alloc := autoid.NewAllocatorWithStep(b.handle.store, schemaID, t.AutoIDCache)
 		var tbl table.Table
 		tbl, err := tables.TableFromMeta(alloc, t)
 		if err != nil {
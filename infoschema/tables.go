@@ -14,8 +14,10 @@
 package infoschema
 
 import (
+	"encoding/hex"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
@@ -26,27 +28,33 @@ import (
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/hotregion"
+	"github.com/pingcap/tidb/util/slowlog"
 	"github.com/pingcap/tidb/util/types"
 )
 
 const (
-	tableSchemata      = "SCHEMATA"
-	tableTables        = "TABLES"
-	tableColumns       = "COLUMNS"
-	tableStatistics    = "STATISTICS"
-	tableCharacterSets = "CHARACTER_SETS"
-	tableCollations    = "COLLATIONS"
-	tableFiles         = "FILES"
-	catalogVal         = "def"
-	tableProfiling     = "PROFILING"
-	tablePartitions    = "PARTITIONS"
-	tableKeyColumm     = "KEY_COLUMN_USAGE"
-	tableReferConst    = "REFERENTIAL_CONSTRAINTS"
-	tableSessionVar    = "SESSION_VARIABLES"
-	tablePlugins       = "PLUGINS"
-	tableConstraints   = "TABLE_CONSTRAINTS"
-	tableTriggers      = "TRIGGERS"
+	tableSchemata       = "SCHEMATA"
+	tableTables         = "TABLES"
+	tableColumns        = "COLUMNS"
+	tableStatistics     = "STATISTICS"
+	tableCharacterSets  = "CHARACTER_SETS"
+	tableCollations     = "COLLATIONS"
+	tableFiles          = "FILES"
+	catalogVal          = "def"
+	tableProfiling      = "PROFILING"
+	tablePartitions     = "PARTITIONS"
+	tableKeyColumm      = "KEY_COLUMN_USAGE"
+	tableReferConst     = "REFERENTIAL_CONSTRAINTS"
+	tableSessionVar     = "SESSION_VARIABLES"
+	tablePlugins        = "PLUGINS"
+	tableConstraints    = "TABLE_CONSTRAINTS"
+	tableTriggers       = "TRIGGERS"
+	tableTiDBHotRegions = "TIDB_HOT_REGIONS"
+	tableTiDBHotKeys    = "TIDB_HOT_KEYS"
+	tableSlowQuery      = "SLOW_QUERY"
 )
 
 type columnInfo struct {
@@ -322,6 +330,44 @@ var tableTriggersCols = []columnInfo{
 	{"DATABASE_COLLATION", mysql.TypeVarchar, 32, 0, nil, nil},
 }
 
+var tableTiDBHotRegionsCols = []columnInfo{
+	{"REGION_ID", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"DB_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"TABLE_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"INDEX_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"READ_COUNT", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"WRITE_COUNT", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+}
+
+var tableTiDBHotKeysCols = []columnInfo{
+	{"KEY", mysql.TypeVarchar, 1024, 0, nil, nil},
+	{"REGION_ID", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"DB_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"TABLE_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"INDEX_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"READ_COUNT", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"WRITE_COUNT", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+}
+
+var tableSlowQueryCols = []columnInfo{
+	{"TIME", mysql.TypeDatetime, 26, 0, nil, nil},
+	{"TXN_START_TS", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"USER", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"HOST", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"CONN_ID", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"DB", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"QUERY_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"PARSE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"COMPILE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"EXECUTE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"KV_REQUESTS", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"SCANNED_KEYS", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"RESULT_ROWS", mysql.TypeLonglong, 21, mysql.UnsignedFlag, nil, nil},
+	{"SUCC", mysql.TypeTiny, 1, 0, nil, nil},
+	{"DETAILS", mysql.TypeBlob, types.UnspecifiedLength, 0, nil, nil},
+	{"QUERY", mysql.TypeBlob, types.UnspecifiedLength, 0, nil, nil},
+}
+
 func dataForCharacterSets() (records [][]types.Datum) {
 	records = append(records,
 		types.MakeDatums("ascii", "ascii_general_ci", "US ASCII", 1),
@@ -483,18 +529,18 @@ func dataForColumnsInTable(schema *model.DBInfo, tbl *model.TableInfo) [][]types
 			columnDefault,                        // COLUMN_DEFAULT
 			columnDesc.Null,                      // IS_NULLABLE
 			types.TypeToStr(col.Tp, col.Charset), // DATA_TYPE
-			colLen,                            // CHARACTER_MAXIMUM_LENGTH
-			colLen,                            // CHARACTER_OCTET_LENGTH
-			decimal,                           // NUMERIC_PRECISION
-			0,                                 // NUMERIC_SCALE
-			0,                                 // DATETIME_PRECISION
-			col.Charset,                       // CHARACTER_SET_NAME
-			col.Collate,                       // COLLATION_NAME
-			columnType,                        // COLUMN_TYPE
-			columnDesc.Key,                    // COLUMN_KEY
-			columnDesc.Extra,                  // EXTRA
-			"select,insert,update,references", // PRIVILEGES
-			"", // COLUMN_COMMENT
+			colLen,                               // CHARACTER_MAXIMUM_LENGTH
+			colLen,                               // CHARACTER_OCTET_LENGTH
+			decimal,                              // NUMERIC_PRECISION
+			0,                                    // NUMERIC_SCALE
+			0,                                    // DATETIME_PRECISION
+			col.Charset,                          // CHARACTER_SET_NAME
+			col.Collate,                          // COLLATION_NAME
+			columnType,                           // COLUMN_TYPE
+			columnDesc.Key,                       // COLUMN_KEY
+			columnDesc.Extra,                     // EXTRA
+			"select,insert,update,references",    // PRIVILEGES
+			"",                                   // COLUMN_COMMENT
 		)
 		rows = append(rows, record)
 	}
@@ -629,22 +675,141 @@ func dataForTableConstraints(schemas []*model.DBInfo) [][]types.Datum {
 	return rows
 }
 
+// maxHotRegionRows is the max number of the rows of TIDB_HOT_REGIONS and TIDB_HOT_KEYS.
+const maxHotRegionRows = 100
+
+// hotRegionRecorder returns the hot region recorder of the store, it's nil if the store doesn't record them.
+func hotRegionRecorder(store kv.Storage) *hotregion.Recorder {
+	if store, ok := store.(hotregion.Store); ok {
+		return store.HotRegions()
+	}
+	return nil
+}
+
+// KeyOwnerNames returns the names of the database, table and index which the key belongs to, they are empty if the
+// key isn't a table key or the table doesn't exist.
+func KeyOwnerNames(schemas []*model.DBInfo, key kv.Key) (dbName, tableName, indexName string) {
+	tableID, indexID, isRecordKey, err := tablecodec.DecodeKeyHead(key)
+	if err != nil {
+		tableID, indexID, isRecordKey = tablecodec.DecodeTableID(key), 0, true
+	}
+	if tableID == 0 {
+		return
+	}
+	for _, schema := range schemas {
+		for _, tbl := range schema.Tables {
+			if tbl.ID != tableID {
+				continue
+			}
+			dbName, tableName = schema.Name.O, tbl.Name.O
+			if isRecordKey {
+				return
+			}
+			for _, idx := range tbl.Indices {
+				if idx.ID == indexID {
+					indexName = idx.Name.O
+				}
+			}
+			return
+		}
+	}
+	return
+}
+
+func dataForTiDBHotRegions(store kv.Storage, schemas []*model.DBInfo) [][]types.Datum {
+	rows := [][]types.Datum{}
+	recorder := hotRegionRecorder(store)
+	if recorder == nil {
+		return rows
+	}
+	for _, region := range recorder.HotRegions(maxHotRegionRows) {
+		dbName, tableName, indexName := KeyOwnerNames(schemas, region.Key)
+		record := types.MakeDatums(
+			region.RegionID,   // REGION_ID
+			dbName,            // DB_NAME
+			tableName,         // TABLE_NAME
+			indexName,         // INDEX_NAME
+			region.ReadCount,  // READ_COUNT
+			region.WriteCount, // WRITE_COUNT
+		)
+		rows = append(rows, record)
+	}
+	return rows
+}
+
+func dataForTiDBHotKeys(store kv.Storage, schemas []*model.DBInfo) [][]types.Datum {
+	rows := [][]types.Datum{}
+	recorder := hotRegionRecorder(store)
+	if recorder == nil {
+		return rows
+	}
+	for _, key := range recorder.HotKeys(maxHotRegionRows) {
+		dbName, tableName, indexName := KeyOwnerNames(schemas, key.Key)
+		record := types.MakeDatums(
+			strings.ToUpper(hex.EncodeToString(key.Key)), // KEY
+			key.RegionID,   // REGION_ID
+			dbName,         // DB_NAME
+			tableName,      // TABLE_NAME
+			indexName,      // INDEX_NAME
+			key.ReadCount,  // READ_COUNT
+			key.WriteCount, // WRITE_COUNT
+		)
+		rows = append(rows, record)
+	}
+	return rows
+}
+
+// dataForSlowQuery returns the slow queries recorded recently by this server, the times are in seconds.
+func dataForSlowQuery() [][]types.Datum {
+	rows := [][]types.Datum{}
+	for _, e := range slowlog.Entries() {
+		t := types.Time{Time: types.FromGoTime(e.Time), Type: mysql.TypeDatetime, Fsp: types.MaxFsp}
+		succ := 0
+		if e.Succ {
+			succ = 1
+		}
+		record := types.MakeDatums(
+			t,                       // TIME
+			e.TxnStartTS,            // TXN_START_TS
+			e.User,                  // USER
+			e.Host,                  // HOST
+			e.ConnID,                // CONN_ID
+			e.DB,                    // DB
+			e.QueryTime.Seconds(),   // QUERY_TIME
+			e.ParseTime.Seconds(),   // PARSE_TIME
+			e.CompileTime.Seconds(), // COMPILE_TIME
+			e.ExecuteTime.Seconds(), // EXECUTE_TIME
+			e.KVRequests,            // KV_REQUESTS
+			e.ScannedKeys,           // SCANNED_KEYS
+			e.ResultRows,            // RESULT_ROWS
+			succ,                    // SUCC
+			e.Details,               // DETAILS
+			e.Query,                 // QUERY
+		)
+		rows = append(rows, record)
+	}
+	return rows
+}
+
 var tableNameToColumns = map[string]([]columnInfo){
-	tableSchemata:      schemataCols,
-	tableTables:        tablesCols,
-	tableColumns:       columnsCols,
-	tableStatistics:    statisticsCols,
-	tableCharacterSets: charsetCols,
-	tableCollations:    collationsCols,
-	tableFiles:         filesCols,
-	tableProfiling:     profilingCols,
-	tablePartitions:    partitionsCols,
-	tableKeyColumm:     keyColumnUsageCols,
-	tableReferConst:    referConstCols,
-	tableSessionVar:    sessionVarCols,
-	tablePlugins:       pluginsCols,
-	tableConstraints:   tableConstraintsCols,
-	tableTriggers:      tableTriggersCols,
+	tableSchemata:       schemataCols,
+	tableTables:         tablesCols,
+	tableColumns:        columnsCols,
+	tableStatistics:     statisticsCols,
+	tableCharacterSets:  charsetCols,
+	tableCollations:     collationsCols,
+	tableFiles:          filesCols,
+	tableProfiling:      profilingCols,
+	tablePartitions:     partitionsCols,
+	tableKeyColumm:      keyColumnUsageCols,
+	tableReferConst:     referConstCols,
+	tableSessionVar:     sessionVarCols,
+	tablePlugins:        pluginsCols,
+	tableConstraints:    tableConstraintsCols,
+	tableTriggers:       tableTriggersCols,
+	tableTiDBHotRegions: tableTiDBHotRegionsCols,
+	tableTiDBHotKeys:    tableTiDBHotKeysCols,
+	tableSlowQuery:      tableSlowQueryCols,
 }
 
 func createInfoSchemaTable(handle *Handle, meta *model.TableInfo) *infoschemaTable {
@@ -702,6 +867,12 @@ func (it *infoschemaTable) getRows(ctx context.Context, cols []*table.Column) (f
 		fullRows, err = dataForSessionVar(ctx)
 	case tableConstraints:
 		fullRows = dataForTableConstraints(dbs)
+	case tableTiDBHotRegions:
+		fullRows = dataForTiDBHotRegions(it.handle.store, dbs)
+	case tableTiDBHotKeys:
+		fullRows = dataForTiDBHotKeys(it.handle.store, dbs)
+	case tableSlowQuery:
+		fullRows = dataForSlowQuery()
 	case tableFiles:
 	case tableProfiling:
 	case tablePartitions:
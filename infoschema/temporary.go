@@ -0,0 +1,188 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package infoschema
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/meta/autoid"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+)
+
+// TemporaryTables are the temporary tables of a session, they are only visible to the session.
+type TemporaryTables struct {
+	mu     sync.RWMutex
+	byName map[string]*tables.TemporaryTable
+	byID   map[int64]*tables.TemporaryTable
+}
+
+// NewTemporaryTables creates a new TemporaryTables.
+func NewTemporaryTables() *TemporaryTables {
+	return &TemporaryTables{
+		byName: make(map[string]*tables.TemporaryTable),
+		byID:   make(map[int64]*tables.TemporaryTable),
+	}
+}
+
+func temporaryTableKey(schema, tbl model.CIStr) string {
+	return schema.L + "." + tbl.L
+}
+
+// Add adds a temporary table in the schema.
+func (ts *TemporaryTables) Add(schema model.CIStr, tbl *tables.TemporaryTable) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.byName[temporaryTableKey(schema, tbl.Meta().Name)] = tbl
+	ts.byID[tbl.Meta().ID] = tbl
+}
+
+// Remove removes the temporary table, it returns nil if the table doesn't exist.
+func (ts *TemporaryTables) Remove(schema, tbl model.CIStr) *tables.TemporaryTable {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	key := temporaryTableKey(schema, tbl)
+	t, ok := ts.byName[key]
+	if !ok {
+		return nil
+	}
+	delete(ts.byName, key)
+	delete(ts.byID, t.Meta().ID)
+	return t
+}
+
+// TableByName returns the temporary table by its schema and name.
+func (ts *TemporaryTables) TableByName(schema, tbl model.CIStr) (*tables.TemporaryTable, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	t, ok := ts.byName[temporaryTableKey(schema, tbl)]
+	return t, ok
+}
+
+// TableByID returns the temporary table by its ID.
+func (ts *TemporaryTables) TableByID(id int64) (*tables.TemporaryTable, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	t, ok := ts.byID[id]
+	return t, ok
+}
+
+// Len returns the number of the temporary tables.
+func (ts *TemporaryTables) Len() int {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return len(ts.byID)
+}
+
+// Checkpoint returns the checkpoints of the tables by their IDs, the changes made after it can be undone by
+// RollbackTo.
+func (ts *TemporaryTables) Checkpoint() map[int64]int {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	cp := make(map[int64]int, len(ts.byID))
+	for id, t := range ts.byID {
+		cp[id] = t.Checkpoint()
+	}
+	return cp
+}
+
+// RollbackTo undoes the changes of the tables made after the checkpoint. All the changes of a table are undone if
+// the table is not in cp, so the changes of the transaction are undone if cp is nil.
+func (ts *TemporaryTables) RollbackTo(cp map[int64]int) error {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	var err error
+	for id, t := range ts.byID {
+		if err1 := t.RollbackTo(cp[id]); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return errors.Trace(err)
+}
+
+// Commit keeps the changes of the tables made by the transaction.
+func (ts *TemporaryTables) Commit() error {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	var err error
+	for _, t := range ts.byID {
+		if err1 := t.Commit(); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return errors.Trace(err)
+}
+
+// Close drops all the temporary tables and releases their memory and disk.
+func (ts *TemporaryTables) Close() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	var err error
+	for id, t := range ts.byID {
+		if err1 := t.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+		delete(ts.byID, id)
+	}
+	ts.byName = make(map[string]*tables.TemporaryTable)
+	return errors.Trace(err)
+}
+
+// sessionInfoSchema is the InfoSchema of a session with temporary tables, a temporary table hides the table
+// of the same name in the schema.
+type sessionInfoSchema struct {
+	InfoSchema
+	tmpTables *TemporaryTables
+}
+
+// AttachTemporaryTables returns an InfoSchema in which the temporary tables are visible.
+func AttachTemporaryTables(is InfoSchema, tmpTables *TemporaryTables) InfoSchema {
+	if tmpTables == nil || tmpTables.Len() == 0 {
+		return is
+	}
+	if sis, ok := is.(*sessionInfoSchema); ok {
+		is = sis.InfoSchema
+	}
+	return &sessionInfoSchema{InfoSchema: is, tmpTables: tmpTables}
+}
+
+func (is *sessionInfoSchema) TableByName(schema, tbl model.CIStr) (table.Table, error) {
+	if t, ok := is.tmpTables.TableByName(schema, tbl); ok && is.SchemaExists(schema) {
+		return t, nil
+	}
+	return is.InfoSchema.TableByName(schema, tbl)
+}
+
+func (is *sessionInfoSchema) TableExists(schema, tbl model.CIStr) bool {
+	if _, ok := is.tmpTables.TableByName(schema, tbl); ok && is.SchemaExists(schema) {
+		return true
+	}
+	return is.InfoSchema.TableExists(schema, tbl)
+}
+
+func (is *sessionInfoSchema) TableByID(id int64) (table.Table, bool) {
+	if t, ok := is.tmpTables.TableByID(id); ok {
+		return t, true
+	}
+	return is.InfoSchema.TableByID(id)
+}
+
+func (is *sessionInfoSchema) AllocByID(id int64) (autoid.Allocator, bool) {
+	if t, ok := is.tmpTables.TableByID(id); ok {
+		return t.Allocator(), true
+	}
+	return is.InfoSchema.AllocByID(id)
+}
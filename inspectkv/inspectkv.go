@@ -15,6 +15,7 @@ package inspectkv
 
 import (
 	"io"
+	"math"
 	"reflect"
 
 	"github.com/juju/errors"
@@ -48,10 +49,18 @@ func GetDDLInfo(txn kv.Transaction) (*DDLInfo, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	info.Job, err = t.GetDDLJob(0)
+	// The running job in the reorganization queue is preferred, it may take a long time and has the reorganization
+	// handle. Otherwise it's the running job in the default queue.
+	info.Job, err = t.GetDDLJob(0, meta.ReorgJobListKey)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if info.Job == nil {
+		info.Job, err = t.GetDDLJob(0, meta.DefaultJobListKey)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	info.SchemaVer, err = t.GetSchemaVersion()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -68,6 +77,107 @@ func GetDDLInfo(txn kv.Transaction) (*DDLInfo, error) {
 	return info, nil
 }
 
+// GetDDLJobs returns the DDL jobs which are waiting or running in the job queues, the jobs in the reorganization
+// queue are listed after the ones in the default queue.
+func GetDDLJobs(txn kv.Transaction) ([]*model.Job, error) {
+	t := meta.NewMeta(txn)
+	jobs, err := t.GetAllDDLJobs(meta.DefaultJobListKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	reorgJobs, err := t.GetAllDDLJobs(meta.ReorgJobListKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(jobs, reorgJobs...), nil
+}
+
+// GetHistoryDDLJobs returns at most maxNum of the latest finished DDL jobs, the later job comes first.
+func GetHistoryDDLJobs(txn kv.Transaction, maxNum int) ([]*model.Job, error) {
+	t := meta.NewMeta(txn)
+	jobs, err := t.GetAllHistoryDDLJobs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(jobs) > maxNum {
+		jobs = jobs[len(jobs)-maxNum:]
+	}
+	historyJobs := make([]*model.Job, 0, len(jobs))
+	for i := len(jobs) - 1; i >= 0; i-- {
+		historyJobs = append(historyJobs, jobs[i])
+	}
+	return historyJobs, nil
+}
+
+// CancelJobs requests to cancel the DDL jobs of the IDs, the DDL worker cancels the job or rolls back its partially
+// applied states later. It returns an error for every job, which is nil if the job is requested successfully.
+func CancelJobs(txn kv.Transaction, ids []int64) ([]error, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	t := meta.NewMeta(txn)
+	errs := make([]error, len(ids))
+	found := make([]bool, len(ids))
+	for _, jobListKey := range []meta.JobListKeyType{meta.DefaultJobListKey, meta.ReorgJobListKey} {
+		jobs, err := t.GetAllDDLJobs(jobListKey)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for i, id := range ids {
+			for j, job := range jobs {
+				if id != job.ID {
+					continue
+				}
+				found[i] = true
+				errs[i] = checkCancellable(job)
+				if errs[i] != nil || job.IsCancelling() {
+					break
+				}
+				job.State = model.JobCancelling
+				err = t.UpdateDDLJob(int64(j), job, jobListKey)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				break
+			}
+		}
+	}
+	for i, id := range ids {
+		if !found[i] {
+			errs[i] = ErrDDLJobNotFound.GenByArgs(id)
+		}
+	}
+	return errs, nil
+}
+
+// checkCancellable checks whether the job can be cancelled. A job which hasn't changed anything can be cancelled. The
+// add index, add column and modify column jobs can be rolled back before their changes become public, but the modify
+// column job that has replaced the old column goes on running even though it's requested to be cancelled. The
+// multi-schema change job can be cancelled until it starts to make the changes that can't be reverted.
+func checkCancellable(job *model.Job) error {
+	if job.IsFinished() || job.State == model.JobRollback {
+		return ErrCancelFinishedDDLJob.GenByArgs(job.ID)
+	}
+	if job.Type == model.ActionMultiSchemaChange {
+		if job.MultiSchemaInfo.Revertible {
+			return nil
+		}
+		return ErrCannotCancelDDLJob.GenByArgs(job.ID)
+	}
+	if job.SchemaState == model.StateNone {
+		return nil
+	}
+	switch job.Type {
+	case model.ActionAddIndex, model.ActionAddColumn, model.ActionModifyColumn:
+		switch job.SchemaState {
+		case model.StateDeleteOnly, model.StateWriteOnly, model.StateWriteReorganization:
+			return nil
+		}
+	}
+	return ErrCannotCancelDDLJob.GenByArgs(job.ID)
+}
+
 // GetBgDDLInfo returns background DDL information.
 func GetBgDDLInfo(txn kv.Transaction) (*DDLInfo, error) {
 	var err error
@@ -216,7 +326,8 @@ func checkRecordAndIndex(txn kv.Transaction, t table.Table, idx table.Index) err
 		cols[i] = t.Cols()[col.Offset]
 	}
 
-	startKey := t.RecordKey(0)
+	// Handles may be negative, the scan starts from the smallest one.
+	startKey := t.RecordKey(math.MinInt64)
 	filterFunc := func(h1 int64, vals1 []types.Datum, cols []*table.Column) (bool, error) {
 		isExist, h2, err := idx.Exist(txn, vals1, h1)
 		if terror.ErrorEqual(err, kv.ErrKeyExists) {
@@ -294,6 +405,7 @@ func ScanSnapshotTableRecord(store kv.Storage, ver kv.Version, t table.Table, st
 	if err != nil {
 		return nil, 0, errors.Trace(err)
 	}
+	defer snap.Release()
 
 	records, nextHandle, err := ScanTableRecord(snap, t, startHandle, limit)
 
@@ -485,13 +597,24 @@ func iterRecords(retriever kv.Retriever, t table.Table, startKey kv.Key, cols []
 
 // inspectkv error codes.
 const (
-	codeDataNotEqual       terror.ErrCode = 1
-	codeRepeatHandle                      = 2
-	codeInvalidColumnState                = 3
+	codeDataNotEqual         terror.ErrCode = 1
+	codeRepeatHandle                        = 2
+	codeInvalidColumnState                  = 3
+	codeDDLJobNotFound                      = 4
+	codeCancelFinishedDDLJob                = 5
+	codeCannotCancelDDLJob                  = 6
 )
 
 var (
 	errDateNotEqual       = terror.ClassInspectkv.New(codeDataNotEqual, "data isn't equal")
 	errRepeatHandle       = terror.ClassInspectkv.New(codeRepeatHandle, "handle is repeated")
 	errInvalidColumnState = terror.ClassInspectkv.New(codeInvalidColumnState, "invalid column state")
+	// ErrDDLJobNotFound is returned when the DDL job to be cancelled isn't in the job queues.
+	ErrDDLJobNotFound = terror.ClassInspectkv.New(codeDDLJobNotFound, "DDL Job:%v not found")
+	// ErrCancelFinishedDDLJob is returned when the DDL job to be cancelled is finished or rolling back.
+	ErrCancelFinishedDDLJob = terror.ClassInspectkv.New(codeCancelFinishedDDLJob,
+		"This job:%v is finished or rolling back, so can't be cancelled")
+	// ErrCannotCancelDDLJob is returned when the DDL job to be cancelled has applied states that can't be undone.
+	ErrCannotCancelDDLJob = terror.ClassInspectkv.New(codeCannotCancelDDLJob,
+		"This job:%v is almost finished, can't be cancelled now")
 )
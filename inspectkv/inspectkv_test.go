@@ -28,6 +28,7 @@ import (
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
@@ -164,6 +165,94 @@ func (s *testSuite) TestGetDDLInfo(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSuite) TestGetDDLJobs(c *C) {
+	defer testleak.AfterTest(c)()
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	t := meta.NewMeta(txn)
+
+	// The jobs in the store may have been added by the other tests.
+	jobs, err := GetDDLJobs(txn)
+	c.Assert(err, IsNil)
+	cnt := len(jobs)
+	reorgJob := &model.Job{ID: 1, SchemaID: 1, TableID: 2, Type: model.ActionAddIndex}
+	err = t.EnQueueDDLJob(reorgJob, meta.ReorgJobListKey)
+	c.Assert(err, IsNil)
+	job := &model.Job{ID: 2, SchemaID: 1, TableID: 3, Type: model.ActionAddColumn}
+	err = t.EnQueueDDLJob(job, meta.DefaultJobListKey)
+	c.Assert(err, IsNil)
+	jobs, err = GetDDLJobs(txn)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, cnt+2)
+	c.Assert(jobs[cnt].ID, Equals, job.ID)
+	c.Assert(jobs[cnt+1].ID, Equals, reorgJob.ID)
+
+	for i := int64(1); i <= 3; i++ {
+		err = t.AddHistoryDDLJob(&model.Job{ID: i, State: model.JobDone})
+		c.Assert(err, IsNil)
+	}
+	historyJobs, err := GetHistoryDDLJobs(txn, 2)
+	c.Assert(err, IsNil)
+	c.Assert(historyJobs, HasLen, 2)
+	c.Assert(historyJobs[0].ID, Equals, int64(3))
+	c.Assert(historyJobs[1].ID, Equals, int64(2))
+	historyJobs, err = GetHistoryDDLJobs(txn, 10)
+	c.Assert(err, IsNil)
+	c.Assert(historyJobs, HasLen, 3)
+	err = txn.Rollback()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSuite) TestCancelJobs(c *C) {
+	defer testleak.AfterTest(c)()
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	t := meta.NewMeta(txn)
+
+	jobs := []*model.Job{
+		{ID: 101, SchemaID: 1, TableID: 2, Type: model.ActionAddIndex, SchemaState: model.StateWriteReorganization},
+		{ID: 102, SchemaID: 1, TableID: 2, Type: model.ActionDropColumn, SchemaState: model.StateNone},
+		{ID: 103, SchemaID: 1, TableID: 2, Type: model.ActionDropColumn, SchemaState: model.StateWriteOnly},
+		{ID: 104, SchemaID: 1, TableID: 2, Type: model.ActionAddIndex, SchemaState: model.StateDeleteOnly,
+			State: model.JobRollback},
+		{ID: 105, SchemaID: 1, TableID: 2, Type: model.ActionMultiSchemaChange, SchemaState: model.StatePublic,
+			MultiSchemaInfo: &model.MultiSchemaInfo{Revertible: true}},
+		{ID: 106, SchemaID: 1, TableID: 2, Type: model.ActionMultiSchemaChange, SchemaState: model.StateWriteOnly,
+			MultiSchemaInfo: &model.MultiSchemaInfo{Revertible: false}},
+	}
+	err = t.EnQueueDDLJob(jobs[0], meta.ReorgJobListKey)
+	c.Assert(err, IsNil)
+	for _, job := range jobs[1:] {
+		err = t.EnQueueDDLJob(job, meta.DefaultJobListKey)
+		c.Assert(err, IsNil)
+	}
+	errs, err := CancelJobs(txn, []int64{101, 102, 103, 104, 105, 106, 107})
+	c.Assert(err, IsNil)
+	c.Assert(errs, HasLen, 7)
+	c.Assert(errs[0], IsNil)
+	c.Assert(errs[1], IsNil)
+	c.Assert(terror.ErrorEqual(errs[2], ErrCannotCancelDDLJob), IsTrue)
+	c.Assert(terror.ErrorEqual(errs[3], ErrCancelFinishedDDLJob), IsTrue)
+	c.Assert(errs[4], IsNil)
+	c.Assert(terror.ErrorEqual(errs[5], ErrCannotCancelDDLJob), IsTrue)
+	c.Assert(terror.ErrorEqual(errs[6], ErrDDLJobNotFound), IsTrue)
+
+	allJobs, err := GetDDLJobs(txn)
+	c.Assert(err, IsNil)
+	states := make(map[int64]model.JobState)
+	for _, job := range allJobs {
+		states[job.ID] = job.State
+	}
+	c.Assert(states[101], Equals, model.JobCancelling)
+	c.Assert(states[102], Equals, model.JobCancelling)
+	c.Assert(states[103], Equals, model.JobNone)
+	c.Assert(states[104], Equals, model.JobRollback)
+	c.Assert(states[105], Equals, model.JobCancelling)
+	c.Assert(states[106], Equals, model.JobNone)
+	err = txn.Rollback()
+	c.Assert(err, IsNil)
+}
+
 func (s *testSuite) TestGetBgDDLInfo(c *C) {
 	defer testleak.AfterTest(c)()
 	txn, err := s.store.Begin()
@@ -34,6 +34,11 @@ const (
 	codeNotImplemented                            = 10
 	codeTxnTooLarge                               = 11
 	codeEntryTooLarge                             = 12
+	codeWriteConflict                             = 13
+
+	codeLockWaitTimeout = 1205
+	codeDeadlock        = 1213
+	codeLockNowait      = 3572
 
 	codeKeyExists = 1062
 )
@@ -69,11 +74,25 @@ var (
 	ErrKeyExists = terror.ClassKV.New(codeKeyExists, "key already exist")
 	// ErrNotImplemented returns when a function is not implemented yet.
 	ErrNotImplemented = terror.ClassKV.New(codeNotImplemented, "not implemented")
+
+	// ErrWriteConflict is used when a pessimistic transaction locks a key which is changed by another transaction
+	// after the pessimistic transaction starts.
+	ErrWriteConflict = terror.ClassKV.New(codeWriteConflict, "Write conflict, the key is changed by another transaction")
+	// ErrLockWaitTimeout is used when a pessimistic transaction waits for a lock too long.
+	ErrLockWaitTimeout = terror.ClassKV.New(codeLockWaitTimeout, mysql.MySQLErrName[mysql.ErrLockWaitTimeout])
+	// ErrDeadlock is used when the pessimistic transactions wait for the locks of each other.
+	ErrDeadlock = terror.ClassKV.New(codeDeadlock, mysql.MySQLErrName[mysql.ErrLockDeadlock])
+	// ErrLockNowait is used when a pessimistic transaction locks a key locked by another transaction and the
+	// LockWaitPolicy is LockNoWait.
+	ErrLockNowait = terror.ClassKV.New(codeLockNowait, mysql.MySQLErrName[mysql.ErrLockNowait])
 )
 
 func init() {
 	kvMySQLErrCodes := map[terror.ErrCode]uint16{
-		codeKeyExists: mysql.ErrDupEntry,
+		codeKeyExists:       mysql.ErrDupEntry,
+		codeLockWaitTimeout: mysql.ErrLockWaitTimeout,
+		codeDeadlock:        mysql.ErrLockDeadlock,
+		codeLockNowait:      mysql.ErrLockNowait,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassKV] = kvMySQLErrCodes
 }
@@ -87,6 +106,7 @@ func IsRetryableError(err error) bool {
 	if terror.ErrorEqual(err, ErrRetryable) ||
 		terror.ErrorEqual(err, ErrLockConflict) ||
 		terror.ErrorEqual(err, ErrConditionNotMatch) ||
+		terror.ErrorEqual(err, ErrWriteConflict) ||
 		// TiKV exception message will tell you if you should retry or not
 		strings.Contains(err.Error(), "try again later") {
 		return true
@@ -86,6 +86,16 @@ func (t *InjectedTransaction) Get(k Key) ([]byte, error) {
 	return t.Transaction.Get(k)
 }
 
+// Prefetch returns an error if cfg.getError is set.
+func (t *InjectedTransaction) Prefetch(keys []Key) error {
+	t.cfg.RLock()
+	defer t.cfg.RUnlock()
+	if t.cfg.getError != nil {
+		return t.cfg.getError
+	}
+	return t.Transaction.Prefetch(keys)
+}
+
 // InjectedSnapshot wraps a Snapshot with injections.
 type InjectedSnapshot struct {
 	Snapshot
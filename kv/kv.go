@@ -33,12 +33,92 @@ const (
 	SkipCheckForWrite
 	// SchemaLeaseChecker is used for schema lease check.
 	SchemaLeaseChecker
+	// Pessimistic indicates the transaction is pessimistic, the keys written by it are locked when they are
+	// written instead of when the transaction commits.
+	Pessimistic
+	// LockWaitTimeout is the max time.Duration to wait for a key locked by another pessimistic transaction.
+	LockWaitTimeout
+	// ReplicaRead is the ReplicaReadType of the reads of the transaction.
+	ReplicaRead
+	// BackoffDetails is the *execdetails.BackoffDetails which the backoffs of the reads are recorded into, it's
+	// set for each statement of the transaction.
+	BackoffDetails
+	// Priority is the priority of the reads of the transaction, it's set for each statement of the transaction.
+	Priority
+	// ReadDetails is the *execdetails.ReadDetails which the kv requests of the reads are recorded into, it's set for
+	// each statement of the transaction.
+	ReadDetails
+	// LockWaitPolicy is the LockWaitPolicyType of LockKeys, it's set by the locking reads with NOWAIT or
+	// SKIP LOCKED and deleted after the keys are locked.
+	LockWaitPolicy
+)
+
+// LockWaitPolicyType is how LockKeys waits for the keys locked by the other pessimistic transactions.
+type LockWaitPolicyType int
+
+const (
+	// LockWait waits for the locked keys at most LockWaitTimeout.
+	LockWait LockWaitPolicyType = iota
+	// LockNoWait returns ErrLockNowait at once if a key is locked.
+	LockNoWait
+)
+
+// ReplicaReadType is the type of the replicas which serve the reads.
+type ReplicaReadType int
+
+const (
+	// ReplicaReadLeader reads from the leaders.
+	ReplicaReadLeader ReplicaReadType = iota
+	// ReplicaReadFollower reads from the followers, a follower checks it has caught up with the leader by a
+	// read index request before serving the read, so the read is as consistent as the leader read.
+	ReplicaReadFollower
+	// ReplicaReadLeaderPreferred reads from the leaders, unless a leader is much slower than the followers.
+	ReplicaReadLeaderPreferred
+	// ReplicaReadClosest reads from the replicas whose store labels match the location of the server most, so
+	// the reads don't cross the zones.
+	ReplicaReadClosest
+	// ReplicaReadRoundRobin reads from all the replicas, the leaders included, in turn.
+	ReplicaReadRoundRobin
+)
+
+// IsFollowerRead returns whether the reads may be served by the followers.
+func (r ReplicaReadType) IsFollowerRead() bool {
+	return r != ReplicaReadLeader
+}
+
+// Capability is a feature which is only supported by some storage engines, the planner and the executor check the
+// capabilities of the storage to choose how to access the data.
+type Capability int
+
+const (
+	// CapCoprocessor means the requests can be pushed down to the storage by Client, the supported request types
+	// and expressions are checked by Client.SupportRequestType.
+	CapCoprocessor Capability = iota + 1
+	// CapFollowerRead means the reads can be served by the followers, see ReplicaReadFollower.
+	CapFollowerRead
+	// CapRawKV means the storage can be accessed by the raw KV API, which reads and writes the keys without
+	// transactions.
+	CapRawKV
+	// CapSplitRegion means the storage implements SplittableStore and can split its regions.
+	CapSplitRegion
+)
+
+// Priority value for the reads of a request or a snapshot.
+const (
+	PriorityNormal = iota
+	PriorityLow
+	PriorityHigh
 )
 
 // Those limits is enforced to make sure the transaction can be well handled by TiKV.
 const (
 	// The limit of single entry size (len(key) + len(value)).
 	TxnEntrySizeLimit = 6 * 1024 * 1024
+)
+
+// Those limits can be raised to run the large transactions, the memory used by a transaction
+// is about twice of its size when it commits.
+var (
 	// The limit of number of entries in the MemBuffer.
 	TxnEntryCountLimit = 300 * 1000
 	// The limit of the sum of all entry size.
@@ -76,6 +156,9 @@ type RetrieverMutator interface {
 	Mutator
 }
 
+// StagingHandle is the handle of a staging of a MemBuffer.
+type StagingHandle int
+
 // MemBuffer is an in-memory kv collection, can be used to buffer write operations.
 type MemBuffer interface {
 	RetrieverMutator
@@ -83,6 +166,13 @@ type MemBuffer interface {
 	Size() int
 	// Len returns the number of entries in the DB.
 	Len() int
+	// Staging begins a staging, the changes made after it can be discarded by Cleanup, e.g. when a statement fails,
+	// or be kept by Release. The stagings can be nested, the inner stagings must be released or cleaned up first.
+	Staging() StagingHandle
+	// Release keeps the changes of the staging, they become the changes of the outer staging if there is one.
+	Release(h StagingHandle)
+	// Cleanup discards the changes of the staging.
+	Cleanup(h StagingHandle)
 }
 
 // Transaction defines the interface for operations inside a Transaction.
@@ -109,6 +199,9 @@ type Transaction interface {
 	// Valid returns if the transaction is valid.
 	// A transaction become invalid after commit or rollback.
 	Valid() bool
+	// Prefetch reads the keys from the snapshot with a single BatchGet, so reading them later one by one doesn't
+	// access the store again.
+	Prefetch(keys []Key) error
 }
 
 // Client is used to send request to KV layer.
@@ -147,6 +240,18 @@ type Request struct {
 	// ResponseIterator.Next is called. If concurrency is greater than 1, the request will be
 	// sent to multiple storage units concurrently.
 	Concurrency int
+	// If Limit is greater than 0, the caller stops reading the response after Limit rows. For an ordered request,
+	// the tasks are sent only when their results are going to be read, so the remaining tasks are never sent.
+	Limit int64
+	// Priority is the priority of the request, the low priority requests are used by the background jobs,
+	// so they don't starve the foreground requests.
+	Priority int
+	// If Streaming is true, the rows of a storage unit are returned in several small responses instead of a big one,
+	// so the caller can start to handle the rows earlier with less memory. It's only used for the table scans without
+	// aggregation, limit and TopN.
+	Streaming bool
+	// ReplicaRead is the type of the replicas which serve the request.
+	ReplicaRead ReplicaReadType
 }
 
 // Response represents the response returned from KV layer.
@@ -164,6 +269,13 @@ type Snapshot interface {
 	Retriever
 	// BatchGet gets a batch of values from snapshot.
 	BatchGet(keys []Key) (map[string][]byte, error)
+	// SetPriority sets the priority of the reads of the snapshot.
+	SetPriority(priority int)
+	// SetReplicaRead sets the type of the replicas which serve the reads of the snapshot.
+	SetReplicaRead(typ ReplicaReadType)
+	// Release releases the snapshot when it's not used anymore, so the GC of the storage can collect the versions it
+	// reads.
+	Release()
 }
 
 // Driver is the interface that must be implemented by a KV storage.
@@ -191,6 +303,14 @@ type Storage interface {
 	UUID() string
 	// CurrentVersion returns current max committed version.
 	CurrentVersion() (Version, error)
+	// SupportCapability checks if the storage supports the capability.
+	SupportCapability(c Capability) bool
+}
+
+// SplittableStore is the storage which can split its regions.
+type SplittableStore interface {
+	// SplitRegion splits the region which contains splitKey into two regions at splitKey.
+	SplitRegion(splitKey Key) error
 }
 
 // FnKeyCmp is the function for iterator the keys
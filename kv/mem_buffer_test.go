@@ -18,6 +18,7 @@ package kv
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"testing"
 
 	. "github.com/pingcap/check"
@@ -240,6 +241,145 @@ func (s *testKVSuite) TestBufferLimit(c *C) {
 	c.Assert(err, NotNil) // buffer len limit
 }
 
+func (s *testKVSuite) TestStaging(c *C) {
+	defer testleak.AfterTest(c)()
+	buffer := NewMemDbBuffer().(*memDbBuffer)
+	c.Assert(buffer.Set([]byte("a"), []byte("1")), IsNil)
+	c.Assert(buffer.Set([]byte("c"), []byte("1")), IsNil)
+	size, length := buffer.Size(), buffer.Len()
+
+	h1 := buffer.Staging()
+	c.Assert(buffer.Set([]byte("a"), []byte("2")), IsNil)
+	c.Assert(buffer.Set([]byte("b"), []byte("2")), IsNil)
+	c.Assert(buffer.Delete([]byte("c")), IsNil)
+	h2 := buffer.Staging()
+	c.Assert(buffer.Set([]byte("d"), []byte("3")), IsNil)
+	buffer.Release(h2)
+	h3 := buffer.Staging()
+	c.Assert(buffer.Set([]byte("a"), []byte("3")), IsNil)
+	buffer.Cleanup(h3)
+	checkBuffer(c, buffer, "a", "2", "b", "2", "c", "", "d", "3")
+
+	// The changes of the released inner staging are discarded with the outer one.
+	buffer.Cleanup(h1)
+	checkBuffer(c, buffer, "a", "1", "c", "1")
+	c.Assert(buffer.Size(), Equals, size)
+	c.Assert(buffer.Len(), Equals, length)
+	_, err := buffer.Get([]byte("b"))
+	c.Assert(IsErrNotFound(err), IsTrue)
+
+	// The keys removed by Cleanup can be set again.
+	h4 := buffer.Staging()
+	c.Assert(buffer.Set([]byte("b"), []byte("4")), IsNil)
+	buffer.Release(h4)
+	checkBuffer(c, buffer, "a", "1", "b", "4", "c", "1")
+}
+
+// checkBuffer checks the buffer has the key value pairs by the iterators in both orders.
+func checkBuffer(c *C, buffer MemBuffer, kvs ...string) {
+	var got []string
+	iter, err := buffer.Seek(nil)
+	c.Assert(err, IsNil)
+	for ; iter.Valid(); iter.Next() {
+		got = append(got, string(iter.Key()), string(iter.Value()))
+	}
+	c.Assert(got, DeepEquals, kvs)
+
+	got = got[:0]
+	iter, err = buffer.SeekReverse(nil)
+	c.Assert(err, IsNil)
+	for ; iter.Valid(); iter.Next() {
+		got = append([]string{string(iter.Key()), string(iter.Value())}, got...)
+	}
+	c.Assert(got, DeepEquals, kvs)
+}
+
+func (s *testKVSuite) TestRandomOperations(c *C) {
+	defer testleak.AfterTest(c)()
+	buffer := NewMemDbBuffer().(*memDbBuffer)
+	expected := make(map[string]string)
+	var saved map[string]string
+	var h StagingHandle
+	for i := 0; i < 20000; i++ {
+		key := encodeInt(rand.Intn(5000))
+		switch op := rand.Intn(100); {
+		case op < 60:
+			value := encodeInt(i)
+			c.Assert(buffer.Set(key, value), IsNil)
+			expected[string(key)] = string(value)
+		case op < 80:
+			c.Assert(buffer.Delete(key), IsNil)
+			expected[string(key)] = ""
+		case op < 90 && h == 0:
+			h = buffer.Staging()
+			saved = make(map[string]string, len(expected))
+			for k, v := range expected {
+				saved[k] = v
+			}
+		case op < 95 && h != 0:
+			buffer.Release(h)
+			h = 0
+		case h != 0:
+			buffer.Cleanup(h)
+			h, expected = 0, saved
+		}
+	}
+	keys := make([]string, 0, len(expected))
+	size := 0
+	for k, v := range expected {
+		keys = append(keys, k)
+		size += len(k) + len(v)
+	}
+	sort.Strings(keys)
+	kvs := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		kvs = append(kvs, k, expected[k])
+	}
+	checkBuffer(c, buffer, kvs...)
+	c.Assert(buffer.Len(), Equals, len(expected))
+	c.Assert(buffer.Size(), Equals, size)
+
+	// Seek in the middle of the keys.
+	iter, err := buffer.Seek([]byte(keys[len(keys)/2]))
+	c.Assert(err, IsNil)
+	c.Assert(string(iter.Key()), Equals, keys[len(keys)/2])
+	iter, err = buffer.SeekReverse([]byte(keys[len(keys)/2]))
+	c.Assert(err, IsNil)
+	c.Assert(string(iter.Key()), Equals, keys[len(keys)/2-1])
+}
+
+func (s *testKVSuite) TestCleanupReleasesMemory(c *C) {
+	defer testleak.AfterTest(c)()
+	buffer := NewMemDbBuffer().(*memDbBuffer)
+	c.Assert(buffer.Set([]byte("k"), []byte("v")), IsNil)
+	mem := buffer.Memory()
+	h := buffer.Staging()
+	for i := 0; i < 1000; i++ {
+		c.Assert(buffer.Set([]byte("k"), make([]byte, 1024)), IsNil)
+	}
+	c.Assert(buffer.Memory() > mem+1000*1024, IsTrue)
+	buffer.Cleanup(h)
+	// The value log is truncated, only the undo log is left.
+	c.Assert(buffer.Memory() < mem+64*1024, IsTrue)
+	val, err := buffer.Get([]byte("k"))
+	c.Assert(err, IsNil)
+	c.Assert(string(val), Equals, "v")
+}
+
+func (s *testKVSuite) TestGetValueNotReused(c *C) {
+	defer testleak.AfterTest(c)()
+	buffer := NewMemDbBuffer()
+	c.Assert(buffer.Set([]byte("a"), []byte("v")), IsNil)
+	h := buffer.Staging()
+	c.Assert(buffer.Set([]byte("k"), []byte("v1")), IsNil)
+	val, err := buffer.Get([]byte("k"))
+	c.Assert(err, IsNil)
+	buffer.Cleanup(h)
+	// The space of the value cleaned up is reused by the later writes.
+	c.Assert(buffer.Set([]byte("k"), []byte("v2")), IsNil)
+	c.Assert(string(val), Equals, "v1")
+}
+
 var opCnt = 100000
 
 func BenchmarkMemDbBufferSequential(b *testing.B) {
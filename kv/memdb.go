@@ -0,0 +1,381 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+)
+
+const (
+	// memdbMaxHeight is the max height of the skiplist, 4^12 entries are enough for a transaction.
+	memdbMaxHeight = 12
+	// memdbBranching is the inverse of the probability that a node grows one more level.
+	memdbBranching = 4
+
+	arenaMinBlockSize = 256
+	arenaMaxBlockSize = 4 * 1024 * 1024
+	nodesMinChunkSize = 32
+	nodesMaxChunkSize = 64 * 1024
+)
+
+// arenaAddr is the address of a slice in an arena, the high 32 bits are the block index and the low 32 bits are the
+// offset in the block.
+type arenaAddr uint64
+
+func newArenaAddr(block, offset int) arenaAddr {
+	return arenaAddr(uint64(block)<<32 | uint64(offset))
+}
+
+func (addr arenaAddr) block() int {
+	return int(addr >> 32)
+}
+
+func (addr arenaAddr) offset() int {
+	return int(uint32(addr))
+}
+
+// memdbArena allocates the byte slices from the big blocks, so the keys and values don't create an object for each
+// entry. The slices are never moved, they can be referenced until the arena is truncated.
+type memdbArena struct {
+	blocks [][]byte
+	// capacity is the total size of the blocks.
+	capacity int
+}
+
+// arenaCheckpoint is the state of an arena, the arena can be truncated to it to release the slices allocated later.
+type arenaCheckpoint struct {
+	blocks int
+	offset int
+}
+
+func (a *memdbArena) alloc(n int) (arenaAddr, []byte) {
+	if len(a.blocks) > 0 {
+		idx := len(a.blocks) - 1
+		blk := a.blocks[idx]
+		if off := len(blk); off+n <= cap(blk) {
+			a.blocks[idx] = blk[:off+n]
+			return newArenaAddr(idx, off), blk[off : off+n : off+n]
+		}
+	}
+	size := arenaMinBlockSize
+	if len(a.blocks) > 0 {
+		size = cap(a.blocks[len(a.blocks)-1]) * 2
+		if size > arenaMaxBlockSize {
+			size = arenaMaxBlockSize
+		}
+	}
+	if size < n {
+		size = n
+	}
+	blk := make([]byte, n, size)
+	a.blocks = append(a.blocks, blk)
+	a.capacity += size
+	return newArenaAddr(len(a.blocks)-1, 0), blk[:n:n]
+}
+
+func (a *memdbArena) get(addr arenaAddr, n int) []byte {
+	off := addr.offset()
+	return a.blocks[addr.block()][off : off+n : off+n]
+}
+
+func (a *memdbArena) checkpoint() arenaCheckpoint {
+	if len(a.blocks) == 0 {
+		return arenaCheckpoint{}
+	}
+	return arenaCheckpoint{blocks: len(a.blocks), offset: len(a.blocks[len(a.blocks)-1])}
+}
+
+// truncate releases the slices allocated after the checkpoint, the blocks allocated after it are dropped.
+func (a *memdbArena) truncate(cp arenaCheckpoint) {
+	for i := len(a.blocks) - 1; i >= cp.blocks; i-- {
+		a.capacity -= cap(a.blocks[i])
+		a.blocks[i] = nil
+	}
+	a.blocks = a.blocks[:cp.blocks]
+	if cp.blocks > 0 {
+		a.blocks[cp.blocks-1] = a.blocks[cp.blocks-1][:cp.offset]
+	}
+}
+
+// The words of a skiplist node.
+const (
+	nodeKeyAddr = iota
+	// nodeKeyLen is the key length << 8 | the height of the node.
+	nodeKeyLen
+	nodeValueAddr
+	// nodeValueLen is the value length, or nodeAbsent if the key is not in the memdb.
+	nodeValueLen
+	nodeNext
+)
+
+// nodeAbsent marks the nodes whose keys are removed by Cleanup, the nodes are kept in the skiplist and skipped by
+// the reads.
+const nodeAbsent = uint64(1) << 63
+
+// memdbNodes allocates the skiplist nodes from the chunks of words, the address of a node is the chunk index << 32
+// | the offset in the chunk. The head node is allocated first, so the address 0 is used as nil.
+type memdbNodes struct {
+	chunks   [][]uint64
+	capacity int
+}
+
+func (ns *memdbNodes) alloc(words int) uint64 {
+	if len(ns.chunks) > 0 {
+		idx := len(ns.chunks) - 1
+		chunk := ns.chunks[idx]
+		if off := len(chunk); off+words <= cap(chunk) {
+			ns.chunks[idx] = chunk[:off+words]
+			return uint64(idx)<<32 | uint64(off)
+		}
+	}
+	size := nodesMinChunkSize
+	if len(ns.chunks) > 0 {
+		size = cap(ns.chunks[len(ns.chunks)-1]) * 2
+		if size > nodesMaxChunkSize {
+			size = nodesMaxChunkSize
+		}
+	}
+	ns.chunks = append(ns.chunks, make([]uint64, words, size))
+	ns.capacity += size * 8
+	return uint64(len(ns.chunks)-1) << 32
+}
+
+func (ns *memdbNodes) words(node uint64) []uint64 {
+	return ns.chunks[node>>32][uint32(node):]
+}
+
+// memdbUndo is the state of a node before it's changed in a staging.
+type memdbUndo struct {
+	node      uint64
+	valueAddr uint64
+	valueLen  uint64
+}
+
+// memdbStage is the state of the memdb when a staging begins.
+type memdbStage struct {
+	undoLen int
+	vlog    arenaCheckpoint
+	count   int
+	size    int
+}
+
+// memdb is an ordered in-memory key-value store, it's a skiplist whose keys, values and nodes are allocated from big
+// blocks, so millions of entries don't make millions of objects for GC to scan, and the entries can be iterated in
+// order without allocations. The values are appended to a value log, a staging records the old values of the changed
+// keys, and its changes are discarded by restoring the old values and truncating the value log.
+// It's not thread safe.
+type memdb struct {
+	keys   memdbArena
+	vlog   memdbArena
+	nodes  memdbNodes
+	height int
+	seed   uint32
+	// prev is used to find the previous nodes of an inserted key.
+	prev [memdbMaxHeight]uint64
+
+	// count is the number of the keys in the memdb.
+	count int
+	// size is the total length of the keys and values in the memdb.
+	size int
+
+	stages []memdbStage
+	undo   []memdbUndo
+}
+
+func (db *memdb) init() {
+	db.height, db.seed = 1, 0x2545f491
+	db.nodes.alloc(nodeNext + memdbMaxHeight)
+}
+
+func (db *memdb) randomHeight() int {
+	h := 1
+	for h < memdbMaxHeight {
+		// xorshift32
+		db.seed ^= db.seed << 13
+		db.seed ^= db.seed >> 17
+		db.seed ^= db.seed << 5
+		if db.seed%memdbBranching != 0 {
+			break
+		}
+		h++
+	}
+	return h
+}
+
+func (db *memdb) nodeKey(node uint64) []byte {
+	w := db.nodes.words(node)
+	return db.keys.get(arenaAddr(w[nodeKeyAddr]), int(w[nodeKeyLen]>>8))
+}
+
+// nodeValue returns the value of the node, ok is false if the key is not in the memdb.
+func (db *memdb) nodeValue(node uint64) (value []byte, ok bool) {
+	w := db.nodes.words(node)
+	if w[nodeValueLen] == nodeAbsent {
+		return nil, false
+	}
+	if w[nodeValueLen] == 0 {
+		return []byte{}, true
+	}
+	return db.vlog.get(arenaAddr(w[nodeValueAddr]), int(w[nodeValueLen])), true
+}
+
+func (db *memdb) next(node uint64, level int) uint64 {
+	return db.nodes.words(node)[nodeNext+level]
+}
+
+// findGE returns the first node whose key is greater than or equal to key, or 0 if there isn't one. If setPrev is
+// true, the nodes before it on each level are saved in db.prev.
+func (db *memdb) findGE(key []byte, setPrev bool) (node uint64, exact bool) {
+	x := uint64(0)
+	for level := db.height - 1; level >= 0; level-- {
+		next := db.next(x, level)
+		cmp := 1
+		for next != 0 {
+			cmp = bytes.Compare(db.nodeKey(next), key)
+			if cmp >= 0 {
+				break
+			}
+			x, next = next, db.next(next, level)
+		}
+		if setPrev {
+			db.prev[level] = x
+		}
+		if level == 0 {
+			return next, next != 0 && cmp == 0
+		}
+	}
+	return 0, false
+}
+
+// findLT returns the last node whose key is less than key, or 0 if there isn't one.
+func (db *memdb) findLT(key []byte) uint64 {
+	x := uint64(0)
+	for level := db.height - 1; level >= 0; level-- {
+		for next := db.next(x, level); next != 0 && bytes.Compare(db.nodeKey(next), key) < 0; next = db.next(x, level) {
+			x = next
+		}
+	}
+	return x
+}
+
+// findLast returns the last node, or 0 if the memdb is empty.
+func (db *memdb) findLast() uint64 {
+	x := uint64(0)
+	for level := db.height - 1; level >= 0; level-- {
+		for next := db.next(x, level); next != 0; next = db.next(x, level) {
+			x = next
+		}
+	}
+	return x
+}
+
+func (db *memdb) get(key []byte) ([]byte, bool) {
+	node, exact := db.findGE(key, false)
+	if !exact {
+		return nil, false
+	}
+	return db.nodeValue(node)
+}
+
+// set sets the value of the key, an empty value marks the key as deleted.
+func (db *memdb) set(key, value []byte) {
+	node, exact := db.findGE(key, true)
+	if !exact {
+		node = db.insert(key)
+	}
+	w := db.nodes.words(node)
+	if len(db.stages) > 0 {
+		db.undo = append(db.undo, memdbUndo{node: node, valueAddr: w[nodeValueAddr], valueLen: w[nodeValueLen]})
+	}
+	if w[nodeValueLen] == nodeAbsent {
+		db.count++
+		db.size += len(key)
+	} else {
+		db.size -= int(w[nodeValueLen])
+	}
+	db.size += len(value)
+	w[nodeValueAddr], w[nodeValueLen] = 0, uint64(len(value))
+	if len(value) > 0 {
+		addr, buf := db.vlog.alloc(len(value))
+		copy(buf, value)
+		w[nodeValueAddr] = uint64(addr)
+	}
+}
+
+// insert inserts an absent node of the key after the nodes in db.prev.
+func (db *memdb) insert(key []byte) uint64 {
+	h := db.randomHeight()
+	for ; db.height < h; db.height++ {
+		db.prev[db.height] = 0
+	}
+	keyAddr, buf := db.keys.alloc(len(key))
+	copy(buf, key)
+	node := db.nodes.alloc(nodeNext + h)
+	w := db.nodes.words(node)
+	w[nodeKeyAddr] = uint64(keyAddr)
+	w[nodeKeyLen] = uint64(len(key))<<8 | uint64(h)
+	w[nodeValueLen] = nodeAbsent
+	for level := 0; level < h; level++ {
+		prev := db.nodes.words(db.prev[level])
+		w[nodeNext+level] = prev[nodeNext+level]
+		prev[nodeNext+level] = node
+	}
+	return node
+}
+
+// staging begins a new staging, it returns the handle of the staging which is greater than 0.
+func (db *memdb) staging() int {
+	db.stages = append(db.stages, memdbStage{
+		undoLen: len(db.undo),
+		vlog:    db.vlog.checkpoint(),
+		count:   db.count,
+		size:    db.size,
+	})
+	return len(db.stages)
+}
+
+// release keeps the changes of the staging and the stagings in it, they are merged into the parent staging.
+func (db *memdb) release(h int) {
+	db.checkStaging(h)
+	db.stages = db.stages[:h-1]
+	if len(db.stages) == 0 {
+		db.undo = db.undo[:0]
+	}
+}
+
+// cleanup discards the changes of the staging and the stagings in it.
+func (db *memdb) cleanup(h int) {
+	db.checkStaging(h)
+	stage := db.stages[h-1]
+	for i := len(db.undo) - 1; i >= stage.undoLen; i-- {
+		u := db.undo[i]
+		w := db.nodes.words(u.node)
+		w[nodeValueAddr], w[nodeValueLen] = u.valueAddr, u.valueLen
+	}
+	db.undo = db.undo[:stage.undoLen]
+	db.vlog.truncate(stage.vlog)
+	db.count, db.size = stage.count, stage.size
+	db.stages = db.stages[:h-1]
+}
+
+func (db *memdb) checkStaging(h int) {
+	if h <= 0 || h > len(db.stages) {
+		panic("the staging is already released or cleaned up")
+	}
+}
+
+// memory returns the bytes allocated by the memdb.
+func (db *memdb) memory() int {
+	return db.keys.capacity + db.vlog.capacity + db.nodes.capacity + cap(db.undo)*24
+}
@@ -16,67 +16,74 @@
 package kv
 
 import (
+	"sync"
+
 	"github.com/juju/errors"
-	"github.com/pingcap/goleveldb/leveldb"
-	"github.com/pingcap/goleveldb/leveldb/comparer"
-	"github.com/pingcap/goleveldb/leveldb/iterator"
-	"github.com/pingcap/goleveldb/leveldb/memdb"
-	"github.com/pingcap/goleveldb/leveldb/util"
-	"github.com/pingcap/tidb/terror"
 )
 
+// memDbBuffer is the MemBuffer on a memdb, the reads and writes are protected by a lock.
 type memDbBuffer struct {
-	db              *memdb.DB
+	mu              sync.RWMutex
+	db              memdb
 	entrySizeLimit  int
 	bufferLenLimit  int
 	bufferSizeLimit int
 }
 
 type memDbIter struct {
-	iter    iterator.Iterator
+	m       *memDbBuffer
+	node    uint64
+	key     Key
+	value   []byte
 	reverse bool
 }
 
 // NewMemDbBuffer creates a new memDbBuffer.
 func NewMemDbBuffer() MemBuffer {
-	return &memDbBuffer{
-		db:              memdb.New(comparer.DefaultComparer, 4*1024),
+	m := &memDbBuffer{
 		entrySizeLimit:  TxnEntrySizeLimit,
 		bufferLenLimit:  TxnEntryCountLimit,
 		bufferSizeLimit: TxnTotalSizeLimit,
 	}
+	m.db.init()
+	return m
 }
 
 // Seek creates an Iterator.
 func (m *memDbBuffer) Seek(k Key) (Iterator, error) {
-	var i Iterator
-	if k == nil {
-		i = &memDbIter{iter: m.db.NewIterator(&util.Range{}), reverse: false}
-	} else {
-		i = &memDbIter{iter: m.db.NewIterator(&util.Range{Start: []byte(k)}), reverse: false}
-	}
-	i.Next()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, _ := m.db.findGE(k, false)
+	i := &memDbIter{m: m}
+	i.skip(node)
 	return i, nil
 }
 
 func (m *memDbBuffer) SeekReverse(k Key) (Iterator, error) {
-	var i *memDbIter
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var node uint64
 	if k == nil {
-		i = &memDbIter{iter: m.db.NewIterator(&util.Range{}), reverse: true}
+		node = m.db.findLast()
 	} else {
-		i = &memDbIter{iter: m.db.NewIterator(&util.Range{Limit: []byte(k)}), reverse: true}
+		node = m.db.findLT(k)
 	}
-	i.iter.Last()
+	i := &memDbIter{m: m, reverse: true}
+	i.skip(node)
 	return i, nil
 }
 
 // Get returns the value associated with key.
 func (m *memDbBuffer) Get(k Key) ([]byte, error) {
-	v, err := m.db.Get(k)
-	if terror.ErrorEqual(err, leveldb.ErrNotFound) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.db.get(k)
+	if !ok {
 		return nil, ErrNotExist
 	}
-	return v, nil
+	// The value points into the arena, whose space is reused after the staging buffer is cleaned up, so the caller
+	// gets a copy which can be held.
+	return append([]byte(nil), v...), nil
 }
 
 // Set associates key with value.
@@ -88,58 +95,115 @@ func (m *memDbBuffer) Set(k Key, v []byte) error {
 		return ErrEntryTooLarge.Gen("entry too large, size: %d", len(k)+len(v))
 	}
 
-	err := m.db.Put(k, v)
-	if m.Size() > m.bufferSizeLimit {
-		return ErrTxnTooLarge.Gen("transaction too large, size:%d", m.Size())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.db.set(k, v)
+	if m.db.size > m.bufferSizeLimit {
+		return ErrTxnTooLarge.Gen("transaction too large, size:%d", m.db.size)
 	}
-	if m.Len() > m.bufferLenLimit {
-		return ErrTxnTooLarge.Gen("transaction too large, len:%d", m.Len())
+	if m.db.count > m.bufferLenLimit {
+		return ErrTxnTooLarge.Gen("transaction too large, len:%d", m.db.count)
 	}
-	return errors.Trace(err)
+	return nil
 }
 
 // Delete removes the entry from buffer with provided key.
 func (m *memDbBuffer) Delete(k Key) error {
-	err := m.db.Put(k, nil)
-	return errors.Trace(err)
+	m.mu.Lock()
+	m.db.set(k, nil)
+	m.mu.Unlock()
+	return nil
 }
 
 // Size returns sum of keys and values length.
 func (m *memDbBuffer) Size() int {
-	return m.db.Size()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.db.size
 }
 
 // Len returns the number of entries in the DB.
 func (m *memDbBuffer) Len() int {
-	return m.db.Len()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.db.count
+}
+
+// Staging implements the MemBuffer Staging interface.
+func (m *memDbBuffer) Staging() StagingHandle {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return StagingHandle(m.db.staging())
+}
+
+// Release implements the MemBuffer Release interface.
+func (m *memDbBuffer) Release(h StagingHandle) {
+	m.mu.Lock()
+	m.db.release(int(h))
+	m.mu.Unlock()
+}
+
+// Cleanup implements the MemBuffer Cleanup interface.
+func (m *memDbBuffer) Cleanup(h StagingHandle) {
+	m.mu.Lock()
+	m.db.cleanup(int(h))
+	m.mu.Unlock()
+}
+
+// Memory returns the bytes allocated by the buffer, it's larger than Size because the overwritten values and the
+// skiplist nodes take memory as well.
+func (m *memDbBuffer) Memory() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.db.memory()
+}
+
+// skip moves the iterator to the node, the nodes removed by Cleanup are skipped.
+func (i *memDbIter) skip(node uint64) {
+	db := &i.m.db
+	for node != 0 {
+		if value, ok := db.nodeValue(node); ok {
+			i.node, i.key, i.value = node, db.nodeKey(node), value
+			return
+		}
+		if i.reverse {
+			node = db.findLT(db.nodeKey(node))
+		} else {
+			node = db.next(node, 0)
+		}
+	}
+	i.node, i.key, i.value = 0, nil, nil
 }
 
 // Next implements the Iterator Next.
 func (i *memDbIter) Next() error {
+	if i.node == 0 {
+		return nil
+	}
+	i.m.mu.RLock()
+	defer i.m.mu.RUnlock()
 	if i.reverse {
-		i.iter.Prev()
+		i.skip(i.m.db.findLT(i.key))
 	} else {
-		i.iter.Next()
+		i.skip(i.m.db.next(i.node, 0))
 	}
 	return nil
 }
 
 // Valid implements the Iterator Valid.
 func (i *memDbIter) Valid() bool {
-	return i.iter.Valid()
+	return i.node != 0
 }
 
 // Key implements the Iterator Key.
 func (i *memDbIter) Key() Key {
-	return i.iter.Key()
+	return i.key
 }
 
 // Value implements the Iterator Value.
 func (i *memDbIter) Value() []byte {
-	return i.iter.Value()
+	return i.value
 }
 
 // Close Implements the Iterator Close.
-func (i *memDbIter) Close() {
-	i.iter.Release()
-}
+func (i *memDbIter) Close() {}
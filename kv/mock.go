@@ -62,6 +62,10 @@ func (t *mockTxn) IsReadOnly() bool {
 func (t *mockTxn) StartTS() uint64 {
 	return uint64(0)
 }
+
+func (t *mockTxn) Prefetch(keys []Key) error {
+	return nil
+}
 func (t *mockTxn) Get(k Key) ([]byte, error) {
 	return nil, nil
 }
@@ -93,6 +97,14 @@ func (t *mockTxn) Size() int {
 	return 0
 }
 
+func (t *mockTxn) Staging() StagingHandle {
+	return 0
+}
+
+func (t *mockTxn) Release(h StagingHandle) {}
+
+func (t *mockTxn) Cleanup(h StagingHandle) {}
+
 // mockStorage is used to start a must commit-failed txn.
 type mockStorage struct {
 }
@@ -133,6 +145,10 @@ func (s *mockStorage) GetClient() Client {
 	return nil
 }
 
+func (s *mockStorage) SupportCapability(c Capability) bool {
+	return false
+}
+
 // MockTxn is used for test cases that need more interfaces than Transaction.
 type MockTxn interface {
 	Transaction
@@ -167,6 +183,12 @@ func (s *mockSnapshot) BatchGet(keys []Key) (map[string][]byte, error) {
 	return m, nil
 }
 
+func (s *mockSnapshot) SetPriority(priority int) {}
+
+func (s *mockSnapshot) SetReplicaRead(typ ReplicaReadType) {}
+
+func (s *mockSnapshot) Release() {}
+
 func (s *mockSnapshot) Seek(k Key) (Iterator, error) {
 	return s.store.Seek(k)
 }
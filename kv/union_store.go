@@ -35,6 +35,9 @@ type UnionStore interface {
 	DelOption(opt Option)
 	// GetOption gets an option.
 	GetOption(opt Option) interface{}
+	// Prefetch reads the values of the keys from the snapshot by a BatchGet, the later reads of the keys are served
+	// from the prefetched values instead of the snapshot.
+	Prefetch(keys []Key) error
 }
 
 // Option is used for customizing kv store's behaviors during a transaction.
@@ -61,6 +64,8 @@ type unionStore struct {
 	snapshot           Snapshot                    // for read
 	lazyConditionPairs map[string](*conditionPair) // for delay check
 	opts               options
+	// prefetched are the snapshot values read by Prefetch, an empty value means the key doesn't exist.
+	prefetched map[string][]byte
 }
 
 // NewUnionStore builds a new UnionStore.
@@ -151,10 +156,31 @@ func (lmb *lazyMemBuffer) Len() int {
 	return lmb.mb.Len()
 }
 
+func (lmb *lazyMemBuffer) Staging() StagingHandle {
+	if lmb.mb == nil {
+		lmb.mb = NewMemDbBuffer()
+	}
+	return lmb.mb.Staging()
+}
+
+func (lmb *lazyMemBuffer) Release(h StagingHandle) {
+	lmb.mb.Release(h)
+}
+
+func (lmb *lazyMemBuffer) Cleanup(h StagingHandle) {
+	lmb.mb.Cleanup(h)
+}
+
 // Get implements the Retriever interface.
 func (us *unionStore) Get(k Key) ([]byte, error) {
 	v, err := us.MemBuffer.Get(k)
 	if IsErrNotFound(err) {
+		if v, ok := us.prefetched[string(k)]; ok {
+			if len(v) == 0 {
+				return nil, errors.Trace(ErrNotExist)
+			}
+			return v, nil
+		}
 		if _, ok := us.opts.Get(PresumeKeyNotExists); ok {
 			e, ok := us.opts.Get(PresumeKeyNotExistsError)
 			if ok && e != nil {
@@ -215,6 +241,30 @@ func (us *unionStore) CheckLazyConditionPairs() error {
 	return nil
 }
 
+// Prefetch implements the UnionStore Prefetch interface.
+func (us *unionStore) Prefetch(keys []Key) error {
+	if us.prefetched == nil {
+		us.prefetched = make(map[string][]byte, len(keys))
+	}
+	toGet := make([]Key, 0, len(keys))
+	for _, k := range keys {
+		if _, ok := us.prefetched[string(k)]; !ok {
+			toGet = append(toGet, k)
+		}
+	}
+	if len(toGet) == 0 {
+		return nil
+	}
+	values, err := us.snapshot.BatchGet(toGet)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, k := range toGet {
+		us.prefetched[string(k)] = values[string(k)]
+	}
+	return nil
+}
+
 // SetOption implements the UnionStore SetOption interface.
 func (us *unionStore) SetOption(opt Option, val interface{}) {
 	us.opts[opt] = val
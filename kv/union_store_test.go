@@ -57,6 +57,32 @@ func (s *testUnionStoreSuite) TestDelete(c *C) {
 	c.Assert(v, BytesEquals, []byte("2"))
 }
 
+func (s *testUnionStoreSuite) TestPrefetch(c *C) {
+	defer testleak.AfterTest(c)()
+	s.store.Set([]byte("1"), []byte("1"))
+	err := s.us.Prefetch([]Key{Key("1"), Key("2")})
+	c.Assert(err, IsNil)
+
+	// The prefetched values are read instead of the snapshot.
+	s.store.Set([]byte("1"), []byte("2"))
+	s.store.Set([]byte("2"), []byte("2"))
+	v, err := s.us.Get([]byte("1"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("1"))
+	_, err = s.us.Get([]byte("2"))
+	c.Assert(IsErrNotFound(err), IsTrue)
+
+	// The buffered values are read before the prefetched ones.
+	s.us.Set([]byte("2"), []byte("3"))
+	v, err = s.us.Get([]byte("2"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("3"))
+	err = s.us.Delete([]byte("1"))
+	c.Assert(err, IsNil)
+	_, err = s.us.Get([]byte("1"))
+	c.Assert(IsErrNotFound(err), IsTrue)
+}
+
 func (s *testUnionStoreSuite) TestSeek(c *C) {
 	defer testleak.AfterTest(c)()
 	s.store.Set([]byte("1"), []byte("1"))
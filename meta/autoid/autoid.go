@@ -14,6 +14,8 @@
 package autoid
 
 import (
+	"encoding/binary"
+	"hash/fnv"
 	"math"
 	"sync"
 	"sync/atomic"
@@ -28,7 +30,13 @@ import (
 // Test needs to change it, so it's a variable.
 var step = int64(5000)
 
-var errInvalidTableID = terror.ClassAutoid.New(codeInvalidTableID, "invalid TableID")
+var (
+	errInvalidTableID = terror.ClassAutoid.New(codeInvalidTableID, "invalid TableID")
+	// ErrAutoRandomOverflow is returned when the auto ID of the AUTO_RANDOM column can't be kept in its bits.
+	ErrAutoRandomOverflow = terror.ClassAutoid.New(codeAutoRandomOverflow, "auto random ID overflows")
+	// ErrShardRowIDOverflow is returned when the implicit row ID can't be kept in the bits except the shard bits.
+	ErrShardRowIDOverflow = terror.ClassAutoid.New(codeShardRowIDOverflow, "shard row ID overflows")
+)
 
 // Allocator is an auto increment id generator.
 // Just keep id unique actually.
@@ -48,6 +56,8 @@ type allocator struct {
 	end   int64
 	store kv.Storage
 	dbID  int64
+	// customStep is the number of IDs allocated at a time, the default step is used if it's 0.
+	customStep int64
 }
 
 // GetStep is only used by tests
@@ -55,6 +65,13 @@ func GetStep() int64 {
 	return step
 }
 
+func (alloc *allocator) step() int64 {
+	if alloc.customStep > 0 {
+		return alloc.customStep
+	}
+	return step
+}
+
 // Rebase implements autoid.Allocator Rebase interface.
 func (alloc *allocator) Rebase(tableID, newBase int64, allocIDs bool) error {
 	if tableID == 0 {
@@ -70,6 +87,10 @@ func (alloc *allocator) Rebase(tableID, newBase int64, allocIDs bool) error {
 		alloc.base = newBase
 		return nil
 	}
+	// The allocator with step 1 doesn't cache the IDs, so the IDs are strictly increasing across the servers.
+	if alloc.step() == 1 {
+		allocIDs = false
+	}
 
 	return kv.RunInNewTxn(alloc.store, true, func(txn kv.Transaction) error {
 		m := meta.NewMeta(txn)
@@ -81,7 +102,7 @@ func (alloc *allocator) Rebase(tableID, newBase int64, allocIDs bool) error {
 		if newBase < end {
 			newBase = end
 		}
-		newStep := newBase - end + step
+		newStep := newBase - end + alloc.step()
 		if !allocIDs {
 			newStep = newBase - end
 		}
@@ -107,6 +128,7 @@ func (alloc *allocator) Alloc(tableID int64) (int64, error) {
 	alloc.mu.Lock()
 	defer alloc.mu.Unlock()
 	if alloc.base == alloc.end { // step
+		step := alloc.step()
 		err := kv.RunInNewTxn(alloc.store, true, func(txn kv.Transaction) error {
 			m := meta.NewMeta(txn)
 			base, err1 := m.GetAutoTableID(alloc.dbID, tableID)
@@ -173,6 +195,36 @@ func (alloc *memoryAllocator) Alloc(tableID int64) (int64, error) {
 	return alloc.base, nil
 }
 
+// localAllocator allocates the IDs of a single table in memory, the IDs are not shared with other tables.
+type localAllocator struct {
+	mu   sync.Mutex
+	base int64
+}
+
+// Rebase implements autoid.Allocator Rebase interface.
+func (alloc *localAllocator) Rebase(tableID, newBase int64, allocIDs bool) error {
+	if tableID == 0 {
+		return errInvalidTableID.Gen("Invalid tableID")
+	}
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+	if newBase > alloc.base {
+		alloc.base = newBase
+	}
+	return nil
+}
+
+// Alloc implements autoid.Allocator Alloc interface.
+func (alloc *localAllocator) Alloc(tableID int64) (int64, error) {
+	if tableID == 0 {
+		return 0, errInvalidTableID.Gen("Invalid tableID")
+	}
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+	alloc.base++
+	return alloc.base, nil
+}
+
 // NewAllocator returns a new auto increment id generator on the store.
 func NewAllocator(store kv.Storage, dbID int64) Allocator {
 	return &allocator{
@@ -181,6 +233,23 @@ func NewAllocator(store kv.Storage, dbID int64) Allocator {
 	}
 }
 
+// NewAllocatorWithStep returns a new auto increment id generator on the store, which allocates step IDs from the store
+// at a time. The default step is used if step isn't positive. The IDs allocated by different servers are strictly
+// increasing if step is 1.
+func NewAllocatorWithStep(store kv.Storage, dbID int64, step int64) Allocator {
+	return &allocator{
+		store:      store,
+		dbID:       dbID,
+		customStep: step,
+	}
+}
+
+// NewLocalAllocator returns a new auto increment id generator of a table in memory, it's used by the tables
+// whose rows are only kept in the TiDB server, like the temporary tables.
+func NewLocalAllocator() Allocator {
+	return &localAllocator{}
+}
+
 // NewMemoryAllocator returns a new auto increment id generator in memory.
 func NewMemoryAllocator(dbID int64) Allocator {
 	return &memoryAllocator{
@@ -189,7 +258,59 @@ func NewMemoryAllocator(dbID int64) Allocator {
 }
 
 //autoid error codes.
-const codeInvalidTableID terror.ErrCode = 1
+const (
+	codeInvalidTableID     terror.ErrCode = 1
+	codeAutoRandomOverflow terror.ErrCode = 2
+	codeShardRowIDOverflow terror.ErrCode = 3
+)
+
+// The shard bits of the AUTO_RANDOM column.
+const (
+	DefaultAutoRandomBits = 5
+	MaxAutoRandomBits     = 15
+)
+
+// MaxShardRowIDBits is the max number of the shard bits of the implicit row IDs.
+const MaxShardRowIDBits = 15
+
+// EncodeAutoRandomID puts the shard in the shard bits of the AUTO_RANDOM ID, which are the highest bits except the
+// sign bit, and the auto ID in the rest bits. The rows inserted in order are spread by the shards.
+func EncodeAutoRandomID(autoID int64, shard uint64, shardBits uint64) (int64, error) {
+	incrementalBits := 64 - 1 - shardBits
+	if autoID >= 1<<incrementalBits {
+		return 0, ErrAutoRandomOverflow.Gen("auto random ID %d overflows %d bits", autoID, incrementalBits)
+	}
+	return encodeShard(autoID, shard, shardBits), nil
+}
+
+// EncodeShardRowID puts the shard in the shard bits of the implicit row ID like EncodeAutoRandomID.
+func EncodeShardRowID(rowID int64, shard uint64, shardBits uint64) (int64, error) {
+	incrementalBits := 64 - 1 - shardBits
+	if rowID >= 1<<incrementalBits {
+		return 0, ErrShardRowIDOverflow.Gen("shard row ID %d overflows %d bits", rowID, incrementalBits)
+	}
+	return encodeShard(rowID, shard, shardBits), nil
+}
+
+func encodeShard(id int64, shard uint64, shardBits uint64) int64 {
+	incrementalBits := 64 - 1 - shardBits
+	return int64(shard&(1<<shardBits-1))<<incrementalBits | id
+}
+
+// TxnShard returns the shard of the IDs allocated in the transaction. It's the hash of the start timestamp, so the
+// rows inserted by a transaction are kept together, and the rows inserted by the transactions are spread.
+func TxnShard(startTS uint64) uint64 {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], startTS)
+	h := fnv.New64a()
+	h.Write(b[:])
+	return h.Sum64()
+}
+
+// DecodeAutoRandomID returns the auto ID in the AUTO_RANDOM ID.
+func DecodeAutoRandomID(id int64, shardBits uint64) int64 {
+	return id & (1<<(64-1-shardBits) - 1)
+}
 
 var localSchemaID = int64(math.MaxInt64)
 
@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/terror"
 )
 
 func TestT(t *testing.T) {
@@ -121,6 +122,77 @@ func (*testSuite) TestT(c *C) {
 	id, err = alloc.Alloc(3)
 	c.Assert(err, IsNil)
 	c.Assert(id, Equals, int64(6544))
+
+	alloc = NewLocalAllocator()
+	id, err = alloc.Alloc(4)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(1))
+	c.Assert(alloc.Rebase(4, 10, false), IsNil)
+	c.Assert(alloc.Rebase(4, 5, false), IsNil)
+	id, err = alloc.Alloc(4)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(11))
+}
+
+func (*testSuite) TestAllocWithStep(c *C) {
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		err = m.CreateDatabase(&model.DBInfo{ID: 1, Name: model.NewCIStr("a")})
+		c.Assert(err, IsNil)
+		err = m.CreateTable(1, &model.TableInfo{ID: 1, Name: model.NewCIStr("t")})
+		c.Assert(err, IsNil)
+		err = m.CreateTable(1, &model.TableInfo{ID: 2, Name: model.NewCIStr("t1")})
+		c.Assert(err, IsNil)
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	// The IDs allocated by the allocators with step 1 are strictly increasing.
+	alloc1 := NewAllocatorWithStep(store, 1, 1)
+	alloc2 := NewAllocatorWithStep(store, 1, 1)
+	for i := int64(1); i <= 6; i += 2 {
+		id, err1 := alloc1.Alloc(1)
+		c.Assert(err1, IsNil)
+		c.Assert(id, Equals, i)
+		id, err1 = alloc2.Alloc(1)
+		c.Assert(err1, IsNil)
+		c.Assert(id, Equals, i+1)
+	}
+	c.Assert(alloc1.Rebase(1, 10, true), IsNil)
+	id, err := alloc2.Alloc(1)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(11))
+	id, err = alloc1.Alloc(1)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(12))
+
+	alloc1 = NewAllocatorWithStep(store, 1, 10)
+	alloc2 = NewAllocatorWithStep(store, 1, 10)
+	id, err = alloc1.Alloc(2)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(1))
+	id, err = alloc2.Alloc(2)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(11))
+}
+
+func (*testSuite) TestAutoRandomID(c *C) {
+	id, err := EncodeAutoRandomID(3, 0x25, 5)
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, int64(0x05)<<58|3)
+	c.Assert(DecodeAutoRandomID(id, 5), Equals, int64(3))
+	c.Assert(id > 0, IsTrue)
+
+	id, err = EncodeAutoRandomID(1<<48-1, 1, 15)
+	c.Assert(err, IsNil)
+	c.Assert(DecodeAutoRandomID(id, 15), Equals, int64(1<<48-1))
+	_, err = EncodeAutoRandomID(1<<48, 1, 15)
+	c.Assert(terror.ErrorEqual(err, ErrAutoRandomOverflow), IsTrue)
 }
 
 // TestConcurrentAlloc is used for the test that
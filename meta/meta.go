@@ -424,6 +424,7 @@ func (m *Meta) GetTable(dbID int64, tableID int64) (*model.TableInfo, error) {
 // DDL job structure
 //	DDLOnwer: []byte
 //	DDLJobList: list jobs
+//	DDLReorgJobList: list jobs
 //	DDLJobHistory: hash
 //	DDLJobReorg: hash
 //
@@ -431,12 +432,32 @@ func (m *Meta) GetTable(dbID int64, tableID int64) (*model.TableInfo, error) {
 // to operate DDL jobs, and dispatch them to MR Jobs.
 
 var (
-	mDDLJobOwnerKey   = []byte("DDLJobOwner")
-	mDDLJobListKey    = []byte("DDLJobList")
-	mDDLJobHistoryKey = []byte("DDLJobHistory")
-	mDDLJobReorgKey   = []byte("DDLJobReorg")
+	mDDLJobOwnerKey     = []byte("DDLJobOwner")
+	mDDLJobListKey      = []byte("DDLJobList")
+	mDDLReorgJobListKey = []byte("DDLReorgJobList")
+	mDDLJobHistoryKey   = []byte("DDLJobHistory")
+	mDDLJobReorgKey     = []byte("DDLJobReorg")
 )
 
+// JobListKeyType is a key type of the DDL job queue.
+type JobListKeyType []byte
+
+var (
+	// DefaultJobListKey keeps all the DDL jobs except the jobs that reorganize the data.
+	DefaultJobListKey JobListKeyType = mDDLJobListKey
+	// ReorgJobListKey keeps the DDL jobs that reorganize the data, like adding index. They may take a long time, so
+	// they're handled in a separate queue.
+	ReorgJobListKey JobListKeyType = mDDLReorgJobListKey
+)
+
+// jobListKey returns the key of the DDL job queue, it's DefaultJobListKey if no key is specified.
+func jobListKey(jobListKeys []JobListKeyType) []byte {
+	if len(jobListKeys) == 0 {
+		return mDDLJobListKey
+	}
+	return jobListKeys[0]
+}
+
 func (m *Meta) getJobOwner(key []byte) (*model.Owner, error) {
 	value, err := m.txn.Get(key)
 	if err != nil || value == nil {
@@ -475,8 +496,8 @@ func (m *Meta) enQueueDDLJob(key []byte, job *model.Job) error {
 }
 
 // EnQueueDDLJob adds a DDL job to the list.
-func (m *Meta) EnQueueDDLJob(job *model.Job) error {
-	return m.enQueueDDLJob(mDDLJobListKey, job)
+func (m *Meta) EnQueueDDLJob(job *model.Job, jobListKeys ...JobListKeyType) error {
+	return m.enQueueDDLJob(jobListKey(jobListKeys), job)
 }
 
 func (m *Meta) deQueueDDLJob(key []byte) (*model.Job, error) {
@@ -491,8 +512,8 @@ func (m *Meta) deQueueDDLJob(key []byte) (*model.Job, error) {
 }
 
 // DeQueueDDLJob pops a DDL job from the list.
-func (m *Meta) DeQueueDDLJob() (*model.Job, error) {
-	return m.deQueueDDLJob(mDDLJobListKey)
+func (m *Meta) DeQueueDDLJob(jobListKeys ...JobListKeyType) (*model.Job, error) {
+	return m.deQueueDDLJob(jobListKey(jobListKeys))
 }
 
 func (m *Meta) getDDLJob(key []byte, index int64) (*model.Job, error) {
@@ -507,11 +528,29 @@ func (m *Meta) getDDLJob(key []byte, index int64) (*model.Job, error) {
 }
 
 // GetDDLJob returns the DDL job with index.
-func (m *Meta) GetDDLJob(index int64) (*model.Job, error) {
-	job, err := m.getDDLJob(mDDLJobListKey, index)
+func (m *Meta) GetDDLJob(index int64, jobListKeys ...JobListKeyType) (*model.Job, error) {
+	job, err := m.getDDLJob(jobListKey(jobListKeys), index)
 	return job, errors.Trace(err)
 }
 
+// GetAllDDLJobs gets all the DDL jobs in the list.
+func (m *Meta) GetAllDDLJobs(jobListKeys ...JobListKeyType) ([]*model.Job, error) {
+	key := jobListKey(jobListKeys)
+	n, err := m.txn.LLen(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	jobs := make([]*model.Job, 0, n)
+	for i := int64(0); i < n; i++ {
+		job, err := m.getDDLJob(key, i)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
 func (m *Meta) updateDDLJob(index int64, job *model.Job, key []byte) error {
 	// TODO: use timestamp allocated by TSO
 	job.LastUpdateTS = time.Now().UnixNano()
@@ -523,13 +562,13 @@ func (m *Meta) updateDDLJob(index int64, job *model.Job, key []byte) error {
 }
 
 // UpdateDDLJob updates the DDL job with index.
-func (m *Meta) UpdateDDLJob(index int64, job *model.Job) error {
-	return m.updateDDLJob(index, job, mDDLJobListKey)
+func (m *Meta) UpdateDDLJob(index int64, job *model.Job, jobListKeys ...JobListKeyType) error {
+	return m.updateDDLJob(index, job, jobListKey(jobListKeys))
 }
 
 // DDLJobQueueLen returns the DDL job queue length.
-func (m *Meta) DDLJobQueueLen() (int64, error) {
-	return m.txn.LLen(mDDLJobListKey)
+func (m *Meta) DDLJobQueueLen(jobListKeys ...JobListKeyType) (int64, error) {
+	return m.txn.LLen(jobListKey(jobListKeys))
 }
 
 func (m *Meta) jobIDKey(id int64) []byte {
@@ -698,6 +737,49 @@ func (m *Meta) SetBgJobOwner(o *model.Owner) error {
 	return m.setJobOwner(mBgJobOwnerKey, o)
 }
 
+// Schema syncer structure:
+//	SchemaSync: hash
+//
+// every server records the schema version it has loaded in SchemaSync,
+// so the DDL owner can know when all the servers have caught up.
+
+var (
+	mSchemaSyncKey = []byte("SchemaSync")
+)
+
+// SetSchemaSyncInfo sets the schema version loaded by a server.
+func (m *Meta) SetSchemaSyncInfo(info *model.SchemaSyncInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return m.txn.HSet(mSchemaSyncKey, []byte(info.ServerID), b)
+}
+
+// DelSchemaSyncInfo deletes the schema version loaded by a server.
+func (m *Meta) DelSchemaSyncInfo(serverID string) error {
+	return m.txn.HDel(mSchemaSyncKey, []byte(serverID))
+}
+
+// GetAllSchemaSyncInfos gets the schema versions loaded by all the servers.
+func (m *Meta) GetAllSchemaSyncInfos() ([]*model.SchemaSyncInfo, error) {
+	pairs, err := m.txn.HGetAll(mSchemaSyncKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	infos := make([]*model.SchemaSyncInfo, 0, len(pairs))
+	for _, pair := range pairs {
+		info := &model.SchemaSyncInfo{}
+		err = json.Unmarshal(pair.Value, info)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 func (m *Meta) tableStatsKey(tableID int64) []byte {
 	return []byte(fmt.Sprintf("%s:%d", mTableStatsPrefix, tableID))
 }
@@ -237,6 +237,27 @@ func (s *testSuite) TestDDL(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(owner, DeepEquals, ov)
 
+	infos, err := t.GetAllSchemaSyncInfos()
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 0)
+	info1 := &model.SchemaSyncInfo{ServerID: "1", Version: 1}
+	info2 := &model.SchemaSyncInfo{ServerID: "2", Version: 2}
+	err = t.SetSchemaSyncInfo(info1)
+	c.Assert(err, IsNil)
+	err = t.SetSchemaSyncInfo(info2)
+	c.Assert(err, IsNil)
+	info1.Version = 3
+	err = t.SetSchemaSyncInfo(info1)
+	c.Assert(err, IsNil)
+	infos, err = t.GetAllSchemaSyncInfos()
+	c.Assert(err, IsNil)
+	c.Assert(infos, DeepEquals, []*model.SchemaSyncInfo{info1, info2})
+	err = t.DelSchemaSyncInfo("1")
+	c.Assert(err, IsNil)
+	infos, err = t.GetAllSchemaSyncInfos()
+	c.Assert(err, IsNil)
+	c.Assert(infos, DeepEquals, []*model.SchemaSyncInfo{info2})
+
 	job := &model.Job{ID: 1}
 	err = t.EnQueueDDLJob(job)
 	c.Assert(err, IsNil)
@@ -282,6 +303,23 @@ func (s *testSuite) TestDDL(c *C) {
 		lastID = job.ID
 	}
 
+	// DDL reorganization job queue test
+	reorgJob := &model.Job{ID: 3}
+	err = t.EnQueueDDLJob(reorgJob, meta.ReorgJobListKey)
+	c.Assert(err, IsNil)
+	n, err = t.DDLJobQueueLen(meta.ReorgJobListKey)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(1))
+	n, err = t.DDLJobQueueLen()
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(0))
+	jobs, err := t.GetAllDDLJobs(meta.ReorgJobListKey)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, DeepEquals, []*model.Job{reorgJob})
+	v, err = t.DeQueueDDLJob(meta.ReorgJobListKey)
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, reorgJob)
+
 	// DDL background job test
 	err = t.SetBgJobOwner(owner)
 	c.Assert(err, IsNil)
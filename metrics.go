@@ -57,6 +57,13 @@ var (
 			Help:      "Bucketed histogram of session retry count.",
 			Buckets:   prometheus.LinearBuckets(0, 1, 10),
 		})
+	txnRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "session",
+			Name:      "txn_retry_total",
+			Help:      "Counter of the retried transactions by the type of the transactions and the retry results.",
+		}, []string{"type", "result"})
 )
 
 func init() {
@@ -65,4 +72,18 @@ func init() {
 	prometheus.MustRegister(sessionExecuteRunDuration)
 	prometheus.MustRegister(schemaLeaseErrorCounter)
 	prometheus.MustRegister(sessionRetry)
+	prometheus.MustRegister(txnRetryCounter)
+}
+
+const (
+	txnRetryOptimistic  = "optimistic"
+	txnRetryPessimistic = "pessimistic"
+)
+
+func observeTxnRetry(txnType string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	txnRetryCounter.WithLabelValues(txnType, result).Inc()
 }
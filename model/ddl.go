@@ -42,6 +42,15 @@ const (
 	ActionModifyColumn
 	ActionRenameTable
 	ActionSetDefaultValue
+	ActionRenameTables
+	ActionMultiSchemaChange
+	ActionRebaseAutoID
+	ActionModifyTableAutoIDCache
+	ActionAlterTTLInfo
+	ActionRemoveTTL
+	ActionAlterIndexVisibility
+	ActionRecoverTable
+	ActionModifyTableOptions
 )
 
 func (action ActionType) String() string {
@@ -74,6 +83,24 @@ func (action ActionType) String() string {
 		return "rename table"
 	case ActionSetDefaultValue:
 		return "set default value"
+	case ActionRenameTables:
+		return "rename tables"
+	case ActionMultiSchemaChange:
+		return "multi schema change"
+	case ActionRebaseAutoID:
+		return "rebase auto_increment ID"
+	case ActionModifyTableAutoIDCache:
+		return "modify auto id cache"
+	case ActionAlterTTLInfo:
+		return "alter ttl info"
+	case ActionRemoveTTL:
+		return "remove ttl"
+	case ActionAlterIndexVisibility:
+		return "alter index visibility"
+	case ActionRecoverTable:
+		return "recover table"
+	case ActionModifyTableOptions:
+		return "modify table options"
 	default:
 		return "none"
 	}
@@ -84,6 +111,8 @@ type HistoryInfo struct {
 	SchemaVersion int64
 	DBInfo        *DBInfo
 	TableInfo     *TableInfo
+	// MultipleTableInfos is only used by the DDL which changes more than one table, e.g. rename tables.
+	MultipleTableInfos []*TableInfo
 }
 
 // AddDBInfo adds schema version and schema information that are used for binlog.
@@ -93,6 +122,12 @@ func (h *HistoryInfo) AddDBInfo(schemaVer int64, dbInfo *DBInfo) {
 	h.DBInfo = dbInfo
 }
 
+// SetTableInfos sets schema version and the information of the tables that are changed by one DDL for binlog.
+func (h *HistoryInfo) SetTableInfos(schemaVer int64, tblInfos []*TableInfo) {
+	h.SchemaVersion = schemaVer
+	h.MultipleTableInfos = tblInfos
+}
+
 // AddTableInfo adds schema version and table information that are used for binlog.
 // tblInfo is added except for the following operations: create database, drop database.
 func (h *HistoryInfo) AddTableInfo(schemaVer int64, tblInfo *TableInfo) {
@@ -125,6 +160,21 @@ type Job struct {
 	// Query string of the ddl job.
 	Query      string       `json:"query"`
 	BinlogInfo *HistoryInfo `json:"binlog"`
+	// DependencyID is the ID of the job in the other queue which must be done before this job runs.
+	DependencyID int64 `json:"dependency_id"`
+	// MultiSchemaInfo is only used by the multi-schema change job.
+	MultiSchemaInfo *MultiSchemaInfo `json:"multi_schema_info"`
+}
+
+// MultiSchemaInfo keeps the sub-jobs of the multi-schema change job, which makes the changes of an ALTER TABLE
+// statement with multiple specifications. The sub-jobs run in order.
+type MultiSchemaInfo struct {
+	SubJobs []*Job `json:"sub_jobs"`
+	// RevertJobs are the jobs to revert the done sub-jobs at the same positions if a later sub-job fails.
+	// The revert job is nil if the sub-job can't be reverted.
+	RevertJobs []*Job `json:"revert_jobs"`
+	// Revertible becomes false when a sub-job starts to make the changes that can't be reverted.
+	Revertible bool `json:"revertible"`
 }
 
 // SetRowCount sets the number of rows. Make sure it can pass `make race`.
@@ -151,6 +201,21 @@ func (job *Job) Encode() ([]byte, error) {
 		return nil, errors.Trace(err)
 	}
 
+	if job.MultiSchemaInfo != nil {
+		for _, subJobs := range [][]*Job{job.MultiSchemaInfo.SubJobs, job.MultiSchemaInfo.RevertJobs} {
+			for _, sub := range subJobs {
+				// The args of the sub-job aren't decoded if it hasn't run, keep its raw args.
+				if sub == nil || sub.Args == nil {
+					continue
+				}
+				sub.RawArgs, err = json.Marshal(sub.Args)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+		}
+	}
+
 	var b []byte
 	job.Mu.Lock()
 	defer job.Mu.Unlock()
@@ -191,6 +256,11 @@ func (job *Job) IsDone() bool {
 	return job.State == JobDone
 }
 
+// IsCancelling returns whether job is requested to be cancelled or not.
+func (job *Job) IsCancelling() bool {
+	return job.State == JobCancelling
+}
+
 // IsRunning returns whether job is still running or not.
 func (job *Job) IsRunning() bool {
 	return job.State == JobRunning
@@ -210,6 +280,9 @@ const (
 	JobRollbackDone
 	JobDone
 	JobCancelled
+	// JobCancelling is the state of the job which is requested to be cancelled by the user, the DDL worker cancels it
+	// or rolls it back if it's cancellable.
+	JobCancelling
 )
 
 // String implements fmt.Stringer interface.
@@ -225,6 +298,8 @@ func (s JobState) String() string {
 		return "done"
 	case JobCancelled:
 		return "cancelled"
+	case JobCancelling:
+		return "cancelling"
 	default:
 		return "none"
 	}
@@ -243,6 +318,14 @@ func (o *Owner) String() string {
 	return fmt.Sprintf("ID:%s, LastUpdateTS:%d", o.OwnerID, o.LastUpdateTS)
 }
 
+// SchemaSyncInfo is the schema version that a server has loaded.
+type SchemaSyncInfo struct {
+	ServerID string `json:"server_id"`
+	Version  int64  `json:"version"`
+	// unix nano seconds
+	LastUpdateTS int64 `json:"last_update_ts"`
+}
+
 // SchemaDiff contains the schema modification at a particular schema version.
 // It is used to reduce schema reload cost.
 type SchemaDiff struct {
@@ -255,4 +338,14 @@ type SchemaDiff struct {
 	OldTableID int64 `json:"old_table_id"`
 	// OldSchemaID is the schema ID before rename table, only used by rename table DDL.
 	OldSchemaID int64 `json:"old_schema_id"`
+
+	// AffectedOpts is used by the DDL which changes more than one table, e.g. rename tables.
+	AffectedOpts []*AffectedOption `json:"affected_options"`
+}
+
+// AffectedOption is the table changed by the DDL which changes more than one table.
+type AffectedOption struct {
+	SchemaID    int64 `json:"schema_id"`
+	TableID     int64 `json:"table_id"`
+	OldSchemaID int64 `json:"old_schema_id"`
 }
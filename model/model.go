@@ -15,6 +15,7 @@ package model
 
 import (
 	"strings"
+	"time"
 
 	"github.com/pingcap/tidb/util/types"
 )
@@ -66,6 +67,30 @@ type ColumnInfo struct {
 	types.FieldType    `json:"type"`
 	State              SchemaState `json:"state"`
 	Comment            string      `json:"comment"`
+	// DefaultIsExpr is true if DefaultValue is the string of an expression, it's evaluated every time the default
+	// value is used, like DEFAULT (uuid()).
+	DefaultIsExpr bool `json:"default_is_expr,omitempty"`
+	// ChangeStateInfo is set if the values of the column are converted from another column, when the column type is
+	// being changed.
+	ChangeStateInfo *ChangeStateInfo `json:"change_state_info"`
+	// GeneratedExprString is set if the column is the hidden column of an expression index, its values are evaluated
+	// from the expression on the other columns, they're written to the index but not to the row.
+	GeneratedExprString string `json:"generated_expr_string,omitempty"`
+}
+
+// IsGenerated returns whether the column is the hidden column of an expression index.
+func (c *ColumnInfo) IsGenerated() bool {
+	return len(c.GeneratedExprString) != 0
+}
+
+// ChangeStateInfo is used when the type of a column is changed. The values of the changed column are written to a
+// non-public column of the new type, which replaces the original column after the existing rows are converted.
+type ChangeStateInfo struct {
+	// DependencyColumnOffset is the offset of the column whose values are converted to the values of this column.
+	DependencyColumnOffset int `json:"dependency_col_offset"`
+	// Origin means this is the original column which is being dropped after it's replaced, the values that can't be
+	// converted back are written as the truncated values rather than failing the writes.
+	Origin bool `json:"origin"`
 }
 
 // Clone clones ColumnInfo.
@@ -90,10 +115,28 @@ type TableInfo struct {
 	AutoIncID   int64         `json:"auto_inc_id"`
 	MaxColumnID int64         `json:"max_col_id"`
 	MaxIndexID  int64         `json:"max_idx_id"`
+	// AutoIDCache is the number of the auto IDs which are allocated at a time, the default step is used if it's 0.
+	// The auto IDs are strictly increasing across the servers if it's 1.
+	AutoIDCache int64 `json:"auto_id_cache,omitempty"`
+	// AutoRandomBits is the number of the shard bits of the AUTO_RANDOM primary key, it's 0 if there is no such column.
+	AutoRandomBits uint64 `json:"auto_random_bits,omitempty"`
+	// ShardRowIDBits is the number of the shard bits of the implicit row IDs, the rows inserted in order are spread
+	// by the shards. It's 0 if the row IDs aren't sharded.
+	ShardRowIDBits uint64 `json:"shard_row_id_bits,omitempty"`
+	// PreSplitRegions is the number of the shard bits by which the regions of the table are split when it's created,
+	// the table has 2^PreSplitRegions regions then.
+	PreSplitRegions uint64 `json:"pre_split_regions,omitempty"`
+	// TTLInfo is the TTL option of the table, it's nil if the rows never expire.
+	TTLInfo *TTLInfo `json:"ttl_info,omitempty"`
+	// StorageOptions are the storage options given when the table is created or altered, it's nil if there isn't any.
+	StorageOptions *TableStorageOptions `json:"storage_options,omitempty"`
 	// Because auto increment ID has schemaID as prefix,
 	// We need to save original schemaID to keep autoID unchanged
 	// while renaming a table from one database to another.
+	// Now the auto increment ID is moved along with the renamed table, it's only kept for the tables renamed before.
 	OldSchemaID int64 `json:"old_schema_id,omitempty"`
+	// Temporary is true for a session temporary table, its meta is only kept in the session and never persisted.
+	Temporary bool `json:"-"`
 }
 
 // Clone clones TableInfo.
@@ -115,9 +158,26 @@ func (t *TableInfo) Clone() *TableInfo {
 		nt.ForeignKeys[i] = t.ForeignKeys[i].Clone()
 	}
 
+	if t.TTLInfo != nil {
+		nt.TTLInfo = t.TTLInfo.Clone()
+	}
+	if t.StorageOptions != nil {
+		nt.StorageOptions = t.StorageOptions.Clone()
+	}
+
 	return &nt
 }
 
+// IsExpressionIndex returns whether the index has the hidden columns of the expressions.
+func (t *TableInfo) IsExpressionIndex(index *IndexInfo) bool {
+	for _, ic := range index.Columns {
+		if t.Columns[ic.Offset].IsGenerated() {
+			return true
+		}
+	}
+	return false
+}
+
 // IndexColumn provides index column info.
 type IndexColumn struct {
 	Name   CIStr `json:"name"`   // Index name
@@ -164,6 +224,8 @@ type IndexInfo struct {
 	State   SchemaState    `json:"state"`
 	Comment string         `json:"comment"`    // Comment
 	Tp      IndexType      `json:"index_type"` // Index type: Btree or Hash
+	// Invisible is true if the index is ignored by the planner, it's still maintained when the rows are changed.
+	Invisible bool `json:"is_invisible,omitempty"`
 }
 
 // Clone clones IndexInfo.
@@ -210,6 +272,59 @@ func (fk *FKInfo) Clone() *FKInfo {
 	return &nfk
 }
 
+// DefaultTTLJobInterval is the default interval between two TTL jobs of a table.
+const DefaultTTLJobInterval = "1h"
+
+// TTLInfo provides meta data describing the TTL option of a table.
+// A row expires IntervalValue of IntervalTimeUnit after the value of its time column.
+type TTLInfo struct {
+	ColumnName       CIStr  `json:"column"`
+	IntervalValue    uint64 `json:"interval_value"`
+	IntervalTimeUnit string `json:"interval_time_unit"`
+	// Enable is false if the expired rows are kept.
+	Enable bool `json:"enable"`
+	// JobInterval is the interval between two TTL jobs of the table, like "1h" or "30m".
+	JobInterval string `json:"job_interval"`
+}
+
+// TableStorageOptions are the storage options of a table. They're kept for the compatibility with MySQL, so the
+// dumped schema can be restored as it's defined, but they don't change how the rows are stored.
+type TableStorageOptions struct {
+	RowFormat     string `json:"row_format,omitempty"`
+	Compression   string `json:"compression,omitempty"`
+	KeyBlockSize  uint64 `json:"key_block_size,omitempty"`
+	AvgRowLength  uint64 `json:"avg_row_length,omitempty"`
+	MaxRows       uint64 `json:"max_rows,omitempty"`
+	MinRows       uint64 `json:"min_rows,omitempty"`
+	Checksum      uint64 `json:"checksum,omitempty"`
+	DelayKeyWrite uint64 `json:"delay_key_write,omitempty"`
+}
+
+// Clone clones TableStorageOptions.
+func (o *TableStorageOptions) Clone() *TableStorageOptions {
+	no := *o
+	return &no
+}
+
+// IsEmpty returns whether none of the options is set.
+func (o *TableStorageOptions) IsEmpty() bool {
+	return *o == TableStorageOptions{}
+}
+
+// Clone clones TTLInfo.
+func (t *TTLInfo) Clone() *TTLInfo {
+	nt := *t
+	return &nt
+}
+
+// GetJobInterval gets the interval between two TTL jobs of the table.
+func (t *TTLInfo) GetJobInterval() (time.Duration, error) {
+	if t.JobInterval == "" {
+		return time.ParseDuration(DefaultTTLJobInterval)
+	}
+	return time.ParseDuration(t.JobInterval)
+}
+
 // DBInfo provides meta data describing a DB.
 type DBInfo struct {
 	ID      int64        `json:"id"`      // Database ID
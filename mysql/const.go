@@ -93,6 +93,14 @@ const (
 	ComResetConnection
 )
 
+// Cursor types of the COM_STMT_EXECUTE command.
+// See https://dev.mysql.com/doc/internals/en/com-stmt-execute.html
+const (
+	CursorTypeReadOnly byte = 1 << iota
+	CursorTypeForUpdate
+	CursorTypeScrollable
+)
+
 // Client informations.
 const (
 	ClientLongPassword uint32 = 1 << iota
@@ -147,6 +155,8 @@ const (
 	GlobalStatusTable = "GLOBAL_STATUS"
 	// TiDBTable is the table contains tidb info.
 	TiDBTable = "tidb"
+	// ResourceGroupTable is the table contains the limits of the resource groups.
+	ResourceGroupTable = "resource_group"
 )
 
 // PrivilegeType  privilege
@@ -178,6 +188,10 @@ const (
 	ExecutePriv
 	// IndexPriv is the privilege to create/drop index.
 	IndexPriv
+	// ProcessPriv is the privilege to see the statements executed by the other users.
+	ProcessPriv
+	// SuperPriv is the privilege to do the administrative operations, such as setting the global system variables.
+	SuperPriv
 	// AllPriv is the privilege for all actions.
 	AllPriv
 )
@@ -199,6 +213,8 @@ var Priv2UserCol = map[PrivilegeType]string{
 	AlterPriv:      "Alter_priv",
 	ExecutePriv:    "Execute_priv",
 	IndexPriv:      "Index_priv",
+	ProcessPriv:    "Process_priv",
+	SuperPriv:      "Super_priv",
 }
 
 // Col2PrivType is the privilege tables column name to privilege type.
@@ -215,10 +231,12 @@ var Col2PrivType = map[string]PrivilegeType{
 	"Alter_priv":       AlterPriv,
 	"Execute_priv":     ExecutePriv,
 	"Index_priv":       IndexPriv,
+	"Process_priv":     ProcessPriv,
+	"Super_priv":       SuperPriv,
 }
 
 // AllGlobalPrivs is all the privileges in global scope.
-var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv}
+var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv, ProcessPriv, SuperPriv}
 
 // Priv2Str is the map for privilege to string.
 var Priv2Str = map[PrivilegeType]string{
@@ -234,6 +252,8 @@ var Priv2Str = map[PrivilegeType]string{
 	AlterPriv:      "Alter",
 	ExecutePriv:    "Execute",
 	IndexPriv:      "Index",
+	ProcessPriv:    "Process",
+	SuperPriv:      "Super",
 }
 
 // Priv2SetStr is the map for privilege to string.
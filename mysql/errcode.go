@@ -882,4 +882,27 @@ const (
 	ErrMustChangePasswordLogin                                      = 1862
 	ErrRowInWrongPartition                                          = 1863
 	ErrErrorLast                                                    = 1863
+
+	// MySQL 5.7 errors
+	ErrJSONDocumentNULLKey = 3158
+
+	// MySQL 8.0 errors
+	ErrPKIndexCantBeInvisible                = 3522
+	ErrLockNowait                            = 3572
+	ErrCTERecursiveRequiresUnion             = 3573
+	ErrCTERecursiveRequiresNonRecursiveFirst = 3574
+	ErrCTERecursiveForbidsAggregation        = 3575
+	ErrWindowInvalidWindowFuncUse            = 3593
+	ErrCTEMaxRecursionDepth                  = 3636
+	ErrResourceGroupExists                   = 3650
+	ErrResourceGroupNotExists                = 3651
+	ErrDisallowedOperation                   = 3655
+	ErrResourceGroupBusy                     = 3656
+	ErrFunctionalIndexRefAutoIncrement       = 3754
+	ErrCannotDropColumnFunctionalIndex       = 3755
+	ErrFunctionalIndexPrimaryKey             = 3756
+	ErrFunctionalIndexOnLob                  = 3757
+	ErrFunctionalIndexFunctionIsNotAllowed   = 3758
+	ErrFunctionalIndexOnField                = 3762
+	ErrDependentByFunctionalIndex            = 3837
 )
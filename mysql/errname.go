@@ -879,4 +879,27 @@ var MySQLErrName = map[uint16]string{
 	ErrAlterOperationNotSupportedReasonNotNull:               "cannot silently convert NULL values, as required in this SQLMODE",
 	ErrMustChangePasswordLogin:                               "Your password has expired. To log in you must change it using a client that supports expired passwords.",
 	ErrRowInWrongPartition:                                   "Found a row in wrong partition %s",
+
+	// MySQL 5.7 errors
+	ErrJSONDocumentNULLKey: "JSON documents may not contain NULL member names.",
+
+	// MySQL 8.0 errors
+	ErrPKIndexCantBeInvisible:                "A primary key index cannot be invisible",
+	ErrLockNowait:                            "Statement aborted because lock(s) could not be acquired immediately and NOWAIT is set.",
+	ErrCTERecursiveRequiresUnion:             "Recursive Common Table Expression '%s' should contain a UNION",
+	ErrCTERecursiveRequiresNonRecursiveFirst: "Recursive Common Table Expression '%s' should have one or more non-recursive query blocks followed by one or more recursive ones",
+	ErrCTERecursiveForbidsAggregation:        "Recursive Common Table Expression '%s' can contain neither aggregation nor window functions in recursive query block",
+	ErrWindowInvalidWindowFuncUse:            "You cannot use the window function '%s' in this context.",
+	ErrCTEMaxRecursionDepth:                  "Recursive query aborted after %d iterations. Try increasing @@cte_max_recursion_depth to a larger value.",
+	ErrResourceGroupExists:                   "Resource Group '%s' exists",
+	ErrResourceGroupNotExists:                "Resource Group '%s' does not exist.",
+	ErrDisallowedOperation:                   "%s operation is disallowed on %s",
+	ErrResourceGroupBusy:                     "Resource group %s is busy.",
+	ErrFunctionalIndexRefAutoIncrement:       "Expression of functional index '%s' cannot refer to an auto-increment column.",
+	ErrCannotDropColumnFunctionalIndex:       "Cannot drop column '%s' because it is used by a functional index. In order to drop the column, you must remove the functional index.",
+	ErrFunctionalIndexPrimaryKey:             "The primary key cannot be a functional index",
+	ErrFunctionalIndexOnLob:                  "Cannot create a functional index on an expression that returns a BLOB or TEXT. Please consider using CAST.",
+	ErrFunctionalIndexFunctionIsNotAllowed:   "Expression of functional index '%s' contains a disallowed function.",
+	ErrFunctionalIndexOnField:                "Functional index on a column is not supported. Consider using a regular index instead.",
+	ErrDependentByFunctionalIndex:            "Column '%s' has a functional index dependency and cannot be dropped or renamed.",
 }
@@ -80,6 +80,9 @@ const (
 	MinInt24  = -1 << 23
 )
 
+// MaxDecimalWidth is the max precision of the decimal type.
+const MaxDecimalWidth = 65
+
 // HasNotNullFlag checks if NotNullFlag is set.
 func HasNotNullFlag(flag uint) bool {
 	return (flag & NotNullFlag) > 0
@@ -0,0 +1,121 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Normalize returns the normalized text of the sql. The literals are replaced by "?", a list of literals is folded
+// to "...", the comments and the blanks are removed, the keywords and identifiers are in lower case.
+// Statements differing only in the literals have the same normalized text.
+func Normalize(sql string) string {
+	s := NewScanner(sql)
+	tokens := make([]string, 0, 32)
+	for {
+		tok, _, lit := s.scan()
+		if tok == 0 || (tok == unicode.ReplacementChar && s.r.eof()) {
+			break
+		}
+		switch tok {
+		case intLit, floatLit, decLit, hexLit, bitLit, stringLit:
+			tokens = appendLiteral(tokens)
+			continue
+		}
+		if lit == "" && tok > 0 && tok < unicode.MaxASCII {
+			lit = string(rune(tok))
+		}
+		if lit == "" {
+			continue
+		}
+		tokens = append(tokens, strings.ToLower(lit))
+	}
+	if n := len(tokens); n > 0 && tokens[n-1] == ";" {
+		tokens = tokens[:n-1]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// RedactPassword returns the sql with the password literals replaced by "?", so it can be kept in the logs. The
+// string literals following IDENTIFIED BY [PASSWORD] or PASSWORD(, and the ones in the value of SET PASSWORD are
+// the passwords, the rest of the sql is kept as it is.
+func RedactPassword(sql string) string {
+	s := NewScanner(sql)
+	var buf bytes.Buffer
+	// prev and prev2 are the last two tokens, stmtTokens is the number of the tokens of the current statement.
+	var prev, prev2 string
+	var stmtTokens, start int
+	var inSetPassword, inPasswordValue bool
+	for {
+		tok, pos, lit := s.scan()
+		if tok == 0 || (tok == unicode.ReplacementChar && s.r.eof()) {
+			break
+		}
+		if tok == invalid && s.r.p.Offset == pos.Offset {
+			// The illegal character isn't consumed by the scanner, it's skipped.
+			s.r.inc()
+			continue
+		}
+		if tok == stringLit {
+			if inPasswordValue || (prev == "(" && prev2 == "password") || (prev == "by" && prev2 == "identified") ||
+				(prev == "password" && prev2 == "by") {
+				buf.WriteString(sql[start:pos.Offset])
+				buf.WriteString("?")
+				start = s.r.p.Offset
+			}
+			lit = "?"
+		}
+		if lit == "" && tok > 0 && tok < unicode.MaxASCII {
+			lit = string(rune(tok))
+		}
+		lit = strings.ToLower(lit)
+		switch {
+		case lit == ";":
+			stmtTokens, inSetPassword, inPasswordValue = 0, false, false
+			prev, prev2 = "", ""
+			continue
+		case stmtTokens == 1 && prev == "set" && lit == "password":
+			inSetPassword = true
+		case inSetPassword && lit == "=":
+			inPasswordValue = true
+		}
+		stmtTokens++
+		prev, prev2 = lit, prev
+	}
+	if start == 0 {
+		return sql
+	}
+	buf.WriteString(sql[start:])
+	return buf.String()
+}
+
+// appendLiteral appends a literal to the normalized tokens, "?, ?" is folded to "...".
+func appendLiteral(tokens []string) []string {
+	n := len(tokens)
+	if n >= 2 && tokens[n-1] == "," && (tokens[n-2] == "?" || tokens[n-2] == "...") {
+		tokens[n-2] = "..."
+		return tokens[:n-1]
+	}
+	return append(tokens, "?")
+}
+
+// Digest returns the digest of the normalized sql text.
+func Digest(normalized string) string {
+	hash := sha256.Sum256([]byte(normalized))
+	return fmt.Sprintf("%x", hash[:16])
+}
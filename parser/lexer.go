@@ -357,6 +357,21 @@ func startWithSlash(s *Scanner) (tok int, pos Pos, lit string) {
 			}
 		}
 
+		// Convert "/*T![feature_id] TiDB-specific-code */" to "TiDB-specific-code" if the feature is supported,
+		// otherwise it's ignored as a normal comment, so the older versions can parse the output of the newer ones.
+		if strings.HasPrefix(comment, "/*T![") {
+			if begin, ok := tidbFeatureCodeOffset(comment); ok {
+				s.specialComment = &mysqlSpecificCodeScanner{
+					Scanner: NewScanner(specCodeEnd.ReplaceAllString(comment[begin:], "")),
+					Pos: Pos{
+						pos.Line,
+						pos.Col,
+						pos.Offset + begin,
+					},
+				}
+			}
+		}
+
 		return s.scan()
 	}
 	tok = int('/')
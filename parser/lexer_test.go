@@ -139,6 +139,8 @@ func (s *testLexerSuite) TestComment(c *C) {
 	table := []testCaseItem{
 		{"-- select --\n1", intLit},
 		{"/*!40101 SET character_set_client = utf8 */;", set},
+		{"/*T![clustered_index] CLUSTERED */", clustered},
+		{"/*T![clustered_index,unknown_feature] CLUSTERED */;", int(';')},
 		{"/*+ BKA(t1) */", hintBegin},
 		{"/* SET character_set_client = utf8 */;", int(';')},
 		{"/* some comments */ SELECT ", selectKwd},
@@ -314,3 +316,43 @@ func (s *testLexerSuite) TestSQLModeANSIQuotes(c *C) {
 		c.Assert(v.ident, Equals, t.ident)
 	}
 }
+
+func (s *testLexerSuite) TestNormalize(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []struct {
+		sql        string
+		normalized string
+	}{
+		{"SELECT * FROM t WHERE a = 1", "select * from t where a = ?"},
+		{"select *  from t where a = 'x' /* comment */ and b > 1.5;", "select * from t where a = ? and b > ?"},
+		{"select * from `T` where a in (1, 2, 3) and b = x'01'", "select * from t where a in ( ... ) and b = ?"},
+		{"insert into t values (1, 'a'), (2, 'b')", "insert into t values ( ... ) , ( ... )"},
+		{"select a from t where b >= -10 -- comment", "select a from t where b >= - ?"},
+	}
+	for _, t := range table {
+		c.Check(Normalize(t.sql), Equals, t.normalized, Commentf("sql: %s", t.sql))
+	}
+	c.Assert(Digest(Normalize("select a from t where b = 1")), Equals, Digest(Normalize("select a from t where b = 2")))
+	c.Assert(Digest(Normalize("select a from t where b = 1")), Not(Equals), Digest(Normalize("select a from t where c = 1")))
+	c.Assert(Digest(""), HasLen, 32)
+}
+
+func (s *testLexerSuite) TestRedactPassword(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []struct {
+		sql      string
+		redacted string
+	}{
+		{"create user 'u'@'%' identified by 'hunter2'", "create user 'u'@'%' identified by ?"},
+		{"CREATE USER u1 IDENTIFIED BY 'a', u2 IDENTIFIED BY PASSWORD '*0123'", "CREATE USER u1 IDENTIFIED BY ?, u2 IDENTIFIED BY PASSWORD ?"},
+		{"grant select on *.* to 'u'@'%' identified by \"x\\\"y\"", "grant select on *.* to 'u'@'%' identified by ?"},
+		{"set password for 'u'@'%' = password('pwd')", "set password for 'u'@'%' = password(?)"},
+		{"SET PASSWORD = 'pwd'; select 'pwd'", "SET PASSWORD = ?; select 'pwd'"},
+		{"select password('pwd'), 'by'", "select password(?), 'by'"},
+		{"select * from t where a = 'x'", "select * from t where a = 'x'"},
+		{"select ? [arguments: [1]]; set password = 'pwd'", "select ? [arguments: [1]]; set password = ?"},
+	}
+	for _, t := range table {
+		c.Check(RedactPassword(t.sql), Equals, t.redacted, Commentf("sql: %s", t.sql))
+	}
+}
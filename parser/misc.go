@@ -141,12 +141,14 @@ var tokenMap = map[string]int{
 	"ADDDATE":                    addDate,
 	"ADDTIME":                    addTime,
 	"ADMIN":                      admin,
+	"ADVICE":                     advice,
 	"AES_DECRYPT":                aesDecrypt,
 	"AES_ENCRYPT":                aesEncrypt,
 	"AFTER":                      after,
 	"ALL":                        all,
 	"ALTER":                      alter,
 	"ANALYZE":                    analyze,
+	"APPROX_COUNT_DISTINCT":      approxCountDistinct,
 	"AND":                        and,
 	"ANY":                        any,
 	"AS":                         as,
@@ -155,7 +157,9 @@ var tokenMap = map[string]int{
 	"ASCII":                      ascii,
 	"ATAN":                       atan,
 	"ATAN2":                      atan2,
+	"AUTO_ID_CACHE":              autoIdCache,
 	"AUTO_INCREMENT":             autoIncrement,
+	"AUTO_RANDOM":                autoRandom,
 	"AVG":                        avg,
 	"AVG_ROW_LENGTH":             avgRowLength,
 	"BEGIN":                      begin,
@@ -164,6 +168,7 @@ var tokenMap = map[string]int{
 	"BINLOG":                     binlog,
 	"BOTH":                       both,
 	"BTREE":                      btree,
+	"CANCEL":                     cancel,
 	"BY":                         by,
 	"BYTE":                       byteType,
 	"CASE":                       caseKwd,
@@ -175,6 +180,7 @@ var tokenMap = map[string]int{
 	"CHARSET":                    charsetKwd,
 	"CHECK":                      check,
 	"CHECKSUM":                   checksum,
+	"CLUSTERED":                  clustered,
 	"COALESCE":                   coalesce,
 	"COLLATE":                    collate,
 	"COLLATION":                  collation,
@@ -202,6 +208,7 @@ var tokenMap = map[string]int{
 	"CURDATE":                    curDate,
 	"UTC_DATE":                   utcDate,
 	"UTC_TIMESTAMP":              utcTimestamp,
+	"CURRENT":                    current,
 	"CURRENT_DATE":               currentDate,
 	"CURTIME":                    curTime,
 	"CURRENT_TIME":               currentTime,
@@ -225,11 +232,13 @@ var tokenMap = map[string]int{
 	"DELAYED":                    delayed,
 	"DELAY_KEY_WRITE":            delayKeyWrite,
 	"DELETE":                     deleteKwd,
+	"DENSE_RANK":                 denseRank,
 	"DESC":                       desc,
 	"DESCRIBE":                   describe,
 	"DISABLE":                    disable,
 	"DISTINCT":                   distinct,
 	"TIDB_SMJ":                   tidbSMJ,
+	"TIDB_INLJ":                  tidbINLJ,
 	"DIV":                        div,
 	"DO":                         do,
 	"DROP":                       drop,
@@ -260,7 +269,9 @@ var tokenMap = map[string]int{
 	"FIND_IN_SET":                findInSet,
 	"FIRST":                      first,
 	"FIXED":                      fixed,
+	"FLASHBACK":                  flashback,
 	"FOREIGN":                    foreign,
+	"FOLLOWING":                  following,
 	"FOR":                        forKwd,
 	"FORCE":                      force,
 	"FORMAT":                     format,
@@ -281,6 +292,8 @@ var tokenMap = map[string]int{
 	"GREATEST":                   greatest,
 	"GROUP":                      group,
 	"GROUP_CONCAT":               groupConcat,
+	"JSON_ARRAYAGG":              jsonArrayAgg,
+	"JSON_OBJECTAGG":             jsonObjectAgg,
 	"HASH":                       hash,
 	"HAVING":                     having,
 	"HIGH_PRIORITY":              highPriority,
@@ -288,6 +301,7 @@ var tokenMap = map[string]int{
 	"HEX":                        hex,
 	"UNHEX":                      unhex,
 	"IDENTIFIED":                 identified,
+	"INVISIBLE":                  invisible,
 	"IGNORE":                     ignore,
 	"IF":                         ifKwd,
 	"IFNULL":                     ifNull,
@@ -304,12 +318,16 @@ var tokenMap = map[string]int{
 	"IS":                         is,
 	"ISNULL":                     isNull,
 	"ISOLATION":                  isolation,
+	"JOB":                        job,
+	"JOBS":                       jobs,
 	"JOIN":                       join,
 	"KEY":                        key,
 	"KEY_BLOCK_SIZE":             keyBlockSize,
 	"KEYS":                       keys,
+	"LAG":                        lag,
 	"LAST_INSERT_ID":             lastInsertID,
 	"LEADING":                    leading,
+	"LEAD":                       lead,
 	"LEAST":                      least,
 	"LEFT":                       left,
 	"LENGTH":                     length,
@@ -324,6 +342,7 @@ var tokenMap = map[string]int{
 	"LOCAL":                      local,
 	"LOCATE":                     locate,
 	"LOCK":                       lock,
+	"LOCKED":                     locked,
 	"LOG":                        log,
 	"LOG2":                       log2,
 	"LOG10":                      log10,
@@ -337,6 +356,7 @@ var tokenMap = map[string]int{
 	"MAKE_SET":                   makeSet,
 	"MAX":                        max,
 	"MAXVALUE":                   maxValue,
+	"MAX_CONCURRENCY":            maxConcurrency,
 	"MAX_ROWS":                   maxRows,
 	"MICROSECOND":                microsecond,
 	"MID":                        mid,
@@ -350,48 +370,64 @@ var tokenMap = map[string]int{
 	"MONTHNAME":                  monthname,
 	"NAMES":                      names,
 	"NATIONAL":                   national,
+	"NONCLUSTERED":               nonclustered,
 	"NONE":                       none,
 	"NOT":                        not,
+	"NOWAIT":                     nowait,
 	"NO_WRITE_TO_BINLOG":         noWriteToBinLog,
 	"NULL":                       null,
 	"NULLIF":                     nullIf,
 	"OCT":                        oct,
 	"OCTET_LENGTH":               octetLength,
+	"OF":                         of,
 	"OFFSET":                     offset,
 	"ON":                         on,
 	"ONLY":                       only,
+	"OPTIMISTIC":                 optimistic,
 	"OPTION":                     option,
 	"OR":                         or,
 	"ORD":                        ord,
 	"ORDER":                      order,
 	"OUTER":                      outer,
+	"OVER":                       over,
 	"PASSWORD":                   password,
 	"PERIOD_ADD":                 periodAdd,
 	"PERIOD_DIFF":                periodDiff,
+	"PESSIMISTIC":                pessimistic,
 	"PI":                         pi,
 	"POSITION":                   position,
 	"POW":                        pow,
 	"POWER":                      power,
 	"PREPARE":                    prepare,
+	"PRE_SPLIT_REGIONS":          preSplitRegions,
 	"PRIMARY":                    primary,
 	"PRIVILEGES":                 privileges,
 	"PROCEDURE":                  procedure,
+	"PROCESS":                    process,
 	"PROCESSLIST":                processlist,
 	"QUARTER":                    quarter,
+	"QUEUE_SIZE":                 queueSize,
 	"QUICK":                      quick,
 	"RADIANS":                    radians,
 	"QUERY":                      query,
 	"QUOTE":                      quote,
 	"RANGE":                      rangeKwd,
+	"RANK":                       rank,
 	"RAND":                       rand,
 	"READ":                       read,
+	"RECOVER":                    recover,
+	"REGIONS":                    regions,
 	"REDUNDANT":                  redundant,
+	"REMOVE":                     remove,
+	"RECURSIVE":                  recursive,
 	"REFERENCES":                 references,
 	"REGEXP":                     regexpKwd,
+	"RELEASE":                    release,
 	"RELEASE_LOCK":               releaseLock,
 	"RENAME":                     rename,
 	"REPEAT":                     repeat,
 	"REPEATABLE":                 repeatable,
+	"RESOURCE":                   resource,
 	"REPLACE":                    replace,
 	"REVOKE":                     revoke,
 	"RIGHT":                      right,
@@ -400,30 +436,39 @@ var tokenMap = map[string]int{
 	"ROUND":                      round,
 	"ROW":                        row,
 	"ROW_FORMAT":                 rowFormat,
+	"ROWS":                       rows,
+	"ROW_NUMBER":                 rowNumber,
+	"RU_PER_SEC":                 ruPerSec,
 	"RTRIM":                      rtrim,
 	"REVERSE":                    reverse,
+	"SAVEPOINT":                  savepoint,
 	"SCHEMA":                     schema,
 	"SCHEMAS":                    schemas,
 	"SEC_TO_TIME":                secToTime,
 	"SECOND":                     second,
 	"SELECT":                     selectKwd,
+	"SEPARATOR":                  separator,
 	"SERIALIZABLE":               serializable,
 	"SESSION":                    session,
 	"SET":                        set,
 	"SHARE":                      share,
+	"SHARD_ROW_ID_BITS":          shardRowIDBits,
 	"SHOW":                       show,
 	"SLEEP":                      sleep,
 	"SIGN":                       sign,
 	"SIGNED":                     signed,
 	"SIN":                        sin,
+	"SKIP":                       skip,
 	"SNAPSHOT":                   snapshot,
 	"SOME":                       some,
 	"SPACE":                      space,
+	"SPLIT":                      split,
 	"SQRT":                       sqrt,
 	"START":                      start,
 	"STARTING":                   starting,
 	"STATS_PERSISTENT":           statsPersistent,
 	"STATUS":                     status,
+	"STATS":                      stats,
 	"SUBDATE":                    subDate,
 	"SUBTIME":                    subTime,
 	"STRCMP":                     strcmp,
@@ -432,10 +477,12 @@ var tokenMap = map[string]int{
 	"SUBSTRING":                  substring,
 	"SUBSTRING_INDEX":            substringIndex,
 	"SUM":                        sum,
+	"SUPER":                      super,
 	"SYSDATE":                    sysDate,
 	"TIDB":                       tidb,
 	"TABLE":                      tableKwd,
 	"TABLES":                     tables,
+	"TEMPORARY":                  temporary,
 	"TAN":                        tan,
 	"TERMINATED":                 terminated,
 	"TIMEDIFF":                   timediff,
@@ -454,6 +501,10 @@ var tokenMap = map[string]int{
 	"TRIM":                       trim,
 	"TRUE":                       trueKwd,
 	"TRUNCATE":                   truncate,
+	"TTL":                        ttl,
+	"TTL_ENABLE":                 ttlEnable,
+	"TTL_JOB_INTERVAL":           ttlJobInterval,
+	"UNBOUNDED":                  unbounded,
 	"UNCOMMITTED":                uncommitted,
 	"UNKNOWN":                    unknown,
 	"UNION":                      union,
@@ -473,6 +524,7 @@ var tokenMap = map[string]int{
 	"VARIABLES":                  variables,
 	"VERSION":                    version,
 	"VIEW":                       view,
+	"VISIBLE":                    visible,
 	"WARNINGS":                   warnings,
 	"WEEK":                       week,
 	"WEEKDAY":                    weekday,
@@ -503,6 +555,7 @@ var tokenMap = map[string]int{
 	"NUMERIC":                    numericType,
 	"FLOAT":                      floatType,
 	"DOUBLE":                     doubleType,
+	"PRECEDING":                  preceding,
 	"PRECISION":                  precisionType,
 	"REAL":                       realType,
 	"DATE":                       dateType,
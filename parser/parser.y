@@ -97,6 +97,7 @@ import (
 	describe		"DESCRIBE"
 	distinct		"DISTINCT"
 	tidbSMJ			"TIDB_SMJ"
+	tidbINLJ		"TIDB_INLJ"
 	div 			"DIV"
 	doubleType		"DOUBLE"
 	drop			"DROP"
@@ -162,12 +163,14 @@ import (
 	numericType		"NUMERIC"
 	oct			"OCT"
 	octetLength		"OCTET_LENGTH"
+	of			"OF"
 	on			"ON"
 	option			"OPTION"
 	or			"OR"
 	ord			"ORD"
 	order			"ORDER"
 	outer			"OUTER"
+	over			"OVER"
 	partition		"PARTITION"
 	partitions		"PARTITIONS"
 	position		"POSITION"
@@ -178,8 +181,10 @@ import (
 	rangeKwd		"RANGE"
 	read			"READ"
 	realType		"REAL"
+	recursive		"RECURSIVE"
 	references		"REFERENCES"
 	regexpKwd		"REGEXP"
+	release			"RELEASE"
 	rename         		"RENAME"
 	repeat			"REPEAT"
 	replace			"REPLACE"
@@ -230,6 +235,7 @@ import (
 	addDate				"ADDDATE"
 	addTime				"ADDTIME"
 	admin				"ADMIN"
+	approxCountDistinct		"APPROX_COUNT_DISTINCT"
 	aesDecrypt			"AES_DECRYPT"
 	benchmark			"BENCHMARK"
 	aesEncrypt			"AES_ENCRYPT"
@@ -259,6 +265,7 @@ import (
 	dayofweek			"DAYOFWEEK"
 	dayofyear			"DAYOFYEAR"
 	degrees				"DEGREES"
+	denseRank			"DENSE_RANK"
 	fromDays			"FROM_DAYS"
 	events				"EVENTS"
 	exp				"EXP"
@@ -274,6 +281,8 @@ import (
 	getFormat			"GET_FORMAT"
 	grant				"GRANT"
 	groupConcat			"GROUP_CONCAT"
+	jsonArrayAgg			"JSON_ARRAYAGG"
+	jsonObjectAgg			"JSON_OBJECTAGG"
 	greatest			"GREATEST"
 	hour				"HOUR"
 	hex				"HEX"
@@ -298,6 +307,8 @@ import (
 	ltrim				"LTRIM"
 	makeDate			"MAKEDATE"
 	makeTime			"MAKETIME"
+	lag				"LAG"
+	lead				"LEAD"
 	max				"MAX"
 	microsecond			"MICROSECOND"
 	min				"MIN"
@@ -314,7 +325,9 @@ import (
 	query				"QUERY"
 	rand				"RAND"
 	radians				"RADIANS"
+	rank				"RANK"
 	rowCount			"ROW_COUNT"
+	rowNumber			"ROW_NUMBER"
 	secToTime			"SEC_TO_TIME"
 	second				"SECOND"
 	sessionUser			"SESSION_USER"
@@ -405,11 +418,14 @@ import (
 
 	/* the following tokens belong to UnReservedKeyword*/
 	action		"ACTION"
+	advice		"ADVICE"
 	after		"AFTER"
 	any 		"ANY"
 	ascii		"ASCII"
 	at		"AT"
+	autoIdCache	"AUTO_ID_CACHE"
 	autoIncrement	"AUTO_INCREMENT"
+	autoRandom	"AUTO_RANDOM"
 	avgRowLength	"AVG_ROW_LENGTH"
 	avg		"AVG"
 	begin		"BEGIN"
@@ -419,8 +435,10 @@ import (
 	boolType	"BOOL"
 	btree		"BTREE"
 	byteType	"BYTE"
+	cancel		"CANCEL"
 	charsetKwd	"CHARSET"
 	checksum	"CHECKSUM"
+	clustered	"CLUSTERED"
 	collation	"COLLATION"
 	columns		"COLUMNS"
 	comment 	"COMMENT"
@@ -431,6 +449,7 @@ import (
 	compression	"COMPRESSION"
 	connection 	"CONNECTION"
 	consistent	"CONSISTENT"
+	current		"CURRENT"
 	data 		"DATA"
 	dateType	"DATE"
 	datetimeType	"DATETIME"
@@ -449,52 +468,81 @@ import (
 	fields		"FIELDS"
 	first		"FIRST"
 	fixed		"FIXED"
+	flashback	"FLASHBACK"
+	following	"FOLLOWING"
 	flush		"FLUSH"
 	full		"FULL"
 	function	"FUNCTION"
 	hash		"HASH"
 	identified	"IDENTIFIED"
+	invisible	"INVISIBLE"
 	isolation	"ISOLATION"
+	job		"JOB"
+	jobs		"JOBS"
 	indexes		"INDEXES"
 	keyBlockSize	"KEY_BLOCK_SIZE"
 	local		"LOCAL"
 	less		"LESS"
 	level		"LEVEL"
+	locked		"LOCKED"
 	mode		"MODE"
 	modify		"MODIFY"
+	maxConcurrency	"MAX_CONCURRENCY"
 	maxRows		"MAX_ROWS"
 	minRows		"MIN_ROWS"
 	names		"NAMES"
 	national	"NATIONAL"
 	no		"NO"
+	nonclustered	"NONCLUSTERED"
 	none		"NONE"
+	nowait		"NOWAIT"
 	offset		"OFFSET"
 	only		"ONLY"
+	optimistic	"OPTIMISTIC"
 	password	"PASSWORD"
+	pessimistic	"PESSIMISTIC"
+	preceding	"PRECEDING"
 	prepare		"PREPARE"
+	preSplitRegions	"PRE_SPLIT_REGIONS"
 	privileges	"PRIVILEGES"
+	process		"PROCESS"
 	processlist	"PROCESSLIST"
 	quarter		"QUARTER"
+	queueSize	"QUEUE_SIZE"
 	quick		"QUICK"
+	recover		"RECOVER"
 	redundant	"REDUNDANT"
+	regions		"REGIONS"
+	remove		"REMOVE"
 	repeatable	"REPEATABLE"
+	resource	"RESOURCE"
 	reverse		"REVERSE"
 	rollback	"ROLLBACK"
 	row 		"ROW"
 	rowFormat	"ROW_FORMAT"
+	rows		"ROWS"
+	ruPerSec	"RU_PER_SEC"
+	savepoint	"SAVEPOINT"
+	separator	"SEPARATOR"
 	serializable	"SERIALIZABLE"
 	session		"SESSION"
 	share		"SHARE"
+	shardRowIDBits	"SHARD_ROW_ID_BITS"
 	signed		"SIGNED"
+	skip		"SKIP"
 	snapshot	"SNAPSHOT"
 	space 		"SPACE"
+	split		"SPLIT"
 	sqlCache	"SQL_CACHE"
 	sqlNoCache	"SQL_NO_CACHE"
 	start		"START"
 	status		"STATUS"
+	stats		"STATS"
+	super		"SUPER"
 	some 		"SOME"
 	global		"GLOBAL"
 	tables		"TABLES"
+	temporary	"TEMPORARY"
 	textType	"TEXT"
 	than		"THAN"
 	tidb		"TIDB"
@@ -504,12 +552,17 @@ import (
 	transaction	"TRANSACTION"
 	triggers	"TRIGGERS"
 	truncate	"TRUNCATE"
+	ttl		"TTL"
+	ttlEnable	"TTL_ENABLE"
+	ttlJobInterval	"TTL_JOB_INTERVAL"
+	unbounded	"UNBOUNDED"
 	uncommitted	"UNCOMMITTED"
 	unknown 	"UNKNOWN"
 	user		"USER"
 	value		"VALUE"
 	variables	"VARIABLES"
 	view		"VIEW"
+	visible		"VISIBLE"
 	warnings	"WARNINGS"
 	week		"WEEK"
 	yearType	"YEAR"
@@ -544,9 +597,15 @@ import (
 
 %type   <item>
 	AdminStmt		"Check table statement or show ddl statement"
+	AsOfClause		"AS OF TIMESTAMP clause of a table"
+	CommonTableExpr		"Common table expression"
+	CommonTableExprList	"Common table expression list"
+	CTEColumnList		"Column name list of common table expression"
+	CTEColumnListOpt	"Optional column name list of common table expression"
 	AlterTableStmt		"Alter table statement"
 	AlterTableSpec		"Alter table specification"
 	AlterTableSpecList	"Alter table specification list"
+	AlterResourceGroupStmt	"ALTER RESOURCE GROUP statement"
 	AlterUserStmt		"Alter user statement"
 	AnalyzeTableStmt	"Analyze table statement"
 	AnyOrAll		"Any or All for subquery"
@@ -580,10 +639,15 @@ import (
 	DatabaseOptionList	"CREATE Database specification list"
 	DatabaseOptionListOpt	"CREATE Database specification list opt"
 	CreateTableStmt		"CREATE TABLE statement"
+	CreateTableSelect	"CREATE TABLE ... SELECT query"
+	CreateTableSelectOpt	"CREATE TABLE ... SELECT optional query"
+	CreateResourceGroupStmt	"CREATE RESOURCE GROUP statement"
 	CreateUserStmt		"CREATE User statement"
 	DBName			"Database Name"
 	DeallocateStmt		"Deallocate prepared statement"
 	DefaultValueExpr	"DefaultValueExpr(Now or Signed Literal)"
+	DefaultValueParenExpr	"the expression default value"
+	AlterColumnDefaultValue	"the default value of ALTER COLUMN SET DEFAULT"
 	DeleteFromStmt		"DELETE FROM statement"
 	DistinctOpt		"Distinct option"
 	DoStmt			"Do statement"
@@ -592,6 +656,7 @@ import (
 	DropTableStmt		"DROP TABLE statement"
 	DropUserStmt		"DROP USER"
 	DropViewStmt		"DROP VIEW statement"
+	DropResourceGroupStmt	"DROP RESOURCE GROUP statement"
 	EmptyStmt		"empty statement"
 	Enclosed		"Enclosed by"
 	EqOpt			"= or empty"
@@ -617,6 +682,7 @@ import (
 	TableRefsClause		"Table references clause"
 	Function		"function expr"
 	FunctionCallAgg		"Function call on aggregate data"
+	FunctionCallWindow	"Function call with a window"
 	FunctionCallConflict	"Function call with reserved keyword as function name"
 	FunctionCallKeyword	"Function call with keyword as function name"
 	FunctionCallNonKeyword	"Function call with nonkeyword as function name"
@@ -627,6 +693,7 @@ import (
 	HashString		"Hashed string"
 	HavingClause		"HAVING clause"
 	IfExists		"If Exists"
+	IgnoreLines		"Ignore num(int) lines"
 	IfNotExists		"If Not Exists"
 	IgnoreOptional		"IGNORE or empty"
 	IndexColName		"Index column name"
@@ -641,6 +708,9 @@ import (
 	IndexOption		"Index Option"
 	IndexOptionList		"Index Option List or empty"
 	IndexType		"index type"
+	IndexVisibility		"index visibility"
+	PrimaryKeyType		"primary key type"
+	PrimaryKeyTypeOpt	"primary key type option"
 	IndexTypeOpt		"Optional index type"
 	InsertIntoStmt		"INSERT INTO statement"
 	InsertValues		"Rest part of INSERT/REPLACE INTO statement"
@@ -655,11 +725,13 @@ import (
 	LinesTerminated		"Lines terminated by"
 	Literal			"literal value"
 	LoadDataStmt		"Load data statement"
+	LoadStatsStmt		"Load statistics statement"
 	LocalOpt		"Local opt"
 	LockTablesStmt		"Lock tables statement"
 	LowPriorityOptional	"LOW_PRIORITY or empty"
 	NotOpt			"optional NOT"
 	NumLiteral		"Num/Int/Float/Decimal Literal"
+	NumList			"Num list"
 	NoWriteToBinLogAliasOpt "NO_WRITE_TO_BINLOG alias LOCAL or empty"
 	NowSymOptionFraction	"NowSym with optional fraction part"
 	ObjectType		"Grant statement object type"
@@ -667,6 +739,7 @@ import (
 	Operand			"operand"
 	OptFull			"Full or empty"
 	Order			"ORDER BY clause optional collation specification"
+	OptGConcatSeparator	"optional SEPARATOR of GROUP_CONCAT"
 	OrderBy			"ORDER BY clause"
 	ByItem			"BY item"
 	OrderByOptional		"Optional ORDER BY clause optional"
@@ -693,16 +766,29 @@ import (
 	OnUpdateOpt		"optional ON UPDATE clause"
 	ReferOpt		"reference option"
 	RenameTableStmt         "rename table statement"
+	RecoverTableStmt	"recover table statement"
+	FlashbackTableStmt	"flashback table statement"
+	FlashbackToNewName	"optional new name of flashback table statement"
+	RecursiveOpt		"Optional RECURSIVE keyword of WITH clause"
 	ReplaceIntoStmt		"REPLACE INTO statement"
 	ReplacePriority		"replace statement priority"
+	ReleaseSavepointStmt	"RELEASE SAVEPOINT statement"
+	ResourceGroupName	"resource group name"
+	ResourceGroupOption	"resource group option"
+	ResourceGroupOptionList	"resource group option list"
+	ResourceGroupOptionListOpt	"optional resource group option list"
 	RevokeStmt		"Revoke statement"
 	RollbackStmt		"ROLLBACK statement"
+	SavepointStmt		"SAVEPOINT statement"
+	SplitRegionStmt		"split region statement"
+	SplitOption		"split region option"
 	RowFormat		"Row format option"
 	SelectLockOpt		"FOR UPDATE or LOCK IN SHARE MODE,"
 	SelectStmt		"SELECT statement"
 	SelectStmtCalcFoundRows	"SELECT statement optional SQL_CALC_FOUND_ROWS"
 	SelectStmtSQLCache	"SELECT statement optional SQL_CAHCE/SQL_NO_CACHE"
 	SelectStmtDistinct	"SELECT statement optional DISTINCT clause"
+	SelectStmtPriority	"SELECT statement optional HIGH_PRIORITY/LOW_PRIORITY"
 	SelectStmtFieldList	"SELECT statement field list"
 	SelectStmtLimit		"SELECT statement optional LIMIT clause"
 	SelectStmtOpts		"Select statement options"
@@ -734,10 +820,13 @@ import (
 	TableName		"Table name"
 	TableNameList		"Table name list"
 	TableNameListOpt	"Table name list opt"
+	TableToTable		"rename table to table"
+	TableToTableList	"rename table to table by list"
 	TableOption		"create table option"
 	TableOptionList		"create table option list"
 	TableOptionListOpt	"create table option list opt"
 	TableRef 		"table reference"
+	TemporaryOpt		"TEMPORARY or empty"
 	TableRefs 		"table references"
 	TrimDirection		"Trim string direction"
 	TruncateTableStmt	"TRANSACTION TABLE statement"
@@ -761,7 +850,14 @@ import (
 	WhereClauseOptional	"Optional WHERE clause"
 	WhenClause		"When clause"
 	WhenClauseList		"When clause list"
+	WindowFrameBound	"Window frame bound"
+	WindowFrameOpt		"Optional window frame clause"
+	WindowFrameUnit		"Window frame unit, ROWS or RANGE"
+	WindowPartitionByOpt	"Optional PARTITION BY clause of a window"
+	WindowSpec		"OVER clause of a window function"
+	WithClause		"WITH clause"
 	WithReadLockOpt		"With Read Lock opt"
+	WithSelectStmt		"Select or union statement with WITH clause"
 	WithGrantOptionOpt	"With Grant Option opt"
 	ElseOpt			"Optional else clause"
 	ExpressionOpt		"Optional expression"
@@ -988,7 +1084,7 @@ AlterTableSpec:
 			NewColumn: 	$4.(*ast.ColumnDef),
 		}
 	}
-|	"ALTER" ColumnKeywordOpt ColumnName "SET" "DEFAULT" SignedLiteral
+|	"ALTER" ColumnKeywordOpt ColumnName "SET" "DEFAULT" AlterColumnDefaultValue
 	{
 		option := &ast.ColumnOption{Expr: $6.(ast.ExprNode)}
 		$$ = &ast.AlterTableSpec{
@@ -1028,6 +1124,18 @@ AlterTableSpec:
 			Tp:    		ast.AlterTableLock,
 		}
 	}
+|	"REMOVE" "TTL"
+	{
+		$$ = &ast.AlterTableSpec{Tp: ast.AlterTableRemoveTTL}
+	}
+|	"ALTER" "INDEX" Identifier IndexVisibility
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:		ast.AlterTableIndexVisibility,
+			Name:		$3,
+			Visibility:	$4.(ast.IndexVisibility),
+		}
+	}
 
 
 KeyOrIndex: "KEY" | "INDEX"
@@ -1085,13 +1193,56 @@ Symbol:
  * See http://dev.mysql.com/doc/refman/5.7/en/rename-table.html
  *******************************************************************************************/
 RenameTableStmt:
-	 "RENAME" "TABLE" TableName "TO" TableName
-	 {
-		$$ = &ast.RenameTableStmt{
-			OldTable: $3.(*ast.TableName), 
-			NewTable: $5.(*ast.TableName),
-		}	
-	 }
+	"RENAME" "TABLE" TableToTableList
+	{
+		$$ = &ast.RenameTableStmt{TableToTables: $3.([]*ast.TableToTable)}
+	}
+
+TableToTableList:
+	TableToTable
+	{
+		$$ = []*ast.TableToTable{$1.(*ast.TableToTable)}
+	}
+|	TableToTableList ',' TableToTable
+	{
+		$$ = append($1.([]*ast.TableToTable), $3.(*ast.TableToTable))
+	}
+
+TableToTable:
+	TableName "TO" TableName
+	{
+		$$ = &ast.TableToTable{
+			OldTable:	$1.(*ast.TableName),
+			NewTable:	$3.(*ast.TableName),
+		}
+	}
+
+/*******************************************************************************************/
+
+RecoverTableStmt:
+	"RECOVER" "TABLE" "BY" "JOB" NUM
+	{
+		$$ = &ast.RecoverTableStmt{JobID: int64(getUint64FromNUM($5))}
+	}
+|	"RECOVER" "TABLE" TableName
+	{
+		$$ = &ast.RecoverTableStmt{Table: $3.(*ast.TableName)}
+	}
+
+FlashbackTableStmt:
+	"FLASHBACK" "TABLE" TableName FlashbackToNewName
+	{
+		$$ = &ast.FlashbackTableStmt{Table: $3.(*ast.TableName), NewName: $4.(string)}
+	}
+
+FlashbackToNewName:
+	{
+		$$ = ""
+	}
+|	"TO" Identifier
+	{
+		$$ = $2
+	}
 
 /*******************************************************************************************/
 
@@ -1101,6 +1252,39 @@ AnalyzeTableStmt:
 		$$ = &ast.AnalyzeTableStmt{TableNames: $3.([]*ast.TableName)}
 	 }
 
+/*******************************************************************************************/
+
+SplitRegionStmt:
+	"SPLIT" "TABLE" TableName SplitOption
+	{
+		$$ = &ast.SplitRegionStmt{
+			Table:    $3.(*ast.TableName),
+			SplitOpt: $4.(*ast.SplitOption),
+		}
+	}
+|	"SPLIT" "TABLE" TableName "INDEX" Identifier SplitOption
+	{
+		$$ = &ast.SplitRegionStmt{
+			Table:     $3.(*ast.TableName),
+			IndexName: model.NewCIStr($5),
+			SplitOpt:  $6.(*ast.SplitOption),
+		}
+	}
+
+SplitOption:
+	"BETWEEN" '(' ExpressionList ')' "AND" '(' ExpressionList ')' "REGIONS" NUM
+	{
+		$$ = &ast.SplitOption{
+			Lower: $3.([]ast.ExprNode),
+			Upper: $7.([]ast.ExprNode),
+			Num:   int64(getUint64FromNUM($10)),
+		}
+	}
+|	"BY" ExpressionListList
+	{
+		$$ = &ast.SplitOption{ValueLists: $2.([][]ast.ExprNode)}
+	}
+
 /*******************************************************************************************/
 Assignment:
 	ColumnName eq Expression
@@ -1130,6 +1314,14 @@ BeginTransactionStmt:
 	{
 		$$ = &ast.BeginStmt{}
 	}
+|	"BEGIN" "PESSIMISTIC"
+	{
+		$$ = &ast.BeginStmt{Mode: ast.Pessimistic}
+	}
+|	"BEGIN" "OPTIMISTIC"
+	{
+		$$ = &ast.BeginStmt{Mode: ast.Optimistic}
+	}
 |	"START" "TRANSACTION"
 	{
 		$$ = &ast.BeginStmt{}
@@ -1208,12 +1400,16 @@ ColumnOption:
 	{
 		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionAutoIncrement}
 	}
-|	PrimaryOpt "KEY"
+|	"AUTO_RANDOM" OptFieldLen
+	{
+		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionAutoRandom, AutoRandomBitLength: $2.(int)}
+	}
+|	PrimaryOpt "KEY" PrimaryKeyTypeOpt
 	{
 		// KEY is normally a synonym for INDEX. The key attribute PRIMARY KEY
 		// can also be specified as just KEY when given in a column definition.
 		// See http://dev.mysql.com/doc/refman/5.7/en/create-table.html
-		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionPrimaryKey}
+		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionPrimaryKey, PrimaryKeyTp: $3.(ast.PrimaryKeyType)}
 	}
 |	"UNIQUE" %prec lowerThanKey
 	{
@@ -1458,8 +1654,23 @@ ReferOpt:
  * See http://dev.mysql.com/doc/refman/5.7/en/create-table.html
  *      https://github.com/mysql/mysql-server/blob/5.7/sql/sql_yacc.yy#L6832
  */
+AlterColumnDefaultValue:
+	SignedLiteral | DefaultValueParenExpr
+
 DefaultValueExpr:
-	NowSymOptionFraction | SignedLiteral
+	NowSymOptionFraction | SignedLiteral | DefaultValueParenExpr
+
+DefaultValueParenExpr:
+	'(' Expression ')'
+	{
+		// The expression default value is evaluated every time the default value is used.
+		// See https://dev.mysql.com/doc/refman/8.0/en/data-type-defaults.html
+		startOffset := parser.startOffset(&yyS[yypt-1])
+		endOffset := parser.endOffset(&yyS[yypt])
+		expr := $2.(ast.ExprNode)
+		expr.SetText(parser.src[startOffset:endOffset])
+		$$ = &ast.ParenthesesExpr{Expr: expr}
+	}
 
 NowSymOptionFraction:
 	NowSym
@@ -1532,6 +1743,14 @@ IndexColName:
 		//Order is parsed but just ignored as MySQL did
 		$$ = &ast.IndexColName{Column: $1.(*ast.ColumnName), Length: $2.(int)}
 	}
+|	'(' Expression ')' Order
+	{
+		startOffset := parser.startOffset(&yyS[yypt-2])
+		endOffset := parser.endOffset(&yyS[yypt-1])
+		expr := $2.(ast.ExprNode)
+		expr.SetText(parser.src[startOffset:endOffset])
+		$$ = &ast.IndexColName{Expr: expr, Length: types.UnspecifiedLength}
+	}
 
 IndexColNameList:
 	{
@@ -1616,9 +1835,9 @@ DatabaseOptionList:
  *      )
  *******************************************************************/
 CreateTableStmt:
-	"CREATE" "TABLE" IfNotExists TableName '(' TableElementList ')' TableOptionListOpt PartitionOpt
+	"CREATE" TemporaryOpt "TABLE" IfNotExists TableName '(' TableElementList ')' TableOptionListOpt PartitionOpt CreateTableSelectOpt
 	{
-		tes := $6.([]interface {})
+		tes := $7.([]interface {})
 		var columnDefs []*ast.ColumnDef
 		var constraints []*ast.Constraint
 		for _, te := range tes {
@@ -1629,27 +1848,61 @@ CreateTableStmt:
 				constraints = append(constraints, te)
 			}
 		}
-		if len(columnDefs) == 0 {
+		if len(columnDefs) == 0 && $11 == nil {
 			yylex.Errorf("Column Definition List can't be empty.")
 			return 1
 		}
-		$$ = &ast.CreateTableStmt{
-			Table:          $4.(*ast.TableName),
-			IfNotExists:    $3.(bool),
+		stmt := &ast.CreateTableStmt{
+			IsTemporary:    $2.(bool),
+			Table:          $5.(*ast.TableName),
+			IfNotExists:    $4.(bool),
 			Cols:           columnDefs,
 			Constraints:    constraints,
-			Options:        $8.([]*ast.TableOption),
+			Options:        $9.([]*ast.TableOption),
+		}
+		if $11 != nil {
+			stmt.Select = $11.(ast.ResultSetNode)
+		}
+		$$ = stmt
+	}
+|	"CREATE" TemporaryOpt "TABLE" IfNotExists TableName TableOptionListOpt CreateTableSelect
+	{
+		$$ = &ast.CreateTableStmt{
+			IsTemporary:    $2.(bool),
+			Table:          $5.(*ast.TableName),
+			IfNotExists:    $4.(bool),
+			Options:        $6.([]*ast.TableOption),
+			Select:         $7.(ast.ResultSetNode),
 		}
 	}
-|	"CREATE" "TABLE" IfNotExists TableName "LIKE" TableName
+|	"CREATE" TemporaryOpt "TABLE" IfNotExists TableName "LIKE" TableName
 	{
 		$$ = &ast.CreateTableStmt{
-			Table:          $4.(*ast.TableName),
-			ReferTable:	$6.(*ast.TableName),
-			IfNotExists:    $3.(bool),
+			IsTemporary:    $2.(bool),
+			Table:          $5.(*ast.TableName),
+			ReferTable:	$7.(*ast.TableName),
+			IfNotExists:    $4.(bool),
 		}
 	}
 
+/* The query without AS can't start with '(', which starts the column definitions. */
+CreateTableSelect:
+	"AS" SelectStmt
+	{
+		$$ = $2
+	}
+|	"AS" UnionStmt
+	{
+		$$ = $2
+	}
+|	SelectStmt
+
+CreateTableSelectOpt:
+	{
+		$$ = nil
+	}
+|	CreateTableSelect
+
 DefaultKwdOpt:
 	{}
 |	"DEFAULT"
@@ -1773,13 +2026,13 @@ DropIndexStmt:
 	}
 
 DropTableStmt:
-	"DROP" TableOrTables TableNameList
+	"DROP" TemporaryOpt TableOrTables TableNameList
 	{
-		$$ = &ast.DropTableStmt{Tables: $3.([]*ast.TableName)}
+		$$ = &ast.DropTableStmt{IsTemporary: $2.(bool), Tables: $4.([]*ast.TableName)}
 	}
-|	"DROP" TableOrTables "IF" "EXISTS" TableNameList
+|	"DROP" TemporaryOpt TableOrTables "IF" "EXISTS" TableNameList
 	{
-		$$ = &ast.DropTableStmt{IfExists: true, Tables: $5.([]*ast.TableName)}
+		$$ = &ast.DropTableStmt{IsTemporary: $2.(bool), IfExists: true, Tables: $6.([]*ast.TableName)}
 	}
 
 DropViewStmt:
@@ -1802,6 +2055,15 @@ TableOrTables:
 	"TABLE"
 |	"TABLES"
 
+TemporaryOpt:
+	{
+		$$ = false
+	}
+|	"TEMPORARY"
+	{
+		$$ = true
+	}
+
 EqOpt:
 	{}
 |	eq
@@ -1839,6 +2101,19 @@ ExplainStmt:
 	{
 		$$ = &ast.ExplainStmt{Stmt: $2.(ast.StmtNode)}
 	}
+|	ExplainSym "ANALYZE" ExplainableStmt
+	{
+		$$ = &ast.ExplainStmt{
+			Stmt:		$3.(ast.StmtNode),
+			Analyze:	true,
+		}
+	}
+|	ExplainSym "FOR" "CONNECTION" NUM
+	{
+		$$ = &ast.ExplainForStmt{
+			ConnectionID: getUint64FromNUM($4),
+		}
+	}
 
 LengthNum:
 	NUM
@@ -2199,6 +2474,10 @@ IndexOptionList:
 				opt1.Comment = opt2.Comment
 			} else if opt2.Tp != 0 {
 				opt1.Tp = opt2.Tp
+			} else if opt2.Visibility != ast.IndexVisibilityDefault {
+				opt1.Visibility = opt2.Visibility
+			} else if opt2.PrimaryKeyTp != ast.PrimaryKeyTypeDefault {
+				opt1.PrimaryKeyTp = opt2.PrimaryKeyTp
 			}
 			$$ = opt1
 		}
@@ -2225,6 +2504,47 @@ IndexOption:
 			Comment: $2,
 		}
 	}
+|	IndexVisibility
+	{
+		$$ = &ast.IndexOption {
+			Visibility: $1.(ast.IndexVisibility),
+		}
+	}
+|	PrimaryKeyType
+	{
+		$$ = &ast.IndexOption {
+			PrimaryKeyTp: $1.(ast.PrimaryKeyType),
+		}
+	}
+
+IndexVisibility:
+	"VISIBLE"
+	{
+		$$ = ast.IndexVisibilityVisible
+	}
+|	"INVISIBLE"
+	{
+		$$ = ast.IndexVisibilityInvisible
+	}
+
+PrimaryKeyType:
+	"CLUSTERED"
+	{
+		$$ = ast.PrimaryKeyTypeClustered
+	}
+|	"NONCLUSTERED"
+	{
+		$$ = ast.PrimaryKeyTypeNonClustered
+	}
+
+PrimaryKeyTypeOpt:
+	{
+		$$ = ast.PrimaryKeyTypeDefault
+	}
+|	PrimaryKeyType
+	{
+		$$ = $1
+	}
 
 IndexType:
 	"USING" "BTREE"
@@ -2261,9 +2581,14 @@ UnReservedKeyword:
 | "TRANSACTION" | "TRUNCATE" | "UNKNOWN" | "VALUE" | "WARNINGS" | "YEAR" | "MODE"  | "WEEK"  | "ANY" | "SOME" | "USER" | "IDENTIFIED"
 | "COLLATION" | "COMMENT" | "AVG_ROW_LENGTH" | "CONNECTION" | "CHECKSUM" | "COMPRESSION" | "KEY_BLOCK_SIZE" | "MAX_ROWS"
 | "MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
-| "REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESSLIST"
+| "REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESS" | "PROCESSLIST"
 | "SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY" | "EVENTS" | "PARTITIONS"
-| "TIMESTAMPDIFF" | "NONE"
+| "TIMESTAMPDIFF" | "NONE" | "STATS" | "ADVICE" | "SEPARATOR" | "TEMPORARY" | "JOBS" | "CANCEL"
+| "AUTO_ID_CACHE" | "AUTO_RANDOM" | "REMOVE" | "TTL" | "TTL_ENABLE" | "TTL_JOB_INTERVAL" | "VISIBLE" | "INVISIBLE"
+| "RECOVER" | "FLASHBACK" | "JOB" | "CLUSTERED" | "NONCLUSTERED" | "PESSIMISTIC" | "OPTIMISTIC"
+| "SHARD_ROW_ID_BITS" | "PRE_SPLIT_REGIONS" | "SPLIT" | "REGIONS" | "SAVEPOINT" | "NOWAIT" | "SKIP" | "LOCKED" | "QUERY"
+| "RESOURCE" | "MAX_CONCURRENCY" | "RU_PER_SEC" | "QUEUE_SIZE" | "SUPER" | "CURRENT" | "FOLLOWING" | "PRECEDING"
+| "ROWS" | "UNBOUNDED"
 
 ReservedKeyword:
 "ADD" | "ALL" | "ALTER" | "ANALYZE" | "AND" | "AS" | "ASC" | "BETWEEN" | "BIGINT"
@@ -2278,8 +2603,8 @@ ReservedKeyword:
 | "INTERVAL" | "IS" | "JOIN" | "KEY" | "KEYS" | "KILL" | "LEADING" | "LEFT" | "LIKE" | "LIMIT" | "LINES" | "LOAD"
 | "LOCALTIME" | "LOCALTIMESTAMP" | "LOCK" | "LONGBLOB" | "LONGTEXT" | "MAXVALUE" | "MEDIUMBLOB" | "MEDIUMINT" | "MEDIUMTEXT"
 | "MINUTE_MICROSECOND" | "MINUTE_SECOND" | "MOD" | "NOT" | "NO_WRITE_TO_BINLOG" | "NULL" | "NUMERIC"
-| "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "PARTITION" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "RANGE" | "READ" 
-| "REAL" | "REFERENCES" | "REGEXP" | "RENAME" | "REPEAT" | "REPLACE" | "RESTRICT" | "REVOKE" | "RIGHT" | "RLIKE"
+| "OF" | "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "OVER" | "PARTITION" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "RANGE" | "READ" 
+| "REAL" | "RECURSIVE" | "REFERENCES" | "REGEXP" | "RELEASE" | "RENAME" | "REPEAT" | "REPLACE" | "RESTRICT" | "REVOKE" | "RIGHT" | "RLIKE"
 | "SCHEMA" | "SCHEMAS" | "SECOND_MICROSECOND" | "SELECT" | "SET" | "SHOW" | "SMALLINT"
 | "STARTING" | "TABLE" | "TERMINATED" | "THEN" | "TINYBLOB" | "TINYINT" | "TINYTEXT" | "TO"
 | "TRAILING" | "TRUE" | "UNION" | "UNIQUE" | "UNLOCK" | "UNSIGNED"
@@ -2291,14 +2616,15 @@ ReservedKeyword:
 
 
 NotKeywordToken:
-	"ABS" | "ACOS" | "ADDTIME" | "ADDDATE" | "ADMIN" | "ASIN" | "ATAN" | "ATAN2" | "BENCHMARK" | "BIN" | "COALESCE" | "COERCIBILITY" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CONVERT_TZ" | "CUR_TIME"| "COS" | "COT" | "COUNT" | "DAY"
+	"ABS" | "ACOS" | "ADDTIME" | "ADDDATE" | "ADMIN" | "APPROX_COUNT_DISTINCT" | "ASIN" | "ATAN" | "ATAN2" | "BENCHMARK" | "BIN" | "COALESCE" | "COERCIBILITY" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CONVERT_TZ" | "CUR_TIME"| "COS" | "COT" | "COUNT" | "DAY"
 |	"DATEDIFF" | "DATE_ADD" | "DATE_FORMAT" | "DATE_SUB" | "DAYNAME" | "DAYOFMONTH" | "DAYOFWEEK" | "DAYOFYEAR" | "DEGREES" | "ELT" | "EXP" | "EXPORT_SET" | "FROM_DAYS" | "FROM_BASE64" | "FIND_IN_SET" | "FOUND_ROWS"
-|	"GET_FORMAT" | "GROUP_CONCAT" | "GREATEST" | "LEAST" | "HOUR" | "HEX" | "UNHEX" | "IFNULL" | "INSTR" | "ISNULL" | "LAST_INSERT_ID" | "LCASE" | "LENGTH" | "LOAD_FILE" | "LOCATE" | "LOWER" | "LPAD" | "LTRIM"
+|	"GET_FORMAT" | "GROUP_CONCAT" | "GREATEST" | "JSON_ARRAYAGG" | "JSON_OBJECTAGG" | "LEAST" | "HOUR" | "HEX" | "UNHEX" | "IFNULL" | "INSTR" | "ISNULL" | "LAST_INSERT_ID" | "LCASE" | "LENGTH" | "LOAD_FILE" | "LOCATE" | "LOWER" | "LPAD" | "LTRIM"
 |	"MAKE_SET" | "MAX" | "MAKEDATE" | "MAKETIME" | "MICROSECOND" | "MID" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" |  "OCT" | "OCTET_LENGTH" | "ORD" | "POSITION" | "PERIOD_ADD" | "PERIOD_DIFF" | "PI" | "POW" | "POWER" | "RAND" | "RADIANS" | "ROW_COUNT"
 	"QUOTE" | "SEC_TO_TIME" | "SECOND" | "SIGN" | "SIN" | "SLEEP" | "SQRT" | "SQL_CALC_FOUND_ROWS" | "STR_TO_DATE" | "SUBTIME" | "SUBDATE" | "SUBSTRING" %prec lowerThanLeftParen |
 	"SESSION_USER" | "SUBSTRING_INDEX" | "SUM" | "SYSTEM_USER" | "TAN" | "TIME_FORMAT" | "TIME_TO_SEC" | "TIMESTAMPADD" | "TO_DAYS" | "TO_SECONDS" | "TRIM" | "RTRIM" | "UCASE" | "UTC_TIME" | "UPPER" | "VERSION" | "WEEKDAY" | "WEEKOFYEAR" | "YEARWEEK" | "ROUND"
 |	"STATS_PERSISTENT" | "GET_LOCK" | "RELEASE_LOCK" | "CEIL" | "CEILING" | "FLOOR" | "FROM_UNIXTIME" | "TIMEDIFF" | "LN" | "LOG" | "LOG2" | "LOG10" | "FIELD_KWD"
 |	"AES_DECRYPT" | "AES_ENCRYPT" | "QUOTE"
+|	"ROW_NUMBER" | "RANK" | "DENSE_RANK" | "LAG" | "LEAD"
 |	"ANY_VALUE" | "INET_ATON" | "INET_NTOA" | "INET6_ATON" | "INET6_NTOA" | "IS_FREE_LOCK" | "IS_IPV4" | "IS_IPV4_COMPAT" | "IS_IPV4_MAPPED" | "IS_IPV6" | "IS_USED_LOCK" | "MASTER_POS_WAIT" | "NAME_CONST" | "RELEASE_ALL_LOCKS" | "UUID" | "UUID_SHORT"
 |	"ASYMMETRIC_DECRYPT" | "ASYMMETRIC_DERIVE" | "ASYMMETRIC_ENCRYPT" | "ASYMMETRIC_SIGN" | "ASYMMETRIC_VERIFY" | "COMPRESS" | "CREATE_ASYMMETRIC_PRIV_KEY" | "CREATE_ASYMMETRIC_PUB_KEY" | "CREATE_DH_PARAMETERS" | "CREATE_DIGEST" | "DECODE" | "DES_DECRYPT" | "DES_ENCRYPT" | "ENCODE" | "ENCRYPT" | "MD5" | "OLD_PASSWORD" | "RANDOM_BYTES" | "SHA1" | "SHA" | "SHA2" | "UNCOMPRESS" | "UNCOMPRESSED_LENGTH" | "VALIDATE_PASSWORD_STRENGTH"
 
@@ -2639,6 +2965,7 @@ Function:
 |	FunctionCallNonKeyword
 |	FunctionCallConflict
 |	FunctionCallAgg
+|	FunctionCallWindow
 
 FunctionNameConflict:
 	"DATABASE"
@@ -4009,7 +4336,11 @@ TrimDirection:
 	}
 
 FunctionCallAgg:
-	"AVG" '(' DistinctOpt Expression ')'
+	"APPROX_COUNT_DISTINCT" '(' ExpressionList ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: $3.([]ast.ExprNode)}
+	}
+|	"AVG" '(' DistinctOpt Expression ')'
 	{
 		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}, Distinct: $3.(bool)}
 	}
@@ -4034,9 +4365,21 @@ FunctionCallAgg:
 		args := []ast.ExprNode{ast.NewValueExpr(1)}
 		$$ = &ast.AggregateFuncExpr{F: $1, Args: args}
 	}
-|	"GROUP_CONCAT" '(' DistinctOpt ExpressionList ')'
+|	"GROUP_CONCAT" '(' DistinctOpt ExpressionList OrderByOptional OptGConcatSeparator ')'
+	{
+		agg := &ast.AggregateFuncExpr{F: $1, Args: $4.([]ast.ExprNode), Distinct: $3.(bool), Separator: $6.(string)}
+		if $5 != nil {
+			agg.Order = $5.(*ast.OrderByClause)
+		}
+		$$ = agg
+	}
+|	"JSON_ARRAYAGG" '(' Expression ')'
+	{
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"JSON_OBJECTAGG" '(' Expression ',' Expression ')'
 	{
-		$$ = &ast.AggregateFuncExpr{F: $1, Args: $4.([]ast.ExprNode), Distinct: $3.(bool)}
+		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
 	}
 |	"MAX" '(' DistinctOpt Expression ')'
 	{
@@ -4051,6 +4394,119 @@ FunctionCallAgg:
 		$$ = &ast.AggregateFuncExpr{F: $1, Args: []ast.ExprNode{$4.(ast.ExprNode)}, Distinct: $3.(bool)}
 	}
 
+FunctionCallWindow:
+	"ROW_NUMBER" '(' ')' WindowSpec
+	{
+		$$ = &ast.WindowFuncExpr{F: $1, Spec: $4.(ast.WindowSpec)}
+	}
+|	"RANK" '(' ')' WindowSpec
+	{
+		$$ = &ast.WindowFuncExpr{F: $1, Spec: $4.(ast.WindowSpec)}
+	}
+|	"DENSE_RANK" '(' ')' WindowSpec
+	{
+		$$ = &ast.WindowFuncExpr{F: $1, Spec: $4.(ast.WindowSpec)}
+	}
+|	"LAG" '(' ExpressionList ')' WindowSpec
+	{
+		$$ = &ast.WindowFuncExpr{F: $1, Args: $3.([]ast.ExprNode), Spec: $5.(ast.WindowSpec)}
+	}
+|	"LEAD" '(' ExpressionList ')' WindowSpec
+	{
+		$$ = &ast.WindowFuncExpr{F: $1, Args: $3.([]ast.ExprNode), Spec: $5.(ast.WindowSpec)}
+	}
+|	FunctionCallAgg WindowSpec
+	{
+		agg := $1.(*ast.AggregateFuncExpr)
+		if agg.Distinct || agg.Order != nil {
+			yylex.Errorf("DISTINCT and ORDER BY are not supported in window function %s", agg.F)
+			return 1
+		}
+		$$ = &ast.WindowFuncExpr{F: agg.F, Args: agg.Args, Spec: $2.(ast.WindowSpec)}
+	}
+
+WindowSpec:
+	"OVER" '(' WindowPartitionByOpt OrderByOptional WindowFrameOpt ')'
+	{
+		spec := ast.WindowSpec{PartitionBy: $3.([]ast.ExprNode)}
+		if $4 != nil {
+			spec.OrderBy = $4.(*ast.OrderByClause)
+			// An integer in the order by items of a window is a constant rather than a position.
+			for _, item := range spec.OrderBy.Items {
+				if position, ok := item.Expr.(*ast.PositionExpr); ok {
+					item.Expr = ast.NewValueExpr(int64(position.N))
+				}
+			}
+		}
+		if $5 != nil {
+			spec.Frame = $5.(*ast.FrameClause)
+		}
+		$$ = spec
+	}
+
+WindowPartitionByOpt:
+	{
+		$$ = []ast.ExprNode{}
+	}
+|	"PARTITION" "BY" ExpressionList
+	{
+		$$ = $3
+	}
+
+WindowFrameOpt:
+	{
+		$$ = nil
+	}
+|	WindowFrameUnit WindowFrameBound
+	{
+		$$ = &ast.FrameClause{Type: $1.(ast.FrameType), Start: $2.(ast.FrameBound), End: ast.FrameBound{Type: ast.CurrentRow}}
+	}
+|	WindowFrameUnit "BETWEEN" WindowFrameBound "AND" WindowFrameBound
+	{
+		$$ = &ast.FrameClause{Type: $1.(ast.FrameType), Start: $3.(ast.FrameBound), End: $5.(ast.FrameBound)}
+	}
+
+WindowFrameUnit:
+	"ROWS"
+	{
+		$$ = ast.Rows
+	}
+|	"RANGE"
+	{
+		$$ = ast.Ranges
+	}
+
+WindowFrameBound:
+	"UNBOUNDED" "PRECEDING"
+	{
+		$$ = ast.FrameBound{Type: ast.UnboundedPreceding}
+	}
+|	"UNBOUNDED" "FOLLOWING"
+	{
+		$$ = ast.FrameBound{Type: ast.UnboundedFollowing}
+	}
+|	"CURRENT" "ROW"
+	{
+		$$ = ast.FrameBound{Type: ast.CurrentRow}
+	}
+|	LengthNum "PRECEDING"
+	{
+		$$ = ast.FrameBound{Type: ast.Preceding, Num: int64($1.(uint64))}
+	}
+|	LengthNum "FOLLOWING"
+	{
+		$$ = ast.FrameBound{Type: ast.Following, Num: int64($1.(uint64))}
+	}
+
+OptGConcatSeparator:
+	{
+		$$ = ","
+	}
+|	"SEPARATOR" stringLit
+	{
+		$$ = $2
+	}
+
 FuncDatetimePrec:
 	{
 		$$ = nil
@@ -4440,12 +4896,36 @@ RollbackStmt:
 	{
 		$$ = &ast.RollbackStmt{}
 	}
+|	"ROLLBACK" "TO" Identifier
+	{
+		$$ = &ast.RollbackStmt{SavepointName: $3}
+	}
+|	"ROLLBACK" "TO" "SAVEPOINT" Identifier
+	{
+		$$ = &ast.RollbackStmt{SavepointName: $4}
+	}
+
+/*
+ * See https://dev.mysql.com/doc/refman/5.7/en/savepoint.html
+ */
+SavepointStmt:
+	"SAVEPOINT" Identifier
+	{
+		$$ = &ast.SavepointStmt{Name: $2}
+	}
+
+ReleaseSavepointStmt:
+	"RELEASE" "SAVEPOINT" Identifier
+	{
+		$$ = &ast.ReleaseSavepointStmt{Name: $3}
+	}
 
 SelectStmt:
 	"SELECT" SelectStmtOpts SelectStmtFieldList SelectStmtLimit SelectLockOpt
 	{
 		st := &ast.SelectStmt {
 			Distinct:      $2.(*ast.SelectStmtOpts).Distinct,
+			Priority:      $2.(*ast.SelectStmtOpts).Priority,
 			Fields:        $3.(*ast.FieldList),
 			LockTp:	       $5.(ast.SelectLockType),
 		}
@@ -4474,6 +4954,7 @@ SelectStmt:
 	{
 		st := &ast.SelectStmt {
 			Distinct:      $2.(*ast.SelectStmtOpts).Distinct,
+			Priority:      $2.(*ast.SelectStmtOpts).Priority,
 			Fields:        $3.(*ast.FieldList),
 			LockTp:	       $7.(ast.SelectLockType),
 		}
@@ -4497,6 +4978,7 @@ SelectStmt:
 		opts := $2.(*ast.SelectStmtOpts)
 		st := &ast.SelectStmt{
 			Distinct:		opts.Distinct,
+			Priority:		opts.Priority,
 			Fields:		$3.(*ast.FieldList),
 			From:		$5.(*ast.TableRefsClause),
 			LockTp:		$11.(ast.SelectLockType),
@@ -4591,6 +5073,20 @@ TableFactor:
 		tn.IndexHints = $3.([]*ast.IndexHint)
 		$$ = &ast.TableSource{Source: tn, AsName: $2.(model.CIStr)}
 	}
+|	TableName AsOfClause IndexHintListOpt
+	{
+		tn := $1.(*ast.TableName)
+		tn.AsOf = $2.(*ast.AsOfClause)
+		tn.IndexHints = $3.([]*ast.IndexHint)
+		$$ = &ast.TableSource{Source: tn}
+	}
+|	TableName TableAsName AsOfClause IndexHintListOpt
+	{
+		tn := $1.(*ast.TableName)
+		tn.AsOf = $3.(*ast.AsOfClause)
+		tn.IndexHints = $4.([]*ast.IndexHint)
+		$$ = &ast.TableSource{Source: tn, AsName: $2.(model.CIStr)}
+	}
 |	'(' SelectStmt ')' TableAsName
 	{
 		st := $2.(*ast.SelectStmt)
@@ -4607,6 +5103,12 @@ TableFactor:
 		$$ = $2
 	}
 
+AsOfClause:
+	"AS" "OF" "TIMESTAMP" Expression
+	{
+		$$ = &ast.AsOfClause{TsExpr: $4.(ast.ExprNode)}
+	}
+
 TableAsNameOpt:
 	{
 		$$ = model.CIStr{}
@@ -4792,8 +5294,22 @@ SelectStmtDistinct:
 		$$ = true
 	}
 
+SelectStmtPriority:
+	/* EMPTY */
+	{
+		$$ = ast.NoPriority
+	}
+|	"HIGH_PRIORITY"
+	{
+		$$ = ast.HighPriority
+	}
+|	"LOW_PRIORITY"
+	{
+		$$ = ast.LowPriority
+	}
+
 SelectStmtOpts:
-	TableOptimizerHints SelectStmtDistinct SelectStmtSQLCache SelectStmtCalcFoundRows
+	TableOptimizerHints SelectStmtDistinct SelectStmtPriority SelectStmtSQLCache SelectStmtCalcFoundRows
 	{
 		opt := &ast.SelectStmtOpts{}
 		if $1 != nil {
@@ -4802,11 +5318,12 @@ SelectStmtOpts:
 		if $2 != nil {
 		    opt.Distinct = $2.(bool)
 		}
-		if $3 != nil {
-		    opt.SQLCache = $3.(bool)
-		}
+		opt.Priority = $3.(int)
 		if $4 != nil {
-		    opt.CalcFoundRows = $4.(bool)
+		    opt.SQLCache = $4.(bool)
+		}
+		if $5 != nil {
+		    opt.CalcFoundRows = $5.(bool)
 		}
 
 		$$ = opt
@@ -4847,6 +5364,10 @@ TableOptimizerHintOpt:
 	{
 		$$ = &ast.TableOptimizerHint{HintName: model.NewCIStr($1), Tables: $3.([]model.CIStr)}
 	}
+|	tidbINLJ '(' HintTableList ')'
+	{
+		$$ = &ast.TableOptimizerHint{HintName: model.NewCIStr($1), Tables: $3.([]model.CIStr)}
+	}
 
 SelectStmtCalcFoundRows:
 	%prec lowerThanCalcFoundRows
@@ -4915,11 +5436,91 @@ SelectLockOpt:
 	{
 		$$ = ast.SelectLockForUpdate
 	}
+|	"FOR" "UPDATE" "NOWAIT"
+	{
+		$$ = ast.SelectLockForUpdateNoWait
+	}
+|	"FOR" "UPDATE" "SKIP" "LOCKED"
+	{
+		$$ = ast.SelectLockForUpdateSkipLocked
+	}
 |	"LOCK" "IN" "SHARE" "MODE"
 	{
 		$$ = ast.SelectLockInShareMode
 	}
 
+// See https://dev.mysql.com/doc/refman/8.0/en/with.html
+WithSelectStmt:
+	WithClause SelectStmt
+	{
+		st := $2.(*ast.SelectStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+|	WithClause UnionStmt
+	{
+		st := $2.(*ast.UnionStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+
+WithClause:
+	"WITH" RecursiveOpt CommonTableExprList
+	{
+		$$ = &ast.WithClause{
+			IsRecursive:	$2.(bool),
+			CTEs:		$3.([]*ast.CommonTableExpression),
+		}
+	}
+
+RecursiveOpt:
+	{
+		$$ = false
+	}
+|	"RECURSIVE"
+	{
+		$$ = true
+	}
+
+CommonTableExprList:
+	CommonTableExpr
+	{
+		$$ = []*ast.CommonTableExpression{$1.(*ast.CommonTableExpression)}
+	}
+|	CommonTableExprList ',' CommonTableExpr
+	{
+		$$ = append($1.([]*ast.CommonTableExpression), $3.(*ast.CommonTableExpression))
+	}
+
+CommonTableExpr:
+	Identifier CTEColumnListOpt "AS" SubSelect
+	{
+		$$ = &ast.CommonTableExpression{
+			Name:		model.NewCIStr($1),
+			ColNames:	$2.([]model.CIStr),
+			Query:		$4.(*ast.SubqueryExpr),
+		}
+	}
+
+CTEColumnListOpt:
+	{
+		$$ = []model.CIStr{}
+	}
+|	'(' CTEColumnList ')'
+	{
+		$$ = $2.([]model.CIStr)
+	}
+
+CTEColumnList:
+	Identifier
+	{
+		$$ = []model.CIStr{model.NewCIStr($1)}
+	}
+|	CTEColumnList ',' Identifier
+	{
+		$$ = append($1.([]model.CIStr), model.NewCIStr($3))
+	}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/union.html
 UnionStmt:
 	UnionClauseList "UNION" UnionOpt SelectStmt
@@ -5195,6 +5796,17 @@ AdminStmt:
 	{
 		$$ = &ast.AdminStmt{Tp: ast.AdminShowDDL}
 	}
+|	"ADMIN" "SHOW" "DDL" "JOBS"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowDDLJobs}
+	}
+|	"ADMIN" "CANCEL" "DDL" "JOBS" NumList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminCancelDDLJobs,
+			JobIDs:	$5.([]int64),
+		}
+	}
 |	"ADMIN" "CHECK" "TABLE" TableNameList
 	{
 		$$ = &ast.AdminStmt{
@@ -5202,6 +5814,27 @@ AdminStmt:
 			Tables: $4.([]*ast.TableName),
 		}
 	}
+|	"ADMIN" "CHECKSUM" "TABLE" TableNameList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminChecksumTable,
+			Tables: $4.([]*ast.TableName),
+		}
+	}
+|	"ADMIN" "SHOW" "INDEX" "ADVICE"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowIndexAdvice}
+	}
+
+NumList:
+	intLit
+	{
+		$$ = []int64{int64(getUint64FromNUM($1))}
+	}
+|	NumList ',' intLit
+	{
+		$$ = append($1.([]int64), int64(getUint64FromNUM($3)))
+	}
 
 /****************************Show Statement*******************************/
 ShowStmt:
@@ -5244,10 +5877,11 @@ ShowStmt:
 			User:	$4.(string),
 		}
 	}
-|	"SHOW" "PROCESSLIST"
+|	"SHOW" OptFull "PROCESSLIST"
 	{
 		$$ = &ast.ShowStmt{
-			Tp: ast.ShowProcessList,
+			Tp:	ast.ShowProcessList,
+			Full:	$2.(bool),
 		}
 	}
 
@@ -5499,6 +6133,7 @@ Statement:
 	EmptyStmt
 |	AdminStmt
 |	AlterTableStmt
+|	AlterResourceGroupStmt
 |	AlterUserStmt
 |	AnalyzeTableStmt
 |	BeginTransactionStmt
@@ -5511,30 +6146,39 @@ Statement:
 |	CreateDatabaseStmt
 |	CreateIndexStmt
 |	CreateTableStmt
+|	CreateResourceGroupStmt
 |	CreateUserStmt
 |	DoStmt
 |	DropDatabaseStmt
 |	DropIndexStmt
 |	DropTableStmt
 |	DropViewStmt
+|	DropResourceGroupStmt
 |	DropUserStmt
 |	FlushStmt
 |	GrantStmt
 |	InsertIntoStmt
 |	KillStmt
 |	LoadDataStmt
+|	LoadStatsStmt
 |	PreparedStmt
 |	RollbackStmt
+|	ReleaseSavepointStmt
 |	RenameTableStmt
+|	RecoverTableStmt
+|	FlashbackTableStmt
 |	ReplaceIntoStmt
 |	RevokeStmt
+|	SavepointStmt
 |	SelectStmt
 |	UnionStmt
 |	SetStmt
+|	SplitRegionStmt
 |	ShowStmt
 |	TruncateTableStmt
 |	UpdateStmt
 |	UseStmt
+|	WithSelectStmt
 |	SubSelect
 	{
 		// `(select 1)`; is a valid select statement
@@ -5551,6 +6195,7 @@ ExplainableStmt:
 |	InsertIntoStmt
 |	ReplaceIntoStmt
 |	UnionStmt
+|	WithSelectStmt
 
 StatementList:
 	Statement
@@ -5638,6 +6283,35 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionAutoIncrement, UintValue: $3.(uint64)}
 	}
+|	"AUTO_ID_CACHE" EqOpt LengthNum
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionAutoIDCache, UintValue: $3.(uint64)}
+	}
+|	"SHARD_ROW_ID_BITS" EqOpt LengthNum
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionShardRowID, UintValue: $3.(uint64)}
+	}
+|	"PRE_SPLIT_REGIONS" EqOpt LengthNum
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionPreSplitRegion, UintValue: $3.(uint64)}
+	}
+|	"TTL" EqOpt Identifier '+' "INTERVAL" LengthNum TimeUnit
+	{
+		$$ = &ast.TableOption{
+			Tp:		ast.TableOptionTTL,
+			ColumnName:	&ast.ColumnName{Name: model.NewCIStr($3)},
+			UintValue:	$6.(uint64),
+			StrValue:	$7,
+		}
+	}
+|	"TTL_ENABLE" EqOpt stringLit
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionTTLEnable, StrValue: $3}
+	}
+|	"TTL_JOB_INTERVAL" EqOpt stringLit
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionTTLJobInterval, StrValue: $3}
+	}
 |	"COMMENT" EqOpt stringLit
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionComment, StrValue: $3}
@@ -5662,6 +6336,10 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionCompression, StrValue: $3}
 	}
+|	"COMPRESSION" EqOpt stringLit
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionCompression, StrValue: $3}
+	}
 |	"KEY_BLOCK_SIZE" EqOpt LengthNum
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionKeyBlockSize, UintValue: $3.(uint64)}
@@ -6281,6 +6959,89 @@ AlterUserStmt:
 			CurrentAuth: auth,
 		}
 	}
+|	"ALTER" "USER" IfExists UserSpecList "RESOURCE" "GROUP" ResourceGroupName
+	{
+		$$ = &ast.AlterUserStmt{
+			IfExists: $3.(bool),
+			Specs: $4.([]*ast.UserSpec),
+			ResourceGroup: $7.(string),
+		}
+	}
+
+/********************************************************************
+ * Resource Group Statements
+ *******************************************************************/
+CreateResourceGroupStmt:
+	"CREATE" "RESOURCE" "GROUP" IfNotExists ResourceGroupName ResourceGroupOptionListOpt
+	{
+		$$ = &ast.CreateResourceGroupStmt{
+			IfNotExists: $4.(bool),
+			Name: $5.(string),
+			Options: $6.([]*ast.ResourceGroupOption),
+		}
+	}
+
+AlterResourceGroupStmt:
+	"ALTER" "RESOURCE" "GROUP" ResourceGroupName ResourceGroupOptionList
+	{
+		$$ = &ast.AlterResourceGroupStmt{
+			Name: $4.(string),
+			Options: $5.([]*ast.ResourceGroupOption),
+		}
+	}
+
+DropResourceGroupStmt:
+	"DROP" "RESOURCE" "GROUP" IfExists ResourceGroupName
+	{
+		$$ = &ast.DropResourceGroupStmt{
+			IfExists: $4.(bool),
+			Name: $5.(string),
+		}
+	}
+
+ResourceGroupName:
+	Identifier
+	{
+		$$ = strings.ToLower($1)
+	}
+|	"DEFAULT"
+	{
+		$$ = ast.DefaultResourceGroup
+	}
+
+ResourceGroupOption:
+	"MAX_CONCURRENCY" EqOpt LengthNum
+	{
+		$$ = &ast.ResourceGroupOption{Tp: ast.ResourceGroupMaxConcurrency, UintValue: $3.(uint64)}
+	}
+|	"RU_PER_SEC" EqOpt LengthNum
+	{
+		$$ = &ast.ResourceGroupOption{Tp: ast.ResourceGroupRUPerSec, UintValue: $3.(uint64)}
+	}
+|	"QUEUE_SIZE" EqOpt LengthNum
+	{
+		$$ = &ast.ResourceGroupOption{Tp: ast.ResourceGroupQueueSize, UintValue: $3.(uint64)}
+	}
+
+ResourceGroupOptionList:
+	ResourceGroupOption
+	{
+		$$ = []*ast.ResourceGroupOption{$1.(*ast.ResourceGroupOption)}
+	}
+|	ResourceGroupOptionList ResourceGroupOption
+	{
+		$$ = append($1.([]*ast.ResourceGroupOption), $2.(*ast.ResourceGroupOption))
+	}
+|	ResourceGroupOptionList ',' ResourceGroupOption
+	{
+		$$ = append($1.([]*ast.ResourceGroupOption), $3.(*ast.ResourceGroupOption))
+	}
+
+ResourceGroupOptionListOpt:
+	{
+		$$ = []*ast.ResourceGroupOption{}
+	}
+|	ResourceGroupOptionList
 
 UserSpec:
 	Username AuthOption
@@ -6419,6 +7180,10 @@ PrivType:
 	{
 		$$ = mysql.InsertPriv
 	}
+|	"PROCESS"
+	{
+		$$ = mysql.ProcessPriv
+	}
 |	"SELECT"
 	{
 		$$ = mysql.SelectPriv
@@ -6427,6 +7192,10 @@ PrivType:
 	{
 		$$ = mysql.ShowDBPriv
 	}
+|	"SUPER"
+	{
+		$$ = mysql.SuperPriv
+	}
 |	"UPDATE"
 	{
 		$$ = mysql.UpdatePriv
@@ -6499,11 +7268,12 @@ RevokeStmt:
  * See https://dev.mysql.com/doc/refman/5.7/en/load-data.html
  *******************************************************************************************/
 LoadDataStmt:
-	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines
+	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines IgnoreLines
 	{
 		x := &ast.LoadDataStmt{
-			Path:       $5,
-			Table:      $8.(*ast.TableName),
+			Path:        $5,
+			Table:       $8.(*ast.TableName),
+			IgnoreLines: $11.(uint64),
 		}
 		if $3 != nil {
 			x.IsLocal = true
@@ -6517,6 +7287,18 @@ LoadDataStmt:
 		$$ = x
 	}
 
+/*********************************************************************
+ * Load Statistics Statement
+ * LOAD STATS 'file_path'
+ *********************************************************************/
+LoadStatsStmt:
+	"LOAD" "STATS" stringLit
+	{
+		$$ = &ast.LoadStatsStmt{
+			Path:	$3,
+		}
+	}
+
 LocalOpt:
 	{
 		$$ = nil 
@@ -6613,6 +7395,15 @@ LinesTerminated:
 		$$ = $3
 	}
 
+IgnoreLines:
+	{
+		$$ = uint64(0)
+	}
+|	"IGNORE" LengthNum "LINES"
+	{
+		$$ = $2
+	}
+
 
 /*********************************************************************
  * Lock/Unlock Tables
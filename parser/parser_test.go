@@ -21,6 +21,7 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -53,7 +54,7 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"localtime", "localtimestamp", "lock", "longblob", "longtext", "mediumblob", "maxvalue", "mediumint", "mediumtext",
 		"minute_microsecond", "minute_second", "mod", "not", "no_write_to_binlog", "null", "numeric",
 		"on", "option", "or", "order", "outer", "partition", "precision", "primary", "procedure", "range", "read", "real",
-		"references", "regexp", "rename", "repeat", "replace", "revoke", "restrict", "right", "rlike",
+		"recursive", "references", "regexp", "release", "rename", "repeat", "replace", "revoke", "restrict", "right", "rlike",
 		"schema", "schemas", "second_microsecond", "select", "set", "show", "smallint",
 		"starting", "table", "terminated", "then", "tinyblob", "tinyint", "tinytext", "to",
 		"trailing", "true", "union", "unique", "unlock", "unsigned",
@@ -89,8 +90,10 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"curtime", "variables", "dayname", "version", "btree", "hash", "row_format", "dynamic", "fixed", "compressed",
 		"compact", "redundant", "sql_no_cache sql_no_cache", "sql_cache sql_cache", "action", "round",
 		"enable", "disable", "reverse", "space", "privileges", "get_lock", "release_lock", "sleep", "no", "greatest", "least",
-		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist", "events", "less", "than", "timediff",
-		"ln", "log", "log2", "log10", "timestampdiff", "pi", "quote", "none",
+		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "process", "processlist", "events", "less", "than", "timediff",
+		"ln", "log", "log2", "log10", "timestampdiff", "pi", "quote", "none", "stats", "advice", "separator", "temporary", "jobs", "cancel",
+		"recover", "flashback", "job", "savepoint", "nowait", "skip", "locked", "query", "resource", "max_concurrency",
+		"ru_per_sec", "queue_size",
 	}
 	for _, kw := range unreservedKws {
 		src := fmt.Sprintf("SELECT %s FROM tbl;", kw)
@@ -244,9 +247,20 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 			FROM stuff)`, true},
 		{"BEGIN", true},
 		{"START TRANSACTION", true},
+		{"BEGIN PESSIMISTIC", true},
+		{"BEGIN OPTIMISTIC", true},
+		{"START TRANSACTION PESSIMISTIC", false},
 		// 45
 		{"COMMIT", true},
 		{"ROLLBACK", true},
+		{"SAVEPOINT sp1", true},
+		{"SAVEPOINT", false},
+		{"ROLLBACK TO sp1", true},
+		{"ROLLBACK TO SAVEPOINT sp1", true},
+		{"ROLLBACK TO savepoint", true},
+		{"ROLLBACK TO", false},
+		{"RELEASE SAVEPOINT sp1", true},
+		{"RELEASE sp1", false},
 		{`BEGIN;
 			INSERT INTO foo VALUES (42, 3.14);
 			INSERT INTO foo VALUES (-1, 2.78);
@@ -277,6 +291,10 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"load data infile '/tmp/t.csv' into table t lines starting by 'ab' terminated by 'xy'", true},
 		{"load data infile '/tmp/t.csv' into table t fields terminated by 'ab' lines terminated by 'xy'", true},
 		{"load data infile '/tmp/t.csv' into table t terminated by 'xy' fields terminated by 'ab'", false},
+		{"load data local infile '/tmp/t.csv' into table t ignore 1 lines", true},
+		{"load data infile '/tmp/t.csv' into table t fields terminated by 'ab' lines terminated by 'xy' ignore 100 lines", true},
+		{"load data infile '/tmp/t.csv' into table t ignore lines", false},
+		{"load data infile '/tmp/t.csv' into table t ignore -1 lines", false},
 		{"load data local infile '/tmp/t.csv' into table t", true},
 		{"load data local infile '/tmp/t.csv' into table t fields terminated by 'ab'", true},
 		{"load data local infile '/tmp/t.csv' into table t columns terminated by 'ab'", true},
@@ -290,6 +308,10 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		// select for update
 		{"SELECT * from t for update", true},
 		{"SELECT * from t lock in share mode", true},
+		{"SELECT * from t for update nowait", true},
+		{"SELECT * from t for update skip locked", true},
+		{"SELECT * from t for update skip", false},
+		{"SELECT * from t lock in share mode nowait", false},
 
 		// from join
 		{"SELECT * from t1, t2, t3", true},
@@ -299,7 +321,14 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 
 		// for admin
 		{"admin show ddl;", true},
+		{"admin show ddl jobs;", true},
+		{"admin cancel ddl jobs 1", true},
+		{"admin cancel ddl jobs 1, 2", true},
+		{"admin cancel ddl jobs", false},
 		{"admin check table t1, t2;", true},
+		{"admin checksum table t1, t2;", true},
+		{"admin checksum table test.t1;", true},
+		{"admin show index advice;", true},
 
 		// for on duplicate key update
 		{"INSERT INTO t (a,b,c) VALUES (1,2,3),(4,5,6) ON DUPLICATE KEY UPDATE c=VALUES(a)+VALUES(b);", true},
@@ -333,6 +362,10 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 
 		{`ANALYZE TABLE t`, true},
 
+		// for load stats
+		{`LOAD STATS '/tmp/stats.json'`, true},
+		{`LOAD STATS`, false},
+
 		// for Binlog stmt
 		{`BINLOG '
 BxSFVw8JAAAA8QAAAPUAAAAAAAQANS41LjQ0LU1hcmlhREItbG9nAAAAAAAAAAAAAAAAAAAAAAAA
@@ -930,6 +963,13 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`select count(all c1) from t;`, true},
 		{`select group_concat(c2,c1) from t group by c1;`, true},
 		{`select group_concat(distinct c2,c1) from t group by c1;`, true},
+		{`select group_concat(c2,c1 order by c1 desc, c2 separator ';') from t group by c1;`, true},
+		{`select group_concat(distinct c2 separator '') from t group by c1;`, true},
+		{`select group_concat(c2 separator c1) from t group by c1;`, false},
+		{`select json_arrayagg(c2), json_objectagg(c1, c2) from t group by c1;`, true},
+		{`select json_objectagg(c1) from t;`, false},
+		{`select approx_count_distinct(c1), approx_count_distinct(c1, c2) from t;`, true},
+		{`select approx_count_distinct(distinct c1) from t;`, false},
 
 		// for encryption and compression functions
 		{`select AES_ENCRYPT('text',UNHEX('F3229A0B371ED2D9441B830D21A390C3'))`, true},
@@ -1028,6 +1068,7 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"create table t (c int) checksum 1", true},
 		{"create table t (c int) compression = none", true},
 		{"create table t (c int) compression lz4", true},
+		{"create table t (c int) compression = 'zlib'", true},
 		{"create table t (c int) connection = 'abc'", true},
 		{"create table t (c int) connection 'abc'", true},
 		{"create table t (c int) key_block_size = 1024", true},
@@ -1077,6 +1118,9 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"drop table if exists xxx", true},
 		{"drop table if not exists xxx", false},
 		{"drop view if exists xxx", true},
+		{"drop temporary table xxx", true},
+		{"drop temporary table if exists xxx, yyy", true},
+		{"drop temporary xxx", false},
 		// for issue 974
 		{`CREATE TABLE address (
 		id bigint(20) NOT NULL AUTO_INCREMENT,
@@ -1167,15 +1211,86 @@ func (s *testParserSuite) TestDDL(c *C) {
 		PRIMARY KEY (union_name)) ENGINE=MyISAM DEFAULT CHARSET=binary;`, true},
 		// Create table with multiple index options.
 		{`create table t (c int, index ci (c) USING BTREE COMMENT "123");`, true},
+		// Create table with the auto ID cache.
+		{"create table t (c int auto_increment key) auto_id_cache 1", true},
+		{"create table t (c int auto_increment key) auto_increment=10, auto_id_cache=100", true},
+		{"alter table t auto_id_cache = 1", true},
+		{"create table auto_id_cache (auto_id_cache int)", true},
+		{"create table t (c int) shard_row_id_bits = 4", true},
+		{"create table t (c int) shard_row_id_bits 4 pre_split_regions = 2", true},
+		{"create table t (c int) pre_split_regions = 2, shard_row_id_bits = 4", true},
+		{"create table t (c int) shard_row_id_bits = -1", false},
+		{"create table shard_row_id_bits (pre_split_regions int)", true},
+		// Create table with the auto random column.
+		{"create table t (a bigint auto_random primary key, b int)", true},
+		{"create table t (a bigint auto_random(3) primary key, b int)", true},
+		{"create table t (a bigint primary key auto_random(3))", true},
+		{"create table t (auto_random int)", true},
+		{"create table t (a bigint auto_random(x) primary key)", false},
+		// Create table with the TTL options.
+		{"create table t (a int, created_at datetime) ttl = created_at + interval 3 month", true},
+		{"create table t (a int, created_at datetime) ttl `created_at` + interval 1 day ttl_enable 'off' ttl_job_interval = '1h'", true},
+		{"create table t (a int, created_at datetime) ttl = created_at + interval 1 day, ttl_enable = 'on'", true},
+		{"create table t (a int, created_at datetime) ttl = created_at + interval -1 day", false},
+		{"create table t (a int, created_at datetime) ttl = created_at + 1", false},
+		{"create table ttl (ttl int, ttl_enable int, ttl_job_interval int, remove int)", true},
+		{"alter table t ttl = created_at + interval 7 day", true},
+		{"alter table t ttl_enable = 'off'", true},
+		{"alter table t remove ttl", true},
+		// Create table and alter table with the invisible index.
+		{"create table t (a int, b int, index idx_a (a) invisible, unique key idx_b (b) comment 'b' visible)", true},
+		{"create table t (a int, index idx_a (a) using btree invisible comment 'a')", true},
+		{"create table visible (visible int, invisible int)", true},
+		{"alter table t alter index idx_a invisible", true},
+		{"alter table t alter index idx_a visible, alter index idx_b invisible", true},
+		{"alter table t alter index idx_a", false},
+		{"alter table t alter key idx_a invisible", false},
+		{"create table t (a int, key `idx_a` (`a`) /*!80000 INVISIBLE */)", true},
+		// for the clustered primary key
+		{"create table t (a int primary key clustered, b int)", true},
+		{"create table t (a varchar(10) key nonclustered, b int)", true},
+		{"create table t (a int, b int, primary key (a, b) nonclustered)", true},
+		{"create table t (a int, b int, primary key idx (a) using btree clustered comment 'pk')", true},
+		{"create table t (a int, primary key (`a`) /*T![clustered_index] NONCLUSTERED */)", true},
+		{"create table t (a int, primary key (`a`) /*T![unknown_feature] NONCLUSTERED */)", true},
+		{"create table t (a int clustered)", false},
+		{"create table clustered (clustered int, nonclustered int)", true},
+		// for expression index
+		{"create index idx on t ((lower(name)), (a + 1) desc, b)", true},
+		{"create unique index idx on t ((concat(a, '-', b)))", true},
+		{"create table t (name varchar(10), index idx ((lower(name))), unique key ((a + b)))", true},
+		{"alter table t add index idx ((lower(name)))", true},
+		{"create index idx on t (lower(name))", false},
+		{"create index idx on t (())", false},
 		// for default value
 		{"CREATE TABLE sbtest (id INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, k integer UNSIGNED DEFAULT '0' NOT NULL, c char(120) DEFAULT '' NOT NULL, pad char(60) DEFAULT '' NOT NULL, PRIMARY KEY  (id) )", true},
 		{"create table test (create_date TIMESTAMP NOT NULL COMMENT '创建日期 create date' DEFAULT now());", true},
 		{"create table ts (t int, v timestamp(3) default CURRENT_TIMESTAMP(3));", true},
+		{"create table t (a varchar(36) default (uuid()), b datetime default (date_add(now(), interval 1 day)), c int default ((1)))", true},
+		{"alter table t alter column a set default (uuid())", true},
+		{"alter table t alter column a set default ()", false},
+		{"create table t (a int default ())", false},
 		// Create table with primary key name.
 		{"create table if not exists `t` (`id` int not null auto_increment comment '消息ID', primary key `pk_id` (`id`) );", true},
 		// Create table with like.
 		{"create table a like b", true},
 		{"create table if not exists a like b", true},
+		// Create table with select.
+		{"create table a select * from b", true},
+		{"create table a as select * from b", true},
+		{"create table if not exists a engine = innodb as select c, d + 1 as e from b where c > 1", true},
+		{"create table a (c int primary key, d varchar(10)) select c from b", true},
+		{"create table a (c int) as select c from b union select c from d", true},
+		{"create table a as (select c from b) union (select c from d)", true},
+		{"create temporary table a select 1", true},
+		{"create table a as", false},
+		{"create table a like b select c from d", false},
+		// Create temporary table.
+		{"create temporary table t (a int primary key, b varchar(10), unique key (b))", true},
+		{"create temporary table if not exists t (a int) engine = memory", true},
+		{"create temporary table a like b", true},
+		{"create table temporary (temporary int)", true},
+		{"create table temporary t (a int)", false},
 		{"create table t (a timestamp default now)", false},
 		{"create table t (a timestamp default now())", true},
 		{"create table t (a timestamp default now() on update now)", false},
@@ -1205,12 +1320,46 @@ func (s *testParserSuite) TestDDL(c *C) {
 		// for rename table statement
 		{"RENAME TABLE t TO t1", true},
 		{"RENAME TABLE d.t TO d1.t1", true},
+		{"RENAME TABLE t TO t1, t1 TO t2", true},
+		{"RENAME TABLE d.t TO d1.t1, d.t2 TO d1.t3", true},
+		{"RENAME TABLE t TO t1,", false},
+
+		// for recover table statement
+		{"RECOVER TABLE t", true},
+		{"RECOVER TABLE d.t", true},
+		{"RECOVER TABLE BY JOB 11", true},
+		{"RECOVER TABLE BY JOB", false},
+		{"RECOVER TABLE t TO t1", false},
+
+		// for flashback table statement
+		{"FLASHBACK TABLE t", true},
+		{"FLASHBACK TABLE d.t TO t1", true},
+		{"FLASHBACK TABLE t TO d.t1", false},
+
+		// for split region statement
+		{"split table t between (0) and (1000000000) regions 10", true},
+		{"split table d.t between (0) and (100) regions 10", true},
+		{"split table t index idx between (1, 'a') and (2, 'b') regions 3", true},
+		{"split table t by (0), (1000), (1000000)", true},
+		{"split table t index idx by (1, 'a'), (2, 'b')", true},
+		{"split table t between (0) and (100)", false},
+		{"split table t", false},
+		{"split table t index by (1)", false},
+		{"create table split (regions int)", true},
 
 		// for truncate statement
 		{"TRUNCATE TABLE t1", true},
 		{"TRUNCATE t1", true},
 	}
 	s.RunTest(c, table)
+
+	// The text of an index expression is kept for showing the index.
+	stmt, err := New().ParseOneStmt("create index idx on t (( lower( name ) ), (a+1))", "", "")
+	c.Assert(err, IsNil)
+	colNames := stmt.(*ast.CreateIndexStmt).IndexColNames
+	c.Assert(colNames[0].Column, IsNil)
+	c.Assert(colNames[0].Expr.Text(), Equals, "lower( name )")
+	c.Assert(colNames[1].Expr.Text(), Equals, "a+1")
 }
 
 func (s *testParserSuite) TestOptimizerHints(c *C) {
@@ -1231,6 +1380,17 @@ func (s *testParserSuite) TestOptimizerHints(c *C) {
 	c.Assert(hints[1].Tables[1].L, Equals, "t4")
 
 	c.Assert(len(selectStmt.TableHints), Equals, 2)
+
+	stmt, err = parser.Parse("select /*+ TIDB_INLJ(t1, T2) */ c1, c2 from t1, t2 where t1.c1 = t2.c1", "", "")
+	c.Assert(err, IsNil)
+	selectStmt = stmt[0].(*ast.SelectStmt)
+
+	hints = selectStmt.TableHints
+	c.Assert(len(hints), Equals, 1)
+	c.Assert(hints[0].HintName.L, Equals, "tidb_inlj")
+	c.Assert(len(hints[0].Tables), Equals, 2)
+	c.Assert(hints[0].Tables[0].L, Equals, "t1")
+	c.Assert(hints[0].Tables[1].L, Equals, "t2")
 }
 
 func (s *testParserSuite) TestType(c *C) {
@@ -1289,6 +1449,19 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{`ALTER USER IF EXISTS USER() IDENTIFIED BY 'new-password'`, true},
 		{`DROP USER 'root'@'localhost', 'root1'@'localhost'`, true},
 		{`DROP USER IF EXISTS 'root'@'localhost'`, true},
+		{`ALTER USER 'root'@'localhost', 'root'@'127.0.0.1' RESOURCE GROUP rg1`, true},
+		{`ALTER USER IF EXISTS 'root'@'localhost' RESOURCE GROUP default`, true},
+		{`ALTER USER 'root'@'localhost' RESOURCE GROUP`, false},
+
+		// for resource group statements
+		{"CREATE RESOURCE GROUP rg1", true},
+		{"CREATE RESOURCE GROUP IF NOT EXISTS rg1 MAX_CONCURRENCY = 10 RU_PER_SEC = 1000 QUEUE_SIZE = 100", true},
+		{"CREATE RESOURCE GROUP rg1 MAX_CONCURRENCY 10, QUEUE_SIZE 100", true},
+		{"CREATE RESOURCE GROUP rg1 MAX_CONCURRENCY = -1", false},
+		{"ALTER RESOURCE GROUP rg1 RU_PER_SEC = 0", true},
+		{"ALTER RESOURCE GROUP rg1", false},
+		{"DROP RESOURCE GROUP rg1", true},
+		{"DROP RESOURCE GROUP IF EXISTS rg1", true},
 
 		// for grant statement
 		{"GRANT ALL ON db1.* TO 'jeffrey'@'localhost';", true},
@@ -1303,6 +1476,7 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{"GRANT SELECT (col1), INSERT (col1,col2) ON mydb.mytbl TO 'someuser'@'somehost';", true},
 		{"grant all privileges on zabbix.* to 'zabbix'@'localhost' identified by 'password';", true},
 		{"GRANT SELECT ON test.* to 'test'", true}, // For issue 2654.
+		{"GRANT PROCESS ON *.* TO 'someuser'@'somehost';", true},
 
 		// for revoke statement
 		{"REVOKE ALL ON db1.* FROM 'jeffrey'@'localhost';", true},
@@ -1376,6 +1550,33 @@ func (s *testParserSuite) TestUnion(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestCommonTableExpression(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"with cte as (select 1) select * from cte", true},
+		{"with cte (a, b) as (select 1, 2), cte2 as (select a from cte) select * from cte2", true},
+		{"with cte as (select c1 from t1 union select c2 from t2) select * from cte", true},
+		{"with recursive cte (n) as (select 1 union all select n + 1 from cte where n < 10) select * from cte", true},
+		{"with recursive cte as (select 1 as n) select * from cte union select 2", true},
+		{"explain with cte as (select 1) select * from cte", true},
+		{"with cte as select 1 select * from cte", false},
+		{"with cte () as (select 1) select * from cte", false},
+		{"with recursive select 1", false},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("with recursive cte (n) as (select 1 union all select n + 1 from cte) select * from cte", "", "")
+	c.Assert(err, IsNil)
+	sel := stmt.(*ast.SelectStmt)
+	c.Assert(sel.With.IsRecursive, IsTrue)
+	c.Assert(sel.With.CTEs, HasLen, 1)
+	cte := sel.With.CTEs[0]
+	c.Assert(cte.Name.O, Equals, "cte")
+	c.Assert(cte.ColNames, DeepEquals, []model.CIStr{model.NewCIStr("n")})
+	c.Assert(cte.Selects(), HasLen, 2)
+}
+
 func (s *testParserSuite) TestLikeEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -1416,6 +1617,87 @@ func (s *testParserSuite) TestIndexHint(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestAsOf(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select * from t as of timestamp '2017-09-01 10:00:00'`, true},
+		{`select * from t as of timestamp date_sub(now(), interval 10 second) where a > 1`, true},
+		{`select * from t as t1 as of timestamp '2017-09-01 10:00:00' use index (idx)`, true},
+		{`select * from t t1 as of timestamp '2017-09-01 10:00:00', t2 as of timestamp '2017-09-01 10:00:00'`, true},
+		{`select * from t as of '2017-09-01 10:00:00'`, false},
+		{`select * from t as of timestamp`, false},
+		{`select of from t`, false},
+	}
+	s.RunTest(c, table)
+
+	src := "select * from t as t1 as of timestamp '2017-09-01 10:00:00'"
+	st, err := New().ParseOneStmt(src, "", "")
+	c.Assert(err, IsNil)
+	ts := st.(*ast.SelectStmt).From.TableRefs.Left.(*ast.TableSource)
+	c.Assert(ts.AsName.L, Equals, "t1")
+	c.Assert(ts.Source.(*ast.TableName).AsOf, NotNil)
+}
+
+func (s *testParserSuite) TestWindowFunction(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select row_number() over () from t`, true},
+		{`select a, rank() over (partition by b order by c desc), dense_rank() over (order by c) from t`, true},
+		{`select lag(a) over (order by b), lead(a, 2, 0) over (partition by c order by b) from t`, true},
+		{`select sum(a) over (partition by b order by c rows between 1 preceding and 1 following) from t`, true},
+		{`select count(*) over (order by b range between unbounded preceding and current row) from t`, true},
+		{`select avg(a) over (rows unbounded preceding), max(a) over (rows 2 preceding) from t`, true},
+		{`select min(a) over (order by b rows between current row and unbounded following) from t`, true},
+		{`select rows, preceding, following, unbounded, current, rank, lag, lead, row_number from t`, true},
+		{`select sum(distinct a) over () from t`, false},
+		{`select group_concat(a order by b) over () from t`, false},
+		{`select row_number() from t`, false},
+		{`select rank(a) over () from t`, false},
+		{`select sum(a) over (rows between a preceding and current row) from t`, false},
+		{`select over from t`, false},
+	}
+	s.RunTest(c, table)
+
+	src := "select sum(a) over (partition by b order by 1 rows between 2 preceding and unbounded following) from t"
+	st, err := New().ParseOneStmt(src, "", "")
+	c.Assert(err, IsNil)
+	wf := st.(*ast.SelectStmt).Fields.Fields[0].Expr.(*ast.WindowFuncExpr)
+	c.Assert(wf.F, Equals, "sum")
+	c.Assert(wf.Spec.PartitionBy, HasLen, 1)
+	_, ok := wf.Spec.OrderBy.Items[0].Expr.(*ast.ValueExpr)
+	c.Assert(ok, IsTrue)
+	c.Assert(*wf.Spec.Frame, Equals, ast.FrameClause{
+		Type:  ast.Rows,
+		Start: ast.FrameBound{Type: ast.Preceding, Num: 2},
+		End:   ast.FrameBound{Type: ast.UnboundedFollowing},
+	})
+}
+
+func (s *testParserSuite) TestPriority(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select high_priority * from t`, true},
+		{`select distinct low_priority a from t where a > 1`, true},
+		{`select /*+ TIDB_INLJ(t) */ high_priority sql_no_cache * from t`, true},
+		{`select low_priority 1`, true},
+		{`select high_priority distinct * from t`, false},
+		{`select delayed * from t`, false},
+		{`insert delayed into t values (1)`, true},
+		{`update low_priority t set a = 1`, true},
+	}
+	s.RunTest(c, table)
+
+	st, err := New().ParseOneStmt("select high_priority * from t", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(st.(*ast.SelectStmt).Priority, Equals, ast.HighPriority)
+	st, err = New().ParseOneStmt("select low_priority * from t", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(st.(*ast.SelectStmt).Priority, Equals, ast.LowPriority)
+	st, err = New().ParseOneStmt("select * from t", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(st.(*ast.SelectStmt).Priority, Equals, ast.NoPriority)
+}
+
 func (s *testParserSuite) TestEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -1468,6 +1750,14 @@ func (s *testParserSuite) TestExplain(c *C) {
 		{"explain replace into foo values (1 || 2)", true},
 		{"explain update t set id = id + 1 order by id desc;", true},
 		{"explain select c1 from t1 union (select c2 from t2) limit 1, 1", true},
+		{"explain analyze select c1 from t1", true},
+		{"explain analyze delete from t1 where c1 = 1", true},
+		{"desc analyze select c1 from t1 union (select c2 from t2) limit 1, 1", true},
+		{"explain analyze t1", false},
+		{"explain for connection 42", true},
+		{"desc for connection 42", true},
+		{"explain for connection", false},
+		{"explain analyze for connection 42", false},
 	}
 	s.RunTest(c, table)
 }
@@ -1504,6 +1794,7 @@ func (s *testParserSuite) TestSessionManage(c *C) {
 		{"kill tidb connection 23123", true},
 		{"kill tidb query 23123", true},
 		{"show processlist", true},
+		{"show full processlist", true},
 	}
 	s.RunTest(c, table)
 }
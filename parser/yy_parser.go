@@ -17,6 +17,7 @@ import (
 	"math"
 	"regexp"
 	"strconv"
+	"strings"
 	"unicode"
 
 	"github.com/juju/errors"
@@ -41,8 +42,36 @@ var (
 	specCodePattern = regexp.MustCompile(`\/\*!(M?[0-9]{5,6})?([^*]|\*+[^*/])*\*+\/`)
 	specCodeStart   = regexp.MustCompile(`^\/\*!(M?[0-9]{5,6} )?[ \t]*`)
 	specCodeEnd     = regexp.MustCompile(`[ \t]*\*\/$`)
+
+	tidbFeatureCodeStart = regexp.MustCompile(`^\/\*T!\[([a-z0-9_,]+)\][ \t]*`)
+)
+
+// Feature IDs of the "/*T![feature_id] TiDB-specific-code */" comments.
+const (
+	// FeatureIDClusteredIndex is the feature ID of the CLUSTERED/NONCLUSTERED primary key option.
+	FeatureIDClusteredIndex = "clustered_index"
 )
 
+// supportedFeatureIDs are the feature IDs whose TiDB-specific code is parsed.
+var supportedFeatureIDs = map[string]struct{}{
+	FeatureIDClusteredIndex: {},
+}
+
+// tidbFeatureCodeOffset returns the offset of the code in the "/*T![feature_id] TiDB-specific-code */" comment.
+// It returns false if any of the features isn't supported.
+func tidbFeatureCodeOffset(comment string) (int, bool) {
+	loc := tidbFeatureCodeStart.FindStringSubmatchIndex(comment)
+	if loc == nil {
+		return 0, false
+	}
+	for _, id := range strings.Split(comment[loc[2]:loc[3]], ",") {
+		if _, ok := supportedFeatureIDs[id]; !ok {
+			return 0, false
+		}
+	}
+	return loc[1], true
+}
+
 func trimComment(txt string) string {
 	txt = specCodeStart.ReplaceAllString(txt, "")
 	return specCodeEnd.ReplaceAllString(txt, "")
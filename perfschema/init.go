@@ -197,6 +197,29 @@ var stagesCurrentCols = []columnInfo{
 	{mysql.TypeEnum, -1, 0, nil, []string{"TRANSACTION", "STATEMENT", "STAGE"}},
 }
 
+var stmtsSummaryByDigestCols = []columnInfo{
+	{mysql.TypeVarchar, 64, 0, nil, nil},
+	{mysql.TypeVarchar, 64, 0, nil, nil},
+	{mysql.TypeLongBlob, -1, 0, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeLonglong, 20, mysql.NotNullFlag | mysql.UnsignedFlag, nil, nil},
+	{mysql.TypeDatetime, 26, mysql.NotNullFlag, nil, nil},
+	{mysql.TypeDatetime, 26, mysql.NotNullFlag, nil, nil},
+	{mysql.TypeLongBlob, -1, 0, nil, nil},
+	{mysql.TypeLongBlob, -1, 0, nil, nil},
+}
+
 func createMemoryTable(meta *model.TableInfo, alloc autoid.Allocator) (table.Table, error) {
 	tbl, _ := tables.MemoryTableFromMeta(alloc, meta)
 	return tbl, nil
@@ -224,6 +247,12 @@ func (ps *perfSchema) buildTables() error {
 			tbl = createBoundedTable(meta, alloc, currentElemMax)
 		case TableStmtsHistory, TableStmtsHistoryLong, TableTransHistory, TableTransHistoryLong, TableStagesHistory, TableStagesHistoryLong:
 			tbl = createBoundedTable(meta, alloc, historyElemMax)
+		case TableStmtsSummaryByDigest:
+			var err error
+			tbl, err = createVirtualTable(meta, alloc, dataForStmtsSummaryByDigest)
+			if err != nil {
+				return errors.Trace(err)
+			}
 		default:
 			var err error
 			tbl, err = createMemoryTable(meta, alloc)
@@ -355,6 +384,7 @@ func (ps *perfSchema) initialize() (err error) {
 		stagesCurrentCols,
 		stagesCurrentCols, // same as above
 		stagesCurrentCols, // same as above
+		stmtsSummaryByDigestCols,
 	}
 
 	allColNames := [][]string{
@@ -373,6 +403,7 @@ func (ps *perfSchema) initialize() (err error) {
 		ColumnStagesCurrent,
 		ColumnStagesHistory,
 		ColumnStagesHistoryLong,
+		ColumnStmtsSummaryByDigest,
 	}
 
 	// initialize all table, column and result field definitions
@@ -311,7 +311,9 @@ func (ps *perfSchema) registerStatements() {
 	ps.RegisterStatement("sql", "grant", (*ast.GrantStmt)(nil))
 	ps.RegisterStatement("sql", "insert", (*ast.InsertStmt)(nil))
 	ps.RegisterStatement("sql", "prepare", (*ast.PrepareStmt)(nil))
+	ps.RegisterStatement("sql", "release_savepoint", (*ast.ReleaseSavepointStmt)(nil))
 	ps.RegisterStatement("sql", "rollback", (*ast.RollbackStmt)(nil))
+	ps.RegisterStatement("sql", "savepoint", (*ast.SavepointStmt)(nil))
 	ps.RegisterStatement("sql", "select", (*ast.SelectStmt)(nil))
 	ps.RegisterStatement("sql", "set", (*ast.SetStmt)(nil))
 	ps.RegisterStatement("sql", "set_password", (*ast.SetPwdStmt)(nil))
@@ -0,0 +1,128 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perfschema
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta/autoid"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util/stmtsummary"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// virtualTable is a table whose rows are generated when it's read by IterRecords, it can't be written.
+type virtualTable struct {
+	table.Table
+	rows func() [][]types.Datum
+}
+
+// IsVirtualTable returns whether the rows of tbl are generated when it's read, the rows can only be read by
+// IterRecords.
+func IsVirtualTable(tbl table.Table) bool {
+	_, ok := tbl.(*virtualTable)
+	return ok
+}
+
+func createVirtualTable(meta *model.TableInfo, alloc autoid.Allocator, rows func() [][]types.Datum) (table.Table, error) {
+	tbl, err := createMemoryTable(meta, alloc)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &virtualTable{Table: tbl, rows: rows}, nil
+}
+
+// IterRecords implements table.Table IterRecords interface.
+func (vt *virtualTable) IterRecords(ctx context.Context, startKey kv.Key, cols []*table.Column,
+	fn table.RecordIterFunc) error {
+	if len(startKey) != 0 {
+		return table.ErrUnsupportedOp
+	}
+	for i, fullRow := range vt.rows() {
+		row := make([]types.Datum, len(cols))
+		for j, col := range cols {
+			row[j] = fullRow[col.Offset]
+		}
+		more, err := fn(int64(i), row, cols)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !more {
+			break
+		}
+	}
+	return nil
+}
+
+// AddRecord implements table.Table AddRecord interface.
+func (vt *virtualTable) AddRecord(ctx context.Context, r []types.Datum) (int64, error) {
+	return 0, table.ErrUnsupportedOp
+}
+
+// UpdateRecord implements table.Table UpdateRecord interface.
+func (vt *virtualTable) UpdateRecord(ctx context.Context, h int64, oldData, newData []types.Datum, touched map[int]bool) error {
+	return table.ErrUnsupportedOp
+}
+
+// RemoveRecord implements table.Table RemoveRecord interface.
+func (vt *virtualTable) RemoveRecord(ctx context.Context, h int64, r []types.Datum) error {
+	return table.ErrUnsupportedOp
+}
+
+// picoseconds converts d to picoseconds, the unit of the timers in the summary tables, same as MySQL.
+func picoseconds(d time.Duration) uint64 {
+	return uint64(d.Nanoseconds()) * 1000
+}
+
+func dataForStmtsSummaryByDigest() [][]types.Datum {
+	summaries := stmtsummary.Summaries()
+	rows := make([][]types.Datum, 0, len(summaries))
+	for _, s := range summaries {
+		var schemaName, digest, digestText interface{}
+		if s.Digest != "" {
+			schemaName, digest, digestText = s.SchemaName, s.Digest, s.DigestText
+		}
+		firstSeen := types.Time{Time: types.FromGoTime(s.FirstSeen), Type: mysql.TypeDatetime, Fsp: types.MaxFsp}
+		lastSeen := types.Time{Time: types.FromGoTime(s.LastSeen), Type: mysql.TypeDatetime, Fsp: types.MaxFsp}
+		avgLatency := s.SumLatency / time.Duration(s.Count)
+		rows = append(rows, types.MakeDatums(
+			schemaName,                     // SCHEMA_NAME
+			digest,                         // DIGEST
+			digestText,                     // DIGEST_TEXT
+			s.Count,                        // COUNT_STAR
+			picoseconds(s.SumLatency),      // SUM_TIMER_WAIT
+			picoseconds(s.MinLatency),      // MIN_TIMER_WAIT
+			picoseconds(avgLatency),        // AVG_TIMER_WAIT
+			picoseconds(s.MaxLatency),      // MAX_TIMER_WAIT
+			picoseconds(s.Quantile(0.95)),  // QUANTILE_95
+			picoseconds(s.Quantile(0.99)),  // QUANTILE_99
+			picoseconds(s.Quantile(0.999)), // QUANTILE_999
+			s.Errors,                       // SUM_ERRORS
+			s.Warnings,                     // SUM_WARNINGS
+			s.SumAffectedRows,              // SUM_ROWS_AFFECTED
+			s.SumSentRows,                  // SUM_ROWS_SENT
+			s.SumExaminedRows,              // SUM_ROWS_EXAMINED
+			firstSeen,                      // FIRST_SEEN
+			lastSeen,                       // LAST_SEEN
+			s.SampleSQL,                    // QUERY_SAMPLE_TEXT
+			s.SamplePlan,                   // QUERY_SAMPLE_PLAN
+		))
+	}
+	return rows
+}
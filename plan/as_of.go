@@ -0,0 +1,146 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// GetAsOfTS gets the timestamp of the AS OF TIMESTAMP clauses of the statement, it returns 0 if the statement doesn't
+// have any. The statement reads all its tables from the snapshot at the timestamp, so it must be a SELECT statement
+// out of any transaction, and all the tables must be read at the same timestamp.
+func GetAsOfTS(ctx context.Context, node ast.StmtNode) (uint64, error) {
+	collector := &asOfCollector{}
+	node.Accept(collector)
+	if len(collector.clauses) == 0 {
+		return 0, nil
+	}
+	switch x := node.(type) {
+	case *ast.SelectStmt:
+		if x.LockTp != ast.SelectLockNone {
+			return 0, ErrAsOf.GenByArgs("the rows of a snapshot can't be locked")
+		}
+	case *ast.UnionStmt:
+	default:
+		return 0, ErrAsOf.GenByArgs("only the SELECT statements can read a snapshot")
+	}
+	if collector.withoutAsOf {
+		return 0, ErrAsOf.GenByArgs("all the tables must be read at the same timestamp")
+	}
+	vars := ctx.GetSessionVars()
+	if vars.InTxn() {
+		return 0, ErrAsOf.GenByArgs("a snapshot can't be read in a transaction")
+	}
+	if vars.SnapshotTS != 0 {
+		return 0, ErrAsOf.GenByArgs("a snapshot can't be read when tidb_snapshot is set")
+	}
+	var readTS uint64
+	for _, clause := range collector.clauses {
+		ts, err := evalAsOfTS(ctx, clause.TsExpr)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if readTS != 0 && ts != readTS {
+			return 0, ErrAsOf.GenByArgs("all the tables must be read at the same timestamp")
+		}
+		readTS = ts
+	}
+	return readTS, nil
+}
+
+// evalAsOfTS evaluates the expression of an AS OF TIMESTAMP clause to the timestamp of the store.
+func evalAsOfTS(ctx context.Context, expr ast.ExprNode) (uint64, error) {
+	checker := &asOfExprChecker{}
+	expr.Accept(checker)
+	if checker.invalid {
+		return 0, ErrAsOf.GenByArgs("the timestamp can't refer to any column or subquery")
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	if err := InferType(sc, expr); err != nil {
+		return 0, errors.Trace(err)
+	}
+	val, err := evalAstExpr(expr, ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	ft := types.NewFieldType(mysql.TypeDatetime)
+	ft.Decimal = types.MaxFsp
+	val, err = val.ConvertTo(sc, ft)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if val.IsNull() {
+		return 0, ErrAsOf.GenByArgs("the timestamp is NULL")
+	}
+	loc := ctx.GetSessionVars().TimeZone
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := val.GetMysqlTime().Time.GoTime(loc)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if t.After(time.Now()) {
+		return 0, ErrAsOf.GenByArgs("the timestamp is in the future")
+	}
+	return varsutil.GoTimeToTS(t), nil
+}
+
+// asOfCollector collects the AS OF TIMESTAMP clauses of the tables of a statement.
+type asOfCollector struct {
+	clauses []*ast.AsOfClause
+	// withoutAsOf is true if any table of the statement doesn't have the clause.
+	withoutAsOf bool
+}
+
+func (c *asOfCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok {
+		if tn.AsOf != nil {
+			c.clauses = append(c.clauses, tn.AsOf)
+		} else {
+			c.withoutAsOf = true
+		}
+	}
+	return in, false
+}
+
+func (c *asOfCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// asOfExprChecker checks if the expression of an AS OF TIMESTAMP clause refers to any column or subquery, the
+// expression is evaluated before the statement is planned.
+type asOfExprChecker struct {
+	invalid bool
+}
+
+func (c *asOfExprChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch in.(type) {
+	case *ast.ColumnNameExpr, *ast.SubqueryExpr:
+		c.invalid = true
+		return in, true
+	}
+	return in, false
+}
+
+func (c *asOfExprChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
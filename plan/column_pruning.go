@@ -133,6 +133,34 @@ func (p *Sort) PruneColumns(parentUsedCols []*expression.Column) {
 	p.SetSchema(p.children[0].Schema())
 }
 
+// PruneColumns implements LogicalPlan interface.
+func (p *Window) PruneColumns(parentUsedCols []*expression.Column) {
+	child := p.children[0].(LogicalPlan)
+	var selfUsedCols []*expression.Column
+	// The columns of the window functions are not passed to the child.
+	for _, col := range parentUsedCols {
+		if child.Schema().ColumnIndex(col) != -1 {
+			selfUsedCols = append(selfUsedCols, col)
+		}
+	}
+	for _, f := range p.WindowFuncs {
+		for _, arg := range f.Args {
+			selfUsedCols = append(selfUsedCols, expression.ExtractColumns(arg)...)
+		}
+	}
+	for _, expr := range p.PartitionBy {
+		selfUsedCols = append(selfUsedCols, expression.ExtractColumns(expr)...)
+	}
+	for _, item := range p.OrderBy {
+		selfUsedCols = append(selfUsedCols, expression.ExtractColumns(item.Expr)...)
+	}
+	child.PruneColumns(selfUsedCols)
+	funcCols := p.schema.Columns[p.schema.Len()-len(p.WindowFuncs):]
+	cols := make([]*expression.Column, 0, child.Schema().Len()+len(funcCols))
+	cols = append(cols, child.Schema().Columns...)
+	p.SetSchema(expression.NewSchema(append(cols, funcCols...)...))
+}
+
 // PruneColumns implements LogicalPlan interface.
 func (p *Union) PruneColumns(parentUsedCols []*expression.Column) {
 	used := getUsedList(parentUsedCols, p.Schema())
@@ -0,0 +1,308 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// CTEDefinition is a common table expression of a statement, it's materialized before the statement is executed.
+// The rows of the seed part are generated first, then the recursive part is executed repeatedly, each time it reads
+// the rows generated by the last iteration from the work table, until no new rows are generated.
+type CTEDefinition struct {
+	Name          model.CIStr
+	TableInfo     *model.TableInfo
+	WorkTableInfo *model.TableInfo
+	Seed          PhysicalPlan
+	// Recursive is nil if the CTE is not recursive.
+	Recursive PhysicalPlan
+	// Distinct means the rows of the CTE are deduplicated, it's set if a recursive CTE is defined by UNION DISTINCT.
+	Distinct bool
+}
+
+// cteReferrer checks whether a select refers to a CTE.
+type cteReferrer struct {
+	name  model.CIStr
+	found bool
+}
+
+func (r *cteReferrer) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok && tn.Schema.L == "" && tn.Name.L == r.name.L {
+		r.found = true
+	}
+	return in, r.found
+}
+
+func (r *cteReferrer) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+func refersCTE(sel *ast.SelectStmt, name model.CIStr) bool {
+	r := &cteReferrer{name: name}
+	sel.Accept(r)
+	return r.found
+}
+
+// handleWithClause resolves the common table expressions in order, so a CTE can refer to the CTEs defined before it.
+// The non-recursive part of a recursive CTE is resolved first to decide the columns of the CTE, then the recursive
+// part is resolved with the name of the CTE referring to the work table.
+func (nr *nameResolver) handleWithClause(w *ast.WithClause) {
+	if nr.cteTables == nil {
+		nr.cteTables = make(map[string]*model.TableInfo)
+	}
+	for _, cte := range w.CTEs {
+		if _, ok := nr.cteTables[cte.Name.L]; ok {
+			nr.Err = ErrNonUniqTable.GenByArgs(cte.Name.O)
+			return
+		}
+		selects := cte.Selects()
+		cte.SeedCount = len(selects)
+		if w.IsRecursive {
+			nr.splitRecursiveCTE(cte)
+			if nr.Err != nil {
+				return
+			}
+		}
+		if !cte.IsRecursive() {
+			cte.Query.Accept(nr)
+			if nr.Err != nil {
+				return
+			}
+			tblInfo := nr.buildCTETableInfo(cte, selects[0].GetResultFields())
+			if nr.Err != nil {
+				return
+			}
+			nr.cteTables[cte.Name.L] = tblInfo
+			continue
+		}
+		for _, sel := range selects[:cte.SeedCount] {
+			sel.Accept(nr)
+			if nr.Err != nil {
+				return
+			}
+		}
+		tblInfo := nr.buildCTETableInfo(cte, selects[0].GetResultFields())
+		if nr.Err != nil {
+			return
+		}
+		workTblInfo := *tblInfo
+		nr.lastCTETableID--
+		workTblInfo.ID = nr.lastCTETableID
+		cte.WorkTableInfo = &workTblInfo
+		nr.cteTables[cte.Name.L] = cte.WorkTableInfo
+		for _, sel := range selects[cte.SeedCount:] {
+			sel.Accept(nr)
+			if nr.Err != nil {
+				return
+			}
+			if len(sel.GetResultFields()) != len(tblInfo.Columns) {
+				nr.Err = ErrWrongNumberOfColumns.GenByArgs()
+				return
+			}
+		}
+		nr.cteTables[cte.Name.L] = tblInfo
+	}
+}
+
+// splitRecursiveCTE finds out the selects of the non-recursive part, which are the selects before the first one
+// referring to the CTE, and checks the recursive part is supported.
+func (nr *nameResolver) splitRecursiveCTE(cte *ast.CommonTableExpression) {
+	selects := cte.Selects()
+	for i, sel := range selects {
+		if refersCTE(sel, cte.Name) {
+			cte.SeedCount = i
+			break
+		}
+	}
+	if !cte.IsRecursive() {
+		if cte.SeedCount == 0 {
+			// The CTE refers to itself without a non-recursive part.
+			if _, ok := cte.Query.Query.(*ast.UnionStmt); ok {
+				nr.Err = ErrCTERecursiveRequiresNonRecursiveFirst.GenByArgs(cte.Name.O)
+			} else {
+				nr.Err = ErrCTERecursiveRequiresUnion.GenByArgs(cte.Name.O)
+			}
+		}
+		return
+	}
+	union := cte.Query.Query.(*ast.UnionStmt)
+	if union.OrderBy != nil || union.Limit != nil {
+		nr.Err = ErrNotSupportedYet.GenByArgs("ORDER BY / LIMIT over UNION in recursive Common Table Expression")
+		return
+	}
+	for _, sel := range selects[cte.SeedCount:] {
+		if !refersCTE(sel, cte.Name) {
+			nr.Err = ErrCTERecursiveRequiresNonRecursiveFirst.GenByArgs(cte.Name.O)
+			return
+		}
+		if sel.GroupBy != nil || sel.Having != nil || hasAggregateField(sel) {
+			nr.Err = ErrCTERecursiveForbidsAggregation.GenByArgs(cte.Name.O)
+			return
+		}
+		if sel.Distinct || sel.OrderBy != nil || sel.Limit != nil {
+			nr.Err = ErrNotSupportedYet.GenByArgs("ORDER BY / LIMIT / SELECT DISTINCT in recursive query block of Common Table Expression")
+			return
+		}
+	}
+}
+
+func hasAggregateField(sel *ast.SelectStmt) bool {
+	for _, field := range sel.Fields.Fields {
+		if field.Expr != nil && ast.HasAggFlag(field.Expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildCTETableInfo builds the table info of a CTE by the result fields of its first select, the column names can be
+// overwritten by the column list of the CTE. The column types are set after the types of the first select are inferred.
+func (nr *nameResolver) buildCTETableInfo(cte *ast.CommonTableExpression, rfs []*ast.ResultField) *model.TableInfo {
+	if len(cte.ColNames) > 0 && len(cte.ColNames) != len(rfs) {
+		nr.Err = ErrViewWrongList.GenByArgs()
+		return nil
+	}
+	nr.lastCTETableID--
+	tblInfo := &model.TableInfo{
+		ID:        nr.lastCTETableID,
+		Name:      cte.Name,
+		Columns:   make([]*model.ColumnInfo, 0, len(rfs)),
+		State:     model.StatePublic,
+		Temporary: true,
+	}
+	for i, rf := range rfs {
+		name := rf.ColumnAsName
+		if len(cte.ColNames) > 0 {
+			name = cte.ColNames[i]
+		} else if name.L == "" {
+			name = rf.Column.Name
+		}
+		tblInfo.Columns = append(tblInfo.Columns, &model.ColumnInfo{
+			ID:     int64(i + 1),
+			Name:   name,
+			Offset: i,
+			State:  model.StatePublic,
+		})
+	}
+	cte.TableInfo = tblInfo
+	return tblInfo
+}
+
+// setCTEColumnTypes sets the column types of the CTE by the result fields of its first select, which must have been
+// inferred. The work table shares the columns with the CTE.
+func setCTEColumnTypes(cte *ast.CommonTableExpression) {
+	if cte.TableInfo == nil {
+		return
+	}
+	for i, rf := range cte.Selects()[0].GetResultFields() {
+		col := cte.TableInfo.Columns[i]
+		col.FieldType = rf.Column.FieldType
+		if col.Flen == 0 {
+			col.Flen = types.UnspecifiedLength
+		}
+		// The rows of the other selects may have NULL or duplicated values.
+		col.Flag &^= mysql.NotNullFlag | mysql.PriKeyFlag | mysql.UniqueKeyFlag | mysql.MultipleKeyFlag | mysql.AutoIncrementFlag
+	}
+}
+
+// buildWith builds the plans of the common table expressions.
+func (b *planBuilder) buildWith(w *ast.WithClause) {
+	for _, cte := range w.CTEs {
+		def := &CTEDefinition{
+			Name:          cte.Name,
+			TableInfo:     cte.TableInfo,
+			WorkTableInfo: cte.WorkTableInfo,
+		}
+		b.ctes = append(b.ctes, def)
+		if !cte.IsRecursive() {
+			def.Seed = b.buildCTEPart(cte.Query.Query)
+			if b.err != nil {
+				return
+			}
+			continue
+		}
+		selects := cte.Selects()
+		union := cte.Query.Query.(*ast.UnionStmt)
+		def.Distinct = union.Distinct
+		def.Seed = b.buildCTEPart(unionOf(selects[:cte.SeedCount], union.Distinct))
+		if b.err != nil {
+			return
+		}
+		def.Recursive = b.buildCTEPart(unionOf(selects[cte.SeedCount:], false))
+		if b.err != nil {
+			return
+		}
+	}
+}
+
+func unionOf(selects []*ast.SelectStmt, distinct bool) ast.ResultSetNode {
+	if len(selects) == 1 {
+		return selects[0]
+	}
+	return &ast.UnionStmt{Distinct: distinct, SelectList: &ast.UnionSelectList{Selects: selects}}
+}
+
+// buildCTEPart builds and optimizes the plan of a part of CTE, it's executed separately from the statement.
+func (b *planBuilder) buildCTEPart(node ast.ResultSetNode) PhysicalPlan {
+	optFlag := b.optFlag
+	b.optFlag = flagPrunColumns
+	defer func() {
+		b.optFlag = optFlag
+	}()
+	p := b.buildResultSetNode(node)
+	if b.err != nil {
+		return nil
+	}
+	pp, err := doOptimize(b.optFlag, p, b.ctx, b.allocator)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	return pp
+}
+
+// isCTETable checks whether the table is the result or the work table of a CTE.
+func (b *planBuilder) isCTETable(tblInfo *model.TableInfo) bool {
+	for _, cte := range b.ctes {
+		if tblInfo == cte.TableInfo || tblInfo == cte.WorkTableInfo {
+			return true
+		}
+	}
+	return false
+}
+
+// attachCTEs attaches the CTEs to the plan of the statement, so they're materialized before the statement is executed.
+func (b *planBuilder) attachCTEs(p PhysicalPlan) PhysicalPlan {
+	if len(b.ctes) == 0 {
+		return p
+	}
+	cte := &PhysicalCTE{CTEs: b.ctes}
+	cte.tp = "CTE"
+	cte.allocator = b.allocator
+	cte.initIDAndContext(b.ctx)
+	cte.SetSchema(p.Schema())
+	children := []Plan{p}
+	for _, def := range b.ctes {
+		children = append(children, def.Seed)
+		if def.Recursive != nil {
+			children = append(children, def.Recursive)
+		}
+	}
+	cte.SetChildren(children...)
+	return cte
+}
@@ -14,6 +14,8 @@
 package plan
 
 import (
+	"time"
+
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
@@ -110,6 +112,12 @@ func (pc pbConverter) columnToPBExpr(column *expression.Column) *tipb.Expr {
 	switch column.GetType().Tp {
 	case mysql.TypeBit, mysql.TypeSet, mysql.TypeEnum, mysql.TypeGeometry, mysql.TypeUnspecified:
 		return nil
+	case mysql.TypeTimestamp:
+		// The coprocessor evaluates the TIMESTAMP values in the system time zone, in which they are encoded, so
+		// they can't be pushed down when the session time zone is different.
+		if pc.sc != nil && pc.sc.TimeZone != nil && pc.sc.TimeZone != time.Local {
+			return nil
+		}
 	}
 
 	id := column.ID
@@ -354,6 +362,8 @@ func aggFuncToPBExpr(sc *variable.StatementContext, client kv.Client, aggFunc ex
 		tp = tipb.ExprType_Sum
 	case ast.AggFuncAvg:
 		tp = tipb.ExprType_Avg
+	default:
+		return nil
 	}
 	if !client.SupportRequestType(kv.ReqTypeSelect, int64(tp)) {
 		return nil
@@ -367,6 +377,15 @@ func aggFuncToPBExpr(sc *variable.StatementContext, client kv.Client, aggFunc ex
 		}
 		children = append(children, pbArg)
 	}
+	if tp == tipb.ExprType_GroupConcat {
+		// The values of group_concat with order by are sorted after all the values are collected, so it can't be
+		// pushed down. The separator is sent in the value of the expression.
+		separator, ordered := expression.GroupConcatInfo(aggFunc)
+		if ordered {
+			return nil
+		}
+		return &tipb.Expr{Tp: tp, Children: children, Val: []byte(separator)}
+	}
 	return &tipb.Expr{Tp: tp, Children: children}
 }
 
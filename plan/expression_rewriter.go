@@ -51,6 +51,71 @@ func evalAstExpr(expr ast.ExprNode, ctx context.Context) (types.Datum, error) {
 	return newExpr.Eval(nil)
 }
 
+// rewriteTableExpr rewrites the ast expression on the public columns of the table, the columns are indexed by their
+// offsets, so the expression can be evaluated on the rows of the table.
+func rewriteTableExpr(expr ast.ExprNode, ctx context.Context, tblInfo *model.TableInfo) (expression.Expression, error) {
+	b := &planBuilder{
+		ctx:       ctx,
+		allocator: new(idAllocator),
+		colMapper: make(map[*ast.ColumnNameExpr]int),
+	}
+	schema := expression.NewSchema(make([]*expression.Column, 0, len(tblInfo.Columns))...)
+	for _, col := range tblInfo.Columns {
+		if col.State != model.StatePublic {
+			continue
+		}
+		schema.Append(&expression.Column{
+			FromID:   tblInfo.Name.L,
+			ColName:  col.Name,
+			TblName:  tblInfo.Name,
+			RetType:  &col.FieldType,
+			Position: col.Offset,
+			Index:    col.Offset,
+			ID:       col.ID})
+	}
+	resolver := &tableColumnResolver{tblInfo: tblInfo}
+	expr.Accept(resolver)
+	if resolver.err != nil {
+		return nil, errors.Trace(resolver.err)
+	}
+	if err := InferType(ctx.GetSessionVars().StmtCtx, expr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	p := &TableDual{}
+	p.SetSchema(schema)
+	newExpr, _, err := b.rewrite(expr, p, nil, true)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newExpr, nil
+}
+
+// tableColumnResolver resolves the column names in the expression to the public columns of the table, so the types
+// of the expression can be inferred.
+type tableColumnResolver struct {
+	tblInfo *model.TableInfo
+	err     error
+}
+
+func (r *tableColumnResolver) Enter(in ast.Node) (ast.Node, bool) {
+	return in, false
+}
+
+func (r *tableColumnResolver) Leave(in ast.Node) (ast.Node, bool) {
+	cn, ok := in.(*ast.ColumnNameExpr)
+	if !ok {
+		return in, true
+	}
+	for _, col := range r.tblInfo.Columns {
+		if col.State == model.StatePublic && col.Name.L == cn.Name.Name.L {
+			cn.Refer = &ast.ResultField{Column: col, Table: r.tblInfo}
+			return in, true
+		}
+	}
+	r.err = ErrUnknownColumn.GenByArgs(cn.Name.Name.O, "expression")
+	return in, false
+}
+
 // rewrite function rewrites ast expr to expression.Expression.
 // aggMapper maps ast.AggregateFuncExpr to the columns offset in p's output schema.
 // asScalar means whether this expression must be treated as a scalar expression.
@@ -160,6 +225,14 @@ func (er *expressionRewriter) Enter(inNode ast.Node) (ast.Node, bool) {
 		}
 		er.ctxStack = append(er.ctxStack, er.schema.Columns[index])
 		return inNode, true
+	case *ast.WindowFuncExpr:
+		index, ok := er.b.windowMapper[v]
+		if !ok {
+			er.err = ErrInvalidWindowFuncUse.GenByArgs(v.F)
+			return inNode, true
+		}
+		er.ctxStack = append(er.ctxStack, er.schema.Columns[index])
+		return inNode, true
 	case *ast.ColumnNameExpr:
 		if index, ok := er.b.colMapper[v]; ok {
 			er.ctxStack = append(er.ctxStack, er.schema.Columns[index])
@@ -445,7 +518,7 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 		return v, true
 	}
 	np = er.b.buildExists(np)
-	if len(np.extractCorrelatedCols()) > 0 {
+	if !er.canEvalSubquery(np) {
 		er.p = er.b.buildSemiApply(er.p, np.Children()[0].(LogicalPlan), nil, er.asScalar, false)
 		if !er.asScalar {
 			return v, true
@@ -465,6 +538,12 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 	return v, true
 }
 
+// canEvalSubquery checks whether the subquery can be evaluated when the plan is built. A correlated subquery can't,
+// neither can a subquery in a statement with CTEs, which may read the CTEs before they are materialized.
+func (er *expressionRewriter) canEvalSubquery(np LogicalPlan) bool {
+	return len(np.extractCorrelatedCols()) == 0 && len(er.b.ctes) == 0
+}
+
 func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node, bool) {
 	asScalar := er.asScalar
 	er.asScalar = true
@@ -490,7 +569,7 @@ func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node,
 	// Sometimes we can unfold the in subquery. For example, a in (select * from t) can rewrite to `a in (1,2,3,4)`.
 	// TODO: Now we cannot add it to CBO framework. Instead, user can set a session variable to open this optimization.
 	// We will improve our CBO framework in future.
-	if lLen == 1 && er.ctx.GetSessionVars().AllowInSubqueryUnFolding && len(np.extractCorrelatedCols()) == 0 {
+	if lLen == 1 && er.ctx.GetSessionVars().AllowInSubqueryUnFolding && er.canEvalSubquery(np) {
 		physicalPlan, err := doOptimize(er.b.optFlag, np, er.b.ctx, er.b.allocator)
 		if err != nil {
 			er.err = errors.Trace(err)
@@ -556,7 +635,7 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 		return v, true
 	}
 	np = er.b.buildMaxOneRow(np)
-	if len(np.extractCorrelatedCols()) > 0 {
+	if !er.canEvalSubquery(np) {
 		er.p = er.b.buildApplyWithJoinType(er.p, np, LeftOuterJoin)
 		if np.Schema().Len() > 1 {
 			newCols := make([]expression.Expression, 0, np.Schema().Len())
@@ -613,7 +692,7 @@ func (er *expressionRewriter) Leave(inNode ast.Node) (retNode ast.Node, ok bool)
 	}
 
 	switch v := inNode.(type) {
-	case *ast.AggregateFuncExpr, *ast.ColumnNameExpr, *ast.ParenthesesExpr, *ast.WhenClause,
+	case *ast.AggregateFuncExpr, *ast.WindowFuncExpr, *ast.ColumnNameExpr, *ast.ParenthesesExpr, *ast.WhenClause,
 		*ast.SubqueryExpr, *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr, *ast.ValuesExpr:
 	case *ast.ValueExpr:
 		value := &expression.Constant{Value: v.Datum, RetType: &v.Type}
@@ -0,0 +1,255 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// IndexCandidate is an index which may speed up a statement, it's built from the predicates of the statement.
+type IndexCandidate struct {
+	DBName  model.CIStr
+	Table   model.CIStr
+	TableID int64
+	Columns []model.CIStr
+}
+
+// String implements fmt.Stringer interface.
+func (c *IndexCandidate) String() string {
+	cols := make([]string, 0, len(c.Columns))
+	for _, col := range c.Columns {
+		cols = append(cols, col.O)
+	}
+	return fmt.Sprintf("%s.%s(%s)", c.DBName.O, c.Table.O, strings.Join(cols, ","))
+}
+
+// hypoIndexesKeyType is a dummy type to avoid naming collision in context.
+type hypoIndexesKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k hypoIndexesKeyType) String() string {
+	return "hypo_indexes"
+}
+
+// hypoIndexesKey is the key of the hypothetical indexes in the context, the optimizer considers them as if they
+// existed, they are never used to execute a plan.
+const hypoIndexesKey hypoIndexesKeyType = 0
+
+// IndexCandidates returns the index candidates of the node, built from the predicates pushed down to the tables.
+// The node must be prepared first.
+func IndexCandidates(ctx context.Context, node ast.Node, is infoschema.InfoSchema) ([]*IndexCandidate, error) {
+	p, builder, err := build(ctx, node, is)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	logic, ok := p.(LogicalPlan)
+	if !ok {
+		return nil, nil
+	}
+	logic, err = logicalOptimize(builder.optFlag, logic, ctx, builder.allocator)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return collectIndexCandidates(logic, nil), nil
+}
+
+// EstimateCost returns the cost of the best plan of the node, considering the hypothetical indexes.
+// The node must be prepared first.
+func EstimateCost(ctx context.Context, node ast.Node, is infoschema.InfoSchema, hypoIndexes []*IndexCandidate) (float64, error) {
+	ctx.SetValue(hypoIndexesKey, hypoIndexes)
+	defer ctx.ClearValue(hypoIndexesKey)
+	p, builder, err := build(ctx, node, is)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	logic, ok := p.(LogicalPlan)
+	if !ok {
+		return 0, nil
+	}
+	info, err := doOptimizeWithCost(builder.optFlag, logic, ctx, builder.allocator)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return info.cost, nil
+}
+
+func collectIndexCandidates(p LogicalPlan, candidates []*IndexCandidate) []*IndexCandidate {
+	if ds, ok := p.(*DataSource); ok && !ds.isMemTable() {
+		if sel, ok := ds.parents[0].(*Selection); ok {
+			if candidate := ds.indexCandidate(sel.Conditions); candidate != nil {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	for _, child := range p.Children() {
+		candidates = collectIndexCandidates(child.(LogicalPlan), candidates)
+	}
+	return candidates
+}
+
+// indexCandidate builds an index on the columns compared with constants in the conditions, the columns of the
+// equal conditions come first, followed by a column of the range conditions.
+func (p *DataSource) indexCandidate(conds []expression.Expression) *IndexCandidate {
+	var eqCols, rangeCols []*model.ColumnInfo
+	for _, cond := range conds {
+		col, eq := p.predicateColumn(cond)
+		if col == nil {
+			continue
+		}
+		if eq {
+			eqCols = appendColumnIfNotExists(eqCols, col)
+		} else {
+			rangeCols = appendColumnIfNotExists(rangeCols, col)
+		}
+	}
+	cols := eqCols
+	for _, col := range rangeCols {
+		if newCols := appendColumnIfNotExists(cols, col); len(newCols) > len(cols) {
+			cols = newCols
+			break
+		}
+	}
+	if len(cols) == 0 || p.hasIndexPrefix(cols) {
+		return nil
+	}
+	candidate := &IndexCandidate{DBName: p.DBName, Table: p.tableInfo.Name, TableID: p.tableInfo.ID}
+	for _, col := range cols {
+		candidate.Columns = append(candidate.Columns, col.Name)
+	}
+	return candidate
+}
+
+// predicateColumn returns the column of the condition if it compares a column with constants, and whether it's an
+// equal condition.
+func (p *DataSource) predicateColumn(cond expression.Expression) (*model.ColumnInfo, bool) {
+	sf, ok := cond.(*expression.ScalarFunction)
+	if !ok {
+		return nil, false
+	}
+	args := sf.GetArgs()
+	var eq bool
+	switch sf.FuncName.L {
+	case ast.EQ, ast.NullEQ, ast.In, ast.IsNull:
+		eq = true
+	case ast.LT, ast.LE, ast.GT, ast.GE:
+		if _, ok := args[0].(*expression.Constant); ok && len(args) == 2 {
+			args = []expression.Expression{args[1], args[0]}
+		}
+	default:
+		return nil, false
+	}
+	col, ok := args[0].(*expression.Column)
+	if !ok || !p.Schema().Contains(col) {
+		return nil, false
+	}
+	for _, arg := range args[1:] {
+		if _, ok := arg.(*expression.Constant); !ok {
+			return nil, false
+		}
+	}
+	for _, colInfo := range p.tableInfo.Columns {
+		if colInfo.Name.L != col.ColName.L {
+			continue
+		}
+		if p.tableInfo.PKIsHandle && mysql.HasPriKeyFlag(colInfo.Flag) {
+			return nil, false
+		}
+		if types.IsTypeBlob(colInfo.Tp) {
+			return nil, false
+		}
+		return colInfo, eq
+	}
+	return nil, false
+}
+
+func appendColumnIfNotExists(cols []*model.ColumnInfo, col *model.ColumnInfo) []*model.ColumnInfo {
+	for _, c := range cols {
+		if c.Name.L == col.Name.L {
+			return cols
+		}
+	}
+	return append(cols, col)
+}
+
+// hasIndexPrefix checks if an index of the table starts with the columns.
+func (p *DataSource) hasIndexPrefix(cols []*model.ColumnInfo) bool {
+	for _, index := range p.tableInfo.Indices {
+		if index.State != model.StatePublic || index.Invisible || len(index.Columns) < len(cols) {
+			continue
+		}
+		match := true
+		for i, col := range cols {
+			if index.Columns[i].Name.L != col.Name.L || index.Columns[i].Length != types.UnspecifiedLength {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// withHypoIndexes returns a copy of the table info with the hypothetical indexes of the table appended.
+func withHypoIndexes(tblInfo *model.TableInfo, hypoIndexes []*IndexCandidate) *model.TableInfo {
+	var indices []*model.IndexInfo
+	for _, candidate := range hypoIndexes {
+		if candidate.TableID != tblInfo.ID {
+			continue
+		}
+		if index := buildHypoIndex(tblInfo, candidate, len(tblInfo.Indices)+len(indices)); index != nil {
+			indices = append(indices, index)
+		}
+	}
+	if len(indices) == 0 {
+		return tblInfo
+	}
+	newInfo := *tblInfo
+	newInfo.Indices = append(append(make([]*model.IndexInfo, 0, len(tblInfo.Indices)+len(indices)), tblInfo.Indices...), indices...)
+	return &newInfo
+}
+
+func buildHypoIndex(tblInfo *model.TableInfo, candidate *IndexCandidate, offset int) *model.IndexInfo {
+	index := &model.IndexInfo{
+		Name:  model.NewCIStr(fmt.Sprintf("hypo_%d", offset)),
+		Table: tblInfo.Name,
+		State: model.StatePublic,
+		Tp:    model.IndexTypeBtree,
+	}
+	for _, name := range candidate.Columns {
+		var colInfo *model.ColumnInfo
+		for _, col := range tblInfo.Columns {
+			if col.Name.L == name.L && col.State == model.StatePublic {
+				colInfo = col
+				break
+			}
+		}
+		if colInfo == nil {
+			return nil
+		}
+		index.Columns = append(index.Columns, &model.IndexColumn{Name: colInfo.Name, Offset: colInfo.Offset, Length: types.UnspecifiedLength})
+	}
+	return index
+}
@@ -15,6 +15,7 @@ package plan
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
@@ -29,6 +30,8 @@ import (
 const (
 	// TiDBMergeJoin is hint enforce merge join
 	TiDBMergeJoin = "tidb_smj"
+	// TiDBIndexNestedLoopJoin is hint enforce index nested loop join, the listed tables are used as the outer tables.
+	TiDBIndexNestedLoopJoin = "tidb_inlj"
 )
 
 type idAllocator struct {
@@ -71,7 +74,26 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 			p = np
 			newArgList = append(newArgList, newArg)
 		}
-		newFunc := expression.NewAggFunction(aggFunc.F, newArgList, aggFunc.Distinct)
+		var newFunc expression.AggregationFunction
+		if strings.ToLower(aggFunc.F) == ast.AggFuncGroupConcat {
+			var byItems []expression.Expression
+			var descs []bool
+			if aggFunc.Order != nil {
+				for _, item := range aggFunc.Order.Items {
+					newItem, np, err := b.rewrite(item.Expr, p, nil, true)
+					if err != nil {
+						b.err = errors.Trace(err)
+						return nil, nil
+					}
+					p = np
+					byItems = append(byItems, newItem)
+					descs = append(descs, item.Desc)
+				}
+			}
+			newFunc = expression.NewGroupConcatFunction(newArgList, aggFunc.Distinct, byItems, descs, aggFunc.Separator)
+		} else {
+			newFunc = expression.NewAggFunction(aggFunc.F, newArgList, aggFunc.Distinct)
+		}
 		combined := false
 		for j, oldFunc := range agg.AggFuncs {
 			if oldFunc.Equal(newFunc, b.ctx) {
@@ -231,6 +253,12 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 
 	if b.TableHints() != nil {
 		joinPlan.preferMergeJoin = b.TableHints().ifPreferMergeJoin(leftAlias, rightAlias)
+		if b.TableHints().ifPreferINLJ(leftAlias) {
+			joinPlan.preferINLJ |= preferLeftAsOuter
+		}
+		if b.TableHints().ifPreferINLJ(rightAlias) {
+			joinPlan.preferINLJ |= preferRightAsOuter
+		}
 	}
 
 	if join.On != nil {
@@ -348,6 +376,120 @@ func (b *planBuilder) buildProjection(p LogicalPlan, fields []*ast.SelectField,
 	return proj, oldLen
 }
 
+// buildWindowFunctions builds a Window plan for each window of the window functions in the select fields, the
+// functions on the same window are evaluated by the same Window plan.
+func (b *planBuilder) buildWindowFunctions(p LogicalPlan, fields []*ast.SelectField, aggMapper map[*ast.AggregateFuncExpr]int) LogicalPlan {
+	extractor := &windowFuncExtractor{}
+	for _, f := range fields {
+		f.Expr.Accept(extractor)
+	}
+	if b.windowMapper == nil {
+		b.windowMapper = make(map[*ast.WindowFuncExpr]int)
+	}
+	var (
+		windows   []*Window
+		funcExprs [][]*ast.WindowFuncExpr
+	)
+	for _, wf := range extractor.windowFuncs {
+		window, np, err := b.rewriteWindowFunc(p, wf, aggMapper)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		p = np
+		found := false
+		for i, w := range windows {
+			if w.sameWindow(window) {
+				w.WindowFuncs = append(w.WindowFuncs, window.WindowFuncs...)
+				funcExprs[i] = append(funcExprs[i], wf)
+				found = true
+				break
+			}
+		}
+		if !found {
+			windows = append(windows, window)
+			funcExprs = append(funcExprs, []*ast.WindowFuncExpr{wf})
+		}
+	}
+	for i, window := range windows {
+		if len(window.PartitionBy) > 0 || len(window.OrderBy) > 0 {
+			sort := &Sort{baseLogicalPlan: newBaseLogicalPlan(Srt, b.allocator)}
+			sort.self = sort
+			sort.initIDAndContext(b.ctx)
+			for _, expr := range window.PartitionBy {
+				sort.ByItems = append(sort.ByItems, &ByItems{Expr: expr})
+			}
+			sort.ByItems = append(sort.ByItems, window.OrderBy...)
+			addChild(sort, p)
+			sort.SetSchema(p.Schema().Clone())
+			p = sort
+		}
+		schema := p.Schema().Clone()
+		for _, wf := range funcExprs[i] {
+			b.windowMapper[wf] = schema.Len()
+			schema.Append(&expression.Column{
+				FromID:      window.id,
+				ColName:     model.NewCIStr(fmt.Sprintf("%s_col_%d", window.id, schema.Len())),
+				Position:    schema.Len(),
+				IsAggOrSubq: true,
+				RetType:     wf.GetType()})
+		}
+		addChild(window, p)
+		window.SetSchema(schema)
+		p = window
+	}
+	return p
+}
+
+// rewriteWindowFunc rewrites the window function to a Window plan without child.
+func (b *planBuilder) rewriteWindowFunc(p LogicalPlan, wf *ast.WindowFuncExpr, aggMapper map[*ast.AggregateFuncExpr]int) (*Window, LogicalPlan, error) {
+	window := &Window{baseLogicalPlan: newBaseLogicalPlan(Win, b.allocator), Frame: wf.Spec.Frame}
+	window.self = window
+	window.initIDAndContext(b.ctx)
+	desc := &WindowFuncDesc{Name: strings.ToLower(wf.F)}
+	for _, arg := range wf.Args {
+		newArg, np, err := b.rewrite(arg, p, aggMapper, true)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		p = np
+		desc.Args = append(desc.Args, newArg)
+	}
+	if desc.Name == ast.WindowFuncLag || desc.Name == ast.WindowFuncLead {
+		if len(desc.Args) > 3 {
+			return nil, nil, ErrWindowFuncArguments.GenByArgs(desc.Name)
+		}
+		if len(desc.Args) > 1 {
+			// The offset must be a non-negative integer constant.
+			offset, ok := desc.Args[1].(*expression.Constant)
+			if !ok || offset.Value.Kind() != types.KindInt64 && offset.Value.Kind() != types.KindUint64 ||
+				offset.Value.Kind() == types.KindInt64 && offset.Value.GetInt64() < 0 {
+				return nil, nil, ErrWindowFuncArguments.GenByArgs(desc.Name)
+			}
+		}
+	}
+	window.WindowFuncs = []*WindowFuncDesc{desc}
+	for _, expr := range wf.Spec.PartitionBy {
+		newExpr, np, err := b.rewrite(expr, p, aggMapper, true)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		p = np
+		window.PartitionBy = append(window.PartitionBy, newExpr)
+	}
+	if wf.Spec.OrderBy != nil {
+		for _, item := range wf.Spec.OrderBy.Items {
+			newExpr, np, err := b.rewrite(item.Expr, p, aggMapper, true)
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			p = np
+			window.OrderBy = append(window.OrderBy, &ByItems{Expr: newExpr, Desc: item.Desc})
+		}
+	}
+	return window, p, nil
+}
+
 func (b *planBuilder) buildDistinct(child LogicalPlan, length int) LogicalPlan {
 	b.optFlag = b.optFlag | flagBuildKeyInfo
 	b.optFlag = b.optFlag | flagAggregationOptimize
@@ -367,6 +509,12 @@ func (b *planBuilder) buildDistinct(child LogicalPlan, length int) LogicalPlan {
 }
 
 func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
+	if union.With != nil {
+		b.buildWith(union.With)
+		if b.err != nil {
+			return nil
+		}
+	}
 	u := &Union{baseLogicalPlan: newBaseLogicalPlan(Un, b.allocator)}
 	u.self = u
 	u.initIDAndContext(b.ctx)
@@ -848,17 +996,22 @@ func (b *planBuilder) unfoldWildStar(p LogicalPlan, selectFields []*ast.SelectFi
 }
 
 func (b *planBuilder) pushTableHints(hints []*ast.TableOptimizerHint) bool {
-	var sortMergeTables []model.CIStr
+	var sortMergeTables, indexNestedLoopTables []model.CIStr
 	for _, hint := range hints {
 		switch hint.HintName.L {
 		case TiDBMergeJoin:
 			sortMergeTables = append(sortMergeTables, hint.Tables...)
+		case TiDBIndexNestedLoopJoin:
+			indexNestedLoopTables = append(indexNestedLoopTables, hint.Tables...)
 		default:
 			// ignore hints that not implemented
 		}
 	}
-	if len(sortMergeTables) != 0 {
-		b.tableHintInfo = append(b.tableHintInfo, tableHintInfo{sortMergeTables})
+	if len(sortMergeTables) != 0 || len(indexNestedLoopTables) != 0 {
+		b.tableHintInfo = append(b.tableHintInfo, tableHintInfo{
+			sortMergeJoinTables:       sortMergeTables,
+			indexNestedLoopJoinTables: indexNestedLoopTables,
+		})
 		return true
 	}
 	return false
@@ -876,6 +1029,12 @@ func (b *planBuilder) TableHints() *tableHintInfo {
 }
 
 func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
+	if sel.With != nil {
+		b.buildWith(sel.With)
+		if b.err != nil {
+			return nil
+		}
+	}
 	if sel.TableHints != nil {
 		// table hints without query block support only visible in current SELECT
 		if b.pushTableHints(sel.TableHints) {
@@ -936,6 +1095,12 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 			return nil
 		}
 	}
+	if b.detectSelectWindow(sel) {
+		p = b.buildWindowFunctions(p, sel.Fields.Fields, totalMap)
+		if b.err != nil {
+			return nil
+		}
+	}
 	var oldLen int
 	p, oldLen = b.buildProjection(p, sel.Fields.Fields, totalMap)
 	if b.err != nil {
@@ -995,15 +1160,24 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 		return nil
 	}
 	schemaName := tn.Schema
-	if schemaName.L == "" {
-		schemaName = model.NewCIStr(b.ctx.GetSessionVars().CurrentDB)
+	var tableInfo *model.TableInfo
+	if b.isCTETable(tn.TableInfo) {
+		tableInfo = tn.TableInfo
+	} else {
+		if schemaName.L == "" {
+			schemaName = model.NewCIStr(b.ctx.GetSessionVars().CurrentDB)
+		}
+		tbl, err := b.is.TableByName(schemaName, tn.Name)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		tableInfo = tbl.Meta()
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, schemaName.L, tableInfo.Name.L, "")
 	}
-	tbl, err := b.is.TableByName(schemaName, tn.Name)
-	if err != nil {
-		b.err = errors.Trace(err)
-		return nil
+	if hypoIndexes, ok := b.ctx.Value(hypoIndexesKey).([]*IndexCandidate); ok {
+		tableInfo = withHypoIndexes(tableInfo, hypoIndexes)
 	}
-	tableInfo := tbl.Meta()
 
 	p := &DataSource{
 		indexHints:      tn.IndexHints,
@@ -1015,8 +1189,6 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 	p.self = p
 	p.initIDAndContext(b.ctx)
 
-	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, schemaName.L, tableInfo.Name.L, "")
-
 	// Equal condition contains a column from previous joined table.
 	schema := expression.NewSchema(make([]*expression.Column, 0, len(tableInfo.Columns))...)
 	for i, col := range tableInfo.Columns {
@@ -307,6 +307,10 @@ func (m *mockStore) GetClient() kv.Client {
 	return m.client
 }
 
+func (m *mockStore) SupportCapability(c kv.Capability) bool {
+	return c == kv.CapCoprocessor
+}
+
 func (m *mockStore) Begin() (kv.Transaction, error) {
 	return nil, nil
 }
@@ -597,6 +601,15 @@ func (s *testPlanSuite) TestPlanBuilder(c *C) {
 			sql:  "analyze table t, t",
 			plan: "*plan.Analyze->*plan.Analyze->*plan.Analyze",
 		},
+		{
+			// The functions on the same window share the Window plan.
+			sql:  "select a, rank() over (partition by b order by c), dense_rank() over (partition by b order by c), sum(a) over () from t",
+			plan: "DataScan(t)->Sort->Window->Window->Projection",
+		},
+		{
+			sql:  "select b, count(*), row_number() over (order by count(*)) from t group by b",
+			plan: "DataScan(t)->Aggr(count(1),firstrow(test.t.b))->Sort->Window->Projection",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
@@ -1549,6 +1562,19 @@ func (s *testPlanSuite) TestVisitInfo(c *C) {
 				{mysql.GrantPriv, "test", "ttt", ""},
 			},
 		},
+		{
+			sql: "rename table test.t to test.t1, test.t2 to test.t",
+			ans: []visitInfo{
+				{mysql.AlterPriv, "test", "t", ""},
+				{mysql.DropPriv, "test", "t", ""},
+				{mysql.CreatePriv, "test", "t1", ""},
+				{mysql.InsertPriv, "test", "t1", ""},
+				{mysql.AlterPriv, "test", "t2", ""},
+				{mysql.DropPriv, "test", "t2", ""},
+				{mysql.CreatePriv, "test", "t", ""},
+				{mysql.InsertPriv, "test", "t", ""},
+			},
+		},
 		{
 			sql: `revoke all privileges on *.* from 'test'@'%'`,
 			ans: []visitInfo{
@@ -1563,6 +1589,40 @@ func (s *testPlanSuite) TestVisitInfo(c *C) {
 				{mysql.CreateUserPriv, "", "", ""},
 			},
 		},
+		{
+			sql: `set global max_connections = 1, @@session.autocommit = 1`,
+			ans: []visitInfo{
+				{mysql.SuperPriv, "", "", ""},
+			},
+		},
+		{
+			sql: `set autocommit = 1`,
+			ans: []visitInfo{},
+		},
+		{
+			sql: `load stats '/tmp/stats.json'`,
+			ans: []visitInfo{
+				{mysql.SuperPriv, "", "", ""},
+			},
+		},
+		{
+			sql: `admin show ddl jobs`,
+			ans: []visitInfo{
+				{mysql.ProcessPriv, "", "", ""},
+			},
+		},
+		{
+			sql: `admin cancel ddl jobs 1, 2`,
+			ans: []visitInfo{
+				{mysql.SuperPriv, "", "", ""},
+			},
+		},
+		{
+			sql: `admin checksum table t, test.t`,
+			ans: []visitInfo{
+				{mysql.SelectPriv, "test", "t", ""},
+			},
+		},
 	}
 
 	for _, ca := range cases {
@@ -38,6 +38,11 @@ const (
 	LeftOuterSemiJoin
 )
 
+const (
+	preferLeftAsOuter = 1 << iota
+	preferRightAsOuter
+)
+
 // Join is the logical join plan.
 type Join struct {
 	baseLogicalPlan
@@ -47,6 +52,8 @@ type Join struct {
 	reordered       bool
 	cartesianJoin   bool
 	preferMergeJoin bool
+	// preferINLJ is a bit set of preferLeftAsOuter and preferRightAsOuter, which is set by the TIDB_INLJ hint.
+	preferINLJ int
 
 	EqualConditions []*expression.ScalarFunction
 	LeftConditions  []expression.Expression
@@ -234,6 +241,63 @@ func (p *Sort) extractCorrelatedCols() []*expression.CorrelatedColumn {
 	return corCols
 }
 
+// WindowFuncDesc describes a window function of the Window plan.
+type WindowFuncDesc struct {
+	// Name is the lower case name of the function, it's the name of an aggregate function or one of the
+	// ast.WindowFuncXXX names.
+	Name string
+	Args []expression.Expression
+}
+
+// Window evaluates the window functions which share the same window on the rows sorted by PartitionBy and then
+// OrderBy, the results of the functions are appended to the columns of the child.
+type Window struct {
+	baseLogicalPlan
+
+	WindowFuncs []*WindowFuncDesc
+	PartitionBy []expression.Expression
+	OrderBy     []*ByItems
+	// Frame is nil if the window has no frame clause.
+	Frame *ast.FrameClause
+}
+
+// sameWindow checks if the partition by items, the order by items and the frame of the two windows are the same.
+func (p *Window) sameWindow(other *Window) bool {
+	if len(p.PartitionBy) != len(other.PartitionBy) || len(p.OrderBy) != len(other.OrderBy) {
+		return false
+	}
+	for i, expr := range p.PartitionBy {
+		if !expr.Equal(other.PartitionBy[i], p.ctx) {
+			return false
+		}
+	}
+	for i, item := range p.OrderBy {
+		if item.Desc != other.OrderBy[i].Desc || !item.Expr.Equal(other.OrderBy[i].Expr, p.ctx) {
+			return false
+		}
+	}
+	if p.Frame == nil || other.Frame == nil {
+		return p.Frame == other.Frame
+	}
+	return *p.Frame == *other.Frame
+}
+
+func (p *Window) extractCorrelatedCols() []*expression.CorrelatedColumn {
+	corCols := p.basePlan.extractCorrelatedCols()
+	for _, f := range p.WindowFuncs {
+		for _, arg := range f.Args {
+			corCols = append(corCols, extractCorColumns(arg)...)
+		}
+	}
+	for _, expr := range p.PartitionBy {
+		corCols = append(corCols, extractCorColumns(expr)...)
+	}
+	for _, item := range p.OrderBy {
+		corCols = append(corCols, extractCorColumns(item.Expr)...)
+	}
+	return corCols
+}
+
 // Update represents Update plan.
 type Update struct {
 	baseLogicalPlan
@@ -199,6 +199,17 @@ func (p *PhysicalMergeJoin) matchProperty(prop *requiredProperty, childPlanInfo
 	return &physicalPlanInfo{p: &np, cost: cost, count: estimateJoinCount(lRes.count, rRes.count)}
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *PhysicalIndexJoin) matchProperty(prop *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
+	lRes, rRes := childPlanInfo[0], childPlanInfo[1]
+	np := *p
+	np.SetChildren(lRes.p, rRes.p)
+	outerRes := childPlanInfo[p.OuterIndex]
+	// Every outer row needs a look up of the inner table.
+	cost := outerRes.cost + float64(outerRes.count)*netWorkFactor
+	return &physicalPlanInfo{p: &np, cost: cost, count: estimateJoinCount(lRes.count, rRes.count)}
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *PhysicalHashJoin) matchProperty(prop *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
 	lRes, rRes := childPlanInfo[0], childPlanInfo[1]
@@ -281,6 +292,11 @@ func (p *Cache) matchProperty(prop *requiredProperty, childPlanInfo ...*physical
 	panic("You can't call this function!")
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *PhysicalCTE) matchProperty(prop *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *MaxOneRow) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
 	panic("You can't call this function!")
@@ -306,6 +322,11 @@ func (p *TableDual) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *
 	panic("You can't call this function!")
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *Window) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *Sort) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
 	panic("You can't call this function!")
@@ -51,9 +51,25 @@ type logicalOptRule interface {
 // Optimize does optimization and creates a Plan.
 // The node must be prepared first.
 func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Plan, error) {
+	p, builder, err := build(ctx, node, is)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if logic, ok := p.(LogicalPlan); ok {
+		pp, err := doOptimize(builder.optFlag, logic, ctx, builder.allocator)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return builder.attachCTEs(pp), nil
+	}
+	return p, nil
+}
+
+// build builds the node to a plan and checks the privileges.
+func build(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Plan, *planBuilder, error) {
 	// We have to infer type again because after parameter is set, the expression type may change.
 	if err := InferType(ctx.GetSessionVars().StmtCtx, node); err != nil {
-		return nil, errors.Trace(err)
+		return nil, nil, errors.Trace(err)
 	}
 	allocator := new(idAllocator)
 	builder := &planBuilder{
@@ -64,21 +80,17 @@ func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Pla
 	}
 	p := builder.build(node)
 	if builder.err != nil {
-		return nil, errors.Trace(builder.err)
+		return nil, nil, errors.Trace(builder.err)
 	}
 
 	// Maybe it's better to move this to Preprocess, but check privilege need table
 	// information, which is collected into visitInfo during logical plan builder.
 	if checker := privilege.GetPrivilegeChecker(ctx); checker != nil {
 		if !checkPrivilege(checker, builder.visitInfo) {
-			return nil, errors.New("privilege check fail")
+			return nil, nil, errors.New("privilege check fail")
 		}
 	}
-
-	if logic, ok := p.(LogicalPlan); ok {
-		return doOptimize(builder.optFlag, logic, ctx, allocator)
-	}
-	return p, nil
+	return p, builder, nil
 }
 
 func checkPrivilege(checker privilege.Checker, vs []visitInfo) bool {
@@ -91,6 +103,14 @@ func checkPrivilege(checker privilege.Checker, vs []visitInfo) bool {
 }
 
 func doOptimize(flag uint64, logic LogicalPlan, ctx context.Context, allocator *idAllocator) (PhysicalPlan, error) {
+	info, err := doOptimizeWithCost(flag, logic, ctx, allocator)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return info.p, nil
+}
+
+func doOptimizeWithCost(flag uint64, logic LogicalPlan, ctx context.Context, allocator *idAllocator) (*physicalPlanInfo, error) {
 	logic, err := logicalOptimize(flag, logic, ctx, allocator)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -119,7 +139,7 @@ func logicalOptimize(flag uint64, logic LogicalPlan, ctx context.Context, alloc
 	return logic, errors.Trace(err)
 }
 
-func physicalOptimize(flag uint64, logic LogicalPlan, allocator *idAllocator) (PhysicalPlan, error) {
+func physicalOptimize(flag uint64, logic LogicalPlan, allocator *idAllocator) (*physicalPlanInfo, error) {
 	info, err := logic.convert2PhysicalPlan(&requiredProperty{})
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -129,7 +149,7 @@ func physicalOptimize(flag uint64, logic LogicalPlan, allocator *idAllocator) (P
 	if flag&(flagDecorrelate) > 0 {
 		addCachePlan(pp, allocator)
 	}
-	return pp, nil
+	return &physicalPlanInfo{p: pp, cost: info.cost, count: info.count}, nil
 }
 
 func existsCartesianProduct(p LogicalPlan) bool {
@@ -159,11 +179,12 @@ func PrepareStmt(is infoschema.InfoSchema, ctx context.Context, node ast.Node) e
 
 // Optimizer error codes.
 const (
-	CodeOperandColumns      terror.ErrCode = 1
-	CodeInvalidWildCard     terror.ErrCode = 3
-	CodeUnsupported         terror.ErrCode = 4
-	CodeInvalidGroupFuncUse terror.ErrCode = 5
-	CodeIllegalReference    terror.ErrCode = 6
+	CodeOperandColumns       terror.ErrCode = 1
+	CodeInvalidWildCard      terror.ErrCode = 3
+	CodeUnsupported          terror.ErrCode = 4
+	CodeInvalidGroupFuncUse  terror.ErrCode = 5
+	CodeIllegalReference     terror.ErrCode = 6
+	CodeInvalidWindowFuncUse terror.ErrCode = 7
 )
 
 // Optimizer base errors.
@@ -173,15 +194,18 @@ var (
 	ErrCartesianProductUnsupported = terror.ClassOptimizer.New(CodeUnsupported, "Cartesian product is unsupported")
 	ErrInvalidGroupFuncUse         = terror.ClassOptimizer.New(CodeInvalidGroupFuncUse, "Invalid use of group function")
 	ErrIllegalReference            = terror.ClassOptimizer.New(CodeIllegalReference, "Illegal reference")
+	ErrInvalidWindowFuncUse        = terror.ClassOptimizer.New(CodeInvalidWindowFuncUse, mysql.MySQLErrName[mysql.ErrWindowInvalidWindowFuncUse])
 )
 
 func init() {
 	mySQLErrCodes := map[terror.ErrCode]uint16{
-		CodeOperandColumns:      mysql.ErrOperandColumns,
-		CodeInvalidWildCard:     mysql.ErrParse,
-		CodeInvalidGroupFuncUse: mysql.ErrInvalidGroupFuncUse,
-		CodeIllegalReference:    mysql.ErrIllegalReference,
+		CodeOperandColumns:       mysql.ErrOperandColumns,
+		CodeInvalidWildCard:      mysql.ErrParse,
+		CodeInvalidGroupFuncUse:  mysql.ErrInvalidGroupFuncUse,
+		CodeIllegalReference:     mysql.ErrIllegalReference,
+		CodeInvalidWindowFuncUse: mysql.ErrWindowInvalidWindowFuncUse,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassOptimizer] = mySQLErrCodes
 	expression.EvalAstExpr = evalAstExpr
+	expression.RewriteTableExpr = rewriteTableExpr
 }
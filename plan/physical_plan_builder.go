@@ -18,12 +18,14 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -37,9 +39,6 @@ const (
 	joinFactor      = 0.3
 )
 
-// JoinConcurrency means the number of goroutines that participate in joining.
-var JoinConcurrency = 5
-
 func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	client := p.ctx.GetClient()
 	ts := &PhysicalTableScan{
@@ -145,8 +144,26 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		for _, cond := range sel.Conditions {
 			conds = append(conds, cond.Clone())
 		}
+		hiddenCols, genExprs := p.buildExpressionIndexColumns(index)
+		if len(hiddenCols) > 0 {
+			// The expressions of the expression index are replaced by the hidden columns to detach the access
+			// conditions, and they're restored after the ranges are built.
+			for i, cond := range conds {
+				conds[i] = replaceExpr(cond, func(expr expression.Expression) expression.Expression {
+					for j, genExpr := range genExprs {
+						if expr.Equal(genExpr, p.ctx) {
+							return hiddenCols[j].Clone()
+						}
+					}
+					return nil
+				})
+			}
+		}
 		is.AccessCondition, newSel.Conditions = DetachIndexScanConditions(conds, is)
-		memDB := infoschema.IsMemoryDB(p.DBName.L)
+		if len(hiddenCols) > 0 {
+			newSel.Conditions = restoreHiddenColumns(newSel.Conditions, hiddenCols, genExprs)
+		}
+		memDB := p.isMemTable()
 		isDistReq := !memDB && client != nil && client.SupportRequestType(kv.ReqTypeIndex, 0)
 		if isDistReq {
 			idxConds, tblConds := DetachIndexFilterConditions(newSel.Conditions, is.Index.Columns, is.Table)
@@ -161,6 +178,9 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 			}
 			log.Warn("truncate error in buildIndexRange")
 		}
+		if len(hiddenCols) > 0 {
+			is.AccessCondition = restoreHiddenColumns(is.AccessCondition, hiddenCols, genExprs)
+		}
 		rowCount, err = is.getRowCountByIndexRanges(sc, statsTbl)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -197,6 +217,99 @@ func isCoveringIndex(columns []*model.ColumnInfo, indexColumns []*model.IndexCol
 	return true
 }
 
+// buildExpressionIndexColumns returns the hidden columns of the expression index and their expressions on the columns
+// of the data source. Nothing is returned if an expression uses the column which isn't in the data source.
+func (p *DataSource) buildExpressionIndexColumns(index *model.IndexInfo) ([]*expression.Column, []expression.Expression) {
+	var (
+		hiddenCols []*expression.Column
+		genExprs   []expression.Expression
+	)
+	for _, ic := range index.Columns {
+		colInfo := p.tableInfo.Columns[ic.Offset]
+		if !colInfo.IsGenerated() {
+			continue
+		}
+		genExpr, err := table.BuildGeneratedExpr(p.ctx, p.tableInfo, colInfo)
+		if err != nil {
+			log.Warnf("[plan] build the expression of index %s err %v", index.Name, err)
+			return nil, nil
+		}
+		found := true
+		genExpr = replaceExpr(genExpr, func(expr expression.Expression) expression.Expression {
+			col, ok := expr.(*expression.Column)
+			if !ok {
+				return nil
+			}
+			for _, schemaCol := range p.schema.Columns {
+				if schemaCol.ColName.L == col.ColName.L {
+					return schemaCol.Clone()
+				}
+			}
+			found = false
+			return col
+		})
+		if !found {
+			return nil, nil
+		}
+		hiddenCols = append(hiddenCols, &expression.Column{
+			FromID:   p.id,
+			ColName:  colInfo.Name,
+			TblName:  p.tableInfo.Name,
+			DBName:   p.DBName,
+			RetType:  &colInfo.FieldType,
+			Position: colInfo.Offset,
+			ID:       colInfo.ID,
+		})
+		genExprs = append(genExprs, genExpr)
+	}
+	return hiddenCols, genExprs
+}
+
+// restoreHiddenColumns replaces the hidden columns in the conditions by their expressions.
+func restoreHiddenColumns(conds []expression.Expression, hiddenCols []*expression.Column,
+	genExprs []expression.Expression) []expression.Expression {
+	restored := make([]expression.Expression, 0, len(conds))
+	for _, cond := range conds {
+		restored = append(restored, replaceExpr(cond, func(expr expression.Expression) expression.Expression {
+			if col, ok := expr.(*expression.Column); ok {
+				for i, hiddenCol := range hiddenCols {
+					if col.ColName.L == hiddenCol.ColName.L {
+						return genExprs[i].Clone()
+					}
+				}
+			}
+			return nil
+		}))
+	}
+	return restored
+}
+
+// replaceExpr returns the expression whose sub-expressions are replaced by the results of f, the sub-expression is
+// kept if f returns nil.
+func replaceExpr(expr expression.Expression, f func(expression.Expression) expression.Expression) expression.Expression {
+	if newExpr := f(expr); newExpr != nil {
+		return newExpr
+	}
+	sf, ok := expr.(*expression.ScalarFunction)
+	if !ok {
+		return expr
+	}
+	if sf.FuncName.L == ast.Cast {
+		newFunc := sf.Clone().(*expression.ScalarFunction)
+		newFunc.GetArgs()[0] = replaceExpr(newFunc.GetArgs()[0], f)
+		return newFunc
+	}
+	newArgs := make([]expression.Expression, 0, len(sf.GetArgs()))
+	for _, arg := range sf.GetArgs() {
+		newArgs = append(newArgs, replaceExpr(arg, f))
+	}
+	newFunc, err := expression.NewFunction(sf.GetCtx(), sf.FuncName.L, sf.RetType, newArgs...)
+	if err != nil {
+		return expr
+	}
+	return newFunc
+}
+
 func (p *DataSource) need2ConsiderIndex(prop *requiredProperty) bool {
 	if _, ok := p.parents[0].(*Selection); ok || len(prop.props) > 0 {
 		return true
@@ -204,6 +317,12 @@ func (p *DataSource) need2ConsiderIndex(prop *requiredProperty) bool {
 	return false
 }
 
+// isMemTable checks if the rows of the table are kept in the TiDB server, they are scanned by MemTableScan rather
+// than being read from the store.
+func (p *DataSource) isMemTable() bool {
+	return infoschema.IsMemoryDB(p.DBName.L) || p.tableInfo.Temporary
+}
+
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
 // If there is no index that matches the required property, the returned physicalPlanInfo
 // will be table scan and has the cost of MaxInt64. But this can be ignored because the parent will call
@@ -222,7 +341,7 @@ func (p *DataSource) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlan
 		return info, errors.Trace(err)
 	}
 	client := p.ctx.GetClient()
-	memDB := infoschema.IsMemoryDB(p.DBName.L)
+	memDB := p.isMemTable()
 	isDistReq := !memDB && client != nil && client.SupportRequestType(kv.ReqTypeSelect, 0)
 	if !isDistReq {
 		memTable := &PhysicalMemTable{
@@ -396,6 +515,28 @@ func (p *Limit) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	return info, nil
 }
 
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+// The rows locked by the other transactions are skipped by SKIP LOCKED, so the limit isn't pushed down below it.
+func (p *SelectLock) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	if p.Lock != ast.SelectLockForUpdateSkipLocked {
+		return p.baseLogicalPlan.convert2PhysicalPlan(prop)
+	}
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	info, err = p.children[0].(LogicalPlan).convert2PhysicalPlan(removeLimit(prop))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info = addPlanToResponse(p, info)
+	info = enforceProperty(limitProperty(prop.limit), info)
+	return info, p.storePlanInfo(prop, info)
+}
+
 // convert2PhysicalPlanSemi converts the semi join to *physicalPlanInfo.
 func (p *Join) convert2PhysicalPlanSemi(prop *requiredProperty) (*physicalPlanInfo, error) {
 	lChild := p.children[0].(LogicalPlan)
@@ -465,7 +606,7 @@ func (p *Join) convert2PhysicalPlanLeft(prop *requiredProperty, innerJoin bool)
 		OtherConditions: p.OtherConditions,
 		SmallTable:      1,
 		// TODO: decide concurrency by data size.
-		Concurrency:   JoinConcurrency,
+		Concurrency:   p.ctx.GetSessionVars().HashJoinConcurrency,
 		DefaultValues: p.DefaultValues,
 	}
 	join.tp = "HashLeftJoin"
@@ -537,7 +678,7 @@ func (p *Join) convert2PhysicalPlanRight(prop *requiredProperty, innerJoin bool)
 		RightConditions: p.RightConditions,
 		OtherConditions: p.OtherConditions,
 		// TODO: decide concurrency by data size.
-		Concurrency:   JoinConcurrency,
+		Concurrency:   p.ctx.GetSessionVars().HashJoinConcurrency,
 		DefaultValues: p.DefaultValues,
 	}
 	join.tp = "HashRightJoin"
@@ -627,8 +768,10 @@ func constructPropertyByJoin(join *Join) ([][]*requiredProperty, []int, error) {
 }
 
 // convert2PhysicalMergeJoin converts the merge join to *physicalPlanInfo.
+// If enforceSort is false, both children must deliver the required order by themselves, e.g. by index scans,
+// otherwise nil is returned.
 // TODO: Refactor and merge with hash join
-func (p *Join) convert2PhysicalMergeJoin(parentProp *requiredProperty, lProp *requiredProperty, rProp *requiredProperty, condIndex int, joinType JoinType) (*physicalPlanInfo, error) {
+func (p *Join) convert2PhysicalMergeJoin(parentProp *requiredProperty, lProp *requiredProperty, rProp *requiredProperty, condIndex int, joinType JoinType, enforceSort bool) (*physicalPlanInfo, error) {
 	lChild := p.children[0].(LogicalPlan)
 	rChild := p.children[1].(LogicalPlan)
 
@@ -661,54 +804,74 @@ func (p *Join) convert2PhysicalMergeJoin(parentProp *requiredProperty, lProp *re
 	join.SetSchema(p.schema)
 	join.JoinType = joinType
 
-	var lInfo *physicalPlanInfo
-	var rInfo *physicalPlanInfo
-
-	// Try no sort first
-	lInfoEnforceSort, err := lChild.convert2PhysicalPlan(&requiredProperty{})
-	if err != nil {
+	lInfo, err := convert2OrderedChild(lChild, lProp, enforceSort)
+	if err != nil || lInfo == nil {
 		return nil, errors.Trace(err)
 	}
-
-	lInfoEnforceSort = enforceProperty(lProp, lInfoEnforceSort)
-
-	lInfoNoSorted, err := lChild.convert2PhysicalPlan(lProp)
-	if err != nil {
+	rInfo, err := convert2OrderedChild(rChild, rProp, enforceSort)
+	if err != nil || rInfo == nil {
 		return nil, errors.Trace(err)
 	}
+	parentProp = join.tryConsumeOrder(parentProp, eqCond)
 
-	if lInfoNoSorted.cost < lInfoEnforceSort.cost {
-		lInfo = lInfoNoSorted
-	} else {
-		lInfo = lInfoEnforceSort
-	}
+	resultInfo := join.matchProperty(parentProp, lInfo, rInfo)
+	// TODO: Considering keeping order in join to remove at least
+	// one ordering property
+	resultInfo = enforceProperty(parentProp, resultInfo)
+	return resultInfo, nil
+}
 
-	rInfoEnforceSort, err := rChild.convert2PhysicalPlan(&requiredProperty{})
+// convert2OrderedChild converts a child of merge join to *physicalPlanInfo which satisfies the required order.
+// If enforceSort is true, the cheaper one of reading in order and sorting is returned. Otherwise only reading
+// in order is considered, and nil is returned if the child can't deliver the order without a sort or a double read.
+func convert2OrderedChild(child LogicalPlan, prop *requiredProperty, enforceSort bool) (*physicalPlanInfo, error) {
+	if !enforceSort {
+		info, err := child.convert2PhysicalPlan(prop)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if info.p == nil || info.cost == math.MaxFloat64 || !isNaturallyOrdered(info.p) {
+			return nil, nil
+		}
+		return info, nil
+	}
+	infoEnforceSort, err := child.convert2PhysicalPlan(&requiredProperty{})
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	rInfoEnforceSort = enforceProperty(rProp, rInfoEnforceSort)
-
-	rInfoNoSorted, err := rChild.convert2PhysicalPlan(rProp)
+	infoEnforceSort = enforceProperty(prop, infoEnforceSort)
+	infoNoSorted, err := child.convert2PhysicalPlan(prop)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-
-	if rInfoEnforceSort.cost < rInfoNoSorted.cost {
-		rInfo = rInfoEnforceSort
-	} else {
-		rInfo = rInfoNoSorted
+	if infoEnforceSort.cost < infoNoSorted.cost {
+		return infoEnforceSort, nil
 	}
-	parentProp = join.tryConsumeOrder(parentProp, eqCond)
+	return infoNoSorted, nil
+}
 
-	resultInfo := join.matchProperty(parentProp, lInfo, rInfo)
-	// TODO: Considering keeping order in join to remove at least
-	// one ordering property
-	resultInfo = enforceProperty(parentProp, resultInfo)
-	return resultInfo, nil
+// isNaturallyOrdered checks if the plan tree keeps the order of its underlying scans, that is, there is
+// neither a sort operator nor an index scan that has to read the table rows in another kv request.
+func isNaturallyOrdered(p Plan) bool {
+	switch x := p.(type) {
+	case *Sort:
+		return false
+	case *PhysicalIndexScan:
+		if x.DoubleRead {
+			return false
+		}
+	}
+	for _, child := range p.Children() {
+		if !isNaturallyOrdered(child) {
+			return false
+		}
+	}
+	return true
 }
 
-func (p *Join) convert2PhysicalMergeJoinOnCost(prop *requiredProperty) (*physicalPlanInfo, error) {
+// convert2PhysicalMergeJoinOnCost tries every equal condition as the merge key and returns the cheapest plan.
+// It returns nil if no merge join plan can be built.
+func (p *Join) convert2PhysicalMergeJoinOnCost(prop *requiredProperty, enforceSort bool) (*physicalPlanInfo, error) {
 	var info *physicalPlanInfo
 	reqPropPairs, condIndex, err := constructPropertyByJoin(p)
 	if err != nil {
@@ -720,10 +883,13 @@ func (p *Join) convert2PhysicalMergeJoinOnCost(prop *requiredProperty) (*physica
 	minCost := math.MaxFloat64
 	var minInfo *physicalPlanInfo
 	for i, reqPropPair := range reqPropPairs {
-		info, err = p.convert2PhysicalMergeJoin(prop, reqPropPair[0], reqPropPair[1], condIndex[i], p.JoinType)
+		info, err = p.convert2PhysicalMergeJoin(prop, reqPropPair[0], reqPropPair[1], condIndex[i], p.JoinType, enforceSort)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		if info == nil {
+			continue
+		}
 		// Force to choose first instead of nil if all Inf cost
 		// TODO: Consider cost instead of force merge
 		if minInfo == nil {
@@ -748,6 +914,218 @@ func (p *Join) ifValidForMergeJoin() bool {
 	return true
 }
 
+// findIndexJoinLookUpKey finds the join key that can be used to look up the inner table of an index join, which is
+// the handle or the first column of an index. It returns the position of the key and the index, the index is nil if
+// the handle is used. The position is -1 if no key can be used.
+func (p *DataSource) findIndexJoinLookUpKey(outerKeys, innerKeys []*expression.Column) (int, *model.IndexInfo) {
+	indices, includeTableScan := availableIndices(p.indexHints, p.tableInfo)
+	colInfos := make([]*model.ColumnInfo, len(innerKeys))
+	for i, innerKey := range innerKeys {
+		// The outer keys are converted to the type of the inner key to build the ranges, so the conversion
+		// must be the same as the one used for comparing them.
+		if types.MergeFieldType(outerKeys[i].RetType.Tp, innerKey.RetType.Tp) != innerKey.RetType.Tp {
+			continue
+		}
+		if idx := p.Schema().ColumnIndex(innerKey); idx != -1 {
+			colInfos[i] = p.Columns[idx]
+		}
+	}
+	if includeTableScan && p.tableInfo.PKIsHandle {
+		for i, colInfo := range colInfos {
+			if colInfo != nil && mysql.HasPriKeyFlag(colInfo.Flag) {
+				return i, nil
+			}
+		}
+	}
+	for i, colInfo := range colInfos {
+		if colInfo == nil {
+			continue
+		}
+		for _, index := range indices {
+			idxCol := index.Columns[0]
+			if idxCol.Name.L == colInfo.Name.L && idxCol.Length == types.UnspecifiedLength {
+				return i, index
+			}
+		}
+	}
+	return -1, nil
+}
+
+// convert2IndexJoinInner builds the scan of the inner table of an index join. It reads the rows by the handle if
+// index is nil, otherwise by the index. The ranges of the scan are built from the outer rows during execution.
+// The conditions that can't be pushed down to the scan are returned.
+func (p *DataSource) convert2IndexJoinInner(index *model.IndexInfo, conds []expression.Expression) (PhysicalPlan, []expression.Expression) {
+	client := p.ctx.GetClient()
+	sc := p.ctx.GetSessionVars().StmtCtx
+	newConds := make([]expression.Expression, 0, len(conds))
+	for _, cond := range conds {
+		newConds = append(newConds, cond.Clone())
+	}
+	if index == nil {
+		ts := &PhysicalTableScan{
+			Table:               p.tableInfo,
+			Columns:             p.Columns,
+			TableAsName:         p.TableAsName,
+			DBName:              p.DBName,
+			physicalTableSource: physicalTableSource{client: client, readOnly: true},
+		}
+		ts.tp = Tbl
+		ts.allocator = p.allocator
+		ts.SetSchema(p.Schema())
+		ts.initIDAndContext(p.ctx)
+		ts.TableConditionPBExpr, ts.tableFilterConditions, newConds = ExpressionsToPB(sc, newConds, client)
+		return ts, newConds
+	}
+	is := &PhysicalIndexScan{
+		Index:               index,
+		Table:               p.tableInfo,
+		Columns:             p.Columns,
+		TableAsName:         p.TableAsName,
+		OutOfOrder:          true,
+		DBName:              p.DBName,
+		physicalTableSource: physicalTableSource{client: client, readOnly: true},
+	}
+	is.tp = Idx
+	is.allocator = p.allocator
+	is.initIDAndContext(p.ctx)
+	is.SetSchema(p.Schema())
+	is.DoubleRead = !isCoveringIndex(is.Columns, is.Index.Columns, is.Table.PKIsHandle)
+	if client.SupportRequestType(kv.ReqTypeIndex, 0) {
+		idxConds, tblConds := DetachIndexFilterConditions(newConds, is.Index.Columns, is.Table)
+		is.IndexConditionPBExpr, is.indexFilterConditions, idxConds = ExpressionsToPB(sc, idxConds, client)
+		is.TableConditionPBExpr, is.tableFilterConditions, tblConds = ExpressionsToPB(sc, tblConds, client)
+		newConds = append(idxConds, tblConds...)
+	}
+	return is, newConds
+}
+
+// convert2IndexJoin converts the join to an index nested loop join which uses the outerIdx-th child as the outer side.
+// It returns nil if the inner child is not a table that can be looked up by a join key.
+func (p *Join) convert2IndexJoin(prop *requiredProperty, outerIdx int) (*physicalPlanInfo, error) {
+	innerChild := p.children[1-outerIdx].(LogicalPlan)
+	var innerConds []expression.Expression
+	if sel, ok := innerChild.(*Selection); ok {
+		innerConds = sel.Conditions
+		innerChild = sel.children[0].(LogicalPlan)
+	}
+	ds, ok := innerChild.(*DataSource)
+	if !ok {
+		return nil, nil
+	}
+	client := p.ctx.GetClient()
+	if ds.isMemTable() || client == nil || !client.SupportRequestType(kv.ReqTypeSelect, 0) {
+		return nil, nil
+	}
+	// The look up can't read the rows written in the current transaction.
+	if p.ctx.Txn() != nil && !p.ctx.Txn().IsReadOnly() {
+		return nil, nil
+	}
+	outerKeys := make([]*expression.Column, 0, len(p.EqualConditions))
+	innerKeys := make([]*expression.Column, 0, len(p.EqualConditions))
+	for _, eqCond := range p.EqualConditions {
+		lKey, lOK := eqCond.GetArgs()[0].(*expression.Column)
+		rKey, rOK := eqCond.GetArgs()[1].(*expression.Column)
+		if !lOK || !rOK {
+			return nil, nil
+		}
+		if outerIdx == 0 {
+			outerKeys, innerKeys = append(outerKeys, lKey), append(innerKeys, rKey)
+		} else {
+			outerKeys, innerKeys = append(outerKeys, rKey), append(innerKeys, lKey)
+		}
+	}
+	keyIdx, index := ds.findIndexJoinLookUpKey(outerKeys, innerKeys)
+	if keyIdx == -1 {
+		return nil, nil
+	}
+	// The look up key is always the first one.
+	outerKeys[0], outerKeys[keyIdx] = outerKeys[keyIdx], outerKeys[0]
+	innerKeys[0], innerKeys[keyIdx] = innerKeys[keyIdx], innerKeys[0]
+	innerPlan, remained := ds.convert2IndexJoinInner(index, innerConds)
+
+	join := &PhysicalIndexJoin{
+		JoinType:        p.JoinType,
+		OuterIndex:      outerIdx,
+		OuterJoinKeys:   outerKeys,
+		InnerJoinKeys:   innerKeys,
+		LeftConditions:  p.LeftConditions,
+		RightConditions: p.RightConditions,
+		OtherConditions: p.OtherConditions,
+		DefaultValues:   p.DefaultValues,
+	}
+	if outerIdx == 0 {
+		join.RightConditions = append(append([]expression.Expression{}, p.RightConditions...), remained...)
+	} else {
+		join.LeftConditions = append(append([]expression.Expression{}, p.LeftConditions...), remained...)
+	}
+	join.tp = "IndexJoin"
+	join.allocator = p.allocator
+	join.initIDAndContext(p.ctx)
+	join.SetSchema(p.schema)
+
+	// The index join keeps the order of the outer child.
+	outerChild := p.children[outerIdx].(LogicalPlan)
+	allOuter := true
+	for _, col := range prop.props {
+		if !outerChild.Schema().Contains(col.col) {
+			allOuter = false
+		}
+	}
+	outerProp := prop
+	if !allOuter {
+		outerProp = &requiredProperty{}
+	}
+	var outerInfo *physicalPlanInfo
+	var err error
+	if p.JoinType == InnerJoin {
+		outerInfo, err = outerChild.convert2PhysicalPlan(removeLimit(outerProp))
+	} else {
+		outerInfo, err = outerChild.convert2PhysicalPlan(convertLimitOffsetToCount(outerProp))
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if outerInfo.p == nil {
+		return nil, nil
+	}
+	innerInfo := &physicalPlanInfo{p: innerPlan, count: uint64(ds.statisticTable.Count)}
+	var resultInfo *physicalPlanInfo
+	if outerIdx == 0 {
+		resultInfo = join.matchProperty(prop, outerInfo, innerInfo)
+	} else {
+		resultInfo = join.matchProperty(prop, innerInfo, outerInfo)
+	}
+	if !allOuter {
+		resultInfo = enforceProperty(prop, resultInfo)
+	} else {
+		resultInfo = enforceProperty(limitProperty(prop.limit), resultInfo)
+	}
+	return resultInfo, nil
+}
+
+// convert2IndexJoinByHint converts the join to an index nested loop join whose outer table is specified by the
+// TIDB_INLJ hint. It returns nil if it's impossible, then the other join algorithms are considered.
+func (p *Join) convert2IndexJoinByHint(prop *requiredProperty) (*physicalPlanInfo, error) {
+	var info *physicalPlanInfo
+	if p.preferINLJ&preferLeftAsOuter > 0 && (p.JoinType == InnerJoin || p.JoinType == LeftOuterJoin) {
+		lInfo, err := p.convert2IndexJoin(prop, 0)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		info = lInfo
+	}
+	if p.preferINLJ&preferRightAsOuter > 0 && (p.JoinType == InnerJoin || p.JoinType == RightOuterJoin) {
+		rInfo, err := p.convert2IndexJoin(prop, 1)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if rInfo != nil && (info == nil || rInfo.cost < info.cost) {
+			info = rInfo
+		}
+	}
+	return info, nil
+}
+
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
 func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	info, err := p.getPlanInfo(prop)
@@ -757,6 +1135,16 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	if info != nil {
 		return info, nil
 	}
+	if p.preferINLJ > 0 {
+		info, err = p.convert2IndexJoinByHint(prop)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if info != nil {
+			p.storePlanInfo(prop, info)
+			return info, nil
+		}
+	}
 	switch p.JoinType {
 	case SemiJoin, LeftOuterSemiJoin:
 		info, err = p.convert2PhysicalPlanSemi(prop)
@@ -765,7 +1153,7 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 		}
 	case LeftOuterJoin:
 		if p.preferMergeJoin && p.ifValidForMergeJoin() {
-			info, err = p.convert2PhysicalMergeJoinOnCost(prop)
+			info, err = p.convert2PhysicalMergeJoinOnCost(prop, true)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -780,7 +1168,7 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 		}
 	case RightOuterJoin:
 		if p.preferMergeJoin && p.ifValidForMergeJoin() {
-			info, err = p.convert2PhysicalMergeJoinOnCost(prop)
+			info, err = p.convert2PhysicalMergeJoinOnCost(prop, true)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -795,7 +1183,7 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	default:
 		// Inner Join
 		if p.preferMergeJoin && p.ifValidForMergeJoin() {
-			info, err = p.convert2PhysicalMergeJoinOnCost(prop)
+			info, err = p.convert2PhysicalMergeJoinOnCost(prop, true)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -817,6 +1205,17 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 			}
 		}
 	}
+	// Without the hint, merge join is still chosen when both children are already ordered on a join key
+	// and it is cheaper, since it doesn't need to build a hash table.
+	if !p.preferMergeJoin && p.JoinType != SemiJoin && p.JoinType != LeftOuterSemiJoin && p.ifValidForMergeJoin() {
+		mergeInfo, err := p.convert2PhysicalMergeJoinOnCost(prop, false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if mergeInfo != nil && mergeInfo.cost < info.cost {
+			info = mergeInfo
+		}
+	}
 	p.storePlanInfo(prop, info)
 	return info, nil
 }
@@ -1191,7 +1590,7 @@ func (p *Selection) convert2PhysicalPlanPushOrder(prop *requiredProperty) (*phys
 		info = p.appendSelToInfo(info)
 	} else {
 		client := p.ctx.GetClient()
-		memDB := infoschema.IsMemoryDB(ds.DBName.L)
+		memDB := ds.isMemTable()
 		isDistReq := !memDB && client != nil && client.SupportRequestType(kv.ReqTypeSelect, 0)
 		if !isDistReq {
 			info = p.appendSelToInfo(info)
@@ -1291,6 +1690,26 @@ func matchProp(ctx context.Context, target, new *requiredProperty) bool {
 	return true
 }
 
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+// The window functions are evaluated on all the rows of the child in the order of its Sort child, so neither the
+// required order nor the limit can be passed to the child.
+func (p *Window) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	info, err = p.children[0].(LogicalPlan).convert2PhysicalPlan(&requiredProperty{})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info = addPlanToResponse(p, info)
+	info = enforceProperty(prop, info)
+	return info, p.storePlanInfo(prop, info)
+}
+
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
 func (p *Sort) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	info, err := p.getPlanInfo(prop)
@@ -1478,8 +1897,13 @@ func addCachePlan(p PhysicalPlan, allocator *idAllocator) []*expression.Correlat
 	}
 	selfCorCols := p.extractCorrelatedCols()
 	newChildren := make([]Plan, 0, len(p.Children()))
-	for _, child := range p.Children() {
+	for i, child := range p.Children() {
 		childCorCols := addCachePlan(child.(PhysicalPlan), allocator)
+		// The inner child of an index join is read with different ranges for every batch of outer rows.
+		if join, ok := p.(*PhysicalIndexJoin); ok && i != join.OuterIndex {
+			newChildren = append(newChildren, child)
+			continue
+		}
 		// If p is a Selection and controls the access condition of below scan plan, there shouldn't have a cache plan.
 		if sel, ok := p.(*Selection); len(selfCorCols) > 0 && len(childCorCols) == 0 && (!ok || !sel.ScanController) {
 			newChild := &Cache{}
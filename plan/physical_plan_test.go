@@ -172,6 +172,18 @@ func (s *testPlanSuite) TestPushDownOrderbyAndLimit(c *C) {
 			orderByItmes: "[]",
 			limit:        "nil",
 		},
+		{
+			sql:          "select * from t limit 5 for update",
+			best:         "Table(t)->Limit->Lock->Projection",
+			orderByItmes: "[]",
+			limit:        "5",
+		},
+		{
+			sql:          "select * from t limit 5 for update skip locked",
+			best:         "Table(t)->Lock->Limit->Projection",
+			orderByItmes: "[]",
+			limit:        "nil",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
@@ -366,6 +378,26 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select * from t t1 use index(c_d_e)",
 			best: "Index(t.c_d_e)[[<nil>,+inf]]",
 		},
+		{
+			sql:  "select * from t a, t b where a.a = b.a",
+			best: "MergeJoin{Table(t)->Table(t)}(a.a,b.a)",
+		},
+		{
+			sql:  "select /*+ TIDB_INLJ(a) */ * from t a, t b where a.c = b.a",
+			best: "IndexJoin{Table(t)->Table(t)}(a.c,b.a)",
+		},
+		{
+			sql:  "select /*+ TIDB_INLJ(b) */ * from t a left join t b on a.a = b.c and a.d = b.b",
+			best: "LeftHashJoin{Table(t)->Table(t)}(a.a,b.c)(a.d,b.b)",
+		},
+		{
+			sql:  "select /*+ TIDB_INLJ(a) */ * from t a left join t b on a.d = b.b and a.a = b.c where b.d > 1",
+			best: "IndexJoin{Table(t)->Index(t.c_d_e)[]}(a.a,b.c)(a.d,b.b)",
+		},
+		{
+			sql:  "select a.c, b.c from t a, t b where a.c = b.c",
+			best: "MergeJoin{Index(t.c_d_e)[[<nil>,+inf]]->Index(t.c_d_e)[[<nil>,+inf]]}(a.c,b.c)",
+		},
 		{
 			sql:  "select * from t where (t.c > 0 and t.c < 1) or (t.c > 2 and t.c < 3) or (t.c > 4 and t.c < 5) or (t.c > 6 and t.c < 7) or (t.c > 9 and t.c < 10)",
 			best: "Index(t.c_d_e)[(0 +inf,1 <nil>) (2 +inf,3 <nil>) (4 +inf,5 <nil>) (6 +inf,7 <nil>) (9 +inf,10 <nil>)]",
@@ -436,7 +468,7 @@ func (s *testPlanSuite) TestCBO(c *C) {
 		},
 		{
 			sql:  "select sum(b.a) from t a, t b where a.c = b.c and cast(b.d as char) group by b.d",
-			best: "RightHashJoin{Index(t.c_d_e)[[<nil>,+inf]]->Selection->StreamAgg->Table(t)}(b.c,a.c)->HashAgg",
+			best: "MergeJoin{Index(t.c_d_e)[[<nil>,+inf]]->Selection->StreamAgg->Index(t.c_d_e)[[<nil>,+inf]]}(b.c,a.c)->HashAgg",
 		},
 		{
 			sql:  "select count(*) from t group by e order by d limit 1",
@@ -458,6 +490,15 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select count(distinct e) from t group by d",
 			best: "Table(t)->HashAgg",
 		},
+		{
+			// The group by columns are in a different order from the index.
+			sql:  "select count(*) from t where concat(a,b) = 'abc' group by d, c",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]->Selection->StreamAgg",
+		},
+		{
+			sql:  "select count(distinct e) from t where c = 1 and concat(c,d) = 'abc' group by e, d",
+			best: "Index(t.c_d_e)[[1,1]]->Selection->StreamAgg",
+		},
 		{
 			// Multi distinct column can't apply stream agg.
 			sql:  "select count(distinct e), sum(distinct c) from t where c = 1 group by d",
@@ -788,7 +829,7 @@ func (s *testPlanSuite) TestAddCache(c *C) {
 	}{
 		{
 			sql: "select * from t t1 where t1.a=(select min(t2.a) from t t2, t t3 where t2.a=t3.a and t2.b > t1.b + t3.b)",
-			ans: "Apply{Table(t)->LeftHashJoin{Table(t)->Cache->Table(t)->Cache}(t2.a,t3.a)->StreamAgg->MaxOneRow}->Selection->Projection",
+			ans: "Apply{Table(t)->MergeJoin{Table(t)->Cache->Table(t)->Cache}(t2.a,t3.a)->StreamAgg->MaxOneRow}->Selection->Projection",
 		},
 	}
 	for _, ca := range cases {
@@ -297,7 +297,8 @@ func (p *physicalTableSource) addAggregation(ctx context.Context, agg *PhysicalA
 	agg.GroupByItems = []expression.Expression{schema.Columns[cursor]}
 	newAggFuncs := make([]expression.AggregationFunction, len(agg.AggFuncs))
 	for i, aggFun := range agg.AggFuncs {
-		fun := expression.NewAggFunction(aggFun.GetName(), nil, false)
+		// The function is cloned to keep its attributes such as the separator of group_concat.
+		fun := aggFun.Clone()
 		var args []expression.Expression
 		colName := model.NewCIStr(fmt.Sprint(aggFun.GetArgs()))
 		if needCount(fun) {
@@ -384,6 +385,27 @@ type PhysicalMergeJoin struct {
 	Desc          bool
 }
 
+// PhysicalIndexJoin represents the index nested loop join. It reads a batch of rows from the outer child, then
+// looks up the matching rows of the inner table through its handle or an index on the join key.
+// The inner child is a table scan or an index scan without ranges, whose ranges are built from the outer rows
+// during execution.
+type PhysicalIndexJoin struct {
+	basePlan
+
+	JoinType JoinType
+	// OuterIndex is the index of the outer child, 0 means the left child and 1 means the right child.
+	OuterIndex int
+
+	// OuterJoinKeys and InnerJoinKeys are the join keys of both sides. The first pair is used to look up the inner table.
+	OuterJoinKeys   []*expression.Column
+	InnerJoinKeys   []*expression.Column
+	LeftConditions  []expression.Expression
+	RightConditions []expression.Expression
+	OtherConditions []expression.Expression
+
+	DefaultValues []types.Datum
+}
+
 // PhysicalHashSemiJoin represents hash join for semi join.
 type PhysicalHashSemiJoin struct {
 	basePlan
@@ -431,6 +453,14 @@ type Cache struct {
 	basePlan
 }
 
+// PhysicalCTE materializes the common table expressions before its first child, which is the plan of the statement,
+// is executed. The other children are the plans of the CTEs, they're only used for explaining.
+type PhysicalCTE struct {
+	basePlan
+
+	CTEs []*CTEDefinition
+}
+
 func (p *PhysicalMergeJoin) tryConsumeOrder(prop *requiredProperty, eqCond *expression.ScalarFunction) *requiredProperty {
 	// TODO: We still can consume a partial sorted results somehow if main key matched.
 	// To do that, we need a Sort operator being able to do a secondary sort
@@ -486,6 +516,37 @@ func (p *PhysicalHashJoin) extractCorrelatedCols() []*expression.CorrelatedColum
 	return corCols
 }
 
+func (p *PhysicalMergeJoin) extractCorrelatedCols() []*expression.CorrelatedColumn {
+	corCols := p.basePlan.extractCorrelatedCols()
+	for _, fun := range p.EqualConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	for _, fun := range p.LeftConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	for _, fun := range p.RightConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	for _, fun := range p.OtherConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	return corCols
+}
+
+func (p *PhysicalIndexJoin) extractCorrelatedCols() []*expression.CorrelatedColumn {
+	corCols := p.basePlan.extractCorrelatedCols()
+	for _, fun := range p.LeftConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	for _, fun := range p.RightConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	for _, fun := range p.OtherConditions {
+		corCols = append(corCols, extractCorColumns(fun)...)
+	}
+	return corCols
+}
+
 func (p *PhysicalHashSemiJoin) extractCorrelatedCols() []*expression.CorrelatedColumn {
 	corCols := p.basePlan.extractCorrelatedCols()
 	for _, fun := range p.EqualConditions {
@@ -599,6 +660,79 @@ func (p *PhysicalApply) Copy() PhysicalPlan {
 	return &np
 }
 
+// CloneInnerPlan copies the inner plan with new correlated columns, so the copy can be executed for an outer row while
+// the original one is executed for another. The returned correlated columns are in the order of OuterSchema.
+// It returns false if the inner plan contains a plan that can't be copied.
+func (p *PhysicalApply) CloneInnerPlan() (PhysicalPlan, []*expression.CorrelatedColumn, bool) {
+	dataMap := make(map[*types.Datum]*types.Datum, len(p.OuterSchema))
+	corCols := make([]*expression.CorrelatedColumn, 0, len(p.OuterSchema))
+	for _, col := range p.OuterSchema {
+		data := new(types.Datum)
+		dataMap[col.Data] = data
+		corCols = append(corCols, &expression.CorrelatedColumn{Column: col.Column, Data: data})
+	}
+	inner, ok := cloneWithCorColData(p.PhysicalJoin.Children()[1].(PhysicalPlan), dataMap)
+	return inner, corCols, ok
+}
+
+func cloneWithCorColData(p PhysicalPlan, dataMap map[*types.Datum]*types.Datum) (PhysicalPlan, bool) {
+	children := make([]Plan, 0, len(p.Children()))
+	for _, child := range p.Children() {
+		newChild, ok := cloneWithCorColData(child.(PhysicalPlan), dataMap)
+		if !ok {
+			return nil, false
+		}
+		children = append(children, newChild)
+	}
+	substitute := func(exprs []expression.Expression) []expression.Expression {
+		newExprs := make([]expression.Expression, 0, len(exprs))
+		for _, expr := range exprs {
+			newExprs = append(newExprs, expression.SubstituteCorColData(expr, dataMap))
+		}
+		return newExprs
+	}
+	np := p.Copy()
+	switch x := np.(type) {
+	case *PhysicalTableScan, *PhysicalIndexScan, *PhysicalMemTable, *PhysicalDummyScan, *TableDual, *Limit, *Exists,
+		*MaxOneRow, *Union, *Cache:
+	case *Selection:
+		x.Conditions = substitute(x.Conditions)
+	case *Projection:
+		x.Exprs = substitute(x.Exprs)
+	case *Sort:
+		byItems := make([]*ByItems, 0, len(x.ByItems))
+		for _, item := range x.ByItems {
+			byItems = append(byItems, &ByItems{Expr: expression.SubstituteCorColData(item.Expr, dataMap), Desc: item.Desc})
+		}
+		x.ByItems = byItems
+	case *PhysicalAggregation:
+		x.GroupByItems = substitute(x.GroupByItems)
+		aggFuncs := make([]expression.AggregationFunction, 0, len(x.AggFuncs))
+		for _, fun := range x.AggFuncs {
+			newFun := fun.Clone()
+			newFun.SetArgs(substitute(fun.GetArgs()))
+			aggFuncs = append(aggFuncs, newFun)
+		}
+		x.AggFuncs = aggFuncs
+	case *PhysicalHashJoin:
+		x.LeftConditions = substitute(x.LeftConditions)
+		x.RightConditions = substitute(x.RightConditions)
+		x.OtherConditions = substitute(x.OtherConditions)
+	case *PhysicalHashSemiJoin:
+		x.LeftConditions = substitute(x.LeftConditions)
+		x.RightConditions = substitute(x.RightConditions)
+		x.OtherConditions = substitute(x.OtherConditions)
+	case *PhysicalUnionScan:
+		if x.Condition != nil {
+			x.Condition = expression.SubstituteCorColData(x.Condition, dataMap)
+		}
+	default:
+		return nil, false
+	}
+	np.SetChildren(children...)
+	return np, true
+}
+
 // MarshalJSON implements json.Marshaler interface.
 func (p *PhysicalApply) MarshalJSON() ([]byte, error) {
 	join, err := json.Marshal(p.PhysicalJoin)
@@ -733,6 +867,51 @@ func (p *PhysicalMergeJoin) MarshalJSON() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalIndexJoin) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalIndexJoin) MarshalJSON() ([]byte, error) {
+	leftChild := p.children[0].(PhysicalPlan)
+	rightChild := p.children[1].(PhysicalPlan)
+	outerKeys, err := json.Marshal(p.OuterJoinKeys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	innerKeys, err := json.Marshal(p.InnerJoinKeys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	leftConds, err := json.Marshal(p.LeftConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rightConds, err := json.Marshal(p.RightConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	otherConds, err := json.Marshal(p.OtherConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		"\"outerKey\": %s,\n "+
+			"\"innerKey\": %s,\n "+
+			"\"leftCond\": %s,\n "+
+			"\"rightCond\": %s,\n "+
+			"\"otherCond\": %s,\n"+
+			"\"outerIndex\": %d,\n "+
+			"\"leftPlan\": \"%s\",\n "+
+			"\"rightPlan\": \"%s\""+
+			"}",
+		outerKeys, innerKeys, leftConds, rightConds, otherConds, p.OuterIndex, leftChild.ID(), rightChild.ID()))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Selection) Copy() PhysicalPlan {
 	np := *p
@@ -813,6 +992,35 @@ func (p *Union) Copy() PhysicalPlan {
 	return &np
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *Window) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *Window) MarshalJSON() ([]byte, error) {
+	funcs, err := json.Marshal(p.WindowFuncs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	partitionBy, err := json.Marshal(p.PartitionBy)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	orderBy, err := json.Marshal(p.OrderBy)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		" \"funcs\": %s,\n"+
+			" \"partitionBy\": %s,\n"+
+			" \"orderBy\": %s,\n"+
+			" \"child\": \"%s\"}", funcs, partitionBy, orderBy, p.children[0].ID()))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Sort) Copy() PhysicalPlan {
 	np := *p
@@ -912,6 +1120,32 @@ func (p *Cache) Copy() PhysicalPlan {
 	return &np
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalCTE) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalCTE) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(" \"ctes\": [")
+	for i, cte := range p.CTEs {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		recursive := "null"
+		if cte.Recursive != nil {
+			recursive = fmt.Sprintf("\"%s\"", cte.Recursive.ID())
+		}
+		buffer.WriteString(fmt.Sprintf(
+			"{\"name\": \"%s\", \"seed\": \"%s\", \"recursive\": %s, \"distinct\": %v}",
+			cte.Name.O, cte.Seed.ID(), recursive, cte.Distinct))
+	}
+	buffer.WriteString(fmt.Sprintf("],\n \"child\": \"%s\"}", p.children[0].ID()))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the Analyze Copy interface.
 func (p *Analyze) Copy() PhysicalPlan {
 	np := *p
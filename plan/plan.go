@@ -50,6 +50,8 @@ const (
 	App = "Apply"
 	// MOR is the type of MaxOneRow.
 	MOR = "MaxOneRow"
+	// Win is the type of Window.
+	Win = "Window"
 	// Ext is the type of Exists.
 	Ext = "Exists"
 	// Dual is the type of TableDual.
@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -36,22 +37,57 @@ var (
 	ErrUnknownColumn        = terror.ClassOptimizerPlan.New(CodeUnknownColumn, "Unknown column '%s' in '%s'")
 	ErrWrongArguments       = terror.ClassOptimizerPlan.New(CodeWrongArguments, "Incorrect arguments to EXECUTE")
 	ErrAmbiguous            = terror.ClassOptimizerPlan.New(CodeAmbiguous, "Column '%s' in field list is ambiguous")
+	ErrNonUniqTable         = terror.ClassOptimizerPlan.New(CodeNonUniqTable, mysql.MySQLErrName[mysql.ErrNonuniqTable])
+	ErrWrongNumberOfColumns = terror.ClassOptimizerPlan.New(CodeWrongNumberOfColumns,
+		mysql.MySQLErrName[mysql.ErrWrongNumberOfColumnsInSelect])
+	ErrNotSupportedYet = terror.ClassOptimizerPlan.New(CodeNotSupportedYet, mysql.MySQLErrName[mysql.ErrNotSupportedYet])
+	ErrViewWrongList   = terror.ClassOptimizerPlan.New(CodeViewWrongList,
+		"In definition of view, derived table or common table expression, SELECT list and column names list have different column counts")
+	ErrCTERecursiveRequiresUnion = terror.ClassOptimizerPlan.New(CodeCTERecursiveRequiresUnion,
+		mysql.MySQLErrName[mysql.ErrCTERecursiveRequiresUnion])
+	ErrCTERecursiveRequiresNonRecursiveFirst = terror.ClassOptimizerPlan.New(CodeCTERecursiveRequiresNonRecursiveFirst,
+		mysql.MySQLErrName[mysql.ErrCTERecursiveRequiresNonRecursiveFirst])
+	ErrCTERecursiveForbidsAggregation = terror.ClassOptimizerPlan.New(CodeCTERecursiveForbidsAggregation,
+		mysql.MySQLErrName[mysql.ErrCTERecursiveForbidsAggregation])
+	ErrAsOf         = terror.ClassOptimizerPlan.New(CodeAsOf, "invalid AS OF TIMESTAMP: %s")
+	ErrSplitRegion  = terror.ClassOptimizerPlan.New(CodeSplitRegion, "invalid split region: %s")
+	ErrNoSuchThread = terror.ClassOptimizerPlan.New(CodeNoSuchThread, "Unknown thread id: %d")
+	// ErrWindowFuncArguments is returned when the arguments of a window function are invalid.
+	ErrWindowFuncArguments = terror.ClassOptimizerPlan.New(CodeWrongArguments, mysql.MySQLErrName[mysql.ErrWrongArguments])
 )
 
 // Error codes.
 const (
-	CodeUnsupportedType terror.ErrCode = 1
-	SystemInternalError terror.ErrCode = 2
-	CodeAmbiguous       terror.ErrCode = 1052
-	CodeUnknownColumn   terror.ErrCode = 1054
-	CodeWrongArguments  terror.ErrCode = 1210
+	CodeUnsupportedType                       terror.ErrCode = 1
+	SystemInternalError                       terror.ErrCode = 2
+	CodeAsOf                                  terror.ErrCode = 3
+	CodeSplitRegion                           terror.ErrCode = 4
+	CodeAmbiguous                             terror.ErrCode = 1052
+	CodeUnknownColumn                         terror.ErrCode = 1054
+	CodeNonUniqTable                          terror.ErrCode = 1066
+	CodeNoSuchThread                          terror.ErrCode = 1094
+	CodeWrongArguments                        terror.ErrCode = 1210
+	CodeWrongNumberOfColumns                  terror.ErrCode = 1222
+	CodeNotSupportedYet                       terror.ErrCode = 1235
+	CodeViewWrongList                         terror.ErrCode = 1353
+	CodeCTERecursiveRequiresUnion             terror.ErrCode = 3573
+	CodeCTERecursiveRequiresNonRecursiveFirst terror.ErrCode = 3574
+	CodeCTERecursiveForbidsAggregation        terror.ErrCode = 3575
 )
 
 func init() {
 	tableMySQLErrCodes := map[terror.ErrCode]uint16{
-		CodeUnknownColumn:  mysql.ErrBadField,
-		CodeAmbiguous:      mysql.ErrNonUniq,
-		CodeWrongArguments: mysql.ErrWrongArguments,
+		CodeUnknownColumn:                         mysql.ErrBadField,
+		CodeAmbiguous:                             mysql.ErrNonUniq,
+		CodeWrongArguments:                        mysql.ErrWrongArguments,
+		CodeNonUniqTable:                          mysql.ErrNonuniqTable,
+		CodeNoSuchThread:                          mysql.ErrNoSuchThread,
+		CodeWrongNumberOfColumns:                  mysql.ErrWrongNumberOfColumnsInSelect,
+		CodeNotSupportedYet:                       mysql.ErrNotSupportedYet,
+		CodeViewWrongList:                         mysql.ErrViewWrongList,
+		CodeCTERecursiveRequiresUnion:             mysql.ErrCTERecursiveRequiresUnion,
+		CodeCTERecursiveRequiresNonRecursiveFirst: mysql.ErrCTERecursiveRequiresNonRecursiveFirst,
+		CodeCTERecursiveForbidsAggregation:        mysql.ErrCTERecursiveForbidsAggregation,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassOptimizerPlan] = tableMySQLErrCodes
 }
@@ -64,7 +100,8 @@ type visitInfo struct {
 }
 
 type tableHintInfo struct {
-	sortMergeJoinTables []model.CIStr
+	sortMergeJoinTables       []model.CIStr
+	indexNestedLoopJoinTables []model.CIStr
 }
 
 func (info *tableHintInfo) ifPreferMergeJoin(tableNames ...*model.CIStr) bool {
@@ -88,6 +125,20 @@ func (info *tableHintInfo) ifPreferMergeJoin(tableNames ...*model.CIStr) bool {
 	return false
 }
 
+// ifPreferINLJ checks whether the table is listed in the TIDB_INLJ hint, which means that it should be
+// the outer table of an index nested loop join.
+func (info *tableHintInfo) ifPreferINLJ(tableName *model.CIStr) bool {
+	if tableName == nil {
+		return false
+	}
+	for _, curEntry := range info.indexNestedLoopJoinTables {
+		if curEntry.L == tableName.L {
+			return true
+		}
+	}
+	return false
+}
+
 // planBuilder builds Plan from an ast.Node.
 // It just builds the ast node straightforwardly.
 type planBuilder struct {
@@ -101,10 +152,15 @@ type planBuilder struct {
 	inUpdateStmt bool
 	// colMapper stores the column that must be pre-resolved.
 	colMapper map[*ast.ColumnNameExpr]int
+	// windowMapper maps the window functions of the select fields to the columns offset in the output schema of
+	// their Window plans.
+	windowMapper map[*ast.WindowFuncExpr]int
 	// Collect the visit information for privilege check.
 	visitInfo     []visitInfo
 	tableHintInfo []tableHintInfo
 	optFlag       uint64
+	// ctes are the common table expressions of the statement.
+	ctes []*CTEDefinition
 }
 
 func (b *planBuilder) build(node ast.Node) Plan {
@@ -120,10 +176,14 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildExecute(x)
 	case *ast.ExplainStmt:
 		return b.buildExplain(x)
+	case *ast.ExplainForStmt:
+		return b.buildExplainFor(x)
 	case *ast.InsertStmt:
 		return b.buildInsert(x)
 	case *ast.LoadDataStmt:
 		return b.buildLoadData(x)
+	case *ast.LoadStatsStmt:
+		return b.buildLoadStats(x)
 	case *ast.PrepareStmt:
 		return b.buildPrepare(x)
 	case *ast.SelectStmt:
@@ -140,9 +200,12 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildSet(x)
 	case *ast.AnalyzeTableStmt:
 		return b.buildAnalyze(x)
+	case *ast.SplitRegionStmt:
+		return b.buildSplitRegion(x)
 	case *ast.BinlogStmt, *ast.FlushStmt, *ast.UseStmt,
-		*ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt,
-		*ast.GrantStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.RevokeStmt, *ast.KillStmt:
+		*ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt, *ast.SavepointStmt, *ast.ReleaseSavepointStmt,
+		*ast.CreateUserStmt, *ast.SetPwdStmt, *ast.GrantStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.RevokeStmt,
+		*ast.KillStmt, *ast.CreateResourceGroupStmt, *ast.AlterResourceGroupStmt, *ast.DropResourceGroupStmt:
 		return b.buildSimple(node.(ast.StmtNode))
 	case ast.DDLNode:
 		return b.buildDDL(x)
@@ -201,6 +264,10 @@ func (b *planBuilder) buildSet(v *ast.SetStmt) Plan {
 			IsGlobal: vars.IsGlobal,
 			IsSystem: vars.IsSystem,
 		}
+		if vars.IsGlobal {
+			// The global variables affect all the sessions.
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+		}
 		if _, ok := vars.Value.(*ast.DefaultExpr); !ok {
 			assign.Expr, _, b.err = b.rewrite(vars.Value, nil, nil, true)
 			if b.err != nil {
@@ -247,6 +314,15 @@ func (b *planBuilder) detectSelectAgg(sel *ast.SelectStmt) bool {
 	return false
 }
 
+func (b *planBuilder) detectSelectWindow(sel *ast.SelectStmt) bool {
+	for _, f := range sel.Fields.Fields {
+		if ast.HasWindowFlag(f.Expr) {
+			return true
+		}
+	}
+	return false
+}
+
 func availableIndices(hints []*ast.IndexHint, tableInfo *model.TableInfo) (indices []*model.IndexInfo, includeTableScan bool) {
 	var usableHints []*ast.IndexHint
 	for _, hint := range hints {
@@ -256,7 +332,8 @@ func availableIndices(hints []*ast.IndexHint, tableInfo *model.TableInfo) (indic
 	}
 	publicIndices := make([]*model.IndexInfo, 0, len(tableInfo.Indices))
 	for _, index := range tableInfo.Indices {
-		if index.State == model.StatePublic {
+		// The invisible indices are ignored, even if they are in the index hints.
+		if index.State == model.StatePublic && !index.Invisible {
 			publicIndices = append(publicIndices, index)
 		}
 	}
@@ -352,9 +429,30 @@ func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
 	case ast.AdminCheckTable:
 		p = &CheckTable{Tables: as.Tables}
 		p.SetSchema(expression.NewSchema())
+	case ast.AdminChecksumTable:
+		p = &ChecksumTable{Tables: as.Tables}
+		p.SetSchema(buildChecksumTableFields())
+		// The checksum is computed from the rows, so they must be readable by the user.
+		for _, tbl := range as.Tables {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, tbl.Schema.L, tbl.Name.L, "")
+		}
+	case ast.AdminShowIndexAdvice:
+		p = &ShowIndexAdvice{}
+		p.SetSchema(buildShowIndexAdviceFields())
 	case ast.AdminShowDDL:
 		p = &ShowDDL{}
 		p.SetSchema(buildShowDDLFields())
+		// Like SHOW PROCESSLIST, the jobs of all the users are shown.
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.ProcessPriv, "", "", "")
+	case ast.AdminShowDDLJobs:
+		p = &ShowDDLJobs{}
+		p.SetSchema(buildShowDDLJobsFields())
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.ProcessPriv, "", "", "")
+	case ast.AdminCancelDDLJobs:
+		p = &CancelDDLJobs{JobIDs: as.JobIDs}
+		p.SetSchema(buildCancelDDLJobsFields())
+		// The jobs may be submitted by any user.
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
 	default:
 		b.err = ErrUnsupportedType.Gen("Unsupported type %T", as)
 	}
@@ -376,14 +474,12 @@ func getColumnOffsets(tn *ast.TableName) (indexOffsets []int, columnOffsets []in
 			}
 		}
 	}
-	indices, _ := availableIndices(tn.IndexHints, tn.TableInfo)
-	for _, index := range indices {
-		for i, idx := range tn.TableInfo.Indices {
-			if index.Name.L == idx.Name.L {
-				indexOffsets = append(indexOffsets, i)
-				break
-			}
+	for i, index := range tn.TableInfo.Indices {
+		// The invisible indices are analyzed too, so their statistics are ready when they are made visible.
+		if index.State != model.StatePublic {
+			continue
 		}
+		indexOffsets = append(indexOffsets, i)
 		if len(index.Columns) == 1 {
 			idxNames = append(idxNames, index.Columns[0].Name.L)
 		}
@@ -440,6 +536,57 @@ func buildShowDDLFields() *expression.Schema {
 	return schema
 }
 
+func buildShowDDLJobsFields() *expression.Schema {
+	schema := expression.NewSchema(make([]*expression.Column, 0, 9)...)
+	schema.Append(buildColumn("", "JOB_ID", mysql.TypeLonglong, 4))
+	schema.Append(buildColumn("", "DB_NAME", mysql.TypeVarchar, 64))
+	schema.Append(buildColumn("", "TABLE_NAME", mysql.TypeVarchar, 64))
+	schema.Append(buildColumn("", "JOB_TYPE", mysql.TypeVarchar, 64))
+	schema.Append(buildColumn("", "SCHEMA_STATE", mysql.TypeVarchar, 64))
+	schema.Append(buildColumn("", "SCHEMA_ID", mysql.TypeLonglong, 4))
+	schema.Append(buildColumn("", "TABLE_ID", mysql.TypeLonglong, 4))
+	schema.Append(buildColumn("", "ROW_COUNT", mysql.TypeLonglong, 4))
+	schema.Append(buildColumn("", "STATE", mysql.TypeVarchar, 64))
+
+	return schema
+}
+
+func buildCancelDDLJobsFields() *expression.Schema {
+	schema := expression.NewSchema(make([]*expression.Column, 0, 2)...)
+	schema.Append(buildColumn("", "JOB_ID", mysql.TypeLonglong, 4))
+	schema.Append(buildColumn("", "RESULT", mysql.TypeVarchar, 128))
+
+	return schema
+}
+
+func buildChecksumTableFields() *expression.Schema {
+	schema := expression.NewSchema(make([]*expression.Column, 0, 5)...)
+	schema.Append(buildColumn("", "Db_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumn("", "Table_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumn("", "Checksum_crc64_xor", mysql.TypeLonglong, 22))
+	schema.Append(buildColumn("", "Total_kvs", mysql.TypeLonglong, 22))
+	schema.Append(buildColumn("", "Total_bytes", mysql.TypeLonglong, 22))
+	return schema
+}
+
+func buildSplitRegionFields() *expression.Schema {
+	schema := expression.NewSchema(make([]*expression.Column, 0, 2)...)
+	schema.Append(buildColumn("", "TOTAL_SPLIT_REGION", mysql.TypeLonglong, 4))
+	schema.Append(buildColumn("", "SCATTER_FINISH_RATIO", mysql.TypeDouble, 8))
+	return schema
+}
+
+func buildShowIndexAdviceFields() *expression.Schema {
+	schema := expression.NewSchema(make([]*expression.Column, 0, 6)...)
+	schema.Append(buildColumn("", "Db_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumn("", "Table_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumn("", "Index_columns", mysql.TypeVarchar, 256))
+	schema.Append(buildColumn("", "Statements", mysql.TypeLonglong, 22))
+	schema.Append(buildColumn("", "Exec_count", mysql.TypeLonglong, 22))
+	schema.Append(buildColumn("", "Cost_reduction", mysql.TypeDouble, 22))
+	return schema
+}
+
 func buildColumn(tableName, name string, tp byte, size int) *expression.Column {
 	cs, cl := types.DefaultCharsetForType(tp)
 	flag := mysql.UnsignedFlag
@@ -557,6 +704,9 @@ func (b *planBuilder) buildSimple(node ast.StmtNode) Plan {
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.CreateUserPriv, "", "", "")
 	case *ast.GrantStmt:
 		b.visitInfo = collectVisitInfoFromGrantStmt(b.visitInfo, raw)
+	case *ast.CreateResourceGroupStmt, *ast.AlterResourceGroupStmt, *ast.DropResourceGroupStmt:
+		// The users with the global CREATE USER privilege administrate the resource groups, same as the users.
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.CreateUserPriv, "", "", "")
 	case *ast.SetPwdStmt, *ast.RevokeStmt:
 		// TODO: Require SUPER privilege, it's a temporary solution here.
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.CreateUserPriv, "", "", "")
@@ -733,11 +883,104 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 
 func (b *planBuilder) buildLoadData(ld *ast.LoadDataStmt) Plan {
 	p := &LoadData{
-		IsLocal:    ld.IsLocal,
-		Path:       ld.Path,
-		Table:      ld.Table,
-		FieldsInfo: ld.FieldsInfo,
-		LinesInfo:  ld.LinesInfo,
+		IsLocal:     ld.IsLocal,
+		Path:        ld.Path,
+		Table:       ld.Table,
+		FieldsInfo:  ld.FieldsInfo,
+		LinesInfo:   ld.LinesInfo,
+		IgnoreLines: ld.IgnoreLines,
+	}
+	p.SetSchema(expression.NewSchema())
+	return p
+}
+
+// MaxSplitRegionNum is the max number of the regions split by a 'split table' statement.
+const MaxSplitRegionNum = 1000
+
+func (b *planBuilder) buildSplitRegion(node *ast.SplitRegionStmt) Plan {
+	tblInfo := node.Table.TableInfo
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.InsertPriv, node.Table.Schema.L, tblInfo.Name.L, "")
+	p := &SplitRegion{TableInfo: tblInfo}
+	// The handle is the only value when the record data is split.
+	fieldTypes := []*types.FieldType{types.NewFieldType(mysql.TypeLonglong)}
+	if tblInfo.PKIsHandle {
+		for _, col := range tblInfo.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) {
+				fieldTypes[0] = &col.FieldType
+			}
+		}
+	}
+	if node.IndexName.L != "" {
+		p.IndexInfo = findIndexByName(tblInfo.Indices, node.IndexName)
+		if p.IndexInfo == nil || p.IndexInfo.State != model.StatePublic {
+			b.err = ErrSplitRegion.GenByArgs(fmt.Sprintf("index %s doesn't exist in table %s", node.IndexName.O,
+				tblInfo.Name.O))
+			return nil
+		}
+		fieldTypes = fieldTypes[:0]
+		for _, idxCol := range p.IndexInfo.Columns {
+			fieldTypes = append(fieldTypes, &tblInfo.Columns[idxCol.Offset].FieldType)
+		}
+	}
+	convertValues := func(exprs []ast.ExprNode) []types.Datum {
+		// The values of the leading index columns are enough to split an index.
+		if len(exprs) == 0 || len(exprs) > len(fieldTypes) || (p.IndexInfo == nil && len(exprs) != 1) {
+			b.err = ErrSplitRegion.GenByArgs(fmt.Sprintf(
+				"the number of the values %d doesn't match the number of the columns %d", len(exprs), len(fieldTypes)))
+			return nil
+		}
+		sc := b.ctx.GetSessionVars().StmtCtx
+		values := make([]types.Datum, 0, len(exprs))
+		for i, expr := range exprs {
+			value, err := evalAstExpr(expr, b.ctx)
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			value, err = value.ConvertTo(sc, fieldTypes[i])
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			values = append(values, value)
+		}
+		return values
+	}
+	opt := node.SplitOpt
+	if len(opt.ValueLists) > 0 {
+		if len(opt.ValueLists) > MaxSplitRegionNum {
+			b.err = ErrSplitRegion.GenByArgs(fmt.Sprintf("the number of the regions should be at most %d", MaxSplitRegionNum))
+			return nil
+		}
+		for _, exprs := range opt.ValueLists {
+			values := convertValues(exprs)
+			if b.err != nil {
+				return nil
+			}
+			p.ValueLists = append(p.ValueLists, values)
+		}
+	} else {
+		if opt.Num < 1 || opt.Num > MaxSplitRegionNum {
+			b.err = ErrSplitRegion.GenByArgs(fmt.Sprintf("the number of the regions should be in [1, %d]", MaxSplitRegionNum))
+			return nil
+		}
+		p.Num = int(opt.Num)
+		if p.Lower = convertValues(opt.Lower); b.err != nil {
+			return nil
+		}
+		if p.Upper = convertValues(opt.Upper); b.err != nil {
+			return nil
+		}
+	}
+	p.SetSchema(buildSplitRegionFields())
+	return p
+}
+
+func (b *planBuilder) buildLoadStats(ld *ast.LoadStatsStmt) Plan {
+	// The file is read from the file system of the server, and the statistics of any table can be overwritten.
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+	p := &LoadStats{
+		Path: ld.Path,
 	}
 	p.SetSchema(expression.NewSchema())
 	return p
@@ -775,6 +1018,14 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 				table:     v.ReferTable.Name.L,
 			})
 		}
+		if v.Select != nil {
+			b.visitInfo = append(b.visitInfo, visitInfo{
+				privilege: mysql.InsertPriv,
+				db:        v.Table.Schema.L,
+				table:     v.Table.Name.L,
+			})
+			return b.buildCreateTableSelect(v)
+		}
 	case *ast.DropDatabaseStmt:
 		b.visitInfo = append(b.visitInfo, visitInfo{
 			privilege: mysql.DropPriv,
@@ -801,16 +1052,38 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 			table:     v.Table.Name.L,
 		})
 	case *ast.RenameTableStmt:
-		b.visitInfo = append(b.visitInfo, visitInfo{
-			privilege: mysql.AlterPriv,
-			db:        v.OldTable.Schema.L,
-			table:     v.OldTable.Name.L,
-		})
-		b.visitInfo = append(b.visitInfo, visitInfo{
-			privilege: mysql.AlterPriv,
-			db:        v.NewTable.Schema.L,
-			table:     v.NewTable.Name.L,
-		})
+		// Renaming a table requires the ALTER and DROP privileges on the old table, and the CREATE and INSERT
+		// privileges on the new table.
+		for _, t := range v.TableToTables {
+			for _, priv := range []mysql.PrivilegeType{mysql.AlterPriv, mysql.DropPriv} {
+				b.visitInfo = append(b.visitInfo, visitInfo{
+					privilege: priv,
+					db:        t.OldTable.Schema.L,
+					table:     t.OldTable.Name.L,
+				})
+			}
+			for _, priv := range []mysql.PrivilegeType{mysql.CreatePriv, mysql.InsertPriv} {
+				b.visitInfo = append(b.visitInfo, visitInfo{
+					privilege: priv,
+					db:        t.NewTable.Schema.L,
+					table:     t.NewTable.Name.L,
+				})
+			}
+		}
+	case *ast.RecoverTableStmt:
+		// Recovering a table requires the CREATE and DROP privileges on it, the table of the DDL job is unknown
+		// here, so the global privileges are required.
+		var db, table string
+		if v.Table != nil {
+			db, table = v.Table.Schema.L, v.Table.Name.L
+		}
+		for _, priv := range []mysql.PrivilegeType{mysql.CreatePriv, mysql.DropPriv} {
+			b.visitInfo = appendVisitInfo(b.visitInfo, priv, db, table, "")
+		}
+	case *ast.FlashbackTableStmt:
+		for _, priv := range []mysql.PrivilegeType{mysql.CreatePriv, mysql.DropPriv} {
+			b.visitInfo = appendVisitInfo(b.visitInfo, priv, v.Table.Schema.L, v.Table.Name.L, "")
+		}
 	}
 
 	p := &DDL{Statement: node}
@@ -818,6 +1091,20 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 	return p
 }
 
+// buildCreateTableSelect builds the plan of CREATE TABLE ... SELECT. The query is optimized as the child of the DDL
+// plan, the same as the statement of EXPLAIN.
+func (b *planBuilder) buildCreateTableSelect(stmt *ast.CreateTableStmt) Plan {
+	selectPlan, err := Optimize(b.ctx, stmt.Select, b.is)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	p := &DDL{Statement: stmt}
+	addChild(p, selectPlan)
+	p.SetSchema(expression.NewSchema())
+	return p
+}
+
 func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 	if show, ok := explain.Stmt.(*ast.ShowStmt); ok {
 		return b.buildShow(show)
@@ -827,9 +1114,37 @@ func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 		b.err = errors.Trace(err)
 		return nil
 	}
-	p := &Explain{StmtPlan: targetPlan}
+	p := &Explain{StmtPlan: targetPlan, Analyze: explain.Analyze}
 	addChild(p, targetPlan)
-	schema := expression.NewSchema(make([]*expression.Column, 0, 3)...)
+	p.SetSchema(buildExplainSchema(explain.Analyze))
+	return p
+}
+
+// buildExplainFor builds the Explain of the plan of the statement executing in another connection.
+func (b *planBuilder) buildExplainFor(explainFor *ast.ExplainForStmt) Plan {
+	var pi util.ProcessInfo
+	ok := false
+	if sm := b.ctx.GetSessionManager(); sm != nil {
+		pi, ok = sm.GetProcessInfo(explainFor.ConnectionID)
+	}
+	if !ok {
+		b.err = ErrNoSuchThread.GenByArgs(explainFor.ConnectionID)
+		return nil
+	}
+	// The PROCESS privilege is required to explain the statements of the other users.
+	if pi.User+"@"+pi.Host != b.ctx.GetSessionVars().User {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.ProcessPriv, "", "", "")
+	}
+	// The plan is nil if the connection isn't executing a statement, the result is empty then.
+	// The plan isn't added as a child, because it's still used by the other connection.
+	targetPlan, _ := pi.Plan.(Plan)
+	p := &Explain{StmtPlan: targetPlan}
+	p.SetSchema(buildExplainSchema(false))
+	return p
+}
+
+func buildExplainSchema(analyze bool) *expression.Schema {
+	schema := expression.NewSchema(make([]*expression.Column, 0, 4)...)
 	schema.Append(&expression.Column{
 		ColName: model.NewCIStr("ID"),
 		RetType: types.NewFieldType(mysql.TypeString),
@@ -842,8 +1157,13 @@ func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 		ColName: model.NewCIStr("ParentID"),
 		RetType: types.NewFieldType(mysql.TypeString),
 	})
-	p.SetSchema(schema)
-	return p
+	if analyze {
+		schema.Append(&expression.Column{
+			ColName: model.NewCIStr("ExecInfo"),
+			RetType: types.NewFieldType(mysql.TypeString),
+		})
+	}
+	return schema
 }
 
 func buildShowProcedureSchema() *expression.Schema {
@@ -984,10 +1304,11 @@ func buildShowSchema(s *ast.ShowStmt) (schema *expression.Schema) {
 	case ast.ShowIndex:
 		names = []string{"Table", "Non_unique", "Key_name", "Seq_in_index",
 			"Column_name", "Collation", "Cardinality", "Sub_part", "Packed",
-			"Null", "Index_type", "Comment", "Index_comment"}
+			"Null", "Index_type", "Comment", "Index_comment", "Expression"}
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong,
-			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar}
+			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar,
+			mysql.TypeVarchar}
 	case ast.ShowProcessList:
 		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
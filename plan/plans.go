@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/util/types"
@@ -40,6 +41,19 @@ type ShowDDL struct {
 	basePlan
 }
 
+// ShowDDLJobs is for showing the DDL jobs in the job queues and the latest finished ones, built from the
+// 'admin show ddl jobs' statement.
+type ShowDDLJobs struct {
+	basePlan
+}
+
+// CancelDDLJobs is used for cancelling the DDL jobs, built from the 'admin cancel ddl jobs' statement.
+type CancelDDLJobs struct {
+	basePlan
+
+	JobIDs []int64
+}
+
 // CheckTable is used for checking table data, built from the 'admin check table' statement.
 type CheckTable struct {
 	basePlan
@@ -47,6 +61,33 @@ type CheckTable struct {
 	Tables []*ast.TableName
 }
 
+// ChecksumTable is used for calculating the checksums of tables, built from the 'admin checksum table' statement.
+type ChecksumTable struct {
+	basePlan
+
+	Tables []*ast.TableName
+}
+
+// ShowIndexAdvice is used for recommending indexes for the recorded workload, built from the
+// 'admin show index advice' statement.
+type ShowIndexAdvice struct {
+	basePlan
+}
+
+// SplitRegion is used for splitting the regions of a table or an index, built from the 'split table' statement.
+// The regions are split at every value list of ValueLists, or evenly into Num regions between Lower and Upper. The
+// values are the handles when IndexInfo is nil, otherwise they are the values of the index columns.
+type SplitRegion struct {
+	basePlan
+
+	TableInfo  *model.TableInfo
+	IndexInfo  *model.IndexInfo
+	Lower      []types.Datum
+	Upper      []types.Datum
+	Num        int
+	ValueLists [][]types.Datum
+}
+
 // IndexRange represents an index range to be scanned.
 type IndexRange struct {
 	LowVal      []types.Datum
@@ -205,11 +246,19 @@ type Analyze struct {
 type LoadData struct {
 	basePlan
 
-	IsLocal    bool
-	Path       string
-	Table      *ast.TableName
-	FieldsInfo *ast.FieldsClause
-	LinesInfo  *ast.LinesClause
+	IsLocal     bool
+	Path        string
+	Table       *ast.TableName
+	FieldsInfo  *ast.FieldsClause
+	LinesInfo   *ast.LinesClause
+	IgnoreLines uint64
+}
+
+// LoadStats represents a load stats plan.
+type LoadStats struct {
+	basePlan
+
+	Path string
 }
 
 // DDL represents a DDL statement plan.
@@ -224,4 +273,6 @@ type Explain struct {
 	basePlan
 
 	StmtPlan Plan
+	// Analyze is true for EXPLAIN ANALYZE, the plan is executed to collect the runtime statistics.
+	Analyze bool
 }
@@ -347,6 +347,14 @@ func (p *Limit) PredicatePushDown(predicates []expression.Expression) ([]express
 	return predicates, p, errors.Trace(err)
 }
 
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+func (p *Window) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	// The conditions filter the rows before the window functions are evaluated if they're pushed down, so Window
+	// forbids any condition to push down.
+	_, _, err := p.baseLogicalPlan.PredicatePushDown(nil)
+	return predicates, p, errors.Trace(err)
+}
+
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
 func (p *MaxOneRow) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
 	// MaxOneRow forbids any condition to push down.
@@ -74,6 +74,22 @@ func (p *Sort) ResolveIndicesAndCorCols() {
 	}
 }
 
+// ResolveIndicesAndCorCols implements LogicalPlan interface.
+func (p *Window) ResolveIndicesAndCorCols() {
+	p.baseLogicalPlan.ResolveIndicesAndCorCols()
+	for _, f := range p.WindowFuncs {
+		for _, arg := range f.Args {
+			arg.ResolveIndices(p.children[0].Schema())
+		}
+	}
+	for _, expr := range p.PartitionBy {
+		expr.ResolveIndices(p.children[0].Schema())
+	}
+	for _, item := range p.OrderBy {
+		item.Expr.ResolveIndices(p.children[0].Schema())
+	}
+}
+
 // ResolveIndicesAndCorCols implements LogicalPlan interface.
 func (p *Apply) ResolveIndicesAndCorCols() {
 	p.Join.ResolveIndicesAndCorCols()
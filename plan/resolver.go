@@ -59,6 +59,11 @@ type nameResolver struct {
 	useOuterContext bool
 
 	contextStack []*resolverContext
+	// cteTables are the tables of the common table expressions defined in the WITH clause, keyed by the CTE names.
+	cteTables map[string]*model.TableInfo
+	// lastCTETableID is the last table ID allocated for the CTEs, the IDs are negative so they don't conflict with
+	// the IDs of the real tables.
+	lastCTETableID int64
 }
 
 // resolverContext stores information in a single level of select statement
@@ -197,6 +202,11 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.currentContext().inGroupBy = true
 	case *ast.HavingClause:
 		nr.currentContext().inHaving = true
+	case *ast.IndexColName:
+		if v.Expr != nil {
+			// The columns in the expression of an expression index are resolved on the table by DDL.
+			return inNode, true
+		}
 	case *ast.InsertStmt:
 		nr.pushContext()
 	case *ast.LoadDataStmt:
@@ -207,11 +217,13 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.currentContext().inOnCondition = true
 	case *ast.OrderByClause:
 		nr.currentContext().inOrderBy = true
-	case *ast.RenameTableStmt:
+	case *ast.RenameTableStmt, *ast.RecoverTableStmt, *ast.FlashbackTableStmt:
 		nr.pushContext()
 		nr.currentContext().inCreateOrDropTable = true
 	case *ast.SelectStmt:
 		nr.pushContext()
+	case *ast.SplitRegionStmt:
+		nr.pushContext()
 	case *ast.SetStmt:
 		for _, assign := range v.Variables {
 			if cn, ok := assign.Value.(*ast.ColumnNameExpr); ok && cn.Name.Table.L == "" {
@@ -232,6 +244,9 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.pushContext()
 	case *ast.UpdateStmt:
 		nr.pushContext()
+	case *ast.WithClause:
+		nr.handleWithClause(v)
+		return inNode, true
 	}
 	return inNode, false
 }
@@ -295,7 +310,7 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.currentContext().inByItemExpression = false
 	case *ast.PositionExpr:
 		nr.handlePosition(v)
-	case *ast.RenameTableStmt:
+	case *ast.RenameTableStmt, *ast.RecoverTableStmt, *ast.FlashbackTableStmt:
 		nr.popContext()
 	case *ast.SelectStmt:
 		ctx := nr.currentContext()
@@ -308,6 +323,8 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.popContext()
 	case *ast.ShowStmt:
 		nr.popContext()
+	case *ast.SplitRegionStmt:
+		nr.popContext()
 	case *ast.SubqueryExpr:
 		if nr.useOuterContext {
 			// TODO: check this
@@ -341,6 +358,11 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 // handleTableName looks up and sets the schema information and result fields for table name.
 func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 	if tn.Schema.L == "" {
+		if tblInfo, ok := nr.cteTables[tn.Name.L]; ok {
+			tn.TableInfo = tblInfo
+			nr.setTableNameResultFields(tn)
+			return
+		}
 		tn.Schema = nr.DefaultSchema
 	}
 	ctx := nr.currentContext()
@@ -370,7 +392,11 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 	tn.TableInfo = table.Meta()
 	dbInfo, _ := nr.Info.SchemaByName(tn.Schema)
 	tn.DBInfo = dbInfo
+	nr.setTableNameResultFields(tn)
+}
 
+// setTableNameResultFields sets the result fields of the table name by the columns of its table.
+func (nr *nameResolver) setTableNameResultFields(tn *ast.TableName) {
 	rfs := make([]*ast.ResultField, 0, len(tn.TableInfo.Columns))
 	tmp := make([]struct {
 		ast.ValueExpr
@@ -402,7 +428,6 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 		rfs = append(rfs, rf)
 	}
 	tn.SetResultFields(rfs)
-	return
 }
 
 // handleTableSources checks name duplication
@@ -925,10 +950,11 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 	case ast.ShowIndex:
 		names = []string{"Table", "Non_unique", "Key_name", "Seq_in_index",
 			"Column_name", "Collation", "Cardinality", "Sub_part", "Packed",
-			"Null", "Index_type", "Comment", "Index_comment"}
+			"Null", "Index_type", "Comment", "Index_comment", "Expression"}
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong,
-			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar}
+			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar,
+			mysql.TypeVarchar}
 	case ast.ShowProcessList:
 		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
@@ -32,7 +32,8 @@ func (is *PhysicalIndexScan) getRowCountByIndexRanges(sc *variable.StatementCont
 			break
 		}
 	}
-	if len(statsTbl.Indices[offset].Numbers) == 0 {
+	// The hypothetical indexes of the index advisor have no statistics.
+	if offset >= len(statsTbl.Indices) || len(statsTbl.Indices[offset].Numbers) == 0 {
 		return getPseudoRowCountByIndexRanges(sc, statsTbl, is.Ranges, is.Index, is.accessInAndEqCount)
 	}
 	return getRealRowCountByIndexRanges(sc, statsTbl, is.Ranges, is.Index, offset)
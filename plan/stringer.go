@@ -26,7 +26,7 @@ func ToString(p Plan) string {
 
 func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 	switch in.(type) {
-	case *Join, *Union, *PhysicalHashJoin, *PhysicalHashSemiJoin, *Apply, *PhysicalApply:
+	case *Join, *Union, *PhysicalHashJoin, *PhysicalMergeJoin, *PhysicalIndexJoin, *PhysicalHashSemiJoin, *Apply, *PhysicalApply:
 		idxs = append(idxs, len(strs))
 	}
 
@@ -38,6 +38,12 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 	switch x := in.(type) {
 	case *CheckTable:
 		str = "CheckTable"
+	case *ChecksumTable:
+		str = "ChecksumTable"
+	case *ShowIndexAdvice:
+		str = "ShowIndexAdvice"
+	case *SplitRegion:
+		str = "SplitRegion"
 	case *PhysicalIndexScan:
 		str = fmt.Sprintf("Index(%s.%s)%v", x.Table.Name.L, x.Index.Name.L, x.Ranges)
 	case *PhysicalTableScan:
@@ -60,6 +66,28 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 			r := eq.GetArgs()[1].String()
 			str += fmt.Sprintf("(%s,%s)", l, r)
 		}
+	case *PhysicalMergeJoin:
+		last := len(idxs) - 1
+		idx := idxs[last]
+		children := strs[idx:]
+		strs = strs[:idx]
+		idxs = idxs[:last]
+		str = "MergeJoin{" + strings.Join(children, "->") + "}"
+		for _, eq := range x.EqualConditions {
+			l := eq.GetArgs()[0].String()
+			r := eq.GetArgs()[1].String()
+			str += fmt.Sprintf("(%s,%s)", l, r)
+		}
+	case *PhysicalIndexJoin:
+		last := len(idxs) - 1
+		idx := idxs[last]
+		children := strs[idx:]
+		strs = strs[:idx]
+		idxs = idxs[:last]
+		str = "IndexJoin{" + strings.Join(children, "->") + "}"
+		for i := range x.OuterJoinKeys {
+			str += fmt.Sprintf("(%s,%s)", x.OuterJoinKeys[i], x.InnerJoinKeys[i])
+		}
 	case *PhysicalHashSemiJoin:
 		last := len(idxs) - 1
 		idx := idxs[last]
@@ -84,10 +112,16 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		str = "MaxOneRow"
 	case *Limit:
 		str = "Limit"
+	case *Window:
+		str = "Window"
 	case *SelectLock:
 		str = "Lock"
 	case *ShowDDL:
 		str = "ShowDDL"
+	case *ShowDDLJobs:
+		str = "ShowDDLJobs"
+	case *CancelDDLJobs:
+		str = "CancelDDLJobs"
 	case *Sort:
 		str = "Sort"
 		if x.ExecLimit != nil {
@@ -14,7 +14,9 @@
 package plan
 
 import (
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
@@ -43,9 +45,35 @@ type typeInferrer struct {
 }
 
 func (v *typeInferrer) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
+	switch x := in.(type) {
+	case *ast.CommonTableExpression:
+		v.commonTableExpr(x)
+		return in, true
+	case *ast.IndexColName:
+		// The expression of the expression index is inferred on the table when the index is built.
+		return in, x.Expr != nil
+	}
 	return in, false
 }
 
+// commonTableExpr infers the types of the CTE query. The recursive part refers to the columns of the CTE, whose types
+// are decided by the first select, so the non-recursive part is inferred first.
+func (v *typeInferrer) commonTableExpr(x *ast.CommonTableExpression) {
+	if !x.IsRecursive() {
+		x.Query.Accept(v)
+		setCTEColumnTypes(x)
+		return
+	}
+	selects := x.Selects()
+	for _, sel := range selects[:x.SeedCount] {
+		sel.Accept(v)
+	}
+	setCTEColumnTypes(x)
+	for _, sel := range selects[x.SeedCount:] {
+		sel.Accept(v)
+	}
+}
+
 func (v *typeInferrer) Leave(in ast.Node) (out ast.Node, ok bool) {
 	switch x := in.(type) {
 	case *ast.AggregateFuncExpr:
@@ -106,6 +134,8 @@ func (v *typeInferrer) Leave(in ast.Node) (out ast.Node, ok bool) {
 		v.handleValueExpr(x)
 	case *ast.ValuesExpr:
 		v.handleValuesExpr(x)
+	case *ast.WindowFuncExpr:
+		v.windowFunc(x)
 	case *ast.VariableExpr:
 		x.SetType(types.NewFieldType(mysql.TypeVarString))
 		x.Type.Charset = v.defaultCharset
@@ -131,23 +161,29 @@ func (v *typeInferrer) selectStmt(x *ast.SelectStmt) {
 }
 
 func (v *typeInferrer) aggregateFunc(x *ast.AggregateFuncExpr) {
-	name := strings.ToLower(x.F)
+	if ft := v.aggFuncType(x.F, x.Args); ft != nil {
+		x.SetType(ft)
+	}
+}
+
+func (v *typeInferrer) aggFuncType(funcName string, args []ast.ExprNode) *types.FieldType {
+	name := strings.ToLower(funcName)
 	switch name {
-	case ast.AggFuncCount:
+	case ast.AggFuncCount, ast.AggFuncApproxCountDistinct:
 		ft := types.NewFieldType(mysql.TypeLonglong)
 		ft.Flen = 21
 		ft.Charset = charset.CharsetBin
 		ft.Collate = charset.CollationBin
-		x.SetType(ft)
+		return ft
 	case ast.AggFuncMax, ast.AggFuncMin:
-		x.SetType(x.Args[0].GetType())
+		return args[0].GetType()
 	case ast.AggFuncSum, ast.AggFuncAvg:
 		ft := types.NewFieldType(mysql.TypeNewDecimal)
 		ft.Charset = charset.CharsetBin
 		ft.Collate = charset.CollationBin
-		ft.Decimal = x.Args[0].GetType().Decimal
-		x.SetType(ft)
-	case ast.AggFuncGroupConcat:
+		ft.Decimal = args[0].GetType().Decimal
+		return ft
+	case ast.AggFuncGroupConcat, ast.AggFuncJSONArrayAgg, ast.AggFuncJSONObjectAgg:
 		ft := types.NewFieldType(mysql.TypeVarString)
 		ft.Charset = v.defaultCharset
 		cln, err := charset.GetDefaultCollation(v.defaultCharset)
@@ -155,7 +191,25 @@ func (v *typeInferrer) aggregateFunc(x *ast.AggregateFuncExpr) {
 			v.err = err
 		}
 		ft.Collate = cln
+		return ft
+	}
+	return nil
+}
+
+func (v *typeInferrer) windowFunc(x *ast.WindowFuncExpr) {
+	switch strings.ToLower(x.F) {
+	case ast.WindowFuncRowNumber, ast.WindowFuncRank, ast.WindowFuncDenseRank:
+		ft := types.NewFieldType(mysql.TypeLonglong)
+		ft.Flen = 21
+		ft.Charset = charset.CharsetBin
+		ft.Collate = charset.CollationBin
 		x.SetType(ft)
+	case ast.WindowFuncLag, ast.WindowFuncLead:
+		x.SetType(x.Args[0].GetType())
+	default:
+		if ft := v.aggFuncType(x.F, x.Args); ft != nil {
+			x.SetType(ft)
+		}
 	}
 }
 
@@ -178,6 +232,9 @@ func (v *typeInferrer) binaryOperation(x *ast.BinaryOperationExpr) {
 			rightUnsigned := x.R.GetType().Flag & mysql.UnsignedFlag
 			// If both operands are unsigned, result is unsigned.
 			x.Type.Flag |= (leftUnsigned & rightUnsigned)
+			if x.Op != opcode.Mod {
+				setArithFlenDecimal(&x.Type, x.Op, x.L.GetType(), x.R.GetType())
+			}
 		}
 	case opcode.Div:
 		if x.L.GetType() != nil && x.R.GetType() != nil {
@@ -192,6 +249,59 @@ func (v *typeInferrer) binaryOperation(x *ast.BinaryOperationExpr) {
 	x.Type.Collate = charset.CollationBin
 }
 
+// setArithFlenDecimal sets the length and the decimal of the integer or decimal result of +, - and *, the same as
+// MySQL. They are left unspecified if the length or the decimal of any operand is unknown.
+func setArithFlenDecimal(tp *types.FieldType, op opcode.Op, fta, ftb *types.FieldType) {
+	if tp.Tp != mysql.TypeLonglong && tp.Tp != mysql.TypeNewDecimal {
+		return
+	}
+	flenA, decA := arithFlenDecimal(fta)
+	flenB, decB := arithFlenDecimal(ftb)
+	if flenA == types.UnspecifiedLength || flenB == types.UnspecifiedLength {
+		return
+	}
+	var flen, dec int
+	if op == opcode.Mul {
+		flen, dec = flenA+flenB, decA+decB
+	} else {
+		// The integer part of the sum may have one more digit than the longer integer part of the operands.
+		dec = decA
+		if decB > dec {
+			dec = decB
+		}
+		intA, intB := flenA-decA, flenB-decB
+		if intB > intA {
+			intA = intB
+		}
+		flen = intA + dec + 1
+	}
+	if tp.Tp == mysql.TypeLonglong {
+		dec = types.UnspecifiedLength
+	}
+	if flen > mysql.MaxDecimalWidth {
+		flen = mysql.MaxDecimalWidth
+	}
+	if dec > types.MaxFraction {
+		dec = types.MaxFraction
+	}
+	tp.Flen, tp.Decimal = flen, dec
+}
+
+// arithFlenDecimal returns the length and the decimal of the operand of an arithmetic operation, the decimal of the
+// integer is 0.
+func arithFlenDecimal(ft *types.FieldType) (flen, dec int) {
+	switch ft.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		return ft.Flen, 0
+	case mysql.TypeNewDecimal:
+		if ft.Decimal == types.UnspecifiedLength {
+			return types.UnspecifiedLength, types.UnspecifiedLength
+		}
+		return ft.Flen, ft.Decimal
+	}
+	return types.UnspecifiedLength, types.UnspecifiedLength
+}
+
 // toArithType converts DateTime, Duration and Timestamp types to NewDecimal type if Decimal > 0.
 func toArithType(ft *types.FieldType) (tp byte) {
 	tp = ft.Tp
@@ -282,6 +392,22 @@ func (v *typeInferrer) unaryOperation(x *ast.UnaryOperationExpr) {
 
 func (v *typeInferrer) handleValueExpr(x *ast.ValueExpr) {
 	types.DefaultTypeForValue(x.GetValue(), x.GetType())
+	// The lengths of the literals are only used to derive the lengths of the columns created by CREATE TABLE ...
+	// SELECT, the other statements keep them unspecified.
+	if v.sc == nil || !v.sc.InCreateTableSelectStmt {
+		return
+	}
+	switch val := x.GetValue().(type) {
+	case int64:
+		x.Type.Flen = len(strconv.FormatInt(val, 10))
+	case uint64:
+		x.Type.Flen = len(strconv.FormatUint(val, 10))
+	case string:
+		x.Type.Flen = utf8.RuneCountInString(val)
+	case *types.MyDecimal:
+		_, frac := val.PrecisionAndFrac()
+		x.Type.Flen, x.Type.Decimal = len(val.String()), frac
+	}
 }
 
 func (v *typeInferrer) handleValuesExpr(x *ast.ValuesExpr) {
@@ -375,8 +501,12 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 		}
 	case "str_to_date":
 		tp = types.NewFieldType(mysql.TypeDatetime)
+	case "concat":
+		tp = types.NewFieldType(mysql.TypeVarString)
+		chs = v.defaultCharset
+		tp.Flen = concatFlen(x.Args)
 	case "dayname", "version", "database", "user", "current_user", "schema",
-		"concat", "concat_ws", "left", "lcase", "lower", "repeat",
+		"concat_ws", "left", "lcase", "lower", "repeat",
 		"replace", "ucase", "upper", "convert", "substring", "elt",
 		"substring_index", "trim", "ltrim", "rtrim", "reverse", "hex", "unhex",
 		"date_format", "rpad", "lpad", "char_func", "conv", "make_set", "oct", "uuid",
@@ -438,6 +568,20 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 	x.SetType(tp)
 }
 
+// concatFlen returns the length of the result of CONCAT, which is the sum of the lengths of the arguments. It's
+// unspecified if the length of any argument is unknown.
+func concatFlen(args []ast.ExprNode) int {
+	flen := 0
+	for _, arg := range args {
+		argFlen := arg.GetType().Flen
+		if argFlen == types.UnspecifiedLength {
+			return types.UnspecifiedLength
+		}
+		flen += argFlen
+	}
+	return flen
+}
+
 // The return type of a CASE expression is the compatible aggregated type of all return values,
 // but also depends on the context in which it is used.
 // If used in a string context, the result is returned as a string.
@@ -471,11 +615,35 @@ func (v *typeInferrer) handleCaseExpr(x *ast.CaseExpr) {
 			currType.Tp = mtp
 		}
 	}
+	currType.Flen = caseFlen(x)
 	x.SetType(&currType)
 	// TODO: We need a better way to set charset/collation
 	x.Type.Charset, x.Type.Collate = types.DefaultCharsetForType(x.Type.Tp)
 }
 
+// caseFlen returns the length of the result of the CASE expression, which is the max length of the results. It's
+// unspecified if the length of any result is unknown.
+func caseFlen(x *ast.CaseExpr) int {
+	results := make([]ast.ExprNode, 0, len(x.WhenClauses)+1)
+	for _, w := range x.WhenClauses {
+		results = append(results, w.Result)
+	}
+	if x.ElseClause != nil {
+		results = append(results, x.ElseClause)
+	}
+	flen := 0
+	for _, result := range results {
+		resultFlen := result.GetType().Flen
+		if resultFlen == types.UnspecifiedLength {
+			return types.UnspecifiedLength
+		}
+		if resultFlen > flen {
+			flen = resultFlen
+		}
+	}
+	return flen
+}
+
 // like expression expects the target expression and pattern to be a string, if it's not, we add a cast function.
 func (v *typeInferrer) handleLikeExpr(x *ast.PatternLikeExpr) {
 	x.SetType(types.NewFieldType(mysql.TypeLonglong))
@@ -45,3 +45,25 @@ func (a *AggregateFuncExtractor) Leave(n ast.Node) (ast.Node, bool) {
 	}
 	return n, true
 }
+
+// windowFuncExtractor collects the window functions of an expression, the ones in the subqueries are not collected.
+type windowFuncExtractor struct {
+	windowFuncs []*ast.WindowFuncExpr
+}
+
+// Enter implements Visitor interface.
+func (e *windowFuncExtractor) Enter(n ast.Node) (ast.Node, bool) {
+	switch n.(type) {
+	case *ast.SelectStmt, *ast.UnionStmt:
+		return n, true
+	}
+	return n, false
+}
+
+// Leave implements Visitor interface.
+func (e *windowFuncExtractor) Leave(n ast.Node) (ast.Node, bool) {
+	if v, ok := n.(*ast.WindowFuncExpr); ok {
+		e.windowFuncs = append(e.windowFuncs, v)
+	}
+	return n, true
+}
@@ -39,6 +39,7 @@ type validator struct {
 	wildCardCount int
 	inPrepare     bool
 	inAggregate   bool
+	inWindowFunc  bool
 }
 
 func (v *validator) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
@@ -50,6 +51,13 @@ func (v *validator) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 			return in, true
 		}
 		v.inAggregate = true
+	case *ast.WindowFuncExpr:
+		if v.inAggregate || v.inWindowFunc {
+			// Window function can not be nested in aggregate function or window function.
+			v.err = ErrInvalidWindowFuncUse.GenByArgs(node.F)
+			return in, true
+		}
+		v.inWindowFunc = true
 	case *ast.CreateTableStmt:
 		v.checkCreateTableGrammar(node)
 		if v.err != nil {
@@ -73,6 +81,8 @@ func (v *validator) Leave(in ast.Node) (out ast.Node, ok bool) {
 	switch x := in.(type) {
 	case *ast.AggregateFuncExpr:
 		v.inAggregate = false
+	case *ast.WindowFuncExpr:
+		v.inWindowFunc = false
 	case *ast.CreateTableStmt:
 		v.checkAutoIncrement(x)
 	case *ast.ParamMarkerExpr:
@@ -137,7 +147,7 @@ func isConstraintKeyTp(constraints []*ast.Constraint, colDef *ast.ColumnDef) boo
 		}
 		// If the constraint as follows: primary key(c1, c2)
 		// we only support c1 column can be auto_increment.
-		if colDef.Name.Name.L != c.Keys[0].Column.Name.L {
+		if c.Keys[0].Column == nil || colDef.Name.Name.L != c.Keys[0].Column.Name.L {
 			continue
 		}
 		switch c.Tp {
@@ -280,8 +290,14 @@ func (v *validator) checkAlterTableGrammar(stmt *ast.AlterTableStmt) {
 // checkDuplicateColumnName checks if index exists duplicated columns.
 func checkDuplicateColumnName(indexColNames []*ast.IndexColName) error {
 	for i := 0; i < len(indexColNames); i++ {
+		if indexColNames[i].Column == nil {
+			continue
+		}
 		name1 := indexColNames[i].Column.Name
 		for j := i + 1; j < len(indexColNames); j++ {
+			if indexColNames[j].Column == nil {
+				continue
+			}
 			name2 := indexColNames[j].Column.Name
 			if name1.L == name2.L {
 				return infoschema.ErrColumnExists.GenByArgs(name2)
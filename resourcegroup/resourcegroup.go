@@ -0,0 +1,351 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcegroup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/stringutil"
+)
+
+// Resource group error codes.
+const (
+	codeGroupExists     terror.ErrCode = 3650
+	codeGroupNotExists  terror.ErrCode = 3651
+	codeGroupDisallowed terror.ErrCode = 3655
+	codeGroupBusy       terror.ErrCode = 3656
+
+	codeAdmissionCanceled terror.ErrCode = 1
+)
+
+var (
+	// ErrGroupExists is returned when the resource group to create exists.
+	ErrGroupExists = terror.ClassResourceGroup.New(codeGroupExists, "Resource Group '%s' exists")
+	// ErrGroupNotExists is returned when the resource group doesn't exist.
+	ErrGroupNotExists = terror.ClassResourceGroup.New(codeGroupNotExists, "Resource Group '%s' does not exist.")
+	// ErrGroupDisallowed is returned when the operation can't be done on the resource group, like dropping the
+	// default group.
+	ErrGroupDisallowed = terror.ClassResourceGroup.New(codeGroupDisallowed, "%s operation is disallowed on %s")
+	// ErrGroupBusy is returned when the queue of the resource group is full.
+	ErrGroupBusy = terror.ClassResourceGroup.New(codeGroupBusy, "Resource group %s is busy.")
+	// ErrAdmissionCanceled is returned when the statement is canceled while it waits to be admitted.
+	ErrAdmissionCanceled = terror.ClassResourceGroup.New(codeAdmissionCanceled, "canceled while waiting in the queue of the resource group")
+)
+
+func init() {
+	resourceGroupMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeGroupExists:     mysql.ErrResourceGroupExists,
+		codeGroupNotExists:  mysql.ErrResourceGroupNotExists,
+		codeGroupDisallowed: mysql.ErrDisallowedOperation,
+		codeGroupBusy:       mysql.ErrResourceGroupBusy,
+
+		codeAdmissionCanceled: mysql.ErrQueryInterrupted,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassResourceGroup] = resourceGroupMySQLErrCodes
+}
+
+// Settings are the limits of a resource group, 0 means unlimited.
+type Settings struct {
+	// MaxConcurrency is the max number of the statements executing at the same time.
+	MaxConcurrency uint64
+	// RUPerSec is the request units the statements may consume per second, see Usage.RequestUnits.
+	RUPerSec uint64
+	// QueueSize is the max number of the statements waiting to be admitted, the statements are rejected with
+	// ErrGroupBusy when the queue is full.
+	QueueSize uint64
+}
+
+// The request units approximate the resources consumed by a statement by its reads, writes and execution time.
+const (
+	// ruPerReadRequest is the request units of a kv read request.
+	ruPerReadRequest = 0.25
+	// scannedKeysPerRU is the number of the keys scanned by the reads for a request unit.
+	scannedKeysPerRU = 64
+	// ruPerWrittenRow is the request units of a row written.
+	ruPerWrittenRow = 1
+	// executionTimePerRU is the execution time for a request unit, it's the proxy of the CPU time.
+	executionTimePerRU = 3 * time.Millisecond
+)
+
+// Usage is the resources consumed by a statement.
+type Usage struct {
+	ReadRequests  int64
+	ScannedKeys   int64
+	AffectedRows  uint64
+	ExecutionTime time.Duration
+}
+
+// RequestUnits returns the request units of the usage.
+func (u Usage) RequestUnits() float64 {
+	return float64(u.ReadRequests)*ruPerReadRequest + float64(u.ScannedKeys)/scannedKeysPerRU +
+		float64(u.AffectedRows)*ruPerWrittenRow + float64(u.ExecutionTime)/float64(executionTimePerRU)
+}
+
+// group is the runtime state of a resource group.
+type group struct {
+	name string
+
+	mu       sync.Mutex
+	settings Settings
+	running  uint64
+	queued   uint64
+	// tokens is the request units available, it's refilled by RUPerSec per second up to RUPerSec, and may be
+	// negative since the request units of a statement are only known when it finishes.
+	tokens     float64
+	lastRefill time.Time
+	// wakeup is closed and renewed when a statement finishes or the settings change, so the waiting statements
+	// check whether they can be admitted again.
+	wakeup chan struct{}
+}
+
+func newGroup(name string, settings Settings) *group {
+	return &group{
+		name:       name,
+		settings:   settings,
+		tokens:     float64(settings.RUPerSec),
+		lastRefill: time.Now(),
+		wakeup:     make(chan struct{}),
+	}
+}
+
+func (g *group) refill(now time.Time) {
+	if g.settings.RUPerSec == 0 {
+		return
+	}
+	g.tokens += now.Sub(g.lastRefill).Seconds() * float64(g.settings.RUPerSec)
+	if g.tokens > float64(g.settings.RUPerSec) {
+		g.tokens = float64(g.settings.RUPerSec)
+	}
+	g.lastRefill = now
+}
+
+// tokenWait returns how long it takes to have the tokens to admit a statement, it's 0 if the tokens are available.
+func (g *group) tokenWait() time.Duration {
+	if g.settings.RUPerSec == 0 || g.tokens > 0 {
+		return 0
+	}
+	// Wait until the debt is paid off, plus a little so the tokens are positive.
+	return time.Duration((-g.tokens/float64(g.settings.RUPerSec))*float64(time.Second)) + time.Millisecond
+}
+
+func (g *group) notify() {
+	close(g.wakeup)
+	g.wakeup = make(chan struct{})
+}
+
+func (g *group) setSettings(settings Settings) {
+	g.mu.Lock()
+	g.refill(time.Now())
+	g.settings = settings
+	if g.tokens > float64(settings.RUPerSec) {
+		g.tokens = float64(settings.RUPerSec)
+	}
+	g.notify()
+	g.mu.Unlock()
+}
+
+func (g *group) admit(done <-chan struct{}) error {
+	// queued is true once the statement enters the queue, the queue size is only checked when it enters, so the
+	// waiting statement keeps its place when it's woken up but can't be admitted yet.
+	var queued bool
+	g.mu.Lock()
+	for {
+		g.refill(time.Now())
+		wait := g.tokenWait()
+		if (g.settings.MaxConcurrency == 0 || g.running < g.settings.MaxConcurrency) && wait == 0 {
+			g.running++
+			g.mu.Unlock()
+			return nil
+		}
+		if !queued && g.settings.QueueSize != 0 && g.queued >= g.settings.QueueSize {
+			g.mu.Unlock()
+			return ErrGroupBusy.GenByArgs(g.name)
+		}
+		queued = true
+		g.queued++
+		wakeup := g.wakeup
+		g.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		var canceled bool
+		select {
+		case <-wakeup:
+		case <-timeout:
+		case <-done:
+			canceled = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		g.mu.Lock()
+		g.queued--
+		if canceled {
+			g.mu.Unlock()
+			return ErrAdmissionCanceled.GenByArgs()
+		}
+	}
+}
+
+func (g *group) release(ru float64) {
+	g.mu.Lock()
+	g.running--
+	if g.settings.RUPerSec != 0 {
+		g.refill(time.Now())
+		g.tokens -= ru
+	}
+	g.notify()
+	g.mu.Unlock()
+}
+
+// Ticket is the admission of a statement, it must be released when the statement finishes.
+type Ticket struct {
+	g *group
+}
+
+// Release releases the admission, the request units of the usage are consumed from the group.
+func (t *Ticket) Release(usage Usage) {
+	t.g.release(usage.RequestUnits())
+}
+
+type userRecord struct {
+	Host          string
+	User          string
+	ResourceGroup string
+
+	// Compiled from Host, cached for pattern match performance.
+	patChars []byte
+	patTypes []byte
+}
+
+func (record *userRecord) match(user, host string) bool {
+	return record.User == user && stringutil.DoMatch(host, record.patChars, record.patTypes)
+}
+
+// Manager loads the resource groups and the groups of the users from the mysql.resource_group and mysql.user tables,
+// and admits the statements of the users by the limits of their groups. The statements of the users not in a group,
+// or in the default group, are not limited.
+type Manager struct {
+	ctx context.Context
+	// loadMu serializes the loads in ctx, Update is called by the reload loop and the statements changing the groups.
+	loadMu sync.Mutex
+
+	mu     sync.RWMutex
+	groups map[string]*group
+	users  []userRecord
+}
+
+// NewManager creates a Manager which loads the tables in the session ctx.
+func NewManager(ctx context.Context) *Manager {
+	return &Manager{ctx: ctx, groups: make(map[string]*group)}
+}
+
+// Update loads the resource groups and the groups of the users. The state of the existing groups is kept, so the
+// running and waiting statements are still counted.
+func (m *Manager) Update() error {
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	settings := make(map[string]Settings)
+	err := m.loadTable("select Name,Max_concurrency,RU_per_sec,Queue_size from mysql.resource_group", func(row *ast.Row) {
+		settings[row.Data[0].GetString()] = Settings{
+			MaxConcurrency: row.Data[1].GetUint64(),
+			RUPerSec:       row.Data[2].GetUint64(),
+			QueueSize:      row.Data[3].GetUint64(),
+		}
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var users []userRecord
+	err = m.loadTable("select Host,User,Resource_group from mysql.user order by host, user", func(row *ast.Row) {
+		record := userRecord{
+			Host:          row.Data[0].GetString(),
+			User:          row.Data[1].GetString(),
+			ResourceGroup: row.Data[2].GetString(),
+		}
+		record.patChars, record.patTypes = stringutil.CompilePattern(record.Host, '\\')
+		users = append(users, record)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	groups := make(map[string]*group, len(settings))
+	for name, s := range settings {
+		if g, ok := m.groups[name]; ok {
+			g.setSettings(s)
+			groups[name] = g
+		} else {
+			groups[name] = newGroup(name, s)
+		}
+	}
+	m.groups = groups
+	m.users = users
+	return nil
+}
+
+func (m *Manager) loadTable(sql string, decodeRow func(*ast.Row)) error {
+	tmp, err := m.ctx.(sqlexec.SQLExecutor).Execute(sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rs := tmp[0]
+	defer rs.Close()
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			return nil
+		}
+		decodeRow(row)
+	}
+}
+
+// Admit waits until the statement of the user can be executed by the limits of the user's group. It returns
+// ErrGroupBusy if the queue of the group is full, and ErrAdmissionCanceled if done is closed while it waits. The
+// returned ticket is nil if the statement isn't limited.
+func (m *Manager) Admit(user, host string, done <-chan struct{}) (*Ticket, error) {
+	m.mu.RLock()
+	var g *group
+	for i := range m.users {
+		if record := &m.users[i]; record.match(user, host) {
+			g = m.groups[record.ResourceGroup]
+			break
+		}
+	}
+	m.mu.RUnlock()
+	if g == nil {
+		return nil, nil
+	}
+	if err := g.admit(done); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Ticket{g: g}, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcegroup
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/stringutil"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testResourceGroupSuite{})
+
+type testResourceGroupSuite struct{}
+
+// waitQueued waits until n statements are waiting in the queue of g.
+func waitQueued(c *C, g *group, n uint64) {
+	for i := 0; i < 500; i++ {
+		g.mu.Lock()
+		queued := g.queued
+		g.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Fatalf("the queue length doesn't reach %d", n)
+}
+
+func (s *testResourceGroupSuite) TestAdmitConcurrency(c *C) {
+	defer testleak.AfterTest(c)()
+	g := newGroup("rg", Settings{MaxConcurrency: 2, QueueSize: 1})
+	c.Assert(g.admit(nil), IsNil)
+	c.Assert(g.admit(nil), IsNil)
+
+	admitted := make(chan error, 1)
+	go func() {
+		admitted <- g.admit(nil)
+	}()
+	waitQueued(c, g, 1)
+	// The queue is full.
+	err := g.admit(nil)
+	c.Assert(terror.ErrorEqual(err, ErrGroupBusy), IsTrue, Commentf("err %v", err))
+	c.Assert(err.Error(), Matches, ".*Resource group rg is busy.")
+
+	// The waiting statement is admitted when a statement finishes.
+	g.release(0)
+	select {
+	case err = <-admitted:
+		c.Assert(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the waiting statement isn't admitted")
+	}
+	c.Assert(g.running, Equals, uint64(2))
+	c.Assert(g.queued, Equals, uint64(0))
+
+	// The waiting statement returns when it's canceled.
+	done := make(chan struct{})
+	go func() {
+		admitted <- g.admit(done)
+	}()
+	waitQueued(c, g, 1)
+	close(done)
+	err = <-admitted
+	c.Assert(terror.ErrorEqual(err, ErrAdmissionCanceled), IsTrue, Commentf("err %v", err))
+	c.Assert(ErrAdmissionCanceled.ToSQLError().Code, Equals, uint16(mysql.ErrQueryInterrupted))
+	c.Assert(g.queued, Equals, uint64(0))
+
+	// The waiting statements are admitted when the limit is raised.
+	go func() {
+		admitted <- g.admit(nil)
+	}()
+	waitQueued(c, g, 1)
+	g.setSettings(Settings{})
+	c.Assert(<-admitted, IsNil)
+	c.Assert(g.running, Equals, uint64(3))
+}
+
+func (s *testResourceGroupSuite) TestAdmitQueued(c *C) {
+	defer testleak.AfterTest(c)()
+	g := newGroup("rg", Settings{MaxConcurrency: 1, QueueSize: 2})
+	c.Assert(g.admit(nil), IsNil)
+	admitted := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			admitted <- g.admit(nil)
+		}()
+	}
+	waitQueued(c, g, 2)
+
+	// The waiting statements keep their places when they are woken up, even if the queue is full after the queue
+	// size is reduced.
+	g.setSettings(Settings{MaxConcurrency: 1, QueueSize: 1})
+	// Wait for the waiting statements to check whether they can be admitted.
+	time.Sleep(10 * time.Millisecond)
+	err := g.admit(nil)
+	c.Assert(terror.ErrorEqual(err, ErrGroupBusy), IsTrue, Commentf("err %v", err))
+
+	// A waiting statement is admitted when a statement finishes, the other one still waits.
+	for i := 0; i < 2; i++ {
+		g.release(0)
+		select {
+		case err = <-admitted:
+			c.Assert(err, IsNil)
+		case <-time.After(5 * time.Second):
+			c.Fatal("the waiting statement isn't admitted")
+		}
+		waitQueued(c, g, uint64(1-i))
+	}
+	c.Assert(g.running, Equals, uint64(1))
+}
+
+func (s *testResourceGroupSuite) TestAdmitRequestUnits(c *C) {
+	defer testleak.AfterTest(c)()
+	g := newGroup("rg", Settings{RUPerSec: 100})
+	c.Assert(g.admit(nil), IsNil)
+	// The statement consumes 10 request units more than the tokens, the next one waits 0.1 second for the debt.
+	g.release(110)
+
+	start := time.Now()
+	c.Assert(g.admit(nil), IsNil)
+	c.Assert(time.Since(start) >= 80*time.Millisecond, IsTrue, Commentf("waited %v", time.Since(start)))
+	g.release(0)
+
+	// The tokens are refilled up to RUPerSec.
+	g.lastRefill = time.Now().Add(-time.Hour)
+	g.refill(time.Now())
+	c.Assert(g.tokens, Equals, float64(100))
+
+	// The request units are not limited if RUPerSec is 0.
+	g.setSettings(Settings{})
+	c.Assert(g.admit(nil), IsNil)
+	g.release(1e6)
+	c.Assert(g.admit(nil), IsNil)
+}
+
+func (s *testResourceGroupSuite) TestRequestUnits(c *C) {
+	defer testleak.AfterTest(c)()
+	usage := Usage{
+		ReadRequests:  4,
+		ScannedKeys:   128,
+		AffectedRows:  3,
+		ExecutionTime: 30 * time.Millisecond,
+	}
+	c.Assert(usage.RequestUnits(), Equals, float64(1+2+3+10))
+	c.Assert(Usage{}.RequestUnits(), Equals, float64(0))
+}
+
+func (s *testResourceGroupSuite) TestAdmitUsers(c *C) {
+	defer testleak.AfterTest(c)()
+	m := NewManager(nil)
+	m.groups["rg"] = newGroup("rg", Settings{MaxConcurrency: 1, QueueSize: 1})
+	for _, record := range []userRecord{
+		{Host: "10.0.%", User: "u1", ResourceGroup: "rg"},
+		{Host: "localhost", User: "u1"},
+		{Host: "%", User: "u2", ResourceGroup: "dropped"},
+	} {
+		record.patChars, record.patTypes = stringutil.CompilePattern(record.Host, '\\')
+		m.users = append(m.users, record)
+	}
+
+	// The users not in a group, or in a group not loaded, are not limited.
+	for _, user := range [][]string{{"u1", "localhost"}, {"u2", "10.0.0.1"}, {"u3", "10.0.0.1"}} {
+		ticket, err := m.Admit(user[0], user[1], nil)
+		c.Assert(err, IsNil)
+		c.Assert(ticket, IsNil)
+	}
+
+	ticket, err := m.Admit("u1", "10.0.0.1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(ticket, NotNil)
+	c.Assert(m.groups["rg"].running, Equals, uint64(1))
+	ticket.Release(Usage{})
+	c.Assert(m.groups["rg"].running, Equals, uint64(0))
+}
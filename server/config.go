@@ -23,4 +23,22 @@ type Config struct {
 	ReportStatus bool   `json:"report_status" toml:"report_status"`
 	StorePath    string `json:"store_path" toml:"store_path"`
 	Store        string `json:"store" toml:"store"`
+
+	// StatusAdmin enables the HTTP endpoints on the status address which change the settings, resign the DDL owner
+	// or reload the TLS certificates. The status address isn't authenticated, so they are disabled by default.
+	StatusAdmin bool `json:"status_admin" toml:"status_admin"`
+
+	// SSLCert and SSLKey are the paths of the PEM encoded certificate and key of the server, the clients can use TLS
+	// if both of them are set.
+	SSLCert string `json:"ssl_cert" toml:"ssl_cert"`
+	SSLKey  string `json:"ssl_key" toml:"ssl_key"`
+	// SSLCA is the path of the PEM encoded CA certificates to verify the client certificates with.
+	SSLCA string `json:"ssl_ca" toml:"ssl_ca"`
+	// SSLVerifyClient makes the TLS clients present a certificate signed by SSLCA.
+	SSLVerifyClient bool `json:"ssl_verify_client" toml:"ssl_verify_client"`
+
+	// ProxyProtocolNetworks is the comma separated addresses or CIDRs of the proxies, "*" means all the addresses.
+	// The connections from them must send the PROXY protocol header, the client address in it is used as the
+	// address of the connection.
+	ProxyProtocolNetworks string `json:"proxy_protocol_networks" toml:"proxy_protocol_networks"`
 }
@@ -35,7 +35,9 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -43,6 +45,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -59,7 +62,7 @@ var defaultCapability = mysql.ClientLongPassword | mysql.ClientLongFlag |
 	mysql.ClientConnectWithDB | mysql.ClientProtocol41 |
 	mysql.ClientTransactions | mysql.ClientSecureConnection | mysql.ClientFoundRows |
 	mysql.ClientMultiStatements | mysql.ClientMultiResults | mysql.ClientLocalFiles |
-	mysql.ClientConnectAtts
+	mysql.ClientConnectAtts | mysql.ClientCompress
 
 // clientConn represents a connection between server and client, it maintains connection specific state,
 // handles client query.
@@ -77,7 +80,9 @@ type clientConn struct {
 	lastCmd      string            // latest sql query string, currently used for logging error.
 	ctx          QueryCtx          // an interface to execute sql statements.
 	attrs        map[string]string // attributes parsed from client handshake response, not used for now.
-	killed       bool
+	tlsConfig    *tls.Config       // the TLS config of the server when the connection is accepted, nil if TLS is not enabled.
+	killed       int32             // set to 1 by KILL CONNECTION, accessed atomically.
+	status       int32             // connStatusDispatching, connStatusReading or connStatusShutdown, accessed atomically.
 }
 
 func (cc *clientConn) String() string {
@@ -107,20 +112,22 @@ func (cc *clientConn) handshake() error {
 	}
 
 	err := cc.writePacket(data)
-	cc.pkt.sequence = 0
+	cc.pkt.resetSequence()
 	if err != nil {
 		return errors.Trace(err)
 	}
-
-	return errors.Trace(cc.flush())
+	if err = cc.flush(); err != nil {
+		return errors.Trace(err)
+	}
+	// The packets after the OK packet of the handshake are compressed.
+	if cc.capability&mysql.ClientCompress > 0 {
+		cc.pkt.setCompressed()
+	}
+	return nil
 }
 
 func (cc *clientConn) Close() error {
-	cc.server.rwlock.Lock()
-	delete(cc.server.clients, cc.connectionID)
-	connections := len(cc.server.clients)
-	cc.server.rwlock.Unlock()
-	connGauge.Set(float64(connections))
+	cc.server.unregisterConn(cc)
 	cc.conn.Close()
 	if cc.ctx != nil {
 		return cc.ctx.Close()
@@ -144,15 +151,16 @@ func (cc *clientConn) writeInitialHandshake() error {
 	data = append(data, cc.salt[0:8]...)
 	// filler [00]
 	data = append(data, 0)
-	// capability flag lower 2 bytes, using default capability here
-	data = append(data, byte(defaultCapability), byte(defaultCapability>>8))
+	// capability flag lower 2 bytes
+	capability := cc.serverCapability()
+	data = append(data, byte(capability), byte(capability>>8))
 	// charset, utf-8 default
 	data = append(data, uint8(mysql.DefaultCollationID))
 	//status
 	data = append(data, dumpUint16(mysql.ServerStatusAutocommit)...)
 	// below 13 byte may not be used
-	// capability flag upper 2 bytes, using default capability here
-	data = append(data, byte(defaultCapability>>16), byte(defaultCapability>>24))
+	// capability flag upper 2 bytes
+	data = append(data, byte(capability>>16), byte(capability>>24))
 	// filler [0x15], for wireshark dump, value is 0x15
 	data = append(data, 0x15)
 	// reserved 10 [00]
@@ -284,17 +292,39 @@ func parseAttrs(data []byte) (map[string]string, error) {
 	return attrs, nil
 }
 
+// serverCapability returns the capability advertised to the client, ClientSSL is set if TLS is enabled.
+func (cc *clientConn) serverCapability() uint32 {
+	if cc.tlsConfig != nil {
+		return defaultCapability | mysql.ClientSSL
+	}
+	return defaultCapability
+}
+
+// sslRequestLen is the length of the SSLRequest packet, which is the fixed length part of the handshake response.
+// See http://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::SSLRequest
+const sslRequestLen = 32
+
 func (cc *clientConn) readHandshakeResponse() error {
 	data, err := cc.readPacket()
 	if err != nil {
 		return errors.Trace(err)
 	}
+	// A TLS client sends the SSLRequest packet first, then sends the whole handshake response after the TLS handshake.
+	if cc.tlsConfig != nil && len(data) == sslRequestLen && binary.LittleEndian.Uint32(data)&mysql.ClientSSL > 0 {
+		if err = cc.upgradeToTLS(); err != nil {
+			return errors.Trace(err)
+		}
+		data, err = cc.readPacket()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
 
 	var p handshakeResponse41
 	if err = handshakeResponseFromData(&p, data); err != nil {
 		return errors.Trace(err)
 	}
-	cc.capability = p.Capability & defaultCapability
+	cc.capability = p.Capability & cc.serverCapability()
 	cc.user = p.User
 	cc.dbname = p.DBName
 	cc.collation = p.Collation
@@ -303,7 +333,6 @@ func (cc *clientConn) readHandshakeResponse() error {
 	// Open session and do auth
 	cc.ctx, err = cc.server.driver.OpenCtx(uint64(cc.connectionID), cc.capability, uint8(cc.collation), cc.dbname)
 	if err != nil {
-		cc.Close()
 		return errors.Trace(err)
 	}
 	if !cc.server.skipAuth() {
@@ -319,12 +348,48 @@ func (cc *clientConn) readHandshakeResponse() error {
 		}
 	}
 	cc.ctx.SetSessionManager(cc.server)
-	return nil
+	return errors.Trace(cc.server.registerConn(cc))
 }
 
 // Run reads client query and writes query result to client in for loop, if there is a panic during query handling,
 // it will be recovered and log the panic error.
 // This function returns and the connection is closed if there is an IO error or there is a panic.
+// upgradeToTLS runs the TLS handshake on the connection, the following packets are read and written over TLS.
+func (cc *clientConn) upgradeToTLS() error {
+	// The client may send the TLS handshake right after the SSLRequest, so the bytes buffered by the packet reader
+	// should be read by TLS first.
+	tlsConn := tls.Server(&bufferedConn{Conn: cc.conn, rb: cc.pkt.rb}, cc.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return errors.Trace(err)
+	}
+	cc.conn = tlsConn
+	cc.pkt.setConn(tlsConn)
+	return nil
+}
+
+// bufferedConn is a net.Conn whose reads go through a buffered reader.
+type bufferedConn struct {
+	net.Conn
+	rb *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.rb.Read(b)
+}
+
+// The status of a connection, the idle connection which is reading the next command is closed by the graceful shutdown.
+const (
+	connStatusDispatching int32 = iota
+	connStatusReading
+	connStatusShutdown
+)
+
+// inTransaction checks whether the connection is in a transaction, it should be called when the connection is not
+// dispatching a command.
+func (cc *clientConn) inTransaction() bool {
+	return cc.ctx.Status()&mysql.ServerStatusInTrans > 0
+}
+
 func (cc *clientConn) Run() {
 	const size = 4096
 	defer func() {
@@ -338,11 +403,21 @@ func (cc *clientConn) Run() {
 		cc.Close()
 	}()
 
-	for !cc.killed {
+	for atomic.LoadInt32(&cc.killed) == 0 {
+		// The connection is closed after its transaction finishes when the server is shutting down.
+		if cc.server.inShutdownMode() && !cc.inTransaction() {
+			return
+		}
 		cc.alloc.Reset()
+		atomic.StoreInt32(&cc.status, connStatusReading)
 		data, err := cc.readPacket()
+		if !atomic.CompareAndSwapInt32(&cc.status, connStatusReading, connStatusDispatching) {
+			// The idle connection is closed by the graceful shutdown.
+			return
+		}
 		if err != nil {
-			if terror.ErrorNotEqual(err, io.EOF) {
+			// The connection is closed by KILL CONNECTION if it's killed.
+			if terror.ErrorNotEqual(err, io.EOF) && atomic.LoadInt32(&cc.killed) == 0 {
 				log.Error(errors.ErrorStack(err))
 			}
 			return
@@ -368,7 +443,7 @@ func (cc *clientConn) Run() {
 			cc.writeError(err)
 		}
 		cc.addMetrics(data[0], startTime, err)
-		cc.pkt.sequence = 0
+		cc.pkt.resetSequence()
 	}
 }
 
@@ -413,6 +488,8 @@ func (cc *clientConn) addMetrics(cmd byte, startTime time.Time, err error) {
 		label = "StmtSendLongData"
 	case mysql.ComStmtReset:
 		label = "StmtReset"
+	case mysql.ComStmtFetch:
+		label = "StmtFetch"
 	case mysql.ComSetOption:
 		label = "SetOption"
 	default:
@@ -474,6 +551,8 @@ func (cc *clientConn) dispatch(data []byte) error {
 		return cc.handleStmtSendLongData(data)
 	case mysql.ComStmtReset:
 		return cc.handleStmtReset(data)
+	case mysql.ComStmtFetch:
+		return cc.handleStmtFetch(data)
 	case mysql.ComSetOption:
 		return cc.handleSetOption(data)
 	default:
@@ -497,12 +576,21 @@ func (cc *clientConn) flush() error {
 }
 
 func (cc *clientConn) writeOK() error {
+	return errors.Trace(cc.writeOKWithMore(false))
+}
+
+// writeOKWithMore writes an OK packet, SERVER_MORE_RESULTS_EXISTS is set if more is true.
+func (cc *clientConn) writeOKWithMore(more bool) error {
 	data := cc.alloc.AllocWithLen(4, 32)
 	data = append(data, mysql.OKHeader)
 	data = append(data, dumpLengthEncodedInt(uint64(cc.ctx.AffectedRows()))...)
 	data = append(data, dumpLengthEncodedInt(uint64(cc.ctx.LastInsertID()))...)
 	if cc.capability&mysql.ClientProtocol41 > 0 {
-		data = append(data, dumpUint16(cc.ctx.Status())...)
+		status := cc.ctx.Status()
+		if more {
+			status |= mysql.ServerMoreResultsExists
+		}
+		data = append(data, dumpUint16(status)...)
 		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
 	}
 
@@ -523,7 +611,7 @@ func (cc *clientConn) writeError(e error) error {
 	originErr := errors.Cause(e)
 	if te, ok = originErr.(*terror.Error); ok {
 		m = te.ToSQLError()
-	} else {
+	} else if m, ok = originErr.(*mysql.SQLError); !ok {
 		m = mysql.NewErrf(mysql.ErrUnknown, e.Error())
 	}
 
@@ -559,7 +647,21 @@ func (cc *clientConn) writeEOF(more bool) error {
 		if more {
 			status |= mysql.ServerMoreResultsExists
 		}
-		data = append(data, dumpUint16(cc.ctx.Status())...)
+		data = append(data, dumpUint16(status)...)
+	}
+
+	err := cc.writePacket(data)
+	return errors.Trace(err)
+}
+
+// writeEOFWithStatus writes an EOF packet with the server status, like writeEOF it doesn't flush the stream.
+func (cc *clientConn) writeEOFWithStatus(status uint16) error {
+	data := cc.alloc.AllocWithLen(4, 9)
+
+	data = append(data, mysql.EOFHeader)
+	if cc.capability&mysql.ClientProtocol41 > 0 {
+		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
+		data = append(data, dumpUint16(status)...)
 	}
 
 	err := cc.writePacket(data)
@@ -579,6 +681,8 @@ func (cc *clientConn) writeReq(filePath string) error {
 	return errors.Trace(cc.flush())
 }
 
+// defaultLoadDataBatchCnt is the number of rows committed in a transaction by LOAD DATA, if tidb_dml_batch_size
+// isn't set.
 var defaultLoadDataBatchCnt = 20000
 
 func insertDataWithCommit(prevData, curData []byte, loadDataInfo *executor.LoadDataInfo) ([]byte, error) {
@@ -592,11 +696,7 @@ func insertDataWithCommit(prevData, curData []byte, loadDataInfo *executor.LoadD
 		if !reachLimit {
 			break
 		}
-		// Make sure that there are no retries when committing.
-		if err = loadDataInfo.Ctx.Txn().Commit(); err != nil {
-			return nil, errors.Trace(err)
-		}
-		if err = loadDataInfo.Ctx.NewTxn(); err != nil {
+		if err = loadDataInfo.CommitBatch(); err != nil {
 			return nil, errors.Trace(err)
 		}
 		curData = prevData
@@ -623,8 +723,11 @@ func (cc *clientConn) handleLoadData(loadDataInfo *executor.LoadDataInfo) error
 
 	var shouldBreak bool
 	var prevData, curData []byte
-	// TODO: Make the loadDataRowCnt settable.
-	loadDataInfo.SetBatchCount(int64(defaultLoadDataBatchCnt))
+	batchCnt := loadDataInfo.Ctx.GetSessionVars().DMLBatchSize
+	if batchCnt <= 0 {
+		batchCnt = defaultLoadDataBatchCnt
+	}
+	loadDataInfo.SetBatchCount(int64(batchCnt))
 	err = loadDataInfo.Ctx.NewTxn()
 	if err != nil {
 		return errors.Trace(err)
@@ -659,7 +762,11 @@ func (cc *clientConn) handleLoadData(loadDataInfo *executor.LoadDataInfo) error
 				log.Errorf("load data rollback failed: %v", err1)
 			}
 		}
-		return errors.Trace(err)
+		// The committed batches are kept, the rest of the file can be loaded by skipping the committed lines.
+		committed := loadDataInfo.CommittedLines()
+		log.Warnf("[%d] load data failed after %d lines are committed, resume it with \"IGNORE %d LINES\"",
+			cc.connectionID, committed, committed)
+		return errors.Annotatef(err, "%d lines are committed", committed)
 	}
 	return errors.Trace(txn.Commit())
 }
@@ -668,6 +775,9 @@ func (cc *clientConn) handleLoadData(loadDataInfo *executor.LoadDataInfo) error
 // As the execution time of this function represents the performance of TiDB, we do time log and metrics here.
 // There is a special query `load data` that does not return result, which is handled differently.
 func (cc *clientConn) handleQuery(sql string) (err error) {
+	if cc.capability&mysql.ClientMultiStatements > 0 {
+		return errors.Trace(cc.handleMultiStatements(sql))
+	}
 	rs, err := cc.ctx.Execute(sql)
 	if err != nil {
 		executeErrorCounter.WithLabelValues(executeErrorToLabel(err)).Inc()
@@ -680,18 +790,59 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 			err = cc.writeMultiResultset(rs, false)
 		}
 	} else {
-		loadDataInfo := cc.ctx.Value(executor.LoadDataVarKey)
-		if loadDataInfo != nil {
-			defer cc.ctx.SetValue(executor.LoadDataVarKey, nil)
-			if err = cc.handleLoadData(loadDataInfo.(*executor.LoadDataInfo)); err != nil {
-				return errors.Trace(err)
-			}
+		if err = cc.handleLoadDataIfAny(); err != nil {
+			return errors.Trace(err)
 		}
 		err = cc.writeOK()
 	}
 	return errors.Trace(err)
 }
 
+// handleMultiStatements executes the statements of the query one by one, the result of a statement is written before
+// the next statement is executed, and all the results but the last one have the SERVER_MORE_RESULTS_EXISTS flag. The
+// statements after a failed one are not executed.
+// See https://dev.mysql.com/doc/internals/en/multi-statement.html
+func (cc *clientConn) handleMultiStatements(sql string) error {
+	stmts, err := cc.ctx.Parse(sql)
+	if err != nil {
+		executeErrorCounter.WithLabelValues(executeErrorToLabel(err)).Inc()
+		return errors.Trace(err)
+	}
+	for i, stmt := range stmts {
+		more := i < len(stmts)-1
+		rs, err := cc.ctx.ExecuteStmt(stmt)
+		if err != nil {
+			executeErrorCounter.WithLabelValues(executeErrorToLabel(err)).Inc()
+			return errors.Trace(err)
+		}
+		if rs != nil {
+			err = cc.writeResultset(rs, false, more)
+		} else {
+			if err = cc.handleLoadDataIfAny(); err != nil {
+				return errors.Trace(err)
+			}
+			err = cc.writeOKWithMore(more)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(stmts) == 0 {
+		return errors.Trace(cc.writeOK())
+	}
+	return nil
+}
+
+// handleLoadDataIfAny reads the file from the client if the statement is LOAD DATA LOCAL INFILE.
+func (cc *clientConn) handleLoadDataIfAny() error {
+	loadDataInfo := cc.ctx.Value(executor.LoadDataVarKey)
+	if loadDataInfo == nil {
+		return nil
+	}
+	defer cc.ctx.SetValue(executor.LoadDataVarKey, nil)
+	return errors.Trace(cc.handleLoadData(loadDataInfo.(*executor.LoadDataInfo)))
+}
+
 // handleFieldList returns the field list for a table.
 // The sql string is composed of a table name and a terminating character \x00.
 func (cc *clientConn) handleFieldList(sql string) (err error) {
@@ -732,25 +883,15 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 		return errors.Trace(err)
 	}
 
-	columnLen := dumpLengthEncodedInt(uint64(len(columns)))
-	data := cc.alloc.AllocWithLen(4, 1024)
-	data = append(data, columnLen...)
-	if err = cc.writePacket(data); err != nil {
+	if err = cc.writeColumnInfo(columns); err != nil {
 		return errors.Trace(err)
 	}
-
-	for _, v := range columns {
-		data = data[0:4]
-		data = append(data, v.Dump(cc.alloc)...)
-		if err = cc.writePacket(data); err != nil {
-			return errors.Trace(err)
-		}
-	}
-
 	if err = cc.writeEOF(false); err != nil {
 		return errors.Trace(err)
 	}
 
+	data := cc.alloc.AllocWithLen(4, 1024)
+
 	for {
 		if err != nil {
 			return errors.Trace(err)
@@ -795,6 +936,25 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 	return errors.Trace(cc.flush())
 }
 
+// writeColumnInfo writes the column count and the column definitions of a result set, without the EOF packet.
+func (cc *clientConn) writeColumnInfo(columns []*ColumnInfo) error {
+	columnLen := dumpLengthEncodedInt(uint64(len(columns)))
+	data := cc.alloc.AllocWithLen(4, 1024)
+	data = append(data, columnLen...)
+	if err := cc.writePacket(data); err != nil {
+		return errors.Trace(err)
+	}
+
+	for _, v := range columns {
+		data = data[0:4]
+		data = append(data, v.Dump(cc.alloc)...)
+		if err := cc.writePacket(data); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func (cc *clientConn) writeMultiResultset(rss []ResultSet, binary bool) error {
 	for _, rs := range rss {
 		if err := cc.writeResultset(rs, binary, true); err != nil {
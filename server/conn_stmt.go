@@ -36,10 +36,12 @@ package server
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 	"strconv"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/hack"
 )
@@ -118,10 +120,11 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 
 	flag := data[pos]
 	pos++
-	//now we only support CURSOR_TYPE_NO_CURSOR flag
-	if flag != 0 {
+	// Only the read only cursor is supported besides CURSOR_TYPE_NO_CURSOR.
+	if flag&^mysql.CursorTypeReadOnly != 0 {
 		return mysql.NewErrf(mysql.ErrUnknown, "unsupported flag %d", flag)
 	}
+	useCursor := flag&mysql.CursorTypeReadOnly > 0
 
 	//skip iteration-count, always 1
 	pos += 4
@@ -163,6 +166,8 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 			return errors.Trace(err)
 		}
 	}
+	// Executing the statement again closes its cursor, and the long data is only used by one execution.
+	stmt.Reset()
 	rs, err := stmt.Execute(args...)
 	if err != nil {
 		return errors.Trace(err)
@@ -170,10 +175,86 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 	if rs == nil {
 		return errors.Trace(cc.writeOK())
 	}
+	if useCursor {
+		return errors.Trace(cc.openCursor(stmt, rs))
+	}
 
 	return errors.Trace(cc.writeResultset(rs, true, false))
 }
 
+// openCursor stores the result set in the statement and writes the column definitions only, the rows are written
+// by COM_STMT_FETCH later. The executors are suspended between the fetches, instead of reading all the rows at once.
+func (cc *clientConn) openCursor(stmt PreparedStatement, rs ResultSet) error {
+	stmt.StoreResultSet(rs)
+	columns, err := rs.Columns()
+	if err != nil {
+		stmt.StoreResultSet(nil)
+		return errors.Trace(err)
+	}
+	if err = cc.writeColumnInfo(columns); err != nil {
+		return errors.Trace(err)
+	}
+	if err = cc.writeEOFWithStatus(cc.ctx.Status() | mysql.ServerStatusCursorExists); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// handleStmtFetch writes at most the requested number of rows of the cursor of the statement.
+// See https://dev.mysql.com/doc/internals/en/com-stmt-fetch.html
+func (cc *clientConn) handleStmtFetch(data []byte) (err error) {
+	if len(data) < 8 {
+		return mysql.ErrMalformPacket
+	}
+
+	stmtID := binary.LittleEndian.Uint32(data[0:4])
+	fetchSize := binary.LittleEndian.Uint32(data[4:8])
+	stmt := cc.ctx.GetStatement(int(stmtID))
+	if stmt == nil {
+		return mysql.NewErr(mysql.ErrUnknownStmtHandler,
+			strconv.FormatUint(uint64(stmtID), 10), "stmt_fetch")
+	}
+	rs := stmt.GetResultSet()
+	if rs == nil {
+		return mysql.NewErrf(mysql.ErrStmtHasNoOpenCursor, "The statement (%d) has no open cursor.", stmtID)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		stmt.StoreResultSet(nil)
+		return errors.Trace(err)
+	}
+
+	data = cc.alloc.AllocWithLen(4, 1024)
+	for i := uint32(0); i < fetchSize; i++ {
+		row, err := rs.Next()
+		if err != nil {
+			stmt.StoreResultSet(nil)
+			return errors.Trace(err)
+		}
+		if row == nil {
+			// The cursor is closed after the last row is sent.
+			stmt.StoreResultSet(nil)
+			if err = cc.writeEOFWithStatus(cc.ctx.Status() | mysql.ServerStatusLastRowSend); err != nil {
+				return errors.Trace(err)
+			}
+			return errors.Trace(cc.flush())
+		}
+		rowData, err := dumpRowValuesBinary(cc.alloc, columns, row)
+		if err != nil {
+			stmt.StoreResultSet(nil)
+			return errors.Trace(err)
+		}
+		data = append(data[0:4], rowData...)
+		if err = cc.writePacket(data); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err = cc.writeEOFWithStatus(cc.ctx.Status() | mysql.ServerStatusCursorExists); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
 func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTypes, paramValues []byte) (err error) {
 	pos := 0
 	var v []byte
@@ -211,7 +292,7 @@ func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTy
 			if isUnsigned {
 				args[i] = uint64(paramValues[pos])
 			} else {
-				args[i] = int64(paramValues[pos])
+				args[i] = int64(int8(paramValues[pos]))
 			}
 
 			pos++
@@ -226,7 +307,7 @@ func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTy
 			if isUnsigned {
 				args[i] = uint64(valU16)
 			} else {
-				args[i] = int64(valU16)
+				args[i] = int64(int16(valU16))
 			}
 			pos += 2
 			continue
@@ -240,7 +321,7 @@ func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTy
 			if isUnsigned {
 				args[i] = uint64(valU32)
 			} else {
-				args[i] = int64(valU32)
+				args[i] = int64(int32(valU32))
 			}
 			pos += 4
 			continue
@@ -279,12 +360,34 @@ func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTy
 			pos += 8
 			continue
 
+		case mysql.TypeDate, mysql.TypeTimestamp, mysql.TypeDatetime, mysql.TypeDuration:
+			// The temporal values are sent in binary, the first byte is the length of the value.
+			if len(paramValues) < (pos + 1) {
+				err = mysql.ErrMalformPacket
+				return
+			}
+			length := int(paramValues[pos])
+			pos++
+			if len(paramValues) < (pos + length) {
+				err = mysql.ErrMalformPacket
+				return
+			}
+			if tp == mysql.TypeDuration {
+				args[i], err = parseBinaryDuration(paramValues[pos : pos+length])
+			} else {
+				args[i], err = parseBinaryDateTime(paramValues[pos : pos+length])
+			}
+			if err != nil {
+				return
+			}
+			pos += length
+			continue
+
 		case mysql.TypeUnspecified, mysql.TypeNewDecimal, mysql.TypeVarchar,
 			mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet, mysql.TypeTinyBlob,
 			mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob,
 			mysql.TypeVarString, mysql.TypeString, mysql.TypeGeometry,
-			mysql.TypeDate, mysql.TypeNewDate,
-			mysql.TypeTimestamp, mysql.TypeDatetime, mysql.TypeDuration:
+			mysql.TypeNewDate:
 			if len(paramValues) < (pos + 1) {
 				err = mysql.ErrMalformPacket
 				return
@@ -310,6 +413,44 @@ func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTy
 	return
 }
 
+// parseBinaryDateTime parses the binary DATE, DATETIME and TIMESTAMP value to a string.
+// See https://dev.mysql.com/doc/internals/en/binary-protocol-value.html
+func parseBinaryDateTime(data []byte) (string, error) {
+	switch len(data) {
+	case 0:
+		return "0000-00-00 00:00:00", nil
+	case 4:
+		return fmt.Sprintf("%04d-%02d-%02d", binary.LittleEndian.Uint16(data[0:2]), data[2], data[3]), nil
+	case 7:
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", binary.LittleEndian.Uint16(data[0:2]), data[2], data[3],
+			data[4], data[5], data[6]), nil
+	case 11:
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d.%06d", binary.LittleEndian.Uint16(data[0:2]), data[2], data[3],
+			data[4], data[5], data[6], binary.LittleEndian.Uint32(data[7:11])), nil
+	default:
+		return "", mysql.ErrMalformPacket
+	}
+}
+
+// parseBinaryDuration parses the binary TIME value to a string.
+func parseBinaryDuration(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "0", nil
+	}
+	if len(data) != 8 && len(data) != 12 {
+		return "", mysql.ErrMalformPacket
+	}
+	var sign string
+	if data[0] == 1 {
+		sign = "-"
+	}
+	hours := binary.LittleEndian.Uint32(data[1:5])*24 + uint32(data[5])
+	if len(data) == 8 {
+		return fmt.Sprintf("%s%d:%02d:%02d", sign, hours, data[6], data[7]), nil
+	}
+	return fmt.Sprintf("%s%d:%02d:%02d.%06d", sign, hours, data[6], data[7], binary.LittleEndian.Uint32(data[8:12])), nil
+}
+
 func (cc *clientConn) handleStmtClose(data []byte) (err error) {
 	if len(data) < 4 {
 		return
@@ -323,21 +464,28 @@ func (cc *clientConn) handleStmtClose(data []byte) (err error) {
 	return
 }
 
+// handleStmtSendLongData appends the data to a parameter of the statement. The server doesn't respond to
+// COM_STMT_SEND_LONG_DATA, so the errors are logged only, like MySQL ignores them.
+// See https://dev.mysql.com/doc/internals/en/com-stmt-send-long-data.html
 func (cc *clientConn) handleStmtSendLongData(data []byte) (err error) {
 	if len(data) < 6 {
-		return mysql.ErrMalformPacket
+		log.Warnf("[%d] malformed stmt_send_longdata packet", cc.connectionID)
+		return nil
 	}
 
 	stmtID := int(binary.LittleEndian.Uint32(data[0:4]))
 
 	stmt := cc.ctx.GetStatement(stmtID)
 	if stmt == nil {
-		return mysql.NewErr(mysql.ErrUnknownStmtHandler,
-			strconv.Itoa(stmtID), "stmt_send_longdata")
+		log.Warnf("[%d] unknown prepared statement %d in stmt_send_longdata", cc.connectionID, stmtID)
+		return nil
 	}
 
 	paramID := int(binary.LittleEndian.Uint16(data[4:6]))
-	return stmt.AppendParam(paramID, data[6:])
+	if err = stmt.AppendParam(paramID, data[6:]); err != nil {
+		log.Warnf("[%d] stmt_send_longdata of statement %d error: %v", cc.connectionID, stmtID, err)
+	}
+	return nil
 }
 
 func (cc *clientConn) handleStmtReset(data []byte) (err error) {
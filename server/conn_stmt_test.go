@@ -0,0 +1,68 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/mysql"
+)
+
+func (ts ConnTestSuite) TestParseStmtArgs(c *C) {
+	c.Parallel()
+	paramTypes := []byte{
+		mysql.TypeTiny, 0,
+		mysql.TypeShort, 0,
+		mysql.TypeLong, 0,
+		mysql.TypeLong, 0x80,
+		mysql.TypeDatetime, 0,
+		mysql.TypeDate, 0,
+		mysql.TypeDuration, 0,
+		mysql.TypeDatetime, 0,
+		mysql.TypeBlob, 0,
+		mysql.TypeVarString, 0,
+	}
+	paramValues := []byte{
+		0xff,
+		0xfe, 0xff,
+		0xfd, 0xff, 0xff, 0xff,
+		0xfd, 0xff, 0xff, 0xff,
+		11, 0xe1, 0x07, 1, 5, 10, 11, 12, 0x40, 0xe2, 0x01, 0x00,
+		4, 0xe1, 0x07, 1, 5,
+		8, 1, 1, 0, 0, 0, 2, 3, 4,
+		0,
+		3, 'a', 'b', 'c',
+	}
+	// The 9th parameter is sent by COM_STMT_SEND_LONG_DATA.
+	boundParams := make([][]byte, 10)
+	boundParams[8] = []byte("long")
+	args := make([]interface{}, 10)
+	err := parseStmtArgs(args, boundParams, []byte{0, 0}, paramTypes, paramValues)
+	c.Assert(err, IsNil)
+	c.Assert(args, DeepEquals, []interface{}{
+		int64(-1),
+		int64(-2),
+		int64(-3),
+		uint64(4294967293),
+		"2017-01-05 10:11:12.123456",
+		"2017-01-05",
+		"-26:03:04",
+		"0000-00-00 00:00:00",
+		[]byte("long"),
+		"abc",
+	})
+
+	// The binary temporal values have fixed lengths.
+	err = parseStmtArgs(args[:1], boundParams, []byte{0}, []byte{mysql.TypeDatetime, 0}, []byte{3, 1, 2, 3})
+	c.Assert(err, NotNil)
+}
@@ -14,6 +14,9 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/mysql"
 )
@@ -97,6 +100,45 @@ func (ts ConnTestSuite) TestIssue1768(c *C) {
 	c.Assert(len(p.Auth) > 0, IsTrue)
 }
 
+func (ts ConnTestSuite) TestCompressedPacket(c *C) {
+	c.Parallel()
+	buf := new(bytes.Buffer)
+	writer := &packetIO{wb: bufio.NewWriter(buf)}
+	writer.setCompressed()
+	reader := &packetIO{rb: bufio.NewReader(buf)}
+	reader.setCompressed()
+	writeAndRead := func(payload []byte) []byte {
+		data := append(make([]byte, 4), payload...)
+		c.Assert(writer.writePacket(data), IsNil)
+		c.Assert(writer.flush(), IsNil)
+		raw := append([]byte(nil), buf.Bytes()...)
+		data, err := reader.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data, DeepEquals, payload)
+		return raw
+	}
+
+	// The short payload is not compressed.
+	raw := writeAndRead([]byte("abc"))
+	c.Assert(raw, DeepEquals, []byte{7, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 'a', 'b', 'c'})
+
+	// The long payload is compressed.
+	raw = writeAndRead(bytes.Repeat([]byte("a"), 100*1024))
+	c.Assert(len(raw), Less, 10*1024)
+	c.Assert(raw[3], Equals, byte(1))
+	c.Assert(raw[4:7], Not(DeepEquals), []byte{0, 0, 0})
+	c.Assert(writer.compressedSequence, Equals, uint8(2))
+	c.Assert(reader.compressedSequence, Equals, writer.compressedSequence)
+
+	// The sequence of the compressed packets is checked.
+	reader.resetSequence()
+	data := append(make([]byte, 4), 'a')
+	c.Assert(writer.writePacket(data), IsNil)
+	c.Assert(writer.flush(), IsNil)
+	_, err := reader.readPacket()
+	c.Assert(err, NotNil)
+}
+
 func mapIdentical(m1, m2 map[string]string) bool {
 	return mapBelong(m1, m2) && mapBelong(m2, m1)
 }
@@ -16,6 +16,8 @@ package server
 import (
 	"fmt"
 
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -58,6 +60,12 @@ type QueryCtx interface {
 	// Execute executes a SQL statement.
 	Execute(sql string) ([]ResultSet, error)
 
+	// Parse parses a SQL to statements.
+	Parse(sql string) ([]ast.StmtNode, error)
+
+	// ExecuteStmt executes a statement returned by Parse.
+	ExecuteStmt(stmt ast.StmtNode) (ResultSet, error)
+
 	// SetClientCapability sets client capability flags
 	SetClientCapability(uint32)
 
@@ -83,6 +91,12 @@ type QueryCtx interface {
 
 	// Cancel the execution of current transaction.
 	Cancel()
+
+	// GetGlobalSysVar gets the value of a global system variable.
+	GetGlobalSysVar(name string) (string, error)
+
+	// RequestVerification verifies the global privilege of the user.
+	RequestVerification(priv mysql.PrivilegeType) bool
 }
 
 // PreparedStatement is the interface to use a prepared statement.
@@ -108,9 +122,16 @@ type PreparedStatement interface {
 	// GetParamsType returns the type for parameters.
 	GetParamsType() []byte
 
-	// Reset removes all bound parameters.
+	// Reset removes all bound parameters and closes the cursor.
 	Reset()
 
+	// StoreResultSet stores the result set of the statement executed with a cursor, the rows are fetched later.
+	// The result set of the previous cursor is closed, a nil result set just closes it.
+	StoreResultSet(rs ResultSet)
+
+	// GetResultSet returns the result set of the cursor of the statement, it's nil if no cursor is open.
+	GetResultSet() ResultSet
+
 	// Close closes the statement.
 	Close() error
 }
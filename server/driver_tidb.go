@@ -17,10 +17,14 @@ import (
 	"fmt"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -52,6 +56,7 @@ type TiDBStatement struct {
 	boundParams [][]byte
 	paramsType  []byte
 	ctx         *TiDBContext
+	rs          ResultSet
 }
 
 // ID implements PreparedStatement ID method.
@@ -108,11 +113,36 @@ func (ts *TiDBStatement) Reset() {
 	for i := range ts.boundParams {
 		ts.boundParams[i] = nil
 	}
+	ts.StoreResultSet(nil)
+}
+
+// StoreResultSet implements PreparedStatement StoreResultSet method.
+// The statement context of the execution is saved with the result set, because other statements are executed in
+// the session before the rows are fetched.
+func (ts *TiDBStatement) StoreResultSet(rs ResultSet) {
+	if ts.rs != nil && ts.rs != rs {
+		if err := ts.rs.Close(); err != nil {
+			log.Errorf("[%d] close cursor of statement %d error: %v", ts.ctx.session.GetSessionVars().ConnectionID, ts.id, err)
+		}
+	}
+	if rs != nil {
+		if _, ok := rs.(*cursorResultSet); !ok {
+			sessVars := ts.ctx.session.GetSessionVars()
+			rs = &cursorResultSet{ResultSet: rs, sessVars: sessVars, stmtCtx: sessVars.StmtCtx}
+		}
+	}
+	ts.rs = rs
+}
+
+// GetResultSet implements PreparedStatement GetResultSet method.
+func (ts *TiDBStatement) GetResultSet() ResultSet {
+	return ts.rs
 }
 
 // Close implements PreparedStatement Close method.
 func (ts *TiDBStatement) Close() error {
 	//TODO close at tidb level
+	ts.StoreResultSet(nil)
 	err := ts.ctx.session.DropPreparedStmt(ts.id)
 	if err != nil {
 		return errors.Trace(err)
@@ -206,6 +236,25 @@ func (tc *TiDBContext) Execute(sql string) (rs []ResultSet, err error) {
 	return
 }
 
+// Parse implements QueryCtx Parse method.
+func (tc *TiDBContext) Parse(sql string) ([]ast.StmtNode, error) {
+	charset, collation := tc.session.GetSessionVars().GetCharsetInfo()
+	stmts, err := tc.session.ParseSQL(sql, charset, collation)
+	return stmts, errors.Trace(err)
+}
+
+// ExecuteStmt implements QueryCtx ExecuteStmt method.
+func (tc *TiDBContext) ExecuteStmt(stmt ast.StmtNode) (ResultSet, error) {
+	rs, err := tc.session.ExecuteStmt(stmt)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if rs == nil {
+		return nil, nil
+	}
+	return &tidbResultSet{recordSet: rs}, nil
+}
+
 // SetSessionManager implements the QueryCtx interface.
 func (tc *TiDBContext) SetSessionManager(sm util.SessionManager) {
 	tc.session.SetSessionManager(sm)
@@ -218,6 +267,9 @@ func (tc *TiDBContext) SetClientCapability(flags uint32) {
 
 // Close implements QueryCtx Close method.
 func (tc *TiDBContext) Close() (err error) {
+	for _, stmt := range tc.stmts {
+		stmt.StoreResultSet(nil)
+	}
 	return tc.session.Close()
 }
 
@@ -285,6 +337,46 @@ func (tc *TiDBContext) Cancel() {
 	tc.session.Cancel()
 }
 
+// GetGlobalSysVar implements QueryCtx GetGlobalSysVar method.
+func (tc *TiDBContext) GetGlobalSysVar(name string) (string, error) {
+	value, err := varsutil.GetGlobalSystemVar(tc.session.GetSessionVars(), name)
+	return value, errors.Trace(err)
+}
+
+// RequestVerification implements QueryCtx RequestVerification method.
+func (tc *TiDBContext) RequestVerification(priv mysql.PrivilegeType) bool {
+	return privilege.GetPrivilegeChecker(tc.session).RequestVerification("", "", "", priv)
+}
+
+// cursorResultSet is the result set of a cursor. The statement context of the execution is restored while the rows
+// are fetched and the result set is closed, so the warnings, the read details and the resources charged to the
+// resource group of the user are recorded for the statement of the cursor.
+type cursorResultSet struct {
+	ResultSet
+	sessVars *variable.SessionVars
+	stmtCtx  *variable.StatementContext
+}
+
+// restoreStmtCtx sets the statement context of the cursor in the session, it returns the function to set it back.
+func (crs *cursorResultSet) restoreStmtCtx() func() {
+	sc := crs.sessVars.StmtCtx
+	crs.sessVars.StmtCtx = crs.stmtCtx
+	return func() {
+		crs.sessVars.StmtCtx = sc
+	}
+}
+
+func (crs *cursorResultSet) Next() ([]types.Datum, error) {
+	defer crs.restoreStmtCtx()()
+	row, err := crs.ResultSet.Next()
+	return row, errors.Trace(err)
+}
+
+func (crs *cursorResultSet) Close() error {
+	defer crs.restoreStmtCtx()()
+	return errors.Trace(crs.ResultSet.Close())
+}
+
 type tidbResultSet struct {
 	recordSet ast.RecordSet
 }
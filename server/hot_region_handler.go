@@ -0,0 +1,137 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/util/hotregion"
+)
+
+const (
+	qLimit = "limit"
+	// defaultHotRegionLimit is the default number of the hot regions and keys returned.
+	defaultHotRegionLimit = 100
+)
+
+// HotRegionHandler is the handler for the hot regions and keys accessed by this server.
+type HotRegionHandler struct {
+	server *Server
+}
+
+// HotRegions is the hot regions and keys accessed by this server.
+type HotRegions struct {
+	Regions []HotRegion `json:"regions"`
+	Keys    []HotKey    `json:"keys"`
+}
+
+// HotRegion is the access statistics of a region.
+type HotRegion struct {
+	RegionID   uint64 `json:"region_id"`
+	DBName     string `json:"db_name"`
+	TableName  string `json:"table_name"`
+	IndexName  string `json:"index_name"`
+	ReadCount  uint64 `json:"read_count"`
+	WriteCount uint64 `json:"write_count"`
+}
+
+// HotKey is the access statistics of a key.
+type HotKey struct {
+	Key        string `json:"key"`
+	RegionID   uint64 `json:"region_id"`
+	DBName     string `json:"db_name"`
+	TableName  string `json:"table_name"`
+	IndexName  string `json:"index_name"`
+	ReadCount  uint64 `json:"read_count"`
+	WriteCount uint64 `json:"write_count"`
+}
+
+func (s *Server) newHotRegionHandler() HotRegionHandler {
+	return HotRegionHandler{server: s}
+}
+
+// ServeHTTP handles request of getting the hottest regions and keys in json format, the number of them is limited by
+// the "limit" query parameter.
+func (hh HotRegionHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	limit := defaultHotRegionLimit
+	if v := req.FormValue(qLimit); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid limit: " + v))
+			return
+		}
+	}
+	js, err := hh.dumpHotRegions(limit)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set(headerContentType, contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+func (hh HotRegionHandler) dumpHotRegions(limit int) ([]byte, error) {
+	store := hh.server.driver.(*TiDBDriver).store
+	hotStore, ok := store.(hotregion.Store)
+	if !ok {
+		return nil, errors.Errorf("the hot regions are not recorded by the store %s", hh.server.cfg.Store)
+	}
+	session, err := tidb.CreateSession(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer session.Close()
+	schemas := sessionctx.GetDomain(session.(context.Context)).InfoSchema().AllSchemas()
+
+	recorder := hotStore.HotRegions()
+	hot := HotRegions{Regions: []HotRegion{}, Keys: []HotKey{}}
+	for _, region := range recorder.HotRegions(limit) {
+		dbName, tableName, indexName := infoschema.KeyOwnerNames(schemas, region.Key)
+		hot.Regions = append(hot.Regions, HotRegion{
+			RegionID:   region.RegionID,
+			DBName:     dbName,
+			TableName:  tableName,
+			IndexName:  indexName,
+			ReadCount:  region.ReadCount,
+			WriteCount: region.WriteCount,
+		})
+	}
+	for _, key := range recorder.HotKeys(limit) {
+		dbName, tableName, indexName := infoschema.KeyOwnerNames(schemas, key.Key)
+		hot.Keys = append(hot.Keys, HotKey{
+			Key:        strings.ToUpper(hex.EncodeToString(key.Key)),
+			RegionID:   key.RegionID,
+			DBName:     dbName,
+			TableName:  tableName,
+			IndexName:  indexName,
+			ReadCount:  key.ReadCount,
+			WriteCount: key.WriteCount,
+		})
+	}
+	js, err := json.Marshal(hot)
+	return js, errors.Trace(err)
+}
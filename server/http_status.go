@@ -23,7 +23,10 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/pd/pd-client"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/store/tikv"
 	"github.com/pingcap/tidb/util/printer"
 	"github.com/prometheus/client_golang/prometheus"
@@ -71,6 +74,26 @@ func (s *Server) startHTTPServer(pdClient pd.Client) {
 	router.Handle("/tables/{db}/{table}/regions", s.newTableRegionsHandler(pdClient))
 	router.Handle("/regions/{regionID}", s.newRegionHandler(pdClient))
 
+	// HTTP path for dumping statistics.
+	router.Handle("/stats/dump/{db}/{table}", s.newStatsHandler())
+
+	// HTTP path for the hot regions and keys.
+	router.Handle("/hot_regions", s.newHotRegionHandler())
+
+	// HTTP path for reloading the TLS certificates.
+	router.HandleFunc("/tls/reload", s.handleReloadTLS).Methods("POST")
+
+	// HTTP path for the databases and the tables.
+	router.Handle("/schema", s.newSchemaHandler())
+	router.Handle("/schema/{db}", s.newSchemaHandler())
+	router.Handle("/schema/{db}/{table}", s.newSchemaHandler())
+
+	// HTTP path for reading and changing the settings.
+	router.Handle("/settings", s.newSettingsHandler())
+
+	// HTTP path for resigning the DDL job owner.
+	router.HandleFunc("/ddl/owner/resign", s.handleResignDDLOwner).Methods("POST")
+
 	addr := s.cfg.StatusAddr
 	if len(addr) == 0 {
 		addr = defaultStatusAddr
@@ -106,3 +129,46 @@ func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
 		w.Write(js)
 	}
 }
+
+func (s *Server) handleReloadTLS(w http.ResponseWriter, req *http.Request) {
+	if !s.checkStatusAdmin(w) {
+		return
+	}
+	if err := s.ReloadTLSConfig(); err != nil {
+		// The error may contain the paths of the certificate files, it's only written to the log.
+		log.Errorf("reload TLS config error %v", errors.ErrorStack(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("reload TLS config failed, see the log of the server for details"))
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// checkStatusAdmin writes http.StatusForbidden and returns false if the endpoints which change the server are
+// disabled, see Config.StatusAdmin.
+func (s *Server) checkStatusAdmin(w http.ResponseWriter) bool {
+	if s.cfg.StatusAdmin {
+		return true
+	}
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("the status admin API is disabled, start tidb-server with -status-admin to enable it"))
+	return false
+}
+
+func (s *Server) handleResignDDLOwner(w http.ResponseWriter, req *http.Request) {
+	if !s.checkStatusAdmin(w) {
+		return
+	}
+	session, err := tidb.CreateSession(s.driver.(*TiDBDriver).store)
+	if err == nil {
+		err = sessionctx.GetDomain(session.(context.Context)).DDL().ResignOwner()
+		session.Close()
+	}
+	if err != nil {
+		log.Errorf("resign DDL owner error %v", errors.ErrorStack(err))
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHTTPStatusSuite{})
+
+type testHTTPStatusSuite struct{}
+
+func (s *testHTTPStatusSuite) TestReloadTLS(c *C) {
+	server := &Server{cfg: &Config{SSLCert: "/path/to/cert.pem", SSLKey: "/path/to/key.pem"}}
+	w := httptest.NewRecorder()
+	server.handleReloadTLS(w, httptest.NewRequest("POST", "/tls/reload", nil))
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+
+	// The error isn't returned to the client, it's only in the log.
+	server.cfg.StatusAdmin = true
+	w = httptest.NewRecorder()
+	server.handleReloadTLS(w, httptest.NewRequest("POST", "/tls/reload", nil))
+	c.Assert(w.Code, Equals, http.StatusInternalServerError)
+	c.Assert(w.Body.String(), Not(Matches), ".*/path/to.*")
+	c.Assert(w.Body.String(), Not(Matches), ".*TLS is not enabled.*")
+}
@@ -62,13 +62,23 @@ var (
 			Name:      "critical_error",
 			Help:      "Counter of critical errors.",
 		})
+
+	refusedConnCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "refused_connections",
+			Help:      "Counter of the connections refused by the connection limits.",
+		}, []string{"type"})
 )
 
 func init() {
 	prometheus.MustRegister(queryHistogram)
 	prometheus.MustRegister(queryCounter)
 	prometheus.MustRegister(connGauge)
+	prometheus.MustRegister(executeErrorCounter)
 	prometheus.MustRegister(criticalErrorCounter)
+	prometheus.MustRegister(refusedConnCounter)
 }
 
 func executeErrorToLabel(err error) string {
@@ -36,6 +36,8 @@ package server
 
 import (
 	"bufio"
+	"bytes"
+	"compress/zlib"
 	"io"
 	"net"
 
@@ -54,6 +56,10 @@ type packetIO struct {
 	wb *bufio.Writer
 
 	sequence uint8
+
+	// cw is not nil if the compressed protocol is used, the packets are written and read in compressed packets.
+	cw                 *compressedWriter
+	compressedSequence uint8
 }
 
 func newPacketIO(conn net.Conn) *packetIO {
@@ -65,6 +71,26 @@ func newPacketIO(conn net.Conn) *packetIO {
 	return p
 }
 
+// setCompressed makes the packetIO use the compressed protocol, it's called after the handshake if the client has set
+// ClientCompress. The packets are compressed by zlib, the zstd compression is not supported.
+func (p *packetIO) setCompressed() {
+	p.rb = bufio.NewReaderSize(&compressedReader{p: p, rb: p.rb}, defaultReaderSize)
+	p.cw = &compressedWriter{p: p, wb: p.wb}
+	p.wb = bufio.NewWriterSize(p.cw, defaultWriterSize)
+}
+
+// resetSequence resets the sequences for a new command.
+func (p *packetIO) resetSequence() {
+	p.sequence = 0
+	p.compressedSequence = 0
+}
+
+// setConn makes the packetIO read and write data on conn, the sequence is kept.
+func (p *packetIO) setConn(conn net.Conn) {
+	p.rb = bufio.NewReaderSize(conn, defaultReaderSize)
+	p.wb = bufio.NewWriterSize(conn, defaultWriterSize)
+}
+
 func (p *packetIO) readOnePacket() ([]byte, error) {
 	var header [4]byte
 
@@ -153,5 +179,136 @@ func (p *packetIO) writePacket(data []byte) error {
 }
 
 func (p *packetIO) flush() error {
-	return p.wb.Flush()
+	if err := p.wb.Flush(); err != nil {
+		return errors.Trace(err)
+	}
+	if p.cw != nil {
+		return errors.Trace(p.cw.wb.Flush())
+	}
+	return nil
+}
+
+const (
+	// compressedHeaderLen is the length of the header of a compressed packet, it consists of the length of the
+	// compressed payload, the sequence and the length of the payload before compression.
+	// See https://dev.mysql.com/doc/internals/en/compressed-packet-header.html
+	compressedHeaderLen = 7
+	// minCompressLen is the minimal length of the payload to compress, the shorter payloads are sent uncompressed.
+	minCompressLen = 50
+)
+
+// compressedReader reads the payloads of the compressed packets from rb.
+type compressedReader struct {
+	p  *packetIO
+	rb *bufio.Reader
+	// data is the decompressed payload which is not read yet.
+	data []byte
+}
+
+func (r *compressedReader) Read(b []byte) (int, error) {
+	if len(r.data) == 0 {
+		if err := r.readCompressedPacket(); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	n := copy(b, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func (r *compressedReader) readCompressedPacket() error {
+	var header [compressedHeaderLen]byte
+	if _, err := io.ReadFull(r.rb, header[:]); err != nil {
+		return errors.Trace(err)
+	}
+	sequence := header[3]
+	if sequence != r.p.compressedSequence {
+		return errInvalidSequence.Gen("invalid compressed sequence %d != %d", sequence, r.p.compressedSequence)
+	}
+	r.p.compressedSequence++
+
+	length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	uncompressedLength := int(uint32(header[4]) | uint32(header[5])<<8 | uint32(header[6])<<16)
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.rb, data); err != nil {
+		return errors.Trace(err)
+	}
+	// The payload is not compressed if the length before compression is 0.
+	if uncompressedLength == 0 {
+		r.data = data
+		return nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	r.data = make([]byte, uncompressedLength)
+	if _, err = io.ReadFull(zr, r.data); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(zr.Close())
+}
+
+// compressedWriter writes the data in compressed packets to wb.
+type compressedWriter struct {
+	p   *packetIO
+	wb  *bufio.Writer
+	buf bytes.Buffer
+	zw  *zlib.Writer
+}
+
+func (w *compressedWriter) Write(data []byte) (int, error) {
+	n := len(data)
+	for len(data) > 0 {
+		payload := data
+		if len(payload) > mysql.MaxPayloadLen {
+			payload = payload[:mysql.MaxPayloadLen]
+		}
+		if err := w.writeCompressedPacket(payload); err != nil {
+			return 0, errors.Trace(err)
+		}
+		data = data[len(payload):]
+	}
+	return n, nil
+}
+
+func (w *compressedWriter) writeCompressedPacket(payload []byte) error {
+	uncompressedLength := len(payload)
+	if len(payload) >= minCompressLen {
+		w.buf.Reset()
+		if w.zw == nil {
+			w.zw = zlib.NewWriter(&w.buf)
+		} else {
+			w.zw.Reset(&w.buf)
+		}
+		if _, err := w.zw.Write(payload); err != nil {
+			return errors.Trace(err)
+		}
+		if err := w.zw.Close(); err != nil {
+			return errors.Trace(err)
+		}
+		// The payload is sent uncompressed if it can't be compressed.
+		if w.buf.Len() < len(payload) {
+			payload = w.buf.Bytes()
+		} else {
+			uncompressedLength = 0
+		}
+	} else {
+		uncompressedLength = 0
+	}
+
+	length := len(payload)
+	header := [compressedHeaderLen]byte{
+		byte(length), byte(length >> 8), byte(length >> 16),
+		w.p.compressedSequence,
+		byte(uncompressedLength), byte(uncompressedLength >> 8), byte(uncompressedLength >> 16),
+	}
+	w.p.compressedSequence++
+	if _, err := w.wb.Write(header[:]); err != nil {
+		return errors.Trace(mysql.ErrBadConn)
+	}
+	if _, err := w.wb.Write(payload); err != nil {
+		return errors.Trace(mysql.ErrBadConn)
+	}
+	return nil
 }
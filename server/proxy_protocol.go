@@ -0,0 +1,211 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// proxyHeaderTimeout is how long the server waits for the PROXY protocol header of a connection from the proxies.
+var proxyHeaderTimeout = 5 * time.Second
+
+const (
+	// proxyV1MaxLen is the max length of the PROXY protocol v1 header, including the CRLF.
+	proxyV1MaxLen = 107
+	// The fixed part of the v2 header is the signature, the version and the command, the family and the protocol,
+	// and the length of the addresses.
+	proxyV2HeaderLen = 16
+	proxyV2CmdLocal  = 0x0
+	proxyV2CmdProxy  = 0x1
+	proxyV2FamINET   = 0x1
+	proxyV2FamINET6  = 0x2
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyConn is a connection accepted from a proxy, its RemoteAddr is the address of the client sent by the proxy in
+// the PROXY protocol header.
+type proxyConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+// RemoteAddr implements net.Conn RemoteAddr interface.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// parseProxyNetworks parses the comma separated CIDRs of the proxies, "*" means all the addresses.
+func parseProxyNetworks(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, str := range strings.Split(s, ",") {
+		str = strings.TrimSpace(str)
+		switch {
+		case str == "":
+			continue
+		case str == "*":
+			for _, all := range []string{"0.0.0.0/0", "::/0"} {
+				_, network, _ := net.ParseCIDR(all)
+				networks = append(networks, network)
+			}
+			continue
+		case !strings.Contains(str, "/"):
+			// A single address.
+			if ip := net.ParseIP(str); ip != nil && ip.To4() != nil {
+				str += "/32"
+			} else {
+				str += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(str)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// fromProxy checks if the connection is from one of the proxies.
+func (s *Server) fromProxy(conn net.Conn) bool {
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range s.proxyNetworks {
+		if network.Contains(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptProxyConn reads the PROXY protocol header of the connection from a proxy, the header is required. The
+// connection is returned as it is if the header doesn't carry the address of a client, like the health checks of the
+// proxy.
+func acceptProxyConn(conn net.Conn) (net.Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	addr, err := readProxyHeader(conn)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if addr == nil {
+		return conn, nil
+	}
+	return &proxyConn{Conn: conn, remoteAddr: addr}, nil
+}
+
+// readProxyHeader reads the PROXY protocol v1 or v2 header, and returns the source address in it. The header is read
+// without buffering, so the following bytes are left in the connection.
+func readProxyHeader(r io.Reader) (net.Addr, error) {
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(r, first); err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch first[0] {
+	case 'P':
+		return readProxyHeaderV1(r)
+	case proxyV2Signature[0]:
+		return readProxyHeaderV2(r)
+	}
+	return nil, errors.New("invalid PROXY protocol header")
+}
+
+func readProxyHeaderV1(r io.Reader) (net.Addr, error) {
+	// The header is like "PROXY TCP4 192.168.0.1 192.168.0.11 56324 4000\r\n", and "P" is read already.
+	buf := []byte{'P'}
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(buf, []byte("\r\n")) {
+		if len(buf) >= proxyV1MaxLen {
+			return nil, errors.New("PROXY protocol v1 header is too long")
+		}
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, errors.Trace(err)
+		}
+		buf = append(buf, b[0])
+	}
+	fields := strings.Fields(string(buf))
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", buf)
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, errors.Errorf("unknown protocol %s in PROXY protocol v1 header", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", buf)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, errors.Errorf("invalid source address in PROXY protocol v1 header %q", buf)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func readProxyHeaderV2(r io.Reader) (net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLen)
+	header[0] = proxyV2Signature[0]
+	if _, err := io.ReadFull(r, header[1:]); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !bytes.Equal(header[:len(proxyV2Signature)], proxyV2Signature) || header[12]>>4 != 2 {
+		return nil, errors.New("invalid PROXY protocol v2 header")
+	}
+	// The addresses and the TLVs are read even if they are not used, so the MySQL packets follow.
+	addrs := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, addrs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch header[12] & 0xf {
+	case proxyV2CmdLocal:
+		return nil, nil
+	case proxyV2CmdProxy:
+	default:
+		return nil, errors.Errorf("unknown command %d in PROXY protocol v2 header", header[12]&0xf)
+	}
+	var ipLen int
+	switch header[13] >> 4 {
+	case proxyV2FamINET:
+		ipLen = net.IPv4len
+	case proxyV2FamINET6:
+		ipLen = net.IPv6len
+	default:
+		// The unspecified or unix addresses, the address of the connection is used.
+		return nil, nil
+	}
+	if len(addrs) < 2*ipLen+4 {
+		return nil, errors.New("PROXY protocol v2 header is too short for the addresses")
+	}
+	ip := make(net.IP, ipLen)
+	copy(ip, addrs[:ipLen])
+	port := binary.BigEndian.Uint16(addrs[2*ipLen:])
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+var _ = Suite(&testProxyProtocolSuite{})
+
+type testProxyProtocolSuite struct {
+}
+
+func (s *testProxyProtocolSuite) TestParseProxyNetworks(c *C) {
+	defer testleak.AfterTest(c)()
+	networks, err := parseProxyNetworks("")
+	c.Assert(err, IsNil)
+	c.Assert(networks, HasLen, 0)
+
+	networks, err = parseProxyNetworks("192.168.1.0/24, 10.0.0.1,::1")
+	c.Assert(err, IsNil)
+	c.Assert(networks, HasLen, 3)
+	c.Assert(networks[0].Contains(net.ParseIP("192.168.1.100")), IsTrue)
+	c.Assert(networks[0].Contains(net.ParseIP("192.168.2.1")), IsFalse)
+	c.Assert(networks[1].Contains(net.ParseIP("10.0.0.1")), IsTrue)
+	c.Assert(networks[1].Contains(net.ParseIP("10.0.0.2")), IsFalse)
+	c.Assert(networks[2].Contains(net.ParseIP("::1")), IsTrue)
+
+	networks, err = parseProxyNetworks("*")
+	c.Assert(err, IsNil)
+	c.Assert(networks, HasLen, 2)
+	c.Assert(networks[0].Contains(net.ParseIP("1.2.3.4")), IsTrue)
+	c.Assert(networks[1].Contains(net.ParseIP("fe80::1")), IsTrue)
+
+	_, err = parseProxyNetworks("192.168.1.0/33")
+	c.Assert(err, NotNil)
+	_, err = parseProxyNetworks("xxx")
+	c.Assert(err, NotNil)
+}
+
+func (s *testProxyProtocolSuite) TestReadProxyHeaderV1(c *C) {
+	defer testleak.AfterTest(c)()
+	r := bytes.NewBufferString("PROXY TCP4 192.168.1.10 192.168.1.1 56324 4000\r\nrest")
+	addr, err := readProxyHeader(r)
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "192.168.1.10:56324")
+	// The bytes after the header are left.
+	rest, _ := ioutil.ReadAll(r)
+	c.Assert(string(rest), Equals, "rest")
+
+	addr, err = readProxyHeader(bytes.NewBufferString("PROXY TCP6 fe80::1 fe80::2 56324 4000\r\n"))
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "[fe80::1]:56324")
+
+	addr, err = readProxyHeader(bytes.NewBufferString("PROXY UNKNOWN\r\n"))
+	c.Assert(err, IsNil)
+	c.Assert(addr, IsNil)
+
+	tbl := []string{
+		"XPROXY TCP4 192.168.1.10 192.168.1.1 56324 4000\r\n",
+		"PROXY UDP4 192.168.1.10 192.168.1.1 56324 4000\r\n",
+		"PROXY TCP4 192.168.1.10 192.168.1.1 56324\r\n",
+		"PROXY TCP4 xxx 192.168.1.1 56324 4000\r\n",
+		"PROXY TCP4 192.168.1.10 192.168.1.1 65536 4000\r\n",
+		"PROXY TCP4 192.168.1.10 192.168.1.1 56324 4000",
+		"PROXY " + string(bytes.Repeat([]byte{'x'}, proxyV1MaxLen)) + "\r\n",
+	}
+	for _, t := range tbl {
+		_, err = readProxyHeader(bytes.NewBufferString(t))
+		c.Assert(err, NotNil, Commentf("%q", t))
+	}
+}
+
+func (s *testProxyProtocolSuite) TestReadProxyHeaderV2(c *C) {
+	defer testleak.AfterTest(c)()
+	header := func(cmd, fam byte, addrs []byte) []byte {
+		buf := append([]byte{}, proxyV2Signature...)
+		buf = append(buf, 0x20|cmd, fam<<4|0x1, 0, 0)
+		binary.BigEndian.PutUint16(buf[14:], uint16(len(addrs)))
+		return append(buf, addrs...)
+	}
+
+	addrs := append(net.ParseIP("192.168.1.10").To4(), net.ParseIP("192.168.1.1").To4()...)
+	addrs = append(addrs, 0xdc, 0x04, 0x0f, 0xa0)
+	// The TLVs are skipped.
+	addrs = append(addrs, 0x1, 0x0, 0x1, 'h')
+	r := bytes.NewBuffer(append(header(proxyV2CmdProxy, proxyV2FamINET, addrs), "rest"...))
+	addr, err := readProxyHeader(r)
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "192.168.1.10:56324")
+	rest, _ := ioutil.ReadAll(r)
+	c.Assert(string(rest), Equals, "rest")
+
+	addrs = append(net.ParseIP("fe80::1"), net.ParseIP("fe80::2")...)
+	addrs = append(addrs, 0xdc, 0x04, 0x0f, 0xa0)
+	addr, err = readProxyHeader(bytes.NewBuffer(header(proxyV2CmdProxy, proxyV2FamINET6, addrs)))
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "[fe80::1]:56324")
+
+	// The health checks of the proxy.
+	addr, err = readProxyHeader(bytes.NewBuffer(header(proxyV2CmdLocal, 0, nil)))
+	c.Assert(err, IsNil)
+	c.Assert(addr, IsNil)
+
+	// The addresses are too short.
+	_, err = readProxyHeader(bytes.NewBuffer(header(proxyV2CmdProxy, proxyV2FamINET, addrs[:8])))
+	c.Assert(err, NotNil)
+	// Unknown command.
+	_, err = readProxyHeader(bytes.NewBuffer(header(0x2, proxyV2FamINET, addrs)))
+	c.Assert(err, NotNil)
+	// Wrong version.
+	buf := header(proxyV2CmdProxy, proxyV2FamINET6, addrs)
+	buf[12] = 0x11
+	_, err = readProxyHeader(bytes.NewBuffer(buf))
+	c.Assert(err, NotNil)
+	// Truncated header.
+	_, err = readProxyHeader(bytes.NewBuffer(header(proxyV2CmdProxy, proxyV2FamINET6, addrs)[:20]))
+	c.Assert(err, NotNil)
+}
@@ -18,9 +18,14 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 
+	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/store/tikv"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
 	"github.com/pingcap/tidb/tablecodec"
@@ -165,6 +170,177 @@ func (ts *TidbRegionHandlerTestSuite) TestGetRegionByIDWithError(c *C) {
 	defer resp.Body.Close()
 }
 
+func (ts *TidbRegionHandlerTestSuite) TestHotRegionsAPI(c *C) {
+	ts.startServer(c)
+	defer ts.stopServer(c)
+	resp, err := http.Get("http://127.0.0.1:10090/hot_regions?limit=5")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+
+	var data HotRegions
+	err = decoder.Decode(&data)
+	c.Assert(err, IsNil)
+	// The bootstrap writes the system tables.
+	c.Assert(len(data.Regions) > 0, IsTrue)
+	c.Assert(len(data.Keys) > 0 && len(data.Keys) <= 5, IsTrue)
+
+	resp, err = http.Get("http://127.0.0.1:10090/hot_regions")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	decoder = json.NewDecoder(resp.Body)
+	err = decoder.Decode(&data)
+	c.Assert(err, IsNil)
+	found := false
+	for _, key := range data.Keys {
+		if key.DBName == "mysql" && key.TableName == "user" {
+			found = true
+		}
+	}
+	c.Assert(found, IsTrue)
+
+	resp, err = http.Get("http://127.0.0.1:10090/hot_regions?limit=xxx")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	defer resp.Body.Close()
+}
+
+func (ts *TidbRegionHandlerTestSuite) TestSchemaAPI(c *C) {
+	ts.startServer(c)
+	defer ts.stopServer(c)
+	resp, err := http.Get("http://127.0.0.1:10090/schema")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	var schemas []SchemaTables
+	err = json.NewDecoder(resp.Body).Decode(&schemas)
+	c.Assert(err, IsNil)
+	var userTableID int64
+	for _, schema := range schemas {
+		if schema.Name != "mysql" {
+			continue
+		}
+		for _, tbl := range schema.Tables {
+			if tbl.Name == "user" {
+				userTableID = tbl.ID
+			}
+		}
+	}
+	c.Assert(userTableID > 0, IsTrue)
+
+	resp, err = http.Get("http://127.0.0.1:10090/schema/mysql")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	var tblInfos []*model.TableInfo
+	err = json.NewDecoder(resp.Body).Decode(&tblInfos)
+	c.Assert(err, IsNil)
+	c.Assert(len(tblInfos) > 0, IsTrue)
+
+	resp, err = http.Get("http://127.0.0.1:10090/schema/mysql/user")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	var tblInfo model.TableInfo
+	err = json.NewDecoder(resp.Body).Decode(&tblInfo)
+	c.Assert(err, IsNil)
+	c.Assert(tblInfo.ID, Equals, userTableID)
+	c.Assert(tblInfo.Name.L, Equals, "user")
+
+	resp, err = http.Get("http://127.0.0.1:10090/schema/xxx")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	defer resp.Body.Close()
+	resp, err = http.Get("http://127.0.0.1:10090/schema/mysql/xxx")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	defer resp.Body.Close()
+}
+
+func (ts *TidbRegionHandlerTestSuite) TestSettingsAPI(c *C) {
+	ts.startServer(c)
+	defer ts.stopServer(c)
+	defer log.SetLevel(log.GetLogLevel())
+
+	form := make(url.Values)
+	form.Set("log_level", "error")
+	form.Set("tikv_gc_life_time", "1h")
+	resp, err := http.PostForm("http://127.0.0.1:10090/settings", form)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+
+	resp, err = http.Get("http://127.0.0.1:10090/settings")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	var settings Settings
+	err = json.NewDecoder(resp.Body).Decode(&settings)
+	c.Assert(err, IsNil)
+	c.Assert(settings.LogLevel, Equals, "error")
+	c.Assert(settings.GC["tikv_gc_life_time"], Equals, "1h0m0s")
+	c.Assert(settings.Config.Store, Equals, "tikv")
+
+	// Nothing is changed if any of the settings is invalid.
+	form = make(url.Values)
+	form.Set("log_level", "xxx")
+	form.Set("tikv_gc_life_time", "2h")
+	resp, err = http.PostForm("http://127.0.0.1:10090/settings", form)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	defer resp.Body.Close()
+	form = make(url.Values)
+	form.Set("tikv_gc_run_interval", "20m")
+	form.Set("tikv_gc_life_time", "1m")
+	resp, err = http.PostForm("http://127.0.0.1:10090/settings", form)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	defer resp.Body.Close()
+	resp, err = http.Get("http://127.0.0.1:10090/settings")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	settings = Settings{}
+	err = json.NewDecoder(resp.Body).Decode(&settings)
+	c.Assert(err, IsNil)
+	c.Assert(settings.LogLevel, Equals, "error")
+	c.Assert(settings.GC["tikv_gc_life_time"], Equals, "1h0m0s")
+	c.Assert(settings.GC["tikv_gc_run_interval"], Not(Equals), "20m0s")
+}
+
+func (ts *TidbRegionHandlerTestSuite) TestResignDDLOwnerAPI(c *C) {
+	ts.startServer(c)
+	defer ts.stopServer(c)
+	resp, err := http.Post("http://127.0.0.1:10090/ddl/owner/resign", "", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	defer resp.Body.Close()
+	// The server isn't the owner anymore.
+	resp, err = http.Post("http://127.0.0.1:10090/ddl/owner/resign", "", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	defer resp.Body.Close()
+}
+
+func (ts *TidbRegionHandlerTestSuite) TestStatusAdminDisabled(c *C) {
+	server := &Server{cfg: &Config{SSLCert: "cert.pem", SSLKey: "key.pem"}}
+	req := httptest.NewRequest("POST", "/settings", strings.NewReader("log_level=error"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	server.newSettingsHandler().ServeHTTP(w, req)
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+	w = httptest.NewRecorder()
+	server.handleResignDDLOwner(w, httptest.NewRequest("POST", "/ddl/owner/resign", nil))
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+
+	// The key path is redacted from the settings, the config of the server is kept.
+	cfg := redactConfig(server.cfg)
+	c.Assert(cfg.SSLKey, Equals, redacted)
+	c.Assert(cfg.SSLCert, Equals, "cert.pem")
+	c.Assert(server.cfg.SSLKey, Equals, "key.pem")
+}
+
 func (ts *TidbRegionHandlerTestSuite) startServer(c *C) {
 	cluster := mocktikv.NewCluster()
 	store, err := tikv.NewMockTikvStoreWithCluster(cluster)
@@ -178,6 +354,7 @@ func (ts *TidbRegionHandlerTestSuite) startServer(c *C) {
 		LogLevel:     "debug",
 		StatusAddr:   ":10090",
 		ReportStatus: true,
+		StatusAdmin:  true,
 		Store:        "tikv",
 	}
 	server, err := NewServer(cfg, tidbdrv)
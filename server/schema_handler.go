@@ -0,0 +1,125 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx"
+)
+
+// SchemaHandler is the handler for the databases and the tables with their IDs.
+type SchemaHandler struct {
+	server *Server
+}
+
+// SchemaTables is a database with its tables.
+type SchemaTables struct {
+	ID     int64         `json:"id"`
+	Name   string        `json:"name"`
+	Tables []SchemaTable `json:"tables"`
+}
+
+// SchemaTable is the ID and the name of a table.
+type SchemaTable struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (s *Server) newSchemaHandler() SchemaHandler {
+	return SchemaHandler{server: s}
+}
+
+// ServeHTTP handles request of the schemas in json format.
+// "/schema" lists all the databases with the IDs and the names of their tables, "/schema/{db}" lists the table infos
+// of the database, "/schema/{db}/{table}" gets the table info of the table.
+func (sh SchemaHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	js, err := sh.dumpSchema(params[pDBName], params[pTableName])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set(headerContentType, contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+func (sh SchemaHandler) dumpSchema(dbName, tableName string) ([]byte, error) {
+	session, err := tidb.CreateSession(sh.server.driver.(*TiDBDriver).store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer session.Close()
+	is := sessionctx.GetDomain(session.(context.Context)).InfoSchema()
+
+	var v interface{}
+	switch {
+	case dbName == "":
+		v = listSchemaTables(is)
+	case tableName == "":
+		if _, ok := is.SchemaByName(model.NewCIStr(dbName)); !ok {
+			return nil, errors.Trace(infoschema.ErrDatabaseNotExists.GenByArgs(dbName))
+		}
+		tblInfos := []*model.TableInfo{}
+		for _, tbl := range is.SchemaTables(model.NewCIStr(dbName)) {
+			tblInfos = append(tblInfos, tbl.Meta())
+		}
+		v = tblInfos
+	default:
+		tbl, err := is.TableByName(model.NewCIStr(dbName), model.NewCIStr(tableName))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		v = tbl.Meta()
+	}
+	js, err := json.Marshal(v)
+	return js, errors.Trace(err)
+}
+
+// listSchemaTables lists the databases and their tables ordered by the names.
+func listSchemaTables(is infoschema.InfoSchema) []SchemaTables {
+	dbNames := is.AllSchemaNames()
+	sort.Strings(dbNames)
+	schemas := make([]SchemaTables, 0, len(dbNames))
+	for _, dbName := range dbNames {
+		dbInfo, ok := is.SchemaByName(model.NewCIStr(dbName))
+		if !ok {
+			continue
+		}
+		tables := is.SchemaTables(dbInfo.Name)
+		tblNames := make([]string, 0, len(tables))
+		tblIDs := make(map[string]int64, len(tables))
+		for _, tbl := range tables {
+			tblNames = append(tblNames, tbl.Meta().Name.O)
+			tblIDs[tbl.Meta().Name.O] = tbl.Meta().ID
+		}
+		sort.Strings(tblNames)
+		schema := SchemaTables{ID: dbInfo.ID, Name: dbInfo.Name.O, Tables: make([]SchemaTable, 0, len(tblNames))}
+		for _, tblName := range tblNames {
+			schema.Tables = append(schema.Tables, SchemaTable{ID: tblIDs[tblName], Name: tblName})
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
@@ -29,8 +29,12 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"math/rand"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -66,6 +70,15 @@ type Server struct {
 	rwlock            *sync.RWMutex
 	concurrentLimiter *TokenLimiter
 	clients           map[uint32]*clientConn
+	// inShutdown is set to 1 when the server starts the graceful shutdown, accessed atomically.
+	inShutdown int32
+	// userConns is the number of the connections of each user, it's protected by rwlock too.
+	userConns map[string]int
+	// tlsConfig stores the *tls.Config used by the new TLS connections, it's replaced when the certificates are
+	// reloaded. It's empty if TLS is not enabled.
+	tlsConfig atomic.Value
+	// proxyNetworks are the networks of the proxies which send the PROXY protocol header.
+	proxyNetworks []*net.IPNet
 
 	// When a critical error occurred, we don't want to exit the process, because there may be
 	// a supervisor automatically restart it, then new client connection will be created, but we can't server it.
@@ -113,6 +126,7 @@ func (s *Server) newConn(conn net.Conn) *clientConn {
 		connectionID: atomic.AddUint32(&baseConnID, 1),
 		collation:    mysql.DefaultCollationID,
 		alloc:        arena.NewAllocator(32 * 1024),
+		tlsConfig:    s.getTLSConfig(),
 	}
 	log.Infof("[%d] new connection %s", cc.connectionID, conn.RemoteAddr().String())
 	cc.salt = randomBuf(20)
@@ -123,6 +137,69 @@ func (s *Server) skipAuth() bool {
 	return s.cfg.SkipAuth
 }
 
+// getTLSConfig returns the TLS config for the new connections, it returns nil if TLS is not enabled.
+func (s *Server) getTLSConfig() *tls.Config {
+	cfg, _ := s.tlsConfig.Load().(*tls.Config)
+	return cfg
+}
+
+// ReloadTLSConfig reloads the certificates and the CA of the server from the files in the config, so the certificates
+// can be rotated without restarting the server. The connections which are already established are not affected. If
+// it fails, the server keeps using the certificates loaded before.
+func (s *Server) ReloadTLSConfig() error {
+	if s.getTLSConfig() == nil {
+		return errors.New("TLS is not enabled")
+	}
+	tlsConfig, err := loadTLSConfig(s.cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.tlsConfig.Store(tlsConfig)
+	log.Infof("Server reloaded the TLS certificates")
+	return nil
+}
+
+// loadTLSConfig builds the TLS config from the certificate files in the config, it returns nil if TLS is not enabled.
+func loadTLSConfig(cfg *Config) (*tls.Config, error) {
+	if cfg.SSLCert == "" && cfg.SSLKey == "" {
+		if cfg.SSLCA != "" || cfg.SSLVerifyClient {
+			return nil, errors.New("ssl_cert and ssl_key are required to enable TLS")
+		}
+		return nil, nil
+	}
+	if cfg.SSLCert == "" || cfg.SSLKey == "" {
+		return nil, errors.New("both ssl_cert and ssl_key should be set")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.SSLCert, cfg.SSLKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS10,
+	}
+	if cfg.SSLCA != "" {
+		pem, err := ioutil.ReadFile(cfg.SSLCA)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate is found in %s", cfg.SSLCA)
+		}
+		tlsConfig.ClientCAs = pool
+		// The certificates of the clients are verified if they are given.
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if cfg.SSLVerifyClient {
+		if tlsConfig.ClientCAs == nil {
+			return nil, errors.New("ssl_ca is required to verify the client certificates")
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
 const tokenLimit = 1000
 
 // NewServer creates a new Server.
@@ -133,10 +210,22 @@ func NewServer(cfg *Config, driver IDriver) (*Server, error) {
 		concurrentLimiter: NewTokenLimiter(tokenLimit),
 		rwlock:            &sync.RWMutex{},
 		clients:           make(map[uint32]*clientConn),
+		userConns:         make(map[string]int),
 		stopListenerCh:    make(chan struct{}, 1),
 	}
 
-	var err error
+	tlsConfig, err := loadTLSConfig(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tlsConfig != nil {
+		s.tlsConfig.Store(tlsConfig)
+		log.Infof("Server enabled TLS for the client connections")
+	}
+	if s.proxyNetworks, err = parseProxyNetworks(cfg.ProxyProtocolNetworks); err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	if cfg.Socket != "" {
 		cfg.SkipAuth = true
 		s.listener, err = net.Listen("unix", cfg.Socket)
@@ -207,6 +296,15 @@ func (s *Server) Close() {
 
 // onConn runs in its own goroutine, handles queries from this connection.
 func (s *Server) onConn(c net.Conn) {
+	if s.fromProxy(c) {
+		proxied, err := acceptProxyConn(c)
+		if err != nil {
+			log.Warnf("read PROXY protocol header from %s error %s", c.RemoteAddr(), errors.ErrorStack(err))
+			c.Close()
+			return
+		}
+		c = proxied
+	}
 	conn := s.newConn(c)
 	defer func() {
 		log.Infof("[%d] close connection", conn.connectionID)
@@ -216,17 +314,130 @@ func (s *Server) onConn(c net.Conn) {
 		// Some keep alive services will send request to TiDB and disconnect immediately.
 		// So we use info log level.
 		log.Infof("handshake error %s", errors.ErrorStack(err))
-		c.Close()
+		conn.Close()
 		return
 	}
 
+	conn.Run()
+}
+
+func (s *Server) inShutdownMode() bool {
+	return atomic.LoadInt32(&s.inShutdown) == 1
+}
+
+// GracefulShutdown stops accepting new connections, and waits for the connections to finish their transactions.
+// The idle connections which are not in a transaction are closed, the others are closed after their transactions
+// finish. The connections left after the timeout are killed, and their transactions are rolled back.
+func (s *Server) GracefulShutdown(timeout time.Duration) {
+	log.Infof("Server is shutting down, wait %v for the %d connections at most", timeout, s.ConnectionCount())
+	atomic.StoreInt32(&s.inShutdown, 1)
+	s.Close()
+
+	const checkInterval = 100 * time.Millisecond
+	deadline := time.Now().Add(timeout)
+	for {
+		s.closeIdleConns()
+		if s.ConnectionCount() == 0 {
+			log.Infof("Server closed all the connections")
+			return
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(checkInterval)
+	}
+
+	s.rwlock.RLock()
+	ids := make([]uint32, 0, len(s.clients))
+	for id := range s.clients {
+		ids = append(ids, id)
+	}
+	s.rwlock.RUnlock()
+	log.Warnf("Server kills the %d connections which are not finished in %v", len(ids), timeout)
+	for _, id := range ids {
+		s.Kill(uint64(id), false)
+	}
+	// Wait for the killed connections to roll back their transactions.
+	for i := 0; i < 10 && s.ConnectionCount() > 0; i++ {
+		time.Sleep(checkInterval)
+	}
+}
+
+// closeIdleConns closes the connections which are waiting for the next command out of a transaction.
+func (s *Server) closeIdleConns() {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+	for _, cc := range s.clients {
+		if atomic.LoadInt32(&cc.status) != connStatusReading || cc.inTransaction() {
+			continue
+		}
+		if atomic.CompareAndSwapInt32(&cc.status, connStatusReading, connStatusShutdown) {
+			cc.conn.Close()
+		}
+	}
+}
+
+// registerConn adds the authenticated connection to the clients of the server, it fails if the connection exceeds
+// max_connections or max_user_connections. Like MySQL, one more connection than max_connections is reserved for
+// the users with the SUPER privilege, so the administrators can always connect to see what happens.
+func (s *Server) registerConn(cc *clientConn) error {
+	maxConns, err := getConnectionLimit(cc.ctx, "max_connections")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	maxUserConns, err := getConnectionLimit(cc.ctx, "max_user_connections")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if maxConns > 0 && cc.ctx.RequestVerification(mysql.SuperPriv) {
+		maxConns++
+	}
+
 	s.rwlock.Lock()
-	s.clients[conn.connectionID] = conn
-	connections := len(s.clients)
-	s.rwlock.Unlock()
-	connGauge.Set(float64(connections))
+	defer s.rwlock.Unlock()
+	if s.inShutdownMode() {
+		return mysql.NewErr(mysql.ErrServerShutdown)
+	}
+	if maxConns > 0 && len(s.clients) >= maxConns {
+		refusedConnCounter.WithLabelValues("max_connections").Inc()
+		return mysql.NewErr(mysql.ErrConCount)
+	}
+	if maxUserConns > 0 && s.userConns[cc.user] >= maxUserConns {
+		refusedConnCounter.WithLabelValues("max_user_connections").Inc()
+		return mysql.NewErr(mysql.ErrTooManyUserConnections, cc.user)
+	}
+	s.clients[cc.connectionID] = cc
+	s.userConns[cc.user]++
+	connGauge.Set(float64(len(s.clients)))
+	return nil
+}
 
-	conn.Run()
+// unregisterConn removes the connection from the clients of the server.
+func (s *Server) unregisterConn(cc *clientConn) {
+	s.rwlock.Lock()
+	defer s.rwlock.Unlock()
+	if _, ok := s.clients[cc.connectionID]; !ok {
+		return
+	}
+	delete(s.clients, cc.connectionID)
+	s.userConns[cc.user]--
+	if s.userConns[cc.user] == 0 {
+		delete(s.userConns, cc.user)
+	}
+	connGauge.Set(float64(len(s.clients)))
+}
+
+// getConnectionLimit gets a connection limit from the global system variables, 0 means unlimited.
+func getConnectionLimit(ctx QueryCtx, name string) (int, error) {
+	value, err := ctx.GetGlobalSysVar(name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return limit, nil
 }
 
 // ShowProcessList implements the SessionManager interface.
@@ -240,19 +451,32 @@ func (s *Server) ShowProcessList() []util.ProcessInfo {
 	return rs
 }
 
+// GetProcessInfo implements the SessionManager interface.
+func (s *Server) GetProcessInfo(connectionID uint64) (util.ProcessInfo, bool) {
+	s.rwlock.RLock()
+	conn, ok := s.clients[uint32(connectionID)]
+	s.rwlock.RUnlock()
+	if !ok {
+		return util.ProcessInfo{}, false
+	}
+	return conn.ctx.ShowProcess(), true
+}
+
 // Kill implements the SessionManager interface.
 func (s *Server) Kill(connectionID uint64, query bool) {
-	s.rwlock.Lock()
-	defer s.rwlock.Unlock()
-
+	s.rwlock.RLock()
 	conn, ok := s.clients[uint32(connectionID)]
+	s.rwlock.RUnlock()
 	if !ok {
 		return
 	}
 
 	conn.ctx.Cancel()
 	if !query {
-		conn.killed = true
+		atomic.StoreInt32(&conn.killed, 1)
+		// Closing the connection makes the client which is waiting for a query go away too, the session is closed
+		// when its executing statement returns.
+		conn.conn.Close()
 	}
 }
 
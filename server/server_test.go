@@ -356,6 +356,26 @@ xxx row5_col1	- 	row5_col3`)
 		dbt.Assert(affectedRows, Equals, int64(799))
 		rows = dbt.mustQuery("select * from test")
 		dbt.Check(rows.Next(), IsTrue, Commentf("unexpected data"))
+		rows.Close()
+
+		// skip the lines loaded before
+		dbt.mustExec("delete from test")
+		_, err = dbt.db.Exec("load data local infile '/tmp/load_data_test.csv' into table test fields terminated by '\t- ' lines starting by 'xxx ' terminated by '\n' ignore 700 lines")
+		dbt.Assert(err, IsNil)
+		rows = dbt.mustQuery("select count(*), min(c) from test")
+		dbt.Check(rows.Next(), IsTrue, Commentf("unexpected data"))
+		var cnt, minID int
+		err = rows.Scan(&cnt, &minID)
+		dbt.Check(err, IsNil)
+		dbt.Check(cnt, Equals, 99)
+		dbt.Check(rows.Next(), IsFalse, Commentf("unexpected data"))
+		rows.Close()
+		rows = dbt.mustQuery("select a from test where c = ?", minID)
+		dbt.Check(rows.Next(), IsTrue, Commentf("unexpected data"))
+		err = rows.Scan(&a)
+		dbt.Check(err, IsNil)
+		dbt.Check(a, Equals, "row702_col1")
+		rows.Close()
 
 		// don't support lines terminated is ""
 		rs, err = dbt.db.Exec("load data local infile '/tmp/load_data_test.csv' into table test lines terminated by ''")
@@ -552,7 +572,9 @@ func runTestMultiStatements(c *C) {
 
 func runTestStmtCount(t *C) {
 	runTests(t, dsn, func(dbt *DBTest) {
-		originStmtCnt := getStmtCnt(string(getMetrics(t)))
+		originMetrics := string(getMetrics(t))
+		originStmtCnt := getStmtCnt(originMetrics)
+		originDurationCnt := getStmtDurationCnt(originMetrics)
 
 		dbt.mustExec("create table test (a int)")
 
@@ -572,7 +594,8 @@ func runTestStmtCount(t *C) {
 		dbt.mustExec("prepare stmt2 from 'select * from test'")
 		dbt.mustExec("execute stmt2")
 
-		currentStmtCnt := getStmtCnt(string(getMetrics(t)))
+		currentMetrics := string(getMetrics(t))
+		currentStmtCnt := getStmtCnt(currentMetrics)
 		t.Assert(currentStmtCnt[executor.CreateTable], Equals, originStmtCnt[executor.CreateTable]+1)
 		t.Assert(currentStmtCnt[executor.Insert], Equals, originStmtCnt[executor.Insert]+5)
 		deleteLabel := "DeleteTableFull"
@@ -581,6 +604,12 @@ func runTestStmtCount(t *C) {
 		t.Assert(currentStmtCnt[updateLabel], Equals, originStmtCnt[updateLabel]+2)
 		selectLabel := "SelectTableFull"
 		t.Assert(currentStmtCnt[selectLabel], Equals, originStmtCnt[selectLabel]+2)
+
+		// The durations of the statements are observed by the labels too.
+		currentDurationCnt := getStmtDurationCnt(currentMetrics)
+		t.Assert(currentDurationCnt[executor.Insert], Equals, originDurationCnt[executor.Insert]+5)
+		t.Assert(currentDurationCnt[updateLabel], Equals, originDurationCnt[updateLabel]+2)
+		t.Assert(currentDurationCnt[selectLabel], Equals, originDurationCnt[selectLabel]+2)
 	})
 }
 
@@ -604,6 +633,17 @@ func getStmtCnt(content string) (stmtCnt map[string]int) {
 	return stmtCnt
 }
 
+func getStmtDurationCnt(content string) (durationCnt map[string]int) {
+	durationCnt = make(map[string]int)
+	r, _ := regexp.Compile("tidb_executor_statement_duration_seconds_count{result=\"ok\",type=\"([A-Z|a-z|-]+)\"} (\\d+)")
+	matchResult := r.FindAllStringSubmatch(content, -1)
+	for _, v := range matchResult {
+		cnt, _ := strconv.Atoi(v[2])
+		durationCnt[v[1]] = cnt
+	}
+	return durationCnt
+}
+
 const retryTime = 100
 
 func waitUntilServerOnline(statusAddr string) {
@@ -0,0 +1,186 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/store/tikv"
+)
+
+const (
+	qLogLevel = "log_level"
+	// redacted replaces the secret values in the config.
+	redacted = "******"
+)
+
+// SettingsHandler is the handler for the settings of the server, a safe subset of them can be changed at runtime.
+type SettingsHandler struct {
+	server *Server
+}
+
+// Settings is the current settings of the server.
+type Settings struct {
+	// Config is the config the server starts with, the secret values are redacted.
+	Config   *Config `json:"config"`
+	LogLevel string  `json:"log_level"`
+	// GC is the GC settings in mysql.tidb, which are shared by all the servers of the cluster.
+	GC map[string]string `json:"gc"`
+}
+
+func (s *Server) newSettingsHandler() SettingsHandler {
+	return SettingsHandler{server: s}
+}
+
+// ServeHTTP handles request of the settings in json format.
+// The POST request changes the settings by the form values, "log_level" changes the log level of the server,
+// "tikv_gc_life_time" and "tikv_gc_run_interval" change the GC settings of the cluster. The POST request is
+// forbidden unless Config.StatusAdmin is set.
+func (sh SettingsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var err error
+	switch req.Method {
+	case "GET":
+	case "POST":
+		if !sh.server.checkStatusAdmin(w) {
+			return
+		}
+		err = sh.updateSettings(req)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var js []byte
+	if err == nil {
+		js, err = sh.dumpSettings()
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set(headerContentType, contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+func (sh SettingsHandler) updateSettings(req *http.Request) error {
+	level := req.FormValue(qLogLevel)
+	if level != "" && logLevelString(log.StringToLogLevel(level)) != level {
+		return errors.Errorf("invalid log level %s", level)
+	}
+
+	// The GC settings are saved in a transaction, so all of them are saved or none of them are.
+	gcSettings := make(map[string]string)
+	for _, key := range tikv.GCSettingKeys {
+		if v := req.FormValue(key); v != "" {
+			gcSettings[key] = v
+		}
+	}
+	if len(gcSettings) > 0 {
+		session, err := sh.createSession()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer session.Close()
+		if _, err = session.Execute("begin"); err != nil {
+			return errors.Trace(err)
+		}
+		for key, value := range gcSettings {
+			if err = tikv.SaveGCSetting(session, key, value); err != nil {
+				session.RollbackTxn()
+				return errors.Trace(err)
+			}
+		}
+		if err = session.CommitTxn(); err != nil {
+			return errors.Trace(err)
+		}
+		log.Infof("[settings] change GC settings %v", gcSettings)
+	}
+
+	if level != "" {
+		log.SetLevelByString(level)
+		log.Infof("[settings] change log level to %s", level)
+	}
+	return nil
+}
+
+func (sh SettingsHandler) dumpSettings() ([]byte, error) {
+	session, err := sh.createSession()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer session.Close()
+	sql := fmt.Sprintf("SELECT variable_name, variable_value FROM mysql.tidb WHERE variable_name IN ('%s')",
+		strings.Join(tikv.GCSettingKeys, "', '"))
+	rs, err := session.Execute(sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rows, err := tidb.GetRows(rs[0])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	settings := Settings{
+		Config:   redactConfig(sh.server.cfg),
+		LogLevel: logLevelString(log.GetLogLevel()),
+		GC:       make(map[string]string, len(rows)),
+	}
+	for _, row := range rows {
+		settings.GC[row[0].GetString()] = row[1].GetString()
+	}
+	js, err := json.Marshal(settings)
+	return js, errors.Trace(err)
+}
+
+// redactConfig returns a copy of the config whose secret values are redacted.
+func redactConfig(cfg *Config) *Config {
+	ret := *cfg
+	if ret.SSLKey != "" {
+		ret.SSLKey = redacted
+	}
+	return &ret
+}
+
+func (sh SettingsHandler) createSession() (tidb.Session, error) {
+	session, err := tidb.CreateSession(sh.server.driver.(*TiDBDriver).store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The session reads and writes mysql.tidb on behalf of the server, it isn't checked by the privileges.
+	privilege.BindPrivilegeChecker(session, nil)
+	return session, nil
+}
+
+func logLevelString(level log.LogLevel) string {
+	switch level {
+	case log.LOG_LEVEL_FATAL:
+		return "fatal"
+	case log.LOG_LEVEL_ERROR:
+		return "error"
+	case log.LOG_LEVEL_WARN:
+		return "warn"
+	case log.LOG_LEVEL_INFO:
+		return "info"
+	case log.LOG_LEVEL_DEBUG:
+		return "debug"
+	}
+	return ""
+}
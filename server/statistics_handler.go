@@ -0,0 +1,71 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/statistics"
+)
+
+// StatsHandler is the handler for dumping statistics.
+type StatsHandler struct {
+	server *Server
+}
+
+func (s *Server) newStatsHandler() StatsHandler {
+	return StatsHandler{server: s}
+}
+
+// ServeHTTP handles request of dumping a table's statistics in json format.
+// The result can be loaded into another cluster by the LOAD STATS statement.
+func (sh StatsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	js, err := sh.dumpStats(params[pDBName], params[pTableName])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set(headerContentType, contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+func (sh StatsHandler) dumpStats(dbName, tableName string) ([]byte, error) {
+	session, err := tidb.CreateSession(sh.server.driver.(*TiDBDriver).store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer session.Close()
+	is := sessionctx.GetDomain(session.(context.Context)).InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr(dbName), model.NewCIStr(tableName))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	statsTbl := statistics.GetStatisticsTableCache(tbl.Meta())
+	jsonTbl, err := statistics.DumpStatsToJSON(dbName, statsTbl)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	js, err := json.Marshal(jsonTbl)
+	return js, errors.Trace(err)
+}
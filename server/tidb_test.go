@@ -15,11 +15,31 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
 	"time"
 
+	tmysql "github.com/go-sql-driver/mysql"
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/arena"
+	goctx "golang.org/x/net/context"
 )
 
 type TidbTestSuite struct {
@@ -131,3 +151,462 @@ func (ts *TidbTestSuite) TestSocket(c *C) {
 	dsn = tcpDsn
 	server.Close()
 }
+
+func (ts *TidbTestSuite) TestCursor(c *C) {
+	qctx, err := ts.tidbdrv.OpenCtx(0, mysql.ClientProtocol41, mysql.DefaultCollationID, "test")
+	c.Assert(err, IsNil)
+	defer qctx.Close()
+	buf := new(bytes.Buffer)
+	cc := &clientConn{
+		pkt:        &packetIO{wb: bufio.NewWriter(buf)},
+		capability: mysql.ClientProtocol41,
+		alloc:      arena.NewAllocator(1024),
+		ctx:        qctx,
+	}
+	_, err = qctx.Execute("create table cursor_t (a int)")
+	c.Assert(err, IsNil)
+	_, err = qctx.Execute("insert cursor_t values (1), (2), (3)")
+	c.Assert(err, IsNil)
+	stmt, _, _, err := qctx.Prepare("select a from cursor_t order by a")
+	c.Assert(err, IsNil)
+
+	// readPackets returns the payloads written since the last call.
+	readPackets := func() [][]byte {
+		var packets [][]byte
+		data := buf.Bytes()
+		for len(data) > 0 {
+			length := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+			packets = append(packets, data[4:4+length])
+			data = data[4+length:]
+		}
+		buf.Reset()
+		return packets
+	}
+	eofStatus := func(packet []byte) uint16 {
+		c.Assert(packet[0], Equals, byte(mysql.EOFHeader))
+		return binary.LittleEndian.Uint16(packet[3:5])
+	}
+	fetch := func(n uint32) [][]byte {
+		data := make([]byte, 8)
+		binary.LittleEndian.PutUint32(data[0:4], uint32(stmt.ID()))
+		binary.LittleEndian.PutUint32(data[4:8], n)
+		c.Assert(cc.handleStmtFetch(data), IsNil)
+		return readPackets()
+	}
+	execute := func(flag byte) error {
+		data := make([]byte, 9)
+		binary.LittleEndian.PutUint32(data[0:4], uint32(stmt.ID()))
+		data[4] = flag
+		binary.LittleEndian.PutUint32(data[5:9], 1)
+		return cc.handleStmtExecute(data)
+	}
+
+	// The column definitions are written on execute, without the rows.
+	c.Assert(execute(mysql.CursorTypeReadOnly), IsNil)
+	packets := readPackets()
+	c.Assert(packets, HasLen, 3)
+	c.Assert(eofStatus(packets[2])&mysql.ServerStatusCursorExists, Greater, uint16(0))
+	c.Assert(stmt.GetResultSet(), NotNil)
+
+	// The rows are fetched with the statement context of the cursor, after another statement is executed.
+	sessVars := qctx.(*TiDBContext).session.GetSessionVars()
+	cursorSC := sessVars.StmtCtx
+	_, err = qctx.Execute("set @a = 1")
+	c.Assert(err, IsNil)
+	otherSC := sessVars.StmtCtx
+	c.Assert(otherSC, Not(Equals), cursorSC)
+
+	packets = fetch(2)
+	c.Assert(sessVars.StmtCtx, Equals, otherSC)
+	c.Assert(cursorSC.ReadDetails.Requests(), Greater, int64(0))
+	c.Assert(otherSC.ReadDetails.Requests(), Equals, int64(0))
+	c.Assert(packets, HasLen, 3)
+	c.Assert(eofStatus(packets[2])&mysql.ServerStatusCursorExists, Greater, uint16(0))
+	packets = fetch(2)
+	c.Assert(packets, HasLen, 2)
+	c.Assert(eofStatus(packets[1])&mysql.ServerStatusLastRowSend, Greater, uint16(0))
+	c.Assert(stmt.GetResultSet(), IsNil)
+
+	// The cursor is closed after the last row is sent.
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(stmt.ID()))
+	err = cc.handleStmtFetch(data)
+	c.Assert(err.(*mysql.SQLError).Code, Equals, uint16(mysql.ErrStmtHasNoOpenCursor))
+
+	// Executing the statement again opens a new cursor, reset closes it.
+	c.Assert(execute(mysql.CursorTypeReadOnly), IsNil)
+	readPackets()
+	c.Assert(fetch(1), HasLen, 2)
+	stmt.Reset()
+	c.Assert(stmt.GetResultSet(), IsNil)
+
+	// The other cursor types are not supported.
+	c.Assert(execute(mysql.CursorTypeForUpdate), NotNil)
+}
+
+// generateCert generates a certificate signed by parent and writes it to dir, it's self-signed if parent is nil.
+func generateCert(c *C, dir, name string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, name+"-cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	c.Assert(err, IsNil)
+	return cert, key
+}
+
+func (ts *TidbTestSuite) TestTLS(c *C) {
+	dir, err := ioutil.TempDir("", "tidb-tls")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	ca, caKey := generateCert(c, dir, "ca", 1, nil, nil)
+	generateCert(c, dir, "server", 2, ca, caKey)
+	clientCert, clientKey := generateCert(c, dir, "client", 3, ca, caKey)
+
+	cfg := &Config{
+		Addr:     ":4002",
+		LogLevel: "debug",
+		SSLCert:  filepath.Join(dir, "server-cert.pem"),
+		SSLKey:   filepath.Join(dir, "server-key.pem"),
+		SSLCA:    filepath.Join(dir, "ca-cert.pem"),
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	c.Assert(err, IsNil)
+	go server.Run()
+	defer server.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	// serial is the serial number of the certificate presented by the server in the last TLS handshake.
+	var serial int64
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	clientConfig := &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			cert, err1 := x509.ParseCertificate(rawCerts[0])
+			if err1 == nil {
+				serial = cert.SerialNumber.Int64()
+			}
+			return err1
+		},
+	}
+	c.Assert(tmysql.RegisterTLSConfig("tidb-test", clientConfig), IsNil)
+	defer tmysql.DeregisterTLSConfig("tidb-test")
+	clientConfigWithCert := &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{clientCert.Raw},
+			PrivateKey:  clientKey,
+		}},
+	}
+	c.Assert(tmysql.RegisterTLSConfig("tidb-test-cert", clientConfigWithCert), IsNil)
+	defer tmysql.DeregisterTLSConfig("tidb-test-cert")
+
+	query := func(tlsName string) error {
+		dsn := "root@tcp(localhost:4002)/test?strict=true"
+		if tlsName != "" {
+			dsn += "&tls=" + tlsName
+		}
+		db, err1 := sql.Open("mysql", dsn)
+		c.Assert(err1, IsNil)
+		defer db.Close()
+		var v int
+		return db.QueryRow("select 1").Scan(&v)
+	}
+
+	// The clients can connect with or without TLS, the certificate of the client is verified if it's given.
+	c.Assert(query("tidb-test"), IsNil)
+	c.Assert(serial, Equals, int64(2))
+	c.Assert(query("tidb-test-cert"), IsNil)
+	c.Assert(query(""), IsNil)
+
+	// The rotated certificate is used by the new connections after reloading.
+	generateCert(c, dir, "server", 4, ca, caKey)
+	c.Assert(server.ReloadTLSConfig(), IsNil)
+	c.Assert(query("tidb-test"), IsNil)
+	c.Assert(serial, Equals, int64(4))
+
+	// The certificate loaded before is kept if the reloading fails.
+	c.Assert(ioutil.WriteFile(cfg.SSLKey, []byte("invalid"), 0600), IsNil)
+	c.Assert(server.ReloadTLSConfig(), NotNil)
+	c.Assert(query("tidb-test"), IsNil)
+	c.Assert(serial, Equals, int64(4))
+
+	// The TLS clients must present a certificate if SSLVerifyClient is set.
+	generateCert(c, dir, "server", 5, ca, caKey)
+	cfg.SSLVerifyClient = true
+	c.Assert(server.ReloadTLSConfig(), IsNil)
+	c.Assert(query("tidb-test"), NotNil)
+	c.Assert(query("tidb-test-cert"), IsNil)
+
+	// Reloading fails if TLS is not enabled.
+	c.Assert(ts.server.ReloadTLSConfig(), NotNil)
+}
+
+func (ts *TidbTestSuite) TestMultiStatementsResults(c *C) {
+	capability := mysql.ClientProtocol41 | mysql.ClientMultiStatements | mysql.ClientMultiResults
+	qctx, err := ts.tidbdrv.OpenCtx(0, capability, mysql.DefaultCollationID, "test")
+	c.Assert(err, IsNil)
+	defer qctx.Close()
+	buf := new(bytes.Buffer)
+	cc := &clientConn{
+		pkt:        &packetIO{wb: bufio.NewWriter(buf)},
+		capability: capability,
+		alloc:      arena.NewAllocator(1024),
+		ctx:        qctx,
+	}
+	// readStatus returns the status of the OK and EOF packets written since the last call.
+	readStatus := func() []uint16 {
+		var status []uint16
+		data := buf.Bytes()
+		for len(data) > 0 {
+			length := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+			packet := data[4 : 4+length]
+			switch packet[0] {
+			case mysql.OKHeader:
+				// The affected rows and the last insert ID are 1 byte long here.
+				status = append(status, binary.LittleEndian.Uint16(packet[3:5]))
+			case mysql.EOFHeader:
+				status = append(status, binary.LittleEndian.Uint16(packet[3:5]))
+			}
+			data = data[4+length:]
+		}
+		buf.Reset()
+		return status
+	}
+	more := func(status uint16) bool {
+		return status&mysql.ServerMoreResultsExists > 0
+	}
+
+	err = cc.handleQuery("create table multi_t (a int); insert multi_t values (1); select a from multi_t; select 2")
+	c.Assert(err, IsNil)
+	status := readStatus()
+	// 2 OK packets, and 2 EOF packets for each result set.
+	c.Assert(status, HasLen, 6)
+	c.Assert(more(status[0]), IsTrue)
+	c.Assert(more(status[1]), IsTrue)
+	c.Assert(more(status[2]), IsFalse)
+	c.Assert(more(status[3]), IsTrue)
+	c.Assert(more(status[4]), IsFalse)
+	c.Assert(more(status[5]), IsFalse)
+
+	// The statements after the failed one are not executed, the results before it are written.
+	err = cc.handleQuery("insert multi_t values (2); insert multi_t values ('x', 'y'); insert multi_t values (3)")
+	c.Assert(err, NotNil)
+	status = readStatus()
+	c.Assert(status, HasLen, 1)
+	c.Assert(more(status[0]), IsTrue)
+	rs, err := qctx.Execute("select count(*) from multi_t")
+	c.Assert(err, IsNil)
+	row, err := rs[0].Next()
+	c.Assert(err, IsNil)
+	c.Assert(row[0].GetInt64(), Equals, int64(2))
+	rs[0].Close()
+}
+
+func (ts *TidbTestSuite) TestConnectionLimits(c *C) {
+	// Use another store, so the global variables don't affect the other tests.
+	store, err := tidb.NewStore("memory:///tmp/tidb-conn-limits")
+	c.Assert(err, IsNil)
+	defer store.Close()
+	_, err = tidb.BootstrapSession(store)
+	c.Assert(err, IsNil)
+	cfg := &Config{
+		Addr:     ":4003",
+		LogLevel: "debug",
+	}
+	server, err := NewServer(cfg, NewTiDBDriver(store))
+	c.Assert(err, IsNil)
+	go server.Run()
+	defer server.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	se, err := tidb.CreateSession(store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	_, err = se.Execute("create user 'other'@'%'")
+	c.Assert(err, IsNil)
+	_, err = se.Execute("set @@global.max_connections = 1, @@global.max_user_connections = 3")
+	c.Assert(err, IsNil)
+
+	// connect opens a connection and keeps it open until the test ends.
+	var conns []*sql.Conn
+	connect := func(user string) error {
+		db, err1 := sql.Open("mysql", user+"@tcp(localhost:4003)/test?strict=true")
+		c.Assert(err1, IsNil)
+		defer db.Close()
+		conn, err1 := db.Conn(goctx.Background())
+		if err1 != nil {
+			return err1
+		}
+		conns = append(conns, conn)
+		return nil
+	}
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	// One more connection is reserved for the administrators, all the users are administrators if the privilege
+	// check is disabled.
+	c.Assert(connect("root"), IsNil)
+	c.Assert(connect("root"), IsNil)
+	checkErrorCode(c, connect("root"), mysql.ErrConCount)
+
+	// The connections of each user are limited by max_user_connections.
+	_, err = se.Execute("set @@global.max_connections = 100, @@global.max_user_connections = 2")
+	c.Assert(err, IsNil)
+	checkErrorCode(c, connect("root"), mysql.ErrTooManyUserConnections)
+	c.Assert(connect("other"), IsNil)
+
+	// The closed connections are not counted.
+	c.Assert(conns[0].Close(), IsNil)
+	conns = conns[1:]
+	time.Sleep(time.Millisecond * 100)
+	c.Assert(connect("root"), IsNil)
+	c.Assert(server.ConnectionCount(), Equals, 3)
+}
+
+func (ts *TidbTestSuite) TestGracefulShutdown(c *C) {
+	newServer := func(addr string) *Server {
+		cfg := &Config{
+			Addr:     addr,
+			LogLevel: "debug",
+		}
+		server, err := NewServer(cfg, ts.tidbdrv)
+		c.Assert(err, IsNil)
+		go server.Run()
+		time.Sleep(time.Millisecond * 100)
+		return server
+	}
+	connect := func(addr string) *sql.Conn {
+		db, err := sql.Open("mysql", "root@tcp(localhost"+addr+")/test?strict=true")
+		c.Assert(err, IsNil)
+		defer db.Close()
+		conn, err := db.Conn(goctx.Background())
+		c.Assert(err, IsNil)
+		return conn
+	}
+	exec := func(conn *sql.Conn, sql string) error {
+		_, err := conn.ExecContext(goctx.Background(), sql)
+		return err
+	}
+
+	server := newServer(":4004")
+	idle := connect(":4004")
+	defer idle.Close()
+	txn := connect(":4004")
+	defer txn.Close()
+	c.Assert(exec(txn, "drop table if exists graceful_shutdown"), IsNil)
+	c.Assert(exec(txn, "create table graceful_shutdown (a int)"), IsNil)
+	c.Assert(exec(txn, "begin"), IsNil)
+	c.Assert(exec(txn, "insert graceful_shutdown values (1)"), IsNil)
+
+	done := make(chan struct{})
+	go func() {
+		server.GracefulShutdown(10 * time.Second)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 300)
+	// The idle connection is closed, and the new connections are refused.
+	c.Assert(exec(idle, "select 1"), NotNil)
+	db, err := sql.Open("mysql", "root@tcp(localhost:4004)/test?strict=true")
+	c.Assert(err, IsNil)
+	c.Assert(db.Ping(), NotNil)
+	db.Close()
+	c.Assert(server.ConnectionCount(), Equals, 1)
+	// The transaction in flight can finish, and then the connection is closed.
+	c.Assert(exec(txn, "insert graceful_shutdown values (2)"), IsNil)
+	c.Assert(exec(txn, "commit"), IsNil)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the graceful shutdown is not finished")
+	}
+	c.Assert(server.ConnectionCount(), Equals, 0)
+	c.Assert(exec(txn, "select 1"), NotNil)
+
+	// The transaction which is not finished in the drain timeout is rolled back.
+	server = newServer(":4005")
+	txn = connect(":4005")
+	defer txn.Close()
+	c.Assert(exec(txn, "begin"), IsNil)
+	c.Assert(exec(txn, "insert graceful_shutdown values (3)"), IsNil)
+	server.GracefulShutdown(200 * time.Millisecond)
+	c.Assert(server.ConnectionCount(), Equals, 0)
+	c.Assert(exec(txn, "commit"), NotNil)
+
+	se, err := tidb.CreateSession(ts.tidbdrv.store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	rs, err := se.Execute("select count(*) from test.graceful_shutdown")
+	c.Assert(err, IsNil)
+	row, err := rs[0].Next()
+	c.Assert(err, IsNil)
+	c.Assert(row.Data[0].GetInt64(), Equals, int64(2))
+	rs[0].Close()
+}
+
+func (ts *TidbTestSuite) TestProxyProtocol(c *C) {
+	defer func(timeout time.Duration) {
+		proxyHeaderTimeout = timeout
+	}(proxyHeaderTimeout)
+	proxyHeaderTimeout = 500 * time.Millisecond
+	cfg := &Config{
+		Addr:                  ":4006",
+		LogLevel:              "debug",
+		ProxyProtocolNetworks: "127.0.0.1, ::1",
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	c.Assert(err, IsNil)
+	go server.Run()
+	defer server.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	tmysql.RegisterDial("proxy", func(addr string) (net.Conn, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		_, err = conn.Write([]byte("PROXY TCP4 192.168.1.10 127.0.0.1 56324 4006\r\n"))
+		return conn, err
+	})
+	db, err := sql.Open("mysql", "root@proxy(127.0.0.1:4006)/test?strict=true")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	// The client address in the header is used.
+	var user string
+	err = db.QueryRow("select user()").Scan(&user)
+	c.Assert(err, IsNil)
+	c.Assert(user, Equals, "root@192.168.1.10")
+
+	// The connections from the proxies without the header are refused.
+	db, err = sql.Open("mysql", "root@tcp(127.0.0.1:4006)/test?strict=true")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	c.Assert(db.Ping(), NotNil)
+}
@@ -43,6 +43,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/arena"
 	"github.com/pingcap/tidb/util/hack"
 	"github.com/pingcap/tidb/util/types"
@@ -262,60 +263,82 @@ func dumpRowValuesBinary(alloc arena.Allocator, columns []*ColumnInfo, row []typ
 	}
 	data = append(data, nulls...)
 	for i, val := range row {
+		if val.IsNull() {
+			continue
+		}
+		data, err = dumpBinaryValue(data, alloc, columns[i].Type, val)
+		if err != nil {
+			return data, errors.Trace(err)
+		}
+	}
+	return
+}
+
+// dumpBinaryValue appends the binary value of a not null datum to data, the encoding is decided by the column type
+// because the clients decode the values by it. The values of the other types are dumped as length encoded strings.
+// See https://dev.mysql.com/doc/internals/en/binary-protocol-value.html
+func dumpBinaryValue(data []byte, alloc arena.Allocator, tp byte, val types.Datum) ([]byte, error) {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeYear, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		var v uint64
 		switch val.Kind() {
 		case types.KindInt64:
-			v := val.GetInt64()
-			switch columns[i].Type {
-			case mysql.TypeTiny:
-				data = append(data, byte(v))
-			case mysql.TypeShort, mysql.TypeYear:
-				data = append(data, dumpUint16(uint16(v))...)
-			case mysql.TypeInt24, mysql.TypeLong:
-				data = append(data, dumpUint32(uint32(v))...)
-			case mysql.TypeLonglong:
-				data = append(data, dumpUint64(uint64(v))...)
-			}
+			v = uint64(val.GetInt64())
 		case types.KindUint64:
-			v := val.GetUint64()
-			switch columns[i].Type {
-			case mysql.TypeTiny:
-				data = append(data, byte(v))
-			case mysql.TypeShort, mysql.TypeYear:
-				data = append(data, dumpUint16(uint16(v))...)
-			case mysql.TypeInt24, mysql.TypeLong:
-				data = append(data, dumpUint32(uint32(v))...)
-			case mysql.TypeLonglong:
-				data = append(data, dumpUint64(uint64(v))...)
+			v = val.GetUint64()
+		default:
+			i, err := val.ToInt64(new(variable.StatementContext))
+			if err != nil {
+				return data, errors.Trace(err)
 			}
-		case types.KindFloat32:
-			floatBits := math.Float32bits(val.GetFloat32())
-			data = append(data, dumpUint32(floatBits)...)
-		case types.KindFloat64:
-			floatBits := math.Float64bits(val.GetFloat64())
-			data = append(data, dumpUint64(floatBits)...)
-		case types.KindString, types.KindBytes:
-			data = append(data, dumpLengthEncodedString(val.GetBytes(), alloc)...)
-		case types.KindMysqlDecimal:
-			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlDecimal().String()), alloc)...)
-		case types.KindMysqlTime:
-			tmp, err := dumpBinaryDateTime(val.GetMysqlTime(), nil)
+			v = uint64(i)
+		}
+		switch tp {
+		case mysql.TypeTiny:
+			return append(data, byte(v)), nil
+		case mysql.TypeShort, mysql.TypeYear:
+			return append(data, dumpUint16(uint16(v))...), nil
+		case mysql.TypeInt24, mysql.TypeLong:
+			return append(data, dumpUint32(uint32(v))...), nil
+		default:
+			return append(data, dumpUint64(v)...), nil
+		}
+	case mysql.TypeFloat, mysql.TypeDouble:
+		var v float64
+		switch val.Kind() {
+		case types.KindFloat32, types.KindFloat64:
+			v = val.GetFloat64()
+		default:
+			f, err := val.ToFloat64(new(variable.StatementContext))
 			if err != nil {
 				return data, errors.Trace(err)
 			}
-			data = append(data, tmp...)
-		case types.KindMysqlDuration:
-			data = append(data, dumpBinaryTime(val.GetMysqlDuration().Duration)...)
-		case types.KindMysqlSet:
-			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlSet().String()), alloc)...)
-		case types.KindMysqlHex:
-			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlHex().ToString()), alloc)...)
-		case types.KindMysqlEnum:
-			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlEnum().String()), alloc)...)
-		case types.KindMysqlBit:
-			data = append(data, dumpLengthEncodedString(hack.Slice(val.GetMysqlBit().ToString()), alloc)...)
+			v = f
+		}
+		if tp == mysql.TypeFloat {
+			return append(data, dumpUint32(math.Float32bits(float32(v)))...), nil
+		}
+		return append(data, dumpUint64(math.Float64bits(v))...), nil
+	case mysql.TypeDate, mysql.TypeNewDate, mysql.TypeDatetime, mysql.TypeTimestamp:
+		if val.Kind() == types.KindMysqlTime {
+			t := val.GetMysqlTime()
+			t.Type = tp
+			tmp, err := dumpBinaryDateTime(t, nil)
+			if err != nil {
+				return data, errors.Trace(err)
+			}
+			return append(data, tmp...), nil
+		}
+	case mysql.TypeDuration:
+		if val.Kind() == types.KindMysqlDuration {
+			return append(data, dumpBinaryTime(val.GetMysqlDuration().Duration)...), nil
 		}
 	}
-	return
+	tmp, err := dumpTextValue(tp, val)
+	if err != nil {
+		return data, errors.Trace(err)
+	}
+	return append(data, dumpLengthEncodedString(tmp, alloc)...), nil
 }
 
 func dumpTextValue(mysqlType uint8, value types.Datum) ([]byte, error) {
@@ -14,8 +14,11 @@
 package server
 
 import (
+	"math"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/arena"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -107,3 +110,41 @@ func (s *testUtilSuite) TestDumpTextValue(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(string(bs), Equals, "1.23")
 }
+
+func (s *testUtilSuite) TestDumpRowValuesBinary(c *C) {
+	defer testleak.AfterTest(c)()
+	columns := []*ColumnInfo{
+		{Type: mysql.TypeShort},
+		{Type: mysql.TypeLonglong},
+		{Type: mysql.TypeFloat},
+		{Type: mysql.TypeDouble},
+		{Type: mysql.TypeVarchar},
+		{Type: mysql.TypeDate},
+		{Type: mysql.TypeVarchar},
+		{Type: mysql.TypeNewDecimal},
+	}
+	tm, err := types.ParseTime("2017-01-05 10:11:12", mysql.TypeDatetime, 0)
+	c.Assert(err, IsNil)
+	row := []types.Datum{
+		types.NewIntDatum(-2),
+		types.NewDatum(nil),
+		// The encoding follows the column type instead of the kind of the datum.
+		types.NewFloat64Datum(1.5),
+		types.NewIntDatum(3),
+		types.NewIntDatum(12),
+		types.NewDatum(tm),
+		types.NewDatum(tm),
+		types.NewDecimalDatum(types.NewDecFromStringForTest("1.23")),
+	}
+	data, err := dumpRowValuesBinary(arena.NewAllocator(1024), columns, row)
+	c.Assert(err, IsNil)
+	expected := []byte{mysql.OKHeader, 0x08, 0x00, 0xfe, 0xff}
+	expected = append(expected, dumpUint32(math.Float32bits(1.5))...)
+	expected = append(expected, dumpUint64(math.Float64bits(3))...)
+	expected = append(expected, 2, '1', '2')
+	expected = append(expected, 4, 0xe1, 0x07, 1, 5)
+	expected = append(expected, 19)
+	expected = append(expected, "2017-01-05 10:11:12"...)
+	expected = append(expected, 4, '1', '.', '2', '3')
+	c.Assert(data, DeepEquals, expected)
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/mysql"
@@ -46,6 +47,7 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/generallog"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-binlog"
 	goctx "golang.org/x/net/context"
@@ -61,6 +63,9 @@ type Session interface {
 	String() string                              // For debug
 	CommitTxn() error
 	RollbackTxn() error
+	// For execute the statements of a query one by one.
+	ParseSQL(sql, charset, collation string) ([]ast.StmtNode, error)
+	ExecuteStmt(stmtNode ast.StmtNode) (ast.RecordSet, error)
 	// For execute prepare statement in binary protocol.
 	PrepareStmt(sql string) (stmtID uint32, paramCount int, fields []*ast.ResultField, err error)
 	// Execute a prepared statement.
@@ -107,9 +112,12 @@ type session struct {
 	processInfo atomic.Value
 	txn         kv.Transaction // current transaction
 	txnCh       chan *txnWithErr
-	// For cancel the execution of current transaction.
-	goCtx      goctx.Context
-	cancelFunc goctx.CancelFunc
+	// cancelCtx is used to cancel the execution of current statement, it's renewed when a statement starts.
+	cancelCtx struct {
+		sync.Mutex
+		goCtx      goctx.Context
+		cancelFunc goctx.CancelFunc
+	}
 
 	values map[fmt.Stringer]interface{}
 	store  kv.Storage
@@ -120,21 +128,42 @@ type session struct {
 	// For performance_schema only.
 	stmtState *perfschema.StatementState
 	parser    *parser.Parser
+	// parseTime is the time spent in parsing the last query, it's logged with the slow queries of the query.
+	parseTime time.Duration
 
 	sessionVars    *variable.SessionVars
 	sessionManager util.SessionManager
 }
 
-// Cancel cancels the execution of current transaction.
+// Cancel cancels the execution of current statement, it's interrupted at the checkpoints of the executors and the
+// waits for the coprocessor responses.
 func (s *session) Cancel() {
-	// TODO: How to wait for the resource to release and make sure
-	// it's not leak?
-	s.cancelFunc()
+	atomic.StoreUint32(&s.sessionVars.Killed, 1)
+	s.cancelCtx.Lock()
+	if s.cancelCtx.cancelFunc != nil {
+		s.cancelCtx.cancelFunc()
+	}
+	s.cancelCtx.Unlock()
 }
 
-// Canceled implements context.Context interface.
+// Done implements context.Context interface.
 func (s *session) Done() <-chan struct{} {
-	return s.goCtx.Done()
+	s.cancelCtx.Lock()
+	defer s.cancelCtx.Unlock()
+	if s.cancelCtx.goCtx == nil {
+		return nil
+	}
+	return s.cancelCtx.goCtx.Done()
+}
+
+// prepareStmtCancel renews the cancellation of the statement, a KILL QUERY only interrupts the statement executing
+// when it's received.
+func (s *session) prepareStmtCancel() {
+	goCtx, cancelFunc := goctx.WithCancel(goctx.Background())
+	s.cancelCtx.Lock()
+	s.cancelCtx.goCtx, s.cancelCtx.cancelFunc = goCtx, cancelFunc
+	s.cancelCtx.Unlock()
+	atomic.StoreUint32(&s.sessionVars.Killed, 0)
 }
 
 func (s *session) cleanRetryInfo() {
@@ -165,6 +194,8 @@ func (s *session) SetClientCapability(capability uint32) {
 
 func (s *session) SetConnectionID(connectionID uint64) {
 	s.sessionVars.ConnectionID = connectionID
+	// The connection shows in the process list before it executes any statement.
+	s.SetProcessInfo("", nil)
 }
 
 func (s *session) SetSessionManager(sm util.SessionManager) {
@@ -221,7 +252,7 @@ func (s *session) doCommit() error {
 		s.txn = nil
 		s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
 	}()
-	if binloginfo.PumpClient != nil {
+	if binloginfo.Enabled() {
 		prewriteValue := binloginfo.GetPrewriteValue(s, false)
 		if prewriteValue != nil {
 			prewriteData, err := prewriteValue.Marshal()
@@ -265,9 +296,11 @@ func (s *session) doCommitWithRetry() error {
 	}
 	s.cleanRetryInfo()
 	if err != nil {
+		s.rollbackTemporaryTables(nil)
 		log.Warnf("[%d] finished txn:%v, %v", s.sessionVars.ConnectionID, s.txn, err)
 		return errors.Trace(err)
 	}
+	s.commitTemporaryTables()
 	return nil
 }
 
@@ -282,12 +315,18 @@ func (s *session) RollbackTxn() error {
 	}
 	s.cleanRetryInfo()
 	s.txn = nil
-	s.txnCh = nil
+	s.rollbackTemporaryTables(nil)
+	s.discardPendingTxn()
 	s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
 	return errors.Trace(err)
 }
 
+// GetClient implements context.Context GetClient interface, it returns nil if the requests can't be pushed down to the
+// store.
 func (s *session) GetClient() kv.Client {
+	if !s.store.SupportCapability(kv.CapCoprocessor) {
+		return nil
+	}
 	return s.store.GetClient()
 }
 
@@ -324,23 +363,29 @@ func (s *session) isRetryableError(err error) bool {
 	return kv.IsRetryableError(err) || terror.ErrorEqual(err, domain.ErrInfoSchemaChanged)
 }
 
-func (s *session) retry(maxCnt int) error {
+func (s *session) retry(maxCnt int) (err error) {
 	connID := s.sessionVars.ConnectionID
 	if s.sessionVars.TxnCtx.ForUpdate {
 		return errors.Errorf("[%d] can not retry select for update statement", connID)
 	}
+	if s.sessionVars.TxnCtx.BatchDML {
+		// The previous batches are committed already, executing the statement again may update them twice.
+		return errors.Errorf("[%d] can not retry batched DML statement", connID)
+	}
 	s.sessionVars.RetryInfo.Retrying = true
 	retryCnt := 0
 	defer func() {
 		s.sessionVars.RetryInfo.Retrying = false
 		sessionRetry.Observe(float64(retryCnt))
+		observeTxnRetry(txnRetryOptimistic, err)
 		s.txn = nil
 		s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
 	}()
 	nh := getHistory(s)
-	var err error
 	for {
 		s.prepareTxnCtx()
+		// The statements write the temporary tables again.
+		s.rollbackTemporaryTables(nil)
 		s.sessionVars.RetryInfo.ResetOffset()
 		for i, sr := range nh.history {
 			st := sr.st
@@ -380,6 +425,68 @@ func (s *session) retry(maxCnt int) error {
 	return err
 }
 
+// restartPessimisticTxn restarts the pessimistic transaction when the statement st waits for a key locked by a
+// transaction committed after the pessimistic transaction starts, so the pessimistic transaction doesn't fail on the
+// write conflict when it commits. The transaction is rolled back to release its locks, the previous statements are
+// executed again in a new transaction like retry, then st is executed again.
+func (s *session) restartPessimisticTxn(st ast.Statement, idCnt int, err error) (ast.RecordSet, error) {
+	connID := s.sessionVars.ConnectionID
+	if s.sessionVars.TxnCtx.ForUpdate || s.sessionVars.TxnCtx.BatchDML {
+		// The rows read by the previous statements are returned to the client already.
+		return nil, errors.Trace(err)
+	}
+	nh := getHistory(s)
+	retryInfo := s.sessionVars.RetryInfo
+	stmtCtx := s.sessionVars.StmtCtx
+	var rs ast.RecordSet
+	restarted := false
+	defer func() {
+		if restarted {
+			observeTxnRetry(txnRetryPessimistic, err)
+		}
+	}()
+	for retryCnt := 0; terror.ErrorEqual(err, kv.ErrWriteConflict); retryCnt++ {
+		if retryCnt >= commitRetryLimit {
+			log.Warnf("[%d] Restart pessimistic txn reached max count %d", connID, retryCnt)
+			return nil, errors.Trace(err)
+		}
+		restarted = true
+		log.Warnf("[%d] restart pessimistic txn: %v, err: %v", connID, s.txn, err)
+		if s.txn != nil && s.txn.Valid() {
+			if err = s.txn.Rollback(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		s.txn = nil
+		s.rollbackTemporaryTables(nil)
+		s.prepareTxnCtx()
+		s.sessionVars.TxnCtx.IsPessimistic = true
+		s.sessionVars.TxnCtx.Histroy = nh
+		if err = s.ActivePendingTxn(); err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		retryInfo.Retrying = true
+		retryInfo.ResetOffset()
+		for _, sr := range nh.history {
+			s.sessionVars.StmtCtx = sr.stmtCtx
+			s.sessionVars.StmtCtx.ResetForRetry()
+			if _, err = sr.st.Exec(s); err != nil {
+				break
+			}
+		}
+		retryInfo.Retrying = false
+		s.sessionVars.StmtCtx = stmtCtx
+		if err == nil {
+			// The IDs allocated by st are allocated again.
+			retryInfo.TruncateAutoIncrementIDs(idCnt)
+			stmtCtx.ResetForRetry()
+			rs, err = st.Exec(s)
+		}
+	}
+	return rs, errors.Trace(err)
+}
+
 func sqlForLog(sql string) string {
 	if len(sql) > sqlLogMaxLen {
 		return sql[:sqlLogMaxLen] + fmt.Sprintf("(len:%d)", len(sql))
@@ -487,7 +594,7 @@ func (s *session) GetGlobalSysVar(name string) (string, error) {
 	if err != nil {
 		if executor.ErrResultIsEmpty.Equal(err) {
 			sv, ok := variable.SysVars[name]
-			isUninitializedGlobalVariable := ok && sv.Scope|variable.ScopeGlobal > 0
+			isUninitializedGlobalVariable := ok && sv.Scope&variable.ScopeGlobal > 0
 			if isUninitializedGlobalVariable {
 				return sv.Value, nil
 			}
@@ -501,17 +608,20 @@ func (s *session) GetGlobalSysVar(name string) (string, error) {
 // SetGlobalSysVar implements GlobalVarAccessor.SetGlobalSysVar interface.
 func (s *session) SetGlobalSysVar(name string, value string) error {
 	sql := fmt.Sprintf(`REPLACE %s.%s VALUES ('%s', '%s');`,
-		mysql.SystemDB, mysql.GlobalVariablesTable, strings.ToLower(name), value)
+		mysql.SystemDB, mysql.GlobalVariablesTable, strings.ToLower(name), strings.Replace(value, "'", "''", -1))
 	_, _, err := s.ExecRestrictedSQL(s, sql)
 	return errors.Trace(err)
 }
 
 func (s *session) ParseSQL(sql, charset, collation string) ([]ast.StmtNode, error) {
+	startTS := time.Now()
 	s.parser.SetSQLMode(s.sessionVars.SQLMode)
-	return s.parser.Parse(sql, charset, collation)
+	stmts, err := s.parser.Parse(sql, charset, collation)
+	s.parseTime = time.Since(startTS)
+	return stmts, err
 }
 
-func (s *session) SetProcessInfo(sql string) {
+func (s *session) SetProcessInfo(sql string, p interface{}) {
 	pi := util.ProcessInfo{
 		ID:      s.sessionVars.ConnectionID,
 		DB:      s.sessionVars.CurrentDB,
@@ -519,6 +629,10 @@ func (s *session) SetProcessInfo(sql string) {
 		Time:    time.Now(),
 		State:   s.Status(),
 		Info:    sql,
+		Plan:    p,
+	}
+	if sql == "" {
+		pi.Command = "Sleep"
 	}
 	strs := strings.Split(s.sessionVars.User, "@")
 	if len(strs) == 2 {
@@ -528,47 +642,91 @@ func (s *session) SetProcessInfo(sql string) {
 	s.processInfo.Store(pi)
 }
 
-func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
+// logGeneralQuery writes the statement to the general log if general_log is on, the restricted SQL executed by the
+// server itself is not logged. The passwords in the statement are redacted.
+func (s *session) logGeneralQuery(sql string) {
+	if !generallog.Enabled() || s.sessionVars.InRestrictedSQL {
+		return
+	}
+	e := &generallog.Entry{
+		Time:   time.Now(),
+		ConnID: s.sessionVars.ConnectionID,
+		DB:     s.sessionVars.CurrentDB,
+		Query:  strings.TrimSpace(parser.RedactPassword(sql)),
+	}
+	if strs := strings.Split(s.sessionVars.User, "@"); len(strs) == 2 {
+		e.User, e.Host = strs[0], strs[1]
+	}
+	generallog.Log(e)
+}
+
+// ExecuteStmt executes a statement parsed by ParseSQL, the clients which support multiple statements execute the
+// statements of a query one by one, and get the result of a statement before the next one is executed.
+func (s *session) ExecuteStmt(stmtNode ast.StmtNode) (ast.RecordSet, error) {
+	r, err := s.executeStatement(stmtNode.Text(), stmtNode)
+	return r, errors.Trace(err)
+}
+
+// executeStatement executes a statement of the sql.
+func (s *session) executeStatement(sql string, stmtNode ast.StmtNode) (ast.RecordSet, error) {
+	connID := s.sessionVars.ConnectionID
+	if text := stmtNode.Text(); text != "" {
+		s.logGeneralQuery(text)
+	} else {
+		s.logGeneralQuery(sql)
+	}
 	s.prepareTxnCtx()
 	startTS := time.Now()
+	// Some execution is done in compile stage, so we reset it before compile.
+	resetStmtCtx(s, stmtNode)
+	st, err := Compile(s, stmtNode)
+	if err != nil {
+		log.Warnf("[%d] compile error:\n%v\n%s", connID, err, sql)
+		s.RollbackTxn()
+		return nil, errors.Trace(err)
+	}
+	sc := s.sessionVars.StmtCtx
+	sc.ParseTime, sc.CompileTime = s.parseTime, time.Since(startTS)
+	sessionExecuteCompileDuration.Observe(sc.CompileTime.Seconds())
+
+	ph := sessionctx.GetDomain(s).PerfSchema()
+	s.stmtState = ph.StartStatement(sql, connID, perfschema.CallerNameSessionExecute, stmtNode)
+	s.SetValue(context.QueryString, st.OriginText())
+
+	startTS = time.Now()
+	r, err := runStmt(s, st)
+	ph.EndStatement(s.stmtState)
+	if err != nil {
+		if !terror.ErrorEqual(err, kv.ErrKeyExists) {
+			log.Warnf("[%d] session error:\n%v\n%s", connID, errors.ErrorStack(err), s)
+		}
+		return nil, errors.Trace(err)
+	}
+	sessionExecuteRunDuration.Observe(time.Since(startTS).Seconds())
+	return r, nil
+}
+
+func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
+	s.prepareTxnCtx()
 
 	charset, collation := s.sessionVars.GetCharsetInfo()
 	connID := s.sessionVars.ConnectionID
 	rawStmts, err := s.ParseSQL(sql, charset, collation)
 	if err != nil {
+		// The statements are logged one by one when they're executed, the query is logged as a whole if it
+		// can't be parsed.
+		s.logGeneralQuery(sql)
 		log.Warnf("[%d] parse error:\n%v\n%s", connID, err, sql)
 		return nil, errors.Trace(err)
 	}
-	sessionExecuteParseDuration.Observe(time.Since(startTS).Seconds())
+	sessionExecuteParseDuration.Observe(s.parseTime.Seconds())
 
 	var rs []ast.RecordSet
-	ph := sessionctx.GetDomain(s).PerfSchema()
-	for i, rst := range rawStmts {
-		s.prepareTxnCtx()
-		startTS := time.Now()
-		// Some execution is done in compile stage, so we reset it before compile.
-		resetStmtCtx(s, rst)
-		st, err1 := Compile(s, rst)
-		if err1 != nil {
-			log.Warnf("[%d] compile error:\n%v\n%s", connID, err1, sql)
-			s.RollbackTxn()
-			return nil, errors.Trace(err1)
-		}
-		sessionExecuteCompileDuration.Observe(time.Since(startTS).Seconds())
-
-		s.stmtState = ph.StartStatement(sql, connID, perfschema.CallerNameSessionExecute, rawStmts[i])
-		s.SetValue(context.QueryString, st.OriginText())
-
-		startTS = time.Now()
-		r, err := runStmt(s, st)
-		ph.EndStatement(s.stmtState)
+	for _, rst := range rawStmts {
+		r, err := s.executeStatement(sql, rst)
 		if err != nil {
-			if !terror.ErrorEqual(err, kv.ErrKeyExists) {
-				log.Warnf("[%d] session error:\n%v\n%s", connID, errors.ErrorStack(err), s)
-			}
 			return nil, errors.Trace(err)
 		}
-		sessionExecuteRunDuration.Observe(time.Since(startTS).Seconds())
 		if r != nil {
 			rs = append(rs, r)
 		}
@@ -650,6 +808,7 @@ func (s *session) ExecutePreparedStmt(stmtID uint32, args ...interface{}) (ast.R
 	}
 	s.prepareTxnCtx()
 	st := executor.CompileExecutePreparedStmt(s, stmtID, args...)
+	s.logGeneralQuery(fmt.Sprintf("%s [arguments: %v]", st.OriginText(), args))
 
 	r, err := runStmt(s, st)
 	return r, errors.Trace(err)
@@ -690,6 +849,7 @@ func (s *session) GetTxn(forceNew bool) (kv.Transaction, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	s.setTxnOptions()
 	ac := s.sessionVars.IsAutocommit()
 	if !ac {
 		s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, true)
@@ -714,9 +874,18 @@ func (s *session) NewTxn() error {
 		return errors.Trace(err)
 	}
 	s.txn = txn
+	s.setTxnOptions()
 	return nil
 }
 
+// setTxnOptions sets the options of the new transaction by the session variables.
+func (s *session) setTxnOptions() {
+	vars := s.sessionVars
+	s.txn.SetOption(kv.Pessimistic, vars.TxnCtx.IsPessimistic)
+	s.txn.SetOption(kv.LockWaitTimeout, time.Duration(vars.LockWaitTimeout)*time.Second)
+	s.txn.SetOption(kv.ReplicaRead, varsutil.GetReplicaRead(vars, s.store))
+}
+
 func (s *session) SetValue(key fmt.Stringer, value interface{}) {
 	s.values[key] = value
 }
@@ -730,8 +899,43 @@ func (s *session) ClearValue(key fmt.Stringer) {
 	delete(s.values, key)
 }
 
+// temporaryTablesCheckpoint returns the checkpoint of the temporary tables, the changes made after it can be undone
+// by rollbackTemporaryTables.
+func (s *session) temporaryTablesCheckpoint() map[int64]int {
+	if tmpTables, ok := s.sessionVars.TemporaryTables.(*infoschema.TemporaryTables); ok {
+		return tmpTables.Checkpoint()
+	}
+	return nil
+}
+
+// rollbackTemporaryTables undoes the changes of the temporary tables made after the checkpoint, all the changes of
+// the transaction are undone if cp is nil.
+func (s *session) rollbackTemporaryTables(cp map[int64]int) {
+	if tmpTables, ok := s.sessionVars.TemporaryTables.(*infoschema.TemporaryTables); ok {
+		if err := tmpTables.RollbackTo(cp); err != nil {
+			log.Errorf("[%d] rollback temporary tables error: %v", s.sessionVars.ConnectionID, err)
+		}
+	}
+}
+
+// commitTemporaryTables keeps the changes of the temporary tables made by the transaction.
+func (s *session) commitTemporaryTables() {
+	if tmpTables, ok := s.sessionVars.TemporaryTables.(*infoschema.TemporaryTables); ok {
+		if err := tmpTables.Commit(); err != nil {
+			log.Errorf("[%d] commit temporary tables error: %v", s.sessionVars.ConnectionID, err)
+		}
+	}
+}
+
 // Close function does some clean work when session end.
 func (s *session) Close() error {
+	if tmpTables, ok := s.sessionVars.TemporaryTables.(*infoschema.TemporaryTables); ok {
+		if err := tmpTables.Close(); err != nil {
+			log.Errorf("[%d] drop temporary tables error: %v", s.sessionVars.ConnectionID, err)
+		}
+		s.sessionVars.TemporaryTables = nil
+	}
+	s.sessionVars.HoldSnapshot(nil)
 	return s.RollbackTxn()
 }
 
@@ -769,6 +973,7 @@ func (s *session) Auth(user string, auth []byte, salt []byte) bool {
 	// Check IP.
 	if checker.ConnectionVerification(name, host, auth, salt) {
 		s.sessionVars.User = name + "@" + host
+		s.SetProcessInfo("", nil)
 		return true
 	}
 
@@ -776,6 +981,7 @@ func (s *session) Auth(user string, auth []byte, salt []byte) bool {
 	for _, addr := range getHostByIP(host) {
 		if checker.ConnectionVerification(name, addr, auth, salt) {
 			s.sessionVars.User = name + "@" + addr
+			s.SetProcessInfo("", nil)
 			return true
 		}
 	}
@@ -843,7 +1049,32 @@ func BootstrapSession(store kv.Storage) (*domain.Domain, error) {
 		return nil, errors.Trace(err)
 	}
 	err = dom.LoadTableStatsLoop(se)
-	return dom, errors.Trace(err)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	varsSe, err := createSession(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = dom.LoadServerVarsLoop(varsSe)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The TTL jobs run in their own session, the deletions may take a long time.
+	ttlSe, err := createSession(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	dom.StartTTLJobLoop(ttlSe)
+	groupSe, err := createSession(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = dom.LoadResourceGroupLoop(groupSe)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return dom, nil
 }
 
 // runInBootstrapSession create a special session for boostrap to run.
@@ -892,7 +1123,7 @@ func createSession(store kv.Storage) (*session, error) {
 
 const (
 	notBootstrapped         = 0
-	currentBootstrapVersion = 5
+	currentBootstrapVersion = 10
 )
 
 func getStoreBootstrapVersion(store kv.Storage) int64 {
@@ -941,12 +1172,33 @@ const loadCommonGlobalVarsSQL = "select * from mysql.global_variables where vari
 	variable.AutocommitVar + quoteCommaQuote +
 	variable.SQLModeVar + quoteCommaQuote +
 	variable.MaxAllowedPacket + quoteCommaQuote +
+	variable.GroupConcatMaxLen + quoteCommaQuote +
+	variable.CTEMaxRecursionDepth + quoteCommaQuote +
+	variable.InnodbLockWaitTimeout + quoteCommaQuote +
+	variable.TimeZone + quoteCommaQuote +
 	/* TiDB specific global variables: */
 	variable.TiDBSkipUTF8Check + quoteCommaQuote +
+	variable.TiDBEnableStreaming + quoteCommaQuote +
+	variable.TiDBReplicaRead + quoteCommaQuote +
+	variable.TiDBSlowLogThreshold + quoteCommaQuote +
+	variable.TiDBSlowLogNormalize + quoteCommaQuote +
 	variable.TiDBSkipDDLWait + quoteCommaQuote +
 	variable.TiDBIndexLookupSize + quoteCommaQuote +
 	variable.TiDBIndexLookupConcurrency + quoteCommaQuote +
 	variable.TiDBIndexSerialScanConcurrency + quoteCommaQuote +
+	variable.TiDBHashJoinConcurrency + quoteCommaQuote +
+	variable.TiDBHashAggConcurrency + quoteCommaQuote +
+	variable.TiDBProjectionConcurrency + quoteCommaQuote +
+	variable.TiDBIndexJoinBatchSize + quoteCommaQuote +
+	variable.TiDBMaxSortRowsInMemory + quoteCommaQuote +
+	variable.TiDBMaxHashRowsInMemory + quoteCommaQuote +
+	variable.TiDBEnableParallelApply + quoteCommaQuote +
+	variable.TiDBApplyConcurrency + quoteCommaQuote +
+	variable.TiDBEnableIndexAdvisor + quoteCommaQuote +
+	variable.TiDBMemQuotaQuery + quoteCommaQuote +
+	variable.TiDBMemOOMAction + quoteCommaQuote +
+	variable.TiDBTmpTableMaxSize + quoteCommaQuote +
+	variable.TiDBTxnMode + quoteCommaQuote +
 	variable.TiDBDistSQLScanConcurrency + "')"
 
 // LoadCommonGlobalVariableIfNeeded loads and applies commonly used global variables for the session.
@@ -996,12 +1248,12 @@ func (s *session) prepareTxnCtx() {
 		txn, err := s.store.Begin()
 		txnCh <- &txnWithErr{txn: txn, err: err}
 	}()
-	goCtx, cancelFunc := goctx.WithCancel(goctx.Background())
-	s.txnCh, s.goCtx, s.cancelFunc = txnCh, goCtx, cancelFunc
+	s.txnCh = txnCh
 	is := sessionctx.GetDomain(s).InfoSchema()
 	s.sessionVars.TxnCtx = &variable.TransactionContext{
 		InfoSchema:    is,
 		SchemaVersion: is.SchemaMetaVersion(),
+		IsPessimistic: s.sessionVars.TxnMode == variable.TxnModePessimistic,
 	}
 	if !s.sessionVars.IsAutocommit() {
 		s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, true)
@@ -1026,9 +1278,26 @@ func (s *session) ActivePendingTxn() error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	s.setTxnOptions()
 	return nil
 }
 
+// discardPendingTxn drops the transaction begun by prepareTxnCtx. The transaction is rolled back when it's ready,
+// or the store keeps it as a running one and holds the GC for it.
+func (s *session) discardPendingTxn() {
+	txnCh := s.txnCh
+	if txnCh == nil {
+		return
+	}
+	s.txnCh = nil
+	go func() {
+		txnWithErr := <-txnCh
+		if txnWithErr.err == nil {
+			txnWithErr.txn.Rollback()
+		}
+	}()
+}
+
 // InitTxnWithStartTS create a transaction with startTS.
 func (s *session) InitTxnWithStartTS(startTS uint64) error {
 	if s.txn != nil && s.txn.Valid() {
@@ -1038,7 +1307,7 @@ func (s *session) InitTxnWithStartTS(startTS uint64) error {
 		return errors.New("transaction channel is not set")
 	}
 	// no need to get txn from txnCh since txn should init with startTs
-	s.txnCh = nil
+	s.discardPendingTxn()
 	var err error
 	s.txn, err = s.store.BeginWithStartTS(startTS)
 	if err != nil {
@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/generallog"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -1493,6 +1494,7 @@ func (s *testSessionSuite) TestGroupBy(c *C) {
 	dbName := "test_groupby"
 	dropDBSQL := fmt.Sprintf("drop database %s;", dbName)
 	se := newSession(c, s.store, dbName)
+	mustExecSQL(c, se, "set @@tidb_hash_agg_concurrency=1")
 	mustExecSQL(c, se, "drop table if exists t")
 	mustExecSQL(c, se, "create table t (c1 int, c2 int)")
 	mustExecSQL(c, se, "insert into t values (1,1), (2,2), (1,2), (1,3)")
@@ -1555,6 +1557,7 @@ func (s *testSessionSuite) TestHaving(c *C) {
 	dbName := "test_having"
 	dropDBSQL := fmt.Sprintf("drop database %s;", dbName)
 	se := newSession(c, s.store, dbName)
+	mustExecSQL(c, se, "set @@tidb_hash_agg_concurrency=1")
 	mustExecSQL(c, se, "drop table if exists t")
 	mustExecSQL(c, se, "create table t (c1 int, c2 int, c3 int)")
 	mustExecSQL(c, se, "insert into t values (1,2,3), (2, 3, 1), (3, 1, 2)")
@@ -2711,3 +2714,60 @@ func (s *testSessionSuite) TestRetryResetStmtCtx(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(se.AffectedRows(), Equals, uint64(1))
 }
+
+type generalLogSink struct {
+	sync.Mutex
+	queries []string
+}
+
+func (s *generalLogSink) Write(e *generallog.Entry) error {
+	s.Lock()
+	defer s.Unlock()
+	// The other sessions running in the background are not checked.
+	if e.ConnID == 1234 {
+		s.queries = append(s.queries, fmt.Sprintf("%s@%s %s: %s", e.User, e.Host, e.DB, e.Query))
+	}
+	return nil
+}
+
+func (s *generalLogSink) Close() error {
+	return nil
+}
+
+func (s *testSessionSuite) TestGeneralLog(c *C) {
+	defer testleak.AfterTest(c)()
+	dbName := "test_general_log"
+	se := newSession(c, s.store, dbName)
+	se.SetConnectionID(1234)
+	c.Assert(se.Auth(`root@127.0.0.1`, []byte(""), []byte("")), IsTrue)
+	sink := &generalLogSink{}
+	generallog.SetSink(sink)
+	defer generallog.SetSink(nil)
+
+	mustExecSQL(c, se, "select 1")
+	mustExecSQL(c, se, "set global general_log = 1")
+	mustExecSQL(c, se, "create table t (a int); insert t values (1)")
+	_, err := se.Execute("select * from not_exist")
+	c.Assert(err, NotNil)
+	_, err = se.Execute("select * frm t")
+	c.Assert(err, NotNil)
+	id, _, _, err := se.PrepareStmt("select * from t where a = ?")
+	c.Assert(err, IsNil)
+	_, err = se.ExecutePreparedStmt(id, 1)
+	c.Assert(err, IsNil)
+	mustExecSQL(c, se, "create user 'general_user' identified by 'general_pwd'")
+	mustExecSQL(c, se, "set global general_log = 0")
+	mustExecSQL(c, se, "select 2")
+
+	c.Assert(sink.queries, DeepEquals, []string{
+		"root@127.0.0.1 test_general_log: create table t (a int);",
+		"root@127.0.0.1 test_general_log: insert t values (1)",
+		"root@127.0.0.1 test_general_log: select * from not_exist",
+		"root@127.0.0.1 test_general_log: select * frm t",
+		"root@127.0.0.1 test_general_log: select * from t where a = ? [arguments: [1]]",
+		"root@127.0.0.1 test_general_log: create user 'general_user' identified by ?",
+		"root@127.0.0.1 test_general_log: set global general_log = 0",
+	})
+	mustExecSQL(c, se, "drop user 'general_user'")
+	mustExecSQL(c, se, "drop database "+dbName)
+}
@@ -14,6 +14,7 @@
 package binloginfo
 
 import (
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -34,6 +35,48 @@ func init() {
 // shared by all sessions.
 var PumpClient binlog.PumpClient
 
+// Sink is where the binlog is written instead of Pump, like a file read by a drainer.
+// A transaction writes a prewrite binlog with its row images before it commits, then a commit or
+// rollback binlog with the same start ts. The binlog is written again if it fails, so the consumers
+// may get a binlog more than once and should deduplicate them by the type and the start ts.
+type Sink interface {
+	// WriteBinlog writes the binlog, it's called by the transactions concurrently. The binlog must
+	// be durable when it returns nil.
+	WriteBinlog(bin *binlog.Binlog) error
+	// Close is called when the sink is replaced.
+	Close() error
+}
+
+var sink struct {
+	sync.RWMutex
+	s Sink
+}
+
+// SetSink makes the binlog written to s instead of Pump, the previous sink is closed. Pump is used
+// again if s is nil.
+func SetSink(s Sink) {
+	sink.Lock()
+	old := sink.s
+	sink.s = s
+	sink.Unlock()
+	if old != nil {
+		if err := old.Close(); err != nil {
+			log.Warnf("close binlog sink err %v", err)
+		}
+	}
+}
+
+func getSink() Sink {
+	sink.RLock()
+	defer sink.RUnlock()
+	return sink.s
+}
+
+// Enabled returns whether the transactions write binlog.
+func Enabled() bool {
+	return PumpClient != nil || getSink() != nil
+}
+
 // GetPrewriteValue gets binlog prewrite value in the context.
 func GetPrewriteValue(ctx context.Context, createIfNotExists bool) *binlog.PrewriteValue {
 	vars := ctx.GetSessionVars()
@@ -46,24 +89,40 @@ func GetPrewriteValue(ctx context.Context, createIfNotExists bool) *binlog.Prewr
 	return v
 }
 
-// WriteBinlog writes a binlog to Pump.
+// writeBinlogRetryInterval is the time to wait before writing a binlog again.
+var writeBinlogRetryInterval = time.Second
+
+// WriteBinlog writes a binlog to the sink if it's set, otherwise to Pump.
 func WriteBinlog(bin *binlog.Binlog, clusterID uint64) error {
-	commitData, _ := bin.Marshal()
-	req := &binlog.WriteBinlogReq{ClusterID: clusterID, Payload: commitData}
+	var write func() error
+	if s := getSink(); s != nil {
+		write = func() error {
+			return s.WriteBinlog(bin)
+		}
+	} else {
+		commitData, err := bin.Marshal()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		req := &binlog.WriteBinlogReq{ClusterID: clusterID, Payload: commitData}
+		write = func() error {
+			resp, err := PumpClient.WriteBinlog(goctx.Background(), req)
+			if err == nil && resp.Errmsg != "" {
+				err = errors.New(resp.Errmsg)
+			}
+			return err
+		}
+	}
 
 	// Retry many times because we may raise CRITICAL error here.
 	var err error
 	for i := 0; i < 20; i++ {
-		var resp *binlog.WriteBinlogResp
-		resp, err = PumpClient.WriteBinlog(goctx.Background(), req)
-		if err == nil && resp.Errmsg != "" {
-			err = errors.New(resp.Errmsg)
-		}
+		err = write()
 		if err == nil {
 			return nil
 		}
 		log.Errorf("write binlog error %v", err)
-		time.Sleep(time.Second)
+		time.Sleep(writeBinlogRetryInterval)
 	}
 	return terror.ErrCritical.GenByArgs(err)
 }
@@ -14,12 +14,17 @@
 package binloginfo_test
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
@@ -225,6 +230,93 @@ func (s *testBinlogSuite) TestBinlog(c *C) {
 	c.Assert(newBinlogLen, Equals, originBinlogLen)
 }
 
+func (s *testBinlogSuite) TestFileSink(c *C) {
+	dir, err := ioutil.TempDir("", "binlog")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "binlog")
+	sink, err := binloginfo.NewFileSink(path)
+	c.Assert(err, IsNil)
+	binloginfo.SetSink(sink)
+	defer binloginfo.SetSink(nil)
+
+	// The sink takes precedence over Pump.
+	s.pump.mu.Lock()
+	pumpLen := len(s.pump.mu.payloads)
+	s.pump.mu.Unlock()
+	tk := s.tk
+	tk.MustExec("drop table if exists file_binlog")
+	tk.MustExec("create table file_binlog (id int primary key, name varchar(10))")
+	tk.MustExec("insert file_binlog values (1, 'abc')")
+	tk.MustExec("begin")
+	tk.MustExec("update file_binlog set name = 'xyz' where id = 1")
+	tk.MustExec("rollback")
+	tk.MustExec("delete from file_binlog where id = 1")
+
+	// The prewrite binlog of a transaction is followed by the commit binlog with the same start ts,
+	// the commit binlog is written asynchronously.
+	var bins []*binlog.Binlog
+	for i := 0; i < 100; i++ {
+		data, err := ioutil.ReadFile(path)
+		c.Assert(err, IsNil)
+		bins = bins[:0]
+		d := binloginfo.NewDecoder(bytes.NewReader(data))
+		for {
+			bin, err := d.Decode()
+			if errors.Cause(err) == io.EOF {
+				break
+			}
+			c.Assert(err, IsNil)
+			bins = append(bins, bin)
+		}
+		if len(bins) == 6 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(bins, HasLen, 6)
+	startTS := make(map[int64]bool)
+	var prewrites []*binlog.PrewriteValue
+	for _, bin := range bins {
+		switch bin.Tp {
+		case binlog.BinlogType_Prewrite:
+			c.Assert(bin.CommitTs, Equals, int64(0))
+			c.Assert(bin.PrewriteKey, NotNil)
+			startTS[bin.StartTs] = true
+			if bin.DdlJobId == 0 {
+				preVal := new(binlog.PrewriteValue)
+				c.Assert(preVal.Unmarshal(bin.PrewriteValue), IsNil)
+				prewrites = append(prewrites, preVal)
+			}
+		case binlog.BinlogType_Commit:
+			c.Assert(startTS[bin.StartTs], IsTrue)
+			c.Assert(bin.CommitTs, Greater, bin.StartTs)
+		default:
+			c.Fatalf("unexpected binlog type %v", bin.Tp)
+		}
+	}
+	c.Assert(startTS, HasLen, 3)
+	c.Assert(prewrites, HasLen, 2)
+	gotRows := mutationRowsToRows(c, prewrites[0].Mutations[0].InsertedRows, 0, 2)
+	c.Assert(gotRows, DeepEquals, [][]types.Datum{{types.NewIntDatum(1), types.NewStringDatum("abc")}})
+	gotRows = mutationRowsToRows(c, prewrites[1].Mutations[0].DeletedRows, 1, 3)
+	c.Assert(gotRows, DeepEquals, [][]types.Datum{{types.NewIntDatum(1), types.NewStringDatum("abc")}})
+	s.pump.mu.Lock()
+	c.Assert(s.pump.mu.payloads, HasLen, pumpLen)
+	s.pump.mu.Unlock()
+
+	// An incomplete record at the end is reported.
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	d := binloginfo.NewDecoder(bytes.NewReader(data[:len(data)-1]))
+	for i := 0; i < 5; i++ {
+		_, err = d.Decode()
+		c.Assert(err, IsNil)
+	}
+	_, err = d.Decode()
+	c.Assert(errors.Cause(err), Equals, io.ErrUnexpectedEOF)
+}
+
 func getLatestBinlogPrewriteValue(c *C, pump *mockBinlogPump) *binlog.PrewriteValue {
 	var bin *binlog.Binlog
 	pump.mu.Lock()
@@ -0,0 +1,139 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binloginfo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tipb/go-binlog"
+)
+
+// The binlog file is a sequence of records, a record is:
+//
+//	magic (uint32) | payload length (uint64) | payload | crc32 of payload (uint32)
+//
+// All the integers are big endian and the payload is the marshaled binlog.Binlog.
+const (
+	recordMagic      uint32 = 0x7469626c // "tibl"
+	recordHeaderSize        = 4 + 8
+	recordFooterSize        = 4
+	maxRecordSize           = 1 << 32
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// fileSink appends the binlog to a file, the file is synced after each binlog so the binlog is durable
+// before the transaction commits.
+type fileSink struct {
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileSink opens the file at path to append binlog, it's read by NewDecoder.
+func NewFileSink(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.Trace(err)
+	}
+	return &fileSink{f: f, size: info.Size()}, nil
+}
+
+// WriteBinlog implements Sink WriteBinlog interface.
+func (s *fileSink) WriteBinlog(bin *binlog.Binlog) error {
+	payload, err := bin.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	buf := make([]byte, recordHeaderSize+len(payload)+recordFooterSize)
+	binary.BigEndian.PutUint32(buf, recordMagic)
+	binary.BigEndian.PutUint64(buf[4:], uint64(len(payload)))
+	copy(buf[recordHeaderSize:], payload)
+	binary.BigEndian.PutUint32(buf[recordHeaderSize+len(payload):], crc32.Checksum(payload, crcTable))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.f.Write(buf); err == nil {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		// Cut the partial record, so the record written by the retry follows a complete one.
+		if terr := s.f.Truncate(s.size); terr != nil {
+			return errors.Annotatef(err, "truncate binlog file err %v", terr)
+		}
+		return errors.Trace(err)
+	}
+	s.size += int64(len(buf))
+	return nil
+}
+
+// Close implements Sink Close interface.
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Trace(s.f.Close())
+}
+
+// Decoder reads the binlog written by the file sink.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder creates a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next binlog. The cause of the error is io.EOF if there is no more binlog, and
+// io.ErrUnexpectedEOF if the last record is incomplete.
+func (d *Decoder) Decode() (*binlog.Binlog, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if magic := binary.BigEndian.Uint32(header); magic != recordMagic {
+		return nil, errors.Errorf("invalid binlog record magic %x", magic)
+	}
+	size := binary.BigEndian.Uint64(header[4:])
+	if size > maxRecordSize {
+		return nil, errors.Errorf("invalid binlog record size %d", size)
+	}
+	buf := make([]byte, size+recordFooterSize)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, errors.Trace(err)
+	}
+	payload := buf[:size]
+	if crc := binary.BigEndian.Uint32(buf[size:]); crc != crc32.Checksum(payload, crcTable) {
+		return nil, errors.Errorf("binlog record checksum mismatch")
+	}
+	bin := new(binlog.Binlog)
+	if err := bin.Unmarshal(payload); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return bin, nil
+}
@@ -20,6 +20,8 @@ import (
 
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/execdetails"
+	"github.com/pingcap/tidb/util/memory"
 )
 
 const (
@@ -57,6 +59,18 @@ func (r *RetryInfo) AddAutoIncrementID(id int64) {
 	r.autoIncrementIDs = append(r.autoIncrementIDs, id)
 }
 
+// AutoIncrementIDCount returns the number of the AutoIncrementIDs.
+func (r *RetryInfo) AutoIncrementIDCount() int {
+	return len(r.autoIncrementIDs)
+}
+
+// TruncateAutoIncrementIDs keeps the first n AutoIncrementIDs, it's used when a statement is executed again.
+func (r *RetryInfo) TruncateAutoIncrementIDs(n int) {
+	if n < len(r.autoIncrementIDs) {
+		r.autoIncrementIDs = r.autoIncrementIDs[:n]
+	}
+}
+
 // ResetOffset resets the current retry offset.
 func (r *RetryInfo) ResetOffset() {
 	r.currRetryOff = 0
@@ -76,11 +90,39 @@ func (r *RetryInfo) GetCurrAutoIncrementID() (int64, error) {
 // TransactionContext is used to store variables that has transaction scope.
 type TransactionContext struct {
 	ForUpdate     bool
+	BatchDML      bool
 	DirtyDB       interface{}
 	Binlog        interface{}
 	InfoSchema    interface{}
 	Histroy       interface{}
 	SchemaVersion int64
+	// IsPessimistic indicates the keys written by the transaction are locked when they are written.
+	IsPessimistic bool
+	// Savepoints are the savepoints of the transaction in the order they are set.
+	Savepoints []SavepointRecord
+}
+
+// SavepointRecord is a savepoint of the transaction, the transaction can be rolled back to it.
+type SavepointRecord struct {
+	// Name is the name of the savepoint in lower case.
+	Name string
+	// StagingHandle is the kv.StagingHandle of the staging of the transaction buffer begun when the savepoint is set,
+	// it's an int because the kv package depends on this package in tests.
+	StagingHandle int
+	// DirtyDB is a copy of the DirtyDB of the transaction when the savepoint is set.
+	DirtyDB interface{}
+	// TemporaryTables is the checkpoint of the temporary tables when the savepoint is set.
+	TemporaryTables map[int64]int
+}
+
+// FindSavepoint returns the index of the savepoint in Savepoints, or -1 if it doesn't exist.
+func (tc *TransactionContext) FindSavepoint(name string) int {
+	for i, sp := range tc.Savepoints {
+		if sp.Name == name {
+			return i
+		}
+	}
+	return -1
 }
 
 // SessionVars is to handle user-defined or global variables in current session.
@@ -132,6 +174,13 @@ type SessionVars struct {
 	// version, we load an old version schema for query.
 	SnapshotInfoschema interface{}
 
+	// snapshot is the kv.Snapshot at SnapshotTS, it's held until tidb_snapshot changes, so the GC keeps the versions
+	// read by the session. The kv package can't be imported here, it imports this package in its tests.
+	snapshot snapshotReleaser
+
+	// TemporaryTables are the temporary tables created in the session, they are dropped when the session is closed.
+	TemporaryTables interface{}
+
 	// GlobalAccessor is used to set and get global variables.
 	GlobalVarsAccessor GlobalVarAccessor
 
@@ -159,9 +208,19 @@ type SessionVars struct {
 	// SkipConstraintCheck is true when importing data.
 	SkipConstraintCheck bool
 
+	// DMLBatchSize is the number of rows a batched DELETE or UPDATE, or LOAD DATA commits in a transaction,
+	// 0 means no batching for DELETE and UPDATE, and the default batch size for LOAD DATA.
+	DMLBatchSize int
+
+	// BatchDMLDryRun makes a batched DELETE or UPDATE report its batches without modifying any row.
+	BatchDMLDryRun bool
+
 	// SkipUTF8 check on input value.
 	SkipUTF8Check bool
 
+	// EnableStreaming indicates whether the table scans use the streaming coprocessor requests.
+	EnableStreaming bool
+
 	// SkipDDLWait can be set to true to skip 2 lease wait after create/drop/truncate table, create/drop database.
 	// Then if there are multiple TiDB servers, the new table may not be available for other TiDB servers.
 	SkipDDLWait bool
@@ -180,6 +239,65 @@ type SessionVars struct {
 
 	// The number of concurrent index serial scan worker.
 	IndexSerialScanConcurrency int
+
+	// The number of concurrent hash join probe worker.
+	HashJoinConcurrency int
+
+	// The number of concurrent hash aggregation partial and final worker.
+	HashAggConcurrency int
+
+	// The number of concurrent projection worker.
+	ProjectionConcurrency int
+
+	// The number of outer rows for a look up task in index nested loop join executor.
+	IndexJoinBatchSize int
+
+	// The maximum number of rows the sort executor keeps in memory before spilling them to disk.
+	MaxSortRowsInMemory int
+
+	// The maximum number of hash join build side rows or hash aggregation groups kept in memory before spilling to disk.
+	MaxHashRowsInMemory int
+
+	// EnableParallelApply makes the apply executor fetch the outer rows in a background goroutine and execute the inner
+	// plan for them in concurrent workers.
+	EnableParallelApply bool
+
+	// The number of concurrent inner workers of the apply executor in parallel mode.
+	ApplyConcurrency int
+
+	// EnableIndexAdvisor makes the statements recorded in the workload of the index advisor.
+	EnableIndexAdvisor bool
+
+	// MemQuotaQuery is the memory quota of a statement in bytes, a value <= 0 means no quota.
+	MemQuotaQuery int64
+
+	// MemOOMAction is the action taken when a statement exceeds MemQuotaQuery.
+	MemOOMAction memory.ActionOnExceed
+
+	// TmpTableMaxSize is the maximum memory size of a temporary table in bytes, it's spilled to disk beyond that.
+	TmpTableMaxSize int64
+
+	// CTEMaxRecursionDepth is the maximum number of iterations of a recursive common table expression.
+	CTEMaxRecursionDepth int64
+
+	// TxnMode is the mode of the transactions, it's "pessimistic", "optimistic" or "".
+	TxnMode string
+
+	// LockWaitTimeout is the seconds that a pessimistic transaction waits for a locked key.
+	LockWaitTimeout int64
+
+	// ReplicaRead is the type of the replicas which serve the reads, see TiDBReplicaRead.
+	ReplicaRead string
+
+	// SlowLogThreshold is the threshold of the slow queries, the statements which take longer are logged.
+	SlowLogThreshold time.Duration
+
+	// SlowLogNormalize makes the slow queries logged with the normalized query text.
+	SlowLogNormalize bool
+
+	// Killed is set to 1 when the session is killed by KILL QUERY or KILL CONNECTION, the executing statement is
+	// interrupted at the checkpoints of the execution. It's reset when a statement starts, and accessed atomically.
+	Killed uint32
 }
 
 // NewSessionVars creates a session vars object.
@@ -200,6 +318,24 @@ func NewSessionVars() *SessionVars {
 		IndexLookupConcurrency:     DefIndexLookupConcurrency,
 		IndexSerialScanConcurrency: DefIndexSerialScanConcurrency,
 		DistSQLScanConcurrency:     DefDistSQLScanConcurrency,
+		HashJoinConcurrency:        defaultHashJoinConcurrency,
+		HashAggConcurrency:         DefHashAggConcurrency,
+		ProjectionConcurrency:      DefProjectionConcurrency,
+		IndexJoinBatchSize:         DefIndexJoinBatchSize,
+		MaxSortRowsInMemory:        DefMaxSortRowsInMemory,
+		MaxHashRowsInMemory:        DefMaxHashRowsInMemory,
+		EnableParallelApply:        DefEnableParallelApply,
+		ApplyConcurrency:           DefApplyConcurrency,
+		EnableIndexAdvisor:         DefEnableIndexAdvisor,
+		MemQuotaQuery:              DefMemQuotaQuery,
+		MemOOMAction:               memory.ActionCancel,
+		TmpTableMaxSize:            DefTmpTableMaxSize,
+		CTEMaxRecursionDepth:       DefCTEMaxRecursionDepth,
+		TxnMode:                    DefTxnMode,
+		LockWaitTimeout:            DefLockWaitTimeout,
+		ReplicaRead:                DefReplicaRead,
+		SlowLogThreshold:           DefSlowLogThreshold * time.Millisecond,
+		SlowLogNormalize:           DefSlowLogNormalize,
 	}
 }
 
@@ -223,6 +359,20 @@ func (s *SessionVars) GetCharsetInfo() (charset, collation string) {
 	return
 }
 
+// snapshotReleaser is implemented by kv.Snapshot.
+type snapshotReleaser interface {
+	Release()
+}
+
+// HoldSnapshot holds the kv.Snapshot of tidb_snapshot and releases the one held before, the snapshot is released if
+// it's nil.
+func (s *SessionVars) HoldSnapshot(snapshot snapshotReleaser) {
+	if s.snapshot != nil {
+		s.snapshot.Release()
+	}
+	s.snapshot = snapshot
+}
+
 // SetLastInsertID saves the last insert id to the session context.
 // TODO: we may store the result for last_insert_id sys var later.
 func (s *SessionVars) SetLastInsertID(insertID uint64) {
@@ -263,21 +413,54 @@ func (s *SessionVars) GetNextPreparedStmtID() uint32 {
 
 // special session variables.
 const (
-	SQLModeVar          = "sql_mode"
-	AutocommitVar       = "autocommit"
-	CharacterSetResults = "character_set_results"
-	MaxAllowedPacket    = "max_allowed_packet"
-	TimeZone            = "time_zone"
+	SQLModeVar            = "sql_mode"
+	AutocommitVar         = "autocommit"
+	CharacterSetResults   = "character_set_results"
+	MaxAllowedPacket      = "max_allowed_packet"
+	TimeZone              = "time_zone"
+	GroupConcatMaxLen     = "group_concat_max_len"
+	CTEMaxRecursionDepth  = "cte_max_recursion_depth"
+	InnodbLockWaitTimeout = "innodb_lock_wait_timeout"
+	TxIsolation           = "tx_isolation"
+	TxReadOnly            = "tx_read_only"
+	GeneralLog            = "general_log"
+	GeneralLogFile        = "general_log_file"
 )
 
+// DefCTEMaxRecursionDepth is the default value of cte_max_recursion_depth.
+const DefCTEMaxRecursionDepth = 1000
+
 // StatementContext contains variables for a statement.
 // It should be reset before executing a statement.
 type StatementContext struct {
 	/* Variables that are set before execution */
-	InUpdateOrDeleteStmt bool
-	IgnoreTruncate       bool
-	TruncateAsWarning    bool
-	InShowWarning        bool
+	InUpdateOrDeleteStmt    bool
+	InCreateTableSelectStmt bool
+	IgnoreTruncate          bool
+	TruncateAsWarning       bool
+	InShowWarning           bool
+
+	// MemTracker tracks the memory usage of the statement, the trackers of the executors are attached to it.
+	MemTracker *memory.Tracker
+	// RuntimeStatsColl collects the runtime statistics of the executors of the statement.
+	RuntimeStatsColl *execdetails.RuntimeStatsColl
+	// BackoffDetails collects the backoffs of the kv requests of the statement.
+	BackoffDetails *execdetails.BackoffDetails
+	// ReadDetails collects the kv requests of the reads of the statement.
+	ReadDetails *execdetails.ReadDetails
+	// ParseTime and CompileTime are the time spent in parsing and compiling the statement, they are logged with the
+	// slow query. The parse time is the time of the whole query which contains the statement.
+	ParseTime   time.Duration
+	CompileTime time.Duration
+	// Priority is the kv priority of the reads of the statement, it's set by the priority option of the statement.
+	Priority int
+	// ReadTS is the timestamp of the AS OF TIMESTAMP clauses, the statement reads the snapshot at the timestamp.
+	ReadTS uint64
+	// ReadInfoSchema is the schema at ReadTS.
+	ReadInfoSchema interface{}
+	// TimeZone is the time zone of the session, the TIMESTAMP values are converted between it and the system time
+	// zone when they are written and read. nil means the system time zone.
+	TimeZone *time.Location
 
 	/* Variables that changes during execution. */
 	mu struct {
@@ -48,6 +48,17 @@ type SysVar struct {
 // SysVars is global sys vars map.
 var SysVars map[string]*SysVar
 
+// defaultHashJoinConcurrency is the default of tidb_hash_join_concurrency.
+var defaultHashJoinConcurrency = DefHashJoinConcurrency
+
+// SetDefaultHashJoinConcurrency sets the default of tidb_hash_join_concurrency, it's the value of the new sessions if
+// the global variable isn't stored, and the global value stored when a store is bootstrapped. It's used by the
+// deprecated join-concurrency flag of tidb-server, and must be called before the server starts.
+func SetDefaultHashJoinConcurrency(n int) {
+	defaultHashJoinConcurrency = n
+	SysVars[TiDBHashJoinConcurrency].Value = strconv.Itoa(n)
+}
+
 // GetSysVar returns sys var info for name as key.
 func GetSysVar(name string) *SysVar {
 	name = strings.ToLower(name)
@@ -58,7 +69,12 @@ func GetSysVar(name string) *SysVar {
 const (
 	CodeUnknownStatusVar terror.ErrCode = 1
 	CodeUnknownSystemVar terror.ErrCode = 1193
+	CodeLocalVariable    terror.ErrCode = 1228
+	CodeGlobalVariable   terror.ErrCode = 1229
+	CodeWrongValueForVar terror.ErrCode = 1231
+	CodeWrongTypeForVar  terror.ErrCode = 1232
 	CodeIncorrectScope   terror.ErrCode = 1238
+	CodeUnknownTimeZone  terror.ErrCode = 1298
 )
 
 // Variable errors
@@ -66,6 +82,18 @@ var (
 	UnknownStatusVar  = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
 	UnknownSystemVar  = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable '%s'")
 	ErrIncorrectScope = terror.ClassVariable.New(CodeIncorrectScope, "Incorrect variable scope")
+	// ErrWrongValueForVar is returned when the value isn't valid for the variable.
+	ErrWrongValueForVar = terror.ClassVariable.New(CodeWrongValueForVar, "Variable '%s' can't be set to the value of '%s'")
+	// ErrWrongTypeForVar is returned when the type of the value doesn't match the variable, like a string for an integer variable.
+	ErrWrongTypeForVar = terror.ClassVariable.New(CodeWrongTypeForVar, "Incorrect argument type to variable '%s'")
+	// ErrReadOnly is returned when setting a variable whose scope is ScopeNone.
+	ErrReadOnly = terror.ClassVariable.New(CodeIncorrectScope, "Variable '%s' is a read only variable")
+	// ErrLocalVariable is returned when setting a session only variable with SET GLOBAL.
+	ErrLocalVariable = terror.ClassVariable.New(CodeLocalVariable, "Variable '%s' is a SESSION variable and can't be used with SET GLOBAL")
+	// ErrGlobalVariable is returned when setting a global only variable without SET GLOBAL.
+	ErrGlobalVariable = terror.ClassVariable.New(CodeGlobalVariable, "Variable '%s' is a GLOBAL variable and should be set with SET GLOBAL")
+	// ErrUnknownTimeZone is returned when the time_zone value is neither a named time zone nor a valid offset.
+	ErrUnknownTimeZone = terror.ClassVariable.New(CodeUnknownTimeZone, "Unknown or incorrect time zone: '%s'")
 )
 
 func init() {
@@ -77,7 +105,12 @@ func init() {
 	// Register terror to mysql error map.
 	mySQLErrCodes := map[terror.ErrCode]uint16{
 		CodeUnknownSystemVar: mysql.ErrUnknownSystemVariable,
+		CodeLocalVariable:    mysql.ErrLocalVariable,
+		CodeGlobalVariable:   mysql.ErrGlobalVariable,
+		CodeWrongValueForVar: mysql.ErrWrongValueForVar,
+		CodeWrongTypeForVar:  mysql.ErrWrongTypeForVar,
 		CodeIncorrectScope:   mysql.ErrIncorrectGlobalLocalVar,
+		CodeUnknownTimeZone:  mysql.ErrUnknownTimeZone,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassVariable] = mySQLErrCodes
 }
@@ -174,7 +207,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "innodb_log_write_ahead_size", ""},
 	{ScopeNone, "innodb_log_group_home_dir", "./"},
 	{ScopeNone, "performance_schema_events_statements_history_size", "10"},
-	{ScopeGlobal, "general_log", "OFF"},
+	{ScopeGlobal, GeneralLog, "OFF"},
 	{ScopeGlobal, "validate_password_dictionary_file", ""},
 	{ScopeGlobal, "binlog_order_commits", "ON"},
 	{ScopeGlobal, "master_verify_checksum", "OFF"},
@@ -214,7 +247,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "concurrent_insert", "AUTO"},
 	{ScopeGlobal, "innodb_adaptive_hash_index", "ON"},
 	{ScopeGlobal, "innodb_ft_enable_stopword", "ON"},
-	{ScopeGlobal, "general_log_file", "/usr/local/mysql/data/localhost.log"},
+	{ScopeGlobal, GeneralLogFile, ""},
 	{ScopeGlobal | ScopeSession, "innodb_support_xa", "ON"},
 	{ScopeGlobal, "innodb_compression_level", "6"},
 	{ScopeNone, "innodb_file_format_check", "ON"},
@@ -524,7 +557,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "basedir", "/usr/local/mysql"},
 	{ScopeGlobal, "innodb_old_blocks_time", "1000"},
 	{ScopeGlobal, "innodb_stats_method", "nulls_equal"},
-	{ScopeGlobal | ScopeSession, "innodb_lock_wait_timeout", "50"},
+	{ScopeGlobal | ScopeSession, InnodbLockWaitTimeout, strconv.Itoa(DefLockWaitTimeout)},
 	{ScopeGlobal, "local_infile", "ON"},
 	{ScopeGlobal | ScopeSession, "myisam_stats_method", "nulls_unequal"},
 	{ScopeNone, "version_compile_os", "osx10.8"},
@@ -542,7 +575,8 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "back_log", "80"},
 	{ScopeNone, "lower_case_file_system", "ON"},
 	{ScopeGlobal, "rpl_semi_sync_master_wait_no_slave", ""},
-	{ScopeGlobal | ScopeSession, "group_concat_max_len", "1024"},
+	{ScopeGlobal | ScopeSession, GroupConcatMaxLen, "1024"},
+	{ScopeGlobal | ScopeSession, CTEMaxRecursionDepth, strconv.Itoa(DefCTEMaxRecursionDepth)},
 	{ScopeSession, "pseudo_thread_id", ""},
 	{ScopeNone, "socket", "/tmp/myssock"},
 	{ScopeNone, "have_dynamic_loading", "YES"},
@@ -593,6 +627,8 @@ var defaultSysVars = []*SysVar{
 	/* TiDB specific variables */
 	{ScopeSession, TiDBSnapshot, ""},
 	{ScopeSession, TiDBSkipConstraintCheck, "0"},
+	{ScopeSession, TiDBDMLBatchSize, "0"},
+	{ScopeSession, TiDBBatchDMLDryRun, "0"},
 	{ScopeSession, TiDBOptAggPushDown, boolToIntStr(DefOptAggPushDown)},
 	{ScopeSession, TiDBOptInSubqUnFolding, boolToIntStr(DefOptInSubqUnfolding)},
 	{ScopeSession, TiDBBuildStatsConcurrency, strconv.Itoa(DefBuildStatsConcurrency)},
@@ -600,8 +636,33 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, TiDBIndexLookupSize, strconv.Itoa(DefIndexLookupSize)},
 	{ScopeGlobal | ScopeSession, TiDBIndexLookupConcurrency, strconv.Itoa(DefIndexLookupConcurrency)},
 	{ScopeGlobal | ScopeSession, TiDBIndexSerialScanConcurrency, strconv.Itoa(DefIndexSerialScanConcurrency)},
+	{ScopeGlobal | ScopeSession, TiDBHashJoinConcurrency, strconv.Itoa(DefHashJoinConcurrency)},
+	{ScopeGlobal | ScopeSession, TiDBHashAggConcurrency, strconv.Itoa(DefHashAggConcurrency)},
+	{ScopeGlobal | ScopeSession, TiDBProjectionConcurrency, strconv.Itoa(DefProjectionConcurrency)},
+	{ScopeGlobal | ScopeSession, TiDBIndexJoinBatchSize, strconv.Itoa(DefIndexJoinBatchSize)},
+	{ScopeGlobal | ScopeSession, TiDBMaxSortRowsInMemory, strconv.Itoa(DefMaxSortRowsInMemory)},
+	{ScopeGlobal | ScopeSession, TiDBMaxHashRowsInMemory, strconv.Itoa(DefMaxHashRowsInMemory)},
+	{ScopeGlobal | ScopeSession, TiDBEnableParallelApply, boolToIntStr(DefEnableParallelApply)},
+	{ScopeGlobal | ScopeSession, TiDBApplyConcurrency, strconv.Itoa(DefApplyConcurrency)},
+	{ScopeGlobal | ScopeSession, TiDBEnableIndexAdvisor, boolToIntStr(DefEnableIndexAdvisor)},
+	{ScopeGlobal | ScopeSession, TiDBMemQuotaQuery, strconv.FormatInt(DefMemQuotaQuery, 10)},
+	{ScopeGlobal | ScopeSession, TiDBMemOOMAction, DefMemOOMAction},
+	{ScopeGlobal | ScopeSession, TiDBTmpTableMaxSize, strconv.FormatInt(DefTmpTableMaxSize, 10)},
 	{ScopeGlobal | ScopeSession, TiDBSkipDDLWait, boolToIntStr(DefSkipDDLWait)},
 	{ScopeGlobal | ScopeSession, TiDBSkipUTF8Check, boolToIntStr(DefSkipUTF8Check)},
+	{ScopeGlobal | ScopeSession, TiDBTxnMode, DefTxnMode},
+	{ScopeGlobal | ScopeSession, TiDBEnableStreaming, boolToIntStr(DefEnableStreaming)},
+	{ScopeGlobal | ScopeSession, TiDBReplicaRead, DefReplicaRead},
+	{ScopeGlobal | ScopeSession, TiDBSlowLogThreshold, strconv.Itoa(DefSlowLogThreshold)},
+	{ScopeGlobal | ScopeSession, TiDBSlowLogNormalize, boolToIntStr(DefSlowLogNormalize)},
+	{ScopeGlobal, TiDBEnableStmtSummary, boolToIntStr(DefEnableStmtSummary)},
+	{ScopeGlobal, TiDBTTLJobEnable, boolToIntStr(DefTTLJobEnable)},
+	{ScopeGlobal, TiDBTTLDeleteBatchSize, strconv.Itoa(DefTTLDeleteBatchSize)},
+	{ScopeGlobal, TiDBTTLJobScheduleWindowStartTime, DefTTLJobScheduleWindowStart},
+	{ScopeGlobal, TiDBTTLJobScheduleWindowEndTime, DefTTLJobScheduleWindowEnd},
+	{ScopeGlobal, TiDBDDLReorgWorkerCount, strconv.Itoa(DefDDLReorgWorkerCount)},
+	{ScopeGlobal, TiDBDDLReorgBatchSize, strconv.Itoa(DefDDLReorgBatchSize)},
+	{ScopeGlobal, TiDBDDLReorgPriority, DefDDLReorgPriority},
 }
 
 // SetNamesVariables is the system variable names related to set names statements.
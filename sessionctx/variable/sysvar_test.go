@@ -36,3 +36,11 @@ func (*testSysVarSuite) TestSysVar(c *C) {
 	f = GetSysVar("wrong-var-name")
 	c.Assert(f, IsNil)
 }
+
+func (*testSysVarSuite) TestSetDefaultHashJoinConcurrency(c *C) {
+	defer SetDefaultHashJoinConcurrency(DefHashJoinConcurrency)
+	c.Assert(NewSessionVars().HashJoinConcurrency, Equals, DefHashJoinConcurrency)
+	SetDefaultHashJoinConcurrency(8)
+	c.Assert(NewSessionVars().HashJoinConcurrency, Equals, 8)
+	c.Assert(GetSysVar(TiDBHashJoinConcurrency).Value, Equals, "8")
+}
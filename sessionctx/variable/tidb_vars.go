@@ -38,6 +38,18 @@ const (
 	// When the value is set to true, unique index constraint is not checked.
 	TiDBSkipConstraintCheck = "tidb_skip_constraint_check"
 
+	// tidb_dml_batch_size is used for cleaning up huge amounts of data with a single DELETE or UPDATE statement.
+	// When it's positive and the session is in autocommit mode, a single table DELETE or UPDATE commits its changes
+	// every tidb_dml_batch_size rows in separate transactions, so the statement is no longer atomic, a failure in the
+	// middle leaves the committed batches in place. The default value 0 disables it.
+	// LOAD DATA always commits in batches, of tidb_dml_batch_size rows if it's positive, or 20000 rows otherwise.
+	TiDBDMLBatchSize = "tidb_dml_batch_size"
+
+	// tidb_batch_dml_dry_run is used with tidb_dml_batch_size.
+	// When it's on, a batched DELETE or UPDATE only reports the handle range of each batch as a note, without
+	// modifying any row.
+	TiDBBatchDMLDryRun = "tidb_batch_dml_dry_run"
+
 	// tidb_opt_agg_push_down is used to endable/disable the optimizer rule of aggregation push down.
 	TiDBOptAggPushDown = "tidb_opt_agg_push_down"
 
@@ -74,6 +86,71 @@ const (
 	// when we need to keep the data output order the same as the order of index data.
 	TiDBIndexSerialScanConcurrency = "tidb_index_serial_scan_concurrency"
 
+	// tidb_hash_join_concurrency is used for hash join executor.
+	// The hash join executor builds a hash table from the smaller input, then probes it with rows of the bigger
+	// input in this number of concurrent workers.
+	TiDBHashJoinConcurrency = "tidb_hash_join_concurrency"
+
+	// tidb_hash_agg_concurrency is used for hash aggregation executor.
+	// The hash aggregation executor aggregates the input rows in this number of partial workers, then merges the
+	// partial results of the same group in one of this number of final workers.
+	// Set it to 1 to aggregate in the executor goroutine only.
+	TiDBHashAggConcurrency = "tidb_hash_agg_concurrency"
+
+	// tidb_projection_concurrency is used for projection executor.
+	// The projection executor evaluates the expensive expressions, like regexp, over batches of input rows in this
+	// number of concurrent workers, the order of the rows is kept.
+	// Set it to 1 to evaluate in the executor goroutine only.
+	TiDBProjectionConcurrency = "tidb_projection_concurrency"
+
+	// tidb_index_join_batch_size is used for index nested loop join executor.
+	// The index join executor reads this number of rows from the outer input, then looks up the inner table
+	// with the join keys of them in one request.
+	// Small value sends more RPCs to TiKV, large value consumes more memory.
+	TiDBIndexJoinBatchSize = "tidb_index_join_batch_size"
+
+	// tidb_max_sort_rows_in_memory is used for sort executor.
+	// When the sort executor holds more than this number of rows, it sorts them in runs written to temporary files,
+	// then merges the runs on output.
+	TiDBMaxSortRowsInMemory = "tidb_max_sort_rows_in_memory"
+
+	// tidb_max_hash_rows_in_memory is used for hash join and hash aggregation executors.
+	// When the build side of a hash join has more than this number of rows, both sides are partitioned by the join key
+	// to temporary files and joined partition by partition. A hash aggregation worker keeps at most this number of
+	// groups in memory, the rows of the other groups are aggregated later in the same way.
+	TiDBMaxHashRowsInMemory = "tidb_max_hash_rows_in_memory"
+
+	// tidb_enable_parallel_apply is used for apply executor.
+	// When it's on, the apply executor fetches and filters the outer rows in a background goroutine, and executes the
+	// inner plan for the fetched outer rows in tidb_apply_concurrency workers.
+	TiDBEnableParallelApply = "tidb_enable_parallel_apply"
+
+	// tidb_apply_concurrency is used for apply executor.
+	// In parallel mode, the inner plan is executed for this number of outer rows at the same time, each worker has its
+	// own copy of the inner plan. The inner plan runs in one worker if it contains a plan that can't be copied.
+	TiDBApplyConcurrency = "tidb_apply_concurrency"
+
+	// tidb_enable_index_advisor is used for the index advisor.
+	// When it's on, the digests of the SELECT, UPDATE and DELETE statements and the indexes built from their
+	// predicates are recorded in the workload, 'admin show index advice' evaluates those indexes with the cost model.
+	TiDBEnableIndexAdvisor = "tidb_enable_index_advisor"
+
+	// tidb_mem_quota_query is the memory quota of a statement in bytes.
+	// The memory usage of the executors of a statement is tracked, when it exceeds the quota, the action set by
+	// tidb_mem_oom_action is taken. A value <= 0 means no quota.
+	TiDBMemQuotaQuery = "tidb_mem_quota_query"
+
+	// tidb_mem_oom_action is the action taken when a statement exceeds tidb_mem_quota_query, it can be:
+	// "cancel": cancel the statement with an error.
+	// "spill": the sort, hash join and hash aggregation executors spill to disk, the others log a warning.
+	// "log": log a warning and go on.
+	TiDBMemOOMAction = "tidb_mem_oom_action"
+
+	// tidb_tmp_table_max_size is the maximum memory size of a temporary table in bytes.
+	// The rows of a temporary table are kept in memory, when their size exceeds this value, they are spilled to a
+	// temporary directory on disk.
+	TiDBTmpTableMaxSize = "tidb_tmp_table_max_size"
+
 	// tidb_skip_ddl_wait skips the wait tiem of two lease after executing CREATE TABLE statement.
 	// When we have multiple TiDB servers in a cluster, the newly created table may not be available on all TiDB server
 	// until two lease time later, set this value to true will reduce the time to create a table, with the risk that
@@ -83,6 +160,71 @@ const (
 	// tidb_skip_utf8_check skips the UTF8 validate process, validate UTF8 has performance cost, if we can make sure
 	// the input string values are valid, we can skip the check.
 	TiDBSkipUTF8Check = "tidb_skip_utf8_check"
+
+	// tidb_txn_mode is the mode of the transactions, it can be:
+	// "optimistic" or "": the keys written by a transaction are locked when it commits, it fails on the conflicts
+	// with the other transactions then.
+	// "pessimistic": the keys are locked when they are written, a transaction waits for the keys locked by the other
+	// pessimistic transactions at most innodb_lock_wait_timeout seconds, like InnoDB. It's allowed only if
+	// EnablePessimisticTxn is set.
+	// BEGIN PESSIMISTIC and BEGIN OPTIMISTIC override it for a transaction.
+	TiDBTxnMode = "tidb_txn_mode"
+
+	// tidb_enable_streaming enables the streaming coprocessor requests of the table scans, the rows of a region are
+	// returned in several small responses, so the memory usage is smoother and the rows are handled earlier.
+	TiDBEnableStreaming = "tidb_enable_streaming"
+
+	// tidb_replica_read is the replicas which serve the reads, it can be:
+	// "leader": the reads are served by the leaders.
+	// "follower": the reads are served by the followers in turn, so the read-heavy workloads don't overload the
+	// leaders. A follower catches up with the leader before serving a read, the reads are still consistent.
+	// "leader-preferred": the reads are served by the leaders, unless a leader is much slower than its followers.
+	// "closest": the reads are served by the replicas whose store labels match the labels of the server most, the
+	// leaders are preferred among them, so the reads stay in the zone of the server.
+	// "round-robin": the reads are served by all the replicas in turn.
+	TiDBReplicaRead = "tidb_replica_read"
+
+	// tidb_slow_log_threshold is the threshold in milliseconds of the slow queries, the statements which take longer
+	// are logged with the details of their execution, and shown in information_schema.SLOW_QUERY.
+	TiDBSlowLogThreshold = "tidb_slow_log_threshold"
+
+	// tidb_slow_log_normalize makes the slow queries logged with the normalized query text, in which the constants
+	// are replaced by '?', so the sensitive data is not logged and the similar queries can be grouped.
+	TiDBSlowLogNormalize = "tidb_slow_log_normalize"
+
+	/* Global only */
+
+	// tidb_enable_stmt_summary makes the executed statements summarized by their schemas and digests in
+	// performance_schema.events_statements_summary_by_digest, the summaries are cleared when it's turned off.
+	TiDBEnableStmtSummary = "tidb_enable_stmt_summary"
+
+	// tidb_ttl_job_enable is used to enable/disable the TTL jobs, which delete the expired rows of the tables with
+	// the TTL option in the background.
+	TiDBTTLJobEnable = "tidb_ttl_job_enable"
+
+	// tidb_ttl_delete_batch_size is the number of the expired rows deleted by a TTL job in a transaction.
+	// Large value makes the job faster, with the cost of larger transactions and more write conflicts with the users.
+	TiDBTTLDeleteBatchSize = "tidb_ttl_delete_batch_size"
+
+	// tidb_ttl_job_schedule_window_start_time and tidb_ttl_job_schedule_window_end_time are the local time of day,
+	// like '01:00', between which the TTL jobs are started, so the deletions run in the off-peak hours.
+	// The window goes past midnight if the start time is later than the end time.
+	TiDBTTLJobScheduleWindowStartTime = "tidb_ttl_job_schedule_window_start_time"
+	TiDBTTLJobScheduleWindowEndTime   = "tidb_ttl_job_schedule_window_end_time"
+
+	// tidb_ddl_reorg_worker_cnt, tidb_ddl_reorg_batch_size and tidb_ddl_reorg_priority limit the resources used by
+	// the reorganization of the DDL jobs, so the backfill of adding an index doesn't starve the foreground requests.
+	// The changes take effect on the running job.
+
+	// tidb_ddl_reorg_worker_cnt is the number of the workers that backfill the index concurrently.
+	TiDBDDLReorgWorkerCount = "tidb_ddl_reorg_worker_cnt"
+
+	// tidb_ddl_reorg_batch_size is the number of the rows that a worker backfills in a transaction.
+	TiDBDDLReorgBatchSize = "tidb_ddl_reorg_batch_size"
+
+	// tidb_ddl_reorg_priority is the priority of the reads of the reorganization,
+	// it's one of PRIORITY_LOW, PRIORITY_NORMAL and PRIORITY_HIGH.
+	TiDBDDLReorgPriority = "tidb_ddl_reorg_priority"
 )
 
 // Default TiDB system variable values.
@@ -91,9 +233,56 @@ const (
 	DefIndexSerialScanConcurrency = 1
 	DefIndexLookupSize            = 20000
 	DefDistSQLScanConcurrency     = 10
+	DefHashJoinConcurrency        = 5
+	DefHashAggConcurrency         = 4
+	DefProjectionConcurrency      = 4
+	DefIndexJoinBatchSize         = 25000
+	DefMaxSortRowsInMemory        = 1000000
+	DefMaxHashRowsInMemory        = 1000000
+	DefEnableParallelApply        = false
+	DefApplyConcurrency           = 4
+	DefEnableIndexAdvisor         = false
+	DefMemQuotaQuery              = 32 << 30 // 32GB.
+	DefMemOOMAction               = "cancel"
+	DefTmpTableMaxSize            = 64 << 20 // 64MB.
 	DefBuildStatsConcurrency      = 4
 	DefSkipDDLWait                = false
 	DefSkipUTF8Check              = false
 	DefOptAggPushDown             = true
 	DefOptInSubqUnfolding         = false
+	DefTTLJobEnable               = true
+	DefTTLDeleteBatchSize         = 100
+	DefTTLJobScheduleWindowStart  = "00:00"
+	DefTTLJobScheduleWindowEnd    = "23:59"
+	DefDDLReorgWorkerCount        = 16
+	DefDDLReorgBatchSize          = 128
+	DefDDLReorgPriority           = "PRIORITY_LOW"
+	DefTxnMode                    = ""
+	DefLockWaitTimeout            = 50
+	DefEnableStreaming            = false
+	DefReplicaRead                = ReplicaReadLeader
+	DefSlowLogThreshold           = 300
+	DefSlowLogNormalize           = false
+	DefEnableStmtSummary          = false
+)
+
+// The values of tidb_txn_mode.
+const (
+	TxnModeOptimistic  = "optimistic"
+	TxnModePessimistic = "pessimistic"
+)
+
+// EnablePessimisticTxn enables the pessimistic transaction mode, it's experimental and disabled by default. The keys
+// are locked in the memory of the tidb-server, so only the pessimistic transactions of the same tidb-server wait for
+// each other, the transactions of the other tidb-servers still conflict when they commit. It's meant for the
+// deployments of a single tidb-server.
+var EnablePessimisticTxn = false
+
+// The values of tidb_replica_read.
+const (
+	ReplicaReadLeader          = "leader"
+	ReplicaReadFollower        = "follower"
+	ReplicaReadLeaderPreferred = "leader-preferred"
+	ReplicaReadClosest         = "closest"
+	ReplicaReadRoundRobin      = "round-robin"
 )
@@ -19,8 +19,11 @@ import (
 	"time"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -45,8 +48,11 @@ func GetSessionSystemVar(s *variable.SessionVars, key string) (string, error) {
 	if err != nil {
 		return "", errors.Trace(err)
 	}
-	s.Systems[key] = gVal
-	return gVal, nil
+	// The session states are updated with the global value too.
+	if err = SetSessionSystemVar(s, key, types.NewStringDatum(gVal)); err != nil {
+		return "", errors.Trace(err)
+	}
+	return s.Systems[key], nil
 }
 
 // GetGlobalSystemVar gets a global system variable.
@@ -87,7 +93,11 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 	}
 	switch name {
 	case variable.TimeZone:
-		vars.TimeZone = parseTimeZone(sVal)
+		loc := parseTimeZone(sVal)
+		if loc == nil {
+			return variable.ErrUnknownTimeZone.GenByArgs(sVal)
+		}
+		vars.TimeZone = loc
 	case variable.SQLModeVar:
 		sVal = strings.ToUpper(sVal)
 		// TODO: Remove this latter.
@@ -116,8 +126,14 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 		}
 	case variable.TiDBSkipConstraintCheck:
 		vars.SkipConstraintCheck = tidbOptOn(sVal)
+	case variable.TiDBDMLBatchSize:
+		vars.DMLBatchSize = tidbOptPositiveInt(sVal, 0)
+	case variable.TiDBBatchDMLDryRun:
+		vars.BatchDMLDryRun = tidbOptOn(sVal)
 	case variable.TiDBSkipUTF8Check:
 		vars.SkipUTF8Check = tidbOptOn(sVal)
+	case variable.TiDBEnableStreaming:
+		vars.EnableStreaming = tidbOptOn(sVal)
 	case variable.TiDBSkipDDLWait:
 		vars.SkipDDLWait = tidbOptOn(sVal)
 	case variable.TiDBOptAggPushDown:
@@ -132,11 +148,259 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 		vars.DistSQLScanConcurrency = tidbOptPositiveInt(sVal, variable.DefDistSQLScanConcurrency)
 	case variable.TiDBIndexSerialScanConcurrency:
 		vars.IndexSerialScanConcurrency = tidbOptPositiveInt(sVal, variable.DefIndexSerialScanConcurrency)
+	case variable.TiDBHashJoinConcurrency:
+		vars.HashJoinConcurrency = tidbOptPositiveInt(sVal, variable.DefHashJoinConcurrency)
+	case variable.TiDBHashAggConcurrency:
+		vars.HashAggConcurrency = tidbOptPositiveInt(sVal, variable.DefHashAggConcurrency)
+	case variable.TiDBProjectionConcurrency:
+		vars.ProjectionConcurrency = tidbOptPositiveInt(sVal, variable.DefProjectionConcurrency)
+	case variable.TiDBIndexJoinBatchSize:
+		vars.IndexJoinBatchSize = tidbOptPositiveInt(sVal, variable.DefIndexJoinBatchSize)
+	case variable.TiDBMaxSortRowsInMemory:
+		vars.MaxSortRowsInMemory = tidbOptPositiveInt(sVal, variable.DefMaxSortRowsInMemory)
+	case variable.TiDBMaxHashRowsInMemory:
+		vars.MaxHashRowsInMemory = tidbOptPositiveInt(sVal, variable.DefMaxHashRowsInMemory)
+	case variable.TiDBEnableParallelApply:
+		vars.EnableParallelApply = tidbOptOn(sVal)
+	case variable.TiDBApplyConcurrency:
+		vars.ApplyConcurrency = tidbOptPositiveInt(sVal, variable.DefApplyConcurrency)
+	case variable.TiDBEnableIndexAdvisor:
+		vars.EnableIndexAdvisor = tidbOptOn(sVal)
+	case variable.TiDBMemQuotaQuery:
+		vars.MemQuotaQuery = tidbOptInt64(sVal, variable.DefMemQuotaQuery)
+	case variable.TiDBMemOOMAction:
+		action, err := memory.ParseActionOnExceed(sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		vars.MemOOMAction = action
+		sVal = action.String()
+	case variable.TiDBTmpTableMaxSize:
+		vars.TmpTableMaxSize = tidbOptInt64(sVal, variable.DefTmpTableMaxSize)
+	case variable.CTEMaxRecursionDepth:
+		vars.CTEMaxRecursionDepth = tidbOptInt64(sVal, variable.DefCTEMaxRecursionDepth)
+	case variable.TiDBTxnMode:
+		mode := strings.ToLower(sVal)
+		if mode != "" && mode != variable.TxnModeOptimistic && mode != variable.TxnModePessimistic {
+			return variable.ErrWrongValueForVar.GenByArgs(name, sVal)
+		}
+		if mode == variable.TxnModePessimistic && !variable.EnablePessimisticTxn {
+			return variable.ErrWrongValueForVar.GenByArgs(name, sVal)
+		}
+		vars.TxnMode = mode
+		sVal = mode
+	case variable.InnodbLockWaitTimeout:
+		vars.LockWaitTimeout = tidbOptInt64(sVal, variable.DefLockWaitTimeout)
+	case variable.TiDBReplicaRead:
+		replicaRead := strings.ToLower(sVal)
+		if _, ok := replicaReadTypes[replicaRead]; !ok {
+			return variable.ErrWrongValueForVar.GenByArgs(name, sVal)
+		}
+		vars.ReplicaRead = replicaRead
+		sVal = replicaRead
+	case variable.TiDBSlowLogThreshold:
+		vars.SlowLogThreshold = time.Duration(tidbOptInt64(sVal, variable.DefSlowLogThreshold)) * time.Millisecond
+	case variable.TiDBSlowLogNormalize:
+		vars.SlowLogNormalize = tidbOptOn(sVal)
 	}
 	vars.Systems[name] = sVal
 	return nil
 }
 
+// ValidateSetSystemVar checks the value to set to the system variable name, and returns the normalized value.
+// The value is checked by the type of the variable, the values of the variables that aren't listed below are not
+// checked.
+func ValidateSetSystemVar(name string, value string) (string, error) {
+	name = strings.ToLower(name)
+	if normalize, ok := boolSysVars[name]; ok {
+		switch strings.ToUpper(value) {
+		case "ON", "TRUE":
+			return normalize(true, value), nil
+		case "OFF", "FALSE":
+			return normalize(false, value), nil
+		case "1", "0":
+			return normalize(value == "1", value), nil
+		}
+		return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+	}
+	if min, ok := intSysVars[name]; ok {
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return value, variable.ErrWrongTypeForVar.GenByArgs(name)
+		}
+		if val < min {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+		return strconv.FormatInt(val, 10), nil
+	}
+	switch name {
+	case variable.SQLModeVar:
+		value = strings.ToUpper(value)
+		if value == "" {
+			return value, nil
+		}
+		for _, mode := range strings.Split(value, ",") {
+			if _, ok := mysql.Str2SQLMode[strings.TrimSpace(mode)]; !ok {
+				return value, variable.ErrWrongValueForVar.GenByArgs(name, mode)
+			}
+		}
+	case variable.TxIsolation:
+		value = strings.ToUpper(value)
+		switch value {
+		case "READ-UNCOMMITTED", "READ-COMMITTED", "REPEATABLE-READ", "SERIALIZABLE":
+		default:
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+	case "character_set_client", "character_set_connection", variable.CharacterSetResults, variable.CharsetDatabase,
+		"character_set_server", "character_set_filesystem":
+		cs, _, err := charset.GetCharsetInfo(value)
+		if err != nil {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+		value = cs
+	case variable.CollationConnection, variable.CollationDatabase, "collation_server":
+		co, err := charset.GetCollationByName(value)
+		if err != nil {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+		value = co.Name
+	case variable.TiDBTxnMode:
+		value = strings.ToLower(value)
+		if value != "" && value != variable.TxnModeOptimistic && value != variable.TxnModePessimistic {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+		// The pessimistic mode is experimental, see variable.EnablePessimisticTxn.
+		if value == variable.TxnModePessimistic && !variable.EnablePessimisticTxn {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+	case variable.TiDBReplicaRead:
+		value = strings.ToLower(value)
+		if _, ok := replicaReadTypes[value]; !ok {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+	case variable.TimeZone:
+		if parseTimeZone(value) == nil {
+			return value, variable.ErrUnknownTimeZone.GenByArgs(value)
+		}
+	case variable.TiDBMemOOMAction:
+		action, err := memory.ParseActionOnExceed(value)
+		if err != nil {
+			return value, variable.ErrWrongValueForVar.GenByArgs(name, value)
+		}
+		value = action.String()
+	}
+	return value, nil
+}
+
+// The MySQL boolean variables are shown as "ON" or "OFF", while the numbers set to them are kept as they are.
+// The TiDB boolean variables are shown as "1" or "0".
+func mysqlBool(on bool, value string) string {
+	if value == "1" || value == "0" {
+		return value
+	}
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+func tidbBool(on bool, _ string) string {
+	if on {
+		return "1"
+	}
+	return "0"
+}
+
+// boolSysVars is the boolean system variables, which accept ON, OFF, TRUE, FALSE, 1 and 0.
+var boolSysVars = map[string]func(on bool, value string) string{
+	variable.AutocommitVar:                    mysqlBool,
+	variable.TxReadOnly:                       tidbBool,
+	"low_priority_updates":                    mysqlBool,
+	"big_tables":                              mysqlBool,
+	"sql_log_bin":                             mysqlBool,
+	"sql_notes":                               mysqlBool,
+	"sql_warnings":                            mysqlBool,
+	"sql_auto_is_null":                        mysqlBool,
+	"sql_big_selects":                         mysqlBool,
+	"sql_buffer_result":                       mysqlBool,
+	"sql_log_off":                             mysqlBool,
+	"sql_quote_show_create":                   mysqlBool,
+	"sql_safe_updates":                        mysqlBool,
+	"foreign_key_checks":                      mysqlBool,
+	"unique_checks":                           mysqlBool,
+	"profiling":                               mysqlBool,
+	variable.GeneralLog:                       mysqlBool,
+	"slow_query_log":                          mysqlBool,
+	"read_only":                               mysqlBool,
+	"local_infile":                            mysqlBool,
+	"innodb_strict_mode":                      mysqlBool,
+	"innodb_table_locks":                      mysqlBool,
+	"innodb_file_per_table":                   mysqlBool,
+	"log_queries_not_using_indexes":           mysqlBool,
+	"log_bin_trust_function_creators":         mysqlBool,
+	"automatic_sp_privileges":                 mysqlBool,
+	"show_old_temporals":                      mysqlBool,
+	"end_markers_in_json":                     mysqlBool,
+	"binlog_direct_non_transactional_updates": mysqlBool,
+	variable.TiDBSkipConstraintCheck:          tidbBool,
+	variable.TiDBBatchDMLDryRun:               tidbBool,
+	variable.TiDBOptAggPushDown:               tidbBool,
+	variable.TiDBOptInSubqUnFolding:           tidbBool,
+	variable.TiDBEnableParallelApply:          tidbBool,
+	variable.TiDBEnableIndexAdvisor:           tidbBool,
+	variable.TiDBSkipDDLWait:                  tidbBool,
+	variable.TiDBSkipUTF8Check:                tidbBool,
+	variable.TiDBEnableStreaming:              tidbBool,
+	variable.TiDBSlowLogNormalize:             tidbBool,
+	variable.TiDBEnableStmtSummary:            tidbBool,
+	variable.TiDBTTLJobEnable:                 tidbBool,
+}
+
+// intSysVars is the integer system variables with their min values.
+var intSysVars = map[string]int64{
+	variable.MaxAllowedPacket:               0,
+	variable.GroupConcatMaxLen:              0,
+	variable.CTEMaxRecursionDepth:           0,
+	variable.InnodbLockWaitTimeout:          0,
+	variable.TiDBDMLBatchSize:               0,
+	variable.TiDBMemQuotaQuery:              0,
+	variable.TiDBTmpTableMaxSize:            0,
+	variable.TiDBSlowLogThreshold:           0,
+	variable.TiDBTTLDeleteBatchSize:         0,
+	variable.TiDBBuildStatsConcurrency:      1,
+	variable.TiDBDistSQLScanConcurrency:     1,
+	variable.TiDBIndexLookupSize:            1,
+	variable.TiDBIndexLookupConcurrency:     1,
+	variable.TiDBIndexSerialScanConcurrency: 1,
+	variable.TiDBHashJoinConcurrency:        1,
+	variable.TiDBHashAggConcurrency:         1,
+	variable.TiDBProjectionConcurrency:      1,
+	variable.TiDBIndexJoinBatchSize:         1,
+	variable.TiDBMaxSortRowsInMemory:        1,
+	variable.TiDBMaxHashRowsInMemory:        1,
+	variable.TiDBApplyConcurrency:           1,
+	"max_connections":                       1,
+	"max_user_connections":                  0,
+}
+
+var replicaReadTypes = map[string]kv.ReplicaReadType{
+	variable.ReplicaReadLeader:          kv.ReplicaReadLeader,
+	variable.ReplicaReadFollower:        kv.ReplicaReadFollower,
+	variable.ReplicaReadLeaderPreferred: kv.ReplicaReadLeaderPreferred,
+	variable.ReplicaReadClosest:         kv.ReplicaReadClosest,
+	variable.ReplicaReadRoundRobin:      kv.ReplicaReadRoundRobin,
+}
+
+// GetReplicaRead gets the type of the replicas which serve the reads of the session, the reads are served by the
+// leaders if the store doesn't support the follower read.
+func GetReplicaRead(vars *variable.SessionVars, store kv.Storage) kv.ReplicaReadType {
+	typ := replicaReadTypes[vars.ReplicaRead]
+	if typ.IsFollowerRead() && !store.SupportCapability(kv.CapFollowerRead) {
+		return kv.ReplicaReadLeader
+	}
+	return typ
+}
+
 // For all tidb session variable options, we use "ON"/1 to turn on the options.
 func tidbOptOn(opt string) bool {
 	return strings.EqualFold(opt, "ON") || opt == "1"
@@ -150,26 +414,47 @@ func tidbOptPositiveInt(opt string, defaultVal int) int {
 	return val
 }
 
-func parseTimeZone(s string) *time.Location {
-	if s == "SYSTEM" {
-		// TODO: Support global time_zone variable, it should be set to global time_zone value.
-		return time.Local
+func tidbOptInt64(opt string, defaultVal int64) int64 {
+	val, err := strconv.ParseInt(opt, 10, 64)
+	if err != nil {
+		return defaultVal
 	}
+	return val
+}
 
-	loc, err := time.LoadLocation(s)
-	if err == nil {
-		return loc
+// parseTimeZone parses the value of time_zone, it returns nil if the value is invalid.
+// SYSTEM means the system time zone, in which the TIMESTAMP values are encoded.
+func parseTimeZone(s string) *time.Location {
+	if strings.EqualFold(s, "SYSTEM") {
+		return time.Local
 	}
 
-	// The value can be given as a string indicating an offset from UTC, such as '+10:00' or '-6:00'.
+	// The value can be given as a string indicating an offset from UTC, such as '+10:00' or '-6:00',
+	// the offset ranges from '-12:59' to '+13:00' as MySQL.
 	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
 		d, err := types.ParseDuration(s[1:], 0)
-		if err == nil {
-			return time.FixedZone("UTC", int(d.Duration/time.Second))
+		if err != nil || strings.Count(s, ":") != 1 {
+			return nil
+		}
+		offset := d.Duration
+		if s[0] == '-' {
+			offset = -offset
 		}
+		if offset > 13*time.Hour || offset <= -13*time.Hour {
+			return nil
+		}
+		return time.FixedZone("UTC", int(offset/time.Second))
 	}
 
-	return nil
+	// LoadLocation takes "" as UTC and "Local" as the system time zone, they are not valid values.
+	if s == "" || s == "Local" {
+		return nil
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return nil
+	}
+	return loc
 }
 
 func setSnapshotTS(s *variable.SessionVars, sVal string) error {
@@ -181,9 +466,17 @@ func setSnapshotTS(s *variable.SessionVars, sVal string) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
-	// TODO: Consider time_zone variable.
-	t1, err := t.Time.GoTime(time.Local)
-	ts := (t1.UnixNano() / int64(time.Millisecond)) << epochShiftBits
-	s.SnapshotTS = uint64(ts)
+	loc := s.TimeZone
+	if loc == nil {
+		loc = time.Local
+	}
+	t1, err := t.Time.GoTime(loc)
+	s.SnapshotTS = GoTimeToTS(t1)
 	return errors.Trace(err)
 }
+
+// GoTimeToTS converts a time to the timestamp of the store, the logical part of the timestamp is 0.
+func GoTimeToTS(t time.Time) uint64 {
+	ts := (t.UnixNano() / int64(time.Millisecond)) << epochShiftBits
+	return uint64(ts)
+}
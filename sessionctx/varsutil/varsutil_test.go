@@ -18,8 +18,11 @@ import (
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -102,6 +105,15 @@ func (s *testVarsutilSuite) TestVarsutil(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(val, Equals, "1")
 
+	c.Assert(v.DMLBatchSize, Equals, 0)
+	SetSessionSystemVar(v, variable.TiDBDMLBatchSize, types.NewStringDatum("100"))
+	c.Assert(v.DMLBatchSize, Equals, 100)
+	SetSessionSystemVar(v, variable.TiDBDMLBatchSize, types.NewStringDatum("-1"))
+	c.Assert(v.DMLBatchSize, Equals, 0)
+	c.Assert(v.BatchDMLDryRun, IsFalse)
+	SetSessionSystemVar(v, variable.TiDBBatchDMLDryRun, types.NewStringDatum("1"))
+	c.Assert(v.BatchDMLDryRun, IsTrue)
+
 	// Test case for get TiDBSkipDDLWait session variable.
 	val, err = GetSessionSystemVar(v, variable.TiDBSkipDDLWait)
 	c.Assert(val, Equals, "0")
@@ -127,6 +139,11 @@ func (s *testVarsutilSuite) TestVarsutil(c *C) {
 	c.Assert(t2.Sub(t1), Equals, 10*time.Hour)
 	SetSessionSystemVar(v, variable.TimeZone, types.NewStringDatum("-6:00"))
 	c.Assert(v.TimeZone.String(), Equals, "UTC")
+	t1 = time.Date(2000, 1, 1, 0, 0, 0, 0, v.TimeZone)
+	c.Assert(t1.Sub(t2), Equals, 6*time.Hour)
+	err = SetSessionSystemVar(v, variable.TimeZone, types.NewStringDatum("Asia/Xxx"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrUnknownTimeZone), IsTrue)
+	c.Assert(v.TimeZone.String(), Equals, "UTC")
 
 	// Test case for sql mode.
 	for str, mode := range mysql.Str2SQLMode {
@@ -142,6 +159,182 @@ func (s *testVarsutilSuite) TestVarsutil(c *C) {
 	c.Assert(v.IndexSerialScanConcurrency, Equals, 1)
 	SetSessionSystemVar(v, variable.TiDBIndexSerialScanConcurrency, types.NewStringDatum("4"))
 	c.Assert(v.IndexSerialScanConcurrency, Equals, 4)
+
+	// Test case for tidb_hash_join_concurrency.
+	c.Assert(v.HashJoinConcurrency, Equals, variable.DefHashJoinConcurrency)
+	SetSessionSystemVar(v, variable.TiDBHashJoinConcurrency, types.NewStringDatum("8"))
+	c.Assert(v.HashJoinConcurrency, Equals, 8)
+	SetSessionSystemVar(v, variable.TiDBHashJoinConcurrency, types.NewStringDatum("0"))
+	c.Assert(v.HashJoinConcurrency, Equals, variable.DefHashJoinConcurrency)
+
+	// Test case for tidb_hash_agg_concurrency.
+	c.Assert(v.HashAggConcurrency, Equals, variable.DefHashAggConcurrency)
+	SetSessionSystemVar(v, variable.TiDBHashAggConcurrency, types.NewStringDatum("1"))
+	c.Assert(v.HashAggConcurrency, Equals, 1)
+	SetSessionSystemVar(v, variable.TiDBHashAggConcurrency, types.NewStringDatum("-1"))
+	c.Assert(v.HashAggConcurrency, Equals, variable.DefHashAggConcurrency)
+
+	// Test case for tidb_projection_concurrency.
+	c.Assert(v.ProjectionConcurrency, Equals, variable.DefProjectionConcurrency)
+	SetSessionSystemVar(v, variable.TiDBProjectionConcurrency, types.NewStringDatum("1"))
+	c.Assert(v.ProjectionConcurrency, Equals, 1)
+	SetSessionSystemVar(v, variable.TiDBProjectionConcurrency, types.NewStringDatum("-1"))
+	c.Assert(v.ProjectionConcurrency, Equals, variable.DefProjectionConcurrency)
+
+	c.Assert(v.IndexJoinBatchSize, Equals, variable.DefIndexJoinBatchSize)
+	SetSessionSystemVar(v, variable.TiDBIndexJoinBatchSize, types.NewStringDatum("100"))
+	c.Assert(v.IndexJoinBatchSize, Equals, 100)
+
+	c.Assert(v.MaxSortRowsInMemory, Equals, variable.DefMaxSortRowsInMemory)
+	SetSessionSystemVar(v, variable.TiDBMaxSortRowsInMemory, types.NewStringDatum("10"))
+	c.Assert(v.MaxSortRowsInMemory, Equals, 10)
+
+	c.Assert(v.MaxHashRowsInMemory, Equals, variable.DefMaxHashRowsInMemory)
+	SetSessionSystemVar(v, variable.TiDBMaxHashRowsInMemory, types.NewStringDatum("10"))
+	c.Assert(v.MaxHashRowsInMemory, Equals, 10)
+
+	c.Assert(v.EnableParallelApply, IsFalse)
+	SetSessionSystemVar(v, variable.TiDBEnableParallelApply, types.NewStringDatum("1"))
+	c.Assert(v.EnableParallelApply, IsTrue)
+
+	c.Assert(v.ApplyConcurrency, Equals, variable.DefApplyConcurrency)
+	SetSessionSystemVar(v, variable.TiDBApplyConcurrency, types.NewStringDatum("2"))
+	c.Assert(v.ApplyConcurrency, Equals, 2)
+	SetSessionSystemVar(v, variable.TiDBApplyConcurrency, types.NewStringDatum("-1"))
+	c.Assert(v.ApplyConcurrency, Equals, variable.DefApplyConcurrency)
+
+	c.Assert(v.EnableIndexAdvisor, IsFalse)
+	SetSessionSystemVar(v, variable.TiDBEnableIndexAdvisor, types.NewStringDatum("1"))
+	c.Assert(v.EnableIndexAdvisor, IsTrue)
+
+	c.Assert(v.MemQuotaQuery, Equals, int64(variable.DefMemQuotaQuery))
+	SetSessionSystemVar(v, variable.TiDBMemQuotaQuery, types.NewStringDatum("1024"))
+	c.Assert(v.MemQuotaQuery, Equals, int64(1024))
+	c.Assert(v.MemOOMAction, Equals, memory.ActionCancel)
+	err = SetSessionSystemVar(v, variable.TiDBMemOOMAction, types.NewStringDatum("SPILL"))
+	c.Assert(err, IsNil)
+	c.Assert(v.MemOOMAction, Equals, memory.ActionSpill)
+	val, err = GetSessionSystemVar(v, variable.TiDBMemOOMAction)
+	c.Assert(err, IsNil)
+	c.Assert(val, Equals, "spill")
+	err = SetSessionSystemVar(v, variable.TiDBMemOOMAction, types.NewStringDatum("abort"))
+	c.Assert(err, NotNil)
+	c.Assert(v.MemOOMAction, Equals, memory.ActionSpill)
+
+	c.Assert(v.TmpTableMaxSize, Equals, int64(variable.DefTmpTableMaxSize))
+	SetSessionSystemVar(v, variable.TiDBTmpTableMaxSize, types.NewStringDatum("4096"))
+	c.Assert(v.TmpTableMaxSize, Equals, int64(4096))
+
+	c.Assert(v.CTEMaxRecursionDepth, Equals, int64(variable.DefCTEMaxRecursionDepth))
+	SetSessionSystemVar(v, variable.CTEMaxRecursionDepth, types.NewStringDatum("10"))
+	c.Assert(v.CTEMaxRecursionDepth, Equals, int64(10))
+
+	c.Assert(v.SlowLogThreshold, Equals, 300*time.Millisecond)
+	SetSessionSystemVar(v, variable.TiDBSlowLogThreshold, types.NewStringDatum("0"))
+	c.Assert(v.SlowLogThreshold, Equals, time.Duration(0))
+	c.Assert(v.SlowLogNormalize, IsFalse)
+	SetSessionSystemVar(v, variable.TiDBSlowLogNormalize, types.NewStringDatum("ON"))
+	c.Assert(v.SlowLogNormalize, IsTrue)
+}
+
+func (s *testVarsutilSuite) TestGetReplicaRead(c *C) {
+	defer testleak.AfterTest(c)()
+	v := variable.NewSessionVars()
+	v.GlobalVarsAccessor = newMockGlobalAccessor()
+	store := &mockStore{followerRead: true}
+	c.Assert(GetReplicaRead(v, store), Equals, kv.ReplicaReadLeader)
+	c.Assert(SetSessionSystemVar(v, variable.TiDBReplicaRead, types.NewStringDatum("FOLLOWER")), IsNil)
+	c.Assert(GetReplicaRead(v, store), Equals, kv.ReplicaReadFollower)
+	c.Assert(SetSessionSystemVar(v, variable.TiDBReplicaRead, types.NewStringDatum("Closest")), IsNil)
+	c.Assert(GetReplicaRead(v, store), Equals, kv.ReplicaReadClosest)
+	c.Assert(SetSessionSystemVar(v, variable.TiDBReplicaRead, types.NewStringDatum("leader-preferred")), IsNil)
+	c.Assert(GetReplicaRead(v, store), Equals, kv.ReplicaReadLeaderPreferred)
+	c.Assert(SetSessionSystemVar(v, variable.TiDBReplicaRead, types.NewStringDatum("round-robin")), IsNil)
+	c.Assert(GetReplicaRead(v, store), Equals, kv.ReplicaReadRoundRobin)
+	c.Assert(SetSessionSystemVar(v, variable.TiDBReplicaRead, types.NewStringDatum("nearest")), NotNil)
+	c.Assert(v.ReplicaRead, Equals, variable.ReplicaReadRoundRobin)
+	// The leaders serve the reads if the store doesn't support the follower read.
+	store.followerRead = false
+	c.Assert(GetReplicaRead(v, store), Equals, kv.ReplicaReadLeader)
+}
+
+func (s *testVarsutilSuite) TestValidateSetSystemVar(c *C) {
+	defer testleak.AfterTest(c)()
+	variable.EnablePessimisticTxn = true
+	defer func() {
+		variable.EnablePessimisticTxn = false
+	}()
+	tbl := []struct {
+		name  string
+		value string
+		res   string
+		err   *terror.Error
+	}{
+		{variable.AutocommitVar, "on", "ON", nil},
+		{variable.AutocommitVar, "False", "OFF", nil},
+		{variable.AutocommitVar, "1", "1", nil},
+		{variable.AutocommitVar, "2", "", variable.ErrWrongValueForVar},
+		{variable.TiDBSkipUTF8Check, "ON", "1", nil},
+		{variable.TiDBSkipUTF8Check, "false", "0", nil},
+		{variable.TiDBSkipUTF8Check, "yes", "", variable.ErrWrongValueForVar},
+		{variable.TiDBIndexLookupSize, "10", "10", nil},
+		{variable.TiDBIndexLookupSize, "0", "", variable.ErrWrongValueForVar},
+		{variable.TiDBIndexLookupSize, "a", "", variable.ErrWrongTypeForVar},
+		{variable.TiDBIndexLookupSize, "1.5", "", variable.ErrWrongTypeForVar},
+		{variable.TiDBDMLBatchSize, "0", "0", nil},
+		{variable.TiDBDMLBatchSize, "-1", "", variable.ErrWrongValueForVar},
+		{"max_connections", "0", "", variable.ErrWrongValueForVar},
+		{"max_user_connections", "0", "0", nil},
+		{variable.SQLModeVar, "strict_trans_tables,ansi_quotes", "STRICT_TRANS_TABLES,ANSI_QUOTES", nil},
+		{variable.SQLModeVar, "", "", nil},
+		{variable.SQLModeVar, "STRICT_TRANS_TABLES,XXX", "", variable.ErrWrongValueForVar},
+		{variable.TxIsolation, "read-committed", "READ-COMMITTED", nil},
+		{variable.TxIsolation, "READ COMMITTED", "", variable.ErrWrongValueForVar},
+		{"character_set_client", "UTF8MB4", "utf8mb4", nil},
+		{"character_set_client", "gbk", "", variable.ErrWrongValueForVar},
+		{variable.CollationConnection, "UTF8_BIN", "utf8_bin", nil},
+		{variable.CollationConnection, "utf8_xxx", "", variable.ErrWrongValueForVar},
+		{variable.TiDBTxnMode, "Pessimistic", variable.TxnModePessimistic, nil},
+		{variable.TiDBTxnMode, "unknown", "", variable.ErrWrongValueForVar},
+		{variable.TiDBReplicaRead, "FOLLOWER", variable.ReplicaReadFollower, nil},
+		{variable.TiDBReplicaRead, "learner", "", variable.ErrWrongValueForVar},
+		{variable.TiDBMemOOMAction, "CANCEL", "cancel", nil},
+		{variable.TiDBMemOOMAction, "xxx", "", variable.ErrWrongValueForVar},
+		{variable.TimeZone, "SYSTEM", "SYSTEM", nil},
+		{variable.TimeZone, "Asia/Shanghai", "Asia/Shanghai", nil},
+		{variable.TimeZone, "+13:00", "+13:00", nil},
+		{variable.TimeZone, "-12:59", "-12:59", nil},
+		{variable.TimeZone, "+13:01", "", variable.ErrUnknownTimeZone},
+		{variable.TimeZone, "-13:00", "", variable.ErrUnknownTimeZone},
+		{variable.TimeZone, "Asia/Xxx", "", variable.ErrUnknownTimeZone},
+		{variable.TimeZone, "", "", variable.ErrUnknownTimeZone},
+		// The values of the other variables are not checked.
+		{"low_priority_updates", "ON", "ON", nil},
+		{"date_format", "%Y", "%Y", nil},
+	}
+	for _, t := range tbl {
+		res, err := ValidateSetSystemVar(t.name, t.value)
+		if t.err != nil {
+			c.Assert(terror.ErrorEqual(err, t.err), IsTrue, Commentf("%s = %s, err %v", t.name, t.value, err))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("%s = %s", t.name, t.value))
+		c.Assert(res, Equals, t.res, Commentf("%s = %s", t.name, t.value))
+	}
+
+	// The pessimistic mode is rejected if it's not enabled.
+	variable.EnablePessimisticTxn = false
+	_, err := ValidateSetSystemVar(variable.TiDBTxnMode, variable.TxnModePessimistic)
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue, Commentf("err %v", err))
+}
+
+type mockStore struct {
+	kv.Storage
+	followerRead bool
+}
+
+func (m *mockStore) SupportCapability(capability kv.Capability) bool {
+	return capability == kv.CapFollowerRead && m.followerRead
 }
 
 type mockGlobalAccessor struct {
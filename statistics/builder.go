@@ -166,6 +166,7 @@ func (t *Table) build4SortedColumn(sc *variable.StatementContext, offset int, re
 		Numbers: make([]int64, 1, bucketCount),
 		Values:  make([]types.Datum, 1, bucketCount),
 		Repeats: make([]int64, 1, bucketCount),
+		isIndex: !isPK,
 	}
 	var valuesPerBucket, lastNumber, bucketIdx int64 = 1, 0, 0
 	count := int64(0)
@@ -265,7 +266,9 @@ func (t *Table) buildColumn(sc *variable.StatementContext, offset int, samples [
 	bucketIdx := 0
 	var lastNumber int64
 	for i := int64(0); i < int64(len(samples)); i++ {
-		cmp, err := col.Values[bucketIdx].CompareDatum(sc, samples[i])
+		// Long strings are stored by their prefixes, strings sharing a prefix are counted as repeats.
+		sample := truncateToPrefix(samples[i])
+		cmp, err := col.Values[bucketIdx].CompareDatum(sc, sample)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -278,14 +281,14 @@ func (t *Table) buildColumn(sc *variable.StatementContext, offset int, samples [
 		} else if i*sampleFactor-lastNumber <= valuesPerBucket {
 			// The bucket still have room to store a new item, update the bucket.
 			col.Numbers[bucketIdx] = i * sampleFactor
-			col.Values[bucketIdx] = samples[i]
+			col.Values[bucketIdx] = sample
 			col.Repeats[bucketIdx] = 0
 		} else {
 			// The bucket is full, store the item in the next bucket.
 			lastNumber = col.Numbers[bucketIdx]
 			bucketIdx++
 			col.Numbers = append(col.Numbers, i*sampleFactor)
-			col.Values = append(col.Values, samples[i])
+			col.Values = append(col.Values, sample)
 			col.Repeats = append(col.Repeats, 0)
 		}
 	}
@@ -339,7 +342,7 @@ func copyFromIndexColumns(ind *Column, id, numBuckets int64) (*Column, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		col.Values = append(col.Values, data[0])
+		col.Values = append(col.Values, truncateToPrefix(data[0]))
 	}
 	return col, nil
 }
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
@@ -44,6 +45,42 @@ type Column struct {
 	Numbers []int64
 	Values  []types.Datum
 	Repeats []int64
+
+	// isIndex is true for the histogram of an index, its values are the encoded index keys, they are not cut
+	// to their prefixes.
+	isIndex bool
+}
+
+// maxPrefixLength is the max length in bytes of a string value stored in a column histogram.
+// Longer strings are cut to their prefix when the histogram is built, so a bucket value stands for
+// every string sharing that prefix. Values compared against the histogram are cut in the same way
+// before searching: a string whose prefix equals a bucket value is treated as equal to it, and the
+// order between strings with different prefixes is the order of their prefixes. Because cutting is
+// monotonic, range estimations stay consistent, they only lose precision inside one prefix.
+const maxPrefixLength = 64
+
+// truncateToPrefix cuts a string or bytes datum to at most maxPrefixLength bytes. A string is cut at a rune
+// boundary, so it's still valid UTF-8.
+func truncateToPrefix(d types.Datum) types.Datum {
+	switch d.Kind() {
+	case types.KindString, types.KindBytes:
+		b := d.GetBytes()
+		if len(b) <= maxPrefixLength {
+			return d
+		}
+		var ret types.Datum
+		if d.Kind() == types.KindString {
+			n := maxPrefixLength
+			for n > 0 && !utf8.RuneStart(b[n]) {
+				n--
+			}
+			ret.SetString(string(b[:n]))
+		} else {
+			ret.SetBytes(b[:maxPrefixLength])
+		}
+		return ret
+	}
+	return d
 }
 
 func (c *Column) saveToStorage(ctx context.Context, tableID int64, isIndex int) error {
@@ -88,6 +125,7 @@ func colStatsFromStorage(ctx context.Context, tableID int64, colID int64, tp *ty
 		Numbers: make([]int64, bucketSize),
 		Repeats: make([]int64, bucketSize),
 		Values:  make([]types.Datum, bucketSize),
+		isIndex: isIndex == 1,
 	}
 	for i := 0; i < bucketSize; i++ {
 		bucketID := rows[i].Data[0].GetInt64()
@@ -221,6 +259,9 @@ func (c *Column) inBucketBetweenCount() int64 {
 }
 
 func (c *Column) search(sc *variable.StatementContext, target types.Datum) (index int, match bool, err error) {
+	if !c.isIndex {
+		target = truncateToPrefix(target)
+	}
 	index = sort.Search(len(c.Values), func(i int) bool {
 		cmp, err1 := c.Values[i].CompareDatum(sc, target)
 		if err1 != nil {
@@ -0,0 +1,137 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// JSONTable is used for dumping statistics.
+type JSONTable struct {
+	DatabaseName string                 `json:"database_name"`
+	TableName    string                 `json:"table_name"`
+	Count        int64                  `json:"count"`
+	Columns      map[string]*JSONColumn `json:"columns"`
+	Indices      map[string]*JSONColumn `json:"indices"`
+}
+
+// JSONColumn is used for dumping the histogram of a column or an index.
+// Bucket values are stored in the codec value format, so they keep their types.
+type JSONColumn struct {
+	NDV     int64    `json:"ndv"`
+	Numbers []int64  `json:"numbers"`
+	Values  [][]byte `json:"values"`
+	Repeats []int64  `json:"repeats"`
+}
+
+func dumpColumn(col *Column) (*JSONColumn, error) {
+	jsonCol := &JSONColumn{
+		NDV:     col.NDV,
+		Numbers: col.Numbers,
+		Values:  make([][]byte, 0, len(col.Values)),
+		Repeats: col.Repeats,
+	}
+	for _, val := range col.Values {
+		b, err := codec.EncodeValue(nil, val)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		jsonCol.Values = append(jsonCol.Values, b)
+	}
+	return jsonCol, nil
+}
+
+func loadColumn(id int64, jsonCol *JSONColumn, isIndex bool) (*Column, error) {
+	if len(jsonCol.Numbers) != len(jsonCol.Values) || len(jsonCol.Numbers) != len(jsonCol.Repeats) {
+		return nil, errors.Errorf("the histogram of %d has mismatched bucket count", id)
+	}
+	col := &Column{
+		ID:      id,
+		NDV:     jsonCol.NDV,
+		Numbers: jsonCol.Numbers,
+		Values:  make([]types.Datum, 0, len(jsonCol.Values)),
+		Repeats: jsonCol.Repeats,
+		isIndex: isIndex,
+	}
+	for _, b := range jsonCol.Values {
+		_, val, err := codec.DecodeOne(b)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		col.Values = append(col.Values, val)
+	}
+	return col, nil
+}
+
+// DumpStatsToJSON dumps statistic to json.
+func DumpStatsToJSON(dbName string, t *Table) (*JSONTable, error) {
+	jsonTbl := &JSONTable{
+		DatabaseName: dbName,
+		TableName:    t.Info.Name.L,
+		Count:        t.Count,
+		Columns:      make(map[string]*JSONColumn, len(t.Columns)),
+		Indices:      make(map[string]*JSONColumn, len(t.Indices)),
+	}
+	for i, col := range t.Columns {
+		jsonCol, err := dumpColumn(col)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		jsonTbl.Columns[t.Info.Columns[i].Name.L] = jsonCol
+	}
+	for i, idx := range t.Indices {
+		jsonIdx, err := dumpColumn(idx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		jsonTbl.Indices[t.Info.Indices[i].Name.L] = jsonIdx
+	}
+	return jsonTbl, nil
+}
+
+// TableStatsFromJSON loads statistic from a JSONTable and returns the Table of statistic.
+// Columns and indices are matched by name, so the statistics can be loaded into a table with different IDs.
+func TableStatsFromJSON(tableInfo *model.TableInfo, jsonTbl *JSONTable) (*Table, error) {
+	t := &Table{
+		Info:    tableInfo,
+		Count:   jsonTbl.Count,
+		Columns: make([]*Column, len(tableInfo.Columns)),
+		Indices: make([]*Column, len(tableInfo.Indices)),
+	}
+	var err error
+	for i, colInfo := range tableInfo.Columns {
+		jsonCol, ok := jsonTbl.Columns[colInfo.Name.L]
+		if !ok {
+			return nil, errors.Errorf("cannot find the statistics of column %s", colInfo.Name)
+		}
+		t.Columns[i], err = loadColumn(colInfo.ID, jsonCol, false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for i, idxInfo := range tableInfo.Indices {
+		jsonIdx, ok := jsonTbl.Indices[idxInfo.Name.L]
+		if !ok {
+			return nil, errors.Errorf("cannot find the statistics of index %s", idxInfo.Name)
+		}
+		t.Indices[i], err = loadColumn(idxInfo.ID, jsonIdx, true)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return t, nil
+}
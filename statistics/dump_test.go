@@ -0,0 +1,77 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/statistics"
+	"github.com/pingcap/tidb/util/testkit"
+)
+
+func (s *testStatsCacheSuite) TestDumpAndLoadStats(c *C) {
+	store, do, err := newStoreWithBootstrap()
+	c.Assert(err, IsNil)
+	defer store.Close()
+	testKit := testkit.NewTestKit(c, store)
+	testKit.MustExec("use test")
+	testKit.MustExec("create table t (c1 int, c2 varchar(20), index idx_c2(c2))")
+	for i := 0; i < 100; i++ {
+		testKit.MustExec("insert into t values (?, ?)", i, fmt.Sprintf("str%d", i))
+	}
+	testKit.MustExec("analyze table t")
+	is := do.InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("t"))
+	c.Assert(err, IsNil)
+	tableInfo := tbl.Meta()
+	statsTbl := statistics.GetStatisticsTableCache(tableInfo)
+
+	jsonTbl, err := statistics.DumpStatsToJSON("test", statsTbl)
+	c.Assert(err, IsNil)
+	js, err := json.Marshal(jsonTbl)
+	c.Assert(err, IsNil)
+	f, err := ioutil.TempFile("", "stats")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	_, err = f.Write(js)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	do.StatsHandle().Clear()
+	c.Assert(statistics.GetStatisticsTableCache(tableInfo).Pseudo, IsTrue)
+	testKit.MustExec(fmt.Sprintf("load stats '%s'", f.Name()))
+	loadTbl := statistics.GetStatisticsTableCache(tableInfo)
+	c.Assert(loadTbl.Pseudo, IsFalse)
+	c.Assert(loadTbl.Count, Equals, statsTbl.Count)
+	compareTwoColumnsStatsSlice(statsTbl.Columns, loadTbl.Columns, c)
+	compareTwoColumnsStatsSlice(statsTbl.Indices, loadTbl.Indices, c)
+
+	// The loaded statistics are persisted and can be read back by the handle.
+	do.StatsHandle().Clear()
+	do.StatsHandle().Update(is)
+	c.Assert(statistics.GetStatisticsTableCache(tableInfo).Pseudo, IsFalse)
+
+	// Loading statistics into a table that doesn't match the dump fails.
+	jsonTbl.Columns = nil
+	js, err = json.Marshal(jsonTbl)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(f.Name(), js, 0644), IsNil)
+	_, err = testKit.Exec(fmt.Sprintf("load stats '%s'", f.Name()))
+	c.Assert(err, NotNil)
+}
@@ -14,13 +14,17 @@
 package statistics
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -233,3 +237,113 @@ func (s *testStatisticsSuite) TestPseudoTable(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(count, Equals, int64(250000))
 }
+
+func (s *testStatisticsSuite) TestLongStringPrefix(c *C) {
+	tblInfo := &model.TableInfo{ID: 1}
+	tblInfo.Columns = []*model.ColumnInfo{
+		{
+			ID:        1,
+			Name:      model.NewCIStr("a"),
+			FieldType: *types.NewFieldType(mysql.TypeBlob),
+		},
+	}
+	padding := strings.Repeat("x", maxPrefixLength)
+	samples := make([]types.Datum, 1000)
+	for i := range samples {
+		// Every ten samples share the same prefix and differ only after maxPrefixLength bytes.
+		samples[i].SetString(fmt.Sprintf("%03d%s%d", i/10, padding, i%10))
+	}
+	builder := &Builder{
+		Ctx:           mock.NewContext(),
+		TblInfo:       tblInfo,
+		Count:         1000,
+		NumBuckets:    256,
+		ColumnSamples: [][]types.Datum{samples},
+		ColOffsets:    []int{0},
+		PkOffset:      -1,
+	}
+	sc := builder.Ctx.GetSessionVars().StmtCtx
+	t, err := builder.NewTable()
+	c.Assert(err, IsNil)
+	col := t.Columns[0]
+	for _, val := range col.Values {
+		c.Assert(len(val.GetBytes()), LessEqual, maxPrefixLength)
+	}
+	count, err := col.EqualRowCount(sc, types.NewStringDatum(fmt.Sprintf("%03d%s", 50, padding)))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(10))
+	count, err = col.LessRowCount(sc, types.NewStringDatum("050"))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(499))
+	count, err = col.BetweenRowCount(sc, types.NewStringDatum("010"), types.NewStringDatum("020"))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(100))
+
+	// The values of the index histogram are the encoded keys, they are kept whole.
+	tblInfo.Indices = []*model.IndexInfo{
+		{
+			ID:      1,
+			Columns: []*model.IndexColumn{{Name: model.NewCIStr("a"), Length: types.UnspecifiedLength}},
+		},
+	}
+	builder.ColumnSamples, builder.ColOffsets = nil, nil
+	builder.IdxRecords = []ast.RecordSet{&recordSet{data: samples, count: int64(len(samples))}}
+	builder.IdxOffsets = []int{0}
+	t, err = builder.NewTable()
+	c.Assert(err, IsNil)
+	idx := t.Indices[0]
+	key, err := codec.EncodeKey(nil, samples[505])
+	c.Assert(err, IsNil)
+	count, err = idx.EqualRowCount(sc, types.NewBytesDatum(key))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(1))
+	count, err = idx.LessRowCount(sc, types.NewBytesDatum(key))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(506))
+}
+
+func (s *testStatisticsSuite) TestMultiByteStringPrefix(c *C) {
+	// A 3 bytes rune starts at maxPrefixLength-1, the string is cut before it.
+	d := truncateToPrefix(types.NewStringDatum(strings.Repeat("x", maxPrefixLength-1) + "中文"))
+	c.Assert(d.GetString(), Equals, strings.Repeat("x", maxPrefixLength-1))
+	// The bytes are cut at maxPrefixLength anyway.
+	d = truncateToPrefix(types.NewBytesDatum([]byte(strings.Repeat("x", maxPrefixLength-1) + "中文")))
+	c.Assert(len(d.GetBytes()), Equals, maxPrefixLength)
+
+	tblInfo := &model.TableInfo{ID: 1}
+	tblInfo.Columns = []*model.ColumnInfo{
+		{
+			ID:        1,
+			Name:      model.NewCIStr("a"),
+			FieldType: *types.NewFieldType(mysql.TypeVarchar),
+		},
+	}
+	padding := strings.Repeat("中", maxPrefixLength/3)
+	samples := make([]types.Datum, 1000)
+	for i := range samples {
+		samples[i].SetString(fmt.Sprintf("%03d%s%d", i/10, padding, i%10))
+	}
+	builder := &Builder{
+		Ctx:           mock.NewContext(),
+		TblInfo:       tblInfo,
+		Count:         1000,
+		NumBuckets:    256,
+		ColumnSamples: [][]types.Datum{samples},
+		ColOffsets:    []int{0},
+		PkOffset:      -1,
+	}
+	sc := builder.Ctx.GetSessionVars().StmtCtx
+	t, err := builder.NewTable()
+	c.Assert(err, IsNil)
+	col := t.Columns[0]
+	for _, val := range col.Values {
+		c.Assert(len(val.GetBytes()), LessEqual, maxPrefixLength)
+		c.Assert(utf8.ValidString(val.GetString()), IsTrue)
+	}
+	count, err := col.EqualRowCount(sc, types.NewStringDatum(fmt.Sprintf("%03d%s", 50, padding)))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(10))
+	count, err = col.LessRowCount(sc, types.NewStringDatum("050"))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(499))
+}
@@ -39,6 +39,9 @@ var (
 
 type db struct {
 	*leveldb.DB
+	// wo is the write options of the commits, the commits of the database on disk are synced, so the committed
+	// transactions are not lost when the machine crashes.
+	wo *opt.WriteOptions
 }
 
 func (d *db) Get(key []byte) ([]byte, error) {
@@ -84,7 +87,7 @@ func (d *db) Commit(b engine.Batch) error {
 	if !ok {
 		return errors.Errorf("invalid batch type %T", b)
 	}
-	err := d.DB.Write(batch, nil)
+	err := d.DB.Write(batch, d.wo)
 	batch.Reset()
 	p.Put(batch)
 	return err
@@ -102,7 +105,7 @@ type Driver struct {
 func (driver Driver) Open(path string) (engine.DB, error) {
 	d, err := leveldb.OpenFile(path, &opt.Options{BlockCacheCapacity: 600 * 1024 * 1024})
 
-	return &db{d}, err
+	return &db{DB: d, wo: &opt.WriteOptions{Sync: true}}, err
 }
 
 // MemoryDriver implements engine Driver
@@ -112,5 +115,5 @@ type MemoryDriver struct {
 // Open opens a memory storage database.
 func (driver MemoryDriver) Open(path string) (engine.DB, error) {
 	d, err := leveldb.Open(storage.NewMemStorage(), nil)
-	return &db{d}, err
+	return &db{DB: d}, err
 }
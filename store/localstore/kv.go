@@ -350,6 +350,12 @@ func (s *dbStore) GetClient() kv.Client {
 	return &dbClient{store: s, regionInfo: s.pd.GetRegionInfo()}
 }
 
+// SupportCapability implements kv.Storage SupportCapability interface. The local store only has one replica and one
+// region, the requests are pushed down to the local coprocessor.
+func (s *dbStore) SupportCapability(c kv.Capability) bool {
+	return c == kv.CapCoprocessor
+}
+
 func (s *dbStore) CurrentVersion() (kv.Version, error) {
 	return globalVersionProvider.CurrentVersion()
 }
@@ -83,8 +83,7 @@ type aggItem struct {
 	// Number of rows, this could be used in cout/avg
 	count uint64
 	// This could be used to store sum/max/min/first
-	value types.Datum
-	// TODO: support group_concat
+	value  types.Datum
 	buffer *bytes.Buffer // Buffer is used for group_concat.
 	// It will check if the agg has met the first row key.
 	gotFirstRow bool
@@ -118,6 +117,8 @@ func (n *aggregateFuncExpr) update(ctx *selectContext, args []types.Datum) error
 		return n.updateMaxMin(ctx, args, true)
 	case tipb.ExprType_Min:
 		return n.updateMaxMin(ctx, args, false)
+	case tipb.ExprType_GroupConcat:
+		return n.updateConcat(ctx, args)
 	}
 	return errors.Errorf("Unknown AggExpr: %v", n.expr.GetTp())
 }
@@ -128,6 +129,8 @@ func (n *aggregateFuncExpr) toDatums(ctx *selectContext) (ds []types.Datum, err
 		ds = n.getCountDatum()
 	case tipb.ExprType_First, tipb.ExprType_Max, tipb.ExprType_Min:
 		ds = n.getValueDatum()
+	case tipb.ExprType_GroupConcat:
+		ds = n.getConcatDatum()
 	case tipb.ExprType_Sum:
 		d, err := getSumValue(ctx, n.getAggItem())
 		if err != nil {
@@ -172,6 +175,39 @@ func (n *aggregateFuncExpr) getValueDatum() []types.Datum {
 	return []types.Datum{item.value}
 }
 
+// Convert the concatenated values to datum list.
+func (n *aggregateFuncExpr) getConcatDatum() []types.Datum {
+	item := n.getAggItem()
+	if item.buffer == nil {
+		return []types.Datum{{}}
+	}
+	return []types.Datum{types.NewStringDatum(item.buffer.String())}
+}
+
+// updateConcat concatenates the values of the row and appends them to the partial result, the separator is in the
+// value of the expression.
+func (n *aggregateFuncExpr) updateConcat(ctx *selectContext, args []types.Datum) error {
+	var value []byte
+	for _, arg := range args {
+		if arg.IsNull() {
+			return nil
+		}
+		s, err := arg.ToString()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		value = append(value, s...)
+	}
+	aggItem := n.getAggItem()
+	if aggItem.buffer == nil {
+		aggItem.buffer = &bytes.Buffer{}
+	} else {
+		aggItem.buffer.Write(n.expr.Val)
+	}
+	aggItem.buffer.Write(value)
+	return nil
+}
+
 var singleGroupKey = []byte("SingleGroup")
 
 // getAggItem gets aggregate evaluation context for the current group.
@@ -1,6 +1,8 @@
 package localstore
 
 import (
+	"sync"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tipb/go-tipb"
@@ -12,15 +14,19 @@ type dbClient struct {
 	regionInfo []*regionInfo
 }
 
+// Send splits the request into a task for every region, and handles the tasks with req.Concurrency workers.
 func (c *dbClient) Send(ctx goctx.Context, req *kv.Request) kv.Response {
 	it := &response{
+		ctx:         ctx,
 		client:      c,
 		concurrency: req.Concurrency,
+		keepOrder:   req.KeepOrder,
+		finished:    make(chan struct{}),
 	}
 	it.tasks = buildRegionTasks(c, req)
 	if len(it.tasks) == 0 {
 		// Empty range doesn't produce any task.
-		it.finished = true
+		close(it.finished)
 		return it
 	}
 	if it.concurrency > len(it.tasks) {
@@ -29,8 +35,9 @@ func (c *dbClient) Send(ctx goctx.Context, req *kv.Request) kv.Response {
 		it.concurrency = 1
 	}
 	it.taskChan = make(chan *task, it.concurrency)
-	it.errChan = make(chan error, it.concurrency)
-	it.respChan = make(chan *regionResponse, it.concurrency)
+	if !it.keepOrder {
+		it.respChan = make(chan *regionResult, it.concurrency)
+	}
 	it.run()
 	return it
 }
@@ -70,7 +77,7 @@ func supportExpr(exprType tipb.ExprType) bool {
 		return true
 	// aggregate functions.
 	case tipb.ExprType_Count, tipb.ExprType_First, tipb.ExprType_Sum,
-		tipb.ExprType_Avg, tipb.ExprType_Max, tipb.ExprType_Min:
+		tipb.ExprType_Avg, tipb.ExprType_Max, tipb.ExprType_Min, tipb.ExprType_GroupConcat:
 		return true
 	// bitwise operators.
 	case tipb.ExprType_BitAnd, tipb.ExprType_BitOr, tipb.ExprType_BitXor, tipb.ExprType_BitNeg:
@@ -93,54 +100,74 @@ func (c *dbClient) updateRegionInfo() {
 }
 
 type response struct {
+	ctx         goctx.Context
 	client      *dbClient
-	reqSent     int
-	respGot     int
 	concurrency int
+	keepOrder   bool
 	tasks       []*task
-	responses   []*regionResponse
 	taskChan    chan *task
-	respChan    chan *regionResponse
-	errChan     chan error
-	finished    bool
+	finished    chan struct{}
+	wg          sync.WaitGroup
+
+	// If keepOrder, the results are stored in task.respChan, and read out task by task.
+	curr int
+	// Otherwise, the results are stored in respChan.
+	respChan chan *regionResult
 }
 
 type task struct {
-	request *regionRequest
-	region  *localRegion
+	request  *regionRequest
+	region   *localRegion
+	respChan chan *regionResult
+}
+
+type regionResult struct {
+	resp *regionResponse
+	err  error
 }
 
+// Next returns the data of the next region response, it returns nil when all the tasks are done or the response is
+// closed.
 func (it *response) Next() (resp []byte, err error) {
-	if it.finished {
-		return nil, nil
-	}
-	var regionResp *regionResponse
-	select {
-	case regionResp = <-it.respChan:
-	case err = <-it.errChan:
+	var result *regionResult
+	for result == nil {
+		select {
+		case <-it.finished:
+			return nil, nil
+		default:
+		}
+		ch := it.respChan
+		if it.keepOrder {
+			if it.curr >= len(it.tasks) {
+				return nil, nil
+			}
+			ch = it.tasks[it.curr].respChan
+		} else if ch == nil {
+			return nil, nil
+		}
+		var ok bool
+		select {
+		case result, ok = <-ch:
+		case <-it.finished:
+			return nil, nil
+		case <-it.ctx.Done():
+			return nil, errors.Trace(it.ctx.Err())
+		}
+		if !ok {
+			if !it.keepOrder {
+				return nil, nil
+			}
+			// Switch to the next task.
+			it.curr++
+		}
 	}
-	if err != nil {
-		it.Close()
-		return nil, errors.Trace(err)
+	if result.err != nil {
+		return nil, errors.Trace(result.err)
 	}
-	if len(regionResp.newStartKey) != 0 {
+	if len(result.resp.newStartKey) != 0 {
 		it.client.updateRegionInfo()
-		retryTasks := it.createRetryTasks(regionResp)
-		it.tasks = append(it.tasks, retryTasks...)
-	}
-	if it.reqSent < len(it.tasks) {
-		it.taskChan <- it.tasks[it.reqSent]
-		it.reqSent++
 	}
-	it.respGot++
-	if it.reqSent == len(it.tasks) && it.respGot == it.reqSent {
-		it.Close()
-	}
-	return regionResp.data, nil
-}
-
-func (it *response) createRetryTasks(resp *regionResponse) []*task {
-	return nil
+	return result.resp.data, nil
 }
 
 func buildRegionTasks(client *dbClient, req *kv.Request) (tasks []*task) {
@@ -170,8 +197,9 @@ func buildRegionTasks(client *dbClient, req *kv.Request) (tasks []*task) {
 				ranges:   req.KeyRanges,
 			}
 			task := &task{
-				region:  info.rs,
-				request: regionReq,
+				region:   info.rs,
+				request:  regionReq,
+				respChan: make(chan *regionResult, 1),
 			}
 			tasks = append(tasks, task)
 			infoCursor++
@@ -186,29 +214,62 @@ func buildRegionTasks(client *dbClient, req *kv.Request) (tasks []*task) {
 	return
 }
 
+// Close stops the workers and waits for them to exit.
 func (it *response) Close() error {
-	// Make goroutines quit.
-	if it.finished {
-		return nil
+	select {
+	case <-it.finished:
+	default:
+		close(it.finished)
 	}
-	close(it.taskChan)
-	it.finished = true
+	it.wg.Wait()
 	return nil
 }
 
+// run starts the workers, and feeds them with the tasks in a goroutine.
 func (it *response) run() {
+	it.wg.Add(it.concurrency)
 	for i := 0; i < it.concurrency; i++ {
-		go func() {
-			for task := range it.taskChan {
-				resp, err := task.region.Handle(task.request)
-				if err != nil {
-					it.errChan <- err
-					break
-				}
-				it.respChan <- resp
+		go it.work()
+	}
+	go func() {
+		defer func() {
+			close(it.taskChan)
+			it.wg.Wait()
+			if !it.keepOrder {
+				close(it.respChan)
 			}
 		}()
-		it.taskChan <- it.tasks[i]
-		it.reqSent++
+		for _, t := range it.tasks {
+			select {
+			case it.taskChan <- t:
+			case <-it.finished:
+				return
+			case <-it.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// work handles the tasks from taskChan, and sends the results to respChan, or to the respChan of the task if the
+// results should be kept in order.
+func (it *response) work() {
+	defer it.wg.Done()
+	for task := range it.taskChan {
+		resp, err := task.region.Handle(task.request)
+		ch := it.respChan
+		if it.keepOrder {
+			ch = task.respChan
+		}
+		select {
+		case ch <- &regionResult{resp: resp, err: errors.Trace(err)}:
+		case <-it.finished:
+			return
+		case <-it.ctx.Done():
+			return
+		}
+		if it.keepOrder {
+			close(ch)
+		}
 	}
 }
@@ -16,6 +16,8 @@ package localstore
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -342,6 +344,40 @@ func (t *testMvccSuite) TestMvccSuiteGetLatest(c *C) {
 	txn1.Commit()
 }
 
+func (t *testMvccSuite) TestReopen(c *C) {
+	dir, err := ioutil.TempDir("", "localstore")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	d := Driver{goleveldb.Driver{}}
+	path := "goleveldb://" + dir
+
+	store, err := d.Open(path)
+	c.Assert(err, IsNil)
+	txn, err := store.Begin()
+	c.Assert(err, IsNil)
+	c.Assert(txn.Set([]byte("k1"), []byte("v1")), IsNil)
+	c.Assert(txn.Set([]byte("k2"), []byte("v2")), IsNil)
+	c.Assert(txn.Commit(), IsNil)
+	txn, err = store.Begin()
+	c.Assert(err, IsNil)
+	c.Assert(txn.Delete([]byte("k2")), IsNil)
+	c.Assert(txn.Commit(), IsNil)
+	c.Assert(store.Close(), IsNil)
+
+	// The committed data is read after the store is reopened.
+	store, err = d.Open(path)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	txn, err = store.Begin()
+	c.Assert(err, IsNil)
+	v, err := txn.Get([]byte("k1"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("v1"))
+	_, err = txn.Get([]byte("k2"))
+	c.Assert(kv.IsErrNotFound(err), IsTrue)
+	c.Assert(txn.Rollback(), IsNil)
+}
+
 func (t *testMvccSuite) TestBufferedIterator(c *C) {
 	s := createMemStore(time.Now().Nanosecond())
 	tx, _ := s.Begin()
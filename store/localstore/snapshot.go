@@ -147,6 +147,18 @@ func (s *dbSnapshot) BatchGet(keys []kv.Key) (map[string][]byte, error) {
 	return m, nil
 }
 
+// SetPriority implements kv.Snapshot SetPriority interface.
+// The local store serves a single server, there isn't any foreground request to yield to.
+func (s *dbSnapshot) SetPriority(priority int) {}
+
+// SetReplicaRead implements kv.Snapshot SetReplicaRead interface.
+// The local store doesn't have any replica.
+func (s *dbSnapshot) SetReplicaRead(typ kv.ReplicaReadType) {}
+
+// Release implements kv.Snapshot Release interface.
+// The local store doesn't collect the old versions.
+func (s *dbSnapshot) Release() {}
+
 func (s *dbSnapshot) Seek(k kv.Key) (kv.Iterator, error) {
 	it, err := newDBIter(s, k, false)
 	return it, errors.Trace(err)
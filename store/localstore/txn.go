@@ -155,6 +155,10 @@ func (txn *dbTxn) IsReadOnly() bool {
 	return !txn.dirty
 }
 
+func (txn *dbTxn) Prefetch(keys []kv.Key) error {
+	return errors.Trace(txn.us.Prefetch(keys))
+}
+
 func (txn *dbTxn) StartTS() uint64 {
 	return txn.tid
 }
@@ -170,3 +174,15 @@ func (txn *dbTxn) Size() int {
 func (txn *dbTxn) Len() int {
 	return txn.us.Len()
 }
+
+func (txn *dbTxn) Staging() kv.StagingHandle {
+	return txn.us.Staging()
+}
+
+func (txn *dbTxn) Release(h kv.StagingHandle) {
+	txn.us.Release(h)
+}
+
+func (txn *dbTxn) Cleanup(h kv.StagingHandle) {
+	txn.us.Cleanup(h)
+}
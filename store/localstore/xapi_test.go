@@ -63,6 +63,9 @@ func (s *testXAPISuite) TestSelect(c *C) {
 	// Select Table request.
 	txn, err := store.Begin()
 	c.Check(err, IsNil)
+	// The requests are pushed down to the local store, which has only one replica.
+	c.Assert(store.SupportCapability(kv.CapCoprocessor), IsTrue)
+	c.Assert(store.SupportCapability(kv.CapFollowerRead), IsFalse)
 	client := store.GetClient()
 	req, err := prepareSelectRequest(tbInfo, txn.StartTS())
 	c.Check(err, IsNil)
@@ -114,6 +117,78 @@ func (s *testXAPISuite) TestSelect(c *C) {
 	store.Close()
 }
 
+func (s *testXAPISuite) TestConcurrentSelect(c *C) {
+	defer testleak.AfterTest(c)()
+	store := createMemStore(time.Now().Nanosecond())
+	defer store.Close()
+	count := int64(10)
+	err := prepareTableData(store, tbInfo, count, genValues)
+	c.Assert(err, IsNil)
+
+	// Split the table into 4 regions, so the request is handled by multiple tasks.
+	var infos []*regionInfo
+	startKey := kv.Key("")
+	for i, h := range []int64{3, 5, 8} {
+		endKey := kv.Key(tablecodec.EncodeRowKey(tbInfo.tID, codec.EncodeInt(nil, h)))
+		rs := &localRegion{id: i + 1, store: store.(*dbStore), startKey: startKey, endKey: endKey}
+		infos = append(infos, &regionInfo{startKey: startKey, endKey: endKey, rs: rs})
+		startKey = endKey
+	}
+	rs := &localRegion{id: 4, store: store.(*dbStore), startKey: startKey, endKey: kv.Key("z")}
+	infos = append(infos, &regionInfo{startKey: startKey, endKey: kv.Key("z"), rs: rs})
+	client := &dbClient{store: store.(*dbStore), regionInfo: infos}
+
+	txn, err := store.Begin()
+	c.Assert(err, IsNil)
+	defer txn.Rollback()
+	for _, concurrency := range []int{1, 3, 10} {
+		for _, keepOrder := range []bool{true, false} {
+			req, err := prepareSelectRequest(tbInfo, txn.StartTS())
+			c.Assert(err, IsNil)
+			req.Concurrency = concurrency
+			req.KeepOrder = keepOrder
+			resp := client.Send(goctx.Background(), req)
+			var handles []int
+			for {
+				data, err := resp.Next()
+				c.Assert(err, IsNil)
+				if data == nil {
+					break
+				}
+				selResp := new(tipb.SelectResponse)
+				c.Assert(proto.Unmarshal(data, selResp), IsNil)
+				for _, chunk := range selResp.Chunks {
+					for _, rowMeta := range chunk.RowsMeta {
+						handles = append(handles, int(rowMeta.Handle))
+					}
+				}
+			}
+			c.Assert(resp.Close(), IsNil)
+			c.Assert(handles, HasLen, int(count))
+			if !keepOrder {
+				sort.Ints(handles)
+			}
+			for i, h := range handles {
+				c.Assert(h, Equals, i+1, Commentf("concurrency %d keep order %v", concurrency, keepOrder))
+			}
+		}
+	}
+
+	// Close the response before all the results are read.
+	req, err := prepareSelectRequest(tbInfo, txn.StartTS())
+	c.Assert(err, IsNil)
+	req.Concurrency = 2
+	req.KeepOrder = true
+	resp := client.Send(goctx.Background(), req)
+	data, err := resp.Next()
+	c.Assert(err, IsNil)
+	c.Assert(data, NotNil)
+	c.Assert(resp.Close(), IsNil)
+	data, err = resp.Next()
+	c.Assert(err, IsNil)
+	c.Assert(data, IsNil)
+}
+
 // simpleTableInfo just have the minimum information enough to describe the table.
 // The first column is pk handle column.
 type simpleTableInfo struct {
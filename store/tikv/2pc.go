@@ -80,7 +80,8 @@ func newTwoPhaseCommitter(txn *tikvTxn) (*twoPhaseCommitter, error) {
 		delCnt  int
 		lockCnt int
 	)
-	mutations := make(map[string]*pb.Mutation)
+	mutations := make(map[string]*pb.Mutation, txn.us.Len())
+	keys = make([][]byte, 0, txn.us.Len())
 	err := txn.us.WalkBuffer(func(k kv.Key, v []byte) error {
 		if len(v) > 0 {
 			mutations[string(k)] = &pb.Mutation{
@@ -259,13 +260,18 @@ func (c *twoPhaseCommitter) doActionOnBatches(bo *Backoffer, action twoPhaseComm
 		cancel = bo.WithCancel()
 	}
 
-	// Concurrently do the work for each batch.
+	// Concurrently do the work for each batch, at most txnCommitConcurrency batches are done at the same time.
 	ch := make(chan error, len(batches))
-	for _, batch := range batches {
-		go func(batch batchKeys) {
-			ch <- singleBatchActionFunc(bo.Fork(), batch)
-		}(batch)
-	}
+	go func() {
+		tokens := make(chan struct{}, txnCommitConcurrency)
+		for _, batch := range batches {
+			tokens <- struct{}{}
+			go func(batch batchKeys) {
+				ch <- singleBatchActionFunc(bo.Fork(), batch)
+				<-tokens
+			}(batch)
+		}
+	}()
 	var err error
 	for i := 0; i < len(batches); i++ {
 		if e := <-ch; e != nil {
@@ -335,6 +341,7 @@ func (c *twoPhaseCommitter) prewriteSingleBatch(bo *Backoffer, batch batchKeys)
 		}
 		keyErrs := prewriteResp.GetErrors()
 		if len(keyErrs) == 0 {
+			c.store.hotRegions.RecordWrite(batch.region.id, batch.keys)
 			// We need to cleanup all written keys if transaction aborts.
 			c.mu.Lock()
 			defer c.mu.Unlock()
@@ -500,6 +507,8 @@ func (c *twoPhaseCommitter) execute() error {
 		log.Debugf("2PC failed on prewrite: %v, tid: %d", err, c.startTS)
 		return errors.Trace(err)
 	}
+	// The mutations are not used after prewrite, release them for the large transactions.
+	c.mutations = nil
 
 	commitTS, err := c.store.getTimestampWithRetry(NewBackoffer(tsoMaxBackoff, ctx))
 	if err != nil {
@@ -584,7 +593,7 @@ func (c *twoPhaseCommitter) writeFinishBinlog(tp binlog.BinlogType, commitTS int
 }
 
 func (c *twoPhaseCommitter) shouldWriteBinlog() bool {
-	if binloginfo.PumpClient == nil {
+	if !binloginfo.Enabled() {
 		return false
 	}
 	_, ok := c.txn.us.GetOption(kv.BinlogData).(*binlog.Binlog)
@@ -595,6 +604,10 @@ func (c *twoPhaseCommitter) shouldWriteBinlog() bool {
 // Key+Value size below 4KB.
 const txnCommitBatchSize = 4 * 1024
 
+// txnCommitConcurrency is the max number of batches which are sent concurrently by a 2PC action,
+// so a large transaction doesn't start all its requests at the same time.
+var txnCommitConcurrency = 128
+
 // batchKeys is a batch of keys in the same region.
 type batchKeys struct {
 	region RegionVerID
@@ -110,6 +110,19 @@ func (s *testCommitterSuite) TestCommitRollback(c *C) {
 	})
 }
 
+func (s *testCommitterSuite) TestCommitManyBatches(c *C) {
+	defer func(concurrency int) { txnCommitConcurrency = concurrency }(txnCommitConcurrency)
+	txnCommitConcurrency = 2
+
+	// Each key makes a batch.
+	m := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		k, v := randKV(10, txnCommitBatchSize)
+		m[k] = v
+	}
+	s.mustCommit(c, m)
+}
+
 func (s *testCommitterSuite) TestPrewriteRollback(c *C) {
 	s.mustCommit(c, map[string]string{
 		"a": "a0",
@@ -21,6 +21,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/util/execdetails"
 	goctx "golang.org/x/net/context"
 )
 
@@ -36,12 +37,12 @@ const (
 )
 
 // NewBackoffFn creates a backoff func which implements exponential backoff with
-// optional jitters.
+// optional jitters. The func stops sleeping when the ctx is done.
 // See http://www.awsarchitectureblog.com/2015/03/backoff.html
-func NewBackoffFn(base, cap, jitter int) func() int {
+func NewBackoffFn(base, cap, jitter int) func(ctx goctx.Context) int {
 	attempts := 0
 	lastSleep := base
-	return func() int {
+	return func(ctx goctx.Context) int {
 		var sleep int
 		switch jitter {
 		case NoJitter:
@@ -55,7 +56,10 @@ func NewBackoffFn(base, cap, jitter int) func() int {
 		case DecorrJitter:
 			sleep = int(math.Min(float64(cap), float64(base+rand.Intn(lastSleep*3-base))))
 		}
-		time.Sleep(time.Duration(sleep) * time.Millisecond)
+		select {
+		case <-time.After(time.Duration(sleep) * time.Millisecond):
+		case <-ctx.Done():
+		}
 
 		attempts++
 		lastSleep = sleep
@@ -78,7 +82,7 @@ const (
 	boServerBusy
 )
 
-func (t backoffType) createFn() func() int {
+func (t backoffType) createFn() func(goctx.Context) int {
 	switch t {
 	case boTiKVRPC:
 		return NewBackoffFn(100, 2000, EqualJitter)
@@ -129,11 +133,15 @@ const (
 	gcMaxBackoff            = 100000
 	gcResolveLockMaxBackoff = 100000
 	rawkvMaxBackoff         = 15000
+	splitRegionMaxBackoff   = 5000
 )
 
 // Backoffer is a utility for retrying queries.
+// Each type of the errors backs off in its own exponential sequence, while the total sleep time is limited by
+// maxSleep. The backoffs stop when the ctx is done, and they are recorded into the *execdetails.BackoffDetails
+// which is the value of execdetails.BackoffDetailsKey in the ctx, so the retries of a statement can be logged.
 type Backoffer struct {
-	fn         map[backoffType]func() int
+	fn         map[backoffType]func(goctx.Context) int
 	maxSleep   int
 	totalSleep int
 	errors     []error
@@ -157,12 +165,12 @@ func (b *Backoffer) WithCancel() goctx.CancelFunc {
 }
 
 // Backoff sleeps a while base on the backoffType and records the error message.
-// It returns a retryable error if total sleep time exceeds maxSleep.
+// It returns a retryable error if total sleep time exceeds maxSleep, or an error if the ctx is done.
 func (b *Backoffer) Backoff(typ backoffType, err error) error {
 	backoffCounter.WithLabelValues(typ.String()).Inc()
 	// Lazy initialize.
 	if b.fn == nil {
-		b.fn = make(map[backoffType]func() int)
+		b.fn = make(map[backoffType]func(goctx.Context) int)
 	}
 	f, ok := b.fn[typ]
 	if !ok {
@@ -170,8 +178,22 @@ func (b *Backoffer) Backoff(typ backoffType, err error) error {
 		b.fn[typ] = f
 	}
 
-	b.totalSleep += f()
+	sleep := f(b.ctx)
+	b.totalSleep += sleep
 	b.types = append(b.types, typ)
+	if details, ok := b.ctx.Value(execdetails.BackoffDetailsKey).(*execdetails.BackoffDetails); ok {
+		details.Record(typ.String(), time.Duration(sleep)*time.Millisecond)
+	}
+	select {
+	case <-b.ctx.Done():
+		// Some contexts, like the one of a killed statement, don't tell the reason.
+		ctxErr := b.ctx.Err()
+		if ctxErr == nil {
+			ctxErr = goctx.Canceled
+		}
+		return errors.Errorf("backoff is interrupted: %v, last error: %v", ctxErr, err)
+	default:
+	}
 
 	log.Debugf("%v, retry later(totalSleep %dms, maxSleep %dms)", err, b.totalSleep, b.maxSleep)
 	b.errors = append(b.errors, err)
@@ -0,0 +1,64 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/execdetails"
+	goctx "golang.org/x/net/context"
+)
+
+type testBackoffSuite struct{}
+
+var _ = Suite(&testBackoffSuite{})
+
+func (s *testBackoffSuite) TestBackoffDetails(c *C) {
+	details := execdetails.NewBackoffDetails()
+	ctx := goctx.WithValue(goctx.Background(), execdetails.BackoffDetailsKey, details)
+	bo := NewBackoffer(5000, ctx)
+	c.Assert(bo.Backoff(boRegionMiss, errors.New("region miss")), IsNil)
+	c.Assert(bo.Backoff(boRegionMiss, errors.New("region miss")), IsNil)
+	c.Assert(bo.Backoff(boTxnLockFast, errors.New("lock")), IsNil)
+	c.Assert(details.Times(), Equals, 3)
+	c.Assert(details.String(), Matches, `regionMiss\{times:2, sleep:300ms\}; txnLockFast\{times:1, sleep:.*\}`)
+
+	// The forked backoffer records into the same details.
+	c.Assert(bo.Fork().Backoff(boRegionMiss, errors.New("region miss")), IsNil)
+	c.Assert(details.Times(), Equals, 4)
+}
+
+func (s *testBackoffSuite) TestBackoffCanceled(c *C) {
+	ctx, cancel := goctx.WithCancel(goctx.Background())
+	bo := NewBackoffer(100000, ctx)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	err := bo.Backoff(boServerBusy, errors.New("server is busy"))
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start), Less, time.Second)
+
+	// The backoffer stops at the deadline of the ctx.
+	ctx, cancel = goctx.WithTimeout(goctx.Background(), 100*time.Millisecond)
+	defer cancel()
+	bo = NewBackoffer(100000, ctx)
+	start = time.Now()
+	err = bo.Backoff(boServerBusy, errors.New("server is busy"))
+	c.Assert(err, ErrorMatches, ".*deadline exceeded.*")
+	c.Assert(time.Since(start), Less, time.Second)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/pingcap/kvproto/pkg/coprocessor"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tipb/go-tipb"
 	goctx "golang.org/x/net/context"
 )
@@ -82,9 +83,15 @@ func (c *CopClient) Send(ctx goctx.Context, req *kv.Request) kv.Response {
 	it := &copIterator{
 		store:       c.store,
 		req:         req,
+		ctx:         ctx,
 		concurrency: req.Concurrency,
 		finished:    make(chan struct{}),
 	}
+	if req.Streaming {
+		if err = it.initStreaming(); err != nil {
+			return copErrorResponse{err}
+		}
+	}
 	it.tasks = tasks
 	if it.concurrency > len(tasks) {
 		it.concurrency = len(tasks)
@@ -95,6 +102,13 @@ func (c *CopClient) Send(ctx goctx.Context, req *kv.Request) kv.Response {
 	}
 	if !it.req.KeepOrder {
 		it.respChan = make(chan copResponse, it.concurrency)
+	} else if it.req.Limit > 0 {
+		// The caller may stop reading after the first few tasks, so the tasks are sent at most concurrency tasks
+		// ahead of the reading.
+		it.sendRate = make(chan struct{}, it.concurrency)
+		for i := 0; i < it.concurrency; i++ {
+			it.sendRate <- struct{}{}
+		}
 	}
 	it.taskCh = make(chan *copTask, req.Concurrency)
 	it.run(ctx)
@@ -285,6 +299,9 @@ type copIterator struct {
 	concurrency int
 	finished    chan struct{}
 	taskCh      chan *copTask
+	// ctx is the context of the request, Next returns its error after it's canceled, so the caller doesn't take the
+	// responses cut by the cancellation as the complete result.
+	ctx goctx.Context
 
 	// If keepOrder, results are stored in copTask.respChan, read them out one by one.
 	tasks []*copTask
@@ -293,6 +310,36 @@ type copIterator struct {
 	// Otherwise, results are stored in respChan.
 	respChan chan copResponse
 	wg       sync.WaitGroup
+
+	// If sendRate is not nil, a task is sent after taking a token from it, and a token is put back after the
+	// results of a task are read out.
+	sendRate chan struct{}
+
+	// streamingTableID is the ID of the table scanned by a streaming request.
+	streamingTableID int64
+}
+
+// copStreamingRows is the max number of rows in a response of a streaming request.
+var copStreamingRows int64 = 1024
+
+// initStreaming limits the rows returned by a request of the streaming request, the task sends the next request from
+// the key after the last returned row until a response returns less rows.
+func (it *copIterator) initStreaming() error {
+	sel := new(tipb.SelectRequest)
+	if err := sel.Unmarshal(it.req.Data); err != nil {
+		return errors.Trace(err)
+	}
+	limit := copStreamingRows
+	sel.Limit = &limit
+	data, err := sel.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req := *it.req
+	req.Data = data
+	it.req = &req
+	it.streamingTableID = sel.TableInfo.GetTableId()
+	return nil
 }
 
 type copResponse struct {
@@ -307,9 +354,17 @@ const minLogCopTaskTime = 300 * time.Millisecond
 func (it *copIterator) work(ctx goctx.Context, taskCh <-chan *copTask) {
 	defer it.wg.Done()
 	for task := range taskCh {
+		var ch chan copResponse
+		if !it.req.KeepOrder {
+			ch = it.respChan
+		} else {
+			ch = task.respChan
+		}
 		bo := NewBackoffer(copNextMaxBackoff, ctx)
 		startTime := time.Now()
-		resps := it.handleTask(bo, task)
+		if !it.handleTask(bo, task, ch) {
+			return
+		}
 		costTime := time.Since(startTime)
 		if costTime > minLogCopTaskTime {
 			log.Infof("[TIME_COP_TASK] %s%s %s", costTime, bo, task)
@@ -318,28 +373,24 @@ func (it *copIterator) work(ctx goctx.Context, taskCh <-chan *copTask) {
 		if bo.totalSleep > 0 {
 			backoffHistogram.Observe(float64(bo.totalSleep) / 1000)
 		}
-		var ch chan copResponse
-		if !it.req.KeepOrder {
-			ch = it.respChan
-		} else {
-			ch = task.respChan
-		}
-
-		for _, resp := range resps {
-			select {
-			case ch <- resp:
-			case <-ctx.Done():
-				return
-			case <-it.finished:
-				return
-			}
-		}
 		if it.req.KeepOrder {
 			close(ch)
 		}
 	}
 }
 
+// sendResp sends the response to ch, it returns false if the iterator is closed or the request is canceled.
+func (it *copIterator) sendResp(bo *Backoffer, ch chan<- copResponse, resp copResponse) bool {
+	select {
+	case ch <- resp:
+		return true
+	case <-bo.ctx.Done():
+		return false
+	case <-it.finished:
+		return false
+	}
+}
+
 func (it *copIterator) run(ctx goctx.Context) {
 	it.wg.Add(it.concurrency)
 	// Start it.concurrency number of workers to handle cop requests.
@@ -366,6 +417,15 @@ func (it *copIterator) run(ctx goctx.Context) {
 }
 
 func (it *copIterator) sendToTaskCh(ctx goctx.Context, t *copTask) (finished bool, canceled bool) {
+	if it.sendRate != nil {
+		select {
+		case <-it.sendRate:
+		case <-it.finished:
+			return true, false
+		case <-ctx.Done():
+			return false, true
+		}
+	}
 	select {
 	case it.taskCh <- t:
 	case <-it.finished:
@@ -388,9 +448,14 @@ func (it *copIterator) Next() ([]byte, error) {
 	// Otherwise all responses are returned from a single channel.
 	if !it.req.KeepOrder {
 		// Get next fetched resp from chan
-		resp, ok = <-it.respChan
+		select {
+		case resp, ok = <-it.respChan:
+		case <-it.ctx.Done():
+			return nil, errors.Trace(it.ctx.Err())
+		}
 		if !ok {
-			return nil, nil
+			// The workers exit early if the request is canceled.
+			return nil, errors.Trace(it.ctx.Err())
 		}
 	} else {
 		for {
@@ -399,12 +464,23 @@ func (it *copIterator) Next() ([]byte, error) {
 				return nil, nil
 			}
 			task := it.tasks[it.curr]
-			resp, ok = <-task.respChan
+			select {
+			case resp, ok = <-task.respChan:
+			case <-it.finished:
+				// The workers may exit without closing the respChan of the task after the iterator is closed.
+				return nil, nil
+			case <-it.ctx.Done():
+				// Neither are the respChans closed after the request is canceled.
+				return nil, errors.Trace(it.ctx.Err())
+			}
 			if ok {
 				break
 			}
 			// Switch to next task.
 			it.curr++
+			if it.sendRate != nil {
+				it.sendRate <- struct{}{}
+			}
 		}
 	}
 
@@ -414,14 +490,16 @@ func (it *copIterator) Next() ([]byte, error) {
 	return resp.Data, nil
 }
 
-// Handle single copTask.
-func (it *copIterator) handleTask(bo *Backoffer, task *copTask) []copResponse {
+// Handle single copTask, the responses are sent to ch. It returns false if the iterator is closed or the request is
+// canceled.
+func (it *copIterator) handleTask(bo *Backoffer, task *copTask, ch chan<- copResponse) bool {
 	coprocessorCounter.WithLabelValues("handle_task").Inc()
 	sender := NewRegionRequestSender(bo, it.store.regionCache, it.store.client)
+	sender.replicaRead = it.req.ReplicaRead
 	for {
 		select {
 		case <-it.finished:
-			return nil
+			return false
 		default:
 		}
 
@@ -430,27 +508,47 @@ func (it *copIterator) handleTask(bo *Backoffer, task *copTask) []copResponse {
 			Data:   it.req.Data,
 			Ranges: task.ranges.toPBRanges(),
 		}
+		var cacheKey string
+		if cache := it.store.coprCache; cache != nil {
+			cacheKey = coprCacheKey(task.region, req)
+			if data, ok := cache.Get(cacheKey); ok {
+				coprocessorCounter.WithLabelValues("cache_hit").Inc()
+				resp := &coprocessor.Response{Data: data}
+				if !it.sendResp(bo, ch, copResponse{Response: resp}) {
+					return false
+				}
+				if it.req.Streaming && it.nextStreamingRanges(task, resp) {
+					continue
+				}
+				return true
+			}
+		}
+		it.store.acquirePriority(it.req.Priority)
 		resp, err := sender.SendCopReq(req, task.region, readTimeoutMedium)
+		it.store.releasePriority(it.req.Priority)
 		if err != nil {
-			return []copResponse{{err: errors.Trace(err)}}
+			return it.sendResp(bo, ch, copResponse{err: errors.Trace(err)})
 		}
 		if regionErr := resp.GetRegionError(); regionErr != nil {
 			err = bo.Backoff(boRegionMiss, errors.New(regionErr.String()))
 			if err != nil {
-				return []copResponse{{err: errors.Trace(err)}}
+				return it.sendResp(bo, ch, copResponse{err: errors.Trace(err)})
 			}
-			return it.handleRegionErrorTask(bo, task)
+			return it.handleRegionErrorTask(bo, task, ch)
+		}
+		if len(req.Ranges) > 0 {
+			it.store.hotRegions.RecordRead(task.region.id, req.Ranges[0].GetStart(), nil)
 		}
 		if e := resp.GetLocked(); e != nil {
 			log.Debugf("coprocessor encounters lock: %v", e)
 			ok, err1 := it.store.lockResolver.ResolveLocks(bo, []*Lock{newLock(e)})
 			if err1 != nil {
-				return []copResponse{{err: errors.Trace(err1)}}
+				return it.sendResp(bo, ch, copResponse{err: errors.Trace(err1)})
 			}
 			if !ok {
 				err = bo.Backoff(boTxnLockFast, errors.New(e.String()))
 				if err != nil {
-					return []copResponse{{err: errors.Trace(err)}}
+					return it.sendResp(bo, ch, copResponse{err: errors.Trace(err)})
 				}
 			}
 			continue
@@ -458,32 +556,93 @@ func (it *copIterator) handleTask(bo *Backoffer, task *copTask) []copResponse {
 		if e := resp.GetOtherError(); e != "" {
 			err = errors.Errorf("other error: %s", e)
 			log.Warnf("coprocessor err: %v", err)
-			return []copResponse{{err: errors.Trace(err)}}
+			return it.sendResp(bo, ch, copResponse{err: errors.Trace(err)})
 		}
 		task.storeAddr = sender.storeAddr
-		return []copResponse{{Response: resp}}
+		if cache := it.store.coprCache; cache != nil {
+			cache.Put(cacheKey, resp.Data)
+		}
+		if !it.sendResp(bo, ch, copResponse{Response: resp}) {
+			return false
+		}
+		if it.req.Streaming && it.nextStreamingRanges(task, resp) {
+			continue
+		}
+		return true
 	}
 }
 
+// nextStreamingRanges cuts the ranges of the task from the last row of the response of a streaming request, it returns
+// false if the task is finished.
+func (it *copIterator) nextStreamingRanges(task *copTask, resp *coprocessor.Response) bool {
+	selResp := new(tipb.SelectResponse)
+	if err := selResp.Unmarshal(resp.Data); err != nil || selResp.Error != nil {
+		// The error is returned to the caller when it decodes the response.
+		return false
+	}
+	var (
+		count int64
+		last  *tipb.RowMeta
+	)
+	for i := range selResp.Chunks {
+		meta := selResp.Chunks[i].RowsMeta
+		count += int64(len(meta))
+		if len(meta) > 0 {
+			last = &meta[len(meta)-1]
+		}
+	}
+	if count < copStreamingRows || last == nil {
+		return false
+	}
+	lastKey := tablecodec.EncodeRowKeyWithHandle(it.streamingTableID, last.Handle)
+	task.ranges = cutCopRanges(task.ranges, lastKey, it.req.Desc)
+	return task.ranges.len() > 0
+}
+
+// cutCopRanges returns the part of the ranges after the key, or before the key if desc is true.
+func cutCopRanges(ranges *copRanges, key kv.Key, desc bool) *copRanges {
+	var mid []kv.KeyRange
+	if !desc {
+		next := key.Next()
+		ranges.do(func(ran *kv.KeyRange) {
+			if bytes.Compare(ran.EndKey, next) <= 0 {
+				return
+			}
+			r := *ran
+			if bytes.Compare(r.StartKey, next) < 0 {
+				r.StartKey = next
+			}
+			mid = append(mid, r)
+		})
+	} else {
+		ranges.do(func(ran *kv.KeyRange) {
+			if bytes.Compare(ran.StartKey, key) >= 0 {
+				return
+			}
+			r := *ran
+			if bytes.Compare(r.EndKey, key) > 0 {
+				r.EndKey = key
+			}
+			mid = append(mid, r)
+		})
+	}
+	return &copRanges{mid: mid}
+}
+
 // Rebuild and handle current task. It may be split into multiple tasks (in region split scenario).
-func (it *copIterator) handleRegionErrorTask(bo *Backoffer, task *copTask) []copResponse {
+func (it *copIterator) handleRegionErrorTask(bo *Backoffer, task *copTask, ch chan<- copResponse) bool {
 	coprocessorCounter.WithLabelValues("rebuild_task").Inc()
 
 	newTasks, err := buildCopTasks(bo, it.store.regionCache, task.ranges, it.req.Desc)
 	if err != nil {
-		return []copResponse{{err: errors.Trace(err)}}
-	}
-	if newTasks == nil {
-		// TODO: check this, this should never happen.
-		return nil
+		return it.sendResp(bo, ch, copResponse{err: errors.Trace(err)})
 	}
-
-	var ret []copResponse
 	for _, t := range newTasks {
-		resp := it.handleTask(bo, t)
-		ret = append(ret, resp...)
+		if !it.handleTask(bo, t, ch) {
+			return false
+		}
 	}
-	return ret
+	return true
 }
 
 func (it *copIterator) Close() error {
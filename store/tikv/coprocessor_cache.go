@@ -0,0 +1,134 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+)
+
+// CoprCacheCapacity is the capacity in bytes of the coprocessor cache of a tikv store, 0 disables the cache.
+// It should be set before the store is opened.
+var CoprCacheCapacity int64
+
+// coprCacheAdmissionRatio limits the size of a single cached response to a part of the capacity, so that a big
+// scan doesn't evict all the other responses.
+const coprCacheAdmissionRatio = 16
+
+// coprCache caches the responses of coprocessor requests in an LRU list.
+//
+// A response is keyed by the region and its epoch, the request type and data, and the key ranges. The request data
+// contains the start ts of the snapshot, which is the data version of the response: a region returns the same
+// response for the same request at the same version. The key of a region changes when the region is split or merged,
+// so a response of a changed region is never served, it's evicted from the list later.
+type coprCache struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	items    map[string]*list.Element
+	lru      *list.List
+}
+
+type coprCacheEntry struct {
+	key  string
+	data []byte
+}
+
+func (e *coprCacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.data))
+}
+
+func newCoprCache(capacity int64) *coprCache {
+	return &coprCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// coprCacheKey builds the cache key of a coprocessor request sent to the region.
+func coprCacheKey(region RegionVerID, req *coprocessor.Request) string {
+	size := 8*4 + len(req.Data)
+	for _, r := range req.Ranges {
+		size += 8*2 + len(r.Start) + len(r.End)
+	}
+	buf := make([]byte, 0, size)
+	buf = appendUint64(buf, region.id)
+	buf = appendUint64(buf, region.confVer)
+	buf = appendUint64(buf, region.ver)
+	buf = appendUint64(buf, uint64(req.Tp))
+	buf = appendBytes(buf, req.Data)
+	for _, r := range req.Ranges {
+		buf = appendBytes(buf, r.Start)
+		buf = appendBytes(buf, r.End)
+	}
+	return string(buf)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// appendBytes appends the length before the bytes, so the keys of different requests are different.
+func appendBytes(buf []byte, v []byte) []byte {
+	buf = appendUint64(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+// Get returns the cached response data of the key.
+func (c *coprCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ele, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(ele)
+	return ele.Value.(*coprCacheEntry).data, true
+}
+
+// Put caches the response data of the key, the least recently used responses are evicted if the cache is full.
+func (c *coprCache) Put(key string, data []byte) {
+	entry := &coprCacheEntry{key: key, data: data}
+	if entry.size() > c.capacity/coprCacheAdmissionRatio {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ele, ok := c.items[key]; ok {
+		c.size -= ele.Value.(*coprCacheEntry).size()
+		c.lru.Remove(ele)
+	}
+	c.items[key] = c.lru.PushFront(entry)
+	c.size += entry.size()
+	for c.size > c.capacity {
+		ele := c.lru.Back()
+		evicted := ele.Value.(*coprCacheEntry)
+		c.lru.Remove(ele)
+		delete(c.items, evicted.key)
+		c.size -= evicted.size()
+	}
+}
+
+// Len returns the number of cached responses.
+func (c *coprCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/ast"
+	goctx "golang.org/x/net/context"
+)
+
+func (s *testCoprocessorSuite) TestCoprCache(c *C) {
+	cache := newCoprCache(4096)
+	region := RegionVerID{id: 1, confVer: 1, ver: 1}
+	req := &coprocessor.Request{
+		Tp:     1,
+		Data:   []byte("data"),
+		Ranges: []*coprocessor.KeyRange{{Start: []byte("a"), End: []byte("b")}},
+	}
+	key := coprCacheKey(region, req)
+	_, ok := cache.Get(key)
+	c.Assert(ok, IsFalse)
+	cache.Put(key, []byte("resp"))
+	data, ok := cache.Get(key)
+	c.Assert(ok, IsTrue)
+	c.Assert(data, BytesEquals, []byte("resp"))
+
+	// The key changes with the region epoch, the request and the ranges.
+	splitRegion := RegionVerID{id: 1, confVer: 1, ver: 2}
+	c.Assert(coprCacheKey(splitRegion, req), Not(Equals), key)
+	c.Assert(coprCacheKey(region, &coprocessor.Request{Tp: 1, Data: []byte("dat"), Ranges: req.Ranges}), Not(Equals), key)
+	otherRanges := []*coprocessor.KeyRange{{Start: []byte("a"), End: []byte("c")}}
+	c.Assert(coprCacheKey(region, &coprocessor.Request{Tp: 1, Data: req.Data, Ranges: otherRanges}), Not(Equals), key)
+
+	// A response which is too big is not cached.
+	cache.Put("big", make([]byte, 4096/coprCacheAdmissionRatio))
+	_, ok = cache.Get("big")
+	c.Assert(ok, IsFalse)
+
+	// The least recently used responses are evicted.
+	for i := 0; i < 200; i++ {
+		cache.Put(string([]byte{byte(i)}), make([]byte, 60))
+		cache.Get(key)
+	}
+	_, ok = cache.Get(key)
+	c.Assert(ok, IsTrue)
+	_, ok = cache.Get(string([]byte{0}))
+	c.Assert(ok, IsFalse)
+	c.Assert(cache.size, LessEqual, cache.capacity)
+	c.Assert(int64(cache.Len()), Equals, int64(len(cache.items)))
+}
+
+type countCopClient struct {
+	Client
+	copCount int64
+}
+
+func (c *countCopClient) SendCopReq(ctx goctx.Context, addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	atomic.AddInt64(&c.copCount, 1)
+	return c.Client.SendCopReq(ctx, addr, req, timeout)
+}
+
+func (s *testStoreSuite) TestCoprCache(c *C) {
+	client := &countCopClient{Client: s.store.client}
+	s.store.client = client
+	s.store.coprCache = newCoprCache(1 << 20)
+	_, err := tidb.BootstrapSession(s.store)
+	c.Assert(err, IsNil)
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+
+	mustExec := func(sql string) []ast.RecordSet {
+		rs, err := session.Execute(sql)
+		c.Assert(err, IsNil, Commentf("sql: %s", sql))
+		return rs
+	}
+	count := func() int64 {
+		rs := mustExec("select count(*) from test.t")
+		row, err := rs[0].Next()
+		c.Assert(err, IsNil)
+		c.Assert(rs[0].Close(), IsNil)
+		return row.Data[0].GetInt64()
+	}
+	mustExec("create table test.t (a int)")
+	mustExec("insert test.t values (1), (2)")
+
+	// The same scan of the same snapshot is served from the cache.
+	mustExec("begin")
+	c.Assert(count(), Equals, int64(2))
+	sent := atomic.LoadInt64(&client.copCount)
+	c.Assert(count(), Equals, int64(2))
+	c.Assert(atomic.LoadInt64(&client.copCount), Equals, sent)
+	mustExec("commit")
+
+	// The scan of a new snapshot reads the new data.
+	mustExec("insert test.t values (3)")
+	c.Assert(count(), Equals, int64(3))
+	c.Assert(atomic.LoadInt64(&client.copCount), Greater, sent)
+}
@@ -14,9 +14,22 @@
 package tikv
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
 	goctx "golang.org/x/net/context"
 )
 
@@ -162,3 +175,255 @@ func (s *testCoprocessorSuite) checkEqual(c *C, copRanges *copRanges, ranges []k
 		}
 	}
 }
+
+// tableCopClient counts the coprocessor requests of a table.
+type tableCopClient struct {
+	Client
+	prefix   atomic.Value
+	copCount int64
+}
+
+func (c *tableCopClient) SendCopReq(ctx goctx.Context, addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	if prefix, ok := c.prefix.Load().(kv.Key); ok && len(req.Ranges) > 0 && bytes.HasPrefix(req.Ranges[0].Start, prefix) {
+		atomic.AddInt64(&c.copCount, 1)
+	}
+	return c.Client.SendCopReq(ctx, addr, req, timeout)
+}
+
+func (s *testStoreSuite) TestOrderedLimit(c *C) {
+	mockClient := s.store.client.(*mocktikv.RPCClient)
+	client := &tableCopClient{Client: s.store.client}
+	s.store.client = client
+	_, err := tidb.BootstrapSession(s.store)
+	c.Assert(err, IsNil)
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+
+	query := func(sql string) string {
+		rss, err := session.Execute(sql)
+		c.Assert(err, IsNil, Commentf("sql: %s", sql))
+		if len(rss) == 0 {
+			return ""
+		}
+		var values []string
+		for {
+			row, err := rss[0].Next()
+			c.Assert(err, IsNil)
+			if row == nil {
+				break
+			}
+			values = append(values, fmt.Sprintf("%d", row.Data[0].GetInt64()))
+		}
+		c.Assert(rss[0].Close(), IsNil)
+		return strings.Join(values, " ")
+	}
+	query("create table test.ordered (a int primary key, b int, c int, index idx(b))")
+	var values []string
+	for i := 0; i < 100; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d, %d)", i, i, i))
+	}
+	query("insert test.ordered values " + strings.Join(values, ","))
+
+	// Split the table and the index into 10 regions.
+	is := sessionctx.GetDomain(session).InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("ordered"))
+	c.Assert(err, IsNil)
+	tblInfo := tbl.Meta()
+	mockClient.Cluster.SplitTable(mockClient.MvccStore, tblInfo.ID, 10)
+	mockClient.Cluster.SplitIndex(mockClient.MvccStore, tblInfo.ID, tblInfo.Indices[0].ID, 10)
+	// Scan the table and the index to resolve the locks left by the insert, so they don't cause retries.
+	c.Assert(query("select count(*) from test.ordered"), Equals, "100")
+	c.Assert(query("select count(b) from test.ordered where b >= 0"), Equals, "100")
+	client.prefix.Store(tablecodec.EncodeTablePrefix(tblInfo.ID))
+
+	// The ordered scans with a limit only send the requests of the regions they read.
+	tests := []struct {
+		sql    string
+		result string
+		sent   int64
+	}{
+		{"select b from test.ordered order by b limit 3", "0 1 2", 1},
+		{"select b from test.ordered order by b desc limit 2, 3", "97 96 95", 1},
+		{"select c from test.ordered order by b limit 3", "0 1 2", 2},
+		{"select a from test.ordered order by a limit 3", "0 1 2", 1},
+		{"select a from test.ordered order by a limit 8, 4", "8 9 10 11", 2},
+	}
+	for _, t := range tests {
+		sent := atomic.LoadInt64(&client.copCount)
+		c.Assert(query(t.sql), Equals, t.result, Commentf("sql: %s", t.sql))
+		c.Assert(atomic.LoadInt64(&client.copCount)-sent, Equals, t.sent, Commentf("sql: %s", t.sql))
+	}
+
+	// The unordered scan sends the requests of all the regions.
+	sent := atomic.LoadInt64(&client.copCount)
+	c.Assert(query("select count(*) from test.ordered"), Equals, "100")
+	c.Assert(atomic.LoadInt64(&client.copCount)-sent, Equals, int64(10))
+}
+
+func (s *testCoprocessorSuite) TestCutCopRanges(c *C) {
+	ranges := &copRanges{mid: []kv.KeyRange{
+		{StartKey: []byte("a"), EndKey: []byte("c")},
+		{StartKey: []byte("d"), EndKey: []byte("f")},
+	}}
+	s.checkEqual(c, cutCopRanges(ranges, kv.Key("b"), false), []kv.KeyRange{
+		{StartKey: []byte("b\x00"), EndKey: []byte("c")},
+		{StartKey: []byte("d"), EndKey: []byte("f")},
+	}, false)
+	s.checkEqual(c, cutCopRanges(ranges, kv.Key("c"), false), []kv.KeyRange{
+		{StartKey: []byte("d"), EndKey: []byte("f")},
+	}, false)
+	s.checkEqual(c, cutCopRanges(ranges, kv.Key("e"), true), []kv.KeyRange{
+		{StartKey: []byte("a"), EndKey: []byte("c")},
+		{StartKey: []byte("d"), EndKey: []byte("e")},
+	}, false)
+	s.checkEqual(c, cutCopRanges(ranges, kv.Key("a"), true), nil, false)
+}
+
+func (s *testStoreSuite) TestCopStreaming(c *C) {
+	defer func(rows int64) { copStreamingRows = rows }(copStreamingRows)
+	copStreamingRows = 3
+	mockClient := s.store.client.(*mocktikv.RPCClient)
+	client := &tableCopClient{Client: s.store.client}
+	s.store.client = client
+	_, err := tidb.BootstrapSession(s.store)
+	c.Assert(err, IsNil)
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+
+	query := func(sql string) string {
+		rss, err := session.Execute(sql)
+		c.Assert(err, IsNil, Commentf("sql: %s", sql))
+		if len(rss) == 0 {
+			return ""
+		}
+		var values []string
+		for {
+			row, err := rss[0].Next()
+			c.Assert(err, IsNil)
+			if row == nil {
+				break
+			}
+			values = append(values, fmt.Sprintf("%d", row.Data[0].GetInt64()))
+		}
+		c.Assert(rss[0].Close(), IsNil)
+		return strings.Join(values, " ")
+	}
+	query("create table test.streaming (a int primary key, b int)")
+	var values []string
+	for i := 0; i < 20; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i))
+	}
+	query("insert test.streaming values " + strings.Join(values, ","))
+
+	// Split the table into 2 regions.
+	is := sessionctx.GetDomain(session).InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("streaming"))
+	c.Assert(err, IsNil)
+	mockClient.Cluster.SplitTable(mockClient.MvccStore, tbl.Meta().ID, 2)
+	c.Assert(query("select count(*) from test.streaming"), Equals, "20")
+	client.prefix.Store(tablecodec.EncodeTablePrefix(tbl.Meta().ID))
+
+	// Every request returns at most 3 rows, the next request starts from the last row.
+	query("set @@tidb_enable_streaming = 1")
+	tests := []struct {
+		sql    string
+		result string
+		sent   int64
+	}{
+		{"select a from test.streaming where b < 15 order by a", "0 1 2 3 4 5 6 7 8 9 10 11 12 13 14", 6},
+		{"select a from test.streaming where b >= 8 order by a desc", "19 18 17 16 15 14 13 12 11 10 9 8", 5},
+		{"select count(*) from test.streaming", "20", 2},
+		{"select a from test.streaming order by a limit 4", "0 1 2 3", 1},
+	}
+	for _, t := range tests {
+		sent := atomic.LoadInt64(&client.copCount)
+		c.Assert(query(t.sql), Equals, t.result, Commentf("sql: %s", t.sql))
+		c.Assert(atomic.LoadInt64(&client.copCount)-sent, Equals, t.sent, Commentf("sql: %s", t.sql))
+	}
+}
+
+// blockCopClient blocks the coprocessor requests of a table until they're canceled.
+type blockCopClient struct {
+	Client
+	prefix  atomic.Value
+	blocked chan struct{}
+}
+
+func (c *blockCopClient) SendCopReq(ctx goctx.Context, addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	if prefix, ok := c.prefix.Load().(kv.Key); ok && len(req.Ranges) > 0 && bytes.HasPrefix(req.Ranges[0].Start, prefix) {
+		select {
+		case c.blocked <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+	}
+	return c.Client.SendCopReq(ctx, addr, req, timeout)
+}
+
+func (s *testStoreSuite) TestCancelCop(c *C) {
+	mockClient := s.store.client.(*mocktikv.RPCClient)
+	client := &blockCopClient{Client: s.store.client, blocked: make(chan struct{}, 1)}
+	s.store.client = client
+	_, err := tidb.BootstrapSession(s.store)
+	c.Assert(err, IsNil)
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+
+	query := func(sql string) (int, error) {
+		rss, err := session.Execute(sql)
+		c.Assert(err, IsNil, Commentf("sql: %s", sql))
+		if len(rss) == 0 {
+			return 0, nil
+		}
+		defer rss[0].Close()
+		var count int
+		for {
+			row, err := rss[0].Next()
+			if err != nil {
+				return count, err
+			}
+			if row == nil {
+				return count, nil
+			}
+			count++
+		}
+	}
+	_, err = query("create table test.canceled (a int primary key, b int)")
+	c.Assert(err, IsNil)
+	var values []string
+	for i := 0; i < 20; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i))
+	}
+	_, err = query("insert test.canceled values " + strings.Join(values, ","))
+	c.Assert(err, IsNil)
+	is := sessionctx.GetDomain(session).InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("canceled"))
+	c.Assert(err, IsNil)
+	mockClient.Cluster.SplitTable(mockClient.MvccStore, tbl.Meta().ID, 20)
+	prefix := tablecodec.EncodeTablePrefix(tbl.Meta().ID)
+
+	// The canceled statements fail rather than return the rows read before the cancellation, the ordered scan
+	// doesn't wait for the responses of the requests which are never sent.
+	for _, sql := range []string{"select a from test.canceled order by a", "select count(*) from test.canceled"} {
+		client.prefix.Store(prefix)
+		done := make(chan error, 1)
+		go func() {
+			_, err := query(sql)
+			done <- err
+		}()
+		<-client.blocked
+		session.Cancel()
+		select {
+		case err = <-done:
+			c.Assert(terror.ErrorEqual(err, executor.ErrQueryInterrupted), IsTrue, Commentf("sql: %s, err: %v", sql, err))
+		case <-time.After(5 * time.Second):
+			c.Fatalf("the canceled statement doesn't return, sql: %s", sql)
+		}
+
+		// The cancellation doesn't affect the next statement.
+		client.prefix.Store(kv.Key("unused"))
+		count, err := query(sql)
+		c.Assert(err, IsNil)
+		c.Assert(count > 0, IsTrue)
+	}
+}
@@ -25,6 +25,8 @@ var (
 	errInvalidResponse = errors.New("invalid response")
 	// errBodyMissing response body is missing error
 	errBodyMissing = errors.New("response body is missing")
+	// errSplitRegionNotSupported is returned when the regions can't be split by the client.
+	errSplitRegionNotSupported = errors.New("split region is not supported by the tikv client")
 )
 
 // TiDB decides whether to retry transaction by checking if error message contains
@@ -17,6 +17,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -41,6 +43,11 @@ type GCWorker struct {
 	lastFinish  time.Time
 	quit        chan struct{}
 	done        chan error
+
+	// status describes the progress of the running GC job, it's updated by the job and saved to the system table
+	// by the goroutine which owns the session.
+	statusMu sync.Mutex
+	status   string
 }
 
 // NewGCWorker creates a GCWorker instance.
@@ -87,7 +94,18 @@ const (
 
 	gcLifeTimeKey     = "tikv_gc_life_time"
 	gcDefaultLifeTime = time.Minute * 10
+	gcMinLifeTime     = time.Minute * 10
 	gcSafePointKey    = "tikv_gc_safe_point"
+
+	gcStatusKey         = "tikv_gc_status"
+	gcLastFinishTimeKey = "tikv_gc_last_finish_time"
+
+	// Every GC worker reports the start timestamp of the oldest running transaction of its server in the row
+	// gcMinStartTSKeyPrefix + uuid, and the leader never moves the safe point beyond the reported timestamps.
+	gcMinStartTSKeyPrefix = "tikv_gc_min_start_ts_"
+	// gcMaxWaitTime is how long the GC waits for a running transaction at most, so a leaked transaction can't
+	// stop the GC forever.
+	gcMaxWaitTime = time.Hour * 24
 )
 
 var gcVariableComments = map[string]string{
@@ -95,9 +113,57 @@ var gcVariableComments = map[string]string{
 	gcLeaderDescKey:  "Host name and pid of current GC leader. (DO NOT EDIT)",
 	gcLeaderLeaseKey: "Current GC worker leader lease. (DO NOT EDIT)",
 	gcLastRunTimeKey: "The time when last GC starts. (DO NOT EDIT)",
-	gcRunIntervalKey: "GC run interval, at least 10m, in Go format.",
+	gcRunIntervalKey: "GC run interval, in Go format.",
 	gcLifeTimeKey:    "All versions within life time will not be collected by GC, at least 10m, in Go format.",
 	gcSafePointKey:   "All versions after safe point can be accessed. (DO NOT EDIT)",
+	gcStatusKey:      "The progress of the current or the last GC job. (DO NOT EDIT)",
+
+	gcLastFinishTimeKey:   "The time when last GC finishes. (DO NOT EDIT)",
+	gcMinStartTSKeyPrefix: "The start timestamp and the report time of the oldest running transaction of a server. (DO NOT EDIT)",
+}
+
+// runningTxnTable counts the running transactions and the snapshots in use of this server by their start timestamps.
+type runningTxnTable struct {
+	mu   sync.Mutex
+	txns map[uint64]int
+}
+
+func newRunningTxnTable() *runningTxnTable {
+	return &runningTxnTable{txns: make(map[uint64]int)}
+}
+
+func (t *runningTxnTable) add(startTS uint64) {
+	t.mu.Lock()
+	t.txns[startTS]++
+	t.mu.Unlock()
+}
+
+func (t *runningTxnTable) remove(startTS uint64) {
+	t.mu.Lock()
+	if t.txns[startTS] <= 1 {
+		delete(t.txns, startTS)
+	} else {
+		t.txns[startTS]--
+	}
+	t.mu.Unlock()
+}
+
+// minStartTS returns the smallest start timestamp of the running transactions, or 0 if there is no one. The
+// transactions started before expireTS are treated as leaked and forgotten.
+func (t *runningTxnTable) minStartTS(expireTS uint64) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var minTS uint64
+	for startTS := range t.txns {
+		if startTS < expireTS {
+			delete(t.txns, startTS)
+			continue
+		}
+		if minTS == 0 || startTS < minTS {
+			minTS = startTS
+		}
+	}
+	return minTS
 }
 
 func (w *GCWorker) start() {
@@ -120,6 +186,10 @@ func (w *GCWorker) start() {
 				privilege.BindPrivilegeChecker(w.session, nil)
 			}
 
+			err := w.reportMinStartTS()
+			if err != nil {
+				log.Warnf("[gc worker] report min start ts err: %v", err)
+			}
 			isLeader, err := w.checkLeader()
 			if err != nil {
 				log.Warnf("[gc worker] check leader err: %v", err)
@@ -140,8 +210,19 @@ func (w *GCWorker) start() {
 			w.lastFinish = time.Now()
 			if err != nil {
 				log.Errorf("[gc worker] runGCJob error: %v", err)
+				w.setStatus("failed: %v", err)
+			} else {
+				w.setStatus("finished")
+			}
+			err = w.saveStatus()
+			if err != nil {
+				log.Warnf("[gc worker] save status err: %v", err)
 				break
 			}
+			err = w.saveTime(gcLastFinishTimeKey, w.lastFinish)
+			if err != nil {
+				log.Warnf("[gc worker] save finish time err: %v", err)
+			}
 		case <-w.quit:
 			log.Infof("[gc worker] (%s) quit.", w.uuid)
 			return
@@ -169,7 +250,7 @@ func (w *GCWorker) storeIsBootstrapped() bool {
 // Leader of GC worker checks if it should start a GC job every tick.
 func (w *GCWorker) leaderTick() error {
 	if w.gcIsRunning {
-		return nil
+		return errors.Trace(w.saveStatus())
 	}
 	// When the worker is just started, or an old GC job has just finished,
 	// wait a while before starting a new job.
@@ -184,10 +265,30 @@ func (w *GCWorker) leaderTick() error {
 
 	w.gcIsRunning = true
 	log.Infof("[gc worker] %s starts GC job, safePoint: %v", w.uuid, safePoint)
+	w.setStatus("started, safe point: %v", safePoint)
+	err = w.saveStatus()
+	if err != nil {
+		log.Warnf("[gc worker] save status err: %v", err)
+	}
 	go w.runGCJob(safePoint)
 	return nil
 }
 
+func (w *GCWorker) setStatus(format string, args ...interface{}) {
+	w.statusMu.Lock()
+	w.status = fmt.Sprintf(format, args...)
+	w.statusMu.Unlock()
+}
+
+func (w *GCWorker) saveStatus() error {
+	w.statusMu.Lock()
+	status := w.status
+	w.statusMu.Unlock()
+	// The status may contain an error message, escape it for the SQL statement.
+	status = strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(status)
+	return errors.Trace(w.saveValueToSysTable(gcStatusKey, status))
+}
+
 // prepare checks required conditions for starting a GC job. It returns a bool
 // that indicates whether the GC job should start and the new safePoint.
 func (w *GCWorker) prepare() (bool, uint64, error) {
@@ -245,12 +346,29 @@ func (w *GCWorker) calculateNewSafePoint(now time.Time) (*time.Time, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if *lifeTime < gcMinLifeTime {
+		log.Warnf("[gc worker] %s is too small, use %s instead.", gcLifeTimeKey, gcMinLifeTime)
+		*lifeTime = gcMinLifeTime
+	}
 	gcConfigGauge.WithLabelValues(gcLifeTimeKey).Set(float64(lifeTime.Seconds()))
 	lastSafePoint, err := w.loadTime(gcSafePointKey)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	safePoint := now.Add(-*lifeTime)
+	// The versions read by the running transactions should be kept.
+	minStartTS, err := w.loadMinStartTS(now)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if minStartTS != 0 {
+		startTime := time.Unix(0, oracle.ExtractPhysical(minStartTS)*int64(time.Millisecond))
+		if startTime.Before(safePoint) {
+			log.Infof("[gc worker] %s safe point is held by the transaction %d", w.uuid, minStartTS)
+			gcWorkerCounter.WithLabelValues("wait_txn").Inc()
+			safePoint = startTime
+		}
+	}
 	// We should never decrease safePoint.
 	if lastSafePoint != nil && safePoint.Before(*lastSafePoint) {
 		return nil, nil
@@ -269,10 +387,74 @@ func (w *GCWorker) runGCJob(safePoint uint64) {
 	err = w.DoGC(safePoint)
 	if err != nil {
 		w.done <- errors.Trace(err)
+		return
 	}
 	w.done <- nil
 }
 
+// reportMinStartTS saves the start timestamp of the oldest running transaction of this server, so the leader
+// won't collect the versions it reads.
+func (w *GCWorker) reportMinStartTS() error {
+	now, err := w.getOracleTime()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	minStartTS := w.store.runningTxns.minStartTS(oracle.ComposeTS(oracle.GetPhysical(now.Add(-gcMaxWaitTime)), 0))
+	key := gcMinStartTSKeyPrefix + w.uuid
+	if minStartTS == 0 {
+		stmt := fmt.Sprintf(`DELETE FROM mysql.tidb WHERE variable_name='%s'`, key)
+		_, err = w.session.(sqlexec.SQLExecutor).Execute(stmt)
+		return errors.Trace(err)
+	}
+	value := fmt.Sprintf("%d %s", minStartTS, time.Now().Format(gcTimeFormat))
+	return errors.Trace(w.saveValueToSysTable(key, value))
+}
+
+// loadMinStartTS returns the start timestamp of the oldest running transaction of all the servers, or 0 if there
+// is no one. The reports which are not refreshed in a lease are ignored, because their servers may be down.
+func (w *GCWorker) loadMinStartTS(now time.Time) (uint64, error) {
+	expireTS := oracle.ComposeTS(oracle.GetPhysical(now.Add(-gcMaxWaitTime)), 0)
+	minStartTS := w.store.runningTxns.minStartTS(expireTS)
+
+	stmt := fmt.Sprintf(`SELECT variable_name, variable_value FROM mysql.tidb WHERE variable_name LIKE '%s%%'`, gcMinStartTSKeyPrefix)
+	rs, err := w.session.(sqlexec.SQLExecutor).Execute(stmt)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	for {
+		row, err := rs[0].Next()
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		name, value := row.Data[0].GetString(), row.Data[1].GetString()
+		fields := strings.SplitN(value, " ", 2)
+		if len(fields) != 2 {
+			log.Warnf("[gc worker] invalid report %s:%s", name, value)
+			continue
+		}
+		startTS, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			log.Warnf("[gc worker] invalid report %s:%s", name, value)
+			continue
+		}
+		reportTime, err := time.Parse(gcTimeFormat, fields[1])
+		if err != nil {
+			log.Warnf("[gc worker] invalid report %s:%s", name, value)
+			continue
+		}
+		if time.Since(reportTime) > gcWorkerLease || startTS < expireTS {
+			continue
+		}
+		if minStartTS == 0 || startTS < minStartTS {
+			minStartTS = startTS
+		}
+	}
+	return minStartTS, nil
+}
+
 func (w *GCWorker) resolveLocks(safePoint uint64) error {
 	gcWorkerCounter.WithLabelValues("resolve_locks").Inc()
 
@@ -336,6 +518,7 @@ func (w *GCWorker) resolveLocks(safePoint uint64) error {
 		}
 		regions++
 		totalResolvedLocks += len(locks)
+		w.setStatus("resolving locks, safe point: %v, regions: %d, resolved locks: %d", safePoint, regions, totalResolvedLocks)
 		key = loc.EndKey
 		if len(key) == 0 {
 			break
@@ -393,6 +576,7 @@ func (w *GCWorker) DoGC(safePoint uint64) error {
 			return errors.Errorf("unexpected gc error: %s", gcResp.GetError())
 		}
 		regions++
+		w.setStatus("doing gc, safe point: %v, regions: %d", safePoint, regions)
 		key = loc.EndKey
 		if len(key) == 0 {
 			break
@@ -536,11 +720,41 @@ func (w *GCWorker) loadValueFromSysTable(key string) (string, error) {
 }
 
 func (w *GCWorker) saveValueToSysTable(key, value string) error {
+	return errors.Trace(saveGCValue(w.session.(sqlexec.SQLExecutor), key, value))
+}
+
+func saveGCValue(exec sqlexec.SQLExecutor, key, value string) error {
+	comment := gcVariableComments[key]
+	if strings.HasPrefix(key, gcMinStartTSKeyPrefix) {
+		comment = gcVariableComments[gcMinStartTSKeyPrefix]
+	}
 	stmt := fmt.Sprintf(`INSERT INTO mysql.tidb VALUES ('%[1]s', '%[2]s', '%[3]s')
 			       ON DUPLICATE KEY
 			       UPDATE variable_value = '%[2]s', comment = '%[3]s'`,
-		key, value, gcVariableComments[key])
-	_, err := w.session.(sqlexec.SQLExecutor).Execute(stmt)
+		key, value, comment)
+	_, err := exec.Execute(stmt)
 	log.Debugf("[gc worker] save kv, %s:%s %v", key, value, err)
 	return errors.Trace(err)
 }
+
+// GCSettingKeys are the names of the GC settings which can be changed at runtime, the GC worker loads them from
+// mysql.tidb every time it checks if the GC should run.
+var GCSettingKeys = []string{gcRunIntervalKey, gcLifeTimeKey}
+
+// SaveGCSetting checks the value of the GC setting and saves it to mysql.tidb by exec.
+func SaveGCSetting(exec sqlexec.SQLExecutor, key, value string) error {
+	if key != gcRunIntervalKey && key != gcLifeTimeKey {
+		return errors.Errorf("unknown GC setting %s", key)
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if d <= 0 {
+		return errors.Errorf("%s must be positive", key)
+	}
+	if key == gcLifeTimeKey && d < gcMinLifeTime {
+		return errors.Errorf("%s must be at least %s", key, gcMinLifeTime)
+	}
+	return errors.Trace(saveGCValue(exec, key, d.String()))
+}
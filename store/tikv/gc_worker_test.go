@@ -14,11 +14,14 @@
 package tikv
 
 import (
+	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 )
 
 type testGCWorkerSuite struct {
@@ -107,6 +110,32 @@ func (s *testGCWorkerSuite) TestPrepareGC(c *C) {
 	s.timeEqual(c, safePoint.Add(time.Minute*30), now, time.Second)
 }
 
+func (s *testGCWorkerSuite) TestSaveGCSetting(c *C) {
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	defer session.Close()
+	s.gcWorker.session = session
+
+	err = SaveGCSetting(session, gcLifeTimeKey, "1h")
+	c.Assert(err, IsNil)
+	lifeTime, err := s.gcWorker.loadDuration(gcLifeTimeKey)
+	c.Assert(err, IsNil)
+	c.Assert(*lifeTime, Equals, time.Hour)
+	err = SaveGCSetting(session, gcRunIntervalKey, "20m")
+	c.Assert(err, IsNil)
+	runInterval, err := s.gcWorker.loadDuration(gcRunIntervalKey)
+	c.Assert(err, IsNil)
+	c.Assert(*runInterval, Equals, 20*time.Minute)
+
+	c.Assert(SaveGCSetting(session, gcLifeTimeKey, "1m"), NotNil)
+	c.Assert(SaveGCSetting(session, gcRunIntervalKey, "xxx"), NotNil)
+	c.Assert(SaveGCSetting(session, gcRunIntervalKey, "-1m"), NotNil)
+	c.Assert(SaveGCSetting(session, gcSafePointKey, "1h"), NotNil)
+	lifeTime, err = s.gcWorker.loadDuration(gcLifeTimeKey)
+	c.Assert(err, IsNil)
+	c.Assert(*lifeTime, Equals, time.Hour)
+}
+
 func (s *testGCWorkerSuite) TestBootstrapped(c *C) {
 	store := newTestStore(c)
 	store.oracle = &mockOracle{}
@@ -117,3 +146,104 @@ func (s *testGCWorkerSuite) TestBootstrapped(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(gcWorker.storeIsBootstrapped(), IsTrue)
 }
+
+func (s *testGCWorkerSuite) TestMinLifeTime(c *C) {
+	now, err := s.gcWorker.getOracleTime()
+	c.Assert(err, IsNil)
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	s.gcWorker.session = session
+	err = s.gcWorker.saveDuration(gcLifeTimeKey, time.Minute)
+	c.Assert(err, IsNil)
+	safePoint, err := s.gcWorker.calculateNewSafePoint(now)
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, NotNil)
+	s.timeEqual(c, safePoint.Add(gcMinLifeTime), now, time.Second)
+}
+
+func (s *testGCWorkerSuite) TestRunningTxnHoldsSafePoint(c *C) {
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	s.gcWorker.session = session
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+
+	// The safe point doesn't pass the running transaction.
+	s.oracle.addOffset(time.Minute * 30)
+	now, err := s.gcWorker.getOracleTime()
+	c.Assert(err, IsNil)
+	safePoint, err := s.gcWorker.calculateNewSafePoint(now)
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, NotNil)
+	c.Assert(oracle.ComposeTS(oracle.GetPhysical(*safePoint), 0), LessEqual, txn.StartTS())
+
+	// The transaction reported by another server holds the safe point too.
+	err = txn.Rollback()
+	c.Assert(err, IsNil)
+	reportKey := gcMinStartTSKeyPrefix + "another"
+	err = s.gcWorker.saveValueToSysTable(reportKey, fmt.Sprintf("%d %s", txn.StartTS(), time.Now().Format(gcTimeFormat)))
+	c.Assert(err, IsNil)
+	safePoint, err = s.gcWorker.calculateNewSafePoint(now)
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, NotNil)
+	c.Assert(oracle.ComposeTS(oracle.GetPhysical(*safePoint), 0), LessEqual, txn.StartTS())
+
+	// The stale report is ignored.
+	staleTime := time.Now().Add(-gcWorkerLease * 2)
+	err = s.gcWorker.saveValueToSysTable(reportKey, fmt.Sprintf("%d %s", txn.StartTS(), staleTime.Format(gcTimeFormat)))
+	c.Assert(err, IsNil)
+	safePoint, err = s.gcWorker.calculateNewSafePoint(now)
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, NotNil)
+	s.timeEqual(c, safePoint.Add(gcDefaultLifeTime), now, time.Second)
+}
+
+func (s *testGCWorkerSuite) TestSnapshotHoldsSafePoint(c *C) {
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	s.gcWorker.session = session
+	ver, err := s.store.CurrentVersion()
+	c.Assert(err, IsNil)
+	snapshot, err := s.store.GetSnapshot(ver)
+	c.Assert(err, IsNil)
+
+	// The safe point doesn't pass the snapshot in use.
+	s.oracle.addOffset(time.Minute * 30)
+	now, err := s.gcWorker.getOracleTime()
+	c.Assert(err, IsNil)
+	safePoint, err := s.gcWorker.calculateNewSafePoint(now)
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, NotNil)
+	c.Assert(oracle.ComposeTS(oracle.GetPhysical(*safePoint), 0), LessEqual, ver.Ver)
+
+	// The safe point moves on after the snapshot is released, releasing it twice does nothing.
+	snapshot.Release()
+	snapshot.Release()
+	safePoint, err = s.gcWorker.calculateNewSafePoint(now)
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, NotNil)
+	s.timeEqual(c, safePoint.Add(gcDefaultLifeTime), now, time.Second)
+}
+
+func (s *testGCWorkerSuite) TestReportMinStartTS(c *C) {
+	session, err := tidb.CreateSession(s.store)
+	c.Assert(err, IsNil)
+	s.gcWorker.session = session
+	reportKey := gcMinStartTSKeyPrefix + s.gcWorker.uuid
+
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	err = s.gcWorker.reportMinStartTS()
+	c.Assert(err, IsNil)
+	report, err := s.gcWorker.loadValueFromSysTable(reportKey)
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(report, fmt.Sprintf("%d ", txn.StartTS())), IsTrue)
+
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	err = s.gcWorker.reportMinStartTS()
+	c.Assert(err, IsNil)
+	report, err = s.gcWorker.loadValueFromSysTable(reportKey)
+	c.Assert(err, IsNil)
+	c.Assert(report, Equals, "")
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/pingcap/tidb/store/tikv/oracle/oracles"
+	"github.com/pingcap/tidb/util/hotregion"
 	goctx "golang.org/x/net/context"
 )
 
@@ -79,6 +80,11 @@ func (d Driver) Open(path string) (kv.Storage, error) {
 // update oracle's lastTS every 2000ms.
 var oracleUpdateInterval = 2000
 
+// lowPriorityConcurrency is the maximum number of the low priority requests that a store sends concurrently.
+// TiKV doesn't schedule the requests by priority, so the low priority requests of the background jobs are
+// limited here, they can't use up the resources of TiKV and starve the foreground requests.
+var lowPriorityConcurrency = 4
+
 type tikvStore struct {
 	clusterID    uint64
 	uuid         string
@@ -87,6 +93,18 @@ type tikvStore struct {
 	regionCache  *RegionCache
 	lockResolver *LockResolver
 	gcWorker     *GCWorker
+	coprCache    *coprCache
+	// lowPriorityCh limits the number of the low priority requests that are sent concurrently.
+	lowPriorityCh chan struct{}
+	// pessimisticLocks holds the keys locked by the pessimistic transactions.
+	pessimisticLocks *pessimisticLockTable
+	// runningTxns holds the start timestamps of the running transactions and the versions of the snapshots in use,
+	// the GC worker keeps the versions they read.
+	runningTxns *runningTxnTable
+	// txnLatches serializes the commits of the transactions which write the same keys, it's nil if disabled.
+	txnLatches *latches
+	// hotRegions records the accesses of the regions and keys.
+	hotRegions *hotregion.Recorder
 }
 
 func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool) (*tikvStore, error) {
@@ -96,13 +114,24 @@ func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool)
 	}
 
 	store := &tikvStore{
-		clusterID:   pdClient.GetClusterID(goctx.TODO()),
-		uuid:        uuid,
-		oracle:      oracle,
-		client:      client,
-		regionCache: NewRegionCache(pdClient),
+		clusterID:     pdClient.GetClusterID(goctx.TODO()),
+		uuid:          uuid,
+		oracle:        oracle,
+		client:        client,
+		regionCache:   NewRegionCache(pdClient),
+		lowPriorityCh: make(chan struct{}, lowPriorityConcurrency),
+
+		pessimisticLocks: newPessimisticLockTable(),
+		runningTxns:      newRunningTxnTable(),
+		hotRegions:       hotregion.NewRecorder(),
 	}
 	store.lockResolver = newLockResolver(store)
+	if CoprCacheCapacity > 0 {
+		store.coprCache = newCoprCache(CoprCacheCapacity)
+	}
+	if TxnLatchCapacity > 0 {
+		store.txnLatches = newLatches(TxnLatchCapacity)
+	}
 	if enableGC {
 		store.gcWorker, err = NewGCWorker(store)
 		if err != nil {
@@ -159,8 +188,11 @@ func (s *tikvStore) BeginWithStartTS(startTS uint64) (kv.Transaction, error) {
 	return txn, nil
 }
 
+// GetSnapshot gets a snapshot of the version, the GC worker keeps the versions it reads until it's released.
 func (s *tikvStore) GetSnapshot(ver kv.Version) (kv.Snapshot, error) {
 	snapshot := newTiKVSnapshot(s, ver)
+	s.runningTxns.add(ver.Ver)
+	snapshot.held = 1
 	snapshotCounter.Inc()
 	return snapshot, nil
 }
@@ -185,6 +217,35 @@ func (s *tikvStore) UUID() string {
 	return s.uuid
 }
 
+// regionSplitter is implemented by the clients which can split the regions.
+type regionSplitter interface {
+	SplitRegion(key []byte) error
+}
+
+// SplitRegion implements kv.SplittableStore interface. Only the mock tikv client can split the regions, there is no
+// split request in the TiKV protocol yet, so errSplitRegionNotSupported is returned for the real cluster.
+func (s *tikvStore) SplitRegion(splitKey kv.Key) error {
+	splitter, ok := s.client.(regionSplitter)
+	if !ok {
+		return errors.Trace(errSplitRegionNotSupported)
+	}
+	bo := NewBackoffer(splitRegionMaxBackoff, goctx.Background())
+	loc, err := s.regionCache.LocateKey(bo, splitKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = splitter.SplitRegion(splitKey); err != nil {
+		return errors.Trace(err)
+	}
+	s.regionCache.DropRegion(loc.Region)
+	return nil
+}
+
+// HotRegions returns the recorder of the region and key accesses of the store.
+func (s *tikvStore) HotRegions() *hotregion.Recorder {
+	return s.hotRegions
+}
+
 func (s *tikvStore) CurrentVersion() (kv.Version, error) {
 	bo := NewBackoffer(tsoMaxBackoff, goctx.Background())
 	startTS, err := s.getTimestampWithRetry(bo)
@@ -214,11 +275,39 @@ func (s *tikvStore) GetClient() kv.Client {
 	}
 }
 
+// SupportCapability implements kv.Storage SupportCapability interface. The regions can only be split by the mock tikv
+// client, see SplitRegion.
+func (s *tikvStore) SupportCapability(c kv.Capability) bool {
+	switch c {
+	case kv.CapCoprocessor, kv.CapFollowerRead, kv.CapRawKV:
+		return true
+	case kv.CapSplitRegion:
+		_, ok := s.client.(regionSplitter)
+		return ok
+	}
+	return false
+}
+
 func (s *tikvStore) SendKVReq(bo *Backoffer, req *pb.Request, regionID RegionVerID, timeout time.Duration) (*pb.Response, error) {
 	sender := NewRegionRequestSender(bo, s.regionCache, s.client)
 	return sender.SendKVReq(req, regionID, timeout)
 }
 
+// acquirePriority waits for the request to be sent by its priority, the low priority request
+// waits until the running low priority requests are less than lowPriorityConcurrency.
+func (s *tikvStore) acquirePriority(priority int) {
+	if priority == kv.PriorityLow {
+		s.lowPriorityCh <- struct{}{}
+	}
+}
+
+// releasePriority is called after the request acquired by acquirePriority is done.
+func (s *tikvStore) releasePriority(priority int) {
+	if priority == kv.PriorityLow {
+		<-s.lowPriorityCh
+	}
+}
+
 // ParseEtcdAddr parses path to etcd address list
 func ParseEtcdAddr(path string) (etcdAddrs []string, err error) {
 	etcdAddrs, _, err = parsePath(path)
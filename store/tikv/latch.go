@@ -0,0 +1,115 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/kv"
+)
+
+// TxnLatchCapacity is the number of the latch slots of a tikv store, 0 disables the latches.
+// It should be set before the store is opened.
+var TxnLatchCapacity int
+
+// latch is a slot of the latches, the keys hashed to the slot share it.
+type latch struct {
+	locked bool
+	// maxCommitTS is the max commit ts of the transactions which held the latch.
+	maxCommitTS uint64
+	// waiters are closed in turn when the latch is released, the latch is handed over to the first waiter.
+	waiters []chan struct{}
+}
+
+// latches serialize the commits of the transactions of this server which write the same keys, before they
+// prewrite. A transaction waits for the one holding its keys instead of conflicting with it in TiKV, and it gives
+// up early if its keys are committed by another transaction after it starts, so the hot keys, like counters, are
+// retried without the failed prewrites.
+// The keys are hashed to the slots, two keys in the same slot are treated as the same key, which may make a
+// transaction retry without a real conflict. The transactions of the other servers are still checked by 2PC.
+type latches struct {
+	mu    sync.Mutex
+	slots []latch
+}
+
+func newLatches(capacity int) *latches {
+	return &latches{slots: make([]latch, capacity)}
+}
+
+// slotIDs returns the sorted slots of the keys, the slots are acquired in order, so the transactions never wait
+// for each other.
+func (l *latches) slotIDs(keys [][]byte) []int {
+	ids := make([]int, 0, len(keys))
+	seen := make(map[int]struct{}, len(keys))
+	for _, key := range keys {
+		h := fnv.New32a()
+		h.Write(key)
+		id := int(h.Sum32() % uint32(len(l.slots)))
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// acquire acquires the latches of the keys for the transaction startTS, it waits for the transactions holding
+// them. It returns kv.ErrWriteConflict if a key may be committed by another transaction after startTS, the
+// transaction should retry then.
+func (l *latches) acquire(startTS uint64, keys [][]byte) ([]int, error) {
+	ids := l.slotIDs(keys)
+	for i, id := range ids {
+		l.mu.Lock()
+		s := &l.slots[id]
+		if s.locked {
+			waiter := make(chan struct{})
+			s.waiters = append(s.waiters, waiter)
+			l.mu.Unlock()
+			<-waiter
+			l.mu.Lock()
+		}
+		s.locked = true
+		stale := s.maxCommitTS > startTS
+		l.mu.Unlock()
+		if stale {
+			l.release(ids[:i+1], 0)
+			return nil, errors.Trace(kv.ErrWriteConflict)
+		}
+	}
+	return ids, nil
+}
+
+// release releases the latches, commitTS is 0 if the transaction isn't committed.
+func (l *latches) release(ids []int, commitTS uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		s := &l.slots[id]
+		if commitTS > s.maxCommitTS {
+			s.maxCommitTS = commitTS
+		}
+		if len(s.waiters) == 0 {
+			s.locked = false
+			continue
+		}
+		// The latch is still locked, it's held by the first waiter now.
+		close(s.waiters[0])
+		s.waiters = s.waiters[1:]
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/terror"
+)
+
+var _ = Suite(&testLatchSuite{})
+
+type testLatchSuite struct{}
+
+func (s *testLatchSuite) TestLatches(c *C) {
+	l := newLatches(256)
+	k1, k2 := []byte("k1"), []byte("k2")
+
+	// The slots are sorted and deduplicated.
+	ids := l.slotIDs([][]byte{k2, k1, k2})
+	c.Assert(len(ids) <= 2, IsTrue)
+	for i := 1; i < len(ids); i++ {
+		c.Assert(ids[i-1] < ids[i], IsTrue)
+	}
+
+	// The waiter gets the latches when the owner is rolled back.
+	ids1, err := l.acquire(1, [][]byte{k1, k2})
+	c.Assert(err, IsNil)
+	ch := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(2, [][]byte{k2})
+		ch <- err
+	}()
+	select {
+	case <-ch:
+		c.Fatal("the latch is acquired twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.release(ids1, 0)
+	c.Assert(<-ch, IsNil)
+	l.release(l.slotIDs([][]byte{k2}), 3)
+
+	// The transaction started before the last commit gives up.
+	_, err = l.acquire(2, [][]byte{k1, k2})
+	c.Assert(terror.ErrorEqual(err, kv.ErrWriteConflict), IsTrue)
+	// The latches are released after it gives up.
+	ids4, err := l.acquire(4, [][]byte{k1, k2})
+	c.Assert(err, IsNil)
+	l.release(ids4, 5)
+	for _, slot := range l.slots {
+		c.Assert(slot.locked, IsFalse)
+		c.Assert(slot.waiters, HasLen, 0)
+	}
+}
+
+func (s *testLatchSuite) TestCommitWithLatches(c *C) {
+	store := newTestStore(c)
+	defer store.Close()
+	store.txnLatches = newLatches(1024)
+
+	txn1, err := store.Begin()
+	c.Assert(err, IsNil)
+	txn2, err := store.Begin()
+	c.Assert(err, IsNil)
+	c.Assert(txn1.Set([]byte("counter"), []byte("1")), IsNil)
+	c.Assert(txn2.Set([]byte("counter"), []byte("2")), IsNil)
+	c.Assert(txn1.Commit(), IsNil)
+	// The conflict is found by the latches before prewrite.
+	err = txn2.Commit()
+	c.Assert(terror.ErrorEqual(err, kv.ErrWriteConflict), IsTrue)
+	c.Assert(kv.IsRetryableError(err), IsTrue)
+
+	txn3, err := store.Begin()
+	c.Assert(err, IsNil)
+	c.Assert(txn3.Set([]byte("counter"), []byte("3")), IsNil)
+	c.Assert(txn3.Commit(), IsNil)
+}
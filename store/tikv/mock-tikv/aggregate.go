@@ -18,6 +18,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/distsql/xeval"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
@@ -73,7 +74,9 @@ func (h *rpcHandler) aggregate(ctx *selectContext, handle int64, row map[int64][
 			}
 			args = append(args, cv)
 		}
-		agg.update(ctx, args)
+		if err = agg.update(ctx.sc, args); err != nil {
+			return errors.Trace(err)
+		}
 	}
 	return nil
 }
@@ -106,37 +109,37 @@ func (n *aggregateFuncExpr) clear() {
 }
 
 // Update is used for update aggregate context.
-func (n *aggregateFuncExpr) update(ctx *selectContext, args []types.Datum) error {
+func (n *aggregateFuncExpr) update(sc *variable.StatementContext, args []types.Datum) error {
 	switch n.expr.GetTp() {
 	case tipb.ExprType_Count:
-		return n.updateCount(ctx, args)
+		return n.updateCount(sc, args)
 	case tipb.ExprType_First:
-		return n.updateFirst(ctx, args)
+		return n.updateFirst(sc, args)
 	case tipb.ExprType_Sum, tipb.ExprType_Avg:
-		return n.updateSum(ctx, args)
+		return n.updateSum(sc, args)
 	case tipb.ExprType_Max:
-		return n.updateMaxMin(ctx, args, true)
+		return n.updateMaxMin(sc, args, true)
 	case tipb.ExprType_Min:
-		return n.updateMaxMin(ctx, args, false)
+		return n.updateMaxMin(sc, args, false)
 	}
 	return errors.Errorf("Unknown AggExpr: %v", n.expr.GetTp())
 }
 
-func (n *aggregateFuncExpr) toDatums(ctx *selectContext) (ds []types.Datum, err error) {
+func (n *aggregateFuncExpr) toDatums(sc *variable.StatementContext) (ds []types.Datum, err error) {
 	switch n.expr.GetTp() {
 	case tipb.ExprType_Count:
 		ds = n.getCountDatum()
 	case tipb.ExprType_First, tipb.ExprType_Max, tipb.ExprType_Min:
 		ds = n.getValueDatum()
 	case tipb.ExprType_Sum:
-		d, err := getSumValue(ctx, n.getAggItem())
+		d, err := getSumValue(sc, n.getAggItem())
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		ds = []types.Datum{d}
 	case tipb.ExprType_Avg:
 		item := n.getAggItem()
-		sum, err := getSumValue(ctx, item)
+		sum, err := getSumValue(sc, item)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -146,12 +149,12 @@ func (n *aggregateFuncExpr) toDatums(ctx *selectContext) (ds []types.Datum, err
 	return
 }
 
-func getSumValue(ctx *selectContext, item *aggItem) (types.Datum, error) {
+func getSumValue(sc *variable.StatementContext, item *aggItem) (types.Datum, error) {
 	v := item.value
 	var d types.Datum
 	if !v.IsNull() {
 		// For sum result, we should convert it to decimal.
-		de, err1 := v.ToDecimal(ctx.sc)
+		de, err1 := v.ToDecimal(sc)
 		if err1 != nil {
 			return d, errors.Trace(err1)
 		}
@@ -189,7 +192,7 @@ func (n *aggregateFuncExpr) getAggItem() *aggItem {
 	return n.contextPerGroupMap[string(n.currentGroup)]
 }
 
-func (n *aggregateFuncExpr) updateCount(ctx *selectContext, args []types.Datum) error {
+func (n *aggregateFuncExpr) updateCount(sc *variable.StatementContext, args []types.Datum) error {
 	for _, a := range args {
 		if a.IsNull() {
 			return nil
@@ -200,7 +203,7 @@ func (n *aggregateFuncExpr) updateCount(ctx *selectContext, args []types.Datum)
 	return nil
 }
 
-func (n *aggregateFuncExpr) updateFirst(ctx *selectContext, args []types.Datum) error {
+func (n *aggregateFuncExpr) updateFirst(sc *variable.StatementContext, args []types.Datum) error {
 	aggItem := n.getAggItem()
 	if aggItem.gotFirstRow {
 		return nil
@@ -213,7 +216,7 @@ func (n *aggregateFuncExpr) updateFirst(ctx *selectContext, args []types.Datum)
 	return nil
 }
 
-func (n *aggregateFuncExpr) updateSum(ctx *selectContext, args []types.Datum) error {
+func (n *aggregateFuncExpr) updateSum(sc *variable.StatementContext, args []types.Datum) error {
 	if len(args) != 1 {
 		// This should not happen. The length of argument list is already checked in the early stage.
 		// This is just in case of error.
@@ -230,7 +233,7 @@ func (n *aggregateFuncExpr) updateSum(ctx *selectContext, args []types.Datum) er
 		return nil
 	}
 	var err error
-	aggItem.value, err = xeval.ComputeArithmetic(ctx.sc, tipb.ExprType_Plus, arg, aggItem.value)
+	aggItem.value, err = xeval.ComputeArithmetic(sc, tipb.ExprType_Plus, arg, aggItem.value)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -238,7 +241,7 @@ func (n *aggregateFuncExpr) updateSum(ctx *selectContext, args []types.Datum) er
 	return nil
 }
 
-func (n *aggregateFuncExpr) updateMaxMin(ctx *selectContext, args []types.Datum, max bool) error {
+func (n *aggregateFuncExpr) updateMaxMin(sc *variable.StatementContext, args []types.Datum, max bool) error {
 	if len(args) != 1 {
 		// This should not happen. The length of argument list is already checked in the early stage.
 		// This is just in case of error.
@@ -257,7 +260,7 @@ func (n *aggregateFuncExpr) updateMaxMin(ctx *selectContext, args []types.Datum,
 		aggItem.value = arg
 		return nil
 	}
-	c, err := aggItem.value.CompareDatum(ctx.sc, arg)
+	c, err := aggItem.value.CompareDatum(sc, arg)
 	if err != nil {
 		return errors.Trace(err)
 	}
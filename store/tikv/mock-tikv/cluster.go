@@ -16,6 +16,7 @@ package mocktikv
 import (
 	"bytes"
 	"math"
+	"strings"
 	"sync"
 
 	"github.com/golang/protobuf/proto"
@@ -131,6 +132,18 @@ func (c *Cluster) UpdateStoreAddr(storeID uint64, addr string) {
 	c.stores[storeID] = newStore(storeID, addr)
 }
 
+// UpdateStoreLabels updates the labels of the store, the labels are in the form of "key=value".
+func (c *Cluster) UpdateStoreLabels(storeID uint64, labels ...string) {
+	c.Lock()
+	defer c.Unlock()
+	store := c.stores[storeID]
+	store.meta.Labels = nil
+	for _, l := range labels {
+		pair := strings.SplitN(l, "=", 2)
+		store.meta.Labels = append(store.meta.Labels, &metapb.StoreLabel{Key: pair[0], Value: pair[1]})
+	}
+}
+
 // GetRegion returns a Region's meta and leader ID.
 func (c *Cluster) GetRegion(regionID uint64) (*metapb.Region, uint64) {
 	c.RLock()
@@ -54,7 +54,7 @@ type selectContext struct {
 
 func (h *rpcHandler) handleCopRequest(req *coprocessor.Request) (*coprocessor.Response, error) {
 	resp := &coprocessor.Response{}
-	if err := h.checkContext(req.GetContext()); err != nil {
+	if err := h.checkContext(req.GetContext(), true); err != nil {
 		resp.RegionError = err
 		return resp, nil
 	}
@@ -189,7 +189,7 @@ func (h *rpcHandler) getRowsFromAgg(ctx *selectContext) ([]tipb.Chunk, error) {
 		rowData = append(rowData, types.NewBytesDatum(gk))
 		for _, agg := range ctx.aggregates {
 			agg.currentGroup = gk
-			ds, err := agg.toDatums(ctx)
+			ds, err := agg.toDatums(ctx.sc)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
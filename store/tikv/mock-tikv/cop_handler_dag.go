@@ -49,7 +49,7 @@ func (h *rpcHandler) handleCopDAGRequest(req *coprocessor.Request) (*coprocessor
 	if req.GetTp() != kv.ReqTypeDAG {
 		return resp, nil
 	}
-	if err := h.checkContext(req.GetContext()); err != nil {
+	if err := h.checkContext(req.GetContext(), true); err != nil {
 		resp.RegionError = err
 		return resp, nil
 	}
@@ -150,8 +150,52 @@ func (h *rpcHandler) buildExec(ctx *dagContext, curr *tipb.Executor) (executor,
 			columns:   cols,
 			sc:        ctx.sc,
 		}
+	case tipb.ExecType_TypeAggregation:
+		cols := make(map[int64]*tipb.ColumnInfo)
+		aggregates := make([]*aggregateFuncExpr, 0, len(curr.Aggregation.AggFunc))
+		for _, agg := range curr.Aggregation.AggFunc {
+			aggregates = append(aggregates, &aggregateFuncExpr{expr: agg})
+			if err := extractColumnsInExpr(agg, ctx.columns, cols); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		for _, item := range curr.Aggregation.GroupBy {
+			if err := extractColumnsInExpr(item, ctx.columns, cols); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		currExec = &aggregationExec{
+			Aggregation: curr.Aggregation,
+			sc:          ctx.sc,
+			eval:        ctx.eval,
+			columns:     cols,
+			aggregates:  aggregates,
+			groups:      make(map[string]struct{}),
+		}
+		// The result of a group is encoded by the aggregation executor.
+		ctx.setColumns([]*tipb.ColumnInfo{{ColumnId: aggResultColumnID}})
+	case tipb.ExecType_TypeTopN:
+		cols := make(map[int64]*tipb.ColumnInfo)
+		for _, item := range curr.TopN.OrderBy {
+			if err := extractColumnsInExpr(item.Expr, ctx.columns, cols); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		currExec = &topNExec{
+			heap: &topnHeap{
+				totalCount: int(curr.TopN.GetLimit()),
+				topnSorter: topnSorter{
+					orderByItems: curr.TopN.OrderBy,
+					sc:           ctx.sc,
+				},
+			},
+			eval:    ctx.eval,
+			columns: cols,
+		}
+	case tipb.ExecType_TypeLimit:
+		currExec = &limitExec{limit: uint64(curr.Limit.GetLimit())}
 	default:
-		// TODO: Support other types.
+		return nil, errors.Errorf("unsupported executor type %v", curr.GetTp())
 	}
 
 	return currExec, nil
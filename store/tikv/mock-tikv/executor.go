@@ -15,6 +15,7 @@ package mocktikv
 
 import (
 	"bytes"
+	"sort"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/distsql"
@@ -354,3 +355,202 @@ func setColumnValueToEval(eval *xeval.Evaluator, handle int64, row map[int64][]b
 	}
 	return nil
 }
+
+// aggResultColumnID is the pseudo column which the aggregation result of a group is put in. The result is the encoded
+// group key and partial results of the aggregate functions, it's returned as is.
+const aggResultColumnID int64 = 0
+
+type aggregationExec struct {
+	*tipb.Aggregation
+	sc         *variable.StatementContext
+	eval       *xeval.Evaluator
+	columns    map[int64]*tipb.ColumnInfo
+	aggregates []*aggregateFuncExpr
+	groups     map[string]struct{}
+	groupKeys  [][]byte
+	executed   bool
+	cursor     int
+
+	src executor
+}
+
+func (e *aggregationExec) SetSrcExec(exec executor) {
+	e.src = exec
+}
+
+func (e *aggregationExec) Next() (int64, map[int64][]byte, error) {
+	if !e.executed {
+		for {
+			handle, row, err := e.src.Next()
+			if err != nil {
+				return 0, nil, errors.Trace(err)
+			}
+			if row == nil {
+				break
+			}
+			if err = e.aggregate(handle, row); err != nil {
+				return 0, nil, errors.Trace(err)
+			}
+		}
+		e.executed = true
+	}
+	if e.cursor >= len(e.groupKeys) {
+		return 0, nil, nil
+	}
+	gk := e.groupKeys[e.cursor]
+	e.cursor++
+	// The first column is the group key, each partial result is converted to one or two datums.
+	rowData := make([]types.Datum, 0, 1+2*len(e.aggregates))
+	rowData = append(rowData, types.NewBytesDatum(gk))
+	for _, agg := range e.aggregates {
+		agg.currentGroup = gk
+		ds, err := agg.toDatums(e.sc)
+		if err != nil {
+			return 0, nil, errors.Trace(err)
+		}
+		rowData = append(rowData, ds...)
+	}
+	data, err := codec.EncodeValue(nil, rowData...)
+	if err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	return 0, map[int64][]byte{aggResultColumnID: data}, nil
+}
+
+func (e *aggregationExec) aggregate(handle int64, row map[int64][]byte) error {
+	err := setColumnValueToEval(e.eval, handle, row, e.columns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	gk, err := e.getGroupKey()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, ok := e.groups[string(gk)]; !ok {
+		e.groups[string(gk)] = struct{}{}
+		e.groupKeys = append(e.groupKeys, gk)
+	}
+	for _, agg := range e.aggregates {
+		agg.currentGroup = gk
+		args := make([]types.Datum, 0, len(agg.expr.Children))
+		for _, x := range agg.expr.Children {
+			cv, err := e.eval.Eval(x)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			args = append(args, cv)
+		}
+		if err = agg.update(e.sc, args); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (e *aggregationExec) getGroupKey() ([]byte, error) {
+	if len(e.GroupBy) == 0 {
+		return singleGroup, nil
+	}
+	vals := make([]types.Datum, 0, len(e.GroupBy))
+	for _, item := range e.GroupBy {
+		v, err := e.eval.Eval(item)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		vals = append(vals, v)
+	}
+	bs, err := codec.EncodeValue(nil, vals...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return bs, nil
+}
+
+type topNExec struct {
+	heap     *topnHeap
+	eval     *xeval.Evaluator
+	columns  map[int64]*tipb.ColumnInfo
+	executed bool
+	cursor   int
+
+	src executor
+}
+
+func (e *topNExec) SetSrcExec(exec executor) {
+	e.src = exec
+}
+
+func (e *topNExec) Next() (int64, map[int64][]byte, error) {
+	if !e.executed {
+		for {
+			handle, row, err := e.src.Next()
+			if err != nil {
+				return 0, nil, errors.Trace(err)
+			}
+			if row == nil {
+				break
+			}
+			if err = e.evalTopN(handle, row); err != nil {
+				return 0, nil, errors.Trace(err)
+			}
+		}
+		sort.Sort(&e.heap.topnSorter)
+		if e.heap.err != nil {
+			return 0, nil, errors.Trace(e.heap.err)
+		}
+		e.executed = true
+	}
+	if e.cursor >= len(e.heap.rows) {
+		return 0, nil, nil
+	}
+	row := e.heap.rows[e.cursor]
+	e.cursor++
+	return row.meta.Handle, row.values, nil
+}
+
+// evalTopN evaluates the order by items of the row and tries to add it to the heap.
+func (e *topNExec) evalTopN(handle int64, row map[int64][]byte) error {
+	err := setColumnValueToEval(e.eval, handle, row, e.columns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newRow := &sortRow{
+		meta:   tipb.RowMeta{Handle: handle},
+		values: row,
+	}
+	for _, item := range e.heap.orderByItems {
+		result, err := e.eval.Eval(item.Expr)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		newRow.key = append(newRow.key, result)
+	}
+	e.heap.tryToAddRow(newRow)
+	return errors.Trace(e.heap.err)
+}
+
+type limitExec struct {
+	limit  uint64
+	cursor uint64
+
+	src executor
+}
+
+func (e *limitExec) SetSrcExec(exec executor) {
+	e.src = exec
+}
+
+func (e *limitExec) Next() (int64, map[int64][]byte, error) {
+	if e.cursor >= e.limit {
+		return 0, nil, nil
+	}
+	handle, row, err := e.src.Next()
+	if err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	if row == nil {
+		return 0, nil, nil
+	}
+	e.cursor++
+	return handle, row, nil
+}
@@ -14,6 +14,7 @@
 package mocktikv
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -54,7 +55,7 @@ func newRPCHandler(cluster *Cluster, mvccStore *MvccStore, storeID uint64) *rpcH
 
 func (h *rpcHandler) handleRequest(req *kvrpcpb.Request) *kvrpcpb.Response {
 	var resp kvrpcpb.Response
-	if err := h.checkContext(req.GetContext()); err != nil {
+	if err := h.checkContext(req.GetContext(), isReadRequest(req.GetType())); err != nil {
 		resp.RegionError = err
 		return &resp
 	}
@@ -95,7 +96,19 @@ func (h *rpcHandler) handleRequest(req *kvrpcpb.Request) *kvrpcpb.Response {
 	return &resp
 }
 
-func (h *rpcHandler) checkContext(ctx *kvrpcpb.Context) *errorpb.Error {
+// isReadRequest checks whether the request only reads the data, it can be served by a follower.
+func isReadRequest(tp kvrpcpb.MessageType) bool {
+	switch tp {
+	case kvrpcpb.MessageType_CmdGet, kvrpcpb.MessageType_CmdScan, kvrpcpb.MessageType_CmdBatchGet:
+		return true
+	}
+	return false
+}
+
+// checkContext checks the context of the request, if read is true and the request asks for a quorum read, the
+// request can be served by a follower. The mock store doesn't have any replica, the followers always catch up with
+// the leader.
+func (h *rpcHandler) checkContext(ctx *kvrpcpb.Context, read bool) *errorpb.Error {
 	ctxPear := ctx.GetPeer()
 	if ctxPear != nil && ctxPear.GetStoreId() != h.storeID {
 		return &errorpb.Error{
@@ -141,7 +154,7 @@ func (h *rpcHandler) checkContext(ctx *kvrpcpb.Context) *errorpb.Error {
 		}
 	}
 	// The Peer on the Store is not leader.
-	if storePeer.GetId() != leaderPeer.GetId() {
+	if storePeer.GetId() != leaderPeer.GetId() && !(read && ctx.GetReadQuorum()) {
 		return &errorpb.Error{
 			Message: proto.String("not leader"),
 			NotLeader: &errorpb.NotLeader{
@@ -203,7 +216,7 @@ func (h *rpcHandler) onScan(req *kvrpcpb.CmdScanRequest) *kvrpcpb.CmdScanRespons
 	if !h.keyInRegion(req.GetStartKey()) {
 		panic("onScan: startKey not in region")
 	}
-	pairs := h.mvccStore.Scan(req.GetStartKey(), h.endKey, int(req.GetLimit()), req.GetVersion())
+	pairs := h.mvccStore.Scan(req.GetStartKey(), h.rawEndKey, int(req.GetLimit()), req.GetVersion())
 	return &kvrpcpb.CmdScanResponse{
 		Pairs: convertToPbPairs(pairs),
 	}
@@ -377,6 +390,27 @@ type RPCClient struct {
 	MvccStore *MvccStore
 }
 
+// SplitRegion splits the region which contains the key at the key, it does nothing if the key is the start key of
+// the region.
+func (c *RPCClient) SplitRegion(key []byte) error {
+	region, leader := c.Cluster.GetRegionByKey(NewMvccKey(key))
+	if region == nil {
+		return errors.Errorf("no region contains the key %q", key)
+	}
+	if bytes.Equal(region.GetStartKey(), NewMvccKey(key)) {
+		return nil
+	}
+	peerIDs := c.Cluster.AllocIDs(len(region.GetPeers()))
+	var leaderPeerID uint64
+	for i, peer := range region.GetPeers() {
+		if peer.GetId() == leader.GetId() {
+			leaderPeerID = peerIDs[i]
+		}
+	}
+	c.Cluster.Split(region.GetId(), c.Cluster.AllocID(), key, peerIDs, leaderPeerID)
+	return nil
+}
+
 // SendKVReq sends a kv request to mock cluster.
 func (c *RPCClient) SendKVReq(ctx goctx.Context, addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
 	select {
@@ -427,9 +461,7 @@ func (c *RPCClient) SendCopReqNew(addr string, req *coprocessor.Request, timeout
 	}
 
 	var executors []*tipb.Executor
-	// TODO: Now the value of desc is false.
-	// And it's used for testing.
-	desc := false
+	desc := len(sel.OrderBy) > 0 && sel.OrderBy[0].Expr == nil && sel.OrderBy[0].Desc
 	var exec *tipb.Executor
 	if sel.TableInfo != nil {
 		exec = &tipb.Executor{
@@ -458,6 +490,39 @@ func (c *RPCClient) SendCopReqNew(addr string, req *coprocessor.Request, timeout
 		}
 		executors = append(executors, exec)
 	}
+	if len(sel.Aggregates) > 0 || len(sel.GroupBy) > 0 {
+		groupBy := make([]*tipb.Expr, 0, len(sel.GroupBy))
+		for _, item := range sel.GroupBy {
+			groupBy = append(groupBy, item.Expr)
+		}
+		exec := &tipb.Executor{
+			Tp: tipb.ExecType_TypeAggregation,
+			Aggregation: &tipb.Aggregation{
+				GroupBy: groupBy,
+				AggFunc: sel.Aggregates,
+			},
+		}
+		executors = append(executors, exec)
+	}
+	if len(sel.OrderBy) > 0 && sel.OrderBy[0].Expr != nil {
+		if sel.Limit == nil {
+			return nil, errors.New("We don't support pushing down Sort without Limit")
+		}
+		exec := &tipb.Executor{
+			Tp: tipb.ExecType_TypeTopN,
+			TopN: &tipb.TopN{
+				OrderBy: sel.OrderBy,
+				Limit:   sel.Limit,
+			},
+		}
+		executors = append(executors, exec)
+	} else if sel.Limit != nil {
+		exec := &tipb.Executor{
+			Tp:    tipb.ExecType_TypeLimit,
+			Limit: &tipb.Limit{Limit: sel.Limit},
+		}
+		executors = append(executors, exec)
+	}
 
 	dag := &tipb.DAGRequest{
 		StartTs:        sel.GetStartTs(),
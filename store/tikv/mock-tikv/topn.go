@@ -26,6 +26,8 @@ type sortRow struct {
 	key  []types.Datum
 	meta tipb.RowMeta
 	data []byte
+	// values is the row of the DAG request, which is encoded by the executors above TopN.
+	values map[int64][]byte
 }
 
 // topnSorter implements sort.Interface. When all rows have been processed, the topnSorter will sort the whole data in heap.
@@ -0,0 +1,136 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/kv"
+)
+
+// pessimisticLock is a key locked by a pessimistic transaction.
+type pessimisticLock struct {
+	owner uint64
+	// released is closed when the lock is released.
+	released chan struct{}
+	// commitTS is the commit ts of the owner, it's 0 if the owner is rolled back.
+	commitTS uint64
+}
+
+// pessimisticLockTable holds the keys locked by the pessimistic transactions of this server, so the transactions
+// which write the same keys wait for each other when the keys are written instead of conflicting when they commit.
+// The 2PC still checks the conflicts of the transactions, so the transactions of the other servers are isolated.
+// The table is in the memory of this server, the transactions of the other servers don't wait for its locks, so the
+// pessimistic mode is experimental and for a single server only, see variable.EnablePessimisticTxn.
+type pessimisticLockTable struct {
+	mu    sync.Mutex
+	locks map[string]*pessimisticLock
+	// waitFor records the owner of the lock which a transaction waits for, it's used to detect the deadlocks.
+	waitFor map[uint64]uint64
+}
+
+func newPessimisticLockTable() *pessimisticLockTable {
+	return &pessimisticLockTable{
+		locks:   make(map[string]*pessimisticLock),
+		waitFor: make(map[uint64]uint64),
+	}
+}
+
+// lock locks the key for the transaction startTS, it waits for the transaction holding the key at most timeout.
+// It returns kv.ErrWriteConflict if the key is released by a transaction committed after startTS.
+func (t *pessimisticLockTable) lock(key []byte, startTS uint64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		t.mu.Lock()
+		l, ok := t.locks[string(key)]
+		if !ok {
+			t.locks[string(key)] = &pessimisticLock{owner: startTS, released: make(chan struct{})}
+			t.mu.Unlock()
+			return nil
+		}
+		if l.owner == startTS {
+			t.mu.Unlock()
+			return nil
+		}
+		if t.isDeadlock(startTS, l.owner) {
+			t.mu.Unlock()
+			return errors.Trace(kv.ErrDeadlock)
+		}
+		t.waitFor[startTS] = l.owner
+		t.mu.Unlock()
+
+		var err error
+		select {
+		case <-l.released:
+			if l.commitTS > startTS {
+				err = kv.ErrWriteConflict
+			}
+		case <-time.After(deadline.Sub(time.Now())):
+			err = kv.ErrLockWaitTimeout
+		}
+		t.mu.Lock()
+		delete(t.waitFor, startTS)
+		t.mu.Unlock()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// tryLock locks the key for the transaction startTS without waiting, it returns kv.ErrLockNowait if the key is locked
+// by another transaction.
+func (t *pessimisticLockTable) tryLock(key []byte, startTS uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.locks[string(key)]
+	if !ok {
+		t.locks[string(key)] = &pessimisticLock{owner: startTS, released: make(chan struct{})}
+		return nil
+	}
+	if l.owner != startTS {
+		return errors.Trace(kv.ErrLockNowait)
+	}
+	return nil
+}
+
+// isDeadlock checks whether the owner of the lock waits for the transaction startTS directly or indirectly.
+func (t *pessimisticLockTable) isDeadlock(startTS, owner uint64) bool {
+	for {
+		if owner == startTS {
+			return true
+		}
+		next, ok := t.waitFor[owner]
+		if !ok {
+			return false
+		}
+		owner = next
+	}
+}
+
+// release releases the keys locked by the transaction startTS, commitTS is 0 if the transaction is rolled back.
+func (t *pessimisticLockTable) release(keys [][]byte, startTS, commitTS uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		l, ok := t.locks[string(key)]
+		if !ok || l.owner != startTS {
+			continue
+		}
+		l.commitTS = commitTS
+		close(l.released)
+		delete(t.locks, string(key))
+	}
+}
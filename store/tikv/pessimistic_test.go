@@ -0,0 +1,73 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/terror"
+)
+
+var _ = Suite(&testPessimisticSuite{})
+
+type testPessimisticSuite struct{}
+
+func (s *testPessimisticSuite) TestLockTable(c *C) {
+	t := newPessimisticLockTable()
+	k1, k2 := []byte("k1"), []byte("k2")
+
+	// The lock is reentrant.
+	c.Assert(t.lock(k1, 1, time.Second), IsNil)
+	c.Assert(t.lock(k1, 1, time.Second), IsNil)
+
+	// The waiter times out.
+	err := t.lock(k1, 2, 10*time.Millisecond)
+	c.Assert(terror.ErrorEqual(err, kv.ErrLockWaitTimeout), IsTrue)
+
+	// tryLock doesn't wait.
+	err = t.tryLock(k1, 2)
+	c.Assert(terror.ErrorEqual(err, kv.ErrLockNowait), IsTrue)
+	c.Assert(t.tryLock(k1, 1), IsNil)
+	c.Assert(t.tryLock(k2, 2), IsNil)
+	t.release([][]byte{k2}, 2, 0)
+
+	// The waiter gets the lock if the owner is rolled back.
+	ch := make(chan error, 1)
+	go func() { ch <- t.lock(k1, 2, time.Second) }()
+	time.Sleep(10 * time.Millisecond)
+	t.release([][]byte{k1}, 1, 0)
+	c.Assert(<-ch, IsNil)
+
+	// The waiter conflicts if the owner is committed after it starts.
+	go func() { ch <- t.lock(k1, 3, time.Second) }()
+	time.Sleep(10 * time.Millisecond)
+	t.release([][]byte{k1}, 2, 4)
+	err = <-ch
+	c.Assert(terror.ErrorEqual(err, kv.ErrWriteConflict), IsTrue)
+
+	// The deadlock is detected.
+	c.Assert(t.lock(k1, 5, time.Second), IsNil)
+	c.Assert(t.lock(k2, 6, time.Second), IsNil)
+	go func() { ch <- t.lock(k2, 5, time.Second) }()
+	time.Sleep(10 * time.Millisecond)
+	err = t.lock(k1, 6, time.Second)
+	c.Assert(terror.ErrorEqual(err, kv.ErrDeadlock), IsTrue)
+	t.release([][]byte{k2}, 6, 0)
+	c.Assert(<-ch, IsNil)
+	t.release([][]byte{k1, k2}, 5, 7)
+	c.Assert(t.locks, HasLen, 0)
+	c.Assert(t.waitFor, HasLen, 0)
+}
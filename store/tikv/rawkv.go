@@ -24,9 +24,12 @@ import (
 
 // RawKVClient is a client of TiKV server which is used as a key-value storage,
 // only GET/PUT/DELETE commands are supported.
+// The raw keys are in the default column family, TiKV's raw commands don't support scanning or the other column
+// families yet.
 type RawKVClient struct {
 	clusterID   uint64
 	regionCache *RegionCache
+	pdClient    pd.Client
 	rpcClient   Client
 }
 
@@ -39,10 +42,17 @@ func NewRawKVClient(pdAddrs []string) (*RawKVClient, error) {
 	return &RawKVClient{
 		clusterID:   pdCli.GetClusterID(goctx.TODO()),
 		regionCache: NewRegionCache(pdCli),
+		pdClient:    pdCli,
 		rpcClient:   newRPCClient(),
 	}, nil
 }
 
+// Close closes the client.
+func (c *RawKVClient) Close() error {
+	c.pdClient.Close()
+	return errors.Trace(c.rpcClient.Close())
+}
+
 // ClusterID returns the TiKV cluster ID.
 func (c *RawKVClient) ClusterID() uint64 {
 	return c.clusterID
@@ -16,6 +16,8 @@ package tikv
 import (
 	"bytes"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
@@ -23,9 +25,14 @@ import (
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/pd-client"
+	"github.com/pingcap/tidb/kv"
 	goctx "golang.org/x/net/context"
 )
 
+// ServerLabels are the labels of the location of the server, such as "zone" and "host", the replicas on the stores
+// with the same labels are the closest ones for kv.ReplicaReadClosest. It should be set before the store is opened.
+var ServerLabels map[string]string
+
 // RegionCache caches Regions loaded from PD.
 type RegionCache struct {
 	pdClient pd.Client
@@ -58,6 +65,8 @@ type RPCContext struct {
 	Region RegionVerID
 	KVCtx  *kvrpcpb.Context
 	Addr   string
+	// FollowerRead indicates the request is sent to a follower.
+	FollowerRead bool
 }
 
 // GetStoreID returns StoreID.
@@ -70,7 +79,9 @@ func (c *RPCContext) GetStoreID() uint64 {
 
 // GetRPCContext returns RPCContext for a region. If it returns nil, the region
 // must be out of date and already dropped from cache.
-func (c *RegionCache) GetRPCContext(bo *Backoffer, id RegionVerID) (*RPCContext, error) {
+// The replica which serves the request is chosen by replicaRead, see kv.ReplicaReadType, the leader is chosen if
+// no follower is available.
+func (c *RegionCache) GetRPCContext(bo *Backoffer, id RegionVerID, replicaRead kv.ReplicaReadType) (*RPCContext, error) {
 	c.mu.RLock()
 	region, ok := c.mu.regions[id]
 	if !ok {
@@ -78,8 +89,45 @@ func (c *RegionCache) GetRPCContext(bo *Backoffer, id RegionVerID) (*RPCContext,
 		return nil, nil
 	}
 	kvCtx := region.GetContext()
+	var replica *metapb.Peer
+	var candidates []*metapb.Peer
+	switch replicaRead {
+	case kv.ReplicaReadFollower:
+		replica = region.nextReplica(false)
+	case kv.ReplicaReadRoundRobin:
+		replica = region.nextReplica(true)
+	case kv.ReplicaReadLeaderPreferred, kv.ReplicaReadClosest:
+		candidates = region.reachablePeers()
+	}
 	c.mu.RUnlock()
 
+	if len(candidates) > 0 {
+		var err error
+		replica, err = c.selectReplica(bo, replicaRead, kvCtx.GetPeer(), candidates)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	if replica != nil && replica.GetId() != kvCtx.GetPeer().GetId() {
+		addr, err := c.GetStoreAddr(bo, replica.GetStoreId())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if addr != "" {
+			kvCtx.Peer = replica
+			// The follower serves the read after it catches up with the leader by a read index request.
+			kvCtx.ReadQuorum = true
+			return &RPCContext{
+				Context:      bo.ctx,
+				Region:       id,
+				KVCtx:        kvCtx,
+				Addr:         addr,
+				FollowerRead: true,
+			}, nil
+		}
+	}
+
 	addr, err := c.GetStoreAddr(bo, kvCtx.GetPeer().GetStoreId())
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -97,6 +145,63 @@ func (c *RegionCache) GetRPCContext(bo *Backoffer, id RegionVerID) (*RPCContext,
 	}, nil
 }
 
+// slowStoreLatency is the latency above which a leader is considered slow by kv.ReplicaReadLeaderPreferred.
+const slowStoreLatency = 50 * time.Millisecond
+
+// selectReplica chooses the replica which serves a read from the reachable peers of a region by the stores of the
+// peers, leader is the current leader of the region.
+// For kv.ReplicaReadClosest, the peers whose store labels match ServerLabels most are chosen, the leader is preferred
+// among them because the follower reads pay for a read index request, the follower with the lowest latency is
+// chosen otherwise.
+// For kv.ReplicaReadLeaderPreferred, the leader is chosen unless its latency exceeds slowStoreLatency and is more
+// than twice of the latency of a follower, a follower never served by this server is tried as well so that its
+// latency is known afterwards.
+func (c *RegionCache) selectReplica(bo *Backoffer, replicaRead kv.ReplicaReadType, leader *metapb.Peer, candidates []*metapb.Peer) (*metapb.Peer, error) {
+	var (
+		leaderStore *Store
+		best        *metapb.Peer
+		bestStore   *Store
+		bestScore   = -1
+		leaderScore = -1
+	)
+	for _, p := range candidates {
+		store, err := c.getStore(bo, p.GetStoreId())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if store == nil {
+			continue
+		}
+		score := 0
+		if replicaRead == kv.ReplicaReadClosest {
+			score = store.matchLabels(ServerLabels)
+		}
+		if p.GetId() == leader.GetId() {
+			leaderStore, leaderScore = store, score
+			continue
+		}
+		if score > bestScore || (score == bestScore && store.getLatency() < bestStore.getLatency()) {
+			best, bestStore, bestScore = p, store, score
+		}
+	}
+	if leaderStore == nil {
+		// The store of the leader is unknown, it's left to the caller.
+		return best, nil
+	}
+	switch replicaRead {
+	case kv.ReplicaReadClosest:
+		if leaderScore >= bestScore {
+			return leader, nil
+		}
+	case kv.ReplicaReadLeaderPreferred:
+		latency := leaderStore.getLatency()
+		if best == nil || latency <= slowStoreLatency || latency <= 2*bestStore.getLatency() {
+			return leader, nil
+		}
+	}
+	return best, nil
+}
+
 // KeyLocation is the region and range that a key is located.
 type KeyLocation struct {
 	Region   RegionVerID
@@ -120,9 +225,11 @@ func (c *RegionCache) LocateKey(bo *Backoffer, key []byte) (*KeyLocation, error)
 			EndKey:   r.EndKey(),
 		}
 		c.mu.RUnlock()
+		regionCacheCounter.WithLabelValues("locate_key", "hit").Inc()
 		return loc, nil
 	}
 	c.mu.RUnlock()
+	regionCacheCounter.WithLabelValues("locate_key", "miss").Inc()
 
 	r, err := c.loadRegion(bo, key)
 	if err != nil {
@@ -149,9 +256,11 @@ func (c *RegionCache) LocateRegionByID(bo *Backoffer, regionID uint64) (*KeyLoca
 			EndKey:   r.EndKey(),
 		}
 		c.mu.RUnlock()
+		regionCacheCounter.WithLabelValues("locate_region_by_id", "hit").Inc()
 		return loc, nil
 	}
 	c.mu.RUnlock()
+	regionCacheCounter.WithLabelValues("locate_region_by_id", "miss").Inc()
 
 	r, err := c.loadRegionByID(bo, regionID)
 	if err != nil {
@@ -214,9 +323,11 @@ func (c *RegionCache) DropRegion(id RegionVerID) {
 	defer c.mu.Unlock()
 
 	c.dropRegionFromCache(id)
+	regionCacheCounter.WithLabelValues("drop_region", "drop").Inc()
 }
 
-// UpdateLeader update some region cache with newer leader info.
+// UpdateLeader update some region cache with newer leader info. If leaderStoreID is 0, which means the leader is
+// unknown, the next peer is tried, so the region is kept in cache while the leader is being elected.
 func (c *RegionCache) UpdateLeader(regionID RegionVerID, leaderStoreID uint64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -227,10 +338,18 @@ func (c *RegionCache) UpdateLeader(regionID RegionVerID, leaderStoreID uint64) {
 		return
 	}
 
+	if leaderStoreID == 0 {
+		r.switchToNextPeer()
+		regionCacheCounter.WithLabelValues("update_leader", "next_peer").Inc()
+		return
+	}
 	if !r.SwitchPeer(leaderStoreID) {
 		log.Debugf("regionCache: cannot find peer when updating leader %d,%d", regionID, leaderStoreID)
 		c.dropRegionFromCache(r.VerID())
+		regionCacheCounter.WithLabelValues("update_leader", "drop").Inc()
+		return
 	}
+	regionCacheCounter.WithLabelValues("update_leader", "switch_peer").Inc()
 }
 
 func (c *RegionCache) getRegionFromCache(key []byte) *Region {
@@ -349,29 +468,53 @@ func (c *RegionCache) loadRegionByID(bo *Backoffer, regionID uint64) (*Region, e
 // GetStoreAddr returns a tikv server's address by its storeID. It checks cache
 // first, sends request to pd server when necessary.
 func (c *RegionCache) GetStoreAddr(bo *Backoffer, id uint64) (string, error) {
+	store, err := c.getStore(bo, id)
+	if err != nil || store == nil {
+		return "", errors.Trace(err)
+	}
+	return store.Addr, nil
+}
+
+// getStore returns a tikv server by its storeID, it's loaded from pd server if it's not in cache. It returns nil
+// if the store is not found.
+func (c *RegionCache) getStore(bo *Backoffer, id uint64) (*Store, error) {
 	c.storeMu.RLock()
 	if store, ok := c.storeMu.stores[id]; ok {
 		c.storeMu.RUnlock()
-		return store.Addr, nil
+		return store, nil
 	}
 	c.storeMu.RUnlock()
-	return c.ReloadStoreAddr(bo, id)
+	return c.reloadStore(bo, id)
 }
 
 // ReloadStoreAddr reloads store's address.
 func (c *RegionCache) ReloadStoreAddr(bo *Backoffer, id uint64) (string, error) {
-	addr, err := c.loadStoreAddr(bo, id)
-	if err != nil || addr == "" {
+	store, err := c.reloadStore(bo, id)
+	if err != nil || store == nil {
 		return "", errors.Trace(err)
 	}
+	return store.Addr, nil
+}
+
+// reloadStore reloads the store from pd server, the observed latency of the store is kept.
+func (c *RegionCache) reloadStore(bo *Backoffer, id uint64) (*Store, error) {
+	meta, err := c.loadStore(bo, id)
+	if err != nil || meta == nil || meta.GetAddress() == "" {
+		return nil, errors.Trace(err)
+	}
 
 	c.storeMu.Lock()
 	defer c.storeMu.Unlock()
-	c.storeMu.stores[id] = &Store{
-		ID:   id,
-		Addr: addr,
+	store := &Store{
+		ID:     id,
+		Addr:   meta.GetAddress(),
+		Labels: meta.GetLabels(),
+	}
+	if old, ok := c.storeMu.stores[id]; ok {
+		store.latency = int64(old.getLatency())
 	}
-	return addr, nil
+	c.storeMu.stores[id] = store
+	return store, nil
 }
 
 // ClearStoreByID clears store from cache with storeID.
@@ -381,56 +524,69 @@ func (c *RegionCache) ClearStoreByID(id uint64) {
 	delete(c.storeMu.stores, id)
 }
 
-func (c *RegionCache) loadStoreAddr(bo *Backoffer, id uint64) (string, error) {
+func (c *RegionCache) loadStore(bo *Backoffer, id uint64) (*metapb.Store, error) {
 	for {
 		store, err := c.pdClient.GetStore(goctx.TODO(), id)
 		if err != nil {
 			err = errors.Errorf("loadStore from PD failed, id: %d, err: %v", id, err)
 			if err = bo.Backoff(boPDRPC, err); err != nil {
-				return "", errors.Trace(err)
+				return nil, errors.Trace(err)
 			}
 			continue
 		}
-		if store == nil {
-			return "", nil
-		}
-		return store.GetAddress(), nil
+		return store, nil
+	}
+}
+
+// observeStoreLatency records the latency of a request served by the store, the latency of the store is the moving
+// average of the latencies of its requests.
+func (c *RegionCache) observeStoreLatency(id uint64, d time.Duration) {
+	c.storeMu.RLock()
+	store, ok := c.storeMu.stores[id]
+	c.storeMu.RUnlock()
+	if ok {
+		store.observeLatency(d)
 	}
 }
 
 // OnRequestFail is used for clearing cache when a tikv server does not respond.
+// The regions whose leader peers are on the store switch to their next peers, they are dropped only when all
+// their peers are unreachable, so a down store doesn't make all its regions reload from PD at once.
 func (c *RegionCache) OnRequestFail(ctx *RPCContext) {
-	// Switch region's leader peer to next one.
-	regionID := ctx.Region
+	storeID := ctx.KVCtx.GetPeer().GetStoreId()
 	c.mu.Lock()
-	if region, ok := c.mu.regions[regionID]; ok {
-		if !region.OnRequestFail(ctx.KVCtx.GetPeer().GetStoreId()) {
-			c.dropRegionFromCache(regionID)
+	for id, r := range c.mu.regions {
+		if id != ctx.Region && r.peer.GetStoreId() != storeID {
+			continue
+		}
+		if r.OnRequestFail(storeID) {
+			regionCacheCounter.WithLabelValues("request_fail", "switch_peer").Inc()
+		} else {
+			c.dropRegionFromCache(id)
+			regionCacheCounter.WithLabelValues("request_fail", "drop").Inc()
 		}
 	}
 	c.mu.Unlock()
 
 	// Store's meta may be out of date.
-	storeID := ctx.KVCtx.GetPeer().GetStoreId()
 	c.storeMu.Lock()
 	delete(c.storeMu.stores, storeID)
 	c.storeMu.Unlock()
-
-	c.mu.Lock()
-	for id, r := range c.mu.regions {
-		if r.peer.GetStoreId() == storeID {
-			c.dropRegionFromCache(id)
-		}
-	}
-	c.mu.Unlock()
 }
 
-// OnRegionStale removes the old region and inserts new regions into the cache.
+// OnRegionStale replaces the old region with the new regions reported by tikv, the regions in cache which overlap
+// the new regions are dropped.
 func (c *RegionCache) OnRegionStale(ctx *RPCContext, newRegions []*metapb.Region) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// The new regions keep the leader of the old region, the request may be sent to a follower.
+	leaderStoreID := ctx.KVCtx.GetPeer().GetStoreId()
+	if old, ok := c.mu.regions[ctx.Region]; ok {
+		leaderStoreID = old.peer.GetStoreId()
+	}
 	c.dropRegionFromCache(ctx.Region)
+	regionCacheCounter.WithLabelValues("region_stale", "drop").Inc()
 
 	for _, meta := range newRegions {
 		if _, ok := c.pdClient.(*codecPDClient); ok {
@@ -442,12 +598,36 @@ func (c *RegionCache) OnRegionStale(ctx *RPCContext, newRegions []*metapb.Region
 			meta: meta,
 			peer: meta.Peers[0],
 		}
-		region.SwitchPeer(ctx.KVCtx.GetPeer().GetStoreId())
+		region.SwitchPeer(leaderStoreID)
+		c.dropOverlappedRegions(region)
 		c.insertRegionToCache(region)
+		regionCacheCounter.WithLabelValues("region_stale", "insert").Inc()
 	}
 	return nil
 }
 
+// dropOverlappedRegions drops the regions in cache whose ranges overlap r.
+func (c *RegionCache) dropOverlappedRegions(r *Region) {
+	var overlapped []RegionVerID
+	// The region starts before r may cover the start key of r.
+	if prev := c.getRegionFromCache(r.StartKey()); prev != nil {
+		overlapped = append(overlapped, prev.VerID())
+	}
+	c.mu.sorted.AscendGreaterOrEqual(newRBSearchItem(r.StartKey()), func(item llrb.Item) bool {
+		other := item.(*llrbItem).region
+		if len(r.EndKey()) > 0 && bytes.Compare(other.StartKey(), r.EndKey()) >= 0 {
+			return false
+		}
+		overlapped = append(overlapped, other.VerID())
+		return true
+	})
+	for _, id := range overlapped {
+		if id != r.VerID() {
+			c.dropRegionFromCache(id)
+		}
+	}
+}
+
 // moveLeaderToFirst moves the leader peer to the first and makes it easier to
 // try the next peer if the current peer does not respond.
 func moveLeaderToFirst(r *metapb.Region, leaderStoreID uint64) {
@@ -487,6 +667,8 @@ type Region struct {
 	meta              *metapb.Region
 	peer              *metapb.Peer
 	unreachableStores []uint64
+	// followerIdx is used to choose the replicas in turn for the follower reads.
+	followerIdx uint32
 }
 
 // GetID returns id.
@@ -529,6 +711,53 @@ func (r *Region) GetContext() *kvrpcpb.Context {
 	}
 }
 
+// nextReplica returns the next reachable peer of the region, the leader is skipped unless withLeader is true. It
+// returns nil if there isn't any.
+func (r *Region) nextReplica(withLeader bool) *metapb.Peer {
+	peers := r.meta.GetPeers()
+	start := atomic.AddUint32(&r.followerIdx, 1)
+L:
+	for i := 0; i < len(peers); i++ {
+		p := peers[(int(start)+i)%len(peers)]
+		if !withLeader && p.GetId() == r.peer.GetId() {
+			continue
+		}
+		for _, id := range r.unreachableStores {
+			if p.GetStoreId() == id {
+				continue L
+			}
+		}
+		return p
+	}
+	return nil
+}
+
+// reachablePeers returns the peers of the region whose stores are not unreachable.
+func (r *Region) reachablePeers() []*metapb.Peer {
+	peers := make([]*metapb.Peer, 0, len(r.meta.GetPeers()))
+L:
+	for _, p := range r.meta.GetPeers() {
+		for _, id := range r.unreachableStores {
+			if p.GetStoreId() == id {
+				continue L
+			}
+		}
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// switchToNextPeer switches the region to the peer after the current one, it's used when the leader is unknown.
+func (r *Region) switchToNextPeer() {
+	peers := r.meta.GetPeers()
+	for i, p := range peers {
+		if p.GetId() == r.peer.GetId() {
+			r.peer = peers[(i+1)%len(peers)]
+			return
+		}
+	}
+}
+
 // OnRequestFail records unreachable peer and tries to select another valid peer.
 // It returns false if all peers are unreachable.
 func (r *Region) OnRequestFail(storeID uint64) bool {
@@ -570,6 +799,40 @@ func (r *Region) Contains(key []byte) bool {
 
 // Store contains a tikv server's address.
 type Store struct {
-	ID   uint64
-	Addr string
+	ID     uint64
+	Addr   string
+	Labels []*metapb.StoreLabel
+	// latency is the moving average in nanoseconds of the latencies of the requests served by the store, it's 0 if
+	// the store hasn't served any request.
+	latency int64
+}
+
+// storeLatencyDecay is the weight of the old latency in the moving average, a new latency takes 1/storeLatencyDecay.
+const storeLatencyDecay = 8
+
+func (s *Store) getLatency() time.Duration {
+	if s == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&s.latency))
+}
+
+func (s *Store) observeLatency(d time.Duration) {
+	old := atomic.LoadInt64(&s.latency)
+	latency := int64(d)
+	if old > 0 {
+		latency = old + (latency-old)/storeLatencyDecay
+	}
+	atomic.StoreInt64(&s.latency, latency)
+}
+
+// matchLabels returns the number of the labels which are the same in the store.
+func (s *Store) matchLabels(labels map[string]string) int {
+	n := 0
+	for _, l := range s.Labels {
+		if v, ok := labels[l.GetKey()]; ok && v == l.GetValue() {
+			n++
+		}
+	}
+	return n
 }
@@ -15,9 +15,14 @@ package tikv
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
 	goctx "golang.org/x/net/context"
 )
@@ -69,7 +74,7 @@ func (s *testRegionCacheSuite) getRegion(c *C, key []byte) *Region {
 func (s *testRegionCacheSuite) getAddr(c *C, key []byte) string {
 	loc, err := s.cache.LocateKey(s.bo, key)
 	c.Assert(err, IsNil)
-	ctx, err := s.cache.GetRPCContext(s.bo, loc.Region)
+	ctx, err := s.cache.GetRPCContext(s.bo, loc.Region, kv.ReplicaReadLeader)
 	c.Assert(err, IsNil)
 	if ctx == nil {
 		return ""
@@ -90,7 +95,7 @@ func (s *testRegionCacheSuite) TestDropStore(c *C) {
 	s.cluster.RemoveStore(s.store1)
 	loc, err := s.cache.LocateKey(bo, []byte("a"))
 	c.Assert(err, IsNil)
-	ctx, err := s.cache.GetRPCContext(bo, loc.Region)
+	ctx, err := s.cache.GetRPCContext(bo, loc.Region, kv.ReplicaReadLeader)
 	c.Assert(err, IsNil)
 	c.Assert(ctx, IsNil)
 	s.checkCache(c, 0)
@@ -242,12 +247,12 @@ func (s *testRegionCacheSuite) TestRequestFail(c *C) {
 	region := s.getRegion(c, []byte("a"))
 	c.Assert(region.unreachableStores, HasLen, 0)
 
-	ctx, _ := s.cache.GetRPCContext(s.bo, region.VerID())
+	ctx, _ := s.cache.GetRPCContext(s.bo, region.VerID(), kv.ReplicaReadLeader)
 	s.cache.OnRequestFail(ctx)
 	region = s.getRegion(c, []byte("a"))
 	c.Assert(region.unreachableStores, DeepEquals, []uint64{s.store1})
 
-	ctx, _ = s.cache.GetRPCContext(s.bo, region.VerID())
+	ctx, _ = s.cache.GetRPCContext(s.bo, region.VerID(), kv.ReplicaReadLeader)
 	s.cache.OnRequestFail(ctx)
 	region = s.getRegion(c, []byte("a"))
 	// Out of range of Peers, so get Region again and pick Stores[0] as leader.
@@ -269,14 +274,64 @@ func (s *testRegionCacheSuite) TestRequestFail2(c *C) {
 	c.Assert(loc2.Region.id, Equals, region2)
 
 	// Request should fail on region1.
-	ctx, _ := s.cache.GetRPCContext(s.bo, loc1.Region)
+	ctx, _ := s.cache.GetRPCContext(s.bo, loc1.Region, kv.ReplicaReadLeader)
 	c.Assert(s.cache.storeMu.stores, HasLen, 1)
 	s.checkCache(c, 2)
 	s.cache.OnRequestFail(ctx)
-	// Both region2 and store should be dropped from cache.
+	// The store is dropped from cache, region2 is kept and switches to the other peer.
 	c.Assert(s.cache.storeMu.stores, HasLen, 0)
-	c.Assert(s.cache.getRegionFromCache([]byte("x")), IsNil)
+	s.checkCache(c, 2)
+	region := s.cache.getRegionFromCache([]byte("x"))
+	c.Assert(region, NotNil)
+	c.Assert(region.unreachableStores, DeepEquals, []uint64{s.store1})
+	c.Assert(s.getAddr(c, []byte("x")), Equals, s.storeAddr(s.store2))
+}
+
+func (s *testRegionCacheSuite) TestUpdateLeaderWithoutHint(c *C) {
+	loc, err := s.cache.LocateKey(s.bo, []byte("a"))
+	c.Assert(err, IsNil)
+	// tikv-server reports `NotLeader` without the leader, the region is kept and the next peer is tried.
+	s.cache.UpdateLeader(loc.Region, 0)
 	s.checkCache(c, 1)
+	c.Assert(s.getAddr(c, []byte("a")), Equals, s.storeAddr(s.store2))
+	s.cache.UpdateLeader(loc.Region, 0)
+	c.Assert(s.getAddr(c, []byte("a")), Equals, s.storeAddr(s.store1))
+}
+
+func (s *testRegionCacheSuite) TestRegionStale(c *C) {
+	// Cache the region ['' - 'z'] and the stale region ['m' - ''] which is loaded before a merge.
+	region2 := s.cluster.AllocID()
+	newPeers := s.cluster.AllocIDs(2)
+	s.cluster.Split(s.region1, region2, []byte("m"), newPeers, newPeers[0])
+	loc2, err := s.cache.LocateKey(s.bo, []byte("x"))
+	c.Assert(err, IsNil)
+	s.cluster.Merge(s.region1, region2)
+	loc1, err := s.cache.LocateKey(s.bo, []byte("a"))
+	c.Assert(err, IsNil)
+	c.Assert(loc1.Region.id, Equals, s.region1)
+	s.cache.UpdateLeader(loc1.Region, s.store2)
+
+	// Split the region to ['' - 'c' - ''], tikv-server reports `StaleEpoch` with the new regions.
+	region3 := s.cluster.AllocID()
+	newPeers = s.cluster.AllocIDs(2)
+	s.cluster.Split(s.region1, region3, []byte("c"), newPeers, newPeers[0])
+	meta1, _ := s.cluster.GetRegion(s.region1)
+	meta3, _ := s.cluster.GetRegion(region3)
+	// The request is sent to a follower.
+	ctx, err := s.cache.GetRPCContext(s.bo, loc1.Region, kv.ReplicaReadFollower)
+	c.Assert(err, IsNil)
+	c.Assert(ctx.GetStoreID(), Equals, s.store1)
+	err = s.cache.OnRegionStale(ctx, []*metapb.Region{meta1, meta3})
+	c.Assert(err, IsNil)
+
+	// The new regions replace the old ones and keep the leader, the stale region2 is dropped.
+	s.checkCache(c, 2)
+	c.Assert(s.cache.getRegionByIDFromCache(loc2.Region.id), IsNil)
+	c.Assert(s.getRegion(c, []byte("a")).GetID(), Equals, s.region1)
+	c.Assert(s.getRegion(c, []byte("x")).GetID(), Equals, region3)
+	c.Assert(s.getAddr(c, []byte("a")), Equals, s.storeAddr(s.store2))
+	c.Assert(s.getAddr(c, []byte("x")), Equals, s.storeAddr(s.store2))
+	s.checkCache(c, 2)
 }
 
 func (s *testRegionCacheSuite) TestUpdateStoreAddr(c *C) {
@@ -317,3 +372,146 @@ func (s *testRegionCacheSuite) TestListRegionIDsInCache(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(regionIDs, DeepEquals, []uint64{s.region1, region2})
 }
+
+func (s *testRegionCacheSuite) TestFollowerRead(c *C) {
+	loc, err := s.cache.LocateKey(s.bo, []byte("a"))
+	c.Assert(err, IsNil)
+	ctx, err := s.cache.GetRPCContext(s.bo, loc.Region, kv.ReplicaReadFollower)
+	c.Assert(err, IsNil)
+	c.Assert(ctx.Addr, Equals, s.storeAddr(s.store2))
+	c.Assert(ctx.KVCtx.GetPeer().GetId(), Equals, s.peer2)
+	c.Assert(ctx.KVCtx.GetReadQuorum(), IsTrue)
+	c.Assert(ctx.FollowerRead, IsTrue)
+	ctx, err = s.cache.GetRPCContext(s.bo, loc.Region, kv.ReplicaReadLeader)
+	c.Assert(err, IsNil)
+	c.Assert(ctx.Addr, Equals, s.storeAddr(s.store1))
+	c.Assert(ctx.KVCtx.GetReadQuorum(), IsFalse)
+	c.Assert(ctx.FollowerRead, IsFalse)
+
+	// The unreachable follower is skipped, the leader serves the reads if there isn't any other follower.
+	r := s.getRegion(c, []byte("a"))
+	r.unreachableStores = append(r.unreachableStores, s.store2)
+	ctx, err = s.cache.GetRPCContext(s.bo, loc.Region, kv.ReplicaReadFollower)
+	c.Assert(err, IsNil)
+	c.Assert(ctx.Addr, Equals, s.storeAddr(s.store1))
+	c.Assert(ctx.FollowerRead, IsFalse)
+}
+
+func (s *testRegionCacheSuite) TestReplicaReadPolicies(c *C) {
+	loc, err := s.cache.LocateKey(s.bo, []byte("a"))
+	c.Assert(err, IsNil)
+	getAddr := func(replicaRead kv.ReplicaReadType) string {
+		ctx, err := s.cache.GetRPCContext(s.bo, loc.Region, replicaRead)
+		c.Assert(err, IsNil)
+		c.Assert(ctx.FollowerRead, Equals, ctx.Addr != s.storeAddr(s.store1))
+		c.Assert(ctx.KVCtx.GetReadQuorum(), Equals, ctx.FollowerRead)
+		return ctx.Addr
+	}
+
+	// The round-robin reads are served by the leader and the follower in turn.
+	addrs := map[string]int{}
+	for i := 0; i < 4; i++ {
+		addrs[getAddr(kv.ReplicaReadRoundRobin)]++
+	}
+	c.Assert(addrs, DeepEquals, map[string]int{s.storeAddr(s.store1): 2, s.storeAddr(s.store2): 2})
+
+	// The closest reads are served by the replicas matching the labels of the server most, the leader is preferred
+	// among them.
+	defer func(labels map[string]string) { ServerLabels = labels }(ServerLabels)
+	ServerLabels = map[string]string{"zone": "z2", "host": "h2"}
+	s.cluster.UpdateStoreLabels(s.store1, "zone=z1", "host=h1")
+	s.cluster.UpdateStoreLabels(s.store2, "zone=z2", "host=h3")
+	s.cache.ClearStoreByID(s.store1)
+	s.cache.ClearStoreByID(s.store2)
+	c.Assert(getAddr(kv.ReplicaReadClosest), Equals, s.storeAddr(s.store2))
+	s.cluster.UpdateStoreLabels(s.store1, "zone=z2", "host=h1")
+	s.cache.ClearStoreByID(s.store1)
+	c.Assert(getAddr(kv.ReplicaReadClosest), Equals, s.storeAddr(s.store1))
+	ServerLabels = nil
+	c.Assert(getAddr(kv.ReplicaReadClosest), Equals, s.storeAddr(s.store1))
+
+	// The leader-preferred reads are served by the leader, unless it's much slower than the follower.
+	c.Assert(getAddr(kv.ReplicaReadLeaderPreferred), Equals, s.storeAddr(s.store1))
+	s.cache.observeStoreLatency(s.store1, 100*time.Millisecond)
+	c.Assert(getAddr(kv.ReplicaReadLeaderPreferred), Equals, s.storeAddr(s.store2))
+	s.cache.observeStoreLatency(s.store2, 80*time.Millisecond)
+	c.Assert(getAddr(kv.ReplicaReadLeaderPreferred), Equals, s.storeAddr(s.store1))
+	s.cache.observeStoreLatency(s.store2, 10*time.Millisecond)
+	for i := 0; i < 20; i++ {
+		s.cache.observeStoreLatency(s.store2, 10*time.Millisecond)
+	}
+	c.Assert(getAddr(kv.ReplicaReadLeaderPreferred), Equals, s.storeAddr(s.store2))
+	// The latency is kept when the store is reloaded.
+	_, err = s.cache.ReloadStoreAddr(s.bo, s.store1)
+	c.Assert(err, IsNil)
+	c.Assert(getAddr(kv.ReplicaReadLeaderPreferred), Equals, s.storeAddr(s.store2))
+	// An unreachable follower is skipped.
+	r := s.getRegion(c, []byte("a"))
+	r.unreachableStores = append(r.unreachableStores, s.store2)
+	c.Assert(getAddr(kv.ReplicaReadLeaderPreferred), Equals, s.storeAddr(s.store1))
+	c.Assert(getAddr(kv.ReplicaReadRoundRobin), Equals, s.storeAddr(s.store1))
+}
+
+// recordAddrClient records the addresses of the stores which the requests are sent to.
+type recordAddrClient struct {
+	Client
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (c *recordAddrClient) record(addr string) {
+	c.mu.Lock()
+	c.addrs = append(c.addrs, addr)
+	c.mu.Unlock()
+}
+
+func (c *recordAddrClient) sentTo(addr string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var count int
+	for _, a := range c.addrs {
+		if a == addr {
+			count++
+		}
+	}
+	return count
+}
+
+func (c *recordAddrClient) SendKVReq(ctx goctx.Context, addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
+	c.record(addr)
+	return c.Client.SendKVReq(ctx, addr, req, timeout)
+}
+
+func (c *recordAddrClient) SendCopReq(ctx goctx.Context, addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	c.record(addr)
+	return c.Client.SendCopReq(ctx, addr, req, timeout)
+}
+
+func (s *testRegionCacheSuite) TestFollowerReadFallback(c *C) {
+	client := &recordAddrClient{Client: mocktikv.NewRPCClient(s.cluster, mocktikv.NewMvccStore())}
+	loc, err := s.cache.LocateKey(s.bo, []byte("a"))
+	c.Assert(err, IsNil)
+
+	sender := NewRegionRequestSender(s.bo, s.cache, client)
+	sender.replicaRead = kv.ReplicaReadFollower
+	req := &kvrpcpb.Request{
+		Type:      kvrpcpb.MessageType_CmdGet,
+		CmdGetReq: &kvrpcpb.CmdGetRequest{Key: []byte("a"), Version: 1},
+	}
+	resp, err := sender.SendKVReq(req, loc.Region, readTimeoutShort)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetRegionError(), IsNil)
+	c.Assert(client.addrs, DeepEquals, []string{s.storeAddr(s.store2)})
+
+	// The follower can't serve the writes, the request is retried on the leader.
+	client.addrs = nil
+	req = &kvrpcpb.Request{
+		Type:         kvrpcpb.MessageType_CmdRawPut,
+		CmdRawPutReq: &kvrpcpb.CmdRawPutRequest{Key: []byte("a"), Value: []byte("b")},
+	}
+	resp, err = sender.SendKVReq(req, loc.Region, readTimeoutShort)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetRegionError(), IsNil)
+	c.Assert(client.addrs, DeepEquals, []string{s.storeAddr(s.store2), s.storeAddr(s.store1)})
+	c.Assert(sender.replicaRead, Equals, kv.ReplicaReadLeader)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/coprocessor"
 	"github.com/pingcap/kvproto/pkg/errorpb"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
 )
 
 // RegionRequestSender sends KV/Cop requests to tikv server. It handles network
@@ -44,6 +45,9 @@ type RegionRequestSender struct {
 	regionCache *RegionCache
 	client      Client
 	storeAddr   string
+	// replicaRead is the type of the replicas which serve the requests, the requests are sent to the leader after
+	// a follower fails to serve a request.
+	replicaRead kv.ReplicaReadType
 }
 
 // NewRegionRequestSender creates a new sender.
@@ -58,7 +62,7 @@ func NewRegionRequestSender(bo *Backoffer, regionCache *RegionCache, client Clie
 // SendKVReq sends a KV request to tikv server.
 func (s *RegionRequestSender) SendKVReq(req *kvrpcpb.Request, regionID RegionVerID, timeout time.Duration) (*kvrpcpb.Response, error) {
 	for {
-		ctx, err := s.regionCache.GetRPCContext(s.bo, regionID)
+		ctx, err := s.regionCache.GetRPCContext(s.bo, regionID, s.replicaRead)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -101,7 +105,7 @@ func (s *RegionRequestSender) SendKVReq(req *kvrpcpb.Request, regionID RegionVer
 // SendCopReq sends a coprocessor request to tikv server.
 func (s *RegionRequestSender) SendCopReq(req *coprocessor.Request, regionID RegionVerID, timeout time.Duration) (*coprocessor.Response, error) {
 	for {
-		ctx, err := s.regionCache.GetRPCContext(s.bo, regionID)
+		ctx, err := s.regionCache.GetRPCContext(s.bo, regionID, s.replicaRead)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -138,6 +142,7 @@ func (s *RegionRequestSender) SendCopReq(req *coprocessor.Request, regionID Regi
 
 func (s *RegionRequestSender) sendKVReqToRegion(ctx *RPCContext, req *kvrpcpb.Request, timeout time.Duration) (resp *kvrpcpb.Response, retry bool, err error) {
 	req.Context = ctx.KVCtx
+	start := time.Now()
 	resp, err = s.client.SendKVReq(ctx.Context, ctx.Addr, req, timeout)
 	if err != nil {
 		if e := s.onSendFail(ctx, err); e != nil {
@@ -145,6 +150,8 @@ func (s *RegionRequestSender) sendKVReqToRegion(ctx *RPCContext, req *kvrpcpb.Re
 		}
 		return nil, true, nil
 	}
+	// Only the latencies of the kv requests are observed, the coprocessor requests take time by the data they scan.
+	s.regionCache.observeStoreLatency(ctx.GetStoreID(), time.Since(start))
 	return
 }
 
@@ -173,6 +180,12 @@ func (s *RegionRequestSender) onSendFail(ctx *RPCContext, err error) error {
 
 func (s *RegionRequestSender) onRegionError(ctx *RPCContext, regionErr *errorpb.Error) (retry bool, err error) {
 	reportRegionError(regionErr)
+	if notLeader := regionErr.GetNotLeader(); notLeader != nil && ctx.FollowerRead {
+		// The follower can't serve the read, retry on the leader.
+		log.Debugf("tikv reports `NotLeader` for the follower read: %s, ctx: %s, retry on the leader", notLeader, ctx.KVCtx)
+		s.replicaRead = kv.ReplicaReadLeader
+		return true, nil
+	}
 	if notLeader := regionErr.GetNotLeader(); notLeader != nil {
 		// Retry if error is `NotLeader`.
 		log.Debugf("tikv reports `NotLeader`: %s, ctx: %s, retry later", notLeader, ctx.KVCtx)
@@ -88,3 +88,40 @@ func (s *testScanSuite) TestSeek(c *C) {
 		c.Assert(scan.Valid(), IsFalse)
 	}
 }
+
+func (s *testScanSuite) TestSeekSplitRegions(c *C) {
+	if _, ok := s.store.client.(regionSplitter); !ok {
+		c.Skip("the regions are split by the mock tikv client only")
+	}
+	// The keys aren't encoded like encodeKey, they are compared as the raw bytes.
+	key := func(i int) []byte {
+		return []byte(fmt.Sprintf("%s_split_%08d", s.prefix, i))
+	}
+	txn := s.beginTxn(c)
+	for i := 0; i < 10; i++ {
+		c.Assert(txn.Set(key(i), valueBytes(i)), IsNil)
+	}
+	c.Assert(txn.Commit(), IsNil)
+	c.Assert(s.store.SplitRegion(key(3)), IsNil)
+	c.Assert(s.store.SplitRegion(key(7)), IsNil)
+	// Splitting at the start key of a region does nothing.
+	c.Assert(s.store.SplitRegion(key(7)), IsNil)
+
+	// Each key is returned once, the scan of a region stops at its end key.
+	txn = s.beginTxn(c)
+	scan, err := txn.Seek(key(0))
+	c.Assert(err, IsNil)
+	for i := 0; i < 10; i++ {
+		c.Assert(scan.Valid(), IsTrue)
+		c.Assert([]byte(scan.Key()), BytesEquals, key(i))
+		c.Assert(scan.Next(), IsNil)
+	}
+	c.Assert(scan.Valid() && scan.Key().HasPrefix(key(0)[:len(key(0))-8]), IsFalse)
+	scan.Close()
+
+	txn = s.beginTxn(c)
+	for i := 0; i < 10; i++ {
+		c.Assert(txn.Delete(key(i)), IsNil)
+	}
+	c.Assert(txn.Commit(), IsNil)
+}
@@ -15,6 +15,7 @@ package tikv
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/ngaut/log"
 	pb "github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/util/execdetails"
 	goctx "golang.org/x/net/context"
 )
 
@@ -36,8 +38,17 @@ const (
 
 // tikvSnapshot implements MvccSnapshot interface.
 type tikvSnapshot struct {
-	store   *tikvStore
-	version kv.Version
+	store       *tikvStore
+	version     kv.Version
+	priority    int
+	replicaRead kv.ReplicaReadType
+	// backoffDetails records the backoffs of the reads of the current statement.
+	backoffDetails *execdetails.BackoffDetails
+	// readDetails records the kv requests of the reads of the current statement.
+	readDetails *execdetails.ReadDetails
+	// held is 1 if the version is held in the running transactions of the store, so the GC worker keeps the
+	// versions the snapshot reads until it's released.
+	held int32
 }
 
 // newTiKVSnapshot creates a snapshot of an TiKV store.
@@ -57,7 +68,7 @@ func (s *tikvSnapshot) BatchGet(keys []kv.Key) (map[string][]byte, error) {
 
 	// We want [][]byte instead of []kv.Key, use some magic to save memory.
 	bytesKeys := *(*[][]byte)(unsafe.Pointer(&keys))
-	bo := NewBackoffer(batchGetMaxBackoff, goctx.Background())
+	bo := s.newBackoffer(batchGetMaxBackoff)
 
 	// Create a map to collect key-values from region servers.
 	var mu sync.Mutex
@@ -120,7 +131,7 @@ func (s *tikvSnapshot) batchGetSingleRegion(bo *Backoffer, batch batchKeys, coll
 				Version: s.version.Ver,
 			},
 		}
-		resp, err := s.store.SendKVReq(bo, req, batch.region, readTimeoutMedium)
+		resp, err := s.sendKVReq(bo, req, batch.region, readTimeoutMedium)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -173,7 +184,7 @@ func (s *tikvSnapshot) batchGetSingleRegion(bo *Backoffer, batch batchKeys, coll
 
 // Get gets the value for key k from snapshot.
 func (s *tikvSnapshot) Get(k kv.Key) ([]byte, error) {
-	val, err := s.get(NewBackoffer(getMaxBackoff, goctx.Background()), k)
+	val, err := s.get(s.newBackoffer(getMaxBackoff), k)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -196,7 +207,7 @@ func (s *tikvSnapshot) get(bo *Backoffer, k kv.Key) ([]byte, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		resp, err := s.store.SendKVReq(bo, req, loc.Region, readTimeoutShort)
+		resp, err := s.sendKVReq(bo, req, loc.Region, readTimeoutShort)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -233,6 +244,78 @@ func (s *tikvSnapshot) get(bo *Backoffer, k kv.Key) ([]byte, error) {
 	}
 }
 
+// SetPriority implements kv.Snapshot SetPriority interface.
+func (s *tikvSnapshot) SetPriority(priority int) {
+	s.priority = priority
+}
+
+// SetReplicaRead implements kv.Snapshot SetReplicaRead interface.
+func (s *tikvSnapshot) SetReplicaRead(typ kv.ReplicaReadType) {
+	s.replicaRead = typ
+}
+
+// Release implements kv.Snapshot Release interface.
+func (s *tikvSnapshot) Release() {
+	if atomic.CompareAndSwapInt32(&s.held, 1, 0) {
+		s.store.runningTxns.remove(s.version.Ver)
+	}
+}
+
+// newBackoffer creates a Backoffer for the reads of the snapshot.
+func (s *tikvSnapshot) newBackoffer(maxSleep int) *Backoffer {
+	ctx := goctx.Background()
+	if s.backoffDetails != nil {
+		ctx = goctx.WithValue(ctx, execdetails.BackoffDetailsKey, s.backoffDetails)
+	}
+	return NewBackoffer(maxSleep, ctx)
+}
+
+func (s *tikvSnapshot) sendKVReq(bo *Backoffer, req *pb.Request, regionID RegionVerID, timeout time.Duration) (*pb.Response, error) {
+	s.store.acquirePriority(s.priority)
+	defer s.store.releasePriority(s.priority)
+	sender := NewRegionRequestSender(bo, s.store.regionCache, s.store.client)
+	sender.replicaRead = s.replicaRead
+	resp, err := sender.SendKVReq(req, regionID, timeout)
+	if err == nil && resp.GetRegionError() == nil {
+		s.recordRead(regionID.id, req)
+		if s.readDetails != nil {
+			s.readDetails.Record(scannedKeys(resp))
+		}
+	}
+	return resp, errors.Trace(err)
+}
+
+// recordRead records the read request in the hot regions of the store.
+func (s *tikvSnapshot) recordRead(regionID uint64, req *pb.Request) {
+	switch req.GetType() {
+	case pb.MessageType_CmdGet:
+		key := req.GetCmdGetReq().GetKey()
+		s.store.hotRegions.RecordRead(regionID, key, [][]byte{key})
+	case pb.MessageType_CmdBatchGet:
+		keys := req.GetCmdBatchGetReq().GetKeys()
+		if len(keys) > 0 {
+			s.store.hotRegions.RecordRead(regionID, keys[0], keys)
+		}
+	case pb.MessageType_CmdScan:
+		s.store.hotRegions.RecordRead(regionID, req.GetCmdScanReq().GetStartKey(), nil)
+	}
+}
+
+// scannedKeys returns the number of the keys read by the request.
+func scannedKeys(resp *pb.Response) int64 {
+	switch resp.GetType() {
+	case pb.MessageType_CmdGet:
+		if len(resp.GetCmdGetResp().GetValue()) > 0 {
+			return 1
+		}
+	case pb.MessageType_CmdBatchGet:
+		return int64(len(resp.GetCmdBatchGetResp().GetPairs()))
+	case pb.MessageType_CmdScan:
+		return int64(len(resp.GetCmdScanResp().GetPairs()))
+	}
+	return 0
+}
+
 // Seek return a list of key-value pair after `k`.
 func (s *tikvSnapshot) Seek(k kv.Key) (kv.Iterator, error) {
 	scanner, err := newScanner(s, k, scanBatchSize)
@@ -129,6 +129,49 @@ func (s *testSnapshotSuite) TestBatchGetNotExist(c *C) {
 	}
 }
 
+func (s *testSnapshotSuite) TestLowPriority(c *C) {
+	txn := s.beginTxn(c)
+	k := encodeKey(s.prefix, s08d("key", 0))
+	err := txn.Set(k, valueBytes(0))
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	defer s.deleteKeys([]kv.Key{k}, c)
+
+	// Occupy all the slots of the low priority requests.
+	for i := 0; i < lowPriorityConcurrency; i++ {
+		s.store.acquirePriority(kv.PriorityLow)
+	}
+	ver, err := s.store.CurrentVersion()
+	c.Assert(err, IsNil)
+	lowSnapshot := newTiKVSnapshot(s.store, ver)
+	lowSnapshot.SetPriority(kv.PriorityLow)
+	done := make(chan error, 1)
+	go func() {
+		_, err1 := lowSnapshot.Get(k)
+		done <- err1
+	}()
+
+	// The normal priority request isn't blocked.
+	_, err = newTiKVSnapshot(s.store, ver).Get(k)
+	c.Assert(err, IsNil)
+	select {
+	case <-done:
+		c.Fatal("the low priority request isn't blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 0; i < lowPriorityConcurrency; i++ {
+		s.store.releasePriority(kv.PriorityLow)
+	}
+	select {
+	case err = <-done:
+		c.Assert(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the low priority request is blocked")
+	}
+}
+
 func makeKeys(rowNum int, prefix string) []kv.Key {
 	keys := make([]kv.Key, 0, rowNum)
 	for i := 0; i < rowNum; i++ {
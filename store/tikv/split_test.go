@@ -100,3 +100,18 @@ func (s *testSplitSuite) TestStaleEpoch(c *C) {
 	_, err = txn.Get([]byte("c"))
 	c.Assert(err, IsNil)
 }
+
+func (s *testSplitSuite) TestSupportCapability(c *C) {
+	for _, capability := range []kv.Capability{kv.CapCoprocessor, kv.CapFollowerRead, kv.CapRawKV, kv.CapSplitRegion} {
+		c.Assert(s.store.SupportCapability(capability), IsTrue)
+	}
+	// Only the mock tikv client can split the regions.
+	client := &recordAddrClient{Client: mocktikv.NewRPCClient(s.cluster, mocktikv.NewMvccStore())}
+	pdCli := &codecPDClient{mocktikv.NewPDClient(s.cluster)}
+	store, err := newTikvStore("mock-tikv-store-capability", pdCli, client, false)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	c.Assert(store.SupportCapability(kv.CapCoprocessor), IsTrue)
+	c.Assert(store.SupportCapability(kv.CapSplitRegion), IsFalse)
+	c.Assert(store.SplitRegion([]byte("a")), NotNil)
+}
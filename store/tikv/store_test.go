@@ -14,6 +14,7 @@
 package tikv
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -338,3 +339,53 @@ func (c *mockPDClient) GetStore(ctx goctx.Context, storeID uint64) (*metapb.Stor
 }
 
 func (c *mockPDClient) Close() {}
+
+func (s *testStoreSuite) TestFollowerRead(c *C) {
+	// Add a follower to the region.
+	region := s.cluster.GetAllRegions()[0]
+	storeID, peerID := s.cluster.AllocID(), s.cluster.AllocID()
+	followerAddr := fmt.Sprintf("store%d", storeID)
+	s.cluster.AddStore(storeID, followerAddr)
+	s.cluster.AddPeer(region.Meta.GetId(), storeID, peerID)
+	// The domain of a store is cached by its uuid, the store uses a different one to read its own schema.
+	client := &recordAddrClient{Client: mocktikv.NewRPCClient(s.cluster, mocktikv.NewMvccStore())}
+	pdCli := &codecPDClient{mocktikv.NewPDClient(s.cluster)}
+	store, err := newTikvStore("mock-tikv-store-follower-read", pdCli, client, false)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	_, err = tidb.BootstrapSession(store)
+	c.Assert(err, IsNil)
+	session, err := tidb.CreateSession(store)
+	c.Assert(err, IsNil)
+
+	query := func(sql string) {
+		rss, err := session.Execute(sql)
+		c.Assert(err, IsNil, Commentf("sql: %s", sql))
+		for _, rs := range rss {
+			_, err = tidb.GetRows(rs)
+			c.Assert(err, IsNil, Commentf("sql: %s", sql))
+		}
+	}
+	query("create table test.follower (a int primary key, b int)")
+	query("insert test.follower values (1, 1), (2, 2)")
+
+	queries := []string{
+		"select * from test.follower",
+		"select * from test.follower where a in (1, 2)",
+	}
+	for _, sql := range queries {
+		query(sql)
+	}
+	c.Assert(client.sentTo(followerAddr), Equals, 0)
+	query("set @@tidb_replica_read = 'follower'")
+	for _, sql := range queries {
+		sent := client.sentTo(followerAddr)
+		query(sql)
+		c.Assert(client.sentTo(followerAddr), Greater, sent, Commentf("sql: %s", sql))
+	}
+	// The follower can't serve the writes.
+	query("insert test.follower values (3, 3)")
+
+	_, err = session.Execute("set @@tidb_replica_read = 'learner'")
+	c.Assert(err, NotNil)
+}
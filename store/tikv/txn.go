@@ -21,6 +21,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tipb/go-binlog"
 	goctx "golang.org/x/net/context"
 )
@@ -31,6 +32,7 @@ var (
 
 // tikvTxn implements kv.Transaction.
 type tikvTxn struct {
+	snapshot  *tikvSnapshot
 	us        kv.UnionStore
 	store     *tikvStore // for connection to region.
 	startTS   uint64
@@ -39,6 +41,10 @@ type tikvTxn struct {
 	valid     bool
 	lockKeys  [][]byte
 	dirty     bool
+	// pessimisticKeys are the keys locked in the pessimistic lock table of the store.
+	pessimisticKeys map[string]struct{}
+	// stagingLockKeys are the numbers of the lockKeys when the stagings begin, indexed by the staging handles - 1.
+	stagingLockKeys []int
 }
 
 func newTiKVTxn(store *tikvStore) (*tikvTxn, error) {
@@ -47,9 +53,11 @@ func newTiKVTxn(store *tikvStore) (*tikvTxn, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	ver := kv.NewVersion(startTS)
+	snapshot := newTiKVSnapshot(store, kv.NewVersion(startTS))
+	store.runningTxns.add(startTS)
 	return &tikvTxn{
-		us:        kv.NewUnionStore(newTiKVSnapshot(store, ver)),
+		snapshot:  snapshot,
+		us:        kv.NewUnionStore(snapshot),
 		store:     store,
 		startTS:   startTS,
 		startTime: monotime.Now(),
@@ -59,9 +67,11 @@ func newTiKVTxn(store *tikvStore) (*tikvTxn, error) {
 
 // newTikvTxnWithStartTS creates a txn with startTS.
 func newTikvTxnWithStartTS(store *tikvStore, startTS uint64) (*tikvTxn, error) {
-	ver := kv.NewVersion(startTS)
+	snapshot := newTiKVSnapshot(store, kv.NewVersion(startTS))
+	store.runningTxns.add(startTS)
 	return &tikvTxn{
-		us:        kv.NewUnionStore(newTiKVSnapshot(store, ver)),
+		snapshot:  snapshot,
+		us:        kv.NewUnionStore(snapshot),
 		store:     store,
 		startTS:   startTS,
 		startTime: monotime.Now(),
@@ -82,9 +92,20 @@ func (txn *tikvTxn) Get(k kv.Key) ([]byte, error) {
 	return ret, nil
 }
 
+func (txn *tikvTxn) Prefetch(keys []kv.Key) error {
+	txnCmdCounter.WithLabelValues("prefetch").Inc()
+	start := time.Now()
+	defer func() { txnCmdHistogram.WithLabelValues("prefetch").Observe(time.Since(start).Seconds()) }()
+
+	return errors.Trace(txn.us.Prefetch(keys))
+}
+
 func (txn *tikvTxn) Set(k kv.Key, v []byte) error {
 	txnCmdCounter.WithLabelValues("set").Inc()
 
+	if err := txn.lockPessimistic(k); err != nil {
+		return errors.Trace(err)
+	}
 	txn.dirty = true
 	return txn.us.Set(k, v)
 }
@@ -113,12 +134,76 @@ func (txn *tikvTxn) SeekReverse(k kv.Key) (kv.Iterator, error) {
 func (txn *tikvTxn) Delete(k kv.Key) error {
 	txnCmdCounter.WithLabelValues("delete").Inc()
 
+	if err := txn.lockPessimistic(k); err != nil {
+		return errors.Trace(err)
+	}
 	txn.dirty = true
 	return txn.us.Delete(k)
 }
 
+// defaultLockWaitTimeout is the lock wait timeout of the pessimistic transactions which don't set it.
+const defaultLockWaitTimeout = 50 * time.Second
+
+// lockPessimistic locks the keys in the pessimistic lock table if the transaction is pessimistic,
+// it waits if the keys are locked by the other transactions unless the LockWaitPolicy is LockNoWait.
+func (txn *tikvTxn) lockPessimistic(keys ...kv.Key) error {
+	if pessimistic, ok := txn.us.GetOption(kv.Pessimistic).(bool); !ok || !pessimistic {
+		return nil
+	}
+	timeout, ok := txn.us.GetOption(kv.LockWaitTimeout).(time.Duration)
+	if !ok {
+		timeout = defaultLockWaitTimeout
+	}
+	policy, _ := txn.us.GetOption(kv.LockWaitPolicy).(kv.LockWaitPolicyType)
+	if txn.pessimisticKeys == nil {
+		txn.pessimisticKeys = make(map[string]struct{})
+	}
+	for _, k := range keys {
+		if _, ok := txn.pessimisticKeys[string(k)]; ok {
+			continue
+		}
+		var err error
+		if policy == kv.LockNoWait {
+			err = txn.store.pessimisticLocks.tryLock(k, txn.startTS)
+		} else {
+			start := time.Now()
+			err = txn.store.pessimisticLocks.lock(k, txn.startTS, timeout)
+			txnCmdHistogram.WithLabelValues("lock_wait").Observe(time.Since(start).Seconds())
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		txn.pessimisticKeys[string(k)] = struct{}{}
+	}
+	return nil
+}
+
+// releasePessimisticLocks releases the keys locked in the pessimistic lock table,
+// commitTS is 0 if the transaction is rolled back.
+func (txn *tikvTxn) releasePessimisticLocks(commitTS uint64) {
+	if len(txn.pessimisticKeys) == 0 {
+		return
+	}
+	keys := make([][]byte, 0, len(txn.pessimisticKeys))
+	for k := range txn.pessimisticKeys {
+		keys = append(keys, []byte(k))
+	}
+	txn.store.pessimisticLocks.release(keys, txn.startTS, commitTS)
+	txn.pessimisticKeys = nil
+}
+
 func (txn *tikvTxn) SetOption(opt kv.Option, val interface{}) {
 	txn.us.SetOption(opt, val)
+	switch opt {
+	case kv.ReplicaRead:
+		txn.snapshot.SetReplicaRead(val.(kv.ReplicaReadType))
+	case kv.BackoffDetails:
+		txn.snapshot.backoffDetails, _ = val.(*execdetails.BackoffDetails)
+	case kv.ReadDetails:
+		txn.snapshot.readDetails, _ = val.(*execdetails.ReadDetails)
+	case kv.Priority:
+		txn.snapshot.SetPriority(val.(int))
+	}
 }
 
 func (txn *tikvTxn) DelOption(opt kv.Option) {
@@ -130,6 +215,8 @@ func (txn *tikvTxn) Commit() error {
 		return kv.ErrInvalidTxn
 	}
 	defer txn.close()
+	// The locks are released after the transaction is committed, so the waiting transactions can read the new data.
+	defer func() { txn.releasePessimisticLocks(txn.commitTS) }()
 
 	txnCmdCounter.WithLabelValues("commit").Inc()
 	start := time.Now()
@@ -146,6 +233,17 @@ func (txn *tikvTxn) Commit() error {
 	if committer == nil {
 		return nil
 	}
+	if latches := txn.store.txnLatches; latches != nil {
+		startWait := time.Now()
+		ids, err := latches.acquire(txn.startTS, committer.keys)
+		txnCmdHistogram.WithLabelValues("wait_latch").Observe(time.Since(startWait).Seconds())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// The latches are released after the transaction is committed, so the waiting transactions know the
+		// commit ts.
+		defer func() { latches.release(ids, txn.commitTS) }()
+	}
 	err = committer.execute()
 	if err != nil {
 		committer.writeFinishBinlog(binlog.BinlogType_Rollback, 0)
@@ -158,6 +256,7 @@ func (txn *tikvTxn) Commit() error {
 
 func (txn *tikvTxn) close() error {
 	txn.valid = false
+	txn.store.runningTxns.remove(txn.startTS)
 	return nil
 }
 
@@ -166,6 +265,7 @@ func (txn *tikvTxn) Rollback() error {
 		return kv.ErrInvalidTxn
 	}
 	txn.close()
+	txn.releasePessimisticLocks(0)
 	log.Infof("[kv] Rollback txn %d", txn.StartTS())
 	txnCmdCounter.WithLabelValues("rollback").Inc()
 
@@ -174,6 +274,9 @@ func (txn *tikvTxn) Rollback() error {
 
 func (txn *tikvTxn) LockKeys(keys ...kv.Key) error {
 	txnCmdCounter.WithLabelValues("lock_keys").Inc()
+	if err := txn.lockPessimistic(keys...); err != nil {
+		return errors.Trace(err)
+	}
 	for _, key := range keys {
 		txn.lockKeys = append(txn.lockKeys, key)
 	}
@@ -199,3 +302,22 @@ func (txn *tikvTxn) Len() int {
 func (txn *tikvTxn) Size() int {
 	return txn.us.Size()
 }
+
+func (txn *tikvTxn) Staging() kv.StagingHandle {
+	h := txn.us.Staging()
+	txn.stagingLockKeys = append(txn.stagingLockKeys[:h-1], len(txn.lockKeys))
+	return h
+}
+
+func (txn *tikvTxn) Release(h kv.StagingHandle) {
+	txn.us.Release(h)
+	txn.stagingLockKeys = txn.stagingLockKeys[:h-1]
+}
+
+// Cleanup discards the changes and the lock keys of the staging. The keys locked in the pessimistic lock table are
+// kept locked until the transaction ends.
+func (txn *tikvTxn) Cleanup(h kv.StagingHandle) {
+	txn.us.Cleanup(h)
+	txn.lockKeys = txn.lockKeys[:txn.stagingLockKeys[h-1]]
+	txn.stagingLockKeys = txn.stagingLockKeys[:h-1]
+}
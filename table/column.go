@@ -23,10 +23,12 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -141,6 +143,27 @@ func CastValue(ctx context.Context, val types.Datum, col *model.ColumnInfo) (cas
 	return casted, errors.Trace(err)
 }
 
+// ParseGeneratedExpr parses the expression string of the hidden column of an expression index, or the expression
+// default value of a column.
+func ParseGeneratedExpr(exprString string) (ast.ExprNode, error) {
+	stmt, err := parser.New().ParseOneStmt("select "+exprString, "", "")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return stmt.(*ast.SelectStmt).Fields.Fields[0].Expr, nil
+}
+
+// BuildGeneratedExpr builds the expression of the hidden column of an expression index, it's evaluated on the rows
+// of the table which are indexed by the column offsets.
+func BuildGeneratedExpr(ctx context.Context, tblInfo *model.TableInfo, col *model.ColumnInfo) (expression.Expression, error) {
+	node, err := ParseGeneratedExpr(col.GeneratedExprString)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	expr, err := expression.RewriteTableExpr(node, ctx, tblInfo)
+	return expr, errors.Trace(err)
+}
+
 // ColDesc describes column information like MySQL desc and show columns do.
 type ColDesc struct {
 	Field        string
@@ -194,6 +217,8 @@ func NewColDesc(col *Column) *ColDesc {
 		extra = "auto_increment"
 	} else if mysql.HasOnUpdateNowFlag(col.Flag) {
 		extra = "on update CURRENT_TIMESTAMP"
+	} else if col.DefaultIsExpr {
+		extra = "DEFAULT_GENERATED"
 	}
 
 	return &ColDesc{
@@ -263,9 +288,31 @@ func GetColOriginDefaultValue(ctx context.Context, col *model.ColumnInfo) (types
 
 // GetColDefaultValue gets default value of the column.
 func GetColDefaultValue(ctx context.Context, col *model.ColumnInfo) (types.Datum, error) {
+	if col.DefaultIsExpr {
+		return getColDefaultExprValue(ctx, col)
+	}
 	return getColDefaultValue(ctx, col, col.DefaultValue)
 }
 
+// getColDefaultExprValue evaluates the expression default value of the column.
+func getColDefaultExprValue(ctx context.Context, col *model.ColumnInfo) (types.Datum, error) {
+	exprString, ok := col.DefaultValue.(string)
+	if !ok {
+		return types.Datum{}, errGetDefaultFailed.Gen("Field '%s' get default value fail - invalid expression %v",
+			col.Name, col.DefaultValue)
+	}
+	node, err := ParseGeneratedExpr(exprString)
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	value, err := expression.EvalAstExpr(node, ctx)
+	if err != nil {
+		return types.Datum{}, errGetDefaultFailed.Gen("Field '%s' get default value fail - %s", col.Name, err)
+	}
+	value, err = CastValue(ctx, value, col)
+	return value, errors.Trace(err)
+}
+
 func getColDefaultValue(ctx context.Context, col *model.ColumnInfo, defaultVal interface{}) (types.Datum, error) {
 	if defaultVal == nil {
 		return getColDefaultValueFromNil(ctx, col)
@@ -36,7 +36,8 @@ func encodeHandle(h int64) []byte {
 	return buf.Bytes()
 }
 
-func decodeHandle(data []byte) (int64, error) {
+// DecodeHandle decodes the handle stored in the value of a unique index key.
+func DecodeHandle(data []byte) (int64, error) {
 	var h int64
 	buf := bytes.NewBuffer(data)
 	err := binary.Read(buf, binary.BigEndian, &h)
@@ -78,7 +79,7 @@ func (c *indexIter) Next() (val []types.Datum, h int64, err error) {
 		val = vv[0 : len(vv)-1]
 	} else {
 		// otherwise handle is value
-		h, err = decodeHandle(c.it.Value())
+		h, err = DecodeHandle(c.it.Value())
 		if err != nil {
 			return nil, 0, errors.Trace(err)
 		}
@@ -175,7 +176,7 @@ func (c *index) Create(rm kv.RetrieverMutator, indexedValues []types.Datum, h in
 		err = rm.Set(key, encodeHandle(h))
 		return 0, errors.Trace(err)
 	}
-	handle, err := decodeHandle(value)
+	handle, err := DecodeHandle(value)
 	if err != nil {
 		return 0, errors.Trace(err)
 	}
@@ -260,7 +261,7 @@ func (c *index) Exist(rm kv.RetrieverMutator, indexedValues []types.Datum, h int
 
 	// For distinct index, the value of key is handle.
 	if distinct {
-		handle, err := decodeHandle(value)
+		handle, err := DecodeHandle(value)
 		if err != nil {
 			return false, 0, errors.Trace(err)
 		}
@@ -19,6 +19,7 @@ package tables
 
 import (
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
@@ -29,6 +30,7 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
@@ -185,9 +187,20 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 
 	// Compose new row
 	t.composeNewData(touched, currentData, oldData)
+	newRow, err := t.convertChangingColumns(ctx, currentData, touched, false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The old values of the changing columns are converted as well, the rows may have not been reorganized.
+	oldRow, err := t.convertChangingColumns(ctx, oldData, nil, true)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	colIDs := make([]int64, 0, len(t.WritableCols()))
 	for i, col := range t.WritableCols() {
-		if col.State != model.StatePublic && currentData[i].IsNull() {
+		if col.ChangeStateInfo != nil && col.State != model.StatePublic {
+			currentData[i] = newRow[col.Offset]
+		} else if col.State != model.StatePublic && currentData[i].IsNull() {
 			defaultVal, err1 := table.GetColDefaultValue(ctx, col.ToInfo())
 			if err1 != nil {
 				return errors.Trace(err1)
@@ -196,6 +209,16 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 		}
 		colIDs = append(colIDs, col.ID)
 	}
+	tz := ctx.GetSessionVars().StmtCtx.TimeZone
+	if currentData, err = tablecodec.ConvertTimeZone(currentData, tz, nil); err != nil {
+		return errors.Trace(err)
+	}
+	if newRow, err = tablecodec.ConvertTimeZone(newRow, tz, nil); err != nil {
+		return errors.Trace(err)
+	}
+	if oldRow, err = tablecodec.ConvertTimeZone(oldRow, tz, nil); err != nil {
+		return errors.Trace(err)
+	}
 	// Set new row data into KV.
 	key := t.RecordKey(h)
 	value, err := tablecodec.EncodeRow(currentData, colIDs)
@@ -207,7 +230,7 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 	}
 
 	// rebuild index
-	if err = t.rebuildIndices(bs, h, touched, oldData, currentData); err != nil {
+	if err = t.rebuildIndices(bs, h, touched, oldRow, newRow); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -216,6 +239,10 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 		return errors.Trace(err)
 	}
 	if shouldWriteBinlog(ctx) {
+		oldData, err = tablecodec.ConvertTimeZone(oldData, tz, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
 		t.addUpdateBinlog(ctx, h, oldData, value, colIDs)
 	}
 	return nil
@@ -292,21 +319,44 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 			break
 		}
 	}
+	txn := ctx.Txn()
 	if !hasRecordID {
 		recordID, err = t.alloc.Alloc(t.ID)
 		if err != nil {
 			return 0, errors.Trace(err)
 		}
+		if shardBits := t.meta.ShardRowIDBits; shardBits > 0 {
+			recordID, err = autoid.EncodeShardRowID(recordID, autoid.TxnShard(txn.StartTS()), shardBits)
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+		}
 	}
-	txn := ctx.Txn()
 	skipCheck := ctx.GetSessionVars().SkipConstraintCheck
 	if skipCheck {
 		txn.SetOption(kv.SkipCheckForWrite, true)
 	}
 
-	bs := kv.NewBufferStore(txn)
+	// The index entries are buffered until all of them are checked, so nothing is written if one of them is
+	// duplicated. The check can't fail when it's skipped, so they are written to txn directly.
+	var bs *kv.BufferStore
+	var rm kv.RetrieverMutator = txn
+	if !skipCheck {
+		bs = kv.NewBufferStore(txn)
+		rm = bs
+	}
+	r, err = t.convertChangingColumns(ctx, r, nil, false)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	// The TIMESTAMP values are encoded in the system time zone.
+	tz := ctx.GetSessionVars().StmtCtx.TimeZone
+	idxRow, err := tablecodec.ConvertTimeZone(r, tz, nil)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
 	// Insert new entries into indices.
-	h, err := t.addIndices(ctx, recordID, r, bs)
+	h, err := t.addIndices(ctx, recordID, idxRow, rm)
 	if err != nil {
 		return h, errors.Trace(err)
 	}
@@ -319,7 +369,9 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 			continue
 		}
 		var value types.Datum
-		if col.State == model.StateWriteOnly || col.State == model.StateWriteReorganization {
+		if col.ChangeStateInfo != nil && col.State != model.StatePublic {
+			value = r[col.Offset]
+		} else if col.State == model.StateWriteOnly || col.State == model.StateWriteReorganization {
 			// if col is in write only or write reorganization state, we must add it with its default value.
 			value, err = table.GetColDefaultValue(ctx, col.ToInfo())
 			if err != nil {
@@ -335,6 +387,9 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 		colIDs = append(colIDs, col.ID)
 		row = append(row, value)
 	}
+	if row, err = tablecodec.ConvertTimeZone(row, tz, nil); err != nil {
+		return 0, errors.Trace(err)
+	}
 	key := t.RecordKey(recordID)
 	value, err := tablecodec.EncodeRow(row, colIDs)
 	if err != nil {
@@ -343,8 +398,10 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 	if err = txn.Set(key, value); err != nil {
 		return 0, errors.Trace(err)
 	}
-	if err = bs.SaveTo(txn); err != nil {
-		return 0, errors.Trace(err)
+	if bs != nil {
+		if err = bs.SaveTo(txn); err != nil {
+			return 0, errors.Trace(err)
+		}
 	}
 	if shouldWriteBinlog(ctx) {
 		mutation := t.getMutation(ctx)
@@ -358,8 +415,92 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 	return recordID, nil
 }
 
+// convertChangingColumns returns the row with the values of the non-public columns whose types are being changed,
+// they're converted from the values of their dependency columns, and the values of the hidden columns of the
+// expression indices. A column is touched if its dependency column is touched. If ignoreErr is true, the values which
+// fail to be converted are set to the truncated values.
+func (t *Table) convertChangingColumns(ctx context.Context, r []types.Datum, touched map[int]bool, ignoreErr bool) ([]types.Datum, error) {
+	var row []types.Datum
+	for _, col := range t.Columns {
+		if !col.ToInfo().IsGenerated() && (col.ChangeStateInfo == nil || col.State == model.StatePublic) {
+			continue
+		}
+		if row == nil {
+			row = make([]types.Datum, len(t.Columns))
+			copy(row, r)
+		}
+		if col.ToInfo().IsGenerated() {
+			if err := t.evalGeneratedColumn(ctx, col, row, touched, ignoreErr); err != nil {
+				return nil, errors.Trace(err)
+			}
+			continue
+		}
+		depOffset := col.ChangeStateInfo.DependencyColumnOffset
+		// The values are not written in the delete only states, so they needn't be checked.
+		lenient := ignoreErr || col.ChangeStateInfo.Origin || col.State == model.StateDeleteOnly ||
+			col.State == model.StateDeleteReorganization
+		value, err := convertChangingValue(ctx, col, row[depOffset], lenient)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		row[col.Offset] = value
+		if touched != nil && touched[depOffset] {
+			touched[col.Offset] = true
+		}
+	}
+	if row == nil {
+		return r, nil
+	}
+	return row, nil
+}
+
+// evalGeneratedColumn sets the value of the hidden column of an expression index in the row, the column is touched if
+// any column in the expression is touched.
+func (t *Table) evalGeneratedColumn(ctx context.Context, col *table.Column, row []types.Datum, touched map[int]bool,
+	ignoreErr bool) error {
+	expr, err := table.BuildGeneratedExpr(ctx, t.meta, col.ToInfo())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	val, err := expr.Eval(row)
+	if err != nil {
+		if !ignoreErr {
+			return errors.Trace(err)
+		}
+		val = types.Datum{}
+	}
+	val, err = convertChangingValue(ctx, col, val, ignoreErr)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	row[col.Offset] = val
+	if touched != nil {
+		for _, c := range expression.ExtractColumns(expr) {
+			if touched[c.Index] {
+				touched[col.Offset] = true
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func convertChangingValue(ctx context.Context, col *table.Column, val types.Datum, ignoreErr bool) (types.Datum, error) {
+	if ignoreErr {
+		// The warnings are not reported to the statement, the column is invisible.
+		sc := &variable.StatementContext{IgnoreTruncate: true}
+		casted, _ := val.ConvertTo(sc, &col.FieldType)
+		return casted, nil
+	}
+	casted, err := table.CastValue(ctx, val, col.ToInfo())
+	if err != nil {
+		return casted, errors.Trace(err)
+	}
+	return casted, errors.Trace(col.CheckNotNull(casted))
+}
+
 // Generate index content string representation.
-func (t *Table) genIndexKeyStr(colVals []types.Datum) (string, error) {
+func genIndexKeyStr(colVals []types.Datum) (string, error) {
 	// Pass pre-composed error to txn.
 	strVals := make([]string, 0, len(colVals))
 	for _, cv := range colVals {
@@ -377,7 +518,7 @@ func (t *Table) genIndexKeyStr(colVals []types.Datum) (string, error) {
 }
 
 // Add data into indices.
-func (t *Table) addIndices(ctx context.Context, recordID int64, r []types.Datum, bs *kv.BufferStore) (int64, error) {
+func (t *Table) addIndices(ctx context.Context, recordID int64, r []types.Datum, rm kv.RetrieverMutator) (int64, error) {
 	txn := ctx.Txn()
 	// Clean up lazy check error environment
 	defer txn.DelOption(kv.PresumeKeyNotExistsError)
@@ -407,14 +548,14 @@ func (t *Table) addIndices(ctx context.Context, recordID int64, r []types.Datum,
 		}
 		var dupKeyErr error
 		if !skipCheck && (v.Meta().Unique || v.Meta().Primary) {
-			entryKey, err1 := t.genIndexKeyStr(colVals)
+			entryKey, err1 := genIndexKeyStr(colVals)
 			if err1 != nil {
 				return 0, errors.Trace(err1)
 			}
 			dupKeyErr = kv.ErrKeyExists.FastGen("Duplicate entry '%s' for key '%s'", entryKey, v.Meta().Name)
 			txn.SetOption(kv.PresumeKeyNotExistsError, dupKeyErr)
 		}
-		if dupHandle, err := v.Create(bs, colVals, recordID); err != nil {
+		if dupHandle, err := v.Create(rm, colVals, recordID); err != nil {
 			if terror.ErrorEqual(err, kv.ErrKeyExists) {
 				return dupHandle, errors.Trace(dupKeyErr)
 			}
@@ -454,6 +595,9 @@ func (t *Table) RowWithCols(ctx context.Context, h int64, cols []*table.Column)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if err = convertDecodedTimeZone(ctx, row); err != nil {
+		return nil, errors.Trace(err)
+	}
 	for i, col := range cols {
 		if col == nil {
 			continue
@@ -482,6 +626,27 @@ func (t *Table) RowWithCols(ctx context.Context, h int64, cols []*table.Column)
 	return v, nil
 }
 
+// convertDecodedTimeZone converts the TIMESTAMP values of the decoded row from the system time zone, in which they
+// are encoded, to the session time zone.
+func convertDecodedTimeZone(ctx context.Context, row map[int64]types.Datum) error {
+	tz := ctx.GetSessionVars().StmtCtx.TimeZone
+	if tz == nil || tz == time.Local {
+		return nil
+	}
+	for id, d := range row {
+		if d.Kind() != types.KindMysqlTime {
+			continue
+		}
+		t := d.GetMysqlTime()
+		if err := t.ConvertTimeZone(nil, tz); err != nil {
+			return errors.Trace(err)
+		}
+		d.SetMysqlTime(t)
+		row[id] = d
+	}
+	return nil
+}
+
 // Row implements table.Table Row interface.
 func (t *Table) Row(ctx context.Context, h int64) ([]types.Datum, error) {
 	r, err := t.RowWithCols(ctx, h, t.Cols())
@@ -503,6 +668,10 @@ func (t *Table) RemoveRecord(ctx context.Context, h int64, r []types.Datum) erro
 		return errors.Trace(err)
 	}
 	if shouldWriteBinlog(ctx) {
+		r, err = tablecodec.ConvertTimeZone(r, ctx.GetSessionVars().StmtCtx.TimeZone, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
 		err = t.addDeleteBinlog(ctx, r)
 	}
 	return errors.Trace(err)
@@ -549,6 +718,14 @@ func (t *Table) removeRowData(ctx context.Context, h int64) error {
 
 // removeRowAllIndex removes all the indices of a row.
 func (t *Table) removeRowIndices(ctx context.Context, h int64, rec []types.Datum) error {
+	rec, err := t.convertChangingColumns(ctx, rec, nil, true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rec, err = tablecodec.ConvertTimeZone(rec, ctx.GetSessionVars().StmtCtx.TimeZone, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	for _, v := range t.indices {
 		vals, err := v.FetchValues(rec)
 		if vals == nil {
@@ -622,6 +799,9 @@ func (t *Table) IterRecords(ctx context.Context, startKey kv.Key, cols []*table.
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if err = convertDecodedTimeZone(ctx, rowMap); err != nil {
+			return errors.Trace(err)
+		}
 		data := make([]types.Datum, len(cols))
 		for _, col := range cols {
 			if col.IsPKHandleColumn(t.Meta()) {
@@ -695,7 +875,7 @@ func (t *Table) Seek(ctx context.Context, h int64) (int64, bool, error) {
 }
 
 func shouldWriteBinlog(ctx context.Context) bool {
-	if binloginfo.PumpClient == nil {
+	if !binloginfo.Enabled() {
 		return false
 	}
 	return !ctx.GetSessionVars().InRestrictedSQL
@@ -0,0 +1,768 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tables
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/goleveldb/leveldb"
+	"github.com/pingcap/goleveldb/leveldb/comparer"
+	"github.com/pingcap/goleveldb/leveldb/iterator"
+	"github.com/pingcap/goleveldb/leveldb/memdb"
+	"github.com/pingcap/goleveldb/leveldb/util"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta/autoid"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// TemporaryTable implements table.Table interface for a session temporary table.
+// The rows and the unique index entries are kept in memory until their size exceeds the max memory size, then
+// they are spilled to a leveldb in a temporary directory. The changes are applied at once, the table is only
+// visible to its session. If EnableTxn is called, the previous values of the written keys are kept in an undo
+// log, so the changes can be undone when the statement or the transaction fails. The undo log counts towards
+// the max memory size too, it's spilled to the same leveldb as the rows.
+type TemporaryTable struct {
+	ID          int64
+	Name        model.CIStr
+	Columns     []*table.Column
+	pkHandleCol *table.Column
+	// uniqueIndices are the unique indices whose keys are checked when writing rows.
+	uniqueIndices []table.Index
+	colIDs        []int64
+	colTps        map[int64]*types.FieldType
+
+	recordPrefix kv.Key
+	alloc        autoid.Allocator
+	meta         *model.TableInfo
+
+	maxMemSize int64
+	mem        *memdb.DB
+	disk       *leveldb.DB
+	dir        string
+	mu         sync.RWMutex
+
+	// txnEnabled is true if the changes are kept in the undo log.
+	txnEnabled bool
+	// undoLen is the length of the undo log, the first undoOnDisk records are in the leveldb and the others
+	// are kept in undo, in the order they are written.
+	undoLen    int
+	undoOnDisk int
+	undo       []undoRecord
+	// undoSize is the size of the undo records in memory.
+	undoSize int64
+}
+
+// undoRecord is the value of a key before it's written, the value is nil if the key didn't exist.
+type undoRecord struct {
+	key   kv.Key
+	value []byte
+}
+
+// undoPrefix is the prefix of the undo records spilled to the leveldb, it doesn't conflict with the table keys.
+var undoPrefix = []byte("u")
+
+func undoKey(i int) []byte {
+	return codec.EncodeInt(append([]byte{}, undoPrefix...), int64(i))
+}
+
+// encodeUndo encodes the undo record as a flag of whether the value exists, followed by the key and the value.
+func encodeUndo(r undoRecord) []byte {
+	flag := byte(0)
+	if r.value != nil {
+		flag = 1
+	}
+	b := codec.EncodeBytes([]byte{flag}, r.key)
+	return append(b, r.value...)
+}
+
+func decodeUndo(b []byte) (undoRecord, error) {
+	if len(b) == 0 {
+		return undoRecord{}, errors.New("invalid undo record")
+	}
+	value, key, err := codec.DecodeBytes(b[1:])
+	if err != nil {
+		return undoRecord{}, errors.Trace(err)
+	}
+	r := undoRecord{key: key}
+	if b[0] == 1 {
+		r.value = append([]byte{}, value...)
+	}
+	return r, nil
+}
+
+// TemporaryTableFromMeta creates a TemporaryTable instance from model.TableInfo, the rows are spilled to the disk
+// when their size exceeds maxMemSize.
+func TemporaryTableFromMeta(alloc autoid.Allocator, tblInfo *model.TableInfo, maxMemSize int64) (*TemporaryTable, error) {
+	t := &TemporaryTable{
+		ID:           tblInfo.ID,
+		Name:         tblInfo.Name,
+		recordPrefix: tablecodec.GenTableRecordPrefix(tblInfo.ID),
+		alloc:        alloc,
+		meta:         tblInfo,
+		colTps:       make(map[int64]*types.FieldType, len(tblInfo.Columns)),
+		maxMemSize:   maxMemSize,
+		mem:          memdb.New(comparer.DefaultComparer, 4*1024),
+	}
+	for _, colInfo := range tblInfo.Columns {
+		if colInfo.State != model.StatePublic {
+			return nil, table.ErrColumnStateNonPublic.Gen("column %s can't be in none state", colInfo.Name)
+		}
+		col := table.ToColumn(colInfo)
+		t.Columns = append(t.Columns, col)
+		t.colIDs = append(t.colIDs, col.ID)
+		t.colTps[col.ID] = &col.FieldType
+		if col.IsPKHandleColumn(tblInfo) {
+			t.pkHandleCol = col
+		}
+	}
+	for _, idxInfo := range tblInfo.Indices {
+		if idxInfo.Unique || idxInfo.Primary {
+			t.uniqueIndices = append(t.uniqueIndices, NewIndex(tblInfo, idxInfo))
+		}
+	}
+	return t, nil
+}
+
+func (t *TemporaryTable) get(key kv.Key) ([]byte, error) {
+	var (
+		value []byte
+		err   error
+	)
+	if t.disk != nil {
+		value, err = t.disk.Get(key, nil)
+	} else {
+		value, err = t.mem.Get(key)
+	}
+	if err == leveldb.ErrNotFound {
+		return nil, kv.ErrNotExist
+	}
+	return value, errors.Trace(err)
+}
+
+// put sets the value of the key and keeps its previous value in the undo log.
+func (t *TemporaryTable) put(key kv.Key, value []byte) error {
+	if err := t.saveUndo(key); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(t.set(key, value))
+}
+
+// delete deletes the key and keeps its previous value in the undo log.
+func (t *TemporaryTable) delete(key kv.Key) error {
+	if err := t.saveUndo(key); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(t.remove(key))
+}
+
+func (t *TemporaryTable) saveUndo(key kv.Key) error {
+	if !t.txnEnabled {
+		return nil
+	}
+	value, err := t.get(key)
+	if terror.ErrorEqual(err, kv.ErrNotExist) {
+		value = nil
+	} else if err != nil {
+		return errors.Trace(err)
+	} else {
+		// The value returned by the memdb is reused by the following writes, the empty value is kept non-nil.
+		value = append([]byte{}, value...)
+	}
+	t.undo = append(t.undo, undoRecord{key: key, value: value})
+	t.undoLen++
+	t.undoSize += int64(len(key) + len(value))
+	if t.memSize() <= t.maxMemSize {
+		return nil
+	}
+	if t.disk == nil {
+		return errors.Trace(t.spill())
+	}
+	return errors.Trace(t.spillUndo())
+}
+
+// memSize returns the size of the rows and the undo records in memory.
+func (t *TemporaryTable) memSize() int64 {
+	return int64(t.mem.Size()) + t.undoSize
+}
+
+// spillUndo moves the undo records in memory to the leveldb.
+func (t *TemporaryTable) spillUndo() error {
+	if len(t.undo) == 0 {
+		return nil
+	}
+	batch := new(leveldb.Batch)
+	for i, r := range t.undo {
+		batch.Put(undoKey(t.undoOnDisk+i), encodeUndo(r))
+	}
+	if err := t.disk.Write(batch, nil); err != nil {
+		return errors.Trace(err)
+	}
+	t.undoOnDisk = t.undoLen
+	t.undo, t.undoSize = nil, 0
+	return nil
+}
+
+// lastUndo returns the last record of the undo log.
+func (t *TemporaryTable) lastUndo() (undoRecord, error) {
+	if len(t.undo) > 0 {
+		return t.undo[len(t.undo)-1], nil
+	}
+	value, err := t.disk.Get(undoKey(t.undoLen-1), nil)
+	if err != nil {
+		return undoRecord{}, errors.Trace(err)
+	}
+	return decodeUndo(value)
+}
+
+// popUndo removes the last record of the undo log.
+func (t *TemporaryTable) popUndo() error {
+	if n := len(t.undo); n > 0 {
+		r := t.undo[n-1]
+		t.undo = t.undo[:n-1]
+		t.undoSize -= int64(len(r.key) + len(r.value))
+	} else {
+		if err := t.disk.Delete(undoKey(t.undoLen-1), nil); err != nil {
+			return errors.Trace(err)
+		}
+		t.undoOnDisk--
+	}
+	t.undoLen--
+	return nil
+}
+
+// clearUndo removes all records of the undo log.
+func (t *TemporaryTable) clearUndo() error {
+	if t.undoOnDisk > 0 {
+		batch := new(leveldb.Batch)
+		for i := 0; i < t.undoOnDisk; i++ {
+			batch.Delete(undoKey(i))
+		}
+		if err := t.disk.Write(batch, nil); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	t.undo, t.undoSize = nil, 0
+	t.undoLen, t.undoOnDisk = 0, 0
+	return nil
+}
+
+func (t *TemporaryTable) set(key kv.Key, value []byte) error {
+	if t.disk != nil {
+		return errors.Trace(t.disk.Put(key, value, nil))
+	}
+	if err := t.mem.Put(key, value); err != nil {
+		return errors.Trace(err)
+	}
+	if t.memSize() > t.maxMemSize {
+		return errors.Trace(t.spill())
+	}
+	return nil
+}
+
+func (t *TemporaryTable) remove(key kv.Key) error {
+	if t.disk != nil {
+		return errors.Trace(t.disk.Delete(key, nil))
+	}
+	err := t.mem.Delete(key)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	return errors.Trace(err)
+}
+
+func (t *TemporaryTable) newIterator(start, limit kv.Key) iterator.Iterator {
+	slice := &util.Range{Start: start, Limit: limit}
+	if t.disk != nil {
+		return t.disk.NewIterator(slice, nil)
+	}
+	return t.mem.NewIterator(slice)
+}
+
+// spill moves the rows and the undo records in memory to a leveldb in a temporary directory, the following writes
+// go to the disk.
+func (t *TemporaryTable) spill() error {
+	dir, err := ioutil.TempDir("", "tidb-tmp-table-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		return errors.Trace(err)
+	}
+	batch := new(leveldb.Batch)
+	iter := t.mem.NewIterator(nil)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+	}
+	iter.Release()
+	if err = db.Write(batch, nil); err != nil {
+		db.Close()
+		os.RemoveAll(dir)
+		return errors.Trace(err)
+	}
+	log.Infof("[tmp-table] table %s spills %d bytes to %s", t.Name, t.memSize(), dir)
+	t.mem.Reset()
+	t.disk, t.dir = db, dir
+	if err = t.spillUndo(); err != nil {
+		t.closeDisk()
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// Spilled returns whether the rows are spilled to the disk.
+func (t *TemporaryTable) Spilled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.disk != nil
+}
+
+// closeDisk closes the leveldb and removes its directory.
+func (t *TemporaryTable) closeDisk() error {
+	if t.disk == nil {
+		return nil
+	}
+	err := t.disk.Close()
+	if err1 := os.RemoveAll(t.dir); err == nil {
+		err = err1
+	}
+	t.disk, t.dir = nil, ""
+	return errors.Trace(err)
+}
+
+// EnableTxn makes the changes kept in the undo log, so they can be undone by RollbackTo. It's called when the
+// table is created by CREATE TEMPORARY TABLE, the tables used in a statement don't need it.
+func (t *TemporaryTable) EnableTxn() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.txnEnabled = true
+}
+
+// Checkpoint returns the position of the undo log, the changes made after it can be undone by RollbackTo.
+func (t *TemporaryTable) Checkpoint() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.undoLen
+}
+
+// RollbackTo undoes the changes made after the checkpoint in the reverse order, all the changes in the undo log
+// are undone if cp is 0.
+func (t *TemporaryTable) RollbackTo(cp int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.undoLen > cp {
+		r, err := t.lastUndo()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if r.value == nil {
+			err = t.remove(r.key)
+		} else {
+			err = t.set(r.key, r.value)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = t.popUndo(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// Commit keeps the changes of the transaction, the undo log is cleared.
+func (t *TemporaryTable) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return errors.Trace(t.clearUndo())
+}
+
+// Truncate drops all data in the temporary table, it can't be undone.
+func (t *TemporaryTable) Truncate() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mem.Reset()
+	t.undo, t.undoSize = nil, 0
+	t.undoLen, t.undoOnDisk = 0, 0
+	return errors.Trace(t.closeDisk())
+}
+
+// Close releases the memory and the disk used by the temporary table.
+func (t *TemporaryTable) Close() error {
+	return errors.Trace(t.Truncate())
+}
+
+// uniqueKeys returns the unique index keys of the row, the keys of the indexed values which contain NULL
+// are skipped.
+func (t *TemporaryTable) uniqueKeys(r []types.Datum, h int64) ([]kv.Key, [][]types.Datum, error) {
+	keys := make([]kv.Key, 0, len(t.uniqueIndices))
+	vals := make([][]types.Datum, 0, len(t.uniqueIndices))
+	for _, idx := range t.uniqueIndices {
+		colVals, err := idx.FetchValues(r)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		key, distinct, err := idx.GenIndexKey(colVals, h)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if !distinct {
+			continue
+		}
+		keys = append(keys, key)
+		vals = append(vals, colVals)
+	}
+	return keys, vals, nil
+}
+
+// checkUniqueKeys returns the unique index keys of the row. If a key belongs to another row, it returns the
+// handle of that row and kv.ErrKeyExists.
+func (t *TemporaryTable) checkUniqueKeys(r []types.Datum, h int64) ([]kv.Key, int64, error) {
+	keys, vals, err := t.uniqueKeys(r, h)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	for i, key := range keys {
+		value, err := t.get(key)
+		if terror.ErrorEqual(err, kv.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		_, dupHandle, err := codec.DecodeInt(value)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		if dupHandle == h {
+			continue
+		}
+		entryKey, err := genIndexKeyStr(vals[i])
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		return nil, dupHandle, kv.ErrKeyExists.FastGen("Duplicate entry '%s' for key '%s'", entryKey, t.indexName(key))
+	}
+	return keys, 0, nil
+}
+
+func (t *TemporaryTable) indexName(key kv.Key) string {
+	for _, idx := range t.uniqueIndices {
+		prefix := tablecodec.EncodeTableIndexPrefix(t.ID, idx.Meta().ID)
+		if key.HasPrefix(prefix) {
+			return idx.Meta().Name.O
+		}
+	}
+	return ""
+}
+
+func (t *TemporaryTable) putRow(h int64, r []types.Datum, keys []kv.Key) error {
+	value, err := tablecodec.EncodeRow(r, t.colIDs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	handle := codec.EncodeInt(nil, h)
+	for _, key := range keys {
+		if err = t.put(key, handle); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(t.put(t.RecordKey(h), value))
+}
+
+// Seek implements table.Table Seek interface.
+func (t *TemporaryTable) Seek(ctx context.Context, handle int64) (int64, bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	iter := t.newIterator(t.RecordKey(handle), t.recordPrefix.PrefixNext())
+	defer iter.Release()
+	if !iter.First() {
+		return 0, false, errors.Trace(iter.Error())
+	}
+	_, h, err := tablecodec.DecodeRecordKey(iter.Key())
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	return h, true, nil
+}
+
+// Indices implements table.Table Indices interface.
+func (t *TemporaryTable) Indices() []table.Index {
+	return nil
+}
+
+// Meta implements table.Table Meta interface.
+func (t *TemporaryTable) Meta() *model.TableInfo {
+	return t.meta
+}
+
+// Cols implements table.Table Cols interface.
+func (t *TemporaryTable) Cols() []*table.Column {
+	return t.Columns
+}
+
+// WritableCols implements table.Table WritableCols interface.
+func (t *TemporaryTable) WritableCols() []*table.Column {
+	return t.Columns
+}
+
+// RecordPrefix implements table.Table RecordPrefix interface.
+func (t *TemporaryTable) RecordPrefix() kv.Key {
+	return t.recordPrefix
+}
+
+// IndexPrefix implements table.Table IndexPrefix interface.
+func (t *TemporaryTable) IndexPrefix() kv.Key {
+	return nil
+}
+
+// RecordKey implements table.Table RecordKey interface.
+func (t *TemporaryTable) RecordKey(h int64) kv.Key {
+	return tablecodec.EncodeRecordKey(t.recordPrefix, h)
+}
+
+// FirstKey implements table.Table FirstKey interface.
+func (t *TemporaryTable) FirstKey() kv.Key {
+	return t.RecordKey(0)
+}
+
+// UpdateRecord implements table.Table UpdateRecord interface.
+func (t *TemporaryTable) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum, newData []types.Datum, touched map[int]bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.get(t.RecordKey(h)); err != nil {
+		if terror.ErrorEqual(err, kv.ErrNotExist) {
+			return table.ErrRowNotFound
+		}
+		return errors.Trace(err)
+	}
+	tz := ctx.GetSessionVars().StmtCtx.TimeZone
+	newData, err := tablecodec.ConvertTimeZone(newData, tz, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	oldData, err = tablecodec.ConvertTimeZone(oldData, tz, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newKeys, _, err := t.checkUniqueKeys(newData, h)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	oldKeys, _, err := t.uniqueKeys(oldData, h)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, key := range oldKeys {
+		if err = t.delete(key); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(t.putRow(h, newData, newKeys))
+}
+
+// AddRecord implements table.Table AddRecord interface.
+func (t *TemporaryTable) AddRecord(ctx context.Context, r []types.Datum) (recordID int64, err error) {
+	if t.pkHandleCol != nil {
+		recordID, err = r[t.pkHandleCol.Offset].ToInt64(ctx.GetSessionVars().StmtCtx)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	} else {
+		recordID, err = t.alloc.Alloc(t.ID)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	// The TIMESTAMP values are encoded in the system time zone as the values of the normal tables.
+	r, err = tablecodec.ConvertTimeZone(r, ctx.GetSessionVars().StmtCtx.TimeZone, nil)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pkHandleCol != nil {
+		_, err = t.get(t.RecordKey(recordID))
+		if err == nil {
+			return recordID, kv.ErrKeyExists.FastGen("Duplicate entry '%d' for key 'PRIMARY'", recordID)
+		} else if !terror.ErrorEqual(err, kv.ErrNotExist) {
+			return 0, errors.Trace(err)
+		}
+	}
+	keys, dupHandle, err := t.checkUniqueKeys(r, recordID)
+	if err != nil {
+		return dupHandle, errors.Trace(err)
+	}
+	if err = t.putRow(recordID, r, keys); err != nil {
+		return 0, errors.Trace(err)
+	}
+	ctx.GetSessionVars().StmtCtx.AddAffectedRows(1)
+	return recordID, nil
+}
+
+// RowWithCols implements table.Table RowWithCols interface.
+func (t *TemporaryTable) RowWithCols(ctx context.Context, h int64, cols []*table.Column) ([]types.Datum, error) {
+	t.mu.RLock()
+	value, err := t.get(t.RecordKey(h))
+	t.mu.RUnlock()
+	if terror.ErrorEqual(err, kv.ErrNotExist) {
+		return nil, table.ErrRowNotFound
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return t.decodeRow(ctx, value, cols)
+}
+
+func (t *TemporaryTable) decodeRow(ctx context.Context, value []byte, cols []*table.Column) ([]types.Datum, error) {
+	row, err := tablecodec.DecodeRow(value, t.colTps)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = convertDecodedTimeZone(ctx, row); err != nil {
+		return nil, errors.Trace(err)
+	}
+	v := make([]types.Datum, len(cols))
+	for i, col := range cols {
+		if col == nil {
+			continue
+		}
+		v[i] = row[col.ID]
+	}
+	return v, nil
+}
+
+// Row implements table.Table Row interface.
+func (t *TemporaryTable) Row(ctx context.Context, h int64) ([]types.Datum, error) {
+	r, err := t.RowWithCols(ctx, h, t.Cols())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return r, nil
+}
+
+// RemoveRecord implements table.Table RemoveRecord interface.
+func (t *TemporaryTable) RemoveRecord(ctx context.Context, h int64, r []types.Datum) error {
+	r, err := tablecodec.ConvertTimeZone(r, ctx.GetSessionVars().StmtCtx.TimeZone, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys, _, err := t.uniqueKeys(r, h)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, key := range keys {
+		if err = t.delete(key); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(t.delete(t.RecordKey(h)))
+}
+
+// AllocAutoID implements table.Table AllocAutoID interface.
+func (t *TemporaryTable) AllocAutoID() (int64, error) {
+	return t.alloc.Alloc(t.ID)
+}
+
+// Allocator implements table.Table Allocator interface.
+func (t *TemporaryTable) Allocator() autoid.Allocator {
+	return t.alloc
+}
+
+// RebaseAutoID implements table.Table RebaseAutoID interface.
+func (t *TemporaryTable) RebaseAutoID(newBase int64, isSetStep bool) error {
+	return t.alloc.Rebase(t.ID, newBase, isSetStep)
+}
+
+// IterRecords implements table.Table IterRecords interface.
+func (t *TemporaryTable) IterRecords(ctx context.Context, startKey kv.Key, cols []*table.Column,
+	fn table.RecordIterFunc) error {
+	if startKey == nil {
+		startKey = t.recordPrefix
+	}
+	// The rows are read from a snapshot of the leveldb or a copy of the rows in memory, so fn can modify the table.
+	t.mu.RLock()
+	if t.disk == nil {
+		handles, values, err := t.copyRecords(startKey)
+		t.mu.RUnlock()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for i, h := range handles {
+			more, err := t.iterRecord(ctx, h, values[i], cols, fn)
+			if !more || err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	}
+	snap, err := t.disk.GetSnapshot()
+	t.mu.RUnlock()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer snap.Release()
+	iter := snap.NewIterator(&util.Range{Start: startKey, Limit: t.recordPrefix.PrefixNext()}, nil)
+	defer iter.Release()
+	for iter.Next() {
+		_, h, err := tablecodec.DecodeRecordKey(iter.Key())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		more, err := t.iterRecord(ctx, h, iter.Value(), cols, fn)
+		if !more || err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(iter.Error())
+}
+
+// copyRecords copies the rows in memory from startKey, they are no more than the max memory size.
+func (t *TemporaryTable) copyRecords(startKey kv.Key) ([]int64, [][]byte, error) {
+	var (
+		handles []int64
+		values  [][]byte
+	)
+	iter := t.mem.NewIterator(&util.Range{Start: startKey, Limit: t.recordPrefix.PrefixNext()})
+	defer iter.Release()
+	for iter.Next() {
+		_, h, err := tablecodec.DecodeRecordKey(iter.Key())
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		handles = append(handles, h)
+		values = append(values, append([]byte(nil), iter.Value()...))
+	}
+	return handles, values, errors.Trace(iter.Error())
+}
+
+func (t *TemporaryTable) iterRecord(ctx context.Context, h int64, value []byte, cols []*table.Column,
+	fn table.RecordIterFunc) (bool, error) {
+	data, err := t.decodeRow(ctx, value, cols)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return fn(h, data, cols)
+}
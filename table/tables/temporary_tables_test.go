@@ -0,0 +1,236 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tables_test
+
+import (
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta/autoid"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/store/localstore"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var _ = Suite(&testTemporaryTableSuite{})
+
+type testTemporaryTableSuite struct {
+	store   kv.Storage
+	se      tidb.Session
+	tblInfo *model.TableInfo
+}
+
+func (ts *testTemporaryTableSuite) SetUpSuite(c *C) {
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Check(err, IsNil)
+	ts.store = store
+	ts.se, err = tidb.CreateSession(ts.store)
+	c.Assert(err, IsNil)
+
+	// create table t (a int primary key, b varchar(255), unique key b (b))
+	tp1 := types.NewFieldType(mysql.TypeLong)
+	tp1.Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	col1 := &model.ColumnInfo{
+		ID:        1,
+		Name:      model.NewCIStr("a"),
+		Offset:    0,
+		FieldType: *tp1,
+		State:     model.StatePublic,
+	}
+	tp2 := types.NewFieldType(mysql.TypeVarchar)
+	tp2.Flen = 255
+	col2 := &model.ColumnInfo{
+		ID:        2,
+		Name:      model.NewCIStr("b"),
+		Offset:    1,
+		FieldType: *tp2,
+		State:     model.StatePublic,
+	}
+	idx := &model.IndexInfo{
+		ID:      1,
+		Name:    model.NewCIStr("b"),
+		Columns: []*model.IndexColumn{{Name: col2.Name, Offset: 1, Length: types.UnspecifiedLength}},
+		Unique:  true,
+		State:   model.StatePublic,
+	}
+	ts.tblInfo = &model.TableInfo{
+		ID:         101,
+		Name:       model.NewCIStr("t"),
+		Columns:    []*model.ColumnInfo{col1, col2},
+		Indices:    []*model.IndexInfo{idx},
+		PKIsHandle: true,
+		State:      model.StatePublic,
+		Temporary:  true,
+	}
+}
+
+func (ts *testTemporaryTableSuite) TestTemporaryBasic(c *C) {
+	ctx := ts.se.(context.Context)
+	tb, err := tables.TemporaryTableFromMeta(autoid.NewLocalAllocator(), ts.tblInfo, 1<<20)
+	c.Assert(err, IsNil)
+	defer tb.Close()
+	c.Assert(tb.Meta().Temporary, IsTrue)
+	c.Assert(tb.Indices(), IsNil)
+
+	h, err := tb.AddRecord(ctx, types.MakeDatums(1, "abc"))
+	c.Assert(err, IsNil)
+	c.Assert(h, Equals, int64(1))
+	_, err = tb.AddRecord(ctx, types.MakeDatums(3, nil))
+	c.Assert(err, IsNil)
+	// The NULL values are not duplicated.
+	_, err = tb.AddRecord(ctx, types.MakeDatums(5, nil))
+	c.Assert(err, IsNil)
+
+	// Duplicate primary key.
+	h, err = tb.AddRecord(ctx, types.MakeDatums(1, "xyz"))
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue)
+	c.Assert(h, Equals, int64(1))
+	// Duplicate unique key returns the handle of the existing row.
+	h, err = tb.AddRecord(ctx, types.MakeDatums(2, "abc"))
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue)
+	c.Assert(err.Error(), Equals, "[kv:1062]Duplicate entry 'abc' for key 'b'")
+	c.Assert(h, Equals, int64(1))
+	_, err = tb.Row(ctx, 2)
+	c.Assert(terror.ErrorEqual(err, table.ErrRowNotFound), IsTrue)
+
+	// Update to a duplicated key fails and keeps the row.
+	err = tb.UpdateRecord(ctx, 3, types.MakeDatums(3, nil), types.MakeDatums(3, "abc"), nil)
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue)
+	err = tb.UpdateRecord(ctx, 1, types.MakeDatums(1, "abc"), types.MakeDatums(1, "abd"), nil)
+	c.Assert(err, IsNil)
+	err = tb.UpdateRecord(ctx, 3, types.MakeDatums(3, nil), types.MakeDatums(3, "abc"), nil)
+	c.Assert(err, IsNil)
+	row, err := tb.RowWithCols(ctx, 3, []*table.Column{tb.Cols()[1]})
+	c.Assert(err, IsNil)
+	c.Assert(row[0].GetString(), Equals, "abc")
+
+	h, found, err := tb.Seek(ctx, 2)
+	c.Assert(err, IsNil)
+	c.Assert(found, IsTrue)
+	c.Assert(h, Equals, int64(3))
+	_, found, err = tb.Seek(ctx, 6)
+	c.Assert(err, IsNil)
+	c.Assert(found, IsFalse)
+
+	c.Assert(tb.RemoveRecord(ctx, 1, types.MakeDatums(1, "abd")), IsNil)
+	_, err = tb.AddRecord(ctx, types.MakeDatums(2, "abd"))
+	c.Assert(err, IsNil)
+
+	var handles []int64
+	err = tb.IterRecords(ctx, nil, tb.Cols(), func(h int64, data []types.Datum, cols []*table.Column) (bool, error) {
+		handles = append(handles, h)
+		return true, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(handles, DeepEquals, []int64{2, 3, 5})
+
+	c.Assert(tb.Truncate(), IsNil)
+	_, found, err = tb.Seek(ctx, 0)
+	c.Assert(err, IsNil)
+	c.Assert(found, IsFalse)
+}
+
+func (ts *testTemporaryTableSuite) TestTemporarySpill(c *C) {
+	ctx := ts.se.(context.Context)
+	tb, err := tables.TemporaryTableFromMeta(autoid.NewLocalAllocator(), ts.tblInfo, 1024)
+	c.Assert(err, IsNil)
+	defer tb.Close()
+
+	for i := 0; i < 100; i++ {
+		_, err = tb.AddRecord(ctx, types.MakeDatums(i, fmt.Sprintf("value%d", i)))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tb.Spilled(), IsTrue)
+
+	// The rows and the unique keys written before spilling are still there.
+	_, err = tb.AddRecord(ctx, types.MakeDatums(100, "value0"))
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue)
+	row, err := tb.Row(ctx, 0)
+	c.Assert(err, IsNil)
+	c.Assert(row[1].GetString(), Equals, "value0")
+	c.Assert(tb.RemoveRecord(ctx, 0, row), IsNil)
+	_, err = tb.AddRecord(ctx, types.MakeDatums(100, "value0"))
+	c.Assert(err, IsNil)
+
+	count := 0
+	err = tb.IterRecords(ctx, nil, tb.Cols(), func(h int64, data []types.Datum, cols []*table.Column) (bool, error) {
+		c.Assert(data[0].GetInt64(), Equals, h)
+		count++
+		return true, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 100)
+
+	c.Assert(tb.Truncate(), IsNil)
+	c.Assert(tb.Spilled(), IsFalse)
+}
+
+func (ts *testTemporaryTableSuite) TestTemporaryUndoSpill(c *C) {
+	ctx := ts.se.(context.Context)
+	tb, err := tables.TemporaryTableFromMeta(autoid.NewLocalAllocator(), ts.tblInfo, 1024)
+	c.Assert(err, IsNil)
+	defer tb.Close()
+	tb.EnableTxn()
+
+	_, err = tb.AddRecord(ctx, types.MakeDatums(0, "value0"))
+	c.Assert(err, IsNil)
+	c.Assert(tb.Commit(), IsNil)
+	cp := tb.Checkpoint()
+	// The undo log counts towards the max memory size, the rows don't grow but the table is spilled.
+	for i := 0; i < 100; i++ {
+		err = tb.UpdateRecord(ctx, 0, types.MakeDatums(0, fmt.Sprintf("value%d", i)), types.MakeDatums(0, fmt.Sprintf("value%d", i+1)), nil)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tb.Spilled(), IsTrue)
+	for i := 1; i < 100; i++ {
+		_, err = tb.AddRecord(ctx, types.MakeDatums(i, fmt.Sprintf("new%d", i)))
+		c.Assert(err, IsNil)
+	}
+
+	// The rows are read from a snapshot, the rows added by fn are not visited.
+	count := 0
+	err = tb.IterRecords(ctx, nil, tb.Cols(), func(h int64, data []types.Datum, cols []*table.Column) (bool, error) {
+		count++
+		_, err1 := tb.AddRecord(ctx, types.MakeDatums(h+1000, fmt.Sprintf("more%d", h)))
+		return true, err1
+	})
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 100)
+
+	c.Assert(tb.RollbackTo(cp), IsNil)
+	c.Assert(tb.Checkpoint(), Equals, cp)
+	row, err := tb.Row(ctx, 0)
+	c.Assert(err, IsNil)
+	c.Assert(row[1].GetString(), Equals, "value0")
+	count = 0
+	err = tb.IterRecords(ctx, nil, tb.Cols(), func(h int64, data []types.Datum, cols []*table.Column) (bool, error) {
+		count++
+		return true, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 1)
+	_, err = tb.AddRecord(ctx, types.MakeDatums(1, "value0"))
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue)
+	c.Assert(tb.Commit(), IsNil)
+	c.Assert(tb.Checkpoint(), Equals, 0)
+}
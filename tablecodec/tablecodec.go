@@ -211,6 +211,44 @@ func flatten(data types.Datum) (types.Datum, error) {
 	}
 }
 
+// ConvertTimeZone converts the TIMESTAMP values in the datums from one time zone to another, nil means the
+// system time zone. The TIMESTAMP values are encoded and decoded in the system time zone, so the values in the
+// session time zone are converted before they are encoded, and converted back after they are decoded.
+// The datums are copied when any value is converted, so the caller's slice is never modified.
+func ConvertTimeZone(datums []types.Datum, from, to *time.Location) ([]types.Datum, error) {
+	if from == nil {
+		from = time.Local
+	}
+	if to == nil {
+		to = time.Local
+	}
+	if from == to {
+		return datums, nil
+	}
+	var converted []types.Datum
+	for i, d := range datums {
+		if d.Kind() != types.KindMysqlTime {
+			continue
+		}
+		t := d.GetMysqlTime()
+		if t.Type != mysql.TypeTimestamp || t.IsZero() {
+			continue
+		}
+		if err := t.ConvertTimeZone(from, to); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if converted == nil {
+			converted = make([]types.Datum, len(datums))
+			copy(converted, datums)
+		}
+		converted[i].SetMysqlTime(t)
+	}
+	if converted == nil {
+		return datums, nil
+	}
+	return converted, nil
+}
+
 // DecodeValues decodes a byte slice into datums with column types.
 func DecodeValues(data []byte, fts []*types.FieldType, inIndex bool) ([]types.Datum, error) {
 	if len(data) == 0 {
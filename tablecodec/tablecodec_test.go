@@ -16,6 +16,7 @@ package tablecodec
 import (
 	"math"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/mysql"
@@ -178,6 +179,32 @@ func (s *testTableCodecSuite) TestTimeCodec(c *C) {
 	}
 }
 
+func (s *testTableCodecSuite) TestConvertTimeZone(c *C) {
+	defer testleak.AfterTest(c)()
+	loc := time.FixedZone("UTC", 8*3600)
+	ts, err := types.ParseTimestamp("2016-06-23 11:30:45")
+	c.Assert(err, IsNil)
+	dt, err := types.ParseDatetime("2016-06-23 11:30:45")
+	c.Assert(err, IsNil)
+	row := []types.Datum{types.NewIntDatum(1), types.NewDatum(ts), types.NewDatum(dt)}
+
+	// The same time zone doesn't copy the row.
+	r, err := ConvertTimeZone(row, nil, time.Local)
+	c.Assert(err, IsNil)
+	c.Assert(&r[0], Equals, &row[0])
+
+	r, err = ConvertTimeZone(row, time.UTC, loc)
+	c.Assert(err, IsNil)
+	c.Assert(r[1].GetMysqlTime().String(), Equals, "2016-06-23 19:30:45")
+	c.Assert(r[2].GetMysqlTime().String(), Equals, "2016-06-23 11:30:45")
+	// The original row is not modified.
+	c.Assert(row[1].GetMysqlTime().String(), Equals, "2016-06-23 11:30:45")
+
+	r, err = ConvertTimeZone(r, loc, time.UTC)
+	c.Assert(err, IsNil)
+	c.Assert(r[1].GetMysqlTime().String(), Equals, "2016-06-23 11:30:45")
+}
+
 func (s *testTableCodecSuite) TestCutKey(c *C) {
 	colIDs := []int64{1, 2, 3}
 	values := []types.Datum{types.NewIntDatum(1), types.NewBytesDatum([]byte("abc")), types.NewFloat64Datum(5.5)}
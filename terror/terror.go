@@ -71,6 +71,8 @@ const (
 	ClassTable
 	ClassTypes
 	ClassGlobal
+	ClassUtil
+	ClassResourceGroup
 	// Add more as needed.
 )
 
@@ -115,6 +117,10 @@ func (ec ErrClass) String() string {
 		return "types"
 	case ClassGlobal:
 		return "global"
+	case ClassUtil:
+		return "util"
+	case ClassResourceGroup:
+		return "resourcegroup"
 	}
 	return strconv.Itoa(int(ec))
 }
@@ -19,7 +19,9 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
@@ -34,8 +36,10 @@ import (
 	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/server"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/localstore/boltdb"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/util/generallog"
 	"github.com/pingcap/tidb/util/printer"
 	"github.com/pingcap/tipb/go-binlog"
 	"github.com/prometheus/client_golang/prometheus"
@@ -52,19 +56,35 @@ var (
 	port            = flag.String("P", "4000", "tidb server port")
 	statusPort      = flag.String("status", "10080", "tidb server status port")
 	lease           = flag.String("lease", "1s", "schema lease duration, very dangerous to change only if you know what you do")
+	schemaSync      = flag.String("schema-sync-interval", "100ms", "interval to check the schema versions loaded by the servers, set \"0\" to always wait 2 * lease for schema changes.")
 	socket          = flag.String("socket", "", "The socket file to use for connection.")
 	enablePS        = flag.Bool("perfschema", false, "If enable performance schema.")
 	enablePrivilege = flag.Bool("privilege", false, "If enable privilege check feature. This flag will be removed in the future.")
 	reportStatus    = flag.Bool("report-status", true, "If enable status report HTTP service.")
+	statusAdmin     = flag.Bool("status-admin", false, "enable POST /settings, POST /ddl/owner/resign and POST /tls/reload on the status port, they aren't authenticated, so the status port must only be reachable by the administrators.")
 	logFile         = flag.String("log-file", "", "log file path")
-	joinCon         = flag.Int("join-concurrency", 5, "the number of goroutines that participate joining.")
+	generalLogDir   = flag.String("general-log-dir", "", "the directory the general log files set by general_log_file must be in, it's the directory of log-file if it's not set. The general log is written to the server log if neither is set.")
+	joinCon         = flag.Int("join-concurrency", 0, "deprecated, use the tidb_hash_join_concurrency variable instead, it sets the default of the variable if it's positive.")
 	crossJoin       = flag.Bool("cross-join", true, "whether support cartesian product or not.")
 	metricsAddr     = flag.String("metrics-addr", "", "prometheus pushgateway address, leaves it empty will disable prometheus push.")
 	metricsInterval = flag.Int("metrics-interval", 15, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
 	binlogSocket    = flag.String("binlog-socket", "", "socket file to write binlog")
+	binlogFile      = flag.String("binlog-file", "", "file to write binlog if binlog-socket is not set, the prewrite and commit binlog of the transactions are appended to it for a drainer.")
 	runDDL          = flag.Bool("run-ddl", true, "run ddl worker on this tidb-server")
 	retryLimit      = flag.Int("retry-limit", 10, "the maximum number of retries when commit a transaction")
 	skipGrantTable  = flag.Bool("skip-grant-table", false, "This option causes the server to start without using the privilege system at all.")
+	coprCache       = flag.Int64("copr-cache-capacity", 0, "the capacity in bytes of the coprocessor result cache of the tikv store, set \"0\" to disable the cache.")
+	txnLatch        = flag.Int("txn-latch-capacity", 0, "the number of the latch slots which serialize the commits of the local transactions writing the same keys, set \"0\" to disable the latches.")
+	txnSizeLimit    = flag.Int("txn-total-size-limit", kv.TxnTotalSizeLimit, "the maximum size in bytes of the data written by a transaction.")
+	txnCountLimit   = flag.Int("txn-entry-count-limit", kv.TxnEntryCountLimit, "the maximum number of entries written by a transaction.")
+	sslCert         = flag.String("ssl-cert", "", "path of the PEM encoded certificate of the server, TLS is enabled for the client connections if it's set with ssl-key, the certificates can be reloaded by POST /tls/reload on the status port if status-admin is set.")
+	sslKey          = flag.String("ssl-key", "", "path of the PEM encoded private key of the server.")
+	sslCA           = flag.String("ssl-ca", "", "path of the PEM encoded CA certificates to verify the client certificates.")
+	sslVerifyClient = flag.Bool("ssl-verify-client", false, "whether the TLS clients must present a certificate signed by ssl-ca.")
+	drainTimeout    = flag.String("drain-timeout", "30s", "the time to wait for the in-flight transactions to finish after a SIGTERM, the connections left are killed after it.")
+	proxyNetworks   = flag.String("proxy-protocol-networks", "", "the comma separated addresses or CIDRs of the proxies which send the PROXY protocol header, \"*\" means all the addresses, the client addresses in the headers are used for the privileges, the logs and the process list.")
+	pessimisticTxn  = flag.Bool("experimental-pessimistic-txn", false, "enable the experimental pessimistic transaction mode, the keys are locked in the memory of this tidb-server, so it's for a single tidb-server deployment only.")
+	labels          = flag.String("labels", "", "the labels of the location of this tidb-server, such as \"zone=z1,host=h1\", the replicas on the tikv stores with the same labels serve the reads if tidb_replica_read is \"closest\".")
 
 	timeJumpBackCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
@@ -91,8 +111,10 @@ func main() {
 		os.Exit(-1)
 	}
 
-	leaseDuration := parseLease()
+	leaseDuration := parseDuration("lease", *lease)
 	tidb.SetSchemaLease(leaseDuration)
+	ddl.SchemaSyncInterval = parseDuration("schema sync interval", *schemaSync)
+	drainDuration := parseDuration("drain timeout", *drainTimeout)
 	ddl.RunWorker = *runDDL
 	tidb.SetCommitRetryLimit(*retryLimit)
 
@@ -102,8 +124,16 @@ func main() {
 		StatusAddr:   fmt.Sprintf(":%s", *statusPort),
 		Socket:       *socket,
 		ReportStatus: *reportStatus,
+		StatusAdmin:  *statusAdmin,
 		Store:        *store,
 		StorePath:    *storePath,
+
+		SSLCert:         *sslCert,
+		SSLKey:          *sslKey,
+		SSLCA:           *sslCA,
+		SSLVerifyClient: *sslVerifyClient,
+
+		ProxyProtocolNetworks: *proxyNetworks,
 	}
 
 	// set log options
@@ -115,11 +145,23 @@ func main() {
 		log.SetRotateByDay()
 		log.SetHighlighting(false)
 	}
+	if len(*generalLogDir) > 0 {
+		generallog.SetDir(*generalLogDir)
+	} else if len(*logFile) > 0 {
+		generallog.SetDir(filepath.Dir(*logFile))
+	}
 
-	if joinCon != nil && *joinCon > 0 {
-		plan.JoinConcurrency = *joinCon
+	if *joinCon > 0 {
+		log.Warn("the join-concurrency flag is deprecated, use the tidb_hash_join_concurrency variable instead.")
+		variable.SetDefaultHashJoinConcurrency(*joinCon)
 	}
 	plan.AllowCartesianProduct = *crossJoin
+	tikv.CoprCacheCapacity = *coprCache
+	tikv.TxnLatchCapacity = *txnLatch
+	variable.EnablePessimisticTxn = *pessimisticTxn
+	tikv.ServerLabels = parseLabels(*labels)
+	kv.TxnTotalSizeLimit = *txnSizeLimit
+	kv.TxnEntryCountLimit = *txnCountLimit
 	// Call this before setting log level to make sure that TiDB info could be printed.
 	printer.PrintTiDBInfo()
 	log.SetLevelByString(cfg.LogLevel)
@@ -133,10 +175,12 @@ func main() {
 	privileges.SkipWithGrant = *skipGrantTable
 	if *binlogSocket != "" {
 		createBinlogClient()
+	} else if *binlogFile != "" {
+		createBinlogFileSink()
 	}
 
 	// Bootstrap a session to load information schema.
-	_, err := tidb.BootstrapSession(store)
+	dom, err := tidb.BootstrapSession(store)
 	if err != nil {
 		log.Fatal(errors.ErrorStack(err))
 	}
@@ -156,11 +200,23 @@ func main() {
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
 
+	exited := make(chan struct{})
 	go func() {
 		sig := <-sc
 		log.Infof("Got signal [%d] to exit.", sig)
-		svr.Close()
-		os.Exit(0)
+		go func() {
+			sig := <-sc
+			log.Warnf("Got signal [%d] again, exit immediately.", sig)
+			os.Exit(1)
+		}()
+		svr.GracefulShutdown(drainDuration)
+		// Stopping the domain releases the DDL ownership so that another server takes it over at once. The auto ID
+		// allocators don't need to be flushed, the IDs cached by this server are just skipped.
+		dom.Close()
+		if err := store.Close(); err != nil {
+			log.Errorf("close store error %v", errors.ErrorStack(err))
+		}
+		close(exited)
 	}()
 
 	prometheus.MustRegister(timeJumpBackCounter)
@@ -170,7 +226,12 @@ func main() {
 
 	pushMetric(*metricsAddr, time.Duration(*metricsInterval)*time.Second)
 
-	log.Error(svr.Run())
+	if err = svr.Run(); err != nil {
+		log.Error(errors.ErrorStack(err))
+		os.Exit(1)
+	}
+	// Run returns nil after the listener is closed by the graceful shutdown.
+	<-exited
 }
 
 func createStore() kv.Storage {
@@ -194,6 +255,15 @@ func createBinlogClient() {
 	log.Infof("created binlog client at %s", *binlogSocket)
 }
 
+func createBinlogFileSink() {
+	sink, err := binloginfo.NewFileSink(*binlogFile)
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	binloginfo.SetSink(sink)
+	log.Infof("created binlog file sink at %s", *binlogFile)
+}
+
 // Prometheus push.
 const zeroDuration = time.Duration(0)
 
@@ -224,18 +294,34 @@ func prometheusPushClient(addr string, interval time.Duration) {
 	}
 }
 
-// parseLease parses lease argument string.
-func parseLease() time.Duration {
-	dur, err := time.ParseDuration(*lease)
+// parseDuration parses duration argument string, the unit is second if it's not specified.
+func parseDuration(name, arg string) time.Duration {
+	dur, err := time.ParseDuration(arg)
 	if err != nil {
-		dur, err = time.ParseDuration(*lease + "s")
+		dur, err = time.ParseDuration(arg + "s")
 	}
 	if err != nil || dur < 0 {
-		log.Fatalf("invalid lease duration %s", *lease)
+		log.Fatalf("invalid %s duration %s", name, arg)
 	}
 	return dur
 }
 
+// parseLabels parses the labels in the form of "k1=v1,k2=v2".
+func parseLabels(arg string) map[string]string {
+	labels := make(map[string]string)
+	if arg == "" {
+		return labels
+	}
+	for _, s := range strings.Split(arg, ",") {
+		pair := strings.SplitN(s, "=", 2)
+		if len(pair) != 2 || strings.TrimSpace(pair[0]) == "" {
+			log.Fatalf("invalid label %s", s)
+		}
+		labels[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+	}
+	return labels
+}
+
 func hasRootPrivilege() bool {
 	return os.Geteuid() == 0
 }
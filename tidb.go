@@ -35,7 +35,10 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/store/localstore/engine"
 	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/execdetails"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -148,6 +151,9 @@ func resetStmtCtx(ctx context.Context, s ast.StmtNode) {
 		// Make sure the sql_mode is strict when checking column default value.
 		sc.IgnoreTruncate = false
 		sc.TruncateAsWarning = false
+		if create, ok := s.(*ast.CreateTableStmt); ok {
+			sc.InCreateTableSelectStmt = create.Select != nil
+		}
 	default:
 		sc.IgnoreTruncate = true
 		if show, ok := s.(*ast.ShowStmt); ok {
@@ -157,6 +163,12 @@ func resetStmtCtx(ctx context.Context, s ast.StmtNode) {
 			}
 		}
 	}
+	sc.MemTracker = memory.NewTracker("query", sessVars.MemQuotaQuery)
+	sc.MemTracker.SetActionOnExceed(sessVars.MemOOMAction)
+	sc.RuntimeStatsColl = execdetails.NewRuntimeStatsColl()
+	sc.BackoffDetails = execdetails.NewBackoffDetails()
+	sc.ReadDetails = execdetails.NewReadDetails()
+	sc.TimeZone = sessVars.TimeZone
 	sessVars.StmtCtx = sc
 }
 
@@ -175,9 +187,25 @@ func runStmt(ctx context.Context, s ast.Statement) (ast.RecordSet, error) {
 	var err error
 	var rs ast.RecordSet
 	se := ctx.(*session)
+	se.prepareStmtCancel()
+	idCnt := se.sessionVars.RetryInfo.AutoIncrementIDCount()
+	tmpTablesCP := se.temporaryTablesCheckpoint()
 	rs, err = s.Exec(ctx)
+	if se.sessionVars.TxnCtx.IsPessimistic {
+		if terror.ErrorEqual(err, kv.ErrWriteConflict) {
+			rs, err = se.restartPessimisticTxn(s, idCnt, err)
+		} else if terror.ErrorEqual(err, kv.ErrDeadlock) {
+			// Like InnoDB, the transaction chosen to break the deadlock is rolled back, so the others can go on.
+			log.Infof("[%d] rollback txn %v for deadlock", se.sessionVars.ConnectionID, se.txn)
+			se.RollbackTxn()
+		}
+	}
 	// All the history should be added here.
 	getHistory(ctx).add(0, s, se.sessionVars.StmtCtx)
+	if err != nil && se.sessionVars.InTxn() {
+		// The changes of the temporary tables made by the failed statement are undone.
+		se.rollbackTemporaryTables(tmpTablesCP)
+	}
 	if !se.sessionVars.InTxn() {
 		if err != nil {
 			log.Info("RollbackTxn for ddl/autocommit error.")
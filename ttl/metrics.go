@@ -0,0 +1,52 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ttl
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// job result state.
+	jobSucc    = "succ"
+	jobFailed  = "failed"
+	jobCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "ttl",
+			Name:      "job_total",
+			Help:      "Counter of TTL jobs.",
+		}, []string{"result"})
+
+	jobHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "tidb",
+			Subsystem: "ttl",
+			Name:      "job_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of TTL jobs.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 20),
+		})
+
+	deletedRowsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "ttl",
+			Name:      "deleted_rows_total",
+			Help:      "Counter of expired rows deleted by TTL jobs.",
+		})
+)
+
+func init() {
+	prometheus.MustRegister(jobCounter)
+	prometheus.MustRegister(jobHistogram)
+	prometheus.MustRegister(deletedRowsCounter)
+}
@@ -0,0 +1,226 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ttl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/util/sqlexec"
+)
+
+// JobCheckInterval is the interval to check whether the TTL jobs of the tables should be started.
+var JobCheckInterval = time.Minute
+
+const timeFormat = "2006-01-02 15:04:05"
+
+// JobManager runs the TTL jobs, which delete the expired rows of the tables with the TTL option.
+// A job of a table is started if the last job of the table was started TTL_JOB_INTERVAL ago, and the current time is in
+// the schedule window. The job deletes the expired rows in batches, every batch is committed in its own transaction.
+// The start time, finish time and deleted rows of the last job are recorded in the mysql.tidb_ttl_table_status table,
+// so a table isn't processed again by the other servers, or by this server after a restart, until the interval passes.
+type JobManager struct {
+	ctx  context.Context
+	exit <-chan struct{}
+}
+
+// NewJobManager creates a JobManager which runs the jobs in the session ctx. The running job stops after the current
+// batch when exit is closed.
+func NewJobManager(ctx context.Context, exit <-chan struct{}) *JobManager {
+	return &JobManager{ctx: ctx, exit: exit}
+}
+
+// RunOnce starts the jobs of the tables which should be processed at the time now, and waits for them to finish.
+func (m *JobManager) RunOnce(is infoschema.InfoSchema, now time.Time) error {
+	enable, err := m.getGlobalVar(variable.TiDBTTLJobEnable)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !strings.EqualFold(enable, "ON") && enable != "1" {
+		return nil
+	}
+	inWindow, err := m.inScheduleWindow(now)
+	if err != nil || !inWindow {
+		return errors.Trace(err)
+	}
+	batchSize, err := m.getDeleteBatchSize()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	lastStartTimes, err := m.loadLastStartTimes()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	for _, schema := range is.AllSchemas() {
+		for _, tbl := range is.SchemaTables(schema.Name) {
+			tblInfo := tbl.Meta()
+			ttlInfo := tblInfo.TTLInfo
+			if ttlInfo == nil || !ttlInfo.Enable || tblInfo.State != model.StatePublic {
+				continue
+			}
+			interval, err := ttlInfo.GetJobInterval()
+			if err != nil {
+				log.Warnf("[ttl] invalid job interval of table %s.%s: %v", schema.Name, tblInfo.Name, err)
+				continue
+			}
+			if lastStartTime, ok := lastStartTimes[tblInfo.ID]; ok && now.Sub(lastStartTime) < interval {
+				continue
+			}
+			if err = m.runJob(schema.Name, tblInfo, now, batchSize); err != nil {
+				log.Errorf("[ttl] job of table %s.%s err %v", schema.Name, tblInfo.Name, errors.ErrorStack(err))
+			}
+			select {
+			case <-m.exit:
+				return nil
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// runJob deletes the rows of the table which expired at the time now.
+func (m *JobManager) runJob(schemaName model.CIStr, tblInfo *model.TableInfo, now time.Time, batchSize int) error {
+	startTime := time.Now()
+	sql := fmt.Sprintf(`INSERT INTO mysql.tidb_ttl_table_status (table_id, last_job_start_time) VALUES (%d, '%s')
+		ON DUPLICATE KEY UPDATE last_job_start_time = '%s'`, tblInfo.ID, now.Format(timeFormat), now.Format(timeFormat))
+	if _, _, err := m.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(m.ctx, sql); err != nil {
+		return errors.Trace(err)
+	}
+
+	ttlInfo := tblInfo.TTLInfo
+	deleteSQL := fmt.Sprintf("DELETE FROM %s.%s WHERE %s < DATE_SUB('%s', INTERVAL %d %s) LIMIT %d",
+		quoteName(schemaName.O), quoteName(tblInfo.Name.O), quoteName(ttlInfo.ColumnName.O), now.Format(timeFormat),
+		ttlInfo.IntervalValue, ttlInfo.IntervalTimeUnit, batchSize)
+	var deleted uint64
+	var err error
+	for {
+		if _, err = m.ctx.(sqlexec.SQLExecutor).Execute(deleteSQL); err != nil {
+			break
+		}
+		affectedRows := m.ctx.GetSessionVars().StmtCtx.AffectedRows()
+		deleted += affectedRows
+		deletedRowsCounter.Add(float64(affectedRows))
+		if affectedRows < uint64(batchSize) || m.isExited() {
+			break
+		}
+	}
+
+	finishTime := now.Add(time.Since(startTime))
+	sql = fmt.Sprintf(`UPDATE mysql.tidb_ttl_table_status SET last_job_finish_time = '%s', last_job_deleted_rows = %d,
+		total_deleted_rows = total_deleted_rows + %d WHERE table_id = %d`,
+		finishTime.Format(timeFormat), deleted, deleted, tblInfo.ID)
+	if _, _, err1 := m.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(m.ctx, sql); err1 != nil && err == nil {
+		err = err1
+	}
+	jobHistogram.Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		jobCounter.WithLabelValues(jobFailed).Inc()
+		return errors.Trace(err)
+	}
+	jobCounter.WithLabelValues(jobSucc).Inc()
+	log.Infof("[ttl] job of table %s.%s deleted %d rows in %v", schemaName, tblInfo.Name, deleted, time.Since(startTime))
+	return nil
+}
+
+func (m *JobManager) isExited() bool {
+	select {
+	case <-m.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+// loadLastStartTimes loads the start time of the last job of every table.
+func (m *JobManager) loadLastStartTimes() (map[int64]time.Time, error) {
+	sql := "SELECT table_id, last_job_start_time FROM mysql.tidb_ttl_table_status"
+	rows, _, err := m.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(m.ctx, sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	startTimes := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		if row.Data[1].IsNull() {
+			continue
+		}
+		startTime, err := row.Data[1].GetMysqlTime().Time.GoTime(time.Local)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		startTimes[row.Data[0].GetInt64()] = startTime
+	}
+	return startTimes, nil
+}
+
+// inScheduleWindow checks whether the time of day of now is in the schedule window.
+func (m *JobManager) inScheduleWindow(now time.Time) (bool, error) {
+	start, err := m.getTimeOfDay(variable.TiDBTTLJobScheduleWindowStartTime)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	end, err := m.getTimeOfDay(variable.TiDBTTLJobScheduleWindowEndTime)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	cur := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if start <= end {
+		return start <= cur && cur <= end, nil
+	}
+	return cur >= start || cur <= end, nil
+}
+
+// getTimeOfDay gets the time of day like '23:59' of the global variable as the duration since midnight.
+func (m *JobManager) getTimeOfDay(name string) (time.Duration, error) {
+	val, err := m.getGlobalVar(name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	t, err := time.Parse("15:04", val)
+	if err != nil {
+		return 0, errors.Errorf("invalid %s '%s'", name, val)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (m *JobManager) getDeleteBatchSize() (int, error) {
+	val, err := m.getGlobalVar(variable.TiDBTTLDeleteBatchSize)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	batchSize, err := strconv.Atoi(val)
+	if err != nil || batchSize <= 0 {
+		return 0, errors.Errorf("invalid %s '%s'", variable.TiDBTTLDeleteBatchSize, val)
+	}
+	return batchSize, nil
+}
+
+func (m *JobManager) getGlobalVar(name string) (string, error) {
+	val, err := varsutil.GetGlobalSystemVar(m.ctx.GetSessionVars(), name)
+	return val, errors.Trace(err)
+}
+
+func quoteName(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ttl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/domain"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/util/testkit"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testTTLSuite{})
+
+type testTTLSuite struct{}
+
+func (s *testTTLSuite) TestRunJob(c *C) {
+	defer testleak.AfterTest(c)()
+	store, do, err := newStoreWithBootstrap()
+	c.Assert(err, IsNil)
+	defer store.Close()
+	defer do.Close()
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, created_at datetime) ttl = created_at + interval 1 day ttl_job_interval = '1h'")
+	tk.MustExec(`insert t values (1, '2017-01-01 00:00:00'), (2, '2017-01-01 11:00:00'), (3, '2017-01-01 12:00:01'),
+		(4, '2017-01-02 00:00:00'), (5, null)`)
+	tk.MustExec("set @@global.tidb_ttl_delete_batch_size = 1")
+
+	m := do.TTLJobManager()
+	now := time.Date(2017, 1, 2, 12, 0, 0, 0, time.Local)
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("3", "4", "5"))
+	tk.MustQuery("select last_job_start_time, last_job_deleted_rows, total_deleted_rows from mysql.tidb_ttl_table_status").
+		Check(testkit.Rows("2017-01-02 12:00:00 2 2"))
+
+	// The job isn't started again until the job interval passes.
+	now = now.Add(30 * time.Minute)
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("3", "4", "5"))
+	now = now.Add(30 * time.Minute)
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("4", "5"))
+	tk.MustQuery("select last_job_deleted_rows, total_deleted_rows from mysql.tidb_ttl_table_status").
+		Check(testkit.Rows("1 3"))
+
+	// The job is started in the schedule window only.
+	now = time.Date(2017, 1, 3, 12, 0, 0, 0, time.Local)
+	tk.MustExec("set @@global.tidb_ttl_job_schedule_window_start_time = '23:00'")
+	tk.MustExec("set @@global.tidb_ttl_job_schedule_window_end_time = '01:00'")
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("4", "5"))
+	now = time.Date(2017, 1, 3, 23, 30, 0, 0, time.Local)
+	tk.MustExec("alter table t ttl_enable = 'off'")
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("4", "5"))
+	tk.MustExec("alter table t ttl_enable = 'on'")
+	tk.MustExec("set @@global.tidb_ttl_job_enable = 0")
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("4", "5"))
+	tk.MustExec("set @@global.tidb_ttl_job_enable = 1")
+	c.Assert(m.RunOnce(do.InfoSchema(), now), IsNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("5"))
+
+	tk.MustExec("set @@global.tidb_ttl_delete_batch_size = 0")
+	c.Assert(m.RunOnce(do.InfoSchema(), now), NotNil)
+}
+
+func newStoreWithBootstrap() (kv.Storage, *domain.Domain, error) {
+	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	do, err := tidb.BootstrapSession(store)
+	return store, do, errors.Trace(err)
+}
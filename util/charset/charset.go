@@ -139,6 +139,17 @@ func GetCharsetInfo(cs string) (string, string, error) {
 	return c.Name, c.DefaultCollation.Name, nil
 }
 
+// GetCollationByName returns the collation by its name.
+func GetCollationByName(name string) (*Collation, error) {
+	name = strings.ToLower(name)
+	for _, c := range collations {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("Unknown collation %s", name)
+}
+
 // GetCollations returns a list for all collations.
 func GetCollations() []*Collation {
 	return collations
@@ -87,3 +87,13 @@ func (s *testCharsetSuite) TestGetDefaultCollation(c *C) {
 		testGetDefaultCollation(c, t.cs, t.co, t.succ)
 	}
 }
+
+func (s *testCharsetSuite) TestGetCollationByName(c *C) {
+	defer testleak.AfterTest(c)()
+	co, err := GetCollationByName("Latin1_Bin")
+	c.Assert(err, IsNil)
+	c.Assert(co.CharsetName, Equals, "latin1")
+	c.Assert(co.Name, Equals, "latin1_bin")
+	_, err = GetCollationByName("invalid_co")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chunk
+
+import (
+	"github.com/pingcap/tidb/util/types"
+)
+
+// Chunk stores a batch of rows in columns, the datums of a column are kept together, so an expression can be evaluated
+// over a column at once instead of a row at a time.
+type Chunk struct {
+	columns [][]types.Datum
+	numRows int
+}
+
+// NewChunk creates a chunk of numCols columns, which can hold capacity rows without growing.
+func NewChunk(numCols, capacity int) *Chunk {
+	c := &Chunk{columns: make([][]types.Datum, numCols)}
+	for i := range c.columns {
+		c.columns[i] = make([]types.Datum, 0, capacity)
+	}
+	return c
+}
+
+// NumCols returns the number of the columns.
+func (c *Chunk) NumCols() int {
+	return len(c.columns)
+}
+
+// NumRows returns the number of the rows.
+func (c *Chunk) NumRows() int {
+	return c.numRows
+}
+
+// Reset removes all the rows, the memory of the columns is reused.
+func (c *Chunk) Reset() {
+	for i := range c.columns {
+		c.columns[i] = c.columns[i][:0]
+	}
+	c.numRows = 0
+}
+
+// Resize sets the number of the rows to n, the datums of the rows added are reset, so they can be filled column by
+// column.
+func (c *Chunk) Resize(n int) {
+	for i, col := range c.columns {
+		if cap(col) < n {
+			col = make([]types.Datum, n)
+		} else {
+			col = col[:n]
+			for j := range col {
+				col[j] = types.Datum{}
+			}
+		}
+		c.columns[i] = col
+	}
+	c.numRows = n
+}
+
+// AppendRow appends a row, the number of the datums must be the number of the columns.
+func (c *Chunk) AppendRow(row []types.Datum) {
+	for i := range c.columns {
+		c.columns[i] = append(c.columns[i], row[i])
+	}
+	c.numRows++
+}
+
+// Column returns the datums of the i-th column, one for each row. They can be modified in place.
+func (c *Chunk) Column(i int) []types.Datum {
+	return c.columns[i]
+}
+
+// GetRow gathers the datums of the i-th row into row, which is reallocated if it's too short, and returns it.
+func (c *Chunk) GetRow(i int, row []types.Datum) []types.Datum {
+	if cap(row) < len(c.columns) {
+		row = make([]types.Datum, len(c.columns))
+	}
+	row = row[:len(c.columns)]
+	for j, col := range c.columns {
+		row[j] = col[i]
+	}
+	return row
+}
+
+// Filter keeps the rows whose selected is true, in their order.
+func (c *Chunk) Filter(selected []bool) {
+	n := 0
+	for i := 0; i < c.numRows; i++ {
+		if !selected[i] {
+			continue
+		}
+		if n != i {
+			for _, col := range c.columns {
+				col[n] = col[i]
+			}
+		}
+		n++
+	}
+	for i, col := range c.columns {
+		c.columns[i] = col[:n]
+	}
+	c.numRows = n
+}
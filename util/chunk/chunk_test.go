@@ -0,0 +1,56 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chunk
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testChunkSuite{})
+
+type testChunkSuite struct{}
+
+func (s *testChunkSuite) TestChunk(c *C) {
+	defer testleak.AfterTest(c)()
+	chk := NewChunk(2, 2)
+	for i := 0; i < 5; i++ {
+		chk.AppendRow(types.MakeDatums(i, "a"))
+	}
+	c.Assert(chk.NumCols(), Equals, 2)
+	c.Assert(chk.NumRows(), Equals, 5)
+	c.Assert(chk.Column(0)[3].GetInt64(), Equals, int64(3))
+	row := chk.GetRow(4, nil)
+	c.Assert(row, DeepEquals, types.MakeDatums(4, "a"))
+
+	// The odd rows are kept in order.
+	chk.Filter([]bool{false, true, false, true, false})
+	c.Assert(chk.NumRows(), Equals, 2)
+	c.Assert(chk.GetRow(0, row)[0].GetInt64(), Equals, int64(1))
+	c.Assert(chk.GetRow(1, row)[0].GetInt64(), Equals, int64(3))
+
+	chk.Resize(3)
+	c.Assert(chk.NumRows(), Equals, 3)
+	c.Assert(chk.Column(1)[1].IsNull(), IsTrue)
+	chk.Reset()
+	c.Assert(chk.NumRows(), Equals, 0)
+	c.Assert(chk.Column(0), HasLen, 0)
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execdetails
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RuntimeStats collects the runtime statistics of an executor.
+// It's safe for concurrent use.
+type RuntimeStats struct {
+	// loops is the number of Next calls.
+	loops int64
+	// consume is the time spent in Next in nanoseconds, including the time spent in the children.
+	consume int64
+	// rows is the number of returned rows.
+	rows int64
+	// concurrency is the number of workers used by the executor, 0 means the executor runs serially.
+	concurrency int64
+}
+
+// Record records a Next call which returns rowNum rows in d.
+func (e *RuntimeStats) Record(d time.Duration, rowNum int) {
+	atomic.AddInt64(&e.loops, 1)
+	atomic.AddInt64(&e.consume, int64(d))
+	atomic.AddInt64(&e.rows, int64(rowNum))
+}
+
+// SetConcurrency sets the number of workers used by the executor.
+func (e *RuntimeStats) SetConcurrency(concurrency int) {
+	atomic.StoreInt64(&e.concurrency, int64(concurrency))
+}
+
+// Loops returns the number of Next calls.
+func (e *RuntimeStats) Loops() int64 {
+	return atomic.LoadInt64(&e.loops)
+}
+
+// Rows returns the number of returned rows.
+func (e *RuntimeStats) Rows() int64 {
+	return atomic.LoadInt64(&e.rows)
+}
+
+// Time returns the time spent in Next.
+func (e *RuntimeStats) Time() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.consume))
+}
+
+// String implements fmt.Stringer interface.
+func (e *RuntimeStats) String() string {
+	s := fmt.Sprintf("time:%v, loops:%d, rows:%d", e.Time(), e.Loops(), e.Rows())
+	if concurrency := atomic.LoadInt64(&e.concurrency); concurrency > 0 {
+		s += fmt.Sprintf(", concurrency:%d", concurrency)
+	}
+	return s
+}
+
+// RuntimeStatsColl collects the runtime statistics of the executors of a statement, by the IDs of their plans.
+type RuntimeStatsColl struct {
+	mu    sync.Mutex
+	ids   []string
+	stats map[string]*RuntimeStats
+}
+
+// NewRuntimeStatsColl creates a RuntimeStatsColl.
+func NewRuntimeStatsColl() *RuntimeStatsColl {
+	return &RuntimeStatsColl{stats: make(map[string]*RuntimeStats)}
+}
+
+// Get returns the RuntimeStats of the plan, it's created if it doesn't exist.
+func (e *RuntimeStatsColl) Get(planID string) *RuntimeStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats, ok := e.stats[planID]
+	if !ok {
+		stats = &RuntimeStats{}
+		e.stats[planID] = stats
+		e.ids = append(e.ids, planID)
+	}
+	return stats
+}
+
+// Exists checks whether the RuntimeStats of the plan exists.
+func (e *RuntimeStatsColl) Exists(planID string) bool {
+	e.mu.Lock()
+	_, ok := e.stats[planID]
+	e.mu.Unlock()
+	return ok
+}
+
+// String implements fmt.Stringer interface, the statistics are listed in the order they are created.
+func (e *RuntimeStatsColl) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	buffer := bytes.NewBufferString("")
+	for i, id := range e.ids {
+		if i > 0 {
+			buffer.WriteString("; ")
+		}
+		fmt.Fprintf(buffer, "%s{%s}", id, e.stats[id])
+	}
+	return buffer.String()
+}
+
+// BackoffDetails collects the backoffs of the kv requests of a statement, by the types of the backoffs.
+// It's safe for concurrent use.
+type BackoffDetails struct {
+	mu     sync.Mutex
+	types  []string
+	times  map[string]int
+	sleeps map[string]time.Duration
+}
+
+type backoffDetailsKeyType struct{}
+
+// BackoffDetailsKey is the goctx key of the BackoffDetails which the backoffs of the kv requests are recorded into.
+var BackoffDetailsKey = backoffDetailsKeyType{}
+
+// NewBackoffDetails creates a BackoffDetails.
+func NewBackoffDetails() *BackoffDetails {
+	return &BackoffDetails{
+		times:  make(map[string]int),
+		sleeps: make(map[string]time.Duration),
+	}
+}
+
+// Record records a backoff of the type typ which sleeps for d.
+func (e *BackoffDetails) Record(typ string, d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.times[typ]; !ok {
+		e.types = append(e.types, typ)
+	}
+	e.times[typ]++
+	e.sleeps[typ] += d
+}
+
+// Times returns the number of the backoffs.
+func (e *BackoffDetails) Times() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	times := 0
+	for _, t := range e.times {
+		times += t
+	}
+	return times
+}
+
+// String implements fmt.Stringer interface, the types are listed in the order they first back off.
+func (e *BackoffDetails) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	buffer := bytes.NewBufferString("")
+	for i, typ := range e.types {
+		if i > 0 {
+			buffer.WriteString("; ")
+		}
+		fmt.Fprintf(buffer, "%s{times:%d, sleep:%v}", typ, e.times[typ], e.sleeps[typ])
+	}
+	return buffer.String()
+}
+
+// ReadDetails collects the kv requests of the reads of a statement, and the keys scanned by them.
+// It's safe for concurrent use.
+type ReadDetails struct {
+	requests    int64
+	scannedKeys int64
+}
+
+type readDetailsKeyType struct{}
+
+// ReadDetailsKey is the goctx key of the ReadDetails which the coprocessor requests are recorded into.
+var ReadDetailsKey = readDetailsKeyType{}
+
+// NewReadDetails creates a ReadDetails.
+func NewReadDetails() *ReadDetails {
+	return &ReadDetails{}
+}
+
+// Record records a kv request which scans scannedKeys keys. For the coprocessor requests, the keys are the rows
+// returned by the coprocessor.
+func (e *ReadDetails) Record(scannedKeys int64) {
+	atomic.AddInt64(&e.requests, 1)
+	atomic.AddInt64(&e.scannedKeys, scannedKeys)
+}
+
+// Requests returns the number of the kv requests.
+func (e *ReadDetails) Requests() int64 {
+	return atomic.LoadInt64(&e.requests)
+}
+
+// ScannedKeys returns the number of the scanned keys.
+func (e *ReadDetails) ScannedKeys() int64 {
+	return atomic.LoadInt64(&e.scannedKeys)
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execdetails
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testExecDetailsSuite{})
+
+type testExecDetailsSuite struct{}
+
+func (s *testExecDetailsSuite) TestRuntimeStatsColl(c *C) {
+	defer testleak.AfterTest(c)()
+	coll := NewRuntimeStatsColl()
+	c.Assert(coll.Exists("TableScan_1"), IsFalse)
+	scan := coll.Get("TableScan_1")
+	c.Assert(coll.Exists("TableScan_1"), IsTrue)
+	c.Assert(coll.Get("TableScan_1"), Equals, scan)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			scan.Record(time.Millisecond, 2)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	c.Assert(scan.Loops(), Equals, int64(10))
+	c.Assert(scan.Rows(), Equals, int64(20))
+	c.Assert(scan.Time(), Equals, 10*time.Millisecond)
+	c.Assert(scan.String(), Equals, "time:10ms, loops:10, rows:20")
+
+	join := coll.Get("HashJoin_2")
+	join.Record(time.Second, 0)
+	join.SetConcurrency(5)
+	c.Assert(coll.String(), Equals,
+		"TableScan_1{time:10ms, loops:10, rows:20}; HashJoin_2{time:1s, loops:1, rows:0, concurrency:5}")
+}
+
+func (s *testExecDetailsSuite) TestBackoffDetails(c *C) {
+	defer testleak.AfterTest(c)()
+	details := NewBackoffDetails()
+	c.Assert(details.Times(), Equals, 0)
+	c.Assert(details.String(), Equals, "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			details.Record("regionMiss", 2*time.Millisecond)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	details.Record("txnLock", time.Second)
+	c.Assert(details.Times(), Equals, 11)
+	c.Assert(details.String(), Equals, "regionMiss{times:10, sleep:20ms}; txnLock{times:1, sleep:1s}")
+}
+
+func (s *testExecDetailsSuite) TestReadDetails(c *C) {
+	defer testleak.AfterTest(c)()
+	details := NewReadDetails()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			details.Record(3)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	details.Record(0)
+	c.Assert(details.Requests(), Equals, int64(11))
+	c.Assert(details.ScannedKeys(), Equals, int64(30))
+}
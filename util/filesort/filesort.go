@@ -426,7 +426,7 @@ func (fs *FileSorter) fetchNextRow(index int) (*comparableRow, error) {
 	}
 	rowSize := int(binary.BigEndian.Uint64(fs.head))
 
-	n, err = fs.fds[index].Read(fs.rowBytes)
+	n, err = io.ReadFull(fs.fds[index], fs.rowBytes[:rowSize])
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -434,7 +434,7 @@ func (fs *FileSorter) fetchNextRow(index int) (*comparableRow, error) {
 		return nil, errors.New("incorrect row")
 	}
 
-	fs.dcod, err = codec.Decode(fs.rowBytes, fs.keySize+fs.valSize+1)
+	fs.dcod, err = codec.Decode(fs.rowBytes[:rowSize], fs.keySize+fs.valSize+1)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -0,0 +1,217 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generallog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// Entry is a statement in the general log.
+type Entry struct {
+	// Time is the time when the statement is received.
+	Time   time.Time
+	ConnID uint64
+	User   string
+	Host   string
+	DB     string
+	Query  string
+}
+
+// String implements fmt.Stringer interface, it's the line of the statement in the general log file.
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s conn_id:%d user:%s@%s db:%s sql:%s", e.Time.Format("2006-01-02 15:04:05.000000"),
+		e.ConnID, e.User, e.Host, e.DB, e.Query)
+}
+
+// Sink is where the general log is written, like a file or an audit service.
+type Sink interface {
+	// Write writes the entry, it's called by the sessions concurrently.
+	Write(e *Entry) error
+	// Close is called when the sink is replaced.
+	Close() error
+}
+
+// enabled is 1 if general_log is on.
+var enabled int32
+
+var sinks = struct {
+	sync.RWMutex
+	// custom is set by SetSink, it takes precedence over the file.
+	custom Sink
+	// file is opened by general_log_file, the entries are written to the server log if it's nil.
+	file *fileSink
+	// dir is the directory the files must be in, so the users can't write a file anywhere on the server. No file
+	// can be opened if it's empty.
+	dir string
+}{}
+
+// Enabled returns whether the statements are written to the general log.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// SetEnabled turns the general log on or off.
+func SetEnabled(on bool) {
+	if on {
+		atomic.StoreInt32(&enabled, 1)
+	} else {
+		atomic.StoreInt32(&enabled, 0)
+	}
+}
+
+// SetSink makes the general log written to s instead of the file, the previous sink is closed. The file is used
+// again if s is nil.
+func SetSink(s Sink) {
+	sinks.Lock()
+	old := sinks.custom
+	sinks.custom = s
+	sinks.Unlock()
+	if old != nil {
+		if err := old.Close(); err != nil {
+			log.Warnf("[GENERAL_LOG] close sink err %v", err)
+		}
+	}
+}
+
+// SetDir sets the directory the files of the general log must be in, it's called before the server starts.
+func SetDir(dir string) {
+	sinks.Lock()
+	defer sinks.Unlock()
+	sinks.dir = dir
+}
+
+// SetFile makes the general log written to the file at path, the previous file is closed. The entries are written
+// to the server log if path is "". The path is relative to the directory set by SetDir, and it must not be out of
+// the directory. The previous file is kept if the file can't be opened.
+func SetFile(path string) error {
+	sinks.Lock()
+	defer sinks.Unlock()
+	if path != "" {
+		var err error
+		path, err = pathInDir(sinks.dir, path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if sinks.file == nil && path == "" || sinks.file != nil && sinks.file.path == path {
+		return nil
+	}
+	var f *fileSink
+	if path != "" {
+		var err error
+		f, err = newFileSink(path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	old := sinks.file
+	sinks.file = f
+	if old != nil {
+		if err := old.Close(); err != nil {
+			log.Warnf("[GENERAL_LOG] close file %s err %v", old.path, err)
+		}
+	}
+	return nil
+}
+
+// pathInDir returns the cleaned path of the file in dir, or an error if the file is out of dir.
+func pathInDir(dir, path string) (string, error) {
+	if dir == "" {
+		return "", variable.ErrWrongValueForVar.GenByArgs(variable.GeneralLogFile, path)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", variable.ErrWrongValueForVar.GenByArgs(variable.GeneralLogFile, path)
+	}
+	return path, nil
+}
+
+// SetVariable applies the global variable general_log or general_log_file to the server, the other variables
+// are ignored.
+func SetVariable(name, val string) error {
+	switch name {
+	case variable.GeneralLog:
+		SetEnabled(strings.EqualFold(val, "ON") || val == "1")
+	case variable.GeneralLogFile:
+		return errors.Trace(SetFile(val))
+	}
+	return nil
+}
+
+// Log writes the entry to the general log, the entry is written to the server log if the sink fails, so it's
+// never lost.
+func Log(e *Entry) {
+	sinks.RLock()
+	defer sinks.RUnlock()
+	var err error
+	if sinks.custom != nil {
+		err = sinks.custom.Write(e)
+	} else if sinks.file != nil {
+		err = sinks.file.Write(e)
+	} else {
+		log.Infof("[GENERAL_LOG] %s", e)
+		return
+	}
+	if err != nil {
+		log.Warnf("[GENERAL_LOG] write err %v, %s", err, e)
+	}
+}
+
+// fileSink appends the entries to a file, an entry a line.
+type fileSink struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &fileSink{path: path, f: f}, nil
+}
+
+// Write implements Sink Write interface.
+func (s *fileSink) Write(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.f.WriteString(e.String() + "\n")
+	return errors.Trace(err)
+}
+
+// Close implements Sink Close interface.
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Trace(s.f.Close())
+}
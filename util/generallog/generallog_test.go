@@ -0,0 +1,164 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generallog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testGeneralLogSuite{})
+
+type testGeneralLogSuite struct{}
+
+type memSink struct {
+	entries []*Entry
+	closed  bool
+}
+
+func (s *memSink) Write(e *Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *testGeneralLogSuite) TestString(c *C) {
+	defer testleak.AfterTest(c)()
+	e := &Entry{
+		Time:   time.Date(2017, 1, 2, 3, 4, 5, 6000, time.Local),
+		ConnID: 3,
+		User:   "root",
+		Host:   "127.0.0.1",
+		DB:     "test",
+		Query:  "select * from t",
+	}
+	c.Assert(e.String(), Equals, "2017-01-02 03:04:05.000006 conn_id:3 user:root@127.0.0.1 db:test sql:select * from t")
+}
+
+func (s *testGeneralLogSuite) TestFile(c *C) {
+	defer testleak.AfterTest(c)()
+	dir, err := ioutil.TempDir("", "general_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	// No file can be opened if the directory isn't set.
+	path := filepath.Join(dir, "general.log")
+	c.Assert(SetFile(path), NotNil)
+	c.Assert(sinks.file, IsNil)
+	SetDir(dir)
+	defer SetDir("")
+
+	c.Assert(SetFile(path), IsNil)
+	Log(&Entry{ConnID: 1, Query: "select 1"})
+	Log(&Entry{ConnID: 2, Query: "select 2"})
+	// The same file is not reopened.
+	f := sinks.file
+	c.Assert(SetFile(path), IsNil)
+	c.Assert(sinks.file, Equals, f)
+	// The relative path is in the directory.
+	c.Assert(SetFile("general.log"), IsNil)
+	c.Assert(sinks.file, Equals, f)
+
+	// The files out of the directory are not allowed.
+	for _, p := range []string{"/tmp/general.log", "../general.log", filepath.Join(dir, "..", "general.log"), dir, "."} {
+		err = SetFile(p)
+		c.Assert(variable.ErrWrongValueForVar.Equal(err), IsTrue, Commentf("path %s, err %v", p, err))
+		c.Assert(sinks.file, Equals, f)
+	}
+	// The previous file is kept if the new one can't be opened.
+	c.Assert(SetFile(filepath.Join(dir, "xxx", "general.log")), NotNil)
+	c.Assert(sinks.file, Equals, f)
+	c.Assert(SetFile(""), IsNil)
+	c.Assert(sinks.file, IsNil)
+	Log(&Entry{ConnID: 3, Query: "select 3"})
+
+	b, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(lines[0], Matches, ".* conn_id:1 .* sql:select 1")
+	c.Assert(lines[1], Matches, ".* conn_id:2 .* sql:select 2")
+}
+
+func (s *testGeneralLogSuite) TestSink(c *C) {
+	defer testleak.AfterTest(c)()
+	dir, err := ioutil.TempDir("", "general_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	SetDir(dir)
+	defer SetDir("")
+	path := filepath.Join(dir, "general.log")
+	c.Assert(SetFile(path), IsNil)
+	defer SetFile("")
+
+	// The custom sink takes precedence over the file.
+	sink1 := &memSink{}
+	SetSink(sink1)
+	Log(&Entry{Query: "select 1"})
+	sink2 := &memSink{}
+	SetSink(sink2)
+	c.Assert(sink1.closed, IsTrue)
+	Log(&Entry{Query: "select 2"})
+	SetSink(nil)
+	c.Assert(sink2.closed, IsTrue)
+	Log(&Entry{Query: "select 3"})
+
+	c.Assert(sink1.entries, HasLen, 1)
+	c.Assert(sink1.entries[0].Query, Equals, "select 1")
+	c.Assert(sink2.entries, HasLen, 1)
+	c.Assert(sink2.entries[0].Query, Equals, "select 2")
+	b, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Matches, ".* sql:select 3\n")
+}
+
+func (s *testGeneralLogSuite) TestSetVariable(c *C) {
+	defer testleak.AfterTest(c)()
+	defer SetEnabled(false)
+	c.Assert(Enabled(), IsFalse)
+	c.Assert(SetVariable(variable.GeneralLog, "ON"), IsNil)
+	c.Assert(Enabled(), IsTrue)
+	c.Assert(SetVariable(variable.GeneralLog, "0"), IsNil)
+	c.Assert(Enabled(), IsFalse)
+	c.Assert(SetVariable(variable.GeneralLog, "1"), IsNil)
+	c.Assert(Enabled(), IsTrue)
+	// The other variables are ignored.
+	c.Assert(SetVariable(variable.AutocommitVar, "OFF"), IsNil)
+	c.Assert(Enabled(), IsTrue)
+
+	dir, err := ioutil.TempDir("", "general_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	SetDir(dir)
+	defer SetDir("")
+	c.Assert(SetVariable(variable.GeneralLogFile, filepath.Join(dir, "general.log")), IsNil)
+	c.Assert(sinks.file, NotNil)
+	c.Assert(SetVariable(variable.GeneralLogFile, ""), IsNil)
+	c.Assert(sinks.file, IsNil)
+}
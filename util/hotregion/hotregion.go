@@ -0,0 +1,216 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hotregion
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// decayInterval is the interval to halve the counts, so the hottest regions and keys are the ones accessed recently.
+var decayInterval = time.Minute
+
+// maxKeys is the max number of the keys recorded, the new keys are ignored when there are too many keys, until the
+// cold keys are dropped by the decay.
+var maxKeys = 10000
+
+// Store is implemented by the storages which record the hot regions.
+type Store interface {
+	// HotRegions returns the recorder of the region and key accesses of the store.
+	HotRegions() *Recorder
+}
+
+// RegionStat is the access statistics of a region.
+type RegionStat struct {
+	RegionID uint64
+	// Key is the first key accessed in the region, it shows the data of the region.
+	Key []byte
+	// ReadCount is the number of the read requests.
+	ReadCount uint64
+	// WriteCount is the number of the written keys.
+	WriteCount uint64
+}
+
+// KeyStat is the access statistics of a key.
+type KeyStat struct {
+	Key      []byte
+	RegionID uint64
+	// ReadCount is the number of the point reads.
+	ReadCount uint64
+	// WriteCount is the number of the writes.
+	WriteCount uint64
+}
+
+// Recorder records the accesses of the regions and keys, to find the hottest ones. The counts are halved every
+// decayInterval. It's safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	regions   map[uint64]*RegionStat
+	keys      map[string]*KeyStat
+	lastDecay time.Time
+}
+
+// NewRecorder creates a Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		regions:   make(map[uint64]*RegionStat),
+		keys:      make(map[string]*KeyStat),
+		lastDecay: time.Now(),
+	}
+}
+
+// RecordRead records a read request of the region. startKey is the first key of the request, keys are the keys read
+// by the point reads, they are nil for the range reads.
+func (r *Recorder) RecordRead(regionID uint64, startKey []byte, keys [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maybeDecay()
+	r.region(regionID, startKey).ReadCount++
+	for _, key := range keys {
+		if ks := r.key(regionID, key); ks != nil {
+			ks.ReadCount++
+		}
+	}
+}
+
+// RecordWrite records a write request of the region which writes the keys.
+func (r *Recorder) RecordWrite(regionID uint64, keys [][]byte) {
+	if len(keys) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maybeDecay()
+	r.region(regionID, keys[0]).WriteCount += uint64(len(keys))
+	for _, key := range keys {
+		if ks := r.key(regionID, key); ks != nil {
+			ks.WriteCount++
+		}
+	}
+}
+
+func (r *Recorder) region(regionID uint64, key []byte) *RegionStat {
+	rs, ok := r.regions[regionID]
+	if !ok {
+		rs = &RegionStat{RegionID: regionID, Key: append([]byte(nil), key...)}
+		r.regions[regionID] = rs
+	}
+	return rs
+}
+
+// key returns nil if there are too many keys.
+func (r *Recorder) key(regionID uint64, key []byte) *KeyStat {
+	ks, ok := r.keys[string(key)]
+	if !ok {
+		if len(r.keys) >= maxKeys {
+			return nil
+		}
+		ks = &KeyStat{Key: append([]byte(nil), key...)}
+		r.keys[string(key)] = ks
+	}
+	// The key may be moved to another region by a split.
+	ks.RegionID = regionID
+	return ks
+}
+
+func (r *Recorder) maybeDecay() {
+	now := time.Now()
+	if now.Sub(r.lastDecay) < decayInterval {
+		return
+	}
+	r.lastDecay = now
+	for id, rs := range r.regions {
+		rs.ReadCount /= 2
+		rs.WriteCount /= 2
+		if rs.ReadCount+rs.WriteCount == 0 {
+			delete(r.regions, id)
+		}
+	}
+	for k, ks := range r.keys {
+		ks.ReadCount /= 2
+		ks.WriteCount /= 2
+		if ks.ReadCount+ks.WriteCount == 0 {
+			delete(r.keys, k)
+		}
+	}
+}
+
+// HotRegions returns the hottest regions, which are ordered by the number of the reads and writes.
+func (r *Recorder) HotRegions(limit int) []RegionStat {
+	r.mu.Lock()
+	regions := make([]RegionStat, 0, len(r.regions))
+	for _, rs := range r.regions {
+		regions = append(regions, *rs)
+	}
+	r.mu.Unlock()
+	sort.Sort(regionStatSorter(regions))
+	if len(regions) > limit {
+		regions = regions[:limit]
+	}
+	return regions
+}
+
+// HotKeys returns the hottest keys, which are ordered by the number of the reads and writes.
+func (r *Recorder) HotKeys(limit int) []KeyStat {
+	r.mu.Lock()
+	keys := make([]KeyStat, 0, len(r.keys))
+	for _, ks := range r.keys {
+		keys = append(keys, *ks)
+	}
+	r.mu.Unlock()
+	sort.Sort(keyStatSorter(keys))
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+// regionStatSorter sorts the regions from the hottest to the coldest.
+type regionStatSorter []RegionStat
+
+func (s regionStatSorter) Len() int {
+	return len(s)
+}
+
+func (s regionStatSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s regionStatSorter) Less(i, j int) bool {
+	ci, cj := s[i].ReadCount+s[i].WriteCount, s[j].ReadCount+s[j].WriteCount
+	if ci != cj {
+		return ci > cj
+	}
+	return s[i].RegionID < s[j].RegionID
+}
+
+// keyStatSorter sorts the keys from the hottest to the coldest.
+type keyStatSorter []KeyStat
+
+func (s keyStatSorter) Len() int {
+	return len(s)
+}
+
+func (s keyStatSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s keyStatSorter) Less(i, j int) bool {
+	ci, cj := s[i].ReadCount+s[i].WriteCount, s[j].ReadCount+s[j].WriteCount
+	if ci != cj {
+		return ci > cj
+	}
+	return string(s[i].Key) < string(s[j].Key)
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hotregion
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHotRegionSuite{})
+
+type testHotRegionSuite struct{}
+
+func (s *testHotRegionSuite) TestRecorder(c *C) {
+	defer testleak.AfterTest(c)()
+	r := NewRecorder()
+	k1, k2, k3 := []byte("k1"), []byte("k2"), []byte("k3")
+	r.RecordRead(1, k1, [][]byte{k1})
+	r.RecordRead(1, k1, [][]byte{k1, k2})
+	r.RecordRead(2, k3, nil)
+	r.RecordWrite(2, [][]byte{k3, k3, k3})
+	r.RecordWrite(3, nil)
+
+	regions := r.HotRegions(10)
+	c.Assert(regions, HasLen, 2)
+	c.Assert(regions[0], DeepEquals, RegionStat{RegionID: 2, Key: k3, ReadCount: 1, WriteCount: 3})
+	c.Assert(regions[1], DeepEquals, RegionStat{RegionID: 1, Key: k1, ReadCount: 2})
+	c.Assert(r.HotRegions(1), HasLen, 1)
+
+	keys := r.HotKeys(10)
+	c.Assert(keys, HasLen, 3)
+	c.Assert(keys[0], DeepEquals, KeyStat{Key: k3, RegionID: 2, WriteCount: 3})
+	c.Assert(keys[1], DeepEquals, KeyStat{Key: k1, RegionID: 1, ReadCount: 2})
+	c.Assert(keys[2], DeepEquals, KeyStat{Key: k2, RegionID: 1, ReadCount: 1})
+
+	// The new keys are ignored when there are too many keys.
+	defer func(n int) { maxKeys = n }(maxKeys)
+	maxKeys = 3
+	r.RecordRead(1, k1, [][]byte{[]byte("k4")})
+	c.Assert(r.HotKeys(10), HasLen, 3)
+
+	// The counts are halved and the cold ones are dropped.
+	r.lastDecay = time.Now().Add(-2 * decayInterval)
+	r.RecordRead(2, k3, nil)
+	regions = r.HotRegions(10)
+	c.Assert(regions, HasLen, 2)
+	c.Assert(regions[0], DeepEquals, RegionStat{RegionID: 2, Key: k3, ReadCount: 1, WriteCount: 1})
+	c.Assert(regions[1], DeepEquals, RegionStat{RegionID: 1, Key: k1, ReadCount: 1})
+	keys = r.HotKeys(10)
+	c.Assert(keys, HasLen, 2)
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hyperloglog
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/juju/errors"
+)
+
+// precision is the number of bits of a hash value used to choose a register, there are 2^precision registers.
+// The standard error of the estimation is about 1.04 / sqrt(2^precision), 1.6% for 12.
+const precision = 12
+
+const numRegisters = 1 << precision
+
+// Sketch is a HyperLogLog sketch which estimates the number of distinct values added to it, using a fixed amount
+// of memory. The registers are allocated when the first value is added.
+// It's not thread safe.
+type Sketch struct {
+	registers []uint8
+}
+
+// New creates an empty sketch.
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Insert adds the value to the sketch.
+func (s *Sketch) Insert(value []byte) {
+	h := fnv.New64a()
+	h.Write(value)
+	s.InsertHash(mix(h.Sum64()))
+}
+
+// InsertHash adds a value to the sketch by its hash, the bits of the hash must be well distributed.
+func (s *Sketch) InsertHash(hash uint64) {
+	if s.registers == nil {
+		s.registers = make([]uint8, numRegisters)
+	}
+	idx := hash >> (64 - precision)
+	// The rank is the position of the first 1 bit in the remaining bits, a sentinel bit bounds it.
+	rest := hash<<precision | 1<<(precision-1)
+	rank := uint8(1)
+	for rest&(1<<63) == 0 {
+		rank++
+		rest <<= 1
+	}
+	if rank > s.registers[idx] {
+		s.registers[idx] = rank
+	}
+}
+
+// Merge merges the other sketch into s, then s estimates the distinct values added to both of them.
+func (s *Sketch) Merge(other *Sketch) {
+	if other.registers == nil {
+		return
+	}
+	if s.registers == nil {
+		s.registers = make([]uint8, numRegisters)
+	}
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of distinct values.
+func (s *Sketch) Count() int64 {
+	if s.registers == nil {
+		return 0
+	}
+	m := float64(numRegisters)
+	var sum float64
+	zeros := 0
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// Linear counting is more accurate for small cardinalities.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(estimate + 0.5)
+}
+
+// Encode encodes the sketch to bytes, an empty sketch is encoded to nil.
+func (s *Sketch) Encode() []byte {
+	if s.registers == nil {
+		return nil
+	}
+	return append([]byte(nil), s.registers...)
+}
+
+// Decode decodes the sketch from the bytes encoded by Encode.
+func Decode(data []byte) (*Sketch, error) {
+	if len(data) == 0 {
+		return New(), nil
+	}
+	if len(data) != numRegisters {
+		return nil, errors.Errorf("invalid hyperloglog sketch length %d", len(data))
+	}
+	return &Sketch{registers: append([]uint8(nil), data...)}, nil
+}
+
+// MemUsage returns the memory usage of the registers.
+func (s *Sketch) MemUsage() int64 {
+	return int64(len(s.registers))
+}
+
+// mix is the finalizer of splitmix64, it spreads the bits of the fnv hash, whose high bits are poorly distributed
+// for short values.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hyperloglog
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHyperLogLogSuite{})
+
+type testHyperLogLogSuite struct {
+}
+
+func (s *testHyperLogLogSuite) TestCount(c *C) {
+	defer testleak.AfterTest(c)()
+	sketch := New()
+	c.Assert(sketch.Count(), Equals, int64(0))
+	c.Assert(sketch.Encode(), IsNil)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 10; j++ {
+			sketch.Insert([]byte(strconv.Itoa(j)))
+		}
+	}
+	c.Assert(sketch.Count(), Equals, int64(10))
+
+	for _, n := range []int{1000, 100000} {
+		sketch = New()
+		for i := 0; i < n; i++ {
+			sketch.Insert([]byte(strconv.Itoa(i)))
+		}
+		errRate := math.Abs(float64(sketch.Count()-int64(n))) / float64(n)
+		c.Assert(errRate < 0.05, IsTrue, Commentf("n %d, count %d", n, sketch.Count()))
+	}
+}
+
+func (s *testHyperLogLogSuite) TestMerge(c *C) {
+	defer testleak.AfterTest(c)()
+	s1, s2, all := New(), New(), New()
+	for i := 0; i < 20000; i++ {
+		v := []byte(strconv.Itoa(i))
+		if i%2 == 0 {
+			s1.Insert(v)
+		} else {
+			s2.Insert(v)
+		}
+		// The values of s2 overlap half of the values of s1.
+		if i%4 == 0 {
+			s2.Insert(v)
+		}
+		all.Insert(v)
+	}
+	s1.Merge(New())
+	s1.Merge(s2)
+	c.Assert(s1.Count(), Equals, all.Count())
+
+	decoded, err := Decode(s1.Encode())
+	c.Assert(err, IsNil)
+	c.Assert(decoded.Count(), Equals, all.Count())
+	decoded, err = Decode(nil)
+	c.Assert(err, IsNil)
+	c.Assert(decoded.Count(), Equals, int64(0))
+	_, err = Decode([]byte{1, 2})
+	c.Assert(err, NotNil)
+}
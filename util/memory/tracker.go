@@ -0,0 +1,211 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/terror"
+)
+
+// ActionOnExceed is the action taken when the memory usage exceeds the limit of a tracker.
+type ActionOnExceed int
+
+const (
+	// ActionLog logs a warning once, the statement goes on.
+	ActionLog ActionOnExceed = iota
+	// ActionCancel makes Consume return ErrMemExceed, so the statement is canceled.
+	ActionCancel
+	// ActionSpill makes the executors which can spill to disk spill, the others log a warning as ActionLog.
+	ActionSpill
+)
+
+// String implements fmt.Stringer interface.
+func (a ActionOnExceed) String() string {
+	switch a {
+	case ActionLog:
+		return "log"
+	case ActionCancel:
+		return "cancel"
+	case ActionSpill:
+		return "spill"
+	}
+	return fmt.Sprintf("unknown action %d", int(a))
+}
+
+// ParseActionOnExceed parses the action name to ActionOnExceed.
+func ParseActionOnExceed(name string) (ActionOnExceed, error) {
+	for _, a := range []ActionOnExceed{ActionLog, ActionCancel, ActionSpill} {
+		if strings.EqualFold(name, a.String()) {
+			return a, nil
+		}
+	}
+	return ActionLog, errors.Errorf("unknown action on exceeding the memory quota: %s", name)
+}
+
+// ErrMemExceed is returned by Consume when the memory usage exceeds the limit of a tracker with ActionCancel.
+var ErrMemExceed = terror.ClassUtil.New(codeMemExceed, "Out Of Memory Quota! The memory usage of %s exceeds the quota %d bytes")
+
+const codeMemExceed terror.ErrCode = 1
+
+// Tracker tracks the memory usage of a statement or an executor.
+// Trackers are arranged into a tree, the memory consumed by a tracker is also consumed by its ancestors. A tracker
+// with a positive bytesLimit takes its action when the memory usage of it exceeds the limit.
+// It can be safely used concurrently.
+type Tracker struct {
+	label      string
+	bytesLimit int64
+	action     ActionOnExceed
+
+	bytesConsumed int64
+	maxConsumed   int64
+	// logged is set after the warning is logged, so it is logged only once.
+	logged int32
+
+	parent *Tracker
+	mu     struct {
+		sync.Mutex
+		children []*Tracker
+	}
+}
+
+// NewTracker creates a tracker. bytesLimit <= 0 means no limit.
+func NewTracker(label string, bytesLimit int64) *Tracker {
+	return &Tracker{label: label, bytesLimit: bytesLimit}
+}
+
+// SetActionOnExceed sets the action taken when the memory usage exceeds the limit of t.
+func (t *Tracker) SetActionOnExceed(action ActionOnExceed) {
+	t.action = action
+}
+
+// Label returns the label of t.
+func (t *Tracker) Label() string {
+	return t.label
+}
+
+// AttachTo attaches t to parent as a child, the memory consumed by t is consumed by parent too.
+func (t *Tracker) AttachTo(parent *Tracker) {
+	if t.parent != nil {
+		t.Detach()
+	}
+	parent.mu.Lock()
+	parent.mu.children = append(parent.mu.children, t)
+	parent.mu.Unlock()
+	t.parent = parent
+	t.parent.consume(t.BytesConsumed())
+}
+
+// Detach detaches t from its parent, the memory consumed by t is released from its ancestors.
+func (t *Tracker) Detach() {
+	if t.parent == nil {
+		return
+	}
+	parent := t.parent
+	parent.mu.Lock()
+	for i, child := range parent.mu.children {
+		if child == t {
+			parent.mu.children = append(parent.mu.children[:i], parent.mu.children[i+1:]...)
+			break
+		}
+	}
+	parent.mu.Unlock()
+	parent.consume(-t.BytesConsumed())
+	t.parent = nil
+}
+
+// Consume adds bytes to the memory usage of t and its ancestors, bytes can be negative to release memory.
+// When the memory usage of t or an ancestor exceeds its limit, the action of that tracker is taken, an error is
+// returned if the action is ActionCancel.
+func (t *Tracker) Consume(bytes int64) error {
+	exceeded := t.consume(bytes)
+	if exceeded == nil || bytes <= 0 {
+		return nil
+	}
+	if exceeded.action == ActionCancel {
+		return ErrMemExceed.GenByArgs(exceeded.label, exceeded.bytesLimit)
+	}
+	if atomic.CompareAndSwapInt32(&exceeded.logged, 0, 1) {
+		log.Warnf("[memory] the memory usage of %s exceeds the quota %d bytes:\n%s",
+			exceeded.label, exceeded.bytesLimit, exceeded)
+	}
+	return nil
+}
+
+// consume adds bytes to t and its ancestors, it returns the nearest one whose memory usage exceeds its limit.
+func (t *Tracker) consume(bytes int64) *Tracker {
+	var exceeded *Tracker
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		consumed := atomic.AddInt64(&tracker.bytesConsumed, bytes)
+		for {
+			maxConsumed := atomic.LoadInt64(&tracker.maxConsumed)
+			if consumed <= maxConsumed || atomic.CompareAndSwapInt64(&tracker.maxConsumed, maxConsumed, consumed) {
+				break
+			}
+		}
+		if exceeded == nil && tracker.bytesLimit > 0 && consumed > tracker.bytesLimit {
+			exceeded = tracker
+		}
+	}
+	return exceeded
+}
+
+// ShouldSpill returns whether the executor owning t should spill to disk, it is true when the memory usage of t or
+// an ancestor with ActionSpill exceeds its limit.
+func (t *Tracker) ShouldSpill() bool {
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		if tracker.action == ActionSpill && tracker.bytesLimit > 0 &&
+			atomic.LoadInt64(&tracker.bytesConsumed) > tracker.bytesLimit {
+			return true
+		}
+	}
+	return false
+}
+
+// BytesConsumed returns the memory usage of t.
+func (t *Tracker) BytesConsumed() int64 {
+	return atomic.LoadInt64(&t.bytesConsumed)
+}
+
+// MaxConsumed returns the peak memory usage of t.
+func (t *Tracker) MaxConsumed() int64 {
+	return atomic.LoadInt64(&t.maxConsumed)
+}
+
+// String returns the memory usage of t and its descendants.
+func (t *Tracker) String() string {
+	buf := new(bytes.Buffer)
+	t.toString("", buf)
+	return buf.String()
+}
+
+func (t *Tracker) toString(indent string, buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "%s\"%s\"{\n", indent, t.label)
+	if t.bytesLimit > 0 {
+		fmt.Fprintf(buf, "%s  \"quota\": %d\n", indent, t.bytesLimit)
+	}
+	fmt.Fprintf(buf, "%s  \"consumed\": %d\n", indent, t.BytesConsumed())
+	t.mu.Lock()
+	for _, child := range t.mu.children {
+		child.toString(indent+"  ", buf)
+	}
+	t.mu.Unlock()
+	fmt.Fprintf(buf, "%s}\n", indent)
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testTrackerSuite{})
+
+type testTrackerSuite struct{}
+
+func (s *testTrackerSuite) TestConsume(c *C) {
+	defer testleak.AfterTest(c)()
+	root := NewTracker("root", 0)
+	child1 := NewTracker("child1", 0)
+	child2 := NewTracker("child2", 0)
+	child1.AttachTo(root)
+	child2.AttachTo(root)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Assert(child1.Consume(10), IsNil)
+		}()
+		go func() {
+			defer wg.Done()
+			c.Assert(child2.Consume(20), IsNil)
+		}()
+	}
+	wg.Wait()
+	c.Assert(child1.BytesConsumed(), Equals, int64(100))
+	c.Assert(child2.BytesConsumed(), Equals, int64(200))
+	c.Assert(root.BytesConsumed(), Equals, int64(300))
+
+	c.Assert(child1.Consume(-100), IsNil)
+	c.Assert(root.BytesConsumed(), Equals, int64(200))
+	c.Assert(root.MaxConsumed(), Equals, int64(300))
+
+	child2.Detach()
+	c.Assert(root.BytesConsumed(), Equals, int64(0))
+	child2.AttachTo(child1)
+	c.Assert(child1.BytesConsumed(), Equals, int64(200))
+	c.Assert(root.BytesConsumed(), Equals, int64(200))
+}
+
+func (s *testTrackerSuite) TestActionOnExceed(c *C) {
+	defer testleak.AfterTest(c)()
+	root := NewTracker("query", 100)
+	root.SetActionOnExceed(ActionCancel)
+	child := NewTracker("child", 0)
+	child.AttachTo(root)
+	c.Assert(child.Consume(100), IsNil)
+	err := child.Consume(1)
+	c.Assert(terror.ErrorEqual(err, ErrMemExceed), IsTrue)
+	c.Assert(child.Consume(-50), IsNil)
+	c.Assert(child.Consume(10), IsNil)
+
+	root.SetActionOnExceed(ActionLog)
+	c.Assert(child.Consume(100), IsNil)
+	c.Assert(child.ShouldSpill(), IsFalse)
+
+	root.SetActionOnExceed(ActionSpill)
+	c.Assert(child.ShouldSpill(), IsTrue)
+	c.Assert(child.Consume(-100), IsNil)
+	c.Assert(child.ShouldSpill(), IsFalse)
+}
+
+func (s *testTrackerSuite) TestParseActionOnExceed(c *C) {
+	defer testleak.AfterTest(c)()
+	for _, a := range []ActionOnExceed{ActionLog, ActionCancel, ActionSpill} {
+		parsed, err := ParseActionOnExceed(a.String())
+		c.Assert(err, IsNil)
+		c.Assert(parsed, Equals, a)
+	}
+	parsed, err := ParseActionOnExceed("CANCEL")
+	c.Assert(err, IsNil)
+	c.Assert(parsed, Equals, ActionCancel)
+	_, err = ParseActionOnExceed("abort")
+	c.Assert(err, NotNil)
+}
+
+func (s *testTrackerSuite) TestString(c *C) {
+	defer testleak.AfterTest(c)()
+	root := NewTracker("query", 1000)
+	child := NewTracker("Sort", 0)
+	child.AttachTo(root)
+	c.Assert(child.Consume(10), IsNil)
+	c.Assert(root.String(), Equals, `"query"{
+  "quota": 1000
+  "consumed": 10
+  "Sort"{
+    "consumed": 10
+  }
+}
+`)
+}
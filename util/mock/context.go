@@ -66,7 +66,7 @@ func (c *Context) Txn() kv.Transaction {
 
 // GetClient implements context.Context GetClient interface.
 func (c *Context) GetClient() kv.Client {
-	if c.Store == nil {
+	if c.Store == nil || !c.Store.SupportCapability(kv.CapCoprocessor) {
 		return nil
 	}
 	return c.Store.GetClient()
@@ -19,19 +19,30 @@ import (
 
 // ProcessInfo is a struct used for show processlist statement.
 type ProcessInfo struct {
-	ID      uint64
-	User    string
-	Host    string
-	DB      string
+	ID   uint64
+	User string
+	Host string
+	DB   string
+	// Command is "Query" if a statement is executing, otherwise it's "Sleep".
 	Command string
-	Time    time.Time
-	State   uint16
-	Info    string
+	// Time is when the statement starts, or when the connection becomes idle.
+	Time time.Time
+	// State is the server status flags of the session.
+	State uint16
+	// Info is the text of the executing statement.
+	Info string
+	// Plan is the plan.Plan of the executing statement, it's nil if no statement is executing.
+	// It's shared with the executing statement, so it must not be modified.
+	Plan interface{}
 }
 
 // SessionManager is an interface for session manage. Show processlist and
 // kill statement rely on this interface.
 type SessionManager interface {
 	ShowProcessList() []ProcessInfo
+	// GetProcessInfo returns the ProcessInfo of the connection, it returns false if the connection doesn't exist.
+	GetProcessInfo(connectionID uint64) (ProcessInfo, bool)
+	// Kill interrupts the executing statement of the connection, the connection is closed as well unless query is
+	// true.
 	Kill(connectionID uint64, query bool)
 }
@@ -0,0 +1,90 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxEntries is the max number of the slow queries kept in memory, the oldest ones are dropped when it's exceeded.
+var maxEntries = 1024
+
+// Entry is a slow query.
+type Entry struct {
+	// Time is the time when the statement finished.
+	Time       time.Time
+	TxnStartTS uint64
+	User       string
+	Host       string
+	ConnID     uint64
+	DB         string
+	// Query is the text of the statement, it's normalized if tidb_slow_log_normalize is on.
+	Query string
+	// QueryTime is the sum of ParseTime, CompileTime and ExecuteTime.
+	QueryTime   time.Duration
+	ParseTime   time.Duration
+	CompileTime time.Duration
+	ExecuteTime time.Duration
+	// KVRequests is the number of the kv requests of the reads.
+	KVRequests int64
+	// ScannedKeys is the number of the keys read by the kv requests.
+	ScannedKeys int64
+	// ResultRows is the number of the rows returned to the client.
+	ResultRows int64
+	Succ       bool
+	// Details is the runtime statistics of the executors and the backoffs of the kv requests.
+	Details string
+}
+
+// String implements fmt.Stringer interface, it's the structured text of the slow query log.
+func (e *Entry) String() string {
+	s := fmt.Sprintf("query_time:%v parse_time:%v compile_time:%v execute_time:%v conn_id:%d user:%s@%s db:%s "+
+		"txn_start_ts:%d kv_requests:%d scanned_keys:%d result_rows:%d succ:%v",
+		e.QueryTime, e.ParseTime, e.CompileTime, e.ExecuteTime, e.ConnID, e.User, e.Host, e.DB,
+		e.TxnStartTS, e.KVRequests, e.ScannedKeys, e.ResultRows, e.Succ)
+	if e.Details != "" {
+		s += " " + e.Details
+	}
+	return s + " sql:" + e.Query
+}
+
+var recent = struct {
+	sync.Mutex
+	entries []*Entry
+	// next is the position of the next entry when entries is full.
+	next int
+}{}
+
+// Record records a slow query.
+func Record(e *Entry) {
+	recent.Lock()
+	defer recent.Unlock()
+	if len(recent.entries) < maxEntries {
+		recent.entries = append(recent.entries, e)
+		return
+	}
+	recent.entries[recent.next] = e
+	recent.next = (recent.next + 1) % len(recent.entries)
+}
+
+// Entries returns the slow queries recorded recently, the older ones come first.
+func Entries() []*Entry {
+	recent.Lock()
+	defer recent.Unlock()
+	entries := make([]*Entry, 0, len(recent.entries))
+	entries = append(entries, recent.entries[recent.next:]...)
+	return append(entries, recent.entries[:recent.next]...)
+}
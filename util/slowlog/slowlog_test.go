@@ -0,0 +1,82 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testSlowLogSuite{})
+
+type testSlowLogSuite struct{}
+
+func (s *testSlowLogSuite) TestRecord(c *C) {
+	defer testleak.AfterTest(c)()
+	defer func(n int) {
+		maxEntries = n
+	}(maxEntries)
+	maxEntries = 3
+
+	for i := 0; i < 2; i++ {
+		Record(&Entry{Query: fmt.Sprintf("select %d", i)})
+	}
+	entries := Entries()
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Query, Equals, "select 0")
+	c.Assert(entries[1].Query, Equals, "select 1")
+
+	// The oldest entries are dropped.
+	for i := 2; i < 7; i++ {
+		Record(&Entry{Query: fmt.Sprintf("select %d", i)})
+	}
+	entries = Entries()
+	c.Assert(entries, HasLen, 3)
+	for i, e := range entries {
+		c.Assert(e.Query, Equals, fmt.Sprintf("select %d", i+4))
+	}
+}
+
+func (s *testSlowLogSuite) TestString(c *C) {
+	defer testleak.AfterTest(c)()
+	e := &Entry{
+		User:        "root",
+		Host:        "127.0.0.1",
+		ConnID:      3,
+		DB:          "test",
+		Query:       "select * from t",
+		QueryTime:   time.Second,
+		ParseTime:   time.Millisecond,
+		CompileTime: 2 * time.Millisecond,
+		ExecuteTime: 997 * time.Millisecond,
+		TxnStartTS:  100,
+		KVRequests:  2,
+		ScannedKeys: 20,
+		ResultRows:  10,
+		Succ:        true,
+	}
+	c.Assert(e.String(), Equals, "query_time:1s parse_time:1ms compile_time:2ms execute_time:997ms conn_id:3 "+
+		"user:root@127.0.0.1 db:test txn_start_ts:100 kv_requests:2 scanned_keys:20 result_rows:10 succ:true "+
+		"sql:select * from t")
+	e.Details = "[BACKOFF_DETAILS] txnLock{times:1, sleep:1s}"
+	c.Assert(e.String(), Matches, ".* succ:true \\[BACKOFF_DETAILS\\] .* sql:select \\* from t")
+}
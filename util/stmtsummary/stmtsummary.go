@@ -0,0 +1,235 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stmtsummary
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// maxDigests is the max number of the digests summarized, the statements of the new digests are summarized in a
+// summary without schema and digest when it's reached.
+var maxDigests = 4096
+
+// StmtExecInfo is an executed statement.
+type StmtExecInfo struct {
+	SchemaName string
+	// SQL is the text of the statement, its normalized text and digest are computed by Add.
+	SQL string
+	// Plan is the plan of the statement.
+	Plan      string
+	StartTime time.Time
+	Latency   time.Duration
+	Succ      bool
+	Warnings  uint64
+	// AffectedRows is the number of the rows written.
+	AffectedRows uint64
+	// SentRows is the number of the rows returned to the client.
+	SentRows uint64
+	// ExaminedRows is the number of the keys read by the kv requests.
+	ExaminedRows uint64
+}
+
+// Summary is the statements of a digest in a schema.
+type Summary struct {
+	// SchemaName and Digest are "" for the summary of the statements whose digests are not summarized since there
+	// are too many.
+	SchemaName string
+	Digest     string
+	DigestText string
+	Count      uint64
+	Errors     uint64
+	Warnings   uint64
+	SumLatency time.Duration
+	MinLatency time.Duration
+	MaxLatency time.Duration
+	// SumAffectedRows, SumSentRows and SumExaminedRows are the sums of the rows of the statements.
+	SumAffectedRows uint64
+	SumSentRows     uint64
+	SumExaminedRows uint64
+	FirstSeen       time.Time
+	LastSeen        time.Time
+	// SampleSQL and SamplePlan are the text and the plan of the last statement.
+	SampleSQL  string
+	SamplePlan string
+	// latencies is the histogram of the latencies, latencies[i] is the number of the statements whose latencies are
+	// in (bucketBound(i-1), bucketBound(i)].
+	latencies [numBuckets]uint64
+}
+
+// The latency histogram has buckets growing exponentially from 1 microsecond, each bound is 2^(1/4) times of the
+// previous one, so a quantile is at most 19% larger than the real value. The last bucket has no upper bound.
+const (
+	numBuckets       = 128
+	bucketsPerDouble = 4
+	minBucketBound   = time.Microsecond
+)
+
+func bucketBound(i int) time.Duration {
+	return time.Duration(float64(minBucketBound) * math.Pow(2, float64(i)/bucketsPerDouble))
+}
+
+func bucketIndex(d time.Duration) int {
+	if d <= minBucketBound {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(float64(d)/float64(minBucketBound)) * bucketsPerDouble))
+	if i >= numBuckets {
+		return numBuckets - 1
+	}
+	return i
+}
+
+// Quantile returns the latency which the q quantile of the statements doesn't exceed, q is in [0, 1].
+func (s *Summary) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var count uint64
+	for i, n := range s.latencies {
+		count += n
+		if count >= rank {
+			if i < numBuckets-1 && bucketBound(i) < s.MaxLatency {
+				return bucketBound(i)
+			}
+			break
+		}
+	}
+	return s.MaxLatency
+}
+
+func (s *Summary) add(info *StmtExecInfo) {
+	if s.Count == 0 || info.Latency < s.MinLatency {
+		s.MinLatency = info.Latency
+	}
+	if info.Latency > s.MaxLatency {
+		s.MaxLatency = info.Latency
+	}
+	if s.Count == 0 || info.StartTime.Before(s.FirstSeen) {
+		s.FirstSeen = info.StartTime
+	}
+	if !info.StartTime.Before(s.LastSeen) {
+		s.LastSeen = info.StartTime
+		s.SampleSQL = info.SQL
+		s.SamplePlan = info.Plan
+	}
+	s.Count++
+	if !info.Succ {
+		s.Errors++
+	}
+	s.Warnings += info.Warnings
+	s.SumLatency += info.Latency
+	s.SumAffectedRows += info.AffectedRows
+	s.SumSentRows += info.SentRows
+	s.SumExaminedRows += info.ExaminedRows
+	s.latencies[bucketIndex(info.Latency)]++
+}
+
+// enabled is 1 if tidb_enable_stmt_summary is on.
+var enabled int32
+
+var summaries = struct {
+	sync.Mutex
+	// m is keyed by the schema name and the digest.
+	m map[string]*Summary
+}{m: make(map[string]*Summary)}
+
+// Enabled returns whether the statements are summarized.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// SetEnabled turns the statement summary on or off, the summaries are cleared when it's turned off.
+func SetEnabled(on bool) {
+	if on {
+		atomic.StoreInt32(&enabled, 1)
+		return
+	}
+	atomic.StoreInt32(&enabled, 0)
+	summaries.Lock()
+	summaries.m = make(map[string]*Summary)
+	summaries.Unlock()
+}
+
+// SetVariable applies the global variable tidb_enable_stmt_summary to the server, the other variables are ignored.
+func SetVariable(name, val string) error {
+	if name == variable.TiDBEnableStmtSummary {
+		SetEnabled(strings.EqualFold(val, "ON") || val == "1")
+	}
+	return nil
+}
+
+// Add adds an executed statement to the summary of its digest.
+func Add(info *StmtExecInfo) {
+	if !Enabled() {
+		return
+	}
+	digestText := parser.Normalize(info.SQL)
+	digest := parser.Digest(digestText)
+	key := info.SchemaName + "." + digest
+
+	summaries.Lock()
+	defer summaries.Unlock()
+	s, ok := summaries.m[key]
+	if !ok {
+		if len(summaries.m) >= maxDigests {
+			// The statements of all the schemas are summarized together.
+			key = ""
+			if s = summaries.m[key]; s == nil {
+				s = &Summary{}
+				summaries.m[key] = s
+			}
+		} else {
+			s = &Summary{SchemaName: info.SchemaName, Digest: digest, DigestText: digestText}
+			summaries.m[key] = s
+		}
+	}
+	s.add(info)
+}
+
+// bySchemaAndDigest sorts the summaries by the schema names and the digests.
+type bySchemaAndDigest []*Summary
+
+func (s bySchemaAndDigest) Len() int      { return len(s) }
+func (s bySchemaAndDigest) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySchemaAndDigest) Less(i, j int) bool {
+	if s[i].SchemaName != s[j].SchemaName {
+		return s[i].SchemaName < s[j].SchemaName
+	}
+	return s[i].Digest < s[j].Digest
+}
+
+// Summaries returns copies of the summaries sorted by the schema names and the digests.
+func Summaries() []*Summary {
+	summaries.Lock()
+	ss := make([]*Summary, 0, len(summaries.m))
+	for _, s := range summaries.m {
+		c := *s
+		ss = append(ss, &c)
+	}
+	summaries.Unlock()
+	sort.Sort(bySchemaAndDigest(ss))
+	return ss
+}
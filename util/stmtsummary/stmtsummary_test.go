@@ -0,0 +1,149 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stmtsummary
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testStmtSummarySuite{})
+
+type testStmtSummarySuite struct{}
+
+func (s *testStmtSummarySuite) TestAdd(c *C) {
+	defer testleak.AfterTest(c)()
+	SetEnabled(true)
+	defer SetEnabled(false)
+
+	now := time.Now()
+	Add(&StmtExecInfo{SchemaName: "test", SQL: "select * from t where a = 1", Plan: "Table(t)", StartTime: now,
+		Latency: 2 * time.Millisecond, Succ: true, SentRows: 1, ExaminedRows: 10})
+	Add(&StmtExecInfo{SchemaName: "test", SQL: "select * from t where a = 2", Plan: "Index(t.a)", StartTime: now.Add(time.Second),
+		Latency: time.Millisecond, Succ: false, Warnings: 1})
+	Add(&StmtExecInfo{SchemaName: "test", SQL: "insert t values (1)", StartTime: now, Latency: 3 * time.Millisecond,
+		Succ: true, AffectedRows: 1})
+	Add(&StmtExecInfo{SchemaName: "mysql", SQL: "select * from t where a = 3", StartTime: now, Latency: time.Millisecond,
+		Succ: true})
+
+	selectDigest := parser.Digest("select * from t where a = ?")
+	insertDigest := parser.Digest("insert t values ( ? )")
+	ss := Summaries()
+	c.Assert(ss, HasLen, 3)
+	c.Assert(ss[0].SchemaName, Equals, "mysql")
+	c.Assert(ss[0].Digest, Equals, selectDigest)
+	c.Assert(ss[0].Count, Equals, uint64(1))
+
+	var sel, ins *Summary
+	if ss[1].Digest == selectDigest {
+		sel, ins = ss[1], ss[2]
+	} else {
+		sel, ins = ss[2], ss[1]
+	}
+	c.Assert(sel.SchemaName, Equals, "test")
+	c.Assert(sel.DigestText, Equals, "select * from t where a = ?")
+	c.Assert(sel.Count, Equals, uint64(2))
+	c.Assert(sel.Errors, Equals, uint64(1))
+	c.Assert(sel.Warnings, Equals, uint64(1))
+	c.Assert(sel.SumLatency, Equals, 3*time.Millisecond)
+	c.Assert(sel.MinLatency, Equals, time.Millisecond)
+	c.Assert(sel.MaxLatency, Equals, 2*time.Millisecond)
+	c.Assert(sel.SumSentRows, Equals, uint64(1))
+	c.Assert(sel.SumExaminedRows, Equals, uint64(10))
+	c.Assert(sel.FirstSeen, Equals, now)
+	c.Assert(sel.LastSeen, Equals, now.Add(time.Second))
+	c.Assert(sel.SampleSQL, Equals, "select * from t where a = 2")
+	c.Assert(sel.SamplePlan, Equals, "Index(t.a)")
+	c.Assert(ins.Digest, Equals, insertDigest)
+	c.Assert(ins.SumAffectedRows, Equals, uint64(1))
+
+	// The summaries are cleared when it's turned off.
+	SetEnabled(false)
+	Add(&StmtExecInfo{SchemaName: "test", SQL: "select 1", StartTime: now, Latency: time.Millisecond, Succ: true})
+	c.Assert(Summaries(), HasLen, 0)
+}
+
+func (s *testStmtSummarySuite) TestQuantile(c *C) {
+	defer testleak.AfterTest(c)()
+	sum := &Summary{}
+	c.Assert(sum.Quantile(0.99), Equals, time.Duration(0))
+	for i := 1; i <= 100; i++ {
+		sum.add(&StmtExecInfo{Latency: time.Duration(i) * time.Millisecond})
+	}
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		expected := time.Duration(q * float64(100*time.Millisecond))
+		d := sum.Quantile(q)
+		c.Assert(d >= expected, IsTrue, Commentf("q %v, %v < %v", q, d, expected))
+		c.Assert(float64(d) <= float64(expected)*1.19, IsTrue, Commentf("q %v, %v", q, d))
+	}
+	// The quantile doesn't exceed the max latency.
+	c.Assert(sum.Quantile(0.999), Equals, 100*time.Millisecond)
+	c.Assert(sum.Quantile(1), Equals, 100*time.Millisecond)
+
+	// The latencies out of the bounds of the buckets.
+	sum = &Summary{}
+	sum.add(&StmtExecInfo{Latency: time.Nanosecond})
+	c.Assert(sum.Quantile(0.5), Equals, time.Nanosecond)
+	sum.add(&StmtExecInfo{Latency: 100 * time.Hour})
+	c.Assert(sum.Quantile(1), Equals, 100*time.Hour)
+}
+
+func (s *testStmtSummarySuite) TestMaxDigests(c *C) {
+	defer testleak.AfterTest(c)()
+	defer func(n int) {
+		maxDigests = n
+	}(maxDigests)
+	maxDigests = 2
+	SetEnabled(true)
+	defer SetEnabled(false)
+
+	for i := 0; i < 5; i++ {
+		sql := fmt.Sprintf("select * from t%d", i)
+		Add(&StmtExecInfo{SchemaName: "test", SQL: sql, Latency: time.Millisecond, Succ: true})
+		Add(&StmtExecInfo{SchemaName: "test", SQL: sql, Latency: time.Millisecond, Succ: true})
+	}
+	ss := Summaries()
+	c.Assert(ss, HasLen, 3)
+	// The statements of the new digests are summarized without schema and digest.
+	c.Assert(ss[0].SchemaName, Equals, "")
+	c.Assert(ss[0].Digest, Equals, "")
+	c.Assert(ss[0].Count, Equals, uint64(6))
+	c.Assert(ss[1].Count, Equals, uint64(2))
+	c.Assert(ss[2].Count, Equals, uint64(2))
+}
+
+func (s *testStmtSummarySuite) TestSetVariable(c *C) {
+	defer testleak.AfterTest(c)()
+	defer SetEnabled(false)
+	c.Assert(Enabled(), IsFalse)
+	c.Assert(SetVariable(variable.TiDBEnableStmtSummary, "1"), IsNil)
+	c.Assert(Enabled(), IsTrue)
+	c.Assert(SetVariable(variable.TiDBEnableStmtSummary, "OFF"), IsNil)
+	c.Assert(Enabled(), IsFalse)
+	c.Assert(SetVariable(variable.TiDBEnableStmtSummary, "ON"), IsNil)
+	c.Assert(Enabled(), IsTrue)
+	// The other variables are ignored.
+	c.Assert(SetVariable(variable.AutocommitVar, "0"), IsNil)
+	c.Assert(Enabled(), IsTrue)
+}
@@ -15,6 +15,7 @@ package testkit
 
 import (
 	"fmt"
+	"sort"
 	"sync/atomic"
 
 	"github.com/juju/errors"
@@ -46,6 +47,19 @@ func (res *Result) Check(expected [][]interface{}) {
 	res.c.Assert(got, check.Equals, need, res.comment)
 }
 
+// Sort sorts the result rows by their string forms, it's used to check the result of a query whose order is not
+// defined.
+func (res *Result) Sort() *Result {
+	sort.Sort(byString(res.rows))
+	return res
+}
+
+type byString [][]interface{}
+
+func (s byString) Len() int           { return len(s) }
+func (s byString) Less(i, j int) bool { return fmt.Sprintf("%v", s[i]) < fmt.Sprintf("%v", s[j]) }
+func (s byString) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 // Rows returns the result data.
 func (res *Result) Rows() [][]interface{} {
 	return res.rows
@@ -382,7 +382,8 @@ func (t Time) ToPackedUint() (uint64, error) {
 		return 0, nil
 	}
 	if t.Type == mysql.TypeTimestamp {
-		// TODO: Consider time_zone variable.
+		// The timestamp is in the system time zone here, the callers convert it from the session
+		// time zone by ConvertTimeZone before packing it.
 		if t1, err := t.Time.GoTime(gotime.Local); err == nil {
 			utc := t1.UTC()
 			tm = FromGoTime(utc)
@@ -432,6 +433,26 @@ func (t *Time) FromPackedUint(packed uint64) error {
 	return nil
 }
 
+// ConvertTimeZone converts the time value from one time zone to another, nil means the system time zone.
+// Only the non-zero TIMESTAMP values are converted, the other types have no time zone.
+func (t *Time) ConvertTimeZone(from, to *gotime.Location) error {
+	if from == nil {
+		from = gotime.Local
+	}
+	if to == nil {
+		to = gotime.Local
+	}
+	if t.Type != mysql.TypeTimestamp || t.IsZero() || from == to {
+		return nil
+	}
+	t1, err := t.Time.GoTime(from)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	t.Time = FromGoTime(t1.In(to))
+	return nil
+}
+
 func (t *Time) check() error {
 	switch t.Type {
 	case mysql.TypeTimestamp:
@@ -788,3 +788,32 @@ func (s *testTimeSuite) TestTamestampDiff(c *C) {
 		c.Assert(TimestampDiff(test.unit, t1, t2), Equals, test.expect)
 	}
 }
+
+func (s *testTimeSuite) TestConvertTimeZone(c *C) {
+	defer testleak.AfterTest(c)()
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	c.Assert(err, IsNil)
+	tests := []struct {
+		input  Time
+		from   *time.Location
+		to     *time.Location
+		expect TimeInternal
+	}{
+		{Time{FromDate(2017, 1, 1, 12, 0, 0, 0), mysql.TypeTimestamp, 0}, time.UTC, loc, FromDate(2017, 1, 1, 20, 0, 0, 0)},
+		{Time{FromDate(2017, 1, 1, 12, 0, 0, 0), mysql.TypeTimestamp, 0}, loc, time.UTC, FromDate(2017, 1, 1, 4, 0, 0, 0)},
+		{Time{FromDate(2017, 1, 1, 12, 0, 0, 0), mysql.TypeTimestamp, 0}, loc, loc, FromDate(2017, 1, 1, 12, 0, 0, 0)},
+		{Time{FromDate(2017, 1, 1, 12, 0, 0, 0), mysql.TypeDatetime, 0}, time.UTC, loc, FromDate(2017, 1, 1, 12, 0, 0, 0)},
+		{Time{ZeroTime, mysql.TypeTimestamp, 0}, time.UTC, loc, ZeroTime},
+	}
+	for _, t := range tests {
+		err = t.input.ConvertTimeZone(t.from, t.to)
+		c.Assert(err, IsNil)
+		c.Assert(t.input.Compare(Time{Time: t.expect}), Equals, 0)
+	}
+	// nil means the system time zone.
+	t1 := Time{FromDate(2017, 1, 1, 12, 0, 0, 0), mysql.TypeTimestamp, 0}
+	t2 := t1
+	c.Assert(t1.ConvertTimeZone(nil, loc), IsNil)
+	c.Assert(t2.ConvertTimeZone(time.Local, loc), IsNil)
+	c.Assert(t1.Compare(t2), Equals, 0)
+}